@@ -0,0 +1,293 @@
+// Package client is a Go client for the quotes service API. Failed calls
+// return an *Error, which errors.Is matches against ErrNotFound,
+// ErrValidation, ErrRateLimited and ErrServerError. GET requests that fail
+// with 429, a 5xx or a network error are retried with backoff, within the
+// deadline of their context. StreamQuotes reads large lists a quote at a
+// time.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"quotes-service/internal/models"
+)
+
+// The API models, for callers outside this module.
+type (
+	Quote            = models.Quote
+	AddQuoteRequest  = models.AddQuoteRequest
+	AddQuoteResponse = models.AddQuoteResponse
+)
+
+// RetryPolicy sets how failed GET requests are retried. The wait before
+// each retry doubles from BaseDelay up to MaxDelay, with jitter, unless
+// the server asks for longer with Retry-After.
+type RetryPolicy struct {
+	// MaxAttempts is how many times a request is sent in all; 1 or less
+	// turns retries off.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy is used unless WithRetry is given.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+// Client calls the API at one base URL. It is safe for concurrent use.
+type Client struct {
+	baseURL *url.URL
+	http    *http.Client
+	apiKey  string
+	retry   RetryPolicy
+	// signClient and signSecret sign each request when set.
+	signClient string
+	signSecret string
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sends the requests with hc instead of
+// http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.http = hc
+	}
+}
+
+// WithAPIKey authenticates every request with key.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithRetry replaces DefaultRetryPolicy.
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// New returns a client of the API at baseURL, such as
+// "https://quotes.example.com".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("client: base URL %q is not http or https", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	c := &Client{baseURL: u, http: http.DefaultClient, retry: DefaultRetryPolicy}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// ListOptions selects a page of quotes. Zero values are left to the
+// server's defaults.
+type ListOptions struct {
+	Author string
+	Lang   string
+	Limit  int
+	Offset int
+}
+
+// ListQuotes returns one page of quotes.
+func (c *Client) ListQuotes(ctx context.Context, opts ListOptions) ([]Quote, error) {
+	query := url.Values{}
+	if opts.Author != "" {
+		query.Set("author", opts.Author)
+	}
+	if opts.Lang != "" {
+		query.Set("lang", opts.Lang)
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	var quotes []Quote
+	err := c.do(ctx, http.MethodGet, "/quotes", query, nil, dataOf(&quotes))
+	return quotes, err
+}
+
+// GetQuote returns the quote with the given ID.
+func (c *Client) GetQuote(ctx context.Context, id int64) (Quote, error) {
+	var quote Quote
+	err := c.do(ctx, http.MethodGet, "/quotes/"+strconv.FormatInt(id, 10), nil, nil, dataOf(&quote))
+	return quote, err
+}
+
+// RandomQuote returns a random quote.
+func (c *Client) RandomQuote(ctx context.Context) (Quote, error) {
+	var quote Quote
+	err := c.do(ctx, http.MethodGet, "/quotes/random", nil, nil, dataOf(&quote))
+	return quote, err
+}
+
+// AddQuote adds a quote. It is not retried.
+func (c *Client) AddQuote(ctx context.Context, req AddQuoteRequest) (AddQuoteResponse, error) {
+	var resp AddQuoteResponse
+	err := c.do(ctx, http.MethodPost, "/quotes", nil, req, &resp)
+	return resp, err
+}
+
+// DeleteQuote deletes the quote with the given ID. It is not retried.
+func (c *Client) DeleteQuote(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, "/quotes/"+strconv.FormatInt(id, 10), nil, nil, nil)
+}
+
+// envelope is the standard success response.
+type envelope struct {
+	Data any `json:"data"`
+}
+
+// dataOf decodes the data of the standard success response into v.
+func dataOf(v any) any {
+	return &envelope{Data: v}
+}
+
+// do sends the request, retrying a GET under the retry policy, and decodes
+// a successful response into out unless it is nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("client: encode request: %w", err)
+		}
+	}
+	return c.request(ctx, method, path, query, body, func(resp *http.Response) error {
+		if out == nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			return nil
+		}
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("client: decode response: %w", err)
+		}
+		return nil
+	})
+}
+
+// request sends the request, retrying a GET under the retry policy, and
+// hands a successful response to read.
+func (c *Client) request(ctx context.Context, method, path string, query url.Values, body []byte, read func(*http.Response) error) error {
+	u := *c.baseURL
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	attempts := 1
+	if method == http.MethodGet {
+		attempts = max(c.retry.MaxAttempts, 1)
+	}
+	for attempt := 1; ; attempt++ {
+		err := c.send(ctx, method, u.String(), body, read)
+		if err == nil || attempt >= attempts || !retryable(ctx, err) {
+			return err
+		}
+		if !c.wait(ctx, c.delay(attempt, err)) {
+			return err
+		}
+	}
+}
+
+// send makes one attempt at a request.
+func (c *Client) send(ctx context.Context, method, target string, body []byte, read func(*http.Response) error) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return fmt.Errorf("client: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.signClient != "" {
+		SignRequest(req, body, c.signClient, c.signSecret, time.Now())
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("client: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return newError(resp)
+	}
+	return read(resp)
+}
+
+// retryable reports whether a failed attempt is worth repeating: the
+// server was overloaded or failed, or the request never got an answer.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.retryable()
+	}
+	var stop *stopError
+	if errors.As(err, &stop) {
+		return false
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// delay returns how long to wait after the given failed attempt.
+func (c *Client) delay(attempt int, err error) time.Duration {
+	backoff := c.retry.BaseDelay << (attempt - 1)
+	if c.retry.MaxDelay > 0 && (backoff <= 0 || backoff > c.retry.MaxDelay) {
+		backoff = c.retry.MaxDelay
+	}
+	if backoff > 0 {
+		backoff = backoff/2 + rand.N(backoff/2+1)
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > backoff {
+		return apiErr.RetryAfter
+	}
+	return backoff
+}
+
+// wait sleeps for d and reports whether to try again: not if ctx ends
+// first or its deadline would pass before the next attempt.
+func (c *Client) wait(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return false
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
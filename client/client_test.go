@@ -0,0 +1,243 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"quotes-service/client"
+)
+
+// step is one scripted response.
+type step struct {
+	status     int
+	body       string
+	retryAfter string
+}
+
+// scripted answers the requests with steps in turn, repeating the last
+// one, and counts them.
+func scripted(t *testing.T, steps ...step) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		s := steps[min(n, len(steps))-1]
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-ID", "req-42")
+		if s.retryAfter != "" {
+			w.Header().Set("Retry-After", s.retryAfter)
+		}
+		w.WriteHeader(s.status)
+		io.WriteString(w, s.body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+const okQuote = `{"status":"success","data":{"id":7,"text":"Quote","author":"Author"}}`
+
+var fastRetry = client.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		step           step
+		kind           error
+		expectedCode   string
+		expectedFields int
+		expectedCalls  int32
+	}{
+		{
+			name:          "not found",
+			step:          step{status: http.StatusNotFound, body: `{"status":"error","code":"quote_not_found","error":"Quote not found."}`},
+			kind:          client.ErrNotFound,
+			expectedCode:  "quote_not_found",
+			expectedCalls: 1,
+		},
+		{
+			name:           "validation",
+			step:           step{status: http.StatusBadRequest, body: `{"status":"error","code":"invalid_request","error":"Invalid request.","fields":["text cannot be empty","author cannot be empty"]}`},
+			kind:           client.ErrValidation,
+			expectedCode:   "invalid_request",
+			expectedFields: 2,
+			expectedCalls:  1,
+		},
+		{
+			name:          "unprocessable",
+			step:          step{status: http.StatusUnprocessableEntity, body: `{"status":"error","code":"content_rejected","error":"Quote content is not allowed."}`},
+			kind:          client.ErrValidation,
+			expectedCode:  "content_rejected",
+			expectedCalls: 1,
+		},
+		{
+			name:          "rate limited",
+			step:          step{status: http.StatusTooManyRequests, body: `{"status":"error","code":"rate_limited","error":"Too many requests."}`, retryAfter: "0"},
+			kind:          client.ErrRateLimited,
+			expectedCode:  "rate_limited",
+			expectedCalls: 3,
+		},
+		{
+			name:          "server error",
+			step:          step{status: http.StatusServiceUnavailable, body: `{"status":"error","code":"not_ready","error":"Service is not ready."}`},
+			kind:          client.ErrServerError,
+			expectedCode:  "not_ready",
+			expectedCalls: 3,
+		},
+		{
+			name:          "server error without a body",
+			step:          step{status: http.StatusBadGateway, body: "<html>bad gateway</html>"},
+			kind:          client.ErrServerError,
+			expectedCalls: 3,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server, calls := scripted(t, tc.step)
+			c, err := client.New(server.URL, client.WithRetry(fastRetry))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = c.GetQuote(context.Background(), 7)
+			if !errors.Is(err, tc.kind) {
+				t.Fatalf("expected %v, got %v", tc.kind, err)
+			}
+			for _, other := range []error{client.ErrNotFound, client.ErrValidation, client.ErrRateLimited, client.ErrServerError} {
+				if other != tc.kind && errors.Is(err, other) {
+					t.Fatalf("expected %v not to match %v", err, other)
+				}
+			}
+			var apiErr *client.Error
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected a *client.Error, got %T", err)
+			}
+			if apiErr.StatusCode != tc.step.status || apiErr.Code != tc.expectedCode || len(apiErr.Fields) != tc.expectedFields || apiErr.RequestID != "req-42" {
+				t.Fatalf("unexpected error %+v", apiErr)
+			}
+			if got := calls.Load(); got != tc.expectedCalls {
+				t.Fatalf("expected %d calls, got %d", tc.expectedCalls, got)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	unavailable := step{status: http.StatusServiceUnavailable, body: `{"status":"error","code":"not_ready","error":"Service is not ready."}`}
+	limited := step{status: http.StatusTooManyRequests, body: `{"status":"error","code":"rate_limited","error":"Too many requests."}`}
+	ok := step{status: http.StatusOK, body: okQuote}
+
+	tests := []struct {
+		name          string
+		steps         []step
+		policy        client.RetryPolicy
+		expectedErr   error
+		expectedCalls int32
+	}{
+		{name: "recovers", steps: []step{unavailable, limited, ok}, policy: fastRetry, expectedCalls: 3},
+		{name: "budget spent", steps: []step{unavailable, unavailable, unavailable, ok}, policy: fastRetry, expectedErr: client.ErrServerError, expectedCalls: 3},
+		{name: "retries off", steps: []step{unavailable, ok}, policy: client.RetryPolicy{MaxAttempts: 1}, expectedErr: client.ErrServerError, expectedCalls: 1},
+		{name: "larger budget", steps: []step{unavailable, unavailable, unavailable, ok}, policy: client.RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond}, expectedCalls: 4},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server, calls := scripted(t, tc.steps...)
+			c, err := client.New(server.URL, client.WithRetry(tc.policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			quote, err := c.GetQuote(context.Background(), 7)
+			if tc.expectedErr != nil {
+				if !errors.Is(err, tc.expectedErr) {
+					t.Fatalf("expected %v, got %v", tc.expectedErr, err)
+				}
+			} else if err != nil || quote.ID != 7 {
+				t.Fatalf("unexpected quote %+v, %v", quote, err)
+			}
+			if got := calls.Load(); got != tc.expectedCalls {
+				t.Fatalf("expected %d calls, got %d", tc.expectedCalls, got)
+			}
+		})
+	}
+}
+
+func TestNoRetryForWrites(t *testing.T) {
+	server, calls := scripted(t, step{status: http.StatusInternalServerError, body: `{"status":"error","code":"add_quote_failed","error":"Failed to add quote."}`})
+	c, err := client.New(server.URL, client.WithRetry(fastRetry))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.AddQuote(context.Background(), client.AddQuoteRequest{Text: "Quote", Author: "Author"}); !errors.Is(err, client.ErrServerError) {
+		t.Fatalf("expected ErrServerError, got %v", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected a POST to be sent once, got %d calls", got)
+	}
+}
+
+func TestRetryHonorsDeadline(t *testing.T) {
+	tests := []struct {
+		name   string
+		step   step
+		policy client.RetryPolicy
+	}{
+		{
+			name:   "retry after past the deadline",
+			step:   step{status: http.StatusTooManyRequests, body: `{"status":"error","code":"rate_limited","error":"Too many requests."}`, retryAfter: "30"},
+			policy: fastRetry,
+		},
+		{
+			name:   "backoff past the deadline",
+			step:   step{status: http.StatusServiceUnavailable, body: `{"status":"error","code":"not_ready","error":"Service is not ready."}`},
+			policy: client.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Minute},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server, calls := scripted(t, tc.step)
+			c, err := client.New(server.URL, client.WithRetry(tc.policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			start := time.Now()
+			_, err = c.GetQuote(ctx, 7)
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Fatalf("expected to give up at once, took %v", elapsed)
+			}
+			var apiErr *client.Error
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tc.step.status {
+				t.Fatalf("expected the last API error, got %v", err)
+			}
+			if got := calls.Load(); got != 1 {
+				t.Fatalf("expected 1 call, got %d", got)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	server, _ := scripted(t, step{status: http.StatusTooManyRequests, body: `{"status":"error","code":"rate_limited","error":"Too many requests."}`, retryAfter: "120"})
+	c, err := client.New(server.URL, client.WithRetry(client.RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.RandomQuote(context.Background())
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter != 2*time.Minute {
+		t.Fatalf("expected a Retry-After of 2m, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"quotes-service/client"
+	"quotes-service/internal/http-server/middleware/auth"
+	"quotes-service/internal/http-server/middleware/signature"
+)
+
+// signedServer checks signatures as the service does and answers a
+// created quote for the principal's writes.
+func signedServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	verify := signature.New(logger, signature.Options{
+		Clients:      map[string]string{"acme": "s3cret"},
+		MaxSkew:      time.Minute,
+		Required:     true,
+		MaxBodyBytes: 1 << 20,
+	})
+	server := httptest.NewServer(verify(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req client.AddQuoteRequest
+		principal, _ := auth.Principal(r.Context())
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || principal != "acme" || req.Text == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"id":1,"message":"Quote added successfully"}`)
+	})))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSigning(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           []client.Option
+		expectedStatus int
+	}{
+		{name: "signed", opts: []client.Option{client.WithSigning("acme", "s3cret")}},
+		{name: "wrong secret", opts: []client.Option{client.WithSigning("acme", "guess")}, expectedStatus: http.StatusUnauthorized},
+		{name: "unsigned", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := signedServer(t)
+			c, err := client.New(server.URL+"/", tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := c.AddQuote(context.Background(), client.AddQuoteRequest{Text: "Quote", Author: "Author"})
+			if tc.expectedStatus == 0 {
+				if err != nil || resp.ID != 1 {
+					t.Fatalf("expected the signed quote to be added, got %+v, %v", resp, err)
+				}
+				return
+			}
+			var apiErr *client.Error
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tc.expectedStatus || apiErr.Code != "invalid_signature" {
+				t.Fatalf("expected %d invalid_signature, got %v", tc.expectedStatus, err)
+			}
+		})
+	}
+}
+
+func TestSignRequest(t *testing.T) {
+	server := signedServer(t)
+	body := []byte(`{"text":"Quote","author":"Author"}`)
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/quotes?dry_run=true", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SignRequest(req, body, "acme", "s3cret", time.Now())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,144 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// streamPageSize is the page size StreamQuotes asks for unless
+// ListOptions.Limit is set. The server cuts it down to its maximum.
+const streamPageSize = 1000
+
+// stopError carries an error returned by a StreamQuotes callback, which
+// ends the stream at once instead of being retried.
+type stopError struct {
+	err error
+}
+
+func (e *stopError) Error() string {
+	return e.err.Error()
+}
+
+func (e *stopError) Unwrap() error {
+	return e.err
+}
+
+// StreamQuotes calls fn with each quote opts selects, in order, without
+// holding more than one of them in memory at a time. It reads the JSON
+// Lines export page by page, and falls back to pages of the JSON list when
+// the server has no export or opts.Author is set, which the export cannot
+// filter by. opts.Limit sets the size of the pages asked for and
+// opts.Offset where to start.
+//
+// The stream stops at the first error fn returns, which StreamQuotes
+// returns as is, or when ctx ends. A page that breaks off part way is
+// fetched again without calling fn twice for the quotes already passed on.
+// Quotes added or deleted during the stream shift the pages after them,
+// though, so one may be missed or seen twice.
+func (c *Client) StreamQuotes(ctx context.Context, opts ListOptions, fn func(Quote) error) error {
+	path, lines := "/quotes/export", true
+	if opts.Author != "" {
+		path, lines = "/quotes", false
+	}
+	size := opts.Limit
+	if size <= 0 {
+		size = streamPageSize
+	}
+
+	offset := opts.Offset
+	for {
+		query := url.Values{}
+		if opts.Author != "" {
+			query.Set("author", opts.Author)
+		}
+		if opts.Lang != "" {
+			query.Set("lang", opts.Lang)
+		}
+		query.Set("limit", strconv.Itoa(size))
+		query.Set("offset", strconv.Itoa(offset))
+
+		delivered, total, err := c.streamPage(ctx, path, query, lines, fn)
+		var apiErr *Error
+		if lines && delivered == 0 && errors.As(err, &apiErr) &&
+			(apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusMethodNotAllowed) {
+			path, lines = "/quotes", false
+			continue
+		}
+		if err != nil {
+			var stop *stopError
+			if errors.As(err, &stop) {
+				return stop.err
+			}
+			return err
+		}
+
+		offset += delivered
+		if delivered == 0 || (total >= 0 && offset >= total) {
+			return nil
+		}
+	}
+}
+
+// streamPage passes the quotes of one page to fn, and returns how many it
+// passed and the length of the whole list, or -1 when the server did not
+// say. Attempts after the first skip the quotes fn already had.
+func (c *Client) streamPage(ctx context.Context, path string, query url.Values, lines bool, fn func(Quote) error) (int, int, error) {
+	delivered, total := 0, -1
+	err := c.request(ctx, http.MethodGet, path, query, nil, func(resp *http.Response) error {
+		if n, err := strconv.Atoi(resp.Header.Get("X-Total-Count")); err == nil {
+			total = n
+		}
+		seen := 0
+		each := func(q Quote) error {
+			seen++
+			if seen <= delivered {
+				return nil
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(q); err != nil {
+				return &stopError{err: err}
+			}
+			delivered++
+			return nil
+		}
+
+		if lines {
+			return decodeLines(resp.Body, each)
+		}
+		var quotes []Quote
+		if err := json.NewDecoder(resp.Body).Decode(dataOf(&quotes)); err != nil {
+			return fmt.Errorf("client: decode response: %w", err)
+		}
+		for _, q := range quotes {
+			if err := each(q); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return delivered, total, err
+}
+
+// decodeLines calls each with the quotes of a JSON Lines body in turn.
+func decodeLines(r io.Reader, each func(Quote) error) error {
+	dec := json.NewDecoder(r)
+	for {
+		var q Quote
+		if err := dec.Decode(&q); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("client: decode export: %w", err)
+		}
+		if err := each(q); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"quotes-service/client"
+)
+
+// catalog serves total generated quotes the way the service pages them,
+// from the JSON Lines export and the JSON list, without keeping them.
+type catalog struct {
+	total int
+	// noExport answers the export with 404, as servers without it do.
+	noExport bool
+	// breakOnce cuts the first export page off after ten quotes.
+	breakOnce bool
+
+	exports atomic.Int32
+	lists   atomic.Int32
+	broken  atomic.Bool
+}
+
+const maxPage = 1000
+
+var padding = strings.Repeat("x", 256)
+
+func (c *catalog) quote(i int) client.Quote {
+	return client.Quote{ID: int64(i + 1), Text: "Quote " + strconv.Itoa(i) + " " + padding, Author: "Author"}
+}
+
+func (c *catalog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	limit = min(limit, maxPage)
+	end := min(offset+limit, c.total)
+	w.Header().Set("X-Total-Count", strconv.Itoa(c.total))
+
+	switch r.URL.Path {
+	case "/quotes/export":
+		c.exports.Add(1)
+		if c.noExport {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"status":"error","code":"not_found","error":"Not found."}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		for i := offset; i < end; i++ {
+			if c.breakOnce && i == offset+10 && !c.broken.Swap(true) {
+				io.WriteString(w, `{"id":`)
+				return
+			}
+			enc.Encode(c.quote(i))
+		}
+	case "/quotes":
+		c.lists.Add(1)
+		quotes := []client.Quote{}
+		for i := offset; i < end; i++ {
+			quotes = append(quotes, c.quote(i))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"status": "success", "data": quotes})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newCatalogClient(t *testing.T, cat *catalog) *client.Client {
+	t.Helper()
+	server := httptest.NewServer(cat)
+	t.Cleanup(server.Close)
+	c, err := client.New(server.URL, client.WithRetry(fastRetry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestStreamQuotes(t *testing.T) {
+	tests := []struct {
+		name            string
+		catalog         *catalog
+		opts            client.ListOptions
+		expectedFirst   int64
+		expectedCount   int
+		expectedExports int32
+		expectedLists   int32
+	}{
+		{name: "export", catalog: &catalog{total: 2500}, expectedFirst: 1, expectedCount: 2500, expectedExports: 3},
+		{name: "offset and page size", catalog: &catalog{total: 2500}, opts: client.ListOptions{Limit: 500, Offset: 1000}, expectedFirst: 1001, expectedCount: 1500, expectedExports: 3},
+		{name: "empty", catalog: &catalog{}, expectedCount: 0, expectedExports: 1},
+		{name: "no export", catalog: &catalog{total: 2500, noExport: true}, expectedFirst: 1, expectedCount: 2500, expectedExports: 1, expectedLists: 3},
+		{name: "author", catalog: &catalog{total: 1500}, opts: client.ListOptions{Author: "Author"}, expectedFirst: 1, expectedCount: 1500, expectedLists: 2},
+		{name: "broken page", catalog: &catalog{total: 1500, breakOnce: true}, expectedFirst: 1, expectedCount: 1500, expectedExports: 3},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newCatalogClient(t, tc.catalog)
+
+			count := 0
+			err := c.StreamQuotes(context.Background(), tc.opts, func(q client.Quote) error {
+				if want := tc.expectedFirst + int64(count); q.ID != want {
+					return fmt.Errorf("expected quote %d, got %d", want, q.ID)
+				}
+				count++
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != tc.expectedCount {
+				t.Fatalf("expected %d quotes, got %d", tc.expectedCount, count)
+			}
+			if exports, lists := tc.catalog.exports.Load(), tc.catalog.lists.Load(); exports != tc.expectedExports || lists != tc.expectedLists {
+				t.Fatalf("expected %d export and %d list requests, got %d and %d", tc.expectedExports, tc.expectedLists, exports, lists)
+			}
+		})
+	}
+}
+
+func TestStreamQuotesStops(t *testing.T) {
+	errEnough := errors.New("enough")
+
+	tests := []struct {
+		name        string
+		stop        func(cancel context.CancelFunc) error
+		expectedErr error
+	}{
+		{
+			name:        "callback error",
+			stop:        func(context.CancelFunc) error { return errEnough },
+			expectedErr: errEnough,
+		},
+		{
+			name: "context canceled",
+			stop: func(cancel context.CancelFunc) error {
+				cancel()
+				return nil
+			},
+			expectedErr: context.Canceled,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cat := &catalog{total: 5000}
+			c := newCatalogClient(t, cat)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			count := 0
+			err := c.StreamQuotes(ctx, client.ListOptions{}, func(q client.Quote) error {
+				count++
+				if count == 3 {
+					return tc.stop(cancel)
+				}
+				return nil
+			})
+			if err != tc.expectedErr {
+				t.Fatalf("expected %v, got %v", tc.expectedErr, err)
+			}
+			if count != 3 {
+				t.Fatalf("expected the stream to stop after 3 quotes, got %d", count)
+			}
+			if got := cat.exports.Load(); got != 1 {
+				t.Fatalf("expected 1 request, got %d", got)
+			}
+		})
+	}
+}
+
+func TestStreamQuotesMemory(t *testing.T) {
+	const total = 50_000
+	c := newCatalogClient(t, &catalog{total: total})
+
+	heap := func() int64 {
+		var m runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&m)
+		return int64(m.HeapAlloc)
+	}
+	base, peak := heap(), int64(0)
+
+	count := 0
+	err := c.StreamQuotes(context.Background(), client.ListOptions{}, func(q client.Quote) error {
+		count++
+		if count%5000 == 0 {
+			peak = max(peak, heap())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != total {
+		t.Fatalf("expected %d quotes, got %d", total, count)
+	}
+
+	// Holding the quotes would take more than their texts alone.
+	buffered := int64(total * len(padding))
+	if growth := peak - base; growth > buffered/8 {
+		t.Fatalf("expected the heap to stay flat, it grew by %d bytes of the %d buffering takes", growth, buffered)
+	}
+}
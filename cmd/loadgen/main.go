@@ -0,0 +1,425 @@
+// Command loadgen drives a running quotes-service with a mix of reads and
+// writes and reports latency percentiles per operation.
+//
+//	go run ./cmd/loadgen -url http://localhost:8080 -duration 30s -concurrency 16 \
+//		-mix random=60,author=20,byid=10,list=5,add=5
+//
+// A -url of the form unix:///path/to.sock sends the requests over a Unix
+// domain socket.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"quotes-service/internal/http-server/listener"
+)
+
+// operations lists the request kinds loadgen knows, in report order.
+var operations = []string{"random", "author", "byid", "list", "add"}
+
+type config struct {
+	baseURL     string
+	duration    time.Duration
+	concurrency int
+	mix         map[string]int
+	apiKey      string
+	timeout     time.Duration
+}
+
+func main() {
+	cfg := parseFlags()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	transport := &http.Transport{
+		MaxIdleConns:        cfg.concurrency,
+		MaxIdleConnsPerHost: cfg.concurrency,
+	}
+	if path, ok := listener.UnixPath(cfg.baseURL); ok {
+		var dialer net.Dialer
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", path)
+		}
+		// The host is never resolved; it only fills the request URL.
+		cfg.baseURL = "http://unix"
+	}
+	client := &http.Client{
+		Timeout:   cfg.timeout,
+		Transport: transport,
+	}
+
+	gen := &generator{cfg: cfg, client: client, targets: newTargets()}
+	if err := gen.discover(ctx); err != nil {
+		log.Fatalf("failed to read existing quotes: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.duration)
+	defer cancel()
+
+	fmt.Printf("running %s against %s with %d workers\n", cfg.duration, cfg.baseURL, cfg.concurrency)
+	start := time.Now()
+	results := gen.run(ctx)
+	report(os.Stdout, results, time.Since(start))
+}
+
+func parseFlags() config {
+	var (
+		cfg config
+		mix string
+	)
+	flag.StringVar(&cfg.baseURL, "url", "http://localhost:8080", "base URL of the service, or unix:///path for a Unix socket")
+	flag.DurationVar(&cfg.duration, "duration", 30*time.Second, "how long to generate load")
+	flag.IntVar(&cfg.concurrency, "concurrency", 16, "number of concurrent workers")
+	flag.StringVar(&mix, "mix", "random=60,author=20,byid=10,list=5,add=5", "relative weight of each operation ("+strings.Join(operations, ", ")+")")
+	flag.StringVar(&cfg.apiKey, "api-key", "", "API key sent in the X-API-Key header")
+	flag.DurationVar(&cfg.timeout, "timeout", 10*time.Second, "per-request timeout")
+	flag.Parse()
+
+	if cfg.duration <= 0 {
+		log.Fatal("-duration must be positive")
+	}
+	if cfg.concurrency < 1 {
+		log.Fatal("-concurrency must be at least 1")
+	}
+	cfg.baseURL = strings.TrimRight(cfg.baseURL, "/")
+
+	parsed, err := parseMix(mix)
+	if err != nil {
+		log.Fatalf("invalid -mix: %v", err)
+	}
+	cfg.mix = parsed
+	return cfg
+}
+
+// parseMix reads "op=weight,..." into a map. Operations left out get no
+// traffic.
+func parseMix(s string) (map[string]int, error) {
+	mix := make(map[string]int)
+	total := 0
+	for _, part := range strings.Split(s, ",") {
+		name, weightStr, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not op=weight", part)
+		}
+		if !contains(operations, name) {
+			return nil, fmt.Errorf("unknown operation %q", name)
+		}
+		weight, err := strconv.Atoi(weightStr)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("weight of %s must be a non-negative integer", name)
+		}
+		mix[name] = weight
+		total += weight
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("at least one operation needs a positive weight")
+	}
+	return mix, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// targets holds the quote IDs and authors seen so far, used to build
+// by-ID and by-author requests that hit existing data.
+type targets struct {
+	mu      sync.RWMutex
+	ids     []int64
+	authors []string
+	seen    map[string]struct{}
+}
+
+func newTargets() *targets {
+	return &targets{seen: make(map[string]struct{})}
+}
+
+func (t *targets) add(id int64, author string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ids = append(t.ids, id)
+	if _, ok := t.seen[author]; !ok {
+		t.seen[author] = struct{}{}
+		t.authors = append(t.authors, author)
+	}
+}
+
+func (t *targets) empty() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.ids) == 0
+}
+
+// pick returns a random known ID and author. It must not be called while
+// targets is empty.
+func (t *targets) pick(rnd *rand.Rand) (int64, string) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if len(t.ids) == 0 {
+		return 0, ""
+	}
+	return t.ids[rnd.Intn(len(t.ids))], t.authors[rnd.Intn(len(t.authors))]
+}
+
+type generator struct {
+	cfg     config
+	client  *http.Client
+	targets *targets
+}
+
+// discover loads the IDs and authors already stored in the service,
+// following the list page by page.
+func (g *generator) discover(ctx context.Context) error {
+	for offset := 0; ; {
+		n, err := g.discoverPage(ctx, offset)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		offset += n
+	}
+	fmt.Printf("found %d quotes by %d authors\n", len(g.targets.ids), len(g.targets.authors))
+	return nil
+}
+
+// discoverPage loads the page of quotes at offset and returns its length.
+func (g *generator) discoverPage(ctx context.Context, offset int) (int, error) {
+	req, err := g.newRequest(ctx, http.MethodGet, "/quotes?offset="+strconv.Itoa(offset), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("GET /quotes returned %s", resp.Status)
+	}
+
+	var body struct {
+		Data []struct {
+			ID     int64  `json:"id"`
+			Author string `json:"author"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, err
+	}
+	for _, q := range body.Data {
+		g.targets.add(q.ID, q.Author)
+	}
+	return len(body.Data), nil
+}
+
+func (g *generator) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, g.cfg.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if g.cfg.apiKey != "" {
+		req.Header.Set("X-API-Key", g.cfg.apiKey)
+	}
+	return req, nil
+}
+
+// sample is the outcome of one request.
+type sample struct {
+	op      string
+	latency time.Duration
+	failed  bool
+}
+
+func (g *generator) run(ctx context.Context) map[string][]sample {
+	samples := make(chan sample, g.cfg.concurrency*64)
+	var wg sync.WaitGroup
+	for w := 0; w < g.cfg.concurrency; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			g.work(ctx, rand.New(rand.NewSource(seed)), samples)
+		}(time.Now().UnixNano() + int64(w))
+	}
+	go func() {
+		wg.Wait()
+		close(samples)
+	}()
+
+	results := make(map[string][]sample)
+	for s := range samples {
+		results[s.op] = append(results[s.op], s)
+	}
+	return results
+}
+
+func (g *generator) work(ctx context.Context, rnd *rand.Rand, samples chan<- sample) {
+	total := 0
+	for _, weight := range g.cfg.mix {
+		total += weight
+	}
+
+	for ctx.Err() == nil {
+		op := pickOp(g.cfg.mix, total, rnd)
+		if (op == "byid" || op == "author") && g.targets.empty() {
+			// Nothing to look up yet; seed the service instead.
+			op = "add"
+		}
+		start := time.Now()
+		err := g.do(ctx, op, rnd)
+		if ctx.Err() != nil {
+			// Requests cut off by the end of the run are not counted.
+			return
+		}
+		samples <- sample{op: op, latency: time.Since(start), failed: err != nil}
+	}
+}
+
+func pickOp(mix map[string]int, total int, rnd *rand.Rand) string {
+	n := rnd.Intn(total)
+	for _, op := range operations {
+		n -= mix[op]
+		if n < 0 {
+			return op
+		}
+	}
+	return operations[0]
+}
+
+func (g *generator) do(ctx context.Context, op string, rnd *rand.Rand) error {
+	var (
+		method = http.MethodGet
+		path   string
+		body   []byte
+	)
+	id, author := g.targets.pick(rnd)
+
+	switch op {
+	case "random":
+		path = "/quotes/random"
+	case "list":
+		path = "/quotes"
+	case "byid":
+		path = "/quotes/" + strconv.FormatInt(id, 10)
+	case "author":
+		path = "/quotes?author=" + url.QueryEscape(author)
+	case "add":
+		method = http.MethodPost
+		path = "/quotes"
+		body, _ = json.Marshal(map[string]string{
+			"text":   fmt.Sprintf("Load test quote %d: the only way out is through.", rnd.Int63()),
+			"author": fmt.Sprintf("Load Author %d", rnd.Intn(100)),
+			"lang":   "en",
+		})
+	}
+
+	req, err := g.newRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if op == "add" && resp.StatusCode == http.StatusCreated {
+		var created struct {
+			ID     int64  `json:"id"`
+			Author string `json:"author"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&created); err == nil && created.ID > 0 {
+			g.targets.add(created.ID, created.Author)
+		}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("%s %s returned %s", method, path, resp.Status)
+	}
+	return nil
+}
+
+func report(w io.Writer, results map[string][]sample, elapsed time.Duration) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "op\trequests\terrors\treq/s\tp50\tp90\tp99\tmax\t")
+
+	var all []sample
+	for _, op := range operations {
+		if samples, ok := results[op]; ok {
+			writeRow(tw, op, samples, elapsed)
+			all = append(all, samples...)
+		}
+	}
+	writeRow(tw, "total", all, elapsed)
+	tw.Flush()
+}
+
+func writeRow(w io.Writer, op string, samples []sample, elapsed time.Duration) {
+	latencies := make([]time.Duration, len(samples))
+	errors := 0
+	for i, s := range samples {
+		latencies[i] = s.latency
+		if s.failed {
+			errors++
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	fmt.Fprintf(w, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t\n",
+		op,
+		len(samples),
+		errors,
+		float64(len(samples))/elapsed.Seconds(),
+		percentile(latencies, 50),
+		percentile(latencies, 90),
+		percentile(latencies, 99),
+		percentile(latencies, 100),
+	)
+}
+
+// percentile returns the p-th percentile of sorted latencies using the
+// nearest-rank method.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1].Round(time.Microsecond)
+}
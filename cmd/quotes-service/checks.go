@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"quotes-service/internal/config"
+	"quotes-service/internal/lib/preflight"
+	"quotes-service/internal/lib/s3"
+	"quotes-service/internal/storage/restore"
+	"quotes-service/internal/storage/selfcheck"
+)
+
+// startupChecks lists what has to hold for cfg before the service serves:
+// addresses it listens on are free, directories it writes to are writable,
+// the snapshot to restore exists and the storage answers. The API address
+// is left out when systemd hands over the sockets.
+func startupChecks(cfg *config.Config, storage any, activated bool) []preflight.Check {
+	var checks []preflight.Check
+	listen := func(key, address string) {
+		checks = append(checks, preflight.Check{
+			Class: preflight.ClassNetwork,
+			Name:  "listen " + key,
+			Hint:  fmt.Sprintf("stop the process using %s or change %s", address, key),
+			Run:   preflight.Bindable(address),
+		})
+	}
+	writable := func(class preflight.Class, key, dir string) {
+		if dir == "" {
+			return
+		}
+		checks = append(checks, preflight.Check{
+			Class: class,
+			Name:  "write " + key,
+			Hint:  fmt.Sprintf("make %s writable by the service user or change %s", dir, key),
+			Run:   preflight.Writable(dir),
+		})
+	}
+
+	if !activated {
+		if cfg.ACME.Enabled {
+			listen("acme.https_address", cfg.ACME.HTTPSAddress)
+		} else {
+			listen("http_server.address", cfg.HTTPServer.Address)
+		}
+	}
+	if cfg.AdminServer.Enabled {
+		listen("admin_server.address", cfg.AdminServer.Address)
+	}
+	if cfg.ACME.Enabled {
+		listen("acme.http_address", cfg.ACME.HTTPAddress)
+		writable(preflight.ClassNetwork, "acme.cache_dir", cfg.ACME.CacheDir)
+	}
+
+	if cfg.Backup.Enabled {
+		writable(preflight.ClassStorage, "backup.dir", cfg.Backup.Dir)
+	}
+	if cfg.Exports.Enabled {
+		writable(preflight.ClassStorage, "exports.dir", cfg.Exports.Dir)
+	}
+	if cfg.Imports.Enabled {
+		writable(preflight.ClassStorage, "imports.dir", cfg.Imports.Dir)
+	}
+
+	// An optional restore falls back to an empty store, so a missing
+	// snapshot is not a reason to refuse to start.
+	if cfg.Restore.URL != "" && !cfg.Restore.Optional {
+		opts := restore.Options{
+			URL: cfg.Restore.URL,
+			S3: s3.Options{
+				Endpoint:  cfg.Restore.S3.Endpoint,
+				Region:    cfg.Restore.S3.Region,
+				AccessKey: cfg.Restore.S3.AccessKey,
+				SecretKey: cfg.Restore.S3.SecretKey,
+			},
+		}
+		checks = append(checks, preflight.Check{
+			Class: preflight.ClassStorage,
+			Name:  "restore source",
+			Hint:  "fix restore.url, or set restore.optional to start with an empty store",
+			Run:   func(ctx context.Context) error { return restore.Check(ctx, opts) },
+		})
+	}
+	if pinger, ok := storage.(selfcheck.Pinger); ok {
+		checks = append(checks, preflight.Check{
+			Class: preflight.ClassStorage,
+			Name:  "storage ping",
+			Hint:  "check that the storage backend is up and reachable",
+			Run:   pinger.Ping,
+		})
+	}
+	return checks
+}
@@ -3,16 +3,50 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"quotes-service/internal/config"
+	"quotes-service/internal/jobs/backup"
+	"quotes-service/internal/jobs/claimsweep"
+	"quotes-service/internal/jobs/digest"
+	"quotes-service/internal/jobs/export"
+	"quotes-service/internal/jobs/importer"
+	"quotes-service/internal/jobs/publisher"
+	"quotes-service/internal/jobs/quotesync"
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/listener"
 	approuter "quotes-service/internal/http-server/router"
+	"quotes-service/internal/lib/apikeys"
+	"quotes-service/internal/lib/authormeta"
+	"quotes-service/internal/lib/autotls"
+	"quotes-service/internal/lib/contentfilter"
+	"quotes-service/internal/lib/jwks"
+	"quotes-service/internal/lib/logger/logring"
 	"quotes-service/internal/lib/logger/sl"
+	"quotes-service/internal/lib/panicreport"
+	"quotes-service/internal/lib/preflight"
+	"quotes-service/internal/lib/mailer"
+	"quotes-service/internal/lib/moderation"
+	"quotes-service/internal/lib/publicid"
+	"quotes-service/internal/lib/quoteinput"
+	"quotes-service/internal/lib/s3"
+	"quotes-service/internal/lib/usertext"
+	"quotes-service/internal/lib/webhook"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/countstorage"
+	"quotes-service/internal/storage/faultstorage"
+	"quotes-service/internal/storage/replicastorage"
+	"quotes-service/internal/storage/restore"
+	"quotes-service/internal/storage/selfcheck"
 	"quotes-service/internal/storage/memorystorage"
 )
 
@@ -21,12 +55,29 @@ const (
 	envDev   = "dev"
 	envProd  = "prod"
 	defaulTimeout = 10 * time.Second
+	selfCheckTimeout = 10 * time.Second
+	preflightTimeout = 30 * time.Second
+	restoreTimeout   = 10 * time.Minute
+	storageBackend   = "memory"
+	// uncleanShutdownExitCode is the status when the store did not close
+	// before its deadline, so supervisors know it may not have flushed.
+	uncleanShutdownExitCode = 5
 )
 
 func main() {
+	readyFile := flag.String("ready-file", "", "write the bound listen address and startup details to this file as JSON once serving")
+	checkOnly := flag.Bool("check", false, "check the configuration and environment, report any problems and exit without serving")
+	flag.Parse()
+
 	cfg := config.MustLoad()
 
-	log := setupLogger(cfg.Env)
+	logs := logring.New(cfg.SupportBundle.LogLines)
+	log := setupLogger(cfg.Env, logs)
+	usertext.Set(cfg.UserText)
+	quoteinput.SetMaxTagChars(cfg.API.MaxTagChars)
+	quoteinput.SetTypography(cfg.Normalize.Typography)
+	quoteinput.SetMaxAuthorChars(cfg.API.MaxAuthorChars)
+	quoteinput.SetSanitizeImportedAuthors(cfg.Normalize.SanitizeImportedAuthors)
 
 	log.Info(
 		"starting quote-service",
@@ -35,43 +86,555 @@ func main() {
 	)
 	log.Debug("debug messages are enabled")
 
-	storage, err := memorystorage.New()
+	if cfg.I18n.CatalogPath != "" {
+		if err := apierror.Default.LoadFile(cfg.I18n.CatalogPath); err != nil {
+			log.Error("failed to load message catalog", sl.Err(err))
+			os.Exit(config.ExitCode)
+		}
+	}
+
+	storageOpts := []memorystorage.Option{memorystorage.WithChangeLog(cfg.Changes.MaxEntries, cfg.Changes.MaxAge)}
+	if cfg.Audit.Enabled {
+		storageOpts = append(storageOpts, memorystorage.WithAuditRetention(cfg.Audit.MaxEntries, cfg.Audit.MaxAge))
+	}
+	if cfg.IDs.PublicID != publicid.FormatNone {
+		storageOpts = append(storageOpts, memorystorage.WithPublicIDs(cfg.IDs.PublicID.New))
+	}
+	storage, err := memorystorage.New(storageOpts...)
 	if err != nil {
 		log.Error("failed to init storage", sl.Err(err))
-		os.Exit(1)
+		os.Exit(preflight.ClassStorage.ExitCode())
 	}
+	// Deferred first, so it runs last; a store stuck closing past the
+	// deadline exits with its own status rather than hanging.
+	var uncleanStop bool
 	defer func() {
-		log.Info("closing storage")
-		if err := storage.Close(); err != nil {
-			log.Error("failed to close storage", sl.Err(err))
+		if !closeStorage(log, "storage", storage, cfg.Storage.CloseTimeout) || uncleanStop {
+			os.Exit(uncleanShutdownExitCode)
 		}
 	}()
 
-	mainRouter := approuter.New(log, storage)
+	// Under systemd socket activation the API is served on every inherited
+	// socket instead of its configured address.
+	activated, err := listener.Activated()
+	if err != nil {
+		log.Error("failed to use systemd sockets", sl.Err(err))
+		os.Exit(preflight.ClassNetwork.ExitCode())
+	}
+
+	// Everything that would stop the service later is checked up front and
+	// reported together, so one failed start shows every problem.
+	checks := startupChecks(cfg, storage, len(activated) > 0)
+	checkCtx, cancelChecks := context.WithTimeout(context.Background(), preflightTimeout)
+	report := preflight.Run(checkCtx, checks)
+	cancelChecks()
+	if !report.OK() {
+		log.Error("startup checks failed", slog.Any("report", report))
+		os.Exit(report.ExitCode())
+	}
+	if *checkOnly {
+		log.Info("startup checks passed", slog.Any("report", report))
+		return
+	}
+
+	if cfg.Restore.URL != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), restoreTimeout)
+		_, err := restore.Run(ctx, log, storage, restore.Options{
+			URL: cfg.Restore.URL,
+			S3: s3.Options{
+				Endpoint:  cfg.Restore.S3.Endpoint,
+				Region:    cfg.Restore.S3.Region,
+				AccessKey: cfg.Restore.S3.AccessKey,
+				SecretKey: cfg.Restore.S3.SecretKey,
+			},
+		})
+		cancel()
+		if err != nil {
+			if !cfg.Restore.Optional {
+				log.Error("failed to restore snapshot", sl.Err(err))
+				os.Exit(preflight.ClassStorage.ExitCode())
+			}
+			log.Warn("failed to restore snapshot, starting with an empty store", sl.Err(err))
+		}
+	}
+
+	var selfCheck *selfcheck.Result
+	if cfg.SelfCheck.Mode != selfcheck.ModeOff {
+		ctx, cancel := context.WithTimeout(context.Background(), selfCheckTimeout)
+		result := selfcheck.Run(ctx, storage, cfg.SelfCheck.Mode)
+		cancel()
+		if result.Err != nil {
+			log.Error("storage self-check failed", slog.String("mode", string(result.Mode)), slog.Duration("duration", result.Duration), sl.Err(result.Err))
+			os.Exit(preflight.ClassStorage.ExitCode())
+		}
+		log.Info("storage self-check passed", slog.String("mode", string(result.Mode)), slog.Duration("duration", result.Duration))
+		selfCheck = &result
+	}
+
+	var st approuter.Storage = storage
+	var replica *replicastorage.Storage
+	if cfg.Replication.Enabled {
+		secondary, err := memorystorage.New()
+		if err != nil {
+			log.Error("failed to init secondary storage", sl.Err(err))
+			os.Exit(preflight.ClassStorage.ExitCode())
+		}
+		defer func() {
+			if !closeStorage(log, "secondary storage", secondary, cfg.Storage.CloseTimeout) {
+				uncleanStop = true
+			}
+		}()
+		replica = replicastorage.New(log, storage, secondary, replicastorage.Options{
+			QueueSize:         cfg.Replication.QueueSize,
+			BatchSize:         cfg.Replication.BatchSize,
+			ReadFromSecondary: cfg.Replication.ReadFromSecondary,
+		})
+		st = replica
+	}
+	// Inside the fault injector, so that only the calls that reach the
+	// store are counted.
+	st = countstorage.New(st)
+	if cfg.Faults.Enabled {
+		log.Warn("storage fault injection is enabled", slog.Any("admins", cfg.Auth.Admins()))
+		st = faultstorage.New(st)
+	}
+
+	readiness := approuter.Readiness{SelfCheck: selfCheck}
+	var certs *autotls.Manager
+	if cfg.ACME.Enabled {
+		certs = autotls.New(log, autotls.Options{
+			Domains:      cfg.ACME.Domains,
+			CacheDir:     cfg.ACME.CacheDir,
+			DirectoryURL: cfg.ACME.DirectoryURL,
+			Email:        cfg.ACME.Email,
+		})
+		readiness.Certs = certs
+	}
+
+	// Background jobs stop when jobsCtx is canceled during shutdown.
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	var jobsWG sync.WaitGroup
+
+	var jobs approuter.Jobs
+	jobs.Logs = logs
+	if replica != nil {
+		jobs.Replication = replica
+		jobsWG.Add(1)
+		go func() {
+			defer jobsWG.Done()
+			replica.Run(jobsCtx)
+		}()
+		log.Info("replication is enabled", slog.String("secondary", cfg.Replication.Secondary), slog.Bool("read_from_secondary", cfg.Replication.ReadFromSecondary))
+	}
+	if cfg.Sync.Enabled {
+		syncer := quotesync.New(log, st, quotesync.Options{
+			SourceURL: cfg.Sync.SourceURL,
+			Interval:  cfg.Sync.Interval,
+			PageSize:  cfg.Sync.PageSize,
+			MaxPages:  cfg.Sync.MaxPages,
+			Authors:   cfg.Sync.Authors,
+			Lang:      cfg.Sync.Lang,
+		})
+		jobs.Sync = syncer
+		jobsWG.Add(1)
+		go func() {
+			defer jobsWG.Done()
+			syncer.Run(jobsCtx)
+		}()
+		log.Info("quote sync is enabled", slog.String("source_url", cfg.Sync.SourceURL), slog.Duration("interval", cfg.Sync.Interval))
+	}
+
+	if cfg.Schedule.Enabled {
+		endpoints := make([]webhook.Endpoint, 0, len(cfg.Webhooks.Endpoints))
+		for _, e := range cfg.Webhooks.Endpoints {
+			endpoints = append(endpoints, webhook.Endpoint{URL: e.URL, Secret: e.Secret})
+		}
+		pub := publisher.New(log, cfg.Schedule.Parsed, st, webhook.New(log, endpoints), publisher.Options{
+			Mode:       cfg.Schedule.Mode,
+			Collection: cfg.Schedule.Collection,
+			Backfill:   cfg.Schedule.Backfill,
+			StateFile:  cfg.Schedule.StateFile,
+			Window:     cfg.Schedule.Window,
+		})
+		jobs.Schedule = pub
+		if cfg.Schedule.Mode == publisher.ModeDaily {
+			jobs.Daily = pub
+		}
+		jobsWG.Add(1)
+		go func() {
+			defer jobsWG.Done()
+			pub.Run(jobsCtx)
+		}()
+		log.Info("scheduled publishing is enabled", slog.String("cron", cfg.Schedule.Cron), slog.String("timezone", cfg.Schedule.Timezone), slog.String("mode", cfg.Schedule.Mode))
+	}
+
+	if cfg.Digest.Enabled {
+		sender := mailer.New(mailer.Options{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+		})
+		dig := digest.New(log, cfg.Digest.Parsed, st, sender, digest.Options{
+			From:      cfg.SMTP.From,
+			To:        cfg.Digest.Recipients,
+			Subject:   cfg.Digest.Subject,
+			StateFile: cfg.Digest.StateFile,
+		})
+		jobs.Digest = dig
+		jobsWG.Add(1)
+		go func() {
+			defer jobsWG.Done()
+			dig.Run(jobsCtx)
+		}()
+		log.Info("email digest is enabled", slog.String("cron", cfg.Digest.Cron), slog.String("timezone", cfg.Digest.Timezone), slog.Int("recipients", len(cfg.Digest.Recipients)))
+	}
+
+	if cfg.Backup.Enabled {
+		var objects backup.ObjectStore
+		if cfg.Backup.S3.Bucket != "" {
+			client, err := s3.New(s3.Options{
+				Endpoint:  cfg.Backup.S3.Endpoint,
+				Region:    cfg.Backup.S3.Region,
+				AccessKey: cfg.Backup.S3.AccessKey,
+				SecretKey: cfg.Backup.S3.SecretKey,
+			})
+			if err != nil {
+				log.Error("failed to init backup bucket", sl.Err(err))
+				os.Exit(config.ExitCode)
+			}
+			objects = client
+		}
+		backups := backup.New(log, st, objects, backup.Options{
+			Interval: cfg.Backup.Interval,
+			Dir:      cfg.Backup.Dir,
+			Bucket:   cfg.Backup.S3.Bucket,
+			Prefix:   cfg.Backup.S3.Prefix,
+			KeepLast: cfg.Backup.KeepLast,
+			MaxAge:   cfg.Backup.MaxAge,
+		})
+		jobs.Backup = backups
+		jobsWG.Add(1)
+		go func() {
+			defer jobsWG.Done()
+			backups.Run(jobsCtx)
+		}()
+		log.Info("backups are enabled", slog.Duration("interval", cfg.Backup.Interval), slog.String("dir", cfg.Backup.Dir), slog.String("bucket", cfg.Backup.S3.Bucket))
+	}
+
+	if cfg.Exports.Enabled {
+		var objects export.ObjectStore
+		if cfg.Exports.S3.Bucket != "" {
+			client, err := s3.New(s3.Options{
+				Endpoint:  cfg.Exports.S3.Endpoint,
+				Region:    cfg.Exports.S3.Region,
+				AccessKey: cfg.Exports.S3.AccessKey,
+				SecretKey: cfg.Exports.S3.SecretKey,
+			})
+			if err != nil {
+				log.Error("failed to init export bucket", sl.Err(err))
+				os.Exit(config.ExitCode)
+			}
+			objects = client
+		}
+		exports := export.New(log, st, objects, export.Options{
+			Dir:       cfg.Exports.Dir,
+			Bucket:    cfg.Exports.S3.Bucket,
+			Prefix:    cfg.Exports.S3.Prefix,
+			TTL:       cfg.Exports.TTL,
+			Workers:   cfg.Exports.Workers,
+			QueueSize: cfg.Exports.QueueSize,
+			StateFile: cfg.Exports.StateFile,
+		})
+		jobs.Exports = exports
+		jobsWG.Add(1)
+		go func() {
+			defer jobsWG.Done()
+			exports.Run(jobsCtx)
+		}()
+		log.Info("background exports are enabled", slog.Int("workers", cfg.Exports.Workers), slog.Duration("ttl", cfg.Exports.TTL), slog.String("bucket", cfg.Exports.S3.Bucket))
+	}
+
+	if cfg.Imports.Enabled {
+		imports := importer.New(log, st, importer.Options{
+			Dir:          cfg.Imports.Dir,
+			TTL:          cfg.Imports.TTL,
+			Workers:      cfg.Imports.Workers,
+			QueueSize:    cfg.Imports.QueueSize,
+			BatchSize:    cfg.Imports.BatchSize,
+			MaxBytes:     cfg.Imports.MaxBytes,
+			FetchTimeout: cfg.Imports.FetchTimeout,
+			Fetch: importer.FetchOptions{
+				Schemes:            cfg.Imports.Fetch.AllowedSchemes,
+				Hosts:              cfg.Imports.Fetch.AllowedHosts,
+				MaxRedirects:       cfg.Imports.Fetch.MaxRedirects,
+				ContentTypes:       cfg.Imports.Fetch.ContentTypes,
+				InsecureSkipVerify: cfg.Imports.Fetch.InsecureSkipVerify,
+			},
+		})
+		jobs.Imports = imports
+		jobsWG.Add(1)
+		go func() {
+			defer jobsWG.Done()
+			imports.Run(jobsCtx)
+		}()
+		log.Info("background imports are enabled", slog.Int("workers", cfg.Imports.Workers), slog.Int("batch_size", cfg.Imports.BatchSize), slog.Duration("ttl", cfg.Imports.TTL))
+		if cfg.Imports.Fetch.InsecureSkipVerify {
+			log.Warn("TLS certificates of import URLs are not verified", slog.String("env", cfg.Env))
+		}
+	}
+
+	if claims, ok := st.(claimsweep.Store); ok && cfg.Claims.Enabled {
+		jobsWG.Add(1)
+		go func() {
+			defer jobsWG.Done()
+			claimsweep.Run(jobsCtx, log, claims, cfg.Claims.SweepInterval)
+		}()
+		log.Info("quote claims are enabled", slog.Duration("default_lease", cfg.Claims.DefaultLease), slog.Duration("max_lease", cfg.Claims.MaxLease))
+	}
+
+	if cfg.JWT.Enabled {
+		keys := jwks.New(log, jwks.Options{
+			URL:        cfg.JWT.JWKSURL,
+			Refresh:    cfg.JWT.Refresh,
+			MinRefetch: cfg.JWT.MinRefetch,
+		})
+		jobs.JWKS = keys
+		jobsWG.Add(1)
+		go func() {
+			defer jobsWG.Done()
+			keys.Run(jobsCtx)
+		}()
+		log.Info("jwt authentication is enabled", slog.String("issuer", cfg.JWT.Issuer), slog.String("jwks_url", cfg.JWT.JWKSURL), slog.Duration("refresh", cfg.JWT.Refresh))
+	}
+
+	if cfg.Auth.KeysFile != "" {
+		keyStore, err := apikeys.Open(log, cfg.Auth.KeysFile)
+		if err != nil {
+			log.Error("failed to open api key store", sl.Err(err))
+			os.Exit(config.ExitCode)
+		}
+		jobs.APIKeys = keyStore
+		jobsWG.Add(1)
+		go func() {
+			defer jobsWG.Done()
+			keyStore.Run(jobsCtx)
+		}()
+		log.Info("runtime api keys are enabled", slog.String("file", cfg.Auth.KeysFile), slog.Int("keys", len(keyStore.List())))
+	}
+
+	if cfg.Audit.Enabled {
+		// The trail lives in memory beside the quotes, and always in the
+		// primary store, whatever wraps it.
+		jobs.Audit = storage
+		log.Info("audit trail is enabled", slog.Int("max_entries", cfg.Audit.MaxEntries), slog.Duration("max_age", cfg.Audit.MaxAge))
+	}
+
+	if cfg.ContentFilter.Enabled {
+		filter, err := contentfilter.New(cfg.ContentFilter.File, cfg.ContentFilter.Action)
+		if err != nil {
+			log.Error("failed to load content filter", sl.Err(err))
+			os.Exit(config.ExitCode)
+		}
+		var held *moderation.Queue
+		if filter.Action() == contentfilter.ActionHold {
+			held = moderation.New(cfg.ContentFilter.MaxHeld)
+			jobs.Moderation = held
+		}
+		quoteinput.SetContentFilter(filter, held)
+		jobs.ContentFilter = filter
+
+		// SIGHUP reloads the denylist; a broken file keeps the old one.
+		reload := make(chan os.Signal, 1)
+		signal.Notify(reload, syscall.SIGHUP)
+		jobsWG.Add(1)
+		go func() {
+			defer jobsWG.Done()
+			defer signal.Stop(reload)
+			for {
+				select {
+				case <-jobsCtx.Done():
+					return
+				case <-reload:
+					if err := filter.Reload(); err != nil {
+						log.Error("failed to reload content filter, keeping the previous denylist", sl.Err(err))
+						continue
+					}
+					log.Info("content filter reloaded", slog.Int("terms", filter.Terms()))
+				}
+			}
+		}()
+		log.Info("content filter is enabled", slog.String("action", filter.Action()), slog.Int("terms", filter.Terms()))
+	}
+
+	if cfg.Panics.Sink != "" {
+		reporter, err := panicreport.New(log, panicreport.Options{
+			Sink:      cfg.Panics.Sink,
+			URL:       cfg.Panics.URL,
+			Secret:    cfg.Panics.Secret,
+			QueueSize: cfg.Panics.QueueSize,
+		})
+		if err != nil {
+			log.Error("failed to init panic reporter", sl.Err(err))
+			os.Exit(config.ExitCode)
+		}
+		jobs.Panics = reporter
+		jobsWG.Add(1)
+		go func() {
+			defer jobsWG.Done()
+			reporter.Run(jobsCtx)
+		}()
+		log.Info("panic reports are enabled", slog.String("sink", cfg.Panics.Sink))
+	}
 
-	log.Info("starting server", slog.String("address", cfg.HTTPServer.Address))
+	switch cfg.AuthorMetadata.Source {
+	case config.AuthorMetadataFile:
+		meta, err := authormeta.LoadFile(cfg.AuthorMetadata.File)
+		if err != nil {
+			log.Error("failed to load author metadata", slog.String("file", cfg.AuthorMetadata.File), sl.Err(err))
+			os.Exit(config.ExitCode)
+		}
+		jobs.Authors = meta
+		log.Info("author metadata is enabled", slog.String("file", cfg.AuthorMetadata.File), slog.Int("authors", meta.Len()))
+	case config.AuthorMetadataRemote:
+		jobs.Authors = authormeta.NewRemote(log, authormeta.RemoteOptions{
+			URL:       cfg.AuthorMetadata.URL,
+			Timeout:   cfg.AuthorMetadata.Timeout,
+			CacheTTL:  cfg.AuthorMetadata.CacheTTL,
+			CacheSize: cfg.AuthorMetadata.CacheSize,
+		})
+		log.Info("author metadata is enabled", slog.String("url", cfg.AuthorMetadata.URL))
+	}
+
+	handlers := approuter.New(log, cfg, st, readiness, jobs)
 
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
-	srv := &http.Server{
+	servers := []*http.Server{{
 		Addr:         cfg.HTTPServer.Address,
-		Handler:      mainRouter,
+		Handler:      handlers.API,
 		ReadTimeout:  cfg.HTTPServer.Timeout,
 		WriteTimeout: cfg.HTTPServer.Timeout,
+	}}
+	var adminServer *http.Server
+	if handlers.Admin != nil {
+		// No write timeout, so that CPU profiles longer than the API
+		// timeout can finish.
+		adminServer = &http.Server{
+			Addr:        cfg.AdminServer.Address,
+			Handler:     handlers.Admin,
+			ReadTimeout: cfg.HTTPServer.Timeout,
+		}
+		servers = append(servers, adminServer)
+	}
+	if certs != nil {
+		log.Info("automatic tls is enabled", slog.Any("domains", cfg.ACME.Domains))
+		servers[0].Addr = cfg.ACME.HTTPSAddress
+		servers[0].TLSConfig = certs.TLSConfig()
+		servers = append(servers, &http.Server{
+			Addr:         cfg.ACME.HTTPAddress,
+			Handler:      certs.HTTPHandler(),
+			ReadTimeout:  cfg.HTTPServer.Timeout,
+			WriteTimeout: cfg.HTTPServer.Timeout,
+		})
+	}
+
+	type binding struct {
+		srv       *http.Server
+		ln        net.Listener
+		activated bool
+	}
+	var bindings []binding
+
+	for _, ln := range activated {
+		bindings = append(bindings, binding{srv: servers[0], ln: ln, activated: true})
 	}
 
-	go func() {
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Error("failed to start server", sl.Err(err))
+	// A ready file left by an earlier run must not announce this one
+	// before it listens.
+	if *readyFile != "" {
+		if err := os.Remove(*readyFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Error("failed to remove stale ready file", sl.Err(err))
 			os.Exit(1)
 		}
-	}()
+	}
+
+	// Listen on every address before serving any of them, so a bad address
+	// fails startup instead of leaving one listener running alone.
+	for i, srv := range servers {
+		if i == 0 && len(activated) > 0 {
+			continue
+		}
+		ln, err := listener.New(srv.Addr, cfg.HTTPServer.SocketMode)
+		if err != nil {
+			log.Error("failed to listen", slog.String("address", srv.Addr), sl.Err(err))
+			for _, b := range bindings {
+				b.ln.Close()
+			}
+			os.Exit(preflight.ClassNetwork.ExitCode())
+		}
+		bindings = append(bindings, binding{srv: srv, ln: ln})
+	}
+
+	serveErr := make(chan error, len(bindings)+1)
+	for _, b := range bindings {
+		log.Info("starting server", slog.String("address", b.ln.Addr().String()), slog.Bool("socket_activated", b.activated))
+		go func(b binding) {
+			// Shutdown closes the listener, which also removes a Unix
+			// socket file.
+			serve := b.srv.Serve
+			if b.srv.TLSConfig != nil {
+				serve = func(ln net.Listener) error { return b.srv.ServeTLS(ln, "", "") }
+			}
+			if err := serve(b.ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serveErr <- fmt.Errorf("%s: %w", b.ln.Addr(), err)
+			}
+		}(b)
+	}
+
+	// One event with the addresses as bound, for tooling that starts the
+	// service on ":0" and needs the port the system picked.
+	ready := listener.Ready{TLS: certs != nil, Storage: storageBackend, Version: cfg.Version}
+	for _, b := range bindings {
+		switch {
+		case b.srv == servers[0] && ready.Address == "":
+			ready.Address = listener.Address(b.ln)
+		case b.srv == adminServer:
+			ready.AdminAddress = listener.Address(b.ln)
+		}
+	}
+	log.Info(
+		"server_listening",
+		slog.String("address", ready.Address),
+		slog.String("admin_address", ready.AdminAddress),
+		slog.Bool("tls", ready.TLS),
+		slog.String("storage", ready.Storage),
+		slog.String("version", ready.Version),
+	)
+	if *readyFile != "" {
+		if err := listener.WriteReadyFile(*readyFile, ready); err != nil {
+			serveErr <- err
+		}
+	}
 
-	log.Info("server started and listening for quote requests")
+	warmCtx, stopWarm := context.WithCancel(context.Background())
+	defer stopWarm()
+	if certs != nil {
+		go certs.Warm(warmCtx)
+	}
 
-	<-done
-	log.Info("stopping server")
+	// A listener that fails takes the others down with it, so the service
+	// never runs half-exposed.
+	failed := false
+	select {
+	case <-done:
+		log.Info("stopping server")
+	case err := <-serveErr:
+		log.Error("failed to start server", sl.Err(err))
+		failed = true
+	}
 
 	shutdownTimeout := defaulTimeout
 	if cfg.HTTPServer.Timeout > 0 {
@@ -81,14 +644,56 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Error("failed to stop server gracefully", sl.Err(err))
+	var wg sync.WaitGroup
+	for _, srv := range servers {
+		wg.Add(1)
+		go func(srv *http.Server) {
+			defer wg.Done()
+			if err := srv.Shutdown(ctx); err != nil {
+				log.Error("failed to stop server gracefully", slog.String("address", srv.Addr), sl.Err(err))
+			}
+		}(srv)
+	}
+	wg.Wait()
+
+	stopJobs()
+	jobsWG.Wait()
+
+	if *readyFile != "" {
+		os.Remove(*readyFile)
 	}
 
 	log.Info("server stopped")
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// closeStorage closes store within timeout and reports whether it finished.
+// A store still closing at the deadline is abandoned, so the process can
+// exit instead of waiting to be killed.
+func closeStorage(log *slog.Logger, name string, store interface{ Close() error }, timeout time.Duration) bool {
+	log.Info("closing "+name, slog.Duration("timeout", timeout))
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := storage.Shutdown(ctx, store)
+	switch {
+	case errors.Is(err, storage.ErrShutdownTimeout):
+		log.Error(name+" did not close before the deadline, exiting uncleanly",
+			slog.Duration("timeout", timeout),
+			slog.Int("exit_code", uncleanShutdownExitCode),
+		)
+		return false
+	case err != nil:
+		log.Error("failed to close "+name, sl.Err(err))
+	}
+	return true
 }
 
-func setupLogger(env string) *slog.Logger {
+// setupLogger returns the logger for env. Its records at info and above
+// also go to logs, for the support bundle.
+func setupLogger(env string, logs *logring.Ring) *slog.Logger {
 	var handler slog.Handler
 
 	switch env {
@@ -105,5 +710,5 @@ func setupLogger(env string) *slog.Logger {
 		tempLogger.Warn("Invalid 'env' in config, defaulting to 'prod' logger settings (Info level).", slog.String("configured_env", env))
 		handler = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: defaultLevel})
 	}
-	return slog.New(handler)
+	return slog.New(logs.Tee(handler, slog.LevelInfo))
 }
\ No newline at end of file
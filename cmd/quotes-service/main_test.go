@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"quotes-service/internal/http-server/listener"
+)
+
+// runMainEnv makes the test binary run the service instead of the tests,
+// so a test can boot it in a child process.
+const runMainEnv = "QUOTES_SERVICE_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestReadyFile(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	readyPath := filepath.Join(dir, "ready.json")
+	config := `{"version": "1.2.3", "env": "prod", "http_server": {"address": "127.0.0.1:0", "timeout": "4s"}}`
+	if err := os.WriteFile(configPath, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(os.Args[0], "-ready-file", readyPath)
+	cmd.Env = append(os.Environ(), runMainEnv+"=1", "CONFIG_PATH="+configPath)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stdout
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start the service: %v", err)
+	}
+	defer cmd.Process.Kill()
+
+	var data []byte
+	for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+		var err error
+		if data, err = os.ReadFile(readyPath); err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) || time.Now().After(deadline) {
+			t.Fatalf("ready file did not appear: %v\n%s", err, stdout.String())
+		}
+	}
+
+	var ready listener.Ready
+	if err := json.Unmarshal(data, &ready); err != nil {
+		t.Fatalf("ready file is not JSON: %v, %s", err, data)
+	}
+	if ready.Version != "1.2.3" || ready.Storage != storageBackend || ready.TLS || strings.HasSuffix(ready.Address, ":0") {
+		t.Fatalf("unexpected ready file %+v", ready)
+	}
+
+	resp, err := http.Get("http://" + ready.Address + "/healthz")
+	if err != nil {
+		t.Fatalf("failed to reach the service at %s: %v", ready.Address, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 from /healthz, got %d", resp.StatusCode)
+	}
+
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("service did not stop cleanly: %v\n%s", err, stdout.String())
+	}
+	if _, err := os.Stat(readyPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the ready file to be removed on shutdown, got %v", err)
+	}
+	if !strings.Contains(stdout.String(), `"msg":"server_listening"`) || !strings.Contains(stdout.String(), `"address":"`+ready.Address+`"`) {
+		t.Fatalf("expected a server_listening event with the bound address, got\n%s", stdout.String())
+	}
+}
+
+func TestCheck(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	tests := []struct {
+		name          string
+		config        string
+		expectedCode  int
+		expectedCheck string
+	}{
+		{
+			name:   "ok",
+			config: `{"env": "prod", "http_server": {"address": "127.0.0.1:0"}}`,
+		},
+		{
+			name:          "port in use",
+			config:        `{"env": "prod", "http_server": {"address": "` + busy.Addr().String() + `"}}`,
+			expectedCode:  4,
+			expectedCheck: "listen http_server.address",
+		},
+		{
+			name:          "missing snapshot",
+			config:        `{"env": "prod", "http_server": {"address": "127.0.0.1:0"}, "restore": {"url": "file:///nonexistent/quotes.jsonl"}}`,
+			expectedCode:  3,
+			expectedCheck: "restore source",
+		},
+		{
+			name:          "storage before network",
+			config:        `{"env": "prod", "http_server": {"address": "` + busy.Addr().String() + `"}, "restore": {"url": "file:///nonexistent/quotes.jsonl"}}`,
+			expectedCode:  3,
+			expectedCheck: "listen http_server.address",
+		},
+		{
+			name:         "bad config",
+			config:       `{"env": "prod", "http_server": {"address": "127.0.0.1:0", "timeout": "soon"}}`,
+			expectedCode: 2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(configPath, []byte(tc.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			cmd := exec.Command(os.Args[0], "-check")
+			cmd.Env = append(os.Environ(), runMainEnv+"=1", "CONFIG_PATH="+configPath)
+			output, err := cmd.CombinedOutput()
+
+			code := 0
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				code = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatalf("failed to run the service: %v", err)
+			}
+			if code != tc.expectedCode {
+				t.Fatalf("expected exit code %d, got %d\n%s", tc.expectedCode, code, output)
+			}
+			if tc.expectedCheck != "" && !strings.Contains(string(output), `"check":"`+tc.expectedCheck+`"`) {
+				t.Fatalf("expected a failure of %q in the report, got\n%s", tc.expectedCheck, output)
+			}
+			if strings.Contains(string(output), "server_listening") {
+				t.Fatalf("expected -check not to serve, got\n%s", output)
+			}
+		})
+	}
+}
+
+// stuckStore never finishes closing, like a backend waiting on a dead
+// connection, whether or not it is given a deadline.
+type stuckStore struct {
+	release chan struct{}
+}
+
+func (s stuckStore) Close() error {
+	<-s.release
+	return nil
+}
+
+type stuckShutdownStore struct {
+	stuckStore
+}
+
+func (s stuckShutdownStore) Shutdown(ctx context.Context) error {
+	return s.Close()
+}
+
+type quickStore struct{}
+
+func (quickStore) Close() error { return errors.New("already closed") }
+
+func TestCloseStorageDeadline(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	release := make(chan struct{})
+	defer close(release)
+
+	tests := []struct {
+		name     string
+		store    interface{ Close() error }
+		expected bool
+	}{
+		{name: "close blocks", store: stuckStore{release}, expected: false},
+		{name: "shutdown ignores its deadline", store: stuckShutdownStore{stuckStore{release}}, expected: false},
+		// A store that fails to close has still stopped.
+		{name: "close fails", store: quickStore{}, expected: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			start := time.Now()
+			if got := closeStorage(log, "storage", tc.store, 50*time.Millisecond); got != tc.expected {
+				t.Fatalf("expected %v, got %v", tc.expected, got)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Fatalf("expected to give up at the deadline, took %v", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,7 @@
+// Package e2e tests the service from the outside: it builds the real
+// binary, starts it on an ephemeral port and drives the API through the
+// client package, so that route wiring and shutdown are covered as
+// deployed. The tests are behind the e2e build tag:
+//
+//	go test -tags e2e ./e2e
+package e2e
@@ -0,0 +1,426 @@
+//go:build e2e
+
+package e2e_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"quotes-service/client"
+	"quotes-service/internal/http-server/listener"
+)
+
+// binary is the service built once for all tests.
+var binary string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "quotes-e2e")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	binary = filepath.Join(dir, "quotes-service")
+	build := exec.Command("go", "build", "-o", binary, "quotes-service/cmd/quotes-service")
+	if out, err := build.CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to build the service: %v\n%s", err, out)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// logBuffer collects the service's output while it runs.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// service is a running instance of the binary.
+type service struct {
+	cmd    *exec.Cmd
+	logs   *logBuffer
+	url    string
+	client *client.Client
+	// exited is closed once the process has been waited for; err is its
+	// exit error.
+	exited chan struct{}
+	err    error
+}
+
+// start runs the binary with config and waits until it listens. The
+// service is killed when the test ends, and its logs are printed if the
+// test failed.
+func start(t *testing.T, config string) *service {
+	t.Helper()
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	readyPath := filepath.Join(dir, "ready.json")
+	if err := os.WriteFile(configPath, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &service{logs: &logBuffer{}, exited: make(chan struct{})}
+	s.cmd = exec.Command(binary, "-ready-file", readyPath)
+	s.cmd.Env = append(os.Environ(), "CONFIG_PATH="+configPath)
+	s.cmd.Stdout = s.logs
+	s.cmd.Stderr = s.logs
+	if err := s.cmd.Start(); err != nil {
+		t.Fatalf("failed to start the service: %v", err)
+	}
+	go func() {
+		s.err = s.cmd.Wait()
+		close(s.exited)
+	}()
+	t.Cleanup(func() {
+		s.cmd.Process.Kill()
+		<-s.exited
+		if t.Failed() {
+			t.Logf("service logs:\n%s", s.logs.String())
+		}
+	})
+
+	var data []byte
+	for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+		var err error
+		if data, err = os.ReadFile(readyPath); err == nil {
+			break
+		}
+		select {
+		case <-s.exited:
+			t.Fatalf("service exited before listening: %v", s.err)
+		default:
+		}
+		if !errors.Is(err, os.ErrNotExist) || time.Now().After(deadline) {
+			t.Fatalf("ready file did not appear: %v", err)
+		}
+	}
+	var ready listener.Ready
+	if err := json.Unmarshal(data, &ready); err != nil {
+		t.Fatalf("ready file is not JSON: %v, %s", err, data)
+	}
+
+	s.url = "http://" + ready.Address
+	c, err := client.New(s.url, client.WithRetry(client.RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.client = c
+	return s
+}
+
+// stop sends SIGTERM and waits for the service to exit.
+func (s *service) stop(t *testing.T) error {
+	t.Helper()
+	if err := s.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-s.exited:
+		return s.err
+	case <-time.After(10 * time.Second):
+		t.Fatal("service did not stop within 10s of SIGTERM")
+		return nil
+	}
+}
+
+const config = `{"env": "prod", "http_server": {"address": "127.0.0.1:0", "timeout": "4s"}}`
+
+func TestAPI(t *testing.T) {
+	s := start(t, config)
+	ctx := context.Background()
+
+	seed := []client.AddQuoteRequest{
+		{Text: "Manuscripts don't burn.", Author: "Mikhail Bulgakov", Lang: "en"},
+		{Text: "Рукописи не горят.", Author: "Mikhail Bulgakov", Lang: "ru"},
+		{Text: "Brevity is the soul of wit.", Author: "William Shakespeare", Lang: "en"},
+		{Text: "All the world's a stage.", Author: "William Shakespeare", Lang: "en"},
+	}
+	var ids []int64
+	for _, req := range seed {
+		resp, err := s.client.AddQuote(ctx, req)
+		if err != nil {
+			t.Fatalf("failed to add %q: %v", req.Text, err)
+		}
+		if resp.ID == 0 || resp.Text != req.Text || resp.Author != req.Author {
+			t.Fatalf("unexpected add response %+v for %+v", resp, req)
+		}
+		ids = append(ids, resp.ID)
+	}
+
+	texts := func(quotes []client.Quote) []string {
+		result := []string{}
+		for _, q := range quotes {
+			result = append(result, q.Text)
+		}
+		return result
+	}
+
+	listTests := []struct {
+		name     string
+		opts     client.ListOptions
+		expected []string
+	}{
+		{name: "all", expected: []string{seed[0].Text, seed[1].Text, seed[2].Text, seed[3].Text}},
+		{name: "author", opts: client.ListOptions{Author: "william shakespeare"}, expected: []string{seed[2].Text, seed[3].Text}},
+		{name: "unknown author", opts: client.ListOptions{Author: "Nobody"}, expected: []string{}},
+		{name: "lang", opts: client.ListOptions{Lang: "ru"}, expected: []string{seed[1].Text}},
+		{name: "first page", opts: client.ListOptions{Limit: 3}, expected: []string{seed[0].Text, seed[1].Text, seed[2].Text}},
+		{name: "second page", opts: client.ListOptions{Limit: 3, Offset: 3}, expected: []string{seed[3].Text}},
+		{name: "past the end", opts: client.ListOptions{Offset: 10}, expected: []string{}},
+	}
+	for _, tc := range listTests {
+		t.Run("list "+tc.name, func(t *testing.T) {
+			quotes, err := s.client.ListQuotes(ctx, tc.opts)
+			if err != nil {
+				t.Fatalf("failed to list quotes: %v", err)
+			}
+			if got := texts(quotes); strings.Join(got, "|") != strings.Join(tc.expected, "|") {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+
+	t.Run("get", func(t *testing.T) {
+		quote, err := s.client.GetQuote(ctx, ids[2])
+		if err != nil || quote.Text != seed[2].Text {
+			t.Fatalf("expected %q, got %+v, %v", seed[2].Text, quote, err)
+		}
+	})
+
+	t.Run("random", func(t *testing.T) {
+		quote, err := s.client.RandomQuote(ctx)
+		if err != nil {
+			t.Fatalf("failed to get a random quote: %v", err)
+		}
+		found := false
+		for _, id := range ids {
+			found = found || quote.ID == id
+		}
+		if !found {
+			t.Fatalf("expected one of %v, got %+v", ids, quote)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := s.client.DeleteQuote(ctx, ids[3]); err != nil {
+			t.Fatalf("failed to delete: %v", err)
+		}
+		if _, err := s.client.GetQuote(ctx, ids[3]); !errors.Is(err, client.ErrNotFound) {
+			t.Fatalf("expected the deleted quote to be gone, got %v", err)
+		}
+		quotes, err := s.client.ListQuotes(ctx, client.ListOptions{Author: "William Shakespeare"})
+		if err != nil || len(quotes) != 1 {
+			t.Fatalf("expected one quote left by the author, got %d, %v", len(quotes), err)
+		}
+	})
+
+	errorTests := []struct {
+		name     string
+		call     func() error
+		expected error
+		code     string
+	}{
+		{
+			name:     "add without text",
+			call:     func() error { _, err := s.client.AddQuote(ctx, client.AddQuoteRequest{Author: "A"}); return err },
+			expected: client.ErrValidation,
+			code:     "invalid_request",
+		},
+		{
+			name:     "get missing",
+			call:     func() error { _, err := s.client.GetQuote(ctx, 999999); return err },
+			expected: client.ErrNotFound,
+			code:     "quote_not_found",
+		},
+		{
+			name:     "delete missing",
+			call:     func() error { return s.client.DeleteQuote(ctx, 999999) },
+			expected: client.ErrNotFound,
+			code:     "quote_not_found",
+		},
+		{
+			name:     "bad lang filter",
+			call:     func() error { _, err := s.client.ListQuotes(ctx, client.ListOptions{Lang: "zz"}); return err },
+			expected: client.ErrValidation,
+			code:     "invalid_parameter",
+		},
+	}
+	for _, tc := range errorTests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.call()
+			var apiErr *client.Error
+			if !errors.Is(err, tc.expected) || !errors.As(err, &apiErr) || apiErr.Code != tc.code {
+				t.Fatalf("expected %v with code %s, got %v", tc.expected, tc.code, err)
+			}
+		})
+	}
+
+	if err := s.stop(t); err != nil {
+		t.Fatalf("service did not stop cleanly: %v", err)
+	}
+}
+
+// TestGracefulShutdown sends SIGTERM while a request is half sent and
+// checks that the request still completes before the service exits.
+func TestGracefulShutdown(t *testing.T) {
+	s := start(t, config)
+
+	body, bodyWriter := io.Pipe()
+	req, err := http.NewRequest(http.MethodPost, s.url+"/quotes", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		done <- result{status: resp.StatusCode, body: string(data), err: err}
+	}()
+
+	if _, err := io.WriteString(bodyWriter, `{"text": "Still here", `); err != nil {
+		t.Fatal(err)
+	}
+	// Let the server read the headers, so the connection is active when
+	// the signal arrives.
+	time.Sleep(200 * time.Millisecond)
+
+	if err := s.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-s.exited:
+		t.Fatalf("service exited with a request in flight: %v", s.err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	if _, err := io.WriteString(bodyWriter, `"author": "Shutdown"}`); err != nil {
+		t.Fatal(err)
+	}
+	bodyWriter.Close()
+
+	res := <-done
+	if res.err != nil || res.status != http.StatusCreated || !strings.Contains(res.body, `"text":"Still here"`) {
+		t.Fatalf("expected the in-flight request to complete with 201, got %d %q, %v", res.status, res.body, res.err)
+	}
+	select {
+	case <-s.exited:
+		if s.err != nil {
+			t.Fatalf("service did not stop cleanly: %v", s.err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("service did not stop within 10s of SIGTERM")
+	}
+	if logs := s.logs.String(); !strings.Contains(logs, `"msg":"server stopped"`) {
+		t.Fatalf("expected a server stopped event")
+	}
+	if _, err := http.Get(s.url + "/healthz"); err == nil {
+		t.Fatal("expected the service to refuse connections after shutdown")
+	}
+}
+
+func TestImportRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/quotes.jsonl", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		io.WriteString(w, `{"text": "Moved, not lost.", "author": "Redirect"}`+"\n")
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/quotes.jsonl", http.StatusFound)
+	})
+	origin := httptest.NewServer(mux)
+	defer origin.Close()
+
+	tests := []struct {
+		name           string
+		maxRedirects   string
+		expectedStatus string
+		expectedError  string
+	}{
+		{name: "default", expectedStatus: "done"},
+		{name: "none", maxRedirects: `, "max_redirects": 0`, expectedStatus: "failed", expectedError: "stopped after 0 redirects"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := start(t, fmt.Sprintf(`{"env": "prod", "http_server": {"address": "127.0.0.1:0", "timeout": "4s"},
+				"imports": {"enabled": true, "dir": %q, "fetch": {"allowed_schemes": ["http"], "allowed_hosts": ["127.0.0.1"]%s}}}`,
+				t.TempDir(), tc.maxRedirects))
+
+			type jobResponse struct {
+				Data struct {
+					ID     string `json:"id"`
+					Status string `json:"status"`
+					Error  string `json:"error"`
+				} `json:"data"`
+			}
+			decode := func(resp *http.Response, expectedStatus int) jobResponse {
+				t.Helper()
+				defer resp.Body.Close()
+				data, _ := io.ReadAll(resp.Body)
+				var job jobResponse
+				if resp.StatusCode != expectedStatus || json.Unmarshal(data, &job) != nil {
+					t.Fatalf("expected %d with a job, got %d %s", expectedStatus, resp.StatusCode, data)
+				}
+				return job
+			}
+
+			resp, err := http.Post(s.url+"/imports/from-url", "application/json", strings.NewReader(fmt.Sprintf(`{"url": %q}`, origin.URL+"/moved")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			job := decode(resp, http.StatusAccepted)
+			for deadline := time.Now().Add(5 * time.Second); job.Data.Status != "done" && job.Data.Status != "failed"; time.Sleep(20 * time.Millisecond) {
+				if time.Now().After(deadline) {
+					t.Fatalf("import did not finish: %+v", job.Data)
+				}
+				resp, err := http.Get(s.url + "/imports/" + job.Data.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				job = decode(resp, http.StatusOK)
+			}
+			if job.Data.Status != tc.expectedStatus || !strings.Contains(job.Data.Error, tc.expectedError) {
+				t.Fatalf("expected a %s import, got %+v", tc.expectedStatus, job.Data)
+			}
+		})
+	}
+}
@@ -2,15 +2,89 @@ package config
 
 import (
 	"encoding/json"
-	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/url"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+
+	"quotes-service/internal/jobs/publisher"
+	"quotes-service/internal/lib/deprecation"
+	"quotes-service/internal/lib/features"
+	"quotes-service/internal/lib/hardening"
+	"quotes-service/internal/lib/language"
+	"quotes-service/internal/lib/normalize"
+	"quotes-service/internal/lib/panicreport"
+	"quotes-service/internal/lib/pathnorm"
+	"quotes-service/internal/lib/publicid"
+	"quotes-service/internal/lib/quoteinput"
+	"quotes-service/internal/lib/role"
+	"quotes-service/internal/lib/schedule"
+	"quotes-service/internal/lib/share"
+	"quotes-service/internal/lib/usertext"
+	"quotes-service/internal/storage/memorystorage"
+	"quotes-service/internal/storage/restore"
+	"quotes-service/internal/storage/selfcheck"
 )
 
 type Config struct {
 	Env         string
 	Version string
 	HTTPServer  HTTPServer
+	Random      Random
+	Stats       Stats
+	Auth        Auth
+	CacheControl CacheControl
+	RateLimit   RateLimit
+	I18n        I18n
+	Faults      Faults
+	ListCache   ListCache
+	Metrics     Metrics
+	SelfCheck   SelfCheck
+	AdminServer AdminServer
+	ACME        ACME
+	Sync        Sync
+	Webhooks    Webhooks
+	Schedule    Schedule
+	SMTP        SMTP
+	Digest      Digest
+	Backup      Backup
+	Restore     Restore
+	Replication Replication
+	IDs         IDs
+	// UserText is how much of the text clients send each sink may
+	// repeat.
+	UserText    usertext.Policy
+	Panics      Panics
+	Changes     Changes
+	API         API
+	Exports     Exports
+	Imports     Imports
+	Validation  Validation
+	ContentFilter ContentFilter
+	Signing Signing
+	JWT JWT
+	Fallback Fallback
+	Dialects Dialects
+	Features Features
+	Normalize Normalize
+	Response Response
+	CORS CORS
+	Audit Audit
+	Storage Storage
+	Hardening Hardening
+	DebugHeaders DebugHeaders
+	Claims Claims
+	Bulk Bulk
+	Deprecations Deprecations
+	Share Share
+	SupportBundle SupportBundle
+	Paths Paths
+	AuthorMetadata AuthorMetadata
 }
 
 type HTTPServer struct {
@@ -18,24 +92,1110 @@ type HTTPServer struct {
 	Timeout     time.Duration
 	User        string
 	Password    string
+	// SocketMode is the file mode of the socket when Address is a
+	// unix:///path address.
+	SocketMode  os.FileMode
+}
+
+// Where the operational routes go while the admin listener is disabled.
+const (
+	AdminFallbackMain = "main"
+	AdminFallbackOff  = "off"
+)
+
+// AdminServer configures the optional second listener for the operational
+// routes: metrics, pprof, health checks and /admin. While it is disabled
+// those routes are served on the main listener, or not at all if Fallback is
+// AdminFallbackOff. pprof is only ever served on the admin listener.
+type AdminServer struct {
+	Enabled  bool
+	Address  string
+	Fallback string
+}
+
+// ACME enables automatic TLS certificates. The API is then served over
+// HTTPS on HTTPSAddress instead of HTTPServer.Address, and HTTPAddress
+// answers HTTP-01 challenges and redirects everything else to HTTPS.
+// Certificates are only ever requested for Domains.
+type ACME struct {
+	Enabled      bool
+	Domains      []string
+	CacheDir     string
+	DirectoryURL string
+	Email        string
+	HTTPSAddress string
+	HTTPAddress  string
+}
+
+// Sync configures the periodic import from an external quotable-compatible
+// API. Authors renames source authors before import.
+type Sync struct {
+	Enabled   bool
+	SourceURL string
+	Interval  time.Duration
+	PageSize  int
+	MaxPages  int
+	Authors   map[string]string
+	Lang      string
+}
+
+// Webhooks lists the endpoints events are delivered to. Secret, when set,
+// signs each delivery.
+type Webhooks struct {
+	Endpoints []WebhookEndpoint
+}
+
+type WebhookEndpoint struct {
+	URL    string
+	Secret string
+}
+
+// Schedule configures the scheduled quote publisher. Parsed is Cron
+// evaluated in Timezone.
+type Schedule struct {
+	Enabled    bool
+	Cron       string
+	Timezone   string
+	Parsed     *schedule.Schedule
+	Mode       string
+	Collection string
+	Backfill   bool
+	StateFile  string
+	// Window is how many days a quote of the day sits out in daily mode.
+	Window int
+}
+
+// SMTP is the mail relay outgoing email goes through. Username and
+// Password are optional; the password can also come from SMTP_PASSWORD.
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Digest configures the email digest of quotes added since the last one.
+// Parsed is Cron evaluated in Timezone.
+type Digest struct {
+	Enabled    bool
+	Cron       string
+	Timezone   string
+	Parsed     *schedule.Schedule
+	Recipients []string
+	Subject    string
+	StateFile  string
+}
+
+// Backup configures periodic snapshots of the quotes, kept in Dir, in an
+// S3-compatible bucket, or both. KeepLast and MaxAge prune old snapshots;
+// zero disables either rule.
+type Backup struct {
+	Enabled  bool
+	Interval time.Duration
+	Dir      string
+	KeepLast int
+	MaxAge   time.Duration
+	S3       BackupS3
+}
+
+// BackupS3 locates the bucket snapshots are uploaded to. Uploads are
+// disabled while Bucket is empty. The keys can also come from
+// BACKUP_S3_ACCESS_KEY and BACKUP_S3_SECRET_KEY.
+type BackupS3 struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+}
+
+// Restore names a snapshot loaded at startup while the store is empty.
+// s3:// URLs reach the service with the backup.s3 endpoint, region and
+// keys, which S3 holds whether or not backups are enabled. A failed restore
+// stops startup unless Optional is set.
+type Restore struct {
+	URL      string
+	Optional bool
+	S3       BackupS3
+}
+
+// Replication mirrors writes to a secondary store while a migration is
+// underway. The memory backend is the only one so far, so Secondary can
+// only be "memory".
+type Replication struct {
+	Enabled           bool
+	Secondary         string
+	QueueSize         int
+	BatchSize         int
+	ReadFromSecondary bool
+}
+
+// IDs configures public quote identifiers. PublicID is the format new
+// quotes get one in, or empty for none. PublicOnly drops the sequential
+// IDs from API responses so that clients only ever see public ones.
+type IDs struct {
+	PublicID   publicid.Format
+	PublicOnly bool
+}
+
+// Panics configures where reports of recovered handler panics are sent.
+// Sink is empty for nowhere, panicreport.SinkWebhook or
+// panicreport.SinkSentry, with URL the webhook endpoint or the Sentry DSN.
+type Panics struct {
+	Sink      string
+	URL       string
+	Secret    string
+	QueueSize int
+}
+
+// Changes bounds the quote change log served by /quotes/changes to
+// MaxEntries changes no older than MaxAge, where a zero MaxAge means no age
+// limit. A zero MaxEntries turns the endpoint off.
+type Changes struct {
+	MaxEntries int
+	MaxAge     time.Duration
+}
+
+// Audit records every write made through the API to an audit trail
+// served by /admin/audit. The trail keeps MaxEntries entries no older than
+// MaxAge, where a zero MaxAge means no age limit.
+type Audit struct {
+	Enabled    bool
+	MaxEntries int
+	MaxAge     time.Duration
+}
+
+// Exports configures background exports. Finished exports are kept in Dir,
+// or uploaded to S3.Bucket when it is set, and removed TTL after they
+// finish. Workers exports run at once and up to QueueSize wait for one.
+// StateFile keeps the jobs across restarts.
+type Exports struct {
+	Enabled   bool
+	Dir       string
+	TTL       time.Duration
+	Workers   int
+	QueueSize int
+	StateFile string
+	S3        BackupS3
+}
+
+// Imports configures background imports. Uploads wait in Dir until their
+// job runs, and finished jobs are forgotten TTL after they finish. Workers
+// imports run at once and up to QueueSize wait for one. Quotes are stored
+// BatchSize at a time; an upload or a fetched body may not exceed MaxBytes,
+// and fetching one may not take longer than FetchTimeout.
+type Imports struct {
+	Enabled      bool
+	Dir          string
+	TTL          time.Duration
+	Workers      int
+	QueueSize    int
+	BatchSize    int
+	MaxBytes     int64
+	FetchTimeout time.Duration
+	Fetch        ImportFetch
+}
+
+// ImportFetch limits where imports are fetched from, the URL given and
+// every one it redirects to: no host is allowed unless AllowedHosts names
+// it. Empty AllowedSchemes and ContentTypes keep the importer's defaults.
+// InsecureSkipVerify is refused outside the local and dev environments.
+type ImportFetch struct {
+	AllowedSchemes     []string
+	AllowedHosts       []string
+	MaxRedirects       int
+	ContentTypes       []string
+	InsecureSkipVerify bool
+}
+
+// Validation checks requests against the JSON Schemas of the API models
+// before the handlers see them. Responses checks what the handlers write
+// as well and logs mismatches; it is off in the prod environment.
+type Validation struct {
+	Enabled   bool
+	Responses bool
+}
+
+// ContentFilter screens the text of created, updated and imported quotes
+// against the denylist in File, which is read again on SIGHUP. Action is
+// what happens to a quote that matches: "reject" refuses it, "hold" keeps
+// it for moderation, up to MaxHeld quotes at a time.
+type ContentFilter struct {
+	Enabled bool
+	File    string
+	Action  string
+	MaxHeld int
+}
+
+// JWT lets clients authenticate with bearer JWTs from an identity
+// provider, alongside API keys. A token must be issued by Issuer for
+// Audience and signed with a key from the JWKS at JWKSURL, which is fetched
+// every Refresh and at most every MinRefetch for a key ID it lacks. exp and
+// nbf may be off by ClockSkew. The sub claim is the principal and the
+// RoleClaim claim its role, DefaultRole for tokens without one.
+type JWT struct {
+	Enabled     bool
+	Issuer      string
+	Audience    string
+	JWKSURL     string
+	Refresh     time.Duration
+	MinRefetch  time.Duration
+	ClockSkew   time.Duration
+	RoleClaim   string
+	DefaultRole role.Role
+}
+
+// Signing lets machine-to-machine clients authenticate with HMAC-signed
+// requests. Clients maps each client name to its secret. A signature must
+// be made within MaxSkew of the server's clock, over a body of at most
+// MaxBodyBytes. With Required, unsigned writes are refused.
+type Signing struct {
+	Enabled      bool
+	Clients      map[string]string
+	MaxSkew      time.Duration
+	Required     bool
+	MaxBodyBytes int64
+}
+
+// Fallback configures what is served while storage fails. With
+// RandomFromCache, the random quote endpoint answers with one of the last
+// CacheSize quotes it served instead of an error.
+type Fallback struct {
+	RandomFromCache bool
+	CacheSize       int
+}
+
+// Dialects lets clients that expect other names for quote fields send and
+// get them. Definitions maps each dialect name to its renames of canonical
+// field names, and Principals gives principals a dialect of their own.
+type Dialects struct {
+	Definitions map[string]map[string]string
+	Principals  map[string]string
+}
+
+// Features turns the optional route groups on and off by name; features
+// left out keep their features.Defaults state. Features named in Dynamic can
+// also be switched at runtime through /admin/features.
+type Features struct {
+	Flags   map[string]bool
+	Dynamic []string
+}
+
+// Normalize configures how quote text is rewritten before it is stored.
+// Typography straightens or prettifies quote marks and dashes; duplicates
+// are found the same way under every mode. SanitizeImportedAuthors makes
+// imports mend author names with control characters, line breaks or
+// direction overrides, or too long, rather than skip their quotes.
+type Normalize struct {
+	Typography              normalize.Typography
+	SanitizeImportedAuthors bool
+}
+
+// Response sets the default shape of responses. Successes are wrapped in
+// {"status":"success","data":...} and errors are models.ErrorResponse,
+// unless Bare is set: then resources are written as they are and errors as
+// RFC 7807 problems. Requests choose for themselves with ?envelope=.
+type Response struct {
+	Bare bool
+}
+
+// CORS lets browser pages on AllowedOrigins call the API; "*" allows any
+// origin. Preflight requests are answered with AllowedMethods and
+// AllowedHeaders, cacheable for MaxAge. Browsers may read the response
+// headers the service registers for clients, such as X-Request-ID and
+// X-RateLimit-*, and ExposeHeaders on top of them.
+type CORS struct {
+	Enabled          bool
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// API sets the page sizes of the paginated lists: requests without a limit
+// get DefaultPageSize items, and limits above MaxPageSize are clamped to it.
+// The Max*Chars fields bound the length of author names, tags and other
+// path values a request may carry, MaxAuthorChars that of the names stored
+// too; zero lifts a bound.
+type API struct {
+	DefaultPageSize int
+	MaxPageSize     int
+	MaxAuthorChars  int
+	MaxTagChars     int
+	MaxPathChars    int
+}
+
+// Random configures the no-repeat window of the random quote endpoint. The
+// window is applied only to clients that identify themselves. A non-zero
+// CoalesceInterval or CoalesceRequests shares one pick between the plain
+// random requests in that interval or that many requests.
+type Random struct {
+	NoRepeatWindow     int
+	NoRepeatTTL        time.Duration
+	NoRepeatMaxClients int
+	CoalesceInterval   time.Duration
+	CoalesceRequests   int
+}
+
+// Stats configures the text statistics endpoint. A nil Stopwords list means
+// the built-in English list is used.
+type Stats struct {
+	Stopwords []string
+	TopWords  int
+}
+
+// Auth maps API keys to the principal names they authenticate. With no keys
+// configured every request is anonymous. Roles gives principals their roles;
+// the others are writers, as are anonymous requests unless Anonymous is set.
+// KeysFile holds the keys created through /admin/keys, which exists only
+// when it is set.
+type Auth struct {
+	APIKeys   map[string]string
+	Roles     map[string]role.Role
+	Anonymous role.Role
+	KeysFile string
+	Lockout AuthLockout
+}
+
+// AuthLockout locks out remote IPs and credentials after MaxFailures
+// refused credentials within Window, for Duration at first and twice as
+// long each time after, up to MaxDuration. Zero MaxFailures disables it.
+type AuthLockout struct {
+	MaxFailures int
+	Window      time.Duration
+	Duration    time.Duration
+	MaxDuration time.Duration
+	MaxClients  int
+}
+
+// Admins returns the principals with the admin role, sorted.
+func (a Auth) Admins() []string {
+	var admins []string
+	for principal, r := range a.Roles {
+		if r == role.Admin {
+			admins = append(admins, principal)
+		}
+	}
+	slices.Sort(admins)
+	return admins
+}
+
+// Faults enables the storage fault injection admin endpoint. It is refused
+// in the prod environment unless AllowInProd is set as well.
+type Faults struct {
+	Enabled     bool
+	AllowInProd bool
+}
+
+// I18n points at an optional JSON catalog with extra error message
+// translations.
+type I18n struct {
+	CatalogPath string
+}
+
+// RateLimit configures the per-client token buckets: one per remote IP
+// for anonymous requests and one per principal for authenticated ones. A
+// zero rate disables rate limiting by IP.
+type RateLimit struct {
+	RequestsPerSecond float64
+	Burst             int
+	MaxClients        int
+	// Principal is the limit of principals without one in Principals or
+	// Roles. The zero RateLimitRule means RequestsPerSecond and Burst.
+	Principal  RateLimitRule
+	Principals map[string]RateLimitRule
+	Roles      map[role.Role]RateLimitRule
+	// IPForPrincipals also limits authenticated requests by IP.
+	IPForPrincipals bool
+}
+
+// RateLimitRule is a bucket's refill rate and capacity. A zero rate with a
+// positive burst is no limit.
+type RateLimitRule struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// Metrics configures the Prometheus endpoint. Requests whose User-Agent
+// starts with one of ExcludeUserAgents or whose path is in ExcludePaths are
+// served but not counted, and are logged at debug level. /admin/slow lists
+// the SlowRequests slowest counted requests of the last SlowWindow; zero
+// SlowRequests turns it off. TenantLabels labels the request count and
+// latency by API key principal, for at most MaxTenants principals.
+type Metrics struct {
+	Enabled           bool
+	Path              string
+	ExcludeUserAgents []string
+	ExcludePaths      []string
+	SlowRequests      int
+	SlowWindow        time.Duration
+	TenantLabels      bool
+	MaxTenants        int
+}
+
+// Storage bounds how long the store may take to close when the service
+// stops, so a backend stuck on a connection cannot keep the process alive.
+// CallBudget, when positive, is how many storage calls a request may make
+// before it is logged as a warning, for catching handlers that look items
+// up one by one during development.
+type Storage struct {
+	CloseTimeout time.Duration
+	CallBudget   int
+}
+
+// Hardening turns route groups and methods off for deployments whose
+// security baseline forbids them, such as every delete or the admin API.
+// Groups and methods are those of hardening.Groups and hardening.Methods.
+// With Dynamic set, every one of them can also be switched at runtime
+// through /admin/features.
+type Hardening struct {
+	DisabledGroups  []string
+	DisabledMethods []string
+	Response        hardening.Mode
+	Dynamic         bool
+}
+
+// Paths sets how the API answers requests for paths that are not in
+// canonical form, such as "/quotes/" or "//quotes": by redirecting to the
+// canonical path, by serving it in place, or, with pathnorm.ModeOff, not
+// at all.
+type Paths struct {
+	Mode pathnorm.Mode
+}
+
+// Where the author endpoints look up author metadata.
+const (
+	AuthorMetadataFile   = "file"
+	AuthorMetadataRemote = "remote"
+)
+
+// AuthorMetadata adds portraits, years and wiki links to the author
+// endpoints. Source is empty for none, AuthorMetadataFile for the JSON file
+// at File, or AuthorMetadataRemote for the service at URL, whose lookups
+// take at most Timeout and are cached for CacheTTL, CacheSize authors at
+// most. Zero durations and sizes take the authormeta defaults.
+type AuthorMetadata struct {
+	Source    string
+	File      string
+	URL       string
+	Timeout   time.Duration
+	CacheTTL  time.Duration
+	CacheSize int
+}
+
+// DebugHeaders adds X-Backend, X-Data-Version and X-Instance to the API's
+// responses, so support can tell which instance and which data served a
+// stale read. They describe the deployment, so they are off unless
+// enabled. InstanceID defaults to the hostname.
+type DebugHeaders struct {
+	Enabled    bool
+	InstanceID string
+}
+
+// Claims enables POST /quotes/claim and POST /quotes/{id}/release, which
+// lease quotes to consumers for DefaultLease, or as long as they ask up to
+// MaxLease. Expired leases are forgotten every SweepInterval.
+type Claims struct {
+	Enabled       bool
+	DefaultLease  time.Duration
+	MaxLease      time.Duration
+	SweepInterval time.Duration
+}
+
+// Bulk bounds each call of the long bulk operations: deleting an author's
+// quotes, merging authors and reindexing. They run BatchSize quotes at a
+// time and stop starting batches once a call has run for Budget, answering
+// with a resume token. Budget defaults to half of http_server.timeout, so
+// that the last batch and the response fit in the rest.
+type Bulk struct {
+	BatchSize int
+	Budget    time.Duration
+}
+
+// Deprecations marks routes and response fields deprecated. Their
+// responses carry the Deprecation, Sunset and Link headers, and their use
+// is logged at most once per LogInterval for each caller and reported by
+// GET /admin/deprecations/usage, counting MaxCallers callers apart per
+// entry.
+type Deprecations struct {
+	Entries     []deprecation.Entry
+	LogInterval time.Duration
+	MaxCallers  int
+}
+
+// Share configures the styles of GET /quotes/{id}/share: the built-in
+// ones, with their templates and lengths as changed by the config, and any
+// the config adds. Formatter is Styles parsed.
+type Share struct {
+	Styles    map[string]share.Style
+	Formatter *share.Formatter
+}
+
+// SupportBundle bounds what GET /admin/support-bundle includes: the last
+// LogLines log lines at info and above, and the Requests slowest and last
+// failed requests. A zero LogLines keeps no log lines.
+type SupportBundle struct {
+	LogLines int
+	Requests int
+}
+
+// SelfCheck selects the storage check run before the server starts. The
+// memory backend defaults to off since it cannot fail the way a persistent
+// store can.
+type SelfCheck struct {
+	Mode selfcheck.Mode
+}
+
+// ListCache bounds the encoded quote list kept between mutations. A zero
+// MaxBytes disables the cache.
+type ListCache struct {
+	MaxBytes int
+}
+
+// CacheControl holds the Cache-Control header value for each route class.
+// An empty value leaves the header unset.
+type CacheControl struct {
+	Random string
+	ByID   string
+	List   string
 }
 
 type jsonConfig struct {
 	Env string `json:"env"`
 	Version string `json:"version"`
 	HTTPServer jsonHTTPServer `json:"http_server"`
+	Random     jsonRandom     `json:"random"`
+	Stats      jsonStats      `json:"stats"`
+	Auth       jsonAuth       `json:"auth"`
+	CacheControl jsonCacheControl `json:"cache_control"`
+	RateLimit    jsonRateLimit    `json:"rate_limit"`
+	I18n         jsonI18n         `json:"i18n"`
+	Faults       jsonFaults       `json:"faults"`
+	ListCache    jsonListCache    `json:"list_cache"`
+	Metrics      jsonMetrics      `json:"metrics"`
+	SelfCheck    jsonSelfCheck    `json:"self_check"`
+	AdminServer  jsonAdminServer  `json:"admin_server"`
+	ACME         jsonACME         `json:"acme"`
+	Sync         jsonSync         `json:"sync"`
+	Webhooks     jsonWebhooks     `json:"webhooks"`
+	Schedule     jsonSchedule     `json:"schedule"`
+	SMTP         jsonSMTP         `json:"smtp"`
+	Digest       jsonDigest       `json:"digest"`
+	Backup       jsonBackup       `json:"backup"`
+	Restore      jsonRestore      `json:"restore"`
+	Replication  jsonReplication  `json:"replication"`
+	IDs          jsonIDs          `json:"ids"`
+	Logging      jsonLogging      `json:"logging"`
+	UserText     jsonUserText     `json:"user_text"`
+	Panics       jsonPanics       `json:"panics"`
+	Changes      jsonChanges      `json:"changes"`
+	API          jsonAPI          `json:"api"`
+	Exports      jsonExports      `json:"exports"`
+	Imports      jsonImports      `json:"imports"`
+	Validation   jsonValidation   `json:"validation"`
+	ContentFilter jsonContentFilter `json:"content_filter"`
+	Signing jsonSigning `json:"signing"`
+	JWT jsonJWT `json:"jwt"`
+	Fallback jsonFallback `json:"fallback"`
+	Dialects jsonDialects `json:"dialects"`
+	Features map[string]bool `json:"features"`
+	DynamicFeatures []string `json:"dynamic_features"`
+	Normalize jsonNormalize `json:"normalize"`
+	Response jsonResponse `json:"response"`
+	CORS jsonCORS `json:"cors"`
+	Audit jsonAudit `json:"audit"`
+	Storage jsonStorage `json:"storage"`
+	Hardening jsonHardening `json:"hardening"`
+	DebugHeaders jsonDebugHeaders `json:"debug_headers"`
+	Claims jsonClaims `json:"claims"`
+	Bulk jsonBulk `json:"bulk"`
+	Deprecations jsonDeprecations `json:"deprecations"`
+	Share jsonShare `json:"share"`
+	SupportBundle jsonSupportBundle `json:"support_bundle"`
+	Paths jsonPaths `json:"paths"`
+	AuthorMetadata jsonAuthorMetadata `json:"author_metadata"`
+}
+
+type jsonExports struct {
+	Enabled   bool         `json:"enabled"`
+	Dir       string       `json:"dir"`
+	TTL       string       `json:"ttl"`
+	Workers   int          `json:"workers"`
+	QueueSize int          `json:"queue_size"`
+	StateFile string       `json:"state_file"`
+	S3        jsonBackupS3 `json:"s3"`
+}
+
+type jsonImports struct {
+	Enabled      bool   `json:"enabled"`
+	Dir          string `json:"dir"`
+	TTL          string `json:"ttl"`
+	Workers      int    `json:"workers"`
+	QueueSize    int    `json:"queue_size"`
+	BatchSize    int    `json:"batch_size"`
+	MaxBytes     int64  `json:"max_bytes"`
+	FetchTimeout string `json:"fetch_timeout"`
+	Fetch        jsonImportFetch `json:"fetch"`
+}
+
+type jsonImportFetch struct {
+	AllowedSchemes     []string `json:"allowed_schemes"`
+	AllowedHosts       []string `json:"allowed_hosts"`
+	MaxRedirects       *int     `json:"max_redirects"`
+	ContentTypes       []string `json:"content_types"`
+	InsecureSkipVerify bool     `json:"insecure_skip_verify"`
+}
+
+type jsonContentFilter struct {
+	Enabled bool   `json:"enabled"`
+	File    string `json:"file"`
+	Action  string `json:"action"`
+	MaxHeld int    `json:"max_held"`
+}
+
+type jsonSigning struct {
+	Enabled      bool              `json:"enabled"`
+	Clients      map[string]string `json:"clients"`
+	MaxSkew      string            `json:"max_skew"`
+	Required     bool              `json:"required"`
+	MaxBodyBytes int64             `json:"max_body_bytes"`
+}
+
+type jsonJWT struct {
+	Enabled     bool   `json:"enabled"`
+	Issuer      string `json:"issuer"`
+	Audience    string `json:"audience"`
+	JWKSURL     string `json:"jwks_url"`
+	Refresh     string `json:"refresh_interval"`
+	MinRefetch  string `json:"min_refetch_interval"`
+	ClockSkew   string `json:"clock_skew"`
+	RoleClaim   string `json:"role_claim"`
+	DefaultRole string `json:"default_role"`
+}
+
+type jsonFallback struct {
+	RandomFromCache bool `json:"random_from_cache"`
+	CacheSize       *int `json:"cache_size"`
+}
+
+type jsonDialects struct {
+	Definitions map[string]map[string]string `json:"definitions"`
+	Principals  map[string]string            `json:"principals"`
+}
+
+type jsonNormalize struct {
+	Typography              string `json:"typography"`
+	SanitizeImportedAuthors bool   `json:"sanitize_imported_authors"`
+}
+
+type jsonResponse struct {
+	Envelope *bool `json:"envelope"`
+}
+
+type jsonCORS struct {
+	Enabled          bool     `json:"enabled"`
+	AllowedOrigins   []string `json:"allowed_origins"`
+	AllowedMethods   []string `json:"allowed_methods"`
+	AllowedHeaders   []string `json:"allowed_headers"`
+	ExposeHeaders    []string `json:"expose_headers"`
+	AllowCredentials bool     `json:"allow_credentials"`
+	MaxAge           string   `json:"max_age"`
+}
+
+type jsonValidation struct {
+	Enabled   bool `json:"enabled"`
+	Responses bool `json:"responses"`
+}
+
+type jsonAPI struct {
+	DefaultPageSize *int `json:"default_page_size"`
+	MaxPageSize     *int `json:"max_page_size"`
+	MaxAuthorChars  *int `json:"max_author_chars"`
+	MaxTagChars     *int `json:"max_tag_chars"`
+	MaxPathChars    *int `json:"max_path_chars"`
+}
+
+type jsonChanges struct {
+	MaxEntries *int   `json:"max_entries"`
+	MaxAge     string `json:"max_age"`
+}
+
+type jsonAudit struct {
+	Enabled    bool   `json:"enabled"`
+	MaxEntries *int   `json:"max_entries"`
+	MaxAge     string `json:"max_age"`
+}
+
+type jsonPanics struct {
+	Sink      string `json:"sink"`
+	URL       string `json:"url"`
+	Secret    string `json:"secret"`
+	QueueSize int    `json:"queue_size"`
+}
+
+type jsonLogging struct {
+	PreviewChars *int `json:"preview_chars"`
+}
+
+type jsonUserText struct {
+	LogChars     *int `json:"log_chars"`
+	ErrorChars   *int `json:"error_chars"`
+	AuditChars   *int `json:"audit_chars"`
+	WebhookChars *int `json:"webhook_chars"`
+}
+
+type jsonIDs struct {
+	PublicID   string `json:"public_id"`
+	PublicOnly bool   `json:"public_only"`
+}
+
+type jsonReplication struct {
+	Enabled           bool   `json:"enabled"`
+	Secondary         string `json:"secondary"`
+	QueueSize         int    `json:"queue_size"`
+	BatchSize         int    `json:"batch_size"`
+	ReadFromSecondary bool   `json:"read_from_secondary"`
+}
+
+type jsonRestore struct {
+	URL      string `json:"url"`
+	Optional bool   `json:"optional"`
+}
+
+type jsonBackup struct {
+	Enabled  bool         `json:"enabled"`
+	Interval string       `json:"interval"`
+	Dir      string       `json:"dir"`
+	KeepLast int          `json:"keep_last"`
+	MaxAge   string       `json:"max_age"`
+	S3       jsonBackupS3 `json:"s3"`
+}
+
+type jsonBackupS3 struct {
+	Endpoint  string `json:"endpoint"`
+	Region    string `json:"region"`
+	Bucket    string `json:"bucket"`
+	Prefix    string `json:"prefix"`
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+}
+
+type jsonSMTP struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	From     string `json:"from"`
+}
+
+type jsonDigest struct {
+	Enabled    bool     `json:"enabled"`
+	Cron       string   `json:"cron"`
+	Timezone   string   `json:"timezone"`
+	Recipients []string `json:"recipients"`
+	Subject    string   `json:"subject"`
+	StateFile  string   `json:"state_file"`
+}
+
+type jsonWebhooks struct {
+	Endpoints []jsonWebhookEndpoint `json:"endpoints"`
+}
+
+type jsonWebhookEndpoint struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+}
+
+type jsonSchedule struct {
+	Enabled    bool   `json:"enabled"`
+	Cron       string `json:"cron"`
+	Timezone   string `json:"timezone"`
+	Mode       string `json:"mode"`
+	Collection string `json:"collection"`
+	Backfill   bool   `json:"backfill"`
+	StateFile  string `json:"state_file"`
+	Window     *int   `json:"window"`
+}
+
+type jsonSync struct {
+	Enabled   bool              `json:"enabled"`
+	SourceURL string            `json:"source_url"`
+	Interval  string            `json:"interval"`
+	PageSize  *int              `json:"page_size"`
+	MaxPages  *int              `json:"max_pages"`
+	Authors   map[string]string `json:"authors"`
+	Lang      string            `json:"lang"`
+}
+
+type jsonACME struct {
+	Enabled      bool     `json:"enabled"`
+	Domains      []string `json:"domains"`
+	CacheDir     string   `json:"cache_dir"`
+	DirectoryURL string   `json:"directory_url"`
+	Email        string   `json:"email"`
+	HTTPSAddress string   `json:"https_address"`
+	HTTPAddress  string   `json:"http_address"`
+}
+
+type jsonAdminServer struct {
+	Enabled  bool   `json:"enabled"`
+	Address  string `json:"address"`
+	Fallback string `json:"fallback"`
+}
+
+type jsonStorage struct {
+	CloseTimeout string `json:"close_timeout"`
+	CallBudget   *int   `json:"call_budget"`
+}
+
+type jsonDebugHeaders struct {
+	Enabled    bool   `json:"enabled"`
+	InstanceID string `json:"instance_id"`
+}
+
+type jsonClaims struct {
+	Enabled       bool   `json:"enabled"`
+	DefaultLease  string `json:"default_lease"`
+	MaxLease      string `json:"max_lease"`
+	SweepInterval string `json:"sweep_interval"`
+}
+
+type jsonBulk struct {
+	BatchSize int    `json:"batch_size"`
+	Budget    string `json:"budget"`
+}
+
+type jsonDeprecations struct {
+	LogInterval string            `json:"log_interval"`
+	MaxCallers  *int              `json:"max_callers"`
+	Entries     []jsonDeprecation `json:"entries"`
+}
+
+type jsonShare struct {
+	Styles map[string]jsonShareStyle `json:"styles"`
+}
+
+type jsonSupportBundle struct {
+	LogLines *int `json:"log_lines"`
+	Requests *int `json:"requests"`
+}
+
+type jsonShareStyle struct {
+	Template     string `json:"template"`
+	TemplateFile string `json:"template_file"`
+	MaxLength    *int   `json:"max_length"`
+}
+
+type jsonDeprecation struct {
+	Route  string `json:"route"`
+	Field  string `json:"field"`
+	Since  string `json:"since"`
+	Sunset string `json:"sunset"`
+	Link   string `json:"link"`
+}
+
+type jsonHardening struct {
+	DisabledGroups  []string `json:"disabled_groups"`
+	DisabledMethods []string `json:"disabled_methods"`
+	Response        string   `json:"response"`
+	Dynamic         bool     `json:"dynamic"`
+}
+
+type jsonPaths struct {
+	Mode string `json:"mode"`
+}
+
+type jsonAuthorMetadata struct {
+	Source    string `json:"source"`
+	File      string `json:"file"`
+	URL       string `json:"url"`
+	Timeout   string `json:"timeout"`
+	CacheTTL  string `json:"cache_ttl"`
+	CacheSize int    `json:"cache_size"`
+}
+
+type jsonSelfCheck struct {
+	Mode string `json:"mode"`
+}
+
+type jsonMetrics struct {
+	Enabled           *bool    `json:"enabled"`
+	Path              string   `json:"path"`
+	ExcludeUserAgents []string `json:"exclude_user_agents"`
+	ExcludePaths      []string `json:"exclude_paths"`
+	SlowRequests      *int     `json:"slow_requests"`
+	SlowWindow        string   `json:"slow_window"`
+	TenantLabels      bool     `json:"tenant_labels"`
+	MaxTenants        *int     `json:"max_tenants"`
+}
+
+type jsonListCache struct {
+	MaxBytes *int `json:"max_bytes"`
+}
+
+type jsonFaults struct {
+	Enabled     bool `json:"enabled"`
+	AllowInProd bool `json:"allow_in_prod"`
 }
 
 type jsonHTTPServer struct {
-	Address string `json:"address"`
-	Timeout string `json:"timeout"`
+	Address    string `json:"address"`
+	Timeout    string `json:"timeout"`
+	SocketMode string `json:"socket_mode"`
+}
+
+type jsonStats struct {
+	Stopwords []string `json:"stopwords"`
+	TopWords  *int     `json:"top_words"`
+}
+
+type jsonI18n struct {
+	CatalogPath string `json:"catalog_path"`
+}
+
+type jsonRateLimit struct {
+	RequestsPerSecond *float64 `json:"requests_per_second"`
+	Burst             *int     `json:"burst"`
+	MaxClients        *int     `json:"max_clients"`
+	Principal *jsonRateLimitRule `json:"principal"`
+	Principals map[string]jsonRateLimitRule `json:"principals"`
+	Roles map[string]jsonRateLimitRule `json:"roles"`
+	IPForPrincipals bool `json:"ip_for_principals"`
+}
+
+type jsonRateLimitRule struct {
+	RequestsPerSecond *float64 `json:"requests_per_second"`
+	Burst             *int     `json:"burst"`
+}
+
+type jsonCacheControl struct {
+	Random *string `json:"random"`
+	ByID   *string `json:"by_id"`
+	List   *string `json:"list"`
+}
+
+type jsonAuth struct {
+	APIKeys       map[string]jsonAPIKey `json:"api_keys"`
+	Admins        []string              `json:"admins"`
+	AnonymousRole string                `json:"anonymous_role"`
+	KeysFile string `json:"keys_file"`
+	Lockout jsonAuthLockout `json:"lockout"`
+}
+
+type jsonAuthLockout struct {
+	MaxFailures *int `json:"max_failures"`
+	Window string `json:"window"`
+	Duration string `json:"duration"`
+	MaxDuration string `json:"max_duration"`
+	MaxClients *int `json:"max_clients"`
+}
+
+// jsonAPIKey is the principal name of a key, or an object with the name and
+// the principal's role.
+type jsonAPIKey struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+func (k *jsonAPIKey) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &k.Name); err == nil {
+		return nil
+	}
+	type plain jsonAPIKey
+	return json.Unmarshal(data, (*plain)(k))
+}
+
+type jsonRandom struct {
+	NoRepeatWindow     *int   `json:"no_repeat_window"`
+	NoRepeatTTL        string `json:"no_repeat_ttl"`
+	NoRepeatMaxClients *int   `json:"no_repeat_max_clients"`
+	CoalesceInterval   string `json:"coalesce_interval"`
+	CoalesceRequests   *int   `json:"coalesce_requests"`
 }
 
+const (
+	envLocal = "local"
+	envDev   = "dev"
+	envProd  = "prod"
+)
+
 var (
 	defaultAddress = "localhost:8080"
 	defaulTimeout = 4 * time.Second
 	defaultEnv = "local"
 	defaultVersion = "0.0.0"
+	defaultNoRepeatWindow     = 0
+	defaultNoRepeatTTL        = 30 * time.Minute
+	defaultNoRepeatMaxClients = 10000
+	defaultTopWords           = 10
+	defaultCacheControlRandom = "no-store"
+	defaultRateLimitBurst     = 20
+	defaultRateLimitClients   = 10000
+	defaultLockoutFailures    = 10
+	defaultLockoutWindow      = time.Minute
+	defaultLockoutDuration    = time.Minute
+	defaultLockoutMaxDuration = time.Hour
+	defaultLockoutClients     = 10000
+	defaultMetricsPath        = "/metrics"
+	defaultSlowRequests       = 20
+	defaultSlowWindow         = 15 * time.Minute
+	defaultMaxTenants         = 100
+	defaultChangesMaxAge      = 7 * 24 * time.Hour
+	defaultPageSize           = 100
+	defaultMaxPageSize        = 1000
+	defaultMaxPathChars       = 256
+	defaultExportTTL          = 24 * time.Hour
+	defaultExportWorkers      = 2
+	defaultExportQueueSize    = 16
+	defaultImportTTL          = 24 * time.Hour
+	defaultImportWorkers      = 1
+	defaultImportQueueSize    = 16
+	defaultImportBatchSize    = 500
+	defaultImportMaxBytes     int64 = 1 << 30
+	defaultImportFetchTimeout = 10 * time.Minute
+	defaultImportMaxRedirects = 3
+	defaultScheduleWindow     = 7
+	defaultFilterAction       = "reject"
+	defaultMaxHeldQuotes      = 1000
+	defaultSigningMaxSkew     = 5 * time.Minute
+	defaultSignedBodyBytes    int64 = 16 << 20
+	defaultJWKSRefresh        = 15 * time.Minute
+	defaultJWKSMinRefetch     = 30 * time.Second
+	defaultJWTClockSkew       = time.Minute
+	defaultJWTRoleClaim       = "role"
+	defaultFallbackCacheSize  = 32
+	defaultSocketMode         = os.FileMode(0o660)
+	defaultACMEHTTPSAddress   = ":443"
+	defaultACMEHTTPAddress    = ":80"
+	defaultSyncInterval       = time.Hour
+	defaultSyncPageSize       = 50
+	defaultSyncMaxPages       = 20
+	defaultScheduleTimezone   = "UTC"
+	defaultSMTPPort           = 587
+	defaultDigestCron         = "0 9 * * MON"
+	defaultDigestSubject      = "Weekly quotes digest"
+	defaultBackupInterval     = 6 * time.Hour
+	defaultReplicationQueue   = 10000
+	defaultClaimLease         = 5 * time.Minute
+	defaultClaimMaxLease      = time.Hour
+	defaultClaimSweepInterval = time.Minute
+	defaultReplicationBatch   = 500
+	defaultBulkBatchSize      = 500
+	defaultDeprecationLogInterval = time.Hour
+	defaultDeprecationCallers     = 1000
+	defaultSupportBundleLogLines  = 1000
+	defaultSupportBundleRequests  = 20
+	defaultBulkBudget         = 2 * time.Second
+	defaultCORSMethods        = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+	defaultCORSHeaders        = []string{"Authorization", "Content-Type", "If-Match", "If-None-Match", "X-API-Key", "X-Client-ID", "X-Response-Dialect", "X-Signature", "X-Signature-Client", "X-Signature-Timestamp"}
+	defaultCORSMaxAge         = 10 * time.Minute
+	defaultAuditMaxEntries    = 100000
+	defaultAuditMaxAge        = 90 * 24 * time.Hour
+	defaultStorageCloseTimeout = 5 * time.Second
 )
 
 func MustLoad() *Config {
@@ -54,6 +1214,85 @@ func MustLoad() *Config {
 		HTTPServer: HTTPServer{
 			Address: defaultAddress,
 			Timeout: defaulTimeout,
+			SocketMode: defaultSocketMode,
+		},
+		Random: Random{
+			NoRepeatWindow:     defaultNoRepeatWindow,
+			NoRepeatTTL:        defaultNoRepeatTTL,
+			NoRepeatMaxClients: defaultNoRepeatMaxClients,
+		},
+		Stats: Stats{
+			TopWords: defaultTopWords,
+		},
+		CacheControl: CacheControl{
+			Random: defaultCacheControlRandom,
+		},
+		Auth: Auth{
+			Lockout: AuthLockout{
+				MaxFailures: defaultLockoutFailures,
+				Window:      defaultLockoutWindow,
+				Duration:    defaultLockoutDuration,
+				MaxDuration: defaultLockoutMaxDuration,
+				MaxClients:  defaultLockoutClients,
+			},
+		},
+		RateLimit: RateLimit{
+			Burst:      defaultRateLimitBurst,
+			MaxClients: defaultRateLimitClients,
+		},
+		Metrics: Metrics{
+			Enabled:      true,
+			Path:         defaultMetricsPath,
+			SlowRequests: defaultSlowRequests,
+			SlowWindow:   defaultSlowWindow,
+			MaxTenants:   defaultMaxTenants,
+		},
+		SelfCheck: SelfCheck{
+			Mode: selfcheck.ModeOff,
+		},
+		Storage: Storage{
+			CloseTimeout: defaultStorageCloseTimeout,
+		},
+		Bulk: Bulk{
+			BatchSize: defaultBulkBatchSize,
+		},
+		Deprecations: Deprecations{
+			LogInterval: defaultDeprecationLogInterval,
+			MaxCallers:  defaultDeprecationCallers,
+		},
+		SupportBundle: SupportBundle{
+			LogLines: defaultSupportBundleLogLines,
+			Requests: defaultSupportBundleRequests,
+		},
+		Hardening: Hardening{
+			Response: hardening.ModeAuto,
+		},
+		Paths: Paths{
+			Mode: pathnorm.ModeRedirect,
+		},
+		AdminServer: AdminServer{
+			Fallback: AdminFallbackMain,
+		},
+		Sync: Sync{
+			Interval: defaultSyncInterval,
+			PageSize: defaultSyncPageSize,
+			MaxPages: defaultSyncMaxPages,
+		},
+		ACME: ACME{
+			HTTPSAddress: defaultACMEHTTPSAddress,
+			HTTPAddress:  defaultACMEHTTPAddress,
+		},
+		UserText: usertext.Default,
+		Changes: Changes{
+			MaxEntries: memorystorage.DefaultChangeLogEntries,
+			MaxAge:     defaultChangesMaxAge,
+		},
+		API: API{
+			DefaultPageSize: defaultPageSize,
+			MaxPageSize:     defaultMaxPageSize,
+			MaxAuthorChars:  quoteinput.DefaultMaxAuthorChars,
+			MaxTagChars:     quoteinput.DefaultMaxTagChars,
+			MaxPathChars:    defaultMaxPathChars,
 		},
 	}
 
@@ -87,16 +1326,1264 @@ func MustLoad() *Config {
 		cfg.HTTPServer.Timeout = parsedDur
 	}
 
-	if envVal := os.Getenv("ENV"); envVal != "" {
-		cfg.Env = envVal
+	if jsonCfg.HTTPServer.SocketMode != "" {
+		mode, err := strconv.ParseUint(jsonCfg.HTTPServer.SocketMode, 8, 32)
+		if err != nil || mode > 0o777 {
+			log.Fatalf("Неверное значение http_server.socket_mode ('%s'), ожидается восьмеричное число, например 0660", jsonCfg.HTTPServer.SocketMode)
+		}
+		cfg.HTTPServer.SocketMode = os.FileMode(mode)
 	}
 
-	if envVal := os.Getenv("VERSION"); envVal != "" {
-		cfg.HTTPServer.Address = envVal
+	if jsonCfg.Random.NoRepeatWindow != nil {
+		if *jsonCfg.Random.NoRepeatWindow < 0 {
+			log.Fatalf("random.no_repeat_window не может быть отрицательным: %d", *jsonCfg.Random.NoRepeatWindow)
+		}
+		cfg.Random.NoRepeatWindow = *jsonCfg.Random.NoRepeatWindow
 	}
 
-	if envVal := os.Getenv("HTTP_SERVER_ADDRESS"); envVal != "" {
-		cfg.HTTPServer.Address = envVal
+	if jsonCfg.Random.NoRepeatTTL != "" {
+		parsedDur, err := time.ParseDuration(jsonCfg.Random.NoRepeatTTL)
+		if err != nil {
+			log.Fatalf("Ошибка парсинга random.no_repeat_ttl из JSON ('%s'): %v", jsonCfg.Random.NoRepeatTTL, err)
+		}
+		cfg.Random.NoRepeatTTL = parsedDur
+	}
+
+	if jsonCfg.Random.NoRepeatMaxClients != nil {
+		cfg.Random.NoRepeatMaxClients = *jsonCfg.Random.NoRepeatMaxClients
+	}
+
+	if jsonCfg.Random.CoalesceInterval != "" {
+		parsedDur, err := time.ParseDuration(jsonCfg.Random.CoalesceInterval)
+		if err != nil {
+			log.Fatalf("Ошибка парсинга random.coalesce_interval из JSON ('%s'): %v", jsonCfg.Random.CoalesceInterval, err)
+		}
+		if parsedDur < 0 {
+			log.Fatalf("random.coalesce_interval не может быть отрицательным: %s", parsedDur)
+		}
+		cfg.Random.CoalesceInterval = parsedDur
+	}
+
+	if jsonCfg.Random.CoalesceRequests != nil {
+		if *jsonCfg.Random.CoalesceRequests < 0 {
+			log.Fatalf("random.coalesce_requests не может быть отрицательным: %d", *jsonCfg.Random.CoalesceRequests)
+		}
+		cfg.Random.CoalesceRequests = *jsonCfg.Random.CoalesceRequests
+	}
+
+	if jsonCfg.Stats.Stopwords != nil {
+		cfg.Stats.Stopwords = jsonCfg.Stats.Stopwords
+	}
+
+	if jsonCfg.Stats.TopWords != nil {
+		cfg.Stats.TopWords = *jsonCfg.Stats.TopWords
+	}
+
+	cfg.Auth.APIKeys = make(map[string]string, len(jsonCfg.Auth.APIKeys))
+	cfg.Auth.Roles = make(map[string]role.Role)
+	for key, apiKey := range jsonCfg.Auth.APIKeys {
+		if key == "" || apiKey.Name == "" {
+			log.Fatalf("auth.api_keys не может содержать пустой ключ или имя")
+		}
+		cfg.Auth.APIKeys[key] = apiKey.Name
+		if apiKey.Role == "" {
+			continue
+		}
+		r, err := role.Parse(apiKey.Role)
+		if err != nil {
+			log.Fatalf("неверная роль в auth.api_keys для %s: %s", apiKey.Name, apiKey.Role)
+		}
+		if other, ok := cfg.Auth.Roles[apiKey.Name]; ok && other != r {
+			log.Fatalf("auth.api_keys: у клиента %s разные роли: %s и %s", apiKey.Name, other, r)
+		}
+		cfg.Auth.Roles[apiKey.Name] = r
+	}
+
+	// admins predates roles and gives its principals the admin role.
+	for _, admin := range jsonCfg.Auth.Admins {
+		if !hasPrincipal(cfg.Auth.APIKeys, admin) {
+			log.Fatalf("auth.admins содержит неизвестное имя: %s", admin)
+		}
+		if r, ok := cfg.Auth.Roles[admin]; ok && r != role.Admin {
+			log.Fatalf("auth.admins содержит клиента %s с ролью %s", admin, r)
+		}
+		cfg.Auth.Roles[admin] = role.Admin
+	}
+
+	if jsonCfg.Auth.AnonymousRole != "" {
+		r, err := role.Parse(jsonCfg.Auth.AnonymousRole)
+		if err != nil {
+			log.Fatalf("неверная роль в auth.anonymous_role: %s", jsonCfg.Auth.AnonymousRole)
+		}
+		if r == role.Admin {
+			log.Fatal("auth.anonymous_role не может быть admin")
+		}
+		cfg.Auth.Anonymous = r
+	}
+
+	cfg.Auth.KeysFile = jsonCfg.Auth.KeysFile
+
+	lo := jsonCfg.Auth.Lockout
+	if lo.MaxFailures != nil {
+		if *lo.MaxFailures < 0 {
+			log.Fatalf("auth.lockout.max_failures не может быть отрицательным: %d", *lo.MaxFailures)
+		}
+		cfg.Auth.Lockout.MaxFailures = *lo.MaxFailures
+	}
+	if lo.Window != "" {
+		parsedDur, err := time.ParseDuration(lo.Window)
+		if err != nil || parsedDur <= 0 {
+			log.Fatalf("Ошибка парсинга auth.lockout.window из JSON ('%s'): должна быть положительная длительность", lo.Window)
+		}
+		cfg.Auth.Lockout.Window = parsedDur
+	}
+	if lo.Duration != "" {
+		parsedDur, err := time.ParseDuration(lo.Duration)
+		if err != nil || parsedDur <= 0 {
+			log.Fatalf("Ошибка парсинга auth.lockout.duration из JSON ('%s'): должна быть положительная длительность", lo.Duration)
+		}
+		cfg.Auth.Lockout.Duration = parsedDur
+	}
+	if lo.MaxDuration != "" {
+		parsedDur, err := time.ParseDuration(lo.MaxDuration)
+		if err != nil || parsedDur <= 0 {
+			log.Fatalf("Ошибка парсинга auth.lockout.max_duration из JSON ('%s'): должна быть положительная длительность", lo.MaxDuration)
+		}
+		cfg.Auth.Lockout.MaxDuration = parsedDur
+	}
+	if cfg.Auth.Lockout.MaxDuration < cfg.Auth.Lockout.Duration {
+		log.Fatalf("auth.lockout.max_duration (%s) меньше auth.lockout.duration (%s)", cfg.Auth.Lockout.MaxDuration, cfg.Auth.Lockout.Duration)
+	}
+	if lo.MaxClients != nil {
+		cfg.Auth.Lockout.MaxClients = *lo.MaxClients
+	}
+
+	for name, renames := range jsonCfg.Dialects.Definitions {
+		aliases := make(map[string]string, len(renames))
+		for field, alias := range renames {
+			if field == "" || alias == "" {
+				log.Fatalf("dialects.definitions.%s не может содержать пустое имя поля", name)
+			}
+			if other, ok := aliases[alias]; ok {
+				log.Fatalf("dialects.definitions.%s: поля %s и %s переименованы в %s", name, other, field, alias)
+			}
+			if _, ok := renames[alias]; ok && alias != field {
+				log.Fatalf("dialects.definitions.%s: имя %s уже занято полем %s", name, alias, alias)
+			}
+			aliases[alias] = field
+		}
+	}
+	for principal, name := range jsonCfg.Dialects.Principals {
+		if _, ok := jsonCfg.Dialects.Definitions[name]; !ok {
+			log.Fatalf("dialects.principals.%s ссылается на неизвестный диалект: %s", principal, name)
+		}
+	}
+	cfg.Dialects = Dialects{
+		Definitions: jsonCfg.Dialects.Definitions,
+		Principals:  jsonCfg.Dialects.Principals,
+	}
+
+	if jsonCfg.CacheControl.Random != nil {
+		cfg.CacheControl.Random = *jsonCfg.CacheControl.Random
+	}
+
+	if jsonCfg.CacheControl.ByID != nil {
+		cfg.CacheControl.ByID = *jsonCfg.CacheControl.ByID
+	}
+
+	if jsonCfg.CacheControl.List != nil {
+		cfg.CacheControl.List = *jsonCfg.CacheControl.List
+	}
+
+	if jsonCfg.RateLimit.RequestsPerSecond != nil {
+		if *jsonCfg.RateLimit.RequestsPerSecond < 0 {
+			log.Fatalf("rate_limit.requests_per_second не может быть отрицательным: %v", *jsonCfg.RateLimit.RequestsPerSecond)
+		}
+		cfg.RateLimit.RequestsPerSecond = *jsonCfg.RateLimit.RequestsPerSecond
+	}
+
+	if jsonCfg.RateLimit.Burst != nil {
+		if *jsonCfg.RateLimit.Burst < 1 {
+			log.Fatalf("rate_limit.burst должен быть положительным: %d", *jsonCfg.RateLimit.Burst)
+		}
+		cfg.RateLimit.Burst = *jsonCfg.RateLimit.Burst
+	}
+
+	if jsonCfg.RateLimit.MaxClients != nil {
+		cfg.RateLimit.MaxClients = *jsonCfg.RateLimit.MaxClients
+	}
+
+	// Rules left out of the principal limits take the IP limit's values.
+	rateLimitRule := func(name string, rule jsonRateLimitRule) RateLimitRule {
+		parsed := RateLimitRule{RequestsPerSecond: cfg.RateLimit.RequestsPerSecond, Burst: cfg.RateLimit.Burst}
+		if rule.RequestsPerSecond != nil {
+			if *rule.RequestsPerSecond < 0 {
+				log.Fatalf("%s.requests_per_second не может быть отрицательным: %v", name, *rule.RequestsPerSecond)
+			}
+			parsed.RequestsPerSecond = *rule.RequestsPerSecond
+		}
+		if rule.Burst != nil {
+			if *rule.Burst < 1 {
+				log.Fatalf("%s.burst должен быть положительным: %d", name, *rule.Burst)
+			}
+			parsed.Burst = *rule.Burst
+		}
+		return parsed
+	}
+	cfg.RateLimit.Principal = rateLimitRule("rate_limit.principal", jsonRateLimitRule{})
+	if jsonCfg.RateLimit.Principal != nil {
+		cfg.RateLimit.Principal = rateLimitRule("rate_limit.principal", *jsonCfg.RateLimit.Principal)
+	}
+	if len(jsonCfg.RateLimit.Principals) > 0 {
+		cfg.RateLimit.Principals = make(map[string]RateLimitRule, len(jsonCfg.RateLimit.Principals))
+		for principal, rule := range jsonCfg.RateLimit.Principals {
+			if rule.RequestsPerSecond == nil {
+				log.Fatalf("rate_limit.principals.%s.requests_per_second обязателен", principal)
+			}
+			cfg.RateLimit.Principals[principal] = rateLimitRule("rate_limit.principals."+principal, rule)
+		}
+	}
+	if len(jsonCfg.RateLimit.Roles) > 0 {
+		cfg.RateLimit.Roles = make(map[role.Role]RateLimitRule, len(jsonCfg.RateLimit.Roles))
+		for name, rule := range jsonCfg.RateLimit.Roles {
+			r, err := role.Parse(name)
+			if err != nil {
+				log.Fatalf("неверная роль в rate_limit.roles: %s", name)
+			}
+			if rule.RequestsPerSecond == nil {
+				log.Fatalf("rate_limit.roles.%s.requests_per_second обязателен", name)
+			}
+			cfg.RateLimit.Roles[r] = rateLimitRule("rate_limit.roles."+name, rule)
+		}
+	}
+	cfg.RateLimit.IPForPrincipals = jsonCfg.RateLimit.IPForPrincipals
+
+	if jsonCfg.I18n.CatalogPath != "" {
+		if _, err := os.Stat(jsonCfg.I18n.CatalogPath); err != nil {
+			log.Fatalf("Файл каталога сообщений i18n.catalog_path недоступен: %v", err)
+		}
+		cfg.I18n.CatalogPath = jsonCfg.I18n.CatalogPath
+	}
+
+	if jsonCfg.ListCache.MaxBytes != nil {
+		if *jsonCfg.ListCache.MaxBytes < 0 {
+			log.Fatalf("list_cache.max_bytes не может быть отрицательным: %d", *jsonCfg.ListCache.MaxBytes)
+		}
+		cfg.ListCache.MaxBytes = *jsonCfg.ListCache.MaxBytes
+	}
+
+	if jsonCfg.Metrics.Enabled != nil {
+		cfg.Metrics.Enabled = *jsonCfg.Metrics.Enabled
+	}
+
+	if jsonCfg.Metrics.Path != "" {
+		if !strings.HasPrefix(jsonCfg.Metrics.Path, "/") {
+			log.Fatalf("metrics.path должен начинаться с '/': %s", jsonCfg.Metrics.Path)
+		}
+		cfg.Metrics.Path = jsonCfg.Metrics.Path
+	}
+
+	for _, prefix := range jsonCfg.Metrics.ExcludeUserAgents {
+		if prefix == "" {
+			log.Fatal("metrics.exclude_user_agents не может содержать пустую строку")
+		}
+	}
+	cfg.Metrics.ExcludeUserAgents = jsonCfg.Metrics.ExcludeUserAgents
+	cfg.Metrics.ExcludePaths = jsonCfg.Metrics.ExcludePaths
+
+	if jsonCfg.Metrics.SlowRequests != nil {
+		if *jsonCfg.Metrics.SlowRequests < 0 {
+			log.Fatalf("metrics.slow_requests не может быть отрицательным: %d", *jsonCfg.Metrics.SlowRequests)
+		}
+		cfg.Metrics.SlowRequests = *jsonCfg.Metrics.SlowRequests
+	}
+
+	if jsonCfg.Metrics.SlowWindow != "" {
+		parsedDur, err := time.ParseDuration(jsonCfg.Metrics.SlowWindow)
+		if err != nil || parsedDur <= 0 {
+			log.Fatalf("Ошибка парсинга metrics.slow_window из JSON ('%s'): должна быть положительная длительность", jsonCfg.Metrics.SlowWindow)
+		}
+		cfg.Metrics.SlowWindow = parsedDur
+	}
+
+	cfg.Metrics.TenantLabels = jsonCfg.Metrics.TenantLabels
+	if jsonCfg.Metrics.MaxTenants != nil {
+		if *jsonCfg.Metrics.MaxTenants < 1 {
+			log.Fatalf("metrics.max_tenants должен быть положительным: %d", *jsonCfg.Metrics.MaxTenants)
+		}
+		cfg.Metrics.MaxTenants = *jsonCfg.Metrics.MaxTenants
+	}
+
+	if jsonCfg.SelfCheck.Mode != "" {
+		mode, err := selfcheck.ParseMode(jsonCfg.SelfCheck.Mode)
+		if err != nil {
+			log.Fatalf("Неверное значение self_check.mode ('%s'), допустимо off, read или write", jsonCfg.SelfCheck.Mode)
+		}
+		cfg.SelfCheck.Mode = mode
+	}
+
+	if jsonCfg.Storage.CloseTimeout != "" {
+		parsedDur, err := time.ParseDuration(jsonCfg.Storage.CloseTimeout)
+		if err != nil || parsedDur <= 0 {
+			log.Fatalf("Ошибка парсинга storage.close_timeout из JSON ('%s'): должна быть положительная длительность", jsonCfg.Storage.CloseTimeout)
+		}
+		cfg.Storage.CloseTimeout = parsedDur
+	}
+	if jsonCfg.Storage.CallBudget != nil {
+		if *jsonCfg.Storage.CallBudget < 0 {
+			log.Fatalf("storage.call_budget не может быть отрицательным: %d", *jsonCfg.Storage.CallBudget)
+		}
+		cfg.Storage.CallBudget = *jsonCfg.Storage.CallBudget
+	}
+
+	if jsonCfg.DebugHeaders.Enabled {
+		cfg.DebugHeaders.Enabled = true
+		cfg.DebugHeaders.InstanceID = jsonCfg.DebugHeaders.InstanceID
+		if cfg.DebugHeaders.InstanceID == "" {
+			hostname, err := os.Hostname()
+			if err != nil {
+				log.Fatalf("Не удалось определить имя хоста для debug_headers.instance_id: %v", err)
+			}
+			cfg.DebugHeaders.InstanceID = hostname
+		}
+	}
+
+	if jsonCfg.Claims.Enabled {
+		c := jsonCfg.Claims
+		cfg.Claims = Claims{
+			Enabled:       true,
+			DefaultLease:  defaultClaimLease,
+			MaxLease:      defaultClaimMaxLease,
+			SweepInterval: defaultClaimSweepInterval,
+		}
+		for _, d := range []struct {
+			name  string
+			value string
+			dst   *time.Duration
+		}{
+			{"claims.default_lease", c.DefaultLease, &cfg.Claims.DefaultLease},
+			{"claims.max_lease", c.MaxLease, &cfg.Claims.MaxLease},
+			{"claims.sweep_interval", c.SweepInterval, &cfg.Claims.SweepInterval},
+		} {
+			if d.value == "" {
+				continue
+			}
+			parsedDur, err := time.ParseDuration(d.value)
+			if err != nil || parsedDur <= 0 {
+				log.Fatalf("Ошибка парсинга %s из JSON ('%s'), ожидается положительная длительность", d.name, d.value)
+			}
+			*d.dst = parsedDur
+		}
+		if cfg.Claims.DefaultLease > cfg.Claims.MaxLease {
+			log.Fatalf("claims.default_lease (%s) не может превышать claims.max_lease (%s)", cfg.Claims.DefaultLease, cfg.Claims.MaxLease)
+		}
+	}
+
+	if jsonCfg.Bulk.BatchSize < 0 {
+		log.Fatalf("bulk.batch_size не может быть отрицательным: %d", jsonCfg.Bulk.BatchSize)
+	}
+	if jsonCfg.Bulk.BatchSize > 0 {
+		cfg.Bulk.BatchSize = jsonCfg.Bulk.BatchSize
+	}
+	if jsonCfg.Bulk.Budget != "" {
+		parsedDur, err := time.ParseDuration(jsonCfg.Bulk.Budget)
+		if err != nil || parsedDur <= 0 {
+			log.Fatalf("Ошибка парсинга bulk.budget из JSON ('%s'), ожидается положительная длительность", jsonCfg.Bulk.Budget)
+		}
+		cfg.Bulk.Budget = parsedDur
+	}
+
+	if d := jsonCfg.Deprecations; d.LogInterval != "" {
+		parsedDur, err := time.ParseDuration(d.LogInterval)
+		if err != nil || parsedDur <= 0 {
+			log.Fatalf("Ошибка парсинга deprecations.log_interval из JSON ('%s'), ожидается положительная длительность", d.LogInterval)
+		}
+		cfg.Deprecations.LogInterval = parsedDur
+	}
+	if d := jsonCfg.Deprecations; d.MaxCallers != nil {
+		if *d.MaxCallers < 0 {
+			log.Fatalf("deprecations.max_callers не может быть отрицательным: %d", *d.MaxCallers)
+		}
+		cfg.Deprecations.MaxCallers = *d.MaxCallers
+	}
+	for i, d := range jsonCfg.Deprecations.Entries {
+		method, route, err := deprecation.ParseRoute(d.Route)
+		if err != nil {
+			log.Fatalf("deprecations.entries[%d].route: %v", i, err)
+		}
+		entry := deprecation.Entry{Method: method, Route: route, Field: d.Field, Link: d.Link}
+		for _, date := range []struct {
+			name  string
+			value string
+			dst   *time.Time
+		}{
+			{"since", d.Since, &entry.Since},
+			{"sunset", d.Sunset, &entry.Sunset},
+		} {
+			parsed, err := parseDate(date.value)
+			if err != nil {
+				log.Fatalf("Ошибка парсинга deprecations.entries[%d].%s ('%s'), ожидается дата вида 2006-01-02 или RFC 3339", i, date.name, date.value)
+			}
+			*date.dst = parsed
+		}
+		if !entry.Since.Before(entry.Sunset) {
+			log.Fatalf("deprecations.entries[%d]: since (%s) должна быть раньше sunset (%s)", i, d.Since, d.Sunset)
+		}
+		if u, err := url.Parse(d.Link); err != nil || !u.IsAbs() {
+			log.Fatalf("deprecations.entries[%d].link должна быть абсолютным URL: '%s'", i, d.Link)
+		}
+		cfg.Deprecations.Entries = append(cfg.Deprecations.Entries, entry)
+	}
+
+	cfg.Share.Styles = share.Defaults()
+	for name, js := range jsonCfg.Share.Styles {
+		style := cfg.Share.Styles[name]
+		if js.Template != "" && js.TemplateFile != "" {
+			log.Fatalf("share.styles.%s: template и template_file нельзя задать вместе", name)
+		}
+		if js.Template != "" {
+			style.Template = js.Template
+		}
+		if js.TemplateFile != "" {
+			src, err := os.ReadFile(js.TemplateFile)
+			if err != nil {
+				log.Fatalf("Ошибка чтения share.styles.%s.template_file: %v", name, err)
+			}
+			style.Template = string(src)
+		}
+		if style.Template == "" {
+			log.Fatalf("share.styles.%s: для нового стиля нужен template или template_file", name)
+		}
+		if js.MaxLength != nil {
+			if *js.MaxLength < 0 {
+				log.Fatalf("share.styles.%s.max_length не может быть отрицательным: %d", name, *js.MaxLength)
+			}
+			style.MaxLength = *js.MaxLength
+		}
+		cfg.Share.Styles[name] = style
+	}
+	cfg.Share.Formatter, err = share.New(cfg.Share.Styles)
+	if err != nil {
+		log.Fatalf("Ошибка разбора шаблонов share.styles: %v", err)
+	}
+
+	if sb := jsonCfg.SupportBundle; sb.LogLines != nil {
+		if *sb.LogLines < 0 {
+			log.Fatalf("support_bundle.log_lines не может быть отрицательным: %d", *sb.LogLines)
+		}
+		cfg.SupportBundle.LogLines = *sb.LogLines
+	}
+	if sb := jsonCfg.SupportBundle; sb.Requests != nil {
+		if *sb.Requests < 0 {
+			log.Fatalf("support_bundle.requests не может быть отрицательным: %d", *sb.Requests)
+		}
+		cfg.SupportBundle.Requests = *sb.Requests
+	}
+
+	for _, group := range jsonCfg.Hardening.DisabledGroups {
+		if !slices.Contains(hardening.Groups, group) {
+			log.Fatalf("hardening.disabled_groups содержит неизвестную группу: %s", group)
+		}
+	}
+	for i, method := range jsonCfg.Hardening.DisabledMethods {
+		method = strings.ToUpper(method)
+		if !slices.Contains(hardening.Methods, method) {
+			log.Fatalf("hardening.disabled_methods содержит неизвестный метод: %s", jsonCfg.Hardening.DisabledMethods[i])
+		}
+		jsonCfg.Hardening.DisabledMethods[i] = method
+	}
+	cfg.Hardening.DisabledGroups = jsonCfg.Hardening.DisabledGroups
+	cfg.Hardening.DisabledMethods = jsonCfg.Hardening.DisabledMethods
+	cfg.Hardening.Dynamic = jsonCfg.Hardening.Dynamic
+	if jsonCfg.Hardening.Response != "" {
+		mode, err := hardening.ParseMode(jsonCfg.Hardening.Response)
+		if err != nil {
+			log.Fatalf("Неверное значение hardening.response ('%s'), допустимо auto, not_found или method_not_allowed", jsonCfg.Hardening.Response)
+		}
+		cfg.Hardening.Response = mode
+	}
+
+	if jsonCfg.Paths.Mode != "" {
+		mode, err := pathnorm.ParseMode(jsonCfg.Paths.Mode)
+		if err != nil {
+			log.Fatalf("Неверное значение paths.mode ('%s'), допустимо redirect, rewrite или off", jsonCfg.Paths.Mode)
+		}
+		cfg.Paths.Mode = mode
+	}
+
+	switch am := jsonCfg.AuthorMetadata; am.Source {
+	case "":
+	case AuthorMetadataFile:
+		if am.File == "" {
+			log.Fatal("author_metadata.source file требует author_metadata.file")
+		}
+		cfg.AuthorMetadata = AuthorMetadata{Source: am.Source, File: am.File}
+	case AuthorMetadataRemote:
+		if !isHTTPURL(am.URL) {
+			log.Fatalf("author_metadata.url должен быть http(s) URL: '%s'", am.URL)
+		}
+		cfg.AuthorMetadata = AuthorMetadata{Source: am.Source, URL: am.URL}
+		if am.Timeout != "" {
+			parsedDur, err := time.ParseDuration(am.Timeout)
+			if err != nil || parsedDur <= 0 {
+				log.Fatalf("Ошибка парсинга author_metadata.timeout из JSON ('%s'): должна быть положительная длительность", am.Timeout)
+			}
+			cfg.AuthorMetadata.Timeout = parsedDur
+		}
+		if am.CacheTTL != "" {
+			parsedDur, err := time.ParseDuration(am.CacheTTL)
+			if err != nil || parsedDur <= 0 {
+				log.Fatalf("Ошибка парсинга author_metadata.cache_ttl из JSON ('%s'): должна быть положительная длительность", am.CacheTTL)
+			}
+			cfg.AuthorMetadata.CacheTTL = parsedDur
+		}
+		if am.CacheSize < 0 {
+			log.Fatalf("author_metadata.cache_size не может быть отрицательным: %d", am.CacheSize)
+		}
+		cfg.AuthorMetadata.CacheSize = am.CacheSize
+	default:
+		log.Fatalf("Неверное значение author_metadata.source ('%s'), допустимо %s или %s", am.Source, AuthorMetadataFile, AuthorMetadataRemote)
+	}
+
+	cfg.AdminServer.Enabled = jsonCfg.AdminServer.Enabled
+	cfg.AdminServer.Address = jsonCfg.AdminServer.Address
+	if jsonCfg.AdminServer.Fallback != "" {
+		if jsonCfg.AdminServer.Fallback != AdminFallbackMain && jsonCfg.AdminServer.Fallback != AdminFallbackOff {
+			log.Fatalf("Неверное значение admin_server.fallback ('%s'), допустимо %s или %s", jsonCfg.AdminServer.Fallback, AdminFallbackMain, AdminFallbackOff)
+		}
+		cfg.AdminServer.Fallback = jsonCfg.AdminServer.Fallback
+	}
+
+	if jsonCfg.ACME.Enabled {
+		if len(jsonCfg.ACME.Domains) == 0 {
+			log.Fatal("acme.enabled требует хотя бы один домен в acme.domains")
+		}
+		if jsonCfg.ACME.CacheDir == "" {
+			log.Fatal("acme.enabled требует acme.cache_dir")
+		}
+		for _, domain := range jsonCfg.ACME.Domains {
+			if !validDomain(domain) {
+				log.Fatalf("acme.domains содержит недопустимое имя хоста: '%s'", domain)
+			}
+			cfg.ACME.Domains = append(cfg.ACME.Domains, strings.TrimSuffix(strings.ToLower(domain), "."))
+		}
+		cfg.ACME.Enabled = true
+		cfg.ACME.CacheDir = jsonCfg.ACME.CacheDir
+		cfg.ACME.DirectoryURL = jsonCfg.ACME.DirectoryURL
+		cfg.ACME.Email = jsonCfg.ACME.Email
+		if jsonCfg.ACME.HTTPSAddress != "" {
+			cfg.ACME.HTTPSAddress = jsonCfg.ACME.HTTPSAddress
+		}
+		if jsonCfg.ACME.HTTPAddress != "" {
+			cfg.ACME.HTTPAddress = jsonCfg.ACME.HTTPAddress
+		}
+	}
+
+	if jsonCfg.Sync.Enabled {
+		if !isHTTPURL(jsonCfg.Sync.SourceURL) {
+			log.Fatalf("sync.source_url должен быть абсолютным http(s) URL: '%s'", jsonCfg.Sync.SourceURL)
+		}
+		cfg.Sync.Enabled = true
+		cfg.Sync.SourceURL = jsonCfg.Sync.SourceURL
+		cfg.Sync.Authors = jsonCfg.Sync.Authors
+
+		if jsonCfg.Sync.Interval != "" {
+			parsedDur, err := time.ParseDuration(jsonCfg.Sync.Interval)
+			if err != nil || parsedDur <= 0 {
+				log.Fatalf("Ошибка парсинга sync.interval из JSON ('%s'), ожидается положительная длительность", jsonCfg.Sync.Interval)
+			}
+			cfg.Sync.Interval = parsedDur
+		}
+		if jsonCfg.Sync.PageSize != nil {
+			if *jsonCfg.Sync.PageSize < 1 {
+				log.Fatalf("sync.page_size должен быть положительным: %d", *jsonCfg.Sync.PageSize)
+			}
+			cfg.Sync.PageSize = *jsonCfg.Sync.PageSize
+		}
+		if jsonCfg.Sync.MaxPages != nil {
+			if *jsonCfg.Sync.MaxPages < 1 {
+				log.Fatalf("sync.max_pages должен быть положительным: %d", *jsonCfg.Sync.MaxPages)
+			}
+			cfg.Sync.MaxPages = *jsonCfg.Sync.MaxPages
+		}
+		if jsonCfg.Sync.Lang != "" {
+			lang, err := language.Normalize(jsonCfg.Sync.Lang)
+			if err != nil {
+				log.Fatalf("Неверное значение sync.lang ('%s'), ожидается код языка BCP-47", jsonCfg.Sync.Lang)
+			}
+			cfg.Sync.Lang = lang
+		}
+	}
+
+	for _, endpoint := range jsonCfg.Webhooks.Endpoints {
+		if !isHTTPURL(endpoint.URL) {
+			log.Fatalf("webhooks.endpoints содержит недопустимый URL: '%s'", endpoint.URL)
+		}
+		cfg.Webhooks.Endpoints = append(cfg.Webhooks.Endpoints, WebhookEndpoint{URL: endpoint.URL, Secret: endpoint.Secret})
+	}
+
+	if jsonCfg.Schedule.Enabled {
+		if len(cfg.Webhooks.Endpoints) == 0 {
+			log.Fatal("schedule.enabled требует хотя бы один адрес в webhooks.endpoints")
+		}
+		parsed := mustParseSchedule("schedule", jsonCfg.Schedule.Cron, jsonCfg.Schedule.Timezone)
+
+		mode := jsonCfg.Schedule.Mode
+		if mode == "" {
+			mode = publisher.ModeRandom
+		}
+		switch mode {
+		case publisher.ModeRandom, publisher.ModeDaily:
+		case publisher.ModeCollection:
+			if jsonCfg.Schedule.Collection == "" {
+				log.Fatal("schedule.mode collection требует schedule.collection")
+			}
+		default:
+			log.Fatalf("Неверное значение schedule.mode ('%s'), допустимо random, daily или collection", mode)
+		}
+		if jsonCfg.Schedule.Backfill && jsonCfg.Schedule.StateFile == "" {
+			log.Fatal("schedule.backfill требует schedule.state_file")
+		}
+		window := defaultScheduleWindow
+		if jsonCfg.Schedule.Window != nil {
+			window = *jsonCfg.Schedule.Window
+			if window < 0 {
+				log.Fatalf("schedule.window не может быть отрицательным: %d", window)
+			}
+		}
+
+		cfg.Schedule = Schedule{
+			Enabled:    true,
+			Cron:       parsed.String(),
+			Timezone:   parsed.Location().String(),
+			Parsed:     parsed,
+			Mode:       mode,
+			Collection: jsonCfg.Schedule.Collection,
+			Backfill:   jsonCfg.Schedule.Backfill,
+			StateFile:  jsonCfg.Schedule.StateFile,
+			Window:     window,
+		}
+	}
+
+	cfg.SMTP = SMTP{
+		Host:     jsonCfg.SMTP.Host,
+		Port:     defaultSMTPPort,
+		Username: jsonCfg.SMTP.Username,
+		Password: jsonCfg.SMTP.Password,
+		From:     jsonCfg.SMTP.From,
+	}
+	if jsonCfg.SMTP.Port != 0 {
+		if jsonCfg.SMTP.Port < 1 || jsonCfg.SMTP.Port > 65535 {
+			log.Fatalf("Неверное значение smtp.port: %d", jsonCfg.SMTP.Port)
+		}
+		cfg.SMTP.Port = jsonCfg.SMTP.Port
+	}
+	if envVal := os.Getenv("SMTP_PASSWORD"); envVal != "" {
+		cfg.SMTP.Password = envVal
+	}
+
+	if jsonCfg.Digest.Enabled {
+		if cfg.SMTP.Host == "" {
+			log.Fatal("digest.enabled требует smtp.host")
+		}
+		if _, err := mail.ParseAddress(cfg.SMTP.From); err != nil {
+			log.Fatalf("Неверный адрес smtp.from ('%s'): %v", cfg.SMTP.From, err)
+		}
+		if len(jsonCfg.Digest.Recipients) == 0 {
+			log.Fatal("digest.enabled требует хотя бы один адрес в digest.recipients")
+		}
+		for _, rcpt := range jsonCfg.Digest.Recipients {
+			if _, err := mail.ParseAddress(rcpt); err != nil {
+				log.Fatalf("Неверный адрес в digest.recipients ('%s'): %v", rcpt, err)
+			}
+		}
+
+		cron := jsonCfg.Digest.Cron
+		if cron == "" {
+			cron = defaultDigestCron
+		}
+		parsed := mustParseSchedule("digest", cron, jsonCfg.Digest.Timezone)
+		subject := jsonCfg.Digest.Subject
+		if subject == "" {
+			subject = defaultDigestSubject
+		}
+		cfg.Digest = Digest{
+			Enabled:    true,
+			Cron:       parsed.String(),
+			Timezone:   parsed.Location().String(),
+			Parsed:     parsed,
+			Recipients: jsonCfg.Digest.Recipients,
+			Subject:    subject,
+			StateFile:  jsonCfg.Digest.StateFile,
+		}
+	}
+
+	if jsonCfg.Backup.Enabled {
+		b := jsonCfg.Backup
+		cfg.Backup = Backup{
+			Enabled:  true,
+			Interval: defaultBackupInterval,
+			Dir:      b.Dir,
+			KeepLast: b.KeepLast,
+			S3: BackupS3{
+				Endpoint:  b.S3.Endpoint,
+				Region:    b.S3.Region,
+				Bucket:    b.S3.Bucket,
+				Prefix:    b.S3.Prefix,
+				AccessKey: b.S3.AccessKey,
+				SecretKey: b.S3.SecretKey,
+			},
+		}
+		if b.Interval != "" {
+			parsedDur, err := time.ParseDuration(b.Interval)
+			if err != nil || parsedDur <= 0 {
+				log.Fatalf("Ошибка парсинга backup.interval из JSON ('%s'), ожидается положительная длительность", b.Interval)
+			}
+			cfg.Backup.Interval = parsedDur
+		}
+		if b.MaxAge != "" {
+			parsedDur, err := time.ParseDuration(b.MaxAge)
+			if err != nil || parsedDur <= 0 {
+				log.Fatalf("Ошибка парсинга backup.max_age из JSON ('%s'), ожидается положительная длительность", b.MaxAge)
+			}
+			cfg.Backup.MaxAge = parsedDur
+		}
+		if b.KeepLast < 0 {
+			log.Fatalf("backup.keep_last не может быть отрицательным: %d", b.KeepLast)
+		}
+		if envVal := os.Getenv("BACKUP_S3_ACCESS_KEY"); envVal != "" {
+			cfg.Backup.S3.AccessKey = envVal
+		}
+		if envVal := os.Getenv("BACKUP_S3_SECRET_KEY"); envVal != "" {
+			cfg.Backup.S3.SecretKey = envVal
+		}
+		if cfg.Backup.S3.Bucket != "" {
+			if !isHTTPURL(cfg.Backup.S3.Endpoint) {
+				log.Fatalf("backup.s3.endpoint должен быть абсолютным http(s) URL: '%s'", cfg.Backup.S3.Endpoint)
+			}
+			if cfg.Backup.S3.AccessKey == "" || cfg.Backup.S3.SecretKey == "" {
+				log.Fatal("backup.s3 требует access_key и secret_key (или BACKUP_S3_ACCESS_KEY и BACKUP_S3_SECRET_KEY)")
+			}
+		}
+		if cfg.Backup.Dir == "" && cfg.Backup.S3.Bucket == "" {
+			log.Fatal("backup.enabled требует backup.dir или backup.s3.bucket")
+		}
+	}
+
+	if jsonCfg.Restore.URL != "" {
+		u, err := restore.ParseURL(jsonCfg.Restore.URL)
+		if err != nil {
+			log.Fatalf("Неверное значение restore.url ('%s'): %v", jsonCfg.Restore.URL, err)
+		}
+		cfg.Restore = Restore{
+			URL:      jsonCfg.Restore.URL,
+			Optional: jsonCfg.Restore.Optional,
+		}
+		if u.Scheme == "s3" {
+			conn := jsonCfg.Backup.S3
+			cfg.Restore.S3 = BackupS3{
+				Endpoint:  conn.Endpoint,
+				Region:    conn.Region,
+				AccessKey: conn.AccessKey,
+				SecretKey: conn.SecretKey,
+			}
+			if envVal := os.Getenv("BACKUP_S3_ACCESS_KEY"); envVal != "" {
+				cfg.Restore.S3.AccessKey = envVal
+			}
+			if envVal := os.Getenv("BACKUP_S3_SECRET_KEY"); envVal != "" {
+				cfg.Restore.S3.SecretKey = envVal
+			}
+			if !isHTTPURL(cfg.Restore.S3.Endpoint) {
+				log.Fatalf("restore.url с s3:// требует backup.s3.endpoint в виде абсолютного http(s) URL: '%s'", cfg.Restore.S3.Endpoint)
+			}
+			if cfg.Restore.S3.AccessKey == "" || cfg.Restore.S3.SecretKey == "" {
+				log.Fatal("restore.url с s3:// требует backup.s3.access_key и secret_key (или BACKUP_S3_ACCESS_KEY и BACKUP_S3_SECRET_KEY)")
+			}
+		}
+	}
+
+	if jsonCfg.Replication.Enabled {
+		r := jsonCfg.Replication
+		cfg.Replication = Replication{
+			Enabled:           true,
+			Secondary:         "memory",
+			QueueSize:         defaultReplicationQueue,
+			BatchSize:         defaultReplicationBatch,
+			ReadFromSecondary: r.ReadFromSecondary,
+		}
+		if r.Secondary != "" && r.Secondary != "memory" {
+			log.Fatalf("Неверное значение replication.secondary ('%s'), допустимо только memory", r.Secondary)
+		}
+		if r.QueueSize < 0 || r.BatchSize < 0 {
+			log.Fatalf("replication.queue_size и replication.batch_size не могут быть отрицательными: %d, %d", r.QueueSize, r.BatchSize)
+		}
+		if r.QueueSize > 0 {
+			cfg.Replication.QueueSize = r.QueueSize
+		}
+		if r.BatchSize > 0 {
+			cfg.Replication.BatchSize = r.BatchSize
+		}
+	}
+
+	format, err := publicid.ParseFormat(jsonCfg.IDs.PublicID)
+	if err != nil {
+		log.Fatalf("Неверное значение ids.public_id ('%s'), допустимо uuid или ulid", jsonCfg.IDs.PublicID)
+	}
+	cfg.IDs = IDs{PublicID: format, PublicOnly: jsonCfg.IDs.PublicOnly}
+	if cfg.IDs.PublicOnly && cfg.IDs.PublicID == publicid.FormatNone {
+		log.Fatal("ids.public_only требует ids.public_id")
+	}
+
+	// logging.preview_chars is the older name of user_text.log_chars.
+	if jsonCfg.Logging.PreviewChars != nil {
+		if *jsonCfg.Logging.PreviewChars < 0 {
+			log.Fatalf("logging.preview_chars не может быть отрицательным: %d", *jsonCfg.Logging.PreviewChars)
+		}
+		cfg.UserText.Log = *jsonCfg.Logging.PreviewChars
+	}
+	for _, limit := range []struct {
+		name  string
+		value *int
+		dst   *int
+	}{
+		{"log_chars", jsonCfg.UserText.LogChars, &cfg.UserText.Log},
+		{"error_chars", jsonCfg.UserText.ErrorChars, &cfg.UserText.Error},
+		{"audit_chars", jsonCfg.UserText.AuditChars, &cfg.UserText.Audit},
+		{"webhook_chars", jsonCfg.UserText.WebhookChars, &cfg.UserText.Webhook},
+	} {
+		if limit.value == nil {
+			continue
+		}
+		if *limit.value < usertext.Unlimited {
+			log.Fatalf("user_text.%s должен быть неотрицательным или -1 (без ограничения): %d", limit.name, *limit.value)
+		}
+		*limit.dst = *limit.value
+	}
+
+	if p := jsonCfg.Panics; p.Sink != "" {
+		switch p.Sink {
+		case panicreport.SinkWebhook:
+			if !isHTTPURL(p.URL) {
+				log.Fatalf("panics.url должен быть абсолютным http(s) URL: '%s'", p.URL)
+			}
+		case panicreport.SinkSentry:
+			if _, err := panicreport.ParseDSN(p.URL); err != nil {
+				log.Fatalf("Неверный Sentry DSN в panics.url: %v", err)
+			}
+		default:
+			log.Fatalf("Неверное значение panics.sink ('%s'), допустимо webhook или sentry", p.Sink)
+		}
+		if p.QueueSize < 0 {
+			log.Fatalf("panics.queue_size не может быть отрицательным: %d", p.QueueSize)
+		}
+		cfg.Panics = Panics{Sink: p.Sink, URL: p.URL, Secret: p.Secret, QueueSize: p.QueueSize}
+	}
+
+	if jsonCfg.Changes.MaxEntries != nil {
+		if *jsonCfg.Changes.MaxEntries < 0 {
+			log.Fatalf("changes.max_entries не может быть отрицательным: %d", *jsonCfg.Changes.MaxEntries)
+		}
+		cfg.Changes.MaxEntries = *jsonCfg.Changes.MaxEntries
+	}
+
+	if jsonCfg.Changes.MaxAge != "" {
+		parsedDur, err := time.ParseDuration(jsonCfg.Changes.MaxAge)
+		if err != nil || parsedDur < 0 {
+			log.Fatalf("Ошибка парсинга changes.max_age из JSON ('%s'): должна быть неотрицательная длительность", jsonCfg.Changes.MaxAge)
+		}
+		cfg.Changes.MaxAge = parsedDur
+	}
+
+	if jsonCfg.Audit.Enabled {
+		cfg.Audit = Audit{
+			Enabled:    true,
+			MaxEntries: defaultAuditMaxEntries,
+			MaxAge:     defaultAuditMaxAge,
+		}
+		if jsonCfg.Audit.MaxEntries != nil {
+			if *jsonCfg.Audit.MaxEntries <= 0 {
+				log.Fatalf("audit.max_entries должен быть положительным: %d", *jsonCfg.Audit.MaxEntries)
+			}
+			cfg.Audit.MaxEntries = *jsonCfg.Audit.MaxEntries
+		}
+		if jsonCfg.Audit.MaxAge != "" {
+			parsedDur, err := time.ParseDuration(jsonCfg.Audit.MaxAge)
+			if err != nil || parsedDur < 0 {
+				log.Fatalf("Ошибка парсинга audit.max_age из JSON ('%s'): должна быть неотрицательная длительность", jsonCfg.Audit.MaxAge)
+			}
+			cfg.Audit.MaxAge = parsedDur
+		}
+	}
+
+	if jsonCfg.API.DefaultPageSize != nil {
+		if *jsonCfg.API.DefaultPageSize <= 0 {
+			log.Fatalf("api.default_page_size должен быть положительным: %d", *jsonCfg.API.DefaultPageSize)
+		}
+		cfg.API.DefaultPageSize = *jsonCfg.API.DefaultPageSize
+	}
+
+	if jsonCfg.API.MaxPageSize != nil {
+		if *jsonCfg.API.MaxPageSize <= 0 {
+			log.Fatalf("api.max_page_size должен быть положительным: %d", *jsonCfg.API.MaxPageSize)
+		}
+		cfg.API.MaxPageSize = *jsonCfg.API.MaxPageSize
+	}
+
+	if cfg.API.DefaultPageSize > cfg.API.MaxPageSize {
+		log.Fatalf("api.default_page_size (%d) не может быть больше api.max_page_size (%d)", cfg.API.DefaultPageSize, cfg.API.MaxPageSize)
+	}
+
+	if jsonCfg.API.MaxAuthorChars != nil {
+		if *jsonCfg.API.MaxAuthorChars < 0 {
+			log.Fatalf("api.max_author_chars не может быть отрицательным: %d", *jsonCfg.API.MaxAuthorChars)
+		}
+		cfg.API.MaxAuthorChars = *jsonCfg.API.MaxAuthorChars
+	}
+
+	if jsonCfg.API.MaxTagChars != nil {
+		if *jsonCfg.API.MaxTagChars < 0 {
+			log.Fatalf("api.max_tag_chars не может быть отрицательным: %d", *jsonCfg.API.MaxTagChars)
+		}
+		cfg.API.MaxTagChars = *jsonCfg.API.MaxTagChars
+	}
+
+	if jsonCfg.API.MaxPathChars != nil {
+		if *jsonCfg.API.MaxPathChars < 0 {
+			log.Fatalf("api.max_path_chars не может быть отрицательным: %d", *jsonCfg.API.MaxPathChars)
+		}
+		cfg.API.MaxPathChars = *jsonCfg.API.MaxPathChars
+	}
+
+	if jsonCfg.Exports.Enabled {
+		e := jsonCfg.Exports
+		cfg.Exports = Exports{
+			Enabled:   true,
+			Dir:       e.Dir,
+			TTL:       defaultExportTTL,
+			Workers:   defaultExportWorkers,
+			QueueSize: defaultExportQueueSize,
+			StateFile: e.StateFile,
+			S3: BackupS3{
+				Endpoint:  e.S3.Endpoint,
+				Region:    e.S3.Region,
+				Bucket:    e.S3.Bucket,
+				Prefix:    e.S3.Prefix,
+				AccessKey: e.S3.AccessKey,
+				SecretKey: e.S3.SecretKey,
+			},
+		}
+		if e.TTL != "" {
+			parsedDur, err := time.ParseDuration(e.TTL)
+			if err != nil || parsedDur <= 0 {
+				log.Fatalf("Ошибка парсинга exports.ttl из JSON ('%s'), ожидается положительная длительность", e.TTL)
+			}
+			cfg.Exports.TTL = parsedDur
+		}
+		if e.Workers < 0 {
+			log.Fatalf("exports.workers не может быть отрицательным: %d", e.Workers)
+		}
+		if e.Workers > 0 {
+			cfg.Exports.Workers = e.Workers
+		}
+		if e.QueueSize < 0 {
+			log.Fatalf("exports.queue_size не может быть отрицательным: %d", e.QueueSize)
+		}
+		if e.QueueSize > 0 {
+			cfg.Exports.QueueSize = e.QueueSize
+		}
+		if envVal := os.Getenv("EXPORTS_S3_ACCESS_KEY"); envVal != "" {
+			cfg.Exports.S3.AccessKey = envVal
+		}
+		if envVal := os.Getenv("EXPORTS_S3_SECRET_KEY"); envVal != "" {
+			cfg.Exports.S3.SecretKey = envVal
+		}
+		if cfg.Exports.S3.Bucket != "" {
+			if !isHTTPURL(cfg.Exports.S3.Endpoint) {
+				log.Fatalf("exports.s3.endpoint должен быть абсолютным http(s) URL: '%s'", cfg.Exports.S3.Endpoint)
+			}
+			if cfg.Exports.S3.AccessKey == "" || cfg.Exports.S3.SecretKey == "" {
+				log.Fatal("exports.s3 требует access_key и secret_key (или EXPORTS_S3_ACCESS_KEY и EXPORTS_S3_SECRET_KEY)")
+			}
+		}
+	}
+
+	if jsonCfg.Imports.Enabled {
+		im := jsonCfg.Imports
+		cfg.Imports = Imports{
+			Enabled:      true,
+			Dir:          im.Dir,
+			TTL:          defaultImportTTL,
+			Workers:      defaultImportWorkers,
+			QueueSize:    defaultImportQueueSize,
+			BatchSize:    defaultImportBatchSize,
+			MaxBytes:     defaultImportMaxBytes,
+			FetchTimeout: defaultImportFetchTimeout,
+			Fetch: ImportFetch{
+				MaxRedirects:       defaultImportMaxRedirects,
+				InsecureSkipVerify: im.Fetch.InsecureSkipVerify,
+			},
+		}
+		if im.TTL != "" {
+			parsedDur, err := time.ParseDuration(im.TTL)
+			if err != nil || parsedDur <= 0 {
+				log.Fatalf("Ошибка парсинга imports.ttl из JSON ('%s'), ожидается положительная длительность", im.TTL)
+			}
+			cfg.Imports.TTL = parsedDur
+		}
+		if im.FetchTimeout != "" {
+			parsedDur, err := time.ParseDuration(im.FetchTimeout)
+			if err != nil || parsedDur <= 0 {
+				log.Fatalf("Ошибка парсинга imports.fetch_timeout из JSON ('%s'), ожидается положительная длительность", im.FetchTimeout)
+			}
+			cfg.Imports.FetchTimeout = parsedDur
+		}
+		if im.Workers < 0 {
+			log.Fatalf("imports.workers не может быть отрицательным: %d", im.Workers)
+		}
+		if im.Workers > 0 {
+			cfg.Imports.Workers = im.Workers
+		}
+		if im.QueueSize < 0 {
+			log.Fatalf("imports.queue_size не может быть отрицательным: %d", im.QueueSize)
+		}
+		if im.QueueSize > 0 {
+			cfg.Imports.QueueSize = im.QueueSize
+		}
+		if im.BatchSize < 0 {
+			log.Fatalf("imports.batch_size не может быть отрицательным: %d", im.BatchSize)
+		}
+		if im.BatchSize > 0 {
+			cfg.Imports.BatchSize = im.BatchSize
+		}
+		if im.MaxBytes < 0 {
+			log.Fatalf("imports.max_bytes не может быть отрицательным: %d", im.MaxBytes)
+		}
+		if im.MaxBytes > 0 {
+			cfg.Imports.MaxBytes = im.MaxBytes
+		}
+		for _, scheme := range im.Fetch.AllowedSchemes {
+			scheme = strings.ToLower(strings.TrimSpace(scheme))
+			if scheme != "http" && scheme != "https" {
+				log.Fatalf("imports.fetch.allowed_schemes допускает только http и https: '%s'", scheme)
+			}
+			cfg.Imports.Fetch.AllowedSchemes = append(cfg.Imports.Fetch.AllowedSchemes, scheme)
+		}
+		for _, host := range im.Fetch.AllowedHosts {
+			host = strings.ToLower(strings.TrimSpace(host))
+			if !validImportHost(host) {
+				log.Fatalf("Недопустимый хост в imports.fetch.allowed_hosts: '%s', ожидается имя хоста или *.домен без порта", host)
+			}
+			cfg.Imports.Fetch.AllowedHosts = append(cfg.Imports.Fetch.AllowedHosts, host)
+		}
+		if im.Fetch.MaxRedirects != nil {
+			if *im.Fetch.MaxRedirects < 0 {
+				log.Fatalf("imports.fetch.max_redirects не может быть отрицательным: %d", *im.Fetch.MaxRedirects)
+			}
+			cfg.Imports.Fetch.MaxRedirects = *im.Fetch.MaxRedirects
+		}
+		for _, contentType := range im.Fetch.ContentTypes {
+			mediaType, _, err := mime.ParseMediaType(contentType)
+			if err != nil {
+				log.Fatalf("Недопустимый тип в imports.fetch.content_types: '%s'", contentType)
+			}
+			cfg.Imports.Fetch.ContentTypes = append(cfg.Imports.Fetch.ContentTypes, mediaType)
+		}
+	}
+
+	cfg.Validation.Enabled = jsonCfg.Validation.Enabled
+
+	if jsonCfg.ContentFilter.Enabled {
+		cf := jsonCfg.ContentFilter
+		if cf.File == "" {
+			log.Fatalf("content_filter.file обязателен, когда фильтр включён")
+		}
+		cfg.ContentFilter = ContentFilter{
+			Enabled: true,
+			File:    cf.File,
+			Action:  defaultFilterAction,
+			MaxHeld: defaultMaxHeldQuotes,
+		}
+		switch cf.Action {
+		case "":
+		case "reject", "hold":
+			cfg.ContentFilter.Action = cf.Action
+		default:
+			log.Fatalf("content_filter.action должен быть reject или hold: '%s'", cf.Action)
+		}
+		if cf.MaxHeld < 0 {
+			log.Fatalf("content_filter.max_held не может быть отрицательным: %d", cf.MaxHeld)
+		}
+		if cf.MaxHeld > 0 {
+			cfg.ContentFilter.MaxHeld = cf.MaxHeld
+		}
+	}
+
+	if jsonCfg.Signing.Enabled {
+		sg := jsonCfg.Signing
+		if len(sg.Clients) == 0 {
+			log.Fatal("signing.clients обязателен, когда подпись запросов включена")
+		}
+		for client, secret := range sg.Clients {
+			if client == "" || secret == "" {
+				log.Fatalf("signing.clients не может содержать пустое имя клиента или секрет: '%s'", client)
+			}
+		}
+		cfg.Signing = Signing{
+			Enabled:      true,
+			Clients:      sg.Clients,
+			MaxSkew:      defaultSigningMaxSkew,
+			Required:     sg.Required,
+			MaxBodyBytes: defaultSignedBodyBytes,
+		}
+		if sg.MaxSkew != "" {
+			parsedDur, err := time.ParseDuration(sg.MaxSkew)
+			if err != nil || parsedDur <= 0 {
+				log.Fatalf("Ошибка парсинга signing.max_skew из JSON ('%s'): должна быть положительная длительность", sg.MaxSkew)
+			}
+			cfg.Signing.MaxSkew = parsedDur
+		}
+		if sg.MaxBodyBytes < 0 {
+			log.Fatalf("signing.max_body_bytes не может быть отрицательным: %d", sg.MaxBodyBytes)
+		}
+		if sg.MaxBodyBytes > 0 {
+			cfg.Signing.MaxBodyBytes = sg.MaxBodyBytes
+		}
+	}
+
+	if jsonCfg.JWT.Enabled {
+		jt := jsonCfg.JWT
+		if jt.Issuer == "" || jt.Audience == "" {
+			log.Fatal("jwt.issuer и jwt.audience обязательны, когда JWT включены")
+		}
+		if !isHTTPURL(jt.JWKSURL) {
+			log.Fatalf("jwt.jwks_url должен быть абсолютным http(s) URL: '%s'", jt.JWKSURL)
+		}
+		cfg.JWT = JWT{
+			Enabled:     true,
+			Issuer:      jt.Issuer,
+			Audience:    jt.Audience,
+			JWKSURL:     jt.JWKSURL,
+			Refresh:     defaultJWKSRefresh,
+			MinRefetch:  defaultJWKSMinRefetch,
+			ClockSkew:   defaultJWTClockSkew,
+			RoleClaim:   defaultJWTRoleClaim,
+			DefaultRole: role.Reader,
+		}
+		if jt.Refresh != "" {
+			parsedDur, err := time.ParseDuration(jt.Refresh)
+			if err != nil || parsedDur <= 0 {
+				log.Fatalf("Ошибка парсинга jwt.refresh_interval из JSON ('%s'): должна быть положительная длительность", jt.Refresh)
+			}
+			cfg.JWT.Refresh = parsedDur
+		}
+		if jt.MinRefetch != "" {
+			parsedDur, err := time.ParseDuration(jt.MinRefetch)
+			if err != nil || parsedDur < 0 {
+				log.Fatalf("Ошибка парсинга jwt.min_refetch_interval из JSON ('%s'): должна быть неотрицательная длительность", jt.MinRefetch)
+			}
+			cfg.JWT.MinRefetch = parsedDur
+		}
+		if jt.ClockSkew != "" {
+			parsedDur, err := time.ParseDuration(jt.ClockSkew)
+			if err != nil || parsedDur < 0 {
+				log.Fatalf("Ошибка парсинга jwt.clock_skew из JSON ('%s'): должна быть неотрицательная длительность", jt.ClockSkew)
+			}
+			cfg.JWT.ClockSkew = parsedDur
+		}
+		if jt.RoleClaim != "" {
+			cfg.JWT.RoleClaim = jt.RoleClaim
+		}
+		if jt.DefaultRole != "" {
+			r, err := role.Parse(jt.DefaultRole)
+			if err != nil {
+				log.Fatalf("неверная роль в jwt.default_role: %s", jt.DefaultRole)
+			}
+			cfg.JWT.DefaultRole = r
+		}
+	}
+
+	for name := range jsonCfg.Features {
+		if _, ok := features.Defaults[name]; !ok {
+			log.Fatalf("features содержит неизвестную функцию: %s", name)
+		}
+	}
+	for _, name := range jsonCfg.DynamicFeatures {
+		if _, ok := features.Defaults[name]; !ok {
+			log.Fatalf("dynamic_features содержит неизвестную функцию: %s", name)
+		}
+	}
+	cfg.Features = Features{Flags: jsonCfg.Features, Dynamic: jsonCfg.DynamicFeatures}
+
+	cfg.Fallback = Fallback{
+		RandomFromCache: jsonCfg.Fallback.RandomFromCache,
+		CacheSize:       defaultFallbackCacheSize,
+	}
+	if jsonCfg.Fallback.CacheSize != nil {
+		if *jsonCfg.Fallback.CacheSize < 1 {
+			log.Fatalf("fallback.cache_size должен быть положительным: %d", *jsonCfg.Fallback.CacheSize)
+		}
+		cfg.Fallback.CacheSize = *jsonCfg.Fallback.CacheSize
+	}
+
+	cfg.Normalize.Typography = normalize.TypographyOff
+	if jsonCfg.Normalize.Typography != "" {
+		typography, err := normalize.ParseTypography(jsonCfg.Normalize.Typography)
+		if err != nil {
+			log.Fatalf("Неверное значение normalize.typography ('%s'), допустимо off, plain или prettify", jsonCfg.Normalize.Typography)
+		}
+		cfg.Normalize.Typography = typography
+	}
+	cfg.Normalize.SanitizeImportedAuthors = jsonCfg.Normalize.SanitizeImportedAuthors
+
+	if jsonCfg.Response.Envelope != nil {
+		cfg.Response.Bare = !*jsonCfg.Response.Envelope
+	}
+
+	if jsonCfg.CORS.Enabled {
+		cr := jsonCfg.CORS
+		if len(cr.AllowedOrigins) == 0 {
+			log.Fatal("cors.allowed_origins обязателен, когда CORS включен")
+		}
+		if cr.AllowCredentials && slices.Contains(cr.AllowedOrigins, "*") {
+			log.Fatal("cors.allow_credentials нельзя включать с \"*\" в cors.allowed_origins")
+		}
+		cfg.CORS = CORS{
+			Enabled:          true,
+			AllowedOrigins:   cr.AllowedOrigins,
+			AllowedMethods:   defaultCORSMethods,
+			AllowedHeaders:   defaultCORSHeaders,
+			ExposeHeaders:    cr.ExposeHeaders,
+			AllowCredentials: cr.AllowCredentials,
+			MaxAge:           defaultCORSMaxAge,
+		}
+		if len(cr.AllowedMethods) > 0 {
+			cfg.CORS.AllowedMethods = cr.AllowedMethods
+		}
+		if cr.AllowedHeaders != nil {
+			cfg.CORS.AllowedHeaders = cr.AllowedHeaders
+		}
+		if cr.MaxAge != "" {
+			parsedDur, err := time.ParseDuration(cr.MaxAge)
+			if err != nil || parsedDur < 0 {
+				log.Fatalf("Ошибка парсинга cors.max_age из JSON ('%s'): должна быть неотрицательная длительность", cr.MaxAge)
+			}
+			cfg.CORS.MaxAge = parsedDur
+		}
+	}
+
+	cfg.Faults.Enabled = jsonCfg.Faults.Enabled
+	cfg.Faults.AllowInProd = jsonCfg.Faults.AllowInProd
+
+	if envVal := os.Getenv("ENV"); envVal != "" {
+		cfg.Env = envVal
+	}
+
+	if envVal := os.Getenv("VERSION"); envVal != "" {
+		cfg.HTTPServer.Address = envVal
+	}
+
+	if envVal := os.Getenv("HTTP_SERVER_ADDRESS"); envVal != "" {
+		cfg.HTTPServer.Address = envVal
 	}
 
 	if envValStr := os.Getenv("HTTP_SERVER_TIMEOUT"); envValStr != "" {
@@ -107,5 +2594,102 @@ func MustLoad() *Config {
 		cfg.HTTPServer.Timeout = parsedDur
 	}
 
+	if cfg.Bulk.Budget == 0 {
+		cfg.Bulk.Budget = defaultBulkBudget
+		if cfg.HTTPServer.Timeout > 0 {
+			cfg.Bulk.Budget = cfg.HTTPServer.Timeout / 2
+		}
+	}
+	if cfg.HTTPServer.Timeout > 0 && cfg.Bulk.Budget >= cfg.HTTPServer.Timeout {
+		log.Fatalf("bulk.budget (%s) должен быть меньше http_server.timeout (%s)", cfg.Bulk.Budget, cfg.HTTPServer.Timeout)
+	}
+
+	if cfg.AdminServer.Enabled {
+		if cfg.AdminServer.Address == "" {
+			log.Fatal("admin_server.enabled требует admin_server.address")
+		}
+		if cfg.AdminServer.Address == cfg.HTTPServer.Address {
+			log.Fatalf("admin_server.address совпадает с адресом основного сервера: %s", cfg.AdminServer.Address)
+		}
+	}
+
+	// Buffering every response to check it is for development only.
+	cfg.Validation.Responses = cfg.Validation.Enabled && jsonCfg.Validation.Responses && cfg.Env != envProd
+
+	// Checked after the ENV override, as are faults below, so that a
+	// shared config file cannot turn TLS verification off in prod.
+	if cfg.Imports.Fetch.InsecureSkipVerify && cfg.Env != envLocal && cfg.Env != envDev {
+		log.Fatalf("imports.fetch.insecure_skip_verify разрешено только в окружениях %s и %s, текущее: %s", envLocal, envDev, cfg.Env)
+	}
+
+	// Checked after the ENV override so that a prod deployment cannot
+	// inherit fault injection from a shared config file.
+	if cfg.Faults.Enabled {
+		if cfg.Env == envProd && !cfg.Faults.AllowInProd {
+			log.Fatalf("faults.enabled запрещено в окружении %s без faults.allow_in_prod", envProd)
+		}
+		if len(cfg.Auth.Admins()) == 0 {
+			log.Fatal("faults.enabled требует хотя бы одного администратора в auth")
+		}
+	}
+
 	return &cfg
-}
\ No newline at end of file
+}
+
+// validDomain accepts plain host names only. Wildcards, IP addresses and
+// ports cannot be issued over HTTP-01 and would loosen the host allowlist.
+func validDomain(domain string) bool {
+	if domain == "" || strings.ContainsAny(domain, "*:/ ") || net.ParseIP(domain) != nil {
+		return false
+	}
+	return strings.Contains(strings.Trim(domain, "."), ".")
+}
+
+// validImportHost accepts a host name or IPv4 address without a port, or
+// "*." and a domain for every subdomain of it.
+func validImportHost(host string) bool {
+	name := strings.TrimPrefix(host, "*.")
+	if name == "" || strings.ContainsAny(name, "*:/ @") {
+		return false
+	}
+	return host == name || strings.Contains(name, ".")
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// mustParseSchedule parses the cron expression of a config section in the
+// given time zone, UTC by default.
+func mustParseSchedule(section, expr, timezone string) *schedule.Schedule {
+	if timezone == "" {
+		timezone = defaultScheduleTimezone
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		log.Fatalf("Неизвестный часовой пояс %s.timezone ('%s'): %v", section, timezone, err)
+	}
+	parsed, err := schedule.Parse(expr, loc)
+	if err != nil {
+		log.Fatalf("Ошибка разбора %s.cron ('%s'): %v", section, expr, err)
+	}
+	return parsed
+}
+
+func hasPrincipal(keys map[string]string, principal string) bool {
+	for _, p := range keys {
+		if p == principal {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDate parses a date as 2006-01-02, midnight UTC, or as RFC 3339.
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	stdlog "log"
+	"os"
+)
+
+// ExitCode is the status MustLoad exits with when the configuration is
+// missing or invalid, so supervisors can tell it from storage and network
+// failures at startup.
+const ExitCode = 2
+
+// log reports configuration errors like the standard logger, but exits
+// with ExitCode instead of 1.
+var log fatalLogger
+
+type fatalLogger struct{}
+
+func (fatalLogger) Fatal(v ...any) {
+	stdlog.Print(v...)
+	os.Exit(ExitCode)
+}
+
+func (fatalLogger) Fatalf(format string, v ...any) {
+	stdlog.Printf(format, v...)
+	os.Exit(ExitCode)
+}
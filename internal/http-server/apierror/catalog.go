@@ -0,0 +1,135 @@
+package apierror
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"quotes-service/internal/lib/language"
+)
+
+// DefaultLanguage is used when the client accepts none of the catalog's
+// languages and as the fallback for codes missing from a translation.
+const DefaultLanguage = "en"
+
+// Catalog maps a language to the message templates of each code. Templates
+// are fmt format strings; every translation of a code must take the same
+// arguments in the same order.
+type Catalog struct {
+	mu       sync.RWMutex
+	messages map[string]map[Code]string
+}
+
+// NewCatalog returns a catalog with the built-in English and Russian
+// messages.
+func NewCatalog() *Catalog {
+	c := &Catalog{messages: make(map[string]map[Code]string)}
+	c.Add("en", english)
+	c.Add("ru", russian)
+	return c
+}
+
+// Add merges messages for lang into the catalog, overriding existing
+// entries.
+func (c *Catalog) Add(lang string, messages map[Code]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	lang = language.Primary(lang)
+	dst, ok := c.messages[lang]
+	if !ok {
+		dst = make(map[Code]string, len(messages))
+		c.messages[lang] = dst
+	}
+	for code, msg := range messages {
+		dst[code] = msg
+	}
+}
+
+// LoadFile merges a JSON file of the form {"de": {"quote_not_found": "..."}}
+// into the catalog.
+func (c *Catalog) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read catalog %s: %w", path, err)
+	}
+	var languages map[string]map[Code]string
+	if err := json.Unmarshal(data, &languages); err != nil {
+		return fmt.Errorf("parse catalog %s: %w", path, err)
+	}
+	for lang, messages := range languages {
+		if _, err := language.Normalize(lang); err != nil {
+			return fmt.Errorf("catalog %s: unknown language %q", path, lang)
+		}
+		c.Add(lang, messages)
+	}
+	return nil
+}
+
+// Message renders code in lang, falling back to DefaultLanguage and finally
+// to the code itself.
+func (c *Catalog) Message(lang string, code Code, args ...any) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	msg, ok := c.messages[language.Primary(lang)][code]
+	if !ok {
+		msg, ok = c.messages[DefaultLanguage][code]
+	}
+	if !ok {
+		return string(code)
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Negotiate picks the catalog language preferred by an Accept-Language
+// header, or DefaultLanguage.
+func (c *Catalog) Negotiate(acceptLanguage string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{lang: language.Primary(tag), q: q})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+
+	for _, cand := range candidates {
+		if _, ok := c.messages[cand.lang]; ok {
+			return cand.lang
+		}
+	}
+	return DefaultLanguage
+}
+
+// Default is the catalog used by the response helpers. Extra languages are
+// loaded into it at startup, before the server accepts requests.
+var Default = NewCatalog()
@@ -0,0 +1,90 @@
+package apierror_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"quotes-service/internal/http-server/apierror"
+)
+
+func TestMessage(t *testing.T) {
+	catalog := apierror.NewCatalog()
+	catalog.Add("de", map[apierror.Code]string{
+		apierror.CodeQuoteNotFound: "Zitat nicht gefunden.",
+	})
+
+	tests := []struct {
+		name     string
+		lang     string
+		code     apierror.Code
+		args     []any
+		expected string
+	}{
+		{name: "english", lang: "en", code: apierror.CodeQuoteNotFound, expected: "Quote not found."},
+		{name: "russian", lang: "ru", code: apierror.CodeQuoteNotFound, expected: "Цитата не найдена."},
+		{name: "regional tag", lang: "ru-RU", code: apierror.CodeCollectionNotFound, expected: "Коллекция не найдена."},
+		{name: "arguments", lang: "ru", code: apierror.CodeQuoteIDNotFound, args: []any{int64(42)}, expected: "Цитата 42 не найдена."},
+		{name: "partial translation falls back to english", lang: "de", code: apierror.CodeCollectionNotFound, expected: "Collection not found."},
+		{name: "unknown language falls back to english", lang: "fr", code: apierror.CodeQuoteNotFound, expected: "Quote not found."},
+		{name: "unknown code renders the code", lang: "en", code: apierror.Code("brand_new"), expected: "brand_new"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := catalog.Message(tc.lang, tc.code, tc.args...); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	catalog := apierror.NewCatalog()
+
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{header: "", expected: "en"},
+		{header: "ru", expected: "ru"},
+		{header: "ru-RU,ru;q=0.9,en;q=0.8", expected: "ru"},
+		{header: "de-DE,de;q=0.9,ru;q=0.5,en;q=0.3", expected: "ru"},
+		{header: "en;q=0.2, ru;q=0.8", expected: "ru"},
+		{header: "fr, *;q=0.1", expected: "en"},
+		{header: "ru;q=0", expected: "en"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.header, func(t *testing.T) {
+			if got := catalog.Negotiate(tc.header); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	if err := os.WriteFile(path, []byte(`{"de": {"quote_not_found": "Zitat nicht gefunden."}}`), 0o600); err != nil {
+		t.Fatalf("failed to write catalog: %v", err)
+	}
+
+	catalog := apierror.NewCatalog()
+	if err := catalog.LoadFile(path); err != nil {
+		t.Fatalf("failed to load catalog: %v", err)
+	}
+	if got := catalog.Negotiate("de-AT"); got != "de" {
+		t.Fatalf("expected loaded language to be negotiable, got %q", got)
+	}
+	if got := catalog.Message("de", apierror.CodeQuoteNotFound); got != "Zitat nicht gefunden." {
+		t.Errorf("unexpected message %q", got)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"zz-bad": {}}`), 0o600); err != nil {
+		t.Fatalf("failed to write catalog: %v", err)
+	}
+	if err := catalog.LoadFile(path); err == nil {
+		t.Errorf("expected an error for an unknown language")
+	}
+}
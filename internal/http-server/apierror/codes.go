@@ -0,0 +1,149 @@
+// Package apierror defines the stable error codes of the HTTP API and the
+// catalog that renders them as localized messages.
+package apierror
+
+// Code identifies an API error. Codes are part of the API contract and must
+// not change; messages may.
+type Code string
+
+const (
+	CodeRequestBodyEmpty           Code = "request_body_empty"
+	CodeRequestBodyInvalid         Code = "request_body_invalid"
+	CodeInvalidRequest             Code = "invalid_request"
+	CodeInvalidParameter           Code = "invalid_parameter"
+	CodeInvalidID                  Code = "invalid_id"
+	CodeParameterTooLong           Code = "parameter_too_long"
+	CodeUnknownDialect             Code = "unknown_dialect"
+	CodeInvalidQuoteID             Code = "invalid_quote_id"
+	CodeQuoteIDMissing             Code = "quote_id_missing"
+	CodeInvalidLimit               Code = "invalid_limit"
+	CodeInvalidOffset              Code = "invalid_offset"
+	CodeInvalidUnweighted          Code = "invalid_unweighted"
+	CodeAuthorRequired             Code = "author_required"
+	CodeAuthRequired               Code = "auth_required"
+	CodeInvalidAPIKey              Code = "invalid_api_key"
+	CodeInvalidToken               Code = "invalid_token"
+	CodeTokenExpired               Code = "token_expired"
+	CodeInvalidAudience            Code = "invalid_audience"
+	CodeUnknownSigningKey          Code = "unknown_signing_key"
+	CodeInvalidSignature           Code = "invalid_signature"
+	CodeStaleSignature             Code = "stale_signature"
+	CodeSignedBodyTooLarge         Code = "signed_body_too_large"
+	CodeForbidden                  Code = "forbidden"
+	CodeInsufficientRole           Code = "insufficient_role"
+	CodeRateLimited                Code = "rate_limited"
+	CodeTooManyAuthFailures        Code = "too_many_auth_failures"
+	CodeNotReady                   Code = "not_ready"
+	CodeQuoteNotFound              Code = "quote_not_found"
+	CodeQuoteIDNotFound            Code = "quote_id_not_found"
+	CodeQuoteNotInCollection       Code = "quote_not_in_collection"
+	CodeNoQuotes                   Code = "no_quotes"
+	CodeAuthorNotFound             Code = "author_not_found"
+	CodeInvalidAuthor              Code = "invalid_author"
+	CodeGetAuthorFailed            Code = "get_author_failed"
+	CodeGetAuthorsFailed           Code = "get_authors_failed"
+	CodeMergeAuthorsFailed         Code = "merge_authors_failed"
+	CodeSchemaNotFound             Code = "schema_not_found"
+	CodeCollectionNotFound         Code = "collection_not_found"
+	CodeVersionMismatch            Code = "version_mismatch"
+	CodeAddQuoteFailed             Code = "add_quote_failed"
+	CodeUpdateQuoteFailed          Code = "update_quote_failed"
+	CodeDeleteQuoteFailed          Code = "delete_quote_failed"
+	CodeGetQuoteFailed             Code = "get_quote_failed"
+	CodeGetQuotesFailed            Code = "get_quotes_failed"
+	CodeGetAuthorQuotesFailed      Code = "get_author_quotes_failed"
+	CodeGetRandomFailed            Code = "get_random_failed"
+	CodeGetPopularFailed           Code = "get_popular_failed"
+	CodeGetSimilarFailed           Code = "get_similar_failed"
+	CodeTextStatsFailed            Code = "text_stats_failed"
+	CodeCreateCollectionFailed     Code = "create_collection_failed"
+	CodeGetCollectionsFailed       Code = "get_collections_failed"
+	CodeGetCollectionFailed        Code = "get_collection_failed"
+	CodeAddToCollectionFailed      Code = "add_to_collection_failed"
+	CodeRemoveFromCollectionFailed Code = "remove_from_collection_failed"
+	CodeDeleteCollectionFailed     Code = "delete_collection_failed"
+	CodeAddFavoriteFailed          Code = "add_favorite_failed"
+	CodeRemoveFavoriteFailed       Code = "remove_favorite_failed"
+	CodeGetFavoritesFailed         Code = "get_favorites_failed"
+	CodeExportFailed               Code = "export_failed"
+	CodeExportNotFound             Code = "export_not_found"
+	CodeExportNotReady             Code = "export_not_ready"
+	CodeExportQueueFull            Code = "export_queue_full"
+	CodeImportFailed               Code = "import_failed"
+	CodeImportNotFound             Code = "import_not_found"
+	CodeImportFinished             Code = "import_finished"
+	CodeImportQueueFull            Code = "import_queue_full"
+	CodeImportTooLarge             Code = "import_too_large"
+	CodeContentRejected            Code = "content_rejected"
+	CodeModerationQueueFull        Code = "moderation_queue_full"
+	CodeHeldQuoteNotFound          Code = "held_quote_not_found"
+	CodeAPIKeyNotFound             Code = "api_key_not_found"
+	CodeCreateAPIKeyFailed         Code = "create_api_key_failed"
+	CodeRevokeAPIKeyFailed         Code = "revoke_api_key_failed"
+	CodeFeatureNotFound            Code = "feature_not_found"
+	CodeFeatureNotDynamic          Code = "feature_not_dynamic"
+	CodeChangesExpired             Code = "changes_expired"
+	CodeGetChangesFailed           Code = "get_changes_failed"
+	CodeGetAuditFailed             Code = "get_audit_failed"
+	CodeGetDuplicatesFailed        Code = "get_duplicates_failed"
+	CodeResolveDuplicatesFailed    Code = "resolve_duplicates_failed"
+	CodeMethodDisabled             Code = "method_disabled"
+	CodeClaimantRequired           Code = "claimant_required"
+	CodeNothingToClaim             Code = "nothing_to_claim"
+	CodeClaimNotHeld               Code = "claim_not_held"
+	CodeClaimQuoteFailed           Code = "claim_quote_failed"
+	CodeReleaseQuoteFailed         Code = "release_quote_failed"
+	CodeCompactStorageFailed       Code = "compact_storage_failed"
+	CodeGetStorageReportFailed     Code = "get_storage_report_failed"
+	CodeInvalidResumeToken         Code = "invalid_resume_token"
+	CodeDeleteAuthorQuotesFailed   Code = "delete_author_quotes_failed"
+	CodeReindexFailed              Code = "reindex_failed"
+	CodeUnknownShareStyle          Code = "unknown_share_style"
+	CodeShareQuoteFailed           Code = "share_quote_failed"
+	CodeNoMatchingQuote            Code = "no_matching_quote"
+	CodeInvalidAuthorName          Code = "invalid_author_name"
+	CodeInternal                   Code = "internal_error"
+	CodeImportURLNotAllowed        Code = "import_url_not_allowed"
+)
+
+// storageFailures are the codes answered when a request failed because the
+// store did.
+var storageFailures = map[Code]bool{
+	CodeGetAuthorFailed:            true,
+	CodeGetAuthorsFailed:           true,
+	CodeMergeAuthorsFailed:         true,
+	CodeAddQuoteFailed:             true,
+	CodeUpdateQuoteFailed:          true,
+	CodeDeleteQuoteFailed:          true,
+	CodeGetQuoteFailed:             true,
+	CodeGetQuotesFailed:            true,
+	CodeGetAuthorQuotesFailed:      true,
+	CodeGetRandomFailed:            true,
+	CodeGetPopularFailed:           true,
+	CodeGetSimilarFailed:           true,
+	CodeTextStatsFailed:            true,
+	CodeCreateCollectionFailed:     true,
+	CodeGetCollectionsFailed:       true,
+	CodeGetCollectionFailed:        true,
+	CodeAddToCollectionFailed:      true,
+	CodeRemoveFromCollectionFailed: true,
+	CodeDeleteCollectionFailed:     true,
+	CodeAddFavoriteFailed:          true,
+	CodeRemoveFavoriteFailed:       true,
+	CodeGetFavoritesFailed:         true,
+	CodeGetChangesFailed:           true,
+	CodeGetAuditFailed:             true,
+	CodeGetDuplicatesFailed:        true,
+	CodeResolveDuplicatesFailed:    true,
+	CodeClaimQuoteFailed:           true,
+	CodeReleaseQuoteFailed:         true,
+	CodeCompactStorageFailed:       true,
+	CodeGetStorageReportFailed:     true,
+	CodeDeleteAuthorQuotesFailed:   true,
+	CodeReindexFailed:              true,
+}
+
+// StorageFailure reports whether code is answered when the store fails.
+func StorageFailure(code Code) bool {
+	return storageFailures[code]
+}
@@ -0,0 +1,96 @@
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"quotes-service/internal/storage"
+)
+
+// Error is an error as answered to the client: the status, code, fields
+// and message args of the response. Handlers return one, or an error
+// Resolve maps, rather than writing the error response themselves.
+type Error struct {
+	Status int
+	Code   Code
+	Fields []string
+	Args   []any
+	// Err is the failure behind the error, if any.
+	Err error
+
+	// fallback marks an error answered as it is only when Resolve knows
+	// no better answer for Err.
+	fallback bool
+}
+
+// New returns the error answered with status and code. args fill the
+// message template of code.
+func New(status int, code Code, fields []string, args ...any) *Error {
+	return &Error{Status: status, Code: code, Fields: fields, Args: args}
+}
+
+// Failed returns err as answered when an operation fails: as Resolve maps
+// the storage error it wraps, such as 404 quote_not_found for
+// storage.ErrQuoteNotFound, and as 500 with code, the code of the
+// operation, for any other.
+func Failed(code Code, err error) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: code, Err: err, fallback: true}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%d %s", e.Status, e.Code)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// storageErrors maps the storage errors that are the client's to answer
+// rather than the server's failures. A new storage error a handler may
+// return is mapped here and nowhere else.
+var storageErrors = []struct {
+	err    error
+	status int
+	code   Code
+}{
+	{storage.ErrQuoteNotFound, http.StatusNotFound, CodeQuoteNotFound},
+	{storage.ErrCollectionNotFound, http.StatusNotFound, CodeCollectionNotFound},
+	{storage.ErrVersionMismatch, http.StatusPreconditionFailed, CodeVersionMismatch},
+	{storage.ErrChangesExpired, http.StatusGone, CodeChangesExpired},
+	{storage.ErrNothingToClaim, http.StatusConflict, CodeNothingToClaim},
+	{storage.ErrClaimNotHeld, http.StatusConflict, CodeClaimNotHeld},
+}
+
+// Resolve returns the answer to err: the Error err is or wraps, unless it
+// came from Failed; then, as for a bare error, a storage error mapped above
+// is answered as mapped. Any other error is a 500 internal_error.
+func Resolve(err error) *Error {
+	var e *Error
+	found := errors.As(err, &e)
+	if found && !e.fallback {
+		return e
+	}
+	for _, known := range storageErrors {
+		if errors.Is(err, known.err) {
+			return &Error{Status: known.status, Code: known.code, Err: err}
+		}
+	}
+	if found {
+		return e
+	}
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Err: err}
+}
+
+// Level is the level to log err at: Error when it is answered with a
+// server error, Info when the request was the problem.
+func Level(err error) slog.Level {
+	if Resolve(err).Status >= http.StatusInternalServerError {
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
@@ -0,0 +1,112 @@
+package apierror_test
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/storage"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   apierror.Code
+		expectedLevel  slog.Level
+	}{
+		{
+			name:           "explicit error",
+			err:            apierror.New(http.StatusBadRequest, apierror.CodeInvalidID, []string{"id"}),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   apierror.CodeInvalidID,
+			expectedLevel:  slog.LevelInfo,
+		},
+		{
+			name:           "explicit error wins over the storage error it wraps",
+			err:            &apierror.Error{Status: http.StatusNotFound, Code: apierror.CodeNoQuotes, Err: storage.ErrQuoteNotFound},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   apierror.CodeNoQuotes,
+			expectedLevel:  slog.LevelInfo,
+		},
+		{
+			name:           "quote not found",
+			err:            apierror.Failed(apierror.CodeGetQuoteFailed, fmt.Errorf("get: %w", storage.ErrQuoteNotFound)),
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   apierror.CodeQuoteNotFound,
+			expectedLevel:  slog.LevelInfo,
+		},
+		{
+			name:           "collection not found",
+			err:            apierror.Failed(apierror.CodeGetQuoteFailed, storage.ErrCollectionNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   apierror.CodeCollectionNotFound,
+			expectedLevel:  slog.LevelInfo,
+		},
+		{
+			name:           "version mismatch",
+			err:            apierror.Failed(apierror.CodeUpdateQuoteFailed, storage.ErrVersionMismatch),
+			expectedStatus: http.StatusPreconditionFailed,
+			expectedCode:   apierror.CodeVersionMismatch,
+			expectedLevel:  slog.LevelInfo,
+		},
+		{
+			name:           "changes expired",
+			err:            apierror.Failed(apierror.CodeGetQuoteFailed, storage.ErrChangesExpired),
+			expectedStatus: http.StatusGone,
+			expectedCode:   apierror.CodeChangesExpired,
+			expectedLevel:  slog.LevelInfo,
+		},
+		{
+			name:           "nothing to claim",
+			err:            apierror.Failed(apierror.CodeGetQuoteFailed, storage.ErrNothingToClaim),
+			expectedStatus: http.StatusConflict,
+			expectedCode:   apierror.CodeNothingToClaim,
+			expectedLevel:  slog.LevelInfo,
+		},
+		{
+			name:           "claim not held",
+			err:            apierror.Failed(apierror.CodeGetQuoteFailed, storage.ErrClaimNotHeld),
+			expectedStatus: http.StatusConflict,
+			expectedCode:   apierror.CodeClaimNotHeld,
+			expectedLevel:  slog.LevelInfo,
+		},
+		{
+			name:           "failed operation",
+			err:            apierror.Failed(apierror.CodeUpdateQuoteFailed, errors.New("disk full")),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   apierror.CodeUpdateQuoteFailed,
+			expectedLevel:  slog.LevelError,
+		},
+		{
+			name:           "unknown error",
+			err:            errors.New("boom"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   apierror.CodeInternal,
+			expectedLevel:  slog.LevelError,
+		},
+		{
+			name:           "bare storage error",
+			err:            storage.ErrQuoteNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   apierror.CodeQuoteNotFound,
+			expectedLevel:  slog.LevelInfo,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := apierror.Resolve(tc.err)
+			if got.Status != tc.expectedStatus || got.Code != tc.expectedCode {
+				t.Errorf("expected %d %s, got %d %s", tc.expectedStatus, tc.expectedCode, got.Status, got.Code)
+			}
+			if level := apierror.Level(tc.err); level != tc.expectedLevel {
+				t.Errorf("expected level %v, got %v", tc.expectedLevel, level)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package apierror
+
+var english = map[Code]string{
+	CodeRequestBodyEmpty:           "Request body is empty.",
+	CodeRequestBodyInvalid:         "Failed to decode request body.",
+	CodeInvalidRequest:             "Invalid request.",
+	CodeInvalidParameter:           "Invalid %s parameter.",
+	CodeInvalidID:                  "Invalid ID format.",
+	CodeParameterTooLong:           "Parameter %s is longer than %d characters.",
+	CodeUnknownDialect:             "Unknown response dialect %q.",
+	CodeInvalidQuoteID:             "Invalid quote ID format.",
+	CodeQuoteIDMissing:             "Quote ID is missing in path.",
+	CodeInvalidLimit:               "Limit must be a positive integer.",
+	CodeInvalidOffset:              "Offset must be a non-negative integer.",
+	CodeInvalidUnweighted:          "Unweighted must be a boolean.",
+	CodeAuthorRequired:             "Author query parameter is required.",
+	CodeAuthRequired:               "Authentication required.",
+	CodeInvalidAPIKey:              "Invalid API key.",
+	CodeInvalidToken:               "Invalid bearer token.",
+	CodeTokenExpired:               "Bearer token has expired.",
+	CodeInvalidAudience:            "Bearer token was issued for another audience.",
+	CodeUnknownSigningKey:          "Bearer token is signed with an unknown key.",
+	CodeInvalidSignature:           "Request signature is missing or invalid.",
+	CodeStaleSignature:             "Request signature timestamp is outside the allowed window.",
+	CodeSignedBodyTooLarge:         "Signed request body is larger than %d bytes.",
+	CodeForbidden:                  "Access denied.",
+	CodeInsufficientRole:           "The %s role cannot do this; it needs %s.",
+	CodeRateLimited:                "Too many requests.",
+	CodeTooManyAuthFailures:        "Too many failed authentication attempts; try again later.",
+	CodeNotReady:                   "Service is not ready.",
+	CodeQuoteNotFound:              "Quote not found.",
+	CodeQuoteIDNotFound:            "Quote %v not found.",
+	CodeQuoteNotInCollection:       "Quote %d not found in collection.",
+	CodeNoQuotes:                   "No quotes found.",
+	CodeAuthorNotFound:             "Author not found.",
+	CodeInvalidAuthor:              "Invalid author name.",
+	CodeGetAuthorFailed:            "Failed to retrieve author.",
+	CodeGetAuthorsFailed:           "Failed to retrieve authors.",
+	CodeMergeAuthorsFailed:         "Failed to merge authors.",
+	CodeSchemaNotFound:             "Schema not found.",
+	CodeCollectionNotFound:         "Collection not found.",
+	CodeVersionMismatch:            "Quote was modified by another request.",
+	CodeAddQuoteFailed:             "Failed to add quote.",
+	CodeUpdateQuoteFailed:          "Failed to update quote.",
+	CodeDeleteQuoteFailed:          "Failed to delete quote.",
+	CodeGetQuoteFailed:             "Failed to retrieve quote.",
+	CodeGetQuotesFailed:            "Failed to retrieve quotes.",
+	CodeGetAuthorQuotesFailed:      "Failed to retrieve quotes by author.",
+	CodeGetRandomFailed:            "Failed to retrieve random quote.",
+	CodeGetPopularFailed:           "Failed to retrieve popular quotes.",
+	CodeGetSimilarFailed:           "Failed to retrieve similar quotes.",
+	CodeTextStatsFailed:            "Failed to compute text statistics.",
+	CodeCreateCollectionFailed:     "Failed to create collection.",
+	CodeGetCollectionsFailed:       "Failed to retrieve collections.",
+	CodeGetCollectionFailed:        "Failed to retrieve collection.",
+	CodeAddToCollectionFailed:      "Failed to add quotes to collection.",
+	CodeRemoveFromCollectionFailed: "Failed to remove quote from collection.",
+	CodeDeleteCollectionFailed:     "Failed to delete collection.",
+	CodeAddFavoriteFailed:          "Failed to add favorite.",
+	CodeRemoveFavoriteFailed:       "Failed to remove favorite.",
+	CodeGetFavoritesFailed:         "Failed to retrieve favorites.",
+	CodeExportFailed:               "Failed to export quotes.",
+	CodeExportNotFound:             "Export not found.",
+	CodeExportNotReady:             "Export is not ready for download.",
+	CodeExportQueueFull:            "Too many exports are waiting; try again later.",
+	CodeImportFailed:               "Failed to import quotes.",
+	CodeImportNotFound:             "Import not found.",
+	CodeImportFinished:             "Import has already finished.",
+	CodeImportQueueFull:            "Too many imports are waiting; try again later.",
+	CodeImportTooLarge:             "Import is too large.",
+	CodeContentRejected:            "Quote content is not allowed.",
+	CodeModerationQueueFull:        "Too many quotes are awaiting moderation; try again later.",
+	CodeHeldQuoteNotFound:          "Held quote not found.",
+	CodeAPIKeyNotFound:             "API key not found.",
+	CodeCreateAPIKeyFailed:         "Failed to create the API key.",
+	CodeRevokeAPIKeyFailed:         "The API key is revoked, but saving that failed; it will work again after a restart.",
+	CodeFeatureNotFound:            "Feature %s not found.",
+	CodeFeatureNotDynamic:          "Feature %s cannot change at runtime; change it in the config and restart.",
+	CodeChangesExpired:             "Changes since this sequence number are no longer available; fetch all quotes again.",
+	CodeGetChangesFailed:           "Failed to retrieve changes.",
+	CodeGetAuditFailed:             "Failed to retrieve the audit trail.",
+	CodeGetDuplicatesFailed:        "Failed to find duplicate quotes.",
+	CodeResolveDuplicatesFailed:    "Failed to delete duplicate quotes; some may have been deleted.",
+	CodeMethodDisabled:             "Method %s is disabled on this server.",
+	CodeClaimantRequired:           "A claimant is required: authenticate or name one in the body.",
+	CodeNothingToClaim:             "Every matching quote is already claimed.",
+	CodeClaimNotHeld:               "The quote is not claimed by this claimant, or the claim has expired.",
+	CodeClaimQuoteFailed:           "Failed to claim a quote.",
+	CodeReleaseQuoteFailed:         "Failed to release the quote.",
+	CodeCompactStorageFailed:       "Failed to compact the storage.",
+	CodeGetStorageReportFailed:     "Failed to report on the storage.",
+	CodeInvalidResumeToken:         "The resume token is invalid or belongs to another operation.",
+	CodeDeleteAuthorQuotesFailed:   "Failed to delete the author's quotes.",
+	CodeReindexFailed:              "Failed to reindex the quotes.",
+	CodeUnknownShareStyle:          "Unknown share style %q; available styles: %s.",
+	CodeShareQuoteFailed:           "Failed to render the quote for sharing.",
+	CodeNoMatchingQuote:            "No quote matches fingerprint %s.",
+	CodeInvalidAuthorName:          "Invalid author name.",
+	CodeInternal:                   "Internal server error.",
+	CodeImportURLNotAllowed:        "Imports are not allowed from this URL.",
+}
+
+var russian = map[Code]string{
+	CodeRequestBodyEmpty:           "Тело запроса пустое.",
+	CodeRequestBodyInvalid:         "Не удалось разобрать тело запроса.",
+	CodeInvalidRequest:             "Некорректный запрос.",
+	CodeInvalidParameter:           "Некорректный параметр %s.",
+	CodeInvalidID:                  "Некорректный формат ID.",
+	CodeParameterTooLong:           "Параметр %s длиннее %d символов.",
+	CodeUnknownDialect:             "Неизвестный диалект ответа %q.",
+	CodeInvalidQuoteID:             "Некорректный формат ID цитаты.",
+	CodeQuoteIDMissing:             "В пути не указан ID цитаты.",
+	CodeInvalidLimit:               "Limit должен быть положительным целым числом.",
+	CodeInvalidOffset:              "Offset должен быть неотрицательным целым числом.",
+	CodeInvalidUnweighted:          "Unweighted должен быть булевым значением.",
+	CodeAuthorRequired:             "Параметр author обязателен.",
+	CodeAuthRequired:               "Требуется аутентификация.",
+	CodeInvalidAPIKey:              "Неверный API-ключ.",
+	CodeInvalidToken:               "Неверный токен доступа.",
+	CodeTokenExpired:               "Срок действия токена доступа истёк.",
+	CodeInvalidAudience:            "Токен доступа выпущен для другого получателя.",
+	CodeUnknownSigningKey:          "Токен доступа подписан неизвестным ключом.",
+	CodeInvalidSignature:           "Подпись запроса отсутствует или неверна.",
+	CodeStaleSignature:             "Время подписи запроса вне допустимого окна.",
+	CodeSignedBodyTooLarge:         "Тело подписанного запроса больше %d байт.",
+	CodeForbidden:                  "Доступ запрещён.",
+	CodeInsufficientRole:           "Роли %s это недоступно: требуется %s.",
+	CodeRateLimited:                "Слишком много запросов.",
+	CodeTooManyAuthFailures:        "Слишком много неудачных попыток аутентификации; повторите позже.",
+	CodeNotReady:                   "Сервис не готов к работе.",
+	CodeQuoteNotFound:              "Цитата не найдена.",
+	CodeQuoteIDNotFound:            "Цитата %v не найдена.",
+	CodeQuoteNotInCollection:       "Цитата %d не найдена в коллекции.",
+	CodeNoQuotes:                   "Цитаты не найдены.",
+	CodeAuthorNotFound:             "Автор не найден.",
+	CodeInvalidAuthor:              "Некорректное имя автора.",
+	CodeGetAuthorFailed:            "Не удалось получить автора.",
+	CodeGetAuthorsFailed:           "Не удалось получить список авторов.",
+	CodeMergeAuthorsFailed:         "Не удалось объединить авторов.",
+	CodeSchemaNotFound:             "Схема не найдена.",
+	CodeCollectionNotFound:         "Коллекция не найдена.",
+	CodeVersionMismatch:            "Цитата была изменена другим запросом.",
+	CodeAddQuoteFailed:             "Не удалось добавить цитату.",
+	CodeUpdateQuoteFailed:          "Не удалось изменить цитату.",
+	CodeDeleteQuoteFailed:          "Не удалось удалить цитату.",
+	CodeGetQuoteFailed:             "Не удалось получить цитату.",
+	CodeGetQuotesFailed:            "Не удалось получить цитаты.",
+	CodeGetAuthorQuotesFailed:      "Не удалось получить цитаты автора.",
+	CodeGetRandomFailed:            "Не удалось получить случайную цитату.",
+	CodeGetPopularFailed:           "Не удалось получить популярные цитаты.",
+	CodeGetSimilarFailed:           "Не удалось получить похожие цитаты.",
+	CodeTextStatsFailed:            "Не удалось посчитать статистику текстов.",
+	CodeCreateCollectionFailed:     "Не удалось создать коллекцию.",
+	CodeGetCollectionsFailed:       "Не удалось получить коллекции.",
+	CodeGetCollectionFailed:        "Не удалось получить коллекцию.",
+	CodeAddToCollectionFailed:      "Не удалось добавить цитаты в коллекцию.",
+	CodeRemoveFromCollectionFailed: "Не удалось удалить цитату из коллекции.",
+	CodeDeleteCollectionFailed:     "Не удалось удалить коллекцию.",
+	CodeAddFavoriteFailed:          "Не удалось добавить цитату в избранное.",
+	CodeRemoveFavoriteFailed:       "Не удалось удалить цитату из избранного.",
+	CodeGetFavoritesFailed:         "Не удалось получить избранное.",
+	CodeExportFailed:               "Не удалось выгрузить цитаты.",
+	CodeExportNotFound:             "Выгрузка не найдена.",
+	CodeExportNotReady:             "Выгрузка ещё не готова к скачиванию.",
+	CodeExportQueueFull:            "Слишком много выгрузок в очереди; повторите позже.",
+	CodeImportFailed:               "Не удалось загрузить цитаты.",
+	CodeImportNotFound:             "Загрузка не найдена.",
+	CodeImportFinished:             "Загрузка уже завершена.",
+	CodeImportQueueFull:            "Слишком много загрузок в очереди; повторите позже.",
+	CodeImportTooLarge:             "Загрузка слишком велика.",
+	CodeContentRejected:            "Содержимое цитаты недопустимо.",
+	CodeModerationQueueFull:        "Слишком много цитат ожидают модерации; повторите позже.",
+	CodeHeldQuoteNotFound:          "Цитата на модерации не найдена.",
+	CodeAPIKeyNotFound:             "API-ключ не найден.",
+	CodeCreateAPIKeyFailed:         "Не удалось создать API-ключ.",
+	CodeRevokeAPIKeyFailed:         "API-ключ отозван, но сохранить это не удалось; после перезапуска он снова заработает.",
+	CodeFeatureNotFound:            "Функция %s не найдена.",
+	CodeFeatureNotDynamic:          "Функцию %s нельзя переключить на ходу; измените конфигурацию и перезапустите сервис.",
+	CodeChangesExpired:             "Изменения после этого номера больше недоступны; загрузите все цитаты заново.",
+	CodeGetChangesFailed:           "Не удалось получить изменения.",
+	CodeGetAuditFailed:             "Не удалось получить журнал аудита.",
+	CodeGetDuplicatesFailed:        "Не удалось найти дубликаты цитат.",
+	CodeResolveDuplicatesFailed:    "Не удалось удалить дубликаты цитат; часть из них могла быть удалена.",
+	CodeMethodDisabled:             "Метод %s отключён на этом сервере.",
+	CodeClaimantRequired:           "Требуется владелец резерва: выполните аутентификацию или укажите его в теле запроса.",
+	CodeNothingToClaim:             "Все подходящие цитаты уже зарезервированы.",
+	CodeClaimNotHeld:               "Цитата не зарезервирована этим владельцем, или срок резерва истёк.",
+	CodeClaimQuoteFailed:           "Не удалось зарезервировать цитату.",
+	CodeReleaseQuoteFailed:         "Не удалось снять резерв с цитаты.",
+	CodeCompactStorageFailed:       "Не удалось уплотнить хранилище.",
+	CodeGetStorageReportFailed:     "Не удалось получить отчёт о хранилище.",
+	CodeInvalidResumeToken:         "Токен продолжения недействителен или выдан для другой операции.",
+	CodeDeleteAuthorQuotesFailed:   "Не удалось удалить цитаты автора.",
+	CodeReindexFailed:              "Не удалось переиндексировать цитаты.",
+	CodeUnknownShareStyle:          "Неизвестный стиль %q; доступные стили: %s.",
+	CodeShareQuoteFailed:           "Не удалось подготовить цитату для публикации.",
+	CodeNoMatchingQuote:            "Нет цитаты с отпечатком %s.",
+	CodeInvalidAuthorName:          "Недопустимое имя автора.",
+	CodeInternal:                   "Внутренняя ошибка сервера.",
+	CodeImportURLNotAllowed:        "Загрузка с этого адреса запрещена.",
+}
@@ -0,0 +1,155 @@
+// Package bulk runs the long bulk operations, such as deleting every quote
+// of an author, a batch at a time within a request's time budget. An
+// operation that runs out of time answers with how far it got and a resume
+// token the client sends back to go on from there.
+package bulk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// TokenParam is the query parameter a resume token is sent back in.
+const TokenParam = "resume_token"
+
+// tokenVersion prefixes every token, so that the format can change without
+// old tokens being misread.
+const tokenVersion = "v1"
+
+var ErrInvalidToken = errors.New("invalid resume token")
+
+// Limits bounds one call of a bulk operation.
+type Limits struct {
+	// BatchSize is the number of quotes a batch takes at most.
+	BatchSize int
+	// Budget is how long a call goes on starting batches. It should leave
+	// the last batch and the response time to finish before the server's
+	// write timeout.
+	Budget time.Duration
+}
+
+// Progress is how far one call of an operation got.
+type Progress struct {
+	// Processed is the number of quotes the call's batches changed.
+	Processed int
+	// Last is where the next call starts.
+	Last int64
+	// Done reports that nothing is left to do.
+	Done bool
+}
+
+// Step runs one batch of an operation.
+type Step func(ctx context.Context, batch storage.Batch) (storage.BatchResult, error)
+
+// Run runs the batches of an operation from after on until one finishes
+// it, the budget is spent or ctx is done, whichever comes first. ctx is
+// only checked between batches: a batch once started runs to its end, and
+// the first always runs, so every call gets somewhere however little time
+// it has. A batch that fails stops the run; the progress up to it stands.
+func Run(ctx context.Context, limits Limits, after int64, step Step) (Progress, error) {
+	deadline := time.Now().Add(limits.Budget)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	batchCtx := context.WithoutCancel(ctx)
+
+	progress := Progress{Last: after}
+	for {
+		result, err := step(batchCtx, storage.Batch{After: progress.Last, Limit: limits.BatchSize})
+		if err != nil {
+			return progress, err
+		}
+		progress.Processed += len(result.IDs)
+		progress.Last = result.Last
+		if result.Done {
+			progress.Done = true
+			return progress, nil
+		}
+		if ctx.Err() != nil || !time.Now().Before(deadline) {
+			return progress, nil
+		}
+	}
+}
+
+// Token returns the token that resumes op after the quote with ID after.
+// params are what op was asked to do, in a canonical form, so that the
+// token resumes nothing but the same operation on the same input.
+//
+// A token is not a secret and is safe to replay: going over batches again
+// finds nothing left to change in them.
+func Token(op string, params []string, after int64) string {
+	position := strconv.FormatInt(after, 10)
+	raw := strings.Join([]string{tokenVersion, op, position, fingerprint(op, params, position)}, ".")
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseToken returns the ID token resumes after. It fails with
+// ErrInvalidToken when token is malformed, was altered, or was issued for
+// another operation or other params.
+func ParseToken(token, op string, params []string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	parts := strings.Split(string(raw), ".")
+	if len(parts) != 4 || parts[0] != tokenVersion || parts[1] != op {
+		return 0, ErrInvalidToken
+	}
+	after, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || after < 0 || parts[2] != strconv.FormatInt(after, 10) {
+		return 0, ErrInvalidToken
+	}
+	if parts[3] != fingerprint(op, params, parts[2]) {
+		return 0, ErrInvalidToken
+	}
+	return after, nil
+}
+
+// Resume returns the ID the request's resume token resumes op after, or 0
+// for a request without one, which starts op from the beginning.
+func Resume(r *http.Request, op string, params []string) (int64, error) {
+	token := r.URL.Query().Get(TokenParam)
+	if token == "" {
+		return 0, nil
+	}
+	return ParseToken(token, op, params)
+}
+
+// Status is the status of a call's response: 200 once the operation is
+// done, 202 while there is more to do.
+func Status(p Progress) int {
+	if p.Done {
+		return http.StatusOK
+	}
+	return http.StatusAccepted
+}
+
+// Report is the progress of a call as answered to the client, with the
+// token to resume op from while it is not done.
+func Report(p Progress, op string, params []string) models.BulkProgress {
+	report := models.BulkProgress{Processed: p.Processed, Done: p.Done}
+	if !p.Done {
+		report.ResumeToken = Token(op, params, p.Last)
+	}
+	return report
+}
+
+// fingerprint ties a token to its operation, params and position.
+func fingerprint(op string, params []string, position string) string {
+	h := sha256.New()
+	for _, part := range append([]string{op, position}, params...) {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
@@ -0,0 +1,143 @@
+package bulk_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"quotes-service/internal/http-server/bulk"
+	"quotes-service/internal/storage"
+)
+
+func TestParseToken(t *testing.T) {
+	token := bulk.Token("delete_author", []string{"einstein"}, 42)
+	raw, _ := base64.RawURLEncoding.DecodeString(token)
+	forged := base64.RawURLEncoding.EncodeToString([]byte(string(raw[:len("v1.delete_author.")]) + "4" + string(raw[len("v1.delete_author.42"):])))
+
+	tests := []struct {
+		name     string
+		token    string
+		op       string
+		params   []string
+		expected int64
+		wantErr  bool
+	}{
+		{name: "valid", token: token, op: "delete_author", params: []string{"einstein"}, expected: 42},
+		{name: "other op", token: token, op: "merge_authors", params: []string{"einstein"}, wantErr: true},
+		{name: "other params", token: token, op: "delete_author", params: []string{"wilde"}, wantErr: true},
+		{name: "no params", token: token, op: "delete_author", wantErr: true},
+		{name: "position edited", token: forged, op: "delete_author", params: []string{"einstein"}, wantErr: true},
+		{name: "not base64", token: "!!!", op: "delete_author", params: []string{"einstein"}, wantErr: true},
+		{name: "garbage", token: base64.RawURLEncoding.EncodeToString([]byte("v1.delete_author")), op: "delete_author", wantErr: true},
+		{name: "negative", token: bulk.Token("reindex", nil, -1), op: "reindex", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			after, err := bulk.ParseToken(tc.token, tc.op, tc.params)
+			if tc.wantErr {
+				if !errors.Is(err, bulk.ErrInvalidToken) {
+					t.Fatalf("expected ErrInvalidToken, got %d (%v)", after, err)
+				}
+				return
+			}
+			if err != nil || after != tc.expected {
+				t.Fatalf("expected %d, got %d (%v)", tc.expected, after, err)
+			}
+		})
+	}
+}
+
+// countdown is an operation over the IDs 1 to n.
+func countdown(n int64, calls *int) bulk.Step {
+	return func(ctx context.Context, batch storage.Batch) (storage.BatchResult, error) {
+		*calls++
+		if ctx.Err() != nil {
+			return storage.BatchResult{}, ctx.Err()
+		}
+		result := storage.BatchResult{Last: batch.After}
+		for id := batch.After + 1; id <= n && len(result.IDs) < batch.Limit; id++ {
+			result.IDs = append(result.IDs, id)
+			result.Last = id
+		}
+		result.Done = result.Last == n
+		return result, nil
+	}
+}
+
+func TestRun(t *testing.T) {
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	tests := []struct {
+		name            string
+		ctx             context.Context
+		budget          time.Duration
+		expectedDone    bool
+		expectedLast    int64
+		expectedBatches int
+	}{
+		{name: "all in one call", ctx: context.Background(), budget: time.Minute, expectedDone: true, expectedLast: 25, expectedBatches: 3},
+		{name: "budget spent", ctx: context.Background(), budget: time.Nanosecond, expectedLast: 10, expectedBatches: 1},
+		{name: "deadline passed", ctx: expired, budget: time.Minute, expectedLast: 10, expectedBatches: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int
+			progress, err := bulk.Run(tc.ctx, bulk.Limits{BatchSize: 10, Budget: tc.budget}, 0, countdown(25, &calls))
+			if err != nil {
+				t.Fatalf("failed to run: %v", err)
+			}
+			if progress.Done != tc.expectedDone || progress.Last != tc.expectedLast || calls != tc.expectedBatches {
+				t.Fatalf("expected done=%v last=%d after %d batches, got %+v after %d", tc.expectedDone, tc.expectedLast, tc.expectedBatches, progress, calls)
+			}
+		})
+	}
+}
+
+func TestRunResumed(t *testing.T) {
+	limits := bulk.Limits{BatchSize: 10, Budget: time.Nanosecond}
+	var (
+		calls     int
+		processed int
+		after     int64
+	)
+	for range 10 {
+		progress, err := bulk.Run(context.Background(), limits, after, countdown(25, &calls))
+		if err != nil {
+			t.Fatalf("failed to run: %v", err)
+		}
+		processed += progress.Processed
+		report := bulk.Report(progress, "reindex", nil)
+		if progress.Done {
+			if report.ResumeToken != "" {
+				t.Fatalf("expected no token once done, got %q", report.ResumeToken)
+			}
+			break
+		}
+		if after, err = bulk.ParseToken(report.ResumeToken, "reindex", nil); err != nil {
+			t.Fatalf("failed to parse the token handed out: %v", err)
+		}
+	}
+	if processed != 25 || calls != 3 {
+		t.Fatalf("expected 25 quotes over 3 calls, got %d over %d", processed, calls)
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	failed := errors.New("boom")
+	var calls int
+	step := func(ctx context.Context, batch storage.Batch) (storage.BatchResult, error) {
+		if calls++; calls == 2 {
+			return storage.BatchResult{}, failed
+		}
+		return storage.BatchResult{IDs: []int64{batch.After + 1}, Last: batch.After + 1}, nil
+	}
+	progress, err := bulk.Run(context.Background(), bulk.Limits{BatchSize: 1, Budget: time.Minute}, 0, step)
+	if !errors.Is(err, failed) || progress.Processed != 1 || progress.Last != 1 {
+		t.Fatalf("expected the failure after one batch, got %+v (%v)", progress, err)
+	}
+}
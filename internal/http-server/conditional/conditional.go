@@ -0,0 +1,106 @@
+// Package conditional evaluates HTTP conditional requests.
+package conditional
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"quotes-service/internal/storage"
+)
+
+// CheckModified sets Last-Modified from lastModified and reports whether the
+// request's If-Modified-Since shows the client already has this version. In
+// that case a 304 has been written and the caller must not write a body.
+//
+// If-Modified-Since is ignored when the request carries If-None-Match, as
+// RFC 9110 requires; use CheckNoneMatch for that header.
+//
+// HTTP dates have one-second precision, so lastModified is truncated before
+// comparing; otherwise a resource changed at 12:00:00.5 would never match the
+// 12:00:00 the client echoes back. A zero lastModified disables the check.
+func CheckModified(w http.ResponseWriter, r *http.Request, lastModified time.Time) bool {
+	if lastModified.IsZero() {
+		return false
+	}
+	lastModified = lastModified.UTC().Truncate(time.Second)
+	w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || r.Header.Get("If-None-Match") != "" {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	if lastModified.After(since) {
+		return false
+	}
+
+	notModified(w)
+	return true
+}
+
+// CheckNoneMatch reports whether the request's If-None-Match lists etag or
+// is "*". In that case a 304 has been written and the caller must not write
+// a body. Weak comparison is used, as RFC 9110 requires for this header.
+func CheckNoneMatch(w http.ResponseWriter, r *http.Request, etag string) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == want {
+			notModified(w)
+			return true
+		}
+	}
+	return false
+}
+
+func notModified(w http.ResponseWriter) {
+	w.Header().Del("Content-Type")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// ETag formats a quote version as a strong entity tag.
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// IfMatch reads the If-Match header. present is false when the header is
+// absent. "*" yields storage.AnyVersion; any value that is not a single
+// strong tag produced by ETag yields -1, which matches no stored version.
+func IfMatch(r *http.Request) (version int64, present bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return 0, false
+	}
+	if header == "*" {
+		return storage.AnyVersion, true
+	}
+	tag, ok := strings.CutPrefix(header, `"`)
+	if !ok {
+		return -1, true
+	}
+	tag, ok = strings.CutSuffix(tag, `"`)
+	if !ok {
+		return -1, true
+	}
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version <= 0 {
+		return -1, true
+	}
+	return version, true
+}
@@ -0,0 +1,156 @@
+package conditional_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"quotes-service/internal/http-server/conditional"
+)
+
+func TestCheckModified(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	base := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name               string
+		method             string
+		lastModified       time.Time
+		ifModifiedSince    string
+		expectNotModified  bool
+		expectLastModified string
+	}{
+		{
+			name:               "no header",
+			method:             http.MethodGet,
+			lastModified:       base,
+			expectLastModified: "Sun, 10 Mar 2024 12:00:00 GMT",
+		},
+		{
+			name:               "same second",
+			method:             http.MethodGet,
+			lastModified:       base,
+			ifModifiedSince:    "Sun, 10 Mar 2024 12:00:00 GMT",
+			expectNotModified:  true,
+			expectLastModified: "Sun, 10 Mar 2024 12:00:00 GMT",
+		},
+		{
+			name:               "sub-second modification truncated",
+			method:             http.MethodGet,
+			lastModified:       base.Add(900 * time.Millisecond),
+			ifModifiedSince:    "Sun, 10 Mar 2024 12:00:00 GMT",
+			expectNotModified:  true,
+			expectLastModified: "Sun, 10 Mar 2024 12:00:00 GMT",
+		},
+		{
+			name:               "modified after",
+			method:             http.MethodGet,
+			lastModified:       base.Add(time.Second),
+			ifModifiedSince:    "Sun, 10 Mar 2024 12:00:00 GMT",
+			expectLastModified: "Sun, 10 Mar 2024 12:00:01 GMT",
+		},
+		{
+			name:               "non-UTC last modified",
+			method:             http.MethodGet,
+			lastModified:       base.In(moscow),
+			ifModifiedSince:    "Sun, 10 Mar 2024 12:00:00 GMT",
+			expectNotModified:  true,
+			expectLastModified: "Sun, 10 Mar 2024 12:00:00 GMT",
+		},
+		{
+			name:               "RFC 850 date",
+			method:             http.MethodGet,
+			lastModified:       base,
+			ifModifiedSince:    "Sunday, 10-Mar-24 12:00:00 GMT",
+			expectNotModified:  true,
+			expectLastModified: "Sun, 10 Mar 2024 12:00:00 GMT",
+		},
+		{
+			name:               "invalid date ignored",
+			method:             http.MethodGet,
+			lastModified:       base,
+			ifModifiedSince:    "yesterday",
+			expectLastModified: "Sun, 10 Mar 2024 12:00:00 GMT",
+		},
+		{
+			name:               "ignored for unsafe methods",
+			method:             http.MethodDelete,
+			lastModified:       base,
+			ifModifiedSince:    "Sun, 10 Mar 2024 12:00:00 GMT",
+			expectLastModified: "Sun, 10 Mar 2024 12:00:00 GMT",
+		},
+		{
+			name:            "zero time disables",
+			method:          http.MethodGet,
+			ifModifiedSince: "Sun, 10 Mar 2024 12:00:00 GMT",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/quotes/1", nil)
+			if tc.ifModifiedSince != "" {
+				req.Header.Set("If-Modified-Since", tc.ifModifiedSince)
+			}
+			rr := httptest.NewRecorder()
+
+			notModified := conditional.CheckModified(rr, req, tc.lastModified)
+			if notModified != tc.expectNotModified {
+				t.Fatalf("expected not modified %v, got %v", tc.expectNotModified, notModified)
+			}
+			if notModified && rr.Code != http.StatusNotModified {
+				t.Errorf("expected status 304, got %d", rr.Code)
+			}
+			if got := rr.Header().Get("Last-Modified"); got != tc.expectLastModified {
+				t.Errorf("expected Last-Modified %q, got %q", tc.expectLastModified, got)
+			}
+		})
+	}
+}
+
+func TestCheckNoneMatch(t *testing.T) {
+	tests := []struct {
+		name              string
+		method            string
+		ifNoneMatch       string
+		expectNotModified bool
+	}{
+		{name: "no header", method: http.MethodGet},
+		{name: "match", method: http.MethodGet, ifNoneMatch: `"7"`, expectNotModified: true},
+		{name: "weak match", method: http.MethodGet, ifNoneMatch: `W/"7"`, expectNotModified: true},
+		{name: "match in list", method: http.MethodGet, ifNoneMatch: `"5", "7"`, expectNotModified: true},
+		{name: "wildcard", method: http.MethodGet, ifNoneMatch: `*`, expectNotModified: true},
+		{name: "no match", method: http.MethodGet, ifNoneMatch: `"6"`},
+		{name: "non-GET ignored", method: http.MethodPut, ifNoneMatch: `"7"`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/quotes", nil)
+			if tc.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tc.ifNoneMatch)
+			}
+			rr := httptest.NewRecorder()
+
+			got := conditional.CheckNoneMatch(rr, req, conditional.ETag(7))
+			if got != tc.expectNotModified {
+				t.Fatalf("expected %v, got %v", tc.expectNotModified, got)
+			}
+			if got && rr.Code != http.StatusNotModified {
+				t.Fatalf("expected 304, got %d", rr.Code)
+			}
+		})
+	}
+}
+
+func TestCheckModifiedIgnoredWithIfNoneMatch(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/quotes", nil)
+	req.Header.Set("If-Modified-Since", "Sun, 10 Mar 2024 12:00:00 GMT")
+	req.Header.Set("If-None-Match", `"1"`)
+	rr := httptest.NewRecorder()
+
+	if conditional.CheckModified(rr, req, time.Date(2024, time.March, 10, 11, 0, 0, 0, time.UTC)) {
+		t.Fatal("expected If-Modified-Since to be ignored when If-None-Match is present")
+	}
+}
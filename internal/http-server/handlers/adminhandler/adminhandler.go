@@ -0,0 +1,350 @@
+package adminhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage/faultstorage"
+)
+
+type FaultInjector interface {
+	Faults() faultstorage.Faults
+	SetFaults(f faultstorage.Faults) error
+}
+
+func NewGetFaultsHandler(logger *slog.Logger, fi FaultInjector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.admin.GetFaults"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		log.InfoContext(ctx, "retrieved fault settings")
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   toSettings(fi.Faults()),
+		})
+	}
+}
+
+// NewSetFaultsHandler serves PUT /admin/faults. The body replaces the
+// current settings; sending the zero value turns injection off.
+func NewSetFaultsHandler(logger *slog.Logger, fi FaultInjector) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.admin.SetFaults"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		var req models.FaultSettings
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				log.WarnContext(ctx, "request body is empty")
+				return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyEmpty, nil)
+			}
+			log.ErrorContext(ctx, "failed to decode request body", slog.String("error", err.Error()))
+			return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyInvalid, nil)
+		}
+		defer r.Body.Close()
+
+		faults := faultstorage.Faults{
+			ErrorRate: req.ErrorRate,
+			Methods:   req.Methods,
+		}
+		var validationErrors []string
+		if req.Latency != "" {
+			latency, err := time.ParseDuration(req.Latency)
+			if err != nil {
+				validationErrors = append(validationErrors, fmt.Sprintf("latency must be a duration such as \"250ms\": %q", req.Latency))
+			}
+			faults.Latency = latency
+		}
+		validationErrors = append(validationErrors, faults.Validate()...)
+		if len(validationErrors) > 0 {
+			log.WarnContext(ctx, "invalid request", slog.Any("validation_errors", validationErrors))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, validationErrors)
+		}
+
+		if err := fi.SetFaults(faults); err != nil {
+			log.ErrorContext(ctx, "failed to set faults", slog.String("error", err.Error()))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, nil)
+		}
+
+		log.WarnContext(ctx, "storage faults updated",
+			slog.Float64("error_rate", faults.ErrorRate),
+			slog.Duration("latency", faults.Latency),
+			slog.Any("methods", faults.Methods),
+		)
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   toSettings(fi.Faults()),
+		})
+		return nil
+	})
+}
+
+func toSettings(f faultstorage.Faults) models.FaultSettings {
+	methods := f.Methods
+	if methods == nil {
+		methods = []string{}
+	}
+	return models.FaultSettings{
+		ErrorRate: f.ErrorRate,
+		Latency:   f.Latency.String(),
+		Methods:   methods,
+	}
+}
+
+type SyncRunner interface {
+	Status() models.SyncStatus
+	Trigger()
+}
+
+// NewGetSyncStatusHandler serves GET /admin/sync/status.
+func NewGetSyncStatusHandler(logger *slog.Logger, sr SyncRunner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.admin.GetSyncStatus"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		log.InfoContext(ctx, "retrieved sync status")
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   sr.Status(),
+		})
+	}
+}
+
+// NewRunSyncHandler serves POST /admin/sync/run. The sync runs in the
+// background; its report shows up in the status once it is done.
+func NewRunSyncHandler(logger *slog.Logger, sr SyncRunner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.admin.RunSync"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		sr.Trigger()
+
+		log.InfoContext(ctx, "quote sync triggered")
+		response.JSON(w, r, http.StatusAccepted, models.SuccessDataResponse{
+			Status: "success",
+			Data:   sr.Status(),
+		})
+	}
+}
+
+type DigestRunner interface {
+	Status() models.DigestStatus
+	Trigger()
+}
+
+// NewGetDigestStatusHandler serves GET /admin/digest/status.
+func NewGetDigestStatusHandler(logger *slog.Logger, dr DigestRunner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.admin.GetDigestStatus"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		log.InfoContext(ctx, "retrieved digest status")
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   dr.Status(),
+		})
+	}
+}
+
+// NewSendDigestHandler serves POST /admin/digest/send. The digest is sent
+// in the background; its report shows up in the status once it is done.
+func NewSendDigestHandler(logger *slog.Logger, dr DigestRunner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.admin.SendDigest"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		dr.Trigger()
+
+		log.InfoContext(ctx, "digest triggered")
+		response.JSON(w, r, http.StatusAccepted, models.SuccessDataResponse{
+			Status: "success",
+			Data:   dr.Status(),
+		})
+	}
+}
+
+type BackupRunner interface {
+	Status() models.BackupStatus
+	Trigger()
+}
+
+// NewGetBackupStatusHandler serves GET /admin/backup/status.
+func NewGetBackupStatusHandler(logger *slog.Logger, br BackupRunner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.admin.GetBackupStatus"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		log.InfoContext(ctx, "retrieved backup status")
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   br.Status(),
+		})
+	}
+}
+
+// NewUploadBackupHandler serves POST /admin/backup/upload. A snapshot is
+// taken and uploaded in the background; the result shows up in the status
+// once it is done.
+func NewUploadBackupHandler(logger *slog.Logger, br BackupRunner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.admin.UploadBackup"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		br.Trigger()
+
+		log.InfoContext(ctx, "backup triggered")
+		response.JSON(w, r, http.StatusAccepted, models.SuccessDataResponse{
+			Status: "success",
+			Data:   br.Status(),
+		})
+	}
+}
+
+type ReplicationRunner interface {
+	Status() models.ReplicationStatus
+	Backfill()
+}
+
+// NewGetReplicationStatusHandler serves GET /admin/replication/status.
+func NewGetReplicationStatusHandler(logger *slog.Logger, rr ReplicationRunner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.admin.GetReplicationStatus"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		log.InfoContext(ctx, "retrieved replication status")
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   rr.Status(),
+		})
+	}
+}
+
+// NewBackfillReplicationHandler serves POST /admin/replication/backfill.
+// Every quote is copied to the secondary store in the background; the
+// result shows up in the status once it is done.
+func NewBackfillReplicationHandler(logger *slog.Logger, rr ReplicationRunner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.admin.BackfillReplication"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		rr.Backfill()
+
+		log.InfoContext(ctx, "replication backfill triggered")
+		response.JSON(w, r, http.StatusAccepted, models.SuccessDataResponse{
+			Status: "success",
+			Data:   rr.Status(),
+		})
+	}
+}
+
+const (
+	defaultScheduleRuns = 5
+	maxScheduleRuns     = 50
+)
+
+type ScheduleReporter interface {
+	Status(n int) models.ScheduleStatus
+}
+
+// NewGetScheduleHandler serves GET /admin/schedule?limit=5, which shows the
+// publisher's schedule and its next fire times.
+func NewGetScheduleHandler(logger *slog.Logger, sr ScheduleReporter) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.admin.GetSchedule"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		limit := defaultScheduleRuns
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			parsed, err := strconv.Atoi(limitStr)
+			if err != nil || parsed < 1 || parsed > maxScheduleRuns {
+				log.WarnContext(ctx, "invalid limit query parameter", slog.String("limit", limitStr))
+				return apierror.New(http.StatusBadRequest, apierror.CodeInvalidLimit, nil)
+			}
+			limit = parsed
+		}
+
+		log.InfoContext(ctx, "retrieved schedule")
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   sr.Status(limit),
+		})
+		return nil
+	})
+}
+
+const (
+	defaultSlowRequests = 10
+	maxSlowRequests     = 100
+)
+
+type SlowRequestReporter interface {
+	Report(n int) models.SlowRequests
+}
+
+// NewGetSlowRequestsHandler serves GET /admin/slow?limit=10, which lists the
+// slowest recent requests for triage.
+func NewGetSlowRequestsHandler(logger *slog.Logger, sr SlowRequestReporter) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.admin.GetSlowRequests"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		limit := defaultSlowRequests
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			parsed, err := strconv.Atoi(limitStr)
+			if err != nil || parsed < 1 || parsed > maxSlowRequests {
+				log.WarnContext(ctx, "invalid limit query parameter", slog.String("limit", limitStr))
+				return apierror.New(http.StatusBadRequest, apierror.CodeInvalidLimit, nil)
+			}
+			limit = parsed
+		}
+
+		log.InfoContext(ctx, "retrieved slow requests")
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   sr.Report(limit),
+		})
+		return nil
+	})
+}
+
+type HealthSummarizer interface {
+	Summary() models.HealthSummary
+}
+
+// NewGetHealthSummaryHandler serves GET /admin/health/summary, the request
+// counts, error rates, latency percentiles, storage errors and panics of the
+// last 5, 30 and 60 minutes, for telling at a glance whether failures are
+// ours.
+func NewGetHealthSummaryHandler(logger *slog.Logger, hs HealthSummarizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.admin.GetHealthSummary"
+		log := logger.With(slog.String("op", op))
+
+		log.InfoContext(r.Context(), "retrieved health summary")
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   hs.Summary(),
+		})
+	}
+}
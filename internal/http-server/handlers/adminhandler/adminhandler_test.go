@@ -0,0 +1,570 @@
+package adminhandler_test
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/handlers/adminhandler"
+	"quotes-service/internal/http-server/middleware/auth"
+	"quotes-service/internal/lib/role"
+	"quotes-service/internal/lib/slowest"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage/faultstorage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func TestFaultsHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name           string
+		apiKey         string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "set faults",
+			apiKey:         "admin-key",
+			body:           `{"error_rate":0.25,"latency":"150ms","methods":["GetRandomQuote"]}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"error_rate":0.25,"latency":"150ms","methods":["GetRandomQuote"]}}`,
+		},
+		{
+			name:           "clear faults",
+			apiKey:         "admin-key",
+			body:           `{}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"error_rate":0,"latency":"0s","methods":[]}}`,
+		},
+		{
+			name:           "invalid settings",
+			apiKey:         "admin-key",
+			body:           `{"error_rate":2,"latency":"soon","methods":["Nope"]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_request","error":"Invalid request.","fields":["latency must be a duration such as \"250ms\": \"soon\"","error_rate must be between 0 and 1","unknown method \"Nope\""]}`,
+		},
+		{
+			name:           "anonymous",
+			body:           `{}`,
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"status":"error","code":"auth_required","error":"Authentication required."}`,
+		},
+		{
+			name:           "not an admin",
+			apiKey:         "user-key",
+			body:           `{}`,
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"status":"error","code":"insufficient_role","error":"The writer role cannot do this; it needs admin."}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			inner, err := memorystorage.New()
+			if err != nil {
+				t.Fatalf("failed to init storage: %v", err)
+			}
+			injector := faultstorage.New(inner)
+
+			router := mux.NewRouter()
+			router.Use(auth.New(logger, map[string]string{"admin-key": "ops", "user-key": "alice"}))
+			router.Use(auth.Require(logger, auth.Roles{Principals: map[string]role.Role{"ops": role.Admin}}, role.Admin))
+			router.HandleFunc("/admin/faults", adminhandler.NewSetFaultsHandler(logger, injector)).Methods(http.MethodPut)
+
+			req := httptest.NewRequest(http.MethodPut, "/admin/faults", strings.NewReader(tc.body))
+			if tc.apiKey != "" {
+				req.Header.Set(auth.APIKeyHeader, tc.apiKey)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tc.expectedBody) {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+type MockSyncRunner struct {
+	StatusFunc func() models.SyncStatus
+	Triggered  int
+}
+
+func (m *MockSyncRunner) Status() models.SyncStatus {
+	return m.StatusFunc()
+}
+
+func (m *MockSyncRunner) Trigger() {
+	m.Triggered++
+}
+
+func TestSyncHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	finished := time.Date(2024, time.March, 10, 12, 0, 5, 0, time.UTC)
+	status := models.SyncStatus{
+		SourceURL: "https://api.quotable.io/quotes",
+		Interval:  "1h0m0s",
+		LastRun: &models.SyncReport{
+			Trigger:    "schedule",
+			StartedAt:  finished.Add(-5 * time.Second),
+			FinishedAt: finished,
+			Fetched:    40,
+			Inserted:   3,
+			Skipped:    36,
+			Errors:     1,
+			LastError:  "add quote: context canceled",
+		},
+	}
+	statusBody := `{"source_url":"https://api.quotable.io/quotes","interval":"1h0m0s","running":false,"last_run":{"trigger":"schedule","started_at":"2024-03-10T12:00:00Z","finished_at":"2024-03-10T12:00:05Z","fetched":40,"inserted":3,"skipped":36,"errors":1,"last_error":"add quote: context canceled"}}`
+
+	tests := []struct {
+		name            string
+		method          string
+		path            string
+		expectedStatus  int
+		expectedBody    string
+		expectedTrigger int
+	}{
+		{
+			name:           "status",
+			method:         http.MethodGet,
+			path:           "/admin/sync/status",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":` + statusBody + `}`,
+		},
+		{
+			name:            "run",
+			method:          http.MethodPost,
+			path:            "/admin/sync/run",
+			expectedStatus:  http.StatusAccepted,
+			expectedBody:    `{"status":"success","data":` + statusBody + `}`,
+			expectedTrigger: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := &MockSyncRunner{StatusFunc: func() models.SyncStatus { return status }}
+
+			router := mux.NewRouter()
+			router.HandleFunc("/admin/sync/status", adminhandler.NewGetSyncStatusHandler(logger, runner)).Methods(http.MethodGet)
+			router.HandleFunc("/admin/sync/run", adminhandler.NewRunSyncHandler(logger, runner)).Methods(http.MethodPost)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.path, nil))
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tc.expectedBody) {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
+			}
+			if runner.Triggered != tc.expectedTrigger {
+				t.Errorf("expected %d triggers, got %d", tc.expectedTrigger, runner.Triggered)
+			}
+		})
+	}
+}
+
+type MockScheduleReporter struct {
+	StatusFunc func(n int) models.ScheduleStatus
+	Requested  int
+}
+
+func (m *MockScheduleReporter) Status(n int) models.ScheduleStatus {
+	m.Requested = n
+	return m.StatusFunc(n)
+}
+
+func TestGetScheduleHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	next := time.Date(2024, time.March, 11, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name              string
+		query             string
+		expectedStatus    int
+		expectedBody      string
+		expectedRequested int
+	}{
+		{
+			name:              "default limit",
+			expectedStatus:    http.StatusOK,
+			expectedBody:      `{"status":"success","data":{"cron":"0 9 * * MON-FRI","timezone":"UTC","mode":"daily","backfill":false,"next_runs":["2024-03-11T09:00:00Z","2024-03-12T09:00:00Z","2024-03-13T09:00:00Z","2024-03-14T09:00:00Z","2024-03-15T09:00:00Z"]}}`,
+			expectedRequested: 5,
+		},
+		{
+			name:              "custom limit",
+			query:             "?limit=1",
+			expectedStatus:    http.StatusOK,
+			expectedBody:      `{"status":"success","data":{"cron":"0 9 * * MON-FRI","timezone":"UTC","mode":"daily","backfill":false,"next_runs":["2024-03-11T09:00:00Z"]}}`,
+			expectedRequested: 1,
+		},
+		{
+			name:           "limit too large",
+			query:          "?limit=51",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_limit","error":"Limit must be a positive integer."}`,
+		},
+		{
+			name:           "invalid limit",
+			query:          "?limit=abc",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_limit","error":"Limit must be a positive integer."}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reporter := &MockScheduleReporter{StatusFunc: func(n int) models.ScheduleStatus {
+				runs := make([]time.Time, n)
+				for i := range runs {
+					runs[i] = next.AddDate(0, 0, i)
+				}
+				return models.ScheduleStatus{Cron: "0 9 * * MON-FRI", Timezone: "UTC", Mode: "daily", NextRuns: runs}
+			}}
+
+			router := mux.NewRouter()
+			router.HandleFunc("/admin/schedule", adminhandler.NewGetScheduleHandler(logger, reporter)).Methods(http.MethodGet)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/schedule"+tc.query, nil))
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tc.expectedBody) {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
+			}
+			if reporter.Requested != tc.expectedRequested {
+				t.Errorf("expected %d runs requested, got %d", tc.expectedRequested, reporter.Requested)
+			}
+		})
+	}
+}
+
+type MockDigestRunner struct {
+	StatusFunc func() models.DigestStatus
+	Triggered  int
+}
+
+func (m *MockDigestRunner) Status() models.DigestStatus {
+	return m.StatusFunc()
+}
+
+func (m *MockDigestRunner) Trigger() {
+	m.Triggered++
+}
+
+func TestDigestHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	since := time.Date(2024, time.March, 4, 9, 0, 0, 0, time.UTC)
+	status := models.DigestStatus{
+		Cron:       "0 9 * * MON",
+		Timezone:   "UTC",
+		Recipients: 2,
+		Since:      since,
+		LastRun: &models.DigestReport{
+			Trigger:    "schedule",
+			StartedAt:  since,
+			FinishedAt: since.Add(time.Second),
+			Since:      since.AddDate(0, 0, -7),
+			Until:      since,
+			Quotes:     3,
+			Sent:       true,
+		},
+	}
+	statusBody := `{"cron":"0 9 * * MON","timezone":"UTC","recipients":2,"running":false,"since":"2024-03-04T09:00:00Z","last_run":{"trigger":"schedule","started_at":"2024-03-04T09:00:00Z","finished_at":"2024-03-04T09:00:01Z","since":"2024-02-26T09:00:00Z","until":"2024-03-04T09:00:00Z","quotes":3,"sent":true}}`
+
+	tests := []struct {
+		name            string
+		method          string
+		path            string
+		expectedStatus  int
+		expectedBody    string
+		expectedTrigger int
+	}{
+		{
+			name:           "status",
+			method:         http.MethodGet,
+			path:           "/admin/digest/status",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":` + statusBody + `}`,
+		},
+		{
+			name:            "send",
+			method:          http.MethodPost,
+			path:            "/admin/digest/send",
+			expectedStatus:  http.StatusAccepted,
+			expectedBody:    `{"status":"success","data":` + statusBody + `}`,
+			expectedTrigger: 1,
+		},
+		{
+			name:           "send requires POST",
+			method:         http.MethodGet,
+			path:           "/admin/digest/send",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := &MockDigestRunner{StatusFunc: func() models.DigestStatus { return status }}
+
+			router := mux.NewRouter()
+			router.HandleFunc("/admin/digest/status", adminhandler.NewGetDigestStatusHandler(logger, runner)).Methods(http.MethodGet)
+			router.HandleFunc("/admin/digest/send", adminhandler.NewSendDigestHandler(logger, runner)).Methods(http.MethodPost)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.path, nil))
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tc.expectedBody != "" && strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tc.expectedBody) {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
+			}
+			if runner.Triggered != tc.expectedTrigger {
+				t.Errorf("expected %d triggers, got %d", tc.expectedTrigger, runner.Triggered)
+			}
+		})
+	}
+}
+
+type MockBackupRunner struct {
+	StatusFunc func() models.BackupStatus
+	Triggered  int
+}
+
+func (m *MockBackupRunner) Status() models.BackupStatus {
+	return m.StatusFunc()
+}
+
+func (m *MockBackupRunner) Trigger() {
+	m.Triggered++
+}
+
+func TestBackupHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	at := time.Date(2024, time.March, 11, 9, 0, 0, 0, time.UTC)
+	status := models.BackupStatus{
+		Interval: "6h0m0s",
+		Bucket:   "backups",
+		Prefix:   "prod/",
+		LastSnapshot: &models.BackupSnapshot{
+			Trigger:   "schedule",
+			CreatedAt: at,
+			Quotes:    2,
+			Bytes:     312,
+		},
+		LastUpload: &models.BackupUpload{
+			Trigger:    "schedule",
+			StartedAt:  at,
+			FinishedAt: at.Add(2 * time.Second),
+			Key:        "prod/quotes-20240311T090000Z.json.gz",
+			Attempts:   3,
+			Error:      "attempt 1: s3: 503 SlowDown: Please reduce your request rate.",
+		},
+	}
+	statusBody := `{"interval":"6h0m0s","bucket":"backups","prefix":"prod/","uploading":false,"last_snapshot":{"trigger":"schedule","created_at":"2024-03-11T09:00:00Z","quotes":2,"bytes":312},"last_upload":{"trigger":"schedule","started_at":"2024-03-11T09:00:00Z","finished_at":"2024-03-11T09:00:02Z","key":"prod/quotes-20240311T090000Z.json.gz","attempts":3,"pruned":0,"error":"attempt 1: s3: 503 SlowDown: Please reduce your request rate."}}`
+
+	tests := []struct {
+		name            string
+		method          string
+		path            string
+		expectedStatus  int
+		expectedBody    string
+		expectedTrigger int
+	}{
+		{
+			name:           "status",
+			method:         http.MethodGet,
+			path:           "/admin/backup/status",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":` + statusBody + `}`,
+		},
+		{
+			name:            "upload",
+			method:          http.MethodPost,
+			path:            "/admin/backup/upload",
+			expectedStatus:  http.StatusAccepted,
+			expectedBody:    `{"status":"success","data":` + statusBody + `}`,
+			expectedTrigger: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := &MockBackupRunner{StatusFunc: func() models.BackupStatus { return status }}
+
+			router := mux.NewRouter()
+			router.HandleFunc("/admin/backup/status", adminhandler.NewGetBackupStatusHandler(logger, runner)).Methods(http.MethodGet)
+			router.HandleFunc("/admin/backup/upload", adminhandler.NewUploadBackupHandler(logger, runner)).Methods(http.MethodPost)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.path, nil))
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tc.expectedBody) {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
+			}
+			if runner.Triggered != tc.expectedTrigger {
+				t.Errorf("expected %d triggers, got %d", tc.expectedTrigger, runner.Triggered)
+			}
+		})
+	}
+}
+
+type MockReplicationRunner struct {
+	StatusFunc func() models.ReplicationStatus
+	Backfills  int
+}
+
+func (m *MockReplicationRunner) Status() models.ReplicationStatus {
+	return m.StatusFunc()
+}
+
+func (m *MockReplicationRunner) Backfill() {
+	m.Backfills++
+}
+
+func TestReplicationHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	at := time.Date(2024, time.March, 11, 9, 0, 0, 0, time.UTC)
+	status := models.ReplicationStatus{
+		ReadsFrom:     "primary",
+		QueueDepth:    3,
+		QueueCapacity: 10000,
+		Mirrored:      120,
+		Divergences:   1,
+		LastError:     "AddQuote: queue is full",
+		LastErrorAt:   &at,
+		LastBackfill: &models.ReplicationBackfill{
+			StartedAt:  at,
+			FinishedAt: at.Add(time.Second),
+			Quotes:     100,
+			Copied:     100,
+			Batches:    1,
+		},
+	}
+	statusBody := `{"reads_from":"primary","queue_depth":3,"queue_capacity":10000,"mirrored":120,"divergences":1,"last_error":"AddQuote: queue is full","last_error_at":"2024-03-11T09:00:00Z","backfilling":false,"last_backfill":{"started_at":"2024-03-11T09:00:00Z","finished_at":"2024-03-11T09:00:01Z","quotes":100,"copied":100,"batches":1}}`
+
+	tests := []struct {
+		name              string
+		method            string
+		path              string
+		expectedStatus    int
+		expectedBody      string
+		expectedBackfills int
+	}{
+		{
+			name:           "status",
+			method:         http.MethodGet,
+			path:           "/admin/replication/status",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":` + statusBody + `}`,
+		},
+		{
+			name:              "backfill",
+			method:            http.MethodPost,
+			path:              "/admin/replication/backfill",
+			expectedStatus:    http.StatusAccepted,
+			expectedBody:      `{"status":"success","data":` + statusBody + `}`,
+			expectedBackfills: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := &MockReplicationRunner{StatusFunc: func() models.ReplicationStatus { return status }}
+
+			router := mux.NewRouter()
+			router.HandleFunc("/admin/replication/status", adminhandler.NewGetReplicationStatusHandler(logger, runner)).Methods(http.MethodGet)
+			router.HandleFunc("/admin/replication/backfill", adminhandler.NewBackfillReplicationHandler(logger, runner)).Methods(http.MethodPost)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.path, nil))
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tc.expectedBody) {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
+			}
+			if runner.Backfills != tc.expectedBackfills {
+				t.Errorf("expected %d backfills, got %d", tc.expectedBackfills, runner.Backfills)
+			}
+		})
+	}
+}
+
+func TestGetSlowRequestsHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	now := time.Date(2024, time.March, 11, 9, 0, 0, 0, time.UTC)
+	window := slowest.New(5, time.Minute, slowest.WithClock(func() time.Time { return now }))
+	for i, d := range []time.Duration{20 * time.Millisecond, 5 * time.Millisecond, 1500 * time.Millisecond} {
+		window.Record(slowest.Request{
+			Time:      now,
+			RequestID: fmt.Sprintf("req%d", i),
+			Method:    http.MethodGet,
+			Route:     "/quotes/{id}",
+			Status:    http.StatusOK,
+			Duration:  d,
+		})
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "default limit",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"window":"1m0s","requests":[{"time":"2024-03-11T09:00:00Z","request_id":"req2","method":"GET","route":"/quotes/{id}","status":200,"duration":"1.5s","duration_ms":1500},{"time":"2024-03-11T09:00:00Z","request_id":"req0","method":"GET","route":"/quotes/{id}","status":200,"duration":"20ms","duration_ms":20},{"time":"2024-03-11T09:00:00Z","request_id":"req1","method":"GET","route":"/quotes/{id}","status":200,"duration":"5ms","duration_ms":5}]}}`,
+		},
+		{
+			name:           "custom limit",
+			query:          "?limit=1",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"window":"1m0s","requests":[{"time":"2024-03-11T09:00:00Z","request_id":"req2","method":"GET","route":"/quotes/{id}","status":200,"duration":"1.5s","duration_ms":1500}]}}`,
+		},
+		{
+			name:           "limit too large",
+			query:          "?limit=101",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_limit","error":"Limit must be a positive integer."}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router := mux.NewRouter()
+			router.HandleFunc("/admin/slow", adminhandler.NewGetSlowRequestsHandler(logger, window)).Methods(http.MethodGet)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/slow"+tc.query, nil))
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tc.expectedBody) {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package adminhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/pagination"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/logger/sl"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// auditExportBatch is how many entries an export reads from the store at
+// a time.
+const auditExportBatch = 1000
+
+// NewGetAuditHandler serves GET /admin/audit, the audit trail oldest
+// first, filtered by ?principal=, ?operation= (such as "DELETE
+// /quotes/{id}"), ?quote_id= and the RFC 3339 times ?since= and ?until=.
+// It is paginated like the other lists, unless ?format=ndjson asks for
+// every matching entry as JSON Lines.
+func NewGetAuditHandler(logger *slog.Logger, as storage.AuditStore, sizes pagination.Sizes) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.admin.GetAudit"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		query, param := parseAuditQuery(r)
+		if param != "" {
+			log.WarnContext(ctx, "invalid audit query parameter", slog.String("param", param), sl.UserText("value", r.URL.Query().Get(param)))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, param)
+		}
+
+		switch format := r.URL.Query().Get("format"); format {
+		case "":
+		case "ndjson":
+			return exportAudit(w, r, log, as, query)
+		default:
+			log.WarnContext(ctx, "invalid audit format", slog.String("format", format))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, "format")
+		}
+
+		page, err := pagination.Parse(r, sizes)
+		if err != nil {
+			log.WarnContext(ctx, "invalid pagination", sl.UserText("query", r.URL.RawQuery), slog.String("error", err.Error()))
+			if errors.Is(err, pagination.ErrInvalidOffset) {
+				return apierror.New(http.StatusBadRequest, apierror.CodeInvalidOffset, nil)
+			}
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidLimit, nil)
+		}
+		query.Limit, query.Offset = page.Limit, page.Offset
+
+		entries, total, err := as.QueryAudit(ctx, query)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to query audit trail", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeGetAuditFailed, nil)
+		}
+		if entries == nil {
+			entries = []models.AuditEntry{}
+		}
+
+		log.InfoContext(ctx, "retrieved audit entries", slog.Int("count", len(entries)), slog.Int("total", total))
+		pagination.SetHeaders(w, r, page, total)
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data: models.AuditPage{
+				Entries: entries,
+				Total:   total,
+				Limit:   page.Limit,
+				Offset:  page.Offset,
+			},
+		})
+		return nil
+	})
+}
+
+// exportAudit writes every entry matching query as JSON Lines. It reads
+// them in batches after the last one written, so entries recorded or
+// trimmed meanwhile neither repeat nor shift the export. Only a failure
+// before the first line is returned; later ones cut the export short.
+func exportAudit(w http.ResponseWriter, r *http.Request, log *slog.Logger, as storage.AuditStore, query storage.AuditQuery) error {
+	ctx := r.Context()
+	query.Limit = auditExportBatch
+
+	entries, _, err := as.QueryAudit(ctx, query)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to query audit trail", slog.String("error", err.Error()))
+		return apierror.New(http.StatusInternalServerError, apierror.CodeGetAuditFailed, nil)
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit.jsonl"`)
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	count := 0
+	for len(entries) > 0 {
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				log.ErrorContext(ctx, "failed to write audit export", slog.String("error", err.Error()))
+				return nil
+			}
+		}
+		count += len(entries)
+		if len(entries) < auditExportBatch {
+			break
+		}
+		query.AfterSeq = entries[len(entries)-1].Seq
+		// The response has started, so a failure can only cut it short.
+		if entries, _, err = as.QueryAudit(ctx, query); err != nil {
+			log.ErrorContext(ctx, "failed to query audit trail during export", slog.Int("written", count), slog.String("error", err.Error()))
+			return nil
+		}
+	}
+
+	log.InfoContext(ctx, "exported audit entries", slog.Int("count", count))
+	return nil
+}
+
+// parseAuditQuery reads the filters of r, or returns the name of the one
+// that is invalid.
+func parseAuditQuery(r *http.Request) (storage.AuditQuery, string) {
+	values := r.URL.Query()
+	q := storage.AuditQuery{
+		Principal: values.Get("principal"),
+		Operation: values.Get("operation"),
+	}
+	if raw := values.Get("quote_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			return q, "quote_id"
+		}
+		q.QuoteID = id
+	}
+	for _, bound := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &q.Since}, {"until", &q.Until}} {
+		if raw := values.Get(bound.name); raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return q, bound.name
+			}
+			*bound.dst = parsed
+		}
+	}
+	return q, ""
+}
@@ -0,0 +1,139 @@
+package adminhandler_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"quotes-service/internal/http-server/handlers/adminhandler"
+	"quotes-service/internal/http-server/pagination"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func TestGetAuditHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	start := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	now := start
+	store, err := memorystorage.New(memorystorage.WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for _, e := range []models.AuditEntry{
+		{Principal: "importer", Operation: "POST /quotes", QuoteID: 1, Status: 201},
+		{Principal: "editor", Operation: "PATCH /quotes/{id}", QuoteID: 1, Status: 200},
+		{Principal: "editor", Operation: "DELETE /quotes/{id}", QuoteID: 1, Status: 200},
+		{Principal: "ops", Operation: "POST /admin/keys", Status: 201},
+		{Principal: "importer", Operation: "POST /quotes", QuoteID: 2, Status: 201},
+	} {
+		if err := store.RecordAudit(context.Background(), e); err != nil {
+			t.Fatalf("failed to record: %v", err)
+		}
+		now = now.Add(time.Minute)
+	}
+	handler := adminhandler.NewGetAuditHandler(logger, store, pagination.Sizes{Default: 2, Max: 10})
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedSeqs   []uint64
+		expectedTotal  int
+	}{
+		{name: "first page", expectedStatus: http.StatusOK, expectedSeqs: []uint64{1, 2}, expectedTotal: 5},
+		{name: "principal", query: "?principal=editor", expectedStatus: http.StatusOK, expectedSeqs: []uint64{2, 3}, expectedTotal: 2},
+		{name: "operation", query: "?operation=POST+/quotes&limit=10", expectedStatus: http.StatusOK, expectedSeqs: []uint64{1, 5}, expectedTotal: 2},
+		{name: "quote", query: "?quote_id=1&limit=10", expectedStatus: http.StatusOK, expectedSeqs: []uint64{1, 2, 3}, expectedTotal: 3},
+		{
+			name:           "time range",
+			query:          "?since=2024-03-10T12:01:00Z&until=2024-03-10T12:04:00Z&limit=10",
+			expectedStatus: http.StatusOK,
+			expectedSeqs:   []uint64{2, 3, 4},
+			expectedTotal:  3,
+		},
+		{name: "no match", query: "?principal=nobody", expectedStatus: http.StatusOK, expectedSeqs: []uint64{}, expectedTotal: 0},
+		{name: "bad quote id", query: "?quote_id=x", expectedStatus: http.StatusBadRequest},
+		{name: "bad time", query: "?since=yesterday", expectedStatus: http.StatusBadRequest},
+		{name: "bad format", query: "?format=csv", expectedStatus: http.StatusBadRequest},
+		{name: "bad offset", query: "?offset=-1", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/audit"+tc.query, nil))
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Data models.AuditPage `json:"data"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			seqs := []uint64{}
+			for _, e := range resp.Data.Entries {
+				seqs = append(seqs, e.Seq)
+			}
+			if !slices.Equal(seqs, tc.expectedSeqs) || resp.Data.Total != tc.expectedTotal {
+				t.Fatalf("expected %v of %d, got %v of %d", tc.expectedSeqs, tc.expectedTotal, seqs, resp.Data.Total)
+			}
+			if got := rr.Header().Get(pagination.TotalCountHeader); got != fmt.Sprint(tc.expectedTotal) {
+				t.Fatalf("expected X-Total-Count %d, got %q", tc.expectedTotal, got)
+			}
+		})
+	}
+}
+
+func TestExportAudit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	// More than one batch, with every third entry by the principal asked
+	// for.
+	for i := 0; i < 3500; i++ {
+		principal := []string{"importer", "editor", "ops"}[i%3]
+		if err := store.RecordAudit(context.Background(), models.AuditEntry{Principal: principal, Operation: "POST /quotes", Status: 201}); err != nil {
+			t.Fatalf("failed to record: %v", err)
+		}
+	}
+	handler := adminhandler.NewGetAuditHandler(logger, store, pagination.Sizes{Default: 2, Max: 10})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/audit?format=ndjson&principal=editor", nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("expected a JSON Lines export, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+
+	var last uint64
+	count := 0
+	scanner := bufio.NewScanner(rr.Body)
+	for scanner.Scan() {
+		var e models.AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("line %d: %v", count+1, err)
+		}
+		if e.Principal != "editor" || e.Seq <= last {
+			t.Fatalf("line %d: expected editor's entries in order, got %+v after seq %d", count+1, e, last)
+		}
+		last = e.Seq
+		count++
+	}
+	if count != 1167 {
+		t.Fatalf("expected every one of editor's 1167 entries, got %d", count)
+	}
+}
@@ -0,0 +1,34 @@
+package adminhandler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/models"
+)
+
+// DeprecationUsage reports who uses the deprecated routes and fields.
+// *deprecation.Registry is the real one.
+type DeprecationUsage interface {
+	Usage() []models.DeprecationUsage
+}
+
+// NewGetDeprecationUsageHandler serves GET /admin/deprecations/usage, every
+// deprecated route and field with its sunset and the callers still using
+// it, the most frequent first. Entries no one has used since the start are
+// listed too, with no callers: they are the ones safe to remove.
+func NewGetDeprecationUsageHandler(logger *slog.Logger, du DeprecationUsage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.admin.GetDeprecationUsage"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		usage := du.Usage()
+		log.InfoContext(ctx, "retrieved deprecation usage", slog.Int("entries", len(usage)))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   usage,
+		})
+	}
+}
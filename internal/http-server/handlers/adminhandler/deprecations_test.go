@@ -0,0 +1,49 @@
+package adminhandler_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"quotes-service/internal/http-server/handlers/adminhandler"
+	"quotes-service/internal/lib/deprecation"
+)
+
+func TestGetDeprecationUsageHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	registry := deprecation.New([]deprecation.Entry{
+		{
+			Method: http.MethodGet,
+			Route:  "/quotes/digest",
+			Since:  time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC),
+			Sunset: time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC),
+			Link:   "https://docs.example.com/digest",
+		},
+		{
+			Route:  "/quotes/{id}",
+			Field:  "author_key",
+			Since:  time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+			Sunset: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+			Link:   "https://docs.example.com/author-key",
+		},
+	}, deprecation.Options{LogInterval: time.Hour}, deprecation.WithClock(func() time.Time { return now }))
+	registry.Record(http.MethodGet, "/quotes/digest", "ip:192.0.2.1")
+	registry.Record(http.MethodGet, "/quotes/digest", "principal:alice")
+	registry.Record(http.MethodGet, "/quotes/digest", "principal:alice")
+
+	rr := httptest.NewRecorder()
+	adminhandler.NewGetDeprecationUsageHandler(logger, registry).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/deprecations/usage", nil))
+
+	expected := `{"status":"success","data":[` +
+		`{"route":"GET /quotes/digest","since":"2026-05-01T00:00:00Z","sunset":"2026-12-01T00:00:00Z","link":"https://docs.example.com/digest","uses":3,"callers":[` +
+		`{"caller":"principal:alice","uses":2,"first_seen":"2026-06-01T12:00:00Z","last_seen":"2026-06-01T12:00:00Z"},` +
+		`{"caller":"ip:192.0.2.1","uses":1,"first_seen":"2026-06-01T12:00:00Z","last_seen":"2026-06-01T12:00:00Z"}]},` +
+		`{"route":"/quotes/{id}","field":"author_key","since":"2026-04-01T00:00:00Z","sunset":"2027-01-01T00:00:00Z","link":"https://docs.example.com/author-key","uses":0,"callers":[]}]}` + "\n"
+	if rr.Code != http.StatusOK || rr.Body.String() != expected {
+		t.Fatalf("expected 200 with\n%s\ngot %d with\n%s", expected, rr.Code, rr.Body.String())
+	}
+}
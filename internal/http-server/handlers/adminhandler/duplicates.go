@@ -0,0 +1,138 @@
+package adminhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/pagination"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/logger/sl"
+	"quotes-service/internal/lib/usertext"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage/dedupe"
+)
+
+// duplicateTextChars is how much of each quote's text a duplicate group
+// shows, enough to tell what the quote is.
+const duplicateTextChars = 80
+
+// NewGetDuplicatesHandler serves GET /admin/duplicates, the groups of
+// quotes that differ only in case, punctuation, spacing or typography,
+// paginated like the other lists. Each request scans the whole catalog.
+func NewGetDuplicatesHandler(logger *slog.Logger, store dedupe.Store, sizes pagination.Sizes) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.admin.GetDuplicates"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		page, err := pagination.Parse(r, sizes)
+		if err != nil {
+			log.WarnContext(ctx, "invalid pagination", sl.UserText("query", r.URL.RawQuery), slog.String("error", err.Error()))
+			if errors.Is(err, pagination.ErrInvalidOffset) {
+				return apierror.New(http.StatusBadRequest, apierror.CodeInvalidOffset, nil)
+			}
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidLimit, nil)
+		}
+
+		groups, err := dedupe.Scan(ctx, store)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to scan for duplicates", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeGetDuplicatesFailed, nil)
+		}
+
+		total := len(groups)
+		groups = groups[min(page.Offset, total):min(page.Offset+page.Limit, total)]
+		out := make([]models.DuplicateGroup, 0, len(groups))
+		for _, g := range groups {
+			dg := models.DuplicateGroup{
+				Key:      g.Key,
+				QuoteIDs: make([]int64, 0, len(g.Quotes)),
+				Texts:    make([]string, 0, len(g.Quotes)),
+			}
+			for _, q := range g.Quotes {
+				dg.QuoteIDs = append(dg.QuoteIDs, q.ID)
+				dg.Texts = append(dg.Texts, usertext.Truncate(q.Text, duplicateTextChars))
+			}
+			out = append(out, dg)
+		}
+
+		log.InfoContext(ctx, "retrieved duplicate groups", slog.Int("count", len(out)), slog.Int("total", total))
+		pagination.SetHeaders(w, r, page, total)
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data: models.DuplicatePage{
+				Groups: out,
+				Total:  total,
+				Limit:  page.Limit,
+				Offset: page.Offset,
+			},
+		})
+		return nil
+	})
+}
+
+// NewResolveDuplicatesHandler serves POST /admin/duplicates/resolve, which
+// scans the catalog again and deletes all but one quote of each duplicate
+// group, the oldest or newest as the strategy in the body says. It answers
+// with how many quotes went; groups that changed meanwhile are skipped.
+func NewResolveDuplicatesHandler(logger *slog.Logger, store dedupe.Store) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.admin.ResolveDuplicates"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		var req models.ResolveDuplicatesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				log.WarnContext(ctx, "request body is empty")
+				return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyEmpty, nil)
+			}
+			log.ErrorContext(ctx, "failed to decode request body", slog.String("error", err.Error()))
+			return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyInvalid, nil)
+		}
+		defer r.Body.Close()
+
+		if !dedupe.ValidStrategy(req.Strategy) {
+			log.WarnContext(ctx, "invalid strategy", sl.UserText("strategy", req.Strategy))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, []string{"strategy must be keep_oldest or keep_newest"})
+		}
+
+		groups, err := dedupe.Scan(ctx, store)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to scan for duplicates", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeResolveDuplicatesFailed, nil)
+		}
+		result, err := dedupe.Resolve(ctx, store, groups, req.Strategy)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to resolve duplicates",
+				slog.Int("resolved", result.Resolved),
+				slog.Int("deleted", result.Deleted),
+				slog.String("error", err.Error()),
+			)
+			return apierror.New(http.StatusInternalServerError, apierror.CodeResolveDuplicatesFailed, nil)
+		}
+
+		log.WarnContext(ctx, "duplicates resolved",
+			slog.String("strategy", req.Strategy),
+			slog.Int("groups", len(groups)),
+			slog.Int("deleted", result.Deleted),
+			slog.Int("skipped", result.Skipped),
+		)
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data: models.DuplicateResolution{
+				Strategy: req.Strategy,
+				Groups:   len(groups),
+				Resolved: result.Resolved,
+				Deleted:  result.Deleted,
+				Skipped:  result.Skipped,
+				Atomic:   result.Atomic,
+			},
+		})
+		return nil
+	})
+}
@@ -0,0 +1,139 @@
+package adminhandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"quotes-service/internal/http-server/handlers/adminhandler"
+	"quotes-service/internal/http-server/pagination"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func newDuplicateStore(t *testing.T) *memorystorage.Storage {
+	t.Helper()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for _, q := range []models.Quote{
+		{Text: "Stay hungry, stay foolish.", Author: "Steve Jobs"},
+		{Text: "Imagination is more important than knowledge.", Author: "Albert Einstein"},
+		{Text: "STAY HUNGRY — STAY FOOLISH", Author: "Steve Jobs"},
+		{Text: "imagination is more important than knowledge", Author: "Albert  Einstein"},
+		{Text: "Be yourself; everyone else is already taken.", Author: "Oscar Wilde"},
+	} {
+		if _, err := store.AddQuote(context.Background(), q); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+	return store
+}
+
+func TestGetDuplicatesHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := adminhandler.NewGetDuplicatesHandler(logger, newDuplicateStore(t), pagination.Sizes{Default: 1, Max: 10})
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedGroups [][]int64
+	}{
+		{name: "first page", expectedStatus: http.StatusOK, expectedGroups: [][]int64{{1, 3}}},
+		{name: "second page", query: "?offset=1", expectedStatus: http.StatusOK, expectedGroups: [][]int64{{2, 4}}},
+		{name: "past the end", query: "?offset=5", expectedStatus: http.StatusOK, expectedGroups: [][]int64{}},
+		{name: "bad limit", query: "?limit=x", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/duplicates"+tc.query, nil))
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Data models.DuplicatePage `json:"data"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			groups := [][]int64{}
+			for _, g := range resp.Data.Groups {
+				groups = append(groups, g.QuoteIDs)
+				if len(g.Texts) != len(g.QuoteIDs) || g.Key == "" {
+					t.Fatalf("expected a key and a text per quote, got %+v", g)
+				}
+			}
+			if !slices.EqualFunc(groups, tc.expectedGroups, slices.Equal) || resp.Data.Total != 2 {
+				t.Fatalf("expected %v of 2, got %v of %d", tc.expectedGroups, groups, resp.Data.Total)
+			}
+		})
+	}
+}
+
+func TestResolveDuplicatesHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name              string
+		body              string
+		expectedStatus    int
+		expectedRemaining []int64
+	}{
+		{name: "keep oldest", body: `{"strategy":"keep_oldest"}`, expectedStatus: http.StatusOK, expectedRemaining: []int64{1, 2, 5}},
+		{name: "keep newest", body: `{"strategy":"keep_newest"}`, expectedStatus: http.StatusOK, expectedRemaining: []int64{3, 4, 5}},
+		{name: "unknown strategy", body: `{"strategy":"keep_all"}`, expectedStatus: http.StatusBadRequest, expectedRemaining: []int64{1, 2, 3, 4, 5}},
+		{name: "empty body", expectedStatus: http.StatusBadRequest, expectedRemaining: []int64{1, 2, 3, 4, 5}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newDuplicateStore(t)
+			handler := adminhandler.NewResolveDuplicatesHandler(logger, store)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/duplicates/resolve", strings.NewReader(tc.body)))
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if rr.Code == http.StatusOK {
+				var resp struct {
+					Data models.DuplicateResolution `json:"data"`
+				}
+				if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if resp.Data.Groups != 2 || resp.Data.Deleted != 2 || !resp.Data.Atomic {
+					t.Fatalf("expected 2 groups resolved atomically, got %+v", resp.Data)
+				}
+			}
+
+			quotes, err := store.GetAllQuotes(context.Background(), storage.QuoteFilter{})
+			if err != nil {
+				t.Fatalf("failed to get quotes: %v", err)
+			}
+			var remaining []int64
+			for _, q := range quotes {
+				remaining = append(remaining, q.ID)
+			}
+			slices.Sort(remaining)
+			if !slices.Equal(remaining, tc.expectedRemaining) {
+				t.Fatalf("expected quotes %v to remain, got %v", tc.expectedRemaining, remaining)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package adminhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/features"
+	"quotes-service/internal/models"
+)
+
+// FeatureFlags holds the state of the optional features. *features.Set is
+// the real one.
+type FeatureFlags interface {
+	List() []models.FeatureFlag
+	Get(name string) (models.FeatureFlag, bool)
+	Set(name string, enabled bool) error
+}
+
+// NewGetFeaturesHandler serves GET /admin/features, every feature with its
+// state and whether it can change at runtime.
+func NewGetFeaturesHandler(logger *slog.Logger, ff FeatureFlags) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.admin.GetFeatures"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		log.InfoContext(ctx, "retrieved feature flags")
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   ff.List(),
+		})
+	}
+}
+
+// NewSetFeatureHandler serves PUT /admin/features/{name}, which turns a
+// dynamic feature on or off. Other features answer 409, as they change
+// only with the config.
+func NewSetFeatureHandler(logger *slog.Logger, ff FeatureFlags) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.admin.SetFeature"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		name := mux.Vars(r)["name"]
+		var req models.SetFeatureRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				log.WarnContext(ctx, "request body is empty")
+				return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyEmpty, nil)
+			}
+			log.ErrorContext(ctx, "failed to decode request body", slog.String("error", err.Error()))
+			return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyInvalid, nil)
+		}
+		defer r.Body.Close()
+
+		if req.Enabled == nil {
+			log.WarnContext(ctx, "invalid request", slog.String("feature", name))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, []string{"enabled is required"})
+		}
+
+		if err := ff.Set(name, *req.Enabled); err != nil {
+			if errors.Is(err, features.ErrUnknown) {
+				log.InfoContext(ctx, "feature not found", slog.String("feature", name))
+				return apierror.New(http.StatusNotFound, apierror.CodeFeatureNotFound, nil, name)
+			}
+			log.InfoContext(ctx, "feature is not dynamic", slog.String("feature", name))
+			return apierror.New(http.StatusConflict, apierror.CodeFeatureNotDynamic, nil, name)
+		}
+
+		flag, _ := ff.Get(name)
+		log.WarnContext(ctx, "feature flag updated", slog.String("feature", name), slog.Bool("enabled", flag.Enabled))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   flag,
+		})
+		return nil
+	})
+}
@@ -0,0 +1,123 @@
+package adminhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/apikeys"
+	"quotes-service/internal/lib/role"
+	"quotes-service/internal/models"
+)
+
+// KeyManager creates and revokes API keys at runtime. *apikeys.Store is
+// the real one.
+type KeyManager interface {
+	Create(name string, r role.Role) (models.CreatedAPIKey, error)
+	List() []models.APIKey
+	Revoke(id string) error
+}
+
+// NewCreateKeyHandler serves POST /admin/keys, which creates an API key for
+// a principal and answers 201 with its secret. The secret is not shown
+// again.
+func NewCreateKeyHandler(logger *slog.Logger, km KeyManager) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.admin.CreateKey"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		var req models.CreateAPIKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				log.WarnContext(ctx, "request body is empty")
+				return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyEmpty, nil)
+			}
+			log.ErrorContext(ctx, "failed to decode request body", slog.String("error", err.Error()))
+			return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyInvalid, nil)
+		}
+		defer r.Body.Close()
+
+		var fields []string
+		name := strings.TrimSpace(req.Name)
+		if name == "" {
+			fields = append(fields, "name is required")
+		}
+		var keyRole role.Role
+		if req.Role != "" {
+			parsed, err := role.Parse(req.Role)
+			if err != nil {
+				fields = append(fields, "role must be none, reader, writer or admin")
+			}
+			keyRole = parsed
+		}
+		if len(fields) > 0 {
+			log.WarnContext(ctx, "invalid request", slog.Any("fields", fields))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, fields)
+		}
+
+		key, err := km.Create(name, keyRole)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to create api key", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeCreateAPIKeyFailed, nil)
+		}
+
+		log.WarnContext(ctx, "api key created", slog.String("key_id", key.ID), slog.String("name", key.Name), slog.String("role", key.Role))
+		response.JSON(w, r, http.StatusCreated, models.SuccessDataResponse{
+			Status: "success",
+			Data:   key,
+		})
+		return nil
+	})
+}
+
+// NewGetKeysHandler serves GET /admin/keys, the keys created at runtime,
+// oldest first, without their secrets.
+func NewGetKeysHandler(logger *slog.Logger, km KeyManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.admin.GetKeys"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		keys := km.List()
+		log.InfoContext(ctx, "retrieved api keys", slog.Int("count", len(keys)))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   keys,
+		})
+	}
+}
+
+// NewRevokeKeyHandler serves DELETE /admin/keys/{id}. The key stops working
+// with the next request, even when saving the change fails with 500; it
+// then comes back on restart.
+func NewRevokeKeyHandler(logger *slog.Logger, km KeyManager) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.admin.RevokeKey"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		id := mux.Vars(r)["id"]
+		if err := km.Revoke(id); err != nil {
+			if errors.Is(err, apikeys.ErrNotFound) {
+				log.InfoContext(ctx, "api key not found", slog.String("key_id", id))
+				return apierror.New(http.StatusNotFound, apierror.CodeAPIKeyNotFound, nil)
+			}
+			log.ErrorContext(ctx, "failed to save revoked api key", slog.String("key_id", id), slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeRevokeAPIKeyFailed, nil)
+		}
+
+		log.WarnContext(ctx, "api key revoked", slog.String("key_id", id))
+		response.JSON(w, r, http.StatusOK, models.GenericMessageResponse{
+			Status:  "success",
+			Message: "API key revoked.",
+		})
+		return nil
+	})
+}
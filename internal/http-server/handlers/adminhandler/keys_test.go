@@ -0,0 +1,64 @@
+package adminhandler_test
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/handlers/adminhandler"
+	"quotes-service/internal/lib/apikeys"
+	"quotes-service/internal/lib/role"
+	"quotes-service/internal/models"
+)
+
+// brokenKeys fails to save anything.
+type brokenKeys struct{}
+
+func (brokenKeys) Create(name string, r role.Role) (models.CreatedAPIKey, error) {
+	return models.CreatedAPIKey{}, errors.New("disk full")
+}
+func (brokenKeys) List() []models.APIKey { return nil }
+func (brokenKeys) Revoke(id string) error {
+	if id != "abc" {
+		return apikeys.ErrNotFound
+	}
+	return errors.New("disk full")
+}
+
+func TestKeyHandlersErrors(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := mux.NewRouter()
+	router.HandleFunc("/admin/keys", adminhandler.NewCreateKeyHandler(logger, brokenKeys{})).Methods(http.MethodPost)
+	router.HandleFunc("/admin/keys/{id}", adminhandler.NewRevokeKeyHandler(logger, brokenKeys{})).Methods(http.MethodDelete)
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "empty body", method: http.MethodPost, url: "/admin/keys", expectedStatus: http.StatusBadRequest, expectedCode: "request_body_empty"},
+		{name: "no name", method: http.MethodPost, url: "/admin/keys", body: `{"role": "reader"}`, expectedStatus: http.StatusBadRequest, expectedCode: "invalid_request"},
+		{name: "unknown role", method: http.MethodPost, url: "/admin/keys", body: `{"name": "a", "role": "root"}`, expectedStatus: http.StatusBadRequest, expectedCode: "invalid_request"},
+		{name: "create fails", method: http.MethodPost, url: "/admin/keys", body: `{"name": "a"}`, expectedStatus: http.StatusInternalServerError, expectedCode: "create_api_key_failed"},
+		{name: "unknown key", method: http.MethodDelete, url: "/admin/keys/def", expectedStatus: http.StatusNotFound, expectedCode: "api_key_not_found"},
+		{name: "revoke not saved", method: http.MethodDelete, url: "/admin/keys/abc", expectedStatus: http.StatusInternalServerError, expectedCode: "revoke_api_key_failed"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body)))
+			if rr.Code != tc.expectedStatus || !strings.Contains(rr.Body.String(), `"`+tc.expectedCode+`"`) {
+				t.Fatalf("expected %d %s, got %d. Body: %s", tc.expectedStatus, tc.expectedCode, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package adminhandler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/moderation"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// ModerationQueue holds the quotes the content filter held.
+// *moderation.Queue is the real one.
+type ModerationQueue interface {
+	List() []models.HeldQuote
+	Take(id string) (moderation.Entry, error)
+	Restore(entry moderation.Entry)
+}
+
+// ModeratedStore stores the quotes a moderator approves.
+type ModeratedStore interface {
+	AddQuote(ctx context.Context, quote models.Quote) (int64, error)
+	UpdateQuote(ctx context.Context, id int64, update storage.QuoteUpdate, ifVersion int64) (models.Quote, error)
+}
+
+// NewGetHeldQuotesHandler serves GET /admin/moderation, the quotes held
+// for moderation, oldest first.
+func NewGetHeldQuotesHandler(logger *slog.Logger, mq ModerationQueue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.admin.GetHeldQuotes"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		held := mq.List()
+		log.InfoContext(ctx, "retrieved held quotes", slog.Int("count", len(held)))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   held,
+		})
+	}
+}
+
+// NewApproveHeldQuoteHandler serves POST /admin/moderation/{id}/approve.
+// A held quote is added and answers 201 Created; a held update is applied
+// and answers 200 OK. Either way the stored quote is returned. If storing
+// fails, the quote stays held.
+func NewApproveHeldQuoteHandler(logger *slog.Logger, mq ModerationQueue, store ModeratedStore) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.admin.ApproveHeldQuote"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		id := mux.Vars(r)["id"]
+		entry, err := mq.Take(id)
+		if err != nil {
+			log.InfoContext(ctx, "held quote not found", slog.String("held_id", id))
+			return apierror.New(http.StatusNotFound, apierror.CodeHeldQuoteNotFound, nil)
+		}
+
+		if entry.Update != nil {
+			quote, err := store.UpdateQuote(ctx, entry.QuoteID, *entry.Update, 0)
+			if err != nil {
+				mq.Restore(entry)
+				log.Log(ctx, apierror.Level(err), "failed to apply held update", slog.String("held_id", id), slog.String("error", err.Error()))
+				return apierror.Failed(apierror.CodeUpdateQuoteFailed, err)
+			}
+			log.InfoContext(ctx, "held update approved", slog.String("held_id", id), slog.Int64("id", quote.ID))
+			response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+				Status: "success",
+				Data:   quote,
+			})
+			return nil
+		}
+
+		quote := entry.Quote
+		quote.ID, err = store.AddQuote(ctx, quote)
+		if err != nil {
+			mq.Restore(entry)
+			log.ErrorContext(ctx, "failed to add held quote", slog.String("held_id", id), slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeAddQuoteFailed, nil)
+		}
+		log.InfoContext(ctx, "held quote approved", slog.String("held_id", id), slog.Int64("id", quote.ID))
+		response.JSON(w, r, http.StatusCreated, models.SuccessDataResponse{
+			Status: "success",
+			Data:   quote,
+		})
+		return nil
+	})
+}
+
+// NewRejectHeldQuoteHandler serves DELETE /admin/moderation/{id}, which
+// drops a held quote without storing it.
+func NewRejectHeldQuoteHandler(logger *slog.Logger, mq ModerationQueue) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.admin.RejectHeldQuote"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		id := mux.Vars(r)["id"]
+		if _, err := mq.Take(id); err != nil {
+			log.InfoContext(ctx, "held quote not found", slog.String("held_id", id))
+			return apierror.New(http.StatusNotFound, apierror.CodeHeldQuoteNotFound, nil)
+		}
+
+		log.InfoContext(ctx, "held quote rejected", slog.String("held_id", id))
+		response.JSON(w, r, http.StatusOK, models.GenericMessageResponse{
+			Status:  "success",
+			Message: "Held quote rejected.",
+		})
+		return nil
+	})
+}
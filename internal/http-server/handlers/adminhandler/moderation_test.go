@@ -0,0 +1,85 @@
+package adminhandler_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/handlers/adminhandler"
+	"quotes-service/internal/lib/moderation"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func TestModerationHandlers(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	id, err := store.AddQuote(ctx, models.Quote{Text: "Original", Author: "Author", Lang: "en"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queue := moderation.New(10)
+	hold := func(entry moderation.Entry) string {
+		held, err := queue.Hold(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return held.ID
+	}
+	text := "Updated"
+	created := hold(moderation.Entry{HeldQuote: models.HeldQuote{Source: "create", Quote: models.Quote{Text: "Held", Author: "Author", Lang: "en"}}})
+	updated := hold(moderation.Entry{HeldQuote: models.HeldQuote{Source: "update", QuoteID: id}, Update: &storage.QuoteUpdate{Text: &text}})
+	orphan := hold(moderation.Entry{HeldQuote: models.HeldQuote{Source: "update", QuoteID: 999}, Update: &storage.QuoteUpdate{Text: &text}})
+	rejected := hold(moderation.Entry{HeldQuote: models.HeldQuote{Source: "create", Quote: models.Quote{Text: "Rejected", Author: "Author"}}})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/admin/moderation", adminhandler.NewGetHeldQuotesHandler(logger, queue)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/moderation/{id}/approve", adminhandler.NewApproveHeldQuoteHandler(logger, queue, store)).Methods(http.MethodPost)
+	router.HandleFunc("/admin/moderation/{id}", adminhandler.NewRejectHeldQuoteHandler(logger, queue)).Methods(http.MethodDelete)
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		expectedStatus int
+	}{
+		{name: "list", method: http.MethodGet, url: "/admin/moderation", expectedStatus: http.StatusOK},
+		{name: "approve create", method: http.MethodPost, url: "/admin/moderation/" + created + "/approve", expectedStatus: http.StatusCreated},
+		{name: "approve update", method: http.MethodPost, url: "/admin/moderation/" + updated + "/approve", expectedStatus: http.StatusOK},
+		{name: "approve update of a deleted quote", method: http.MethodPost, url: "/admin/moderation/" + orphan + "/approve", expectedStatus: http.StatusNotFound},
+		{name: "reject", method: http.MethodDelete, url: "/admin/moderation/" + rejected, expectedStatus: http.StatusOK},
+		{name: "approve rejected", method: http.MethodPost, url: "/admin/moderation/" + rejected + "/approve", expectedStatus: http.StatusNotFound},
+		{name: "reject twice", method: http.MethodDelete, url: "/admin/moderation/" + rejected, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.url, nil))
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	quotes, err := store.GetAllQuotes(ctx, storage.QuoteFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quotes) != 2 || quotes[0].Text != "Updated" || quotes[1].Text != "Held" {
+		t.Fatalf("unexpected stored quotes %+v", quotes)
+	}
+	if held := queue.List(); len(held) != 1 || held[0].ID != orphan {
+		t.Fatalf("expected only the failed approval to stay held, got %+v", held)
+	}
+}
@@ -0,0 +1,50 @@
+package adminhandler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/bulk"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// reindexOp names reindexes in their resume tokens.
+const reindexOp = "reindex"
+
+// NewReindexHandler serves POST /admin/reindex, which rebuilds the search,
+// language and author index entries of every quote a batch at a time
+// within limits. A store with more quotes than a call gets through is
+// answered 202 with a resume token, to be sent back as ?resume_token= to
+// go on; the last call is answered 200.
+func NewReindexHandler(logger *slog.Logger, b storage.Batcher, limits bulk.Limits) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.admin.Reindex"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		after, err := bulk.Resume(r, reindexOp, nil)
+		if err != nil {
+			log.WarnContext(ctx, "invalid resume token")
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidResumeToken, nil)
+		}
+
+		progress, err := bulk.Run(ctx, limits, after, func(ctx context.Context, batch storage.Batch) (storage.BatchResult, error) {
+			return b.ReindexBatch(ctx, batch)
+		})
+		if err != nil {
+			log.ErrorContext(ctx, "failed to reindex", slog.Int("processed", progress.Processed), slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeReindexFailed, nil)
+		}
+
+		log.InfoContext(ctx, "quotes reindexed", slog.Int("reindexed", progress.Processed), slog.Bool("done", progress.Done))
+		response.JSON(w, r, bulk.Status(progress), models.SuccessDataResponse{
+			Status: "success",
+			Data:   bulk.Report(progress, reindexOp, nil),
+		})
+		return nil
+	})
+}
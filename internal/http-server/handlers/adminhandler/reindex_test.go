@@ -0,0 +1,67 @@
+package adminhandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"quotes-service/internal/http-server/bulk"
+	"quotes-service/internal/http-server/handlers/adminhandler"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func TestReindexHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for i := range 12 {
+		if _, err := store.AddQuote(context.Background(), models.Quote{Text: fmt.Sprintf("quote %d", i), Author: "A"}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+	handler := adminhandler.NewReindexHandler(logger, store, bulk.Limits{BatchSize: 5, Budget: time.Nanosecond})
+
+	steps := []struct {
+		expectedStatus    int
+		expectedProcessed int
+	}{
+		{http.StatusAccepted, 5},
+		{http.StatusAccepted, 5},
+		{http.StatusOK, 2},
+	}
+	var token string
+	for i, step := range steps {
+		target := "/admin/reindex"
+		if token != "" {
+			target += "?" + bulk.TokenParam + "=" + url.QueryEscape(token)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, target, nil))
+		var resp struct {
+			Data models.BulkProgress `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if rr.Code != step.expectedStatus || resp.Data.Processed != step.expectedProcessed {
+			t.Fatalf("call %d: expected %d with %d reindexed, got %d: %s", i+1, step.expectedStatus, step.expectedProcessed, rr.Code, rr.Body.String())
+		}
+		token = resp.Data.ResumeToken
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/reindex?"+bulk.TokenParam+"=bogus", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bogus token, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,96 @@
+package adminhandler
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// NewGetStorageReportHandler serves GET /admin/storage, what the store
+// holds and roughly how much memory each of its structures takes, along
+// with the heap the process has in use.
+func NewGetStorageReportHandler(logger *slog.Logger, c storage.Compactor) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.admin.GetStorageReport"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		report, err := c.MemoryReport(ctx)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to report on storage", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeGetStorageReportFailed, nil)
+		}
+		report.HeapInuseBytes = heapInuse()
+
+		log.InfoContext(ctx, "retrieved storage report", slog.Int("quotes", report.Quotes), slog.Int64("approx_bytes", report.ApproxBytes))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   report,
+		})
+		return nil
+	})
+}
+
+// NewCompactStorageHandler serves POST /admin/compact. It compacts the
+// store, then has the runtime collect what compaction let go of and return
+// it to the operating system, so the process's memory drops right away.
+// Requests wait for the compaction, which holds the store's write lock.
+func NewCompactStorageHandler(logger *slog.Logger, c storage.Compactor) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.admin.CompactStorage"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		before, err := c.MemoryReport(ctx)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to report on storage", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeCompactStorageFailed, nil)
+		}
+		result := models.CompactionResult{
+			ApproxBytesBefore:    before.ApproxBytes,
+			HeapInuseBytesBefore: heapInuse(),
+		}
+
+		start := time.Now()
+		if err := c.Compact(ctx); err != nil {
+			log.ErrorContext(ctx, "failed to compact storage", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeCompactStorageFailed, nil)
+		}
+		debug.FreeOSMemory()
+		took := time.Since(start)
+
+		after, err := c.MemoryReport(ctx)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to report on storage", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeCompactStorageFailed, nil)
+		}
+		result.ApproxBytesAfter = after.ApproxBytes
+		result.HeapInuseBytesAfter = heapInuse()
+		result.Duration = took.String()
+		result.DurationMS = float64(took) / float64(time.Millisecond)
+
+		log.InfoContext(ctx, "storage compacted",
+			slog.Int64("approx_bytes_before", result.ApproxBytesBefore),
+			slog.Int64("approx_bytes_after", result.ApproxBytesAfter),
+			slog.Duration("duration", took),
+		)
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   result,
+		})
+		return nil
+	})
+}
+
+func heapInuse() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse
+}
@@ -0,0 +1,129 @@
+package adminhandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"quotes-service/internal/http-server/handlers/adminhandler"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+type brokenCompactor struct {
+	compactErr error
+	reportErr  error
+}
+
+func (c brokenCompactor) Compact(context.Context) error { return c.compactErr }
+
+func (c brokenCompactor) MemoryReport(context.Context) (models.StorageReport, error) {
+	return models.StorageReport{Backend: "broken"}, c.reportErr
+}
+
+func TestStorageHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for i := range 500 {
+		if _, err := store.AddQuote(ctx, models.Quote{Text: fmt.Sprintf("quote %d", i), Author: "A"}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+	for id := int64(11); id <= 500; id++ {
+		if err := store.DeleteQuote(ctx, id, storage.AnyVersion); err != nil {
+			t.Fatalf("failed to delete quote: %v", err)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	adminhandler.NewCompactStorageHandler(logger, store).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/compact", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 from compact, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var compacted struct {
+		Data models.CompactionResult `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &compacted); err != nil {
+		t.Fatalf("failed to decode compaction result: %v", err)
+	}
+	result := compacted.Data
+	if result.ApproxBytesAfter >= result.ApproxBytesBefore || result.HeapInuseBytesBefore == 0 || result.HeapInuseBytesAfter == 0 || result.Duration == "" {
+		t.Fatalf("unexpected compaction result %+v", result)
+	}
+
+	rr = httptest.NewRecorder()
+	adminhandler.NewGetStorageReportHandler(logger, store).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/storage", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 from report, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var reported struct {
+		Data models.StorageReport `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &reported); err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+	report := reported.Data
+	if report.Quotes != 10 || report.ApproxBytes != result.ApproxBytesAfter || report.CompactedAt == nil || report.HeapInuseBytes == 0 || len(report.Structures) == 0 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
+
+func TestStorageHandlersFailures(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	failed := errors.New("boom")
+
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		method    string
+		target    string
+		wantError string
+	}{
+		{
+			name:      "report fails",
+			handler:   adminhandler.NewGetStorageReportHandler(logger, brokenCompactor{reportErr: failed}),
+			method:    http.MethodGet,
+			target:    "/admin/storage",
+			wantError: "get_storage_report_failed",
+		},
+		{
+			name:      "compact fails",
+			handler:   adminhandler.NewCompactStorageHandler(logger, brokenCompactor{compactErr: failed}),
+			method:    http.MethodPost,
+			target:    "/admin/compact",
+			wantError: "compact_storage_failed",
+		},
+		{
+			name:      "report before compaction fails",
+			handler:   adminhandler.NewCompactStorageHandler(logger, brokenCompactor{reportErr: failed}),
+			method:    http.MethodPost,
+			target:    "/admin/compact",
+			wantError: "compact_storage_failed",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			tc.handler.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.target, nil))
+			if rr.Code != http.StatusInternalServerError {
+				t.Fatalf("expected 500, got %d: %s", rr.Code, rr.Body.String())
+			}
+			var resp models.ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || resp.Code != tc.wantError {
+				t.Fatalf("expected code %s, got %s", tc.wantError, rr.Body.String())
+			}
+		})
+	}
+}
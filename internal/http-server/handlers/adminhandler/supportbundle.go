@@ -0,0 +1,40 @@
+package adminhandler
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// SupportBundle writes a support bundle. *supportbundle.Bundle is the real
+// one.
+type SupportBundle interface {
+	Write(ctx context.Context, w io.Writer) error
+}
+
+// NewGetSupportBundleHandler serves GET /admin/support-bundle, a zip of
+// what an operator attaches to a bug report: the effective configuration
+// with its secrets masked, the version, the last log lines, runtime and
+// storage statistics and the slowest and last failed requests. The zip is
+// streamed as it is built, so a failure partway can only cut it short.
+func NewGetSupportBundleHandler(logger *slog.Logger, b SupportBundle) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.admin.GetSupportBundle"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		name := "support-bundle-" + time.Now().UTC().Format("20060102T150405Z") + ".zip"
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		if err := b.Write(ctx, w); err != nil {
+			log.ErrorContext(ctx, "failed to write support bundle", slog.String("error", err.Error()))
+			return
+		}
+
+		log.InfoContext(ctx, "wrote support bundle", slog.String("name", name))
+	}
+}
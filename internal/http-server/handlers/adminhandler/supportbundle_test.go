@@ -0,0 +1,53 @@
+package adminhandler_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"quotes-service/internal/http-server/handlers/adminhandler"
+	"quotes-service/internal/lib/supportbundle"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func TestGetSupportBundleHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	bundle := &supportbundle.Bundle{
+		Config:  struct{ Env string }{Env: "test"},
+		Version: "1.2.3",
+		Store:   store,
+	}
+
+	rr := httptest.NewRecorder()
+	adminhandler.NewGetSupportBundleHandler(logger, bundle).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/support-bundle", nil))
+
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("expected a 200 zip, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	disposition := regexp.MustCompile(`^attachment; filename="support-bundle-\d{8}T\d{6}Z\.zip"$`)
+	if got := rr.Header().Get("Content-Disposition"); !disposition.MatchString(got) {
+		t.Errorf("unexpected Content-Disposition %q", got)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+	if err != nil {
+		t.Fatalf("failed to read bundle: %v", err)
+	}
+	names := make(map[string]bool)
+	for _, f := range zr.File {
+		names[f.Name] = true
+	}
+	for _, name := range []string{supportbundle.EntryConfig, supportbundle.EntryStorage, supportbundle.EntryManifest} {
+		if !names[name] {
+			t.Errorf("expected entry %s, got %v", name, names)
+		}
+	}
+}
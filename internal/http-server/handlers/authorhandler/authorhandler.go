@@ -0,0 +1,374 @@
+package authorhandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/bulk"
+	"quotes-service/internal/http-server/pagination"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/authorname"
+	"quotes-service/internal/lib/logger/sl"
+	"quotes-service/internal/lib/quoteinput"
+	"quotes-service/internal/lib/rss"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+const (
+	feedSize       = 20
+	maxTitleLength = 80
+)
+
+type AuthorStore interface {
+	GetQuotesByAuthor(ctx context.Context, authorFilter string, filter storage.QuoteFilter) ([]models.Quote, error)
+	MergeAuthors(ctx context.Context, into string, from []string) (map[string]int, error)
+	GetAuthors(ctx context.Context, query storage.AuthorQuery) ([]models.AuthorSummary, int, error)
+}
+
+// AuthorEnricher looks up what is known about an author beyond their
+// quotes, matching the name by its key. An author it knows nothing about,
+// or cannot look up, has no metadata; that is never an error.
+// *authormeta.File and *authormeta.Remote are the real ones.
+type AuthorEnricher interface {
+	Enrich(ctx context.Context, name string) (models.AuthorMetadata, bool)
+}
+
+// enrich adds the metadata ae has for the author of summary, if any. A nil
+// ae has none.
+func enrich(ctx context.Context, ae AuthorEnricher, summary *models.AuthorSummary) {
+	if ae == nil {
+		return
+	}
+	if meta, ok := ae.Enrich(ctx, summary.Name); ok {
+		summary.Metadata = &meta
+	}
+}
+
+// NewGetAuthorsHandler serves GET /authors, a page of the authors with
+// their quote counts. Spellings that differ only in case, punctuation or
+// spacing are one author, listed under its display name. ?sort=name (the
+// default) or ?sort=quote_count with ?order=asc or desc orders the list,
+// and ?q= keeps the authors whose names start with it. Authors ae has
+// metadata for include it.
+func NewGetAuthorsHandler(logger *slog.Logger, as AuthorStore, sizes pagination.Sizes, ae AuthorEnricher) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.author.GetAuthors"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		page, err := pagination.Parse(r, sizes)
+		if err != nil {
+			log.WarnContext(ctx, "invalid pagination", sl.UserText("query", r.URL.RawQuery), slog.String("error", err.Error()))
+			if errors.Is(err, pagination.ErrInvalidOffset) {
+				return apierror.New(http.StatusBadRequest, apierror.CodeInvalidOffset, nil)
+			}
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidLimit, nil)
+		}
+
+		query := r.URL.Query()
+		authorQuery := storage.AuthorQuery{
+			Prefix: query.Get("q"),
+			Sort:   query.Get("sort"),
+			Limit:  page.Limit,
+			Offset: page.Offset,
+		}
+		switch authorQuery.Sort {
+		case "":
+			authorQuery.Sort = storage.AuthorSortName
+		case storage.AuthorSortName, storage.AuthorSortQuoteCount:
+		default:
+			log.WarnContext(ctx, "invalid sort", slog.String("sort", authorQuery.Sort))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, "sort")
+		}
+		switch order := query.Get("order"); order {
+		case "", "asc":
+		case "desc":
+			authorQuery.Desc = true
+		default:
+			log.WarnContext(ctx, "invalid order", slog.String("order", order))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, "order")
+		}
+
+		authors, total, err := as.GetAuthors(ctx, authorQuery)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to get authors", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeGetAuthorsFailed, nil)
+		}
+		for i := range authors {
+			enrich(ctx, ae, &authors[i])
+		}
+
+		log.InfoContext(ctx, "retrieved authors", slog.Int("total", total), slog.Int("limit", page.Limit), slog.Int("offset", page.Offset), slog.String("sort", authorQuery.Sort), slog.Bool("desc", authorQuery.Desc))
+		pagination.SetHeaders(w, r, page, total)
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   authors,
+		})
+		return nil
+	})
+}
+
+// NewGetAuthorHandler serves GET /authors/{name}, with the metadata ae has
+// for the author, if any. The router must use encoded paths so that names
+// containing slashes reach the handler intact.
+func NewGetAuthorHandler(logger *slog.Logger, as AuthorStore, ae AuthorEnricher) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.author.GetAuthor"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		name, quotes, err := authorQuotes(r, log, as)
+		if err != nil {
+			return err
+		}
+
+		summary := authorname.Summarize(quotes)
+		enrich(ctx, ae, &summary)
+
+		log.InfoContext(ctx, "retrieved author summary", sl.UserText("author", name), slog.Int("quotes", len(quotes)))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   summary,
+		})
+		return nil
+	})
+}
+
+// NewGetAuthorFeedHandler serves GET /authors/{name}/feed, an RSS feed of
+// the author's most recently added quotes.
+func NewGetAuthorFeedHandler(logger *slog.Logger, as AuthorStore) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.author.GetAuthorFeed"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		_, quotes, err := authorQuotes(r, log, as)
+		if err != nil {
+			return err
+		}
+		name := authorname.Summarize(quotes).Name
+
+		sort.SliceStable(quotes, func(i, j int) bool {
+			if !quotes[i].CreatedAt.Equal(quotes[j].CreatedAt) {
+				return quotes[i].CreatedAt.After(quotes[j].CreatedAt)
+			}
+			return quotes[i].ID > quotes[j].ID
+		})
+		quotes = quotes[:min(len(quotes), feedSize)]
+
+		base := baseURL(r)
+		channel := rss.Channel{
+			Title:       fmt.Sprintf("Quotes by %s", name),
+			Link:        base + "/authors/" + url.PathEscape(name),
+			Description: fmt.Sprintf("Recently added quotes by %s.", name),
+			Items:       make([]rss.Item, 0, len(quotes)),
+		}
+		if len(quotes) > 0 {
+			channel.LastBuildDate = rss.Date(quotes[0].CreatedAt)
+		}
+		for _, q := range quotes {
+			// Link by public ID where there is one, so feeds keep working
+			// when the sequential IDs are hidden.
+			link := fmt.Sprintf("%s/quotes/%d", base, q.ID)
+			if q.PublicID != "" {
+				link = base + "/quotes/" + q.PublicID
+			}
+			channel.Items = append(channel.Items, rss.Item{
+				Title:       title(q.Text),
+				Link:        link,
+				Description: q.Text,
+				GUID:        rss.GUID{Value: link, IsPermaLink: true},
+				PubDate:     rss.Date(q.CreatedAt),
+			})
+		}
+
+		var buf bytes.Buffer
+		if err := rss.Encode(&buf, channel); err != nil {
+			log.ErrorContext(ctx, "failed to encode feed", sl.UserText("author", name), slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeGetAuthorFailed, nil)
+		}
+
+		log.InfoContext(ctx, "served author feed", sl.UserText("author", name), slog.Int("items", len(channel.Items)))
+		w.Header().Set("Content-Type", rss.ContentType)
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			log.ErrorContext(ctx, "failed to write feed", slog.String("error", err.Error()))
+		}
+		return nil
+	})
+}
+
+// NewMergeAuthorsHandler serves POST /authors/merge, moving the quotes of
+// several author spellings to one name. A store that is a storage.Batcher
+// merges a batch at a time within limits: a merge that runs out of time is
+// answered 202 with a resume token, to be sent back with the same body as
+// ?resume_token= to go on. Other stores merge at once.
+func NewMergeAuthorsHandler(logger *slog.Logger, as AuthorStore, limits bulk.Limits) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.author.MergeAuthors"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		var req models.MergeAuthorsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				log.WarnContext(ctx, "request body is empty")
+				return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyEmpty, nil)
+			}
+			log.ErrorContext(ctx, "failed to decode request body", slog.String("error", err.Error()))
+			return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyInvalid, nil)
+		}
+		defer r.Body.Close()
+
+		// The from names are left unchecked: they may be the bad names the
+		// merge is cleaning up.
+		var validationErrors []string
+		code := apierror.CodeInvalidRequest
+		if strings.TrimSpace(req.Into) == "" {
+			validationErrors = append(validationErrors, "into cannot be empty")
+		} else if problem := quoteinput.AuthorProblem("into", req.Into); problem != "" {
+			validationErrors = append(validationErrors, problem)
+			code = apierror.CodeInvalidAuthorName
+		}
+		if len(req.From) == 0 {
+			validationErrors = append(validationErrors, "from cannot be empty")
+		}
+		for _, name := range req.From {
+			if strings.TrimSpace(name) == "" {
+				validationErrors = append(validationErrors, "from cannot contain empty names")
+				break
+			}
+		}
+		for _, name := range req.From {
+			if name == req.Into {
+				validationErrors = append(validationErrors, "cannot merge an author into itself")
+				break
+			}
+		}
+		if len(validationErrors) > 0 {
+			log.WarnContext(ctx, "invalid request", slog.Any("validation_errors", validationErrors))
+			return apierror.New(http.StatusBadRequest, code, validationErrors)
+		}
+
+		result := models.MergeAuthorsResult{Into: req.Into, Merged: make(map[string]int, len(req.From))}
+		status := http.StatusOK
+		if batcher, ok := as.(storage.Batcher); ok {
+			params := mergeParams(req)
+			after, err := bulk.Resume(r, mergeOp, params)
+			if err != nil {
+				log.WarnContext(ctx, "invalid resume token", sl.UserText("into", req.Into))
+				return apierror.New(http.StatusBadRequest, apierror.CodeInvalidResumeToken, nil)
+			}
+			progress, err := bulk.Run(ctx, limits, after, func(ctx context.Context, batch storage.Batch) (storage.BatchResult, error) {
+				batchResult, err := batcher.MergeAuthorsBatch(ctx, req.Into, req.From, batch)
+				for name, n := range batchResult.Counts {
+					result.Merged[name] += n
+				}
+				return batchResult, err
+			})
+			if err != nil {
+				log.ErrorContext(ctx, "failed to merge authors", sl.UserText("into", req.Into), slog.Int("processed", progress.Processed), slog.String("error", err.Error()))
+				return apierror.New(http.StatusInternalServerError, apierror.CodeMergeAuthorsFailed, nil)
+			}
+			result.BulkProgress = bulk.Report(progress, mergeOp, params)
+			status = bulk.Status(progress)
+		} else {
+			counts, err := as.MergeAuthors(ctx, req.Into, req.From)
+			if err != nil {
+				log.ErrorContext(ctx, "failed to merge authors", sl.UserText("into", req.Into), slog.String("error", err.Error()))
+				return apierror.New(http.StatusInternalServerError, apierror.CodeMergeAuthorsFailed, nil)
+			}
+			result.Merged = counts
+			result.Done = true
+		}
+		for _, n := range result.Merged {
+			result.Total += n
+		}
+		result.Processed = result.Total
+
+		log.InfoContext(ctx, "authors merged",
+			sl.UserText("into", req.Into),
+			slog.Any("from", req.From),
+			slog.Int("quotes", result.Total),
+			slog.Bool("done", result.Done),
+		)
+		response.JSON(w, r, status, models.SuccessDataResponse{
+			Status: "success",
+			Data:   result,
+		})
+		return nil
+	})
+}
+
+// mergeOp names merges in their resume tokens.
+const mergeOp = "merge_authors"
+
+// mergeParams are what a merge's resume token is tied to: the target name
+// as asked and the keys of the source names, which are what is matched.
+func mergeParams(req models.MergeAuthorsRequest) []string {
+	params := []string{req.Into}
+	for _, name := range req.From {
+		params = append(params, authorname.Key(name))
+	}
+	return params
+}
+
+// authorQuotes decodes the author from the path and loads their quotes,
+// writing an error response when that fails or the author is unknown.
+// Names are matched by key, as the storage does for ?author=.
+func authorQuotes(r *http.Request, log *slog.Logger, as AuthorStore) (string, []models.Quote, error) {
+	ctx := r.Context()
+
+	raw := mux.Vars(r)["name"]
+	name, err := url.PathUnescape(raw)
+	if err != nil || strings.TrimSpace(name) == "" || !utf8.ValidString(name) {
+		log.WarnContext(ctx, "invalid author name in path", sl.UserText("name", raw))
+		return "", nil, apierror.New(http.StatusBadRequest, apierror.CodeInvalidAuthor, nil)
+	}
+
+	quotes, err := as.GetQuotesByAuthor(ctx, name, storage.QuoteFilter{})
+	if err != nil {
+		log.ErrorContext(ctx, "failed to get quotes by author", sl.UserText("author", name), slog.String("error", err.Error()))
+		return "", nil, apierror.New(http.StatusInternalServerError, apierror.CodeGetAuthorFailed, nil)
+	}
+	if len(quotes) == 0 {
+		log.InfoContext(ctx, "author not found", sl.UserText("author", name))
+		return "", nil, apierror.New(http.StatusNotFound, apierror.CodeAuthorNotFound, nil)
+	}
+	return name, quotes, nil
+}
+
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// title shortens a quote to a feed item title on a word boundary.
+func title(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= maxTitleLength {
+		return text
+	}
+	runes := []rune(text)[:maxTitleLength]
+	cut := string(runes)
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return cut + "…"
+}
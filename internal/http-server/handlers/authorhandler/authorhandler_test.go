@@ -0,0 +1,451 @@
+package authorhandler_test
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/bulk"
+	"quotes-service/internal/http-server/handlers/authorhandler"
+	"quotes-service/internal/http-server/pagination"
+	"quotes-service/internal/lib/rss"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+type MockAuthorStore struct {
+	GetQuotesByAuthorFunc func(ctx context.Context, authorFilter string, filter storage.QuoteFilter) ([]models.Quote, error)
+	MergeAuthorsFunc      func(ctx context.Context, into string, from []string) (map[string]int, error)
+	GetAuthorsFunc        func(ctx context.Context, query storage.AuthorQuery) ([]models.AuthorSummary, int, error)
+}
+
+func (m *MockAuthorStore) GetQuotesByAuthor(ctx context.Context, authorFilter string, filter storage.QuoteFilter) ([]models.Quote, error) {
+	if m.GetQuotesByAuthorFunc != nil {
+		return m.GetQuotesByAuthorFunc(ctx, authorFilter, filter)
+	}
+	return nil, errors.New("GetQuotesByAuthorFunc not implemented")
+}
+
+func (m *MockAuthorStore) MergeAuthors(ctx context.Context, into string, from []string) (map[string]int, error) {
+	if m.MergeAuthorsFunc != nil {
+		return m.MergeAuthorsFunc(ctx, into, from)
+	}
+	return nil, errors.New("MergeAuthorsFunc not implemented")
+}
+
+func (m *MockAuthorStore) GetAuthors(ctx context.Context, query storage.AuthorQuery) ([]models.AuthorSummary, int, error) {
+	if m.GetAuthorsFunc != nil {
+		return m.GetAuthorsFunc(ctx, query)
+	}
+	return nil, 0, errors.New("GetAuthorsFunc not implemented")
+}
+
+func newRouter(logger *slog.Logger, as authorhandler.AuthorStore) *mux.Router {
+	router := mux.NewRouter()
+	router.UseEncodedPath()
+	router.HandleFunc("/authors/merge", authorhandler.NewMergeAuthorsHandler(logger, as, bulk.Limits{BatchSize: 100, Budget: time.Second})).Methods(http.MethodPost)
+	router.HandleFunc("/authors", authorhandler.NewGetAuthorsHandler(logger, as, pagination.Sizes{Default: 20, Max: 100}, nil)).Methods(http.MethodGet)
+	router.HandleFunc("/authors/{name}", authorhandler.NewGetAuthorHandler(logger, as, nil)).Methods(http.MethodGet)
+	router.HandleFunc("/authors/{name}/feed", authorhandler.NewGetAuthorFeedHandler(logger, as)).Methods(http.MethodGet)
+	return router
+}
+
+func TestGetAuthorHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	first := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedAuthor string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "space",
+			path:           "/authors/Lao%20Tzu",
+			expectedAuthor: "Lao Tzu",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"name":"Lao Tzu","quote_count":2,"first_added_at":"2024-03-10T12:00:00Z","last_added_at":"2024-03-10T13:00:00Z"}}`,
+		},
+		{
+			name:           "unicode",
+			path:           "/authors/%D0%A4%D1%91%D0%B4%D0%BE%D1%80%20%D0%94%D0%BE%D1%81%D1%82%D0%BE%D0%B5%D0%B2%D1%81%D0%BA%D0%B8%D0%B9",
+			expectedAuthor: "Фёдор Достоевский",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"name":"Фёдор Достоевский","quote_count":2,"first_added_at":"2024-03-10T12:00:00Z","last_added_at":"2024-03-10T13:00:00Z"}}`,
+		},
+		{
+			name:           "escaped slash and percent",
+			path:           "/authors/AC%2FDC%2050%25",
+			expectedAuthor: "AC/DC 50%",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"name":"AC/DC 50%","quote_count":2,"first_added_at":"2024-03-10T12:00:00Z","last_added_at":"2024-03-10T13:00:00Z"}}`,
+		},
+		{
+			name:           "spelling variants",
+			path:           "/authors/lao%20tzu",
+			expectedAuthor: "lao tzu",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"name":"Lao Tzu","variants":["lao tzu","Lao Tzu"],"quote_count":3,"first_added_at":"2024-03-10T12:00:00Z","last_added_at":"2024-03-10T14:00:00Z"}}`,
+		},
+		{
+			name:           "unknown author",
+			path:           "/authors/Nobody",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"status":"error","code":"author_not_found","error":"Author not found."}`,
+		},
+		{
+			name:           "blank author",
+			path:           "/authors/%20",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_author","error":"Invalid author name."}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockStore := &MockAuthorStore{
+				GetQuotesByAuthorFunc: func(ctx context.Context, author string, filter storage.QuoteFilter) ([]models.Quote, error) {
+					if author != tc.expectedAuthor {
+						return []models.Quote{}, nil
+					}
+					if author == "lao tzu" {
+						return []models.Quote{
+							{ID: 1, Text: "A", Author: "lao tzu", CreatedAt: first},
+							{ID: 2, Text: "B", Author: "Lao Tzu", CreatedAt: first.Add(time.Hour)},
+							{ID: 3, Text: "C", Author: "Lao Tzu", CreatedAt: first.Add(2 * time.Hour)},
+						}, nil
+					}
+					return []models.Quote{
+						{ID: 2, Text: "B", Author: author, CreatedAt: first.Add(time.Hour)},
+						{ID: 1, Text: "A", Author: author, CreatedAt: first},
+					}, nil
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			rr := httptest.NewRecorder()
+			newRouter(logger, mockStore).ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tc.expectedBody) {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetAuthorsHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name           string
+		query          string
+		authors        []models.AuthorSummary
+		total          int
+		err            error
+		expectedQuery  storage.AuthorQuery
+		expectedStatus int
+		expectedBody   string
+		expectedTotal  string
+	}{
+		{
+			name: "success",
+			authors: []models.AuthorSummary{
+				{Name: "Albert Einstein", Variants: []string{"Albert Einstein", "albert einstein"}, QuoteCount: 3},
+				{Name: "Lao Tzu", QuoteCount: 1},
+			},
+			total:          2,
+			expectedQuery:  storage.AuthorQuery{Sort: storage.AuthorSortName, Limit: 20},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":[{"name":"Albert Einstein","variants":["Albert Einstein","albert einstein"],"quote_count":3},{"name":"Lao Tzu","quote_count":1}]}`,
+			expectedTotal:  "2",
+		},
+		{
+			name:           "sorted and filtered page",
+			query:          "?sort=quote_count&order=desc&q=lao&limit=1&offset=1",
+			authors:        []models.AuthorSummary{{Name: "Lao Tzu", QuoteCount: 1}},
+			total:          2,
+			expectedQuery:  storage.AuthorQuery{Prefix: "lao", Sort: storage.AuthorSortQuoteCount, Desc: true, Limit: 1, Offset: 1},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":[{"name":"Lao Tzu","quote_count":1}]}`,
+			expectedTotal:  "2",
+		},
+		{
+			name:           "no match",
+			query:          "?q=zz",
+			authors:        []models.AuthorSummary{},
+			expectedQuery:  storage.AuthorQuery{Prefix: "zz", Sort: storage.AuthorSortName, Limit: 20},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":[]}`,
+			expectedTotal:  "0",
+		},
+		{
+			name:           "invalid sort",
+			query:          "?sort=age",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_parameter","error":"Invalid sort parameter."}`,
+		},
+		{
+			name:           "invalid order",
+			query:          "?order=up",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_parameter","error":"Invalid order parameter."}`,
+		},
+		{
+			name:           "storage error",
+			err:            errors.New("db error"),
+			expectedQuery:  storage.AuthorQuery{Sort: storage.AuthorSortName, Limit: 20},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"status":"error","code":"get_authors_failed","error":"Failed to retrieve authors."}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockStore := &MockAuthorStore{
+				GetAuthorsFunc: func(ctx context.Context, query storage.AuthorQuery) ([]models.AuthorSummary, int, error) {
+					if query != tc.expectedQuery {
+						t.Errorf("expected query %+v, got %+v", tc.expectedQuery, query)
+					}
+					return tc.authors, tc.total, tc.err
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			newRouter(logger, mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/authors"+tc.query, nil))
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if strings.TrimSpace(rr.Body.String()) != tc.expectedBody {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
+			}
+			if got := rr.Header().Get("X-Total-Count"); got != tc.expectedTotal {
+				t.Errorf("expected X-Total-Count %q, got %q", tc.expectedTotal, got)
+			}
+		})
+	}
+}
+
+type MockAuthorEnricher map[string]models.AuthorMetadata
+
+func (m MockAuthorEnricher) Enrich(ctx context.Context, name string) (models.AuthorMetadata, bool) {
+	meta, ok := m[name]
+	return meta, ok
+}
+
+func TestAuthorMetadata(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	added := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	mockStore := &MockAuthorStore{
+		GetQuotesByAuthorFunc: func(ctx context.Context, authorFilter string, filter storage.QuoteFilter) ([]models.Quote, error) {
+			return []models.Quote{{ID: 1, Text: "Quote", Author: authorFilter, CreatedAt: added}}, nil
+		},
+		GetAuthorsFunc: func(ctx context.Context, query storage.AuthorQuery) ([]models.AuthorSummary, int, error) {
+			return []models.AuthorSummary{{Name: "Mark Twain", QuoteCount: 1}, {Name: "Nobody", QuoteCount: 1}}, 2, nil
+		},
+	}
+	enricher := MockAuthorEnricher{"Mark Twain": {BirthYear: 1835, DeathYear: 1910, ImageURL: "https://example.com/twain.jpg"}}
+	router := mux.NewRouter()
+	router.UseEncodedPath()
+	router.HandleFunc("/authors", authorhandler.NewGetAuthorsHandler(logger, mockStore, pagination.Sizes{Default: 20, Max: 100}, enricher)).Methods(http.MethodGet)
+	router.HandleFunc("/authors/{name}", authorhandler.NewGetAuthorHandler(logger, mockStore, enricher)).Methods(http.MethodGet)
+
+	tests := []struct {
+		name         string
+		path         string
+		expectedBody string
+	}{
+		{
+			name:         "author with metadata",
+			path:         "/authors/Mark%20Twain",
+			expectedBody: `{"status":"success","data":{"name":"Mark Twain","quote_count":1,"first_added_at":"2024-03-10T12:00:00Z","last_added_at":"2024-03-10T12:00:00Z","metadata":{"image_url":"https://example.com/twain.jpg","birth_year":1835,"death_year":1910}}}`,
+		},
+		{
+			name:         "author without metadata",
+			path:         "/authors/Nobody",
+			expectedBody: `{"status":"success","data":{"name":"Nobody","quote_count":1,"first_added_at":"2024-03-10T12:00:00Z","last_added_at":"2024-03-10T12:00:00Z"}}`,
+		},
+		{
+			name:         "summaries",
+			path:         "/authors",
+			expectedBody: `{"status":"success","data":[{"name":"Mark Twain","quote_count":1,"metadata":{"image_url":"https://example.com/twain.jpg","birth_year":1835,"death_year":1910}},{"name":"Nobody","quote_count":1}]}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d. Body: %s", rr.Code, rr.Body.String())
+			}
+			if got := rr.Body.String(); got != tc.expectedBody+"\n" {
+				t.Fatalf("expected body %s, got %s", tc.expectedBody, got)
+			}
+		})
+	}
+}
+
+func TestGetAuthorFeedHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	first := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	mockStore := &MockAuthorStore{
+		GetQuotesByAuthorFunc: func(ctx context.Context, author string, filter storage.QuoteFilter) ([]models.Quote, error) {
+			quotes := make([]models.Quote, 0, 25)
+			for i := 0; i < 25; i++ {
+				quotes = append(quotes, models.Quote{
+					ID:        int64(i + 1),
+					Text:      "Quote <" + author + "> & more",
+					Author:    author,
+					CreatedAt: first.Add(time.Duration(i) * time.Minute),
+				})
+			}
+			return quotes, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://quotes.example/authors/Lao%20Tzu/feed", nil)
+	rr := httptest.NewRecorder()
+	newRouter(logger, mockStore).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Content-Type"); got != rss.ContentType {
+		t.Errorf("unexpected content type %q", got)
+	}
+
+	var feed rss.Feed
+	if err := xml.Unmarshal(rr.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("feed is not valid XML: %v", err)
+	}
+	if feed.Version != "2.0" || feed.Channel.Title != "Quotes by Lao Tzu" {
+		t.Errorf("unexpected channel %+v", feed.Channel)
+	}
+	if feed.Channel.Link != "http://quotes.example/authors/Lao%20Tzu" {
+		t.Errorf("unexpected channel link %q", feed.Channel.Link)
+	}
+	if len(feed.Channel.Items) != 20 {
+		t.Fatalf("expected 20 items, got %d", len(feed.Channel.Items))
+	}
+	newest := feed.Channel.Items[0]
+	if newest.Link != "http://quotes.example/quotes/25" || newest.Description != "Quote <Lao Tzu> & more" {
+		t.Errorf("unexpected newest item %+v", newest)
+	}
+	if newest.PubDate != "Sun, 10 Mar 2024 12:24:00 +0000" {
+		t.Errorf("unexpected pubDate %q", newest.PubDate)
+	}
+}
+
+func TestMergeAuthorsHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name           string
+		body           string
+		mockStoreSetup func(*MockAuthorStore)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "success",
+			body: `{"into":"Albert Einstein","from":["A. Einstein","Einstein, A."]}`,
+			mockStoreSetup: func(ms *MockAuthorStore) {
+				ms.MergeAuthorsFunc = func(ctx context.Context, into string, from []string) (map[string]int, error) {
+					return map[string]int{"A. Einstein": 2, "Einstein, A.": 0}, nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"into":"Albert Einstein","merged":{"A. Einstein":2,"Einstein, A.":0},"total":2,"processed":2,"done":true}}`,
+		},
+		{
+			name:           "empty from",
+			body:           `{"into":"Albert Einstein","from":[]}`,
+			mockStoreSetup: func(ms *MockAuthorStore) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_request","error":"Invalid request.","fields":["from cannot be empty"]}`,
+		},
+		{
+			name:           "merge into itself",
+			body:           `{"into":"Albert Einstein","from":["A. Einstein","Albert Einstein"]}`,
+			mockStoreSetup: func(ms *MockAuthorStore) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_request","error":"Invalid request.","fields":["cannot merge an author into itself"]}`,
+		},
+		{
+			name:           "empty into",
+			body:           `{"from":["A. Einstein"]}`,
+			mockStoreSetup: func(ms *MockAuthorStore) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_request","error":"Invalid request.","fields":["into cannot be empty"]}`,
+		},
+		{
+			name:           "into with newline",
+			body:           `{"into":"Albert\nEinstein","from":["A. Einstein"]}`,
+			mockStoreSetup: func(ms *MockAuthorStore) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_author_name","error":"Invalid author name.","fields":["into must not contain control characters, line breaks or direction overrides"]}`,
+		},
+		{
+			name:           "into with right-to-left override",
+			body:           `{"into":"Albert \u202enietsniE","from":["A. Einstein"]}`,
+			mockStoreSetup: func(ms *MockAuthorStore) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_author_name","error":"Invalid author name.","fields":["into must not contain control characters, line breaks or direction overrides"]}`,
+		},
+		{
+			name: "bad from names can be merged away",
+			body: `{"into":"Albert Einstein","from":["Albert\nEinstein"]}`,
+			mockStoreSetup: func(ms *MockAuthorStore) {
+				ms.MergeAuthorsFunc = func(ctx context.Context, into string, from []string) (map[string]int, error) {
+					return map[string]int{"Albert\nEinstein": 1}, nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"into":"Albert Einstein","merged":{"Albert\nEinstein":1},"total":1,"processed":1,"done":true}}`,
+		},
+		{
+			name: "storage error",
+			body: `{"into":"B","from":["A"]}`,
+			mockStoreSetup: func(ms *MockAuthorStore) {
+				ms.MergeAuthorsFunc = func(ctx context.Context, into string, from []string) (map[string]int, error) {
+					return nil, errors.New("boom")
+				}
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"status":"error","code":"merge_authors_failed","error":"Failed to merge authors."}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockStore := &MockAuthorStore{}
+			tc.mockStoreSetup(mockStore)
+
+			req := httptest.NewRequest(http.MethodPost, "/authors/merge", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			newRouter(logger, mockStore).ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tc.expectedBody) {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
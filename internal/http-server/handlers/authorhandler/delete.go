@@ -0,0 +1,77 @@
+package authorhandler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/bulk"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/authorname"
+	"quotes-service/internal/lib/logger/sl"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// deleteOp names author deletes in their resume tokens.
+const deleteOp = "delete_author"
+
+// NewDeleteAuthorQuotesHandler serves DELETE /authors/{name}/quotes,
+// which deletes every quote of an author a batch at a time within limits.
+// An author with more quotes than a call gets through is answered 202 with
+// a resume token, to be sent back as ?resume_token= to go on; the last
+// call is answered 200. An author without quotes is a 404 on the first
+// call only, so that a call resumed after the last quote went still
+// succeeds.
+func NewDeleteAuthorQuotesHandler(logger *slog.Logger, b storage.Batcher, limits bulk.Limits) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.author.DeleteAuthorQuotes"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		raw := mux.Vars(r)["name"]
+		name, err := url.PathUnescape(raw)
+		if err != nil || strings.TrimSpace(name) == "" || !utf8.ValidString(name) {
+			log.WarnContext(ctx, "invalid author name in path", sl.UserText("name", raw))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidAuthor, nil)
+		}
+
+		params := []string{authorname.Key(name)}
+		after, err := bulk.Resume(r, deleteOp, params)
+		if err != nil {
+			log.WarnContext(ctx, "invalid resume token", sl.UserText("author", name))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidResumeToken, nil)
+		}
+
+		progress, err := bulk.Run(ctx, limits, after, func(ctx context.Context, batch storage.Batch) (storage.BatchResult, error) {
+			return b.DeleteAuthorBatch(ctx, name, batch)
+		})
+		if err != nil {
+			log.ErrorContext(ctx, "failed to delete author quotes", sl.UserText("author", name), slog.Int("processed", progress.Processed), slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeDeleteAuthorQuotesFailed, nil)
+		}
+		if after == 0 && progress.Done && progress.Processed == 0 {
+			log.InfoContext(ctx, "author not found", sl.UserText("author", name))
+			return apierror.New(http.StatusNotFound, apierror.CodeAuthorNotFound, nil)
+		}
+
+		log.InfoContext(ctx, "author quotes deleted",
+			sl.UserText("author", name),
+			slog.Int("deleted", progress.Processed),
+			slog.Bool("done", progress.Done),
+		)
+		response.JSON(w, r, bulk.Status(progress), models.SuccessDataResponse{
+			Status: "success",
+			Data: models.DeleteAuthorQuotesResult{
+				Author:       name,
+				BulkProgress: bulk.Report(progress, deleteOp, params),
+			},
+		})
+		return nil
+	})
+}
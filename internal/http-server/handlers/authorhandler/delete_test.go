@@ -0,0 +1,199 @@
+package authorhandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/bulk"
+	"quotes-service/internal/http-server/handlers/authorhandler"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+// newBulkRouter serves the bulk author routes over a store with quotes by
+// Alice and Bob. Its budget lets each call run a single batch.
+func newBulkRouter(t *testing.T, alice, bob int) (http.Handler, *memorystorage.Storage) {
+	t.Helper()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for i := range alice + bob {
+		// Bob's quotes come between the first of Alice's.
+		author := "Alice"
+		if i%2 == 1 && i < 2*bob {
+			author = "Bob"
+		}
+		if _, err := store.AddQuote(context.Background(), models.Quote{Text: fmt.Sprintf("quote %d", i), Author: author}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	limits := bulk.Limits{BatchSize: 10, Budget: time.Nanosecond}
+	router := mux.NewRouter()
+	router.UseEncodedPath()
+	router.HandleFunc("/authors/merge", authorhandler.NewMergeAuthorsHandler(logger, store, limits)).Methods(http.MethodPost)
+	router.HandleFunc("/authors/{name}/quotes", authorhandler.NewDeleteAuthorQuotesHandler(logger, store, limits)).Methods(http.MethodDelete)
+	return router, store
+}
+
+type bulkResponse struct {
+	Data struct {
+		models.BulkProgress
+		Merged map[string]int `json:"merged"`
+	} `json:"data"`
+}
+
+func call(t *testing.T, router http.Handler, method, target, token, body string) (*httptest.ResponseRecorder, bulkResponse) {
+	t.Helper()
+	if token != "" {
+		target += "?" + bulk.TokenParam + "=" + url.QueryEscape(token)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(method, target, strings.NewReader(body)))
+	var resp bulkResponse
+	if rr.Code < 300 {
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+	}
+	return rr, resp
+}
+
+func TestDeleteAuthorQuotesHandlerResumed(t *testing.T) {
+	router, store := newBulkRouter(t, 25, 5)
+
+	var (
+		token     string
+		processed int
+		calls     int
+		tokens    []string
+	)
+	for {
+		calls++
+		rr, resp := call(t, router, http.MethodDelete, "/authors/alice/quotes", token, "")
+		processed += resp.Data.Processed
+		if resp.Data.Done {
+			if rr.Code != http.StatusOK || resp.Data.ResumeToken != "" {
+				t.Fatalf("expected 200 without a token at the end, got %d: %s", rr.Code, rr.Body.String())
+			}
+			break
+		}
+		if rr.Code != http.StatusAccepted || resp.Data.ResumeToken == "" || calls > 10 {
+			t.Fatalf("expected 202 with a token, got %d: %s", rr.Code, rr.Body.String())
+		}
+		token = resp.Data.ResumeToken
+		tokens = append(tokens, token)
+	}
+	if processed != 25 || calls != 3 {
+		t.Fatalf("expected 25 quotes deleted over 3 calls, got %d over %d", processed, calls)
+	}
+	left, _ := store.GetAllQuotes(context.Background(), storage.QuoteFilter{})
+	for _, q := range left {
+		if q.Author != "Bob" {
+			t.Fatalf("expected only Bob's quotes left, got %+v", q)
+		}
+	}
+	if len(left) != 5 {
+		t.Fatalf("expected Bob's 5 quotes left, got %d", len(left))
+	}
+
+	// Replaying a token deletes nothing more and still succeeds.
+	rr, resp := call(t, router, http.MethodDelete, "/authors/alice/quotes", tokens[0], "")
+	if rr.Code != http.StatusOK || resp.Data.Processed != 0 {
+		t.Fatalf("expected an empty 200 on replay, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestDeleteAuthorQuotesHandlerDeadline(t *testing.T) {
+	router, store := newBulkRouter(t, 25, 0)
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	req := httptest.NewRequest(http.MethodDelete, "/authors/Alice/quotes", nil).WithContext(ctx)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusAccepted || !strings.Contains(rr.Body.String(), `"processed":10`) {
+		t.Fatalf("expected a batch done before the deadline was noticed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if left, _ := store.GetAllQuotes(context.Background(), storage.QuoteFilter{}); len(left) != 15 {
+		t.Fatalf("expected 15 quotes left, got %d", len(left))
+	}
+}
+
+func TestDeleteAuthorQuotesHandlerErrors(t *testing.T) {
+	router, _ := newBulkRouter(t, 25, 5)
+	_, first := call(t, router, http.MethodDelete, "/authors/Alice/quotes", "", "")
+	token := first.Data.ResumeToken
+
+	tests := []struct {
+		name           string
+		target         string
+		token          string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "unknown author", target: "/authors/Carol/quotes", expectedStatus: http.StatusNotFound, expectedCode: "author_not_found"},
+		{name: "token of another author", target: "/authors/Bob/quotes", token: token, expectedStatus: http.StatusBadRequest, expectedCode: "invalid_resume_token"},
+		{name: "altered token", target: "/authors/Alice/quotes", token: token + "A", expectedStatus: http.StatusBadRequest, expectedCode: "invalid_resume_token"},
+		{name: "token of another spelling", target: "/authors/ALICE./quotes", token: token, expectedStatus: http.StatusAccepted},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr, _ := call(t, router, http.MethodDelete, tc.target, tc.token, "")
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tc.expectedCode != "" && !strings.Contains(rr.Body.String(), `"`+tc.expectedCode+`"`) {
+				t.Fatalf("expected code %s, got %s", tc.expectedCode, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestMergeAuthorsHandlerResumed(t *testing.T) {
+	router, store := newBulkRouter(t, 25, 5)
+	body := `{"into": "Bob", "from": ["Alice"]}`
+
+	var (
+		token  string
+		merged int
+		calls  int
+	)
+	for {
+		calls++
+		rr, resp := call(t, router, http.MethodPost, "/authors/merge", token, body)
+		merged += resp.Data.Merged["Alice"]
+		if resp.Data.Done {
+			break
+		}
+		if rr.Code != http.StatusAccepted || calls > 10 {
+			t.Fatalf("expected 202, got %d: %s", rr.Code, rr.Body.String())
+		}
+		token = resp.Data.ResumeToken
+	}
+	if merged != 25 || calls != 3 {
+		t.Fatalf("expected 25 quotes merged over 3 calls, got %d over %d", merged, calls)
+	}
+	if quotes, _ := store.GetQuotesByAuthor(context.Background(), "Bob", storage.QuoteFilter{}); len(quotes) != 30 {
+		t.Fatalf("expected 30 quotes by Bob, got %d", len(quotes))
+	}
+
+	rr, _ := call(t, router, http.MethodPost, "/authors/merge", token, `{"into": "Carol", "from": ["Alice"]}`)
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), `"invalid_resume_token"`) {
+		t.Fatalf("expected a token for another merge to be refused, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
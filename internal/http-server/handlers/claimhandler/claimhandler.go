@@ -0,0 +1,163 @@
+// Package claimhandler lets distributed consumers reserve quotes, so that
+// several workers pulling the next quote to post never post the same one.
+package claimhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/middleware/auth"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/language"
+	"quotes-service/internal/lib/logger/sl"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// maxClaimantChars bounds the claimant named in a body.
+const maxClaimantChars = 128
+
+// Leases bounds the lease of a claim. Default is used when a request names
+// none; a request may not ask for more than Max.
+type Leases struct {
+	Default time.Duration
+	Max     time.Duration
+}
+
+// NewClaimQuoteHandler serves POST /quotes/claim. It claims a random
+// quote, or the oldest with "strategy": "oldest", among those matching the
+// body's filters that no one else holds a claim on, and answers with the
+// quote and the claim's expiry. The claimant is the body's, or the
+// caller's principal. When every matching quote is claimed it answers 409
+// nothing_to_claim.
+func NewClaimQuoteHandler(logger *slog.Logger, cs storage.Claimer, leases Leases) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.claim.ClaimQuote"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		var req models.ClaimRequest
+		defer r.Body.Close()
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			log.ErrorContext(ctx, "failed to decode request body", slog.String("error", err.Error()))
+			return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyInvalid, nil)
+		}
+
+		claimant, err := resolveClaimant(r, log, req.Claimant)
+		if err != nil {
+			return err
+		}
+
+		opts := storage.ClaimOptions{
+			Filter: storage.QuoteFilter{HasSource: req.HasSource},
+			Lease:  leases.Default,
+		}
+		if req.Lease != "" {
+			lease, err := time.ParseDuration(req.Lease)
+			if err != nil || lease <= 0 || lease > leases.Max {
+				log.WarnContext(ctx, "invalid claim lease", sl.UserText("lease", req.Lease))
+				return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, "lease")
+			}
+			opts.Lease = lease
+		}
+		switch req.Strategy {
+		case "", models.ClaimRandom:
+		case models.ClaimOldest:
+			opts.Oldest = true
+		default:
+			log.WarnContext(ctx, "invalid claim strategy", sl.UserText("strategy", req.Strategy))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, "strategy")
+		}
+		if req.Lang != "" {
+			normalized, err := language.Normalize(req.Lang)
+			if err != nil {
+				log.WarnContext(ctx, "invalid claim language", sl.UserText("lang", req.Lang))
+				return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, "lang")
+			}
+			opts.Filter.Lang = normalized
+		}
+
+		claim, err := cs.ClaimQuote(ctx, claimant, opts)
+		if err != nil {
+			log.Log(ctx, apierror.Level(err), "failed to claim quote", slog.String("error", err.Error()))
+			return apierror.Failed(apierror.CodeClaimQuoteFailed, err)
+		}
+
+		log.InfoContext(ctx, "quote claimed",
+			slog.Int64("id", claim.Quote.ID),
+			slog.String("claimant", claimant),
+			slog.Time("expires_at", claim.ExpiresAt),
+		)
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   claim,
+		})
+		return nil
+	})
+}
+
+// NewReleaseQuoteHandler serves POST /quotes/{id}/release, which ends the
+// caller's claim on a quote before its lease runs out. Releasing a quote
+// claimed by someone else, or whose claim has expired, answers 409
+// claim_not_held.
+func NewReleaseQuoteHandler(logger *slog.Logger, cs storage.Claimer) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.claim.ReleaseQuote"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		idStr := mux.Vars(r)["id"]
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			log.WarnContext(ctx, "invalid quote ID format", slog.String("id", idStr), slog.String("error", err.Error()))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidQuoteID, nil)
+		}
+
+		var req models.ReleaseRequest
+		defer r.Body.Close()
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			log.ErrorContext(ctx, "failed to decode request body", slog.String("error", err.Error()))
+			return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyInvalid, nil)
+		}
+		claimant, err := resolveClaimant(r, log, req.Claimant)
+		if err != nil {
+			return err
+		}
+
+		if err := cs.ReleaseQuote(ctx, id, claimant); err != nil {
+			log.Log(ctx, apierror.Level(err), "failed to release quote", slog.Int64("id", id), slog.String("claimant", claimant), slog.String("error", err.Error()))
+			return apierror.Failed(apierror.CodeReleaseQuoteFailed, err)
+		}
+
+		log.InfoContext(ctx, "quote released", slog.Int64("id", id), slog.String("claimant", claimant))
+		response.JSON(w, r, http.StatusOK, models.GenericMessageResponse{
+			Status:  "success",
+			Message: "Quote released.",
+		})
+		return nil
+	})
+}
+
+// resolveClaimant returns the claimant named in a body, or the caller's
+// principal when it names none.
+func resolveClaimant(r *http.Request, log *slog.Logger, named string) (string, error) {
+	if named != "" {
+		if len([]rune(named)) > maxClaimantChars {
+			log.WarnContext(r.Context(), "claimant too long", sl.UserText("claimant", named))
+			return "", apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, "claimant")
+		}
+		return named, nil
+	}
+	if principal, ok := auth.Principal(r.Context()); ok {
+		return principal, nil
+	}
+	log.InfoContext(r.Context(), "claim without a claimant")
+	return "", apierror.New(http.StatusBadRequest, apierror.CodeClaimantRequired, nil)
+}
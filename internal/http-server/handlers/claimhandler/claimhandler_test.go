@@ -0,0 +1,164 @@
+package claimhandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/handlers/claimhandler"
+	"quotes-service/internal/http-server/middleware/auth"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func newClaimRouter(t *testing.T, quotes int) http.Handler {
+	t.Helper()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for i := range quotes {
+		if _, err := store.AddQuote(context.Background(), models.Quote{Text: fmt.Sprintf("quote %d", i), Author: "A"}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	leases := claimhandler.Leases{Default: time.Minute, Max: time.Hour}
+	router := mux.NewRouter()
+	router.HandleFunc("/quotes/claim", claimhandler.NewClaimQuoteHandler(logger, store, leases)).Methods(http.MethodPost)
+	router.HandleFunc("/quotes/{id}/release", claimhandler.NewReleaseQuoteHandler(logger, store)).Methods(http.MethodPost)
+	return router
+}
+
+func post(router http.Handler, target, principal, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	if principal != "" {
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestClaimQuoteHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		principal      string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "principal", principal: "alice", expectedStatus: http.StatusOK},
+		{name: "named claimant", body: `{"claimant": "worker-1", "strategy": "oldest", "lease": "30m"}`, expectedStatus: http.StatusOK},
+		{name: "filter", principal: "alice", body: `{"has_source": false}`, expectedStatus: http.StatusOK},
+		{name: "no claimant", expectedStatus: http.StatusBadRequest, expectedCode: "claimant_required"},
+		{name: "lease too long", principal: "alice", body: `{"lease": "2h"}`, expectedStatus: http.StatusBadRequest, expectedCode: "invalid_parameter"},
+		{name: "negative lease", principal: "alice", body: `{"lease": "-1m"}`, expectedStatus: http.StatusBadRequest, expectedCode: "invalid_parameter"},
+		{name: "unknown strategy", principal: "alice", body: `{"strategy": "newest"}`, expectedStatus: http.StatusBadRequest, expectedCode: "invalid_parameter"},
+		{name: "bad lang", principal: "alice", body: `{"lang": "not a tag"}`, expectedStatus: http.StatusBadRequest, expectedCode: "invalid_parameter"},
+		{name: "malformed body", principal: "alice", body: `{`, expectedStatus: http.StatusBadRequest, expectedCode: "request_body_invalid"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := post(newClaimRouter(t, 3), "/quotes/claim", tc.principal, tc.body)
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tc.expectedCode != "" && !strings.Contains(rr.Body.String(), `"`+tc.expectedCode+`"`) {
+				t.Fatalf("expected code %s, got %s", tc.expectedCode, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestClaimAndRelease(t *testing.T) {
+	router := newClaimRouter(t, 1)
+
+	rr := post(router, "/quotes/claim", "alice", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Data models.QuoteClaim `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode claim: %v", err)
+	}
+	if resp.Data.Claimant != "alice" || resp.Data.ExpiresAt.Sub(resp.Data.ClaimedAt) != time.Minute {
+		t.Fatalf("unexpected claim %+v", resp.Data)
+	}
+
+	steps := []struct {
+		target         string
+		principal      string
+		body           string
+		expectedStatus int
+	}{
+		{"/quotes/claim", "bob", "", http.StatusConflict},
+		{"/quotes/1/release", "bob", "", http.StatusConflict},
+		{"/quotes/2/release", "alice", "", http.StatusNotFound},
+		{"/quotes/x/release", "alice", "", http.StatusBadRequest},
+		{"/quotes/1/release", "", `{"claimant": "alice"}`, http.StatusOK},
+		{"/quotes/1/release", "alice", "", http.StatusConflict},
+		{"/quotes/claim", "bob", "", http.StatusOK},
+	}
+	for _, step := range steps {
+		rr := post(router, step.target, step.principal, step.body)
+		if rr.Code != step.expectedStatus {
+			t.Fatalf("expected %d for %s by %q, got %d: %s", step.expectedStatus, step.target, step.principal, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestClaimQuoteHandlerConcurrent(t *testing.T) {
+	const quotes = 50
+	router := newClaimRouter(t, quotes)
+
+	var (
+		mu     sync.Mutex
+		owners = make(map[int64]string)
+		wg     sync.WaitGroup
+	)
+	for w := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			claimant := fmt.Sprintf("worker-%d", w)
+			for {
+				rr := post(router, "/quotes/claim", claimant, "")
+				if rr.Code == http.StatusConflict {
+					return
+				}
+				var resp struct {
+					Data models.QuoteClaim `json:"data"`
+				}
+				if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &resp) != nil {
+					t.Errorf("unexpected claim response %d: %s", rr.Code, rr.Body.String())
+					return
+				}
+				mu.Lock()
+				if owner, taken := owners[resp.Data.Quote.ID]; taken {
+					t.Errorf("quote %d claimed by both %s and %s", resp.Data.Quote.ID, owner, claimant)
+				}
+				owners[resp.Data.Quote.ID] = claimant
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(owners) != quotes {
+		t.Fatalf("expected all %d quotes claimed once, got %d", quotes, len(owners))
+	}
+}
@@ -0,0 +1,286 @@
+package collectionhandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+const maxNameLength = 100
+
+type CollectionStore interface {
+	CreateCollection(ctx context.Context, name string, description string) (models.Collection, error)
+	GetCollections(ctx context.Context) ([]models.Collection, error)
+	GetCollection(ctx context.Context, id int64) (models.CollectionWithQuotes, error)
+	AddQuotesToCollection(ctx context.Context, id int64, quoteIDs []int64) error
+	RemoveQuoteFromCollection(ctx context.Context, id int64, quoteID int64) error
+	DeleteCollection(ctx context.Context, id int64) error
+	GetRandomCollectionQuote(ctx context.Context, id int64) (models.Quote, error)
+	GetQuoteByPublicID(ctx context.Context, publicID string) (models.Quote, error)
+}
+
+func NewCreateCollectionHandler(logger *slog.Logger, cs CollectionStore) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.collection.CreateCollection"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		var req models.CreateCollectionRequest
+		if err := decodeBody(r, log, &req); err != nil {
+			return err
+		}
+
+		var validationErrors []string
+		name := strings.TrimSpace(req.Name)
+		if name == "" {
+			validationErrors = append(validationErrors, "name cannot be empty")
+		} else if utf8.RuneCountInString(name) > maxNameLength {
+			validationErrors = append(validationErrors, fmt.Sprintf("name cannot be longer than %d characters", maxNameLength))
+		}
+		if len(validationErrors) > 0 {
+			log.WarnContext(ctx, "invalid request", slog.Any("validation_errors", validationErrors))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, validationErrors)
+		}
+
+		collection, err := cs.CreateCollection(ctx, name, strings.TrimSpace(req.Description))
+		if err != nil {
+			log.ErrorContext(ctx, "failed to create collection", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeCreateCollectionFailed, nil)
+		}
+
+		log.InfoContext(ctx, "collection created", slog.Int64("id", collection.ID))
+		response.JSON(w, r, http.StatusCreated, models.SuccessDataResponse{
+			Status: "success",
+			Data:   collection,
+		})
+		return nil
+	})
+}
+
+func NewGetCollectionsHandler(logger *slog.Logger, cs CollectionStore) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.collection.GetCollections"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		collections, err := cs.GetCollections(ctx)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to get collections", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeGetCollectionsFailed, nil)
+		}
+
+		log.InfoContext(ctx, "retrieved collections", slog.Int("count", len(collections)))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   collections,
+		})
+		return nil
+	})
+}
+
+func NewGetCollectionHandler(logger *slog.Logger, cs CollectionStore) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.collection.GetCollection"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		id, err := parseID(r, log, "id")
+		if err != nil {
+			return err
+		}
+
+		collection, err := cs.GetCollection(ctx, id)
+		if err != nil {
+			log.Log(ctx, apierror.Level(err), "failed to get collection", slog.Int64("id", id), slog.String("error", err.Error()))
+			return apierror.Failed(apierror.CodeGetCollectionFailed, err)
+		}
+
+		log.InfoContext(ctx, "retrieved collection", slog.Int64("id", id), slog.Int("quotes", len(collection.Quotes)))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   collection,
+		})
+		return nil
+	})
+}
+
+func NewAddCollectionQuotesHandler(logger *slog.Logger, cs CollectionStore) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.collection.AddCollectionQuotes"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		id, err := parseID(r, log, "id")
+		if err != nil {
+			return err
+		}
+
+		var req models.CollectionQuotesRequest
+		if err := decodeBody(r, log, &req); err != nil {
+			return err
+		}
+		if len(req.QuoteIDs) == 0 && len(req.PublicQuoteIDs) == 0 {
+			log.WarnContext(ctx, "no quote IDs in request")
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, []string{"quote_ids cannot be empty"})
+		}
+
+		quoteIDs := req.QuoteIDs
+		for _, publicID := range req.PublicQuoteIDs {
+			quote, err := cs.GetQuoteByPublicID(ctx, publicID)
+			if err != nil {
+				if errors.Is(err, storage.ErrQuoteNotFound) {
+					log.InfoContext(ctx, "quote not found for collection", slog.Int64("id", id), slog.String("public_id", publicID))
+					return apierror.New(http.StatusNotFound, apierror.CodeQuoteIDNotFound, nil, publicID)
+				}
+				log.ErrorContext(ctx, "failed to resolve public ID", slog.String("public_id", publicID), slog.String("error", err.Error()))
+				return apierror.New(http.StatusInternalServerError, apierror.CodeAddToCollectionFailed, nil)
+			}
+			quoteIDs = append(quoteIDs, quote.ID)
+		}
+
+		err = cs.AddQuotesToCollection(ctx, id, quoteIDs)
+		if err != nil {
+			var notFound *storage.QuoteNotFoundError
+			if errors.As(err, &notFound) {
+				log.InfoContext(ctx, "quote not found for collection", slog.Int64("id", id), slog.Int64("quote_id", notFound.ID))
+				return apierror.New(http.StatusNotFound, apierror.CodeQuoteIDNotFound, nil, notFound.ID)
+			}
+			log.Log(ctx, apierror.Level(err), "failed to add quotes to collection", slog.Int64("id", id), slog.String("error", err.Error()))
+			return apierror.Failed(apierror.CodeAddToCollectionFailed, err)
+		}
+
+		log.InfoContext(ctx, "quotes added to collection", slog.Int64("id", id), slog.Int("count", len(quoteIDs)))
+		response.JSON(w, r, http.StatusOK, models.GenericMessageResponse{
+			Status:  "success",
+			Message: "Quotes added to collection.",
+		})
+		return nil
+	})
+}
+
+func NewRemoveCollectionQuoteHandler(logger *slog.Logger, cs CollectionStore) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.collection.RemoveCollectionQuote"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		id, err := parseID(r, log, "id")
+		if err != nil {
+			return err
+		}
+		quoteID, err := parseID(r, log, "quote_id")
+		if err != nil {
+			return err
+		}
+
+		err = cs.RemoveQuoteFromCollection(ctx, id, quoteID)
+		if err != nil {
+			if errors.Is(err, storage.ErrQuoteNotFound) {
+				log.InfoContext(ctx, "quote not in collection", slog.Int64("id", id), slog.Int64("quote_id", quoteID))
+				return apierror.New(http.StatusNotFound, apierror.CodeQuoteNotInCollection, nil, quoteID)
+			}
+			log.Log(ctx, apierror.Level(err), "failed to remove quote from collection", slog.Int64("id", id), slog.String("error", err.Error()))
+			return apierror.Failed(apierror.CodeRemoveFromCollectionFailed, err)
+		}
+
+		log.InfoContext(ctx, "quote removed from collection", slog.Int64("id", id), slog.Int64("quote_id", quoteID))
+		response.JSON(w, r, http.StatusOK, models.GenericMessageResponse{
+			Status:  "success",
+			Message: "Quote removed from collection.",
+		})
+		return nil
+	})
+}
+
+func NewDeleteCollectionHandler(logger *slog.Logger, cs CollectionStore) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.collection.DeleteCollection"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		id, err := parseID(r, log, "id")
+		if err != nil {
+			return err
+		}
+
+		if err := cs.DeleteCollection(ctx, id); err != nil {
+			log.Log(ctx, apierror.Level(err), "failed to delete collection", slog.Int64("id", id), slog.String("error", err.Error()))
+			return apierror.Failed(apierror.CodeDeleteCollectionFailed, err)
+		}
+
+		log.InfoContext(ctx, "collection deleted", slog.Int64("id", id))
+		response.JSON(w, r, http.StatusOK, models.GenericMessageResponse{
+			Status:  "success",
+			Message: "Collection deleted successfully.",
+		})
+		return nil
+	})
+}
+
+func NewGetRandomCollectionQuoteHandler(logger *slog.Logger, cs CollectionStore) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.collection.GetRandomCollectionQuote"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		id, err := parseID(r, log, "id")
+		if err != nil {
+			return err
+		}
+
+		quote, err := cs.GetRandomCollectionQuote(ctx, id)
+		if err != nil {
+			if errors.Is(err, storage.ErrQuoteNotFound) {
+				log.InfoContext(ctx, "collection is empty", slog.Int64("id", id))
+				return apierror.New(http.StatusNotFound, apierror.CodeNoQuotes, nil)
+			}
+			log.Log(ctx, apierror.Level(err), "failed to get random collection quote", slog.Int64("id", id), slog.String("error", err.Error()))
+			return apierror.Failed(apierror.CodeGetRandomFailed, err)
+		}
+
+		log.InfoContext(ctx, "retrieved random collection quote", slog.Int64("id", id), slog.Int64("quote_id", quote.ID))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   quote,
+		})
+		return nil
+	})
+}
+
+func decodeBody(r *http.Request, log *slog.Logger, dst interface{}) error {
+	ctx := r.Context()
+	defer r.Body.Close()
+
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		if errors.Is(err, io.EOF) {
+			log.WarnContext(ctx, "request body is empty")
+			return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyEmpty, nil)
+		}
+		log.ErrorContext(ctx, "failed to decode request body", slog.String("error", err.Error()))
+		return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyInvalid, nil)
+	}
+	return nil
+}
+
+func parseID(r *http.Request, log *slog.Logger, name string) (int64, error) {
+	idStr := mux.Vars(r)[name]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		log.WarnContext(r.Context(), "invalid ID format", slog.String(name, idStr), slog.String("error", err.Error()))
+		return 0, apierror.New(http.StatusBadRequest, apierror.CodeInvalidID, nil)
+	}
+	return id, nil
+}
@@ -0,0 +1,338 @@
+package collectionhandler_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/handlers/collectionhandler"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+var errTestStorageInternal = errors.New("test: internal storage error")
+
+type MockCollectionStore struct {
+	CreateCollectionFunc          func(ctx context.Context, name string, description string) (models.Collection, error)
+	GetCollectionsFunc            func(ctx context.Context) ([]models.Collection, error)
+	GetCollectionFunc             func(ctx context.Context, id int64) (models.CollectionWithQuotes, error)
+	AddQuotesToCollectionFunc     func(ctx context.Context, id int64, quoteIDs []int64) error
+	RemoveQuoteFromCollectionFunc func(ctx context.Context, id int64, quoteID int64) error
+	DeleteCollectionFunc          func(ctx context.Context, id int64) error
+	GetRandomCollectionQuoteFunc  func(ctx context.Context, id int64) (models.Quote, error)
+	GetQuoteByPublicIDFunc        func(ctx context.Context, publicID string) (models.Quote, error)
+}
+
+func (m *MockCollectionStore) CreateCollection(ctx context.Context, name string, description string) (models.Collection, error) {
+	if m.CreateCollectionFunc != nil {
+		return m.CreateCollectionFunc(ctx, name, description)
+	}
+	return models.Collection{}, errors.New("CreateCollectionFunc not implemented")
+}
+
+func (m *MockCollectionStore) GetCollections(ctx context.Context) ([]models.Collection, error) {
+	if m.GetCollectionsFunc != nil {
+		return m.GetCollectionsFunc(ctx)
+	}
+	return nil, errors.New("GetCollectionsFunc not implemented")
+}
+
+func (m *MockCollectionStore) GetCollection(ctx context.Context, id int64) (models.CollectionWithQuotes, error) {
+	if m.GetCollectionFunc != nil {
+		return m.GetCollectionFunc(ctx, id)
+	}
+	return models.CollectionWithQuotes{}, errors.New("GetCollectionFunc not implemented")
+}
+
+func (m *MockCollectionStore) AddQuotesToCollection(ctx context.Context, id int64, quoteIDs []int64) error {
+	if m.AddQuotesToCollectionFunc != nil {
+		return m.AddQuotesToCollectionFunc(ctx, id, quoteIDs)
+	}
+	return errors.New("AddQuotesToCollectionFunc not implemented")
+}
+
+func (m *MockCollectionStore) RemoveQuoteFromCollection(ctx context.Context, id int64, quoteID int64) error {
+	if m.RemoveQuoteFromCollectionFunc != nil {
+		return m.RemoveQuoteFromCollectionFunc(ctx, id, quoteID)
+	}
+	return errors.New("RemoveQuoteFromCollectionFunc not implemented")
+}
+
+func (m *MockCollectionStore) DeleteCollection(ctx context.Context, id int64) error {
+	if m.DeleteCollectionFunc != nil {
+		return m.DeleteCollectionFunc(ctx, id)
+	}
+	return errors.New("DeleteCollectionFunc not implemented")
+}
+
+func (m *MockCollectionStore) GetRandomCollectionQuote(ctx context.Context, id int64) (models.Quote, error) {
+	if m.GetRandomCollectionQuoteFunc != nil {
+		return m.GetRandomCollectionQuoteFunc(ctx, id)
+	}
+	return models.Quote{}, errors.New("GetRandomCollectionQuoteFunc not implemented")
+}
+
+func (m *MockCollectionStore) GetQuoteByPublicID(ctx context.Context, publicID string) (models.Quote, error) {
+	if m.GetQuoteByPublicIDFunc != nil {
+		return m.GetQuoteByPublicIDFunc(ctx, publicID)
+	}
+	return models.Quote{}, errors.New("GetQuoteByPublicIDFunc not implemented")
+}
+
+func newRouter(logger *slog.Logger, cs collectionhandler.CollectionStore) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/collections", collectionhandler.NewCreateCollectionHandler(logger, cs)).Methods(http.MethodPost)
+	router.HandleFunc("/collections", collectionhandler.NewGetCollectionsHandler(logger, cs)).Methods(http.MethodGet)
+	router.HandleFunc("/collections/{id}", collectionhandler.NewGetCollectionHandler(logger, cs)).Methods(http.MethodGet)
+	router.HandleFunc("/collections/{id}", collectionhandler.NewDeleteCollectionHandler(logger, cs)).Methods(http.MethodDelete)
+	router.HandleFunc("/collections/{id}/quotes", collectionhandler.NewAddCollectionQuotesHandler(logger, cs)).Methods(http.MethodPost)
+	router.HandleFunc("/collections/{id}/quotes/{quote_id}", collectionhandler.NewRemoveCollectionQuoteHandler(logger, cs)).Methods(http.MethodDelete)
+	router.HandleFunc("/collections/{id}/random", collectionhandler.NewGetRandomCollectionQuoteHandler(logger, cs)).Methods(http.MethodGet)
+	return router
+}
+
+func TestCollectionHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		mockStoreSetup func(*MockCollectionStore)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "create success",
+			method: http.MethodPost,
+			path:   "/collections",
+			body:   `{"name":" Standup openers ","description":"Morning energy"}`,
+			mockStoreSetup: func(ms *MockCollectionStore) {
+				ms.CreateCollectionFunc = func(ctx context.Context, name string, description string) (models.Collection, error) {
+					return models.Collection{ID: 1, Name: name, Description: description}, nil
+				}
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"status":"success","data":{"id":1,"name":"Standup openers","description":"Morning energy","quote_count":0}}`,
+		},
+		{
+			name:           "create empty name",
+			method:         http.MethodPost,
+			path:           "/collections",
+			body:           `{"name":"  "}`,
+			mockStoreSetup: func(ms *MockCollectionStore) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_request","error":"Invalid request.","fields":["name cannot be empty"]}`,
+		},
+		{
+			name:           "create empty body",
+			method:         http.MethodPost,
+			path:           "/collections",
+			body:           ``,
+			mockStoreSetup: func(ms *MockCollectionStore) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"request_body_empty","error":"Request body is empty."}`,
+		},
+		{
+			name:   "list success",
+			method: http.MethodGet,
+			path:   "/collections",
+			mockStoreSetup: func(ms *MockCollectionStore) {
+				ms.GetCollectionsFunc = func(ctx context.Context) ([]models.Collection, error) {
+					return []models.Collection{{ID: 1, Name: "Retro closers", QuoteCount: 2}}, nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":[{"id":1,"name":"Retro closers","quote_count":2}]}`,
+		},
+		{
+			name:   "get success",
+			method: http.MethodGet,
+			path:   "/collections/1",
+			mockStoreSetup: func(ms *MockCollectionStore) {
+				ms.GetCollectionFunc = func(ctx context.Context, id int64) (models.CollectionWithQuotes, error) {
+					return models.CollectionWithQuotes{
+						Collection: models.Collection{ID: 1, Name: "Retro closers", QuoteCount: 1},
+						Quotes:     []models.Quote{{ID: 5, Text: "Done", Author: "Team"}},
+					}, nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"id":1,"name":"Retro closers","quote_count":1,"quotes":[{"id":5,"text":"Done","author":"Team"}]}}`,
+		},
+		{
+			name:   "get not found",
+			method: http.MethodGet,
+			path:   "/collections/2",
+			mockStoreSetup: func(ms *MockCollectionStore) {
+				ms.GetCollectionFunc = func(ctx context.Context, id int64) (models.CollectionWithQuotes, error) {
+					return models.CollectionWithQuotes{}, storage.ErrCollectionNotFound
+				}
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"status":"error","code":"collection_not_found","error":"Collection not found."}`,
+		},
+		{
+			name:   "add quotes success",
+			method: http.MethodPost,
+			path:   "/collections/1/quotes",
+			body:   `{"quote_ids":[1,2]}`,
+			mockStoreSetup: func(ms *MockCollectionStore) {
+				ms.AddQuotesToCollectionFunc = func(ctx context.Context, id int64, quoteIDs []int64) error {
+					return nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","message":"Quotes added to collection."}`,
+		},
+		{
+			name:   "add missing quote",
+			method: http.MethodPost,
+			path:   "/collections/1/quotes",
+			body:   `{"quote_ids":[1,42]}`,
+			mockStoreSetup: func(ms *MockCollectionStore) {
+				ms.AddQuotesToCollectionFunc = func(ctx context.Context, id int64, quoteIDs []int64) error {
+					return &storage.QuoteNotFoundError{ID: 42}
+				}
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"status":"error","code":"quote_id_not_found","error":"Quote 42 not found."}`,
+		},
+		{
+			name:   "add quotes by public id",
+			method: http.MethodPost,
+			path:   "/collections/1/quotes",
+			body:   `{"quote_ids":[1],"public_quote_ids":["01ARZ3NDEKTSV4RRFFQ69G5FAV"]}`,
+			mockStoreSetup: func(ms *MockCollectionStore) {
+				ms.GetQuoteByPublicIDFunc = func(ctx context.Context, publicID string) (models.Quote, error) {
+					return models.Quote{ID: 7, PublicID: publicID}, nil
+				}
+				ms.AddQuotesToCollectionFunc = func(ctx context.Context, id int64, quoteIDs []int64) error {
+					if !reflect.DeepEqual(quoteIDs, []int64{1, 7}) {
+						return fmt.Errorf("unexpected quote ids %v", quoteIDs)
+					}
+					return nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","message":"Quotes added to collection."}`,
+		},
+		{
+			name:   "add missing public id",
+			method: http.MethodPost,
+			path:   "/collections/1/quotes",
+			body:   `{"public_quote_ids":["01ARZ3NDEKTSV4RRFFQ69G5FAV"]}`,
+			mockStoreSetup: func(ms *MockCollectionStore) {
+				ms.GetQuoteByPublicIDFunc = func(ctx context.Context, publicID string) (models.Quote, error) {
+					return models.Quote{}, storage.ErrQuoteNotFound
+				}
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"status":"error","code":"quote_id_not_found","error":"Quote 01ARZ3NDEKTSV4RRFFQ69G5FAV not found."}`,
+		},
+		{
+			name:           "add no quotes",
+			method:         http.MethodPost,
+			path:           "/collections/1/quotes",
+			body:           `{"quote_ids":[]}`,
+			mockStoreSetup: func(ms *MockCollectionStore) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_request","error":"Invalid request.","fields":["quote_ids cannot be empty"]}`,
+		},
+		{
+			name:   "remove quote not member",
+			method: http.MethodDelete,
+			path:   "/collections/1/quotes/7",
+			mockStoreSetup: func(ms *MockCollectionStore) {
+				ms.RemoveQuoteFromCollectionFunc = func(ctx context.Context, id int64, quoteID int64) error {
+					return &storage.QuoteNotFoundError{ID: quoteID}
+				}
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"status":"error","code":"quote_not_in_collection","error":"Quote 7 not found in collection."}`,
+		},
+		{
+			name:   "delete storage error",
+			method: http.MethodDelete,
+			path:   "/collections/1",
+			mockStoreSetup: func(ms *MockCollectionStore) {
+				ms.DeleteCollectionFunc = func(ctx context.Context, id int64) error {
+					return errTestStorageInternal
+				}
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"status":"error","code":"delete_collection_failed","error":"Failed to delete collection."}`,
+		},
+		{
+			name:   "random empty collection",
+			method: http.MethodGet,
+			path:   "/collections/1/random",
+			mockStoreSetup: func(ms *MockCollectionStore) {
+				ms.GetRandomCollectionQuoteFunc = func(ctx context.Context, id int64) (models.Quote, error) {
+					return models.Quote{}, storage.ErrQuoteNotFound
+				}
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"status":"error","code":"no_quotes","error":"No quotes found."}`,
+		},
+		{
+			name:           "invalid id",
+			method:         http.MethodGet,
+			path:           "/collections/abc",
+			mockStoreSetup: func(ms *MockCollectionStore) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_id","error":"Invalid ID format."}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockStore := &MockCollectionStore{}
+			tc.mockStoreSetup(mockStore)
+			router := newRouter(logger, mockStore)
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tc.expectedBody) {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestCollectionHandlersLocalizedErrors(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockStore := &MockCollectionStore{
+		GetCollectionFunc: func(ctx context.Context, id int64) (models.CollectionWithQuotes, error) {
+			return models.CollectionWithQuotes{}, storage.ErrCollectionNotFound
+		},
+	}
+	router := newRouter(logger, mockStore)
+
+	req := httptest.NewRequest(http.MethodGet, "/collections/5", nil)
+	req.Header.Set("Accept-Language", "ru-RU,ru;q=0.9,en;q=0.5")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	expected := `{"status":"error","code":"collection_not_found","error":"Коллекция не найдена."}`
+	if strings.TrimSpace(rr.Body.String()) != expected {
+		t.Errorf("expected body %q, got %q", expected, rr.Body.String())
+	}
+	if got := rr.Header().Get("Content-Language"); got != "ru" {
+		t.Errorf("expected Content-Language ru, got %q", got)
+	}
+}
@@ -0,0 +1,158 @@
+package exporthandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/headers"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/jobs/export"
+	"quotes-service/internal/lib/language"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+func init() {
+	headers.Expose("Location", "Content-Disposition")
+}
+
+// ExportManager runs exports in the background. *export.Manager is the
+// real one.
+type ExportManager interface {
+	Submit(format string, filter storage.QuoteFilter) (models.ExportJob, error)
+	Get(id string) (models.ExportJob, error)
+	Open(ctx context.Context, id string) (models.ExportJob, io.ReadCloser, error)
+}
+
+// NewCreateExportHandler serves POST /exports. It queues an export of the
+// quotes the body's filters match and answers 202 Accepted with the job,
+// which GET /exports/{id} then reports on. An empty body exports every
+// quote in the native format.
+func NewCreateExportHandler(logger *slog.Logger, em ExportManager) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.export.CreateExport"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		var req models.ExportRequest
+		defer r.Body.Close()
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			log.ErrorContext(ctx, "failed to decode request body", slog.String("error", err.Error()))
+			return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyInvalid, nil)
+		}
+
+		filter := storage.QuoteFilter{HasSource: req.HasSource}
+		if req.Lang != "" {
+			normalized, err := language.Normalize(req.Lang)
+			if err != nil {
+				log.WarnContext(ctx, "invalid export language", slog.String("lang", req.Lang))
+				return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, "lang")
+			}
+			filter.Lang = normalized
+		}
+
+		job, err := em.Submit(req.Format, filter)
+		if err != nil {
+			switch {
+			case errors.Is(err, export.ErrUnknownFormat):
+				log.WarnContext(ctx, "invalid export format", slog.String("format", req.Format))
+				return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, "format")
+			case errors.Is(err, export.ErrQueueFull):
+				log.WarnContext(ctx, "export queue is full")
+				return apierror.New(http.StatusServiceUnavailable, apierror.CodeExportQueueFull, nil)
+			default:
+				log.ErrorContext(ctx, "failed to queue export", slog.String("error", err.Error()))
+				return apierror.New(http.StatusInternalServerError, apierror.CodeExportFailed, nil)
+			}
+		}
+
+		log.InfoContext(ctx, "export queued", slog.String("id", job.ID), slog.String("format", job.Format))
+		w.Header().Set("Location", "/exports/"+job.ID)
+		response.JSON(w, r, http.StatusAccepted, models.SuccessDataResponse{
+			Status: "success",
+			Data:   job,
+		})
+		return nil
+	})
+}
+
+// NewGetExportHandler serves GET /exports/{id}, the status and progress of
+// an export job.
+func NewGetExportHandler(logger *slog.Logger, em ExportManager) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.export.GetExport"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		id := mux.Vars(r)["id"]
+		job, err := em.Get(id)
+		if err != nil {
+			if errors.Is(err, export.ErrNotFound) {
+				log.InfoContext(ctx, "export not found", slog.String("id", id))
+				return apierror.New(http.StatusNotFound, apierror.CodeExportNotFound, nil)
+			}
+			log.ErrorContext(ctx, "failed to get export", slog.String("id", id), slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeExportFailed, nil)
+		}
+
+		log.InfoContext(ctx, "retrieved export", slog.String("id", id), slog.String("status", job.Status))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   job,
+		})
+		return nil
+	})
+}
+
+// NewDownloadExportHandler serves GET /exports/{id}/download, the file of
+// a finished export as JSON Lines. Jobs that are not done yet, or did not
+// finish successfully, answer 409 Conflict.
+func NewDownloadExportHandler(logger *slog.Logger, em ExportManager) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.export.DownloadExport"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		id := mux.Vars(r)["id"]
+		job, body, err := em.Open(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, export.ErrNotFound):
+				log.InfoContext(ctx, "export not found", slog.String("id", id))
+				return apierror.New(http.StatusNotFound, apierror.CodeExportNotFound, nil)
+			case errors.Is(err, export.ErrNotReady):
+				log.InfoContext(ctx, "export not ready", slog.String("id", id), slog.String("status", job.Status))
+				return apierror.New(http.StatusConflict, apierror.CodeExportNotReady, nil)
+			default:
+				log.ErrorContext(ctx, "failed to open export", slog.String("id", id), slog.String("error", err.Error()))
+				return apierror.New(http.StatusInternalServerError, apierror.CodeExportFailed, nil)
+			}
+		}
+		defer body.Close()
+
+		// A large export can take longer to send than the server's write
+		// timeout allows a response.
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+		w.Header().Set("Content-Type", export.ContentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="quotes-`+job.ID+`.jsonl"`)
+		w.Header().Set("Content-Length", strconv.FormatInt(job.Bytes, 10))
+		w.WriteHeader(http.StatusOK)
+		n, err := io.Copy(w, body)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to send export", slog.String("id", id), slog.Int64("bytes", n), slog.String("error", err.Error()))
+			return nil
+		}
+
+		log.InfoContext(ctx, "export downloaded", slog.String("id", id), slog.Int64("bytes", n))
+		return nil
+	})
+}
@@ -0,0 +1,242 @@
+package exporthandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/handlers/exporthandler"
+	"quotes-service/internal/jobs/export"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+type MockExportManager struct {
+	SubmitFunc func(format string, filter storage.QuoteFilter) (models.ExportJob, error)
+	GetFunc    func(id string) (models.ExportJob, error)
+	OpenFunc   func(ctx context.Context, id string) (models.ExportJob, io.ReadCloser, error)
+}
+
+func (m *MockExportManager) Submit(format string, filter storage.QuoteFilter) (models.ExportJob, error) {
+	return m.SubmitFunc(format, filter)
+}
+
+func (m *MockExportManager) Get(id string) (models.ExportJob, error) {
+	return m.GetFunc(id)
+}
+
+func (m *MockExportManager) Open(ctx context.Context, id string) (models.ExportJob, io.ReadCloser, error) {
+	return m.OpenFunc(ctx, id)
+}
+
+func newRouter(em exporthandler.ExportManager) *mux.Router {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := mux.NewRouter()
+	router.HandleFunc("/exports", exporthandler.NewCreateExportHandler(logger, em)).Methods(http.MethodPost)
+	router.HandleFunc("/exports/{id}", exporthandler.NewGetExportHandler(logger, em)).Methods(http.MethodGet)
+	router.HandleFunc("/exports/{id}/download", exporthandler.NewDownloadExportHandler(logger, em)).Methods(http.MethodGet)
+	return router
+}
+
+func decodeJob(t *testing.T, body []byte) models.ExportJob {
+	t.Helper()
+	var resp struct {
+		Data models.ExportJob `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("failed to decode job: %v", err)
+	}
+	return resp.Data
+}
+
+func TestExportLifecycle(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for _, q := range []models.Quote{
+		{Text: "First", Author: "A", Lang: "en"},
+		{Text: "Premier", Author: "B", Lang: "fr"},
+		{Text: "Second", Author: "A", Lang: "en"},
+	} {
+		if _, err := store.AddQuote(ctx, q); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	manager := export.New(logger, store, nil, export.Options{Dir: t.TempDir()})
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		manager.Run(runCtx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	router := newRouter(manager)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/exports", strings.NewReader(`{"lang":"EN"}`)))
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+	job := decodeJob(t, rr.Body.Bytes())
+	if job.Lang != "en" || job.Format != export.FormatNative {
+		t.Fatalf("unexpected queued job %+v", job)
+	}
+	if got := rr.Header().Get("Location"); got != "/exports/"+job.ID {
+		t.Fatalf("unexpected Location %q", got)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status != models.JobDone {
+		if time.Now().After(deadline) {
+			t.Fatalf("export did not finish: %+v", job)
+		}
+		time.Sleep(5 * time.Millisecond)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/exports/"+job.ID, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d. Body: %s", rr.Code, rr.Body.String())
+		}
+		job = decodeJob(t, rr.Body.Bytes())
+	}
+	if job.Total != 2 || job.Written != 2 {
+		t.Fatalf("expected 2 of 2 quotes written, got %+v", job)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/exports/"+job.ID+"/download", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Content-Type"); got != export.ContentType {
+		t.Fatalf("unexpected Content-Type %q", got)
+	}
+	lines := strings.Split(strings.TrimSuffix(rr.Body.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"text":"First"`) || !strings.Contains(lines[1], `"text":"Second"`) {
+		t.Fatalf("unexpected export %q", rr.Body.String())
+	}
+}
+
+func TestExportHandlerErrors(t *testing.T) {
+	queued := models.ExportJob{ID: "abc", Status: models.JobRunning}
+	manager := &MockExportManager{
+		SubmitFunc: func(format string, filter storage.QuoteFilter) (models.ExportJob, error) {
+			switch format {
+			case "full":
+				return models.ExportJob{}, export.ErrQueueFull
+			case "broken":
+				return models.ExportJob{}, errors.New("boom")
+			}
+			return models.ExportJob{}, export.ErrUnknownFormat
+		},
+		GetFunc: func(id string) (models.ExportJob, error) {
+			return models.ExportJob{}, export.ErrNotFound
+		},
+		OpenFunc: func(ctx context.Context, id string) (models.ExportJob, io.ReadCloser, error) {
+			if id == queued.ID {
+				return queued, nil, export.ErrNotReady
+			}
+			return models.ExportJob{}, nil, export.ErrNotFound
+		},
+	}
+	router := newRouter(manager)
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "invalid body",
+			method:         http.MethodPost,
+			url:            "/exports",
+			body:           `{"format":`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"request_body_invalid","error":"Failed to decode request body."}`,
+		},
+		{
+			name:           "invalid lang",
+			method:         http.MethodPost,
+			url:            "/exports",
+			body:           `{"lang":"not a language"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_parameter","error":"Invalid lang parameter."}`,
+		},
+		{
+			name:           "unknown format",
+			method:         http.MethodPost,
+			url:            "/exports",
+			body:           `{"format":"csv"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_parameter","error":"Invalid format parameter."}`,
+		},
+		{
+			name:           "queue full",
+			method:         http.MethodPost,
+			url:            "/exports",
+			body:           `{"format":"full"}`,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"status":"error","code":"export_queue_full","error":"Too many exports are waiting; try again later."}`,
+		},
+		{
+			name:           "submit fails",
+			method:         http.MethodPost,
+			url:            "/exports",
+			body:           `{"format":"broken"}`,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"status":"error","code":"export_failed","error":"Failed to export quotes."}`,
+		},
+		{
+			name:           "status of unknown job",
+			method:         http.MethodGet,
+			url:            "/exports/missing",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"status":"error","code":"export_not_found","error":"Export not found."}`,
+		},
+		{
+			name:           "download of unknown job",
+			method:         http.MethodGet,
+			url:            "/exports/missing/download",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"status":"error","code":"export_not_found","error":"Export not found."}`,
+		},
+		{
+			name:           "download of running job",
+			method:         http.MethodGet,
+			url:            "/exports/abc/download",
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"status":"error","code":"export_not_ready","error":"Export is not ready for download."}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body)))
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if got := rr.Body.String(); got != tc.expectedBody+"\n" {
+				t.Fatalf("expected body %s, got %s", tc.expectedBody, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package favoritehandler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/middleware/auth"
+	"quotes-service/internal/http-server/pagination"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/logger/sl"
+	"quotes-service/internal/models"
+)
+
+type FavoriteStore interface {
+	AddFavorite(ctx context.Context, principal string, quoteID int64) error
+	RemoveFavorite(ctx context.Context, principal string, quoteID int64) error
+	GetFavorites(ctx context.Context, principal string, limit, offset int) ([]models.Quote, int, error)
+}
+
+func NewAddFavoriteHandler(logger *slog.Logger, fs FavoriteStore) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.favorite.AddFavorite"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		principal, err := requirePrincipal(r, log)
+		if err != nil {
+			return err
+		}
+		id, err := parseID(r, log)
+		if err != nil {
+			return err
+		}
+
+		if err := fs.AddFavorite(ctx, principal, id); err != nil {
+			log.Log(ctx, apierror.Level(err), "failed to add favorite", slog.Int64("id", id), slog.String("error", err.Error()))
+			return apierror.Failed(apierror.CodeAddFavoriteFailed, err)
+		}
+
+		log.InfoContext(ctx, "favorite added", slog.String("principal", principal), slog.Int64("id", id))
+		response.JSON(w, r, http.StatusOK, models.GenericMessageResponse{
+			Status:  "success",
+			Message: "Quote added to favorites.",
+		})
+		return nil
+	})
+}
+
+func NewRemoveFavoriteHandler(logger *slog.Logger, fs FavoriteStore) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.favorite.RemoveFavorite"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		principal, err := requirePrincipal(r, log)
+		if err != nil {
+			return err
+		}
+		id, err := parseID(r, log)
+		if err != nil {
+			return err
+		}
+
+		if err := fs.RemoveFavorite(ctx, principal, id); err != nil {
+			log.ErrorContext(ctx, "failed to remove favorite", slog.Int64("id", id), slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeRemoveFavoriteFailed, nil)
+		}
+
+		log.InfoContext(ctx, "favorite removed", slog.String("principal", principal), slog.Int64("id", id))
+		response.JSON(w, r, http.StatusOK, models.GenericMessageResponse{
+			Status:  "success",
+			Message: "Quote removed from favorites.",
+		})
+		return nil
+	})
+}
+
+func NewGetFavoritesHandler(logger *slog.Logger, fs FavoriteStore, sizes pagination.Sizes) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.favorite.GetFavorites"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		principal, err := requirePrincipal(r, log)
+		if err != nil {
+			return err
+		}
+
+		page, err := pagination.Parse(r, sizes)
+		if err != nil {
+			log.WarnContext(ctx, "invalid pagination", sl.UserText("query", r.URL.RawQuery), slog.String("error", err.Error()))
+			if errors.Is(err, pagination.ErrInvalidOffset) {
+				return apierror.New(http.StatusBadRequest, apierror.CodeInvalidOffset, nil)
+			}
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidLimit, nil)
+		}
+
+		quotes, total, err := fs.GetFavorites(ctx, principal, page.Limit, page.Offset)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to get favorites", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeGetFavoritesFailed, nil)
+		}
+
+		log.InfoContext(ctx, "retrieved favorites", slog.String("principal", principal), slog.Int("count", len(quotes)), slog.Int("total", total))
+		pagination.SetHeaders(w, r, page, total)
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data: models.QuotePage{
+				Quotes: quotes,
+				Total:  total,
+				Limit:  page.Limit,
+				Offset: page.Offset,
+			},
+		})
+		return nil
+	})
+}
+
+func requirePrincipal(r *http.Request, log *slog.Logger) (string, error) {
+	principal, ok := auth.Principal(r.Context())
+	if !ok {
+		log.InfoContext(r.Context(), "unauthenticated favorites request")
+		return "", apierror.New(http.StatusUnauthorized, apierror.CodeAuthRequired, nil)
+	}
+	return principal, nil
+}
+
+func parseID(r *http.Request, log *slog.Logger) (int64, error) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		log.WarnContext(r.Context(), "invalid ID format", slog.String("id", idStr), slog.String("error", err.Error()))
+		return 0, apierror.New(http.StatusBadRequest, apierror.CodeInvalidID, nil)
+	}
+	return id, nil
+}
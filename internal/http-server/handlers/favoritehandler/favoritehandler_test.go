@@ -0,0 +1,186 @@
+package favoritehandler_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/handlers/favoritehandler"
+	"quotes-service/internal/http-server/middleware/auth"
+	"quotes-service/internal/http-server/pagination"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+type MockFavoriteStore struct {
+	AddFavoriteFunc    func(ctx context.Context, principal string, quoteID int64) error
+	RemoveFavoriteFunc func(ctx context.Context, principal string, quoteID int64) error
+	GetFavoritesFunc   func(ctx context.Context, principal string, limit, offset int) ([]models.Quote, int, error)
+}
+
+func (m *MockFavoriteStore) AddFavorite(ctx context.Context, principal string, quoteID int64) error {
+	if m.AddFavoriteFunc != nil {
+		return m.AddFavoriteFunc(ctx, principal, quoteID)
+	}
+	return errors.New("AddFavoriteFunc not implemented")
+}
+
+func (m *MockFavoriteStore) RemoveFavorite(ctx context.Context, principal string, quoteID int64) error {
+	if m.RemoveFavoriteFunc != nil {
+		return m.RemoveFavoriteFunc(ctx, principal, quoteID)
+	}
+	return errors.New("RemoveFavoriteFunc not implemented")
+}
+
+func (m *MockFavoriteStore) GetFavorites(ctx context.Context, principal string, limit, offset int) ([]models.Quote, int, error) {
+	if m.GetFavoritesFunc != nil {
+		return m.GetFavoritesFunc(ctx, principal, limit, offset)
+	}
+	return nil, 0, errors.New("GetFavoritesFunc not implemented")
+}
+
+func TestFavoriteHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	keys := map[string]string{"secret-key": "alice"}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		apiKey         string
+		mockStoreSetup func(*MockFavoriteStore)
+		expectedStatus int
+		expectedBody   string
+		expectedLink   string
+	}{
+		{
+			name:   "add favorite",
+			method: http.MethodPut,
+			path:   "/quotes/3/favorite",
+			apiKey: "secret-key",
+			mockStoreSetup: func(ms *MockFavoriteStore) {
+				ms.AddFavoriteFunc = func(ctx context.Context, principal string, quoteID int64) error {
+					if principal != "alice" || quoteID != 3 {
+						return errors.New("unexpected arguments")
+					}
+					return nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","message":"Quote added to favorites."}`,
+		},
+		{
+			name:           "add favorite unauthenticated",
+			method:         http.MethodPut,
+			path:           "/quotes/3/favorite",
+			mockStoreSetup: func(ms *MockFavoriteStore) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"status":"error","code":"auth_required","error":"Authentication required."}`,
+		},
+		{
+			name:           "unknown API key",
+			method:         http.MethodPut,
+			path:           "/quotes/3/favorite",
+			apiKey:         "wrong-key",
+			mockStoreSetup: func(ms *MockFavoriteStore) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"status":"error","code":"invalid_api_key","error":"Invalid API key."}`,
+		},
+		{
+			name:   "add favorite quote not found",
+			method: http.MethodPut,
+			path:   "/quotes/9/favorite",
+			apiKey: "secret-key",
+			mockStoreSetup: func(ms *MockFavoriteStore) {
+				ms.AddFavoriteFunc = func(ctx context.Context, principal string, quoteID int64) error {
+					return &storage.QuoteNotFoundError{ID: quoteID}
+				}
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"status":"error","code":"quote_not_found","error":"Quote not found."}`,
+		},
+		{
+			name:   "remove favorite",
+			method: http.MethodDelete,
+			path:   "/quotes/3/favorite",
+			apiKey: "secret-key",
+			mockStoreSetup: func(ms *MockFavoriteStore) {
+				ms.RemoveFavoriteFunc = func(ctx context.Context, principal string, quoteID int64) error {
+					return nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","message":"Quote removed from favorites."}`,
+		},
+		{
+			name:   "list favorites",
+			method: http.MethodGet,
+			path:   "/favorites?limit=1&offset=1",
+			apiKey: "secret-key",
+			mockStoreSetup: func(ms *MockFavoriteStore) {
+				ms.GetFavoritesFunc = func(ctx context.Context, principal string, limit, offset int) ([]models.Quote, int, error) {
+					if limit != 1 || offset != 1 {
+						return nil, 0, errors.New("unexpected pagination")
+					}
+					return []models.Quote{{ID: 2, Text: "T2", Author: "A2"}}, 2, nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"quotes":[{"id":2,"text":"T2","author":"A2"}],"total":2,"limit":1,"offset":1}}`,
+			expectedLink:   `</favorites?limit=1&offset=0>; rel="first", </favorites?limit=1&offset=0>; rel="prev", </favorites?limit=1&offset=1>; rel="last"`,
+		},
+		{
+			name:           "list favorites invalid offset",
+			method:         http.MethodGet,
+			path:           "/favorites?offset=-1",
+			apiKey:         "secret-key",
+			mockStoreSetup: func(ms *MockFavoriteStore) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_offset","error":"Offset must be a non-negative integer."}`,
+		},
+		{
+			name:           "list favorites unauthenticated",
+			method:         http.MethodGet,
+			path:           "/favorites",
+			mockStoreSetup: func(ms *MockFavoriteStore) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"status":"error","code":"auth_required","error":"Authentication required."}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockStore := &MockFavoriteStore{}
+			tc.mockStoreSetup(mockStore)
+
+			router := mux.NewRouter()
+			router.Use(auth.New(logger, keys))
+			router.HandleFunc("/quotes/{id}/favorite", favoritehandler.NewAddFavoriteHandler(logger, mockStore)).Methods(http.MethodPut)
+			router.HandleFunc("/quotes/{id}/favorite", favoritehandler.NewRemoveFavoriteHandler(logger, mockStore)).Methods(http.MethodDelete)
+			router.HandleFunc("/favorites", favoritehandler.NewGetFavoritesHandler(logger, mockStore, pagination.Sizes{Default: 20, Max: 100})).Methods(http.MethodGet)
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.apiKey != "" {
+				req.Header.Set(auth.APIKeyHeader, tc.apiKey)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tc.expectedBody) {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
+			}
+			if tc.expectedLink != "" && rr.Header().Get("Link") != tc.expectedLink {
+				t.Errorf("expected Link %q, got %q", tc.expectedLink, rr.Header().Get("Link"))
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package healthhandler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage/selfcheck"
+)
+
+type HealthStore interface {
+	Version(ctx context.Context) (uint64, error)
+}
+
+// CertStatus reports the automatically managed TLS certificates.
+type CertStatus interface {
+	Certificates() []models.CertificateStatus
+}
+
+// NewLivezHandler serves GET /healthz. It only shows the process is up.
+func NewLivezHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response.JSON(w, r, http.StatusOK, models.GenericMessageResponse{
+			Status:  "success",
+			Message: "ok",
+		})
+	}
+}
+
+// NewReadyzHandler serves GET /readyz. The service is ready when the
+// startup self-check passed, or was skipped, and the store answers a cheap
+// query, and every managed TLS certificate is valid. check is nil when no
+// self-check ran, certs when TLS certificates are not managed.
+func NewReadyzHandler(logger *slog.Logger, hs HealthStore, check *selfcheck.Result, certs CertStatus) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.health.Readyz"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		readiness := models.Readiness{Ready: true}
+		var reasons []string
+		if check != nil {
+			readiness.SelfCheck = &models.SelfCheckResult{
+				Mode:      string(check.Mode),
+				Passed:    check.Err == nil,
+				Duration:  check.Duration.String(),
+				CheckedAt: check.CheckedAt.UTC(),
+			}
+			if check.Err != nil {
+				readiness.Ready = false
+				readiness.SelfCheck.Error = check.Err.Error()
+				reasons = append(reasons, "self-check failed: "+check.Err.Error())
+			}
+		}
+
+		if certs != nil {
+			readiness.Certificates = certs.Certificates()
+			for _, cert := range readiness.Certificates {
+				if cert.Ready {
+					continue
+				}
+				readiness.Ready = false
+				reason := "no tls certificate for " + cert.Domain
+				if cert.Error != "" {
+					reason += ": " + cert.Error
+				}
+				reasons = append(reasons, reason)
+			}
+		}
+
+		if readiness.Ready {
+			if _, err := hs.Version(ctx); err != nil {
+				log.WarnContext(ctx, "storage not ready", slog.String("error", err.Error()))
+				readiness.Ready = false
+				reasons = append(reasons, "storage is not responding")
+			}
+		}
+
+		if !readiness.Ready {
+			return apierror.New(http.StatusServiceUnavailable, apierror.CodeNotReady, reasons)
+		}
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   readiness,
+		})
+		return nil
+	})
+}
@@ -0,0 +1,116 @@
+package healthhandler_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"quotes-service/internal/http-server/handlers/healthhandler"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage/selfcheck"
+)
+
+type MockHealthStore struct {
+	VersionFunc func(ctx context.Context) (uint64, error)
+}
+
+func (m *MockHealthStore) Version(ctx context.Context) (uint64, error) {
+	if m.VersionFunc != nil {
+		return m.VersionFunc(ctx)
+	}
+	return 0, errors.New("VersionFunc not implemented")
+}
+
+type MockCertStatus struct {
+	CertificatesFunc func() []models.CertificateStatus
+}
+
+func (m *MockCertStatus) Certificates() []models.CertificateStatus {
+	return m.CertificatesFunc()
+}
+
+func TestReadyzHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	checkedAt := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	healthy := func(ctx context.Context) (uint64, error) { return 1, nil }
+
+	tests := []struct {
+		name           string
+		check          *selfcheck.Result
+		certs          healthhandler.CertStatus
+		versionFunc    func(ctx context.Context) (uint64, error)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "no self-check",
+			versionFunc:    healthy,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"ready":true}}`,
+		},
+		{
+			name:           "self-check passed",
+			check:          &selfcheck.Result{Mode: selfcheck.ModeWrite, Duration: 1500 * time.Microsecond, CheckedAt: checkedAt},
+			versionFunc:    healthy,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"ready":true,"self_check":{"mode":"write","passed":true,"duration":"1.5ms","checked_at":"2024-03-10T12:00:00Z"}}}`,
+		},
+		{
+			name:           "self-check failed",
+			check:          &selfcheck.Result{Mode: selfcheck.ModeWrite, CheckedAt: checkedAt, Err: errors.New("add sentinel quote: read-only file")},
+			versionFunc:    healthy,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"status":"error","code":"not_ready","error":"Service is not ready.","fields":["self-check failed: add sentinel quote: read-only file"]}`,
+		},
+		{
+			name:        "certificate ready",
+			versionFunc: healthy,
+			certs: &MockCertStatus{CertificatesFunc: func() []models.CertificateStatus {
+				return []models.CertificateStatus{{Domain: "quotes.example.com", Ready: true, NotAfter: &checkedAt}}
+			}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"ready":true,"certificates":[{"domain":"quotes.example.com","ready":true,"not_after":"2024-03-10T12:00:00Z"}]}}`,
+		},
+		{
+			name:        "certificate missing",
+			versionFunc: healthy,
+			certs: &MockCertStatus{CertificatesFunc: func() []models.CertificateStatus {
+				return []models.CertificateStatus{
+					{Domain: "quotes.example.com", Ready: true, NotAfter: &checkedAt},
+					{Domain: "www.example.com", Error: "acme: rate limited"},
+				}
+			}},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"status":"error","code":"not_ready","error":"Service is not ready.","fields":["no tls certificate for www.example.com: acme: rate limited"]}`,
+		},
+		{
+			name:           "storage down",
+			versionFunc:    func(ctx context.Context) (uint64, error) { return 0, errors.New("closed") },
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"status":"error","code":"not_ready","error":"Service is not ready.","fields":["storage is not responding"]}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &MockHealthStore{VersionFunc: tc.versionFunc}
+			handler := healthhandler.NewReadyzHandler(logger, store, tc.check, tc.certs)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tc.expectedBody) {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
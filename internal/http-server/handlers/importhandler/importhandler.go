@@ -0,0 +1,195 @@
+package importhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/headers"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/jobs/importer"
+	"quotes-service/internal/lib/quoteinput"
+	"quotes-service/internal/models"
+)
+
+func init() {
+	headers.Expose("Location")
+}
+
+// ImportManager runs imports in the background. *importer.Manager is the
+// real one.
+type ImportManager interface {
+	Submit(req models.ImportRequest, body io.Reader) (models.ImportJob, error)
+	Get(id string) (models.ImportJob, error)
+	Cancel(id string) (models.ImportJob, error)
+}
+
+// NewCreateImportHandler serves POST /imports. A JSON body names a URL to
+// fetch the JSON Lines from; any other body is the JSON Lines themselves,
+// with ?format= and ?transactional= in the query. It answers 202 Accepted
+// with the job, which GET /imports/{id} then reports on.
+func NewCreateImportHandler(logger *slog.Logger, im ImportManager) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.import.CreateImport"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+		defer r.Body.Close()
+
+		var (
+			req  models.ImportRequest
+			body io.Reader
+		)
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				log.ErrorContext(ctx, "failed to decode request body", slog.String("error", err.Error()))
+				return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyInvalid, nil)
+			}
+			if !quoteinput.ValidSourceURL(req.URL) {
+				log.WarnContext(ctx, "invalid import url", slog.String("url", req.URL))
+				return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, "url")
+			}
+		} else {
+			query := r.URL.Query()
+			req.Format = query.Get("format")
+			if raw := query.Get("transactional"); raw != "" {
+				var err error
+				if req.Transactional, err = strconv.ParseBool(raw); err != nil {
+					log.WarnContext(ctx, "invalid transactional parameter", slog.String("transactional", raw))
+					return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, "transactional")
+				}
+			}
+			body = r.Body
+		}
+
+		return submit(w, r, log, im, req, body)
+	})
+}
+
+// NewCreateImportFromURLHandler serves POST /imports/from-url, whose JSON
+// body names the URL to fetch the JSON Lines from, which must be one the
+// imports' fetch options allow. It answers like POST /imports.
+func NewCreateImportFromURLHandler(logger *slog.Logger, im ImportManager) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.import.CreateImportFromURL"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+		defer r.Body.Close()
+
+		var req models.ImportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.ErrorContext(ctx, "failed to decode request body", slog.String("error", err.Error()))
+			return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyInvalid, nil)
+		}
+		if !quoteinput.ValidSourceURL(req.URL) {
+			log.WarnContext(ctx, "invalid import url", slog.String("url", req.URL))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, "url")
+		}
+		return submit(w, r, log, im, req, nil)
+	})
+}
+
+// submit queues the import and answers 202 Accepted with its job.
+func submit(w http.ResponseWriter, r *http.Request, log *slog.Logger, im ImportManager, req models.ImportRequest, body io.Reader) error {
+	ctx := r.Context()
+	job, err := im.Submit(req, body)
+	if err != nil {
+		switch {
+		case errors.Is(err, importer.ErrUnknownFormat):
+			log.WarnContext(ctx, "invalid import format", slog.String("format", req.Format))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, "format")
+		case errors.Is(err, importer.ErrURLNotAllowed):
+			log.WarnContext(ctx, "import url is not allowed", slog.String("url", req.URL))
+			return apierror.New(http.StatusForbidden, apierror.CodeImportURLNotAllowed, nil)
+		case errors.Is(err, importer.ErrTooLarge):
+			log.WarnContext(ctx, "import is too large")
+			return apierror.New(http.StatusRequestEntityTooLarge, apierror.CodeImportTooLarge, nil)
+		case errors.Is(err, importer.ErrQueueFull):
+			log.WarnContext(ctx, "import queue is full")
+			return apierror.New(http.StatusServiceUnavailable, apierror.CodeImportQueueFull, nil)
+		default:
+			log.ErrorContext(ctx, "failed to queue import", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeImportFailed, nil)
+		}
+	}
+
+	log.InfoContext(ctx, "import queued", slog.String("id", job.ID), slog.String("format", job.Format), slog.Bool("transactional", job.Transactional))
+	w.Header().Set("Location", "/imports/"+job.ID)
+	response.JSON(w, r, http.StatusAccepted, models.SuccessDataResponse{
+		Status: "success",
+		Data:   job,
+	})
+	return nil
+}
+
+// NewGetImportHandler serves GET /imports/{id}, the status, progress and
+// report of an import job.
+func NewGetImportHandler(logger *slog.Logger, im ImportManager) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.import.GetImport"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		id := mux.Vars(r)["id"]
+		job, err := im.Get(id)
+		if err != nil {
+			if errors.Is(err, importer.ErrNotFound) {
+				log.InfoContext(ctx, "import not found", slog.String("id", id))
+				return apierror.New(http.StatusNotFound, apierror.CodeImportNotFound, nil)
+			}
+			log.ErrorContext(ctx, "failed to get import", slog.String("id", id), slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeImportFailed, nil)
+		}
+
+		log.InfoContext(ctx, "retrieved import", slog.String("id", id), slog.String("status", job.Status))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   job,
+		})
+		return nil
+	})
+}
+
+// NewCancelImportHandler serves DELETE /imports/{id}. A queued job is
+// canceled at once and answers 200 OK; a running one answers 202 Accepted
+// and stops at its next batch. Jobs that have already finished answer 409
+// Conflict.
+func NewCancelImportHandler(logger *slog.Logger, im ImportManager) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.import.CancelImport"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		id := mux.Vars(r)["id"]
+		job, err := im.Cancel(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, importer.ErrNotFound):
+				log.InfoContext(ctx, "import not found", slog.String("id", id))
+				return apierror.New(http.StatusNotFound, apierror.CodeImportNotFound, nil)
+			case errors.Is(err, importer.ErrFinished):
+				log.InfoContext(ctx, "import already finished", slog.String("id", id), slog.String("status", job.Status))
+				return apierror.New(http.StatusConflict, apierror.CodeImportFinished, nil)
+			default:
+				log.ErrorContext(ctx, "failed to cancel import", slog.String("id", id), slog.String("error", err.Error()))
+				return apierror.New(http.StatusInternalServerError, apierror.CodeImportFailed, nil)
+			}
+		}
+
+		status := http.StatusAccepted
+		if job.Status == models.JobCanceled {
+			status = http.StatusOK
+		}
+		log.InfoContext(ctx, "import canceled", slog.String("id", id), slog.String("status", job.Status))
+		response.JSON(w, r, status, models.SuccessDataResponse{
+			Status: "success",
+			Data:   job,
+		})
+		return nil
+	})
+}
@@ -0,0 +1,379 @@
+package importhandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/handlers/importhandler"
+	"quotes-service/internal/jobs/importer"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+type MockImportManager struct {
+	SubmitFunc func(req models.ImportRequest, body io.Reader) (models.ImportJob, error)
+	GetFunc    func(id string) (models.ImportJob, error)
+	CancelFunc func(id string) (models.ImportJob, error)
+}
+
+func (m *MockImportManager) Submit(req models.ImportRequest, body io.Reader) (models.ImportJob, error) {
+	return m.SubmitFunc(req, body)
+}
+
+func (m *MockImportManager) Get(id string) (models.ImportJob, error) {
+	return m.GetFunc(id)
+}
+
+func (m *MockImportManager) Cancel(id string) (models.ImportJob, error) {
+	return m.CancelFunc(id)
+}
+
+func newRouter(im importhandler.ImportManager) *mux.Router {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := mux.NewRouter()
+	router.HandleFunc("/imports", importhandler.NewCreateImportHandler(logger, im)).Methods(http.MethodPost)
+	router.HandleFunc("/imports/from-url", importhandler.NewCreateImportFromURLHandler(logger, im)).Methods(http.MethodPost)
+	router.HandleFunc("/imports/{id}", importhandler.NewGetImportHandler(logger, im)).Methods(http.MethodGet)
+	router.HandleFunc("/imports/{id}", importhandler.NewCancelImportHandler(logger, im)).Methods(http.MethodDelete)
+	return router
+}
+
+func decodeJob(t *testing.T, body []byte) models.ImportJob {
+	t.Helper()
+	var resp struct {
+		Data models.ImportJob `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("failed to decode job: %v", err)
+	}
+	return resp.Data
+}
+
+func TestImportLifecycle(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	manager := importer.New(logger, store, importer.Options{Dir: t.TempDir(), BatchSize: 100})
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		manager.Run(runCtx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	router := newRouter(manager)
+
+	var body strings.Builder
+	for i := range 5000 {
+		fmt.Fprintf(&body, `{"content":"Quote number %d","author":"Author %d","tags":["bulk"]}`+"\n", i, i%50)
+	}
+	body.WriteString(`{"content":"","author":"Nobody"}` + "\n")
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/imports?format=quotable&transactional=true", strings.NewReader(body.String()))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+	job := decodeJob(t, rr.Body.Bytes())
+	if job.Format != importer.FormatQuotable || !job.Transactional {
+		t.Fatalf("unexpected queued job %+v", job)
+	}
+	if got := rr.Header().Get("Location"); got != "/imports/"+job.ID {
+		t.Fatalf("unexpected Location %q", got)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for job.Status != models.JobDone {
+		if time.Now().After(deadline) {
+			t.Fatalf("import did not finish: %+v", job)
+		}
+		time.Sleep(5 * time.Millisecond)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/imports/"+job.ID, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d. Body: %s", rr.Code, rr.Body.String())
+		}
+		job = decodeJob(t, rr.Body.Bytes())
+		if job.Processed > job.Total {
+			t.Fatalf("processed %d of %d quotes", job.Processed, job.Total)
+		}
+	}
+	if job.Lines != 5001 || job.Total != 5000 || job.Processed != 5000 || job.Imported != 5000 || !job.Atomic {
+		t.Fatalf("unexpected finished job %+v", job)
+	}
+	if job.Skipped != 1 || len(job.Errors) != 1 || job.Errors[0].Line != 5001 {
+		t.Fatalf("unexpected errors %+v", job.Errors)
+	}
+	quotes, err := store.GetAllQuotes(ctx, storage.QuoteFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quotes) != 5000 {
+		t.Fatalf("expected 5000 quotes stored, got %d", len(quotes))
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/imports/"+job.ID, nil))
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a finished import, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestImportFromURL(t *testing.T) {
+	fixture := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest" {
+			http.Redirect(w, r, "/quotes.jsonl", http.StatusMovedPermanently)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for i := range 10 {
+			fmt.Fprintf(w, `{"text":"Fetched quote %d","author":"Author"}`+"\n", i)
+		}
+	}))
+	defer fixture.Close()
+
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	manager := importer.New(logger, store, importer.Options{
+		Dir:   t.TempDir(),
+		Fetch: importer.FetchOptions{Schemes: []string{"http"}, Hosts: []string{"127.0.0.1"}, MaxRedirects: 1},
+	})
+	runCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		manager.Run(runCtx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	router := newRouter(manager)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/imports/from-url", strings.NewReader(`{"url":"`+fixture.URL+`/latest"}`)))
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+	job := decodeJob(t, rr.Body.Bytes())
+	if job.URL != fixture.URL+"/latest" || job.Format != importer.FormatNative {
+		t.Fatalf("unexpected queued job %+v", job)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for job.Status == models.JobQueued || job.Status == models.JobRunning {
+		if time.Now().After(deadline) {
+			t.Fatalf("import did not finish: %+v", job)
+		}
+		time.Sleep(5 * time.Millisecond)
+		if job, err = manager.Get(job.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if job.Status != models.JobDone || job.Imported != 10 {
+		t.Fatalf("unexpected finished job %+v", job)
+	}
+}
+
+func TestCancelImport(t *testing.T) {
+	manager := &MockImportManager{
+		CancelFunc: func(id string) (models.ImportJob, error) {
+			status := models.JobCanceled
+			if id == "running" {
+				status = models.JobRunning
+			}
+			return models.ImportJob{ID: id, Status: status}, nil
+		},
+	}
+	router := newRouter(manager)
+
+	for id, expected := range map[string]int{"queued": http.StatusOK, "running": http.StatusAccepted} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/imports/"+id, nil))
+		if rr.Code != expected {
+			t.Fatalf("expected %d canceling a %s import, got %d. Body: %s", expected, id, rr.Code, rr.Body.String())
+		}
+		if job := decodeJob(t, rr.Body.Bytes()); job.ID != id {
+			t.Fatalf("unexpected job %+v", job)
+		}
+	}
+}
+
+func TestImportHandlerErrors(t *testing.T) {
+	manager := &MockImportManager{
+		SubmitFunc: func(req models.ImportRequest, body io.Reader) (models.ImportJob, error) {
+			switch req.Format {
+			case "full":
+				return models.ImportJob{}, importer.ErrQueueFull
+			case "large":
+				return models.ImportJob{}, importer.ErrTooLarge
+			case "broken":
+				return models.ImportJob{}, errors.New("boom")
+			case "elsewhere":
+				return models.ImportJob{}, importer.ErrURLNotAllowed
+			}
+			return models.ImportJob{}, importer.ErrUnknownFormat
+		},
+		GetFunc: func(id string) (models.ImportJob, error) {
+			return models.ImportJob{}, importer.ErrNotFound
+		},
+		CancelFunc: func(id string) (models.ImportJob, error) {
+			if id == "done" {
+				return models.ImportJob{ID: id, Status: models.JobDone}, importer.ErrFinished
+			}
+			return models.ImportJob{}, importer.ErrNotFound
+		},
+	}
+	router := newRouter(manager)
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		contentType    string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "invalid json body",
+			method:         http.MethodPost,
+			url:            "/imports",
+			contentType:    "application/json",
+			body:           `{"url":`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"request_body_invalid","error":"Failed to decode request body."}`,
+		},
+		{
+			name:           "invalid url",
+			method:         http.MethodPost,
+			url:            "/imports",
+			contentType:    "application/json; charset=utf-8",
+			body:           `{"url":"ftp://example.com/quotes.jsonl"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_parameter","error":"Invalid url parameter."}`,
+		},
+		{
+			name:           "from url with invalid json body",
+			method:         http.MethodPost,
+			url:            "/imports/from-url",
+			body:           `{"url":`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"request_body_invalid","error":"Failed to decode request body."}`,
+		},
+		{
+			name:           "from url without url",
+			method:         http.MethodPost,
+			url:            "/imports/from-url",
+			body:           `{"format":"native"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_parameter","error":"Invalid url parameter."}`,
+		},
+		{
+			name:           "from url not allowed",
+			method:         http.MethodPost,
+			url:            "/imports/from-url",
+			body:           `{"url":"https://internal.example.com/quotes.jsonl","format":"elsewhere"}`,
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"status":"error","code":"import_url_not_allowed","error":"Imports are not allowed from this URL."}`,
+		},
+		{
+			name:           "invalid transactional",
+			method:         http.MethodPost,
+			url:            "/imports?transactional=maybe",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_parameter","error":"Invalid transactional parameter."}`,
+		},
+		{
+			name:           "unknown format",
+			method:         http.MethodPost,
+			url:            "/imports?format=csv",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_parameter","error":"Invalid format parameter."}`,
+		},
+		{
+			name:           "too large",
+			method:         http.MethodPost,
+			url:            "/imports?format=large",
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedBody:   `{"status":"error","code":"import_too_large","error":"Import is too large."}`,
+		},
+		{
+			name:           "queue full",
+			method:         http.MethodPost,
+			url:            "/imports",
+			contentType:    "application/json",
+			body:           `{"url":"https://example.com/quotes.jsonl","format":"full"}`,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"status":"error","code":"import_queue_full","error":"Too many imports are waiting; try again later."}`,
+		},
+		{
+			name:           "submit fails",
+			method:         http.MethodPost,
+			url:            "/imports?format=broken",
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"status":"error","code":"import_failed","error":"Failed to import quotes."}`,
+		},
+		{
+			name:           "status of unknown job",
+			method:         http.MethodGet,
+			url:            "/imports/missing",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"status":"error","code":"import_not_found","error":"Import not found."}`,
+		},
+		{
+			name:           "cancel unknown job",
+			method:         http.MethodDelete,
+			url:            "/imports/missing",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"status":"error","code":"import_not_found","error":"Import not found."}`,
+		},
+		{
+			name:           "cancel finished job",
+			method:         http.MethodDelete,
+			url:            "/imports/done",
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"status":"error","code":"import_finished","error":"Import has already finished."}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if got := rr.Body.String(); got != tc.expectedBody+"\n" {
+				t.Fatalf("expected body %s, got %s", tc.expectedBody, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// Package mehandler tells authenticated clients who they are to the
+// service and how much of their rate limit is left.
+package mehandler
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/middleware/auth"
+	mwRateLimit "quotes-service/internal/http-server/middleware/ratelimit"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/models"
+)
+
+// Quotas reads the rate-limit buckets of a request without taking from
+// them. mwRateLimit.Options is the real one.
+type Quotas interface {
+	Quotas(r *http.Request) []mwRateLimit.Quota
+}
+
+// NewGetMeHandler serves GET /me: the caller's principal, role and how it
+// authenticated, with the state of each rate-limit bucket its requests
+// count against, this one included. Nil quotas means no rate limits.
+// Anonymous callers get 401 auth_required.
+func NewGetMeHandler(logger *slog.Logger, roles auth.Roles, quotas Quotas) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.me.GetMe"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		principal, ok := auth.Principal(ctx)
+		if !ok {
+			log.InfoContext(ctx, "anonymous request")
+			return apierror.New(http.StatusUnauthorized, apierror.CodeAuthRequired, nil)
+		}
+		method, _ := auth.AuthMethod(ctx)
+
+		me := models.Me{
+			Principal:  principal,
+			Role:       roles.Of(r).String(),
+			AuthMethod: string(method),
+			RateLimits: []models.QuotaUsage{},
+		}
+		if quotas != nil {
+			now := time.Now().UTC()
+			for _, q := range quotas.Quotas(r) {
+				me.RateLimits = append(me.RateLimits, models.QuotaUsage{
+					Scope:             q.Scope,
+					RequestsPerSecond: q.Limit.Rate,
+					Burst:             q.Limit.Burst,
+					Remaining:         q.Remaining,
+					ResetAt:           now.Add(q.Reset),
+				})
+			}
+		}
+
+		log.InfoContext(ctx, "retrieved principal", slog.String("principal", principal))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   me,
+		})
+		return nil
+	})
+}
@@ -0,0 +1,106 @@
+package mehandler_test
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"quotes-service/internal/http-server/handlers/mehandler"
+	"quotes-service/internal/http-server/middleware/auth"
+	mwRateLimit "quotes-service/internal/http-server/middleware/ratelimit"
+	"quotes-service/internal/lib/ratelimit"
+	"quotes-service/internal/lib/role"
+	"quotes-service/internal/models"
+)
+
+type stubQuotas []mwRateLimit.Quota
+
+func (s stubQuotas) Quotas(r *http.Request) []mwRateLimit.Quota { return s }
+
+func TestGetMe(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	keys := map[string]string{"d-key": "dashboard", "w-key": "app"}
+	roles := auth.Roles{Principals: map[string]role.Role{"dashboard": role.Reader}}
+	quotas := stubQuotas{{
+		Scope:  mwRateLimit.ScopePrincipal,
+		Limit:  ratelimit.Limit{Rate: 2, Burst: 10},
+		Result: ratelimit.Result{Allowed: true, Limit: 10, Remaining: 7, Reset: 1500 * time.Millisecond},
+	}}
+
+	tests := []struct {
+		name           string
+		apiKey         string
+		quotas         mehandler.Quotas
+		expectedStatus int
+		expected       models.Me
+	}{
+		{name: "anonymous", expectedStatus: http.StatusUnauthorized},
+		{
+			name:           "reader",
+			apiKey:         "d-key",
+			quotas:         quotas,
+			expectedStatus: http.StatusOK,
+			expected: models.Me{
+				Principal:  "dashboard",
+				Role:       "reader",
+				AuthMethod: "api_key",
+				RateLimits: []models.QuotaUsage{{Scope: "principal", RequestsPerSecond: 2, Burst: 10, Remaining: 7}},
+			},
+		},
+		{
+			name:           "no rate limits",
+			apiKey:         "w-key",
+			expectedStatus: http.StatusOK,
+			expected:       models.Me{Principal: "app", Role: "writer", AuthMethod: "api_key", RateLimits: []models.QuotaUsage{}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := auth.New(logger, keys)(mehandler.NewGetMeHandler(logger, roles, tc.quotas))
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			if tc.apiKey != "" {
+				req.Header.Set(auth.APIKeyHeader, tc.apiKey)
+			}
+			rr := httptest.NewRecorder()
+			start := time.Now()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Data models.Me `json:"data"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			got := resp.Data
+			if got.Principal != tc.expected.Principal || got.Role != tc.expected.Role || got.AuthMethod != tc.expected.AuthMethod {
+				t.Fatalf("expected %+v, got %+v", tc.expected, got)
+			}
+			if got.RateLimits == nil || len(got.RateLimits) != len(tc.expected.RateLimits) {
+				t.Fatalf("expected rate limits %+v, got %+v", tc.expected.RateLimits, got.RateLimits)
+			}
+			for i, q := range got.RateLimits {
+				want := tc.expected.RateLimits[i]
+				resetAt := q.ResetAt
+				q.ResetAt = time.Time{}
+				if q != want {
+					t.Fatalf("expected rate limit %+v, got %+v", want, q)
+				}
+				if wait := resetAt.Sub(start); wait < 1500*time.Millisecond || wait > 3*time.Second {
+					t.Fatalf("expected the bucket full again in 1.5s, got %v", wait)
+				}
+			}
+		})
+	}
+}
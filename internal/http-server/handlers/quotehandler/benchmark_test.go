@@ -0,0 +1,214 @@
+package quotehandler_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/handlers/quotehandler"
+	"quotes-service/internal/http-server/pagination"
+	"quotes-service/internal/lib/clienthistory"
+	"quotes-service/internal/lib/jsoncache"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+const (
+	benchQuotes  = 10_000
+	benchAuthors = 200
+)
+
+// newBenchStore returns a memory store holding benchQuotes quotes of a
+// realistic length.
+func newBenchStore(b *testing.B) *memorystorage.Storage {
+	b.Helper()
+	store, err := memorystorage.New()
+	if err != nil {
+		b.Fatalf("failed to init storage: %v", err)
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < benchQuotes; i++ {
+		if _, err := store.AddQuote(context.Background(), benchQuote(rnd, i)); err != nil {
+			b.Fatalf("failed to add quote: %v", err)
+		}
+	}
+	return store
+}
+
+// newBenchRouter serves the quote routes on top of newBenchStore. Lists
+// are served whole, in one page.
+func newBenchRouter(b *testing.B, cache *jsoncache.Cache, history *clienthistory.History) http.Handler {
+	b.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := newBenchStore(b)
+
+	sizes := pagination.Sizes{Default: benchQuotes, Max: benchQuotes}
+	router := mux.NewRouter()
+	router.HandleFunc("/quotes", quotehandler.NewAddQuoteHandler(logger, store)).Methods(http.MethodPost)
+	router.HandleFunc("/quotes", quotehandler.NewGetQuotesByAuthorHandler(logger, store, sizes)).Methods(http.MethodGet).Queries("author", "{author}")
+	router.HandleFunc("/quotes", quotehandler.NewGetAllQuotesHandler(logger, store, cache, sizes)).Methods(http.MethodGet)
+	router.HandleFunc("/quotes/random", quotehandler.NewGetRandomQuoteHandler(logger, store, history, nil, nil, nil)).Methods(http.MethodGet)
+	router.HandleFunc("/quotes/{id:[0-9]+}", quotehandler.NewGetQuoteHandler(logger, store)).Methods(http.MethodGet)
+	router.HandleFunc("/quotes/{id:[0-9]+}", quotehandler.NewPatchQuoteHandler(logger, store)).Methods(http.MethodPatch)
+	return router
+}
+
+func benchQuote(rnd *rand.Rand, i int) models.Quote {
+	words := make([]string, 12+rnd.Intn(12))
+	for j := range words {
+		words[j] = fmt.Sprintf("word%d", rnd.Intn(5000))
+	}
+	return models.Quote{
+		Text:   strings.Join(words, " "),
+		Author: fmt.Sprintf("Author %d", i%benchAuthors),
+		Lang:   "en",
+	}
+}
+
+func serveBench(b *testing.B, handler http.Handler, newRequest func(i int) *http.Request) {
+	b.Helper()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, newRequest(i))
+		if rr.Code >= 400 {
+			b.Fatalf("unexpected status %d: %s", rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func BenchmarkGetRandomQuoteHandler(b *testing.B) {
+	b.Run("anonymous", func(b *testing.B) {
+		handler := newBenchRouter(b, nil, nil)
+		serveBench(b, handler, func(i int) *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/quotes/random", nil)
+		})
+	})
+	b.Run("no-repeat", func(b *testing.B) {
+		handler := newBenchRouter(b, nil, clienthistory.New(20, time.Minute, 1000))
+		serveBench(b, handler, func(i int) *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/quotes/random", nil)
+			req.Header.Set(quotehandler.ClientIDHeader, fmt.Sprintf("client-%d", i%100))
+			return req
+		})
+	})
+}
+
+// lockCountingStore counts the calls that take the store's lock on the
+// random quote path.
+type lockCountingStore struct {
+	*memorystorage.Storage
+	calls atomic.Int64
+}
+
+func (s *lockCountingStore) GetRandomQuote(ctx context.Context, opts storage.RandomOptions) (models.Quote, error) {
+	s.calls.Add(1)
+	return s.Storage.GetRandomQuote(ctx, opts)
+}
+
+func (s *lockCountingStore) IncrementServed(ctx context.Context, id int64) error {
+	s.calls.Add(1)
+	return s.Storage.IncrementServed(ctx, id)
+}
+
+func (s *lockCountingStore) AddServed(ctx context.Context, id int64, n int64) error {
+	s.calls.Add(1)
+	return s.Storage.AddServed(ctx, id, n)
+}
+
+// BenchmarkGetRandomQuoteHandlerParallel serves a burst of identical random
+// quote requests from every CPU, with and without coalescing, and reports
+// the store calls, each of which takes the store's lock, per request.
+func BenchmarkGetRandomQuoteHandlerParallel(b *testing.B) {
+	for _, bc := range []struct {
+		name      string
+		coalescer *quotehandler.RandomCoalescer
+	}{
+		{name: "direct"},
+		{name: "coalesced-100ms", coalescer: quotehandler.NewRandomCoalescer(100*time.Millisecond, 0)},
+		{name: "coalesced-1000req", coalescer: quotehandler.NewRandomCoalescer(0, 1000)},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			store := &lockCountingStore{Storage: newBenchStore(b)}
+			handler := quotehandler.NewGetRandomQuoteHandler(logger, store, nil, bc.coalescer, nil, nil)
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					rr := httptest.NewRecorder()
+					handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/quotes/random", nil))
+					if rr.Code != http.StatusOK {
+						b.Errorf("unexpected status %d: %s", rr.Code, rr.Body.String())
+						return
+					}
+				}
+			})
+			b.ReportMetric(float64(store.calls.Load())/float64(b.N), "storecalls/op")
+		})
+	}
+}
+
+func BenchmarkGetQuotesByAuthorHandler(b *testing.B) {
+	handler := newBenchRouter(b, nil, nil)
+	serveBench(b, handler, func(i int) *http.Request {
+		return httptest.NewRequest(http.MethodGet, fmt.Sprintf("/quotes?author=Author+%d", i%benchAuthors), nil)
+	})
+}
+
+func BenchmarkGetQuoteHandler(b *testing.B) {
+	handler := newBenchRouter(b, nil, nil)
+	serveBench(b, handler, func(i int) *http.Request {
+		return httptest.NewRequest(http.MethodGet, fmt.Sprintf("/quotes/%d", i%benchQuotes+1), nil)
+	})
+}
+
+func BenchmarkAddQuoteHandler(b *testing.B) {
+	handler := newBenchRouter(b, nil, nil)
+	rnd := rand.New(rand.NewSource(2))
+	bodies := make([][]byte, 1000)
+	for i := range bodies {
+		q := benchQuote(rnd, i)
+		bodies[i] = []byte(fmt.Sprintf(`{"text":%q,"author":%q,"lang":"en"}`, q.Text, q.Author))
+	}
+	serveBench(b, handler, func(i int) *http.Request {
+		return httptest.NewRequest(http.MethodPost, "/quotes", bytes.NewReader(bodies[i%len(bodies)]))
+	})
+}
+
+func BenchmarkPatchQuoteHandler(b *testing.B) {
+	handler := newBenchRouter(b, nil, nil)
+	serveBench(b, handler, func(i int) *http.Request {
+		body := strings.NewReader(fmt.Sprintf(`{"weight":%d}`, i%10+1))
+		return httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/quotes/%d", i%benchQuotes+1), body)
+	})
+}
+
+func BenchmarkGetAllQuotesHandler(b *testing.B) {
+	for _, bc := range []struct {
+		name  string
+		cache *jsoncache.Cache
+	}{
+		{name: "uncached", cache: nil},
+		{name: "cached", cache: jsoncache.New(16 << 20)},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			handler := newBenchRouter(b, bc.cache, nil)
+			serveBench(b, handler, func(i int) *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/quotes", nil)
+			})
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package quotehandler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+const (
+	defaultChangesLimit = 500
+	maxChangesLimit     = 1000
+)
+
+// NewGetQuoteChangesHandler serves GET /quotes/changes?since=<seq>, the
+// changes to quotes made after the sequence number a client last synced
+// to, oldest first. Replaying them over that client's copy brings it up to
+// date. When the log no longer reaches back to since, the client gets 410
+// Gone and has to fetch every quote again; the seq of /quotes/changes
+// with since=0 taken before that fetch is where to sync from afterwards.
+func NewGetQuoteChangesHandler(logger *slog.Logger, cl storage.ChangeLog) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.quote.GetQuoteChanges"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		var since uint64
+		if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
+			parsed, err := strconv.ParseUint(sinceStr, 10, 64)
+			if err != nil {
+				log.WarnContext(ctx, "invalid since query parameter", slog.String("since", sinceStr))
+				return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, "since")
+			}
+			since = parsed
+		}
+		limit, err := parseLimit(r, defaultChangesLimit, maxChangesLimit)
+		if err != nil {
+			log.WarnContext(ctx, "invalid limit query parameter", slog.String("limit", r.URL.Query().Get("limit")))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidLimit, nil)
+		}
+
+		changes, latest, err := cl.ChangesSince(ctx, since, limit)
+		if err != nil {
+			log.Log(ctx, apierror.Level(err), "failed to get changes", slog.Uint64("since", since), slog.String("error", err.Error()))
+			return apierror.Failed(apierror.CodeGetChangesFailed, err)
+		}
+
+		page := models.QuoteChanges{
+			Changes: changes,
+			Seq:     latest,
+		}
+		if page.Changes == nil {
+			page.Changes = []models.QuoteChange{}
+		}
+		if len(changes) > 0 {
+			page.Seq = changes[len(changes)-1].Seq
+		}
+		page.More = page.Seq < latest
+
+		log.InfoContext(ctx, "retrieved changes", slog.Uint64("since", since), slog.Int("count", len(changes)), slog.Bool("more", page.More))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   page,
+		})
+		return nil
+	})
+}
@@ -0,0 +1,199 @@
+package quotehandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/handlers/quotehandler"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func newChangesRouter(store *memorystorage.Storage) *mux.Router {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := mux.NewRouter()
+	router.HandleFunc("/quotes/changes", quotehandler.NewGetQuoteChangesHandler(logger, store)).Methods(http.MethodGet)
+	return router
+}
+
+// syncClient keeps a local copy of the quotes up to date the way the
+// mobile app does: it replays pages of changes until there are no more.
+type syncClient struct {
+	t      *testing.T
+	router http.Handler
+	seq    uint64
+	quotes map[int64]models.Quote
+}
+
+func (c *syncClient) getChanges(limit int) (int, models.QuoteChanges) {
+	c.t.Helper()
+	rr := httptest.NewRecorder()
+	url := "/quotes/changes?since=" + strconv.FormatUint(c.seq, 10) + "&limit=" + strconv.Itoa(limit)
+	c.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, url, nil))
+	var resp struct {
+		Data models.QuoteChanges `json:"data"`
+	}
+	if rr.Code == http.StatusOK {
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			c.t.Fatalf("failed to decode changes: %v", err)
+		}
+	}
+	return rr.Code, resp.Data
+}
+
+// sync replays changes in pages of limit and returns how many pages it
+// took.
+func (c *syncClient) sync(limit int) int {
+	c.t.Helper()
+	for pages := 1; ; pages++ {
+		status, page := c.getChanges(limit)
+		if status != http.StatusOK {
+			c.t.Fatalf("expected 200, got %d", status)
+		}
+		for _, change := range page.Changes {
+			switch change.Op {
+			case models.ChangeAdd, models.ChangeUpdate:
+				c.quotes[change.QuoteID] = *change.Quote
+			case models.ChangeDelete:
+				delete(c.quotes, change.QuoteID)
+			default:
+				c.t.Fatalf("unexpected op %q", change.Op)
+			}
+		}
+		c.seq = page.Seq
+		if !page.More {
+			return pages
+		}
+	}
+}
+
+func (c *syncClient) assertInSync(store *memorystorage.Storage) {
+	c.t.Helper()
+	quotes, err := store.GetAllQuotes(context.Background(), storage.QuoteFilter{})
+	if err != nil {
+		c.t.Fatalf("failed to list quotes: %v", err)
+	}
+	want := make(map[int64]models.Quote, len(quotes))
+	for _, q := range quotes {
+		want[q.ID] = q
+	}
+	if !reflect.DeepEqual(c.quotes, want) {
+		c.t.Fatalf("local copy diverged:\n got %+v\nwant %+v", c.quotes, want)
+	}
+}
+
+func TestQuoteChangesReplay(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	client := &syncClient{t: t, router: newChangesRouter(store), quotes: map[int64]models.Quote{}}
+
+	for _, author := range []string{"A", "B", "A", "C"} {
+		if _, err := store.AddQuote(ctx, models.Quote{Text: "by " + author, Author: author}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+	if pages := client.sync(3); pages != 2 {
+		t.Fatalf("expected 4 changes to take 2 pages of 3, took %d", pages)
+	}
+	client.assertInSync(store)
+
+	text := "edited"
+	if _, err := store.UpdateQuote(ctx, 2, storage.QuoteUpdate{Text: &text}, storage.AnyVersion); err != nil {
+		t.Fatalf("failed to update quote: %v", err)
+	}
+	if err := store.DeleteQuote(ctx, 4, storage.AnyVersion); err != nil {
+		t.Fatalf("failed to delete quote: %v", err)
+	}
+	if _, err := store.MergeAuthors(ctx, "Z", []string{"A"}); err != nil {
+		t.Fatalf("failed to merge authors: %v", err)
+	}
+	if _, err := store.AddQuote(ctx, models.Quote{Text: "late", Author: "D"}); err != nil {
+		t.Fatalf("failed to add quote: %v", err)
+	}
+	client.sync(100)
+	client.assertInSync(store)
+
+	// Syncing again with nothing new is an empty page at the same seq.
+	seq := client.seq
+	status, page := client.getChanges(10)
+	if status != http.StatusOK || len(page.Changes) != 0 || page.Seq != seq || page.More {
+		t.Fatalf("expected an empty page at seq %d, got %d %+v", seq, status, page)
+	}
+}
+
+func TestQuoteChangesGone(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store, err := memorystorage.New(
+		memorystorage.WithChangeLog(2, 0),
+		memorystorage.WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for range 3 {
+		if _, err := store.AddQuote(ctx, models.Quote{Text: "text", Author: "A"}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+	router := newChangesRouter(store)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "before the horizon",
+			query:          "?since=0",
+			expectedStatus: http.StatusGone,
+			expectedBody:   `{"status":"error","code":"changes_expired","error":"Changes since this sequence number are no longer available; fetch all quotes again."}`,
+		},
+		{
+			name:           "ahead of the log",
+			query:          "?since=4",
+			expectedStatus: http.StatusGone,
+			expectedBody:   `{"status":"error","code":"changes_expired","error":"Changes since this sequence number are no longer available; fetch all quotes again."}`,
+		},
+		{
+			name:           "at the horizon",
+			query:          "?since=1&limit=1",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"changes":[{"seq":2,"op":"add","quote_id":2,"quote":{"id":2,"text":"text","author":"A","author_key":"a","weight":1,"lang":"und","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","version":1},"time":"2026-01-01T00:00:00Z"}],"seq":2,"more":true}}`,
+		},
+		{
+			name:           "invalid since",
+			query:          "?since=-1",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_parameter","error":"Invalid since parameter."}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/quotes/changes"+tc.query, nil))
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if got := rr.Body.String(); got != tc.expectedBody+"\n" {
+				t.Fatalf("expected body %s, got %s", tc.expectedBody, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package quotehandler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// RandomCoalescer shares one random pick between the plain random quote
+// requests that arrive close together, so that a burst of them costs one
+// store lookup per refresh instead of one per request. The pick is
+// replaced once it is interval old or has been served requests times,
+// whichever comes first; a zero value turns either limit off.
+//
+// Requests served a shared pick are not counted one by one: their number
+// is added to the quote's served total at the refresh after the pick is
+// replaced, once no request can still be serving it. Served counts lag
+// by up to two refreshes as a result.
+type RandomCoalescer struct {
+	interval time.Duration
+	requests int64
+	now      func() time.Time
+
+	current atomic.Pointer[sharedPick]
+
+	// mu serializes refreshes and guards prev.
+	mu   sync.Mutex
+	prev *sharedPick
+}
+
+type sharedPick struct {
+	quote models.Quote
+	// expires is zero without an interval.
+	expires time.Time
+	// left counts down the requests the pick may still serve.
+	left   atomic.Int64
+	served atomic.Int64
+}
+
+// NewRandomCoalescer returns a coalescer that refreshes its pick every
+// interval or every requests requests. It returns nil, which serves every
+// request from the store, if both are zero.
+func NewRandomCoalescer(interval time.Duration, requests int) *RandomCoalescer {
+	if interval <= 0 && requests <= 0 {
+		return nil
+	}
+	return &RandomCoalescer{
+		interval: interval,
+		requests: int64(requests),
+		now:      time.Now,
+	}
+}
+
+// get returns the shared pick, refreshing it from qs when it is used up.
+func (c *RandomCoalescer) get(ctx context.Context, log *slog.Logger, qs QuoteStore) (models.Quote, error) {
+	if quote, ok := c.serve(c.current.Load()); ok {
+		return quote, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Another request may have refreshed the pick while this one waited.
+	if quote, ok := c.serve(c.current.Load()); ok {
+		return quote, nil
+	}
+
+	quote, err := qs.GetRandomQuote(ctx, storage.RandomOptions{})
+	if err != nil {
+		return models.Quote{}, err
+	}
+	next := &sharedPick{quote: quote}
+	if c.interval > 0 {
+		next.expires = c.now().Add(c.interval)
+	}
+	next.left.Store(c.requests)
+	next.served.Store(1)
+
+	if c.prev != nil {
+		c.flush(ctx, log, qs, c.prev)
+	}
+	c.prev = c.current.Swap(next)
+	return quote, nil
+}
+
+// serve claims one use of p, if p is still good for one.
+func (c *RandomCoalescer) serve(p *sharedPick) (models.Quote, bool) {
+	if p == nil {
+		return models.Quote{}, false
+	}
+	if c.interval > 0 && !c.now().Before(p.expires) {
+		return models.Quote{}, false
+	}
+	if c.requests > 0 && p.left.Add(-1) <= 0 {
+		return models.Quote{}, false
+	}
+	p.served.Add(1)
+	return p.quote, true
+}
+
+// flush adds the serves of a retired pick to the quote's served count in
+// the background, in one call when qs is a storage.ServedAdder.
+func (c *RandomCoalescer) flush(ctx context.Context, log *slog.Logger, qs QuoteStore, p *sharedPick) {
+	n := p.served.Load()
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		var err error
+		if adder, ok := qs.(storage.ServedAdder); ok {
+			err = adder.AddServed(ctx, p.quote.ID, n)
+		} else {
+			for range n {
+				if err = qs.IncrementServed(ctx, p.quote.ID); err != nil {
+					break
+				}
+			}
+		}
+		// A quote deleted since it was picked has no count left.
+		if err != nil && !ErrorsIs(err, storage.ErrQuoteNotFound) {
+			log.WarnContext(ctx, "failed to increment served counter", slog.Int64("id", p.quote.ID), slog.Int64("count", n), slog.String("error", err.Error()))
+		}
+	}()
+}
@@ -0,0 +1,97 @@
+package quotehandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"quotes-service/internal/http-server/handlers/quotehandler"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func TestGetRandomQuoteHandlerCoalesced(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+
+	inner, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for _, author := range []string{"A", "B", "C", "D"} {
+		if _, err := inner.AddQuote(ctx, models.Quote{Text: "by " + author, Author: author}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+	store := &lockCountingStore{Storage: inner}
+	handler := quotehandler.NewGetRandomQuoteHandler(logger, store, nil, quotehandler.NewRandomCoalescer(0, 5), nil, nil)
+
+	get := func(query string) int64 {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/quotes/random"+query, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d. Body: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			Data models.Quote `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode quote: %v", err)
+		}
+		return resp.Data.ID
+	}
+
+	// Every run of 5 requests shares one pick.
+	const requests = 20
+	ids := make([]int64, requests)
+	for i := range ids {
+		ids[i] = get("")
+		if i%5 != 0 && ids[i] != ids[i-1] {
+			t.Fatalf("request %d: expected the shared pick %d, got %d", i, ids[i-1], ids[i])
+		}
+	}
+
+	// Two refreshes flushed the first two picks in one call each; the last
+	// two picks are not counted yet.
+	served := func() int64 {
+		popular, err := inner.GetPopularQuotes(ctx, 4)
+		if err != nil {
+			t.Fatalf("failed to get popular quotes: %v", err)
+		}
+		var total int64
+		for _, p := range popular {
+			total += p.Served
+		}
+		return total
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for served() < 10 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := served(); got != 10 {
+		t.Fatalf("expected 10 flushed serves, got %d", got)
+	}
+	if got := store.calls.Load(); got != requests/5+2 {
+		t.Fatalf("expected %d store calls, got %d", requests/5+2, got)
+	}
+
+	// Requests that ask for something else skip the shared pick: each one
+	// picks and counts its own serve.
+	before := store.calls.Load()
+	for range 3 {
+		get("?unweighted=true")
+	}
+	deadline = time.Now().Add(2 * time.Second)
+	for store.calls.Load() < before+6 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := store.calls.Load() - before; got != 6 {
+		t.Fatalf("expected 6 store calls for 3 unweighted requests, got %d", got)
+	}
+}
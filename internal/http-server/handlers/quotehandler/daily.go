@@ -0,0 +1,64 @@
+package quotehandler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+const (
+	defaultDailyHistoryLimit = 30
+	maxDailyHistoryLimit     = 366
+)
+
+// DailyHistory reports the quotes of the day. *publisher.Publisher is the
+// real one, in daily mode.
+type DailyHistory interface {
+	DailyHistory(n int) []models.DailyPick
+}
+
+// NewGetDailyHistoryHandler serves GET /quotes/daily/history?limit=30, the
+// latest quotes of the day first, each with its date and how many times it
+// had been featured by then.
+func NewGetDailyHistoryHandler(logger *slog.Logger, dh DailyHistory, qs QuoteStore) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.quote.GetDailyHistory"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		limit, err := parseLimit(r, defaultDailyHistoryLimit, maxDailyHistoryLimit)
+		if err != nil {
+			log.WarnContext(ctx, "invalid limit query parameter", slog.String("limit", r.URL.Query().Get("limit")))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidLimit, nil)
+		}
+
+		history := dh.DailyHistory(limit)
+		if len(history) > 0 {
+			// One read of the catalog, joined here, rather than a read
+			// per day.
+			quotes, err := qs.GetAllQuotes(ctx, storage.QuoteFilter{})
+			if err != nil {
+				log.ErrorContext(ctx, "failed to get quotes of the day", slog.String("error", err.Error()))
+				return apierror.Failed(apierror.CodeGetQuoteFailed, err)
+			}
+			byID := make(map[int64]*models.Quote, len(quotes))
+			for i := range quotes {
+				byID[quotes[i].ID] = &quotes[i]
+			}
+			for i := range history {
+				history[i].Quote = byID[history[i].QuoteID]
+			}
+		}
+
+		log.InfoContext(ctx, "retrieved daily history", slog.Int("count", len(history)))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   history,
+		})
+		return nil
+	})
+}
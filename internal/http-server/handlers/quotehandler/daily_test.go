@@ -0,0 +1,84 @@
+package quotehandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"quotes-service/internal/http-server/handlers/quotehandler"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+type MockDailyHistory struct {
+	picks []models.DailyPick
+}
+
+func (m *MockDailyHistory) DailyHistory(n int) []models.DailyPick {
+	return append([]models.DailyPick(nil), m.picks[:min(n, len(m.picks))]...)
+}
+
+func TestGetDailyHistoryHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	if _, err := store.AddQuote(context.Background(), models.Quote{Text: "Carpe diem.", Author: "Horace"}); err != nil {
+		t.Fatalf("failed to add quote: %v", err)
+	}
+	history := &MockDailyHistory{picks: []models.DailyPick{
+		{Date: "2024-03-12", QuoteID: 1, FeaturedCount: 2},
+		{Date: "2024-03-11", QuoteID: 9, FeaturedCount: 1},
+		{Date: "2024-03-10", QuoteID: 1, FeaturedCount: 1},
+	}}
+	handler := quotehandler.NewGetDailyHistoryHandler(logger, history, store)
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedDates  []string
+	}{
+		{name: "default limit", url: "/quotes/daily/history", expectedStatus: http.StatusOK, expectedDates: []string{"2024-03-12", "2024-03-11", "2024-03-10"}},
+		{name: "limit", url: "/quotes/daily/history?limit=2", expectedStatus: http.StatusOK, expectedDates: []string{"2024-03-12", "2024-03-11"}},
+		{name: "invalid limit", url: "/quotes/daily/history?limit=0", expectedStatus: http.StatusBadRequest},
+		{name: "limit over the maximum", url: "/quotes/daily/history?limit=1000", expectedStatus: http.StatusOK, expectedDates: []string{"2024-03-12", "2024-03-11", "2024-03-10"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.url, nil))
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Data []models.DailyPick `json:"data"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(resp.Data) != len(tc.expectedDates) {
+				t.Fatalf("expected %d picks, got %+v", len(tc.expectedDates), resp.Data)
+			}
+			for i, pick := range resp.Data {
+				if pick.Date != tc.expectedDates[i] {
+					t.Errorf("pick %d: expected %s, got %s", i, tc.expectedDates[i], pick.Date)
+				}
+				// Quote 9 has been deleted since it was featured.
+				if found := pick.Quote != nil; found != (pick.QuoteID == 1) {
+					t.Errorf("unexpected quote in pick %+v", pick)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package quotehandler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+)
+
+// maxPooledBodyBytes keeps the buffer of an unusually large body from
+// staying in the pool for good.
+const maxPooledBodyBytes = 64 << 10
+
+var bodyBuffers = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// decodeBody decodes a JSON request body into v. The body is read into a
+// pooled buffer and unmarshaled from there, which allocates far less than
+// a json.Decoder per request. Input that Unmarshal rejects as malformed is
+// decoded again with a json.Decoder, so an empty body still fails with
+// io.EOF and data after the first value is still ignored.
+func decodeBody(body io.Reader, v any) error {
+	buf := bodyBuffers.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledBodyBytes {
+			buf.Reset()
+			bodyBuffers.Put(buf)
+		}
+	}()
+
+	if _, err := buf.ReadFrom(body); err != nil {
+		return err
+	}
+	err := json.Unmarshal(buf.Bytes(), v)
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return json.NewDecoder(bytes.NewReader(buf.Bytes())).Decode(v)
+	}
+	return err
+}
@@ -0,0 +1,107 @@
+package quotehandler
+
+import (
+	"cmp"
+	"encoding/binary"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/cespare/xxhash/v2"
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/conditional"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// digestCache holds the digest of one storage version, so the quotes are
+// only hashed again after a mutation.
+type digestCache struct {
+	mu     sync.Mutex
+	valid  bool
+	digest models.QuoteDigest
+}
+
+func (c *digestCache) get(version uint64) (models.QuoteDigest, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.digest, c.valid && c.digest.Version == version
+}
+
+func (c *digestCache) put(digest models.QuoteDigest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.digest, c.valid = digest, true
+}
+
+// NewGetQuotesDigestHandler serves GET /quotes/digest, a tiny summary of
+// the catalog that changes whenever any quote is added, updated or
+// deleted. Clients compare it with the one they stored to decide whether
+// to fetch the list again. The hash doubles as an ETag.
+func NewGetQuotesDigestHandler(logger *slog.Logger, qs QuoteStore) http.HandlerFunc {
+	var cache digestCache
+
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.quote.GetQuotesDigest"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		// The version is read before the quotes, so a racing mutation at
+		// worst causes an extra miss, never a stale hit.
+		version, err := qs.Version(ctx)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to get storage version", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeGetQuotesFailed, nil)
+		}
+
+		digest, hit := cache.get(version)
+		if !hit {
+			quotes, err := qs.GetAllQuotes(ctx, storage.QuoteFilter{})
+			if err != nil {
+				log.ErrorContext(ctx, "failed to get all quotes", slog.String("error", err.Error()))
+				return apierror.New(http.StatusInternalServerError, apierror.CodeGetQuotesFailed, nil)
+			}
+			digest = models.QuoteDigest{
+				Version: version,
+				Hash:    contentHash(quotes),
+				Count:   len(quotes),
+			}
+			cache.put(digest)
+		}
+
+		etag := `"` + digest.Hash + `"`
+		w.Header().Set("ETag", etag)
+		if conditional.CheckNoneMatch(w, r, etag) {
+			log.InfoContext(ctx, "quotes digest not modified", slog.Bool("cache_hit", hit))
+			return nil
+		}
+
+		log.InfoContext(ctx, "retrieved quotes digest", slog.Bool("cache_hit", hit))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   digest,
+		})
+		return nil
+	})
+}
+
+// contentHash hashes the ID, version and update time of every quote in ID
+// order. Any write to a quote bumps its version and update time, so the
+// hash changes with the data and not with anything kept only in memory.
+func contentHash(quotes []models.Quote) string {
+	quotes = slices.SortedFunc(slices.Values(quotes), func(a, b models.Quote) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	h := xxhash.New()
+	var buf [24]byte
+	for _, q := range quotes {
+		binary.BigEndian.PutUint64(buf[0:], uint64(q.ID))
+		binary.BigEndian.PutUint64(buf[8:], uint64(q.Version))
+		binary.BigEndian.PutUint64(buf[16:], uint64(q.UpdatedAt.UnixNano()))
+		_, _ = h.Write(buf[:])
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
@@ -0,0 +1,154 @@
+package quotehandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/handlers/quotehandler"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func newDigestRouter(t *testing.T) (*mux.Router, *memorystorage.Storage) {
+	t.Helper()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store, err := memorystorage.New(memorystorage.WithClock(func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}))
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for _, text := range []string{"first", "second"} {
+		if _, err := store.AddQuote(context.Background(), models.Quote{Text: text, Author: "A"}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := mux.NewRouter()
+	router.HandleFunc("/quotes/digest", quotehandler.NewGetQuotesDigestHandler(logger, store)).Methods(http.MethodGet)
+	return router, store
+}
+
+func getDigest(t *testing.T, router http.Handler) models.QuoteDigest {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/quotes/digest", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Data models.QuoteDigest `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode digest: %v", err)
+	}
+	if got, want := rr.Header().Get("ETag"), `"`+resp.Data.Hash+`"`; got != want {
+		t.Fatalf("expected ETag %s, got %s", want, got)
+	}
+	return resp.Data
+}
+
+func TestGetQuotesDigestHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		act     func(ctx context.Context, store *memorystorage.Storage) error
+		changes bool
+	}{
+		{
+			name: "add",
+			act: func(ctx context.Context, store *memorystorage.Storage) error {
+				_, err := store.AddQuote(ctx, models.Quote{Text: "third", Author: "B"})
+				return err
+			},
+			changes: true,
+		},
+		{
+			name: "update",
+			act: func(ctx context.Context, store *memorystorage.Storage) error {
+				text := "changed"
+				_, err := store.UpdateQuote(ctx, 1, storage.QuoteUpdate{Text: &text}, storage.AnyVersion)
+				return err
+			},
+			changes: true,
+		},
+		{
+			name: "delete",
+			act: func(ctx context.Context, store *memorystorage.Storage) error {
+				return store.DeleteQuote(ctx, 2, storage.AnyVersion)
+			},
+			changes: true,
+		},
+		{
+			name: "reads",
+			act: func(ctx context.Context, store *memorystorage.Storage) error {
+				if _, err := store.GetAllQuotes(ctx, storage.QuoteFilter{}); err != nil {
+					return err
+				}
+				if _, err := store.GetQuote(ctx, 1); err != nil {
+					return err
+				}
+				q, err := store.GetRandomQuote(ctx, storage.RandomOptions{})
+				if err != nil {
+					return err
+				}
+				return store.IncrementServed(ctx, q.ID)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router, store := newDigestRouter(t)
+			before := getDigest(t, router)
+			if before.Count != 2 || before.Hash == "" {
+				t.Fatalf("unexpected digest %+v", before)
+			}
+
+			if err := tc.act(context.Background(), store); err != nil {
+				t.Fatalf("failed to act on the store: %v", err)
+			}
+			after := getDigest(t, router)
+
+			if changed := after.Hash != before.Hash; changed != tc.changes {
+				t.Fatalf("expected hash change %v, got %+v -> %+v", tc.changes, before, after)
+			}
+			if changed := after.Version != before.Version; changed != tc.changes {
+				t.Fatalf("expected version change %v, got %+v -> %+v", tc.changes, before, after)
+			}
+		})
+	}
+}
+
+func TestQuotesDigestDerivesFromData(t *testing.T) {
+	// Two stores given the same writes stand in for one store before and
+	// after a restart.
+	first, _ := newDigestRouter(t)
+	second, _ := newDigestRouter(t)
+	if a, b := getDigest(t, first), getDigest(t, second); a.Hash != b.Hash {
+		t.Fatalf("expected equal data to hash the same, got %s and %s", a.Hash, b.Hash)
+	}
+}
+
+func TestQuotesDigestNotModified(t *testing.T) {
+	router, _ := newDigestRouter(t)
+	digest := getDigest(t, router)
+
+	req := httptest.NewRequest(http.MethodGet, "/quotes/digest", nil)
+	req.Header.Set("If-None-Match", `"`+digest.Hash+`"`)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,229 @@
+package quotehandler
+
+import (
+	"cmp"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/fairpick"
+	"quotes-service/internal/lib/logger/sl"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// ClientNonceParam switches GET /quotes/random to a fair pick: one derived
+// from the client's nonce and the server's nonce committed to beforehand,
+// both returned with the quote so the pick can be checked with GET
+// /quotes/random/verify. CommitmentParam names the commitment, from GET
+// /quotes/random/commit, which the pick uses up.
+const (
+	ClientNonceParam = "client_nonce"
+	CommitmentParam  = "commitment"
+)
+
+// NewCommitRandomQuoteHandler serves GET /quotes/random/commit, which draws
+// a server nonce for a fair pick and answers with only the commitment to
+// it. The client chooses its nonce after, so the server nonce cannot have
+// been drawn to suit it.
+func NewCommitRandomQuoteHandler(logger *slog.Logger, commitments *fairpick.Commitments) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.quote.CommitRandomQuote"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		commitment, expiresAt, err := commitments.Issue()
+		if err != nil {
+			log.ErrorContext(ctx, "failed to commit to a server nonce", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeGetRandomFailed, nil)
+		}
+
+		log.InfoContext(ctx, "committed to a server nonce")
+		w.Header().Set("Cache-Control", "no-store")
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data: models.FairPickCommitment{
+				Algorithm:  fairpick.Algorithm,
+				Commitment: commitment,
+				ExpiresAt:  expiresAt,
+			},
+		})
+		return nil
+	})
+}
+
+// serveFairRandom answers GET /quotes/random?commitment=&client_nonce=. It
+// stays off the fast path: the pool is every quote matching the filter,
+// ordered by ID so the client can rebuild it, and neither weights,
+// recently served quotes, coalescing nor the fallback cache play a part.
+func serveFairRandom(w http.ResponseWriter, r *http.Request, log *slog.Logger, qs QuoteStore, commitments *fairpick.Commitments) error {
+	ctx := r.Context()
+
+	clientNonce := r.URL.Query().Get(ClientNonceParam)
+	if n := len([]rune(clientNonce)); n == 0 || n > fairpick.MaxClientNonceChars {
+		log.WarnContext(ctx, "invalid client nonce", sl.UserText("client_nonce", clientNonce))
+		return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, ClientNonceParam)
+	}
+	filter, err := parseQuoteFilter(r)
+	if err != nil {
+		var paramErr *queryParamError
+		errors.As(err, &paramErr)
+		log.WarnContext(ctx, "invalid filter query parameter", slog.String("param", paramErr.param), sl.UserText("value", paramErr.value))
+		return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, paramErr.param)
+	}
+	commitment := r.URL.Query().Get(CommitmentParam)
+	var serverNonce string
+	var ok bool
+	if commitments != nil {
+		serverNonce, ok = commitments.Take(commitment)
+	}
+	if !ok {
+		log.WarnContext(ctx, "unknown, expired or used commitment", sl.UserText("commitment", commitment))
+		return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, CommitmentParam)
+	}
+
+	// The version is read before the pool, so a racing mutation makes the
+	// proof name an older version than the pool, never a newer one.
+	version, err := qs.Version(ctx)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to get storage version", slog.String("error", err.Error()))
+		return apierror.New(http.StatusInternalServerError, apierror.CodeGetRandomFailed, nil)
+	}
+	pool, err := fairPool(r, qs, filter)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to get fair pick pool", slog.String("error", err.Error()))
+		return apierror.New(http.StatusInternalServerError, apierror.CodeGetRandomFailed, nil)
+	}
+	if len(pool) == 0 {
+		log.InfoContext(ctx, "no quotes found to get a fair random one")
+		return apierror.New(http.StatusNotFound, apierror.CodeNoQuotes, nil)
+	}
+
+	d, err := fairpick.Derive(serverNonce, clientNonce, len(pool))
+	if err != nil {
+		log.ErrorContext(ctx, "failed to derive fair pick", slog.String("error", err.Error()))
+		return apierror.New(http.StatusInternalServerError, apierror.CodeGetRandomFailed, nil)
+	}
+	quote := pool[d.Index]
+
+	log.InfoContext(ctx, "retrieved fair random quote", slog.Int64("id", quote.ID), slog.Int("pool_size", len(pool)))
+	trackServed(ctx, log, qs, quote.ID)
+	w.Header().Set("Cache-Control", "no-store")
+	response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+		Status: "success",
+		Data: models.FairPick{
+			Quote: quote,
+			Proof: models.FairPickProof{
+				Algorithm:   fairpick.Algorithm,
+				ServerNonce: d.ServerNonce,
+				Commitment:  d.Commitment,
+				ClientNonce: d.ClientNonce,
+				Digest:      d.Digest,
+				PoolSize:    d.PoolSize,
+				PoolVersion: version,
+				Index:       d.Index,
+			},
+		},
+	})
+	return nil
+}
+
+// fairPool returns the quotes a fair pick is made from, ordered by ID.
+func fairPool(r *http.Request, qs QuoteStore, filter storage.QuoteFilter) ([]models.Quote, error) {
+	pool, err := qs.GetAllQuotes(r.Context(), filter)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(pool, func(a, b models.Quote) int { return cmp.Compare(a.ID, b.ID) })
+	return pool, nil
+}
+
+// NewVerifyRandomQuoteHandler serves GET /quotes/random/verify, which
+// recomputes a fair pick from its server_nonce, client_nonce and
+// pool_size. The commitment, digest and index given, if any, are checked
+// against the recomputed ones; without the commitment the client held
+// before choosing its nonce, the check proves the arithmetic only. With a quote_id, and the filter of the pick, the quote
+// at the index of the current pool is checked too; that fails once the
+// pool has changed, which the response says.
+func NewVerifyRandomQuoteHandler(logger *slog.Logger, qs QuoteStore) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.quote.VerifyRandomQuote"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+		query := r.URL.Query()
+
+		badParam := func(param string) error {
+			log.WarnContext(ctx, "invalid verify query parameter", slog.String("param", param), sl.UserText("value", query.Get(param)))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, param)
+		}
+
+		poolSize, err := strconv.Atoi(query.Get("pool_size"))
+		if err != nil {
+			return badParam("pool_size")
+		}
+		d, err := fairpick.Derive(query.Get("server_nonce"), query.Get(ClientNonceParam), poolSize)
+		switch {
+		case errors.Is(err, fairpick.ErrInvalidServerNonce):
+			return badParam("server_nonce")
+		case errors.Is(err, fairpick.ErrInvalidClientNonce):
+			return badParam(ClientNonceParam)
+		case errors.Is(err, fairpick.ErrEmptyPool):
+			return badParam("pool_size")
+		case err != nil:
+			log.ErrorContext(ctx, "failed to derive fair pick", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeGetRandomFailed, nil)
+		}
+
+		result := models.FairPickVerification{Commitment: d.Commitment, Digest: d.Digest, Index: d.Index}
+		if commitment := query.Get(CommitmentParam); commitment != "" && commitment != d.Commitment {
+			result.Problems = append(result.Problems, fairpick.ErrCommitmentMismatch.Error())
+		}
+		if digest := query.Get("digest"); digest != "" && digest != d.Digest {
+			result.Problems = append(result.Problems, fairpick.ErrDigestMismatch.Error())
+		}
+		if indexStr := query.Get("index"); indexStr != "" {
+			index, err := strconv.Atoi(indexStr)
+			if err != nil {
+				return badParam("index")
+			}
+			if index != d.Index {
+				result.Problems = append(result.Problems, fairpick.ErrIndexMismatch.Error())
+			}
+		}
+
+		if quoteIDStr := query.Get("quote_id"); quoteIDStr != "" {
+			quoteID, err := strconv.ParseInt(quoteIDStr, 10, 64)
+			if err != nil {
+				return badParam("quote_id")
+			}
+			filter, err := parseQuoteFilter(r)
+			if err != nil {
+				var paramErr *queryParamError
+				errors.As(err, &paramErr)
+				return badParam(paramErr.param)
+			}
+			pool, err := fairPool(r, qs, filter)
+			if err != nil {
+				log.ErrorContext(ctx, "failed to get fair pick pool", slog.String("error", err.Error()))
+				return apierror.New(http.StatusInternalServerError, apierror.CodeGetRandomFailed, nil)
+			}
+			if len(pool) != d.PoolSize {
+				result.Problems = append(result.Problems, "pool has changed since the pick")
+			} else if result.QuoteID = pool[d.Index].ID; result.QuoteID != quoteID {
+				result.Problems = append(result.Problems, "quote is not the one at the index")
+			}
+		}
+		result.Valid = len(result.Problems) == 0
+
+		log.InfoContext(ctx, "verified fair random quote", slog.Bool("valid", result.Valid))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   result,
+		})
+		return nil
+	})
+}
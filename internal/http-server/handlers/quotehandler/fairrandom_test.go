@@ -0,0 +1,227 @@
+package quotehandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/handlers/quotehandler"
+	"quotes-service/internal/lib/fairpick"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func newFairRouter(t *testing.T) (*mux.Router, *memorystorage.Storage) {
+	t.Helper()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for i := range 20 {
+		if _, err := store.AddQuote(context.Background(), models.Quote{Text: fmt.Sprintf("quote %d", i), Author: "A"}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := mux.NewRouter()
+	commitments := fairpick.NewCommitments(time.Minute, 100)
+	router.HandleFunc("/quotes/random", quotehandler.NewGetRandomQuoteHandler(logger, store, nil, nil, nil, commitments)).Methods(http.MethodGet)
+	router.HandleFunc("/quotes/random/commit", quotehandler.NewCommitRandomQuoteHandler(logger, commitments)).Methods(http.MethodGet)
+	router.HandleFunc("/quotes/random/verify", quotehandler.NewVerifyRandomQuoteHandler(logger, store)).Methods(http.MethodGet)
+	return router, store
+}
+
+func getFair(t *testing.T, router http.Handler, target string, expectedStatus int, data any) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+	if rr.Code != expectedStatus {
+		t.Fatalf("expected %d for %s, got %d: %s", expectedStatus, target, rr.Code, rr.Body.String())
+	}
+	if data == nil {
+		return
+	}
+	resp := struct {
+		Data any `json:"data"`
+	}{Data: data}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+}
+
+// commitFair gets a commitment to a server nonce for a fair pick.
+func commitFair(t *testing.T, router http.Handler) string {
+	t.Helper()
+	var c models.FairPickCommitment
+	getFair(t, router, "/quotes/random/commit", http.StatusOK, &c)
+	if len(c.Commitment) != 64 || c.Algorithm != "hmac-sha256" || c.ExpiresAt.IsZero() {
+		t.Fatalf("unexpected commitment %+v", c)
+	}
+	return c.Commitment
+}
+
+func TestGetRandomQuoteHandlerFair(t *testing.T) {
+	router, store := newFairRouter(t)
+
+	commitment := commitFair(t, router)
+	var pick models.FairPick
+	getFair(t, router, "/quotes/random?client_nonce=giveaway-42&commitment="+commitment, http.StatusOK, &pick)
+	proof := pick.Proof
+	if proof.ClientNonce != "giveaway-42" || len(proof.ServerNonce) != 64 || proof.PoolSize != 20 || proof.Algorithm != "hmac-sha256" {
+		t.Fatalf("unexpected proof %+v", proof)
+	}
+	// The server nonce revealed is the one committed to before the client
+	// nonce was known.
+	if got, err := fairpick.Commit(proof.ServerNonce); err != nil || got != commitment || proof.Commitment != commitment {
+		t.Fatalf("expected the server nonce committed to as %s, got %+v", commitment, proof)
+	}
+	// The pool is ordered by ID and the memory store numbers from 1.
+	if pick.Quote.ID != int64(proof.Index+1) {
+		t.Fatalf("expected the quote at index %d, got quote %d", proof.Index, pick.Quote.ID)
+	}
+
+	var other models.FairPick
+	getFair(t, router, "/quotes/random?client_nonce=giveaway-42&commitment="+commitFair(t, router), http.StatusOK, &other)
+	if other.Proof.ServerNonce == proof.ServerNonce {
+		t.Fatal("expected a fresh server nonce for every pick")
+	}
+
+	fresh := commitFair(t, router)
+	getFair(t, router, "/quotes/random?client_nonce=&commitment="+fresh, http.StatusBadRequest, nil)
+	getFair(t, router, "/quotes/random?client_nonce="+strings.Repeat("x", 129)+"&commitment="+fresh, http.StatusBadRequest, nil)
+	getFair(t, router, "/quotes/random?client_nonce=giveaway-42", http.StatusBadRequest, nil)
+	getFair(t, router, "/quotes/random?client_nonce=giveaway-42&commitment="+strings.Repeat("0", 64), http.StatusBadRequest, nil)
+	// A commitment is good for one pick.
+	getFair(t, router, "/quotes/random?client_nonce=giveaway-43&commitment="+commitment, http.StatusBadRequest, nil)
+
+	params := func(change func(v url.Values)) string {
+		v := url.Values{
+			"server_nonce": {proof.ServerNonce},
+			"commitment":   {proof.Commitment},
+			"client_nonce": {proof.ClientNonce},
+			"pool_size":    {strconv.Itoa(proof.PoolSize)},
+			"digest":       {proof.Digest},
+			"index":        {strconv.Itoa(proof.Index)},
+			"quote_id":     {strconv.FormatInt(pick.Quote.ID, 10)},
+		}
+		if change != nil {
+			change(v)
+		}
+		return "/quotes/random/verify?" + v.Encode()
+	}
+	otherIndex := strconv.Itoa((proof.Index + 1) % proof.PoolSize)
+
+	tests := []struct {
+		name            string
+		target          string
+		expectedStatus  int
+		expectedValid   bool
+		expectedProblem string
+	}{
+		{name: "honest", target: params(nil), expectedStatus: http.StatusOK, expectedValid: true},
+		{
+			name:           "proof only",
+			target:         params(func(v url.Values) { v.Del("quote_id") }),
+			expectedStatus: http.StatusOK,
+			expectedValid:  true,
+		},
+		{
+			name:            "server nonce not committed to",
+			target:          params(func(v url.Values) { v.Set("server_nonce", other.Proof.ServerNonce) }),
+			expectedStatus:  http.StatusOK,
+			expectedProblem: fairpick.ErrCommitmentMismatch.Error(),
+		},
+		{
+			name: "other server nonce and commitment",
+			target: params(func(v url.Values) {
+				v.Set("server_nonce", other.Proof.ServerNonce)
+				v.Set("commitment", other.Proof.Commitment)
+			}),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "other client nonce",
+			target:         params(func(v url.Values) { v.Set("client_nonce", "giveaway-43") }),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "forged digest",
+			target:         params(func(v url.Values) { v.Set("digest", strings.Repeat("0", 64)) }),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "other index",
+			target:         params(func(v url.Values) { v.Set("index", otherIndex) }),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "other quote",
+			target: params(func(v url.Values) {
+				v.Set("quote_id", strconv.FormatInt(pick.Quote.ID%20+1, 10))
+			}),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "other pool size",
+			target:         params(func(v url.Values) { v.Set("pool_size", "19") }),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "malformed server nonce",
+			target:         params(func(v url.Values) { v.Set("server_nonce", "xyz") }),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing client nonce",
+			target:         params(func(v url.Values) { v.Del("client_nonce") }),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "zero pool size",
+			target:         params(func(v url.Values) { v.Set("pool_size", "0") }),
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var result models.FairPickVerification
+			getFair(t, router, tc.target, tc.expectedStatus, &result)
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+			if result.Valid != tc.expectedValid {
+				t.Fatalf("expected valid %v, got %+v", tc.expectedValid, result)
+			}
+			if !tc.expectedValid && len(result.Problems) == 0 {
+				t.Fatalf("expected the problems to be named, got %+v", result)
+			}
+			if tc.expectedProblem != "" && !slices.Contains(result.Problems, tc.expectedProblem) {
+				t.Fatalf("expected the problem %q, got %+v", tc.expectedProblem, result)
+			}
+		})
+	}
+
+	t.Run("pool changed", func(t *testing.T) {
+		if _, err := store.AddQuote(context.Background(), models.Quote{Text: "late", Author: "B"}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+		var result models.FairPickVerification
+		getFair(t, router, params(nil), http.StatusOK, &result)
+		if result.Valid || len(result.Problems) != 1 || !strings.Contains(result.Problems[0], "pool") {
+			t.Fatalf("expected only the pool to be reported changed, got %+v", result)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package quotehandler
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sync"
+
+	"quotes-service/internal/http-server/headers"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// ServedFromHeader is set to "cache" on a random quote served by a
+// RandomFallback because the store failed.
+const ServedFromHeader = "X-Served-From"
+
+func init() {
+	headers.Expose(ServedFromHeader, "ETag", "Content-Disposition")
+}
+
+// RandomFallback remembers the last quotes served at random, so that the
+// random quote endpoint can still answer with one of them while the store
+// fails. It holds at most size quotes, replacing the oldest. Methods on a
+// nil RandomFallback remember nothing and never have a quote. It is safe
+// for concurrent use.
+type RandomFallback struct {
+	mu     sync.Mutex
+	quotes []models.Quote
+	size   int
+	// next is where the next new quote goes once quotes is full.
+	next int
+}
+
+// NewRandomFallback returns a fallback of size quotes, or nil if size is
+// zero or less.
+func NewRandomFallback(size int) *RandomFallback {
+	if size <= 0 {
+		return nil
+	}
+	return &RandomFallback{quotes: make([]models.Quote, 0, size), size: size}
+}
+
+// remember keeps q, in place of its older copy if there is one.
+func (f *RandomFallback) remember(q models.Quote) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if i := slices.IndexFunc(f.quotes, func(held models.Quote) bool { return held.ID == q.ID }); i >= 0 {
+		f.quotes[i] = q
+		return
+	}
+	if len(f.quotes) < f.size {
+		f.quotes = append(f.quotes, q)
+		return
+	}
+	f.quotes[f.next] = q
+	f.next = (f.next + 1) % f.size
+}
+
+// pick returns a random remembered quote that passes opts.Filter, one not
+// in opts.ExcludeIDs if it can, and false if none passes.
+func (f *RandomFallback) pick(opts storage.RandomOptions) (models.Quote, bool) {
+	if f == nil {
+		return models.Quote{}, false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var matching, fresh []models.Quote
+	for _, q := range f.quotes {
+		if !opts.Filter.Matches(q) {
+			continue
+		}
+		matching = append(matching, q)
+		if !slices.Contains(opts.ExcludeIDs, q.ID) {
+			fresh = append(fresh, q)
+		}
+	}
+	if len(fresh) > 0 {
+		matching = fresh
+	}
+	if len(matching) == 0 {
+		return models.Quote{}, false
+	}
+	return matching[rand.IntN(len(matching))], true
+}
@@ -0,0 +1,135 @@
+package quotehandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"quotes-service/internal/http-server/handlers/quotehandler"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage/faultstorage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func TestGetRandomQuoteHandlerFallback(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	failing := faultstorage.Faults{ErrorRate: 1, Methods: []string{"GetRandomQuote"}}
+
+	tests := []struct {
+		name   string
+		quotes []models.Quote
+		warm   int
+		// emptied deletes the quotes after warming instead of failing the store.
+		emptied          bool
+		fallback         *quotehandler.RandomFallback
+		query            string
+		expectedStatus   int
+		expectedServedBy string
+	}{
+		{
+			name:             "served from cache",
+			quotes:           []models.Quote{{Text: "One", Author: "A", Lang: "en"}},
+			warm:             1,
+			fallback:         quotehandler.NewRandomFallback(4),
+			expectedStatus:   http.StatusOK,
+			expectedServedBy: "cache",
+		},
+		{
+			name:           "no cached quote matches",
+			quotes:         []models.Quote{{Text: "One", Author: "A", Lang: "en"}},
+			warm:           1,
+			fallback:       quotehandler.NewRandomFallback(4),
+			query:          "?lang=ru",
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "cold cache",
+			quotes:         []models.Quote{{Text: "One", Author: "A", Lang: "en"}},
+			fallback:       quotehandler.NewRandomFallback(4),
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "fallback disabled",
+			quotes:         []models.Quote{{Text: "One", Author: "A", Lang: "en"}},
+			warm:           1,
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "empty store",
+			quotes:         []models.Quote{{Text: "One", Author: "A", Lang: "en"}},
+			warm:           1,
+			emptied:        true,
+			fallback:       quotehandler.NewRandomFallback(4),
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			inner, err := memorystorage.New()
+			if err != nil {
+				t.Fatalf("failed to init storage: %v", err)
+			}
+			for _, q := range tc.quotes {
+				if _, err := inner.AddQuote(context.Background(), q); err != nil {
+					t.Fatalf("failed to add quote: %v", err)
+				}
+			}
+			store := faultstorage.New(inner)
+			handler := quotehandler.NewGetRandomQuoteHandler(logger, store, nil, nil, tc.fallback, nil)
+
+			get := func(query string) *httptest.ResponseRecorder {
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/quotes/random"+query, nil))
+				return rr
+			}
+
+			var served models.Quote
+			for range tc.warm {
+				rr := get("")
+				if rr.Code != http.StatusOK || rr.Header().Get(quotehandler.ServedFromHeader) != "" {
+					t.Fatalf("expected a quote from the store, got %d %v", rr.Code, rr.Header())
+				}
+				var resp struct {
+					Data models.Quote `json:"data"`
+				}
+				if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("failed to decode quote: %v", err)
+				}
+				served = resp.Data
+			}
+
+			if tc.emptied {
+				if err := inner.DeleteQuote(context.Background(), served.ID, 0); err != nil {
+					t.Fatalf("failed to delete quote: %v", err)
+				}
+			} else if err := store.SetFaults(failing); err != nil {
+				t.Fatal(err)
+			}
+
+			rr := get(tc.query)
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if got := rr.Header().Get(quotehandler.ServedFromHeader); got != tc.expectedServedBy {
+				t.Fatalf("expected %s %q, got %q", quotehandler.ServedFromHeader, tc.expectedServedBy, got)
+			}
+			if tc.expectedServedBy == "" {
+				return
+			}
+			var resp struct {
+				Data models.Quote `json:"data"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode quote: %v", err)
+			}
+			if resp.Data.ID != served.ID {
+				t.Fatalf("expected the cached quote %d, got %d", served.ID, resp.Data.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package quotehandler
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/headers"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage/dedupe"
+)
+
+// FingerprintHeader carries the fingerprint a lookup was made with, on a
+// 404 as on a match, so that clients can cache either answer under it.
+const FingerprintHeader = "X-Quote-Fingerprint"
+
+func init() {
+	headers.Expose(FingerprintHeader)
+}
+
+// NewLookupQuoteHandler serves GET /quotes/lookup?text=&author= and POST
+// /quotes/lookup with the same fields in a JSON body, for texts too long
+// for a URL. It finds quotes by content rather than ID: every quote whose
+// fingerprint, as duplicates are found by, is that of the text and author,
+// so case, punctuation, spacing and typography do not matter. A text that
+// matches nothing is a 404 naming the fingerprint.
+func NewLookupQuoteHandler(logger *slog.Logger, qs QuoteStore) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.quote.LookupQuote"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		var req models.LookupQuoteRequest
+		if r.Method == http.MethodPost {
+			if err := decodeBody(r.Body, &req); err != nil {
+				if ErrorsIs(err, io.EOF) {
+					log.WarnContext(ctx, "request body is empty")
+					return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyEmpty, nil)
+				}
+				log.ErrorContext(ctx, "failed to decode request body", slog.String("error", err.Error()))
+				return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyInvalid, nil)
+			}
+			defer r.Body.Close()
+		} else {
+			query := r.URL.Query()
+			req.Text, req.Author = query.Get("text"), query.Get("author")
+		}
+
+		var validationErrors []string
+		if strings.TrimSpace(req.Text) == "" {
+			validationErrors = append(validationErrors, "text cannot be empty")
+		}
+		if strings.TrimSpace(req.Author) == "" {
+			validationErrors = append(validationErrors, "author cannot be empty")
+		}
+		if len(validationErrors) > 0 {
+			log.WarnContext(ctx, "invalid request", slog.Any("validation_errors", validationErrors))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, validationErrors)
+		}
+
+		group, err := dedupe.Find(ctx, qs, req.Text, req.Author)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to look up quote", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeGetQuotesFailed, nil)
+		}
+		w.Header().Set(FingerprintHeader, group.Key)
+		if len(group.Quotes) == 0 {
+			log.InfoContext(ctx, "no quote matches", slog.String("fingerprint", group.Key))
+			return apierror.New(http.StatusNotFound, apierror.CodeNoMatchingQuote, nil, group.Key)
+		}
+
+		log.InfoContext(ctx, "looked up quote", slog.String("fingerprint", group.Key), slog.Int("matches", len(group.Quotes)))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data: models.QuoteLookup{
+				Fingerprint: group.Key,
+				Quotes:      group.Quotes,
+			},
+		})
+		return nil
+	})
+}
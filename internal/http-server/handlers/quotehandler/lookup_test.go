@@ -0,0 +1,101 @@
+package quotehandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"quotes-service/internal/http-server/handlers/quotehandler"
+	"quotes-service/internal/lib/fingerprint"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func TestLookupQuoteHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for _, q := range []models.Quote{
+		{Text: "Stay hungry, stay foolish.", Author: "Steve Jobs"},
+		{Text: "Imagination is more important than knowledge.", Author: "Albert Einstein"},
+		// A duplicate, as kept while dedupe is off.
+		{Text: "Imagination is more important than knowledge", Author: "albert einstein"},
+	} {
+		if _, err := store.AddQuote(context.Background(), q); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+	handler := quotehandler.NewLookupQuoteHandler(logger, store)
+
+	tests := []struct {
+		name           string
+		method         string
+		text           string
+		author         string
+		expectedStatus int
+		expectedIDs    []int64
+	}{
+		{name: "exact", method: http.MethodGet, text: "Stay hungry, stay foolish.", author: "Steve Jobs", expectedStatus: http.StatusOK, expectedIDs: []int64{1}},
+		{name: "case and whitespace", method: http.MethodGet, text: "  stay HUNGRY,\tstay   foolish ", author: " steve  jobs", expectedStatus: http.StatusOK, expectedIDs: []int64{1}},
+		{name: "typography", method: http.MethodGet, text: "Stay hungry — stay foolish!", author: "Steve Jobs", expectedStatus: http.StatusOK, expectedIDs: []int64{1}},
+		{name: "body", method: http.MethodPost, text: "STAY HUNGRY\nSTAY FOOLISH", author: "Steve Jobs", expectedStatus: http.StatusOK, expectedIDs: []int64{1}},
+		{name: "duplicates", method: http.MethodGet, text: "imagination is more important than knowledge", author: "Albert Einstein", expectedStatus: http.StatusOK, expectedIDs: []int64{2, 3}},
+		{name: "no match", method: http.MethodGet, text: "Stay thirsty.", author: "Steve Jobs", expectedStatus: http.StatusNotFound},
+		{name: "no match in body", method: http.MethodPost, text: "Stay hungry, stay foolish.", author: "Albert Einstein", expectedStatus: http.StatusNotFound},
+		{name: "no author", method: http.MethodGet, text: "Stay hungry.", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var req *http.Request
+			if tc.method == http.MethodPost {
+				body, _ := json.Marshal(models.LookupQuoteRequest{Text: tc.text, Author: tc.author})
+				req = httptest.NewRequest(http.MethodPost, "/quotes/lookup", strings.NewReader(string(body)))
+			} else {
+				query := url.Values{"text": {tc.text}, "author": {tc.author}}
+				req = httptest.NewRequest(http.MethodGet, "/quotes/lookup?"+query.Encode(), nil)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tc.expectedStatus == http.StatusBadRequest {
+				return
+			}
+			key := fingerprint.Of(tc.text, tc.author)
+			if got := rr.Header().Get(quotehandler.FingerprintHeader); got != key {
+				t.Fatalf("expected fingerprint %s, got %q", key, got)
+			}
+			if tc.expectedStatus == http.StatusNotFound {
+				if !strings.Contains(rr.Body.String(), key) {
+					t.Fatalf("expected the fingerprint in %s", rr.Body.String())
+				}
+				return
+			}
+			var resp struct {
+				Data models.QuoteLookup `json:"data"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			var ids []int64
+			for _, q := range resp.Data.Quotes {
+				ids = append(ids, q.ID)
+			}
+			if resp.Data.Fingerprint != key || !slices.Equal(ids, tc.expectedIDs) {
+				t.Fatalf("expected %v under %s, got %+v", tc.expectedIDs, key, resp.Data)
+			}
+		})
+	}
+}
@@ -2,15 +2,33 @@ package quotehandler
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/conditional"
+	"quotes-service/internal/http-server/apierror"
+	mwAudit "quotes-service/internal/http-server/middleware/audit"
+	"quotes-service/internal/http-server/pagination"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/clienthistory"
+	"quotes-service/internal/lib/fairpick"
+	"quotes-service/internal/lib/jsoncache"
+	"quotes-service/internal/lib/language"
+	"quotes-service/internal/lib/language/detect"
+	"quotes-service/internal/lib/logger/sl"
+	"quotes-service/internal/lib/moderation"
+	"quotes-service/internal/lib/quoteinput"
+	"quotes-service/internal/lib/textstats"
 	"quotes-service/internal/models"
 	"quotes-service/internal/storage"
 )
@@ -19,134 +37,538 @@ import (
 var ErrorsIs = errors.Is
 
 type QuoteStore interface {
-	AddQuote(ctx context.Context, text string, author string) (int64, error)
-	GetAllQuotes(ctx context.Context) ([]models.Quote, error)
-	GetRandomQuote(ctx context.Context) (models.Quote, error)
-	GetQuotesByAuthor(ctx context.Context, authorFilter string) ([]models.Quote, error)
-	DeleteQuote(ctx context.Context, id int64) error
+	AddQuote(ctx context.Context, quote models.Quote) (int64, error)
+	GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error)
+	GetQuote(ctx context.Context, id int64) (models.Quote, error)
+	GetRandomQuote(ctx context.Context, opts storage.RandomOptions) (models.Quote, error)
+	GetQuotesByAuthor(ctx context.Context, authorFilter string, filter storage.QuoteFilter) ([]models.Quote, error)
+	UpdateQuote(ctx context.Context, id int64, update storage.QuoteUpdate, ifVersion int64) (models.Quote, error)
+	DeleteQuote(ctx context.Context, id int64, ifVersion int64) error
+	IncrementServed(ctx context.Context, id int64) error
+	GetPopularQuotes(ctx context.Context, limit int) ([]models.PopularQuote, error)
+	GetSimilarQuotes(ctx context.Context, id int64, limit int) ([]models.SimilarQuote, error)
+	Version(ctx context.Context) (uint64, error)
 }
 
-func sendJSONResponse(w http.ResponseWriter, statusCode int, payload interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	if err := json.NewEncoder(w).Encode(payload); err != nil {
-		slog.Error("failed to encode and write JSON response", slog.String("error", err.Error()))
+const (
+	defaultPopularLimit = 10
+	maxPopularLimit     = 100
+	defaultSimilarLimit = 5
+	maxSimilarLimit     = 50
+
+	ClientIDHeader    = "X-Client-ID"
+	ClientIDCookie    = "quotes_client_id"
+	maxClientIDLength = 128
+)
+
+// parseLimit reads the optional limit query parameter, falling back to def
+// and capping the value at max.
+func parseLimit(r *http.Request, def, max int) (int, error) {
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		return def, nil
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		return 0, err
+	}
+	if limit <= 0 {
+		return 0, errors.New("limit must be positive")
+	}
+	return min(limit, max), nil
+}
+
+// parsePage reads the limit and offset of a paginated list, failing with
+// the error to answer when they are invalid.
+func parsePage(r *http.Request, log *slog.Logger, sizes pagination.Sizes) (pagination.Page, error) {
+	page, err := pagination.Parse(r, sizes)
+	if err != nil {
+		log.WarnContext(r.Context(), "invalid pagination", sl.UserText("query", r.URL.RawQuery), slog.String("error", err.Error()))
+		if errors.Is(err, pagination.ErrInvalidOffset) {
+			return pagination.Page{}, apierror.New(http.StatusBadRequest, apierror.CodeInvalidOffset, nil)
+		}
+		return pagination.Page{}, apierror.New(http.StatusBadRequest, apierror.CodeInvalidLimit, nil)
 	}
+	return page, nil
 }
 
-func sendErrorResponse(w http.ResponseWriter, statusCode int, message string, fields []string) {
-	response := models.ErrorResponse{
-		Status: "error",
-		Error:  message,
+// lastUpdated returns the newest UpdatedAt among quotes. Deleting a quote
+// does not move it forward, so list validators only track edits and
+// additions.
+func lastUpdated(quotes []models.Quote) time.Time {
+	var latest time.Time
+	for _, q := range quotes {
+		if q.UpdatedAt.After(latest) {
+			latest = q.UpdatedAt
+		}
+	}
+	return latest
+}
+
+// parseQuoteFilter reads the list filters shared by the list, search and
+// random endpoints from the query string.
+func parseQuoteFilter(r *http.Request) (storage.QuoteFilter, error) {
+	var filter storage.QuoteFilter
+	query := r.URL.Query()
+
+	if lang := query.Get("lang"); lang != "" {
+		normalized, err := language.Normalize(lang)
+		if err != nil {
+			return storage.QuoteFilter{}, &queryParamError{param: "lang", value: lang}
+		}
+		filter.Lang = normalized
 	}
-	if len(fields) > 0 {
-		response.Fields = fields
+
+	if hasSourceStr := query.Get("has_source"); hasSourceStr != "" {
+		hasSource, err := strconv.ParseBool(hasSourceStr)
+		if err != nil {
+			return storage.QuoteFilter{}, &queryParamError{param: "has_source", value: hasSourceStr}
+		}
+		filter.HasSource = &hasSource
 	}
-	sendJSONResponse(w, statusCode, response)
+
+	return filter, nil
+}
+
+type queryParamError struct {
+	param string
+	value string
+}
+
+func (e *queryParamError) Error() string {
+	return fmt.Sprintf("invalid %s query parameter %q", e.param, e.value)
+}
+
+// trackServed bumps the served counter of a quote in the background so the
+// response is never delayed by the bookkeeping.
+func trackServed(ctx context.Context, log *slog.Logger, qs QuoteStore, id int64) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := qs.IncrementServed(ctx, id); err != nil {
+			log.WarnContext(ctx, "failed to increment served counter", slog.Int64("id", id), slog.String("error", err.Error()))
+		}
+	}()
+}
+
+// resolveClientID identifies the caller by the X-Client-ID header or, failing
+// that, by the client cookie. Anonymous callers are issued a cookie so that
+// the next request can be identified; the current one stays anonymous.
+func resolveClientID(w http.ResponseWriter, r *http.Request) string {
+	if clientID := r.Header.Get(ClientIDHeader); clientID != "" {
+		if len(clientID) > maxClientIDLength {
+			return ""
+		}
+		return clientID
+	}
+
+	if cookie, err := r.Cookie(ClientIDCookie); err == nil && cookie.Value != "" && len(cookie.Value) <= maxClientIDLength {
+		return cookie.Value
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     ClientIDCookie,
+		Value:    hex.EncodeToString(buf),
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return ""
 }
 
 func NewAddQuoteHandler(logger *slog.Logger, qs QuoteStore) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
 		const op = "handler.quote.AddQuote"
 		log := logger.With(slog.String("op", op))
 		ctx := r.Context()
 
 		var req models.AddQuoteRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if err := decodeBody(r.Body, &req); err != nil {
 			if ErrorsIs(err, io.EOF) {
 				log.WarnContext(ctx, "request body is empty")
-				sendErrorResponse(w, http.StatusBadRequest, "Request body is empty.", nil)
-				return
+				return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyEmpty, nil)
 			}
 			log.ErrorContext(ctx, "failed to decode request body", slog.String("error", err.Error()))
-			sendErrorResponse(w, http.StatusBadRequest, "Failed to decode request body.", nil)
-			return
+			return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyInvalid, nil)
 		}
 		defer r.Body.Close()
 
-		log.InfoContext(ctx, "request body decoded", slog.Group("request", slog.String("text", req.Text), slog.String("author", req.Author)))
+		logRequestBody(ctx, log, &req.Text, &req.Author)
 
-
-		var validationErrors []string
-		if strings.TrimSpace(req.Text) == "" {
-			validationErrors = append(validationErrors, "text cannot be empty")
-		}
-		if strings.TrimSpace(req.Author) == "" {
-			validationErrors = append(validationErrors, "author cannot be empty")
+		lang, validationErrors := quoteinput.Validate(&req.Text, &req.Author, req.Weight, &req.Lang, &req.SourceURL)
+		weight := storage.DefaultWeight
+		if req.Weight != nil {
+			weight = *req.Weight
 		}
+		langDetected := false
 
 		if len(validationErrors) > 0 {
 			log.WarnContext(ctx, "invalid request", slog.Any("validation_errors", validationErrors))
-			sendErrorResponse(w, http.StatusBadRequest, "Invalid request.", validationErrors)
-			return
+			return apierror.New(http.StatusBadRequest, invalidCode(&req.Author), validationErrors)
+		}
+		req.Text = quoteinput.Text(req.Text)
+
+		if lang == "" {
+			lang, langDetected = detect.Detect(req.Text), true
+			log.DebugContext(ctx, "detected quote language", slog.String("lang", lang))
+		}
+
+		quote := models.Quote{
+			Text:         req.Text,
+			Author:       req.Author,
+			Weight:       weight,
+			Lang:         lang,
+			LangDetected: langDetected,
+			Source:       req.Source,
+			SourceURL:    req.SourceURL,
+		}
+		if ok, err := screen(w, r, log, req.Text, moderation.Entry{HeldQuote: models.HeldQuote{Source: quoteinput.SourceCreate, Quote: quote}}); !ok {
+			return err
 		}
 
-		id, err := qs.AddQuote(ctx, req.Text, req.Author)
+		id, err := qs.AddQuote(ctx, quote)
 		if err != nil {
 			log.ErrorContext(ctx, "failed to add quote to storage", slog.String("error", err.Error()))
-			sendErrorResponse(w, http.StatusInternalServerError, "Failed to add quote.", nil)
-			return
+			return apierror.New(http.StatusInternalServerError, apierror.CodeAddQuoteFailed, nil)
 		}
 
 		log.InfoContext(ctx, "quote added successfully", slog.Int64("id", id))
-		sendJSONResponse(w, http.StatusCreated, models.AddQuoteResponse{
-			Status: "success",
-			ID:     id,
-			Text:   req.Text,
-			Author: req.Author,
+		mwAudit.Quote(w, id)
+
+		// The store assigns the public ID, if any, so read it back. The
+		// quote is added either way, so a failed read only loses the field.
+		var publicID string
+		if stored, err := qs.GetQuote(ctx, id); err == nil {
+			publicID = stored.PublicID
+		} else {
+			log.WarnContext(ctx, "failed to read back added quote", slog.Int64("id", id), slog.String("error", err.Error()))
+		}
+
+		response.JSON(w, r, http.StatusCreated, models.AddQuoteResponse{
+			Status:       "success",
+			ID:           id,
+			PublicID:     publicID,
+			Text:         req.Text,
+			Author:       req.Author,
+			Weight:       weight,
+			Lang:         lang,
+			LangDetected: langDetected,
+			Source:       req.Source,
+			SourceURL:    req.SourceURL,
 		})
-	}
+		return nil
+	})
 }
 
-func NewGetAllQuotesHandler(logger *slog.Logger, qs QuoteStore) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
+// NewGetAllQuotesHandler serves GET /quotes, one page of the quotes at a
+// time. When cache is not nil, the first page at the default size of the
+// unfiltered list is kept encoded and reused until the storage version
+// changes, and the version is sent as that page's ETag.
+func NewGetAllQuotesHandler(logger *slog.Logger, qs QuoteStore, cache *jsoncache.Cache, sizes pagination.Sizes) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
 		const op = "handler.quote.GetAllQuotes"
 		log := logger.With(slog.String("op", op))
 		ctx := r.Context()
 
-		quotes, err := qs.GetAllQuotes(ctx)
+		filter, err := parseQuoteFilter(r)
+		if err != nil {
+			var paramErr *queryParamError
+			errors.As(err, &paramErr)
+			log.WarnContext(ctx, "invalid filter query parameter", slog.String("param", paramErr.param), sl.UserText("value", paramErr.value))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, paramErr.param)
+		}
+		page, err := parsePage(r, log, sizes)
+		if err != nil {
+			return err
+		}
+
+		if cache != nil && filter.IsZero() && page == (pagination.Page{Limit: sizes.Default}) {
+			return serveCachedList(w, r, log, qs, cache, page)
+		}
+
+		quotes, err := qs.GetAllQuotes(ctx, filter)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to get all quotes", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeGetQuotesFailed, nil)
+		}
+
+		pagination.SetHeaders(w, r, page, len(quotes))
+		if conditional.CheckModified(w, r, lastUpdated(quotes)) {
+			log.InfoContext(ctx, "quotes not modified", slog.Int("count", len(quotes)))
+			return nil
+		}
+
+		log.InfoContext(ctx, "retrieved all quotes", slog.Int("total", len(quotes)), slog.Int("limit", page.Limit), slog.Int("offset", page.Offset))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   pagination.Slice(quotes, page),
+		})
+		return nil
+	})
+}
+
+// serveCachedList writes the unfiltered list from cache, encoding and
+// storing it first on a miss. The version is read before the quotes, so a
+// racing mutation at worst causes an extra miss, never a stale hit.
+func serveCachedList(w http.ResponseWriter, r *http.Request, log *slog.Logger, qs QuoteStore, cache *jsoncache.Cache, page pagination.Page) error {
+	ctx := r.Context()
+
+	version, err := qs.Version(ctx)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to get storage version", slog.String("error", err.Error()))
+		return apierror.New(http.StatusInternalServerError, apierror.CodeGetQuotesFailed, nil)
+	}
+
+	entry, hit := cache.Get(version)
+	if !hit {
+		quotes, err := qs.GetAllQuotes(ctx, storage.QuoteFilter{})
 		if err != nil {
 			log.ErrorContext(ctx, "failed to get all quotes", slog.String("error", err.Error()))
-			sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve quotes.", nil)
-			return
+			return apierror.New(http.StatusInternalServerError, apierror.CodeGetQuotesFailed, nil)
 		}
 
-		log.InfoContext(ctx, "retrieved all quotes", slog.Int("count", len(quotes)))
-		sendJSONResponse(w, http.StatusOK, models.SuccessDataResponse{
+		body, err := json.Marshal(models.SuccessDataResponse{
 			Status: "success",
-			Data:   quotes,
+			Data:   pagination.Slice(quotes, page),
 		})
+		if err != nil {
+			log.ErrorContext(ctx, "failed to encode quotes", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeGetQuotesFailed, nil)
+		}
+		entry = jsoncache.Entry{
+			Body:         append(body, '\n'),
+			LastModified: lastUpdated(quotes),
+			Total:        len(quotes),
+		}
+		if !cache.Put(version, entry) {
+			log.DebugContext(ctx, "quote list not cached", slog.Int("bytes", len(entry.Body)))
+		}
+	}
+
+	etag := conditional.ETag(int64(version))
+	w.Header().Set("ETag", etag)
+	pagination.SetHeaders(w, r, page, entry.Total)
+	if conditional.CheckModified(w, r, entry.LastModified) || conditional.CheckNoneMatch(w, r, etag) {
+		log.InfoContext(ctx, "quotes not modified", slog.Bool("cache_hit", hit))
+		return nil
 	}
+
+	log.InfoContext(ctx, "retrieved all quotes", slog.Bool("cache_hit", hit))
+	response.RawJSON(w, r, http.StatusOK, entry.Body)
+	return nil
 }
 
-func NewGetRandomQuoteHandler(logger *slog.Logger, qs QuoteStore) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
+func NewGetQuoteHandler(logger *slog.Logger, qs QuoteStore) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.quote.GetQuote"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		idStr := mux.Vars(r)["id"]
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			log.WarnContext(ctx, "invalid quote ID format", slog.String("id", idStr), slog.String("error", err.Error()))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidQuoteID, nil)
+		}
+
+		quote, err := qs.GetQuote(ctx, id)
+		if err != nil {
+			log.Log(ctx, apierror.Level(err), "failed to get quote", slog.Int64("id", id), slog.String("error", err.Error()))
+			return apierror.Failed(apierror.CodeGetQuoteFailed, err)
+		}
+		trackServed(ctx, log, qs, quote.ID)
+
+		if quote.Version > 0 {
+			w.Header().Set("ETag", conditional.ETag(quote.Version))
+		}
+		if conditional.CheckModified(w, r, quote.UpdatedAt) {
+			log.InfoContext(ctx, "quote not modified", slog.Int64("id", id))
+			return nil
+		}
+
+		log.InfoContext(ctx, "retrieved quote", slog.Int64("id", id))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   quote,
+		})
+		return nil
+	})
+}
+
+// NewGetRandomQuoteHandler serves a random quote. When history is not nil,
+// quotes recently served to an identified client are excluded from the pick.
+// When coalescer is not nil, requests with no filter, unweighted flag or
+// quotes to exclude share its pick; the others always go to the store.
+// When fallback is not nil, a storage failure other than an empty store is
+// answered with one of the quotes it remembers, marked X-Served-From: cache.
+// A request with a client_nonce gets a fair pick instead, see
+// ClientNonceParam, with a server nonce out of commitments; when
+// commitments is nil, no commitment is known and fair picks are refused.
+func NewGetRandomQuoteHandler(logger *slog.Logger, qs QuoteStore, history *clienthistory.History, coalescer *RandomCoalescer, fallback *RandomFallback, commitments *fairpick.Commitments) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
 		const op = "handler.quote.GetRandomQuote"
 		log := logger.With(slog.String("op", op))
 		ctx := r.Context()
 
-		quote, err := qs.GetRandomQuote(ctx)
+		if r.URL.Query().Has(ClientNonceParam) {
+			return serveFairRandom(w, r, log, qs, commitments)
+		}
+
+		filter, err := parseQuoteFilter(r)
+		if err != nil {
+			var paramErr *queryParamError
+			errors.As(err, &paramErr)
+			log.WarnContext(ctx, "invalid filter query parameter", slog.String("param", paramErr.param), sl.UserText("value", paramErr.value))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, paramErr.param)
+		}
+
+		opts := storage.RandomOptions{Filter: filter}
+		if unweightedStr := r.URL.Query().Get("unweighted"); unweightedStr != "" {
+			unweighted, err := strconv.ParseBool(unweightedStr)
+			if err != nil {
+				log.WarnContext(ctx, "invalid unweighted query parameter", slog.String("unweighted", unweightedStr))
+				return apierror.New(http.StatusBadRequest, apierror.CodeInvalidUnweighted, nil)
+			}
+			opts.Unweighted = unweighted
+		}
+
+		var clientID string
+		if history != nil {
+			clientID = resolveClientID(w, r)
+			if clientID != "" {
+				opts.ExcludeIDs = history.Recent(clientID)
+			}
+		}
+
+		coalesced := coalescer != nil && opts.Filter.IsZero() && !opts.Unweighted && len(opts.ExcludeIDs) == 0
+		var quote models.Quote
+		if coalesced {
+			quote, err = coalescer.get(ctx, log, qs)
+		} else {
+			quote, err = qs.GetRandomQuote(ctx, opts)
+		}
 		if err != nil {
 			if ErrorsIs(err, storage.ErrQuoteNotFound) {
 				log.InfoContext(ctx, "no quotes found to get a random one")
-				sendErrorResponse(w, http.StatusNotFound, "No quotes found.", nil)
-				return
+				return apierror.New(http.StatusNotFound, apierror.CodeNoQuotes, nil)
+			}
+			if cached, ok := fallback.pick(opts); ok {
+				log.WarnContext(ctx, "failed to get random quote, serving a cached one", slog.Int64("id", cached.ID), slog.String("error", err.Error()))
+				w.Header().Set(ServedFromHeader, "cache")
+				w.Header().Set("Cache-Control", "no-store")
+				response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+					Status: "success",
+					Data:   cached,
+				})
+				return nil
 			}
 			log.ErrorContext(ctx, "failed to get random quote", slog.String("error", err.Error()))
-			sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve random quote.", nil)
-			return
+			return apierror.New(http.StatusInternalServerError, apierror.CodeGetRandomFailed, nil)
 		}
+		fallback.remember(quote)
 
-		log.InfoContext(ctx, "retrieved random quote", slog.Int64("id", quote.ID))
-		sendJSONResponse(w, http.StatusOK, models.SuccessDataResponse{
+		log.InfoContext(ctx, "retrieved random quote", slog.Int64("id", quote.ID), slog.Bool("coalesced", coalesced))
+		if !coalesced {
+			trackServed(ctx, log, qs, quote.ID)
+		}
+		if history != nil && clientID != "" {
+			history.Remember(clientID, quote.ID)
+		}
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
 			Status: "success",
 			Data:   quote,
 		})
-	}
+		return nil
+	})
+}
+
+func NewGetPopularQuotesHandler(logger *slog.Logger, qs QuoteStore) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.quote.GetPopularQuotes"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		limit, err := parseLimit(r, defaultPopularLimit, maxPopularLimit)
+		if err != nil {
+			log.WarnContext(ctx, "invalid limit query parameter", slog.String("limit", r.URL.Query().Get("limit")))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidLimit, nil)
+		}
+
+		quotes, err := qs.GetPopularQuotes(ctx, limit)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to get popular quotes", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeGetPopularFailed, nil)
+		}
+
+		log.InfoContext(ctx, "retrieved popular quotes", slog.Int("count", len(quotes)))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   quotes,
+		})
+		return nil
+	})
 }
 
-func NewGetQuotesByAuthorHandler(logger *slog.Logger, qs QuoteStore) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
+func NewGetSimilarQuotesHandler(logger *slog.Logger, qs QuoteStore) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.quote.GetSimilarQuotes"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		idStr := mux.Vars(r)["id"]
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			log.WarnContext(ctx, "invalid quote ID format", slog.String("id", idStr), slog.String("error", err.Error()))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidQuoteID, nil)
+		}
+
+		limit, err := parseLimit(r, defaultSimilarLimit, maxSimilarLimit)
+		if err != nil {
+			log.WarnContext(ctx, "invalid limit query parameter", slog.String("limit", r.URL.Query().Get("limit")))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidLimit, nil)
+		}
+
+		quotes, err := qs.GetSimilarQuotes(ctx, id, limit)
+		if err != nil {
+			log.Log(ctx, apierror.Level(err), "failed to get similar quotes", slog.Int64("id", id), slog.String("error", err.Error()))
+			return apierror.Failed(apierror.CodeGetSimilarFailed, err)
+		}
+
+		log.InfoContext(ctx, "retrieved similar quotes", slog.Int64("id", id), slog.Int("count", len(quotes)))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   quotes,
+		})
+		return nil
+	})
+}
+
+func NewGetTextStatsHandler(logger *slog.Logger, qs QuoteStore, analyzer *textstats.Analyzer) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.quote.GetTextStats"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		stats, err := analyzer.Stats(ctx, qs)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to compute text stats", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeTextStatsFailed, nil)
+		}
+
+		log.InfoContext(ctx, "computed text stats", slog.Int("quotes", stats.TotalQuotes))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   stats,
+		})
+		return nil
+	})
+}
+
+func NewGetQuotesByAuthorHandler(logger *slog.Logger, qs QuoteStore, sizes pagination.Sizes) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
 		const op = "handler.quote.GetQuotesByAuthor"
 		log := logger.With(slog.String("op", op))
 		ctx := r.Context()
@@ -154,29 +576,196 @@ func NewGetQuotesByAuthorHandler(logger *slog.Logger, qs QuoteStore) http.Handle
 		author := r.URL.Query().Get("author")
 		if strings.TrimSpace(author) == "" {
 			log.WarnContext(ctx, "author query parameter is missing or empty")
-			sendErrorResponse(w, http.StatusBadRequest, "Author query parameter is required.", nil)
-			return
+			return apierror.New(http.StatusBadRequest, apierror.CodeAuthorRequired, nil)
 		}
 
-		log.InfoContext(ctx, "fetching quotes by author", slog.String("author", author))
+		filter, err := parseQuoteFilter(r)
+		if err != nil {
+			var paramErr *queryParamError
+			errors.As(err, &paramErr)
+			log.WarnContext(ctx, "invalid filter query parameter", slog.String("param", paramErr.param), sl.UserText("value", paramErr.value))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, paramErr.param)
+		}
+		page, err := parsePage(r, log, sizes)
+		if err != nil {
+			return err
+		}
 
-		quotes, err := qs.GetQuotesByAuthor(ctx, author)
+		log.InfoContext(ctx, "fetching quotes by author", sl.UserText("author", author))
+
+		quotes, err := qs.GetQuotesByAuthor(ctx, author, filter)
 		if err != nil {
-			log.ErrorContext(ctx, "failed to get quotes by author", slog.String("author", author), slog.String("error", err.Error()))
-			sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve quotes by author.", nil)
-			return
+			log.ErrorContext(ctx, "failed to get quotes by author", sl.UserText("author", author), slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeGetAuthorQuotesFailed, nil)
+		}
+
+		pagination.SetHeaders(w, r, page, len(quotes))
+		if conditional.CheckModified(w, r, lastUpdated(quotes)) {
+			log.InfoContext(ctx, "quotes by author not modified", sl.UserText("author", author))
+			return nil
 		}
 
-		log.InfoContext(ctx, "retrieved quotes by author", slog.String("author", author), slog.Int("count", len(quotes)))
-		sendJSONResponse(w, http.StatusOK, models.SuccessDataResponse{
+		log.InfoContext(ctx, "retrieved quotes by author", sl.UserText("author", author), slog.Int("total", len(quotes)), slog.Int("limit", page.Limit), slog.Int("offset", page.Offset))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
 			Status: "success",
-			Data:   quotes,
+			Data:   pagination.Slice(quotes, page),
 		})
+		return nil
+	})
+}
+
+// NewReplaceQuoteHandler serves PUT /quotes/{id}. Text and author are
+// required; omitted optional fields are reset as on creation.
+func NewReplaceQuoteHandler(logger *slog.Logger, qs QuoteStore) http.HandlerFunc {
+	return newUpdateQuoteHandler(logger, qs, "handler.quote.ReplaceQuote", false)
+}
+
+// NewPatchQuoteHandler serves PATCH /quotes/{id}, changing only the fields
+// present in the body.
+func NewPatchQuoteHandler(logger *slog.Logger, qs QuoteStore) http.HandlerFunc {
+	return newUpdateQuoteHandler(logger, qs, "handler.quote.PatchQuote", true)
+}
+
+// invalidCode is the code of a request that failed validation: its own for
+// an author name quoteinput.AuthorProblem rejects, so that clients can tell
+// it from the other problems, CodeInvalidRequest otherwise.
+func invalidCode(author *string) apierror.Code {
+	if author != nil && strings.TrimSpace(*author) != "" && quoteinput.AuthorProblem("author", *author) != "" {
+		return apierror.CodeInvalidAuthorName
+	}
+	return apierror.CodeInvalidRequest
+}
+
+// logRequestBody logs the quote text and author of a write request. Quotes
+// can be long and private, so Info only gets their length and a preview;
+// they are logged in full at Debug. Nil fields were not sent.
+func logRequestBody(ctx context.Context, log *slog.Logger, text, author *string) {
+	var attrs []any
+	if text != nil {
+		attrs = append(attrs, sl.Preview("text", *text))
+	}
+	if author != nil {
+		attrs = append(attrs, sl.Preview("author", *author))
+	}
+	log.InfoContext(ctx, "request body decoded", attrs...)
+
+	// The check saves building the attributes on every request.
+	if log.Enabled(ctx, slog.LevelDebug) {
+		var full []any
+		if text != nil {
+			full = append(full, slog.String("text", *text))
+		}
+		if author != nil {
+			full = append(full, slog.String("author", *author))
+		}
+		log.DebugContext(ctx, "request body", slog.Group("request", full...))
 	}
 }
 
+func newUpdateQuoteHandler(logger *slog.Logger, qs QuoteStore, op string, partial bool) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		idStr := mux.Vars(r)["id"]
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			log.WarnContext(ctx, "invalid quote ID format", slog.String("id", idStr), slog.String("error", err.Error()))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidQuoteID, nil)
+		}
+
+		var req models.UpdateQuoteRequest
+		if err := decodeBody(r.Body, &req); err != nil {
+			if ErrorsIs(err, io.EOF) {
+				log.WarnContext(ctx, "request body is empty")
+				return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyEmpty, nil)
+			}
+			log.ErrorContext(ctx, "failed to decode request body", slog.String("error", err.Error()))
+			return apierror.New(http.StatusBadRequest, apierror.CodeRequestBodyInvalid, nil)
+		}
+		defer r.Body.Close()
+
+		logRequestBody(ctx, log, req.Text, req.Author)
+
+		lang, validationErrors := quoteinput.Validate(req.Text, req.Author, req.Weight, req.Lang, req.SourceURL)
+		if !partial {
+			if req.Text == nil {
+				validationErrors = append(validationErrors, "text cannot be empty")
+			}
+			if req.Author == nil {
+				validationErrors = append(validationErrors, "author cannot be empty")
+			}
+		} else if req == (models.UpdateQuoteRequest{}) {
+			validationErrors = append(validationErrors, "at least one field must be set")
+		}
+		if len(validationErrors) > 0 {
+			log.WarnContext(ctx, "invalid request", slog.Any("validation_errors", validationErrors))
+			return apierror.New(http.StatusBadRequest, invalidCode(req.Author), validationErrors)
+		}
+		if req.Text != nil {
+			text := quoteinput.Text(*req.Text)
+			req.Text = &text
+		}
+
+		update := storage.QuoteUpdate{
+			Text:      req.Text,
+			Author:    req.Author,
+			Weight:    req.Weight,
+			Source:    req.Source,
+			SourceURL: req.SourceURL,
+		}
+		if req.Lang != nil {
+			update.Lang = &lang
+		}
+		if !partial {
+			if update.Weight == nil {
+				weight := storage.DefaultWeight
+				update.Weight = &weight
+			}
+			if update.Lang == nil || lang == "" {
+				detected := detect.Detect(*req.Text)
+				update.Lang, update.LangDetected = &detected, true
+			}
+			empty := ""
+			if update.Source == nil {
+				update.Source = &empty
+			}
+			if update.SourceURL == nil {
+				update.SourceURL = &empty
+			}
+		}
+
+		// A held update is applied as it stands when approved, whatever
+		// the quote's version is by then.
+		if req.Text != nil {
+			ok, err := screen(w, r, log, *req.Text, moderation.Entry{
+				HeldQuote: models.HeldQuote{Source: quoteinput.SourceUpdate, QuoteID: id, Quote: heldUpdate(update)},
+				Update:    &update,
+			})
+			if !ok {
+				return err
+			}
+		}
+
+		ifVersion, _ := conditional.IfMatch(r)
+		quote, err := qs.UpdateQuote(ctx, id, update, ifVersion)
+		if err != nil {
+			log.Log(ctx, apierror.Level(err), "failed to update quote", slog.Int64("id", id), slog.Int64("if_version", ifVersion), slog.String("error", err.Error()))
+			return apierror.Failed(apierror.CodeUpdateQuoteFailed, err)
+		}
+
+		log.InfoContext(ctx, "quote updated", slog.Int64("id", id), slog.Int64("version", quote.Version))
+		w.Header().Set("ETag", conditional.ETag(quote.Version))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   quote,
+		})
+		return nil
+	})
+}
+
 func NewDeleteQuoteHandler(logger *slog.Logger, qs QuoteStore) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
 		const op = "handler.quote.DeleteQuote"
 		log := logger.With(slog.String("op", op))
 		ctx := r.Context()
@@ -185,35 +774,29 @@ func NewDeleteQuoteHandler(logger *slog.Logger, qs QuoteStore) http.HandlerFunc
 		idStr, ok := vars["id"]
 		if !ok {
 			log.WarnContext(ctx, "quote ID not found in path")
-			sendErrorResponse(w, http.StatusBadRequest, "Quote ID is missing in path.", nil)
-			return
+			return apierror.New(http.StatusBadRequest, apierror.CodeQuoteIDMissing, nil)
 		}
 
 		id, err := strconv.ParseInt(idStr, 10, 64)
 		if err != nil {
 			log.WarnContext(ctx, "invalid quote ID format", slog.String("id", idStr), slog.String("error", err.Error()))
-			sendErrorResponse(w, http.StatusBadRequest, "Invalid quote ID format.", nil)
-			return
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidQuoteID, nil)
 		}
 
 		log.InfoContext(ctx, "attempting to delete quote", slog.Int64("id", id))
 
-		err = qs.DeleteQuote(ctx, id)
+		ifVersion, _ := conditional.IfMatch(r)
+		err = qs.DeleteQuote(ctx, id, ifVersion)
 		if err != nil {
-			if ErrorsIs(err, storage.ErrQuoteNotFound) {
-				log.InfoContext(ctx, "quote not found for deletion", slog.Int64("id", id))
-				sendErrorResponse(w, http.StatusNotFound, "Quote not found.", nil)
-				return
-			}
-			log.ErrorContext(ctx, "failed to delete quote", slog.Int64("id", id), slog.String("error", err.Error()))
-			sendErrorResponse(w, http.StatusInternalServerError, "Failed to delete quote.", nil)
-			return
+			log.Log(ctx, apierror.Level(err), "failed to delete quote", slog.Int64("id", id), slog.Int64("if_version", ifVersion), slog.String("error", err.Error()))
+			return apierror.Failed(apierror.CodeDeleteQuoteFailed, err)
 		}
 
 		log.InfoContext(ctx, "quote deleted successfully", slog.Int64("id", id))
-		sendJSONResponse(w, http.StatusOK, models.GenericMessageResponse{
+		response.JSON(w, r, http.StatusOK, models.GenericMessageResponse{
 			Status:  "success",
 			Message: "Quote deleted successfully.",
 		})
-	}
+		return nil
+	})
 }
\ No newline at end of file
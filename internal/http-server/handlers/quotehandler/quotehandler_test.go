@@ -5,63 +5,35 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"quotes-service/internal/http-server/handlers/quotehandler"
+	"quotes-service/internal/http-server/pagination"
+	"quotes-service/internal/lib/clienthistory"
+	"quotes-service/internal/lib/jsoncache"
+	"quotes-service/internal/lib/publicid"
+	"quotes-service/internal/lib/textstats"
 	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+	"quotes-service/internal/storage/storagetest"
 )
 
-var errTestQuoteNotFound = errors.New("test: quote not found")
 var errTestStorageInternal = errors.New("test: internal storage error")
 
-type MockQuoteStore struct {
-	AddQuoteFunc          func(ctx context.Context, text string, author string) (int64, error)
-	GetAllQuotesFunc      func(ctx context.Context) ([]models.Quote, error)
-	GetRandomQuoteFunc    func(ctx context.Context) (models.Quote, error)
-	GetQuotesByAuthorFunc func(ctx context.Context, authorFilter string) ([]models.Quote, error)
-	DeleteQuoteFunc       func(ctx context.Context, id int64) error
-}
-
-func (m *MockQuoteStore) AddQuote(ctx context.Context, text string, author string) (int64, error) {
-	if m.AddQuoteFunc != nil {
-		return m.AddQuoteFunc(ctx, text, author)
-	}
-	return 0, errors.New("AddQuoteFunc not implemented")
-}
-
-func (m *MockQuoteStore) GetAllQuotes(ctx context.Context) ([]models.Quote, error) {
-	if m.GetAllQuotesFunc != nil {
-		return m.GetAllQuotesFunc(ctx)
-	}
-	return nil, errors.New("GetAllQuotesFunc not implemented")
-}
-
-func (m *MockQuoteStore) GetRandomQuote(ctx context.Context) (models.Quote, error) {
-	if m.GetRandomQuoteFunc != nil {
-		return m.GetRandomQuoteFunc(ctx)
-	}
-	return models.Quote{}, errors.New("GetRandomQuoteFunc not implemented")
-}
-
-func (m *MockQuoteStore) GetQuotesByAuthor(ctx context.Context, authorFilter string) ([]models.Quote, error) {
-	if m.GetQuotesByAuthorFunc != nil {
-		return m.GetQuotesByAuthorFunc(ctx, authorFilter)
-	}
-	return nil, errors.New("GetQuotesByAuthorFunc not implemented")
-}
-
-func (m *MockQuoteStore) DeleteQuote(ctx context.Context, id int64) error {
-	if m.DeleteQuoteFunc != nil {
-		return m.DeleteQuoteFunc(ctx, id)
-	}
-	return errors.New("DeleteQuoteFunc not implemented")
-}
+var testPageSizes = pagination.Sizes{Default: 100, Max: 1000}
 
 func TestAddQuoteHandler(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
@@ -71,69 +43,149 @@ func TestAddQuoteHandler(t *testing.T) {
 	tests := []struct {
 		name           string
 		reqBody        interface{}
-		mockStoreSetup func(*MockQuoteStore)
+		setup          func(*storagetest.FakeStore)
 		expectedStatus int
 		expectedBody   string
+		expectedStored *models.Quote
 	}{
 		{
-			name: "success",
-			reqBody: models.AddQuoteRequest{Text: "Test", Author: "Author"},
-			mockStoreSetup: func(ms *MockQuoteStore) {
-				ms.AddQuoteFunc = func(ctx context.Context, text, author string) (int64, error) {
-					return 1, nil
-				}
-			},
+			name:           "success",
+			reqBody:        models.AddQuoteRequest{Text: "Test", Author: "Author"},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"status":"success","id":1,"text":"Test","author":"Author","weight":1,"lang":"und","lang_detected":true}`,
+			expectedStored: &models.Quote{ID: 1, Text: "Test", Author: "Author", Weight: 1, Lang: "und", LangDetected: true, Version: 1},
+		},
+		{
+			name:           "success with weight",
+			reqBody:        map[string]interface{}{"text": "Test", "author": "Author", "weight": 5},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"status":"success","id":1,"text":"Test","author":"Author","weight":5,"lang":"und","lang_detected":true}`,
+			expectedStored: &models.Quote{ID: 1, Text: "Test", Author: "Author", Weight: 5, Lang: "und", LangDetected: true, Version: 1},
+		},
+		{
+			name:           "success with lang",
+			reqBody:        models.AddQuoteRequest{Text: "Test", Author: "Author", Lang: "EN-gb"},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"status":"success","id":1,"text":"Test","author":"Author","weight":1,"lang":"en-GB"}`,
+			expectedStored: &models.Quote{ID: 1, Text: "Test", Author: "Author", Weight: 1, Lang: "en-GB", Version: 1},
+		},
+		{
+			name:           "success with detected lang",
+			reqBody:        models.AddQuoteRequest{Text: "Красота спасёт мир.", Author: "Достоевский"},
 			expectedStatus: http.StatusCreated,
-			expectedBody:   `{"status":"success","id":1,"text":"Test","author":"Author"}`,
+			expectedBody:   `{"status":"success","id":1,"text":"Красота спасёт мир.","author":"Достоевский","weight":1,"lang":"ru","lang_detected":true}`,
+			expectedStored: &models.Quote{ID: 1, Text: "Красота спасёт мир.", Author: "Достоевский", Weight: 1, Lang: "ru", LangDetected: true, Version: 1},
+		},
+		{
+			name:           "success with source",
+			reqBody:        models.AddQuoteRequest{Text: "Test", Author: "Author", Lang: "en", Source: "Book", SourceURL: "https://example.com/book"},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"status":"success","id":1,"text":"Test","author":"Author","weight":1,"lang":"en","source":"Book","source_url":"https://example.com/book"}`,
+			expectedStored: &models.Quote{ID: 1, Text: "Test", Author: "Author", Weight: 1, Lang: "en", Source: "Book", SourceURL: "https://example.com/book", Version: 1},
+		},
+		{
+			name:           "validation error relative source url",
+			reqBody:        models.AddQuoteRequest{Text: "Test", Author: "Author", SourceURL: "/books/1"},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_request","error":"Invalid request.","fields":["source_url must be an absolute http(s) URL"]}`,
+		},
+		{
+			name:           "validation error non-http source url",
+			reqBody:        models.AddQuoteRequest{Text: "Test", Author: "Author", SourceURL: "ftp://example.com/book"},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_request","error":"Invalid request.","fields":["source_url must be an absolute http(s) URL"]}`,
+		},
+		{
+			name:           "validation error lang",
+			reqBody:        models.AddQuoteRequest{Text: "Test", Author: "Author", Lang: "klingon"},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_request","error":"Invalid request.","fields":["lang must be a known BCP-47 language code"]}`,
+		},
+		{
+			name:           "validation error weight",
+			reqBody:        map[string]interface{}{"text": "Test", "author": "Author", "weight": 0},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_request","error":"Invalid request.","fields":["weight must be between 1 and 100"]}`,
+		},
+		{
+			name:           "validation error author with newline",
+			reqBody:        models.AddQuoteRequest{Text: "Test", Author: "Mark\nTwain"},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_author_name","error":"Invalid author name.","fields":["author must not contain control characters, line breaks or direction overrides"]}`,
+		},
+		{
+			name:           "validation error author with right-to-left override",
+			reqBody:        models.AddQuoteRequest{Text: "Test", Author: "Mark \u202eniawT", Lang: "klingon"},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_author_name","error":"Invalid author name.","fields":["author must not contain control characters, line breaks or direction overrides","lang must be a known BCP-47 language code"]}`,
+		},
+		{
+			name:           "validation error punctuation author",
+			reqBody:        models.AddQuoteRequest{Text: "Test", Author: "?!"},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_author_name","error":"Invalid author name.","fields":["author must contain a letter or a digit"]}`,
 		},
 		{
 			name:           "empty body",
 			reqBody:        "",
-			mockStoreSetup: func(ms *MockQuoteStore) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"status":"error","error":"Request body is empty."}`,
+			expectedBody:   `{"status":"error","code":"request_body_empty","error":"Request body is empty."}`,
 		},
 		{
 			name:           "malformed json",
 			reqBody:        `{"text": "Test", "author": "Author"`,
-			mockStoreSetup: func(ms *MockQuoteStore) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"status":"error","error":"Failed to decode request body."}`,
+			expectedBody:   `{"status":"error","code":"request_body_invalid","error":"Failed to decode request body."}`,
+		},
+		{
+			name:           "whitespace body",
+			reqBody:        " \n\t",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"request_body_empty","error":"Request body is empty."}`,
+		},
+		{
+			name:           "data after the body is ignored",
+			reqBody:        `{"text": "Test", "author": "Author"} {"text":`,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"status":"success","id":1,"text":"Test","author":"Author","weight":1,"lang":"und","lang_detected":true}`,
+			expectedStored: &models.Quote{ID: 1, Text: "Test", Author: "Author", Weight: 1, Lang: "und", LangDetected: true, Version: 1},
+		},
+		{
+			name:           "wrong field type",
+			reqBody:        `{"text": "Test", "author": 42}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"request_body_invalid","error":"Failed to decode request body."}`,
 		},
 		{
 			name:           "validation error text",
 			reqBody:        models.AddQuoteRequest{Text: " ", Author: "Valid Author"},
-			mockStoreSetup: func(ms *MockQuoteStore) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"status":"error","error":"Invalid request.","fields":["text cannot be empty"]}`,
+			expectedBody:   `{"status":"error","code":"invalid_request","error":"Invalid request.","fields":["text cannot be empty"]}`,
 		},
 		{
 			name:           "validation error author",
 			reqBody:        models.AddQuoteRequest{Text: "Valid Text", Author: " "},
-			mockStoreSetup: func(ms *MockQuoteStore) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"status":"error","error":"Invalid request.","fields":["author cannot be empty"]}`,
+			expectedBody:   `{"status":"error","code":"invalid_request","error":"Invalid request.","fields":["author cannot be empty"]}`,
 		},
 		{
-			name: "storage error",
+			name:    "storage error",
 			reqBody: models.AddQuoteRequest{Text: "Test", Author: "Author"},
-			mockStoreSetup: func(ms *MockQuoteStore) {
-				ms.AddQuoteFunc = func(ctx context.Context, text, author string) (int64, error) {
-					return 0, errTestStorageInternal
-				}
+			setup: func(s *storagetest.FakeStore) {
+				s.Fail("AddQuote", errTestStorageInternal)
 			},
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   `{"status":"error","error":"Failed to add quote."}`,
+			expectedBody:   `{"status":"error","code":"add_quote_failed","error":"Failed to add quote."}`,
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			mockStore := &MockQuoteStore{}
-			if tc.mockStoreSetup != nil {
-				tc.mockStoreSetup(mockStore)
+			store := storagetest.New()
+			if tc.setup != nil {
+				tc.setup(store)
 			}
-			handler := quotehandler.NewAddQuoteHandler(logger, mockStore)
+			handler := quotehandler.NewAddQuoteHandler(logger, store)
 
 			var bodyReader io.Reader
 			if reqBodyStr, ok := tc.reqBody.(string); ok && reqBodyStr == "" && tc.name == "empty body" {
@@ -160,59 +212,120 @@ func TestAddQuoteHandler(t *testing.T) {
 			if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tc.expectedBody) {
 				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
 			}
+			if tc.expectedStatus == http.StatusBadRequest && len(store.Calls()) != 0 {
+				t.Errorf("expected an invalid request not to reach storage, got %+v", store.Calls())
+			}
+			if tc.expectedStored != nil {
+				stored, err := store.GetQuote(context.Background(), tc.expectedStored.ID)
+				if err != nil || !reflect.DeepEqual(stored, *tc.expectedStored) {
+					t.Errorf("expected %+v to be stored, got %+v, %v", *tc.expectedStored, stored, err)
+				}
+			}
 			quotehandler.ErrorsIs = originalErrorsIs
 		})
 	}
 }
 
+func TestAddQuoteHandlerLogsPreview(t *testing.T) {
+	text := strings.Repeat("Секрет. ", 40)
+	body, _ := json.Marshal(models.AddQuoteRequest{Text: text, Author: "Author", Lang: "ru"})
+	for _, level := range []slog.Level{slog.LevelInfo, slog.LevelDebug} {
+		t.Run(level.String(), func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: level}))
+			store := storagetest.New()
+
+			rr := httptest.NewRecorder()
+			quotehandler.NewAddQuoteHandler(logger, store).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/quotes", bytes.NewReader(body)))
+			if rr.Code != http.StatusCreated {
+				t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+			}
+
+			full := strings.Contains(logs.String(), strings.TrimSpace(text))
+			if full != (level == slog.LevelDebug) {
+				t.Fatalf("expected the full text in the logs only at debug level, got %s", logs.String())
+			}
+			if want := `"text":{"len":320,"preview":"Секрет. Секрет.`; !strings.Contains(logs.String(), want) {
+				t.Fatalf("expected the length and preview %s in the logs, got %s", want, logs.String())
+			}
+		})
+	}
+}
+
 func TestGetAllQuotesHandler(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
 
 	tests := []struct {
 		name           string
-		mockStoreSetup func(*MockQuoteStore)
+		query          string
+		quotes         []models.Quote
+		setup          func(*storagetest.FakeStore)
 		expectedStatus int
 		expectedBody   string
 	}{
 		{
-			name: "success empty",
-			mockStoreSetup: func(ms *MockQuoteStore) {
-				ms.GetAllQuotesFunc = func(ctx context.Context) ([]models.Quote, error) {
-					return []models.Quote{}, nil
-				}
-			},
+			name:           "success empty",
 			expectedStatus: http.StatusOK,
 			expectedBody:   `{"status":"success","data":[]}`,
 		},
 		{
-			name: "success non-empty",
-			mockStoreSetup: func(ms *MockQuoteStore) {
-				ms.GetAllQuotesFunc = func(ctx context.Context) ([]models.Quote, error) {
-					return []models.Quote{{ID: 1, Text: "Hello", Author: "World"}}, nil
-				}
-			},
+			name:           "success non-empty",
+			quotes:         []models.Quote{{ID: 1, Text: "Hello", Author: "World"}},
 			expectedStatus: http.StatusOK,
 			expectedBody:   `{"status":"success","data":[{"id":1,"text":"Hello","author":"World"}]}`,
 		},
 		{
-			name: "storage error",
-			mockStoreSetup: func(ms *MockQuoteStore) {
-				ms.GetAllQuotesFunc = func(ctx context.Context) ([]models.Quote, error) {
-					return nil, errTestStorageInternal
-				}
+			name:   "storage error",
+			quotes: []models.Quote{{ID: 1, Text: "Hello", Author: "World"}},
+			setup: func(s *storagetest.FakeStore) {
+				s.Fail("GetAllQuotes", errTestStorageInternal)
 			},
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   `{"status":"error","error":"Failed to retrieve quotes."}`,
+			expectedBody:   `{"status":"error","code":"get_quotes_failed","error":"Failed to retrieve quotes."}`,
+		},
+		{
+			name:  "success lang filter",
+			query: "?lang=RU",
+			quotes: []models.Quote{
+				{ID: 1, Text: "Привет", Author: "Мир", Lang: "ru"},
+				{ID: 2, Text: "Hello", Author: "World", Lang: "en"},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":[{"id":1,"text":"Привет","author":"Мир","lang":"ru"}]}`,
+		},
+		{
+			name:  "success has_source filter",
+			query: "?has_source=false",
+			quotes: []models.Quote{
+				{ID: 1, Text: "Sourced", Author: "A", Source: "Book"},
+				{ID: 2, Text: "Unsourced", Author: "B"},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":[{"id":2,"text":"Unsourced","author":"B"}]}`,
+		},
+		{
+			name:           "invalid has_source filter",
+			query:          "?has_source=sometimes",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_parameter","error":"Invalid has_source parameter."}`,
+		},
+		{
+			name:           "invalid lang filter",
+			query:          "?lang=zz",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_parameter","error":"Invalid lang parameter."}`,
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			mockStore := &MockQuoteStore{}
-			tc.mockStoreSetup(mockStore)
-			handler := quotehandler.NewGetAllQuotesHandler(logger, mockStore)
+			store := storagetest.New(tc.quotes...)
+			if tc.setup != nil {
+				tc.setup(store)
+			}
+			handler := quotehandler.NewGetAllQuotesHandler(logger, store, nil, testPageSizes)
 
-			req := httptest.NewRequest(http.MethodGet, "/quotes", nil)
+			req := httptest.NewRequest(http.MethodGet, "/quotes"+tc.query, nil)
 			rr := httptest.NewRecorder()
 			handler.ServeHTTP(rr, req.WithContext(context.Background()))
 
@@ -226,60 +339,159 @@ func TestGetAllQuotesHandler(t *testing.T) {
 	}
 }
 
+func TestGetAllQuotesHandlerLastModified(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	older := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	store := storagetest.New(
+		models.Quote{ID: 1, Text: "A", Author: "B", UpdatedAt: newer},
+		models.Quote{ID: 2, Text: "C", Author: "D", UpdatedAt: older},
+	)
+	handler := quotehandler.NewGetAllQuotesHandler(logger, store, nil, testPageSizes)
+
+	req := httptest.NewRequest(http.MethodGet, "/quotes", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if got := rr.Header().Get("Last-Modified"); got != "Sun, 10 Mar 2024 13:00:00 GMT" {
+		t.Fatalf("expected Last-Modified of the newest quote, got %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/quotes", nil)
+	req.Header.Set("If-Modified-Since", "Sun, 10 Mar 2024 12:30:00 GMT")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 when the newest quote is newer, got %d", rr.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/quotes", nil)
+	req.Header.Set("If-Modified-Since", "Sun, 10 Mar 2024 13:00:00 GMT")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotModified || rr.Body.Len() != 0 {
+		t.Fatalf("expected empty 304, got %d with body %q", rr.Code, rr.Body.String())
+	}
+}
+
+func TestGetQuoteHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	updated := time.Date(2024, time.March, 10, 12, 0, 0, 500, time.UTC)
+
+	tests := []struct {
+		name            string
+		path            string
+		ifModifiedSince string
+		expectedStatus  int
+		expectedBody    string
+	}{
+		{
+			name:           "success",
+			path:           "/quotes/1",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"id":1,"text":"Hello","author":"World","updated_at":"2024-03-10T12:00:00.0000005Z"}}`,
+		},
+		{
+			name:            "not modified",
+			path:            "/quotes/1",
+			ifModifiedSince: "Sun, 10 Mar 2024 12:00:00 GMT",
+			expectedStatus:  http.StatusNotModified,
+			expectedBody:    ``,
+		},
+		{
+			name:           "not found",
+			path:           "/quotes/2",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"status":"error","code":"quote_not_found","error":"Quote not found."}`,
+		},
+		{
+			name:           "invalid id",
+			path:           "/quotes/abc",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_quote_id","error":"Invalid quote ID format."}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := storagetest.New(models.Quote{ID: 1, Text: "Hello", Author: "World", UpdatedAt: updated})
+			router := mux.NewRouter()
+			router.HandleFunc("/quotes/{id}", quotehandler.NewGetQuoteHandler(logger, store)).Methods(http.MethodGet)
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.ifModifiedSince != "" {
+				req.Header.Set("If-Modified-Since", tc.ifModifiedSince)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tc.expectedBody) {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
 func TestGetRandomQuoteHandler(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	originalErrorsIs := quotehandler.ErrorsIs
-	defer func() { quotehandler.ErrorsIs = originalErrorsIs }()
 
 	tests := []struct {
-		name           string
-		mockStoreSetup func(*MockQuoteStore)
-		expectedStatus int
-		expectedBody   string
+		name               string
+		query              string
+		quotes             []models.Quote
+		setup              func(*storagetest.FakeStore)
+		expectedStatus     int
+		expectedBody       string
+		expectedUnweighted bool
 	}{
 		{
-			name: "success",
-			mockStoreSetup: func(ms *MockQuoteStore) {
-				ms.GetRandomQuoteFunc = func(ctx context.Context) (models.Quote, error) {
-					return models.Quote{ID: 42, Text: "Be random", Author: "Universe"}, nil
-				}
-			},
+			name:           "success",
+			quotes:         []models.Quote{{ID: 42, Text: "Be random", Author: "Universe"}},
 			expectedStatus: http.StatusOK,
 			expectedBody:   `{"status":"success","data":{"id":42,"text":"Be random","author":"Universe"}}`,
 		},
 		{
-			name: "quote not found",
-			mockStoreSetup: func(ms *MockQuoteStore) {
-				ms.GetRandomQuoteFunc = func(ctx context.Context) (models.Quote, error) {
-					return models.Quote{}, errTestQuoteNotFound
-				}
-				quotehandler.ErrorsIs = func(err, target error) bool {
-					return err == errTestQuoteNotFound
-				}
-			},
+			name:               "success unweighted",
+			query:              "?unweighted=true",
+			quotes:             []models.Quote{{ID: 1, Text: "Fair", Author: "Dice"}},
+			expectedStatus:     http.StatusOK,
+			expectedBody:       `{"status":"success","data":{"id":1,"text":"Fair","author":"Dice"}}`,
+			expectedUnweighted: true,
+		},
+		{
+			name:           "invalid unweighted",
+			query:          "?unweighted=maybe",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_unweighted","error":"Unweighted must be a boolean."}`,
+		},
+		{
+			name:           "quote not found",
 			expectedStatus: http.StatusNotFound,
-			expectedBody:   `{"status":"error","error":"No quotes found."}`,
+			expectedBody:   `{"status":"error","code":"no_quotes","error":"No quotes found."}`,
 		},
 		{
-			name: "storage error",
-			mockStoreSetup: func(ms *MockQuoteStore) {
-				ms.GetRandomQuoteFunc = func(ctx context.Context) (models.Quote, error) {
-					return models.Quote{}, errTestStorageInternal
-				}
-				quotehandler.ErrorsIs = errors.Is
+			name:   "storage error",
+			quotes: []models.Quote{{ID: 1, Text: "Fair", Author: "Dice"}},
+			setup: func(s *storagetest.FakeStore) {
+				s.Fail("GetRandomQuote", errTestStorageInternal)
 			},
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   `{"status":"error","error":"Failed to retrieve random quote."}`,
+			expectedBody:   `{"status":"error","code":"get_random_failed","error":"Failed to retrieve random quote."}`,
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			mockStore := &MockQuoteStore{}
-			tc.mockStoreSetup(mockStore)
+			store := storagetest.New(tc.quotes...)
+			if tc.setup != nil {
+				tc.setup(store)
+			}
 
-			handler := quotehandler.NewGetRandomQuoteHandler(logger, mockStore)
-			req := httptest.NewRequest(http.MethodGet, "/quotes/random", nil)
+			handler := quotehandler.NewGetRandomQuoteHandler(logger, store, nil, nil, nil, nil)
+			req := httptest.NewRequest(http.MethodGet, "/quotes/random"+tc.query, nil)
 			rr := httptest.NewRecorder()
 			handler.ServeHTTP(rr, req.WithContext(context.Background()))
 
@@ -289,7 +501,11 @@ func TestGetRandomQuoteHandler(t *testing.T) {
 			if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tc.expectedBody) {
 				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
 			}
-			quotehandler.ErrorsIs = originalErrorsIs
+			if calls := store.Calls("GetRandomQuote"); len(calls) == 1 {
+				if opts := calls[0].Args[0].(storage.RandomOptions); opts.Unweighted != tc.expectedUnweighted {
+					t.Errorf("expected unweighted %v, got %+v", tc.expectedUnweighted, opts)
+				}
+			}
 		})
 	}
 }
@@ -300,60 +516,49 @@ func TestGetQuotesByAuthorHandler(t *testing.T) {
 	tests := []struct {
 		name           string
 		authorQuery    string
-		mockStoreSetup func(*MockQuoteStore)
+		setup          func(*storagetest.FakeStore)
 		expectedStatus int
 		expectedBody   string
 	}{
 		{
-			name:        "success found",
-			authorQuery: "KnownAuthor",
-			mockStoreSetup: func(ms *MockQuoteStore) {
-				ms.GetQuotesByAuthorFunc = func(ctx context.Context, author string) ([]models.Quote, error) {
-					if author == "KnownAuthor" {
-						return []models.Quote{{ID: 7, Text: "A quote", Author: "KnownAuthor"}}, nil
-					}
-					return []models.Quote{}, nil
-				}
-			},
+			name:           "success found",
+			authorQuery:    "KnownAuthor",
 			expectedStatus: http.StatusOK,
 			expectedBody:   `{"status":"success","data":[{"id":7,"text":"A quote","author":"KnownAuthor"}]}`,
 		},
 		{
-			name:        "success not found",
-			authorQuery: "UnknownAuthor",
-			mockStoreSetup: func(ms *MockQuoteStore) {
-				ms.GetQuotesByAuthorFunc = func(ctx context.Context, author string) ([]models.Quote, error) {
-					return []models.Quote{}, nil
-				}
-			},
+			name:           "success not found",
+			authorQuery:    "UnknownAuthor",
 			expectedStatus: http.StatusOK,
 			expectedBody:   `{"status":"success","data":[]}`,
 		},
 		{
 			name:           "missing author query",
 			authorQuery:    "",
-			mockStoreSetup: func(ms *MockQuoteStore) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"status":"error","error":"Author query parameter is required."}`,
+			expectedBody:   `{"status":"error","code":"author_required","error":"Author query parameter is required."}`,
 		},
 		{
 			name:        "storage error",
 			authorQuery: "AnyAuthor",
-			mockStoreSetup: func(ms *MockQuoteStore) {
-				ms.GetQuotesByAuthorFunc = func(ctx context.Context, author string) ([]models.Quote, error) {
-					return nil, errTestStorageInternal
-				}
+			setup: func(s *storagetest.FakeStore) {
+				s.Fail("GetQuotesByAuthor", errTestStorageInternal)
 			},
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   `{"status":"error","error":"Failed to retrieve quotes by author."}`,
+			expectedBody:   `{"status":"error","code":"get_author_quotes_failed","error":"Failed to retrieve quotes by author."}`,
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			mockStore := &MockQuoteStore{}
-			tc.mockStoreSetup(mockStore)
-			handler := quotehandler.NewGetQuotesByAuthorHandler(logger, mockStore)
+			store := storagetest.New(
+				models.Quote{ID: 7, Text: "A quote", Author: "KnownAuthor"},
+				models.Quote{ID: 8, Text: "Another quote", Author: "OtherAuthor"},
+			)
+			if tc.setup != nil {
+				tc.setup(store)
+			}
+			handler := quotehandler.NewGetQuotesByAuthorHandler(logger, store, testPageSizes)
 
 			req := httptest.NewRequest(http.MethodGet, "/quotes/search?author="+tc.authorQuery, nil)
 			rr := httptest.NewRecorder()
@@ -369,79 +574,227 @@ func TestGetQuotesByAuthorHandler(t *testing.T) {
 	}
 }
 
+func TestUpdateQuoteHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		ifMatch        string
+		quotes         []models.Quote
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "patch success",
+			method:         http.MethodPatch,
+			body:           `{"author":"New Author"}`,
+			quotes:         []models.Quote{{ID: 1, Text: "Old", Author: "Old Author", Version: 1}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"id":1,"text":"Old","author":"New Author","version":2}}`,
+		},
+		{
+			name:           "patch empty",
+			method:         http.MethodPatch,
+			body:           `{}`,
+			quotes:         []models.Quote{{ID: 1, Text: "Old", Author: "Old Author", Version: 1}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_request","error":"Invalid request.","fields":["at least one field must be set"]}`,
+		},
+		{
+			name:           "put missing author",
+			method:         http.MethodPut,
+			body:           `{"text":"Only text"}`,
+			quotes:         []models.Quote{{ID: 1, Text: "Old", Author: "Old Author", Version: 1}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_request","error":"Invalid request.","fields":["author cannot be empty"]}`,
+		},
+		{
+			name:           "patch author with newline",
+			method:         http.MethodPatch,
+			body:           `{"author":"Mark\nTwain"}`,
+			quotes:         []models.Quote{{ID: 1, Text: "Old", Author: "Old Author", Version: 1}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_author_name","error":"Invalid author name.","fields":["author must not contain control characters, line breaks or direction overrides"]}`,
+		},
+		{
+			name:           "put author with right-to-left override",
+			method:         http.MethodPut,
+			body:           `{"text":"New","author":"Mark \u202eniawT"}`,
+			quotes:         []models.Quote{{ID: 1, Text: "Old", Author: "Old Author", Version: 1}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_author_name","error":"Invalid author name.","fields":["author must not contain control characters, line breaks or direction overrides"]}`,
+		},
+		{
+			name:   "put resets optional fields",
+			method: http.MethodPut,
+			body:   `{"text":"The quick brown fox jumps over the lazy dog","author":"A"}`,
+			quotes: []models.Quote{{
+				ID: 1, Text: "Old", Author: "Old Author", Weight: 7, Lang: "ru",
+				Source: "Book", SourceURL: "https://example.com/book", Version: 2,
+			}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"id":1,"text":"The quick brown fox jumps over the lazy dog","author":"A","weight":1,"lang":"en","lang_detected":true,"version":3}}`,
+		},
+		{
+			name:           "version mismatch",
+			method:         http.MethodPatch,
+			body:           `{"text":"New"}`,
+			ifMatch:        `"4"`,
+			quotes:         []models.Quote{{ID: 1, Text: "Old", Author: "Old Author", Version: 1}},
+			expectedStatus: http.StatusPreconditionFailed,
+			expectedBody:   `{"status":"error","code":"version_mismatch","error":"Quote was modified by another request."}`,
+		},
+		{
+			name:           "not found",
+			method:         http.MethodPatch,
+			body:           `{"text":"New"}`,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"status":"error","code":"quote_not_found","error":"Quote not found."}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := storagetest.New(tc.quotes...)
+			router := mux.NewRouter()
+			router.HandleFunc("/quotes/{id}", quotehandler.NewReplaceQuoteHandler(logger, store)).Methods(http.MethodPut)
+			router.HandleFunc("/quotes/{id}", quotehandler.NewPatchQuoteHandler(logger, store)).Methods(http.MethodPatch)
+
+			req := httptest.NewRequest(tc.method, "/quotes/1", strings.NewReader(tc.body))
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tc.expectedBody) {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
+			}
+			if tc.expectedStatus == http.StatusBadRequest && store.Count("UpdateQuote") != 0 {
+				t.Errorf("expected an invalid request not to reach storage, got %+v", store.Calls())
+			}
+		})
+	}
+}
+
+// TestIfMatchLostUpdate replays two clients that read the same quote and
+// then write in turn: the second write must not clobber the first.
+func TestIfMatchLostUpdate(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	id, err := store.AddQuote(context.Background(), models.Quote{Text: "Original", Author: "A"})
+	if err != nil {
+		t.Fatalf("failed to add quote: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/quotes/{id}", quotehandler.NewGetQuoteHandler(logger, store)).Methods(http.MethodGet)
+	router.HandleFunc("/quotes/{id}", quotehandler.NewPatchQuoteHandler(logger, store)).Methods(http.MethodPatch)
+	router.HandleFunc("/quotes/{id}", quotehandler.NewDeleteQuoteHandler(logger, store)).Methods(http.MethodDelete)
+
+	do := func(method, body, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, fmt.Sprintf("/quotes/%d", id), strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	etagA := do(http.MethodGet, "", "").Header().Get("ETag")
+	etagB := do(http.MethodGet, "", "").Header().Get("ETag")
+	if etagA == "" || etagA != etagB {
+		t.Fatalf("expected equal ETags for both readers, got %q and %q", etagA, etagB)
+	}
+
+	rr := do(http.MethodPatch, `{"text":"Edited by A"}`, etagA)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("client A update: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("ETag") == etagA {
+		t.Fatalf("expected ETag to change after update")
+	}
+
+	if rr := do(http.MethodPatch, `{"text":"Edited by B"}`, etagB); rr.Code != http.StatusPreconditionFailed {
+		t.Fatalf("client B update: expected 412, got %d", rr.Code)
+	}
+	if rr := do(http.MethodDelete, "", etagB); rr.Code != http.StatusPreconditionFailed {
+		t.Fatalf("client B delete: expected 412, got %d", rr.Code)
+	}
+
+	quote, err := store.GetQuote(context.Background(), id)
+	if err != nil || quote.Text != "Edited by A" {
+		t.Fatalf("expected client A's edit to survive, got %+v, %v", quote, err)
+	}
+
+	if rr := do(http.MethodDelete, "", ""); rr.Code != http.StatusOK {
+		t.Fatalf("unconditional delete: expected 200, got %d", rr.Code)
+	}
+}
+
 func TestDeleteQuoteHandler(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	originalErrorsIs := quotehandler.ErrorsIs
-	defer func() { quotehandler.ErrorsIs = originalErrorsIs }()
 
 	tests := []struct {
 		name           string
 		quoteID        string
-		mockStoreSetup func(*MockQuoteStore)
+		setup          func(*storagetest.FakeStore)
 		expectedStatus int
 		expectedBody   string
 	}{
 		{
-			name:    "success",
-			quoteID: "1",
-			mockStoreSetup: func(ms *MockQuoteStore) {
-				ms.DeleteQuoteFunc = func(ctx context.Context, id int64) error {
-					if id == 1 {
-						return nil
-					}
-					return errors.New("unexpected id for delete")
-				}
-			},
+			name:           "success",
+			quoteID:        "1",
 			expectedStatus: http.StatusOK,
 			expectedBody:   `{"status":"success","message":"Quote deleted successfully."}`,
 		},
 		{
 			name:           "id not in path",
 			quoteID:        "",
-			mockStoreSetup: func(ms *MockQuoteStore) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"status":"error","error":"Quote ID is missing in path."}`,
+			expectedBody:   `{"status":"error","code":"quote_id_missing","error":"Quote ID is missing in path."}`,
 		},
 		{
 			name:           "invalid id format",
 			quoteID:        "abc",
-			mockStoreSetup: func(ms *MockQuoteStore) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"status":"error","error":"Invalid quote ID format."}`,
+			expectedBody:   `{"status":"error","code":"invalid_quote_id","error":"Invalid quote ID format."}`,
 		},
 		{
-			name:    "quote not found",
-			quoteID: "999",
-			mockStoreSetup: func(ms *MockQuoteStore) {
-				ms.DeleteQuoteFunc = func(ctx context.Context, id int64) error {
-					return errTestQuoteNotFound
-				}
-				quotehandler.ErrorsIs = func(err, target error) bool { return err == errTestQuoteNotFound }
-			},
+			name:           "quote not found",
+			quoteID:        "999",
 			expectedStatus: http.StatusNotFound,
-			expectedBody:   `{"status":"error","error":"Quote not found."}`,
+			expectedBody:   `{"status":"error","code":"quote_not_found","error":"Quote not found."}`,
 		},
 		{
 			name:    "storage error",
 			quoteID: "777",
-			mockStoreSetup: func(ms *MockQuoteStore) {
-				ms.DeleteQuoteFunc = func(ctx context.Context, id int64) error {
-					return errTestStorageInternal
-				}
-				quotehandler.ErrorsIs = errors.Is
+			setup: func(s *storagetest.FakeStore) {
+				s.Fail("DeleteQuote", errTestStorageInternal)
 			},
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   `{"status":"error","error":"Failed to delete quote."}`,
+			expectedBody:   `{"status":"error","code":"delete_quote_failed","error":"Failed to delete quote."}`,
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			mockStore := &MockQuoteStore{}
-			tc.mockStoreSetup(mockStore)
+			store := storagetest.New(models.Quote{ID: 1, Text: "Doomed", Author: "Author"})
+			if tc.setup != nil {
+				tc.setup(store)
+			}
 
 			router := mux.NewRouter()
-			handlerFunc := quotehandler.NewDeleteQuoteHandler(logger, mockStore)
+			handlerFunc := quotehandler.NewDeleteQuoteHandler(logger, store)
 
 			var reqPath string
 			if tc.name == "id not in path" {
@@ -452,7 +805,6 @@ func TestDeleteQuoteHandler(t *testing.T) {
 				reqPath = "/quotes/" + tc.quoteID
 			}
 
-
 			req := httptest.NewRequest(http.MethodDelete, reqPath, nil)
 			rr := httptest.NewRecorder()
 
@@ -464,7 +816,595 @@ func TestDeleteQuoteHandler(t *testing.T) {
 			if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tc.expectedBody) {
 				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
 			}
-			quotehandler.ErrorsIs = originalErrorsIs
+			if _, err := store.GetQuote(context.Background(), 1); errors.Is(err, storage.ErrQuoteNotFound) != (tc.expectedStatus == http.StatusOK) {
+				t.Errorf("expected quote 1 to be deleted only on success, got %v", err)
+			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestGetPopularQuotesHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name           string
+		limitQuery     string
+		quotes         []models.Quote
+		setup          func(*storagetest.FakeStore)
+		expectedStatus int
+		expectedBody   string
+		expectedLimit  int
+	}{
+		{
+			name:       "success default limit",
+			limitQuery: "",
+			quotes:     []models.Quote{{ID: 3, Text: "Top", Author: "Star"}},
+			setup: func(s *storagetest.FakeStore) {
+				for i := 0; i < 5; i++ {
+					s.IncrementServed(context.Background(), 3)
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":[{"id":3,"text":"Top","author":"Star","served":5}]}`,
+			expectedLimit:  10,
+		},
+		{
+			name:           "limit is capped",
+			limitQuery:     "1000",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":[]}`,
+			expectedLimit:  100,
+		},
+		{
+			name:           "invalid limit",
+			limitQuery:     "abc",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_limit","error":"Limit must be a positive integer."}`,
+		},
+		{
+			name:       "storage error",
+			limitQuery: "5",
+			setup: func(s *storagetest.FakeStore) {
+				s.Fail("GetPopularQuotes", errTestStorageInternal)
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"status":"error","code":"get_popular_failed","error":"Failed to retrieve popular quotes."}`,
+			expectedLimit:  5,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := storagetest.New(tc.quotes...)
+			if tc.setup != nil {
+				tc.setup(store)
+			}
+			handler := quotehandler.NewGetPopularQuotesHandler(logger, store)
+
+			req := httptest.NewRequest(http.MethodGet, "/quotes/popular?limit="+tc.limitQuery, nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req.WithContext(context.Background()))
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tc.expectedBody) {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
+			}
+			var limits []any
+			for _, call := range store.Calls("GetPopularQuotes") {
+				limits = append(limits, call.Args[0])
+			}
+			if tc.expectedLimit == 0 && len(limits) != 0 || tc.expectedLimit != 0 && !reflect.DeepEqual(limits, []any{tc.expectedLimit}) {
+				t.Errorf("expected limit %d, got calls with %v", tc.expectedLimit, limits)
+			}
+		})
+	}
+}
+
+func TestGetRandomQuoteHandlerServedCounter(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	if _, err := store.AddQuote(ctx, models.Quote{Text: "Only one", Author: "Single"}); err != nil {
+		t.Fatalf("failed to add quote: %v", err)
+	}
+
+	handler := quotehandler.NewGetRandomQuoteHandler(logger, store, nil, nil, nil, nil)
+
+	const requests = 200
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/quotes/random", nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+			}
+		}()
+	}
+	wg.Wait()
+
+	served := func() int64 {
+		popular, err := store.GetPopularQuotes(ctx, 1)
+		if err != nil || len(popular) != 1 {
+			t.Fatalf("failed to get popular quotes: %v", err)
+		}
+		return popular[0].Served
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for served() < requests && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if got := served(); got != requests {
+		t.Errorf("expected served counter %d, got %d", requests, got)
+	}
+}
+
+func TestGetQuoteHandlerServedCounter(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for _, text := range []string{"one", "two"} {
+		if _, err := store.AddQuote(ctx, models.Quote{Text: text, Author: "Author"}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/quotes/{id}", quotehandler.NewGetQuoteHandler(logger, store)).Methods(http.MethodGet)
+	for _, path := range []string{"/quotes/2", "/quotes/2", "/quotes/2", "/quotes/9"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	served := func() map[int64]int64 {
+		popular, err := store.GetPopularQuotes(ctx, 2)
+		if err != nil {
+			t.Fatalf("failed to get popular quotes: %v", err)
+		}
+		counts := make(map[int64]int64)
+		for _, p := range popular {
+			counts[p.ID] = p.Served
+		}
+		return counts
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for served()[2] < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if got := served(); got[2] != 3 || got[1] != 0 {
+		t.Errorf("expected quote 2 served 3 times and quote 1 never, got %v", got)
+	}
+}
+
+func TestGetRandomQuoteHandlerNoRepeat(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for _, text := range []string{"one", "two", "three"} {
+		if _, err := store.AddQuote(ctx, models.Quote{Text: text, Author: "Author"}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+
+	history := clienthistory.New(2, time.Hour, 10)
+	handler := quotehandler.NewGetRandomQuoteHandler(logger, store, history, nil, nil, nil)
+
+	serve := func(setup func(*http.Request)) (*httptest.ResponseRecorder, int64) {
+		req := httptest.NewRequest(http.MethodGet, "/quotes/random", nil)
+		setup(req)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+		}
+		var resp struct {
+			Data models.Quote `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return rr, resp.Data.ID
+	}
+
+	withHeader := func(req *http.Request) { req.Header.Set(quotehandler.ClientIDHeader, "client-1") }
+	var previous []int64
+	for i := 0; i < 50; i++ {
+		_, id := serve(withHeader)
+		for _, prev := range previous {
+			if id == prev {
+				t.Fatalf("quote %d repeated within window, previous %v", id, previous)
+			}
+		}
+		previous = append(previous, id)
+		if len(previous) > 2 {
+			previous = previous[1:]
+		}
+	}
+
+	rr, _ := serve(func(req *http.Request) {})
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != quotehandler.ClientIDCookie {
+		t.Fatalf("expected client cookie to be issued, got %v", cookies)
+	}
+
+	_, first := serve(func(req *http.Request) { req.AddCookie(cookies[0]) })
+	_, second := serve(func(req *http.Request) { req.AddCookie(cookies[0]) })
+	if first == second {
+		t.Errorf("expected cookie-identified client to get different quotes, got %d twice", first)
+	}
+}
+
+func TestGetSimilarQuotesHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name           string
+		path           string
+		setup          func(*storagetest.FakeStore)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "success",
+			path:           "/quotes/1/similar?limit=2",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":[{"id":2,"text":"Alike","author":"Other","score":0.5}]}`,
+		},
+		{
+			name:           "no similar quotes",
+			path:           "/quotes/3/similar",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":[]}`,
+		},
+		{
+			name:           "invalid limit",
+			path:           "/quotes/1/similar?limit=-1",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_limit","error":"Limit must be a positive integer."}`,
+		},
+		{
+			name:           "quote not found",
+			path:           "/quotes/9/similar",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"status":"error","code":"quote_not_found","error":"Quote not found."}`,
+		},
+		{
+			name: "storage error",
+			path: "/quotes/1/similar",
+			setup: func(s *storagetest.FakeStore) {
+				s.Fail("GetSimilarQuotes", errTestStorageInternal)
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"status":"error","code":"get_similar_failed","error":"Failed to retrieve similar quotes."}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := storagetest.New(
+				models.Quote{ID: 1, Text: "Alike ones", Author: "Author"},
+				models.Quote{ID: 2, Text: "Alike", Author: "Other"},
+				models.Quote{ID: 3, Text: "Nothing shared", Author: "Loner"},
+			)
+			if tc.setup != nil {
+				tc.setup(store)
+			}
+
+			router := mux.NewRouter()
+			router.HandleFunc("/quotes/{id}/similar", quotehandler.NewGetSimilarQuotesHandler(logger, store)).Methods(http.MethodGet)
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req.WithContext(context.Background()))
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tc.expectedBody) {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetTextStatsHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name           string
+		setup          func(*storagetest.FakeStore)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "success",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"total_quotes":2,"total_words":3,"average_length":5.5,"longest":{"id":1,"length":9},"shortest":{"id":2,"length":2},"top_words":[{"word":"love","count":2},{"word":"hi","count":1}]}}`,
+		},
+		{
+			name: "storage error",
+			setup: func(s *storagetest.FakeStore) {
+				s.Fail("Version", errTestStorageInternal)
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"status":"error","code":"text_stats_failed","error":"Failed to compute text statistics."}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := storagetest.New(
+				models.Quote{ID: 1, Text: "Love love", Author: "A"},
+				models.Quote{ID: 2, Text: "Hi", Author: "B"},
+			)
+			if tc.setup != nil {
+				tc.setup(store)
+			}
+			handler := quotehandler.NewGetTextStatsHandler(logger, store, textstats.New(textstats.DefaultStopwords, 10))
+
+			req := httptest.NewRequest(http.MethodGet, "/stats/text", nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req.WithContext(context.Background()))
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tc.expectedBody) {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetAllQuotesHandlerCache(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	store := storagetest.New(models.Quote{ID: 1, Text: "A", Author: "B", Lang: "en"})
+	reads := func() int { return store.Count("GetAllQuotes") }
+	handler := quotehandler.NewGetAllQuotesHandler(logger, store, jsoncache.New(1024), testPageSizes)
+	get := func(query string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/quotes"+query, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	first := get("")
+	second := get("")
+	if reads() != 1 {
+		t.Fatalf("expected one storage read for two requests, got %d", reads())
+	}
+	if first.Body.String() != second.Body.String() {
+		t.Fatalf("cached body differs: %q vs %q", first.Body.String(), second.Body.String())
+	}
+	if want := `{"status":"success","data":[{"id":1,"text":"A","author":"B","lang":"en"}]}` + "\n"; second.Body.String() != want {
+		t.Fatalf("expected body %q, got %q", want, second.Body.String())
+	}
+	if got := second.Header().Get("ETag"); got != `"1"` {
+		t.Fatalf("expected ETag \"1\", got %q", got)
+	}
+	if got := second.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("expected JSON content type, got %q", got)
+	}
+
+	if rr := get("", "If-None-Match", `"1"`); rr.Code != http.StatusNotModified || rr.Body.Len() != 0 {
+		t.Fatalf("expected an empty 304 for a matching ETag, got %d %q", rr.Code, rr.Body.String())
+	}
+
+	if _, err := store.UpdateQuote(context.Background(), 1, storage.QuoteUpdate{}, storage.AnyVersion); err != nil {
+		t.Fatalf("failed to update quote: %v", err)
+	}
+	if rr := get("", "If-None-Match", `"1"`); rr.Code != http.StatusOK || rr.Header().Get("ETag") != `"2"` {
+		t.Fatalf("expected a fresh 200 after a mutation, got %d with ETag %q", rr.Code, rr.Header().Get("ETag"))
+	}
+	if reads() != 2 {
+		t.Fatalf("expected the mutation to invalidate the cache, got %d reads", reads())
+	}
+
+	get("?lang=en")
+	get("?lang=en")
+	if reads() != 4 {
+		t.Fatalf("expected filtered lists to bypass the cache, got %d reads", reads())
+	}
+
+	tiny := quotehandler.NewGetAllQuotesHandler(logger, store, jsoncache.New(10), testPageSizes)
+	for i := 0; i < 2; i++ {
+		tiny.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/quotes", nil))
+	}
+	if reads() != 6 {
+		t.Fatalf("expected payloads over the limit not to be cached, got %d reads", reads())
+	}
+}
+
+func TestGetAllQuotesHandlerPageSizes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := store.AddQuote(ctx, models.Quote{Text: "Quote " + strconv.Itoa(i), Author: "A"}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+	sizes := pagination.Sizes{Default: 2, Max: 3}
+
+	tests := []struct {
+		name            string
+		handler         http.HandlerFunc
+		query           string
+		expectedStatus  int
+		expectedIDs     []int64
+		expectedClamped string
+		expectedNext    string
+	}{
+		{
+			name:           "default size",
+			handler:        quotehandler.NewGetAllQuotesHandler(logger, store, nil, sizes),
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int64{1, 2},
+			expectedNext:   `</quotes?limit=2&offset=2>; rel="next"`,
+		},
+		{
+			name:           "default size from the cache",
+			handler:        quotehandler.NewGetAllQuotesHandler(logger, store, jsoncache.New(1<<20), sizes),
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int64{1, 2},
+			expectedNext:   `</quotes?limit=2&offset=2>; rel="next"`,
+		},
+		{
+			name:            "clamped",
+			handler:         quotehandler.NewGetAllQuotesHandler(logger, store, nil, sizes),
+			query:           "?limit=100000",
+			expectedStatus:  http.StatusOK,
+			expectedIDs:     []int64{1, 2, 3},
+			expectedClamped: "true",
+			expectedNext:    `</quotes?limit=3&offset=3>; rel="next"`,
+		},
+		{
+			name:           "offset",
+			handler:        quotehandler.NewGetAllQuotesHandler(logger, store, nil, sizes),
+			query:          "?limit=3&offset=3",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int64{4, 5},
+		},
+		{
+			name:            "by author",
+			handler:         quotehandler.NewGetQuotesByAuthorHandler(logger, store, sizes),
+			query:           "?author=A&limit=9&offset=1",
+			expectedStatus:  http.StatusOK,
+			expectedIDs:     []int64{2, 3, 4},
+			expectedClamped: "true",
+			expectedNext:    `</quotes?author=A&limit=3&offset=4>; rel="next"`,
+		},
+		{
+			name:           "zero limit",
+			handler:        quotehandler.NewGetAllQuotesHandler(logger, store, nil, sizes),
+			query:          "?limit=0",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "negative offset",
+			handler:        quotehandler.NewGetQuotesByAuthorHandler(logger, store, sizes),
+			query:          "?author=A&offset=-2",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			tc.handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/quotes"+tc.query, nil))
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Data []models.Quote `json:"data"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode quotes: %v", err)
+			}
+			ids := make([]int64, len(resp.Data))
+			for i, q := range resp.Data {
+				ids[i] = q.ID
+			}
+			if !slices.Equal(ids, tc.expectedIDs) {
+				t.Errorf("expected quotes %v, got %v", tc.expectedIDs, ids)
+			}
+			if got := rr.Header().Get(pagination.ClampedHeader); got != tc.expectedClamped {
+				t.Errorf("expected %s %q, got %q", pagination.ClampedHeader, tc.expectedClamped, got)
+			}
+			if got := rr.Header().Get(pagination.TotalCountHeader); got != "5" {
+				t.Errorf("expected a total count of 5, got %q", got)
+			}
+			link := rr.Header().Get("Link")
+			if tc.expectedNext != "" && !strings.Contains(link, tc.expectedNext) {
+				t.Errorf("expected Link to contain %s, got %s", tc.expectedNext, link)
+			}
+			if tc.expectedNext == "" && strings.Contains(link, `rel="next"`) {
+				t.Errorf("expected no next link on the last page, got %s", link)
+			}
+		})
+	}
+}
+
+func TestWithQuoteID(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+	store, err := memorystorage.New(memorystorage.WithPublicIDs(publicid.FormatULID.New))
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	id, err := store.AddQuote(ctx, models.Quote{Text: "Hello", Author: "World"})
+	if err != nil {
+		t.Fatalf("failed to add quote: %v", err)
+	}
+	quote, _ := store.GetQuote(ctx, id)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{name: "numeric id", path: fmt.Sprintf("/quotes/%d", id), expectedStatus: http.StatusOK},
+		{name: "public id", path: "/quotes/" + quote.PublicID, expectedStatus: http.StatusOK},
+		{name: "lower case public id", path: "/quotes/" + strings.ToLower(quote.PublicID), expectedStatus: http.StatusOK},
+		{name: "unknown public id", path: "/quotes/01ARZ3NDEKTSV4RRFFQ69G5FAV", expectedStatus: http.StatusNotFound},
+		{name: "invalid id", path: "/quotes/abc", expectedStatus: http.StatusBadRequest},
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/quotes/{id}", quotehandler.WithQuoteID(logger, store, "id", quotehandler.NewGetQuoteHandler(logger, store))).Methods(http.MethodGet)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Data models.Quote `json:"data"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Data.ID != id || resp.Data.PublicID != quote.PublicID {
+				t.Errorf("expected quote %d (%s), got %+v", id, quote.PublicID, resp.Data)
+			}
+		})
+	}
+}
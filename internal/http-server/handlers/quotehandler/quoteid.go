@@ -0,0 +1,54 @@
+package quotehandler
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"quotes-service/internal/http-server/apierror"
+	mwAudit "quotes-service/internal/http-server/middleware/audit"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/publicid"
+	"quotes-service/internal/models"
+)
+
+// PublicIDResolver looks quotes up by their public ID.
+type PublicIDResolver interface {
+	GetQuoteByPublicID(ctx context.Context, publicID string) (models.Quote, error)
+}
+
+// WithQuoteID lets a handler that reads the route variable name as a
+// numeric quote ID be addressed by public ID too. A UUID or ULID is resolved
+// to its quote and the variable rewritten to the quote's ID before next
+// runs, and reported to the audit middleware; anything else is passed
+// through unchanged.
+func WithQuoteID(logger *slog.Logger, resolver PublicIDResolver, name string, next http.HandlerFunc) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.quote.WithQuoteID"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		vars := mux.Vars(r)
+		raw := vars[name]
+		if !publicid.Valid(raw) {
+			next(w, r)
+			return nil
+		}
+
+		quote, err := resolver.GetQuoteByPublicID(ctx, raw)
+		if err != nil {
+			log.Log(ctx, apierror.Level(err), "failed to resolve public ID", slog.String("public_id", raw), slog.String("error", err.Error()))
+			return apierror.Failed(apierror.CodeGetQuoteFailed, err)
+		}
+
+		mwAudit.Quote(w, quote.ID)
+		resolved := maps.Clone(vars)
+		resolved[name] = strconv.FormatInt(quote.ID, 10)
+		next(w, mux.SetURLVars(r, resolved))
+		return nil
+	})
+}
@@ -0,0 +1,65 @@
+package quotehandler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/contentfilter"
+	"quotes-service/internal/lib/moderation"
+	"quotes-service/internal/lib/quoteinput"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// screen runs text through the content filter before entry is stored. A
+// rejected quote fails with 422 and a held one is answered with 202 and the
+// held quote; screen reports whether the handler should go on and store it.
+func screen(w http.ResponseWriter, r *http.Request, log *slog.Logger, text string, entry moderation.Entry) (bool, error) {
+	ctx := r.Context()
+	action, rule := quoteinput.Screen(text, entry.Source)
+	switch action {
+	case contentfilter.ActionReject:
+		log.WarnContext(ctx, "quote rejected by the content filter", slog.Int("rule", rule))
+		return false, apierror.New(http.StatusUnprocessableEntity, apierror.CodeContentRejected, nil)
+	case contentfilter.ActionHold:
+		entry.Rule = rule
+		held, err := quoteinput.Hold(entry)
+		if err != nil {
+			log.WarnContext(ctx, "failed to hold quote for moderation", slog.Int("rule", rule), slog.String("error", err.Error()))
+			return false, apierror.New(http.StatusServiceUnavailable, apierror.CodeModerationQueueFull, nil)
+		}
+		log.InfoContext(ctx, "quote held for moderation", slog.String("held_id", held.ID), slog.Int("rule", rule))
+		response.JSON(w, r, http.StatusAccepted, models.SuccessDataResponse{
+			Status: "success",
+			Data:   held,
+		})
+		return false, nil
+	}
+	return true, nil
+}
+
+// heldUpdate shows the fields update sets as a quote.
+func heldUpdate(update storage.QuoteUpdate) models.Quote {
+	var quote models.Quote
+	if update.Text != nil {
+		quote.Text = *update.Text
+	}
+	if update.Author != nil {
+		quote.Author = *update.Author
+	}
+	if update.Weight != nil {
+		quote.Weight = *update.Weight
+	}
+	if update.Lang != nil {
+		quote.Lang, quote.LangDetected = *update.Lang, update.LangDetected
+	}
+	if update.Source != nil {
+		quote.Source = *update.Source
+	}
+	if update.SourceURL != nil {
+		quote.SourceURL = *update.SourceURL
+	}
+	return quote
+}
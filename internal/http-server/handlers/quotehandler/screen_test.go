@@ -0,0 +1,196 @@
+package quotehandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/handlers/quotehandler"
+	"quotes-service/internal/lib/contentfilter"
+	"quotes-service/internal/lib/moderation"
+	"quotes-service/internal/lib/quoteinput"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+// setContentFilter screens quotes against a denylist of "spam" until the
+// test ends.
+func setContentFilter(t *testing.T, action string) *moderation.Queue {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "denylist.txt")
+	if err := os.WriteFile(path, []byte("spam\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	filter, err := contentfilter.New(path, action)
+	if err != nil {
+		t.Fatalf("failed to load filter: %v", err)
+	}
+	held := moderation.New(10)
+	quoteinput.SetContentFilter(filter, held)
+	t.Cleanup(func() { quoteinput.SetContentFilter(nil, nil) })
+	return held
+}
+
+func TestContentFilter(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name           string
+		action         string
+		method         string
+		url            string
+		body           string
+		expectedStatus int
+		expectedBody   string
+		expectedHeld   string
+	}{
+		{
+			name:           "clean quote",
+			action:         contentfilter.ActionReject,
+			method:         http.MethodPost,
+			url:            "/quotes",
+			body:           `{"text":"Spammers are not welcome","author":"Moderator","lang":"en"}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "rejected create",
+			action:         contentfilter.ActionReject,
+			method:         http.MethodPost,
+			url:            "/quotes",
+			body:           `{"text":"Buy SPAM now","author":"Spammer","lang":"en"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"status":"error","code":"content_rejected","error":"Quote content is not allowed."}`,
+		},
+		{
+			name:           "rejected update",
+			action:         contentfilter.ActionReject,
+			method:         http.MethodPatch,
+			url:            "/quotes/1",
+			body:           `{"text":"Buy spam now"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"status":"error","code":"content_rejected","error":"Quote content is not allowed."}`,
+		},
+		{
+			name:           "update without text",
+			action:         contentfilter.ActionReject,
+			method:         http.MethodPatch,
+			url:            "/quotes/1",
+			body:           `{"author":"Spam"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "held create",
+			action:         contentfilter.ActionHold,
+			method:         http.MethodPost,
+			url:            "/quotes",
+			body:           `{"text":"Buy spam now","author":"Spammer","lang":"en"}`,
+			expectedStatus: http.StatusAccepted,
+			expectedHeld:   "create",
+		},
+		{
+			name:           "held update",
+			action:         contentfilter.ActionHold,
+			method:         http.MethodPatch,
+			url:            "/quotes/1",
+			body:           `{"text":"Buy spam now"}`,
+			expectedStatus: http.StatusAccepted,
+			expectedHeld:   "update",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			held := setContentFilter(t, tc.action)
+			store, err := memorystorage.New()
+			if err != nil {
+				t.Fatalf("failed to init storage: %v", err)
+			}
+			if _, err := store.AddQuote(ctx, models.Quote{Text: "Original", Author: "Author", Lang: "en"}); err != nil {
+				t.Fatal(err)
+			}
+			router := mux.NewRouter()
+			router.HandleFunc("/quotes", quotehandler.NewAddQuoteHandler(logger, store)).Methods(http.MethodPost)
+			router.HandleFunc("/quotes/{id}", quotehandler.NewPatchQuoteHandler(logger, store)).Methods(http.MethodPatch)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body)))
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tc.expectedBody != "" && rr.Body.String() != tc.expectedBody+"\n" {
+				t.Fatalf("expected body %s, got %s", tc.expectedBody, rr.Body.String())
+			}
+
+			quotes, err := store.GetAllQuotes(ctx, storage.QuoteFilter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, q := range quotes {
+				if strings.Contains(strings.ToLower(q.Text), "spam now") {
+					t.Fatalf("expected the filtered quote not to be stored, got %+v", q)
+				}
+			}
+
+			queued := held.List()
+			if tc.expectedHeld == "" {
+				if len(queued) != 0 {
+					t.Fatalf("expected nothing held, got %+v", queued)
+				}
+				return
+			}
+			var resp struct {
+				Data models.HeldQuote `json:"data"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode held quote: %v", err)
+			}
+			if len(queued) != 1 || queued[0].ID != resp.Data.ID || queued[0].Source != tc.expectedHeld || queued[0].Rule != 1 || queued[0].Quote.Text != "Buy spam now" {
+				t.Fatalf("unexpected held quotes %+v, answered %+v", queued, resp.Data)
+			}
+		})
+	}
+}
+
+func TestImportContentFilter(t *testing.T) {
+	body := `{"text":"Clean quote","author":"Author","lang":"en"}` + "\n" +
+		`{"text":"Spam quote","author":"Author","lang":"en"}` + "\n"
+
+	for action, expected := range map[string]models.ImportReport{
+		contentfilter.ActionReject: {Lines: 2, Imported: 1, Skipped: 1},
+		contentfilter.ActionHold:   {Lines: 2, Imported: 1, Held: 1},
+	} {
+		t.Run(action, func(t *testing.T) {
+			held := setContentFilter(t, action)
+			router, _ := newTransferRouter(t)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/quotes/import", strings.NewReader(body)))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d. Body: %s", rr.Code, rr.Body.String())
+			}
+			var resp struct {
+				Data models.ImportReport `json:"data"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode report: %v", err)
+			}
+			report := resp.Data
+			if report.Lines != expected.Lines || report.Imported != expected.Imported || report.Skipped != expected.Skipped || report.Held != expected.Held {
+				t.Fatalf("unexpected report %+v", report)
+			}
+			if got := len(held.List()); got != expected.Held {
+				t.Fatalf("expected %d quotes held, got %d", expected.Held, got)
+			}
+		})
+	}
+}
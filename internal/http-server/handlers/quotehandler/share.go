@@ -0,0 +1,68 @@
+package quotehandler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/logger/sl"
+	"quotes-service/internal/lib/share"
+	"quotes-service/internal/models"
+)
+
+// NewShareQuoteHandler serves GET /quotes/{id}/share, the quote rendered
+// as text to paste into a post, in the style of ?style=, plain by default.
+// An unknown style is a 400 that lists the available ones.
+func NewShareQuoteHandler(logger *slog.Logger, qs QuoteStore, formatter *share.Formatter) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.quote.ShareQuote"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		idStr := mux.Vars(r)["id"]
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			log.WarnContext(ctx, "invalid quote ID format", slog.String("id", idStr), slog.String("error", err.Error()))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidQuoteID, nil)
+		}
+
+		style := r.URL.Query().Get("style")
+		if style == "" {
+			style = share.StylePlain
+		}
+
+		quote, err := qs.GetQuote(ctx, id)
+		if err != nil {
+			log.Log(ctx, apierror.Level(err), "failed to get quote", slog.Int64("id", id), slog.String("error", err.Error()))
+			return apierror.Failed(apierror.CodeGetQuoteFailed, err)
+		}
+
+		text, err := formatter.Format(style, quote)
+		if err != nil {
+			if errors.Is(err, share.ErrUnknownStyle) {
+				log.InfoContext(ctx, "unknown share style", sl.UserText("style", style))
+				return apierror.New(http.StatusBadRequest, apierror.CodeUnknownShareStyle, nil, style, strings.Join(formatter.Styles(), ", "))
+			}
+			log.ErrorContext(ctx, "failed to render quote", slog.Int64("id", id), slog.String("style", style), slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeShareQuoteFailed, nil)
+		}
+
+		log.InfoContext(ctx, "rendered quote for sharing", slog.Int64("id", id), slog.String("style", style))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data: models.SharedQuote{
+				ID:     id,
+				Style:  style,
+				Text:   text,
+				Length: utf8.RuneCountInString(text),
+			},
+		})
+		return nil
+	})
+}
@@ -0,0 +1,81 @@
+package quotehandler_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/handlers/quotehandler"
+	"quotes-service/internal/lib/share"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func TestShareQuoteHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	if _, err := store.AddQuote(context.Background(), models.Quote{Text: "Stay hungry 🍏, stay foolish.", Author: "Steve Jobs"}); err != nil {
+		t.Fatalf("failed to add quote: %v", err)
+	}
+	formatter, err := share.New(share.Defaults())
+	if err != nil {
+		t.Fatalf("failed to parse styles: %v", err)
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/quotes/{id}/share", quotehandler.NewShareQuoteHandler(logger, store, formatter)).Methods(http.MethodGet)
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "default style",
+			url:            "/quotes/1/share",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"id":1,"style":"plain","text":"\"Stay hungry 🍏, stay foolish.\"\n— Steve Jobs","length":43}}` + "\n",
+		},
+		{
+			name:           "twitter",
+			url:            "/quotes/1/share?style=twitter",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"id":1,"style":"twitter","text":"“Stay hungry 🍏, stay foolish.” — Steve Jobs","length":43}}` + "\n",
+		},
+		{
+			name:           "unknown style",
+			url:            "/quotes/1/share?style=haiku",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"unknown_share_style","error":"Unknown share style \"haiku\"; available styles: markdown, plain, twitter."}` + "\n",
+		},
+		{
+			name:           "not found",
+			url:            "/quotes/2/share",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"status":"error","code":"quote_not_found","error":"Quote not found."}` + "\n",
+		},
+		{
+			name:           "invalid id",
+			url:            "/quotes/x/share",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"invalid_quote_id","error":"Invalid quote ID format."}` + "\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.url, nil))
+			if rr.Code != tc.expectedStatus || rr.Body.String() != tc.expectedBody {
+				t.Fatalf("expected %d with %s, got %d with %s", tc.expectedStatus, tc.expectedBody, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
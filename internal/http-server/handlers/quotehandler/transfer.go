@@ -0,0 +1,194 @@
+package quotehandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/pagination"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/fingerprint"
+	"quotes-service/internal/lib/logger/sl"
+	"quotes-service/internal/lib/quotable"
+	"quotes-service/internal/lib/quoteinput"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// Formats of the export and import endpoints. Both read and write JSON
+// Lines, one quote per line.
+const (
+	FormatNative   = quoteinput.FormatNative
+	FormatQuotable = quoteinput.FormatQuotable
+
+	NDJSONContentType = "application/x-ndjson"
+)
+
+const maxImportBytes = 64 << 20
+
+func parseFormat(r *http.Request) (string, bool) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "", FormatNative:
+		return FormatNative, true
+	case FormatQuotable:
+		return FormatQuotable, true
+	default:
+		return format, false
+	}
+}
+
+// NewExportQuotesHandler writes a page of the quotes matching the list
+// filters as JSON Lines, in our own shape or, with ?format=quotable, as
+// quotable records.
+func NewExportQuotesHandler(logger *slog.Logger, qs QuoteStore, sizes pagination.Sizes) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.quote.ExportQuotes"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		format, ok := parseFormat(r)
+		if !ok {
+			log.WarnContext(ctx, "invalid export format", slog.String("format", format))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, "format")
+		}
+		filter, err := parseQuoteFilter(r)
+		if err != nil {
+			var paramErr *queryParamError
+			errors.As(err, &paramErr)
+			log.WarnContext(ctx, "invalid filter query parameter", slog.String("param", paramErr.param), sl.UserText("value", paramErr.value))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, paramErr.param)
+		}
+		page, err := parsePage(r, log, sizes)
+		if err != nil {
+			return err
+		}
+
+		quotes, err := qs.GetAllQuotes(ctx, filter)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to get quotes for export", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeExportFailed, nil)
+		}
+		total := len(quotes)
+		quotes = pagination.Slice(quotes, page)
+
+		pagination.SetHeaders(w, r, page, total)
+		w.Header().Set("Content-Type", NDJSONContentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="quotes.jsonl"`)
+		w.WriteHeader(http.StatusOK)
+		enc := json.NewEncoder(w)
+		for _, q := range quotes {
+			var record any = q
+			if format == FormatQuotable {
+				record = quotable.FromQuote(q)
+			}
+			if err := enc.Encode(record); err != nil {
+				log.ErrorContext(ctx, "failed to write export", slog.String("error", err.Error()))
+				return nil
+			}
+		}
+
+		log.InfoContext(ctx, "exported quotes", slog.String("format", format), slog.Int("count", len(quotes)), slog.Int("total", total))
+		return nil
+	})
+}
+
+// NewImportQuotesHandler adds the quotes in a JSON Lines body, in our own
+// shape or, with ?format=quotable, as quotable records. Quotes the store
+// already holds are not added again. Lines that cannot be imported are
+// skipped and reported rather than failing the whole import. The quotes
+// are stored all together when the store is a storage.Transactor, and one
+// by one otherwise. With ?dry_run=true every line is checked the same way
+// but nothing is stored.
+func NewImportQuotesHandler(logger *slog.Logger, qs QuoteStore) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.quote.ImportQuotes"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		format, ok := parseFormat(r)
+		if !ok {
+			log.WarnContext(ctx, "invalid import format", slog.String("format", format))
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, "format")
+		}
+		dryRun := false
+		if raw := r.URL.Query().Get("dry_run"); raw != "" {
+			var err error
+			if dryRun, err = strconv.ParseBool(raw); err != nil {
+				log.WarnContext(ctx, "invalid dry_run parameter", slog.String("dry_run", raw))
+				return apierror.New(http.StatusBadRequest, apierror.CodeInvalidParameter, nil, "dry_run")
+			}
+		}
+
+		existing, err := qs.GetAllQuotes(ctx, storage.QuoteFilter{})
+		if err != nil {
+			log.ErrorContext(ctx, "failed to load quotes for import", slog.String("error", err.Error()))
+			return apierror.New(http.StatusInternalServerError, apierror.CodeImportFailed, nil)
+		}
+		index := make(fingerprint.Index, len(existing))
+		for _, q := range existing {
+			index.Add(q.Text, q.Author)
+		}
+
+		report := models.ImportReport{DryRun: dryRun, Format: format}
+		// The body is read in full before anything is stored, so a slow
+		// client does not hold a transaction open.
+		pending := quoteinput.Read(http.MaxBytesReader(w, r.Body, maxImportBytes), format, index, &report)
+
+		switch {
+		case dryRun:
+			report.Imported = len(pending)
+		case len(pending) > 0:
+			err := storage.ErrTxUnsupported
+			if transactor, ok := qs.(storage.Transactor); ok {
+				err = transactor.WithinTx(ctx, func(tx storage.QuoteStore) error {
+					for _, p := range pending {
+						if _, err := tx.AddQuote(ctx, p.Quote); err != nil {
+							return fmt.Errorf("line %d: %w", p.Number, err)
+						}
+					}
+					return nil
+				})
+			}
+			switch {
+			case err == nil:
+				report.Atomic = true
+				report.Imported = len(pending)
+			case errors.Is(err, storage.ErrTxUnsupported):
+				log.WarnContext(ctx, "store does not support transactions, importing quotes one by one")
+				for _, p := range pending {
+					if _, err := qs.AddQuote(ctx, p.Quote); err != nil {
+						log.ErrorContext(ctx, "failed to add imported quote", slog.Int("line", p.Number), slog.String("error", err.Error()))
+						quoteinput.Skip(&report, p.Number, "failed to store quote")
+						if ctx.Err() != nil {
+							break
+						}
+						continue
+					}
+					report.Imported++
+				}
+			default:
+				log.ErrorContext(ctx, "failed to import quotes, nothing was stored", slog.String("error", err.Error()))
+				return apierror.New(http.StatusInternalServerError, apierror.CodeImportFailed, nil)
+			}
+		}
+
+		log.InfoContext(ctx, "imported quotes",
+			slog.Bool("dry_run", dryRun),
+			slog.Bool("atomic", report.Atomic),
+			slog.String("format", format),
+			slog.Int("lines", report.Lines),
+			slog.Int("imported", report.Imported),
+			slog.Int("duplicates", report.Duplicates),
+			slog.Int("skipped", report.Skipped),
+		)
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   report,
+		})
+		return nil
+	})
+}
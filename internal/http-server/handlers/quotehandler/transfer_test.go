@@ -0,0 +1,270 @@
+package quotehandler_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/handlers/quotehandler"
+	"quotes-service/internal/lib/quotable"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func newTransferRouter(t *testing.T) (*mux.Router, *memorystorage.Storage) {
+	t.Helper()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	return routeTransfer(store), store
+}
+
+func routeTransfer(qs quotehandler.QuoteStore) *mux.Router {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := mux.NewRouter()
+	router.HandleFunc("/quotes/export", quotehandler.NewExportQuotesHandler(logger, qs, testPageSizes)).Methods(http.MethodGet)
+	router.HandleFunc("/quotes/import", quotehandler.NewImportQuotesHandler(logger, qs)).Methods(http.MethodPost)
+	return router
+}
+
+func importLines(t *testing.T, router http.Handler, format string, body []byte) models.ImportReport {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/quotes/import?format="+format, bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("import: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Data models.ImportReport `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode import report: %v", err)
+	}
+	return resp.Data
+}
+
+func exportLines(t *testing.T, router http.Handler, format string) []byte {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/quotes/export?format="+format, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("export: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Content-Type"); got != quotehandler.NDJSONContentType {
+		t.Fatalf("unexpected content type %q", got)
+	}
+	return rr.Body.Bytes()
+}
+
+func decodeLines[T any](t *testing.T, data []byte) []T {
+	t.Helper()
+	var records []T
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var record T
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestQuotableRoundTrip(t *testing.T) {
+	dataset, err := os.ReadFile("testdata/quotable.jsonl")
+	if err != nil {
+		t.Fatalf("failed to read dataset: %v", err)
+	}
+	want := decodeLines[quotable.Record](t, dataset)
+
+	router, _ := newTransferRouter(t)
+	report := importLines(t, router, quotehandler.FormatQuotable, dataset)
+	if report.Imported != len(want) || report.Skipped != 0 || report.UnknownFields != nil {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	got := decodeLines[quotable.Record](t, exportLines(t, router, quotehandler.FormatQuotable))
+	if len(got) != len(want) {
+		t.Fatalf("expected %d records, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i].Content != want[i].Content || got[i].Author != want[i].Author ||
+			!reflect.DeepEqual(got[i].Tags, want[i].Tags) || got[i].Length != want[i].Length {
+			t.Errorf("record %d differs:\n got %+v\nwant %+v", i, got[i], want[i])
+		}
+	}
+
+	// Importing the export again finds nothing new.
+	again := importLines(t, router, quotehandler.FormatQuotable, exportLines(t, router, quotehandler.FormatQuotable))
+	if again.Imported != 0 || again.Duplicates != len(want) {
+		t.Fatalf("expected only duplicates on re-import, got %+v", again)
+	}
+}
+
+func TestNativeRoundTrip(t *testing.T) {
+	source, store := newTransferRouter(t)
+	ctx := context.Background()
+	for _, q := range []models.Quote{
+		{Text: "Well begun is half done.", Author: "Aristotle", Weight: 3, Lang: "en", Tags: []string{"famous-quotes"}},
+		{Text: "Жизнь коротка, искусство вечно.", Author: "Гиппократ", Lang: "ru", LangDetected: true, Source: "Aphorisms", SourceURL: "https://example.com/aphorisms"},
+	} {
+		if _, err := store.AddQuote(ctx, q); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+	exported := exportLines(t, source, quotehandler.FormatNative)
+
+	target, _ := newTransferRouter(t)
+	if report := importLines(t, target, quotehandler.FormatNative, exported); report.Imported != 2 || report.UnknownFields != nil {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	// The store assigns IDs and timestamps, everything else must survive.
+	want := decodeLines[models.Quote](t, exported)
+	got := decodeLines[models.Quote](t, exportLines(t, target, quotehandler.FormatNative))
+	for _, quotes := range [][]models.Quote{want, got} {
+		for i := range quotes {
+			quotes[i].ID = 0
+			quotes[i].CreatedAt, quotes[i].UpdatedAt = time.Time{}, time.Time{}
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("quotes differ after the round trip:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestImportReportsBadLines(t *testing.T) {
+	router, _ := newTransferRouter(t)
+	body := strings.Join([]string{
+		`{"content":"Well begun is half done.","author":"Aristotle","tags":["famous-quotes"],"likes":3,"source":"x"}`,
+		``,
+		`{"content":"No author here."}`,
+		`{"author":"Nobody"}`,
+		`not json`,
+		`{"content":"Second","author":"B","likes":1}`,
+	}, "\n")
+
+	report := importLines(t, router, quotehandler.FormatQuotable, []byte(body))
+	want := models.ImportReport{
+		Atomic:        true,
+		Format:        quotehandler.FormatQuotable,
+		Lines:         5,
+		Imported:      2,
+		Skipped:       3,
+		UnknownFields: map[string]int{"likes": 2, "source": 1},
+		Errors: []models.ImportError{
+			{Line: 3, Error: "author cannot be empty"},
+			{Line: 4, Error: "content cannot be empty"},
+			{Line: 5, Error: "not a JSON object"},
+		},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Fatalf("unexpected report\n got %+v\nwant %+v", report, want)
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/quotes/import?format=csv", strings.NewReader(body)))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown format, got %d", rr.Code)
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	router, store := newTransferRouter(t)
+	if _, err := store.AddQuote(context.Background(), models.Quote{Text: "Well begun is half done.", Author: "Aristotle"}); err != nil {
+		t.Fatalf("failed to add quote: %v", err)
+	}
+	body := strings.Join([]string{
+		`{"content":"Well begun is half done.","author":"Aristotle"}`,
+		`{"content":"Second","author":"B"}`,
+		`{"content":"Second","author":"B"}`,
+		`{"content":"No author"}`,
+	}, "\n")
+
+	report := importLines(t, router, quotehandler.FormatQuotable+"&dry_run=true", []byte(body))
+	if !report.DryRun || report.Imported != 1 || report.Duplicates != 2 || report.Skipped != 1 {
+		t.Fatalf("unexpected dry run report %+v", report)
+	}
+	if version, _ := store.Version(context.Background()); version != 1 {
+		t.Fatalf("expected the dry run to leave the store alone, got version %d", version)
+	}
+
+	if report := importLines(t, router, quotehandler.FormatQuotable, []byte(body)); report.DryRun || report.Imported != 1 {
+		t.Fatalf("expected the real import to match the dry run, got %+v", report)
+	}
+}
+
+// failingTxStore fails the nth quote added inside a transaction.
+type failingTxStore struct {
+	*memorystorage.Storage
+	n int
+}
+
+func (s *failingTxStore) WithinTx(ctx context.Context, fn func(tx storage.QuoteStore) error) error {
+	return s.Storage.WithinTx(ctx, func(tx storage.QuoteStore) error {
+		return fn(&failingTx{QuoteStore: tx, n: s.n})
+	})
+}
+
+type failingTx struct {
+	storage.QuoteStore
+	n, added int
+}
+
+func (tx *failingTx) AddQuote(ctx context.Context, quote models.Quote) (int64, error) {
+	if tx.added++; tx.added == tx.n {
+		return 0, errors.New("disk full")
+	}
+	return tx.QuoteStore.AddQuote(ctx, quote)
+}
+
+func TestImportIsAtomic(t *testing.T) {
+	body := []byte(strings.Join([]string{
+		`{"content":"First","author":"A"}`,
+		`{"content":"Second","author":"B"}`,
+		`{"content":"Third","author":"C"}`,
+	}, "\n"))
+
+	t.Run("failed write rolls back", func(t *testing.T) {
+		store, err := memorystorage.New()
+		if err != nil {
+			t.Fatalf("failed to init storage: %v", err)
+		}
+		router := routeTransfer(&failingTxStore{Storage: store, n: 2})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/quotes/import?format=quotable", bytes.NewReader(body)))
+		if rr.Code != http.StatusInternalServerError {
+			t.Fatalf("expected 500, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if quotes, _ := store.GetAllQuotes(context.Background(), storage.QuoteFilter{}); len(quotes) != 0 {
+			t.Fatalf("expected no partial import, got %+v", quotes)
+		}
+	})
+
+	t.Run("without transactions", func(t *testing.T) {
+		store, err := memorystorage.New()
+		if err != nil {
+			t.Fatalf("failed to init storage: %v", err)
+		}
+		// Embedding the interface hides WithinTx.
+		router := routeTransfer(struct{ quotehandler.QuoteStore }{store})
+		report := importLines(t, router, quotehandler.FormatQuotable, body)
+		if report.Atomic || report.Imported != 3 {
+			t.Fatalf("expected a non-atomic import of 3 quotes, got %+v", report)
+		}
+	})
+}
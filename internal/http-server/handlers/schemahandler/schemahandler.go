@@ -0,0 +1,86 @@
+package schemahandler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/jsonschema"
+	"quotes-service/internal/models"
+)
+
+const ContentType = "application/schema+json"
+
+// documented lists the models whose schemas are published, in index order.
+var documented = []any{
+	models.AddQuoteRequest{},
+	models.AddQuoteResponse{},
+	models.UpdateQuoteRequest{},
+	models.Quote{},
+	models.ErrorResponse{},
+	models.SuccessDataResponse{},
+	models.GenericMessageResponse{},
+	models.Problem{},
+}
+
+// Schemas holds the encoded schema of every documented model. It is built
+// once at startup since the models cannot change at runtime.
+type Schemas struct {
+	index []models.SchemaRef
+	docs  map[string][]byte
+}
+
+// NewSchemas generates the schemas. Schemas are plain maps of strings,
+// slices and maps, so encoding them can only fail on a programming error.
+func NewSchemas() *Schemas {
+	s := &Schemas{docs: make(map[string][]byte, len(documented))}
+	for _, model := range documented {
+		schema := jsonschema.Generate(model)
+		name := schema["title"].(string)
+		doc, err := json.Marshal(schema)
+		if err != nil {
+			panic("schemahandler: encode schema " + name + ": " + err.Error())
+		}
+		s.docs[name] = doc
+		s.index = append(s.index, models.SchemaRef{Name: name, URL: "/schema/" + name})
+	}
+	return s
+}
+
+func NewGetSchemaIndexHandler(logger *slog.Logger, schemas *Schemas) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.schema.GetSchemaIndex"
+		log := logger.With(slog.String("op", op))
+
+		log.DebugContext(r.Context(), "serving schema index", slog.Int("count", len(schemas.index)))
+		response.JSON(w, r, http.StatusOK, models.SuccessDataResponse{
+			Status: "success",
+			Data:   schemas.index,
+		})
+	}
+}
+
+func NewGetSchemaHandler(logger *slog.Logger, schemas *Schemas) http.HandlerFunc {
+	return response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handler.schema.GetSchema"
+		log := logger.With(slog.String("op", op))
+		ctx := r.Context()
+
+		name := mux.Vars(r)["model"]
+		doc, ok := schemas.docs[name]
+		if !ok {
+			log.InfoContext(ctx, "schema not found", slog.String("model", name))
+			return apierror.New(http.StatusNotFound, apierror.CodeSchemaNotFound, nil)
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(doc); err != nil {
+			log.ErrorContext(ctx, "failed to write schema", slog.String("error", err.Error()))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,133 @@
+package schemahandler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"quotes-service/internal/http-server/handlers/schemahandler"
+)
+
+func newRouter() *mux.Router {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	schemas := schemahandler.NewSchemas()
+	router := mux.NewRouter()
+	router.HandleFunc("/schema", schemahandler.NewGetSchemaIndexHandler(logger, schemas)).Methods(http.MethodGet)
+	router.HandleFunc("/schema/{model}", schemahandler.NewGetSchemaHandler(logger, schemas)).Methods(http.MethodGet)
+	return router
+}
+
+func fetchSchema(t *testing.T, router http.Handler, model string) *jsonschema.Schema {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/schema/"+model, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 for %s, got %d", model, rr.Code)
+	}
+	if got := rr.Header().Get("Content-Type"); got != schemahandler.ContentType {
+		t.Errorf("unexpected content type %q", got)
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(rr.Body.Bytes()))
+	if err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(model+".json", doc); err != nil {
+		t.Fatalf("failed to add schema: %v", err)
+	}
+	schema, err := compiler.Compile(model + ".json")
+	if err != nil {
+		t.Fatalf("failed to compile schema: %v", err)
+	}
+	return schema
+}
+
+func validate(schema *jsonschema.Schema, payload string) error {
+	inst, err := jsonschema.UnmarshalJSON(strings.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	return schema.Validate(inst)
+}
+
+func TestAddQuoteRequestSchema(t *testing.T) {
+	schema := fetchSchema(t, newRouter(), "AddQuoteRequest")
+
+	valid := []string{
+		`{"text":"Simplicity is prerequisite for reliability.","author":"Edsger Dijkstra"}`,
+		`{"text":"T","author":"A","weight":5,"lang":"en","source":"EWD498","source_url":"https://example.com"}`,
+		`{"text":"T","author":"A","weight":null}`,
+	}
+	for _, payload := range valid {
+		if err := validate(schema, payload); err != nil {
+			t.Errorf("expected %s to be valid: %v", payload, err)
+		}
+	}
+
+	invalid := []string{
+		`{"author":"A"}`,
+		`{"text":"T","author":"A","weight":"heavy"}`,
+		`{"text":1,"author":"A"}`,
+	}
+	for _, payload := range invalid {
+		if err := validate(schema, payload); err == nil {
+			t.Errorf("expected %s to be invalid", payload)
+		}
+	}
+}
+
+func TestEnvelopeSchemas(t *testing.T) {
+	router := newRouter()
+
+	quote := fetchSchema(t, router, "Quote")
+	if err := validate(quote, `{"id":1,"text":"T","author":"A","created_at":"2024-03-10T12:00:00Z","version":1}`); err != nil {
+		t.Errorf("expected quote to be valid: %v", err)
+	}
+
+	errResp := fetchSchema(t, router, "ErrorResponse")
+	if err := validate(errResp, `{"status":"error","code":"quote_not_found","error":"Quote not found."}`); err != nil {
+		t.Errorf("expected error response to be valid: %v", err)
+	}
+	if err := validate(errResp, `{"status":"error","error":"Quote not found."}`); err == nil {
+		t.Errorf("expected error response without code to be invalid")
+	}
+
+	data := fetchSchema(t, router, "SuccessDataResponse")
+	if err := validate(data, `{"status":"success","data":[1,"two",{"three":3}]}`); err != nil {
+		t.Errorf("expected any data to be valid: %v", err)
+	}
+}
+
+func TestSchemaIndex(t *testing.T) {
+	router := newRouter()
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/schema", nil))
+	var index struct {
+		Data []struct {
+			Name string `json:"name"`
+			URL  string `json:"url"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &index); err != nil {
+		t.Fatalf("failed to decode index: %v", err)
+	}
+	if len(index.Data) == 0 || index.Data[0].Name != "AddQuoteRequest" || index.Data[0].URL != "/schema/AddQuoteRequest" {
+		t.Fatalf("unexpected index %+v", index.Data)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/schema/Nope", nil))
+	expected := `{"status":"error","code":"schema_not_found","error":"Schema not found."}`
+	if rr.Code != http.StatusNotFound || strings.TrimSpace(rr.Body.String()) != expected {
+		t.Errorf("expected 404 %s, got %d %s", expected, rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,37 @@
+// Package headers keeps the names of the response headers the service's
+// middlewares and handlers set for clients to read. The CORS middleware
+// exposes them to browsers, so a package that adds such a header registers
+// it here, usually from init, and the list never goes stale.
+package headers
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+)
+
+var (
+	mu      sync.Mutex
+	exposed = make(map[string]struct{})
+)
+
+// Expose registers response headers for browsers to read across origins.
+func Expose(names ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, name := range names {
+		exposed[http.CanonicalHeaderKey(name)] = struct{}{}
+	}
+}
+
+// Exposed returns the registered headers, sorted.
+func Exposed() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	names := make([]string, 0, len(exposed))
+	for name := range exposed {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
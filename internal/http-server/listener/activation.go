@@ -0,0 +1,63 @@
+package listener
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFDsStart is the first file descriptor systemd passes to a
+// socket-activated process.
+const listenFDsStart = 3
+
+// Activated returns the listeners systemd passed to this process through
+// the LISTEN_FDS/LISTEN_PID protocol, in the order of the socket unit, or
+// nil if the process was not socket-activated. The variables are cleared so
+// that child processes do not mistake the sockets for their own.
+func Activated() ([]net.Listener, error) {
+	const op = "listener.Activated"
+
+	pid, fds := os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS")
+	names := os.Getenv("LISTEN_FDNAMES")
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	if pid == "" || fds == "" {
+		return nil, nil
+	}
+	// The variables were meant for another process, e.g. a parent that
+	// leaked them into our environment.
+	if pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+
+	count, err := strconv.Atoi(fds)
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("%s: invalid LISTEN_FDS %q", op, fds)
+	}
+
+	fdNames := strings.Split(names, ":")
+	listeners := make([]net.Listener, 0, count)
+	for i := 0; i < count; i++ {
+		name := "LISTEN_FD_" + strconv.Itoa(listenFDsStart+i)
+		if i < len(fdNames) && fdNames[i] != "" {
+			name = fdNames[i]
+		}
+		f := os.NewFile(uintptr(listenFDsStart+i), name)
+		// FileListener works on a duplicate, so the inherited descriptor
+		// is closed either way.
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, ln := range listeners {
+				ln.Close()
+			}
+			return nil, fmt.Errorf("%s: fd %d (%s): %w", op, listenFDsStart+i, name, err)
+		}
+		listeners = append(listeners, ln)
+	}
+	return listeners, nil
+}
@@ -0,0 +1,126 @@
+package listener_test
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+
+	"quotes-service/internal/http-server/listener"
+)
+
+// TestActivationHelperProcess is not a real test: TestActivated runs the
+// test binary again with inherited sockets, the way systemd starts the
+// service, and this function plays the service.
+func TestActivationHelperProcess(t *testing.T) {
+	if os.Getenv("QUOTES_ACTIVATION_HELPER") != "1" {
+		return
+	}
+	// systemd sets LISTEN_PID to the pid it started, which the parent
+	// cannot know in advance.
+	os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+
+	listeners, err := listener.Activated()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	for i, ln := range listeners {
+		srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "socket %d of %d", i, len(listeners))
+		})}
+		go srv.Serve(ln)
+	}
+	// Serve until the parent closes stdin.
+	io.Copy(io.Discard, os.Stdin)
+	os.Exit(0)
+}
+
+func TestActivated(t *testing.T) {
+	var (
+		files []*os.File
+		addrs []string
+	)
+	for i := 0; i < 2; i++ {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		f, err := ln.(*net.TCPListener).File()
+		if err != nil {
+			t.Fatalf("failed to get listener file: %v", err)
+		}
+		addrs = append(addrs, ln.Addr().String())
+		files = append(files, f)
+		ln.Close()
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestActivationHelperProcess$")
+	cmd.Env = append(os.Environ(), "QUOTES_ACTIVATION_HELPER=1", "LISTEN_FDS=2", "LISTEN_FDNAMES=http:http")
+	cmd.ExtraFiles = files
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start helper: %v", err)
+	}
+	for _, f := range files {
+		f.Close()
+	}
+	defer func() {
+		stdin.Close()
+		cmd.Wait()
+	}()
+
+	for i, addr := range addrs {
+		resp, err := http.Get("http://" + addr + "/")
+		if err != nil {
+			t.Fatalf("request to socket %d failed: %v", i, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if want := fmt.Sprintf("socket %d of 2", i); string(body) != want {
+			t.Errorf("expected %q, got %q", want, body)
+		}
+	}
+}
+
+func TestActivatedEnvironment(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	tests := []struct {
+		name    string
+		pid     string
+		fds     string
+		wantErr bool
+	}{
+		{name: "not activated"},
+		{name: "other process", pid: "1", fds: "1"},
+		{name: "no sockets", pid: pid, fds: "0"},
+		{name: "invalid count", pid: pid, fds: "two", wantErr: true},
+		{name: "negative count", pid: pid, fds: "-1", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("LISTEN_PID", tc.pid)
+			t.Setenv("LISTEN_FDS", tc.fds)
+
+			listeners, err := listener.Activated()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if len(listeners) != 0 {
+				t.Errorf("expected no listeners, got %d", len(listeners))
+			}
+			if _, ok := os.LookupEnv("LISTEN_FDS"); ok {
+				t.Error("expected LISTEN_FDS to be cleared")
+			}
+		})
+	}
+}
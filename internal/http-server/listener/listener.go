@@ -0,0 +1,84 @@
+// Package listener opens the sockets the HTTP servers accept connections
+// on. Besides host:port TCP addresses it understands unix:///path/to.sock,
+// which serves on a Unix domain socket.
+package listener
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const unixScheme = "unix://"
+
+// staleDialTimeout bounds the probe that tells a stale socket file from one
+// a running process still listens on.
+const staleDialTimeout = time.Second
+
+// UnixPath returns the socket path of a unix:// address, and false for any
+// other address.
+func UnixPath(address string) (string, bool) {
+	path, ok := strings.CutPrefix(address, unixScheme)
+	if !ok || path == "" {
+		return "", false
+	}
+	return path, true
+}
+
+// New listens on address. For a Unix socket a leftover file from a previous
+// run is removed first, and the new socket gets mode. The socket file is
+// removed again when the listener is closed.
+func New(address string, mode os.FileMode) (net.Listener, error) {
+	const op = "listener.New"
+
+	path, ok := UnixPath(address)
+	if !ok {
+		ln, err := net.Listen("tcp", address)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		return ln, nil
+	}
+
+	if err := removeStale(path); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("%s: set socket mode: %w", op, err)
+	}
+	return ln, nil
+}
+
+// removeStale deletes the socket file at path if no process accepts
+// connections on it. Anything other than a socket is left alone.
+func removeStale(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, staleDialTimeout)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another process", path)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove stale socket: %w", err)
+	}
+	return nil
+}
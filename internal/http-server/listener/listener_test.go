@@ -0,0 +1,134 @@
+package listener_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"quotes-service/internal/http-server/listener"
+)
+
+func unixClient(path string) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+}
+
+func TestServeOverUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quotes.sock")
+
+	ln, err := listener.New("unix://"+path, 0o600)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("socket file missing: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected socket mode 0600, got %o", info.Mode().Perm())
+	}
+
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello over "+r.URL.Path)
+	})}
+	go srv.Serve(ln)
+
+	resp, err := unixClient(path).Get("http://unix/quotes")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "hello over /quotes" {
+		t.Errorf("unexpected body %q", body)
+	}
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the socket file to be removed on shutdown, got %v", err)
+	}
+}
+
+func TestNewRemovesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quotes.sock")
+
+	// Leave a socket file behind as a crashed process would.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("failed to create socket: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, err := listener.New("unix://"+path, 0o660)
+	if err != nil {
+		t.Fatalf("expected the stale socket to be replaced, got %v", err)
+	}
+	ln.Close()
+}
+
+func TestNewRefusesSocketInUse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quotes.sock")
+
+	ln, err := listener.New("unix://"+path, 0o660)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	if second, err := listener.New("unix://"+path, 0o660); err == nil {
+		second.Close()
+		t.Fatal("expected an error for a socket in use")
+	}
+}
+
+func TestNewRefusesNonSocketFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quotes.sock")
+	if err := os.WriteFile(path, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listener.New("unix://"+path, 0o660); err == nil {
+		t.Fatal("expected an error for a regular file")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected the regular file to be left alone, got %v", err)
+	}
+}
+
+func TestUnixPath(t *testing.T) {
+	tests := []struct {
+		address  string
+		wantPath string
+		wantOK   bool
+	}{
+		{address: "unix:///var/run/quotes.sock", wantPath: "/var/run/quotes.sock", wantOK: true},
+		{address: "localhost:8080"},
+		{address: "unix://"},
+	}
+	for _, tc := range tests {
+		path, ok := listener.UnixPath(tc.address)
+		if path != tc.wantPath || ok != tc.wantOK {
+			t.Errorf("UnixPath(%q) = %q, %v; want %q, %v", tc.address, path, ok, tc.wantPath, tc.wantOK)
+		}
+	}
+}
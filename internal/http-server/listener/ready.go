@@ -0,0 +1,63 @@
+package listener
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// Ready describes a server that is accepting connections, for supervisors
+// and provisioning tools waiting for it to come up.
+type Ready struct {
+	// Address is where the API is served, as bound rather than as
+	// configured, so a ":0" address shows the port the system picked.
+	Address      string `json:"address"`
+	AdminAddress string `json:"admin_address,omitempty"`
+	TLS          bool   `json:"tls"`
+	Storage      string `json:"storage"`
+	Version      string `json:"version"`
+}
+
+// Address returns the address ln accepts connections on in the form New
+// takes: host:port, or unix:///path for a Unix socket.
+func Address(ln net.Listener) string {
+	addr := ln.Addr()
+	if addr.Network() == "unix" {
+		return unixScheme + addr.String()
+	}
+	return addr.String()
+}
+
+// WriteReadyFile writes ready to path as JSON. The file is written under a
+// temporary name and renamed into place, so a reader never sees it half
+// written.
+func WriteReadyFile(path string, ready Ready) error {
+	const op = "listener.WriteReadyFile"
+
+	data, err := json.Marshal(ready)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".ready-*")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
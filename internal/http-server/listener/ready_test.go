@@ -0,0 +1,70 @@
+package listener_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"quotes-service/internal/http-server/listener"
+)
+
+func TestAddress(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "quotes.sock")
+
+	tests := []struct {
+		name           string
+		address        string
+		expectedPrefix string
+	}{
+		{name: "tcp", address: "127.0.0.1:0", expectedPrefix: "127.0.0.1:"},
+		{name: "unix", address: "unix://" + sock, expectedPrefix: "unix://" + sock},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ln, err := listener.New(tc.address, 0o600)
+			if err != nil {
+				t.Fatalf("failed to listen: %v", err)
+			}
+			defer ln.Close()
+
+			got := listener.Address(ln)
+			if !strings.HasPrefix(got, tc.expectedPrefix) || strings.HasSuffix(got, ":0") {
+				t.Fatalf("expected the bound address, got %q", got)
+			}
+		})
+	}
+}
+
+func TestWriteReadyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ready.json")
+	if err := os.WriteFile(path, []byte("stale"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ready := listener.Ready{Address: "127.0.0.1:41234", TLS: true, Storage: "memory", Version: "1.0.0"}
+	if err := listener.WriteReadyFile(path, ready); err != nil {
+		t.Fatalf("failed to write ready file: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got listener.Ready
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("ready file is not JSON: %v, %s", err, data)
+	}
+	if got != ready {
+		t.Fatalf("expected %+v, got %+v", ready, got)
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected no temporary files left, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,165 @@
+// Package audit records the writes made through the API to the audit
+// trail.
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/middleware/auth"
+	mwLogger "quotes-service/internal/http-server/middleware/logger"
+	"quotes-service/internal/http-server/middleware/route"
+	"quotes-service/internal/lib/usertext"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// New records every request that does more than read, as route.Reads
+// tells, once it is answered, whatever the answer: the principal and how it authenticated,
+// the method and route template as the operation, the quote it was about,
+// the status and the request ID. The quote is the one in the path, or the
+// one a handler reported with Quote. A failure to record is logged and
+// does not fail the request.
+//
+// It must run after the middlewares that authenticate requests.
+func New(log *slog.Logger, store storage.AuditStore) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		middlewareLog := log.With(
+			slog.String("component", "middleware/audit"),
+		)
+
+		middlewareLog.Info("audit middleware enabled")
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if route.Reads(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			aw := &writer{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(aw, r)
+
+			ctx := r.Context()
+			principal, _ := auth.Principal(ctx)
+			// A bearer token's subject is whatever its issuer put there.
+			principal = usertext.Cut(usertext.Audit, principal)
+			method, _ := auth.AuthMethod(ctx)
+			entry := models.AuditEntry{
+				Principal:  principal,
+				AuthMethod: string(method),
+				Operation:  r.Method + " " + template(r),
+				QuoteID:    aw.quoteID,
+				Status:     aw.status,
+				RequestID:  w.Header().Get(mwLogger.RequestIDHeader),
+			}
+			if entry.QuoteID == 0 {
+				entry.QuoteID = pathQuoteID(r)
+			}
+			// The request may have been canceled, but what it did stays done.
+			if err := store.RecordAudit(context.WithoutCancel(ctx), entry); err != nil {
+				middlewareLog.ErrorContext(ctx, "failed to record audit entry",
+					slog.String("operation", entry.Operation),
+					slog.String("principal", principal),
+					slog.String("error", err.Error()),
+				)
+			}
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Quote tells the middleware through w, or a writer it unwraps to, that
+// the request was about the quote with id, for routes whose path does not
+// name it, such as the one that adds a quote.
+func Quote(w http.ResponseWriter, id int64) {
+	for w != nil {
+		if aw, ok := w.(*writer); ok {
+			aw.quoteID = id
+			return
+		}
+		wrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return
+		}
+		w = wrapper.Unwrap()
+	}
+}
+
+// pathQuoteID returns the numeric quote ID in the path of r, or zero for
+// a path that names a quote by public ID, or none.
+func pathQuoteID(r *http.Request) int64 {
+	vars := mux.Vars(r)
+	raw, ok := vars["quote_id"]
+	if !ok && strings.HasPrefix(template(r), "/quotes/") {
+		raw = vars["id"]
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// template returns the route template of r without the patterns of its
+// variables, "/quotes/{id}" for "/quotes/{id:[0-9]+}", so that operations
+// can be searched for as they are documented.
+func template(r *http.Request) string {
+	tmpl := route.Template(r)
+	if !strings.Contains(tmpl, ":") {
+		return tmpl
+	}
+	var b strings.Builder
+	depth, skipping := 0, false
+	for _, c := range tmpl {
+		switch {
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 && skipping {
+				skipping = false
+			}
+		case c == ':' && depth == 1:
+			skipping = true
+			continue
+		}
+		if !skipping {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+type writer struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	quoteID     int64
+}
+
+func (w *writer) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *writer) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
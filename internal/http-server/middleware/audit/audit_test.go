@@ -0,0 +1,105 @@
+package audit_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/middleware/audit"
+	"quotes-service/internal/http-server/middleware/auth"
+	"quotes-service/internal/lib/usertext"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func TestAudit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.Use(auth.New(logger, map[string]string{"w-key": "app"}))
+	router.Use(audit.New(logger, store))
+	status := func(code int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(code) }
+	}
+	router.HandleFunc("/quotes", func(w http.ResponseWriter, r *http.Request) {
+		audit.Quote(w, 7)
+		w.WriteHeader(http.StatusCreated)
+	}).Methods(http.MethodPost)
+	router.HandleFunc("/quotes", status(http.StatusOK)).Methods(http.MethodGet)
+	router.HandleFunc("/quotes/{id:[0-9]+|[0-9a-f]{8}}", status(http.StatusOK)).Methods(http.MethodPatch)
+	router.HandleFunc("/collections/{id}", status(http.StatusOK)).Methods(http.MethodDelete)
+	router.HandleFunc("/collections/{id}/quotes/{quote_id}", status(http.StatusNotFound)).Methods(http.MethodDelete)
+
+	for _, req := range []struct {
+		method, path, key string
+	}{
+		{http.MethodPost, "/quotes", "w-key"},
+		{http.MethodGet, "/quotes", "w-key"},
+		{http.MethodPatch, "/quotes/3", ""},
+		{http.MethodDelete, "/collections/5", "w-key"},
+		{http.MethodDelete, "/collections/5/quotes/9", "w-key"},
+	} {
+		r := httptest.NewRequest(req.method, req.path, nil)
+		if req.key != "" {
+			r.Header.Set(auth.APIKeyHeader, req.key)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	entries, _, err := store.QueryAudit(context.Background(), storage.AuditQuery{})
+	if err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+	expected := []models.AuditEntry{
+		{Principal: "app", AuthMethod: "api_key", Operation: "POST /quotes", QuoteID: 7, Status: http.StatusCreated},
+		{Operation: "PATCH /quotes/{id}", QuoteID: 3, Status: http.StatusOK},
+		{Principal: "app", AuthMethod: "api_key", Operation: "DELETE /collections/{id}", Status: http.StatusOK},
+		{Principal: "app", AuthMethod: "api_key", Operation: "DELETE /collections/{id}/quotes/{quote_id}", QuoteID: 9, Status: http.StatusNotFound},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %+v", len(expected), entries)
+	}
+	for i, e := range entries {
+		want := expected[i]
+		want.Seq, want.Time = e.Seq, e.Time
+		if e != want {
+			t.Fatalf("entry %d: expected %+v, got %+v", i, want, e)
+		}
+	}
+}
+
+func TestAuditCutsPrincipal(t *testing.T) {
+	defer usertext.Set(usertext.Default)
+	usertext.Set(usertext.Policy{Audit: 8})
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	router := mux.NewRouter()
+	router.Use(auth.New(logger, map[string]string{"w-key": "importer-for-the-nightly-sync"}))
+	router.Use(audit.New(logger, store))
+	router.HandleFunc("/quotes", func(w http.ResponseWriter, r *http.Request) {}).Methods(http.MethodPost)
+
+	r := httptest.NewRequest(http.MethodPost, "/quotes", nil)
+	r.Header.Set(auth.APIKeyHeader, "w-key")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	entries, _, err := store.QueryAudit(context.Background(), storage.AuditQuery{})
+	if err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Principal != "importer…" {
+		t.Fatalf("expected the principal cut to 8 characters, got %+v", entries)
+	}
+}
@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/role"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated principal.
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// Principal returns the principal attached by the middleware, if any.
+func Principal(ctx context.Context) (string, bool) {
+	principal, ok := ctx.Value(principalKey{}).(string)
+	return principal, ok && principal != ""
+}
+
+// Method is how a request was authenticated.
+type Method string
+
+const (
+	MethodAPIKey    Method = "api_key"
+	MethodJWT       Method = "jwt"
+	MethodSignature Method = "signature"
+)
+
+type methodKey struct{}
+
+// AuthMethod returns how the principal of the request authenticated, if it
+// did.
+func AuthMethod(ctx context.Context) (Method, bool) {
+	m, ok := ctx.Value(methodKey{}).(Method)
+	return m, ok
+}
+
+// KeyStore holds API keys created at runtime. *apikeys.Store is the real
+// one.
+type KeyStore interface {
+	// Lookup returns the principal of the key and its role, which is empty
+	// when the key leaves the role to Roles.
+	Lookup(key string) (principal string, r role.Role, ok bool)
+}
+
+type options struct {
+	store KeyStore
+}
+
+type Option func(*options)
+
+// WithKeyStore also accepts the keys in store, which are looked up on
+// every request, so keys created or revoked at runtime take effect at once.
+func WithKeyStore(store KeyStore) Option {
+	return func(o *options) {
+		o.store = store
+	}
+}
+
+// New resolves the API key of each request to a principal. keys maps an API
+// key to the principal name it identifies. Requests without a key pass
+// through anonymously; requests with an unknown key are rejected, so a typo
+// in a key never silently downgrades a client to anonymous access. Requests
+// already authenticated further out, by a bearer token, pass through as
+// they are.
+// Middleware further out learns the principal if its writer, or one it
+// unwraps to, has a SetPrincipal(principal string) method, and of a refused
+// key if it has an AuthFailed(code apierror.Code) method.
+func New(log *slog.Logger, keys map[string]string, opts ...Option) func(next http.Handler) http.Handler {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return func(next http.Handler) http.Handler {
+		middlewareLog := log.With(
+			slog.String("component", "middleware/auth"),
+		)
+
+		middlewareLog.Info("auth middleware enabled", slog.Int("keys", len(keys)), slog.Bool("key_store", o.store != nil))
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			key := APIKey(r)
+			if _, authenticated := Principal(r.Context()); key == "" || authenticated {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if principal, ok := keys[key]; ok {
+				next.ServeHTTP(w, Authenticate(w, r, principal, MethodAPIKey))
+				return
+			}
+			if o.store != nil {
+				if principal, current, ok := o.store.Lookup(key); ok {
+					r = Authenticate(w, r, principal, MethodAPIKey)
+					if current != "" {
+						r = r.WithContext(WithRole(r.Context(), current))
+					}
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			middlewareLog.WarnContext(r.Context(), "unknown API key", slog.String("path", r.URL.Path))
+			Fail(w, apierror.CodeInvalidAPIKey)
+			response.Error(w, r, http.StatusUnauthorized, apierror.CodeInvalidAPIKey, nil)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Authenticate returns r as authenticated as principal by method, and
+// tells the middleware further out through w, as New does for API keys.
+// Other ways of authenticating requests use it to the same effect.
+func Authenticate(w http.ResponseWriter, r *http.Request, principal string, method Method) *http.Request {
+	announce(w, principal)
+	ctx := WithPrincipal(r.Context(), principal)
+	return r.WithContext(context.WithValue(ctx, methodKey{}, method))
+}
+
+// Fail tells the middleware further out through w that the request's
+// credentials were refused with code, before the 401 is written. Every way
+// of authenticating requests calls it, so failures can be counted and
+// locked out in one place.
+func Fail(w http.ResponseWriter, code apierror.Code) {
+	walk(w, func(w http.ResponseWriter) {
+		if outer, ok := w.(interface{ AuthFailed(code apierror.Code) }); ok {
+			outer.AuthFailed(code)
+		}
+	})
+}
+
+// announce hands principal to every writer around w that takes it.
+func announce(w http.ResponseWriter, principal string) {
+	walk(w, func(w http.ResponseWriter) {
+		if outer, ok := w.(interface{ SetPrincipal(principal string) }); ok {
+			outer.SetPrincipal(principal)
+		}
+	})
+}
+
+// walk calls fn with w and every writer it unwraps to.
+func walk(w http.ResponseWriter, fn func(w http.ResponseWriter)) {
+	for w != nil {
+		fn(w)
+		wrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return
+		}
+		w = wrapper.Unwrap()
+	}
+}
+
+// APIKey reads the key from the X-API-Key header or a bearer token.
+func APIKey(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get(APIKeyHeader)); key != "" {
+		return key
+	}
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if found && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// Roles assigns a role to every request.
+type Roles struct {
+	// Principals maps principal names to their roles. A principal not in it
+	// has the zero role.Role, a writer.
+	Principals map[string]role.Role
+	// Anonymous is the role of requests without credentials.
+	Anonymous role.Role
+}
+
+// Of returns the role of r: the one in its context, if a middleware that
+// authenticated it gave it one, or else the one roles assigns.
+func (roles Roles) Of(r *http.Request) role.Role {
+	if current, ok := Role(r.Context()); ok {
+		return current
+	}
+	if principal, ok := Principal(r.Context()); ok {
+		return roles.Principals[principal]
+	}
+	return roles.Anonymous
+}
+
+type roleKey struct{}
+
+// WithRole returns a copy of ctx carrying the role of the request.
+func WithRole(ctx context.Context, r role.Role) context.Context {
+	return context.WithValue(ctx, roleKey{}, r)
+}
+
+// Role returns the role of the request: the one the middleware that
+// authenticated it gave it, or else the one Require resolved, if any.
+func Role(ctx context.Context) (role.Role, bool) {
+	r, ok := ctx.Value(roleKey{}).(role.Role)
+	return r, ok
+}
+
+// Require only lets through requests whose role is at least required. It
+// must run after New and any other middleware that authenticates requests.
+func Require(log *slog.Logger, roles Roles, required role.Role) func(next http.Handler) http.Handler {
+	return RequireFor(log, roles, func(*http.Request) role.Role { return required })
+}
+
+// RequireFor is Require with the role needed chosen for each request. A
+// role already in the request's context is kept; otherwise roles gives one
+// and it is left in the context for Role to return. Anonymous requests
+// that fall short get 401, as they would with credentials, and principals
+// 403 insufficient_role.
+func RequireFor(log *slog.Logger, roles Roles, required func(r *http.Request) role.Role) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		middlewareLog := log.With(
+			slog.String("component", "middleware/auth"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			principal, authenticated := Principal(r.Context())
+			current := roles.Of(r)
+			if _, ok := Role(r.Context()); !ok {
+				r = r.WithContext(WithRole(r.Context(), current))
+			}
+
+			need := required(r)
+			if current.Allows(need) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !authenticated {
+				middlewareLog.InfoContext(r.Context(), "unauthenticated request to protected route", slog.String("path", r.URL.Path), slog.String("required", need.String()))
+				response.Error(w, r, http.StatusUnauthorized, apierror.CodeAuthRequired, nil)
+				return
+			}
+			middlewareLog.WarnContext(r.Context(), "role not allowed",
+				slog.String("principal", principal),
+				slog.String("role", current.String()),
+				slog.String("required", need.String()),
+				slog.String("path", r.URL.Path),
+			)
+			response.Error(w, r, http.StatusForbidden, apierror.CodeInsufficientRole, nil, current, need)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
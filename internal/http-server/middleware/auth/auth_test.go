@@ -0,0 +1,123 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"quotes-service/internal/http-server/middleware/auth"
+	"quotes-service/internal/lib/role"
+	"quotes-service/internal/models"
+)
+
+func TestRequireFor(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	keys := map[string]string{"r-key": "dashboard", "w-key": "app", "a-key": "ops"}
+	roles := auth.Roles{
+		Principals: map[string]role.Role{"dashboard": role.Reader, "ops": role.Admin},
+		Anonymous:  role.None,
+	}
+	// Reads need a reader, everything else an admin.
+	required := func(r *http.Request) role.Role {
+		if r.Method == http.MethodGet {
+			return role.Reader
+		}
+		return role.Admin
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		apiKey         string
+		expectedStatus int
+		expectedCode   string
+		expectedRole   role.Role
+	}{
+		{name: "anonymous read", method: http.MethodGet, expectedStatus: http.StatusUnauthorized, expectedCode: "auth_required"},
+		{name: "reader read", method: http.MethodGet, apiKey: "r-key", expectedStatus: http.StatusOK, expectedRole: role.Reader},
+		{name: "writer by default", method: http.MethodGet, apiKey: "w-key", expectedStatus: http.StatusOK, expectedRole: ""},
+		{name: "reader change", method: http.MethodPost, apiKey: "r-key", expectedStatus: http.StatusForbidden, expectedCode: "insufficient_role"},
+		{name: "writer change", method: http.MethodPost, apiKey: "w-key", expectedStatus: http.StatusForbidden, expectedCode: "insufficient_role"},
+		{name: "admin change", method: http.MethodPost, apiKey: "a-key", expectedStatus: http.StatusOK, expectedRole: role.Admin},
+		{name: "unknown key", method: http.MethodGet, apiKey: "x-key", expectedStatus: http.StatusUnauthorized, expectedCode: "invalid_api_key"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotRole role.Role
+			handler := auth.New(logger, keys)(auth.RequireFor(logger, roles, required)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var ok bool
+				if gotRole, ok = auth.Role(r.Context()); !ok {
+					t.Error("expected the role in the request context")
+				}
+			})))
+			req := httptest.NewRequest(tc.method, "/", nil)
+			if tc.apiKey != "" {
+				req.Header.Set(auth.APIKeyHeader, tc.apiKey)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tc.expectedStatus == http.StatusOK {
+				if gotRole != tc.expectedRole {
+					t.Fatalf("expected role %q, got %q", tc.expectedRole, gotRole)
+				}
+				return
+			}
+			var resp models.ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || resp.Code != tc.expectedCode {
+				t.Fatalf("expected code %s, got %s", tc.expectedCode, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireAnonymous(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := auth.Require(logger, auth.Roles{}, role.Writer)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := auth.Principal(r.Context()); ok {
+			t.Error("expected no principal for an anonymous request")
+		}
+	}))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected anonymous requests to be writers by default, got %d", rr.Code)
+	}
+}
+
+func TestAuthMethod(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := []struct {
+		name       string
+		apiKey     string
+		wantMethod auth.Method
+	}{
+		{name: "api key", apiKey: "w-key", wantMethod: auth.MethodAPIKey},
+		{name: "anonymous"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotMethod auth.Method
+			handler := auth.New(logger, map[string]string{"w-key": "app"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotMethod, _ = auth.AuthMethod(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.apiKey != "" {
+				req.Header.Set(auth.APIKeyHeader, tc.apiKey)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if gotMethod != tc.wantMethod {
+				t.Fatalf("expected method %q, got %q", tc.wantMethod, gotMethod)
+			}
+		})
+	}
+}
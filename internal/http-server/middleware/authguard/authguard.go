@@ -0,0 +1,167 @@
+// Package authguard counts the requests the auth middlewares refuse and
+// locks out the clients that keep presenting bad credentials.
+package authguard
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/headers"
+	"quotes-service/internal/http-server/middleware/auth"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/lockout"
+	"quotes-service/internal/lib/signing"
+)
+
+func init() {
+	headers.Expose("Retry-After")
+}
+
+// Options configures New.
+type Options struct {
+	// Tracker locks out remote IPs and presented credentials that fail too
+	// often. Nil only counts failures.
+	Tracker *lockout.Tracker
+	// Failures counts failures by reason, the code of the 401. It has a
+	// single "reason" label.
+	Failures *prometheus.CounterVec
+}
+
+// NewFailures returns the auth_failures_total counter for Options.
+func NewFailures() *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_failures_total",
+		Help: "Requests whose credentials were refused, by reason.",
+	}, []string{"reason"})
+}
+
+// New must run outside every middleware that authenticates requests, which
+// report failures with auth.Fail. A failure counts against the remote IP
+// and the credential presented: a hash of the API key, or the client of
+// a signature. Bearer JWTs all start alike, so they count against the IP
+// alone. Expired tokens and stale signatures are counted but do not lead
+// to a lockout, as they come from honest clients with old credentials.
+//
+// While either is locked out, requests get 429 too_many_auth_failures with
+// Retry-After, whatever credentials they present. Authenticating resets
+// both.
+func New(log *slog.Logger, opts Options) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		middlewareLog := log.With(
+			slog.String("component", "middleware/authguard"),
+		)
+
+		middlewareLog.Info("auth guard middleware enabled", slog.Bool("lockout", opts.Tracker != nil))
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ip := remoteIP(r)
+			keys := []string{"ip:" + ip}
+			if credential := credential(r); credential != "" {
+				keys = append(keys, credential)
+			}
+
+			if opts.Tracker != nil {
+				for _, key := range keys {
+					if wait := opts.Tracker.Locked(key); wait > 0 {
+						middlewareLog.InfoContext(r.Context(), "request during auth lockout", slog.String("ip", ip), slog.String("path", r.URL.Path))
+						w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(wait.Seconds())), 1)))
+						response.Error(w, r, http.StatusTooManyRequests, apierror.CodeTooManyAuthFailures, nil)
+						return
+					}
+				}
+			}
+
+			gw := &writer{ResponseWriter: w}
+			next.ServeHTTP(gw, r)
+
+			switch {
+			case gw.failed != "":
+				if opts.Failures != nil {
+					opts.Failures.WithLabelValues(string(gw.failed)).Inc()
+				}
+				if opts.Tracker == nil || !lockable(gw.failed) {
+					return
+				}
+				for _, key := range keys {
+					if lockedFor := opts.Tracker.Fail(key); lockedFor > 0 {
+						kind, _, _ := strings.Cut(key, ":")
+						middlewareLog.WarnContext(r.Context(), "auth lockout triggered",
+							slog.String("ip", ip),
+							slog.String("by", kind),
+							slog.String("reason", string(gw.failed)),
+							slog.Duration("duration", lockedFor),
+						)
+					}
+				}
+			case gw.authenticated && opts.Tracker != nil:
+				for _, key := range keys {
+					opts.Tracker.Succeed(key)
+				}
+			}
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// lockable reports whether a failure with code counts towards a lockout.
+func lockable(code apierror.Code) bool {
+	return code != apierror.CodeTokenExpired && code != apierror.CodeStaleSignature
+}
+
+// credential returns the tracking key of the credentials r presents, or
+// "". An API key is tracked by a truncated SHA-256 of the whole of it, so
+// the tracker never holds a key, and keys that only share a start, as
+// those with the qs_ prefix do, are tracked apart.
+func credential(r *http.Request) string {
+	if client := r.Header.Get(signing.ClientHeader); client != "" {
+		return "client:" + client
+	}
+	key := auth.APIKey(r)
+	if key == "" || strings.Count(key, ".") == 2 {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "key:" + hex.EncodeToString(sum[:16])
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return host
+}
+
+// writer learns from the auth middlewares whether they authenticated the
+// request or refused its credentials.
+type writer struct {
+	http.ResponseWriter
+	authenticated bool
+	failed        apierror.Code
+}
+
+func (w *writer) SetPrincipal(string) {
+	w.authenticated = true
+}
+
+func (w *writer) AuthFailed(code apierror.Code) {
+	w.failed = code
+}
+
+func (w *writer) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,139 @@
+package authguard_test
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/middleware/auth"
+	"quotes-service/internal/http-server/middleware/authguard"
+	"quotes-service/internal/lib/lockout"
+)
+
+func TestCredentialStuffing(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	tracker := lockout.New(lockout.Options{
+		MaxFailures: 5,
+		Window:      time.Minute,
+		Lockout:     30 * time.Second,
+		MaxLockout:  time.Hour,
+		MaxKeys:     1000,
+	}, lockout.WithClock(func() time.Time { return now }))
+	failures := authguard.NewFailures()
+	handler := authguard.New(logger, authguard.Options{Tracker: tracker, Failures: failures})(
+		auth.New(logger, map[string]string{"valid-key-123": "app"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	send := func(ip, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/quotes", nil)
+		req.RemoteAddr = ip + ":40000"
+		if key != "" {
+			req.Header.Set(auth.APIKeyHeader, key)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// A burst of guessed keys from one address: the first five are refused
+	// as invalid, the rest locked out without being checked.
+	for i := 0; i < 50; i++ {
+		rr := send("203.0.113.7", fmt.Sprintf("guess-%04d-key", i))
+		expected := http.StatusUnauthorized
+		if i >= 5 {
+			expected = http.StatusTooManyRequests
+		}
+		if rr.Code != expected {
+			t.Fatalf("attempt %d: expected %d, got %d", i, expected, rr.Code)
+		}
+	}
+	if got := testutil.ToFloat64(failures.WithLabelValues(string(apierror.CodeInvalidAPIKey))); got != 5 {
+		t.Fatalf("expected 5 counted failures, got %v", got)
+	}
+
+	// The lockout holds for a valid key too, and says when to come back.
+	rr := send("203.0.113.7", "valid-key-123")
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "30" {
+		t.Fatalf("expected 429 with Retry-After 30, got %d %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+	// Other addresses are not affected.
+	if rr := send("198.51.100.1", "valid-key-123"); rr.Code != http.StatusOK {
+		t.Fatalf("expected another address to get through, got %d", rr.Code)
+	}
+
+	// After the lockout a success resets the count, so the next lockout
+	// takes five more failures and is not doubled.
+	now = now.Add(30 * time.Second)
+	if rr := send("203.0.113.7", "valid-key-123"); rr.Code != http.StatusOK {
+		t.Fatalf("expected the lockout over, got %d", rr.Code)
+	}
+	for i := 0; i < 5; i++ {
+		send("203.0.113.7", fmt.Sprintf("again-%04d-key", i))
+	}
+	if rr := send("203.0.113.7", "valid-key-123"); rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "30" {
+		t.Fatalf("expected a fresh 30s lockout, got %d %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+}
+
+func TestKeyLockout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tracker := lockout.New(lockout.Options{MaxFailures: 3, Window: time.Minute, Lockout: time.Minute, MaxLockout: time.Hour})
+	handler := authguard.New(logger, authguard.Options{Tracker: tracker})(
+		auth.New(logger, map[string]string{"qs_0123456789abcdef": "app"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	send := func(ip, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/quotes", nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set(auth.APIKeyHeader, key)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Bad keys sharing the valid key's start, from many addresses, lock
+	// out none but themselves.
+	for i := 0; i < 3; i++ {
+		send(fmt.Sprintf("192.0.2.%d", i), fmt.Sprintf("qs_01234567%08d", i))
+	}
+	if rr := send("192.0.2.100", "qs_0123456789abcdef"); rr.Code != http.StatusOK {
+		t.Fatalf("expected a key sharing a prefix with refused ones accepted, got %d", rr.Code)
+	}
+
+	// A revoked key tried from many addresses is locked out.
+	for i := 0; i < 3; i++ {
+		send(fmt.Sprintf("198.51.100.%d", i), "qs_fedcba9876543210")
+	}
+	if rr := send("198.51.100.200", "qs_fedcba9876543210"); rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the key locked out, got %d", rr.Code)
+	}
+}
+
+func TestUnlockableFailures(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tracker := lockout.New(lockout.Options{MaxFailures: 2, Window: time.Minute, Lockout: time.Minute, MaxLockout: time.Hour})
+	failures := authguard.NewFailures()
+	// An identity provider's expired tokens are counted, but never lock.
+	expired := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth.Fail(w, apierror.CodeTokenExpired)
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	handler := authguard.New(logger, authguard.Options{Tracker: tracker, Failures: failures})(expired)
+
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/quotes", nil)
+		req.Header.Set("Authorization", "Bearer aaa.bbb.ccc")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i, rr.Code)
+		}
+	}
+	if got := testutil.ToFloat64(failures.WithLabelValues(string(apierror.CodeTokenExpired))); got != 10 {
+		t.Fatalf("expected 10 counted failures, got %v", got)
+	}
+}
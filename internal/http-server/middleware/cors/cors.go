@@ -0,0 +1,115 @@
+// Package cors lets browser clients on other origins call the API.
+package cors
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"quotes-service/internal/http-server/headers"
+)
+
+// Options configures New.
+type Options struct {
+	// AllowedOrigins are the origins allowed to call the API, such as
+	// "https://app.example.com". "*" allows any origin.
+	AllowedOrigins []string
+	// AllowedMethods and AllowedHeaders answer preflight requests.
+	AllowedMethods []string
+	AllowedHeaders []string
+	// ExposeHeaders are response headers browsers may read on top of the
+	// ones registered with headers.Expose.
+	ExposeHeaders []string
+	// AllowCredentials lets browsers send cookies and HTTP auth along.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight answer. Zero leaves
+	// it to the browser.
+	MaxAge time.Duration
+}
+
+// New adds CORS headers to the responses to requests from allowed origins
+// and answers their preflight requests with 204. Requests without an
+// Origin, or from other origins, pass through untouched, so the browser
+// keeps their responses from the page.
+//
+// Access-Control-Expose-Headers lists every header registered with
+// headers.Expose when New is called, along with opts.ExposeHeaders. It
+// must wrap the whole router, since the router does not run its
+// middleware for the OPTIONS requests it has no route for.
+func New(log *slog.Logger, opts Options) func(next http.Handler) http.Handler {
+	anyOrigin := slices.Contains(opts.AllowedOrigins, "*")
+	origins := make(map[string]bool, len(opts.AllowedOrigins))
+	for _, origin := range opts.AllowedOrigins {
+		origins[strings.ToLower(origin)] = true
+	}
+
+	exposed := headers.Exposed()
+	for _, name := range opts.ExposeHeaders {
+		if name = http.CanonicalHeaderKey(name); !slices.Contains(exposed, name) {
+			exposed = append(exposed, name)
+		}
+	}
+	expose := strings.Join(exposed, ", ")
+	methods := strings.Join(opts.AllowedMethods, ", ")
+	allowHeaders := strings.Join(opts.AllowedHeaders, ", ")
+
+	return func(next http.Handler) http.Handler {
+		middlewareLog := log.With(
+			slog.String("component", "middleware/cors"),
+		)
+
+		middlewareLog.Info("cors middleware enabled",
+			slog.Any("allowed_origins", opts.AllowedOrigins),
+			slog.Any("expose_headers", exposed),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header := w.Header()
+			header.Add("Vary", "Origin")
+			if !anyOrigin && !origins[strings.ToLower(origin)] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// A wildcard cannot carry credentials, so the origin is echoed
+			// back then.
+			if anyOrigin && !opts.AllowCredentials {
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				header.Set("Access-Control-Allow-Origin", origin)
+			}
+			if opts.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				header.Add("Vary", "Access-Control-Request-Method")
+				header.Add("Vary", "Access-Control-Request-Headers")
+				header.Set("Access-Control-Allow-Methods", methods)
+				if allowHeaders != "" {
+					header.Set("Access-Control-Allow-Headers", allowHeaders)
+				}
+				if opts.MaxAge > 0 {
+					header.Set("Access-Control-Max-Age", strconv.Itoa(int(opts.MaxAge.Seconds())))
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if expose != "" {
+				header.Set("Access-Control-Expose-Headers", expose)
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,142 @@
+package cors_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"quotes-service/internal/http-server/middleware/cors"
+	"quotes-service/internal/http-server/middleware/logger"
+	mwRateLimit "quotes-service/internal/http-server/middleware/ratelimit"
+	"quotes-service/internal/lib/ratelimit"
+)
+
+func TestCORS(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	limiter := ratelimit.New(10, 10, 100)
+	handler := cors.New(log, cors.Options{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		ExposeHeaders:  []string{"x-build-version"},
+		MaxAge:         10 * time.Minute,
+	})(logger.New(log)(mwRateLimit.New(log, mwRateLimit.Options{IP: limiter})(ok)))
+
+	tests := []struct {
+		name        string
+		method      string
+		headers     map[string]string
+		wantCode    int
+		wantOrigin  string
+		wantExposed []string
+		wantAllowed string
+	}{
+		{
+			name:       "cross origin",
+			method:     http.MethodGet,
+			headers:    map[string]string{"Origin": "https://app.example.com"},
+			wantCode:   http.StatusOK,
+			wantOrigin: "https://app.example.com",
+			wantExposed: []string{
+				http.CanonicalHeaderKey(logger.RequestIDHeader),
+				http.CanonicalHeaderKey(mwRateLimit.HeaderLimit),
+				http.CanonicalHeaderKey(mwRateLimit.HeaderRemaining),
+				http.CanonicalHeaderKey(mwRateLimit.HeaderReset),
+				"Retry-After",
+				"X-Build-Version",
+			},
+		},
+		{
+			name:   "preflight",
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  "POST",
+				"Access-Control-Request-Headers": "content-type",
+			},
+			wantCode:    http.StatusNoContent,
+			wantOrigin:  "https://app.example.com",
+			wantAllowed: "GET, POST",
+		},
+		{
+			name:     "other origin",
+			method:   http.MethodGet,
+			headers:  map[string]string{"Origin": "https://evil.example.com"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "same origin",
+			method:   http.MethodGet,
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/quotes", nil)
+			for name, value := range tc.headers {
+				req.Header.Set(name, value)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d", tc.wantCode, rr.Code)
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Origin"); got != tc.wantOrigin {
+				t.Fatalf("expected allowed origin %q, got %q", tc.wantOrigin, got)
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Methods"); got != tc.wantAllowed {
+				t.Fatalf("expected allowed methods %q, got %q", tc.wantAllowed, got)
+			}
+
+			exposed := strings.Split(rr.Header().Get("Access-Control-Expose-Headers"), ", ")
+			for _, name := range tc.wantExposed {
+				found := false
+				for _, got := range exposed {
+					found = found || got == name
+				}
+				if !found {
+					t.Fatalf("expected %s to be exposed, got %v", name, exposed)
+				}
+			}
+			if tc.wantExposed == nil && rr.Header().Get("Access-Control-Expose-Headers") != "" {
+				t.Fatalf("expected no exposed headers, got %v", exposed)
+			}
+		})
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name        string
+		credentials bool
+		wantOrigin  string
+	}{
+		{name: "without credentials", wantOrigin: "*"},
+		{name: "with credentials", credentials: true, wantOrigin: "https://app.example.com"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := cors.New(log, cors.Options{AllowedOrigins: []string{"*"}, AllowCredentials: tc.credentials})(ok)
+			req := httptest.NewRequest(http.MethodGet, "/quotes", nil)
+			req.Header.Set("Origin", "https://app.example.com")
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if got := rr.Header().Get("Access-Control-Allow-Origin"); got != tc.wantOrigin {
+				t.Fatalf("expected allowed origin %q, got %q", tc.wantOrigin, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// Package debugheaders tells support which instance, storage backend and
+// data version served a response, for reports of stale reads behind a
+// cache.
+package debugheaders
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"quotes-service/internal/http-server/headers"
+	"quotes-service/internal/storage"
+)
+
+const (
+	// BackendHeader names the storage backend, as storage.Backend does.
+	BackendHeader = "X-Backend"
+	// DataVersionHeader carries the store's mutation counter, the one the
+	// ETags of the lists are made from.
+	DataVersionHeader = "X-Data-Version"
+	// InstanceHeader names the instance that served the response.
+	InstanceHeader = "X-Instance"
+)
+
+func init() {
+	headers.Expose(BackendHeader, DataVersionHeader, InstanceHeader)
+}
+
+// Store is what the headers are read from.
+type Store interface {
+	Version(ctx context.Context) (uint64, error)
+}
+
+// New sets the X-Backend, X-Data-Version and X-Instance headers on every
+// response. They are read as the response starts, after the handler has
+// done its work, so a write reports the version it produced. A version
+// that cannot be read is left out rather than failing the response.
+func New(log *slog.Logger, store Store, instance string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		middlewareLog := log.With(
+			slog.String("component", "middleware/debugheaders"),
+		)
+
+		middlewareLog.Info("debug headers middleware enabled", slog.String("instance", instance))
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			dw := &writer{ResponseWriter: w}
+			dw.setHeaders = func() {
+				h := w.Header()
+				h.Set(BackendHeader, storage.Backend(store))
+				h.Set(InstanceHeader, instance)
+				// The request may have been canceled, but its response is
+				// still being written.
+				version, err := store.Version(context.WithoutCancel(r.Context()))
+				if err != nil {
+					middlewareLog.WarnContext(r.Context(), "failed to read data version", slog.String("error", err.Error()))
+					return
+				}
+				h.Set(DataVersionHeader, strconv.FormatUint(version, 10))
+			}
+			next.ServeHTTP(dw, r)
+			// A handler that wrote nothing gets the headers with the
+			// implicit 200.
+			dw.start()
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+type writer struct {
+	http.ResponseWriter
+	setHeaders func()
+	started    bool
+}
+
+func (w *writer) start() {
+	if !w.started {
+		w.started = true
+		w.setHeaders()
+	}
+}
+
+func (w *writer) WriteHeader(code int) {
+	w.start()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	w.start()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *writer) Flush() {
+	w.start()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,63 @@
+package debugheaders_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"quotes-service/internal/http-server/middleware/debugheaders"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage/faultstorage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func TestNew(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	// Wrapped like in production, to check the backend is seen through.
+	handler := debugheaders.New(logger, faultstorage.New(store), "api-7")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			if _, err := store.AddQuote(r.Context(), models.Quote{Text: "Less is more.", Author: "Mies"}); err != nil {
+				t.Errorf("failed to add quote: %v", err)
+			}
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+
+	tests := []struct {
+		name            string
+		method          string
+		expectedVersion string
+	}{
+		{name: "read", method: http.MethodGet, expectedVersion: "0"},
+		{name: "write reports its own version", method: http.MethodPost, expectedVersion: "1"},
+		{name: "read after write", method: http.MethodGet, expectedVersion: "1"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(tc.method, "/quotes", nil))
+			h := rr.Header()
+			if got := h.Get(debugheaders.BackendHeader); got != "memory" {
+				t.Errorf("expected backend memory, got %q", got)
+			}
+			if got := h.Get(debugheaders.InstanceHeader); got != "api-7" {
+				t.Errorf("expected instance api-7, got %q", got)
+			}
+			if got := h.Get(debugheaders.DataVersionHeader); got != tc.expectedVersion {
+				t.Errorf("expected data version %s, got %q", tc.expectedVersion, got)
+			}
+		})
+	}
+
+	if v, _ := store.Version(context.Background()); v != 1 {
+		t.Fatalf("expected one write, store is at version %d", v)
+	}
+}
@@ -0,0 +1,139 @@
+// Package deprecation tells clients that call a deprecated route, or one
+// whose responses carry a deprecated field, with the Deprecation, Sunset
+// and Link headers of RFC 9745 and RFC 8594, and records who they are.
+package deprecation
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+
+	"quotes-service/internal/http-server/headers"
+	"quotes-service/internal/http-server/middleware/auth"
+	"quotes-service/internal/http-server/middleware/route"
+	"quotes-service/internal/lib/deprecation"
+)
+
+const (
+	DeprecationHeader = "Deprecation"
+	SunsetHeader      = "Sunset"
+)
+
+func init() {
+	headers.Expose(DeprecationHeader, SunsetHeader, "Link")
+}
+
+// New marks the responses of deprecated routes. A response that uses
+// several deprecated entries carries the earliest deprecation and sunset
+// and a link to each entry's documentation. A deprecated field is reported
+// on every response of its route, which serves it whether or not the
+// client reads it.
+//
+// Each use is recorded under the caller, its principal or else its remote
+// IP, and logged at most once per log interval for each caller and entry,
+// with the uses since the last line. It must run after the auth middleware.
+func New(log *slog.Logger, registry *deprecation.Registry) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		middlewareLog := log.With(
+			slog.String("component", "middleware/deprecation"),
+		)
+
+		middlewareLog.Info("deprecation middleware enabled")
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			caller := Caller(r)
+			uses := registry.Record(r.Method, route.Template(r), caller)
+			if len(uses) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			for _, use := range uses {
+				if use.Log {
+					middlewareLog.WarnContext(r.Context(), "deprecated API used",
+						slog.String("deprecated", use.Subject()),
+						slog.String("caller", caller),
+						slog.Time("sunset", use.Sunset),
+						slog.Int64("uses", use.Uses),
+					)
+				}
+			}
+
+			// Set as the response starts, so that a handler setting Link
+			// itself, such as for pagination, does not drop the links.
+			dw := &writer{ResponseWriter: w}
+			dw.setHeaders = func() {
+				setHeaders(w.Header(), uses)
+			}
+			next.ServeHTTP(dw, r)
+			dw.start()
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Caller is the key the uses of r are recorded under: "principal:" and
+// the principal for an authenticated request, "ip:" and the remote address
+// for others, as the rate limits count them.
+func Caller(r *http.Request) string {
+	if principal, ok := auth.Principal(r.Context()); ok {
+		return "principal:" + principal
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func setHeaders(h http.Header, uses []deprecation.Use) {
+	since, sunset := uses[0].Since, uses[0].Sunset
+	for _, use := range uses {
+		if use.Since.Before(since) {
+			since = use.Since
+		}
+		if use.Sunset.Before(sunset) {
+			sunset = use.Sunset
+		}
+		if use.Link != "" {
+			h.Add("Link", "<"+use.Link+`>; rel="deprecation"; type="text/html"`)
+		}
+	}
+	h.Set(DeprecationHeader, "@"+strconv.FormatInt(since.Unix(), 10))
+	h.Set(SunsetHeader, sunset.UTC().Format(http.TimeFormat))
+}
+
+type writer struct {
+	http.ResponseWriter
+	setHeaders func()
+	started    bool
+}
+
+func (w *writer) start() {
+	if !w.started {
+		w.started = true
+		w.setHeaders()
+	}
+}
+
+func (w *writer) WriteHeader(code int) {
+	w.start()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	w.start()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *writer) Flush() {
+	w.start()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,139 @@
+package deprecation_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/middleware/auth"
+	mwDeprecation "quotes-service/internal/http-server/middleware/deprecation"
+	"quotes-service/internal/lib/deprecation"
+)
+
+func TestNew(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	registry := deprecation.New([]deprecation.Entry{
+		{
+			Method: http.MethodGet,
+			Route:  "/quotes/digest",
+			Since:  time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC),
+			Sunset: time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC),
+			Link:   "https://docs.example.com/digest",
+		},
+		{
+			Route:  "/quotes/{id}",
+			Field:  "author_key",
+			Since:  time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+			Sunset: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+			Link:   "https://docs.example.com/author-key",
+		},
+	}, deprecation.Options{LogInterval: time.Hour}, deprecation.WithClock(func() time.Time { return now }))
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		// Pagination sets its own Link, which must not drop the deprecation's.
+		w.Header().Set("Link", `</quotes?page=2>; rel="next"`)
+		w.WriteHeader(http.StatusOK)
+	}
+	router := mux.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key := r.Header.Get("X-Test-Principal"); key != "" {
+				r = r.WithContext(auth.WithPrincipal(r.Context(), key))
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	router.Use(mwDeprecation.New(logger, registry))
+	router.HandleFunc("/quotes", ok)
+	router.HandleFunc("/quotes/digest", ok).Methods(http.MethodGet, http.MethodPost)
+	router.HandleFunc("/quotes/{id:[0-9]+}", ok)
+
+	tests := []struct {
+		name            string
+		method          string
+		url             string
+		principal       string
+		advance         time.Duration
+		expectedDep     string
+		expectedSunset  string
+		expectedLinks   int
+		expectedWarning bool
+	}{
+		{name: "deprecated route", method: http.MethodGet, url: "/quotes/digest", expectedDep: "@1777593600", expectedSunset: "Tue, 01 Dec 2026 00:00:00 GMT", expectedLinks: 2, expectedWarning: true},
+		{name: "same caller within the interval", method: http.MethodGet, url: "/quotes/digest", advance: time.Minute, expectedDep: "@1777593600", expectedSunset: "Tue, 01 Dec 2026 00:00:00 GMT", expectedLinks: 2},
+		{name: "another caller", method: http.MethodGet, url: "/quotes/digest", principal: "alice", expectedDep: "@1777593600", expectedSunset: "Tue, 01 Dec 2026 00:00:00 GMT", expectedLinks: 2, expectedWarning: true},
+		{name: "same caller after the interval", method: http.MethodGet, url: "/quotes/digest", advance: time.Hour, expectedDep: "@1777593600", expectedSunset: "Tue, 01 Dec 2026 00:00:00 GMT", expectedLinks: 2, expectedWarning: true},
+		{name: "other method of the route", method: http.MethodPost, url: "/quotes/digest", expectedLinks: 1},
+		{name: "deprecated field", method: http.MethodGet, url: "/quotes/42", expectedDep: "@1775001600", expectedSunset: "Fri, 01 Jan 2027 00:00:00 GMT", expectedLinks: 2, expectedWarning: true},
+		{name: "not deprecated", method: http.MethodGet, url: "/quotes", expectedLinks: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			now = now.Add(tc.advance)
+			logs.Reset()
+			req := httptest.NewRequest(tc.method, tc.url, nil)
+			if tc.principal != "" {
+				req.Header.Set("X-Test-Principal", tc.principal)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			h := rr.Header()
+			if got := h.Get(mwDeprecation.DeprecationHeader); got != tc.expectedDep {
+				t.Errorf("expected Deprecation %q, got %q", tc.expectedDep, got)
+			}
+			if got := h.Get(mwDeprecation.SunsetHeader); got != tc.expectedSunset {
+				t.Errorf("expected Sunset %q, got %q", tc.expectedSunset, got)
+			}
+			if got := h.Values("Link"); len(got) != tc.expectedLinks {
+				t.Errorf("expected %d Link headers, got %q", tc.expectedLinks, got)
+			}
+			if warned := strings.Contains(logs.String(), "deprecated API used"); warned != tc.expectedWarning {
+				t.Errorf("expected warning %v, got logs %q", tc.expectedWarning, logs.String())
+			}
+		})
+	}
+
+	usage := registry.Usage()
+	var callers []string
+	for _, c := range usage[0].Callers {
+		callers = append(callers, c.Caller)
+	}
+	if usage[0].Uses != 4 || strings.Join(callers, ",") != "ip:192.0.2.1,principal:alice" {
+		t.Fatalf("expected 4 uses by the IP and alice, got %d by %v", usage[0].Uses, callers)
+	}
+}
+
+func TestCaller(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  string
+		remoteAddr string
+		expected   string
+	}{
+		{name: "principal", principal: "alice", remoteAddr: "192.0.2.1:1234", expected: "principal:alice"},
+		{name: "ip", remoteAddr: "192.0.2.1:1234", expected: "ip:192.0.2.1"},
+		{name: "ip without port", remoteAddr: "192.0.2.1", expected: "ip:192.0.2.1"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.principal != "" {
+				req = req.WithContext(auth.WithPrincipal(req.Context(), tc.principal))
+			}
+			if got := mwDeprecation.Caller(req); got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+// Package dialect renames quote fields for clients that expect other names
+// for them than the API's own.
+package dialect
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/middleware/auth"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/logger/sl"
+)
+
+// Header selects a dialect by name for one request.
+const Header = "X-Response-Dialect"
+
+// Dialect maps canonical quote field names, such as "text", to the names a
+// client uses instead. Fields it does not name keep their names.
+type Dialect map[string]string
+
+// Options configures New.
+type Options struct {
+	// Dialects holds the dialects by name.
+	Dialects map[string]Dialect
+	// Principals maps a principal to the dialect its requests get when
+	// they do not name one in the header.
+	Principals map[string]string
+}
+
+// renamer renames the fields of quote objects, which are the objects that
+// carry either of the keys in marks.
+type renamer struct {
+	names map[string]string
+	marks [2]string
+}
+
+// New renames quote fields in the JSON and JSON Lines bodies of requests
+// that use a dialect, named in the X-Response-Dialect header or configured
+// for the request's principal: canonical names become the dialect's on the
+// way out, and the dialect's names canonical on the way in, at any depth
+// and in every envelope. A quote object is one with a "text" or "author"
+// field. An unknown dialect in the header gets 400 unknown_dialect.
+// Responses in a dialect are buffered to be rewritten, so this is not for
+// streaming.
+//
+// It must run after auth.New, and before middleware that reads request
+// bodies or checks responses against the canonical schema.
+func New(log *slog.Logger, opts Options) func(next http.Handler) http.Handler {
+	type rewrite struct{ in, out renamer }
+	rewrites := make(map[string]rewrite, len(opts.Dialects))
+	for name, d := range opts.Dialects {
+		out := renamer{names: d, marks: [2]string{"text", "author"}}
+		in := renamer{names: make(map[string]string, len(d)), marks: out.marks}
+		for canonical, alias := range d {
+			in.names[alias] = canonical
+		}
+		for i, mark := range in.marks {
+			if alias, ok := d[mark]; ok {
+				in.marks[i] = alias
+			}
+		}
+		rewrites[name] = rewrite{in: in, out: out}
+	}
+
+	return func(next http.Handler) http.Handler {
+		middlewareLog := log.With(
+			slog.String("component", "middleware/dialect"),
+		)
+
+		middlewareLog.Info("dialect middleware enabled",
+			slog.Int("dialects", len(opts.Dialects)),
+			slog.Int("principals", len(opts.Principals)),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			w.Header().Add("Vary", Header)
+
+			name := r.Header.Get(Header)
+			if principal, ok := auth.Principal(ctx); ok && name == "" {
+				name = opts.Principals[principal]
+			}
+			if name == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			rw, ok := rewrites[name]
+			if !ok {
+				middlewareLog.InfoContext(ctx, "unknown dialect", sl.UserText("dialect", name))
+				response.Error(w, r, http.StatusBadRequest, apierror.CodeUnknownDialect, nil, name)
+				return
+			}
+
+			if r.Body != nil && r.Body != http.NoBody {
+				contentType := mediaType(r.Header.Get("Content-Type"))
+				if contentType == "" {
+					contentType = "application/json"
+				}
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					middlewareLog.WarnContext(ctx, "failed to read request body", slog.String("error", err.Error()))
+					response.Error(w, r, http.StatusBadRequest, apierror.CodeRequestBodyInvalid, nil)
+					return
+				}
+				// A body that does not parse goes to the handler as sent,
+				// for it to reject.
+				if renamed, err := rw.in.body(contentType, body); err == nil {
+					body = renamed
+				}
+				r.Body = readCloser{Reader: bytes.NewReader(body), Closer: r.Body}
+				r.ContentLength = int64(len(body))
+				r.Header.Del("Content-Length")
+			}
+
+			bw := &bufferedWriter{ResponseWriter: w}
+			next.ServeHTTP(bw, r)
+
+			body := bw.body.Bytes()
+			if len(body) > 0 {
+				renamed, err := rw.out.body(mediaType(w.Header().Get("Content-Type")), body)
+				if err != nil {
+					middlewareLog.ErrorContext(ctx, "failed to rewrite response", slog.String("path", r.URL.Path), slog.String("error", err.Error()))
+				} else {
+					body = renamed
+					w.Header().Del("Content-Length")
+				}
+			}
+
+			if bw.status != 0 {
+				w.WriteHeader(bw.status)
+			}
+			if len(body) > 0 {
+				w.Write(body)
+			}
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// bufferedWriter holds the status and body back until the handler is done.
+type bufferedWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (bw *bufferedWriter) WriteHeader(status int) {
+	if bw.status == 0 {
+		bw.status = status
+	}
+}
+
+func (bw *bufferedWriter) Write(b []byte) (int, error) {
+	if bw.status == 0 {
+		bw.status = http.StatusOK
+	}
+	return bw.body.Write(b)
+}
+
+func (bw *bufferedWriter) Unwrap() http.ResponseWriter {
+	return bw.ResponseWriter
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func mediaType(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType
+}
+
+// body renames the fields of a JSON or JSON Lines body. Bodies of other
+// types are returned as they are.
+func (rn renamer) body(mediaType string, body []byte) ([]byte, error) {
+	switch mediaType {
+	case "application/json":
+		renamed, err := rn.value(bytes.TrimSpace(body))
+		if err != nil {
+			return nil, err
+		}
+		return append(renamed, '\n'), nil
+	case "application/x-ndjson":
+		var buf bytes.Buffer
+		for line := range bytes.Lines(body) {
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 {
+				continue
+			}
+			renamed, err := rn.value(line)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(renamed)
+			buf.WriteByte('\n')
+		}
+		return buf.Bytes(), nil
+	default:
+		return body, nil
+	}
+}
+
+// value rewrites one JSON value. Keys keep their order and scalars their
+// exact encoding.
+func (rn renamer) value(raw []byte) ([]byte, error) {
+	if len(raw) == 0 {
+		return raw, nil
+	}
+	switch raw[0] {
+	case '{':
+		return rn.object(raw)
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		buf.WriteByte('[')
+		for i, item := range items {
+			renamed, err := rn.value(item)
+			if err != nil {
+				return nil, err
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(renamed)
+		}
+		buf.WriteByte(']')
+		return buf.Bytes(), nil
+	default:
+		if !json.Valid(raw) {
+			return nil, errors.New("invalid JSON value")
+		}
+		return raw, nil
+	}
+}
+
+func (rn renamer) object(raw []byte) ([]byte, error) {
+	type field struct {
+		key   string
+		value json.RawMessage
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	var fields []field
+	isQuote := false
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, errors.New("object key is not a string")
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if key == rn.marks[0] || key == rn.marks[1] {
+			isQuote = true
+		}
+		fields = append(fields, field{key: key, value: value})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range fields {
+		value, err := rn.value(f.value)
+		if err != nil {
+			return nil, err
+		}
+		name := f.key
+		if renamed, ok := rn.names[name]; ok && isQuote {
+			name = renamed
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,169 @@
+package dialect_test
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"quotes-service/internal/http-server/handlers/quotehandler"
+	"quotes-service/internal/http-server/middleware/auth"
+	"quotes-service/internal/http-server/middleware/dialect"
+	"quotes-service/internal/http-server/pagination"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+var options = dialect.Options{
+	Dialects: map[string]dialect.Dialect{
+		"legacy": {"text": "quote", "author": "quoteAuthor"},
+	},
+	Principals: map[string]string{"partner": "legacy"},
+}
+
+func TestNew(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name           string
+		header         string
+		principal      string
+		contentType    string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "header",
+			header:         "legacy",
+			contentType:    "application/json",
+			body:           `{"status":"success","data":{"id":1,"text":"a < b","author":"A","tags":["text"]}}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"id":1,"quote":"a < b","quoteAuthor":"A","tags":["text"]}}` + "\n",
+		},
+		{
+			name:           "principal",
+			principal:      "partner",
+			contentType:    "application/json; charset=utf-8",
+			body:           `{"id":7,"name":"Favourites","quotes":[{"id":2,"text":"x","author":"A"},{"id":3,"text":"y","author":"B"}]}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":7,"name":"Favourites","quotes":[{"id":2,"quote":"x","quoteAuthor":"A"},{"id":3,"quote":"y","quoteAuthor":"B"}]}` + "\n",
+		},
+		{
+			name:           "json lines",
+			header:         "legacy",
+			contentType:    "application/x-ndjson",
+			body:           `{"text":"x","author":"A"}` + "\n" + `{"text":"y","author":"B"}` + "\n",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"quote":"x","quoteAuthor":"A"}` + "\n" + `{"quote":"y","quoteAuthor":"B"}` + "\n",
+		},
+		{
+			name:           "other objects keep their fields",
+			header:         "legacy",
+			contentType:    "application/json",
+			body:           `{"status":"success","data":{"text":"x","author":"A","held":{"text_hash":"1"}}}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","data":{"quote":"x","quoteAuthor":"A","held":{"text_hash":"1"}}}` + "\n",
+		},
+		{
+			name:           "no dialect",
+			principal:      "someone",
+			contentType:    "application/json",
+			body:           `{"text":"x","author":"A"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"text":"x","author":"A"}`,
+		},
+		{
+			name:           "unknown dialect",
+			header:         "modern",
+			principal:      "partner",
+			contentType:    "application/json",
+			body:           `{"text":"x","author":"A"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := dialect.New(logger, options)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tc.contentType)
+				io.WriteString(w, tc.body)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/quotes", nil)
+			if tc.header != "" {
+				req.Header.Set(dialect.Header, tc.header)
+			}
+			if tc.principal != "" {
+				req = req.WithContext(auth.WithPrincipal(req.Context(), tc.principal))
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tc.expectedStatus != http.StatusOK {
+				if !strings.Contains(rr.Body.String(), `"code":"unknown_dialect"`) {
+					t.Fatalf("expected an unknown_dialect error, got %s", rr.Body.String())
+				}
+				return
+			}
+			if got := rr.Body.String(); got != tc.expectedBody {
+				t.Fatalf("unexpected body\n got %s\nwant %s", got, tc.expectedBody)
+			}
+		})
+	}
+}
+
+func TestNewRoundTrip(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	mw := dialect.New(logger, options)
+	add := mw(quotehandler.NewAddQuoteHandler(logger, store))
+	list := mw(quotehandler.NewGetAllQuotesHandler(logger, store, nil, pagination.Sizes{Default: 20, Max: 100}))
+
+	req := httptest.NewRequest(http.MethodPost, "/quotes", strings.NewReader(`{"quote":"Stay hungry.","quoteAuthor":"Steve Jobs"}`))
+	req.Header.Set(dialect.Header, "legacy")
+	rr := httptest.NewRecorder()
+	add.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d. Body: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	var added map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &added); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if added["quote"] != "Stay hungry." || added["quoteAuthor"] != "Steve Jobs" || added["text"] != nil {
+		t.Fatalf("expected the quote in the dialect, got %v", added)
+	}
+
+	for _, tc := range []struct {
+		header        string
+		expectedText  string
+		expectedField string
+	}{
+		{header: "legacy", expectedText: "quote", expectedField: "quoteAuthor"},
+		{header: "", expectedText: "text", expectedField: "author"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/quotes", nil)
+		if tc.header != "" {
+			req.Header.Set(dialect.Header, tc.header)
+		}
+		rr := httptest.NewRecorder()
+		list.ServeHTTP(rr, req)
+		var resp struct {
+			Data []map[string]any `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(resp.Data) != 1 || resp.Data[0][tc.expectedText] != "Stay hungry." || resp.Data[0][tc.expectedField] != "Steve Jobs" {
+			t.Fatalf("dialect %q: expected fields %s and %s, got %v", tc.header, tc.expectedText, tc.expectedField, resp.Data)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Package envelope chooses per request whether responses are wrapped in
+// the standard envelope or written bare, for clients whose tooling expects
+// plain resources and RFC 7807 errors.
+package envelope
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/logger/sl"
+)
+
+// Param selects the envelope for one request: "false" for bare responses,
+// "true" for the envelope.
+const Param = "envelope"
+
+// New makes responses use the envelope or not as the request's ?envelope=
+// parameter says, and as enveloped says for requests without one. A value
+// that is not a boolean gets 400 invalid_parameter. It must run before any
+// middleware that writes responses, so their errors take the same shape.
+func New(log *slog.Logger, enveloped bool) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		middlewareLog := log.With(
+			slog.String("component", "middleware/envelope"),
+		)
+
+		middlewareLog.Info("envelope middleware enabled", slog.Bool("default", enveloped))
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			choice := enveloped
+			if value := r.URL.Query().Get(Param); value != "" {
+				parsed, err := strconv.ParseBool(value)
+				if err != nil {
+					r = r.WithContext(response.WithEnvelope(r.Context(), enveloped))
+					middlewareLog.InfoContext(r.Context(), "invalid envelope parameter", sl.UserText("value", value))
+					response.Error(w, r, http.StatusBadRequest, apierror.CodeInvalidParameter, nil, Param)
+					return
+				}
+				choice = parsed
+			}
+			next.ServeHTTP(w, r.WithContext(response.WithEnvelope(r.Context(), choice)))
+		}
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,72 @@
+package envelope_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"quotes-service/internal/http-server/middleware/envelope"
+	"quotes-service/internal/http-server/response"
+)
+
+func TestNew(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name              string
+		enveloped         bool
+		query             string
+		expectedStatus    int
+		expectedEnveloped bool
+		expectedType      string
+	}{
+		{name: "default envelope", enveloped: true, expectedStatus: http.StatusOK, expectedEnveloped: true},
+		{name: "default bare", expectedStatus: http.StatusOK},
+		{name: "opt out", enveloped: true, query: "?envelope=false", expectedStatus: http.StatusOK},
+		{name: "opt in", query: "?envelope=true", expectedStatus: http.StatusOK, expectedEnveloped: true},
+		{name: "numeric", enveloped: true, query: "?envelope=0", expectedStatus: http.StatusOK},
+		{
+			name:           "invalid in envelope",
+			enveloped:      true,
+			query:          "?envelope=maybe",
+			expectedStatus: http.StatusBadRequest,
+			expectedType:   "application/json",
+		},
+		{
+			name:           "invalid bare",
+			query:          "?envelope=maybe",
+			expectedStatus: http.StatusBadRequest,
+			expectedType:   response.ProblemContentType,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got *bool
+			handler := envelope.New(logger, tc.enveloped)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				enveloped := response.Enveloped(r)
+				got = &enveloped
+			}))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/quotes"+tc.query, nil))
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tc.expectedStatus != http.StatusOK {
+				if got != nil {
+					t.Fatal("expected an invalid parameter not to reach the handler")
+				}
+				if ct := rr.Header().Get("Content-Type"); ct != tc.expectedType {
+					t.Fatalf("expected the error as %s, got %s", tc.expectedType, ct)
+				}
+				return
+			}
+			if got == nil || *got != tc.expectedEnveloped {
+				t.Fatalf("expected enveloped %v, got %v", tc.expectedEnveloped, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Package hardening refuses the requests that the hardening profile has
+// turned off, by route group or by method.
+package hardening
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/middleware/route"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/hardening"
+)
+
+// Flags reports whether a feature flag is on. *features.Set is the real
+// one.
+type Flags interface {
+	Enabled(name string) bool
+}
+
+type Options struct {
+	// Flags holds the hardening.GroupFlag and hardening.MethodFlag flags,
+	// read on every request so that dynamic ones apply at once.
+	Flags Flags
+	Mode  hardening.Mode
+	// MetricsPath is where the metrics are served, the route of the
+	// metrics group.
+	MetricsPath string
+	// Exempt are the path templates of routes that are never refused,
+	// such as those that switch a dynamic profile: turning off their
+	// group or method must not lock out the request that turns it back
+	// on.
+	Exempt []string
+}
+
+// New answers the requests to a route group or with a method that is off
+// as opts.Mode says, and passes the others on. A hidden group answers 404
+// like an unknown path; a refused method answers 405 naming the method.
+// Routes in opts.Exempt are always passed on.
+func New(log *slog.Logger, opts Options) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		middlewareLog := log.With(
+			slog.String("component", "middleware/hardening"),
+		)
+
+		middlewareLog.Info("hardening middleware enabled", slog.String("mode", string(opts.Mode)))
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			status := 0
+			template := route.Template(r)
+			group := hardening.Group(template, opts.MetricsPath)
+			switch {
+			case slices.Contains(opts.Exempt, template):
+				next.ServeHTTP(w, r)
+				return
+			case group != "" && !opts.Flags.Enabled(hardening.GroupFlag(group)):
+				status = http.StatusNotFound
+			case slices.Contains(hardening.Methods, r.Method) && !opts.Flags.Enabled(hardening.MethodFlag(r.Method)):
+				status = http.StatusMethodNotAllowed
+			default:
+				next.ServeHTTP(w, r)
+				return
+			}
+			switch opts.Mode {
+			case hardening.ModeNotFound:
+				status = http.StatusNotFound
+			case hardening.ModeMethodNotAllowed:
+				status = http.StatusMethodNotAllowed
+			}
+
+			middlewareLog.DebugContext(r.Context(), "request turned off by hardening profile",
+				slog.String("group", group),
+				slog.String("method", r.Method),
+				slog.Int("status", status),
+			)
+			if status == http.StatusNotFound {
+				http.NotFound(w, r)
+				return
+			}
+			response.Error(w, r, http.StatusMethodNotAllowed, apierror.CodeMethodDisabled, nil, r.Method)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
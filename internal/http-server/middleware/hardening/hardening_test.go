@@ -0,0 +1,101 @@
+package hardening_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	mwHardening "quotes-service/internal/http-server/middleware/hardening"
+	"quotes-service/internal/lib/hardening"
+)
+
+// flags is a fixed set of feature flags; those left out are on.
+type flags map[string]bool
+
+func (f flags) Enabled(name string) bool {
+	on, ok := f[name]
+	return !ok || on
+}
+
+func TestNew(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	off := flags{
+		hardening.GroupFlag("admin"):          false,
+		hardening.GroupFlag("metrics"):        false,
+		hardening.MethodFlag(http.MethodPut):  false,
+		hardening.MethodFlag(http.MethodPost): false,
+	}
+
+	newRouter := func(mode hardening.Mode, exempt ...string) *mux.Router {
+		router := mux.NewRouter()
+		router.Use(mwHardening.New(logger, mwHardening.Options{
+			Flags:       off,
+			Mode:        mode,
+			MetricsPath: "/internal/metrics",
+			Exempt:      exempt,
+		}))
+		ok := func(w http.ResponseWriter, r *http.Request) {}
+		router.HandleFunc("/quotes", ok).Methods(http.MethodGet, http.MethodPost)
+		router.HandleFunc("/quotes/{id}", ok).Methods(http.MethodPut, http.MethodDelete)
+		router.HandleFunc("/internal/metrics", ok).Methods(http.MethodGet)
+		router.HandleFunc("/unlisted", ok).Methods(http.MethodGet, http.MethodOptions)
+		admin := router.PathPrefix("/admin").Subrouter()
+		admin.HandleFunc("/duplicates", ok).Methods(http.MethodGet)
+		admin.HandleFunc("/features/{name}", ok).Methods(http.MethodPut)
+		return router
+	}
+
+	tests := []struct {
+		name           string
+		mode           hardening.Mode
+		exempt         []string
+		method         string
+		path           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "allowed", mode: hardening.ModeAuto, method: http.MethodGet, path: "/quotes", expectedStatus: http.StatusOK},
+		{name: "method allowed", mode: hardening.ModeAuto, method: http.MethodDelete, path: "/quotes/1", expectedStatus: http.StatusOK},
+		{name: "method off", mode: hardening.ModeAuto, method: http.MethodPost, path: "/quotes", expectedStatus: http.StatusMethodNotAllowed, expectedCode: "method_disabled"},
+		{name: "group off", mode: hardening.ModeAuto, method: http.MethodGet, path: "/admin/duplicates", expectedStatus: http.StatusNotFound},
+		{name: "metrics path moved", mode: hardening.ModeAuto, method: http.MethodGet, path: "/internal/metrics", expectedStatus: http.StatusNotFound},
+		{name: "no group", mode: hardening.ModeAuto, method: http.MethodGet, path: "/unlisted", expectedStatus: http.StatusOK},
+		{name: "method that cannot be off", mode: hardening.ModeAuto, method: http.MethodOptions, path: "/unlisted", expectedStatus: http.StatusOK},
+		{name: "method off, not found", mode: hardening.ModeNotFound, method: http.MethodPut, path: "/quotes/1", expectedStatus: http.StatusNotFound},
+		{name: "group off, method not allowed", mode: hardening.ModeMethodNotAllowed, method: http.MethodGet, path: "/admin/duplicates", expectedStatus: http.StatusMethodNotAllowed, expectedCode: "method_disabled"},
+		{name: "group and method off", mode: hardening.ModeAuto, method: http.MethodPut, path: "/admin/features/group.admin", expectedStatus: http.StatusNotFound},
+		{
+			name:           "exempt",
+			mode:           hardening.ModeAuto,
+			exempt:         []string{"/admin/features/{name}"},
+			method:         http.MethodPut,
+			path:           "/admin/features/group.admin",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "exempt route only",
+			mode:           hardening.ModeAuto,
+			exempt:         []string{"/admin/features/{name}"},
+			method:         http.MethodGet,
+			path:           "/admin/duplicates",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			newRouter(tc.mode, tc.exempt...).ServeHTTP(rr, httptest.NewRequest(tc.method, tc.path, nil))
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tc.expectedCode != "" && !strings.Contains(rr.Body.String(), `"code":"`+tc.expectedCode+`"`) {
+				t.Fatalf("expected code %s, got %s", tc.expectedCode, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+// Package jwtauth authenticates requests with bearer JWTs from an identity
+// provider, next to the API keys of the auth middleware.
+package jwtauth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/middleware/auth"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/jwks"
+	"quotes-service/internal/lib/jwt"
+	"quotes-service/internal/lib/role"
+)
+
+// KeySource looks up the key a token names in its kid header. *jwks.Set
+// is the real one.
+type KeySource interface {
+	Key(ctx context.Context, id string) (jwks.Key, error)
+}
+
+// Options configures New.
+type Options struct {
+	Issuer   string
+	Audience string
+	// ClockSkew is how far exp and nbf may be off from the server's clock.
+	ClockSkew time.Duration
+	// RoleClaim names the claim that holds the principal's role, a string
+	// or an array of strings, of which the highest role counts.
+	RoleClaim string
+	// DefaultRole is the role of a token without a known role in its
+	// RoleClaim.
+	DefaultRole role.Role
+}
+
+// New checks the bearer token of every request that has one in the form of
+// a JWT, and authenticates a valid one as its sub claim with the role in
+// its RoleClaim. It must run before auth.New, which then leaves the request
+// alone; other bearer tokens are left for auth.New to take as API keys.
+//
+// An expired token gets 401 token_expired, one for another audience 401
+// invalid_audience, one signed with a key the provider does not publish
+// 401 unknown_signing_key, and any other bad token 401 invalid_token.
+func New(log *slog.Logger, keys KeySource, opts Options) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		middlewareLog := log.With(
+			slog.String("component", "middleware/jwtauth"),
+		)
+
+		middlewareLog.Info("jwt auth middleware enabled",
+			slog.String("issuer", opts.Issuer),
+			slog.String("audience", opts.Audience),
+			slog.String("role_claim", opts.RoleClaim),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			token, err := jwt.Parse(bearer(r))
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			reject := func(code apierror.Code, reason error) {
+				middlewareLog.WarnContext(ctx, "rejected bearer token",
+					slog.String("code", string(code)),
+					slog.String("reason", reason.Error()),
+					slog.String("kid", token.Header.Kid),
+					slog.String("path", r.URL.Path),
+				)
+				auth.Fail(w, code)
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				response.Error(w, r, http.StatusUnauthorized, code, nil)
+			}
+
+			key, err := keys.Key(ctx, token.Header.Kid)
+			if err != nil {
+				reject(apierror.CodeUnknownSigningKey, err)
+				return
+			}
+			if key.Alg != "" && key.Alg != token.Header.Alg {
+				reject(apierror.CodeInvalidToken, errors.New("algorithm does not match the key"))
+				return
+			}
+			if err := token.Verify(key.Public); err != nil {
+				reject(apierror.CodeInvalidToken, err)
+				return
+			}
+			err = token.Claims.Validate(jwt.Validation{
+				Issuer:   opts.Issuer,
+				Audience: opts.Audience,
+				Now:      time.Now(),
+				Skew:     opts.ClockSkew,
+			})
+			switch {
+			case errors.Is(err, jwt.ErrExpired):
+				reject(apierror.CodeTokenExpired, err)
+				return
+			case errors.Is(err, jwt.ErrAudience):
+				reject(apierror.CodeInvalidAudience, err)
+				return
+			case err != nil:
+				reject(apierror.CodeInvalidToken, err)
+				return
+			}
+			subject := token.Claims.Subject()
+			if subject == "" {
+				reject(apierror.CodeInvalidToken, errors.New("no sub claim"))
+				return
+			}
+
+			r = auth.Authenticate(w, r, subject, auth.MethodJWT)
+			r = r.WithContext(auth.WithRole(r.Context(), claimedRole(token.Claims.Strings(opts.RoleClaim), opts.DefaultRole)))
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// claimedRole returns the highest of the roles named in claimed, or
+// fallback if it names no known role.
+func claimedRole(claimed []string, fallback role.Role) role.Role {
+	var highest role.Role
+	found := false
+	for _, name := range claimed {
+		r, err := role.Parse(name)
+		if err != nil {
+			continue
+		}
+		if !found || r.Allows(highest) {
+			highest, found = r, true
+		}
+	}
+	if !found {
+		return fallback
+	}
+	return highest
+}
+
+// bearer returns the token of an Authorization: Bearer header, or "".
+func bearer(r *http.Request) string {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,213 @@
+package jwtauth_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"quotes-service/internal/http-server/middleware/auth"
+	"quotes-service/internal/http-server/middleware/jwtauth"
+	"quotes-service/internal/lib/jwks"
+	"quotes-service/internal/lib/role"
+	"quotes-service/internal/models"
+)
+
+var (
+	signingKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	otherKey, _   = rsa.GenerateKey(rand.Reader, 2048)
+)
+
+// keySource publishes signingKey as k1.
+type keySource struct{}
+
+func (keySource) Key(_ context.Context, id string) (jwks.Key, error) {
+	if id != "k1" {
+		return jwks.Key{}, jwks.ErrUnknownKey
+	}
+	return jwks.Key{ID: id, Alg: "RS256", Public: &signingKey.PublicKey}, nil
+}
+
+// token signs claims over the valid defaults with RS256; a nil value drops
+// the claim.
+func token(t *testing.T, kid string, key *rsa.PrivateKey, changes map[string]any) string {
+	t.Helper()
+	claims := map[string]any{
+		"iss": "https://id.example.com/",
+		"aud": "quotes",
+		"sub": "alice",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestNew(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	opts := jwtauth.Options{
+		Issuer:      "https://id.example.com/",
+		Audience:    "quotes",
+		ClockSkew:   time.Minute,
+		RoleClaim:   "roles",
+		DefaultRole: role.Reader,
+	}
+	keys := map[string]string{"w-key": "app"}
+	roles := auth.Roles{Principals: map[string]role.Role{"app": role.Writer}, Anonymous: role.None}
+
+	tests := []struct {
+		name              string
+		authorization     string
+		apiKey            string
+		expectedStatus    int
+		expectedCode      string
+		expectedPrincipal string
+		expectedRole      role.Role
+	}{
+		{
+			name:              "valid token",
+			authorization:     "Bearer " + token(t, "k1", signingKey, map[string]any{"roles": "writer"}),
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: "alice",
+			expectedRole:      role.Writer,
+		},
+		{
+			name:              "highest of several roles",
+			authorization:     "Bearer " + token(t, "k1", signingKey, map[string]any{"roles": []string{"reader", "root", "admin"}}),
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: "alice",
+			expectedRole:      role.Admin,
+		},
+		{
+			name:              "default role",
+			authorization:     "Bearer " + token(t, "k1", signingKey, nil),
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: "alice",
+			expectedRole:      role.Reader,
+		},
+		{
+			name:           "none role",
+			authorization:  "Bearer " + token(t, "k1", signingKey, map[string]any{"roles": "none"}),
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   "insufficient_role",
+		},
+		{
+			name:           "expired",
+			authorization:  "Bearer " + token(t, "k1", signingKey, map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}),
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "token_expired",
+		},
+		{
+			name:           "other audience",
+			authorization:  "Bearer " + token(t, "k1", signingKey, map[string]any{"aud": "billing"}),
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "invalid_audience",
+		},
+		{
+			name:           "other issuer",
+			authorization:  "Bearer " + token(t, "k1", signingKey, map[string]any{"iss": "https://evil.example.com/"}),
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "invalid_token",
+		},
+		{
+			name:           "unknown key",
+			authorization:  "Bearer " + token(t, "k2", signingKey, nil),
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "unknown_signing_key",
+		},
+		{
+			name:           "bad signature",
+			authorization:  "Bearer " + token(t, "k1", otherKey, nil),
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "invalid_token",
+		},
+		{
+			name:           "no subject",
+			authorization:  "Bearer " + token(t, "k1", signingKey, map[string]any{"sub": nil}),
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "invalid_token",
+		},
+		{
+			name:              "api key as bearer",
+			authorization:     "Bearer w-key",
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: "app",
+			expectedRole:      role.Writer,
+		},
+		{
+			name:              "api key header",
+			apiKey:            "w-key",
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: "app",
+			expectedRole:      role.Writer,
+		},
+		{
+			name:           "anonymous",
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "auth_required",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotPrincipal string
+			var gotRole role.Role
+			handler := jwtauth.New(logger, keySource{}, opts)(
+				auth.New(logger, keys)(
+					auth.Require(logger, roles, role.Reader)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						gotPrincipal, _ = auth.Principal(r.Context())
+						gotRole, _ = auth.Role(r.Context())
+					}))))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			if tc.apiKey != "" {
+				req.Header.Set(auth.APIKeyHeader, tc.apiKey)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tc.expectedStatus == http.StatusOK {
+				if gotPrincipal != tc.expectedPrincipal || gotRole != tc.expectedRole {
+					t.Fatalf("expected %s as %q, got %s as %q", tc.expectedPrincipal, tc.expectedRole, gotPrincipal, gotRole)
+				}
+				return
+			}
+			var resp models.ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || resp.Code != tc.expectedCode {
+				t.Fatalf("expected code %s, got %s", tc.expectedCode, rr.Body.String())
+			}
+			if rr.Code == http.StatusUnauthorized && tc.expectedCode != "auth_required" {
+				if got := rr.Header().Get("WWW-Authenticate"); got != `Bearer error="invalid_token"` {
+					t.Fatalf("expected a bearer challenge, got %q", got)
+				}
+			}
+		})
+	}
+}
@@ -2,17 +2,39 @@ package logger
 
 import (
 	"crypto/rand"
+	"encoding/binary"
 	"encoding/hex"
+	"io"
 	"log/slog"
 	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"quotes-service/internal/http-server/headers"
+	"quotes-service/internal/http-server/middleware/route"
+	"quotes-service/internal/lib/logger/sl"
+	"quotes-service/internal/lib/storagecalls"
 )
 
+// callerDepth is how many stack frames are kept for the first WriteHeader,
+// enough to see past response helpers into the handler.
+const callerDepth = 3
+
 type responseWriterInterceptor struct {
 	http.ResponseWriter
 	statusCode    int
 	bytesWritten  int
 	headerWritten bool
+
+	// firstCaller holds the program counters of the first WriteHeader.
+	// They are only resolved to file:line when a second call shows up.
+	firstCaller [callerDepth]uintptr
+	log         *slog.Logger
+	requestID   string
 }
 
 func newResponseWriterInterceptor(w http.ResponseWriter) *responseWriterInterceptor {
@@ -23,17 +45,64 @@ func newResponseWriterInterceptor(w http.ResponseWriter) *responseWriterIntercep
 }
 
 func (wri *responseWriterInterceptor) WriteHeader(code int) {
+	wri.writeHeader(code)
+}
+
+// writeHeader must be called directly from the exported method the
+// handler called, so the recorded frames start at the handler.
+func (wri *responseWriterInterceptor) writeHeader(code int) {
 	if wri.headerWritten {
+		wri.warnSuperfluous(code)
 		return
 	}
+	// Skip runtime.Callers, writeHeader and the exported method.
+	runtime.Callers(3, wri.firstCaller[:])
 	wri.ResponseWriter.WriteHeader(code)
 	wri.statusCode = code
-	wri.headerWritten = true 
+	wri.headerWritten = true
+}
+
+func (wri *responseWriterInterceptor) warnSuperfluous(code int) {
+	if wri.log == nil {
+		return
+	}
+	var second [callerDepth]uintptr
+	// Skip runtime.Callers, warnSuperfluous, writeHeader and WriteHeader.
+	runtime.Callers(4, second[:])
+	wri.log.Warn("superfluous WriteHeader call",
+		slog.String("request_id", wri.requestID),
+		slog.Int("first_status", wri.statusCode),
+		slog.String("first_call", formatCallers(wri.firstCaller[:])),
+		slog.Int("second_status", code),
+		slog.String("second_call", formatCallers(second[:])),
+	)
+}
+
+// formatCallers renders program counters as "file:line <- file:line".
+func formatCallers(pcs []uintptr) string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if frame.PC == 0 {
+			break
+		}
+		if b.Len() > 0 {
+			b.WriteString(" <- ")
+		}
+		b.WriteString(frame.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(frame.Line))
+		if !more {
+			break
+		}
+	}
+	return b.String()
 }
 
 func (wri *responseWriterInterceptor) Write(b []byte) (int, error) {
 	if !wri.headerWritten {
-		wri.WriteHeader(http.StatusOK)
+		wri.writeHeader(http.StatusOK)
 	}
 	n, err := wri.ResponseWriter.Write(b)
 	wri.bytesWritten += n
@@ -48,24 +117,145 @@ func (wri *responseWriterInterceptor) BytesWritten() int {
 	return wri.bytesWritten
 }
 
+// Written reports whether the status line has been sent, after which the
+// status and headers can no longer change.
+func (wri *responseWriterInterceptor) Written() bool {
+	return wri.headerWritten
+}
+
 func (wri *responseWriterInterceptor) Flush() {
 	if flusher, ok := wri.ResponseWriter.(http.Flusher); ok {
+		if !wri.headerWritten {
+			wri.writeHeader(http.StatusOK)
+		}
 		flusher.Flush()
 	}
 }
 
-func generateRequestID(logForError *slog.Logger) string {
-	bytes := make([]byte, 16)
-	if _, err := rand.Read(bytes); err != nil {
+func (wri *responseWriterInterceptor) RequestID() string {
+	return wri.requestID
+}
+
+// Unwrap lets http.ResponseController reach the server's writer.
+func (wri *responseWriterInterceptor) Unwrap() http.ResponseWriter {
+	return wri.ResponseWriter
+}
+
+// Written reports whether a response has already been started on w. It
+// only knows about writers wrapped by this middleware and reports false
+// for any other writer.
+func Written(w http.ResponseWriter) bool {
+	wri, ok := w.(interface{ Written() bool })
+	return ok && wri.Written()
+}
+
+// RequestID returns the ID this middleware gave the request served on w,
+// or "" for a writer it did not wrap.
+func RequestID(w http.ResponseWriter) string {
+	if wri, ok := w.(interface{ RequestID() string }); ok {
+		return wri.RequestID()
+	}
+	return ""
+}
+
+// requestIDBytes is the amount of randomness in a request ID; IDs are its
+// hex encoding, 32 characters long.
+const requestIDBytes = 16
+
+type idBuffer struct {
+	raw [requestIDBytes]byte
+	hex [2 * requestIDBytes]byte
+}
+
+var idBuffers = sync.Pool{
+	New: func() any { return new(idBuffer) },
+}
+
+// idGenerator produces request IDs from random, falling back to a counter
+// when random fails.
+type idGenerator struct {
+	random io.Reader
+	// fallbackPrefix is fixed per process so counter IDs from different
+	// runs do not collide; fallbackCounter makes them unique within a run.
+	fallbackPrefix  uint64
+	fallbackCounter atomic.Uint64
+}
+
+func newIDGenerator(random io.Reader) *idGenerator {
+	return &idGenerator{
+		random:         random,
+		fallbackPrefix: uint64(time.Now().UnixNano()),
+	}
+}
+
+// next returns a new 32 character hex request ID. The only allocation is
+// the returned string.
+func (g *idGenerator) next(logForError *slog.Logger) string {
+	buf := idBuffers.Get().(*idBuffer)
+	defer idBuffers.Put(buf)
+
+	if _, err := io.ReadFull(g.random, buf.raw[:]); err != nil {
 		if logForError != nil {
-			logForError.Error("Failed to generate secure request ID from crypto/rand", slog.String("error", err.Error()))
+			logForError.Error("failed to generate secure request ID, using counter", slog.String("error", err.Error()))
 		}
-		return "fallback_" + time.Now().Format(time.RFC3339Nano)
+		binary.BigEndian.PutUint64(buf.raw[:8], g.fallbackPrefix)
+		binary.BigEndian.PutUint64(buf.raw[8:], g.fallbackCounter.Add(1))
+	}
+	hex.Encode(buf.hex[:], buf.raw[:])
+	return string(buf.hex[:])
+}
+
+type Option func(*options)
+
+type options struct {
+	random     io.Reader
+	debugFor   func(*http.Request) bool
+	callBudget int64
+}
+
+// WithRandom overrides the source of request ID randomness, mainly for
+// tests.
+func WithRandom(r io.Reader) Option {
+	return func(o *options) {
+		o.random = r
 	}
-	return hex.EncodeToString(bytes)
 }
 
-func New(log *slog.Logger) func(next http.Handler) http.Handler {
+// WithDebugFor logs requests matched by match at debug level instead of
+// info, for noisy traffic such as health probes.
+func WithDebugFor(match func(*http.Request) bool) Option {
+	return func(o *options) {
+		o.debugFor = match
+	}
+}
+
+// WithCallBudget warns about every request that makes more than budget
+// storage calls, to catch a handler that looks items up one by one while
+// the data is still small enough for it not to show.
+func WithCallBudget(budget int) Option {
+	return func(o *options) {
+		o.callBudget = int64(budget)
+	}
+}
+
+// RequestIDHeader carries the ID of a request in its response, for
+// clients to quote when reporting a problem.
+const RequestIDHeader = "X-Request-ID"
+
+func init() {
+	headers.Expose(RequestIDHeader)
+}
+
+// New logs every request, with the storage calls made for it, and gives it
+// an ID, which is sent back in the X-Request-ID header. Middleware further out learns the ID if its writer
+// has a SetRequestID(id string) method.
+func New(log *slog.Logger, opts ...Option) func(next http.Handler) http.Handler {
+	o := options{random: rand.Reader}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	ids := newIDGenerator(o.random)
+
 	return func(next http.Handler) http.Handler {
 		middlewareLog := log.With(
 			slog.String("component", "middleware/logger"),
@@ -74,29 +264,61 @@ func New(log *slog.Logger) func(next http.Handler) http.Handler {
 		middlewareLog.Info("logger middleware enabled")
 
 		fn := func(w http.ResponseWriter, r *http.Request) {
-			requestID := generateRequestID(middlewareLog)
-
-			entry := middlewareLog.With(
-				slog.String("method", r.Method),
-				slog.String("path", r.URL.Path),
-				slog.String("remote_addr", r.RemoteAddr),
-				slog.String("user_agent", r.UserAgent()),
-				slog.String("request_id", requestID),
-			)
-
+			requestID := ids.next(middlewareLog)
 			interceptor := newResponseWriterInterceptor(w)
+			interceptor.log = middlewareLog
+			interceptor.requestID = requestID
+			if outer, ok := w.(interface{ SetRequestID(id string) }); ok {
+				outer.SetRequestID(requestID)
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			level := slog.LevelInfo
+			if o.debugFor != nil && o.debugFor(r) {
+				level = slog.LevelDebug
+			}
 
+			ctx, calls := storagecalls.Track(r.Context())
+			r = r.WithContext(ctx)
 			startTime := time.Now()
 			defer func() {
-				entry.Info("request completed",
-					slog.Int("status", interceptor.Status()),
-					slog.Int("bytes", interceptor.BytesWritten()),
-					slog.Duration("duration", time.Since(startTime)),
-				)
+				logRequest(middlewareLog, level, r, requestID, interceptor, startTime, calls.Calls())
+				if o.callBudget > 0 && calls.Calls() > o.callBudget {
+					middlewareLog.WarnContext(ctx, "request exceeded storage call budget",
+						slog.String("method", r.Method),
+						slog.String("route", route.Template(r)),
+						slog.String("request_id", requestID),
+						slog.Int64("storage_calls", calls.Calls()),
+						slog.Int64("budget", o.callBudget),
+					)
+				}
 			}()
 
 			next.ServeHTTP(interceptor, r)
 		}
 		return http.HandlerFunc(fn)
 	}
-}
\ No newline at end of file
+}
+
+// maxLoggedChars bounds the path and user agent in the access log, which
+// clients control.
+const maxLoggedChars = 512
+
+// logRequest writes the access log line. The attributes are built once in
+// a fixed array rather than through Logger.With, which would copy the
+// handler for every request.
+func logRequest(log *slog.Logger, level slog.Level, r *http.Request, requestID string, wri *responseWriterInterceptor, start time.Time, storageCalls int64) {
+	attrs := [...]slog.Attr{
+		slog.String("method", r.Method),
+		slog.String("path", sl.Truncate(r.URL.Path, maxLoggedChars)),
+		slog.String("route", route.Template(r)),
+		slog.String("remote_addr", r.RemoteAddr),
+		slog.String("user_agent", sl.Truncate(r.UserAgent(), maxLoggedChars)),
+		slog.String("request_id", requestID),
+		slog.Int("status", wri.Status()),
+		slog.Int("bytes", wri.BytesWritten()),
+		slog.Duration("duration", time.Since(start)),
+		slog.Int64("storage_calls", storageCalls),
+	}
+	log.LogAttrs(r.Context(), level, "request completed", attrs[:]...)
+}
@@ -0,0 +1,251 @@
+package logger_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+
+	mwLogger "quotes-service/internal/http-server/middleware/logger"
+	"quotes-service/internal/lib/storagecalls"
+)
+
+var requestIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("entropy unavailable")
+}
+
+// requestIDs serves n requests and returns the request IDs from the
+// access log lines.
+func requestIDs(t *testing.T, n int, opts ...mwLogger.Option) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+	handler := mwLogger.New(log, opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	for i := 0; i < n; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/quotes", nil))
+	}
+
+	var ids []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var line struct {
+			Msg       string `json:"msg"`
+			RequestID string `json:"request_id"`
+			Status    int    `json:"status"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("failed to parse log line %q: %v", scanner.Text(), err)
+		}
+		if line.Msg != "request completed" {
+			continue
+		}
+		if line.Status != http.StatusTeapot {
+			t.Errorf("expected status %d in log, got %d", http.StatusTeapot, line.Status)
+		}
+		ids = append(ids, line.RequestID)
+	}
+	if len(ids) != n {
+		t.Fatalf("expected %d access log lines, got %d", n, len(ids))
+	}
+	return ids
+}
+
+func TestRequestIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []mwLogger.Option
+	}{
+		{name: "crypto/rand"},
+		{name: "counter fallback", opts: []mwLogger.Option{mwLogger.WithRandom(failingReader{})}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			seen := make(map[string]struct{})
+			for _, id := range requestIDs(t, 1000, tc.opts...) {
+				if !requestIDPattern.MatchString(id) {
+					t.Fatalf("expected 32 lowercase hex characters, got %q", id)
+				}
+				if _, dup := seen[id]; dup {
+					t.Fatalf("duplicate request ID %q", id)
+				}
+				seen[id] = struct{}{}
+			}
+		})
+	}
+}
+
+func TestSuperfluousWriteHeader(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	var firstLine, secondLine int
+	handler := mwLogger.New(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if mwLogger.Written(w) {
+			t.Error("expected Written to be false before the first WriteHeader")
+		}
+		_, _, firstLine, _ = runtime.Caller(0)
+		w.WriteHeader(http.StatusCreated)
+		if !mwLogger.Written(w) {
+			t.Error("expected Written to be true after WriteHeader")
+		}
+		_, _, secondLine, _ = runtime.Caller(0)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/quotes", nil))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected the first status to win, got %d", rr.Code)
+	}
+
+	var warning, access map[string]any
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var line map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("failed to parse log line %q: %v", scanner.Text(), err)
+		}
+		switch line["msg"] {
+		case "superfluous WriteHeader call":
+			warning = line
+		case "request completed":
+			access = line
+		}
+	}
+	if warning == nil || access == nil {
+		t.Fatalf("expected a warning and an access log line, got:\n%s", buf.String())
+	}
+
+	if warning["level"] != "WARN" {
+		t.Errorf("expected WARN level, got %v", warning["level"])
+	}
+	if warning["request_id"] != access["request_id"] {
+		t.Errorf("expected request ID %v, got %v", access["request_id"], warning["request_id"])
+	}
+	if warning["first_status"] != float64(http.StatusCreated) || warning["second_status"] != float64(http.StatusInternalServerError) {
+		t.Errorf("unexpected statuses in warning: %v", warning)
+	}
+	for key, line := range map[string]int{"first_call": firstLine + 1, "second_call": secondLine + 1} {
+		want := fmt.Sprintf("logger_test.go:%d", line)
+		if call, _ := warning[key].(string); !strings.Contains(strings.Split(call, " <- ")[0], want) {
+			t.Errorf("expected %s to start at %s, got %q", key, want, call)
+		}
+	}
+}
+
+func TestDebugFor(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	probe := func(r *http.Request) bool { return strings.HasPrefix(r.UserAgent(), "kube-probe/") }
+	handler := mwLogger.New(log, mwLogger.WithDebugFor(probe))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, userAgent := range []string{"kube-probe/1.29", "Mozilla/5.0"} {
+		req := httptest.NewRequest(http.MethodGet, "/quotes", nil)
+		req.Header.Set("User-Agent", userAgent)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	levels := map[string]string{}
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var line struct {
+			Msg       string `json:"msg"`
+			Level     string `json:"level"`
+			UserAgent string `json:"user_agent"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("failed to parse log line %q: %v", scanner.Text(), err)
+		}
+		if line.Msg == "request completed" {
+			levels[line.UserAgent] = line.Level
+		}
+	}
+	if levels["kube-probe/1.29"] != "DEBUG" || levels["Mozilla/5.0"] != "INFO" {
+		t.Fatalf("expected probe at DEBUG and browser at INFO, got %v", levels)
+	}
+}
+
+func TestWrittenOnOtherWriters(t *testing.T) {
+	if mwLogger.Written(httptest.NewRecorder()) {
+		t.Fatal("expected false for a writer not wrapped by the middleware")
+	}
+}
+
+func TestCallBudget(t *testing.T) {
+	tests := []struct {
+		name            string
+		calls           int
+		expectedWarning bool
+	}{
+		{name: "within budget", calls: 3},
+		{name: "over budget", calls: 4, expectedWarning: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := slog.New(slog.NewJSONHandler(&buf, nil))
+			handler := mwLogger.New(log, mwLogger.WithCallBudget(3))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for range tc.calls {
+					storagecalls.Add(r.Context())
+				}
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/quotes", nil))
+
+			var logged int
+			warned := false
+			scanner := bufio.NewScanner(&buf)
+			for scanner.Scan() {
+				var line struct {
+					Msg          string `json:"msg"`
+					StorageCalls int    `json:"storage_calls"`
+				}
+				if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+					t.Fatalf("failed to parse log line %q: %v", scanner.Text(), err)
+				}
+				switch line.Msg {
+				case "request completed":
+					logged = line.StorageCalls
+				case "request exceeded storage call budget":
+					warned = true
+				}
+			}
+			if logged != tc.calls {
+				t.Fatalf("expected %d storage calls logged, got %d", tc.calls, logged)
+			}
+			if warned != tc.expectedWarning {
+				t.Fatalf("expected warning %v, got %v", tc.expectedWarning, warned)
+			}
+		})
+	}
+}
+
+func BenchmarkMiddleware(b *testing.B) {
+	log := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	handler := mwLogger.New(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, "/quotes/random", nil)
+	w := httptest.NewRecorder()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		handler.ServeHTTP(w, req)
+	}
+}
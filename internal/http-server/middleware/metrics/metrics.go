@@ -0,0 +1,372 @@
+package metrics
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/middleware/route"
+	"quotes-service/internal/lib/cardinality"
+	"quotes-service/internal/lib/healthsummary"
+	"quotes-service/internal/lib/recentfailures"
+	"quotes-service/internal/lib/slowest"
+	"quotes-service/internal/lib/storagecalls"
+)
+
+// Anonymous is the tenant label of requests without an API key.
+const Anonymous = "anonymous"
+
+// Metrics holds the HTTP collectors. They are registered on the registry
+// passed to NewMetrics, so tests can use a fresh one.
+type Metrics struct {
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	requestSize  *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+	storageCalls *prometheus.HistogramVec
+	// tenants is set when the request count and latency are labeled by
+	// tenant.
+	tenants *cardinality.Guard
+}
+
+type MetricsOption func(*Metrics)
+
+// WithTenants labels the request count and latency with the tenant, the
+// principal of the request's API key or Anonymous. guard caps the tenants
+// labeled; the requests of any others are labeled cardinality.Other.
+func WithTenants(guard *cardinality.Guard) MetricsOption {
+	return func(m *Metrics) {
+		m.tenants = guard
+	}
+}
+
+// sizeBuckets run from 64 B to 1 MiB.
+var sizeBuckets = prometheus.ExponentialBuckets(64, 4, 8)
+
+// callBuckets run from none to 64 storage calls, fine at the low end
+// where a handler should stay.
+var callBuckets = []float64{0, 1, 2, 3, 4, 8, 16, 32, 64}
+
+func NewMetrics(reg prometheus.Registerer, opts ...MetricsOption) *Metrics {
+	m := &Metrics{}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	requestLabels := []string{"method", "route", "status"}
+	durationLabels := []string{"method", "route"}
+	if m.tenants != nil {
+		requestLabels = append(requestLabels, "tenant")
+		durationLabels = append(durationLabels, "tenant")
+	}
+	m.requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route and status.",
+	}, requestLabels)
+	m.duration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, durationLabels)
+	m.requestSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_size_bytes",
+		Help:    "HTTP request body size by method and route.",
+		Buckets: sizeBuckets,
+	}, []string{"method", "route"})
+	m.responseSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_response_size_bytes",
+		Help:    "HTTP response body size by method and route.",
+		Buckets: sizeBuckets,
+	}, []string{"method", "route"})
+	m.storageCalls = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_storage_calls",
+		Help:    "Storage calls made per HTTP request by method and route.",
+		Buckets: callBuckets,
+	}, []string{"method", "route"})
+	reg.MustRegister(m.requests, m.duration, m.requestSize, m.responseSize, m.storageCalls)
+	return m
+}
+
+// Exclusions selects requests that are served but left out of the metrics,
+// such as health probes that would otherwise dominate the counts.
+type Exclusions struct {
+	// UserAgentPrefixes matches the start of the User-Agent header, e.g.
+	// "kube-probe/".
+	UserAgentPrefixes []string
+	// Paths matches the request path exactly.
+	Paths []string
+}
+
+// Match reports whether r is excluded.
+func (e Exclusions) Match(r *http.Request) bool {
+	if len(e.UserAgentPrefixes) > 0 {
+		userAgent := r.UserAgent()
+		for _, prefix := range e.UserAgentPrefixes {
+			if strings.HasPrefix(userAgent, prefix) {
+				return true
+			}
+		}
+	}
+	for _, path := range e.Paths {
+		if r.URL.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+type Option func(*options)
+
+type options struct {
+	slow     *slowest.Window
+	summary  *healthsummary.Window
+	failures *recentfailures.Ring
+}
+
+// WithSlowest offers every counted request to slow, so the slowest recent
+// ones can be listed.
+func WithSlowest(slow *slowest.Window) Option {
+	return func(o *options) {
+		o.slow = slow
+	}
+}
+
+// WithFailures offers every counted request to failures, so the last ones
+// that failed with a server error can be listed.
+func WithFailures(failures *recentfailures.Ring) Option {
+	return func(o *options) {
+		o.failures = failures
+	}
+}
+
+// WithSummary counts every counted request in summary, so recent failure
+// rates and latencies can be summarized.
+func WithSummary(summary *healthsummary.Window) Option {
+	return func(o *options) {
+		o.summary = summary
+	}
+}
+
+// New records a count, latency, request and response sizes and the number
+// of storage calls for every request not matched by exclusions. Requests are labeled by route template
+// rather than raw path to keep the label set bounded.
+func New(log *slog.Logger, m *Metrics, exclusions Exclusions, opts ...Option) func(next http.Handler) http.Handler {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		middlewareLog := log.With(
+			slog.String("component", "middleware/metrics"),
+		)
+
+		middlewareLog.Info("metrics middleware enabled",
+			slog.Int("excluded_user_agents", len(exclusions.UserAgentPrefixes)),
+			slog.Int("excluded_paths", len(exclusions.Paths)),
+			slog.Bool("tenant_labels", m.tenants != nil),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if exclusions.Match(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Without a Content-Length the body is counted as the handler
+			// reads it.
+			var body *countingBody
+			if r.ContentLength < 0 && r.Body != nil && r.Body != http.NoBody {
+				body = &countingBody{ReadCloser: r.Body}
+				r.Body = body
+			}
+
+			ctx, calls := storagecalls.Track(r.Context())
+			r = r.WithContext(ctx)
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+			next.ServeHTTP(recorder, r)
+			elapsed := time.Since(start)
+
+			requestSize := max(r.ContentLength, 0)
+			if body != nil {
+				requestSize = body.n
+			}
+
+			template := route.Template(r)
+			requestLabels := []string{r.Method, template, strconv.Itoa(recorder.status)}
+			durationLabels := []string{r.Method, template}
+			if m.tenants != nil {
+				tenant := Anonymous
+				if recorder.principal != "" {
+					tenant = m.tenants.Value(recorder.principal)
+				}
+				requestLabels = append(requestLabels, tenant)
+				durationLabels = append(durationLabels, tenant)
+			}
+			m.requests.WithLabelValues(requestLabels...).Inc()
+			observeDuration(m.duration.WithLabelValues(durationLabels...), elapsed, r, recorder.requestID)
+			m.requestSize.WithLabelValues(r.Method, template).Observe(float64(requestSize))
+			m.responseSize.WithLabelValues(r.Method, template).Observe(float64(recorder.bytesWritten))
+			m.storageCalls.WithLabelValues(r.Method, template).Observe(float64(calls.Calls()))
+			finished := slowest.Request{
+				Time:      start,
+				RequestID: recorder.requestID,
+				Method:    r.Method,
+				Route:     template,
+				Status:    recorder.status,
+				Duration:  elapsed,
+			}
+			if o.slow != nil {
+				o.slow.Record(finished)
+			}
+			if o.failures != nil {
+				o.failures.Record(finished)
+			}
+			if o.summary != nil {
+				o.summary.Record(healthsummary.Observation{
+					Status:       recorder.status,
+					Duration:     elapsed,
+					StorageError: apierror.StorageFailure(recorder.errorCode),
+					Panic:        recorder.panicked,
+				})
+			}
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// observeDuration records elapsed, with the trace and request IDs as an
+// exemplar when the request carries a W3C trace context, so a slow bucket
+// leads to a trace and the request's log lines.
+func observeDuration(observer prometheus.Observer, elapsed time.Duration, r *http.Request, requestID string) {
+	traceID, ok := traceID(r.Header.Get("traceparent"))
+	exemplars, canExemplar := observer.(prometheus.ExemplarObserver)
+	if !ok || !canExemplar {
+		observer.Observe(elapsed.Seconds())
+		return
+	}
+	labels := prometheus.Labels{"trace_id": traceID}
+	if requestID != "" {
+		labels["request_id"] = requestID
+	}
+	exemplars.ObserveWithExemplar(elapsed.Seconds(), labels)
+}
+
+// traceID returns the trace ID of a traceparent header, as in
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01, and false for a
+// malformed one or the invalid all-zero ID.
+func traceID(traceparent string) (string, bool) {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return "", false
+	}
+	for _, part := range parts[:4] {
+		if !isLowerHex(part) {
+			return "", false
+		}
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return "", false
+	}
+	if strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// countingBody counts the bytes read from a request body.
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// statusRecorder remembers the response status and size for the metrics,
+// the request ID the logger middleware further in gives the request, the
+// principal the auth middleware finds for it, the code of an error response
+// and whether the handler panicked.
+type statusRecorder struct {
+	http.ResponseWriter
+	status       int
+	wroteHeader  bool
+	bytesWritten int
+	requestID    string
+	principal    string
+	errorCode    apierror.Code
+	panicked     bool
+}
+
+func (sr *statusRecorder) WriteHeader(code int) {
+	if !sr.wroteHeader {
+		sr.status = code
+		sr.wroteHeader = true
+	}
+	sr.ResponseWriter.WriteHeader(code)
+}
+
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	sr.wroteHeader = true
+	n, err := sr.ResponseWriter.Write(b)
+	sr.bytesWritten += n
+	return n, err
+}
+
+// SetRequestID is called by the logger middleware with the ID of the
+// request.
+func (sr *statusRecorder) SetRequestID(id string) {
+	sr.requestID = id
+}
+
+// SetPrincipal is called by the auth middleware with the principal of the
+// request's API key.
+func (sr *statusRecorder) SetPrincipal(principal string) {
+	sr.principal = principal
+}
+
+// SetErrorCode is called by response.Error with the code of the error.
+func (sr *statusRecorder) SetErrorCode(code apierror.Code) {
+	sr.errorCode = code
+}
+
+// Panicked is called by the recoverer when the handler panicked.
+func (sr *statusRecorder) Panicked() {
+	sr.panicked = true
+}
+
+func (sr *statusRecorder) Flush() {
+	if flusher, ok := sr.ResponseWriter.(http.Flusher); ok {
+		sr.wroteHeader = true
+		flusher.Flush()
+	}
+}
+
+// Written reports whether the response has started, so middlewares further
+// in can tell through this wrapper.
+func (sr *statusRecorder) Written() bool {
+	return sr.wroteHeader
+}
+
+// Unwrap lets http.ResponseController reach the server's writer.
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
@@ -0,0 +1,305 @@
+package metrics_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	mwAuth "quotes-service/internal/http-server/middleware/auth"
+	mwLogger "quotes-service/internal/http-server/middleware/logger"
+	mwMetrics "quotes-service/internal/http-server/middleware/metrics"
+	"quotes-service/internal/lib/cardinality"
+	"quotes-service/internal/lib/slowest"
+)
+
+// requestCount sums http_requests_total for the given route and status.
+func requestCount(t *testing.T, reg *prometheus.Registry, route, status string) float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	var total float64
+	for _, family := range families {
+		if family.GetName() != "http_requests_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["route"] == route && labels["status"] == status {
+				total += metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return total
+}
+
+func TestExclusions(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name      string
+		path      string
+		userAgent string
+		counted   bool
+	}{
+		{name: "normal request", path: "/quotes/1", userAgent: "Mozilla/5.0", counted: true},
+		{name: "kubernetes probe", path: "/quotes/1", userAgent: "kube-probe/1.29", counted: false},
+		{name: "uptime checker", path: "/quotes/1", userAgent: "UptimeBot/2.0 (+https://example.com)", counted: false},
+		{name: "similar user agent not excluded", path: "/quotes/1", userAgent: "my-kube-probe", counted: true},
+		{name: "excluded path", path: "/healthz", userAgent: "Mozilla/5.0", counted: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reg := prometheus.NewRegistry()
+			exclusions := mwMetrics.Exclusions{
+				UserAgentPrefixes: []string{"kube-probe/", "UptimeBot/"},
+				Paths:             []string{"/healthz"},
+			}
+
+			served := false
+			router := mux.NewRouter()
+			router.Use(mwMetrics.New(logger, mwMetrics.NewMetrics(reg), exclusions))
+			ok := func(w http.ResponseWriter, r *http.Request) {
+				served = true
+				w.WriteHeader(http.StatusOK)
+			}
+			router.HandleFunc("/quotes/{id:[0-9]+}", ok)
+			router.HandleFunc("/healthz", ok)
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("User-Agent", tc.userAgent)
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if !served {
+				t.Fatal("expected the request to be served")
+			}
+			route := "/quotes/{id:[0-9]+}"
+			if tc.path == "/healthz" {
+				route = "/healthz"
+			}
+			want := 0.0
+			if tc.counted {
+				want = 1
+			}
+			if got := requestCount(t, reg, route, "200"); got != want {
+				t.Fatalf("expected count %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestStatusLabel(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	reg := prometheus.NewRegistry()
+
+	router := mux.NewRouter()
+	router.Use(mwMetrics.New(logger, mwMetrics.NewMetrics(reg), mwMetrics.Exclusions{}))
+	router.HandleFunc("/quotes/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	for i := 0; i < 3; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/quotes/42", nil))
+	}
+	if got := requestCount(t, reg, "/quotes/{id:[0-9]+}", "404"); got != 3 {
+		t.Fatalf("expected 3 requests counted under the route template, got %v", got)
+	}
+}
+
+// sizeSum returns the observation count and sum of a size histogram for
+// route.
+func sizeSum(t *testing.T, reg *prometheus.Registry, name, route string) (uint64, float64) {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "route" && label.GetValue() == route {
+					return metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum()
+				}
+			}
+		}
+	}
+	return 0, 0
+}
+
+func TestSizesAndSlowest(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	reg := prometheus.NewRegistry()
+	slow := slowest.New(5, time.Minute)
+
+	router := mux.NewRouter()
+	router.Use(mwMetrics.New(logger, mwMetrics.NewMetrics(reg), mwMetrics.Exclusions{}, mwMetrics.WithSlowest(slow)))
+	router.Use(mwLogger.New(logger))
+	var requestID string
+	router.HandleFunc("/quotes", func(w http.ResponseWriter, r *http.Request) {
+		requestID = mwLogger.RequestID(w)
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("0123456789"))
+	})
+
+	// One request with a Content-Length and one chunked, without.
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/quotes", strings.NewReader(`{"a":1}`)))
+	chunked := httptest.NewRequest(http.MethodPost, "/quotes", io.NopCloser(strings.NewReader(`{"ab":12}`)))
+	chunked.ContentLength = -1
+	router.ServeHTTP(httptest.NewRecorder(), chunked)
+
+	if count, sum := sizeSum(t, reg, "http_request_size_bytes", "/quotes"); count != 2 || sum != 7+9 {
+		t.Errorf("expected 2 request sizes summing to 16, got %d summing to %v", count, sum)
+	}
+	if count, sum := sizeSum(t, reg, "http_response_size_bytes", "/quotes"); count != 2 || sum != 20 {
+		t.Errorf("expected 2 response sizes summing to 20, got %d summing to %v", count, sum)
+	}
+
+	requests := slow.Slowest(5)
+	if len(requests) != 2 {
+		t.Fatalf("expected both requests in the slow window, got %+v", requests)
+	}
+	found := false
+	for _, req := range requests {
+		if req.Route != "/quotes" || req.Status != http.StatusCreated || req.RequestID == "" {
+			t.Errorf("unexpected slow request %+v", req)
+		}
+		found = found || req.RequestID == requestID
+	}
+	if !found {
+		t.Errorf("expected the logger's request ID %q in the slow window, got %+v", requestID, requests)
+	}
+}
+
+func TestTenantLabels(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	reg := prometheus.NewRegistry()
+
+	router := mux.NewRouter()
+	router.Use(mwMetrics.New(logger, mwMetrics.NewMetrics(reg, mwMetrics.WithTenants(cardinality.New(2))), mwMetrics.Exclusions{}))
+	router.Use(mwLogger.New(logger))
+	router.Use(mwAuth.New(logger, map[string]string{"key-a": "acme", "key-b": "globex", "key-c": "initech"}))
+	router.HandleFunc("/quotes", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, key := range []string{"key-a", "key-b", "key-c", "key-a", "", "key-c"} {
+		req := httptest.NewRequest(http.MethodGet, "/quotes", nil)
+		if key != "" {
+			req.Header.Set(mwAuth.APIKeyHeader, key)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	counts := map[string]float64{}
+	latencies := map[string]uint64{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() != "tenant" {
+					continue
+				}
+				switch family.GetName() {
+				case "http_requests_total":
+					counts[label.GetValue()] += metric.GetCounter().GetValue()
+				case "http_request_duration_seconds":
+					latencies[label.GetValue()] += metric.GetHistogram().GetSampleCount()
+				default:
+					t.Errorf("unexpected tenant label on %s", family.GetName())
+				}
+			}
+		}
+	}
+	expected := map[string]float64{"acme": 2, "globex": 1, cardinality.Other: 2, mwMetrics.Anonymous: 1}
+	if len(counts) != len(expected) {
+		t.Fatalf("expected tenants %v, got %v", expected, counts)
+	}
+	for tenant, want := range expected {
+		if counts[tenant] != want || latencies[tenant] != uint64(want) {
+			t.Errorf("tenant %q: expected %v requests, got %v counted and %d timed", tenant, want, counts[tenant], latencies[tenant])
+		}
+	}
+}
+
+func TestExemplars(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name            string
+		traceparent     string
+		expectedTraceID string
+	}{
+		{name: "trace context", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{name: "future version", traceparent: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{name: "no trace context"},
+		{name: "zero trace ID", traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{name: "upper case", traceparent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{name: "malformed", traceparent: "00-4bf92f3577b34da6-01"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reg := prometheus.NewRegistry()
+			router := mux.NewRouter()
+			router.Use(mwMetrics.New(logger, mwMetrics.NewMetrics(reg), mwMetrics.Exclusions{}))
+			router.Use(mwLogger.New(logger))
+			var requestID string
+			router.HandleFunc("/quotes", func(w http.ResponseWriter, r *http.Request) {
+				requestID = mwLogger.RequestID(w)
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/quotes", nil)
+			if tc.traceparent != "" {
+				req.Header.Set("traceparent", tc.traceparent)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			families, err := reg.Gather()
+			if err != nil {
+				t.Fatalf("failed to gather metrics: %v", err)
+			}
+			labels := map[string]string{}
+			for _, family := range families {
+				if family.GetName() != "http_request_duration_seconds" {
+					continue
+				}
+				for _, bucket := range family.GetMetric()[0].GetHistogram().GetBucket() {
+					if exemplar := bucket.GetExemplar(); exemplar != nil {
+						for _, label := range exemplar.GetLabel() {
+							labels[label.GetName()] = label.GetValue()
+						}
+					}
+				}
+			}
+
+			if tc.expectedTraceID == "" {
+				if len(labels) != 0 {
+					t.Fatalf("expected no exemplar, got %v", labels)
+				}
+				return
+			}
+			if labels["trace_id"] != tc.expectedTraceID || labels["request_id"] != requestID {
+				t.Fatalf("expected an exemplar with trace %s and request %s, got %v", tc.expectedTraceID, requestID, labels)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package paramlimit
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/logger/sl"
+)
+
+// Limits are the longest values accepted, in characters.
+type Limits struct {
+	// Author bounds the ?author= filter and the {name} of /authors/{name}.
+	Author int
+	// Path bounds every other path variable: IDs and model names.
+	Path int
+}
+
+// New rejects requests whose author parameter or path variables are
+// longer than limits allow with 400 parameter_too_long, before a handler
+// scans storage with them or logs them. It must run after routing, so the
+// path variables are known. A zero limit is no limit.
+func New(log *slog.Logger, limits Limits) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		middlewareLog := log.With(
+			slog.String("component", "middleware/paramlimit"),
+		)
+
+		middlewareLog.Info("parameter length limits enabled", slog.Int("author", limits.Author), slog.Int("path", limits.Path))
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if !check(w, r, middlewareLog, "author", r.URL.Query().Get("author"), limits.Author) {
+				return
+			}
+			for name, value := range mux.Vars(r) {
+				// The router matches the escaped path, so "%C3%A9" is one
+				// character.
+				if unescaped, err := url.PathUnescape(value); err == nil {
+					value = unescaped
+				}
+				limit := limits.Path
+				if name == "name" {
+					limit = limits.Author
+				}
+				if !check(w, r, middlewareLog, name, value, limit) {
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// check writes the error response and returns false when value is longer
+// than limit.
+func check(w http.ResponseWriter, r *http.Request, log *slog.Logger, name, value string, limit int) bool {
+	if !TooLong(value, limit) {
+		return true
+	}
+	log.WarnContext(r.Context(), "parameter too long",
+		slog.String("param", name),
+		slog.Int("bytes", len(value)),
+		sl.UserText("value", value),
+	)
+	response.Error(w, r, http.StatusBadRequest, apierror.CodeParameterTooLong, nil, name, limit)
+	return false
+}
+
+// TooLong reports whether s has more than limit characters. It stops
+// counting at the limit, so huge values cost no more than short ones. A
+// limit of zero or less is no limit.
+func TooLong(s string, limit int) bool {
+	if limit <= 0 || len(s) <= limit {
+		return false
+	}
+	chars := 0
+	for range s {
+		chars++
+		if chars > limit {
+			return true
+		}
+	}
+	return false
+}
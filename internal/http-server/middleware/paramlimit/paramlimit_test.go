@@ -0,0 +1,93 @@
+package paramlimit_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/middleware/paramlimit"
+)
+
+func TestTooLong(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		limit    int
+		expected bool
+	}{
+		{name: "empty", value: "", limit: 1, expected: false},
+		{name: "at the limit", value: strings.Repeat("a", 8), limit: 8, expected: false},
+		{name: "one over the limit", value: strings.Repeat("a", 9), limit: 8, expected: true},
+		{name: "multibyte at the limit", value: strings.Repeat("ж", 8), limit: 8, expected: false},
+		{name: "multibyte one over the limit", value: strings.Repeat("ж", 9), limit: 8, expected: true},
+		{name: "huge", value: strings.Repeat("a", 1<<20), limit: 256, expected: true},
+		{name: "no limit", value: strings.Repeat("a", 1<<20), limit: 0, expected: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := paramlimit.TooLong(tc.value, tc.limit); got != tc.expected {
+				t.Fatalf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParamLimit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := mux.NewRouter()
+	router.UseEncodedPath()
+	router.Use(paramlimit.New(logger, paramlimit.Limits{Author: 5, Path: 3}))
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	router.HandleFunc("/quotes", ok)
+	router.HandleFunc("/quotes/{id}", ok)
+	router.HandleFunc("/authors/{name}", ok)
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "author at the limit", url: "/quotes?author=" + url.QueryEscape("Сенек"), expectedStatus: http.StatusOK},
+		{
+			name:           "author one over the limit",
+			url:            "/quotes?author=Seneca",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"parameter_too_long","error":"Parameter author is longer than 5 characters."}`,
+		},
+		{name: "id at the limit", url: "/quotes/123", expectedStatus: http.StatusOK},
+		{
+			name:           "id one over the limit",
+			url:            "/quotes/1234",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"parameter_too_long","error":"Parameter id is longer than 3 characters."}`,
+		},
+		{name: "escaped name at the limit", url: "/authors/" + url.PathEscape("Сенек"), expectedStatus: http.StatusOK},
+		{
+			name:           "escaped name one over the limit",
+			url:            "/authors/" + url.PathEscape("Сенека"),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":"parameter_too_long","error":"Parameter name is longer than 5 characters."}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.url, nil))
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tc.expectedBody != "" && rr.Body.String() != tc.expectedBody+"\n" {
+				t.Fatalf("expected body %s, got %s", tc.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+// Package pathnorm answers requests for paths that are not in canonical
+// form, such as "/quotes/" or "//quotes", as the configured mode says.
+package pathnorm
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"quotes-service/internal/lib/logger/sl"
+	"quotes-service/internal/lib/pathnorm"
+)
+
+// New redirects the requests whose escaped path is not pathnorm.Clean's
+// form to the one that is, or serves them as if they had asked for it, as
+// mode says. The query string is kept either way.
+//
+// It must wrap the whole router: the router matches before it runs its
+// middleware, and its own cleaning of the path answers 301 to every
+// method, which turns a POST into a GET without its body.
+func New(log *slog.Logger, mode pathnorm.Mode) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		middlewareLog := log.With(
+			slog.String("component", "middleware/pathnorm"),
+		)
+
+		if mode == pathnorm.ModeOff {
+			return next
+		}
+		middlewareLog.Info("path normalization middleware enabled", slog.String("mode", string(mode)))
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			raw := r.URL.EscapedPath()
+			clean := pathnorm.Clean(raw)
+			if clean == raw {
+				next.ServeHTTP(w, r)
+				return
+			}
+			decoded, err := url.PathUnescape(clean)
+			if err != nil {
+				// An invalid escape is the handler's to reject.
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if mode == pathnorm.ModeRewrite {
+				middlewareLog.DebugContext(r.Context(), "path rewritten", sl.UserText("from", raw), sl.UserText("to", clean))
+				u := *r.URL
+				u.Path, u.RawPath = decoded, clean
+				rewritten := r.Clone(r.Context())
+				rewritten.URL = &u
+				next.ServeHTTP(w, rewritten)
+				return
+			}
+
+			location := clean
+			if r.URL.RawQuery != "" {
+				location += "?" + r.URL.RawQuery
+			}
+			status := http.StatusPermanentRedirect
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				status = http.StatusMovedPermanently
+			}
+			middlewareLog.DebugContext(r.Context(), "path redirected", sl.UserText("from", raw), sl.UserText("to", clean), slog.Int("status", status))
+			w.Header().Set("Location", location)
+			w.WriteHeader(status)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,75 @@
+package pathnorm_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	mwPathNorm "quotes-service/internal/http-server/middleware/pathnorm"
+	"quotes-service/internal/lib/pathnorm"
+)
+
+// echo answers with the method, the matched route and its variables, the
+// query and the body it got.
+func echo() http.Handler {
+	router := mux.NewRouter()
+	router.UseEncodedPath()
+	handle := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		route, _ := mux.CurrentRoute(r).GetPathTemplate()
+		io.WriteString(w, r.Method+" "+route+" "+mux.Vars(r)["name"]+" "+r.URL.RawQuery+" "+string(body))
+	}
+	router.HandleFunc("/quotes", handle).Methods(http.MethodGet, http.MethodPost)
+	router.HandleFunc("/authors/{name}", handle).Methods(http.MethodGet)
+	return router
+}
+
+func TestPathNorm(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name         string
+		mode         pathnorm.Mode
+		method       string
+		target       string
+		body         string
+		wantCode     int
+		wantLocation string
+		wantBody     string
+	}{
+		{name: "canonical", mode: pathnorm.ModeRedirect, method: http.MethodGet, target: "/quotes?limit=5", wantCode: http.StatusOK, wantBody: "GET /quotes  limit=5 "},
+		{name: "redirect trailing slash", mode: pathnorm.ModeRedirect, method: http.MethodGet, target: "/quotes/?limit=5", wantCode: http.StatusMovedPermanently, wantLocation: "/quotes?limit=5"},
+		{name: "redirect duplicate slashes", mode: pathnorm.ModeRedirect, method: http.MethodGet, target: "//quotes", wantCode: http.StatusMovedPermanently, wantLocation: "/quotes"},
+		{name: "redirect post", mode: pathnorm.ModeRedirect, method: http.MethodPost, target: "/quotes/?dry_run=true", body: "{}", wantCode: http.StatusPermanentRedirect, wantLocation: "/quotes?dry_run=true"},
+		{name: "redirect keeps encoded slash", mode: pathnorm.ModeRedirect, method: http.MethodGet, target: "/authors/AC%2fDC/", wantCode: http.StatusMovedPermanently, wantLocation: "/authors/AC%2FDC"},
+		{name: "rewrite trailing slash", mode: pathnorm.ModeRewrite, method: http.MethodGet, target: "/quotes/?limit=5", wantCode: http.StatusOK, wantBody: "GET /quotes  limit=5 "},
+		{name: "rewrite duplicate slashes", mode: pathnorm.ModeRewrite, method: http.MethodGet, target: "//quotes", wantCode: http.StatusOK, wantBody: "GET /quotes   "},
+		{name: "rewrite post", mode: pathnorm.ModeRewrite, method: http.MethodPost, target: "/quotes/", body: "{}", wantCode: http.StatusOK, wantBody: "POST /quotes   {}"},
+		{name: "rewrite decodes unreserved", mode: pathnorm.ModeRewrite, method: http.MethodGet, target: "/author%73/AC%2fDC", wantCode: http.StatusOK, wantBody: "GET /authors/{name} AC%2FDC  "},
+		{name: "off", mode: pathnorm.ModeOff, method: http.MethodGet, target: "/quotes/", wantCode: http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := mwPathNorm.New(log, tc.mode)(echo())
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d with %q", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			if got := rr.Header().Get("Location"); got != tc.wantLocation {
+				t.Errorf("expected Location %q, got %q", tc.wantLocation, got)
+			}
+			if tc.wantBody != "" && rr.Body.String() != tc.wantBody {
+				t.Errorf("expected body %q, got %q", tc.wantBody, rr.Body.String())
+			}
+		})
+	}
+}
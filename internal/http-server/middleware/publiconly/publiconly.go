@@ -0,0 +1,196 @@
+package publiconly
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+)
+
+// New hides sequential quote IDs from JSON and JSON Lines responses: every
+// object that carries a "public_id" loses its "id". Objects without a
+// public ID, such as collections, are left alone, and so is the order of
+// the other fields. Responses are buffered to be rewritten, so this is not
+// for streaming.
+func New(log *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		middlewareLog := log.With(
+			slog.String("component", "middleware/publiconly"),
+		)
+
+		middlewareLog.Info("public only middleware enabled")
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			bw := &bufferedWriter{ResponseWriter: w}
+			next.ServeHTTP(bw, r)
+
+			body := bw.body.Bytes()
+			if len(body) > 0 {
+				var stripped []byte
+				var err error
+				switch mediaType(w.Header().Get("Content-Type")) {
+				case "application/json":
+					stripped, err = strip(bytes.TrimSpace(body))
+					stripped = append(stripped, '\n')
+				case "application/x-ndjson":
+					stripped, err = stripLines(body)
+				default:
+					stripped = body
+				}
+				if err != nil {
+					middlewareLog.ErrorContext(r.Context(), "failed to rewrite response", slog.String("path", r.URL.Path), slog.String("error", err.Error()))
+				} else {
+					body = stripped
+					w.Header().Del("Content-Length")
+				}
+			}
+
+			if bw.status != 0 {
+				w.WriteHeader(bw.status)
+			}
+			if len(body) > 0 {
+				w.Write(body)
+			}
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// bufferedWriter holds the status and body back until the handler is done.
+type bufferedWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (bw *bufferedWriter) WriteHeader(status int) {
+	if bw.status == 0 {
+		bw.status = status
+	}
+}
+
+func (bw *bufferedWriter) Write(b []byte) (int, error) {
+	if bw.status == 0 {
+		bw.status = http.StatusOK
+	}
+	return bw.body.Write(b)
+}
+
+func mediaType(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType
+}
+
+// stripLines applies strip to every line of a JSON Lines body.
+func stripLines(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	for line := range bytes.Lines(body) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		stripped, err := strip(line)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(stripped)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// strip rewrites one JSON value, dropping "id" from objects that have a
+// "public_id". Keys keep their order and scalars their exact encoding.
+func strip(raw []byte) ([]byte, error) {
+	if len(raw) == 0 {
+		return raw, nil
+	}
+	switch raw[0] {
+	case '{':
+		return stripObject(raw)
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		buf.WriteByte('[')
+		for i, item := range items {
+			stripped, err := strip(item)
+			if err != nil {
+				return nil, err
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(stripped)
+		}
+		buf.WriteByte(']')
+		return buf.Bytes(), nil
+	default:
+		return raw, nil
+	}
+}
+
+func stripObject(raw []byte) ([]byte, error) {
+	type field struct {
+		key   string
+		value json.RawMessage
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	var fields []field
+	hasPublicID := false
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, errors.New("object key is not a string")
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if key == "public_id" {
+			hasPublicID = true
+		}
+		fields = append(fields, field{key: key, value: value})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	first := true
+	for _, f := range fields {
+		if hasPublicID && f.key == "id" {
+			continue
+		}
+		value, err := strip(f.value)
+		if err != nil {
+			return nil, err
+		}
+		key, err := json.Marshal(f.key)
+		if err != nil {
+			return nil, err
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
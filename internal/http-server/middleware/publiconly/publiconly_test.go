@@ -0,0 +1,93 @@
+package publiconly_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"quotes-service/internal/http-server/middleware/publiconly"
+	"quotes-service/internal/http-server/response"
+)
+
+func TestNew(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := []struct {
+		name        string
+		contentType string
+		status      int
+		body        string
+		want        string
+	}{
+		{
+			name:        "quote",
+			contentType: "application/json",
+			status:      http.StatusOK,
+			body:        `{"status":"success","data":{"id":1,"public_id":"01ARZ3NDEKTSV4RRFFQ69G5FAV","text":"a < b"}}`,
+			want:        `{"status":"success","data":{"public_id":"01ARZ3NDEKTSV4RRFFQ69G5FAV","text":"a < b"}}` + "\n",
+		},
+		{
+			name:        "collection keeps its id",
+			contentType: "application/json; charset=utf-8",
+			status:      http.StatusCreated,
+			body:        `{"id":7,"quotes":[{"id":2,"public_id":"x"},{"id":3}]}`,
+			want:        `{"id":7,"quotes":[{"public_id":"x"},{"id":3}]}` + "\n",
+		},
+		{
+			name:        "json lines",
+			contentType: "application/x-ndjson",
+			status:      http.StatusOK,
+			body:        `{"id":1,"public_id":"x"}` + "\n" + `{"id":2,"public_id":"y"}` + "\n",
+			want:        `{"public_id":"x"}` + "\n" + `{"public_id":"y"}` + "\n",
+		},
+		{
+			name:        "not json",
+			contentType: "text/plain",
+			status:      http.StatusOK,
+			body:        `{"id":1,"public_id":"x"}`,
+			want:        `{"id":1,"public_id":"x"}`,
+		},
+		{
+			name:        "invalid json is passed through",
+			contentType: "application/json",
+			status:      http.StatusOK,
+			body:        `{"id":1,`,
+			want:        `{"id":1,`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := publiconly.New(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/quotes/1", nil))
+
+			if rr.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, rr.Code)
+			}
+			if got := rr.Body.String(); got != tt.want {
+				t.Errorf("unexpected body\n got %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewMatchesEncoder(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	payload := map[string]any{"text": "<b>&</b>", "public_id": "x"}
+	direct := httptest.NewRecorder()
+	response.JSON(direct, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusOK, payload)
+
+	handler := publiconly.New(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response.JSON(w, r, http.StatusOK, payload)
+	}))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rr.Body.String() != direct.Body.String() {
+		t.Fatalf("expected the body unchanged without an id, got %s want %s", rr.Body.String(), direct.Body.String())
+	}
+}
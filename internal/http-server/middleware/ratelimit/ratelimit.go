@@ -0,0 +1,177 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/headers"
+	"quotes-service/internal/http-server/middleware/auth"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/ratelimit"
+	"quotes-service/internal/lib/role"
+)
+
+const (
+	HeaderLimit     = "X-RateLimit-Limit"
+	HeaderRemaining = "X-RateLimit-Remaining"
+	HeaderReset     = "X-RateLimit-Reset"
+)
+
+func init() {
+	headers.Expose(HeaderLimit, HeaderRemaining, HeaderReset, "Retry-After")
+}
+
+// Options configures New. Requests are limited by remote IP or, once
+// authenticated, by principal; the two layers have separate buckets.
+type Options struct {
+	// IP limits anonymous requests by remote IP. Nil leaves them unlimited.
+	IP *ratelimit.Limiter
+	// IPForPrincipals counts authenticated requests against the bucket of
+	// their IP as well as their principal's, so either can turn them away.
+	IPForPrincipals bool
+	// Principal limits authenticated requests by principal, at its own
+	// limit unless Principals or Roles has one for them. Nil leaves them
+	// unlimited.
+	Principal *ratelimit.Limiter
+	// Principals holds the limits of principals by name, and Roles those of
+	// the principals not named by their role. A zero rate is no limit.
+	Principals map[string]ratelimit.Limit
+	Roles      map[role.Role]ratelimit.Limit
+	// AuthRoles gives the role of requests whose authentication did not.
+	AuthRoles auth.Roles
+}
+
+// New limits requests per client. It must run after the auth middleware.
+// Every response carries the state of the binding bucket: the one that
+// turned the request away, or else the one with fewer requests left.
+func New(log *slog.Logger, opts Options) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		middlewareLog := log.With(
+			slog.String("component", "middleware/ratelimit"),
+		)
+
+		middlewareLog.Info("rate limit middleware enabled",
+			slog.Bool("ip", opts.IP != nil),
+			slog.Bool("principal", opts.Principal != nil),
+			slog.Bool("ip_for_principals", opts.IPForPrincipals),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			var keys []string
+			var res ratelimit.Result
+			limited := false
+
+			principal, authenticated := auth.Principal(r.Context())
+			if authenticated && opts.Principal != nil {
+				if limit := principalLimit(opts, r, principal); limit.Rate > 0 {
+					key := "principal:" + principal
+					keys = append(keys, key)
+					res, limited = opts.Principal.AllowLimit(key, limit), true
+				}
+			}
+			// A principal over its own limit leaves the shared IP bucket alone.
+			if (!authenticated || opts.IPForPrincipals) && opts.IP != nil && (!limited || res.Allowed) {
+				key := "ip:" + remoteIP(r)
+				keys = append(keys, key)
+				ipRes := opts.IP.Allow(key)
+				if !limited || binds(ipRes, res) {
+					res = ipRes
+				}
+				limited = true
+			}
+			if !limited {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header := w.Header()
+			header.Set(HeaderLimit, strconv.Itoa(res.Limit))
+			header.Set(HeaderRemaining, strconv.Itoa(res.Remaining))
+			header.Set(HeaderReset, strconv.Itoa(seconds(res.Reset)))
+
+			if !res.Allowed {
+				middlewareLog.WarnContext(r.Context(), "rate limit exceeded", slog.String("key", keys[len(keys)-1]), slog.String("path", r.URL.Path))
+				header.Set("Retry-After", strconv.Itoa(max(seconds(res.RetryAfter), 1)))
+				response.Error(w, r, http.StatusTooManyRequests, apierror.CodeRateLimited, nil)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// The scopes of the buckets a request counts against.
+const (
+	ScopePrincipal = "principal"
+	ScopeIP        = "ip"
+)
+
+// Quota is the state of a bucket a request counts against.
+type Quota struct {
+	Scope string
+	Limit ratelimit.Limit
+	ratelimit.Result
+}
+
+// Quotas returns the buckets that requests like r count against, as they
+// stand, without taking from them. They are those New limits r by: none
+// for a client without a limit.
+func (opts Options) Quotas(r *http.Request) []Quota {
+	var quotas []Quota
+	principal, authenticated := auth.Principal(r.Context())
+	if authenticated && opts.Principal != nil {
+		if limit := principalLimit(opts, r, principal); limit.Rate > 0 {
+			quotas = append(quotas, Quota{Scope: ScopePrincipal, Limit: limit, Result: opts.Principal.Peek("principal:"+principal, limit)})
+		}
+	}
+	if (!authenticated || opts.IPForPrincipals) && opts.IP != nil {
+		limit := opts.IP.Limit()
+		quotas = append(quotas, Quota{Scope: ScopeIP, Limit: limit, Result: opts.IP.Peek("ip:"+remoteIP(r), limit)})
+	}
+	return quotas
+}
+
+// principalLimit is the limit of principal: its own, its role's or the
+// Principal limiter's.
+func principalLimit(opts Options, r *http.Request, principal string) ratelimit.Limit {
+	if limit, ok := opts.Principals[principal]; ok {
+		return limit
+	}
+	current := opts.AuthRoles.Of(r)
+	if current == "" {
+		current = role.Writer
+	}
+	if limit, ok := opts.Roles[current]; ok {
+		return limit
+	}
+	return opts.Principal.Limit()
+}
+
+// binds reports whether a is the binding result over b: a denial over an
+// allowance, otherwise the one with fewer requests left.
+func binds(a, b ratelimit.Result) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+	return a.Remaining < b.Remaining
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return host
+}
+
+// seconds rounds d up to whole seconds as required by the headers.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,193 @@
+package ratelimit_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"quotes-service/internal/http-server/middleware/auth"
+	mwRateLimit "quotes-service/internal/http-server/middleware/ratelimit"
+	"quotes-service/internal/lib/ratelimit"
+	"quotes-service/internal/lib/role"
+)
+
+func TestRateLimitHeaders(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	limiter := ratelimit.New(1, 5, 100, ratelimit.WithClock(func() time.Time { return now }))
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := auth.New(logger, map[string]string{"k": "alice"})(mwRateLimit.New(logger, mwRateLimit.Options{IP: limiter, Principal: limiter})(ok))
+
+	previous := 5
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/quotes", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, rr.Code)
+		}
+		if got := rr.Header().Get(mwRateLimit.HeaderLimit); got != "5" {
+			t.Fatalf("expected limit 5, got %q", got)
+		}
+		remaining, err := strconv.Atoi(rr.Header().Get(mwRateLimit.HeaderRemaining))
+		if err != nil || remaining >= previous {
+			t.Fatalf("request %d: expected remaining below %d, got %q", i, previous, rr.Header().Get(mwRateLimit.HeaderRemaining))
+		}
+		previous = remaining
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/quotes", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("expected Retry-After 1, got %q", got)
+	}
+	if got := rr.Header().Get(mwRateLimit.HeaderReset); got != "5" {
+		t.Errorf("expected reset in 5 seconds, got %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/quotes", nil)
+	req.Header.Set(auth.APIKeyHeader, "k")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || rr.Header().Get(mwRateLimit.HeaderRemaining) != "4" {
+		t.Fatalf("expected authenticated client to have its own bucket, got %d with remaining %q", rr.Code, rr.Header().Get(mwRateLimit.HeaderRemaining))
+	}
+}
+
+func TestRateLimitLayers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	clock := ratelimit.WithClock(func() time.Time { return now })
+	keys := map[string]string{"app-key": "app", "imp-key": "importer", "dash-key": "dashboard", "ops-key": "ops"}
+	roles := auth.Roles{Principals: map[string]role.Role{"dashboard": role.Reader, "ops": role.Admin}}
+
+	tests := []struct {
+		name            string
+		ipForPrincipals bool
+		// requests are sent in order from one IP, with these API keys.
+		requests []string
+		// expected is the status and X-RateLimit-Limit of the last request.
+		expectedStatus int
+		expectedLimit  string
+	}{
+		{name: "anonymous by ip", requests: []string{"", "", ""}, expectedStatus: http.StatusTooManyRequests, expectedLimit: "2"},
+		{name: "principals behind one ip", requests: []string{"", "", "app-key"}, expectedStatus: http.StatusOK, expectedLimit: "3"},
+		{name: "default principal limit", requests: []string{"app-key", "app-key", "app-key", "app-key"}, expectedStatus: http.StatusTooManyRequests, expectedLimit: "3"},
+		{name: "principal override", requests: []string{"imp-key", "imp-key", "imp-key", "imp-key"}, expectedStatus: http.StatusOK, expectedLimit: "100"},
+		{name: "role override", requests: []string{"dash-key", "dash-key"}, expectedStatus: http.StatusTooManyRequests, expectedLimit: "1"},
+		{name: "unlimited role", requests: []string{"ops-key", "ops-key", "ops-key", "ops-key"}, expectedStatus: http.StatusOK},
+		{
+			name:            "ip binds first",
+			ipForPrincipals: true,
+			requests:        []string{"", "", "app-key"},
+			expectedStatus:  http.StatusTooManyRequests,
+			expectedLimit:   "2",
+		},
+		{
+			name:            "principal binds first",
+			ipForPrincipals: true,
+			requests:        []string{"dash-key", "dash-key"},
+			expectedStatus:  http.StatusTooManyRequests,
+			expectedLimit:   "1",
+		},
+		{
+			name:            "fewer left binds",
+			ipForPrincipals: true,
+			requests:        []string{"imp-key"},
+			expectedStatus:  http.StatusOK,
+			expectedLimit:   "2",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := mwRateLimit.Options{
+				IP:              ratelimit.New(1, 2, 100, clock),
+				IPForPrincipals: tc.ipForPrincipals,
+				Principal:       ratelimit.New(1, 3, 100, clock),
+				Principals:      map[string]ratelimit.Limit{"importer": {Rate: 100, Burst: 100}},
+				Roles:           map[role.Role]ratelimit.Limit{role.Reader: {Rate: 1, Burst: 1}, role.Admin: {Burst: 1}},
+				AuthRoles:       roles,
+			}
+			handler := auth.New(logger, keys)(mwRateLimit.New(logger, opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+			var rr *httptest.ResponseRecorder
+			for _, key := range tc.requests {
+				req := httptest.NewRequest(http.MethodGet, "/quotes", nil)
+				if key != "" {
+					req.Header.Set(auth.APIKeyHeader, key)
+				}
+				rr = httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+			}
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rr.Code)
+			}
+			if got := rr.Header().Get(mwRateLimit.HeaderLimit); got != tc.expectedLimit {
+				t.Fatalf("expected limit %q, got %q", tc.expectedLimit, got)
+			}
+		})
+	}
+}
+
+func TestQuotas(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	clock := ratelimit.WithClock(func() time.Time { return now })
+	opts := mwRateLimit.Options{
+		IP:              ratelimit.New(1, 5, 100, clock),
+		IPForPrincipals: true,
+		Principal:       ratelimit.New(1, 3, 100, clock),
+		Roles:           map[role.Role]ratelimit.Limit{role.Admin: {Burst: 1}},
+		AuthRoles:       auth.Roles{Principals: map[string]role.Role{"ops": role.Admin}},
+	}
+	keys := map[string]string{"app-key": "app", "ops-key": "ops"}
+
+	var quotas []mwRateLimit.Quota
+	handler := auth.New(logger, keys)(mwRateLimit.New(logger, opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		quotas = opts.Quotas(r)
+	})))
+	send := func(key string) {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		if key != "" {
+			req.Header.Set(auth.APIKeyHeader, key)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	send("app-key")
+	send("app-key")
+	if len(quotas) != 2 {
+		t.Fatalf("expected the principal and ip buckets, got %+v", quotas)
+	}
+	if q := quotas[0]; q.Scope != mwRateLimit.ScopePrincipal || q.Limit.Burst != 3 || q.Remaining != 1 || q.Reset != 2*time.Second {
+		t.Fatalf("unexpected principal quota %+v", q)
+	}
+	if q := quotas[1]; q.Scope != mwRateLimit.ScopeIP || q.Limit.Burst != 5 || q.Remaining != 3 {
+		t.Fatalf("unexpected ip quota %+v", q)
+	}
+
+	// Reading the quotas takes nothing from the buckets.
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req = req.WithContext(auth.WithPrincipal(req.Context(), "app"))
+	if again := opts.Quotas(req); again[0].Remaining != 1 || again[1].Remaining != 3 {
+		t.Fatalf("expected the buckets unchanged, got %+v", again)
+	}
+
+	send("ops-key")
+	if len(quotas) != 1 || quotas[0].Scope != mwRateLimit.ScopeIP {
+		t.Fatalf("expected only the ip bucket for an unlimited role, got %+v", quotas)
+	}
+}
@@ -0,0 +1,73 @@
+// Package route resolves the path template of the route a request matched,
+// such as "/quotes/{id}", once per request for the logs, metrics and error
+// reports that label requests by route rather than by raw path.
+package route
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Unmatched is the template of a request that matched no route.
+const Unmatched = "unmatched"
+
+type contextKey struct{}
+
+// New stores the template of the matched route in the request context for
+// Template to return. It must be the outermost middleware so that every
+// other one sees it.
+func New(log *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		middlewareLog := log.With(
+			slog.String("component", "middleware/route"),
+		)
+
+		middlewareLog.Info("route middleware enabled")
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), contextKey{}, resolve(r))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Template returns the path template of the route r matched, or Unmatched.
+// Without the middleware in front it resolves the template itself.
+func Template(r *http.Request) string {
+	if template, ok := r.Context().Value(contextKey{}).(string); ok {
+		return template
+	}
+	return resolve(r)
+}
+
+func resolve(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil && template != "" {
+			return template
+		}
+	}
+	return Unmatched
+}
+
+// readPosts are the routes that take a POST only to carry a query too
+// long for a URL. They read like a GET.
+var readPosts = map[string]bool{
+	"/quotes/lookup": true,
+}
+
+// Reads reports whether r only reads: a GET, HEAD or OPTIONS, or a POST
+// to one of the routes that take a POST in place of a long GET. The role
+// check, the audit trail and required signatures treat these alike.
+func Reads(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	case http.MethodPost:
+		return readPosts[Template(r)]
+	}
+	return false
+}
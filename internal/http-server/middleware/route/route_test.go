@@ -0,0 +1,100 @@
+package route_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/middleware/route"
+)
+
+func TestTemplate(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	var seen string
+	record := func(w http.ResponseWriter, r *http.Request) {
+		seen = route.Template(r)
+	}
+
+	router := mux.NewRouter()
+	router.Use(route.New(logger))
+	router.HandleFunc("/quotes", record)
+	router.HandleFunc("/quotes/{id:[0-9]+}", record)
+	router.HandleFunc("/authors/{name}/quotes", record)
+	admin := router.PathPrefix("/admin").Subrouter()
+	admin.HandleFunc("/quotes/{id}", record)
+
+	tests := []struct {
+		name     string
+		url      string
+		expected string
+	}{
+		{name: "static", url: "/quotes", expected: "/quotes"},
+		{name: "parameterized", url: "/quotes/42", expected: "/quotes/{id:[0-9]+}"},
+		{name: "parameter in the middle", url: "/authors/Seneca/quotes", expected: "/authors/{name}/quotes"},
+		{name: "subrouter", url: "/admin/quotes/7", expected: "/admin/quotes/{id}"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			seen = ""
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.url, nil))
+			if seen != tc.expected {
+				t.Fatalf("expected template %q, got %q", tc.expected, seen)
+			}
+		})
+	}
+}
+
+func TestTemplateUnmatched(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	var seen string
+	handler := route.New(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = route.Template(r)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/quotes/42", nil))
+	if seen != route.Unmatched {
+		t.Fatalf("expected %q outside a router, got %q", route.Unmatched, seen)
+	}
+	if got := route.Template(httptest.NewRequest(http.MethodGet, "/quotes/42", nil)); got != route.Unmatched {
+		t.Fatalf("expected %q without the middleware, got %q", route.Unmatched, got)
+	}
+}
+
+func TestReads(t *testing.T) {
+	var reads bool
+	record := func(w http.ResponseWriter, r *http.Request) {
+		reads = route.Reads(r)
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/quotes", record)
+	router.HandleFunc("/quotes/lookup", record)
+
+	tests := []struct {
+		method   string
+		url      string
+		expected bool
+	}{
+		{method: http.MethodGet, url: "/quotes", expected: true},
+		{method: http.MethodHead, url: "/quotes", expected: true},
+		{method: http.MethodPost, url: "/quotes"},
+		{method: http.MethodDelete, url: "/quotes"},
+		{method: http.MethodGet, url: "/quotes/lookup", expected: true},
+		{method: http.MethodPost, url: "/quotes/lookup", expected: true},
+		{method: http.MethodPut, url: "/quotes/lookup"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.method+" "+tc.url, func(t *testing.T) {
+			reads = !tc.expected
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.method, tc.url, nil))
+			if reads != tc.expected {
+				t.Fatalf("expected reads %t, got %t", tc.expected, reads)
+			}
+		})
+	}
+}
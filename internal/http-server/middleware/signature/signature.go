@@ -0,0 +1,132 @@
+// Package signature checks HMAC-signed requests from machine-to-machine
+// clients, which authenticate with a shared secret instead of an API key.
+package signature
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/middleware/auth"
+	"quotes-service/internal/http-server/middleware/route"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/signing"
+)
+
+// Options configures New.
+type Options struct {
+	// Clients maps a client name, sent in the X-Signature-Client header,
+	// to its secret. A verified request is authenticated as that name.
+	Clients map[string]string
+	// MaxSkew is how far the signing time may be from the server's clock.
+	MaxSkew time.Duration
+	// Required rejects unsigned writes instead of passing them on.
+	Required bool
+	// MaxBodyBytes bounds the body buffered to check the signature.
+	MaxBodyBytes int64
+}
+
+// New checks the signature of every request that has one, as computed by
+// signing.Sign over the timestamp, method, request URI and body. A bad
+// signature or unknown client gets 401 invalid_signature, and a good one
+// signed outside MaxSkew 401 stale_signature. A verified request is
+// authenticated as its client, with its body put back for the handler.
+// Unsigned requests pass through unless Required is set and they write;
+// a POST that only reads, as route.Reads tells, does not.
+//
+// Within MaxSkew a captured request can be replayed; writes that must not
+// be repeated need their own idempotency checks.
+func New(log *slog.Logger, opts Options) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		middlewareLog := log.With(
+			slog.String("component", "middleware/signature"),
+		)
+
+		middlewareLog.Info("signature middleware enabled",
+			slog.Int("clients", len(opts.Clients)),
+			slog.Duration("max_skew", opts.MaxSkew),
+			slog.Bool("required", opts.Required),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			signature := r.Header.Get(signing.Header)
+			if signature == "" {
+				if opts.Required && isWrite(r.Method) && !route.Reads(r) {
+					middlewareLog.WarnContext(ctx, "unsigned write", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+					response.Error(w, r, http.StatusUnauthorized, apierror.CodeInvalidSignature, nil)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client := r.Header.Get(signing.ClientHeader)
+			secret, ok := opts.Clients[client]
+			timestamp, err := strconv.ParseInt(r.Header.Get(signing.TimestampHeader), 10, 64)
+			if !ok || err != nil {
+				middlewareLog.WarnContext(ctx, "invalid signature headers", slog.String("client", client), slog.String("timestamp", r.Header.Get(signing.TimestampHeader)))
+				auth.Fail(w, apierror.CodeInvalidSignature)
+				response.Error(w, r, http.StatusUnauthorized, apierror.CodeInvalidSignature, nil)
+				return
+			}
+
+			var body []byte
+			if r.Body != nil {
+				body, err = io.ReadAll(io.LimitReader(r.Body, opts.MaxBodyBytes+1))
+				if err != nil {
+					middlewareLog.WarnContext(ctx, "failed to read signed body", slog.String("client", client), slog.String("error", err.Error()))
+					response.Error(w, r, http.StatusBadRequest, apierror.CodeRequestBodyInvalid, nil)
+					return
+				}
+				if int64(len(body)) > opts.MaxBodyBytes {
+					middlewareLog.WarnContext(ctx, "signed body too large", slog.String("client", client))
+					response.Error(w, r, http.StatusRequestEntityTooLarge, apierror.CodeSignedBodyTooLarge, nil, opts.MaxBodyBytes)
+					return
+				}
+				r.Body = readCloser{Reader: bytes.NewReader(body), Closer: r.Body}
+			}
+
+			if !signing.Verify([]byte(secret), signature, timestamp, r.Method, requestURI(r), body) {
+				middlewareLog.WarnContext(ctx, "bad signature", slog.String("client", client), slog.String("path", r.URL.Path))
+				auth.Fail(w, apierror.CodeInvalidSignature)
+				response.Error(w, r, http.StatusUnauthorized, apierror.CodeInvalidSignature, nil)
+				return
+			}
+			if !signing.Fresh(timestamp, time.Now(), opts.MaxSkew) {
+				middlewareLog.WarnContext(ctx, "stale signature", slog.String("client", client), slog.Int64("timestamp", timestamp))
+				auth.Fail(w, apierror.CodeStaleSignature)
+				response.Error(w, r, http.StatusUnauthorized, apierror.CodeStaleSignature, nil)
+				return
+			}
+
+			next.ServeHTTP(w, auth.Authenticate(w, r, client, auth.MethodSignature))
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// requestURI returns the path and query as the client sent them.
+func requestURI(r *http.Request) string {
+	if r.RequestURI != "" {
+		return r.RequestURI
+	}
+	return r.URL.RequestURI()
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
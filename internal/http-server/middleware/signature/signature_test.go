@@ -0,0 +1,133 @@
+package signature_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"quotes-service/internal/http-server/middleware/auth"
+	"quotes-service/internal/http-server/middleware/signature"
+	"quotes-service/internal/lib/signing"
+)
+
+func TestSignature(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	now := time.Now().Unix()
+	body := `{"text":"Quote","author":"Author"}`
+
+	signed := func(method, target, client, secret string, timestamp int64, body string) *http.Request {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(signing.ClientHeader, client)
+		req.Header.Set(signing.TimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(signing.Header, signing.Sign([]byte(secret), timestamp, method, target, []byte(body)))
+		return req
+	}
+
+	tests := []struct {
+		name              string
+		required          bool
+		req               *http.Request
+		expectedStatus    int
+		expectedCode      string
+		expectedPrincipal string
+	}{
+		{
+			name:              "signed write",
+			req:               signed(http.MethodPost, "/quotes?dry_run=true", "acme", "s3cret", now, body),
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: "acme",
+		},
+		{
+			name:           "unsigned write",
+			req:            httptest.NewRequest(http.MethodPost, "/quotes", strings.NewReader(body)),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unsigned write when required",
+			required:       true,
+			req:            httptest.NewRequest(http.MethodPost, "/quotes", strings.NewReader(body)),
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "invalid_signature",
+		},
+		{
+			name:           "unsigned read when required",
+			required:       true,
+			req:            httptest.NewRequest(http.MethodGet, "/quotes", nil),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "wrong secret",
+			req:            signed(http.MethodPost, "/quotes", "acme", "guess", now, body),
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "invalid_signature",
+		},
+		{
+			name:           "unknown client",
+			req:            signed(http.MethodPost, "/quotes", "initech", "s3cret", now, body),
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "invalid_signature",
+		},
+		{
+			name: "tampered query",
+			req: func() *http.Request {
+				req := signed(http.MethodPost, "/quotes?dry_run=true", "acme", "s3cret", now, body)
+				tampered := httptest.NewRequest(http.MethodPost, "/quotes?dry_run=false", strings.NewReader(body))
+				tampered.Header = req.Header
+				return tampered
+			}(),
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "invalid_signature",
+		},
+		{
+			name:           "stale timestamp",
+			req:            signed(http.MethodPost, "/quotes", "acme", "s3cret", now-600, body),
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "stale_signature",
+		},
+		{
+			name:           "body too large",
+			req:            signed(http.MethodPost, "/quotes", "acme", "s3cret", now, strings.Repeat("x", 2048)),
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedCode:   "signed_body_too_large",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotBody, gotPrincipal string
+			handler := signature.New(logger, signature.Options{
+				Clients:      map[string]string{"acme": "s3cret"},
+				MaxSkew:      5 * time.Minute,
+				Required:     tc.required,
+				MaxBodyBytes: 1024,
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, _ := io.ReadAll(r.Body)
+				gotBody = string(data)
+				gotPrincipal, _ = auth.Principal(r.Context())
+			}))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, tc.req)
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tc.expectedCode != "" && !strings.Contains(rr.Body.String(), `"code":"`+tc.expectedCode+`"`) {
+				t.Fatalf("expected code %s, got %s", tc.expectedCode, rr.Body.String())
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+			if tc.req.Method == http.MethodPost && gotBody != body {
+				t.Fatalf("expected the handler to read the body, got %q", gotBody)
+			}
+			if gotPrincipal != tc.expectedPrincipal {
+				t.Fatalf("expected principal %q, got %q", tc.expectedPrincipal, gotPrincipal)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+// Package validate checks requests against the JSON Schemas of the API
+// models before they reach the handlers, and can check the responses the
+// handlers write against them too. The schemas are the ones /schema
+// publishes, generated from the models and compiled once at startup.
+package validate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	sjs "github.com/santhosh-tekuri/jsonschema/v6"
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/middleware/route"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/jsonschema"
+	"quotes-service/internal/models"
+)
+
+// maxBodyBytes bounds the request bodies that are checked. Larger ones are
+// passed on unchecked for the handler to reject or stream.
+const maxBodyBytes = 1 << 20
+
+// Operation is what a route accepts and returns.
+type Operation struct {
+	// Body is the model a JSON request body must match, or nil.
+	Body any
+	// Query maps query parameters to the schema of their value.
+	Query map[string]jsonschema.Schema
+	// Response is the model of a successful response, or nil for the
+	// standard envelope. Error responses always match models.ErrorResponse.
+	Response any
+}
+
+// Spec maps "METHOD /path" to the operation served there. Path variables
+// are written without their patterns, as in "/quotes/{id}".
+type Spec map[string]Operation
+
+// Compiled is a Spec ready to validate against.
+type Compiled struct {
+	ops     map[string]*operation
+	errors  *sjs.Schema
+	success *sjs.Schema
+}
+
+type operation struct {
+	body     *sjs.Schema
+	query    map[string]*sjs.Schema
+	response *sjs.Schema
+}
+
+// Compile compiles the schemas of spec. They are generated from Go types
+// and plain literals, so compiling can only fail on a programming error.
+func Compile(spec Spec) *Compiled {
+	c := &Compiled{
+		ops:     make(map[string]*operation, len(spec)),
+		errors:  mustCompile("ErrorResponse", jsonschema.Generate(models.ErrorResponse{})),
+		success: mustCompile("SuccessDataResponse", jsonschema.Generate(models.SuccessDataResponse{})),
+	}
+	for key, op := range spec {
+		compiled := &operation{query: make(map[string]*sjs.Schema, len(op.Query))}
+		if op.Body != nil {
+			compiled.body = mustCompile(key+" body", jsonschema.Generate(op.Body))
+		}
+		if op.Response != nil {
+			compiled.response = mustCompile(key+" response", jsonschema.Generate(op.Response))
+		}
+		for name, schema := range op.Query {
+			compiled.query[name] = mustCompile(key+" query "+name, schema)
+		}
+		c.ops[key] = compiled
+	}
+	return c
+}
+
+func mustCompile(name string, schema jsonschema.Schema) *sjs.Schema {
+	doc, err := json.Marshal(schema)
+	if err != nil {
+		panic("validate: encode schema " + name + ": " + err.Error())
+	}
+	inst, err := sjs.UnmarshalJSON(bytes.NewReader(doc))
+	if err != nil {
+		panic("validate: decode schema " + name + ": " + err.Error())
+	}
+	url := "mem://" + strings.NewReplacer(" ", "_", "{", "", "}", "").Replace(name) + ".json"
+	compiler := sjs.NewCompiler()
+	if err := compiler.AddResource(url, inst); err != nil {
+		panic("validate: add schema " + name + ": " + err.Error())
+	}
+	compiled, err := compiler.Compile(url)
+	if err != nil {
+		panic("validate: compile schema " + name + ": " + err.Error())
+	}
+	return compiled
+}
+
+type options struct {
+	responses bool
+}
+
+type Option func(*options)
+
+// WithResponses also checks the JSON responses of the operations in the
+// spec, logging those that do not match. Responses are buffered to be
+// checked, so this is meant for development.
+func WithResponses() Option {
+	return func(o *options) {
+		o.responses = true
+	}
+}
+
+// New checks the query parameters and JSON body of requests to the
+// operations in spec and answers 400 with the path of every mismatch in
+// the error's fields. Empty bodies and bodies that are not JSON are left
+// to the handlers, which report them with their own codes.
+func New(log *slog.Logger, spec *Compiled, opts ...Option) func(next http.Handler) http.Handler {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return func(next http.Handler) http.Handler {
+		middlewareLog := log.With(
+			slog.String("component", "middleware/validate"),
+		)
+
+		middlewareLog.Info("request validation enabled", slog.Int("operations", len(spec.ops)), slog.Bool("responses", o.responses))
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			key := operationKey(r)
+			op, ok := spec.ops[key]
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			problems := op.checkQuery(r)
+			bodyProblems, err := op.checkBody(r)
+			if err != nil {
+				middlewareLog.ErrorContext(r.Context(), "failed to read request body", slog.String("operation", key), slog.String("error", err.Error()))
+				response.Error(w, r, http.StatusBadRequest, apierror.CodeRequestBodyInvalid, nil)
+				return
+			}
+			problems = append(problems, bodyProblems...)
+			if len(problems) > 0 {
+				middlewareLog.WarnContext(r.Context(), "request does not match the spec", slog.String("operation", key), slog.Any("problems", problems))
+				response.Error(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, problems)
+				return
+			}
+
+			// The schemas describe the envelope, so bare responses are
+			// not checked.
+			if !o.responses || !response.Enveloped(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			bw := &bufferedWriter{ResponseWriter: w}
+			next.ServeHTTP(bw, r)
+			if problems := spec.checkResponse(op, bw); len(problems) > 0 {
+				middlewareLog.WarnContext(r.Context(), "response does not match the spec", slog.String("operation", key), slog.Int("status", bw.status), slog.Any("problems", problems))
+			}
+			bw.flush()
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+var pathPattern = regexp.MustCompile(`\{([^{}:]+):(?:[^{}]|\{[^{}]*\})*\}`)
+
+// operationKey returns the spec key of the route r matched, with the
+// patterns of its path variables dropped.
+func operationKey(r *http.Request) string {
+	tmpl := route.Template(r)
+	if tmpl == route.Unmatched {
+		return ""
+	}
+	return r.Method + " " + pathPattern.ReplaceAllString(tmpl, "{$1}")
+}
+
+// checkQuery checks the query parameters the operation declares. Values
+// that read as JSON numbers or booleans are checked as such, and anything
+// else as a string.
+func (op *operation) checkQuery(r *http.Request) []string {
+	var problems []string
+	query := r.URL.Query()
+	for name, schema := range op.query {
+		raw, ok := query[name]
+		if !ok || len(raw) == 0 {
+			continue
+		}
+		var value any = raw[0]
+		if parsed, err := sjs.UnmarshalJSON(strings.NewReader(raw[0])); err == nil {
+			switch parsed.(type) {
+			case json.Number, bool:
+				value = parsed
+			}
+		}
+		problems = append(problems, describe("query/"+name, schema.Validate(value))...)
+	}
+	slices.Sort(problems)
+	return problems
+}
+
+// checkBody checks the request body against the operation's model and
+// puts it back for the handler.
+func (op *operation) checkBody(r *http.Request) ([]string, error) {
+	if op.body == nil || r.Body == nil {
+		return nil, nil
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxBodyBytes {
+		r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(data), r.Body), Closer: r.Body}
+		return nil, nil
+	}
+	r.Body = readCloser{Reader: bytes.NewReader(data), Closer: r.Body}
+
+	inst, err := sjs.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil
+	}
+	return describe("body", op.body.Validate(inst)), nil
+}
+
+// checkResponse checks a buffered JSON response: errors against
+// models.ErrorResponse, and successes against the operation's model or the
+// standard envelope.
+func (c *Compiled) checkResponse(op *operation, bw *bufferedWriter) []string {
+	mediaType, _, _ := mime.ParseMediaType(bw.Header().Get("Content-Type"))
+	if mediaType != "application/json" || bw.body.Len() == 0 {
+		return nil
+	}
+	status := bw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	var schema *sjs.Schema
+	switch {
+	case status >= http.StatusBadRequest:
+		schema = c.errors
+	case status >= http.StatusOK && status < http.StatusMultipleChoices:
+		schema = c.success
+		if op.response != nil {
+			schema = op.response
+		}
+	default:
+		return nil
+	}
+	inst, err := sjs.UnmarshalJSON(bytes.NewReader(bw.body.Bytes()))
+	if err != nil {
+		return []string{"body: " + err.Error()}
+	}
+	return describe("body", schema.Validate(inst))
+}
+
+// describe turns a validation error into one "prefix/path: problem" line
+// per mismatch.
+func describe(prefix string, err error) []string {
+	if err == nil {
+		return nil
+	}
+	var verr *sjs.ValidationError
+	if !errors.As(err, &verr) {
+		return []string{fmt.Sprintf("%s: %v", prefix, err)}
+	}
+	var problems []string
+	for _, unit := range verr.BasicOutput().Errors {
+		if unit.Error == nil {
+			continue
+		}
+		problems = append(problems, prefix+unit.InstanceLocation+": "+unit.Error.String())
+	}
+	slices.Sort(problems)
+	return problems
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bufferedWriter holds the status and body back until they are checked.
+type bufferedWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (bw *bufferedWriter) WriteHeader(status int) {
+	if bw.status == 0 {
+		bw.status = status
+	}
+}
+
+func (bw *bufferedWriter) Write(b []byte) (int, error) {
+	if bw.status == 0 {
+		bw.status = http.StatusOK
+	}
+	return bw.body.Write(b)
+}
+
+func (bw *bufferedWriter) flush() {
+	if bw.status != 0 {
+		bw.ResponseWriter.WriteHeader(bw.status)
+	}
+	if bw.body.Len() > 0 {
+		bw.ResponseWriter.Write(bw.body.Bytes())
+	}
+}
@@ -0,0 +1,203 @@
+package validate_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"quotes-service/internal/http-server/middleware/validate"
+	"quotes-service/internal/lib/jsonschema"
+	"quotes-service/internal/models"
+)
+
+var spec = validate.Spec{
+	"POST /quotes": {Body: models.AddQuoteRequest{}, Response: models.AddQuoteResponse{}},
+	"PATCH /quotes/{id}": {
+		Body: models.UpdateQuoteRequest{},
+	},
+	"GET /quotes": {Query: map[string]jsonschema.Schema{
+		"limit":  {"type": "integer", "minimum": 1},
+		"offset": {"type": "integer", "minimum": 0},
+	}},
+}
+
+// echo answers with the body it was sent, or with the response set for
+// the test.
+func newRouter(log *slog.Logger, resp string, opts ...validate.Option) *mux.Router {
+	router := mux.NewRouter()
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if resp != "" {
+			body = []byte(resp)
+		}
+		w.Write(body)
+	}
+	router.HandleFunc("/quotes", echo).Methods(http.MethodPost, http.MethodGet)
+	router.HandleFunc("/quotes/{id:[0-9]+|[0-9a-f]{8}}", echo).Methods(http.MethodPatch)
+	router.HandleFunc("/collections", echo).Methods(http.MethodPost)
+	router.Use(validate.New(log, validate.Compile(spec), opts...))
+	return router
+}
+
+func TestValidateRequests(t *testing.T) {
+	router := newRouter(slog.New(slog.NewTextHandler(io.Discard, nil)), "")
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		expectedStatus int
+		expectedFields []string
+	}{
+		{
+			name:           "valid body",
+			method:         http.MethodPost,
+			url:            "/quotes",
+			body:           `{"text":"T","author":"A","weight":3}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid body",
+			method:         http.MethodPost,
+			url:            "/quotes",
+			body:           `{"text":1,"weight":"heavy"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []string{
+				"body/text: got number, want string",
+				"body/weight: got string, want null or integer",
+				"body: missing property 'author'",
+			},
+		},
+		{
+			name:           "path variable with a pattern",
+			method:         http.MethodPatch,
+			url:            "/quotes/0badcafe",
+			body:           `{"text":["T"]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []string{"body/text: got array, want null or string"},
+		},
+		{
+			name:           "empty body is left to the handler",
+			method:         http.MethodPost,
+			url:            "/quotes",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "malformed body is left to the handler",
+			method:         http.MethodPost,
+			url:            "/quotes",
+			body:           `{"text":`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "valid query",
+			method:         http.MethodGet,
+			url:            "/quotes?limit=10&offset=0&author=A",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid query",
+			method:         http.MethodGet,
+			url:            "/quotes?limit=0&offset=first",
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []string{
+				"query/limit: minimum: got 0, want 1",
+				"query/offset: got string, want integer",
+			},
+		},
+		{
+			name:           "operation outside the spec",
+			method:         http.MethodPost,
+			url:            "/collections",
+			body:           `{"name":1}`,
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body)))
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d. Body: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tc.expectedStatus == http.StatusOK {
+				if got := rr.Body.String(); got != tc.body {
+					t.Fatalf("expected the handler to read %q, got %q", tc.body, got)
+				}
+				return
+			}
+			var resp models.ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode error: %v", err)
+			}
+			if resp.Code != "invalid_request" || strings.Join(resp.Fields, "\n") != strings.Join(tc.expectedFields, "\n") {
+				t.Fatalf("unexpected error %+v", resp)
+			}
+		})
+	}
+}
+
+func TestValidateResponses(t *testing.T) {
+	tests := []struct {
+		name       string
+		response   string
+		mismatched bool
+	}{
+		{name: "matching response", response: `{"status":"success","id":1,"text":"T","author":"A","lang":"en","lang_detected":false}`},
+		{name: "mismatched response", response: `{"status":"success","id":"1"}`, mismatched: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			router := newRouter(slog.New(slog.NewTextHandler(&logs, nil)), tc.response, validate.WithResponses())
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/quotes", strings.NewReader(`{"text":"T","author":"A"}`)))
+			if rr.Code != http.StatusOK || rr.Body.String() != tc.response {
+				t.Fatalf("expected the response to pass through, got %d %s", rr.Code, rr.Body.String())
+			}
+			if got := strings.Contains(logs.String(), "response does not match the spec"); got != tc.mismatched {
+				t.Fatalf("expected mismatch logged: %v, logs: %s", tc.mismatched, logs.String())
+			}
+		})
+	}
+}
+
+func BenchmarkValidate(b *testing.B) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	body := `{"text":"Simplicity is prerequisite for reliability.","author":"Edsger Dijkstra","weight":3,"lang":"en"}`
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var req models.AddQuoteRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		w.WriteHeader(http.StatusCreated)
+	}
+
+	for _, bc := range []struct {
+		name     string
+		validate bool
+	}{{"without", false}, {"with", true}} {
+		b.Run(bc.name, func(b *testing.B) {
+			router := mux.NewRouter()
+			router.HandleFunc("/quotes", handler).Methods(http.MethodPost)
+			if bc.validate {
+				router.Use(validate.New(log, validate.Compile(spec)))
+			}
+			b.ReportAllocs()
+			for b.Loop() {
+				rr := httptest.NewRecorder()
+				router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/quotes", strings.NewReader(body)))
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Package pagination parses limit/offset query parameters, enforces the
+// page sizes and builds the matching RFC 8288 Link headers.
+package pagination
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"quotes-service/internal/http-server/headers"
+)
+
+var (
+	ErrInvalidLimit  = errors.New("invalid limit")
+	ErrInvalidOffset = errors.New("invalid offset")
+)
+
+// ClampedHeader is set to "true" on responses whose page is smaller than
+// the limit asked for.
+const ClampedHeader = "X-Page-Size-Clamped"
+
+// TotalCountHeader carries the length of the whole list a page is from.
+const TotalCountHeader = "X-Total-Count"
+
+func init() {
+	headers.Expose(ClampedHeader, TotalCountHeader, "Link")
+}
+
+// Sizes are the page sizes a list endpoint enforces.
+type Sizes struct {
+	// Default is the page size of requests without a limit.
+	Default int
+	// Max is the largest page size served; larger limits are clamped.
+	Max int
+}
+
+// Clamp returns the page size to serve for a requested limit, and whether
+// the limit was cut down to s.Max. A limit of zero or less counts as none
+// and gets s.Default, which is itself never more than s.Max.
+func (s Sizes) Clamp(limit int) (int, bool) {
+	if limit <= 0 {
+		return min(s.Default, s.Max), false
+	}
+	if limit > s.Max {
+		return s.Max, true
+	}
+	return limit, false
+}
+
+// Page is a window into a list.
+type Page struct {
+	Limit  int
+	Offset int
+	// Clamped is set when Limit is less than the limit asked for.
+	Clamped bool
+}
+
+// Slice returns the part of items within page.
+func Slice[T any](items []T, page Page) []T {
+	start := min(page.Offset, len(items))
+	end := min(start+page.Limit, len(items))
+	return items[start:end]
+}
+
+// Parse reads limit and offset from the query string. A missing limit gets
+// the default size and larger ones than the maximum are clamped; a limit
+// or offset that is not a number, or is out of range, is an error.
+func Parse(r *http.Request, sizes Sizes) (Page, error) {
+	query := r.URL.Query()
+	page := Page{}
+	page.Limit, _ = sizes.Clamp(0)
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return Page{}, ErrInvalidLimit
+		}
+		page.Limit, page.Clamped = sizes.Clamp(limit)
+	}
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			return Page{}, ErrInvalidOffset
+		}
+		page.Offset = offset
+	}
+	return page, nil
+}
+
+// Links returns the Link header value for page within a list of total
+// items. URLs keep the request's path and every other query parameter;
+// only limit and offset are replaced. prev and next are omitted at the
+// edges of the list.
+func Links(u *url.URL, page Page, total int) string {
+	links := []string{link(u, page.Limit, 0, "first")}
+	if page.Offset > 0 {
+		links = append(links, link(u, page.Limit, max(page.Offset-page.Limit, 0), "prev"))
+	}
+	if page.Offset+page.Limit < total {
+		links = append(links, link(u, page.Limit, page.Offset+page.Limit, "next"))
+	}
+	last := 0
+	if total > 0 {
+		last = (total - 1) / page.Limit * page.Limit
+	}
+	links = append(links, link(u, page.Limit, last, "last"))
+	return strings.Join(links, ", ")
+}
+
+// SetHeaders writes the Link and X-Total-Count headers for page, and
+// X-Page-Size-Clamped when its limit was clamped.
+func SetHeaders(w http.ResponseWriter, r *http.Request, page Page, total int) {
+	w.Header().Set("Link", Links(r.URL, page, total))
+	w.Header().Set(TotalCountHeader, strconv.Itoa(total))
+	if page.Clamped {
+		w.Header().Set(ClampedHeader, "true")
+	}
+}
+
+func link(u *url.URL, limit, offset int, rel string) string {
+	query := u.Query()
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+	target := url.URL{Path: u.Path, RawQuery: query.Encode()}
+	return "<" + target.String() + `>; rel="` + rel + `"`
+}
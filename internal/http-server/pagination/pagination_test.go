@@ -0,0 +1,232 @@
+package pagination_test
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"testing"
+
+	"quotes-service/internal/http-server/pagination"
+)
+
+func TestClamp(t *testing.T) {
+	sizes := pagination.Sizes{Default: 20, Max: 100}
+	tests := []struct {
+		name            string
+		sizes           pagination.Sizes
+		limit           int
+		expectedLimit   int
+		expectedClamped bool
+	}{
+		{name: "none", sizes: sizes, limit: 0, expectedLimit: 20},
+		{name: "negative", sizes: sizes, limit: -5, expectedLimit: 20},
+		{name: "within", sizes: sizes, limit: 50, expectedLimit: 50},
+		{name: "at max", sizes: sizes, limit: 100, expectedLimit: 100},
+		{name: "above max", sizes: sizes, limit: 100000, expectedLimit: 100, expectedClamped: true},
+		{name: "default above max", sizes: pagination.Sizes{Default: 500, Max: 100}, limit: 0, expectedLimit: 100},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			limit, clamped := tc.sizes.Clamp(tc.limit)
+			if limit != tc.expectedLimit || clamped != tc.expectedClamped {
+				t.Errorf("expected %d, %v, got %d, %v", tc.expectedLimit, tc.expectedClamped, limit, clamped)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		expected    pagination.Page
+		expectedErr error
+	}{
+		{name: "defaults", query: "", expected: pagination.Page{Limit: 20}},
+		{name: "explicit", query: "?limit=5&offset=10", expected: pagination.Page{Limit: 5, Offset: 10}},
+		{name: "clamped", query: "?limit=1000", expected: pagination.Page{Limit: 100, Clamped: true}},
+		{name: "zero limit", query: "?limit=0", expectedErr: pagination.ErrInvalidLimit},
+		{name: "negative limit", query: "?limit=-1", expectedErr: pagination.ErrInvalidLimit},
+		{name: "bad limit", query: "?limit=ten", expectedErr: pagination.ErrInvalidLimit},
+		{name: "zero offset", query: "?offset=0", expected: pagination.Page{Limit: 20}},
+		{name: "negative offset", query: "?offset=-1", expectedErr: pagination.ErrInvalidOffset},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := pagination.Parse(httptest.NewRequest("GET", "/favorites"+tc.query, nil), pagination.Sizes{Default: 20, Max: 100})
+			if err != tc.expectedErr {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if page != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, page)
+			}
+		})
+	}
+}
+
+func TestSlice(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	tests := []struct {
+		name     string
+		page     pagination.Page
+		expected []int
+	}{
+		{name: "first page", page: pagination.Page{Limit: 2}, expected: []int{1, 2}},
+		{name: "last page", page: pagination.Page{Limit: 2, Offset: 4}, expected: []int{5}},
+		{name: "past the end", page: pagination.Page{Limit: 2, Offset: 10}, expected: []int{}},
+		{name: "whole list", page: pagination.Page{Limit: 10}, expected: items},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := pagination.Slice(items, tc.page); !slices.Equal(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSetHeaders(t *testing.T) {
+	tests := []struct {
+		name            string
+		page            pagination.Page
+		expectedClamped string
+	}{
+		{name: "within limits", page: pagination.Page{Limit: 10}},
+		{name: "clamped", page: pagination.Page{Limit: 100, Clamped: true}, expectedClamped: "true"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			pagination.SetHeaders(rr, httptest.NewRequest("GET", "/quotes?limit=5000", nil), tc.page, 25)
+			if got := rr.Header().Get(pagination.ClampedHeader); got != tc.expectedClamped {
+				t.Errorf("expected %s %q, got %q", pagination.ClampedHeader, tc.expectedClamped, got)
+			}
+			if got := rr.Header().Get(pagination.TotalCountHeader); got != "25" {
+				t.Errorf("expected total count 25, got %q", got)
+			}
+			first := parseLinks(t, rr.Header().Get("Link"))["first"]
+			if got := first.Get("limit"); got != strconv.Itoa(tc.page.Limit) {
+				t.Errorf("expected links to carry the effective limit %d, got %s", tc.page.Limit, got)
+			}
+		})
+	}
+}
+
+var linkRe = regexp.MustCompile(`<([^>]*)>; rel="([a-z]+)"`)
+
+func parseLinks(t *testing.T, header string) map[string]url.Values {
+	t.Helper()
+	links := make(map[string]url.Values)
+	for _, m := range linkRe.FindAllStringSubmatch(header, -1) {
+		u, err := url.Parse(m[1])
+		if err != nil {
+			t.Fatalf("invalid link URL %q: %v", m[1], err)
+		}
+		if u.Path != "/quotes" {
+			t.Errorf("expected path /quotes, got %q", u.Path)
+		}
+		links[m[2]] = u.Query()
+	}
+	return links
+}
+
+func TestLinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     pagination.Page
+		total    int
+		expected map[string]string
+	}{
+		{
+			name:     "first page",
+			page:     pagination.Page{Limit: 10, Offset: 0},
+			total:    25,
+			expected: map[string]string{"first": "0", "next": "10", "last": "20"},
+		},
+		{
+			name:     "middle page",
+			page:     pagination.Page{Limit: 10, Offset: 10},
+			total:    25,
+			expected: map[string]string{"first": "0", "prev": "0", "next": "20", "last": "20"},
+		},
+		{
+			name:     "last page",
+			page:     pagination.Page{Limit: 10, Offset: 20},
+			total:    25,
+			expected: map[string]string{"first": "0", "prev": "10", "last": "20"},
+		},
+		{
+			name:     "unaligned offset",
+			page:     pagination.Page{Limit: 10, Offset: 5},
+			total:    30,
+			expected: map[string]string{"first": "0", "prev": "0", "next": "15", "last": "20"},
+		},
+		{
+			name:     "exact multiple",
+			page:     pagination.Page{Limit: 10, Offset: 0},
+			total:    20,
+			expected: map[string]string{"first": "0", "next": "10", "last": "10"},
+		},
+		{
+			name:     "empty list",
+			page:     pagination.Page{Limit: 10, Offset: 0},
+			total:    0,
+			expected: map[string]string{"first": "0", "last": "0"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			u, _ := url.Parse("/quotes?author=A&sort=id")
+			links := parseLinks(t, pagination.Links(u, tc.page, tc.total))
+			if len(links) != len(tc.expected) {
+				t.Fatalf("expected rels %v, got %v", tc.expected, links)
+			}
+			for rel, offset := range tc.expected {
+				query, ok := links[rel]
+				if !ok {
+					t.Fatalf("missing rel %q", rel)
+				}
+				if got := query.Get("offset"); got != offset {
+					t.Errorf("rel %q: expected offset %s, got %s", rel, offset, got)
+				}
+				if got := query.Get("limit"); got != "10" {
+					t.Errorf("rel %q: expected limit 10, got %s", rel, got)
+				}
+				if query.Get("author") != "A" || query.Get("sort") != "id" {
+					t.Errorf("rel %q: other parameters not preserved: %v", rel, query)
+				}
+			}
+		})
+	}
+}
+
+func TestLinksEncoding(t *testing.T) {
+	authors := []string{"Lao Tzu", "Фёдор Достоевский", "Søren & Co", "50% off; a=b"}
+
+	for _, author := range authors {
+		t.Run(author, func(t *testing.T) {
+			u := &url.URL{Path: "/quotes", RawQuery: url.Values{"author": {author}, "limit": {"2"}}.Encode()}
+			header := pagination.Links(u, pagination.Page{Limit: 2, Offset: 2}, 10)
+
+			links := parseLinks(t, header)
+			if len(links) != 4 {
+				t.Fatalf("expected 4 links, got %d in %q", len(links), header)
+			}
+			for rel, query := range links {
+				if got := query.Get("author"); got != author {
+					t.Errorf("rel %q: expected author %q, got %q", rel, author, got)
+				}
+			}
+			if regexp.MustCompile(`[ <>"]`).MatchString(linkRe.FindStringSubmatch(header)[1]) {
+				t.Errorf("link URL is not escaped: %q", header)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package response
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/lib/usertext"
+	"quotes-service/internal/models"
+)
+
+// ProblemContentType is the media type of the RFC 7807 error documents
+// written for requests without the envelope.
+const ProblemContentType = "application/problem+json"
+
+type envelopeKey struct{}
+
+// WithEnvelope returns a copy of ctx in which responses are written in the
+// standard envelope, or bare if enveloped is false.
+func WithEnvelope(ctx context.Context, enveloped bool) context.Context {
+	return context.WithValue(ctx, envelopeKey{}, enveloped)
+}
+
+// Enveloped reports whether responses to r are written in the standard
+// envelope: {"status":"success","data":...} and models.ErrorResponse. It
+// is true unless WithEnvelope said otherwise for r's context.
+func Enveloped(r *http.Request) bool {
+	enveloped, ok := r.Context().Value(envelopeKey{}).(bool)
+	return !ok || enveloped
+}
+
+// JSON writes payload as JSON. For a request without the envelope, a
+// success payload is written bare: the data of models.SuccessDataResponse,
+// or any other payload without its "status" field.
+func JSON(w http.ResponseWriter, r *http.Request, statusCode int, payload interface{}) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("failed to encode and write JSON response", slog.String("error", err.Error()))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	RawJSON(w, r, statusCode, append(body, '\n'))
+}
+
+// Error writes an error response with the message of code rendered in the
+// language negotiated from the request's Accept-Language header. args fill
+// the message template. Requests without the envelope get a models.Problem
+// instead of a models.ErrorResponse. String args and fields are cut to what
+// the usertext policy lets error responses carry, since they may repeat
+// what the client sent. Middleware further out learns the code
+// if its writer, or one it unwraps to, has a SetErrorCode(code
+// apierror.Code) method.
+func Error(w http.ResponseWriter, r *http.Request, statusCode int, code apierror.Code, fields []string, args ...any) {
+	announce(w, code)
+	for i, arg := range args {
+		if s, ok := arg.(string); ok {
+			args[i] = usertext.Cut(usertext.Error, s)
+		}
+	}
+	if len(fields) > 0 {
+		cut := make([]string, len(fields))
+		for i, field := range fields {
+			cut[i] = usertext.Cut(usertext.Error, field)
+		}
+		fields = cut
+	}
+	lang := apierror.Default.Negotiate(r.Header.Get("Accept-Language"))
+	message := apierror.Default.Message(lang, code, args...)
+	w.Header().Set("Content-Language", lang)
+	w.Header().Add("Vary", "Accept-Language")
+
+	if !Enveloped(r) {
+		problem := models.Problem{
+			Type:     "about:blank",
+			Title:    http.StatusText(statusCode),
+			Status:   statusCode,
+			Detail:   message,
+			Instance: r.URL.Path,
+			Code:     string(code),
+			Fields:   fields,
+		}
+		body, err := json.Marshal(problem)
+		if err != nil {
+			slog.Error("failed to encode problem response", slog.String("error", err.Error()))
+		}
+		write(w, ProblemContentType, statusCode, append(body, '\n'))
+		return
+	}
+
+	response := models.ErrorResponse{
+		Status: "error",
+		Code:   string(code),
+		Error:  message,
+	}
+	if len(fields) > 0 {
+		response.Fields = fields
+	}
+	JSON(w, r, statusCode, response)
+}
+
+// WriteError writes the error response apierror.Resolve maps err to.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	e := apierror.Resolve(err)
+	Error(w, r, e.Status, e.Code, e.Fields, e.Args...)
+}
+
+// Handle adapts a handler that returns its errors rather than writing
+// them: a non-nil error is written by WriteError. The handler must not
+// have written anything before it returns one.
+func Handle(h func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h(w, r); err != nil {
+			WriteError(w, r, err)
+		}
+	}
+}
+
+// announce hands code to every writer around w that takes it.
+func announce(w http.ResponseWriter, code apierror.Code) {
+	for w != nil {
+		if outer, ok := w.(interface{ SetErrorCode(code apierror.Code) }); ok {
+			outer.SetErrorCode(code)
+		}
+		wrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return
+		}
+		w = wrapper.Unwrap()
+	}
+}
+
+// RawJSON writes body, which must already be encoded JSON, unwrapping it
+// like JSON for a request without the envelope.
+func RawJSON(w http.ResponseWriter, r *http.Request, statusCode int, body []byte) {
+	if !Enveloped(r) {
+		body = bare(body)
+	}
+	write(w, "application/json", statusCode, body)
+}
+
+func write(w http.ResponseWriter, contentType string, statusCode int, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(body); err != nil {
+		slog.Error("failed to write JSON response", slog.String("error", err.Error()))
+	}
+}
+
+// bare strips the envelope from an encoded success response: an object
+// whose "status" is "success" becomes its "data", or itself without
+// "status" if it has no data. Anything else is returned as it is. The
+// remaining fields keep their order.
+func bare(body []byte) []byte {
+	type field struct {
+		key   string
+		value json.RawMessage
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return body
+	}
+	var fields []field
+	success := false
+	var data json.RawMessage
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return body
+		}
+		key, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return body
+		}
+		switch key {
+		case "status":
+			success = string(value) == `"success"`
+		case "data":
+			data = value
+		default:
+			fields = append(fields, field{key: key, value: value})
+		}
+	}
+	if !success {
+		return body
+	}
+	if data != nil {
+		return append(data, '\n')
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range fields {
+		key, _ := json.Marshal(f.key)
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(f.value)
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
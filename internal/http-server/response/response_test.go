@@ -0,0 +1,140 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"quotes-service/internal/http-server/apierror"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/lib/usertext"
+	"quotes-service/internal/models"
+)
+
+func request(enveloped bool) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/quotes/7", nil)
+	return r.WithContext(response.WithEnvelope(r.Context(), enveloped))
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  any
+		envelope string
+		bare     string
+	}{
+		{
+			name:     "data",
+			payload:  models.SuccessDataResponse{Status: "success", Data: []models.Quote{{ID: 1, Text: "A", Author: "B"}}},
+			envelope: `{"status":"success","data":[{"id":1,"text":"A","author":"B"}]}`,
+			bare:     `[{"id":1,"text":"A","author":"B"}]`,
+		},
+		{
+			name:     "null data",
+			payload:  models.SuccessDataResponse{Status: "success"},
+			envelope: `{"status":"success","data":null}`,
+			bare:     `null`,
+		},
+		{
+			name:     "flat",
+			payload:  models.AddQuoteResponse{Status: "success", ID: 1, Text: "A", Author: "B"},
+			envelope: `{"status":"success","id":1,"text":"A","author":"B"}`,
+			bare:     `{"id":1,"text":"A","author":"B"}`,
+		},
+		{
+			name:     "message",
+			payload:  models.GenericMessageResponse{Status: "success", Message: "Done."},
+			envelope: `{"status":"success","message":"Done."}`,
+			bare:     `{"message":"Done."}`,
+		},
+		{
+			name:     "not an envelope",
+			payload:  map[string]string{"status": "pending"},
+			envelope: `{"status":"pending"}`,
+			bare:     `{"status":"pending"}`,
+		},
+		{
+			name:     "html kept escaped",
+			payload:  models.SuccessDataResponse{Status: "success", Data: "<b>&</b>"},
+			envelope: `{"status":"success","data":"\u003cb\u003e\u0026\u003c/b\u003e"}`,
+			bare:     `"\u003cb\u003e\u0026\u003c/b\u003e"`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for enveloped, expected := range map[bool]string{true: tc.envelope, false: tc.bare} {
+				rr := httptest.NewRecorder()
+				response.JSON(rr, request(enveloped), http.StatusCreated, tc.payload)
+				if rr.Code != http.StatusCreated || rr.Header().Get("Content-Type") != "application/json" {
+					t.Fatalf("enveloped %v: expected 201 JSON, got %d %q", enveloped, rr.Code, rr.Header().Get("Content-Type"))
+				}
+				if got := rr.Body.String(); got != expected+"\n" {
+					t.Fatalf("enveloped %v: expected %s, got %s", enveloped, expected, got)
+				}
+
+				raw := httptest.NewRecorder()
+				response.RawJSON(raw, request(enveloped), http.StatusOK, []byte(tc.envelope+"\n"))
+				if got := raw.Body.String(); got != expected+"\n" {
+					t.Fatalf("enveloped %v: expected raw %s, got %s", enveloped, expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestEnvelopedDefault(t *testing.T) {
+	if !response.Enveloped(httptest.NewRequest(http.MethodGet, "/", nil)) {
+		t.Fatal("expected responses to be enveloped unless the context says otherwise")
+	}
+}
+
+func TestError(t *testing.T) {
+	tests := []struct {
+		name        string
+		enveloped   bool
+		contentType string
+		expected    string
+	}{
+		{
+			name:        "envelope",
+			enveloped:   true,
+			contentType: "application/json",
+			expected:    `{"status":"error","code":"invalid_request","error":"Invalid request.","fields":["text cannot be empty"]}`,
+		},
+		{
+			name:        "problem",
+			contentType: response.ProblemContentType,
+			expected:    `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid request.","instance":"/quotes/7","code":"invalid_request","fields":["text cannot be empty"]}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			response.Error(rr, request(tc.enveloped), http.StatusBadRequest, apierror.CodeInvalidRequest, []string{"text cannot be empty"})
+			if rr.Code != http.StatusBadRequest || rr.Header().Get("Content-Type") != tc.contentType {
+				t.Fatalf("expected 400 %s, got %d %q", tc.contentType, rr.Code, rr.Header().Get("Content-Type"))
+			}
+			if got := rr.Body.String(); got != tc.expected+"\n" {
+				t.Fatalf("expected %s, got %s", tc.expected, got)
+			}
+			if rr.Header().Get("Content-Language") != "en" {
+				t.Fatalf("expected the message language in Content-Language, got %q", rr.Header().Get("Content-Language"))
+			}
+		})
+	}
+}
+
+func TestErrorCutsUserText(t *testing.T) {
+	defer usertext.Set(usertext.Default)
+	usertext.Set(usertext.Policy{Error: 5})
+
+	rr := httptest.NewRecorder()
+	response.Error(rr, request(true), http.StatusBadRequest, apierror.CodeUnknownDialect, []string{"body/text: " + strings.Repeat("x", 20000)}, "quotable-v2")
+	expected := `{"status":"error","code":"unknown_dialect","error":"Unknown response dialect \"quota…\".","fields":["body/…"]}`
+	if got := rr.Body.String(); got != expected+"\n" {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+}
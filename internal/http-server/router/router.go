@@ -1,35 +1,773 @@
 package router
 
 import (
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/http/pprof"
 	"runtime/debug"
+	"slices"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"quotes-service/internal/config"
+	"quotes-service/internal/http-server/bulk"
+	"quotes-service/internal/http-server/handlers/adminhandler"
+	"quotes-service/internal/http-server/handlers/authorhandler"
+	"quotes-service/internal/http-server/handlers/claimhandler"
+	"quotes-service/internal/http-server/handlers/collectionhandler"
+	"quotes-service/internal/http-server/handlers/exporthandler"
+	"quotes-service/internal/http-server/handlers/favoritehandler"
+	"quotes-service/internal/http-server/handlers/healthhandler"
+	"quotes-service/internal/http-server/handlers/importhandler"
+	"quotes-service/internal/http-server/handlers/mehandler"
 	"quotes-service/internal/http-server/handlers/quotehandler"
+	"quotes-service/internal/http-server/handlers/schemahandler"
+	mwAudit "quotes-service/internal/http-server/middleware/audit"
+	mwAuth "quotes-service/internal/http-server/middleware/auth"
+	mwAuthGuard "quotes-service/internal/http-server/middleware/authguard"
+	mwCORS "quotes-service/internal/http-server/middleware/cors"
+	mwDebugHeaders "quotes-service/internal/http-server/middleware/debugheaders"
+	mwDeprecation "quotes-service/internal/http-server/middleware/deprecation"
+	mwDialect "quotes-service/internal/http-server/middleware/dialect"
+	mwEnvelope "quotes-service/internal/http-server/middleware/envelope"
+	mwHardening "quotes-service/internal/http-server/middleware/hardening"
+	mwJWTAuth "quotes-service/internal/http-server/middleware/jwtauth"
 	mwLogger "quotes-service/internal/http-server/middleware/logger"
+	mwMetrics "quotes-service/internal/http-server/middleware/metrics"
+	mwParamLimit "quotes-service/internal/http-server/middleware/paramlimit"
+	mwPathNorm "quotes-service/internal/http-server/middleware/pathnorm"
+	mwPublicOnly "quotes-service/internal/http-server/middleware/publiconly"
+	mwRateLimit "quotes-service/internal/http-server/middleware/ratelimit"
+	mwRoute "quotes-service/internal/http-server/middleware/route"
+	mwSignature "quotes-service/internal/http-server/middleware/signature"
+	mwValidate "quotes-service/internal/http-server/middleware/validate"
+	"quotes-service/internal/http-server/pagination"
+	"quotes-service/internal/lib/cardinality"
+	"quotes-service/internal/lib/clienthistory"
+	"quotes-service/internal/lib/deprecation"
+	"quotes-service/internal/lib/fairpick"
+	"quotes-service/internal/lib/features"
+	"quotes-service/internal/lib/hardening"
+	"quotes-service/internal/lib/healthsummary"
+	"quotes-service/internal/lib/jsoncache"
+	"quotes-service/internal/lib/jsonschema"
+	"quotes-service/internal/lib/lockout"
+	"quotes-service/internal/lib/logger/logring"
+	"quotes-service/internal/lib/logger/sl"
+	"quotes-service/internal/lib/publicid"
+	"quotes-service/internal/lib/ratelimit"
+	"quotes-service/internal/lib/recentfailures"
+	"quotes-service/internal/lib/role"
+	"quotes-service/internal/lib/slowest"
+	"quotes-service/internal/lib/supportbundle"
+	"quotes-service/internal/lib/textstats"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/selfcheck"
 )
 
-func New(logger *slog.Logger, qs quotehandler.QuoteStore) http.Handler {
+// Storage is everything the HTTP API needs from the storage backend.
+type Storage interface {
+	quotehandler.QuoteStore
+	quotehandler.PublicIDResolver
+	collectionhandler.CollectionStore
+	favoritehandler.FavoriteStore
+	authorhandler.AuthorStore
+}
+
+// Handlers are the HTTP handlers for the service's listeners.
+type Handlers struct {
+	// API serves the quotes API, plus the operational routes when there is
+	// no admin listener and they are not turned off.
+	API http.Handler
+	// Admin serves metrics, pprof, health checks and /admin on the admin
+	// listener. It is nil unless the admin listener is enabled.
+	Admin http.Handler
+}
+
+// Readiness holds what /readyz reports on besides the store itself.
+type Readiness struct {
+	// SelfCheck is the result of the startup storage check, or nil if none
+	// ran.
+	SelfCheck *selfcheck.Result
+	// Certs reports the managed TLS certificates, or is nil without ACME.
+	Certs healthhandler.CertStatus
+}
+
+// Jobs are the background jobs controlled through /admin. A nil field is a
+// job that is not running.
+type Jobs struct {
+	Sync     adminhandler.SyncRunner
+	Schedule adminhandler.ScheduleReporter
+	// Authors supplies the author metadata GET /authors and
+	// /authors/{name} include, or is nil for none.
+	Authors authorhandler.AuthorEnricher
+	// Daily reports the quotes of the day behind /quotes/daily/history,
+	// which exists only when it is set, as it is in the daily schedule mode.
+	Daily  quotehandler.DailyHistory
+	Digest adminhandler.DigestRunner
+	Backup adminhandler.BackupRunner
+	// Replication also exports its metrics when it implements
+	// prometheus.Collector.
+	Replication adminhandler.ReplicationRunner
+	// Panics is sent a report of every recovered handler panic, or is nil.
+	Panics PanicReporter
+	// Exports runs the exports behind the /exports routes, which exist
+	// only when it is set.
+	Exports exporthandler.ExportManager
+	// Imports runs the imports behind the /imports routes, which exist
+	// only when it is set.
+	Imports importhandler.ImportManager
+	// Moderation holds the quotes the content filter held, behind the
+	// /admin/moderation routes, which exist only when it is set.
+	Moderation adminhandler.ModerationQueue
+	// ContentFilter exports the content filter's hits, or is nil.
+	ContentFilter prometheus.Collector
+	// JWKS supplies the keys that check bearer JWTs. It is nil unless JWT
+	// authentication is enabled.
+	JWKS mwJWTAuth.KeySource
+	// APIKeys holds the API keys created at runtime, behind the /admin/keys
+	// routes, which exist only when it is set.
+	APIKeys APIKeyStore
+	// Audit keeps the audit trail of the writes made through the API. It
+	// is nil unless auditing is enabled, and /admin/audit exists only then.
+	Audit storage.AuditStore
+	// Logs keeps the last log lines for /admin/support-bundle, or is nil.
+	Logs *logring.Ring
+}
+
+// APIKeyStore is what both the auth middleware and /admin/keys need of
+// the runtime API keys. *apikeys.Store is the real one.
+type APIKeyStore interface {
+	mwAuth.KeyStore
+	adminhandler.KeyManager
+}
+
+// PanicReporter forwards panic reports to an external sink. Report must not
+// block.
+type PanicReporter interface {
+	Report(report models.PanicReport)
+}
+
+// quoteIDPattern matches a quote's sequential or public ID in a route.
+const quoteIDPattern = "[0-9]+|" + publicid.RoutePattern
+
+// pageParams are the query parameters of the paginated lists.
+var pageParams = map[string]jsonschema.Schema{
+	"limit":  {"type": "integer", "minimum": 1},
+	"offset": {"type": "integer", "minimum": 0},
+}
+
+// apiSpec is what the validation middleware checks requests against. It
+// lists only the operations whose models describe their input exactly;
+// the others are left to their handlers.
+var apiSpec = mwValidate.Spec{
+	"POST /quotes":        {Body: models.AddQuoteRequest{}, Response: models.AddQuoteResponse{}},
+	"GET /quotes":         {Query: pageParams},
+	"GET /quotes/export":  {Query: pageParams},
+	"PUT /quotes/{id}":    {Body: models.UpdateQuoteRequest{}},
+	"PATCH /quotes/{id}":  {Body: models.UpdateQuoteRequest{}},
+	"GET /favorites":      {Query: pageParams},
+	"GET /authors":        {Query: pageParams},
+	"POST /authors/merge": {Body: models.MergeAuthorsRequest{}},
+	"POST /quotes/lookup": {Body: models.LookupQuoteRequest{}},
+}
+
+// New builds the HTTP handlers.
+func New(logger *slog.Logger, cfg *config.Config, st Storage, readiness Readiness, jobs Jobs) Handlers {
 	router := mux.NewRouter()
-	router.Use(func(next http.Handler) http.Handler {
+	// Match on the encoded path so author names may contain slashes.
+	router.UseEncodedPath()
+
+	var history *clienthistory.History
+	if cfg.Random.NoRepeatWindow > 0 {
+		history = clienthistory.New(cfg.Random.NoRepeatWindow, cfg.Random.NoRepeatTTL, cfg.Random.NoRepeatMaxClients)
+	}
+	coalescer := quotehandler.NewRandomCoalescer(cfg.Random.CoalesceInterval, cfg.Random.CoalesceRequests)
+	var fallback *quotehandler.RandomFallback
+	if cfg.Fallback.RandomFromCache {
+		fallback = quotehandler.NewRandomFallback(cfg.Fallback.CacheSize)
+	}
+	commitments := fairpick.NewCommitments(fairpick.DefaultCommitmentTTL, fairpick.DefaultMaxCommitments)
+	flags := features.New(cfg.Features.Flags, cfg.Features.Dynamic)
+	var hardenRoutes func(http.Handler) http.Handler
+	if addHardeningFlags(flags, cfg.Hardening) {
+		hardenOpts := mwHardening.Options{
+			Flags:       flags,
+			Mode:        cfg.Hardening.Response,
+			MetricsPath: cfg.Metrics.Path,
+		}
+		// A dynamic profile is switched through /admin/features, which
+		// must stay reachable whatever the profile turns off.
+		if cfg.Hardening.Dynamic {
+			hardenOpts.Exempt = []string{"/admin/features", "/admin/features/{name}"}
+		}
+		hardenRoutes = mwHardening.New(logger, hardenOpts)
+	}
+	// The routes of a feature that is off are not registered, so they 404
+	// like any unknown path. Those of a dynamic feature always are, and
+	// 404 the same way while it is off.
+	hasRoutes := func(feature string) bool {
+		return flags.Dynamic(feature) || flags.Enabled(feature)
+	}
+	gate := func(feature string, next http.HandlerFunc) http.HandlerFunc {
+		if !flags.Dynamic(feature) {
+			return next
+		}
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !flags.Enabled(feature) {
+				http.NotFound(w, r)
+				return
+			}
+			next(w, r)
+		}
+	}
+	pageSizes := pagination.Sizes{Default: cfg.API.DefaultPageSize, Max: cfg.API.MaxPageSize}
+	bulkLimits := bulk.Limits{BatchSize: cfg.Bulk.BatchSize, Budget: cfg.Bulk.Budget}
+
+	stopwords := cfg.Stats.Stopwords
+	if stopwords == nil {
+		stopwords = textstats.DefaultStopwords
+	}
+	analyzer := textstats.New(stopwords, cfg.Stats.TopWords)
+
+	schemas := schemahandler.NewSchemas()
+
+	var listCache *jsoncache.Cache
+	if cfg.ListCache.MaxBytes > 0 {
+		listCache = jsoncache.New(cfg.ListCache.MaxBytes)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	if c, ok := jobs.Replication.(prometheus.Collector); ok {
+		registry.MustRegister(c)
+	}
+	if jobs.ContentFilter != nil {
+		registry.MustRegister(jobs.ContentFilter)
+	}
+	panics := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "panics_total",
+		Help: "Handler panics recovered by the server.",
+	})
+	registry.MustRegister(panics)
+	recoverer := newRecoverer(logger, panics, jobs.Panics)
+	authFailures := mwAuthGuard.NewFailures()
+	registry.MustRegister(authFailures)
+	guardOpts := mwAuthGuard.Options{Failures: authFailures}
+	if lo := cfg.Auth.Lockout; lo.MaxFailures > 0 {
+		guardOpts.Tracker = lockout.New(lockout.Options{
+			MaxFailures: lo.MaxFailures,
+			Window:      lo.Window,
+			Lockout:     lo.Duration,
+			MaxLockout:  lo.MaxDuration,
+			MaxKeys:     lo.MaxClients,
+		})
+	}
+	authGuard := mwAuthGuard.New(logger, guardOpts)
+	exclusions := mwMetrics.Exclusions{
+		UserAgentPrefixes: cfg.Metrics.ExcludeUserAgents,
+		Paths:             cfg.Metrics.ExcludePaths,
+	}
+
+	loggerOpts := []mwLogger.Option{mwLogger.WithDebugFor(exclusions.Match)}
+	if cfg.Storage.CallBudget > 0 {
+		loggerOpts = append(loggerOpts, mwLogger.WithCallBudget(cfg.Storage.CallBudget))
+	}
+
+	serveOps := cfg.AdminServer.Enabled || cfg.AdminServer.Fallback == config.AdminFallbackMain
+
+	// Outermost, so every middleware below sees the route template.
+	router.Use(mwRoute.New(logger))
+	if cfg.DebugHeaders.Enabled {
+		router.Use(mwDebugHeaders.New(logger, st, cfg.DebugHeaders.InstanceID))
+	}
+	// Next, so that the errors of every middleware below take the
+	// response shape the request asked for.
+	envelope := mwEnvelope.New(logger, !cfg.Response.Bare)
+	router.Use(envelope)
+	// Metrics wrap the logger so that handlers write straight to the
+	// logger's writer and its WriteHeader diagnostics name the handler.
+	var slow *slowest.Window
+	var summary *healthsummary.Window
+	var failures *recentfailures.Ring
+	if cfg.Metrics.Enabled && serveOps {
+		summary = healthsummary.New()
+		failures = recentfailures.New(cfg.SupportBundle.Requests)
+		opts := []mwMetrics.Option{mwMetrics.WithSummary(summary), mwMetrics.WithFailures(failures)}
+		if cfg.Metrics.SlowRequests > 0 {
+			slow = slowest.New(cfg.Metrics.SlowRequests, cfg.Metrics.SlowWindow)
+			opts = append(opts, mwMetrics.WithSlowest(slow))
+		}
+		var metricsOpts []mwMetrics.MetricsOption
+		if cfg.Metrics.TenantLabels {
+			metricsOpts = append(metricsOpts, mwMetrics.WithTenants(cardinality.New(cfg.Metrics.MaxTenants)))
+		}
+		router.Use(mwMetrics.New(logger, mwMetrics.NewMetrics(registry, metricsOpts...), exclusions, opts...))
+	}
+	router.Use(mwLogger.New(logger, loggerOpts...))
+	router.Use(recoverer)
+	// Before authentication, so that a hidden group answers the same to
+	// everyone.
+	if hardenRoutes != nil {
+		router.Use(hardenRoutes)
+	}
+	// Outside every way of authenticating, which report their failures.
+	router.Use(authGuard)
+	// Bearer JWTs are checked before API keys, which then leave the
+	// requests they authenticated alone.
+	var jwtAuth func(http.Handler) http.Handler
+	if jobs.JWKS != nil {
+		jwtAuth = mwJWTAuth.New(logger, jobs.JWKS, mwJWTAuth.Options{
+			Issuer:      cfg.JWT.Issuer,
+			Audience:    cfg.JWT.Audience,
+			ClockSkew:   cfg.JWT.ClockSkew,
+			RoleClaim:   cfg.JWT.RoleClaim,
+			DefaultRole: cfg.JWT.DefaultRole,
+		})
+		router.Use(jwtAuth)
+	}
+	router.Use(mwAuth.New(logger, cfg.Auth.APIKeys, authOptions(jobs)...))
+	// After authentication, so that uses are put down to principals.
+	var deprecations *deprecation.Registry
+	if len(cfg.Deprecations.Entries) > 0 {
+		deprecations = deprecation.New(cfg.Deprecations.Entries, deprecation.Options{
+			LogInterval: cfg.Deprecations.LogInterval,
+			MaxCallers:  cfg.Deprecations.MaxCallers,
+		})
+		router.Use(mwDeprecation.New(logger, deprecations))
+	}
+	if cfg.Signing.Enabled {
+		router.Use(mwSignature.New(logger, mwSignature.Options{
+			Clients:      cfg.Signing.Clients,
+			MaxSkew:      cfg.Signing.MaxSkew,
+			Required:     cfg.Signing.Required,
+			MaxBodyBytes: cfg.Signing.MaxBodyBytes,
+		}))
+	}
+	if cfg.IDs.PublicOnly {
+		router.Use(mwPublicOnly.New(logger))
+	}
+	var quotas mehandler.Quotas
+	if rateLimited(cfg.RateLimit) {
+		rlOpts := rateLimitOptions(cfg)
+		router.Use(mwRateLimit.New(logger, rlOpts))
+		quotas = rlOpts
+	}
+	// Inside the rate limit, so requests turned away by it are not
+	// recorded: they did nothing.
+	if jobs.Audit != nil {
+		router.Use(mwAudit.New(logger, jobs.Audit))
+	}
+	// Inside the signature check, which covers the body as sent, and outside
+	// validation, which knows only the canonical field names.
+	if len(cfg.Dialects.Definitions) > 0 {
+		dialects := make(map[string]mwDialect.Dialect, len(cfg.Dialects.Definitions))
+		for name, renames := range cfg.Dialects.Definitions {
+			dialects[name] = renames
+		}
+		router.Use(mwDialect.New(logger, mwDialect.Options{Dialects: dialects, Principals: cfg.Dialects.Principals}))
+	}
+	router.Use(mwParamLimit.New(logger, mwParamLimit.Limits{Author: cfg.API.MaxAuthorChars, Path: cfg.API.MaxPathChars}))
+	// Innermost, so responses are checked before public_only rewrites them.
+	if cfg.Validation.Enabled {
+		var opts []mwValidate.Option
+		if cfg.Validation.Responses {
+			opts = append(opts, mwValidate.WithResponses())
+		}
+		router.Use(mwValidate.New(logger, mwValidate.Compile(apiSpec), opts...))
+	}
+	// The API routes need a reader to read and a writer for anything else.
+	// The operational routes added to router itself by registerOps are
+	// open, except /admin, which needs an admin.
+	api := router.NewRoute().Subrouter()
+	api.Use(mwAuth.RequireFor(logger, authRoles(cfg), apiRole))
+	api.HandleFunc("/quotes", quotehandler.NewAddQuoteHandler(logger, st)).Methods(http.MethodPost)
+	api.HandleFunc("/quotes", withCacheControl(cfg.CacheControl.List, quotehandler.NewGetQuotesByAuthorHandler(logger, st, pageSizes))).Methods(http.MethodGet).Queries("author", "{author}")
+	api.HandleFunc("/quotes", withCacheControl(cfg.CacheControl.List, quotehandler.NewGetAllQuotesHandler(logger, st, listCache, pageSizes))).Methods(http.MethodGet)
+	api.HandleFunc("/quotes/random", withCacheControl(cfg.CacheControl.Random, quotehandler.NewGetRandomQuoteHandler(logger, st, history, coalescer, fallback, commitments))).Methods(http.MethodGet)
+	api.HandleFunc("/quotes/random/commit", quotehandler.NewCommitRandomQuoteHandler(logger, commitments)).Methods(http.MethodGet)
+	api.HandleFunc("/quotes/random/verify", quotehandler.NewVerifyRandomQuoteHandler(logger, st)).Methods(http.MethodGet)
+	api.HandleFunc("/quotes/popular", quotehandler.NewGetPopularQuotesHandler(logger, st)).Methods(http.MethodGet)
+	if jobs.Daily != nil {
+		api.HandleFunc("/quotes/daily/history", quotehandler.NewGetDailyHistoryHandler(logger, jobs.Daily, st)).Methods(http.MethodGet)
+	}
+	api.HandleFunc("/quotes/export", quotehandler.NewExportQuotesHandler(logger, st, pageSizes)).Methods(http.MethodGet)
+	api.HandleFunc("/quotes/import", quotehandler.NewImportQuotesHandler(logger, st)).Methods(http.MethodPost)
+	if jobs.Exports != nil {
+		api.HandleFunc("/exports", exporthandler.NewCreateExportHandler(logger, jobs.Exports)).Methods(http.MethodPost)
+		api.HandleFunc("/exports/{id:[0-9a-f]+}", exporthandler.NewGetExportHandler(logger, jobs.Exports)).Methods(http.MethodGet)
+		api.HandleFunc("/exports/{id:[0-9a-f]+}/download", exporthandler.NewDownloadExportHandler(logger, jobs.Exports)).Methods(http.MethodGet)
+	}
+	if jobs.Imports != nil {
+		api.HandleFunc("/imports", importhandler.NewCreateImportHandler(logger, jobs.Imports)).Methods(http.MethodPost)
+		api.HandleFunc("/imports/from-url", importhandler.NewCreateImportFromURLHandler(logger, jobs.Imports)).Methods(http.MethodPost)
+		api.HandleFunc("/imports/{id:[0-9a-f]+}", importhandler.NewGetImportHandler(logger, jobs.Imports)).Methods(http.MethodGet)
+		api.HandleFunc("/imports/{id:[0-9a-f]+}", importhandler.NewCancelImportHandler(logger, jobs.Imports)).Methods(http.MethodDelete)
+	}
+	api.HandleFunc("/quotes/digest", quotehandler.NewGetQuotesDigestHandler(logger, st)).Methods(http.MethodGet)
+	api.HandleFunc("/quotes/lookup", quotehandler.NewLookupQuoteHandler(logger, st)).Methods(http.MethodGet, http.MethodPost)
+	if claims, ok := st.(storage.Claimer); ok && cfg.Claims.Enabled {
+		leases := claimhandler.Leases{Default: cfg.Claims.DefaultLease, Max: cfg.Claims.MaxLease}
+		api.HandleFunc("/quotes/claim", claimhandler.NewClaimQuoteHandler(logger, claims, leases)).Methods(http.MethodPost)
+		api.HandleFunc("/quotes/{id:"+quoteIDPattern+"}/release", quotehandler.WithQuoteID(logger, st, "id", claimhandler.NewReleaseQuoteHandler(logger, claims))).Methods(http.MethodPost)
+	}
+	if changes, ok := st.(storage.ChangeLog); ok && cfg.Changes.MaxEntries > 0 {
+		api.HandleFunc("/quotes/changes", quotehandler.NewGetQuoteChangesHandler(logger, changes)).Methods(http.MethodGet)
+	}
+	quoteID := func(next http.HandlerFunc) http.HandlerFunc {
+		return quotehandler.WithQuoteID(logger, st, "id", next)
+	}
+	api.HandleFunc("/quotes/{id:"+quoteIDPattern+"}", withCacheControl(cfg.CacheControl.ByID, quoteID(quotehandler.NewGetQuoteHandler(logger, st)))).Methods(http.MethodGet)
+	api.HandleFunc("/quotes/{id:"+quoteIDPattern+"}", quoteID(quotehandler.NewReplaceQuoteHandler(logger, st))).Methods(http.MethodPut)
+	api.HandleFunc("/quotes/{id:"+quoteIDPattern+"}", quoteID(quotehandler.NewPatchQuoteHandler(logger, st))).Methods(http.MethodPatch)
+	api.HandleFunc("/quotes/{id:"+quoteIDPattern+"}", quoteID(quotehandler.NewDeleteQuoteHandler(logger, st))).Methods(http.MethodDelete)
+	if cfg.Share.Formatter != nil {
+		api.HandleFunc("/quotes/{id:"+quoteIDPattern+"}/share", quoteID(quotehandler.NewShareQuoteHandler(logger, st, cfg.Share.Formatter))).Methods(http.MethodGet)
+	}
+	if hasRoutes(features.Similar) {
+		api.HandleFunc("/quotes/{id:"+quoteIDPattern+"}/similar", gate(features.Similar, quoteID(quotehandler.NewGetSimilarQuotesHandler(logger, st)))).Methods(http.MethodGet)
+	}
+	if hasRoutes(features.Favorites) {
+		api.HandleFunc("/quotes/{id:"+quoteIDPattern+"}/favorite", gate(features.Favorites, quoteID(favoritehandler.NewAddFavoriteHandler(logger, st)))).Methods(http.MethodPut)
+		api.HandleFunc("/quotes/{id:"+quoteIDPattern+"}/favorite", gate(features.Favorites, quoteID(favoritehandler.NewRemoveFavoriteHandler(logger, st)))).Methods(http.MethodDelete)
+		api.HandleFunc("/favorites", gate(features.Favorites, favoritehandler.NewGetFavoritesHandler(logger, st, pageSizes))).Methods(http.MethodGet)
+	}
+
+	if hasRoutes(features.Collections) {
+		api.HandleFunc("/collections", gate(features.Collections, collectionhandler.NewCreateCollectionHandler(logger, st))).Methods(http.MethodPost)
+		api.HandleFunc("/collections", gate(features.Collections, collectionhandler.NewGetCollectionsHandler(logger, st))).Methods(http.MethodGet)
+		api.HandleFunc("/collections/{id:[0-9]+}", gate(features.Collections, collectionhandler.NewGetCollectionHandler(logger, st))).Methods(http.MethodGet)
+		api.HandleFunc("/collections/{id:[0-9]+}", gate(features.Collections, collectionhandler.NewDeleteCollectionHandler(logger, st))).Methods(http.MethodDelete)
+		api.HandleFunc("/collections/{id:[0-9]+}/quotes", gate(features.Collections, collectionhandler.NewAddCollectionQuotesHandler(logger, st))).Methods(http.MethodPost)
+		api.HandleFunc("/collections/{id:[0-9]+}/quotes/{quote_id:"+quoteIDPattern+"}", gate(features.Collections, quotehandler.WithQuoteID(logger, st, "quote_id", collectionhandler.NewRemoveCollectionQuoteHandler(logger, st)))).Methods(http.MethodDelete)
+		api.HandleFunc("/collections/{id:[0-9]+}/random", gate(features.Collections, collectionhandler.NewGetRandomCollectionQuoteHandler(logger, st))).Methods(http.MethodGet)
+	}
+
+	api.HandleFunc("/authors/merge", authorhandler.NewMergeAuthorsHandler(logger, st, bulkLimits)).Methods(http.MethodPost)
+	api.HandleFunc("/authors", authorhandler.NewGetAuthorsHandler(logger, st, pageSizes, jobs.Authors)).Methods(http.MethodGet)
+	api.HandleFunc("/authors/{name}", authorhandler.NewGetAuthorHandler(logger, st, jobs.Authors)).Methods(http.MethodGet)
+	if batcher, ok := st.(storage.Batcher); ok {
+		api.HandleFunc("/authors/{name}/quotes", authorhandler.NewDeleteAuthorQuotesHandler(logger, batcher, bulkLimits)).Methods(http.MethodDelete)
+	}
+	if hasRoutes(features.AuthorFeeds) {
+		api.HandleFunc("/authors/{name}/feed", gate(features.AuthorFeeds, authorhandler.NewGetAuthorFeedHandler(logger, st))).Methods(http.MethodGet)
+	}
+
+	if hasRoutes(features.Schema) {
+		api.HandleFunc("/schema", gate(features.Schema, schemahandler.NewGetSchemaIndexHandler(logger, schemas))).Methods(http.MethodGet)
+		api.HandleFunc("/schema/{model}", gate(features.Schema, schemahandler.NewGetSchemaHandler(logger, schemas))).Methods(http.MethodGet)
+	}
+
+	if hasRoutes(features.TextStats) {
+		api.HandleFunc("/stats/text", gate(features.TextStats, quotehandler.NewGetTextStatsHandler(logger, st, analyzer))).Methods(http.MethodGet)
+	}
+
+	// Open to principals of any role, none included, so every client can
+	// check its own limits.
+	router.HandleFunc("/me", mehandler.NewGetMeHandler(logger, authRoles(cfg), quotas)).Methods(http.MethodGet)
+
+	handlers := Handlers{API: router}
+	switch {
+	case cfg.AdminServer.Enabled:
+		admin := mux.NewRouter()
+		admin.Use(envelope)
+		admin.Use(mwLogger.New(logger, loggerOpts...))
+		admin.Use(recoverer)
+		if hardenRoutes != nil {
+			admin.Use(hardenRoutes)
+		}
+		admin.Use(authGuard)
+		if jwtAuth != nil {
+			admin.Use(jwtAuth)
+		}
+		admin.Use(mwAuth.New(logger, cfg.Auth.APIKeys, authOptions(jobs)...))
+		if deprecations != nil {
+			admin.Use(mwDeprecation.New(logger, deprecations))
+		}
+		if jobs.Audit != nil {
+			admin.Use(mwAudit.New(logger, jobs.Audit))
+		}
+		registerOps(admin, logger, cfg, st, readiness, jobs, registry, slow, summary, failures, flags, deprecations)
+
+		// pprof exposes process internals, so unlike the other operational
+		// routes it never falls back to the main listener.
+		admin.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		admin.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		admin.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		admin.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		admin.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+		handlers.Admin = admin
+	case serveOps:
+		registerOps(router, logger, cfg, st, readiness, jobs, registry, slow, summary, failures, flags, deprecations)
+	}
+
+	// Path normalization wraps the router too, as the router matches
+	// before its middleware runs. It goes inside CORS, so that a redirect
+	// carries the CORS headers a browser needs to follow it.
+	if cfg.Paths.Mode != "" {
+		handlers.API = mwPathNorm.New(logger, cfg.Paths.Mode)(handlers.API)
+	}
+
+	// CORS wraps the router rather than running inside it, as the router
+	// has no route for preflight requests.
+	if cfg.CORS.Enabled {
+		handlers.API = mwCORS.New(logger, mwCORS.Options{
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowedMethods:   cfg.CORS.AllowedMethods,
+			AllowedHeaders:   cfg.CORS.AllowedHeaders,
+			ExposeHeaders:    cfg.CORS.ExposeHeaders,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           cfg.CORS.MaxAge,
+		})(handlers.API)
+	}
+
+	return handlers
+}
+
+// registerOps adds the health, metrics and admin routes to router. slow is
+// nil unless the API's slowest requests are tracked, and summary and
+// failures unless its metrics are.
+func registerOps(router *mux.Router, logger *slog.Logger, cfg *config.Config, st Storage, readiness Readiness, jobs Jobs, registry *prometheus.Registry, slow *slowest.Window, summary *healthsummary.Window, failures *recentfailures.Ring, flags *features.Set, deprecations *deprecation.Registry) {
+	router.HandleFunc("/healthz", healthhandler.NewLivezHandler()).Methods(http.MethodGet)
+	router.HandleFunc("/readyz", healthhandler.NewReadyzHandler(logger, st, readiness.SelfCheck, readiness.Certs)).Methods(http.MethodGet)
+
+	if cfg.Metrics.Enabled {
+		// OpenMetrics is the format that carries the latency exemplars.
+		router.Handle(cfg.Metrics.Path, promhttp.HandlerFor(registry, promhttp.HandlerOpts{EnableOpenMetrics: true})).Methods(http.MethodGet)
+	}
+
+	admin := router.PathPrefix("/admin").Subrouter()
+	admin.Use(mwAuth.Require(logger, authRoles(cfg), role.Admin))
+
+	// The fault endpoints only exist when main wrapped the store in a
+	// fault injector, which it does only if faults are enabled in config.
+	if injector, ok := st.(adminhandler.FaultInjector); ok && cfg.Faults.Enabled {
+		admin.HandleFunc("/faults", adminhandler.NewGetFaultsHandler(logger, injector)).Methods(http.MethodGet)
+		admin.HandleFunc("/faults", adminhandler.NewSetFaultsHandler(logger, injector)).Methods(http.MethodPut)
+	}
+	admin.HandleFunc("/features", adminhandler.NewGetFeaturesHandler(logger, flags)).Methods(http.MethodGet)
+	admin.HandleFunc("/features/{name}", adminhandler.NewSetFeatureHandler(logger, flags)).Methods(http.MethodPut)
+	if deprecations != nil {
+		admin.HandleFunc("/deprecations/usage", adminhandler.NewGetDeprecationUsageHandler(logger, deprecations)).Methods(http.MethodGet)
+	}
+	if slow != nil {
+		admin.HandleFunc("/slow", adminhandler.NewGetSlowRequestsHandler(logger, slow)).Methods(http.MethodGet)
+	}
+	if summary != nil {
+		admin.HandleFunc("/health/summary", adminhandler.NewGetHealthSummaryHandler(logger, summary)).Methods(http.MethodGet)
+	}
+	admin.HandleFunc("/support-bundle", adminhandler.NewGetSupportBundleHandler(logger, &supportbundle.Bundle{
+		Config:   *cfg,
+		Version:  cfg.Version,
+		Store:    st,
+		Logs:     jobs.Logs,
+		Slow:     slow,
+		Failures: failures,
+		Requests: cfg.SupportBundle.Requests,
+	})).Methods(http.MethodGet)
+	if jobs.APIKeys != nil {
+		admin.HandleFunc("/keys", adminhandler.NewGetKeysHandler(logger, jobs.APIKeys)).Methods(http.MethodGet)
+		admin.HandleFunc("/keys", adminhandler.NewCreateKeyHandler(logger, jobs.APIKeys)).Methods(http.MethodPost)
+		admin.HandleFunc("/keys/{id:[0-9a-f]+}", adminhandler.NewRevokeKeyHandler(logger, jobs.APIKeys)).Methods(http.MethodDelete)
+	}
+	sizes := pagination.Sizes{Default: cfg.API.DefaultPageSize, Max: cfg.API.MaxPageSize}
+	if jobs.Audit != nil {
+		admin.HandleFunc("/audit", adminhandler.NewGetAuditHandler(logger, jobs.Audit, sizes)).Methods(http.MethodGet)
+	}
+	admin.HandleFunc("/duplicates", adminhandler.NewGetDuplicatesHandler(logger, st, sizes)).Methods(http.MethodGet)
+	admin.HandleFunc("/duplicates/resolve", adminhandler.NewResolveDuplicatesHandler(logger, st)).Methods(http.MethodPost)
+	if compactor, ok := st.(storage.Compactor); ok {
+		admin.HandleFunc("/storage", adminhandler.NewGetStorageReportHandler(logger, compactor)).Methods(http.MethodGet)
+		admin.HandleFunc("/compact", adminhandler.NewCompactStorageHandler(logger, compactor)).Methods(http.MethodPost)
+	}
+	if batcher, ok := st.(storage.Batcher); ok {
+		admin.HandleFunc("/reindex", adminhandler.NewReindexHandler(logger, batcher, bulk.Limits{BatchSize: cfg.Bulk.BatchSize, Budget: cfg.Bulk.Budget})).Methods(http.MethodPost)
+	}
+	if jobs.Moderation != nil {
+		admin.HandleFunc("/moderation", adminhandler.NewGetHeldQuotesHandler(logger, jobs.Moderation)).Methods(http.MethodGet)
+		admin.HandleFunc("/moderation/{id:[0-9a-f]+}/approve", adminhandler.NewApproveHeldQuoteHandler(logger, jobs.Moderation, st)).Methods(http.MethodPost)
+		admin.HandleFunc("/moderation/{id:[0-9a-f]+}", adminhandler.NewRejectHeldQuoteHandler(logger, jobs.Moderation)).Methods(http.MethodDelete)
+	}
+	if jobs.Sync != nil {
+		admin.HandleFunc("/sync/status", adminhandler.NewGetSyncStatusHandler(logger, jobs.Sync)).Methods(http.MethodGet)
+		admin.HandleFunc("/sync/run", adminhandler.NewRunSyncHandler(logger, jobs.Sync)).Methods(http.MethodPost)
+	}
+	if jobs.Schedule != nil {
+		admin.HandleFunc("/schedule", adminhandler.NewGetScheduleHandler(logger, jobs.Schedule)).Methods(http.MethodGet)
+	}
+	if jobs.Digest != nil {
+		admin.HandleFunc("/digest/status", adminhandler.NewGetDigestStatusHandler(logger, jobs.Digest)).Methods(http.MethodGet)
+		admin.HandleFunc("/digest/send", adminhandler.NewSendDigestHandler(logger, jobs.Digest)).Methods(http.MethodPost)
+	}
+	if jobs.Backup != nil {
+		admin.HandleFunc("/backup/status", adminhandler.NewGetBackupStatusHandler(logger, jobs.Backup)).Methods(http.MethodGet)
+		if cfg.Backup.S3.Bucket != "" {
+			admin.HandleFunc("/backup/upload", adminhandler.NewUploadBackupHandler(logger, jobs.Backup)).Methods(http.MethodPost)
+		}
+	}
+	if jobs.Replication != nil {
+		admin.HandleFunc("/replication/status", adminhandler.NewGetReplicationStatusHandler(logger, jobs.Replication)).Methods(http.MethodGet)
+		admin.HandleFunc("/replication/backfill", adminhandler.NewBackfillReplicationHandler(logger, jobs.Replication)).Methods(http.MethodPost)
+	}
+}
+
+// addHardeningFlags adds a flag to flags for every route group and method,
+// off for those the profile disables, and reports whether the profile can
+// turn anything off, now or at runtime.
+func addHardeningFlags(flags *features.Set, profile config.Hardening) bool {
+	if !profile.Dynamic && len(profile.DisabledGroups) == 0 && len(profile.DisabledMethods) == 0 {
+		return false
+	}
+	for _, group := range hardening.Groups {
+		flags.Add(hardening.GroupFlag(group), !slices.Contains(profile.DisabledGroups, group), profile.Dynamic)
+	}
+	for _, method := range hardening.Methods {
+		flags.Add(hardening.MethodFlag(method), !slices.Contains(profile.DisabledMethods, method), profile.Dynamic)
+	}
+	return true
+}
+
+// rateLimited reports whether any client has a rate limit.
+func rateLimited(rl config.RateLimit) bool {
+	return rl.RequestsPerSecond > 0 || rl.Principal.RequestsPerSecond > 0 || len(rl.Principals) > 0 || len(rl.Roles) > 0
+}
+
+func rateLimitOptions(cfg *config.Config) mwRateLimit.Options {
+	rl := cfg.RateLimit
+	limit := func(rule config.RateLimitRule) ratelimit.Limit {
+		return ratelimit.Limit{Rate: rule.RequestsPerSecond, Burst: rule.Burst}
+	}
+	principal := rl.Principal
+	if principal == (config.RateLimitRule{}) {
+		principal = config.RateLimitRule{RequestsPerSecond: rl.RequestsPerSecond, Burst: rl.Burst}
+	}
+
+	opts := mwRateLimit.Options{
+		IPForPrincipals: rl.IPForPrincipals,
+		Principal:       ratelimit.New(principal.RequestsPerSecond, principal.Burst, rl.MaxClients),
+		Principals:      make(map[string]ratelimit.Limit, len(rl.Principals)),
+		Roles:           make(map[role.Role]ratelimit.Limit, len(rl.Roles)),
+		AuthRoles:       authRoles(cfg),
+	}
+	if rl.RequestsPerSecond > 0 {
+		opts.IP = ratelimit.New(rl.RequestsPerSecond, rl.Burst, rl.MaxClients)
+	}
+	for name, rule := range rl.Principals {
+		opts.Principals[name] = limit(rule)
+	}
+	for r, rule := range rl.Roles {
+		opts.Roles[r] = limit(rule)
+	}
+	return opts
+}
+
+func authOptions(jobs Jobs) []mwAuth.Option {
+	if jobs.APIKeys == nil {
+		return nil
+	}
+	return []mwAuth.Option{mwAuth.WithKeyStore(jobs.APIKeys)}
+}
+
+func authRoles(cfg *config.Config) mwAuth.Roles {
+	return mwAuth.Roles{Principals: cfg.Auth.Roles, Anonymous: cfg.Auth.Anonymous}
+}
+
+// apiRole is the least role an API request needs: reading, as mwRoute.Reads
+// tells, needs a reader and anything else a writer.
+func apiRole(r *http.Request) role.Role {
+	if mwRoute.Reads(r) {
+		return role.Reader
+	}
+	return role.Writer
+}
+
+// withCacheControl sets the Cache-Control header configured for a route
+// class before the handler runs.
+func withCacheControl(value string, next http.HandlerFunc) http.HandlerFunc {
+	if value == "" {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", value)
+		next(w, r)
+	}
+}
+
+// Limits on the request metadata and panic details in a report.
+const (
+	maxReportFieldChars = 256
+	maxReportPanicChars = 1024
+	maxReportStackChars = 16 << 10
+)
+
+// newRecoverer returns middleware that turns a handler panic into a 500. It
+// runs inside the logger middleware so the access log records the status,
+// and it leaves the response alone if the handler had already started
+// writing it. Every panic is logged with a structured report, counted in
+// panics, and handed to reporter unless it is nil. Middleware further out
+// learns of it if its writer, or one it unwraps to, has a Panicked()
+// method.
+func newRecoverer(logger *slog.Logger, panics prometheus.Counter, reporter PanicReporter) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if rvr := recover(); rvr != nil {
-					logger.Error("panic recovered", slog.Any("panic", rvr), slog.String("stack", string(debug.Stack())))
+					report := panicReport(w, r, rvr, debug.Stack())
+					panics.Inc()
+					announcePanic(w)
+					logger.Error("panic recovered",
+						slog.String("request_id", report.RequestID),
+						slog.String("route", report.Route),
+						slog.String("path", report.Path),
+						slog.String("panic", report.Panic),
+						slog.String("stack", report.Stack),
+					)
+					if reporter != nil {
+						reporter.Report(report)
+					}
+					if mwLogger.Written(w) {
+						return
+					}
 					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 				}
 			}()
 			next.ServeHTTP(w, r)
 		})
-	})
+	}
+}
 
-	router.Use(mwLogger.New(logger))
-	router.HandleFunc("/quotes", quotehandler.NewAddQuoteHandler(logger, qs)).Methods(http.MethodPost)
-	router.HandleFunc("/quotes", quotehandler.NewGetQuotesByAuthorHandler(logger, qs)).Methods(http.MethodGet).Queries("author", "{author}")
-	router.HandleFunc("/quotes", quotehandler.NewGetAllQuotesHandler(logger, qs)).Methods(http.MethodGet)
-	router.HandleFunc("/quotes/random", quotehandler.NewGetRandomQuoteHandler(logger, qs)).Methods(http.MethodGet)
-	router.HandleFunc("/quotes/{id:[0-9]+}", quotehandler.NewDeleteQuoteHandler(logger, qs)).Methods(http.MethodDelete)
+// announcePanic tells every writer around w that takes it that the handler
+// panicked.
+func announcePanic(w http.ResponseWriter) {
+	for w != nil {
+		if outer, ok := w.(interface{ Panicked() }); ok {
+			outer.Panicked()
+		}
+		wrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return
+		}
+		w = wrapper.Unwrap()
+	}
+}
 
-	return router
-}
\ No newline at end of file
+func panicReport(w http.ResponseWriter, r *http.Request, rvr any, stack []byte) models.PanicReport {
+	report := models.PanicReport{
+		Time:       time.Now().UTC(),
+		RequestID:  mwLogger.RequestID(w),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Query:      sl.Truncate(r.URL.RawQuery, maxReportFieldChars),
+		RemoteAddr: r.RemoteAddr,
+		UserAgent:  sl.Truncate(r.UserAgent(), maxReportFieldChars),
+		Panic:      sl.Truncate(fmt.Sprint(rvr), maxReportPanicChars),
+		Stack:      sl.Truncate(string(stack), maxReportStackChars),
+		Route:      mwRoute.Template(r),
+	}
+	return report
+}
@@ -0,0 +1,1316 @@
+package router_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"quotes-service/internal/config"
+	"quotes-service/internal/http-server/response"
+	"quotes-service/internal/http-server/router"
+	"quotes-service/internal/jobs/export"
+	"quotes-service/internal/jobs/importer"
+	"quotes-service/internal/lib/apikeys"
+	"quotes-service/internal/lib/hardening"
+	"quotes-service/internal/lib/moderation"
+	"quotes-service/internal/lib/panicreport"
+	"quotes-service/internal/lib/pathnorm"
+	"quotes-service/internal/lib/publicid"
+	"quotes-service/internal/lib/role"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/countstorage"
+	"quotes-service/internal/storage/faultstorage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+// opsPaths are the operational routes that must leave the public listener
+// when the admin listener is enabled.
+var opsPaths = []string{"/healthz", "/readyz", "/metrics", "/admin/faults", "/debug/pprof/"}
+
+func newHandlers(t *testing.T, admin config.AdminServer) router.Handlers {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	cfg := &config.Config{
+		Auth: config.Auth{
+			APIKeys: map[string]string{"ops-key": "ops"},
+			Roles:   map[string]role.Role{"ops": role.Admin},
+		},
+		Faults:      config.Faults{Enabled: true},
+		Metrics:     config.Metrics{Enabled: true, Path: "/metrics"},
+		AdminServer: admin,
+	}
+	return router.New(logger, cfg, faultstorage.New(store), router.Readiness{}, router.Jobs{})
+}
+
+func statusOf(h http.Handler, path string) int {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("X-API-Key", "ops-key")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr.Code
+}
+
+func TestAdminListenerSplit(t *testing.T) {
+	handlers := newHandlers(t, config.AdminServer{Enabled: true, Address: ":9090", Fallback: config.AdminFallbackMain})
+	if handlers.Admin == nil {
+		t.Fatal("expected an admin handler when the admin listener is enabled")
+	}
+
+	for _, path := range opsPaths {
+		if code := statusOf(handlers.API, path); code != http.StatusNotFound {
+			t.Errorf("public GET %s: expected 404, got %d", path, code)
+		}
+		if code := statusOf(handlers.Admin, path); code != http.StatusOK {
+			t.Errorf("admin GET %s: expected 200, got %d", path, code)
+		}
+	}
+
+	if code := statusOf(handlers.API, "/quotes"); code != http.StatusOK {
+		t.Errorf("public GET /quotes: expected 200, got %d", code)
+	}
+	if code := statusOf(handlers.Admin, "/quotes"); code != http.StatusNotFound {
+		t.Errorf("admin GET /quotes: expected 404, got %d", code)
+	}
+}
+
+func TestAdminListenerDisabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		fallback string
+		want     map[string]int
+	}{
+		{
+			name:     "fallback to main",
+			fallback: config.AdminFallbackMain,
+			want: map[string]int{
+				"/healthz":      http.StatusOK,
+				"/readyz":       http.StatusOK,
+				"/metrics":      http.StatusOK,
+				"/admin/faults": http.StatusOK,
+				"/debug/pprof/": http.StatusNotFound,
+			},
+		},
+		{
+			name:     "off",
+			fallback: config.AdminFallbackOff,
+			want: map[string]int{
+				"/healthz":      http.StatusNotFound,
+				"/readyz":       http.StatusNotFound,
+				"/metrics":      http.StatusNotFound,
+				"/admin/faults": http.StatusNotFound,
+				"/debug/pprof/": http.StatusNotFound,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			handlers := newHandlers(t, config.AdminServer{Fallback: tc.fallback})
+			if handlers.Admin != nil {
+				t.Fatal("expected no admin handler when the admin listener is disabled")
+			}
+			for _, path := range opsPaths {
+				if code := statusOf(handlers.API, path); code != tc.want[path] {
+					t.Errorf("GET %s: expected %d, got %d", path, tc.want[path], code)
+				}
+			}
+		})
+	}
+}
+
+func TestPublicOnly(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New(memorystorage.WithPublicIDs(publicid.FormatUUID.New))
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	cfg := &config.Config{IDs: config.IDs{PublicID: publicid.FormatUUID, PublicOnly: true}}
+	handler := router.New(logger, cfg, store, router.Readiness{}, router.Jobs{}).API
+
+	req := httptest.NewRequest(http.MethodPost, "/quotes", strings.NewReader(`{"text":"Hello","author":"World"}`))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var added map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &added); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	publicID, _ := added["public_id"].(string)
+	if _, hasID := added["id"]; hasID || !publicid.Valid(publicID) {
+		t.Fatalf("expected only a public id, got %s", rr.Body.String())
+	}
+
+	for _, path := range []string{"/quotes/" + publicID, "/quotes/1"} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d", path, rr.Code)
+		}
+		if body := rr.Body.String(); strings.Contains(body, `"id"`) || !strings.Contains(body, publicID) {
+			t.Errorf("GET %s: expected only the public id, got %s", path, body)
+		}
+	}
+}
+
+// panickingStore panics on every quote lookup.
+type panickingStore struct {
+	*memorystorage.Storage
+}
+
+func (panickingStore) GetQuote(ctx context.Context, id int64) (models.Quote, error) {
+	panic("storage exploded")
+}
+
+func TestPanicReport(t *testing.T) {
+	events := make(chan []byte, 1)
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		events <- body
+	}))
+	defer sink.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	reporter, err := panicreport.New(logger, panicreport.Options{Sink: panicreport.SinkWebhook, URL: sink.URL})
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reporter.Run(ctx)
+
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	cfg := &config.Config{Metrics: config.Metrics{Enabled: true, Path: "/metrics"}, AdminServer: config.AdminServer{Fallback: config.AdminFallbackMain}}
+	handlers := router.New(logger, cfg, panickingStore{store}, router.Readiness{}, router.Jobs{Panics: reporter})
+
+	rr := httptest.NewRecorder()
+	handlers.API.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/quotes/7?secret=x", nil))
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handlers.API.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rr.Body.String(), "\npanics_total 1\n") {
+		t.Fatalf("expected panics_total 1 in the metrics, got:\n%s", rr.Body.String())
+	}
+
+	var event struct {
+		Type string             `json:"type"`
+		Data models.PanicReport `json:"data"`
+	}
+	select {
+	case body := <-events:
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Fatalf("invalid sink payload %s: %v", body, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the panic report")
+	}
+	report := event.Data
+	if event.Type != panicreport.EventType || report.Panic != "storage exploded" || report.Method != http.MethodGet {
+		t.Fatalf("unexpected report %+v", event)
+	}
+	if report.Path != "/quotes/7" || report.Query != "secret=x" || !strings.HasPrefix(report.Route, "/quotes/{id") {
+		t.Fatalf("unexpected request details %+v", report)
+	}
+	if len(report.RequestID) != 32 || !strings.Contains(report.Stack, "panickingStore.GetQuote") {
+		t.Fatalf("expected a request ID and the panicking frame, got %+v", report)
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	cfg := &config.Config{
+		Auth: config.Auth{
+			APIKeys: map[string]string{"ops-key": "ops"},
+			Roles:   map[string]role.Role{"ops": role.Admin},
+		},
+		AdminServer: config.AdminServer{Fallback: config.AdminFallbackMain},
+		Features: config.Features{
+			Flags:   map[string]bool{"collections": false, "text_stats": false, "schema": true},
+			Dynamic: []string{"text_stats", "schema"},
+		},
+	}
+	api := router.New(logger, cfg, store, router.Readiness{}, router.Jobs{}).API
+
+	setFeature := func(name string, enabled bool) int {
+		t.Helper()
+		body := `{"enabled": false}`
+		if enabled {
+			body = `{"enabled": true}`
+		}
+		req := httptest.NewRequest(http.MethodPut, "/admin/features/"+name, strings.NewReader(body))
+		req.Header.Set("X-API-Key", "ops-key")
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	expectStatuses := func(step string, expected map[string]int) {
+		t.Helper()
+		for path, want := range expected {
+			if code := statusOf(api, path); code != want {
+				t.Errorf("%s: GET %s: expected %d, got %d", step, path, want, code)
+			}
+		}
+	}
+
+	expectStatuses("initial", map[string]int{
+		"/collections":    http.StatusNotFound,
+		"/stats/text":     http.StatusNotFound,
+		"/schema":         http.StatusOK,
+		"/authors":        http.StatusOK,
+		"/quotes":         http.StatusOK,
+		"/admin/features": http.StatusOK,
+		"/authors/x/feed": http.StatusNotFound,
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/features", nil)
+	req.Header.Set("X-API-Key", "ops-key")
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	var resp struct {
+		Data []models.FeatureFlag `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode features: %v", err)
+	}
+	listed := make(map[string]models.FeatureFlag)
+	for _, flag := range resp.Data {
+		listed[flag.Name] = flag
+	}
+	if got := listed["text_stats"]; got.Enabled || !got.Dynamic {
+		t.Errorf("expected text_stats off and dynamic, got %+v", got)
+	}
+	if got := listed["collections"]; got.Enabled || got.Dynamic {
+		t.Errorf("expected collections off and static, got %+v", got)
+	}
+	if got := listed["favorites"]; !got.Enabled || got.Dynamic {
+		t.Errorf("expected favorites on by default, got %+v", got)
+	}
+
+	if code := setFeature("text_stats", true); code != http.StatusOK {
+		t.Fatalf("expected 200 turning text_stats on, got %d", code)
+	}
+	if code := setFeature("schema", false); code != http.StatusOK {
+		t.Fatalf("expected 200 turning schema off, got %d", code)
+	}
+	expectStatuses("after toggling", map[string]int{
+		"/collections":    http.StatusNotFound,
+		"/stats/text":     http.StatusOK,
+		"/schema":         http.StatusNotFound,
+		"/schema/quote":   http.StatusNotFound,
+		"/authors":        http.StatusOK,
+		"/quotes":         http.StatusOK,
+		"/favorites":      http.StatusOK,
+		"/admin/features": http.StatusOK,
+	})
+
+	if code := setFeature("collections", true); code != http.StatusConflict {
+		t.Errorf("expected 409 for a static feature, got %d", code)
+	}
+	if code := setFeature("graphql", true); code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown feature, got %d", code)
+	}
+	expectStatuses("after refused toggles", map[string]int{"/collections": http.StatusNotFound})
+}
+
+// TestRoles checks each role against a read, a write and an admin route,
+// and that the operational routes stay open to everyone.
+func TestRoles(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	cfg := &config.Config{
+		API: config.API{DefaultPageSize: 10, MaxPageSize: 100},
+		Auth: config.Auth{
+			APIKeys: map[string]string{"r-key": "dashboard", "w-key": "app", "a-key": "ops"},
+			Roles:   map[string]role.Role{"dashboard": role.Reader, "ops": role.Admin},
+		},
+		AdminServer: config.AdminServer{Fallback: config.AdminFallbackMain},
+	}
+
+	type request struct{ method, path, body string }
+	read := request{http.MethodGet, "/quotes", ""}
+	write := request{http.MethodPost, "/quotes", `{"text": "Stay hungry.", "author": "Steve Jobs"}`}
+	admin := request{http.MethodGet, "/admin/features", ""}
+	health := request{http.MethodGet, "/healthz", ""}
+
+	tests := []struct {
+		name      string
+		anonymous role.Role
+		apiKey    string
+		expected  map[request]int
+	}{
+		{
+			name:     "anonymous writer by default",
+			expected: map[request]int{read: http.StatusOK, write: http.StatusCreated, admin: http.StatusUnauthorized, health: http.StatusOK},
+		},
+		{
+			name:      "anonymous reader",
+			anonymous: role.Reader,
+			expected:  map[request]int{read: http.StatusOK, write: http.StatusUnauthorized, admin: http.StatusUnauthorized, health: http.StatusOK},
+		},
+		{
+			name:      "anonymous none",
+			anonymous: role.None,
+			expected:  map[request]int{read: http.StatusUnauthorized, write: http.StatusUnauthorized, admin: http.StatusUnauthorized, health: http.StatusOK},
+		},
+		{
+			name:      "reader",
+			anonymous: role.None,
+			apiKey:    "r-key",
+			expected:  map[request]int{read: http.StatusOK, write: http.StatusForbidden, admin: http.StatusForbidden, health: http.StatusOK},
+		},
+		{
+			name:      "writer",
+			anonymous: role.None,
+			apiKey:    "w-key",
+			expected:  map[request]int{read: http.StatusOK, write: http.StatusCreated, admin: http.StatusForbidden, health: http.StatusOK},
+		},
+		{
+			name:      "admin",
+			anonymous: role.None,
+			apiKey:    "a-key",
+			expected:  map[request]int{read: http.StatusOK, write: http.StatusCreated, admin: http.StatusOK, health: http.StatusOK},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg.Auth.Anonymous = tc.anonymous
+			api := router.New(logger, cfg, store, router.Readiness{}, router.Jobs{}).API
+			for req, want := range tc.expected {
+				r := httptest.NewRequest(req.method, req.path, strings.NewReader(req.body))
+				if tc.apiKey != "" {
+					r.Header.Set("X-API-Key", tc.apiKey)
+				}
+				rr := httptest.NewRecorder()
+				api.ServeHTTP(rr, r)
+				if rr.Code != want {
+					t.Errorf("%s %s: expected %d, got %d: %s", req.method, req.path, want, rr.Code, rr.Body.String())
+				}
+				if rr.Code == http.StatusForbidden && !strings.Contains(rr.Body.String(), `"code":"insufficient_role"`) {
+					t.Errorf("%s %s: expected insufficient_role, got %s", req.method, req.path, rr.Body.String())
+				}
+			}
+		})
+	}
+}
+
+// TestLookupIsARead checks that POST /quotes/lookup, which takes a body
+// only for texts too long for a URL, is let through like a GET: to
+// readers, without an audit entry, and unsigned while signatures are
+// required for writes.
+func TestLookupIsARead(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	cfg := &config.Config{
+		API: config.API{DefaultPageSize: 10, MaxPageSize: 100},
+		Auth: config.Auth{
+			APIKeys:   map[string]string{"r-key": "dashboard", "w-key": "app"},
+			Roles:     map[string]role.Role{"dashboard": role.Reader},
+			Anonymous: role.None,
+		},
+		AdminServer: config.AdminServer{Fallback: config.AdminFallbackMain},
+	}
+	const lookup = `{"text": "STAY hungry", "author": "steve jobs"}`
+	do := func(api http.Handler, method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", key)
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		return rr
+	}
+
+	api := router.New(logger, cfg, store, router.Readiness{}, router.Jobs{Audit: store}).API
+	if rr := do(api, http.MethodPost, "/quotes", "w-key", `{"text": "Stay hungry.", "author": "Steve Jobs"}`); rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do(api, http.MethodPost, "/quotes/lookup", "r-key", lookup); rr.Code != http.StatusOK {
+		t.Fatalf("expected a reader's lookup to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	entries, total, err := store.QueryAudit(context.Background(), storage.AuditQuery{})
+	if err != nil {
+		t.Fatalf("failed to read the audit trail: %v", err)
+	}
+	if total != 1 || entries[0].Operation != "POST /quotes" {
+		t.Fatalf("expected only the write audited, got %+v", entries)
+	}
+
+	cfg.Signing = config.Signing{
+		Enabled:      true,
+		Clients:      map[string]string{"acme": "s3cret"},
+		MaxSkew:      time.Minute,
+		Required:     true,
+		MaxBodyBytes: 1 << 20,
+	}
+	api = router.New(logger, cfg, store, router.Readiness{}, router.Jobs{}).API
+	if rr := do(api, http.MethodPost, "/quotes/lookup", "r-key", lookup); rr.Code != http.StatusOK {
+		t.Fatalf("expected an unsigned lookup to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do(api, http.MethodPost, "/quotes", "w-key", `{"text": "Stay foolish.", "author": "Steve Jobs"}`); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected an unsigned write refused, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+type syncStub struct{}
+
+func (syncStub) Status() models.SyncStatus { return models.SyncStatus{} }
+func (syncStub) Trigger()                  {}
+
+type digestStub struct{}
+
+func (digestStub) Status() models.DigestStatus { return models.DigestStatus{} }
+func (digestStub) Trigger()                    {}
+
+type backupStub struct{}
+
+func (backupStub) Status() models.BackupStatus { return models.BackupStatus{} }
+func (backupStub) Trigger()                    {}
+
+type replicationStub struct{}
+
+func (replicationStub) Status() models.ReplicationStatus { return models.ReplicationStatus{} }
+func (replicationStub) Backfill()                        {}
+
+type scheduleStub struct{}
+
+func (scheduleStub) Status(n int) models.ScheduleStatus { return models.ScheduleStatus{} }
+
+type moderationStub struct{}
+
+func (moderationStub) List() []models.HeldQuote { return nil }
+func (moderationStub) Take(id string) (moderation.Entry, error) {
+	return moderation.Entry{}, moderation.ErrNotFound
+}
+func (moderationStub) Restore(entry moderation.Entry) {}
+
+type exportStub struct{}
+
+func (exportStub) Submit(format string, filter storage.QuoteFilter) (models.ExportJob, error) {
+	return models.ExportJob{ID: "abc"}, nil
+}
+func (exportStub) Get(id string) (models.ExportJob, error) {
+	return models.ExportJob{}, export.ErrNotFound
+}
+func (exportStub) Open(ctx context.Context, id string) (models.ExportJob, io.ReadCloser, error) {
+	return models.ExportJob{}, nil, export.ErrNotFound
+}
+
+type importStub struct{}
+
+func (importStub) Submit(req models.ImportRequest, body io.Reader) (models.ImportJob, error) {
+	return models.ImportJob{ID: "abc"}, nil
+}
+func (importStub) Get(id string) (models.ImportJob, error) {
+	return models.ImportJob{}, importer.ErrNotFound
+}
+func (importStub) Cancel(id string) (models.ImportJob, error) {
+	return models.ImportJob{}, importer.ErrNotFound
+}
+
+// routePath fills the variables of a mux path template with values that
+// reach the handler: hex for job IDs, "1" for other IDs.
+func routePath(template string) string {
+	var b strings.Builder
+	for i := 0; i < len(template); i++ {
+		if template[i] != '{' {
+			b.WriteByte(template[i])
+			continue
+		}
+		start, depth := i, 0
+		for ; i < len(template); i++ {
+			if template[i] == '{' {
+				depth++
+			} else if template[i] == '}' {
+				depth--
+				if depth == 0 {
+					break
+				}
+			}
+		}
+		variable := template[start+1 : i]
+		name, pattern, _ := strings.Cut(variable, ":")
+		switch {
+		case name == "model":
+			b.WriteString("Quote")
+		case name == "name":
+			b.WriteString("schema")
+		case pattern == "[0-9a-f]+":
+			b.WriteString("abc")
+		default:
+			b.WriteString("1")
+		}
+	}
+	return b.String()
+}
+
+// TestEnvelope requests every route in both response shapes and checks that
+// each JSON answer takes the shape asked for.
+func TestEnvelope(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	cfg := &config.Config{
+		Auth: config.Auth{
+			APIKeys: map[string]string{"ops-key": "ops"},
+			Roles:   map[string]role.Role{"ops": role.Admin},
+		},
+		API:         config.API{DefaultPageSize: 10, MaxPageSize: 100},
+		Faults:      config.Faults{Enabled: true},
+		Metrics:     config.Metrics{Enabled: true, Path: "/metrics"},
+		AdminServer: config.AdminServer{Fallback: config.AdminFallbackMain},
+	}
+	jobs := router.Jobs{
+		Sync:        syncStub{},
+		Schedule:    scheduleStub{},
+		Digest:      digestStub{},
+		Backup:      backupStub{},
+		Replication: replicationStub{},
+		Exports:     exportStub{},
+		Imports:     importStub{},
+		Moderation:  moderationStub{},
+	}
+	jobs.APIKeys, err = apikeys.Open(logger, filepath.Join(t.TempDir(), "keys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := router.New(logger, cfg, faultstorage.New(store), router.Readiness{}, jobs).API
+
+	type route struct{ method, path string }
+	var routes []route
+	err = api.(*mux.Router).Walk(func(r *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := r.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := r.GetMethods()
+		if err != nil {
+			methods = []string{http.MethodGet}
+		}
+		for _, method := range methods {
+			routes = append(routes, route{method, routePath(template)})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk the routes: %v", err)
+	}
+
+	for _, rt := range routes {
+		for _, query := range []string{"", "?envelope=false"} {
+			if _, err := store.AddQuote(context.Background(), models.Quote{Text: "Stay hungry.", Author: "Steve Jobs"}); err != nil {
+				t.Fatalf("failed to seed: %v", err)
+			}
+			req := httptest.NewRequest(rt.method, rt.path+query, strings.NewReader(`{}`))
+			req.Header.Set("X-API-Key", "ops-key")
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			api.ServeHTTP(rr, req)
+
+			contentType := rr.Header().Get("Content-Type")
+			if rr.Body.Len() == 0 || (contentType != "application/json" && contentType != response.ProblemContentType) {
+				continue
+			}
+			name := rt.method + " " + rt.path + query
+			var body map[string]json.RawMessage
+			isObject := json.Unmarshal(rr.Body.Bytes(), &body) == nil
+
+			if query == "" {
+				if contentType != "application/json" || !isObject || body["status"] == nil {
+					t.Errorf("%s: expected an envelope, got %d %s %s", name, rr.Code, contentType, rr.Body.String())
+				}
+				continue
+			}
+			if rr.Code >= http.StatusBadRequest {
+				var problem models.Problem
+				if contentType != response.ProblemContentType || json.Unmarshal(rr.Body.Bytes(), &problem) != nil || problem.Status != rr.Code {
+					t.Errorf("%s: expected a problem, got %d %s %s", name, rr.Code, contentType, rr.Body.String())
+				}
+				continue
+			}
+			if contentType != "application/json" || (isObject && string(body["status"]) == `"success"`) {
+				t.Errorf("%s: expected a bare payload, got %d %s %s", name, rr.Code, contentType, rr.Body.String())
+			}
+		}
+	}
+}
+
+// TestRuntimeKeys creates a key through /admin/keys, uses it and revokes
+// it, all without rebuilding the router.
+func TestRuntimeKeys(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	keys, err := apikeys.Open(logger, filepath.Join(t.TempDir(), "keys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		API: config.API{DefaultPageSize: 10, MaxPageSize: 100},
+		Auth: config.Auth{
+			APIKeys:   map[string]string{"a-key": "ops"},
+			Roles:     map[string]role.Role{"ops": role.Admin},
+			Anonymous: role.None,
+		},
+		AdminServer: config.AdminServer{Fallback: config.AdminFallbackMain},
+	}
+	api := router.New(logger, cfg, store, router.Readiness{}, router.Jobs{APIKeys: keys}).API
+
+	do := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do(http.MethodPost, "/admin/keys", "a-key", `{"name": "dashboard", "role": "reader"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created struct {
+		Data models.CreatedAPIKey `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil || created.Data.Secret == "" {
+		t.Fatalf("expected the secret in the response, got %s", rr.Body.String())
+	}
+	secret := created.Data.Secret
+
+	if rr := do(http.MethodGet, "/quotes", secret, ""); rr.Code != http.StatusOK {
+		t.Fatalf("expected the new key to read, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do(http.MethodPost, "/quotes", secret, `{"text": "Stay hungry.", "author": "Steve Jobs"}`); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected the reader key to be refused a write, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPost, "/admin/keys", "a-key", `{"name": "x", "role": "root"}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown role, got %d", rr.Code)
+	}
+
+	rr = do(http.MethodGet, "/admin/keys", "a-key", "")
+	if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), secret) || !strings.Contains(rr.Body.String(), `"last_used_at"`) {
+		t.Fatalf("expected the key with its last use and without its secret, got %s", rr.Body.String())
+	}
+	if rr := do(http.MethodGet, "/admin/keys", secret, ""); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected a reader to be kept out of /admin/keys, got %d", rr.Code)
+	}
+
+	if rr := do(http.MethodDelete, "/admin/keys/"+created.Data.ID, "a-key", ""); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 revoking, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do(http.MethodGet, "/quotes", secret, ""); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the revoked key to be refused, got %d", rr.Code)
+	}
+	if rr := do(http.MethodDelete, "/admin/keys/"+created.Data.ID, "a-key", ""); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 revoking twice, got %d", rr.Code)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	cfg := &config.Config{
+		RateLimit: config.RateLimit{RequestsPerSecond: 10, Burst: 10, MaxClients: 100},
+		CORS: config.CORS{
+			Enabled:        true,
+			AllowedOrigins: []string{"https://app.example.com"},
+			AllowedMethods: []string{"GET", "POST"},
+			AllowedHeaders: []string{"Content-Type", "X-API-Key"},
+			ExposeHeaders:  []string{"X-Build-Version"},
+		},
+	}
+	api := router.New(logger, cfg, store, router.Readiness{}, router.Jobs{}).API
+
+	req := httptest.NewRequest(http.MethodGet, "/quotes", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Fatalf("expected the origin to be allowed, got %q", got)
+	}
+	exposed := strings.Split(rr.Header().Get("Access-Control-Expose-Headers"), ", ")
+	for _, name := range []string{"X-Request-Id", "X-Ratelimit-Limit", "X-Ratelimit-Remaining", "X-Ratelimit-Reset", "X-Total-Count", "Link", "X-Build-Version"} {
+		found := false
+		for _, got := range exposed {
+			found = found || got == name
+		}
+		if !found {
+			t.Errorf("expected %s to be exposed, got %v", name, exposed)
+		}
+	}
+
+	// The router has no OPTIONS routes, so the preflight must be answered
+	// before it.
+	req = httptest.NewRequest(http.MethodOptions, "/quotes", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("preflight: expected 204, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Headers"); got != "Content-Type, X-API-Key" {
+		t.Fatalf("preflight: expected the allowed headers, got %q", got)
+	}
+}
+
+func TestPaths(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		mode         pathnorm.Mode
+		method       string
+		target       string
+		wantCode     int
+		wantLocation string
+	}{
+		{mode: pathnorm.ModeRedirect, method: http.MethodGet, target: "/quotes/?limit=1", wantCode: http.StatusMovedPermanently, wantLocation: "/quotes?limit=1"},
+		{mode: pathnorm.ModeRedirect, method: http.MethodGet, target: "//quotes", wantCode: http.StatusMovedPermanently, wantLocation: "/quotes"},
+		{mode: pathnorm.ModeRedirect, method: http.MethodPost, target: "/quotes/", wantCode: http.StatusPermanentRedirect, wantLocation: "/quotes"},
+		{mode: pathnorm.ModeRewrite, method: http.MethodGet, target: "/quotes/?limit=1", wantCode: http.StatusOK},
+		{mode: pathnorm.ModeRewrite, method: http.MethodGet, target: "//quotes", wantCode: http.StatusOK},
+		{mode: pathnorm.ModeRewrite, method: http.MethodPost, target: "/quotes/", wantCode: http.StatusCreated},
+	}
+
+	for _, tc := range tests {
+		t.Run(string(tc.mode)+" "+tc.method+" "+tc.target, func(t *testing.T) {
+			store, err := memorystorage.New()
+			if err != nil {
+				t.Fatalf("failed to init storage: %v", err)
+			}
+			cfg := &config.Config{Paths: config.Paths{Mode: tc.mode}}
+			api := router.New(logger, cfg, store, router.Readiness{}, router.Jobs{}).API
+
+			var body io.Reader
+			if tc.method == http.MethodPost {
+				body = strings.NewReader(`{"text":"Stay hungry.","author":"Steve Jobs"}`)
+			}
+			req := httptest.NewRequest(tc.method, tc.target, body)
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			api.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d with %s", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			if got := rr.Header().Get("Location"); tc.wantLocation != "" && got != tc.wantLocation {
+				t.Errorf("expected Location %q, got %q", tc.wantLocation, got)
+			}
+		})
+	}
+}
+
+func TestMe(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	cfg := &config.Config{
+		Auth: config.Auth{
+			APIKeys: map[string]string{"app-key": "app", "off-key": "disabled"},
+			Roles:   map[string]role.Role{"disabled": role.None},
+		},
+		RateLimit: config.RateLimit{RequestsPerSecond: 1, Burst: 5, MaxClients: 100},
+	}
+	api := router.New(logger, cfg, store, router.Readiness{}, router.Jobs{}).API
+
+	get := func(key string) (int, models.Me) {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		var resp struct {
+			Data models.Me `json:"data"`
+		}
+		_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+		return rr.Code, resp.Data
+	}
+
+	if code, _ := get(""); code != http.StatusUnauthorized {
+		t.Fatalf("anonymous: expected 401, got %d", code)
+	}
+	for i := 0; i < 2; i++ {
+		code, me := get("app-key")
+		if code != http.StatusOK || me.Principal != "app" || me.Role != "writer" || me.AuthMethod != "api_key" {
+			t.Fatalf("request %d: unexpected %d %+v", i, code, me)
+		}
+		// The bucket is read after this request took its token.
+		if len(me.RateLimits) != 1 || me.RateLimits[0].Scope != "principal" || me.RateLimits[0].Burst != 5 || me.RateLimits[0].Remaining != 4-i {
+			t.Fatalf("request %d: unexpected rate limits %+v", i, me.RateLimits)
+		}
+	}
+	if code, me := get("off-key"); code != http.StatusOK || me.Role != "none" {
+		t.Fatalf("expected a principal without a role to see itself, got %d %+v", code, me)
+	}
+}
+
+func TestAuditTrail(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	cfg := &config.Config{
+		API: config.API{DefaultPageSize: 10, MaxPageSize: 100},
+		Auth: config.Auth{
+			APIKeys:   map[string]string{"w-key": "importer", "a-key": "ops"},
+			Roles:     map[string]role.Role{"ops": role.Admin},
+			Anonymous: role.Reader,
+		},
+		AdminServer: config.AdminServer{Fallback: config.AdminFallbackMain},
+	}
+	api := router.New(logger, cfg, store, router.Readiness{}, router.Jobs{Audit: store}).API
+
+	do := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := do(http.MethodPost, "/quotes", "w-key", `{"text": "Stay hungry.", "author": "Steve Jobs"}`); rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do(http.MethodGet, "/quotes", "", ""); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if rr := do(http.MethodDelete, "/quotes/1", "", ""); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected an anonymous delete refused, got %d", rr.Code)
+	}
+
+	rr := do(http.MethodGet, "/admin/audit?quote_id=1", "a-key", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Data models.AuditPage `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	entries := resp.Data.Entries
+	if len(entries) != 2 || resp.Data.Total != 2 {
+		t.Fatalf("expected the write and the refused delete, got %+v", entries)
+	}
+	if e := entries[0]; e.Principal != "importer" || e.Operation != "POST /quotes" || e.Status != http.StatusCreated || e.RequestID == "" {
+		t.Fatalf("unexpected entry for the write: %+v", e)
+	}
+	if e := entries[1]; e.Principal != "" || e.Operation != "DELETE /quotes/{id}" || e.Status != http.StatusUnauthorized {
+		t.Fatalf("unexpected entry for the refused delete: %+v", e)
+	}
+	if rr := do(http.MethodGet, "/admin/audit", "w-key", ""); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected a writer to be kept out of /admin/audit, got %d", rr.Code)
+	}
+}
+
+func TestHealthSummary(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	faulty := faultstorage.New(panickingStore{store})
+	if err := faulty.SetFaults(faultstorage.Faults{ErrorRate: 1, Methods: []string{"GetAllQuotes"}}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		Auth: config.Auth{
+			APIKeys: map[string]string{"ops-key": "ops"},
+			Roles:   map[string]role.Role{"ops": role.Admin},
+		},
+		API:         config.API{DefaultPageSize: 10, MaxPageSize: 100},
+		Metrics:     config.Metrics{Enabled: true, Path: "/metrics"},
+		AdminServer: config.AdminServer{Fallback: config.AdminFallbackMain},
+	}
+	api := router.New(logger, cfg, faulty, router.Readiness{}, router.Jobs{}).API
+
+	for _, path := range []string{"/quotes", "/quotes/7", "/quotes?limit=x"} {
+		api.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/health/summary", nil)
+	req.Header.Set("X-API-Key", "ops-key")
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Data models.HealthSummary `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Data.Windows) != 3 {
+		t.Fatalf("expected three windows, got %+v", resp.Data)
+	}
+	got := resp.Data.Windows[0]
+	if got.Requests != 3 || got.StorageErrors != 1 || got.Panics != 1 || got.Statuses["5xx"] != 2 || got.Statuses["4xx"] != 1 {
+		t.Fatalf("expected a storage error, a panic and a 400, got %+v", got)
+	}
+}
+
+// hardeningProbe is a request to a route of each kind a hardening profile
+// can turn off.
+type hardeningProbe struct {
+	method, path, body string
+}
+
+var hardeningProbes = []hardeningProbe{
+	{http.MethodGet, "/quotes", ""},
+	{http.MethodPost, "/quotes", `{"text": "Less is more.", "author": "Mies"}`},
+	{http.MethodGet, "/quotes/1", ""},
+	{http.MethodPatch, "/quotes/1", `{"weight": 2}`},
+	{http.MethodDelete, "/quotes/1", ""},
+	{http.MethodGet, "/authors", ""},
+	{http.MethodGet, "/collections", ""},
+	{http.MethodPost, "/collections", `{"name": "Favourites"}`},
+	{http.MethodGet, "/healthz", ""},
+	{http.MethodGet, "/metrics", ""},
+	{http.MethodGet, "/admin/features", ""},
+	{http.MethodPost, "/admin/duplicates/resolve", `{"strategy": "keep_oldest"}`},
+}
+
+// serveProbe answers probe from a router with profile over a store holding
+// quote 1.
+func serveProbe(t *testing.T, profile config.Hardening, probe hardeningProbe) int {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	if _, err := store.AddQuote(context.Background(), models.Quote{Text: "Form follows function.", Author: "Sullivan"}); err != nil {
+		t.Fatalf("failed to add quote: %v", err)
+	}
+	cfg := &config.Config{
+		Auth: config.Auth{
+			APIKeys: map[string]string{"ops-key": "ops"},
+			Roles:   map[string]role.Role{"ops": role.Admin},
+		},
+		API:         config.API{DefaultPageSize: 10, MaxPageSize: 100},
+		Metrics:     config.Metrics{Enabled: true, Path: "/metrics"},
+		AdminServer: config.AdminServer{Fallback: config.AdminFallbackMain},
+		Hardening:   profile,
+	}
+	api := router.New(logger, cfg, store, router.Readiness{}, router.Jobs{}).API
+
+	req := httptest.NewRequest(probe.method, probe.path, strings.NewReader(probe.body))
+	req.Header.Set("X-API-Key", "ops-key")
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	return rr.Code
+}
+
+// TestHardening runs every probe under several profiles and checks that
+// exactly the probes a profile turns off change their answer.
+func TestHardening(t *testing.T) {
+	baseline := make(map[hardeningProbe]int, len(hardeningProbes))
+	for _, probe := range hardeningProbes {
+		code := serveProbe(t, config.Hardening{}, probe)
+		if code == http.StatusNotFound || code == http.StatusMethodNotAllowed {
+			t.Fatalf("%s %s: expected the route to exist without a profile, got %d", probe.method, probe.path, code)
+		}
+		baseline[probe] = code
+	}
+
+	tests := []struct {
+		name    string
+		profile config.Hardening
+		// expected are the probes turned off, with their answer.
+		expected map[string]int
+	}{
+		{name: "empty dynamic profile", profile: config.Hardening{Response: hardening.ModeAuto, Dynamic: true}},
+		{
+			name:     "no deletes",
+			profile:  config.Hardening{DisabledMethods: []string{http.MethodDelete}, Response: hardening.ModeAuto},
+			expected: map[string]int{"DELETE /quotes/1": http.StatusMethodNotAllowed},
+		},
+		{
+			name:    "no admin",
+			profile: config.Hardening{DisabledGroups: []string{"admin"}, Response: hardening.ModeAuto},
+			expected: map[string]int{
+				"GET /admin/features":            http.StatusNotFound,
+				"POST /admin/duplicates/resolve": http.StatusNotFound,
+			},
+		},
+		{
+			name: "read only, hidden",
+			profile: config.Hardening{
+				DisabledMethods: []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+				Response:        hardening.ModeNotFound,
+			},
+			expected: map[string]int{
+				"POST /quotes":                   http.StatusNotFound,
+				"PATCH /quotes/1":                http.StatusNotFound,
+				"DELETE /quotes/1":               http.StatusNotFound,
+				"POST /collections":              http.StatusNotFound,
+				"POST /admin/duplicates/resolve": http.StatusNotFound,
+			},
+		},
+		{
+			name:    "groups refused",
+			profile: config.Hardening{DisabledGroups: []string{"collections", "metrics"}, Response: hardening.ModeMethodNotAllowed},
+			expected: map[string]int{
+				"GET /collections":  http.StatusMethodNotAllowed,
+				"POST /collections": http.StatusMethodNotAllowed,
+				"GET /metrics":      http.StatusMethodNotAllowed,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, probe := range hardeningProbes {
+				want, off := tc.expected[probe.method+" "+probe.path]
+				if !off {
+					want = baseline[probe]
+				}
+				if code := serveProbe(t, tc.profile, probe); code != want {
+					t.Errorf("%s %s: expected %d, got %d", probe.method, probe.path, want, code)
+				}
+			}
+		})
+	}
+}
+
+// TestHardeningDynamic checks that a dynamic profile can be switched
+// through /admin/features while the service runs.
+func TestHardeningDynamic(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for range 2 {
+		if _, err := store.AddQuote(context.Background(), models.Quote{Text: "Form follows function.", Author: "Sullivan"}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+	cfg := &config.Config{
+		Auth: config.Auth{
+			APIKeys: map[string]string{"ops-key": "ops"},
+			Roles:   map[string]role.Role{"ops": role.Admin},
+		},
+		API:         config.API{DefaultPageSize: 10, MaxPageSize: 100},
+		AdminServer: config.AdminServer{Fallback: config.AdminFallbackMain},
+		Hardening: config.Hardening{
+			DisabledMethods: []string{http.MethodDelete},
+			Response:        hardening.ModeAuto,
+			Dynamic:         true,
+		},
+	}
+	api := router.New(logger, cfg, store, router.Readiness{}, router.Jobs{}).API
+	serve := func(method, path, body string) int {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-API-Key", "ops-key")
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	for _, step := range []struct {
+		method, path, body string
+		expected           int
+	}{
+		{http.MethodDelete, "/quotes/1", "", http.StatusMethodNotAllowed},
+		{http.MethodPut, "/admin/features/" + hardening.MethodFlag(http.MethodDelete), `{"enabled": true}`, http.StatusOK},
+		{http.MethodDelete, "/quotes/1", "", http.StatusOK},
+		{http.MethodPut, "/admin/features/" + hardening.GroupFlag("quotes"), `{"enabled": false}`, http.StatusOK},
+		{http.MethodGet, "/quotes/2", "", http.StatusNotFound},
+		{http.MethodGet, "/authors", "", http.StatusOK},
+		// Turning off what /admin/features needs leaves it reachable, so
+		// the switch can be undone.
+		{http.MethodPut, "/admin/features/" + hardening.GroupFlag("admin"), `{"enabled": false}`, http.StatusOK},
+		{http.MethodGet, "/admin/duplicates", "", http.StatusNotFound},
+		{http.MethodGet, "/admin/features", "", http.StatusOK},
+		{http.MethodPut, "/admin/features/" + hardening.MethodFlag(http.MethodPut), `{"enabled": false}`, http.StatusOK},
+		{http.MethodPut, "/admin/features/" + hardening.GroupFlag("admin"), `{"enabled": true}`, http.StatusOK},
+		{http.MethodGet, "/admin/duplicates", "", http.StatusOK},
+		{http.MethodPut, "/admin/features/" + hardening.MethodFlag(http.MethodPut), `{"enabled": true}`, http.StatusOK},
+	} {
+		if code := serve(step.method, step.path, step.body); code != step.expected {
+			t.Fatalf("%s %s: expected %d, got %d", step.method, step.path, step.expected, code)
+		}
+	}
+}
+
+// TestStorageCalls checks how many storage calls known handlers make, so
+// that one that starts looking items up one by one fails here first.
+// dailyHistory lists its picks, the latest first.
+type dailyHistory []models.DailyPick
+
+func (d dailyHistory) DailyHistory(n int) []models.DailyPick {
+	return slices.Clone(d[:min(n, len(d))])
+}
+
+func TestStorageCalls(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	ctx := context.Background()
+	var ids []int64
+	for i := range 20 {
+		id, err := store.AddQuote(ctx, models.Quote{Text: fmt.Sprintf("Quote number %d.", i), Author: "Anonymous"})
+		if err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	collection, err := store.CreateCollection(ctx, "All", "")
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+	if err := store.AddQuotesToCollection(ctx, collection.ID, ids); err != nil {
+		t.Fatalf("failed to fill collection: %v", err)
+	}
+	cfg := &config.Config{
+		API:         config.API{DefaultPageSize: 10, MaxPageSize: 100},
+		Metrics:     config.Metrics{Enabled: true, Path: "/metrics"},
+		AdminServer: config.AdminServer{Fallback: config.AdminFallbackMain},
+	}
+	var daily dailyHistory
+	for i, id := range ids {
+		daily = append(daily, models.DailyPick{Date: fmt.Sprintf("2024-03-%02d", i+1), QuoteID: id, FeaturedCount: 1})
+	}
+	api := router.New(logger, cfg, countstorage.New(store), router.Readiness{}, router.Jobs{Daily: daily}).API
+
+	tests := []struct {
+		name     string
+		path     string
+		maxCalls int64
+	}{
+		{name: "list", path: "/quotes?limit=20", maxCalls: 1},
+		// The served counter is bumped in the background, before the
+		// request completes or after.
+		{name: "quote", path: "/quotes/1", maxCalls: 2},
+		{name: "collection with quotes", path: fmt.Sprintf("/collections/%d", collection.ID), maxCalls: 2},
+		{name: "authors", path: "/authors", maxCalls: 1},
+		{name: "daily history", path: "/quotes/daily/history", maxCalls: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logs.Reset()
+			rr := httptest.NewRecorder()
+			api.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+
+			var calls int64 = -1
+			for _, line := range bytes.Split(logs.Bytes(), []byte("\n")) {
+				var entry struct {
+					Msg          string `json:"msg"`
+					StorageCalls int64  `json:"storage_calls"`
+				}
+				if json.Unmarshal(line, &entry) == nil && entry.Msg == "request completed" {
+					calls = entry.StorageCalls
+				}
+			}
+			if calls < 1 || calls > tc.maxCalls {
+				t.Fatalf("expected 1 to %d storage calls, got %d", tc.maxCalls, calls)
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if want := `http_request_storage_calls_sum{method="GET",route="/quotes"} 1`; !strings.Contains(rr.Body.String(), want) {
+		t.Fatalf("expected %s in the metrics", want)
+	}
+}
+
+func TestDebugHeaders(t *testing.T) {
+	debugHeaders := []string{"X-Backend", "X-Data-Version", "X-Instance"}
+	tests := []struct {
+		name     string
+		debug    config.DebugHeaders
+		expected bool
+	}{
+		{name: "off by default"},
+		{name: "enabled", debug: config.DebugHeaders{Enabled: true, InstanceID: "api-7"}, expected: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			store, err := memorystorage.New()
+			if err != nil {
+				t.Fatalf("failed to init storage: %v", err)
+			}
+			cfg := &config.Config{
+				API:          config.API{DefaultPageSize: 10, MaxPageSize: 100},
+				DebugHeaders: tc.debug,
+			}
+			api := router.New(logger, cfg, store, router.Readiness{}, router.Jobs{}).API
+
+			for _, path := range []string{"/quotes", "/quotes/404"} {
+				rr := httptest.NewRecorder()
+				api.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+				for _, name := range debugHeaders {
+					if present := rr.Header().Get(name) != ""; present != tc.expected {
+						t.Errorf("GET %s: expected %s present %v, got %q", path, name, tc.expected, rr.Header().Get(name))
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,410 @@
+// Package backup takes periodic snapshots of the quotes, keeps them on
+// local disk and uploads them to an S3-compatible bucket, pruning old ones
+// in both places.
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"quotes-service/internal/lib/s3"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/snapshot"
+)
+
+// Triggers recorded in the status.
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+const (
+	uploadAttempts = 3
+	defaultBackoff = 2 * time.Second
+	// uploadTimeout bounds one attempt, including pruning.
+	uploadTimeout = 5 * time.Minute
+)
+
+type Store interface {
+	GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error)
+}
+
+// ObjectStore is the part of an S3 client the uploader needs. *s3.Client
+// is the real one.
+type ObjectStore interface {
+	PutObject(ctx context.Context, bucket, key string, body []byte, contentType string) error
+	ListObjects(ctx context.Context, bucket, prefix string) ([]s3.Object, error)
+	DeleteObject(ctx context.Context, bucket, key string) error
+}
+
+// Options configures a Backup.
+type Options struct {
+	Interval time.Duration
+	// Dir keeps snapshots on local disk. Empty skips writing them.
+	Dir string
+	// Bucket and Prefix locate the uploaded snapshots. Prefix is put in
+	// front of the file name as is, so it usually ends with a slash.
+	Bucket string
+	Prefix string
+	// KeepLast and MaxAge prune old snapshots after each new one, locally
+	// and in the bucket. Zero disables a rule; with both set a snapshot
+	// must pass both to be kept.
+	KeepLast int
+	MaxAge   time.Duration
+}
+
+type pendingUpload struct {
+	trigger   string
+	createdAt time.Time
+	name      string
+	data      []byte
+}
+
+type Backup struct {
+	log     *slog.Logger
+	store   Store
+	objects ObjectStore
+	opts    Options
+	now     func() time.Time
+	backoff time.Duration
+	trigger chan struct{}
+	uploads chan pendingUpload
+
+	mu           sync.Mutex
+	uploading    bool
+	lastSnapshot *models.BackupSnapshot
+	lastUpload   *models.BackupUpload
+}
+
+type Option func(*Backup)
+
+// WithClock overrides the time source, mainly for tests.
+func WithClock(now func() time.Time) Option {
+	return func(b *Backup) {
+		b.now = now
+	}
+}
+
+// WithBackoff sets the delay before the first upload retry; it doubles
+// for each later one.
+func WithBackoff(d time.Duration) Option {
+	return func(b *Backup) {
+		b.backoff = d
+	}
+}
+
+// New returns a Backup. A nil objects disables uploads.
+func New(log *slog.Logger, store Store, objects ObjectStore, opts Options, options ...Option) *Backup {
+	b := &Backup{
+		log:     log.With(slog.String("op", "backup.Backup")),
+		store:   store,
+		objects: objects,
+		opts:    opts,
+		now:     time.Now,
+		backoff: defaultBackoff,
+		trigger: make(chan struct{}, 1),
+		uploads: make(chan pendingUpload, 1),
+	}
+	for _, opt := range options {
+		opt(b)
+	}
+	return b
+}
+
+// Run takes a snapshot every Interval and whenever Trigger is called,
+// until ctx is done. Uploads run on their own goroutine so a slow bucket
+// never delays a snapshot; if one is still pending when the next snapshot
+// is ready, only the newer one is uploaded.
+func (b *Backup) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	if b.objects != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.uploadLoop(ctx)
+		}()
+	}
+	defer wg.Wait()
+
+	ticker := time.NewTicker(b.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.Snapshot(ctx, TriggerSchedule)
+		case <-b.trigger:
+			b.Snapshot(ctx, TriggerManual)
+		}
+	}
+}
+
+// Trigger asks Run for a snapshot and upload as soon as the current one,
+// if any, is done. Triggers made while one is already pending are merged
+// into it.
+func (b *Backup) Trigger() {
+	select {
+	case b.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Status returns the configuration with the last snapshot and upload.
+func (b *Backup) Status() models.BackupStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := models.BackupStatus{
+		Interval:  b.opts.Interval.String(),
+		Dir:       b.opts.Dir,
+		Bucket:    b.opts.Bucket,
+		Prefix:    b.opts.Prefix,
+		Uploading: b.uploading,
+	}
+	if b.lastSnapshot != nil {
+		last := *b.lastSnapshot
+		status.LastSnapshot = &last
+	}
+	if b.lastUpload != nil {
+		last := *b.lastUpload
+		status.LastUpload = &last
+	}
+	return status
+}
+
+// Snapshot encodes the quotes, writes them to Dir and queues the upload.
+// Failures are recorded in the status rather than returned.
+func (b *Backup) Snapshot(ctx context.Context, trigger string) (report models.BackupSnapshot) {
+	createdAt := b.now().UTC()
+	report = models.BackupSnapshot{Trigger: trigger, CreatedAt: createdAt}
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			report.Error = fmt.Sprintf("panic: %v", rvr)
+		}
+		b.mu.Lock()
+		b.lastSnapshot = &report
+		b.mu.Unlock()
+		if report.Error != "" {
+			b.log.WarnContext(ctx, "backup snapshot failed", slog.String("trigger", trigger), slog.String("error", report.Error))
+			return
+		}
+		b.log.InfoContext(ctx, "backup snapshot taken", slog.String("trigger", trigger), slog.Int("quotes", report.Quotes), slog.Int("bytes", report.Bytes))
+	}()
+
+	quotes, err := b.store.GetAllQuotes(ctx, storage.QuoteFilter{})
+	if err != nil {
+		report.Error = fmt.Sprintf("load quotes: %v", err)
+		return report
+	}
+	data, err := snapshot.Encode(createdAt, quotes)
+	if err != nil {
+		report.Error = fmt.Sprintf("encode: %v", err)
+		return report
+	}
+	name := snapshot.Name(createdAt)
+	report.Quotes = len(quotes)
+	report.Bytes = len(data)
+
+	if b.opts.Dir != "" {
+		path := filepath.Join(b.opts.Dir, name)
+		if err := os.MkdirAll(b.opts.Dir, 0o750); err != nil {
+			report.Error = fmt.Sprintf("create %s: %v", b.opts.Dir, err)
+			return report
+		}
+		if err := writeFile(path, data); err != nil {
+			report.Error = fmt.Sprintf("write %s: %v", path, err)
+			return report
+		}
+		report.File = path
+		if err := b.pruneDir(createdAt); err != nil {
+			b.log.WarnContext(ctx, "failed to prune local backups", slog.String("error", err.Error()))
+		}
+	}
+
+	if b.objects != nil {
+		snap := pendingUpload{trigger: trigger, createdAt: createdAt, name: name, data: data}
+		// Replace a pending upload that has not started yet.
+		select {
+		case <-b.uploads:
+		default:
+		}
+		b.uploads <- snap
+	}
+	return report
+}
+
+func (b *Backup) uploadLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case snap := <-b.uploads:
+			b.upload(ctx, snap)
+		}
+	}
+}
+
+// upload puts snap in the bucket, retrying with backoff, then prunes.
+func (b *Backup) upload(ctx context.Context, snap pendingUpload) {
+	b.mu.Lock()
+	b.uploading = true
+	b.mu.Unlock()
+
+	report := models.BackupUpload{
+		Trigger:   snap.trigger,
+		StartedAt: b.now().UTC(),
+		Key:       b.opts.Prefix + snap.name,
+	}
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			report.Error = fmt.Sprintf("panic: %v", rvr)
+		}
+		report.FinishedAt = b.now().UTC()
+		b.mu.Lock()
+		b.uploading = false
+		b.lastUpload = &report
+		b.mu.Unlock()
+
+		attrs := []slog.Attr{
+			slog.String("trigger", report.Trigger),
+			slog.String("key", report.Key),
+			slog.Int("attempts", report.Attempts),
+			slog.Int("pruned", report.Pruned),
+		}
+		if report.Error != "" {
+			attrs = append(attrs, slog.String("error", report.Error))
+			b.log.LogAttrs(ctx, slog.LevelWarn, "backup upload failed", attrs...)
+			return
+		}
+		b.log.LogAttrs(ctx, slog.LevelInfo, "backup uploaded", attrs...)
+	}()
+
+	backoff := b.backoff
+	var errs []error
+	for attempt := 1; attempt <= uploadAttempts; attempt++ {
+		report.Attempts = attempt
+		attemptCtx, cancel := context.WithTimeout(ctx, uploadTimeout)
+		err := b.objects.PutObject(attemptCtx, b.opts.Bucket, report.Key, snap.data, "application/gzip")
+		cancel()
+		if err == nil {
+			errs = nil
+			break
+		}
+		errs = append(errs, fmt.Errorf("attempt %d: %w", attempt, err))
+		if attempt == uploadAttempts {
+			break
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			errs = append(errs, ctx.Err())
+			report.Error = errors.Join(errs...).Error()
+			return
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+	if len(errs) > 0 {
+		report.Error = errors.Join(errs...).Error()
+		return
+	}
+
+	pruneCtx, cancel := context.WithTimeout(ctx, uploadTimeout)
+	defer cancel()
+	pruned, err := b.pruneBucket(pruneCtx, snap.createdAt)
+	report.Pruned = pruned
+	if err != nil {
+		// The backup itself is safe; pruning is retried after the next one.
+		b.log.WarnContext(ctx, "failed to prune old backups", slog.String("error", err.Error()))
+	}
+}
+
+func (b *Backup) pruneBucket(ctx context.Context, now time.Time) (int, error) {
+	if b.opts.KeepLast == 0 && b.opts.MaxAge == 0 {
+		return 0, nil
+	}
+	objects, err := b.objects.ListObjects(ctx, b.opts.Bucket, b.opts.Prefix)
+	if err != nil {
+		return 0, fmt.Errorf("list: %w", err)
+	}
+	names := make([]string, 0, len(objects))
+	for _, o := range objects {
+		// Keys further down the prefix belong to someone else.
+		if name := strings.TrimPrefix(o.Key, b.opts.Prefix); !strings.Contains(name, "/") {
+			names = append(names, name)
+		}
+	}
+	pruned := 0
+	for _, name := range b.expired(names, now) {
+		if err := b.objects.DeleteObject(ctx, b.opts.Bucket, b.opts.Prefix+name); err != nil {
+			return pruned, fmt.Errorf("delete %s: %w", b.opts.Prefix+name, err)
+		}
+		pruned++
+	}
+	return pruned, nil
+}
+
+func (b *Backup) pruneDir(now time.Time) error {
+	if b.opts.KeepLast == 0 && b.opts.MaxAge == 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(b.opts.Dir)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.Type().IsRegular() {
+			names = append(names, e.Name())
+		}
+	}
+	for _, name := range b.expired(names, now) {
+		if err := os.Remove(filepath.Join(b.opts.Dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expired returns the snapshot names the retention rules drop. Names that
+// are not snapshots are never returned.
+func (b *Backup) expired(names []string, now time.Time) []string {
+	var drop []string
+	for i, name := range snapshot.Sort(names) {
+		at, _ := snapshot.ParseName(name)
+		tooMany := b.opts.KeepLast > 0 && i >= b.opts.KeepLast
+		tooOld := b.opts.MaxAge > 0 && now.Sub(at) > b.opts.MaxAge
+		if tooMany || tooOld {
+			drop = append(drop, name)
+		}
+	}
+	return drop
+}
+
+// writeFile writes through a temporary file so a crash never leaves a
+// truncated snapshot behind.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
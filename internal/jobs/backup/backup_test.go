@@ -0,0 +1,262 @@
+package backup_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"quotes-service/internal/jobs/backup"
+	"quotes-service/internal/lib/s3"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage/memorystorage"
+	"quotes-service/internal/storage/snapshot"
+)
+
+// MockObjectStore keeps objects in memory. PutFunc, when set, runs before
+// a put and can fail it.
+type MockObjectStore struct {
+	mu      sync.Mutex
+	PutFunc func(key string) error
+	Objects map[string][]byte
+	Puts    int
+}
+
+func (m *MockObjectStore) PutObject(ctx context.Context, bucket, key string, body []byte, contentType string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Puts++
+	if m.PutFunc != nil {
+		if err := m.PutFunc(key); err != nil {
+			return err
+		}
+	}
+	m.Objects[bucket+"/"+key] = body
+	return nil
+}
+
+func (m *MockObjectStore) ListObjects(ctx context.Context, bucket, prefix string) ([]s3.Object, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var objects []s3.Object
+	for k, v := range m.Objects {
+		if key, ok := strings.CutPrefix(k, bucket+"/"); ok && strings.HasPrefix(key, prefix) {
+			objects = append(objects, s3.Object{Key: key, Size: int64(len(v))})
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (m *MockObjectStore) DeleteObject(ctx context.Context, bucket, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.Objects, bucket+"/"+key)
+	return nil
+}
+
+func (m *MockObjectStore) Keys() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0, len(m.Objects))
+	for k := range m.Objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func newStore(t *testing.T) *memorystorage.Storage {
+	t.Helper()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{"First quote text", "Second quote text"} {
+		if _, err := store.AddQuote(context.Background(), models.Quote{Text: text, Author: "Author"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+// waitUpload polls the status until the upload of key has finished.
+func waitUpload(t *testing.T, b *backup.Backup, key string) models.BackupUpload {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if last := b.Status().LastUpload; last != nil && last.Key == key {
+			return *last
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("upload of %s did not finish", key)
+	return models.BackupUpload{}
+}
+
+func TestBackup(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+	objects := &MockObjectStore{Objects: map[string][]byte{
+		// Left by other tools; never pruned.
+		"backups/prod/notes.txt": []byte("keep me"),
+	}}
+
+	var mu sync.Mutex
+	now := time.Date(2024, time.March, 11, 9, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+
+	b := backup.New(logger, newStore(t), objects, backup.Options{
+		Interval: time.Hour,
+		Dir:      dir,
+		Bucket:   "backups",
+		Prefix:   "prod/",
+		KeepLast: 2,
+	}, backup.WithClock(clock), backup.WithBackoff(time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Three manual backups an hour apart; only the last two are kept.
+	var keys []string
+	for i := 0; i < 3; i++ {
+		key := "prod/quotes-" + clock().Format("20060102T150405Z") + ".json.gz"
+		keys = append(keys, key)
+		b.Trigger()
+		upload := waitUpload(t, b, key)
+		if upload.Error != "" || upload.Attempts != 1 || upload.Trigger != backup.TriggerManual {
+			t.Fatalf("unexpected upload %+v", upload)
+		}
+		mu.Lock()
+		now = now.Add(time.Hour)
+		mu.Unlock()
+	}
+
+	want := []string{"backups/prod/notes.txt", "backups/" + keys[1], "backups/" + keys[2]}
+	if got := objects.Keys(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected bucket %v, got %v", want, got)
+	}
+	if upload := b.Status().LastUpload; upload.Pruned != 1 {
+		t.Errorf("expected the oldest backup to be pruned, got %+v", upload)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "quotes-*.json.gz"))
+	if len(files) != 2 {
+		t.Errorf("expected 2 local snapshots, got %v", files)
+	}
+
+	snap, err := snapshot.Decode(objects.Objects["backups/"+keys[2]])
+	if err != nil {
+		t.Fatalf("failed to decode the uploaded snapshot: %v", err)
+	}
+	if len(snap.Quotes) != 2 || snap.Quotes[0].Text != "First quote text" {
+		t.Errorf("unexpected snapshot %+v", snap)
+	}
+	local, err := os.ReadFile(b.Status().LastSnapshot.File)
+	if err != nil || string(local) != string(objects.Objects["backups/"+keys[2]]) {
+		t.Errorf("expected the local snapshot to match the upload, err %v", err)
+	}
+}
+
+func TestUploadRetries(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	now := time.Date(2024, time.March, 11, 9, 0, 0, 0, time.UTC)
+	key := "quotes-20240311T090000Z.json.gz"
+
+	tests := []struct {
+		name         string
+		failures     int
+		wantAttempts int
+		wantError    string
+	}{
+		{name: "succeeds after a failure", failures: 1, wantAttempts: 2},
+		{name: "gives up after three attempts", failures: 5, wantAttempts: 3, wantError: "attempt 1: connection reset\nattempt 2: connection reset\nattempt 3: connection reset"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			failures := tc.failures
+			objects := &MockObjectStore{Objects: map[string][]byte{}, PutFunc: func(string) error {
+				if failures > 0 {
+					failures--
+					return errors.New("connection reset")
+				}
+				return nil
+			}}
+			b := backup.New(logger, newStore(t), objects, backup.Options{Interval: time.Hour, Bucket: "backups"},
+				backup.WithClock(func() time.Time { return now }), backup.WithBackoff(time.Millisecond))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				b.Run(ctx)
+				close(done)
+			}()
+			b.Trigger()
+			upload := waitUpload(t, b, key)
+			cancel()
+			<-done
+
+			if upload.Attempts != tc.wantAttempts || upload.Error != tc.wantError {
+				t.Errorf("expected %d attempts with error %q, got %+v", tc.wantAttempts, tc.wantError, upload)
+			}
+			if snap := b.Status().LastSnapshot; snap == nil || snap.Quotes != 2 || snap.Error != "" || snap.File != "" {
+				t.Errorf("unexpected snapshot %+v", snap)
+			}
+		})
+	}
+}
+
+func TestRetentionMaxAge(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+	for _, name := range []string{
+		"quotes-20240301T090000Z.json.gz", // 10 days old
+		"quotes-20240309T090000Z.json.gz", // 2 days old
+		"quotes-garbage.json.gz",
+		"README",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Date(2024, time.March, 11, 9, 0, 0, 0, time.UTC)
+	b := backup.New(logger, newStore(t), nil, backup.Options{Interval: time.Hour, Dir: dir, MaxAge: 7 * 24 * time.Hour},
+		backup.WithClock(func() time.Time { return now }))
+
+	if snap := b.Snapshot(context.Background(), backup.TriggerSchedule); snap.Error != "" {
+		t.Fatalf("snapshot failed: %s", snap.Error)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	want := "README,quotes-20240309T090000Z.json.gz,quotes-20240311T090000Z.json.gz,quotes-garbage.json.gz"
+	if strings.Join(names, ",") != want {
+		t.Errorf("expected %s, got %v", want, names)
+	}
+	if b.Status().LastUpload != nil {
+		t.Error("expected no upload without an object store")
+	}
+}
@@ -0,0 +1,38 @@
+// Package claimsweep forgets the quote claims whose lease has run out.
+// Stores already treat such claims as gone; the sweep only frees what they
+// hold.
+package claimsweep
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Store is what the sweep calls. storage.Claimer is the real one.
+type Store interface {
+	ExpireClaims(ctx context.Context) (int, error)
+}
+
+// Run expires claims every interval until ctx is done.
+func Run(ctx context.Context, log *slog.Logger, store Store, interval time.Duration) {
+	log = log.With(slog.String("component", "jobs/claimsweep"))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := store.ExpireClaims(ctx)
+			if err != nil {
+				log.WarnContext(ctx, "failed to expire claims", slog.String("error", err.Error()))
+				continue
+			}
+			if expired > 0 {
+				log.DebugContext(ctx, "expired claims", slog.Int("count", expired))
+			}
+		}
+	}
+}
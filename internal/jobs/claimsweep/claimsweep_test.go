@@ -0,0 +1,59 @@
+package claimsweep_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"quotes-service/internal/jobs/claimsweep"
+)
+
+type fakeStore struct {
+	calls atomic.Int64
+	err   error
+}
+
+func (s *fakeStore) ExpireClaims(ctx context.Context) (int, error) {
+	s.calls.Add(1)
+	return 1, s.err
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "expires"},
+		{name: "keeps going after a failure", err: errors.New("boom")},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeStore{err: tc.err}
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				claimsweep.Run(ctx, slog.New(slog.NewTextHandler(io.Discard, nil)), store, time.Millisecond)
+			}()
+
+			deadline := time.Now().Add(5 * time.Second)
+			for store.calls.Load() < 3 {
+				if time.Now().After(deadline) {
+					t.Fatalf("expected at least 3 sweeps, got %d", store.calls.Load())
+				}
+				time.Sleep(time.Millisecond)
+			}
+			cancel()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("expected Run to return once ctx is done")
+			}
+		})
+	}
+}
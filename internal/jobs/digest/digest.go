@@ -0,0 +1,347 @@
+// Package digest emails editors a summary of the quotes added since the
+// last digest, on a cron schedule.
+package digest
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"quotes-service/internal/lib/mailer"
+	"quotes-service/internal/lib/schedule"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// Triggers recorded in a report.
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+// sendTimeout bounds one delivery, so a stuck relay cannot hold up the
+// next run.
+const sendTimeout = time.Minute
+
+// dateLayout is how the digest period is written in the email.
+const dateLayout = "2 Jan 2006 15:04 MST"
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var (
+	textTemplate = texttemplate.Must(texttemplate.New("digest.txt.tmpl").Funcs(texttemplate.FuncMap(funcs)).ParseFS(templateFS, "templates/digest.txt.tmpl"))
+	htmlTemplate = htmltemplate.Must(htmltemplate.New("digest.html.tmpl").Funcs(htmltemplate.FuncMap(funcs)).ParseFS(templateFS, "templates/digest.html.tmpl"))
+)
+
+var funcs = map[string]any{
+	"count": countQuotes,
+	"date": func(t time.Time) string {
+		return t.Format(dateLayout)
+	},
+}
+
+func countQuotes(quotes []models.Quote) string {
+	if len(quotes) == 1 {
+		return "1 new quote"
+	}
+	return fmt.Sprintf("%d new quotes", len(quotes))
+}
+
+type Store interface {
+	GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error)
+}
+
+// Sender delivers a composed email. *mailer.SMTP is the real one.
+type Sender interface {
+	Send(ctx context.Context, msg mailer.Message) error
+}
+
+// Options configures a Digest.
+type Options struct {
+	From    string
+	To      []string
+	Subject string
+	// StateFile keeps the end of the last sent digest across restarts.
+	// Empty keeps it in memory only, so the first digest after a restart
+	// starts at startup.
+	StateFile string
+}
+
+// Data is what the templates render: the quotes added in (Since, Until],
+// oldest first, with both times in the schedule's time zone.
+type Data struct {
+	Since  time.Time
+	Until  time.Time
+	Quotes []models.Quote
+}
+
+type Digest struct {
+	log      *slog.Logger
+	schedule *schedule.Schedule
+	store    Store
+	sender   Sender
+	opts     Options
+	now      func() time.Time
+	trigger  chan struct{}
+
+	mu      sync.Mutex
+	running bool
+	since   time.Time
+	last    *models.DigestReport
+}
+
+type Option func(*Digest)
+
+// WithClock overrides the time source, mainly for tests.
+func WithClock(now func() time.Time) Option {
+	return func(d *Digest) {
+		d.now = now
+	}
+}
+
+func New(log *slog.Logger, sched *schedule.Schedule, store Store, sender Sender, opts Options, options ...Option) *Digest {
+	d := &Digest{
+		log:      log.With(slog.String("op", "digest.Digest")),
+		schedule: sched,
+		store:    store,
+		sender:   sender,
+		opts:     opts,
+		now:      time.Now,
+		trigger:  make(chan struct{}, 1),
+	}
+	for _, opt := range options {
+		opt(d)
+	}
+	d.since = d.now().UTC()
+	return d
+}
+
+// Run sends a digest on every tick and whenever Trigger is called, until
+// ctx is done. A digest that fails to go out is retried on the next tick
+// with the quotes it missed included.
+func (d *Digest) Run(ctx context.Context) {
+	since, err := d.loadState()
+	if err != nil {
+		d.log.WarnContext(ctx, "failed to read digest state", slog.String("error", err.Error()))
+	}
+	if !since.IsZero() {
+		d.mu.Lock()
+		d.since = since
+		d.mu.Unlock()
+	}
+
+	for {
+		// A schedule that never fires leaves only manual triggers.
+		var tick <-chan time.Time
+		timer := time.NewTimer(0)
+		timer.Stop()
+		if next := d.schedule.Next(d.now()); !next.IsZero() {
+			timer.Reset(next.Sub(d.now()))
+			tick = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-tick:
+			d.Send(ctx, TriggerSchedule)
+		case <-d.trigger:
+			timer.Stop()
+			d.Send(ctx, TriggerManual)
+		}
+	}
+}
+
+// Trigger asks Run for a digest as soon as the current one, if any, is
+// done. Triggers made while one is already pending are merged into it.
+func (d *Digest) Trigger() {
+	select {
+	case d.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Status returns the configuration, the start of the next digest and the
+// report of the last finished run.
+func (d *Digest) Status() models.DigestStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	status := models.DigestStatus{
+		Cron:       d.schedule.String(),
+		Timezone:   d.schedule.Location().String(),
+		Recipients: len(d.opts.To),
+		Running:    d.running,
+		Since:      d.since,
+	}
+	if next := d.schedule.Next(d.now()); !next.IsZero() {
+		status.NextRun = &next
+	}
+	if d.last != nil {
+		last := *d.last
+		status.LastRun = &last
+	}
+	return status
+}
+
+// Send collects the quotes added since the last digest and emails them.
+// Nothing is sent when there are none. Failures, including a panic, end up
+// in the report rather than taking the service down.
+func (d *Digest) Send(ctx context.Context, trigger string) (report models.DigestReport) {
+	d.mu.Lock()
+	d.running = true
+	since := d.since
+	d.mu.Unlock()
+
+	until := d.now().UTC()
+	report = models.DigestReport{Trigger: trigger, StartedAt: until, Since: since, Until: until}
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			report.Error = fmt.Sprintf("panic: %v", rvr)
+		}
+		report.FinishedAt = d.now().UTC()
+
+		d.mu.Lock()
+		d.running = false
+		if report.Error == "" {
+			d.since = until
+		}
+		d.last = &report
+		d.mu.Unlock()
+
+		attrs := []slog.Attr{
+			slog.String("trigger", trigger),
+			slog.Time("since", since),
+			slog.Int("quotes", report.Quotes),
+			slog.Bool("sent", report.Sent),
+		}
+		if report.Error != "" {
+			attrs = append(attrs, slog.String("error", report.Error))
+			d.log.LogAttrs(ctx, slog.LevelWarn, "digest failed, will retry on the next run", attrs...)
+			return
+		}
+		if err := d.saveState(until); err != nil {
+			d.log.WarnContext(ctx, "failed to save digest state", slog.String("error", err.Error()))
+		}
+		d.log.LogAttrs(ctx, slog.LevelInfo, "digest finished", attrs...)
+	}()
+
+	quotes, err := d.store.GetAllQuotes(ctx, storage.QuoteFilter{})
+	if err != nil {
+		report.Error = fmt.Sprintf("load quotes: %v", err)
+		return report
+	}
+	added := quotes[:0:0]
+	for _, q := range quotes {
+		if q.CreatedAt.After(since) && !q.CreatedAt.After(until) {
+			added = append(added, q)
+		}
+	}
+	report.Quotes = len(added)
+	if len(added) == 0 {
+		return report
+	}
+
+	loc := d.schedule.Location()
+	msg, err := Compose(d.opts, Data{Since: since.In(loc), Until: until.In(loc), Quotes: added})
+	if err != nil {
+		report.Error = fmt.Sprintf("compose: %v", err)
+		return report
+	}
+	msg.Date = until
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	if err := d.sender.Send(sendCtx, msg); err != nil {
+		report.Error = fmt.Sprintf("send: %v", err)
+		return report
+	}
+	report.Sent = true
+	return report
+}
+
+// Compose renders data into a message from opts.From to opts.To. Quotes
+// are listed oldest first.
+func Compose(opts Options, data Data) (mailer.Message, error) {
+	quotes := append([]models.Quote(nil), data.Quotes...)
+	sort.SliceStable(quotes, func(i, j int) bool {
+		if !quotes[i].CreatedAt.Equal(quotes[j].CreatedAt) {
+			return quotes[i].CreatedAt.Before(quotes[j].CreatedAt)
+		}
+		return quotes[i].ID < quotes[j].ID
+	})
+	data.Quotes = quotes
+
+	var text, html bytes.Buffer
+	if err := textTemplate.Execute(&text, data); err != nil {
+		return mailer.Message{}, fmt.Errorf("render text: %w", err)
+	}
+	if err := htmlTemplate.Execute(&html, data); err != nil {
+		return mailer.Message{}, fmt.Errorf("render html: %w", err)
+	}
+	return mailer.Message{
+		From:    opts.From,
+		To:      opts.To,
+		Subject: fmt.Sprintf("%s: %s", opts.Subject, countQuotes(quotes)),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+type state struct {
+	Since time.Time `json:"since"`
+}
+
+func (d *Digest) loadState() (time.Time, error) {
+	if d.opts.StateFile == "" {
+		return time.Time{}, nil
+	}
+	data, err := os.ReadFile(d.opts.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return time.Time{}, fmt.Errorf("decode %s: %w", d.opts.StateFile, err)
+	}
+	return st.Since, nil
+}
+
+// saveState writes the state through a temporary file so a crash never
+// leaves a truncated one behind.
+func (d *Digest) saveState(since time.Time) error {
+	if d.opts.StateFile == "" {
+		return nil
+	}
+	data, err := json.Marshal(state{Since: since})
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(d.opts.StateFile), ".digest-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), d.opts.StateFile)
+}
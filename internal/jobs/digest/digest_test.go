@@ -0,0 +1,219 @@
+package digest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"quotes-service/internal/jobs/digest"
+	"quotes-service/internal/lib/mailer"
+	"quotes-service/internal/lib/schedule"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+type MockSender struct {
+	mu       sync.Mutex
+	SendFunc func(ctx context.Context, msg mailer.Message) error
+	Sent     []mailer.Message
+}
+
+func (m *MockSender) Send(ctx context.Context, msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.SendFunc != nil {
+		if err := m.SendFunc(ctx, msg); err != nil {
+			return err
+		}
+	}
+	m.Sent = append(m.Sent, msg)
+	return nil
+}
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output does not match %s:\n%s", golden, got)
+	}
+}
+
+func TestCompose(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	since := time.Date(2024, time.March, 4, 9, 0, 0, 0, loc)
+	opts := digest.Options{
+		From:    "Quotes Service <quotes@example.com>",
+		To:      []string{"editors@example.com"},
+		Subject: "Weekly quotes digest",
+	}
+	data := digest.Data{
+		Since: since,
+		Until: since.AddDate(0, 0, 7),
+		// Out of order on purpose: the digest lists the oldest first.
+		Quotes: []models.Quote{
+			{ID: 3, Text: "Simplicity is prerequisite for reliability.", Author: "Edsger W. Dijkstra", Source: "EWD498", SourceURL: "https://www.cs.utexas.edu/~EWD/transcriptions/EWD04xx/EWD498.html", CreatedAt: since.Add(48 * time.Hour)},
+			{ID: 2, Text: "Talk is cheap. <Show me the code> & more.", Author: "Linus Torvalds", CreatedAt: since.Add(time.Hour)},
+			{ID: 5, Text: "Рукописи не горят.", Author: "Михаил Булгаков", Source: "Мастер и Маргарита", CreatedAt: since.Add(72 * time.Hour)},
+		},
+	}
+
+	msg, err := digest.Compose(opts, data)
+	if err != nil {
+		t.Fatalf("failed to compose digest: %v", err)
+	}
+	if msg.Subject != "Weekly quotes digest: 3 new quotes" {
+		t.Errorf("unexpected subject %q", msg.Subject)
+	}
+	checkGolden(t, "digest.txt.golden", []byte(msg.Text))
+	checkGolden(t, "digest.html.golden", []byte(msg.HTML))
+
+	msg.Date = data.Until
+	msg.Boundary = "digest-boundary"
+	raw, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("failed to build message: %v", err)
+	}
+	checkGolden(t, "digest.eml.golden", raw)
+}
+
+func TestSend(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sched, err := schedule.Parse("0 9 * * MON", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The clock is advanced by hand: quotes get their CreatedAt from it and
+	// the digest cuts its periods with it.
+	var mu sync.Mutex
+	now := time.Date(2024, time.March, 4, 9, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		now = now.Add(d)
+		mu.Unlock()
+	}
+
+	store, err := memorystorage.New(memorystorage.WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	add := func(text string) {
+		t.Helper()
+		advance(time.Minute)
+		if _, err := store.AddQuote(context.Background(), models.Quote{Text: text, Author: "Author"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	add("Added before the digest job started")
+	sender := &MockSender{}
+	d := digest.New(logger, sched, store, sender, digest.Options{
+		From:    "quotes@example.com",
+		To:      []string{"editors@example.com"},
+		Subject: "Digest",
+	}, digest.WithClock(clock))
+
+	// Nothing new yet: no email.
+	advance(time.Hour)
+	report := d.Send(context.Background(), digest.TriggerManual)
+	if report.Sent || report.Quotes != 0 || report.Error != "" {
+		t.Fatalf("expected an empty run, got %+v", report)
+	}
+
+	// A failed delivery keeps its quotes for the next run.
+	add("First new quote")
+	sender.SendFunc = func(ctx context.Context, msg mailer.Message) error {
+		return errors.New("connection refused")
+	}
+	advance(time.Hour)
+	report = d.Send(context.Background(), digest.TriggerSchedule)
+	if report.Sent || report.Quotes != 1 || report.Error != "send: connection refused" {
+		t.Fatalf("expected a failed run with 1 quote, got %+v", report)
+	}
+
+	add("Second new quote")
+	sender.SendFunc = nil
+	advance(time.Hour)
+	report = d.Send(context.Background(), digest.TriggerSchedule)
+	if !report.Sent || report.Quotes != 2 || report.Error != "" {
+		t.Fatalf("expected 2 quotes to be sent, got %+v", report)
+	}
+	if len(sender.Sent) != 1 || sender.Sent[0].Subject != "Digest: 2 new quotes" {
+		t.Fatalf("unexpected emails %+v", sender.Sent)
+	}
+
+	// Sent quotes are not repeated.
+	advance(time.Hour)
+	if report := d.Send(context.Background(), digest.TriggerSchedule); report.Quotes != 0 {
+		t.Errorf("expected no quotes after a sent digest, got %+v", report)
+	}
+
+	status := d.Status()
+	if status.Recipients != 1 || status.LastRun == nil || !status.Since.Equal(clock().UTC()) {
+		t.Errorf("unexpected status %+v", status)
+	}
+	if status.NextRun == nil || !status.NextRun.Equal(time.Date(2024, time.March, 11, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected next run %v", status.NextRun)
+	}
+}
+
+func TestSendRecoversFromPanic(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sched, err := schedule.Parse("0 9 * * MON", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, time.March, 4, 9, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	store, err := memorystorage.New(memorystorage.WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sender := &MockSender{SendFunc: func(ctx context.Context, msg mailer.Message) error {
+		panic("boom")
+	}}
+	d := digest.New(logger, sched, store, sender, digest.Options{From: "quotes@example.com", To: []string{"editors@example.com"}},
+		digest.WithClock(clock))
+
+	now = now.Add(time.Minute)
+	if _, err := store.AddQuote(context.Background(), models.Quote{Text: "Some quote", Author: "Author"}); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Minute)
+
+	report := d.Send(context.Background(), digest.TriggerManual)
+	if report.Sent || report.Error != "panic: boom" {
+		t.Fatalf("expected the panic in the report, got %+v", report)
+	}
+	if status := d.Status(); status.Running || status.LastRun == nil || status.Since.After(report.Since) {
+		t.Errorf("unexpected status after a panic %+v", status)
+	}
+}
@@ -0,0 +1,585 @@
+// Package export writes exports of the quotes in the background, for
+// catalogs too large to export within one request. Finished exports are
+// kept on local disk or in an S3-compatible bucket until they expire.
+package export
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"quotes-service/internal/lib/quotable"
+	"quotes-service/internal/lib/quoteinput"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// Formats an export is written in, as JSON Lines, one quote per line.
+const (
+	FormatNative   = quoteinput.FormatNative
+	FormatQuotable = quoteinput.FormatQuotable
+
+	ContentType = "application/x-ndjson"
+)
+
+const (
+	defaultWorkers   = 1
+	defaultQueueSize = 16
+	defaultTTL       = 24 * time.Hour
+	// sweepInterval is how often Run removes expired jobs.
+	sweepInterval = time.Minute
+	// progressEvery is how many quotes are written between updates of a
+	// job's progress.
+	progressEvery = 1000
+	// cleanupTimeout bounds deleting an expired export from the bucket.
+	cleanupTimeout = time.Minute
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown export format")
+	ErrQueueFull     = errors.New("export queue is full")
+	ErrNotFound      = errors.New("export not found")
+	ErrNotReady      = errors.New("export is not finished")
+)
+
+type Store interface {
+	GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error)
+}
+
+// ObjectStore is the part of an S3 client exports need. *s3.Client is the
+// real one.
+type ObjectStore interface {
+	PutObject(ctx context.Context, bucket, key string, body []byte, contentType string) error
+	OpenObject(ctx context.Context, bucket, key string) (io.ReadCloser, error)
+	DeleteObject(ctx context.Context, bucket, key string) error
+}
+
+// Options configures a Manager.
+type Options struct {
+	// Dir holds exports while they are written and, without a bucket, until
+	// they expire. Empty uses a directory under os.TempDir.
+	Dir string
+	// Bucket and Prefix locate finished exports when they are uploaded.
+	// Prefix is put in front of the file name as is.
+	Bucket string
+	Prefix string
+	// TTL is how long a finished job and its export are kept.
+	TTL time.Duration
+	// Workers bounds the exports written at once, and QueueSize the jobs
+	// waiting for a worker.
+	Workers   int
+	QueueSize int
+	// StateFile keeps the jobs across restarts. Empty keeps them in memory
+	// only.
+	StateFile string
+}
+
+// job is a models.ExportJob with what the manager needs to run it and
+// serve its export. File and Key are set once the export is finished.
+type job struct {
+	info   models.ExportJob
+	filter storage.QuoteFilter
+	file   string
+	key    string
+}
+
+type Manager struct {
+	log     *slog.Logger
+	store   Store
+	objects ObjectStore
+	opts    Options
+	now     func() time.Time
+	queue   chan string
+
+	mu   sync.Mutex
+	jobs map[string]*job
+
+	// saveMu keeps state files written in the order they were taken.
+	saveMu sync.Mutex
+}
+
+type Option func(*Manager)
+
+// WithClock overrides the time source, mainly for tests.
+func WithClock(now func() time.Time) Option {
+	return func(m *Manager) {
+		m.now = now
+	}
+}
+
+// New returns a Manager. A nil objects keeps finished exports in Dir.
+func New(log *slog.Logger, store Store, objects ObjectStore, opts Options, options ...Option) *Manager {
+	if opts.Dir == "" {
+		opts.Dir = filepath.Join(os.TempDir(), "quotes-exports")
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = defaultTTL
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = defaultWorkers
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultQueueSize
+	}
+	m := &Manager{
+		log:     log.With(slog.String("op", "export.Manager")),
+		store:   store,
+		objects: objects,
+		opts:    opts,
+		now:     time.Now,
+		queue:   make(chan string, opts.QueueSize),
+		jobs:    make(map[string]*job),
+	}
+	for _, opt := range options {
+		opt(m)
+	}
+	return m
+}
+
+// Run loads the jobs kept in StateFile, then writes queued exports on
+// Workers goroutines and removes expired jobs until ctx is done. Exports
+// still running then are canceled, and so are the jobs left in the queue.
+func (m *Manager) Run(ctx context.Context) {
+	if err := m.loadState(); err != nil {
+		m.log.WarnContext(ctx, "failed to read export state", slog.String("error", err.Error()))
+	}
+	m.Expire(ctx)
+
+	var wg sync.WaitGroup
+	for range m.opts.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.work(ctx)
+		}()
+	}
+
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			m.cancelQueued()
+			return
+		case <-ticker.C:
+			m.Expire(ctx)
+		}
+	}
+}
+
+// Submit queues an export of the quotes matching filter in format, which
+// is FormatNative if empty. It returns ErrQueueFull rather than wait when
+// QueueSize jobs are already waiting.
+func (m *Manager) Submit(format string, filter storage.QuoteFilter) (models.ExportJob, error) {
+	switch format {
+	case "":
+		format = FormatNative
+	case FormatNative, FormatQuotable:
+	default:
+		return models.ExportJob{}, ErrUnknownFormat
+	}
+
+	j := &job{
+		info: models.ExportJob{
+			ID:        newID(),
+			Status:    models.JobQueued,
+			Format:    format,
+			Lang:      filter.Lang,
+			HasSource: filter.HasSource,
+			CreatedAt: m.now().UTC(),
+		},
+		filter: filter,
+	}
+
+	m.mu.Lock()
+	select {
+	case m.queue <- j.info.ID:
+	default:
+		m.mu.Unlock()
+		return models.ExportJob{}, ErrQueueFull
+	}
+	m.jobs[j.info.ID] = j
+	info := j.info
+	m.mu.Unlock()
+
+	m.saveState()
+	return info, nil
+}
+
+// Get returns the job with id.
+func (m *Manager) Get(id string) (models.ExportJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return models.ExportJob{}, ErrNotFound
+	}
+	return j.info, nil
+}
+
+// Open returns the job with id and its export for the caller to read and
+// close. It returns ErrNotReady unless the job is done.
+func (m *Manager) Open(ctx context.Context, id string) (models.ExportJob, io.ReadCloser, error) {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	if !ok {
+		m.mu.Unlock()
+		return models.ExportJob{}, nil, ErrNotFound
+	}
+	info, file, key := j.info, j.file, j.key
+	m.mu.Unlock()
+
+	if info.Status != models.JobDone {
+		return info, nil, ErrNotReady
+	}
+	var (
+		body io.ReadCloser
+		err  error
+	)
+	if key != "" {
+		body, err = m.objects.OpenObject(ctx, m.opts.Bucket, key)
+	} else {
+		body, err = os.Open(file)
+	}
+	if err != nil {
+		return info, nil, err
+	}
+	return info, body, nil
+}
+
+// Expire removes the finished jobs past their TTL and deletes their
+// exports. Run calls it every minute.
+func (m *Manager) Expire(ctx context.Context) {
+	now := m.now()
+	var expired []*job
+	m.mu.Lock()
+	for id, j := range m.jobs {
+		if j.info.ExpiresAt != nil && !now.Before(*j.info.ExpiresAt) {
+			expired = append(expired, j)
+			delete(m.jobs, id)
+		}
+	}
+	m.mu.Unlock()
+	if len(expired) == 0 {
+		return
+	}
+
+	for _, j := range expired {
+		if err := m.remove(ctx, j); err != nil {
+			m.log.WarnContext(ctx, "failed to delete expired export", slog.String("id", j.info.ID), slog.String("error", err.Error()))
+		}
+	}
+	m.log.InfoContext(ctx, "expired exports removed", slog.Int("count", len(expired)))
+	m.saveState()
+}
+
+func (m *Manager) remove(ctx context.Context, j *job) error {
+	if j.key != "" {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+		defer cancel()
+		return m.objects.DeleteObject(ctx, m.opts.Bucket, j.key)
+	}
+	if j.file != "" {
+		if err := os.Remove(j.file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-m.queue:
+			m.export(ctx, id)
+		}
+	}
+}
+
+// export writes the export of the job with id. Failures are recorded in
+// the job rather than returned.
+func (m *Manager) export(ctx context.Context, id string) {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	if !ok || j.info.Status != models.JobQueued {
+		m.mu.Unlock()
+		return
+	}
+	started := m.now().UTC()
+	j.info.Status = models.JobRunning
+	j.info.StartedAt = &started
+	m.mu.Unlock()
+	m.saveState()
+
+	var err error
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			err = fmt.Errorf("panic: %v", rvr)
+		}
+		m.finish(ctx, j, err)
+	}()
+	err = m.write(ctx, j)
+}
+
+// finish records how the job ended and starts its TTL.
+func (m *Manager) finish(ctx context.Context, j *job, err error) {
+	finished := m.now().UTC()
+	expires := finished.Add(m.opts.TTL)
+
+	m.mu.Lock()
+	switch {
+	case err == nil:
+		j.info.Status = models.JobDone
+	case ctx.Err() != nil:
+		j.info.Status = models.JobCanceled
+		j.info.Error = err.Error()
+	default:
+		j.info.Status = models.JobFailed
+		j.info.Error = err.Error()
+	}
+	j.info.FinishedAt = &finished
+	j.info.ExpiresAt = &expires
+	info := j.info
+	m.mu.Unlock()
+	m.saveState()
+
+	attrs := []slog.Attr{
+		slog.String("id", info.ID),
+		slog.String("status", info.Status),
+		slog.String("format", info.Format),
+		slog.Int("written", info.Written),
+		slog.Int64("bytes", info.Bytes),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		m.log.LogAttrs(ctx, slog.LevelWarn, "export failed", attrs...)
+		return
+	}
+	m.log.LogAttrs(ctx, slog.LevelInfo, "export finished", attrs...)
+}
+
+// write exports the quotes to a file in Dir and, with a bucket, uploads
+// it.
+func (m *Manager) write(ctx context.Context, j *job) error {
+	quotes, err := m.store.GetAllQuotes(ctx, j.filter)
+	if err != nil {
+		return fmt.Errorf("load quotes: %w", err)
+	}
+	m.mu.Lock()
+	j.info.Total = len(quotes)
+	m.mu.Unlock()
+
+	if err := os.MkdirAll(m.opts.Dir, 0o750); err != nil {
+		return fmt.Errorf("create %s: %w", m.opts.Dir, err)
+	}
+	tmp, err := os.CreateTemp(m.opts.Dir, ".export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	size, err := m.encode(ctx, j, tmp, quotes)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	name := j.info.ID + ".jsonl"
+	if m.objects != nil {
+		data, err := os.ReadFile(tmp.Name())
+		if err != nil {
+			return err
+		}
+		key := m.opts.Prefix + name
+		if err := m.objects.PutObject(ctx, m.opts.Bucket, key, data, ContentType); err != nil {
+			return fmt.Errorf("upload %s: %w", key, err)
+		}
+		m.mu.Lock()
+		j.key = key
+		j.info.Bytes = size
+		m.mu.Unlock()
+		return nil
+	}
+
+	path := filepath.Join(m.opts.Dir, name)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	j.file = path
+	j.info.Bytes = size
+	m.mu.Unlock()
+	return nil
+}
+
+// encode writes quotes to w as JSON Lines, keeping the job's progress up
+// to date, and returns the bytes written.
+func (m *Manager) encode(ctx context.Context, j *job, w io.Writer, quotes []models.Quote) (int64, error) {
+	counter := &countingWriter{w: w}
+	buf := bufio.NewWriter(counter)
+	enc := json.NewEncoder(buf)
+	for i, q := range quotes {
+		if i%progressEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return 0, err
+			}
+			m.mu.Lock()
+			j.info.Written = i
+			m.mu.Unlock()
+		}
+		var record any = q
+		if j.info.Format == FormatQuotable {
+			record = quotable.FromQuote(q)
+		}
+		if err := enc.Encode(record); err != nil {
+			return 0, err
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	j.info.Written = len(quotes)
+	m.mu.Unlock()
+	return counter.n, nil
+}
+
+// cancelQueued cancels the jobs no worker picked up before Run stopped.
+func (m *Manager) cancelQueued() {
+	finished := m.now().UTC()
+	expires := finished.Add(m.opts.TTL)
+	m.mu.Lock()
+	for _, j := range m.jobs {
+		if j.info.Status == models.JobQueued {
+			j.info.Status = models.JobCanceled
+			j.info.Error = "canceled by shutdown"
+			j.info.FinishedAt = &finished
+			j.info.ExpiresAt = &expires
+		}
+	}
+	m.mu.Unlock()
+	m.saveState()
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func newID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+type state struct {
+	Jobs []savedJob `json:"jobs"`
+}
+
+type savedJob struct {
+	models.ExportJob
+	File string `json:"file,omitempty"`
+	Key  string `json:"key,omitempty"`
+}
+
+// loadState adds the jobs kept in StateFile. Jobs that were still queued
+// or running when it was written were cut off by a restart, so they are
+// loaded as failed.
+func (m *Manager) loadState() error {
+	if m.opts.StateFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(m.opts.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return fmt.Errorf("decode %s: %w", m.opts.StateFile, err)
+	}
+
+	now := m.now().UTC()
+	expires := now.Add(m.opts.TTL)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, saved := range st.Jobs {
+		if _, ok := m.jobs[saved.ID]; ok {
+			continue
+		}
+		j := &job{info: saved.ExportJob, file: saved.File, key: saved.Key}
+		if j.info.Status == models.JobQueued || j.info.Status == models.JobRunning {
+			j.info.Status = models.JobFailed
+			j.info.Error = "interrupted by a restart"
+			j.info.FinishedAt = &now
+			j.info.ExpiresAt = &expires
+		}
+		m.jobs[saved.ID] = j
+	}
+	return nil
+}
+
+// saveState writes the jobs through a temporary file so a crash never
+// leaves a truncated one behind. Failures are logged; the jobs carry on in
+// memory.
+func (m *Manager) saveState() {
+	if m.opts.StateFile == "" {
+		return
+	}
+	m.saveMu.Lock()
+	defer m.saveMu.Unlock()
+
+	m.mu.Lock()
+	st := state{Jobs: make([]savedJob, 0, len(m.jobs))}
+	for _, j := range m.jobs {
+		st.Jobs = append(st.Jobs, savedJob{ExportJob: j.info, File: j.file, Key: j.key})
+	}
+	m.mu.Unlock()
+
+	if err := writeState(m.opts.StateFile, st); err != nil {
+		m.log.Warn("failed to save export state", slog.String("error", err.Error()))
+	}
+}
+
+func writeState(path string, st state) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".exports-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
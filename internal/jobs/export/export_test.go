@@ -0,0 +1,327 @@
+package export_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"quotes-service/internal/jobs/export"
+	"quotes-service/internal/lib/quotable"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+// MockObjectStore keeps objects in memory.
+type MockObjectStore struct {
+	mu      sync.Mutex
+	Objects map[string][]byte
+}
+
+func (m *MockObjectStore) PutObject(ctx context.Context, bucket, key string, body []byte, contentType string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Objects[bucket+"/"+key] = body
+	return nil
+}
+
+func (m *MockObjectStore) OpenObject(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.Objects[bucket+"/"+key]
+	if !ok {
+		return nil, errors.New("no such key")
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *MockObjectStore) DeleteObject(ctx context.Context, bucket, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.Objects, bucket+"/"+key)
+	return nil
+}
+
+func (m *MockObjectStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.Objects)
+}
+
+// blockingStore holds every load until its context is canceled.
+type blockingStore struct {
+	started chan struct{}
+}
+
+func (s *blockingStore) GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error) {
+	s.started <- struct{}{}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func newStore(t *testing.T) *memorystorage.Storage {
+	t.Helper()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	quotes := []models.Quote{
+		{Text: "First quote text", Author: "Author", Lang: "en", Source: "Book"},
+		{Text: "Second quote text", Author: "Author", Lang: "en"},
+		{Text: "Texte de la troisième citation", Author: "Auteur", Lang: "fr"},
+	}
+	for _, q := range quotes {
+		if _, err := store.AddQuote(context.Background(), q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+// start runs m until the test ends.
+func start(t *testing.T, m *export.Manager) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// wait polls the job until it has finished.
+func wait(t *testing.T, m *export.Manager, id string) models.ExportJob {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatalf("failed to get job %s: %v", id, err)
+		}
+		if job.Status != models.JobQueued && job.Status != models.JobRunning {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return models.ExportJob{}
+}
+
+func read(t *testing.T, m *export.Manager, id string) []byte {
+	t.Helper()
+	_, body, err := m.Open(context.Background(), id)
+	if err != nil {
+		t.Fatalf("failed to open export %s: %v", id, err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func decodeLines[T any](t *testing.T, data []byte) []T {
+	t.Helper()
+	var records []T
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var record T
+		if err := dec.Decode(&record); err != nil {
+			t.Fatalf("failed to decode export line: %v", err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestManagerLifecycle(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+	stateFile := filepath.Join(t.TempDir(), "exports.json")
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var clockMu sync.Mutex
+	clock := func() time.Time {
+		clockMu.Lock()
+		defer clockMu.Unlock()
+		return now
+	}
+	opts := export.Options{Dir: dir, TTL: time.Hour, Workers: 2, StateFile: stateFile}
+	m := export.New(logger, newStore(t), nil, opts, export.WithClock(clock))
+	start(t, m)
+
+	if _, err := m.Submit("csv", storage.QuoteFilter{}); !errors.Is(err, export.ErrUnknownFormat) {
+		t.Fatalf("expected ErrUnknownFormat, got %v", err)
+	}
+
+	hasSource := true
+	native, err := m.Submit("", storage.QuoteFilter{Lang: "en"})
+	if err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	if native.Status != models.JobQueued || native.Format != export.FormatNative || native.Lang != "en" {
+		t.Fatalf("unexpected submitted job %+v", native)
+	}
+	sourced, err := m.Submit(export.FormatQuotable, storage.QuoteFilter{HasSource: &hasSource})
+	if err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+
+	job := wait(t, m, native.ID)
+	if job.Status != models.JobDone || job.Total != 2 || job.Written != 2 || job.Error != "" {
+		t.Fatalf("unexpected finished job %+v", job)
+	}
+	if job.ExpiresAt == nil || !job.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("expected the job to expire an hour after it finished, got %v", job.ExpiresAt)
+	}
+	data := read(t, m, native.ID)
+	if int64(len(data)) != job.Bytes {
+		t.Fatalf("expected %d bytes, read %d", job.Bytes, len(data))
+	}
+	quotes := decodeLines[models.Quote](t, data)
+	if len(quotes) != 2 || quotes[0].Text != "First quote text" || quotes[1].Text != "Second quote text" {
+		t.Fatalf("unexpected native export %+v", quotes)
+	}
+
+	wait(t, m, sourced.ID)
+	records := decodeLines[quotable.Record](t, read(t, m, sourced.ID))
+	if len(records) != 1 || records[0].Content != "First quote text" {
+		t.Fatalf("unexpected quotable export %+v", records)
+	}
+
+	// A restarted manager picks the finished jobs up from the state file.
+	restarted := export.New(logger, newStore(t), nil, opts, export.WithClock(clock))
+	start(t, restarted)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := restarted.Get(native.ID); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("restarted manager did not load the jobs")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := read(t, restarted, native.ID); !bytes.Equal(got, data) {
+		t.Fatalf("expected the restarted manager to serve the same export")
+	}
+
+	clockMu.Lock()
+	now = now.Add(time.Hour)
+	clockMu.Unlock()
+	m.Expire(context.Background())
+	if _, err := m.Get(native.ID); !errors.Is(err, export.ErrNotFound) {
+		t.Fatalf("expected the expired job to be gone, got %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected the expired exports to be deleted, found %d files", len(entries))
+	}
+}
+
+func TestManagerUploads(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	objects := &MockObjectStore{Objects: make(map[string][]byte)}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	m := export.New(logger, newStore(t), objects, export.Options{
+		Dir:    t.TempDir(),
+		Bucket: "exports",
+		Prefix: "quotes/",
+		TTL:    time.Hour,
+	}, export.WithClock(func() time.Time { return now }))
+	start(t, m)
+
+	job, err := m.Submit(export.FormatNative, storage.QuoteFilter{})
+	if err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	if job = wait(t, m, job.ID); job.Status != models.JobDone {
+		t.Fatalf("unexpected finished job %+v", job)
+	}
+	if _, ok := objects.Objects["exports/quotes/"+job.ID+".jsonl"]; !ok {
+		t.Fatalf("expected the export in the bucket, got %d objects", objects.Len())
+	}
+	if quotes := decodeLines[models.Quote](t, read(t, m, job.ID)); len(quotes) != 3 {
+		t.Fatalf("expected 3 quotes from the bucket, got %d", len(quotes))
+	}
+
+	now = now.Add(time.Hour)
+	m.Expire(context.Background())
+	if objects.Len() != 0 {
+		t.Fatalf("expected the expired export to be deleted from the bucket, got %d objects", objects.Len())
+	}
+}
+
+func TestManagerQueueFull(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	// Without Run nothing leaves the queue.
+	m := export.New(logger, newStore(t), nil, export.Options{Dir: t.TempDir(), QueueSize: 1})
+
+	job, err := m.Submit("", storage.QuoteFilter{})
+	if err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	if _, err := m.Submit("", storage.QuoteFilter{}); !errors.Is(err, export.ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	if _, _, err := m.Open(context.Background(), job.ID); !errors.Is(err, export.ErrNotReady) {
+		t.Fatalf("expected a queued export not to open, got %v", err)
+	}
+}
+
+func TestManagerShutdown(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &blockingStore{started: make(chan struct{}, 1)}
+	stateFile := filepath.Join(t.TempDir(), "exports.json")
+	m := export.New(logger, store, nil, export.Options{Dir: t.TempDir(), Workers: 1, StateFile: stateFile})
+
+	running, err := m.Submit("", storage.QuoteFilter{})
+	if err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	queued, err := m.Submit("", storage.QuoteFilter{})
+	if err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Run(ctx)
+	}()
+	<-store.started
+	cancel()
+	<-done
+
+	for _, id := range []string{running.ID, queued.ID} {
+		job, err := m.Get(id)
+		if err != nil || job.Status != models.JobCanceled {
+			t.Fatalf("expected job %s to be canceled, got %+v, %v", id, job, err)
+		}
+	}
+
+	// Canceled jobs are kept for their TTL like any other finished job.
+	restarted := export.New(logger, store, nil, export.Options{Dir: t.TempDir(), StateFile: stateFile})
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	restarted.Run(ctx)
+	if job, err := restarted.Get(queued.ID); err != nil || job.Status != models.JobCanceled {
+		t.Fatalf("expected the canceled job to survive a restart, got %+v, %v", job, err)
+	}
+}
@@ -0,0 +1,163 @@
+package importer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	// DefaultSchemes are the URL schemes an import is fetched over when
+	// FetchOptions.Schemes is empty.
+	DefaultSchemes = []string{"https"}
+	// DefaultContentTypes are the media types a fetched import may be
+	// served as when FetchOptions.ContentTypes is empty.
+	DefaultContentTypes = []string{"application/x-ndjson", "application/jsonl", "application/json", "text/plain"}
+)
+
+// ErrURLNotAllowed is returned for a URL whose scheme or host the fetch
+// options do not allow.
+var ErrURLNotAllowed = errors.New("import url is not allowed")
+
+// FetchOptions limits where imports are fetched from. Every URL, the first
+// and each one redirected to, must have one of Schemes and one of Hosts;
+// no host is allowed until Hosts names one. Whatever a host name resolves
+// to, a loopback, private or link-local address is only connected to when
+// Hosts names that address itself.
+type FetchOptions struct {
+	Schemes []string
+	// Hosts are host names, without a port, or "*.example.com" for every
+	// subdomain of example.com.
+	Hosts []string
+	// MaxRedirects bounds the redirects followed; 0 follows none.
+	MaxRedirects int
+	ContentTypes []string
+	// InsecureSkipVerify accepts any TLS certificate. It is for
+	// development only; the config refuses it in other environments.
+	InsecureSkipVerify bool
+}
+
+// Allowed reports whether an import may be fetched from u.
+func (o FetchOptions) Allowed(u *url.URL) bool {
+	if !slices.Contains(o.Schemes, strings.ToLower(u.Scheme)) {
+		return false
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return false
+	}
+	for _, allowed := range o.Hosts {
+		allowed = strings.ToLower(allowed)
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok {
+			if strings.HasSuffix(host, suffix) {
+				return true
+			}
+			continue
+		}
+		if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// dialAllowed reports whether an import may be fetched over a connection
+// to ip, one a host name resolved to.
+func (o FetchOptions) dialAllowed(ip net.IP) bool {
+	if !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsUnspecified() {
+		return true
+	}
+	for _, allowed := range o.Hosts {
+		if allowedIP := net.ParseIP(allowed); allowedIP != nil && allowedIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// jobIDKey carries the ID of the job a fetch is for, for the redirect log.
+type jobIDKey struct{}
+
+// newClient returns the client imports are fetched with, which follows
+// the redirects opts allows and logs each of them.
+func newClient(log *slog.Logger, opts Options) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		// Checked on the address dialed, so a host name cannot resolve
+		// its way into the internal network.
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !opts.Fetch.dialAllowed(ip) {
+				return fmt.Errorf("connect to %s: %w", host, ErrURLNotAllowed)
+			}
+			return nil
+		},
+	}
+	transport.DialContext = dialer.DialContext
+	if opts.Fetch.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &http.Client{
+		Timeout:   opts.FetchTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			id, _ := req.Context().Value(jobIDKey{}).(string)
+			from := via[len(via)-1].URL.Redacted()
+			if len(via) > opts.Fetch.MaxRedirects {
+				log.WarnContext(req.Context(), "import redirected too many times", slog.String("id", id), slog.String("from", from), slog.String("to", req.URL.Redacted()))
+				return fmt.Errorf("stopped after %d redirects", opts.Fetch.MaxRedirects)
+			}
+			if !opts.Fetch.Allowed(req.URL) {
+				log.WarnContext(req.Context(), "import redirected to a url that is not allowed", slog.String("id", id), slog.String("from", from), slog.String("to", req.URL.Redacted()))
+				return fmt.Errorf("redirect to %s: %w", req.URL.Redacted(), ErrURLNotAllowed)
+			}
+			log.InfoContext(req.Context(), "import redirected", slog.String("id", id), slog.String("from", from), slog.String("to", req.URL.Redacted()))
+			return nil
+		},
+	}
+}
+
+// fetch returns the body the job's URL serves, checked against the fetch
+// options and cut off at MaxBytes.
+func (m *Manager) fetch(ctx context.Context, j *job) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(context.WithValue(ctx, jobIDKey{}, j.info.ID), http.MethodGet, j.info.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch import: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetch import: unexpected status %s", resp.Status)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !slices.Contains(m.opts.Fetch.ContentTypes, mediaType) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetch import: unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+	if resp.ContentLength > m.opts.MaxBytes {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetch import: %d bytes: %w", resp.ContentLength, ErrTooLarge)
+	}
+	// quoteinput.Read reports a body cut off at MaxBytes like an upload
+	// cut off by the handler.
+	return http.MaxBytesReader(nil, resp.Body, m.opts.MaxBytes), nil
+}
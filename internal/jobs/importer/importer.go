@@ -0,0 +1,589 @@
+// Package importer imports quotes in the background, for uploads too
+// large to import within one request. Jobs report their progress while
+// they run and are kept in memory until they expire.
+package importer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"quotes-service/internal/lib/fingerprint"
+	"quotes-service/internal/lib/quoteinput"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// Formats an import is read in, as JSON Lines, one quote per line.
+const (
+	FormatNative   = quoteinput.FormatNative
+	FormatQuotable = quoteinput.FormatQuotable
+)
+
+const (
+	defaultWorkers      = 1
+	defaultQueueSize    = 16
+	defaultTTL          = 24 * time.Hour
+	defaultBatchSize    = 500
+	defaultMaxBytes     = 1 << 30
+	defaultFetchTimeout = 10 * time.Minute
+	// sweepInterval is how often Run removes expired jobs.
+	sweepInterval = time.Minute
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown import format")
+	ErrQueueFull     = errors.New("import queue is full")
+	ErrTooLarge      = errors.New("import is too large")
+	ErrNotFound      = errors.New("import not found")
+	ErrFinished      = errors.New("import has already finished")
+)
+
+// Store is where imported quotes go. Stores that are also a
+// storage.Transactor get their quotes in batches, or all at once for a
+// transactional import.
+type Store interface {
+	GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error)
+	AddQuote(ctx context.Context, quote models.Quote) (int64, error)
+}
+
+// Options configures a Manager.
+type Options struct {
+	// Dir holds uploads until their job has run. Empty uses a directory
+	// under os.TempDir.
+	Dir string
+	// TTL is how long a finished job is kept.
+	TTL time.Duration
+	// Workers bounds the imports run at once, and QueueSize the jobs
+	// waiting for a worker.
+	Workers   int
+	QueueSize int
+	// BatchSize is how many quotes are stored between updates of a job's
+	// progress, each batch in a transaction of its own when the store
+	// supports them.
+	BatchSize int
+	// MaxBytes bounds an upload or a fetched body.
+	MaxBytes int64
+	// FetchTimeout bounds downloading an import from a URL, and Fetch
+	// limits where one is downloaded from.
+	FetchTimeout time.Duration
+	Fetch        FetchOptions
+}
+
+// job is a models.ImportJob with what the manager needs to run and cancel
+// it.
+type job struct {
+	info models.ImportJob
+	// file is the spooled upload, empty for an import from a URL.
+	file   string
+	cancel context.CancelFunc
+}
+
+type Manager struct {
+	log    *slog.Logger
+	store  Store
+	opts   Options
+	now    func() time.Time
+	client *http.Client
+	queue  chan string
+
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+type Option func(*Manager)
+
+// WithClock overrides the time source, mainly for tests.
+func WithClock(now func() time.Time) Option {
+	return func(m *Manager) {
+		m.now = now
+	}
+}
+
+// WithHTTPClient overrides the client imports are fetched with. Its
+// timeout and redirect policy are left as they are; only the first URL is
+// checked against the fetch options.
+func WithHTTPClient(client *http.Client) Option {
+	return func(m *Manager) {
+		m.client = client
+	}
+}
+
+func New(log *slog.Logger, store Store, opts Options, options ...Option) *Manager {
+	if opts.Dir == "" {
+		opts.Dir = filepath.Join(os.TempDir(), "quotes-imports")
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = defaultTTL
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = defaultWorkers
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultQueueSize
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultMaxBytes
+	}
+	if opts.FetchTimeout <= 0 {
+		opts.FetchTimeout = defaultFetchTimeout
+	}
+	if len(opts.Fetch.Schemes) == 0 {
+		opts.Fetch.Schemes = DefaultSchemes
+	}
+	if len(opts.Fetch.ContentTypes) == 0 {
+		opts.Fetch.ContentTypes = DefaultContentTypes
+	}
+	opts.Fetch.MaxRedirects = max(0, opts.Fetch.MaxRedirects)
+	log = log.With(slog.String("op", "importer.Manager"))
+	m := &Manager{
+		log:    log,
+		store:  store,
+		opts:   opts,
+		now:    time.Now,
+		client: newClient(log, opts),
+		queue:  make(chan string, opts.QueueSize),
+		jobs:   make(map[string]*job),
+	}
+	for _, opt := range options {
+		opt(m)
+	}
+	return m
+}
+
+// Run imports queued jobs on Workers goroutines and removes expired jobs
+// until ctx is done. Imports still running then are canceled, and so are
+// the jobs left in the queue.
+func (m *Manager) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range m.opts.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.work(ctx)
+		}()
+	}
+
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			m.cancelQueued()
+			return
+		case <-ticker.C:
+			m.Expire(ctx)
+		}
+	}
+}
+
+// Submit queues an import in format, which is FormatNative if empty, of
+// body or, when body is nil, of what url serves. A body is copied to Dir
+// before Submit returns, so the caller may close it. It returns
+// ErrQueueFull rather than wait when QueueSize jobs are already waiting,
+// ErrTooLarge for a body over MaxBytes and ErrURLNotAllowed for a URL the
+// fetch options do not allow.
+func (m *Manager) Submit(req models.ImportRequest, body io.Reader) (models.ImportJob, error) {
+	switch req.Format {
+	case "":
+		req.Format = FormatNative
+	case FormatNative, FormatQuotable:
+	default:
+		return models.ImportJob{}, ErrUnknownFormat
+	}
+	if body == nil {
+		u, err := url.Parse(req.URL)
+		if err != nil || !m.opts.Fetch.Allowed(u) {
+			return models.ImportJob{}, ErrURLNotAllowed
+		}
+	}
+	if len(m.queue) == cap(m.queue) {
+		return models.ImportJob{}, ErrQueueFull
+	}
+
+	j := &job{
+		info: models.ImportJob{
+			ID:            newID(),
+			Status:        models.JobQueued,
+			Transactional: req.Transactional,
+			ImportReport:  models.ImportReport{Format: req.Format},
+			CreatedAt:     m.now().UTC(),
+		},
+	}
+	if body != nil {
+		file, err := m.spool(j.info.ID, body)
+		if err != nil {
+			return models.ImportJob{}, err
+		}
+		j.file = file
+	} else {
+		j.info.URL = req.URL
+	}
+
+	m.mu.Lock()
+	select {
+	case m.queue <- j.info.ID:
+	default:
+		m.mu.Unlock()
+		m.removeFile(j)
+		return models.ImportJob{}, ErrQueueFull
+	}
+	m.jobs[j.info.ID] = j
+	info := j.info
+	m.mu.Unlock()
+	return info, nil
+}
+
+// spool copies body to a file in Dir.
+func (m *Manager) spool(id string, body io.Reader) (string, error) {
+	if err := os.MkdirAll(m.opts.Dir, 0o750); err != nil {
+		return "", fmt.Errorf("create %s: %w", m.opts.Dir, err)
+	}
+	path := filepath.Join(m.opts.Dir, id+".jsonl")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", err
+	}
+	n, err := io.Copy(f, io.LimitReader(body, m.opts.MaxBytes+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n > m.opts.MaxBytes {
+		err = ErrTooLarge
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// Get returns the job with id.
+func (m *Manager) Get(id string) (models.ImportJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return models.ImportJob{}, ErrNotFound
+	}
+	return j.info, nil
+}
+
+// Cancel stops the job with id. A queued job is canceled at once; a
+// running one stops at its next batch, so the job returned may still be
+// running. It returns ErrFinished for a job that has already ended.
+func (m *Manager) Cancel(id string) (models.ImportJob, error) {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	if !ok {
+		m.mu.Unlock()
+		return models.ImportJob{}, ErrNotFound
+	}
+	switch j.info.Status {
+	case models.JobQueued:
+		m.end(j, models.JobCanceled, "canceled")
+		info := j.info
+		m.mu.Unlock()
+		m.removeFile(j)
+		return info, nil
+	case models.JobRunning:
+		j.cancel()
+		info := j.info
+		m.mu.Unlock()
+		return info, nil
+	default:
+		info := j.info
+		m.mu.Unlock()
+		return info, ErrFinished
+	}
+}
+
+// Expire removes the finished jobs past their TTL. Run calls it every
+// minute.
+func (m *Manager) Expire(ctx context.Context) {
+	now := m.now()
+	expired := 0
+	m.mu.Lock()
+	for id, j := range m.jobs {
+		if j.info.ExpiresAt != nil && !now.Before(*j.info.ExpiresAt) {
+			delete(m.jobs, id)
+			expired++
+		}
+	}
+	m.mu.Unlock()
+	if expired > 0 {
+		m.log.InfoContext(ctx, "expired imports removed", slog.Int("count", expired))
+	}
+}
+
+func (m *Manager) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-m.queue:
+			m.run(ctx, id)
+		}
+	}
+}
+
+// run imports the job with id. Failures are recorded in the job rather
+// than returned.
+func (m *Manager) run(ctx context.Context, id string) {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	if !ok || j.info.Status != models.JobQueued {
+		m.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	started := m.now().UTC()
+	j.info.Status = models.JobRunning
+	j.info.StartedAt = &started
+	j.cancel = cancel
+	m.mu.Unlock()
+
+	var err error
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			err = fmt.Errorf("panic: %v", rvr)
+		}
+		m.finish(ctx, j, err)
+	}()
+	err = m.importQuotes(ctx, j)
+}
+
+// finish records how the job ended, starts its TTL and removes its upload.
+func (m *Manager) finish(ctx context.Context, j *job, err error) {
+	m.mu.Lock()
+	switch {
+	case err == nil:
+		m.end(j, models.JobDone, "")
+	case ctx.Err() != nil:
+		m.end(j, models.JobCanceled, err.Error())
+	default:
+		m.end(j, models.JobFailed, err.Error())
+	}
+	info := j.info
+	m.mu.Unlock()
+	m.removeFile(j)
+
+	attrs := []slog.Attr{
+		slog.String("id", info.ID),
+		slog.String("status", info.Status),
+		slog.String("format", info.Format),
+		slog.Bool("transactional", info.Transactional),
+		slog.Int("lines", info.Lines),
+		slog.Int("imported", info.Imported),
+		slog.Int("duplicates", info.Duplicates),
+		slog.Int("skipped", info.Skipped),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		m.log.LogAttrs(ctx, slog.LevelWarn, "import failed", attrs...)
+		return
+	}
+	m.log.LogAttrs(ctx, slog.LevelInfo, "import finished", attrs...)
+}
+
+// end moves j to a final status. The caller holds m.mu.
+func (m *Manager) end(j *job, status, reason string) {
+	finished := m.now().UTC()
+	expires := finished.Add(m.opts.TTL)
+	j.info.Status = status
+	j.info.Error = reason
+	j.info.FinishedAt = &finished
+	j.info.ExpiresAt = &expires
+}
+
+func (m *Manager) removeFile(j *job) {
+	if j.file == "" {
+		return
+	}
+	if err := os.Remove(j.file); err != nil && !errors.Is(err, os.ErrNotExist) {
+		m.log.Warn("failed to remove import upload", slog.String("id", j.info.ID), slog.String("error", err.Error()))
+	}
+}
+
+// importQuotes reads the job's input and stores the quotes it finds,
+// keeping the job's progress up to date.
+func (m *Manager) importQuotes(ctx context.Context, j *job) error {
+	src, err := m.open(ctx, j)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	existing, err := m.store.GetAllQuotes(ctx, storage.QuoteFilter{})
+	if err != nil {
+		return fmt.Errorf("load quotes: %w", err)
+	}
+	index := make(fingerprint.Index, len(existing))
+	for _, q := range existing {
+		index.Add(q.Text, q.Author)
+	}
+
+	report := models.ImportReport{Format: j.info.Format}
+	pending := quoteinput.Read(src, j.info.Format, index, &report)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.publish(j, report, 0)
+	m.mu.Lock()
+	j.info.Total = len(pending)
+	m.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+	if j.info.Transactional {
+		return m.storeAll(ctx, j, pending, report)
+	}
+	return m.storeBatches(ctx, j, pending, report)
+}
+
+// open returns the job's upload, or the body of its URL.
+func (m *Manager) open(ctx context.Context, j *job) (io.ReadCloser, error) {
+	if j.file != "" {
+		return os.Open(j.file)
+	}
+	return m.fetch(ctx, j)
+}
+
+// storeAll stores every quote in one transaction, so a failure or a
+// cancellation keeps none of them.
+func (m *Manager) storeAll(ctx context.Context, j *job, pending []quoteinput.Line, report models.ImportReport) error {
+	transactor, ok := m.store.(storage.Transactor)
+	if !ok {
+		return fmt.Errorf("transactional import: %w", storage.ErrTxUnsupported)
+	}
+	err := transactor.WithinTx(ctx, func(tx storage.QuoteStore) error {
+		for start := 0; start < len(pending); start += m.opts.BatchSize {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			end := min(start+m.opts.BatchSize, len(pending))
+			for _, p := range pending[start:end] {
+				if _, err := tx.AddQuote(ctx, p.Quote); err != nil {
+					return fmt.Errorf("line %d: %w", p.Number, err)
+				}
+			}
+			// Imported stays 0 until the transaction commits.
+			m.publish(j, report, end)
+		}
+		return nil
+	})
+	if errors.Is(err, storage.ErrTxUnsupported) {
+		return fmt.Errorf("transactional import: %w", err)
+	}
+	if err != nil {
+		m.mu.Lock()
+		j.info.RolledBack = true
+		m.mu.Unlock()
+		return err
+	}
+	report.Atomic = true
+	report.Imported = len(pending)
+	m.publish(j, report, len(pending))
+	return nil
+}
+
+// storeBatches stores the quotes BatchSize at a time. Each batch goes in
+// a transaction when the store supports them, and quote by quote
+// otherwise or when the transaction fails, skipping the quotes that fail.
+// Batches stored before a cancellation are kept.
+func (m *Manager) storeBatches(ctx context.Context, j *job, pending []quoteinput.Line, report models.ImportReport) error {
+	transactor, useTx := m.store.(storage.Transactor)
+	for start := 0; start < len(pending); start += m.opts.BatchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch := pending[start:min(start+m.opts.BatchSize, len(pending))]
+
+		if useTx {
+			err := transactor.WithinTx(ctx, func(tx storage.QuoteStore) error {
+				for _, p := range batch {
+					if _, err := tx.AddQuote(ctx, p.Quote); err != nil {
+						return fmt.Errorf("line %d: %w", p.Number, err)
+					}
+				}
+				return nil
+			})
+			switch {
+			case err == nil:
+				report.Imported += len(batch)
+				m.publish(j, report, start+len(batch))
+				continue
+			case ctx.Err() != nil:
+				return ctx.Err()
+			case errors.Is(err, storage.ErrTxUnsupported):
+				useTx = false
+			default:
+				m.log.WarnContext(ctx, "failed to store batch, retrying quote by quote", slog.String("id", j.info.ID), slog.String("error", err.Error()))
+			}
+		}
+
+		for _, p := range batch {
+			if _, err := m.store.AddQuote(ctx, p.Quote); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				m.log.WarnContext(ctx, "failed to add imported quote", slog.String("id", j.info.ID), slog.Int("line", p.Number), slog.String("error", err.Error()))
+				quoteinput.Skip(&report, p.Number, "failed to store quote")
+				continue
+			}
+			report.Imported++
+		}
+		m.publish(j, report, start+len(batch))
+	}
+	return nil
+}
+
+// publish copies report and progress into the job for Get to return.
+func (m *Manager) publish(j *job, report models.ImportReport, processed int) {
+	report.Errors = slices.Clone(report.Errors)
+	report.UnknownFields = maps.Clone(report.UnknownFields)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j.info.ImportReport = report
+	j.info.Processed = processed
+}
+
+// cancelQueued cancels the jobs no worker picked up before Run stopped.
+func (m *Manager) cancelQueued() {
+	var canceled []*job
+	m.mu.Lock()
+	for _, j := range m.jobs {
+		if j.info.Status == models.JobQueued {
+			m.end(j, models.JobCanceled, "canceled by shutdown")
+			canceled = append(canceled, j)
+		}
+	}
+	m.mu.Unlock()
+	for _, j := range canceled {
+		m.removeFile(j)
+	}
+}
+
+func newID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
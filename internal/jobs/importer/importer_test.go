@@ -0,0 +1,446 @@
+package importer_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"quotes-service/internal/jobs/importer"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+// gatedStore holds every add after the first limit until its context is
+// canceled, so a test can cancel an import halfway through.
+type gatedStore struct {
+	*memorystorage.Storage
+	limit   int
+	reached chan struct{}
+
+	mu    sync.Mutex
+	added int
+}
+
+func (s *gatedStore) AddQuote(ctx context.Context, quote models.Quote) (int64, error) {
+	if err := s.gate(ctx); err != nil {
+		return 0, err
+	}
+	return s.Storage.AddQuote(ctx, quote)
+}
+
+func (s *gatedStore) WithinTx(ctx context.Context, fn func(tx storage.QuoteStore) error) error {
+	return s.Storage.WithinTx(ctx, func(tx storage.QuoteStore) error {
+		return fn(&gatedTx{QuoteStore: tx, store: s})
+	})
+}
+
+func (s *gatedStore) gate(ctx context.Context) error {
+	s.mu.Lock()
+	s.added++
+	if s.added <= s.limit {
+		s.mu.Unlock()
+		return nil
+	}
+	if s.added == s.limit+1 {
+		close(s.reached)
+	}
+	s.mu.Unlock()
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+type gatedTx struct {
+	storage.QuoteStore
+	store *gatedStore
+}
+
+func (tx *gatedTx) AddQuote(ctx context.Context, quote models.Quote) (int64, error) {
+	if err := tx.store.gate(ctx); err != nil {
+		return 0, err
+	}
+	return tx.QuoteStore.AddQuote(ctx, quote)
+}
+
+// plainStore hides the transactions of the store it wraps.
+type plainStore struct {
+	store *memorystorage.Storage
+}
+
+func (s plainStore) GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error) {
+	return s.store.GetAllQuotes(ctx, filter)
+}
+
+func (s plainStore) AddQuote(ctx context.Context, quote models.Quote) (int64, error) {
+	return s.store.AddQuote(ctx, quote)
+}
+
+func newStore(t *testing.T) *memorystorage.Storage {
+	t.Helper()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddQuote(context.Background(), models.Quote{Text: "Quote 0", Author: "Author", Lang: "en"}); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// lines returns n quotes as native JSON Lines, the first of which the
+// store from newStore already holds.
+func lines(n int) string {
+	var b strings.Builder
+	for i := range n {
+		fmt.Fprintf(&b, `{"text":"Quote %d","author":"Author","lang":"en"}`+"\n", i)
+	}
+	return b.String()
+}
+
+func count(t *testing.T, store *memorystorage.Storage) int {
+	t.Helper()
+	quotes, err := store.GetAllQuotes(context.Background(), storage.QuoteFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(quotes)
+}
+
+// start runs m until the test ends.
+func start(t *testing.T, m *importer.Manager) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// wait polls the job until it has finished.
+func wait(t *testing.T, m *importer.Manager, id string) models.ImportJob {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatalf("failed to get job %s: %v", id, err)
+		}
+		if job.Status != models.JobQueued && job.Status != models.JobRunning {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return models.ImportJob{}
+}
+
+func TestManagerImport(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	body := lines(3000) + "not json\n" + `{"text":"","author":"Nobody"}` + "\n"
+
+	tests := []struct {
+		name          string
+		store         func(*memorystorage.Storage) importer.Store
+		transactional bool
+		expectedAtom  bool
+	}{
+		{name: "batches", store: func(s *memorystorage.Storage) importer.Store { return s }},
+		{name: "transactional", store: func(s *memorystorage.Storage) importer.Store { return s }, transactional: true, expectedAtom: true},
+		{name: "without transactions", store: func(s *memorystorage.Storage) importer.Store { return plainStore{s} }},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newStore(t)
+			dir := t.TempDir()
+			m := importer.New(logger, tc.store(store), importer.Options{Dir: dir, BatchSize: 250})
+			start(t, m)
+
+			job, err := m.Submit(models.ImportRequest{Transactional: tc.transactional}, strings.NewReader(body))
+			if err != nil {
+				t.Fatalf("failed to submit: %v", err)
+			}
+			if job.Status != models.JobQueued || job.Format != importer.FormatNative {
+				t.Fatalf("unexpected submitted job %+v", job)
+			}
+
+			job = wait(t, m, job.ID)
+			if job.Status != models.JobDone || job.Error != "" {
+				t.Fatalf("unexpected finished job %+v", job)
+			}
+			if job.Lines != 3002 || job.Duplicates != 1 || job.Skipped != 2 || len(job.Errors) != 2 {
+				t.Fatalf("unexpected report %+v", job.ImportReport)
+			}
+			if job.Total != 2999 || job.Processed != 2999 || job.Imported != 2999 || job.Atomic != tc.expectedAtom {
+				t.Fatalf("unexpected progress %+v", job)
+			}
+			if got := count(t, store); got != 3000 {
+				t.Fatalf("expected 3000 quotes stored, got %d", got)
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+				t.Fatalf("expected the upload to be removed, found %d files", len(entries))
+			}
+		})
+	}
+}
+
+func TestManagerImportURL(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/quotes.jsonl", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"content":"From a URL","author":"Author","tags":["web"]}`+"\n")
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/quotes.jsonl", http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/elsewhere", func(w http.ResponseWriter, r *http.Request) {
+		// localhost is the same server under a host that is not allowed.
+		http.Redirect(w, r, strings.Replace("http://"+r.Host+"/quotes.jsonl", "127.0.0.1", "localhost", 1), http.StatusFound)
+	})
+	mux.HandleFunc("/large.jsonl", func(w http.ResponseWriter, r *http.Request) {
+		body := lines(100)
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		io.WriteString(w, body)
+	})
+	mux.HandleFunc("/streamed.jsonl", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		// Flushing leaves the length unannounced, so only the read stops.
+		io.WriteString(w, lines(3))
+		w.(http.Flusher).Flush()
+		io.WriteString(w, lines(100))
+	})
+	mux.HandleFunc("/page.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<html></html>")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name             string
+		path             string
+		format           string
+		maxRedirects     int
+		expectedStatus   string
+		expectedImported int
+		expectedError    string
+	}{
+		{name: "import", path: "/quotes.jsonl", format: importer.FormatQuotable, expectedStatus: models.JobDone, expectedImported: 1},
+		{name: "redirect followed", path: "/moved", format: importer.FormatQuotable, maxRedirects: 2, expectedStatus: models.JobDone, expectedImported: 1},
+		{name: "no redirects", path: "/moved", format: importer.FormatQuotable, expectedStatus: models.JobFailed, expectedError: "stopped after 0 redirects"},
+		{name: "too many redirects", path: "/loop", maxRedirects: 2, expectedStatus: models.JobFailed, expectedError: "stopped after 2 redirects"},
+		{name: "redirect to a host not allowed", path: "/elsewhere", maxRedirects: 2, expectedStatus: models.JobFailed, expectedError: importer.ErrURLNotAllowed.Error()},
+		{name: "missing", path: "/missing", expectedStatus: models.JobFailed, expectedError: "404"},
+		{name: "announced over the limit", path: "/large.jsonl", expectedStatus: models.JobFailed, expectedError: importer.ErrTooLarge.Error()},
+		{name: "streamed over the limit", path: "/streamed.jsonl", expectedStatus: models.JobDone, expectedImported: 2},
+		{name: "unexpected content type", path: "/page.html", expectedStatus: models.JobFailed, expectedError: "text/html"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newStore(t)
+			m := importer.New(logger, store, importer.Options{
+				Dir:      t.TempDir(),
+				MaxBytes: int64(len(lines(4))),
+				Fetch: importer.FetchOptions{
+					Schemes:      []string{"http"},
+					Hosts:        []string{"127.0.0.1"},
+					MaxRedirects: tc.maxRedirects,
+				},
+			})
+			start(t, m)
+
+			job, err := m.Submit(models.ImportRequest{URL: server.URL + tc.path, Format: tc.format}, nil)
+			if err != nil {
+				t.Fatalf("failed to submit: %v", err)
+			}
+			job = wait(t, m, job.ID)
+			if job.Status != tc.expectedStatus || job.Imported != tc.expectedImported || !strings.Contains(job.Error, tc.expectedError) {
+				t.Fatalf("unexpected finished job %+v", job)
+			}
+			if got := count(t, store); got != 1+tc.expectedImported {
+				t.Fatalf("expected %d quotes stored, got %d", 1+tc.expectedImported, got)
+			}
+		})
+	}
+}
+
+func TestManagerFetchPrivateAddress(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, lines(1))
+	}))
+	defer server.Close()
+	// localhost is allowed by name, but resolves to a loopback address
+	// the hosts do not name.
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Host = "localhost:" + u.Port()
+
+	store := newStore(t)
+	m := importer.New(logger, store, importer.Options{
+		Dir:   t.TempDir(),
+		Fetch: importer.FetchOptions{Schemes: []string{"http"}, Hosts: []string{"localhost"}},
+	})
+	start(t, m)
+
+	job, err := m.Submit(models.ImportRequest{URL: u.String() + "/quotes.jsonl"}, nil)
+	if err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	job = wait(t, m, job.ID)
+	if job.Status != models.JobFailed || !strings.Contains(job.Error, importer.ErrURLNotAllowed.Error()) {
+		t.Fatalf("expected the fetch refused, got %+v", job)
+	}
+	if got := count(t, store); got != 1 {
+		t.Fatalf("expected nothing imported, got %d quotes", got)
+	}
+}
+
+func TestManagerSubmitURL(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	m := importer.New(logger, newStore(t), importer.Options{
+		Dir:   t.TempDir(),
+		Fetch: importer.FetchOptions{Hosts: []string{"quotes.example.com", "*.data.example.com"}},
+	})
+
+	tests := []struct {
+		name        string
+		url         string
+		expectedErr error
+	}{
+		{name: "allowed host", url: "https://quotes.example.com/dump.jsonl"},
+		{name: "host with a port", url: "https://quotes.example.com:8443/dump.jsonl"},
+		{name: "host in another case", url: "https://Quotes.Example.COM/dump.jsonl"},
+		{name: "subdomain", url: "https://dumps.data.example.com/dump.jsonl"},
+		{name: "wildcard does not match its own domain", url: "https://data.example.com/dump.jsonl", expectedErr: importer.ErrURLNotAllowed},
+		{name: "other host", url: "https://169.254.169.254/latest/meta-data", expectedErr: importer.ErrURLNotAllowed},
+		{name: "lookalike host", url: "https://quotes.example.com.evil.test/dump.jsonl", expectedErr: importer.ErrURLNotAllowed},
+		{name: "scheme not allowed", url: "http://quotes.example.com/dump.jsonl", expectedErr: importer.ErrURLNotAllowed},
+		{name: "not a url", url: "://", expectedErr: importer.ErrURLNotAllowed},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := m.Submit(models.ImportRequest{URL: tc.url}, nil)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestManagerCancel(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name            string
+		transactional   bool
+		expectedStored  int
+		expectedRolled  bool
+		expectedImports int
+	}{
+		{name: "keeps stored batches", expectedStored: 201, expectedImports: 200},
+		{name: "rolls back a transactional import", transactional: true, expectedStored: 1, expectedRolled: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &gatedStore{Storage: newStore(t), limit: 250, reached: make(chan struct{})}
+			m := importer.New(logger, store, importer.Options{Dir: t.TempDir(), BatchSize: 100})
+			start(t, m)
+
+			job, err := m.Submit(models.ImportRequest{Transactional: tc.transactional}, strings.NewReader(lines(1000)))
+			if err != nil {
+				t.Fatalf("failed to submit: %v", err)
+			}
+			<-store.reached
+			if _, err := m.Cancel(job.ID); err != nil {
+				t.Fatalf("failed to cancel: %v", err)
+			}
+
+			job = wait(t, m, job.ID)
+			if job.Status != models.JobCanceled || job.RolledBack != tc.expectedRolled || job.Imported != tc.expectedImports {
+				t.Fatalf("unexpected canceled job %+v", job)
+			}
+			if got := count(t, store.Storage); got != tc.expectedStored {
+				t.Fatalf("expected %d quotes stored, got %d", tc.expectedStored, got)
+			}
+			if _, err := m.Cancel(job.ID); !errors.Is(err, importer.ErrFinished) {
+				t.Fatalf("expected ErrFinished, got %v", err)
+			}
+		})
+	}
+}
+
+func TestManagerTransactionalWithoutTransactions(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := newStore(t)
+	m := importer.New(logger, plainStore{store}, importer.Options{Dir: t.TempDir()})
+	start(t, m)
+
+	job, err := m.Submit(models.ImportRequest{Transactional: true}, strings.NewReader(lines(10)))
+	if err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	job = wait(t, m, job.ID)
+	if job.Status != models.JobFailed || !strings.Contains(job.Error, storage.ErrTxUnsupported.Error()) {
+		t.Fatalf("expected the job to fail, got %+v", job)
+	}
+	if got := count(t, store); got != 1 {
+		t.Fatalf("expected nothing stored, got %d quotes", got)
+	}
+}
+
+func TestManagerSubmit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+	// Without Run nothing leaves the queue.
+	m := importer.New(logger, newStore(t), importer.Options{Dir: dir, QueueSize: 1, MaxBytes: 100})
+
+	if _, err := m.Submit(models.ImportRequest{Format: "csv"}, strings.NewReader("")); !errors.Is(err, importer.ErrUnknownFormat) {
+		t.Fatalf("expected ErrUnknownFormat, got %v", err)
+	}
+	if _, err := m.Submit(models.ImportRequest{}, strings.NewReader(lines(10))); !errors.Is(err, importer.ErrTooLarge) {
+		t.Fatalf("expected ErrTooLarge, got %v", err)
+	}
+	job, err := m.Submit(models.ImportRequest{}, strings.NewReader(lines(1)))
+	if err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	if _, err := m.Submit(models.ImportRequest{}, strings.NewReader(lines(1))); !errors.Is(err, importer.ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+
+	canceled, err := m.Cancel(job.ID)
+	if err != nil || canceled.Status != models.JobCanceled {
+		t.Fatalf("expected the queued job to be canceled, got %+v, %v", canceled, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("expected the uploads to be removed, found %d files", len(entries))
+	}
+	if _, err := m.Cancel("missing"); !errors.Is(err, importer.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,411 @@
+// Package publisher sends a quote to the webhook endpoints whenever a cron
+// schedule fires.
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"quotes-service/internal/lib/rotation"
+	"quotes-service/internal/lib/schedule"
+	"quotes-service/internal/lib/usertext"
+	"quotes-service/internal/lib/webhook"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// EventType is the webhook event published on every tick.
+const EventType = "quote.scheduled"
+
+// How the published quote is picked.
+const (
+	// ModeRandom picks a weighted random quote.
+	ModeRandom = "random"
+	// ModeDaily picks the same quote for a whole calendar day in the
+	// schedule's time zone, taking turns as package rotation does.
+	ModeDaily = "daily"
+	// ModeCollection picks a random quote from the named collection.
+	ModeCollection = "collection"
+)
+
+// maxBackfillSteps bounds the walk over missed ticks, so a minutely
+// schedule after a long downtime cannot stall startup.
+const maxBackfillSteps = 100_000
+
+// maxDailyPicks is how many quotes of the day are kept, a year's worth,
+// the most GET /quotes/daily/history lists. Older ones only add to the
+// featured counts.
+const maxDailyPicks = 366
+
+// ErrCollectionNotFound is returned in collection mode when no collection
+// has the configured name.
+var ErrCollectionNotFound = errors.New("collection not found")
+
+type Store interface {
+	GetRandomQuote(ctx context.Context, opts storage.RandomOptions) (models.Quote, error)
+	GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error)
+	GetCollections(ctx context.Context) ([]models.Collection, error)
+	GetRandomCollectionQuote(ctx context.Context, id int64) (models.Quote, error)
+	IncrementServed(ctx context.Context, id int64) error
+}
+
+type Dispatcher interface {
+	Dispatch(ctx context.Context, event webhook.Event) error
+}
+
+// Options configures a Publisher.
+type Options struct {
+	Mode       string
+	Collection string
+	// Backfill publishes once for the latest tick missed while the service
+	// was down. It needs StateFile to know when the last tick was.
+	Backfill bool
+	// StateFile keeps the time of the last published tick, and the quotes
+	// of the day in daily mode, across restarts. Empty keeps them in
+	// memory only.
+	StateFile string
+	// Window is how many days a quote of the day sits out before it may
+	// be picked again.
+	Window int
+}
+
+type Publisher struct {
+	log        *slog.Logger
+	schedule   *schedule.Schedule
+	store      Store
+	dispatcher Dispatcher
+	opts       Options
+	now        func() time.Time
+
+	mu        sync.Mutex
+	lastFired time.Time
+	lastErr   error
+	// picks are the quotes of the day, oldest first, one per date.
+	picks []rotation.Pick
+	// featured counts, per quote, the picks trimmed from picks.
+	featured map[int64]int
+}
+
+type Option func(*Publisher)
+
+// WithClock overrides the time source, mainly for tests.
+func WithClock(now func() time.Time) Option {
+	return func(p *Publisher) {
+		p.now = now
+	}
+}
+
+func New(log *slog.Logger, sched *schedule.Schedule, store Store, dispatcher Dispatcher, opts Options, options ...Option) *Publisher {
+	p := &Publisher{
+		log:        log.With(slog.String("op", "publisher.Publisher")),
+		schedule:   sched,
+		store:      store,
+		dispatcher: dispatcher,
+		opts:       opts,
+		now:        time.Now,
+	}
+	for _, opt := range options {
+		opt(p)
+	}
+	return p
+}
+
+// Run publishes on every tick until ctx is done. Ticks missed while the
+// process was not running, or was suspended, are skipped unless Backfill
+// is set, in which case only the latest missed one is published.
+func (p *Publisher) Run(ctx context.Context) {
+	st, err := p.loadState()
+	if err != nil {
+		p.log.WarnContext(ctx, "failed to read publisher state", slog.String("error", err.Error()))
+	}
+	last := st.LastFiredAt
+	p.mu.Lock()
+	p.lastFired = last
+	p.picks = st.DailyPicks
+	p.featured = st.Featured
+	p.trim()
+	p.mu.Unlock()
+
+	if p.opts.Backfill && !last.IsZero() {
+		if missed := p.latestMissed(last, p.now()); !missed.IsZero() {
+			p.log.InfoContext(ctx, "publishing missed tick", slog.Time("scheduled_for", missed))
+			_ = p.Publish(ctx, missed)
+		}
+	}
+
+	for {
+		next := p.schedule.Next(p.now())
+		if next.IsZero() {
+			p.log.WarnContext(ctx, "schedule never fires", slog.String("cron", p.schedule.String()))
+			return
+		}
+
+		timer := time.NewTimer(next.Sub(p.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		_ = p.Publish(ctx, next)
+	}
+}
+
+// Publish picks a quote and dispatches it for the tick at scheduledFor.
+func (p *Publisher) Publish(ctx context.Context, scheduledFor time.Time) error {
+	err := p.publish(ctx, scheduledFor)
+
+	p.mu.Lock()
+	p.lastErr = err
+	if err == nil {
+		p.lastFired = scheduledFor
+	}
+	st := state{LastFiredAt: p.lastFired, DailyPicks: slices.Clone(p.picks), Featured: maps.Clone(p.featured)}
+	p.mu.Unlock()
+
+	if err != nil {
+		p.log.ErrorContext(ctx, "failed to publish scheduled quote", slog.Time("scheduled_for", scheduledFor), slog.String("error", err.Error()))
+		return err
+	}
+	if err := p.saveState(st); err != nil {
+		p.log.WarnContext(ctx, "failed to save publisher state", slog.String("error", err.Error()))
+	}
+	return nil
+}
+
+func (p *Publisher) publish(ctx context.Context, scheduledFor time.Time) error {
+	quote, err := p.pick(ctx, scheduledFor)
+	if err != nil {
+		return fmt.Errorf("pick quote: %w", err)
+	}
+	quote.Text = usertext.Cut(usertext.Webhook, quote.Text)
+	quote.Author = usertext.Cut(usertext.Webhook, quote.Author)
+	event := webhook.NewEvent(EventType, models.ScheduledQuote{
+		Quote:        quote,
+		Mode:         p.opts.Mode,
+		ScheduledFor: scheduledFor,
+	})
+	if err := p.dispatcher.Dispatch(ctx, event); err != nil {
+		return err
+	}
+	if p.opts.Mode == ModeDaily {
+		p.record(rotation.Pick{Date: p.date(scheduledFor), QuoteID: quote.ID})
+		// The quote of the day counts as served, like one returned by
+		// the random and by-ID endpoints.
+		if err := p.store.IncrementServed(ctx, quote.ID); err != nil {
+			p.log.WarnContext(ctx, "failed to increment served counter", slog.Int64("id", quote.ID), slog.String("error", err.Error()))
+		}
+	}
+	return nil
+}
+
+func (p *Publisher) pick(ctx context.Context, scheduledFor time.Time) (models.Quote, error) {
+	switch p.opts.Mode {
+	case ModeDaily:
+		quotes, err := p.store.GetAllQuotes(ctx, storage.QuoteFilter{})
+		if err != nil {
+			return models.Quote{}, err
+		}
+		date := p.date(scheduledFor)
+		p.mu.Lock()
+		history := slices.Clone(p.picks)
+		featured := maps.Clone(p.featured)
+		p.mu.Unlock()
+		// A later tick of the same day features the same quote, unless it
+		// has been deleted since.
+		if i, found := slices.BinarySearchFunc(history, date, comparePickDate); found {
+			if j := slices.IndexFunc(quotes, func(q models.Quote) bool { return q.ID == history[i].QuoteID }); j >= 0 {
+				return quotes[j], nil
+			}
+		}
+		quote, ok := rotation.Choose(quotes, featured, history, date, p.opts.Window)
+		if !ok {
+			return models.Quote{}, storage.ErrQuoteNotFound
+		}
+		return quote, nil
+
+	case ModeCollection:
+		collections, err := p.store.GetCollections(ctx)
+		if err != nil {
+			return models.Quote{}, err
+		}
+		for _, c := range collections {
+			if strings.EqualFold(c.Name, p.opts.Collection) {
+				return p.store.GetRandomCollectionQuote(ctx, c.ID)
+			}
+		}
+		return models.Quote{}, fmt.Errorf("%w: %q", ErrCollectionNotFound, p.opts.Collection)
+
+	default:
+		return p.store.GetRandomQuote(ctx, storage.RandomOptions{})
+	}
+}
+
+// date is the day of t in the schedule's time zone.
+func (p *Publisher) date(t time.Time) string {
+	return t.In(p.schedule.Location()).Format(time.DateOnly)
+}
+
+// record logs pick as the quote of its day, replacing the one picked
+// before for the same day.
+func (p *Publisher) record(pick rotation.Pick) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i, found := slices.BinarySearchFunc(p.picks, pick.Date, comparePickDate)
+	if found {
+		p.picks[i] = pick
+		return
+	}
+	p.picks = slices.Insert(p.picks, i, pick)
+	p.trim()
+}
+
+// trim drops the oldest picks past maxDailyPicks, or past the window if
+// that is longer, counting them in p.featured. p.mu must be held.
+func (p *Publisher) trim() {
+	excess := len(p.picks) - max(maxDailyPicks, p.opts.Window)
+	if excess <= 0 {
+		return
+	}
+	if p.featured == nil {
+		p.featured = make(map[int64]int)
+	}
+	for _, pick := range p.picks[:excess] {
+		p.featured[pick.QuoteID]++
+	}
+	p.picks = slices.Delete(p.picks, 0, excess)
+}
+
+func comparePickDate(p rotation.Pick, date string) int {
+	return strings.Compare(p.Date, date)
+}
+
+// DailyHistory returns the last n quotes of the day, the latest first,
+// each with how many times its quote had been featured by then.
+func (p *Publisher) DailyHistory(n int) []models.DailyPick {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	counts := maps.Clone(p.featured)
+	if counts == nil {
+		counts = make(map[int64]int, len(p.picks))
+	}
+	history := make([]models.DailyPick, 0, len(p.picks))
+	for _, pick := range p.picks {
+		counts[pick.QuoteID]++
+		history = append(history, models.DailyPick{
+			Date:          pick.Date,
+			QuoteID:       pick.QuoteID,
+			FeaturedCount: counts[pick.QuoteID],
+		})
+	}
+	slices.Reverse(history)
+	return history[:min(n, len(history))]
+}
+
+// latestMissed returns the last tick after last and not after now, or the
+// zero time if none was missed.
+func (p *Publisher) latestMissed(last, now time.Time) time.Time {
+	var missed time.Time
+	t := last
+	for i := 0; i < maxBackfillSteps; i++ {
+		t = p.schedule.Next(t)
+		if t.IsZero() || t.After(now) {
+			break
+		}
+		missed = t
+	}
+	return missed
+}
+
+// Status reports the schedule with its next n fire times.
+func (p *Publisher) Status(n int) models.ScheduleStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := models.ScheduleStatus{
+		Cron:       p.schedule.String(),
+		Timezone:   p.schedule.Location().String(),
+		Mode:       p.opts.Mode,
+		Collection: p.opts.Collection,
+		Backfill:   p.opts.Backfill,
+		NextRuns:   p.schedule.NextN(p.now(), n),
+	}
+	if !p.lastFired.IsZero() {
+		last := p.lastFired
+		status.LastFiredAt = &last
+	}
+	if p.lastErr != nil {
+		status.LastError = p.lastErr.Error()
+	}
+	return status
+}
+
+type state struct {
+	LastFiredAt time.Time `json:"last_fired_at"`
+	// DailyPicks are the latest quotes of the day, which the rotation is
+	// recomputed from, together with Featured, the counts per quote of
+	// the older ones.
+	DailyPicks []rotation.Pick `json:"daily_picks,omitempty"`
+	Featured   map[int64]int   `json:"featured,omitempty"`
+}
+
+func (p *Publisher) loadState() (state, error) {
+	if p.opts.StateFile == "" {
+		return state{}, nil
+	}
+	data, err := os.ReadFile(p.opts.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return state{}, nil
+	}
+	if err != nil {
+		return state{}, err
+	}
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return state{}, fmt.Errorf("decode %s: %w", p.opts.StateFile, err)
+	}
+	// Kept sorted by date, whatever a hand-edited file holds.
+	slices.SortStableFunc(st.DailyPicks, func(a, b rotation.Pick) int {
+		return strings.Compare(a.Date, b.Date)
+	})
+	st.DailyPicks = slices.CompactFunc(st.DailyPicks, func(a, b rotation.Pick) bool {
+		return a.Date == b.Date
+	})
+	return st, nil
+}
+
+// saveState writes the state through a temporary file so a crash never
+// leaves a truncated one behind.
+func (p *Publisher) saveState(st state) error {
+	if p.opts.StateFile == "" {
+		return nil
+	}
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p.opts.StateFile), ".publisher-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p.opts.StateFile)
+}
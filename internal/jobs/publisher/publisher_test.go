@@ -0,0 +1,479 @@
+package publisher_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"quotes-service/internal/jobs/publisher"
+	"quotes-service/internal/lib/rotation"
+	"quotes-service/internal/lib/schedule"
+	"quotes-service/internal/lib/usertext"
+	"quotes-service/internal/lib/webhook"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+type MockDispatcher struct {
+	mu     sync.Mutex
+	events []webhook.Event
+	err    error
+	sent   chan struct{}
+}
+
+func (m *MockDispatcher) Dispatch(ctx context.Context, event webhook.Event) error {
+	m.mu.Lock()
+	m.events = append(m.events, event)
+	m.mu.Unlock()
+	if m.sent != nil {
+		m.sent <- struct{}{}
+	}
+	return m.err
+}
+
+func (m *MockDispatcher) Events() []webhook.Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]webhook.Event(nil), m.events...)
+}
+
+func newStore(t *testing.T) *memorystorage.Storage {
+	t.Helper()
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for i := 1; i <= 5; i++ {
+		if _, err := store.AddQuote(ctx, models.Quote{Text: fmt.Sprintf("Quote number %d", i), Author: "Author"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	collection, err := store.CreateCollection(ctx, "Monday Motivation", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddQuotesToCollection(ctx, collection.ID, []int64{4}); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func weekdaysAtNine(t *testing.T) *schedule.Schedule {
+	t.Helper()
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := schedule.Parse("0 9 * * MON-FRI", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestPublish(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sched := weekdaysAtNine(t)
+	monday := time.Date(2024, time.March, 11, 9, 0, 0, 0, sched.Location())
+
+	tests := []struct {
+		name       string
+		mode       string
+		collection string
+		wantID     int64
+		wantErr    error
+	}{
+		{name: "random", mode: publisher.ModeRandom},
+		{name: "daily", mode: publisher.ModeDaily},
+		{name: "collection", mode: publisher.ModeCollection, collection: "monday motivation", wantID: 4},
+		{name: "missing collection", mode: publisher.ModeCollection, collection: "Nope", wantErr: publisher.ErrCollectionNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dispatcher := &MockDispatcher{}
+			p := publisher.New(logger, sched, newStore(t), dispatcher, publisher.Options{Mode: tc.mode, Collection: tc.collection})
+
+			err := p.Publish(context.Background(), monday)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("expected %v, got %v", tc.wantErr, err)
+				}
+				if p.Status(1).LastError == "" {
+					t.Error("expected the failure in the status")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("publish failed: %v", err)
+			}
+
+			events := dispatcher.Events()
+			if len(events) != 1 || events[0].Type != publisher.EventType {
+				t.Fatalf("expected one %s event, got %+v", publisher.EventType, events)
+			}
+			payload := events[0].Data.(models.ScheduledQuote)
+			if payload.Quote.ID == 0 || (tc.wantID != 0 && payload.Quote.ID != tc.wantID) {
+				t.Errorf("unexpected quote %+v", payload.Quote)
+			}
+			if !payload.ScheduledFor.Equal(monday) || payload.Mode != tc.mode {
+				t.Errorf("unexpected payload %+v", payload)
+			}
+		})
+	}
+}
+
+func TestDailyModeIsStableWithinADay(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sched := weekdaysAtNine(t)
+	dispatcher := &MockDispatcher{}
+	p := publisher.New(logger, sched, newStore(t), dispatcher, publisher.Options{Mode: publisher.ModeDaily})
+
+	morning := time.Date(2024, time.March, 11, 9, 0, 0, 0, sched.Location())
+	for _, at := range []time.Time{morning, morning.Add(8 * time.Hour)} {
+		if err := p.Publish(context.Background(), at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events := dispatcher.Events()
+	first := events[0].Data.(models.ScheduledQuote).Quote.ID
+	second := events[1].Data.(models.ScheduledQuote).Quote.ID
+	if first != second {
+		t.Errorf("expected the same quote all day, got %d and %d", first, second)
+	}
+}
+
+func TestPublishCutsText(t *testing.T) {
+	defer usertext.Set(usertext.Default)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sched := weekdaysAtNine(t)
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	if _, err := store.AddQuote(context.Background(), models.Quote{Text: strings.Repeat("долгий текст ", 1000), Author: "Author"}); err != nil {
+		t.Fatal(err)
+	}
+	dispatcher := &MockDispatcher{}
+	p := publisher.New(logger, sched, store, dispatcher, publisher.Options{Mode: publisher.ModeRandom})
+
+	usertext.Set(usertext.Policy{Webhook: 12})
+	if err := p.Publish(context.Background(), time.Date(2024, time.March, 11, 9, 0, 0, 0, sched.Location())); err != nil {
+		t.Fatal(err)
+	}
+	quote := dispatcher.Events()[0].Data.(models.ScheduledQuote).Quote
+	if quote.Text != "долгий текст…" || quote.Author != "Author" {
+		t.Fatalf("expected the text cut to 12 characters, got %q by %q", quote.Text, quote.Author)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sched := weekdaysAtNine(t)
+	friday := time.Date(2024, time.March, 8, 10, 0, 0, 0, sched.Location())
+	p := publisher.New(logger, sched, newStore(t), &MockDispatcher{}, publisher.Options{Mode: publisher.ModeRandom},
+		publisher.WithClock(func() time.Time { return friday }))
+
+	status := p.Status(3)
+	if status.Cron != "0 9 * * MON-FRI" || status.Timezone != "Europe/Berlin" {
+		t.Errorf("unexpected status %+v", status)
+	}
+	want := []string{"2024-03-11T09:00:00+01:00", "2024-03-12T09:00:00+01:00", "2024-03-13T09:00:00+01:00"}
+	if len(status.NextRuns) != len(want) {
+		t.Fatalf("expected %d next runs, got %v", len(want), status.NextRuns)
+	}
+	for i, w := range want {
+		if got := status.NextRuns[i].Format(time.RFC3339); got != w {
+			t.Errorf("next run %d: expected %s, got %s", i, w, got)
+		}
+	}
+}
+
+func TestRunBackfill(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sched := weekdaysAtNine(t)
+	lastFired := time.Date(2024, time.March, 6, 9, 0, 0, 0, sched.Location())
+	// Down since Wednesday's tick; Thursday's and Friday's were missed.
+	now := time.Date(2024, time.March, 9, 12, 0, 0, 0, sched.Location())
+
+	tests := []struct {
+		name       string
+		backfill   bool
+		wantEvents int
+	}{
+		{name: "skipped by default", backfill: false, wantEvents: 0},
+		{name: "latest missed tick", backfill: true, wantEvents: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stateFile := filepath.Join(t.TempDir(), "publisher.json")
+			state := fmt.Sprintf(`{"last_fired_at":%q}`, lastFired.Format(time.RFC3339))
+			if err := os.WriteFile(stateFile, []byte(state), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			dispatcher := &MockDispatcher{sent: make(chan struct{}, 4)}
+			p := publisher.New(logger, sched, newStore(t), dispatcher,
+				publisher.Options{Mode: publisher.ModeRandom, Backfill: tc.backfill, StateFile: stateFile},
+				publisher.WithClock(func() time.Time { return now }))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				p.Run(ctx)
+				close(done)
+			}()
+
+			if tc.wantEvents > 0 {
+				select {
+				case <-dispatcher.sent:
+				case <-time.After(5 * time.Second):
+					t.Fatal("expected the missed tick to be published")
+				}
+			} else {
+				time.Sleep(50 * time.Millisecond)
+			}
+			cancel()
+			<-done
+
+			events := dispatcher.Events()
+			if len(events) != tc.wantEvents {
+				t.Fatalf("expected %d events, got %d", tc.wantEvents, len(events))
+			}
+			if tc.wantEvents == 0 {
+				return
+			}
+			friday := time.Date(2024, time.March, 8, 9, 0, 0, 0, sched.Location())
+			if got := events[0].Data.(models.ScheduledQuote).ScheduledFor; !got.Equal(friday) {
+				t.Errorf("expected Friday's tick, got %s", got)
+			}
+			saved, _ := os.ReadFile(stateFile)
+			if want := fmt.Sprintf(`{"last_fired_at":%q}`, friday.Format(time.RFC3339)); string(saved) != want {
+				t.Errorf("expected state %s, got %s", want, saved)
+			}
+		})
+	}
+}
+
+func everyDayAtNine(t *testing.T) *schedule.Schedule {
+	t.Helper()
+	s, err := schedule.Parse("0 9 * * *", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// publishDays advances clock a tick at a time for days ticks, publishing
+// each, and returns the IDs of the quotes published.
+func publishDays(t *testing.T, p *publisher.Publisher, sched *schedule.Schedule, dispatcher *MockDispatcher, clock *time.Time, days int) []int64 {
+	t.Helper()
+	var ids []int64
+	for range days {
+		*clock = sched.Next(*clock)
+		if err := p.Publish(context.Background(), *clock); err != nil {
+			t.Fatal(err)
+		}
+		events := dispatcher.Events()
+		ids = append(ids, events[len(events)-1].Data.(models.ScheduledQuote).Quote.ID)
+	}
+	return ids
+}
+
+func TestDailyRotation(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sched := everyDayAtNine(t)
+	const window = 3
+
+	tests := []struct {
+		name   string
+		quotes int
+	}{
+		{name: "single quote", quotes: 1},
+		{name: "two quotes", quotes: 2},
+		{name: "catalog smaller than the window", quotes: 3},
+		{name: "catalog larger than the window", quotes: 12},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			store, err := memorystorage.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range tc.quotes {
+				if _, err := store.AddQuote(ctx, models.Quote{Text: fmt.Sprintf("Quote %d", i), Author: "Author"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			clock := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+			dispatcher := &MockDispatcher{}
+			p := publisher.New(logger, sched, store, dispatcher, publisher.Options{Mode: publisher.ModeDaily, Window: window},
+				publisher.WithClock(func() time.Time { return clock }))
+
+			ids := publishDays(t, p, sched, dispatcher, &clock, 90)
+			// No quote comes back within the window, or as soon as the
+			// catalog allows in a smaller one.
+			gap := min(window, tc.quotes-1)
+			for i, id := range ids {
+				for _, earlier := range ids[max(0, i-gap):i] {
+					if earlier == id {
+						t.Fatalf("quote %d repeated within %d days: %v", id, gap, ids)
+					}
+				}
+			}
+
+			added, err := store.AddQuote(ctx, models.Quote{Text: "Brand new", Author: "Author"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next := publishDays(t, p, sched, dispatcher, &clock, 1); next[0] != added {
+				t.Fatalf("expected the new quote %d featured the next day, got %d", added, next[0])
+			}
+		})
+	}
+}
+
+func TestDailyRotationSurvivesRestart(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sched := everyDayAtNine(t)
+	store := newStore(t)
+	opts := publisher.Options{Mode: publisher.ModeDaily, Window: 2}
+
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clock := start
+	dispatcher := &MockDispatcher{}
+	uninterrupted := publisher.New(logger, sched, store, dispatcher, opts, publisher.WithClock(func() time.Time { return clock }))
+	want := publishDays(t, uninterrupted, sched, dispatcher, &clock, 40)
+
+	opts.StateFile = filepath.Join(t.TempDir(), "publisher.json")
+	clock = start
+	var got []int64
+	for range 4 {
+		// Run loads the state and is stopped before its first tick.
+		dispatcher := &MockDispatcher{}
+		p := publisher.New(logger, sched, store, dispatcher, opts, publisher.WithClock(func() time.Time { return clock }))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		p.Run(ctx)
+		got = append(got, publishDays(t, p, sched, dispatcher, &clock, 10)...)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected the picks of an uninterrupted run %v, got %v", want, got)
+	}
+
+	history := uninterrupted.DailyHistory(3)
+	if len(history) != 3 || history[0].Date != "2024-02-09" || history[2].Date != "2024-02-07" {
+		t.Fatalf("unexpected history %+v", history)
+	}
+	for i, pick := range history {
+		if pick.QuoteID != want[len(want)-1-i] || pick.FeaturedCount != 8 {
+			t.Errorf("unexpected pick %+v", pick)
+		}
+	}
+}
+
+func TestDailyPicksTrimmed(t *testing.T) {
+	const days = 400
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sched := everyDayAtNine(t)
+	store := newStore(t)
+	opts := publisher.Options{Mode: publisher.ModeDaily, Window: 2, StateFile: filepath.Join(t.TempDir(), "publisher.json")}
+
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clock := start
+	var p *publisher.Publisher
+	var ids []int64
+	for range days / 100 {
+		dispatcher := &MockDispatcher{}
+		p = publisher.New(logger, sched, store, dispatcher, opts, publisher.WithClock(func() time.Time { return clock }))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		p.Run(ctx)
+		ids = append(ids, publishDays(t, p, sched, dispatcher, &clock, 100)...)
+	}
+
+	data, err := os.ReadFile(opts.StateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var st struct {
+		DailyPicks []rotation.Pick `json:"daily_picks"`
+		Featured   map[int64]int   `json:"featured"`
+	}
+	if err := json.Unmarshal(data, &st); err != nil {
+		t.Fatal(err)
+	}
+	if len(st.DailyPicks) != 366 {
+		t.Fatalf("expected 366 picks kept, got %d", len(st.DailyPicks))
+	}
+	trimmed := 0
+	for _, n := range st.Featured {
+		trimmed += n
+	}
+	if trimmed != days-366 {
+		t.Fatalf("expected %d trimmed picks counted, got %v", days-366, st.Featured)
+	}
+
+	// The picks are those of the whole log, trimming changed none.
+	quotes, err := store.GetAllQuotes(context.Background(), storage.QuoteFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var history []rotation.Pick
+	counts := make(map[int64]int)
+	day := start
+	for i, id := range ids {
+		day = sched.Next(day)
+		date := day.Format(time.DateOnly)
+		if q, _ := rotation.Choose(quotes, nil, history, date, opts.Window); q.ID != id {
+			t.Fatalf("expected quote %d on %s, got %d", q.ID, date, id)
+		}
+		history = append(history, rotation.Pick{Date: date, QuoteID: id})
+		counts[id]++
+		if i == len(ids)-1 {
+			latest := p.DailyHistory(1)[0]
+			if latest.QuoteID != id || latest.FeaturedCount != counts[id] {
+				t.Fatalf("expected quote %d featured %d times, got %+v", id, counts[id], latest)
+			}
+		}
+	}
+}
+
+func TestDailyCountsServed(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sched := everyDayAtNine(t)
+	store := newStore(t)
+	clock := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	dispatcher := &MockDispatcher{}
+	p := publisher.New(logger, sched, store, dispatcher, publisher.Options{Mode: publisher.ModeDaily, Window: 2},
+		publisher.WithClock(func() time.Time { return clock }))
+
+	ids := publishDays(t, p, sched, dispatcher, &clock, 12)
+	want := make(map[int64]int64)
+	for _, id := range ids {
+		want[id]++
+	}
+	popular, err := store.GetPopularQuotes(context.Background(), len(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range popular {
+		if q.Served != want[q.ID] {
+			t.Errorf("expected quote %d served %d times, got %d", q.ID, want[q.ID], q.Served)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+// Package quotesync periodically imports quotes from an external API that
+// speaks the quotable.io format (GET ?page=N&limit=M returning
+// {"totalPages": ..., "results": [{"content": ..., "author": ...}]}).
+package quotesync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"quotes-service/internal/lib/fingerprint"
+	"quotes-service/internal/lib/quotable"
+	"quotes-service/internal/lib/quoteinput"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// Triggers recorded in a report.
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+// maxPageBytes caps the size of one page of the source's response.
+const maxPageBytes = 8 << 20
+
+type Store interface {
+	AddQuote(ctx context.Context, quote models.Quote) (int64, error)
+	GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error)
+}
+
+// Options configures a Syncer.
+type Options struct {
+	SourceURL string
+	Interval  time.Duration
+	PageSize  int
+	// MaxPages bounds a single run so a huge source cannot keep the job
+	// busy for hours.
+	MaxPages int
+	// Authors renames source authors to the names used in this service.
+	Authors map[string]string
+	// Lang is stored on imported quotes. Empty lets the store detect it.
+	Lang string
+}
+
+type Syncer struct {
+	log     *slog.Logger
+	store   Store
+	client  *http.Client
+	opts    Options
+	trigger chan struct{}
+
+	mu      sync.Mutex
+	running bool
+	last    *models.SyncReport
+}
+
+type Option func(*Syncer)
+
+// WithHTTPClient replaces the client used to reach the source.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *Syncer) {
+		s.client = client
+	}
+}
+
+func New(log *slog.Logger, store Store, opts Options, options ...Option) *Syncer {
+	s := &Syncer{
+		log:     log.With(slog.String("op", "quotesync.Syncer")),
+		store:   store,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		opts:    opts,
+		trigger: make(chan struct{}, 1),
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+// Run syncs once right away, then every Interval and whenever Trigger is
+// called, until ctx is done.
+func (s *Syncer) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+
+	s.Sync(ctx, TriggerSchedule)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Sync(ctx, TriggerSchedule)
+		case <-s.trigger:
+			s.Sync(ctx, TriggerManual)
+		}
+	}
+}
+
+// Trigger asks Run for a sync as soon as the current one, if any, is done.
+// Triggers made while one is already pending are merged into it.
+func (s *Syncer) Trigger() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Status returns the configuration and the report of the last finished run.
+func (s *Syncer) Status() models.SyncStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := models.SyncStatus{
+		SourceURL: s.opts.SourceURL,
+		Interval:  s.opts.Interval.String(),
+		Running:   s.running,
+	}
+	if s.last != nil {
+		last := *s.last
+		status.LastRun = &last
+	}
+	return status
+}
+
+// Sync runs one import and records its report. Failures, including a
+// panic, end up in the report rather than taking the service down.
+func (s *Syncer) Sync(ctx context.Context, trigger string) (report models.SyncReport) {
+	s.mu.Lock()
+	s.running = true
+	s.mu.Unlock()
+
+	report = models.SyncReport{Trigger: trigger, StartedAt: time.Now().UTC()}
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			report.Errors++
+			report.LastError = fmt.Sprintf("panic: %v", rvr)
+		}
+		report.FinishedAt = time.Now().UTC()
+
+		s.mu.Lock()
+		s.running = false
+		s.last = &report
+		s.mu.Unlock()
+
+		attrs := []slog.Attr{
+			slog.String("trigger", trigger),
+			slog.Int("fetched", report.Fetched),
+			slog.Int("inserted", report.Inserted),
+			slog.Int("skipped", report.Skipped),
+			slog.Int("errors", report.Errors),
+		}
+		if report.Errors > 0 {
+			attrs = append(attrs, slog.String("last_error", report.LastError))
+			s.log.LogAttrs(ctx, slog.LevelWarn, "quote sync finished with errors", attrs...)
+			return
+		}
+		s.log.LogAttrs(ctx, slog.LevelInfo, "quote sync finished", attrs...)
+	}()
+
+	fail := func(err error) {
+		report.Errors++
+		report.LastError = err.Error()
+	}
+
+	existing, err := s.store.GetAllQuotes(ctx, storage.QuoteFilter{})
+	if err != nil {
+		fail(fmt.Errorf("load existing quotes: %w", err))
+		return report
+	}
+	index := make(fingerprint.Index, len(existing))
+	for _, q := range existing {
+		index.Add(q.Text, q.Author)
+	}
+
+	for page := 1; page <= s.opts.MaxPages; page++ {
+		entries, totalPages, err := s.fetchPage(ctx, page)
+		if err != nil {
+			fail(fmt.Errorf("fetch page %d: %w", page, err))
+			break
+		}
+		for _, entry := range entries {
+			report.Fetched++
+			quote, ok := s.normalize(entry)
+			if !ok || !index.Add(quote.Text, quote.Author) {
+				report.Skipped++
+				continue
+			}
+			if _, err := s.store.AddQuote(ctx, quote); err != nil {
+				fail(fmt.Errorf("add quote: %w", err))
+				continue
+			}
+			report.Inserted++
+		}
+		if len(entries) == 0 || page >= totalPages {
+			break
+		}
+	}
+	return report
+}
+
+type sourcePage struct {
+	TotalPages int               `json:"totalPages"`
+	Results    []quotable.Record `json:"results"`
+}
+
+func (s *Syncer) fetchPage(ctx context.Context, page int) ([]quotable.Record, int, error) {
+	u, err := url.Parse(s.opts.SourceURL)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := u.Query()
+	query.Set("page", strconv.Itoa(page))
+	query.Set("limit", strconv.Itoa(s.opts.PageSize))
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("source returned %s", resp.Status)
+	}
+
+	var body sourcePage
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxPageBytes)).Decode(&body); err != nil {
+		return nil, 0, fmt.Errorf("decode response: %w", err)
+	}
+	return body.Results, body.TotalPages, nil
+}
+
+// normalize cleans up a source entry and applies the author mapping. It
+// reports false for entries without text or author.
+func (s *Syncer) normalize(entry quotable.Record) (models.Quote, bool) {
+	text := strings.Join(strings.Fields(entry.Content), " ")
+	text = quoteinput.Text(strings.TrimSpace(strings.Trim(text, `"“”„«»`)))
+	author := strings.Join(strings.Fields(entry.Author), " ")
+	if mapped, ok := s.opts.Authors[author]; ok {
+		author = mapped
+	}
+	author = quoteinput.ImportAuthor(author)
+	if text == "" || author == "" || quoteinput.AuthorProblem("author", author) != "" {
+		return models.Quote{}, false
+	}
+	return models.Quote{
+		Text:   text,
+		Author: author,
+		Lang:   s.opts.Lang,
+	}, true
+}
@@ -0,0 +1,194 @@
+package quotesync_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"quotes-service/internal/jobs/quotesync"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+// fixturePages mimics a quotable-compatible API with two pages.
+var fixturePages = map[string]string{
+	"1": `{"page":1,"totalPages":2,"results":[
+		{"content":"  Stay hungry,   stay foolish. ","author":"Steve Jobs","tags":["life"]},
+		{"content":"The only way out is through.","author":"Robert Frost"},
+		{"content":"","author":"Nobody"}
+	]}`,
+	"2": `{"page":2,"totalPages":2,"results":[
+		{"content":"“Imagination is more important than knowledge.”","author":"Albert Einstein"},
+		{"content":"stay hungry stay foolish","author":"steve jobs"}
+	]}`,
+}
+
+func newStore(t *testing.T) *memorystorage.Storage {
+	t.Helper()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	return store
+}
+
+func newSyncer(t *testing.T, store quotesync.Store, handler http.HandlerFunc) *quotesync.Syncer {
+	t.Helper()
+	source := httptest.NewServer(handler)
+	t.Cleanup(source.Close)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return quotesync.New(logger, store, quotesync.Options{
+		SourceURL: source.URL + "/quotes?tags=life",
+		Interval:  time.Hour,
+		PageSize:  3,
+		MaxPages:  10,
+		Authors:   map[string]string{"Robert Frost": "Robert Lee Frost"},
+		Lang:      "en",
+	}, quotesync.WithHTTPClient(source.Client()))
+}
+
+func fixtureHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("tags"); got != "life" {
+			t.Errorf("expected the source query to be kept, got tags=%q", got)
+		}
+		if got := r.URL.Query().Get("limit"); got != "3" {
+			t.Errorf("expected limit=3, got %q", got)
+		}
+		body, ok := fixturePages[r.URL.Query().Get("page")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, body)
+	}
+}
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	if _, err := store.AddQuote(ctx, models.Quote{Text: "Imagination is more important than knowledge!", Author: "Albert Einstein"}); err != nil {
+		t.Fatal(err)
+	}
+	syncer := newSyncer(t, store, fixtureHandler(t))
+
+	report := syncer.Sync(ctx, quotesync.TriggerManual)
+
+	if report.Fetched != 5 || report.Inserted != 2 || report.Skipped != 3 || report.Errors != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	quotes, err := store.GetAllQuotes(ctx, storage.QuoteFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, q := range quotes {
+		got[q.Author] = q.Text
+	}
+	want := map[string]string{
+		"Albert Einstein":  "Imagination is more important than knowledge!",
+		"Steve Jobs":       "Stay hungry, stay foolish.",
+		"Robert Lee Frost": "The only way out is through.",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected quotes %v, got %v", want, got)
+	}
+
+	// A second run finds nothing new.
+	report = syncer.Sync(ctx, quotesync.TriggerSchedule)
+	if report.Inserted != 0 || report.Skipped != 5 {
+		t.Errorf("expected every quote to be skipped on the second run, got %+v", report)
+	}
+
+	status := syncer.Status()
+	if status.Running || status.LastRun == nil || status.LastRun.Trigger != quotesync.TriggerSchedule {
+		t.Errorf("unexpected status %+v", status)
+	}
+}
+
+func TestSyncSourceFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{
+			name: "server error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "down", http.StatusBadGateway)
+			},
+		},
+		{
+			name: "invalid json",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, "<html>")
+			},
+		},
+		{
+			name: "connection dropped",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			syncer := newSyncer(t, newStore(t), tc.handler)
+
+			report := syncer.Sync(context.Background(), quotesync.TriggerManual)
+			if report.Errors != 1 || report.LastError == "" || report.Inserted != 0 {
+				t.Errorf("expected one recorded error, got %+v", report)
+			}
+			if last := syncer.Status().LastRun; last == nil || last.Errors != 1 {
+				t.Errorf("expected the failed run in the status, got %+v", last)
+			}
+		})
+	}
+}
+
+func TestRunTriggerAndStop(t *testing.T) {
+	requests := make(chan struct{}, 16)
+	handler := fixtureHandler(t)
+	syncer := newSyncer(t, newStore(t), func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "1" {
+			requests <- struct{}{}
+		}
+		handler(w, r)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		syncer.Run(ctx)
+		close(done)
+	}()
+
+	// One run at startup, one for the trigger.
+	for i := 0; i < 2; i++ {
+		if i == 1 {
+			syncer.Trigger()
+		}
+		select {
+		case <-requests:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("sync %d did not start", i+1)
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop after cancel")
+	}
+}
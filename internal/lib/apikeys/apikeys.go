@@ -0,0 +1,296 @@
+// Package apikeys keeps the API keys created at runtime through
+// /admin/keys, next to the ones in the config. The keys are saved to a
+// file holding only a SHA-256 hash of each secret; the secret itself is
+// shown once, when the key is created.
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"quotes-service/internal/lib/role"
+	"quotes-service/internal/models"
+)
+
+var ErrNotFound = errors.New("api key not found")
+
+// usageFlushInterval is how often last-used times are saved, so using a
+// key never waits for a write.
+const usageFlushInterval = time.Minute
+
+// key is a stored key: its public part and the hash of its secret.
+type key struct {
+	models.APIKey
+	Hash string `json:"hash"`
+}
+
+type file struct {
+	Keys []key `json:"keys"`
+}
+
+// Store holds the runtime keys, indexed by the hash of their secret.
+type Store struct {
+	log  *slog.Logger
+	path string
+	now  func() time.Time
+
+	// saving serializes writes of the file.
+	saving sync.Mutex
+
+	mu     sync.Mutex
+	keys   []key
+	byHash map[string]int
+	// used holds the last-used times not yet saved, by key ID.
+	used map[string]time.Time
+}
+
+type Option func(*Store)
+
+// WithClock overrides the time source, mainly for tests.
+func WithClock(now func() time.Time) Option {
+	return func(s *Store) {
+		s.now = now
+	}
+}
+
+// Open loads the keys saved at path. A missing file is an empty store,
+// which creates the file with its first key.
+func Open(log *slog.Logger, path string, options ...Option) (*Store, error) {
+	s := &Store{
+		log:  log.With(slog.String("op", "apikeys.Store"), slog.String("path", path)),
+		path: path,
+		now:  time.Now,
+		used: make(map[string]time.Time),
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	var f file
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("decode %s: %w", path, err)
+		}
+	}
+	s.keys = f.Keys
+	s.index()
+	return s, nil
+}
+
+// Create makes a key for the principal name with r, or with the role the
+// config gives name when r is empty, and returns it with its secret.
+func (s *Store) Create(name string, r role.Role) (models.CreatedAPIKey, error) {
+	secret, err := newSecret()
+	if err != nil {
+		return models.CreatedAPIKey{}, err
+	}
+	k := key{
+		APIKey: models.APIKey{
+			ID:        newID(),
+			Name:      name,
+			Role:      string(r),
+			CreatedAt: s.now().UTC(),
+		},
+		Hash: hash(secret),
+	}
+
+	s.saving.Lock()
+	defer s.saving.Unlock()
+	s.mu.Lock()
+	s.keys = append(s.keys, k)
+	s.index()
+	s.mu.Unlock()
+	if err := s.save(); err != nil {
+		s.mu.Lock()
+		s.keys = slices.DeleteFunc(s.keys, func(other key) bool { return other.ID == k.ID })
+		s.index()
+		s.mu.Unlock()
+		return models.CreatedAPIKey{}, err
+	}
+	return models.CreatedAPIKey{APIKey: k.APIKey, Secret: secret}, nil
+}
+
+// List returns the keys, oldest first, without their secrets or hashes.
+func (s *Store) List() []models.APIKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]models.APIKey, len(s.keys))
+	for i, k := range s.keys {
+		keys[i] = k.APIKey
+		if used, ok := s.used[k.ID]; ok {
+			keys[i].LastUsedAt = &used
+		}
+	}
+	return keys
+}
+
+// Revoke deletes the key with id. Requests with it are refused from then
+// on.
+func (s *Store) Revoke(id string) error {
+	s.saving.Lock()
+	defer s.saving.Unlock()
+	s.mu.Lock()
+	i := slices.IndexFunc(s.keys, func(k key) bool { return k.ID == id })
+	if i < 0 {
+		s.mu.Unlock()
+		return ErrNotFound
+	}
+	s.keys = slices.Delete(s.keys, i, i+1)
+	delete(s.used, id)
+	s.index()
+	s.mu.Unlock()
+	// The key is gone from memory even if saving fails, so a revoked key
+	// never keeps working; it would come back only with a restart.
+	return s.save()
+}
+
+// Lookup returns the principal and role of the key with secret, and
+// records its use for the next flush.
+func (s *Store) Lookup(secret string) (string, role.Role, bool) {
+	h := hash(secret)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.byHash[h]
+	if !ok {
+		return "", "", false
+	}
+	k := s.keys[i]
+	s.used[k.ID] = s.now().UTC()
+	return k.Name, role.Role(k.Role), true
+}
+
+// Run saves the last-used times every minute until ctx is done, and once
+// more then.
+func (s *Store) Run(ctx context.Context) {
+	ticker := time.NewTicker(usageFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.flush()
+			return
+		case <-ticker.C:
+			s.flush()
+		}
+	}
+}
+
+// Flush saves the last-used times recorded since the last save.
+func (s *Store) Flush() error {
+	s.saving.Lock()
+	defer s.saving.Unlock()
+	s.mu.Lock()
+	pending := len(s.used) > 0
+	s.mu.Unlock()
+	if !pending {
+		return nil
+	}
+	return s.save()
+}
+
+func (s *Store) flush() {
+	if err := s.Flush(); err != nil {
+		s.log.Warn("failed to save last-used times", slog.String("error", err.Error()))
+	}
+}
+
+// save writes the keys through a temporary file so a crash never leaves a
+// truncated one behind. The caller holds s.saving.
+func (s *Store) save() error {
+	s.mu.Lock()
+	f := file{Keys: make([]key, len(s.keys))}
+	for i, k := range s.keys {
+		if used, ok := s.used[k.ID]; ok {
+			k.LastUsedAt = &used
+		}
+		f.Keys[i] = k
+	}
+	saved := s.used
+	s.used = make(map[string]time.Time)
+	s.mu.Unlock()
+
+	if err := s.write(f); err != nil {
+		// Keep the times for the next save, unless newer ones came in.
+		s.mu.Lock()
+		for id, used := range saved {
+			if _, ok := s.used[id]; !ok {
+				s.used[id] = used
+			}
+		}
+		s.mu.Unlock()
+		return err
+	}
+
+	s.mu.Lock()
+	for _, k := range f.Keys {
+		if i := slices.IndexFunc(s.keys, func(other key) bool { return other.ID == k.ID }); i >= 0 {
+			s.keys[i].LastUsedAt = k.LastUsedAt
+		}
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Store) write(f file) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".apikeys-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// index rebuilds byHash. The caller holds s.mu.
+func (s *Store) index() {
+	s.byHash = make(map[string]int, len(s.keys))
+	for i, k := range s.keys {
+		s.byHash[k.Hash] = i
+	}
+}
+
+// hash is the hex SHA-256 of secret. The secrets are random, so a plain
+// hash is as good as a slow one.
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func newSecret() (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return "qs_" + hex.EncodeToString(b[:]), nil
+}
+
+func newID() string {
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
@@ -0,0 +1,109 @@
+package apikeys_test
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"quotes-service/internal/lib/apikeys"
+	"quotes-service/internal/lib/role"
+)
+
+func TestStore(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	clock := apikeys.WithClock(func() time.Time { return now })
+	path := filepath.Join(t.TempDir(), "keys.json")
+
+	store, err := apikeys.Open(logger, path, clock)
+	if err != nil {
+		t.Fatalf("failed to open a missing file: %v", err)
+	}
+	importer, err := store.Create("importer", role.Writer)
+	if err != nil {
+		t.Fatalf("failed to create a key: %v", err)
+	}
+	dashboard, err := store.Create("dashboard", "")
+	if err != nil {
+		t.Fatalf("failed to create a key: %v", err)
+	}
+	if importer.Secret == "" || importer.Secret == dashboard.Secret || importer.ID == dashboard.ID {
+		t.Fatalf("expected distinct secrets and IDs, got %+v and %+v", importer, dashboard)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), importer.Secret) || strings.Contains(string(data), dashboard.Secret) {
+		t.Fatalf("expected only hashes in the file, got %s", data)
+	}
+
+	principal, r, ok := store.Lookup(importer.Secret)
+	if !ok || principal != "importer" || r != role.Writer {
+		t.Fatalf("expected importer as writer, got %q %q %v", principal, r, ok)
+	}
+	if _, r, _ := store.Lookup(dashboard.Secret); r != "" {
+		t.Fatalf("expected a key without a role to leave it empty, got %q", r)
+	}
+	if _, _, ok := store.Lookup("qs_guess"); ok {
+		t.Fatal("expected an unknown secret to be refused")
+	}
+
+	// Use is saved on Flush, not on Lookup.
+	if keys := store.List(); len(keys) != 2 || keys[0].LastUsedAt == nil || !keys[0].LastUsedAt.Equal(now) {
+		t.Fatalf("expected the last use in the list, got %+v", keys)
+	}
+	reopened, err := apikeys.Open(logger, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := reopened.List(); keys[0].LastUsedAt != nil {
+		t.Fatalf("expected the last use unsaved before a flush, got %+v", keys[0])
+	}
+	if err := store.Flush(); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+	reopened, err = apikeys.Open(logger, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := reopened.List(); keys[0].LastUsedAt == nil || !keys[0].LastUsedAt.Equal(now) || keys[1].LastUsedAt == nil {
+		t.Fatalf("expected the last uses saved, got %+v", keys)
+	}
+	if principal, _, ok := reopened.Lookup(importer.Secret); !ok || principal != "importer" {
+		t.Fatal("expected a saved key to work after reopening")
+	}
+
+	if err := store.Revoke(importer.ID); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+	if _, _, ok := store.Lookup(importer.Secret); ok {
+		t.Fatal("expected a revoked key to be refused at once")
+	}
+	if err := store.Revoke(importer.ID); !errors.Is(err, apikeys.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound revoking twice, got %v", err)
+	}
+	reopened, err = apikeys.Open(logger, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := reopened.List(); len(keys) != 1 || keys[0].Name != "dashboard" {
+		t.Fatalf("expected the revocation saved, got %+v", keys)
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := apikeys.Open(slog.New(slog.NewTextHandler(io.Discard, nil)), path); err == nil {
+		t.Fatal("expected an error for a corrupt file")
+	}
+}
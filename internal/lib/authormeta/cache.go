@@ -0,0 +1,89 @@
+package authormeta
+
+import (
+	"sync"
+	"time"
+
+	"quotes-service/internal/models"
+)
+
+// Cache keeps looked up metadata, and that an author has none, for a TTL.
+// It holds at most Size authors; adding one more when it is full drops the
+// entry that expires first.
+type Cache struct {
+	ttl  time.Duration
+	size int
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	meta    models.AuthorMetadata
+	found   bool
+	expires time.Time
+}
+
+func NewCache(ttl time.Duration, size int, now func() time.Time) *Cache {
+	if now == nil {
+		now = time.Now
+	}
+	return &Cache{
+		ttl:     ttl,
+		size:    size,
+		now:     now,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// Get returns the metadata cached for key, whether the author has any, and
+// whether key was cached at all.
+func (c *Cache) Get(key string) (meta models.AuthorMetadata, found, cached bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return models.AuthorMetadata{}, false, false
+	}
+	if !c.now().Before(e.expires) {
+		delete(c.entries, key)
+		return models.AuthorMetadata{}, false, false
+	}
+	return e.meta, e.found, true
+}
+
+// Put caches the metadata of key, or with found false that it has none.
+func (c *Cache) Put(key string, meta models.AuthorMetadata, found bool) {
+	if c.size <= 0 || c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		c.evict()
+	}
+	c.entries[key] = cacheEntry{meta: meta, found: found, expires: c.now().Add(c.ttl)}
+}
+
+// Len is the number of authors cached, expired ones included until they
+// are looked up or evicted.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// evict drops the entry that expires first. The caller holds c.mu.
+func (c *Cache) evict() {
+	var (
+		oldest  string
+		expires time.Time
+	)
+	for key, e := range c.entries {
+		if expires.IsZero() || e.expires.Before(expires) {
+			oldest, expires = key, e.expires
+		}
+	}
+	delete(c.entries, oldest)
+}
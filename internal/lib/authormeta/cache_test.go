@@ -0,0 +1,51 @@
+package authormeta_test
+
+import (
+	"testing"
+	"time"
+
+	"quotes-service/internal/lib/authormeta"
+	"quotes-service/internal/models"
+)
+
+func TestCache(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	twain := models.AuthorMetadata{BirthYear: 1835}
+
+	c := authormeta.NewCache(time.Minute, 2, clock)
+	c.Put("mark twain", twain, true)
+	c.Put("nobody", models.AuthorMetadata{}, false)
+
+	if meta, found, cached := c.Get("mark twain"); !cached || !found || meta != twain {
+		t.Fatalf("expected cached metadata, got %+v, %t, %t", meta, found, cached)
+	}
+	if _, found, cached := c.Get("nobody"); !cached || found {
+		t.Fatalf("expected a cached miss, got %t, %t", found, cached)
+	}
+	if _, _, cached := c.Get("ada lovelace"); cached {
+		t.Fatal("expected nothing cached")
+	}
+
+	// A third author drops the one that expires first.
+	now = now.Add(time.Second)
+	c.Put("nobody", models.AuthorMetadata{}, false)
+	c.Put("ada lovelace", models.AuthorMetadata{WikiURL: "https://example.com/ada"}, true)
+	if c.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", c.Len())
+	}
+	if _, _, cached := c.Get("mark twain"); cached {
+		t.Fatal("expected the oldest entry evicted")
+	}
+
+	now = now.Add(time.Minute)
+	if _, _, cached := c.Get("nobody"); cached {
+		t.Fatal("expected the entry expired")
+	}
+
+	disabled := authormeta.NewCache(0, 10, clock)
+	disabled.Put("mark twain", twain, true)
+	if disabled.Len() != 0 {
+		t.Fatal("expected a cache without ttl to keep nothing")
+	}
+}
@@ -0,0 +1,60 @@
+// Package authormeta looks up what is known about an author beyond their
+// quotes, such as a portrait and the years they lived, for the author
+// endpoints to include. Authors are looked up by their match key, so every
+// spelling of a name finds the same metadata. An author without metadata
+// is not an error: the endpoints leave it out.
+package authormeta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"quotes-service/internal/lib/authorname"
+	"quotes-service/internal/models"
+)
+
+// File is metadata read from a JSON file mapping author names to their
+// metadata, such as {"Mark Twain": {"birth_year": 1835}}.
+type File struct {
+	byKey map[string]models.AuthorMetadata
+}
+
+// LoadFile reads the metadata in path. Two names with the same match key
+// are an error, as only one of them could ever be found.
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var byName map[string]models.AuthorMetadata
+	if err := json.Unmarshal(data, &byName); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+
+	f := &File{byKey: make(map[string]models.AuthorMetadata, len(byName))}
+	names := make(map[string]string, len(byName))
+	for name, meta := range byName {
+		key := authorname.Key(name)
+		if other, ok := names[key]; ok {
+			return nil, fmt.Errorf("%s: %q and %q are the same author", path, other, name)
+		}
+		names[key] = name
+		if meta != (models.AuthorMetadata{}) {
+			f.byKey[key] = meta
+		}
+	}
+	return f, nil
+}
+
+// Enrich returns the metadata of the author name, if the file has any.
+func (f *File) Enrich(ctx context.Context, name string) (models.AuthorMetadata, bool) {
+	meta, ok := f.byKey[authorname.Key(name)]
+	return meta, ok
+}
+
+// Len is the number of authors with metadata.
+func (f *File) Len() int {
+	return len(f.byKey)
+}
@@ -0,0 +1,99 @@
+package authormeta_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"quotes-service/internal/lib/authormeta"
+	"quotes-service/internal/models"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "authors.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		expectErr bool
+		expectLen int
+	}{
+		{
+			name:      "valid",
+			content:   `{"Mark Twain": {"birth_year": 1835, "death_year": 1910}, "Ada Lovelace": {"wiki_url": "https://example.com/ada"}}`,
+			expectLen: 2,
+		},
+		{
+			name:      "empty metadata skipped",
+			content:   `{"Mark Twain": {"birth_year": 1835}, "Nobody": {}}`,
+			expectLen: 1,
+		},
+		{
+			name:      "same author twice",
+			content:   `{"Mark Twain": {"birth_year": 1835}, "mark  TWAIN": {"death_year": 1910}}`,
+			expectErr: true,
+		},
+		{
+			name:      "invalid json",
+			content:   `{"Mark Twain":`,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := authormeta.LoadFile(writeFile(t, tc.content))
+			if tc.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if f.Len() != tc.expectLen {
+				t.Fatalf("expected %d authors, got %d", tc.expectLen, f.Len())
+			}
+		})
+	}
+
+	if _, err := authormeta.LoadFile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestFileEnrich(t *testing.T) {
+	f, err := authormeta.LoadFile(writeFile(t, `{"Mark Twain": {"birth_year": 1835, "death_year": 1910}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		author   string
+		expected models.AuthorMetadata
+		found    bool
+	}{
+		{name: "exact", author: "Mark Twain", expected: models.AuthorMetadata{BirthYear: 1835, DeathYear: 1910}, found: true},
+		{name: "other spelling", author: "  mark   TWAIN ", expected: models.AuthorMetadata{BirthYear: 1835, DeathYear: 1910}, found: true},
+		{name: "unknown", author: "Ada Lovelace"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			meta, found := f.Enrich(context.Background(), tc.author)
+			if found != tc.found || meta != tc.expected {
+				t.Fatalf("expected %+v, %t, got %+v, %t", tc.expected, tc.found, meta, found)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package authormeta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"quotes-service/internal/lib/authorname"
+	"quotes-service/internal/models"
+)
+
+// maxResponseBytes caps the metadata document of one author.
+const maxResponseBytes = 64 << 10
+
+const (
+	defaultTimeout    = 2 * time.Second
+	defaultCacheTTL   = time.Hour
+	defaultCacheSize  = 10_000
+	defaultRetryAfter = 30 * time.Second
+)
+
+// RemoteOptions configures a Remote.
+type RemoteOptions struct {
+	// URL is the enrichment service. An author is looked up with
+	// GET URL?key=<match key>&name=<name>, which answers 200 with the
+	// metadata as JSON or 404 when it has none.
+	URL string
+	// Timeout bounds one lookup.
+	Timeout time.Duration
+	// CacheTTL is how long an answer, metadata or none, is kept, and
+	// CacheSize how many authors are.
+	CacheTTL  time.Duration
+	CacheSize int
+	// RetryAfter is how long the service is left alone after a lookup
+	// fails. Until then every author without cached metadata has none.
+	RetryAfter time.Duration
+}
+
+// Remote is metadata looked up from an enrichment service over HTTP. A
+// lookup that fails is logged and answered as no metadata, so an outage of
+// the service never fails a request.
+type Remote struct {
+	log    *slog.Logger
+	client *http.Client
+	opts   RemoteOptions
+	now    func() time.Time
+	cache  *Cache
+
+	mu      sync.Mutex
+	retryAt time.Time
+}
+
+type Option func(*Remote)
+
+// WithHTTPClient replaces the client used to reach the service. Its
+// timeout is left as is; Timeout still bounds each lookup.
+func WithHTTPClient(client *http.Client) Option {
+	return func(r *Remote) {
+		r.client = client
+	}
+}
+
+// WithClock overrides the time source, mainly for tests.
+func WithClock(now func() time.Time) Option {
+	return func(r *Remote) {
+		r.now = now
+	}
+}
+
+func NewRemote(log *slog.Logger, opts RemoteOptions, options ...Option) *Remote {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = defaultCacheTTL
+	}
+	if opts.CacheSize <= 0 {
+		opts.CacheSize = defaultCacheSize
+	}
+	if opts.RetryAfter <= 0 {
+		opts.RetryAfter = defaultRetryAfter
+	}
+	r := &Remote{
+		log:    log.With(slog.String("op", "authormeta.Remote"), slog.String("url", opts.URL)),
+		client: &http.Client{},
+		opts:   opts,
+		now:    time.Now,
+	}
+	for _, opt := range options {
+		opt(r)
+	}
+	r.cache = NewCache(opts.CacheTTL, opts.CacheSize, r.now)
+	return r
+}
+
+// Enrich returns the metadata of the author name, from the cache or the
+// service.
+func (r *Remote) Enrich(ctx context.Context, name string) (models.AuthorMetadata, bool) {
+	key := authorname.Key(name)
+	if meta, found, cached := r.cache.Get(key); cached {
+		return meta, found
+	}
+
+	r.mu.Lock()
+	backingOff := r.now().Before(r.retryAt)
+	r.mu.Unlock()
+	if backingOff {
+		return models.AuthorMetadata{}, false
+	}
+
+	meta, found, err := r.lookup(ctx, key, name)
+	if err != nil {
+		if ctx.Err() == nil {
+			r.mu.Lock()
+			r.retryAt = r.now().Add(r.opts.RetryAfter)
+			r.mu.Unlock()
+		}
+		r.log.WarnContext(ctx, "failed to look up author metadata", slog.String("key", key), slog.String("error", err.Error()))
+		return models.AuthorMetadata{}, false
+	}
+	r.cache.Put(key, meta, found)
+	return meta, found
+}
+
+func (r *Remote) lookup(ctx context.Context, key, name string) (models.AuthorMetadata, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opts.Timeout)
+	defer cancel()
+
+	u, err := url.Parse(r.opts.URL)
+	if err != nil {
+		return models.AuthorMetadata{}, false, err
+	}
+	query := u.Query()
+	query.Set("key", key)
+	query.Set("name", name)
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return models.AuthorMetadata{}, false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return models.AuthorMetadata{}, false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return models.AuthorMetadata{}, false, nil
+	default:
+		return models.AuthorMetadata{}, false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var meta models.AuthorMetadata
+	dec := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes))
+	if err := dec.Decode(&meta); err != nil {
+		return models.AuthorMetadata{}, false, fmt.Errorf("decode metadata: %w", err)
+	}
+	if meta == (models.AuthorMetadata{}) {
+		return models.AuthorMetadata{}, false, nil
+	}
+	return meta, true, nil
+}
@@ -0,0 +1,156 @@
+package authormeta_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"quotes-service/internal/lib/authormeta"
+	"quotes-service/internal/models"
+)
+
+func TestRemoteEnrich(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		expected models.AuthorMetadata
+		found    bool
+	}{
+		{
+			name: "found",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("key") != "mark twain" {
+					http.NotFound(w, r)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				io.WriteString(w, `{"birth_year": 1835, "image_url": "https://example.com/twain.jpg"}`)
+			},
+			expected: models.AuthorMetadata{BirthYear: 1835, ImageURL: "https://example.com/twain.jpg"},
+			found:    true,
+		},
+		{
+			name:    "not found",
+			handler: http.NotFound,
+		},
+		{
+			name: "empty metadata",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, `{}`)
+			},
+		},
+		{
+			name: "server error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+		},
+		{
+			name: "invalid json",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, `{"birth_year":`)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(tc.handler)
+			defer srv.Close()
+
+			r := authormeta.NewRemote(logger, authormeta.RemoteOptions{URL: srv.URL})
+			meta, found := r.Enrich(context.Background(), "  Mark   TWAIN")
+			if found != tc.found || meta != tc.expected {
+				t.Fatalf("expected %+v, %t, got %+v, %t", tc.expected, tc.found, meta, found)
+			}
+		})
+	}
+}
+
+func TestRemoteCaching(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	var (
+		hits    atomic.Int32
+		failing atomic.Bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if r.URL.Query().Get("key") == "nobody" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, `{"birth_year": 1835}`)
+	}))
+	defer srv.Close()
+
+	r := authormeta.NewRemote(logger, authormeta.RemoteOptions{
+		URL:        srv.URL,
+		CacheTTL:   time.Minute,
+		RetryAfter: 10 * time.Second,
+	}, authormeta.WithClock(func() time.Time { return now }))
+	ctx := context.Background()
+
+	for _, name := range []string{"Mark Twain", "mark twain", "MARK  TWAIN", "Nobody", "nobody"} {
+		r.Enrich(ctx, name)
+	}
+	if got := hits.Load(); got != 2 {
+		t.Fatalf("expected one lookup per author, got %d", got)
+	}
+
+	// Once the cache expires, a failure answers no metadata and holds off
+	// further lookups until RetryAfter has passed.
+	now = now.Add(time.Minute)
+	failing.Store(true)
+	if _, found := r.Enrich(ctx, "Mark Twain"); found {
+		t.Fatal("expected no metadata while the service fails")
+	}
+	if _, found := r.Enrich(ctx, "Nobody"); found {
+		t.Fatal("expected no metadata while backing off")
+	}
+	if got := hits.Load(); got != 3 {
+		t.Fatalf("expected no lookup while backing off, got %d lookups", got)
+	}
+
+	now = now.Add(10 * time.Second)
+	failing.Store(false)
+	if meta, found := r.Enrich(ctx, "Mark Twain"); !found || meta.BirthYear != 1835 {
+		t.Fatalf("expected metadata once the service recovers, got %+v, %t", meta, found)
+	}
+	if got := hits.Load(); got != 4 {
+		t.Fatalf("expected 4 lookups, got %d", got)
+	}
+}
+
+func TestRemoteTimeout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	r := authormeta.NewRemote(logger, authormeta.RemoteOptions{URL: srv.URL, Timeout: 50 * time.Millisecond})
+	start := time.Now()
+	if _, found := r.Enrich(context.Background(), "Mark Twain"); found {
+		t.Fatal("expected no metadata after a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the lookup cut off at the timeout, took %s", elapsed)
+	}
+}
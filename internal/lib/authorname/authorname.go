@@ -0,0 +1,72 @@
+// Package authorname derives the two forms of an author name: the display
+// form shown to users and the key that author lookups match on.
+package authorname
+
+import (
+	"strings"
+
+	"quotes-service/internal/lib/tokenizer"
+	"quotes-service/internal/models"
+)
+
+// Display normalizes an author name as given for display: surrounding
+// space is trimmed and inner runs of space collapse to one. Case and
+// punctuation are kept.
+func Display(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// Key returns the match key of an author name: its words lowercased and
+// joined by single spaces, with punctuation dropped, so that "Einstein",
+// "einstein" and "EINSTEIN." share a key. A name with no words at all is
+// its own key, lowercased.
+func Key(name string) string {
+	if tokens := tokenizer.Tokens(name); len(tokens) > 0 {
+		return strings.Join(tokens, " ")
+	}
+	return strings.ToLower(Display(name))
+}
+
+// Choose picks the display name of an author among the spellings of their
+// quotes, given in the order the quotes were added: the most common one,
+// or the first seen of those tied. It also returns the distinct spellings
+// in first-seen order.
+func Choose(spellings []string) (string, []string) {
+	counts := make(map[string]int, 1)
+	var distinct []string
+	for _, name := range spellings {
+		if counts[name] == 0 {
+			distinct = append(distinct, name)
+		}
+		counts[name]++
+	}
+	var display string
+	for _, name := range distinct {
+		if counts[name] > counts[display] {
+			display = name
+		}
+	}
+	return display, distinct
+}
+
+// Summarize describes the quotes by one author, given in the order they
+// were added.
+func Summarize(quotes []models.Quote) models.AuthorSummary {
+	spellings := make([]string, len(quotes))
+	summary := models.AuthorSummary{QuoteCount: len(quotes)}
+	for i, q := range quotes {
+		spellings[i] = q.Author
+		if summary.FirstAddedAt.IsZero() || q.CreatedAt.Before(summary.FirstAddedAt) {
+			summary.FirstAddedAt = q.CreatedAt
+		}
+		if q.CreatedAt.After(summary.LastAddedAt) {
+			summary.LastAddedAt = q.CreatedAt
+		}
+	}
+	var variants []string
+	summary.Name, variants = Choose(spellings)
+	if len(variants) > 1 {
+		summary.Variants = variants
+	}
+	return summary
+}
@@ -0,0 +1,72 @@
+package authorname_test
+
+import (
+	"slices"
+	"testing"
+
+	"quotes-service/internal/lib/authorname"
+)
+
+func TestDisplay(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "unchanged", in: "Albert Einstein", want: "Albert Einstein"},
+		{name: "spacing", in: "  Albert \t Einstein\n", want: "Albert Einstein"},
+		{name: "case and punctuation kept", in: "A. EINSTEIN", want: "A. EINSTEIN"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := authorname.Display(tc.in); got != tc.want {
+				t.Fatalf("Display(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "lowercased", in: "Einstein", want: "einstein"},
+		{name: "punctuation dropped", in: "A. Einstein", want: "a einstein"},
+		{name: "spacing", in: " Albert   Einstein ", want: "albert einstein"},
+		{name: "word order kept", in: "Einstein, A.", want: "einstein a"},
+		{name: "non-latin", in: "Лев Толстой!", want: "лев толстой"},
+		{name: "hyphenated", in: "Saint-Exupéry", want: "saint exupéry"},
+		{name: "no words", in: " ?! ", want: "?!"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := authorname.Key(tc.in); got != tc.want {
+				t.Fatalf("Key(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestChoose(t *testing.T) {
+	tests := []struct {
+		name         string
+		spellings    []string
+		wantDisplay  string
+		wantVariants []string
+	}{
+		{name: "single", spellings: []string{"Einstein"}, wantDisplay: "Einstein", wantVariants: []string{"Einstein"}},
+		{name: "most common", spellings: []string{"einstein", "Einstein", "Einstein"}, wantDisplay: "Einstein", wantVariants: []string{"einstein", "Einstein"}},
+		{name: "tie goes to the first seen", spellings: []string{"EINSTEIN", "Einstein", "Einstein", "EINSTEIN"}, wantDisplay: "EINSTEIN", wantVariants: []string{"EINSTEIN", "Einstein"}},
+		{name: "none", spellings: nil, wantDisplay: "", wantVariants: nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			display, variants := authorname.Choose(tc.spellings)
+			if display != tc.wantDisplay || !slices.Equal(variants, tc.wantVariants) {
+				t.Fatalf("Choose(%q) = %q, %q, want %q, %q", tc.spellings, display, variants, tc.wantDisplay, tc.wantVariants)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+// Package autotls obtains and renews TLS certificates from an ACME
+// authority such as Let's Encrypt, and keeps track of their state so that
+// readiness checks can report a domain the service cannot serve HTTPS for.
+package autotls
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+	"quotes-service/internal/models"
+)
+
+// Options configures a Manager.
+type Options struct {
+	// Domains are the only host names certificates are requested for.
+	Domains []string
+	// CacheDir persists account keys and certificates across restarts.
+	CacheDir string
+	// DirectoryURL is the ACME directory, e.g. the Let's Encrypt staging
+	// endpoint. Empty means Let's Encrypt production.
+	DirectoryURL string
+	// Email is passed to the authority for expiry notices. Optional.
+	Email string
+}
+
+type domainState struct {
+	notAfter time.Time
+	err      error
+}
+
+type Manager struct {
+	log     *slog.Logger
+	manager *autocert.Manager
+	domains []string
+
+	mu    sync.Mutex
+	state map[string]domainState
+}
+
+// New returns a Manager for opts.Domains. Requests for any other host name
+// are refused before they reach the authority, so the server cannot be used
+// to mint certificates for arbitrary hosts.
+func New(log *slog.Logger, opts Options) *Manager {
+	m := &Manager{
+		log: log.With(slog.String("op", "autotls.Manager")),
+		manager: &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      autocert.DirCache(opts.CacheDir),
+			HostPolicy: autocert.HostWhitelist(opts.Domains...),
+			Email:      opts.Email,
+		},
+		domains: slices.Clone(opts.Domains),
+		state:   make(map[string]domainState, len(opts.Domains)),
+	}
+	if opts.DirectoryURL != "" {
+		m.manager.Client = &acme.Client{DirectoryURL: opts.DirectoryURL}
+	}
+	return m
+}
+
+// TLSConfig returns the TLS configuration for the HTTPS listener.
+func (m *Manager) TLSConfig() *tls.Config {
+	cfg := m.manager.TLSConfig()
+	cfg.GetCertificate = m.getCertificate
+	return cfg
+}
+
+// HTTPHandler answers HTTP-01 challenges and redirects every other request
+// to HTTPS.
+func (m *Manager) HTTPHandler() http.Handler {
+	return m.manager.HTTPHandler(nil)
+}
+
+// Warm requests a certificate for every domain, so that failures show up
+// in the log and in readiness right after startup rather than on the first
+// client handshake.
+func (m *Manager) Warm(ctx context.Context) {
+	for _, domain := range m.domains {
+		if ctx.Err() != nil {
+			return
+		}
+		_, _ = m.getCertificate(&tls.ClientHelloInfo{
+			ServerName: domain,
+			// Advertise ECDSA support so the certificate obtained here is
+			// the one real clients get.
+			CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+		})
+	}
+}
+
+// Certificates reports the state of every configured domain.
+func (m *Manager) Certificates() []models.CertificateStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]models.CertificateStatus, 0, len(m.domains))
+	for _, domain := range m.domains {
+		st := m.state[domain]
+		status := models.CertificateStatus{
+			Domain: domain,
+			Ready:  !st.notAfter.IsZero() && time.Now().Before(st.notAfter),
+		}
+		if !st.notAfter.IsZero() {
+			notAfter := st.notAfter.UTC()
+			status.NotAfter = &notAfter
+		}
+		if st.err != nil {
+			status.Error = st.err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+func (m *Manager) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, err := m.manager.GetCertificate(hello)
+
+	// Handshakes for other hosts and TLS-ALPN challenges say nothing
+	// about the certificates we serve.
+	domain := strings.TrimSuffix(strings.ToLower(hello.ServerName), ".")
+	if !slices.Contains(m.domains, domain) || slices.Contains(hello.SupportedProtos, acme.ALPNProto) {
+		return cert, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st := m.state[domain]
+	if err != nil {
+		// Every handshake retries, so only a new failure is logged.
+		if st.err == nil || st.err.Error() != err.Error() {
+			m.log.Error("failed to obtain tls certificate", slog.String("domain", domain), slog.String("error", err.Error()))
+		}
+		st.err = err
+	} else {
+		if st.err != nil || st.notAfter.IsZero() {
+			m.log.Info("tls certificate ready", slog.String("domain", domain))
+		}
+		st.err = nil
+		if cert.Leaf != nil {
+			st.notAfter = cert.Leaf.NotAfter
+		}
+	}
+	m.state[domain] = st
+	return cert, err
+}
@@ -0,0 +1,96 @@
+package autotls_test
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"quotes-service/internal/lib/autotls"
+)
+
+// newManager points the manager at an ACME directory that always fails, so
+// no test ever reaches a real authority. The status is one the ACME client
+// does not retry.
+func newManager(t *testing.T, domains ...string) (*autotls.Manager, *atomic.Int32) {
+	t.Helper()
+	calls := new(atomic.Int32)
+	directory := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "unavailable", http.StatusBadRequest)
+	}))
+	t.Cleanup(directory.Close)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return autotls.New(logger, autotls.Options{
+		Domains:      domains,
+		CacheDir:     t.TempDir(),
+		DirectoryURL: directory.URL,
+	}), calls
+}
+
+func TestRefusesUnlistedHosts(t *testing.T) {
+	m, calls := newManager(t, "quotes.example.com")
+
+	for _, host := range []string{"evil.example.com", "example.com", "sub.quotes.example.com", ""} {
+		_, err := m.TLSConfig().GetCertificate(&tls.ClientHelloInfo{ServerName: host})
+		if err == nil {
+			t.Errorf("expected a certificate for %q to be refused", host)
+		}
+	}
+	if calls.Load() != 0 {
+		t.Errorf("expected no requests to the authority, got %d", calls.Load())
+	}
+
+	status := m.Certificates()
+	if len(status) != 1 || status[0].Error != "" {
+		t.Errorf("refused hosts must not affect the domain status, got %+v", status)
+	}
+}
+
+func TestWarmRecordsFailures(t *testing.T) {
+	m, calls := newManager(t, "quotes.example.com", "www.example.com")
+
+	status := m.Certificates()
+	for _, s := range status {
+		if s.Ready || s.Error != "" {
+			t.Fatalf("expected %s to be pending before warm-up, got %+v", s.Domain, s)
+		}
+	}
+
+	m.Warm(context.Background())
+
+	if calls.Load() == 0 {
+		t.Error("expected warm-up to contact the authority")
+	}
+	status = m.Certificates()
+	if len(status) != 2 {
+		t.Fatalf("expected 2 domains, got %d", len(status))
+	}
+	for i, domain := range []string{"quotes.example.com", "www.example.com"} {
+		if status[i].Domain != domain {
+			t.Errorf("expected domain %s at %d, got %s", domain, i, status[i].Domain)
+		}
+		if status[i].Ready || status[i].Error == "" {
+			t.Errorf("expected %s to report the failure, got %+v", domain, status[i])
+		}
+	}
+}
+
+func TestHTTPHandlerRedirects(t *testing.T) {
+	m, _ := newManager(t, "quotes.example.com")
+
+	rr := httptest.NewRecorder()
+	m.HTTPHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "http://quotes.example.com/quotes/random", nil))
+
+	if rr.Code != http.StatusFound {
+		t.Fatalf("expected 302, got %d", rr.Code)
+	}
+	if loc := rr.Header().Get("Location"); loc != "https://quotes.example.com/quotes/random" {
+		t.Errorf("unexpected redirect target %q", loc)
+	}
+}
@@ -0,0 +1,56 @@
+// Package cardinality caps how many distinct values a metric label takes,
+// so a label fed from an unbounded source, such as tenants or authors,
+// cannot multiply the series of a metric without limit.
+package cardinality
+
+import "sync"
+
+// Other is the label value every value over the cap is counted under.
+const Other = "other"
+
+// Guard lets through the first max distinct values it is given and maps
+// every later one to Other. A value once let through stays so. It is safe
+// for concurrent use.
+type Guard struct {
+	mu   sync.RWMutex
+	max  int
+	seen map[string]struct{}
+}
+
+// New returns a Guard of max distinct values. With max zero or less every
+// value is Other.
+func New(max int) *Guard {
+	return &Guard{max: max, seen: make(map[string]struct{})}
+}
+
+// Value returns value if it is within the cap, and Other otherwise.
+func (g *Guard) Value(value string) string {
+	g.mu.RLock()
+	_, ok := g.seen[value]
+	full := len(g.seen) >= g.max
+	g.mu.RUnlock()
+	if ok {
+		return value
+	}
+	if full {
+		return Other
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.seen[value]; ok {
+		return value
+	}
+	if len(g.seen) >= g.max {
+		return Other
+	}
+	g.seen[value] = struct{}{}
+	return value
+}
+
+// Len returns how many distinct values have been let through.
+func (g *Guard) Len() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.seen)
+}
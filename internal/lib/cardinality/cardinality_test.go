@@ -0,0 +1,82 @@
+package cardinality_test
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"quotes-service/internal/lib/cardinality"
+)
+
+func TestGuard(t *testing.T) {
+	tests := []struct {
+		name     string
+		max      int
+		values   []string
+		expected []string
+	}{
+		{
+			name:     "within the cap",
+			max:      3,
+			values:   []string{"a", "b", "a", "c"},
+			expected: []string{"a", "b", "a", "c"},
+		},
+		{
+			name:     "over the cap",
+			max:      2,
+			values:   []string{"a", "b", "c", "a", "d", "b"},
+			expected: []string{"a", "b", cardinality.Other, "a", cardinality.Other, "b"},
+		},
+		{
+			name:     "no room",
+			max:      0,
+			values:   []string{"a", "b"},
+			expected: []string{cardinality.Other, cardinality.Other},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := cardinality.New(tc.max)
+			for i, value := range tc.values {
+				if got := g.Value(value); got != tc.expected[i] {
+					t.Fatalf("value %d (%q): expected %q, got %q", i, value, tc.expected[i], got)
+				}
+			}
+			if g.Len() > max(tc.max, 0) {
+				t.Fatalf("expected at most %d values let through, got %d", tc.max, g.Len())
+			}
+		})
+	}
+}
+
+func TestGuardConcurrent(t *testing.T) {
+	const limit = 10
+	g := cardinality.New(limit)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				g.Value("tenant-" + strconv.Itoa(i%50))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if g.Len() != limit {
+		t.Fatalf("expected exactly %d values let through, got %d", limit, g.Len())
+	}
+	admitted := 0
+	for i := 0; i < 50; i++ {
+		value := "tenant-" + strconv.Itoa(i)
+		if g.Value(value) == value {
+			admitted++
+		}
+	}
+	if admitted != limit {
+		t.Fatalf("expected %d values to keep their label, got %d", limit, admitted)
+	}
+}
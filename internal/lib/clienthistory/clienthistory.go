@@ -0,0 +1,128 @@
+package clienthistory
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// History remembers the last few quote IDs served to each client so they can
+// be excluded from the next random pick. Entries expire after a TTL and the
+// number of tracked clients is capped; the least recently seen client is
+// evicted first.
+type History struct {
+	mu         sync.Mutex
+	window     int
+	ttl        time.Duration
+	maxClients int
+	now        func() time.Time
+	clients    map[string]*list.Element
+	order      *list.List
+}
+
+type entry struct {
+	clientID string
+	ids      []int64
+	lastSeen time.Time
+}
+
+type Option func(*History)
+
+// WithClock overrides the time source, mainly for tests.
+func WithClock(now func() time.Time) Option {
+	return func(h *History) {
+		h.now = now
+	}
+}
+
+func New(window int, ttl time.Duration, maxClients int, opts ...Option) *History {
+	h := &History{
+		window:     window,
+		ttl:        ttl,
+		maxClients: maxClients,
+		now:        time.Now,
+		clients:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Recent returns the IDs recently served to the client, oldest first.
+func (h *History) Recent(clientID string) []int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	el, ok := h.clients[clientID]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*entry)
+	if h.expired(e) {
+		h.remove(el)
+		return nil
+	}
+
+	ids := make([]int64, len(e.ids))
+	copy(ids, e.ids)
+	return ids
+}
+
+// Remember records that id was served to the client.
+func (h *History) Remember(clientID string, id int64) {
+	if h.window <= 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	el, ok := h.clients[clientID]
+	if ok && h.expired(el.Value.(*entry)) {
+		h.remove(el)
+		ok = false
+	}
+	if !ok {
+		el = h.order.PushFront(&entry{clientID: clientID})
+		h.clients[clientID] = el
+	}
+
+	e := el.Value.(*entry)
+	e.lastSeen = now
+	e.ids = append(e.ids, id)
+	if len(e.ids) > h.window {
+		e.ids = e.ids[len(e.ids)-h.window:]
+	}
+	h.order.MoveToFront(el)
+
+	h.evict()
+}
+
+// Len returns the number of clients currently tracked.
+func (h *History) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.order.Len()
+}
+
+func (h *History) evict() {
+	for el := h.order.Back(); el != nil; el = h.order.Back() {
+		withinCap := h.maxClients <= 0 || h.order.Len() <= h.maxClients
+		if withinCap && !h.expired(el.Value.(*entry)) {
+			return
+		}
+		h.remove(el)
+	}
+}
+
+func (h *History) expired(e *entry) bool {
+	return h.ttl > 0 && h.now().Sub(e.lastSeen) > h.ttl
+}
+
+func (h *History) remove(el *list.Element) {
+	h.order.Remove(el)
+	delete(h.clients, el.Value.(*entry).clientID)
+}
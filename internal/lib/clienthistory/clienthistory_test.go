@@ -0,0 +1,62 @@
+package clienthistory_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"quotes-service/internal/lib/clienthistory"
+)
+
+func TestHistoryKeepsLastWindow(t *testing.T) {
+	h := clienthistory.New(3, time.Hour, 10)
+
+	for _, id := range []int64{1, 2, 3, 4, 5} {
+		h.Remember("client", id)
+	}
+
+	if got, want := h.Recent("client"), []int64{3, 4, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected recent %v, got %v", want, got)
+	}
+	if got := h.Recent("other"); got != nil {
+		t.Errorf("expected no history for unknown client, got %v", got)
+	}
+}
+
+func TestHistoryExpiresAfterTTL(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := clienthistory.New(3, time.Minute, 10, clienthistory.WithClock(func() time.Time { return now }))
+
+	h.Remember("client", 1)
+	now = now.Add(30 * time.Second)
+	if got := h.Recent("client"); len(got) != 1 {
+		t.Fatalf("expected history within TTL, got %v", got)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if got := h.Recent("client"); got != nil {
+		t.Errorf("expected history to expire, got %v", got)
+	}
+	if h.Len() != 0 {
+		t.Errorf("expected expired client to be evicted, got %d clients", h.Len())
+	}
+}
+
+func TestHistoryCapsClients(t *testing.T) {
+	h := clienthistory.New(2, time.Hour, 2)
+
+	h.Remember("a", 1)
+	h.Remember("b", 2)
+	h.Remember("a", 3)
+	h.Remember("c", 4)
+
+	if h.Len() != 2 {
+		t.Fatalf("expected 2 tracked clients, got %d", h.Len())
+	}
+	if got := h.Recent("b"); got != nil {
+		t.Errorf("expected least recently seen client to be evicted, got %v", got)
+	}
+	if got, want := h.Recent("a"), []int64{1, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected recent %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,193 @@
+// Package contentfilter matches quote text against a denylist of words,
+// phrases and regular expressions. Words and phrases match whole words
+// only, compared case-insensitively in any script, so a term never matches
+// inside a longer word.
+package contentfilter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"quotes-service/internal/lib/tokenizer"
+)
+
+// Actions taken on a quote that matches the denylist.
+const (
+	ActionReject = "reject"
+	ActionHold   = "hold"
+)
+
+// regexPrefix marks a denylist line as a regular expression.
+const regexPrefix = "re:"
+
+// Matcher is a compiled denylist. Rules are numbered by their line in the
+// denylist, so a match can be reported without repeating the term.
+type Matcher struct {
+	// phrases maps the first word of every word or phrase term to the
+	// terms starting with it.
+	phrases map[string][]phrase
+	regexps []rule
+}
+
+type phrase struct {
+	words []string
+	line  int
+}
+
+type rule struct {
+	re   *regexp.Regexp
+	line int
+}
+
+// Parse reads a denylist: one term per line, blank lines and lines
+// starting with "#" ignored. A term is a word or a phrase of several
+// words, or, after "re:", a regular expression. Regular expressions are
+// matched case-insensitively and must start and end at word boundaries.
+func Parse(r io.Reader) (*Matcher, error) {
+	m := &Matcher{phrases: make(map[string][]phrase)}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		term := strings.TrimSpace(scanner.Text())
+		if term == "" || strings.HasPrefix(term, "#") {
+			continue
+		}
+		if expr, ok := strings.CutPrefix(term, regexPrefix); ok {
+			re, err := regexp.Compile(`(?i)(?:^|[^\pL\pN\pM])(?:` + expr + `)(?:[^\pL\pN\pM]|$)`)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			m.regexps = append(m.regexps, rule{re: re, line: line})
+			continue
+		}
+		words := tokenizer.Tokens(term)
+		if len(words) == 0 {
+			return nil, fmt.Errorf("line %d: term has no words", line)
+		}
+		m.phrases[words[0]] = append(m.phrases[words[0]], phrase{words: words, line: line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Match reports whether text contains a term of the denylist, and the line
+// of the first one found.
+func (m *Matcher) Match(text string) (line int, ok bool) {
+	if len(m.phrases) > 0 {
+		words := tokenizer.Tokens(text)
+		for i, word := range words {
+			for _, p := range m.phrases[word] {
+				if hasPrefix(words[i:], p.words) {
+					return p.line, true
+				}
+			}
+		}
+	}
+	for _, r := range m.regexps {
+		if r.re.MatchString(text) {
+			return r.line, true
+		}
+	}
+	return 0, false
+}
+
+// Len returns the number of terms in the denylist.
+func (m *Matcher) Len() int {
+	n := len(m.regexps)
+	for _, ps := range m.phrases {
+		n += len(ps)
+	}
+	return n
+}
+
+func hasPrefix(words, prefix []string) bool {
+	if len(words) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if words[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter is the denylist in a file, which Reload reads again. It counts
+// its hits by source and action as a prometheus.Collector.
+type Filter struct {
+	path    string
+	action  string
+	matcher atomic.Pointer[Matcher]
+	hits    *prometheus.CounterVec
+}
+
+// New loads the denylist at path. action is what callers do with quotes
+// that match it: ActionReject or ActionHold.
+func New(path, action string) (*Filter, error) {
+	if action != ActionReject && action != ActionHold {
+		return nil, fmt.Errorf("unknown action %q", action)
+	}
+	f := &Filter{
+		path:   path,
+		action: action,
+		hits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "content_filter_hits_total",
+			Help: "Quotes that matched the content denylist, by source and action.",
+		}, []string{"source", "action"}),
+	}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reload reads the denylist file again. On error the previous denylist
+// stays in use.
+func (f *Filter) Reload() error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	m, err := Parse(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.path, err)
+	}
+	f.matcher.Store(m)
+	return nil
+}
+
+// Action returns what is done with quotes that match.
+func (f *Filter) Action() string {
+	return f.action
+}
+
+// Terms returns the number of terms in the denylist in use.
+func (f *Filter) Terms() int {
+	return f.matcher.Load().Len()
+}
+
+// Check matches text, which came from source, against the denylist and
+// counts a hit. It returns the line of the matching term, or false.
+func (f *Filter) Check(text, source string) (line int, hit bool) {
+	line, hit = f.matcher.Load().Match(text)
+	if hit {
+		f.hits.WithLabelValues(source, f.action).Inc()
+	}
+	return line, hit
+}
+
+func (f *Filter) Describe(ch chan<- *prometheus.Desc) {
+	f.hits.Describe(ch)
+}
+
+func (f *Filter) Collect(ch chan<- prometheus.Metric) {
+	f.hits.Collect(ch)
+}
@@ -0,0 +1,112 @@
+package contentfilter_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"quotes-service/internal/lib/contentfilter"
+)
+
+const denylist = `# words
+ass
+cunt
+
+дурак
+bad words
+re:sp[a4]m+y
+`
+
+func TestMatch(t *testing.T) {
+	m, err := contentfilter.Parse(strings.NewReader(denylist))
+	if err != nil {
+		t.Fatalf("failed to parse denylist: %v", err)
+	}
+	if m.Len() != 5 {
+		t.Fatalf("expected 5 terms, got %d", m.Len())
+	}
+
+	tests := []struct {
+		name         string
+		text         string
+		expectedLine int
+	}{
+		{name: "word", text: "Kiss my ass.", expectedLine: 2},
+		{name: "any case", text: "ASS backwards", expectedLine: 2},
+		{name: "inside a longer word", text: "A classic assessment of Scunthorpe."},
+		{name: "cyrillic in any case", text: "Сам ты ДУРАК!", expectedLine: 5},
+		{name: "cyrillic inside a longer word", text: "Дураки и дороги."},
+		{name: "phrase", text: "No bad   words, please", expectedLine: 6},
+		{name: "phrase words apart", text: "Bad weather, good words"},
+		{name: "regexp", text: "Such SP4MMY text", expectedLine: 7},
+		{name: "regexp inside a longer word", text: "Antispammy measures"},
+		{name: "clean", text: "Be yourself; everyone else is already taken."},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			line, ok := m.Match(tc.text)
+			if ok != (tc.expectedLine != 0) || line != tc.expectedLine {
+				t.Fatalf("expected line %d, got %d (matched %v)", tc.expectedLine, line, ok)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for name, list := range map[string]string{
+		"invalid regexp": "ok\nre:(unclosed\n",
+		"no words":       "ok\n---\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := contentfilter.Parse(strings.NewReader(list))
+			if err == nil || !strings.HasPrefix(err.Error(), "line 2: ") {
+				t.Fatalf("expected an error on line 2, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "denylist.txt")
+	if err := os.WriteFile(path, []byte("spam\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := contentfilter.New(path, "ignore"); err == nil {
+		t.Fatal("expected an unknown action to fail")
+	}
+	f, err := contentfilter.New(path, contentfilter.ActionHold)
+	if err != nil {
+		t.Fatalf("failed to load filter: %v", err)
+	}
+
+	if _, hit := f.Check("Eggs and spam", "create"); !hit {
+		t.Fatal("expected spam to match")
+	}
+	if got := testutil.ToFloat64(f); got != 1 {
+		t.Fatalf("expected 1 hit counted, got %v", got)
+	}
+
+	if err := os.WriteFile(path, []byte("eggs\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Reload(); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+	if _, hit := f.Check("Eggs and ham", "create"); !hit {
+		t.Fatal("expected the reloaded denylist to be used")
+	}
+
+	if err := os.WriteFile(path, []byte("re:(\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Reload(); err == nil {
+		t.Fatal("expected a broken denylist to fail to reload")
+	}
+	if _, hit := f.Check("Eggs and ham", "create"); !hit {
+		t.Fatal("expected the previous denylist to stay in use")
+	}
+}
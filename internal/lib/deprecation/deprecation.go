@@ -0,0 +1,259 @@
+// Package deprecation keeps the routes and response fields that are on
+// their way out, and who still uses them. Each use is counted per caller,
+// so that before a sunset the operators know whom a removal would break.
+package deprecation
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"quotes-service/internal/models"
+)
+
+// OtherCallers is the caller the uses of callers past Options.MaxCallers
+// are counted under.
+const OtherCallers = "other"
+
+// Entry marks a route, or a field of its responses, deprecated.
+type Entry struct {
+	// Method is the method of the route, or "" for all of them.
+	Method string
+	// Route is the path template, such as "/quotes/{id}". The patterns of
+	// its variables may be left out: "/quotes/{id}" is the route
+	// "/quotes/{id:[0-9]+}".
+	Route string
+	// Field is the deprecated field of the route's responses, or "" when
+	// the route itself is deprecated.
+	Field string
+	// Since is when it was deprecated, or will be.
+	Since time.Time
+	// Sunset is when it goes away.
+	Sunset time.Time
+	// Link documents the deprecation and what to use instead.
+	Link string
+}
+
+// ParseRoute splits a route as configured, "GET /quotes/{id}" or
+// "/quotes/{id}" for every method, into its method and path template.
+func ParseRoute(s string) (method, route string, err error) {
+	s = strings.TrimSpace(s)
+	route = s
+	if m, rest, found := strings.Cut(s, " "); found {
+		if !slices.Contains(methods, m) {
+			return "", "", fmt.Errorf("unknown method %q in route %q", m, s)
+		}
+		method, route = m, strings.TrimSpace(rest)
+	}
+	if !strings.HasPrefix(route, "/") {
+		return "", "", fmt.Errorf("route %q must start with /", s)
+	}
+	return method, route, nil
+}
+
+var methods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// MethodRoute is the route of e as configured, such as "GET /quotes/{id}".
+func (e Entry) MethodRoute() string {
+	if e.Method == "" {
+		return e.Route
+	}
+	return e.Method + " " + e.Route
+}
+
+// Subject names what e deprecates, such as "GET /quotes/{id}" or
+// "GET /quotes/{id} field author_key".
+func (e Entry) Subject() string {
+	if e.Field == "" {
+		return e.MethodRoute()
+	}
+	return e.MethodRoute() + " field " + e.Field
+}
+
+// Options configures a Registry.
+type Options struct {
+	// LogInterval is how often the use of an entry by a caller is worth a
+	// log line.
+	LogInterval time.Duration
+	// MaxCallers caps the callers counted apart per entry; those past it
+	// are counted together as OtherCallers. Zero means no cap.
+	MaxCallers int
+}
+
+// Registry holds the deprecated entries and their use.
+type Registry struct {
+	opts    Options
+	now     func() time.Time
+	entries []Entry
+	byRoute map[string][]int
+
+	mu    sync.Mutex
+	usage []map[string]*use
+}
+
+type use struct {
+	count     int64
+	firstSeen time.Time
+	lastSeen  time.Time
+	// logged is when the use was last logged, and unlogged the uses since.
+	logged   time.Time
+	unlogged int64
+}
+
+type Option func(*Registry)
+
+// WithClock overrides the time source, mainly for tests.
+func WithClock(now func() time.Time) Option {
+	return func(r *Registry) {
+		r.now = now
+	}
+}
+
+func New(entries []Entry, opts Options, options ...Option) *Registry {
+	r := &Registry{
+		opts:    opts,
+		now:     time.Now,
+		entries: entries,
+		byRoute: make(map[string][]int),
+		usage:   make([]map[string]*use, len(entries)),
+	}
+	for i, e := range entries {
+		key := bare(e.Route)
+		r.byRoute[key] = append(r.byRoute[key], i)
+		r.usage[i] = make(map[string]*use)
+	}
+	for _, opt := range options {
+		opt(r)
+	}
+	return r
+}
+
+// Use is an entry a request used.
+type Use struct {
+	Entry
+	// Log reports that the use is the caller's first of the entry since
+	// LogInterval, and Uses is how many there were since the last logged
+	// one, this one included.
+	Log  bool
+	Uses int64
+}
+
+// Record records that caller called the route with method and template,
+// and returns the entries it used.
+func (r *Registry) Record(method, template, caller string) []Use {
+	indexes := r.byRoute[bare(template)]
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	var uses []Use
+	for _, i := range indexes {
+		e := r.entries[i]
+		if e.Method != "" && e.Method != method {
+			continue
+		}
+		callers := r.usage[i]
+		key := caller
+		u, ok := callers[key]
+		if !ok && r.opts.MaxCallers > 0 && len(callers) >= r.opts.MaxCallers {
+			key = OtherCallers
+			u, ok = callers[key]
+		}
+		if !ok {
+			u = &use{firstSeen: now}
+			callers[key] = u
+		}
+		u.count++
+		u.unlogged++
+		u.lastSeen = now
+
+		recorded := Use{Entry: e, Uses: u.unlogged}
+		if u.logged.IsZero() || now.Sub(u.logged) >= r.opts.LogInterval {
+			recorded.Log = true
+			u.logged = now
+			u.unlogged = 0
+		}
+		uses = append(uses, recorded)
+	}
+	return uses
+}
+
+// Usage reports every entry, used or not, with its callers, the most
+// frequent first.
+func (r *Registry) Usage() []models.DeprecationUsage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := make([]models.DeprecationUsage, 0, len(r.entries))
+	for i, e := range r.entries {
+		usage := models.DeprecationUsage{
+			Route:   e.MethodRoute(),
+			Field:   e.Field,
+			Since:   e.Since,
+			Sunset:  e.Sunset,
+			Link:    e.Link,
+			Callers: make([]models.DeprecationCaller, 0, len(r.usage[i])),
+		}
+		for caller, u := range r.usage[i] {
+			usage.Uses += u.count
+			usage.Callers = append(usage.Callers, models.DeprecationCaller{
+				Caller:    caller,
+				Uses:      u.count,
+				FirstSeen: u.firstSeen,
+				LastSeen:  u.lastSeen,
+			})
+		}
+		slices.SortFunc(usage.Callers, func(a, b models.DeprecationCaller) int {
+			return cmp.Or(cmp.Compare(b.Uses, a.Uses), cmp.Compare(a.Caller, b.Caller))
+		})
+		report = append(report, usage)
+	}
+	return report
+}
+
+// bare strips the patterns from the variables of template, so that
+// "/quotes/{id:[0-9]+}" becomes "/quotes/{id}".
+func bare(template string) string {
+	if !strings.Contains(template, ":") {
+		return template
+	}
+	var b strings.Builder
+	depth := 0
+	skipping := false
+	for _, c := range template {
+		switch {
+		case c == '{':
+			depth++
+			if depth == 1 {
+				skipping = false
+			}
+		case c == '}':
+			depth--
+			if depth == 0 {
+				skipping = false
+			}
+		case c == ':' && depth == 1:
+			skipping = true
+		}
+		if skipping && depth > 0 {
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,118 @@
+package deprecation_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"quotes-service/internal/lib/deprecation"
+)
+
+func TestParseRoute(t *testing.T) {
+	tests := []struct {
+		route          string
+		expectedMethod string
+		expectedRoute  string
+		wantErr        bool
+	}{
+		{route: "GET /quotes/{id}", expectedMethod: "GET", expectedRoute: "/quotes/{id}"},
+		{route: "/quotes/digest", expectedRoute: "/quotes/digest"},
+		{route: "  DELETE   /authors/{name}/quotes ", expectedMethod: "DELETE", expectedRoute: "/authors/{name}/quotes"},
+		{route: "get /quotes", wantErr: true},
+		{route: "FETCH /quotes", wantErr: true},
+		{route: "GET quotes", wantErr: true},
+		{route: "", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.route, func(t *testing.T) {
+			method, route, err := deprecation.ParseRoute(tc.route)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %q %q", method, route)
+				}
+				return
+			}
+			if err != nil || method != tc.expectedMethod || route != tc.expectedRoute {
+				t.Fatalf("expected %q %q, got %q %q (%v)", tc.expectedMethod, tc.expectedRoute, method, route, err)
+			}
+		})
+	}
+}
+
+func newRegistry(now *time.Time, maxCallers int) *deprecation.Registry {
+	sunset := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	return deprecation.New([]deprecation.Entry{
+		{Method: "GET", Route: "/quotes/digest", Sunset: sunset, Link: "https://docs.example.com/digest"},
+		{Route: "/quotes/{id}", Field: "author_key", Sunset: sunset, Link: "https://docs.example.com/author-key"},
+		{Route: "/authors", Sunset: sunset, Link: "https://docs.example.com/authors"},
+	}, deprecation.Options{LogInterval: time.Hour, MaxCallers: maxCallers}, deprecation.WithClock(func() time.Time { return *now }))
+}
+
+func TestRecord(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	registry := newRegistry(&now, 0)
+
+	steps := []struct {
+		name     string
+		advance  time.Duration
+		method   string
+		template string
+		caller   string
+		expected string
+	}{
+		{name: "first use is logged", method: "GET", template: "/quotes/digest", caller: "ip:1.2.3.4", expected: "[GET /quotes/digest log=true uses=1]"},
+		{name: "again within the interval", advance: time.Minute, method: "GET", template: "/quotes/digest", caller: "ip:1.2.3.4", expected: "[GET /quotes/digest log=false uses=1]"},
+		{name: "another caller is logged apart", method: "GET", template: "/quotes/digest", caller: "principal:alice", expected: "[GET /quotes/digest log=true uses=1]"},
+		{name: "third use within the interval", advance: time.Minute, method: "GET", template: "/quotes/digest", caller: "ip:1.2.3.4", expected: "[GET /quotes/digest log=false uses=2]"},
+		{name: "after the interval with the uses since", advance: time.Hour, method: "GET", template: "/quotes/digest", caller: "ip:1.2.3.4", expected: "[GET /quotes/digest log=true uses=3]"},
+		{name: "other method", method: "POST", template: "/quotes/digest", caller: "ip:1.2.3.4", expected: "[]"},
+		{name: "field on any method", method: "PUT", template: "/quotes/{id}", caller: "ip:1.2.3.4", expected: "[/quotes/{id} field author_key log=true uses=1]"},
+		{name: "template with patterns", method: "GET", template: "/quotes/{id:[0-9]{1,19}}", caller: "ip:1.2.3.4", expected: "[/quotes/{id} field author_key log=false uses=1]"},
+		{name: "not deprecated", method: "GET", template: "/quotes", caller: "ip:1.2.3.4", expected: "[]"},
+	}
+
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		uses := registry.Record(step.method, step.template, step.caller)
+		got := make([]string, 0, len(uses))
+		for _, use := range uses {
+			got = append(got, fmt.Sprintf("%s log=%v uses=%d", use.Subject(), use.Log, use.Uses))
+		}
+		if fmt.Sprint(got) != step.expected {
+			t.Fatalf("%s: expected %s, got %v", step.name, step.expected, got)
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	registry := newRegistry(&now, 2)
+
+	for _, caller := range []string{"principal:alice", "ip:1.2.3.4", "principal:alice", "principal:bob", "principal:carol", "principal:alice"} {
+		now = now.Add(time.Minute)
+		registry.Record("GET", "/quotes/digest", caller)
+	}
+
+	usage := registry.Usage()
+	if len(usage) != 3 {
+		t.Fatalf("expected every entry reported, got %d", len(usage))
+	}
+	digest := usage[0]
+	if digest.Route != "GET /quotes/digest" || digest.Uses != 6 || len(digest.Callers) != 3 {
+		t.Fatalf("unexpected digest usage %+v", digest)
+	}
+	var callers []string
+	for _, c := range digest.Callers {
+		callers = append(callers, fmt.Sprintf("%s=%d", c.Caller, c.Uses))
+	}
+	if fmt.Sprint(callers) != "[principal:alice=3 other=2 ip:1.2.3.4=1]" {
+		t.Fatalf("unexpected callers %v", callers)
+	}
+	if alice := digest.Callers[0]; !alice.FirstSeen.Equal(time.Date(2026, 6, 1, 0, 1, 0, 0, time.UTC)) || !alice.LastSeen.Equal(now) {
+		t.Fatalf("unexpected first and last seen %+v", alice)
+	}
+	if field := usage[1]; field.Route != "/quotes/{id}" || field.Field != "author_key" || field.Uses != 0 || len(field.Callers) != 0 {
+		t.Fatalf("expected the unused field reported without callers, got %+v", field)
+	}
+}
@@ -0,0 +1,120 @@
+package fairpick
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Defaults for NewCommitments: a commitment is good for a few minutes, and
+// a burst of them cannot grow the pending set without bound.
+const (
+	DefaultCommitmentTTL  = 5 * time.Minute
+	DefaultMaxCommitments = 10_000
+)
+
+// Commitments holds the server nonces committed to and not yet used. Each
+// is taken once, by the pick it was committed for, and expires after a
+// TTL; past the cap the oldest is dropped first. They live in memory, so
+// a pick must reach the instance that issued its commitment.
+type Commitments struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxPending int
+	now        func() time.Time
+	pending    map[string]*list.Element
+	order      *list.List
+}
+
+type commitment struct {
+	commitment  string
+	serverNonce string
+	expiresAt   time.Time
+}
+
+type Option func(*Commitments)
+
+// WithClock overrides the time source, mainly for tests.
+func WithClock(now func() time.Time) Option {
+	return func(c *Commitments) {
+		c.now = now
+	}
+}
+
+func NewCommitments(ttl time.Duration, maxPending int, opts ...Option) *Commitments {
+	c := &Commitments{
+		ttl:        ttl,
+		maxPending: maxPending,
+		now:        time.Now,
+		pending:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Issue draws a server nonce and returns the commitment to it, to be given
+// to the client before it chooses its nonce, and when it expires.
+func (c *Commitments) Issue() (string, time.Time, error) {
+	serverNonce, err := NewServerNonce()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	commitmentHex, err := Commit(serverNonce)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiresAt := c.now().Add(c.ttl)
+	c.pending[commitmentHex] = c.order.PushBack(&commitment{commitment: commitmentHex, serverNonce: serverNonce, expiresAt: expiresAt})
+	c.evict()
+	return commitmentHex, expiresAt, nil
+}
+
+// Take returns the server nonce committed to and forgets it, so it is
+// never used for a second pick. It returns false for a commitment that
+// was never issued, has expired or has been taken.
+func (c *Commitments) Take(commitmentHex string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.pending[commitmentHex]
+	if !ok {
+		return "", false
+	}
+	c.remove(el)
+	e := el.Value.(*commitment)
+	if !c.now().Before(e.expiresAt) {
+		return "", false
+	}
+	return e.serverNonce, true
+}
+
+// Len returns the number of commitments pending.
+func (c *Commitments) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// evict drops expired commitments and, past the cap, the oldest. They are
+// issued with the same TTL, so the oldest expire first.
+func (c *Commitments) evict() {
+	now := c.now()
+	for el := c.order.Front(); el != nil; el = c.order.Front() {
+		withinCap := c.maxPending <= 0 || c.order.Len() <= c.maxPending
+		if withinCap && now.Before(el.Value.(*commitment).expiresAt) {
+			return
+		}
+		c.remove(el)
+	}
+}
+
+func (c *Commitments) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.pending, el.Value.(*commitment).commitment)
+}
@@ -0,0 +1,61 @@
+package fairpick_test
+
+import (
+	"testing"
+	"time"
+
+	"quotes-service/internal/lib/fairpick"
+)
+
+func TestCommitments(t *testing.T) {
+	clock := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	c := fairpick.NewCommitments(time.Minute, 2, fairpick.WithClock(func() time.Time { return clock }))
+
+	commitment, expiresAt, err := c.Issue()
+	if err != nil {
+		t.Fatalf("failed to issue: %v", err)
+	}
+	if !expiresAt.Equal(clock.Add(time.Minute)) {
+		t.Fatalf("expected the commitment to expire in a minute, got %v", expiresAt)
+	}
+	serverNonce, ok := c.Take(commitment)
+	if !ok {
+		t.Fatal("expected the issued commitment to be taken")
+	}
+	if got, err := fairpick.Commit(serverNonce); err != nil || got != commitment {
+		t.Fatalf("expected the server nonce committed to, got commitment %q (%v)", got, err)
+	}
+	if _, ok := c.Take(commitment); ok {
+		t.Fatal("expected a commitment to be taken once only")
+	}
+	if _, ok := c.Take("unknown"); ok {
+		t.Fatal("expected an unknown commitment refused")
+	}
+
+	expired, _, err := c.Issue()
+	if err != nil {
+		t.Fatalf("failed to issue: %v", err)
+	}
+	clock = clock.Add(time.Minute)
+	if _, ok := c.Take(expired); ok {
+		t.Fatal("expected an expired commitment refused")
+	}
+
+	var issued []string
+	for range 3 {
+		commitment, _, err := c.Issue()
+		if err != nil {
+			t.Fatalf("failed to issue: %v", err)
+		}
+		issued = append(issued, commitment)
+	}
+	if c.Len() != 2 {
+		t.Fatalf("expected 2 pending commitments, got %d", c.Len())
+	}
+	if _, ok := c.Take(issued[0]); ok {
+		t.Fatal("expected the oldest commitment dropped past the cap")
+	}
+	if _, ok := c.Take(issued[2]); !ok {
+		t.Fatal("expected the newest commitment kept")
+	}
+}
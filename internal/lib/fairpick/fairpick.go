@@ -0,0 +1,120 @@
+// Package fairpick picks from a pool in a way the client can check, by
+// commit and reveal. The server draws its nonce first and hands out only
+// a commitment to it, the SHA-256 of the nonce; the client then chooses
+// its own nonce, and the index is derived from both. The server nonce
+// disclosed with the pick must match the commitment the client held
+// before choosing, so the server cannot redraw it to steer the pick, and
+// the client, not knowing it, cannot steer the pick either.
+package fairpick
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// Algorithm names the derivation, for clients to check they recompute
+// the same one.
+const Algorithm = "hmac-sha256"
+
+const (
+	// serverNonceBytes is the randomness of a server nonce, hex encoded.
+	serverNonceBytes = 32
+	// MaxClientNonceChars bounds the client's nonce, which it may choose
+	// freely otherwise.
+	MaxClientNonceChars = 128
+)
+
+var (
+	ErrInvalidServerNonce = errors.New("server nonce must be 64 hex characters")
+	ErrInvalidClientNonce = fmt.Errorf("client nonce must be 1 to %d characters", MaxClientNonceChars)
+	ErrEmptyPool          = errors.New("pool is empty")
+	ErrCommitmentMismatch = errors.New("server nonce does not match the commitment")
+	ErrDigestMismatch     = errors.New("digest does not match the nonces")
+	ErrIndexMismatch      = errors.New("index does not match the digest and pool size")
+)
+
+// Derivation is a pick and what it was derived from.
+type Derivation struct {
+	ServerNonce string
+	// Commitment is the SHA-256 of the decoded server nonce, hex encoded,
+	// which the client was given before it chose ClientNonce.
+	Commitment  string
+	ClientNonce string
+	PoolSize    int
+	// Digest is HMAC-SHA256 keyed with the decoded server nonce over the
+	// client nonce, hex encoded.
+	Digest string
+	// Index is the first 8 bytes of the digest as a big-endian integer,
+	// modulo PoolSize. Against a 64-bit digest the modulo favors no index
+	// by more than PoolSize in 2^64.
+	Index int
+}
+
+// NewServerNonce returns a fresh server nonce.
+func NewServerNonce() (string, error) {
+	b := make([]byte, serverNonceBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("read random: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Commit returns the commitment to serverNonce.
+func Commit(serverNonce string) (string, error) {
+	key, err := hex.DecodeString(serverNonce)
+	if err != nil || len(key) != serverNonceBytes {
+		return "", ErrInvalidServerNonce
+	}
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Derive picks an index into a pool of poolSize items from the nonces.
+func Derive(serverNonce, clientNonce string, poolSize int) (Derivation, error) {
+	key, err := hex.DecodeString(serverNonce)
+	if err != nil || len(key) != serverNonceBytes {
+		return Derivation{}, ErrInvalidServerNonce
+	}
+	if n := len([]rune(clientNonce)); n == 0 || n > MaxClientNonceChars {
+		return Derivation{}, ErrInvalidClientNonce
+	}
+	if poolSize <= 0 {
+		return Derivation{}, ErrEmptyPool
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(clientNonce))
+	sum := mac.Sum(nil)
+	commitment := sha256.Sum256(key)
+	return Derivation{
+		ServerNonce: serverNonce,
+		Commitment:  hex.EncodeToString(commitment[:]),
+		ClientNonce: clientNonce,
+		PoolSize:    poolSize,
+		Digest:      hex.EncodeToString(sum),
+		Index:       int(binary.BigEndian.Uint64(sum[:8]) % uint64(poolSize)),
+	}, nil
+}
+
+// Verify recomputes d from its nonces and pool size and reports the first
+// of its Commitment, Digest and Index that does not match.
+func Verify(d Derivation) error {
+	want, err := Derive(d.ServerNonce, d.ClientNonce, d.PoolSize)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(want.Commitment), []byte(d.Commitment)) {
+		return ErrCommitmentMismatch
+	}
+	if !hmac.Equal([]byte(want.Digest), []byte(d.Digest)) {
+		return ErrDigestMismatch
+	}
+	if want.Index != d.Index {
+		return ErrIndexMismatch
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package fairpick_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"quotes-service/internal/lib/fairpick"
+)
+
+const serverNonce = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+func TestDerive(t *testing.T) {
+	d, err := fairpick.Derive(serverNonce, "giveaway-2024-03", 50)
+	if err != nil {
+		t.Fatalf("failed to derive: %v", err)
+	}
+	again, err := fairpick.Derive(serverNonce, "giveaway-2024-03", 50)
+	if err != nil || again != d {
+		t.Fatalf("expected the same derivation twice, got %+v and %+v (%v)", d, again, err)
+	}
+	if d.Index < 0 || d.Index >= 50 || len(d.Digest) != 64 {
+		t.Fatalf("expected an index below 50 and a 64 character digest, got %+v", d)
+	}
+
+	other, err := fairpick.Derive(serverNonce, "giveaway-2024-04", 1<<20)
+	if err != nil {
+		t.Fatalf("failed to derive: %v", err)
+	}
+	if other.Digest == d.Digest {
+		t.Fatal("expected another client nonce to give another digest")
+	}
+
+	if commitment, err := fairpick.Commit(serverNonce); err != nil || commitment != d.Commitment {
+		t.Fatalf("expected the derivation to carry the commitment %q, got %q (%v)", commitment, d.Commitment, err)
+	}
+
+	nonce, err := fairpick.NewServerNonce()
+	if err != nil {
+		t.Fatalf("failed to make a server nonce: %v", err)
+	}
+	if _, err := fairpick.Derive(nonce, "x", 1); err != nil {
+		t.Fatalf("expected a fresh server nonce to be usable, got %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	honest, err := fairpick.Derive(serverNonce, "giveaway-2024-03", 50)
+	if err != nil {
+		t.Fatalf("failed to derive: %v", err)
+	}
+	tamper := func(change func(d *fairpick.Derivation)) fairpick.Derivation {
+		d := honest
+		change(&d)
+		return d
+	}
+
+	tests := []struct {
+		name        string
+		derivation  fairpick.Derivation
+		expectedErr error
+	}{
+		{name: "honest", derivation: honest},
+		{
+			name: "server nonce redrawn after the commitment",
+			derivation: tamper(func(d *fairpick.Derivation) {
+				d.ServerNonce = strings.Repeat("ab", 32)
+			}),
+			expectedErr: fairpick.ErrCommitmentMismatch,
+		},
+		{
+			name: "other server nonce and commitment",
+			derivation: tamper(func(d *fairpick.Derivation) {
+				d.ServerNonce = strings.Repeat("ab", 32)
+				d.Commitment, _ = fairpick.Commit(d.ServerNonce)
+			}),
+			expectedErr: fairpick.ErrDigestMismatch,
+		},
+		{
+			name:        "other client nonce",
+			derivation:  tamper(func(d *fairpick.Derivation) { d.ClientNonce = "giveaway-2024-04" }),
+			expectedErr: fairpick.ErrDigestMismatch,
+		},
+		{
+			name:        "other index",
+			derivation:  tamper(func(d *fairpick.Derivation) { d.Index = (d.Index + 1) % d.PoolSize }),
+			expectedErr: fairpick.ErrIndexMismatch,
+		},
+		{
+			name:        "other pool size",
+			derivation:  tamper(func(d *fairpick.Derivation) { d.PoolSize = 7 }),
+			expectedErr: fairpick.ErrIndexMismatch,
+		},
+		{
+			name:        "forged digest",
+			derivation:  tamper(func(d *fairpick.Derivation) { d.Digest = strings.Repeat("0", 64) }),
+			expectedErr: fairpick.ErrDigestMismatch,
+		},
+		{
+			name:        "short server nonce",
+			derivation:  tamper(func(d *fairpick.Derivation) { d.ServerNonce = "abcd" }),
+			expectedErr: fairpick.ErrInvalidServerNonce,
+		},
+		{
+			name:        "empty client nonce",
+			derivation:  tamper(func(d *fairpick.Derivation) { d.ClientNonce = "" }),
+			expectedErr: fairpick.ErrInvalidClientNonce,
+		},
+		{
+			name:        "empty pool",
+			derivation:  tamper(func(d *fairpick.Derivation) { d.PoolSize = 0 }),
+			expectedErr: fairpick.ErrEmptyPool,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := fairpick.Verify(tc.derivation); !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
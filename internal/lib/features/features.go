@@ -0,0 +1,134 @@
+// Package features holds the flags that turn optional route groups on and
+// off. A flag is read once when the routes are registered, unless it is
+// dynamic, in which case it can be flipped while the service runs.
+package features
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"quotes-service/internal/models"
+)
+
+// The features that can be turned off.
+const (
+	AuthorFeeds = "author_feeds"
+	Collections = "collections"
+	Favorites   = "favorites"
+	Schema      = "schema"
+	Similar     = "similar"
+	TextStats   = "text_stats"
+)
+
+// Defaults holds every feature with its state when the config leaves it
+// out.
+var Defaults = map[string]bool{
+	AuthorFeeds: true,
+	Collections: true,
+	Favorites:   true,
+	Schema:      true,
+	Similar:     true,
+	TextStats:   true,
+}
+
+var (
+	ErrUnknown = errors.New("unknown feature")
+	ErrStatic  = errors.New("feature cannot change at runtime")
+)
+
+// Set is the state of every feature: those in Defaults and those added
+// with Add. It is safe for concurrent use.
+type Set struct {
+	mu      sync.RWMutex
+	enabled map[string]bool
+	dynamic map[string]bool
+}
+
+// New returns the Defaults overridden by enabled, with the features named
+// in dynamic open to change at runtime. Names not in Defaults are ignored.
+func New(enabled map[string]bool, dynamic []string) *Set {
+	s := &Set{
+		enabled: make(map[string]bool, len(Defaults)),
+		dynamic: make(map[string]bool, len(dynamic)),
+	}
+	for name, on := range Defaults {
+		if override, ok := enabled[name]; ok {
+			on = override
+		}
+		s.enabled[name] = on
+	}
+	for _, name := range dynamic {
+		if _, ok := Defaults[name]; ok {
+			s.dynamic[name] = true
+		}
+	}
+	return s
+}
+
+// Add registers a feature that is not in Defaults, such as a flag of the
+// hardening profile. It must be called before the Set is shared.
+func (s *Set) Add(name string, enabled, dynamic bool) {
+	s.enabled[name] = enabled
+	if dynamic {
+		s.dynamic[name] = true
+	}
+}
+
+// Enabled reports whether the feature is on now.
+func (s *Set) Enabled(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.enabled[name]
+}
+
+// Dynamic reports whether the feature can change at runtime.
+func (s *Set) Dynamic(name string) bool {
+	return s.dynamic[name]
+}
+
+// Set turns a dynamic feature on or off. It fails with ErrUnknown for an
+// unknown name and ErrStatic for a feature that is not dynamic.
+func (s *Set) Set(name string, enabled bool) error {
+	if !s.known(name) {
+		return ErrUnknown
+	}
+	if !s.dynamic[name] {
+		return ErrStatic
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enabled[name] = enabled
+	return nil
+}
+
+// Get returns the state of one feature, and false for an unknown name.
+func (s *Set) Get(name string) (models.FeatureFlag, bool) {
+	if !s.known(name) {
+		return models.FeatureFlag{}, false
+	}
+	return models.FeatureFlag{Name: name, Enabled: s.Enabled(name), Dynamic: s.dynamic[name]}, true
+}
+
+// List returns the state of every feature, by name.
+func (s *Set) List() []models.FeatureFlag {
+	s.mu.RLock()
+	names := slices.Collect(maps.Keys(s.enabled))
+	s.mu.RUnlock()
+	flags := make([]models.FeatureFlag, 0, len(names))
+	for _, name := range names {
+		flag, _ := s.Get(name)
+		flags = append(flags, flag)
+	}
+	slices.SortFunc(flags, func(a, b models.FeatureFlag) int { return strings.Compare(a.Name, b.Name) })
+	return flags
+}
+
+func (s *Set) known(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.enabled[name]
+	return ok
+}
@@ -0,0 +1,66 @@
+package features_test
+
+import (
+	"errors"
+	"testing"
+
+	"quotes-service/internal/lib/features"
+)
+
+func TestSet(t *testing.T) {
+	flags := features.New(map[string]bool{features.Collections: false, "graphql": true}, []string{features.TextStats, "graphql"})
+
+	tests := []struct {
+		name            string
+		feature         string
+		enable          bool
+		expectedErr     error
+		expectedEnabled bool
+	}{
+		{name: "dynamic off", feature: features.TextStats, enable: false, expectedEnabled: false},
+		{name: "dynamic on", feature: features.TextStats, enable: true, expectedEnabled: true},
+		{name: "static", feature: features.Collections, enable: true, expectedErr: features.ErrStatic, expectedEnabled: false},
+		{name: "static default", feature: features.Favorites, enable: false, expectedErr: features.ErrStatic, expectedEnabled: true},
+		{name: "unknown", feature: "graphql", enable: true, expectedErr: features.ErrUnknown, expectedEnabled: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := flags.Set(tc.feature, tc.enable); !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if got := flags.Enabled(tc.feature); got != tc.expectedEnabled {
+				t.Fatalf("expected enabled %v, got %v", tc.expectedEnabled, got)
+			}
+		})
+	}
+
+	list := flags.List()
+	if len(list) != len(features.Defaults) {
+		t.Fatalf("expected %d features, got %d", len(features.Defaults), len(list))
+	}
+	for i := 1; i < len(list); i++ {
+		if list[i-1].Name >= list[i].Name {
+			t.Fatalf("expected features sorted by name, got %q before %q", list[i-1].Name, list[i].Name)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	flags := features.New(nil, nil)
+	flags.Add("method.delete", false, true)
+	flags.Add("group.admin", true, false)
+
+	if flags.Enabled("method.delete") || !flags.Enabled("group.admin") {
+		t.Fatal("expected added features in the state they were added in")
+	}
+	if err := flags.Set("method.delete", true); err != nil || !flags.Enabled("method.delete") {
+		t.Fatalf("expected a dynamic added feature to turn on, got %v", err)
+	}
+	if err := flags.Set("group.admin", false); !errors.Is(err, features.ErrStatic) {
+		t.Fatalf("expected a static added feature to stay, got %v", err)
+	}
+	if len(flags.List()) != len(features.Defaults)+2 {
+		t.Fatalf("expected the added features listed, got %+v", flags.List())
+	}
+}
@@ -0,0 +1,37 @@
+// Package fingerprint identifies quotes that are the same up to case,
+// punctuation, spacing and typography.
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"quotes-service/internal/lib/authorname"
+	"quotes-service/internal/lib/normalize"
+	"quotes-service/internal/lib/tokenizer"
+)
+
+// Of returns the fingerprint of a quote. Two quotes with the same words by
+// the same author get the same fingerprint, whether typed plainly or with
+// curly quotes and dashes.
+func Of(text, author string) string {
+	h := sha256.New()
+	h.Write([]byte(authorname.Key(author)))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.Join(tokenizer.Tokens(normalize.Plain(text)), " ")))
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// Index is a set of fingerprints. It is not safe for concurrent use.
+type Index map[string]struct{}
+
+// Add records a quote and reports whether it was new.
+func (idx Index) Add(text, author string) bool {
+	fp := Of(text, author)
+	if _, ok := idx[fp]; ok {
+		return false
+	}
+	idx[fp] = struct{}{}
+	return true
+}
@@ -0,0 +1,46 @@
+package fingerprint_test
+
+import (
+	"testing"
+
+	"quotes-service/internal/lib/fingerprint"
+)
+
+func TestOf(t *testing.T) {
+	base := fingerprint.Of("Stay hungry, stay foolish.", "Steve Jobs")
+	tests := []struct {
+		name   string
+		text   string
+		author string
+		same   bool
+	}{
+		{name: "identical", text: "Stay hungry, stay foolish.", author: "Steve Jobs", same: true},
+		{name: "case and punctuation", text: "stay HUNGRY  stay foolish", author: "steve jobs", same: true},
+		{name: "typographic quotes", text: "“Stay hungry, stay foolish.”", author: "Steve Jobs", same: true},
+		{name: "dashes and no-break spaces", text: "Stay\u00a0hungry—stay\u00a0foolish.", author: "Steve Jobs", same: true},
+		{name: "other author", text: "Stay hungry, stay foolish.", author: "Stewart Brand", same: false},
+		{name: "other words", text: "Stay hungry, stay humble.", author: "Steve Jobs", same: false},
+		{name: "words moved between fields", text: "Jobs Stay hungry, stay foolish.", author: "Steve", same: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := fingerprint.Of(tc.text, tc.author) == base; got != tc.same {
+				t.Errorf("expected same=%v, got %v", tc.same, got)
+			}
+		})
+	}
+}
+
+func TestIndexAdd(t *testing.T) {
+	idx := fingerprint.Index{}
+	if !idx.Add("Hello, world", "Anon") {
+		t.Error("expected the first quote to be new")
+	}
+	if idx.Add("hello world!", "ANON") {
+		t.Error("expected the duplicate to be rejected")
+	}
+	if len(idx) != 1 {
+		t.Errorf("expected 1 entry, got %d", len(idx))
+	}
+}
@@ -0,0 +1,95 @@
+// Package hardening names the route groups and methods a deployment can
+// turn off, so that a security baseline can take whole capabilities, such
+// as every delete or the admin API, out of a service without a rebuild.
+// Each group and method is a feature flag, on unless the profile turns it
+// off.
+package hardening
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Mode is how a request to a route that is off is answered.
+type Mode string
+
+const (
+	// ModeAuto hides a group that is off behind a 404, as if its routes
+	// did not exist, and refuses a method that is off with a 405.
+	ModeAuto Mode = "auto"
+	// ModeNotFound answers 404 to both.
+	ModeNotFound Mode = "not_found"
+	// ModeMethodNotAllowed answers 405 to both.
+	ModeMethodNotAllowed Mode = "method_not_allowed"
+)
+
+// Groups are the route groups, each named after the first segment of the
+// paths it covers, except health, which is /healthz and /readyz, and
+// metrics, which is the metrics path wherever it is.
+var Groups = []string{
+	"admin",
+	"authors",
+	"collections",
+	"debug",
+	"exports",
+	"favorites",
+	"health",
+	"imports",
+	"me",
+	"metrics",
+	"quotes",
+	"schema",
+	"stats",
+}
+
+// Methods are the methods that can be turned off.
+var Methods = []string{
+	http.MethodGet,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// ParseMode validates a mode name from configuration.
+func ParseMode(s string) (Mode, error) {
+	switch mode := Mode(s); mode {
+	case ModeAuto, ModeNotFound, ModeMethodNotAllowed:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown hardening response %q", s)
+	}
+}
+
+// GroupFlag returns the name of the feature flag of a route group.
+func GroupFlag(group string) string {
+	return "group." + group
+}
+
+// MethodFlag returns the name of the feature flag of a method.
+func MethodFlag(method string) string {
+	return "method." + strings.ToLower(method)
+}
+
+// Group returns the group of the route with the path template, or "" for
+// one in no group. metricsPath is where the metrics are served.
+func Group(template, metricsPath string) string {
+	if template == metricsPath {
+		return "metrics"
+	}
+	segment, _, _ := strings.Cut(strings.TrimPrefix(template, "/"), "/")
+	switch segment {
+	case "healthz", "readyz":
+		return "health"
+	case "metrics":
+		// The metrics path has moved, so this is no route of theirs.
+		return ""
+	}
+	for _, group := range Groups {
+		if group == segment {
+			return group
+		}
+	}
+	return ""
+}
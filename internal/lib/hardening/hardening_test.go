@@ -0,0 +1,44 @@
+package hardening_test
+
+import (
+	"testing"
+
+	"quotes-service/internal/lib/hardening"
+)
+
+func TestGroup(t *testing.T) {
+	tests := []struct {
+		template    string
+		metricsPath string
+		expected    string
+	}{
+		{template: "/quotes", metricsPath: "/metrics", expected: "quotes"},
+		{template: "/quotes/{id:[0-9]+}/favorite", metricsPath: "/metrics", expected: "quotes"},
+		{template: "/favorites", metricsPath: "/metrics", expected: "favorites"},
+		{template: "/admin/features/{name}", metricsPath: "/metrics", expected: "admin"},
+		{template: "/healthz", metricsPath: "/metrics", expected: "health"},
+		{template: "/readyz", metricsPath: "/metrics", expected: "health"},
+		{template: "/metrics", metricsPath: "/metrics", expected: "metrics"},
+		{template: "/internal/prom", metricsPath: "/internal/prom", expected: "metrics"},
+		{template: "/metrics", metricsPath: "/internal/prom", expected: ""},
+		{template: "/debug/pprof/", metricsPath: "/metrics", expected: "debug"},
+		{template: "unmatched", metricsPath: "/metrics", expected: ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.template, func(t *testing.T) {
+			if got := hardening.Group(tc.template, tc.metricsPath); got != tc.expected {
+				t.Fatalf("expected group %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestFlags(t *testing.T) {
+	if got := hardening.GroupFlag("admin"); got != "group.admin" {
+		t.Fatalf("expected group.admin, got %q", got)
+	}
+	if got := hardening.MethodFlag("DELETE"); got != "method.delete" {
+		t.Fatalf("expected method.delete, got %q", got)
+	}
+}
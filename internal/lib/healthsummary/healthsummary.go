@@ -0,0 +1,218 @@
+// Package healthsummary keeps rolling counts of the requests served over
+// the last hour, for a quick answer to whether the service is failing
+// without a metrics stack.
+package healthsummary
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"quotes-service/internal/models"
+)
+
+const (
+	// span is the slice of time a bucket covers.
+	span = time.Minute
+	// buckets is how many slices are kept: an hour's worth.
+	buckets = 60
+	// latencyBuckets is how many bounds the latency histogram has.
+	latencyBuckets = 30
+)
+
+// Windows are the spans a Summary reports, each up to a minute shorter
+// than its length, since the current minute is still under way.
+var Windows = []time.Duration{5 * time.Minute, 30 * time.Minute, time.Hour}
+
+// latencyBounds are the upper bounds of the latency histogram of each
+// bucket, from 500µs growing by half up to about a minute. Percentiles
+// are estimated from it, so they are accurate to within a bucket.
+var latencyBounds = func() []time.Duration {
+	bounds := make([]time.Duration, latencyBuckets)
+	bound := float64(500 * time.Microsecond)
+	for i := range bounds {
+		bounds[i] = time.Duration(bound)
+		bound *= 1.5
+	}
+	return bounds
+}()
+
+// Observation is a finished request offered to a Window.
+type Observation struct {
+	Status   int
+	Duration time.Duration
+	// StorageError is set when the request failed because the store did.
+	StorageError bool
+	// Panic is set when the handler panicked.
+	Panic bool
+}
+
+// Window counts the requests of the last hour in a ring of one-minute
+// buckets. A bucket is emptied when the ring comes round to it again, so
+// memory stays the same whatever the traffic. It is safe for concurrent
+// use.
+type Window struct {
+	mu      sync.Mutex
+	buckets [buckets]bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	start         time.Time
+	requests      int64
+	classes       [5]int64
+	storageErrors int64
+	panics        int64
+	// latencies counts requests by latencyBounds, the last entry those
+	// slower than every bound.
+	latencies [latencyBuckets + 1]int64
+}
+
+type Option func(*Window)
+
+// WithClock overrides the time source, mainly for tests.
+func WithClock(now func() time.Time) Option {
+	return func(w *Window) {
+		w.now = now
+	}
+}
+
+// New returns an empty Window.
+func New(opts ...Option) *Window {
+	w := &Window{now: time.Now}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Record counts obs in the bucket of the current minute.
+func (w *Window) Record(obs Observation) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	b := w.bucket(w.now())
+	b.requests++
+	if class := obs.Status/100 - 1; class >= 0 && class < len(b.classes) {
+		b.classes[class]++
+	}
+	if obs.StorageError {
+		b.storageErrors++
+	}
+	if obs.Panic {
+		b.panics++
+	}
+	i := 0
+	for i < len(latencyBounds) && obs.Duration > latencyBounds[i] {
+		i++
+	}
+	b.latencies[i]++
+}
+
+// bucket returns the bucket for now, emptying it first if it still holds
+// an older minute. The caller must hold w.mu.
+func (w *Window) bucket(now time.Time) *bucket {
+	start := now.Truncate(span)
+	b := &w.buckets[int(start.UnixNano()/int64(span)%buckets)]
+	if !b.start.Equal(start) {
+		*b = bucket{start: start}
+	}
+	return b
+}
+
+// Summary returns the counts of each of Windows as the admin API shows
+// them.
+func (w *Window) Summary() models.HealthSummary {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.now()
+	current := now.Truncate(span)
+	summary := models.HealthSummary{
+		Time:    now.UTC(),
+		Windows: make([]models.HealthWindow, len(Windows)),
+	}
+	for i, window := range Windows {
+		oldest := current.Add(-span * (time.Duration(max(window/span, 1)) - 1))
+		var total bucket
+		for j := range w.buckets {
+			b := &w.buckets[j]
+			if b.start.Before(oldest) || b.start.After(current) {
+				continue
+			}
+			total.add(b)
+		}
+		summary.Windows[i] = total.report(window)
+	}
+	return summary
+}
+
+func (b *bucket) add(other *bucket) {
+	b.requests += other.requests
+	for i := range b.classes {
+		b.classes[i] += other.classes[i]
+	}
+	b.storageErrors += other.storageErrors
+	b.panics += other.panics
+	for i := range b.latencies {
+		b.latencies[i] += other.latencies[i]
+	}
+}
+
+func (b *bucket) report(window time.Duration) models.HealthWindow {
+	report := models.HealthWindow{
+		Window:        window.String(),
+		Requests:      b.requests,
+		Statuses:      make(map[string]int64, len(b.classes)),
+		ErrorRates:    make(map[string]float64, 2),
+		StorageErrors: b.storageErrors,
+		Panics:        b.panics,
+		LatencyMS: models.LatencyPercentiles{
+			P50: b.percentile(0.50),
+			P95: b.percentile(0.95),
+			P99: b.percentile(0.99),
+		},
+	}
+	for i, count := range b.classes {
+		report.Statuses[string(rune('1'+i))+"xx"] = count
+	}
+	for _, class := range []string{"4xx", "5xx"} {
+		rate := 0.0
+		if b.requests > 0 {
+			rate = float64(report.Statuses[class]) / float64(b.requests)
+		}
+		report.ErrorRates[class] = rate
+	}
+	return report
+}
+
+// percentile estimates the q-th latency in milliseconds, interpolating
+// within the histogram bucket it falls in, or returns zero without
+// requests. Latencies past the last bound are reported as that bound.
+func (b *bucket) percentile(q float64) float64 {
+	if b.requests == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(q * float64(b.requests)))
+	var seen int64
+	for i, count := range b.latencies {
+		if seen+count < rank {
+			seen += count
+			continue
+		}
+		if i == len(latencyBounds) {
+			return milliseconds(latencyBounds[i-1])
+		}
+		lower := time.Duration(0)
+		if i > 0 {
+			lower = latencyBounds[i-1]
+		}
+		fraction := float64(rank-seen) / float64(count)
+		return milliseconds(lower + time.Duration(fraction*float64(latencyBounds[i]-lower)))
+	}
+	return milliseconds(latencyBounds[len(latencyBounds)-1])
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,124 @@
+package healthsummary_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"quotes-service/internal/lib/healthsummary"
+	"quotes-service/internal/models"
+)
+
+func record(w *healthsummary.Window, n int, obs healthsummary.Observation) {
+	for i := 0; i < n; i++ {
+		w.Record(obs)
+	}
+}
+
+// requests returns the request counts of the 5, 30 and 60 minute windows.
+func requests(summary models.HealthSummary) [3]int64 {
+	var counts [3]int64
+	for i, window := range summary.Windows {
+		counts[i] = window.Requests
+	}
+	return counts
+}
+
+func TestWindowRotation(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	w := healthsummary.New(healthsummary.WithClock(func() time.Time { return now }))
+
+	// Four requests in minute 0, two in minute 10 and one in minute 26.
+	now = start.Add(30 * time.Second)
+	record(w, 4, healthsummary.Observation{Status: http.StatusOK, Duration: time.Millisecond})
+	now = start.Add(10 * time.Minute)
+	record(w, 2, healthsummary.Observation{Status: http.StatusServiceUnavailable, Duration: time.Millisecond, StorageError: true})
+	now = start.Add(26 * time.Minute)
+	record(w, 1, healthsummary.Observation{Status: http.StatusInternalServerError, Duration: time.Millisecond, Panic: true})
+
+	tests := []struct {
+		name string
+		at   time.Duration
+		want [3]int64
+	}{
+		// Minutes 25 to 29, 0 to 29 and every one recorded.
+		{name: "end of minute 29", at: 29*time.Minute + 59*time.Second, want: [3]int64{1, 7, 7}},
+		// The 30 minute window has moved past minute 0.
+		{name: "minute 30", at: 30 * time.Minute, want: [3]int64{1, 3, 7}},
+		// So has the hour.
+		{name: "minute 60", at: time.Hour, want: [3]int64{0, 0, 3}},
+		{name: "minute 70", at: 70 * time.Minute, want: [3]int64{0, 0, 1}},
+		{name: "hours later", at: 5 * time.Hour, want: [3]int64{0, 0, 0}},
+	}
+	for _, tc := range tests {
+		now = start.Add(tc.at)
+		if got := requests(w.Summary()); got != tc.want {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+
+	// Minute 70 reuses the bucket of minute 10, which must start empty:
+	// the hour holds minute 26 and the new request.
+	now = start.Add(70 * time.Minute)
+	record(w, 1, healthsummary.Observation{Status: http.StatusOK, Duration: time.Millisecond})
+	hour := w.Summary().Windows[2]
+	if hour.Requests != 2 || hour.StorageErrors != 0 || hour.Statuses["5xx"] != 1 {
+		t.Fatalf("expected the reused bucket emptied, got %+v", hour)
+	}
+}
+
+func TestWindowCounts(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	w := healthsummary.New(healthsummary.WithClock(func() time.Time { return now }))
+
+	record(w, 6, healthsummary.Observation{Status: http.StatusOK, Duration: time.Millisecond})
+	record(w, 2, healthsummary.Observation{Status: http.StatusNotFound, Duration: time.Millisecond})
+	record(w, 1, healthsummary.Observation{Status: http.StatusInternalServerError, Duration: time.Millisecond, StorageError: true})
+	now = now.Add(2 * time.Minute)
+	record(w, 1, healthsummary.Observation{Status: http.StatusInternalServerError, Duration: time.Millisecond, Panic: true})
+
+	got := w.Summary().Windows[0]
+	if got.Window != "5m0s" || got.Requests != 10 || got.StorageErrors != 1 || got.Panics != 1 {
+		t.Fatalf("unexpected window %+v", got)
+	}
+	if got.Statuses["2xx"] != 6 || got.Statuses["4xx"] != 2 || got.Statuses["5xx"] != 2 || got.Statuses["3xx"] != 0 {
+		t.Fatalf("unexpected statuses %v", got.Statuses)
+	}
+	if got.ErrorRates["4xx"] != 0.2 || got.ErrorRates["5xx"] != 0.2 {
+		t.Fatalf("unexpected error rates %v", got.ErrorRates)
+	}
+}
+
+func TestPercentiles(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	w := healthsummary.New(healthsummary.WithClock(func() time.Time { return now }))
+
+	if got := w.Summary().Windows[0].LatencyMS; got != (models.LatencyPercentiles{}) {
+		t.Fatalf("expected zero latencies without requests, got %+v", got)
+	}
+
+	// The percentiles fall in different minutes' buckets, which must add
+	// up.
+	record(w, 90, healthsummary.Observation{Status: http.StatusOK, Duration: time.Millisecond})
+	now = now.Add(time.Minute)
+	record(w, 8, healthsummary.Observation{Status: http.StatusOK, Duration: 100 * time.Millisecond})
+	record(w, 2, healthsummary.Observation{Status: http.StatusOK, Duration: 2 * time.Second})
+
+	got := w.Summary().Windows[0].LatencyMS
+	for _, p := range []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"p50", got.P50, 1},
+		{"p95", got.P95, 100},
+		{"p99", got.P99, 2000},
+	} {
+		// Estimates are accurate to a histogram bucket, half as wide again
+		// as the one below.
+		if p.got < p.want/1.5 || p.got > p.want*1.5 {
+			t.Fatalf("%s: expected about %vms, got %vms", p.name, p.want, p.got)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Package jsoncache keeps a serialized response body keyed by the storage
+// version it was built from.
+package jsoncache
+
+import (
+	"sync"
+	"time"
+)
+
+// Entry is a cached response body and the validators that go with it.
+// Body must not be modified once the entry is stored.
+type Entry struct {
+	Body         []byte
+	LastModified time.Time
+	// Total is the length of the whole list when Body holds one page.
+	Total int
+}
+
+// Cache holds at most one entry, valid only for the storage version it was
+// stored with. Bodies larger than maxBytes are not kept, which bounds the
+// memory the cache can pin.
+type Cache struct {
+	maxBytes int
+
+	mu      sync.RWMutex
+	cached  bool
+	version uint64
+	entry   Entry
+}
+
+func New(maxBytes int) *Cache {
+	return &Cache{maxBytes: maxBytes}
+}
+
+// Get returns the entry stored for version. An entry stored for an older
+// version is dropped, since versions only move forward.
+func (c *Cache) Get(version uint64) (Entry, bool) {
+	c.mu.RLock()
+	if c.cached && c.version == version {
+		entry := c.entry
+		c.mu.RUnlock()
+		return entry, true
+	}
+	stale := c.cached && c.version < version
+	c.mu.RUnlock()
+
+	if stale {
+		c.mu.Lock()
+		if c.cached && c.version < version {
+			c.cached = false
+			c.entry = Entry{}
+		}
+		c.mu.Unlock()
+	}
+	return Entry{}, false
+}
+
+// Put stores entry for version and reports whether it was kept. Entries
+// over the size limit are rejected.
+func (c *Cache) Put(version uint64, entry Entry) bool {
+	if len(entry.Body) > c.maxBytes {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// A request that read an older version may finish after one that read
+	// a newer version; keep the newer entry.
+	if c.cached && c.version > version {
+		return false
+	}
+	c.cached = true
+	c.version = version
+	c.entry = entry
+	return true
+}
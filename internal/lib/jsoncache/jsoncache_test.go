@@ -0,0 +1,65 @@
+package jsoncache_test
+
+import (
+	"sync"
+	"testing"
+
+	"quotes-service/internal/lib/jsoncache"
+)
+
+func TestCache(t *testing.T) {
+	cache := jsoncache.New(8)
+
+	if _, ok := cache.Get(1); ok {
+		t.Fatal("expected a miss on an empty cache")
+	}
+	if !cache.Put(1, jsoncache.Entry{Body: []byte("[1]")}) {
+		t.Fatal("expected a small body to be cached")
+	}
+	if entry, ok := cache.Get(1); !ok || string(entry.Body) != "[1]" {
+		t.Fatalf("expected a hit for version 1, got %q, %v", entry.Body, ok)
+	}
+	if _, ok := cache.Get(2); ok {
+		t.Fatal("expected a miss for a newer version")
+	}
+	if _, ok := cache.Get(1); ok {
+		t.Fatal("expected the stale entry to be dropped")
+	}
+
+	if cache.Put(3, jsoncache.Entry{Body: []byte("[1,2,3,4,5]")}) {
+		t.Fatal("expected a body over the limit to be rejected")
+	}
+	if _, ok := cache.Get(3); ok {
+		t.Fatal("expected a miss after an oversized put")
+	}
+
+	cache.Put(5, jsoncache.Entry{Body: []byte("[5]")})
+	if cache.Put(4, jsoncache.Entry{Body: []byte("[4]")}) {
+		t.Fatal("expected an older version not to replace a newer one")
+	}
+	if _, ok := cache.Get(4); ok {
+		t.Fatal("expected a miss for the older version")
+	}
+	if _, ok := cache.Get(5); !ok {
+		t.Fatal("expected the newer entry to survive a lookup of an older version")
+	}
+}
+
+func TestCacheConcurrent(t *testing.T) {
+	cache := jsoncache.New(64)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for v := uint64(0); v < 1000; v++ {
+				if entry, ok := cache.Get(v); ok && len(entry.Body) != 2 {
+					t.Errorf("unexpected body %q", entry.Body)
+				}
+				cache.Put(v, jsoncache.Entry{Body: []byte("[]")})
+			}
+		}(i)
+	}
+	wg.Wait()
+}
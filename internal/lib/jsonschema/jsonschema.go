@@ -0,0 +1,111 @@
+// Package jsonschema derives JSON Schema documents from Go types using the
+// same rules encoding/json uses to marshal them.
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema document.
+type Schema map[string]any
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// Generate returns the schema of v's type, titled with the type name.
+// Fields without omitempty/omitzero that are not pointers are required.
+func Generate(v any) Schema {
+	t := reflect.TypeOf(v)
+	schema := forType(t)
+	schema["$schema"] = Draft
+	schema["title"] = t.Name()
+	return schema
+}
+
+func forType(t reflect.Type) Schema {
+	if t == timeType {
+		return Schema{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := forType(t.Elem())
+		if typ, ok := schema["type"].(string); ok {
+			schema["type"] = []string{typ, "null"}
+		}
+		return schema
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return Schema{"type": "array", "items": forType(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": forType(t.Elem())}
+	case reflect.Struct:
+		if t.Implements(marshalerType) {
+			return Schema{}
+		}
+		return forStruct(t)
+	default:
+		// Interfaces and anything else can hold any JSON value.
+		return Schema{}
+	}
+}
+
+func forStruct(t reflect.Type) Schema {
+	properties := make(map[string]any)
+	required := make([]string, 0)
+	addFields(t, properties, &required)
+
+	schema := Schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func addFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			addFields(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = forType(field.Type)
+		optional := field.Type.Kind() == reflect.Pointer
+		for _, opt := range strings.Split(opts, ",") {
+			if opt == "omitempty" || opt == "omitzero" {
+				optional = true
+			}
+		}
+		if !optional {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Package jwks keeps the signing keys an identity provider publishes as a
+// JSON Web Key Set. The keys are fetched in the background and kept when a
+// fetch fails, so tokens keep verifying through an outage of the provider.
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrUnknownKey is returned for a key ID the set does not have, even after
+// fetching it again.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// maxSetBytes caps the size of the key set document.
+const maxSetBytes = 1 << 20
+
+// Key is a public signing key from the set.
+type Key struct {
+	ID string
+	// Alg is the algorithm the key is for, or "" if the set does not say.
+	Alg    string
+	Public crypto.PublicKey
+}
+
+// Options configures a Set.
+type Options struct {
+	URL string
+	// Refresh is how often the set is fetched in the background.
+	Refresh time.Duration
+	// MinRefetch is how long Key waits after a fetch before it fetches
+	// again for a key ID it does not know, such as one the provider just
+	// rotated in.
+	MinRefetch time.Duration
+}
+
+type Set struct {
+	log    *slog.Logger
+	client *http.Client
+	opts   Options
+
+	// fetching serializes fetches, so a burst of tokens with a new key ID
+	// fetches the set once.
+	fetching sync.Mutex
+
+	mu        sync.RWMutex
+	keys      map[string]Key
+	attempted time.Time
+}
+
+type Option func(*Set)
+
+// WithHTTPClient replaces the client used to reach the provider.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *Set) {
+		s.client = client
+	}
+}
+
+func New(log *slog.Logger, opts Options, options ...Option) *Set {
+	s := &Set{
+		log:    log.With(slog.String("op", "jwks.Set"), slog.String("url", opts.URL)),
+		client: &http.Client{Timeout: 10 * time.Second},
+		opts:   opts,
+		keys:   make(map[string]Key),
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+// Run fetches the set right away and then every Refresh until ctx is done.
+func (s *Set) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.Refresh)
+	defer ticker.Stop()
+
+	s.refresh(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.refresh(ctx)
+		}
+	}
+}
+
+// Key returns the key with id. A key it does not have is fetched again,
+// at most once every MinRefetch, before it gives up with ErrUnknownKey.
+func (s *Set) Key(ctx context.Context, id string) (Key, error) {
+	if key, ok := s.lookup(id); ok {
+		return key, nil
+	}
+
+	s.fetching.Lock()
+	defer s.fetching.Unlock()
+	// Another request may have fetched it while this one waited.
+	if key, ok := s.lookup(id); ok {
+		return key, nil
+	}
+	s.mu.RLock()
+	recent := time.Since(s.attempted) < s.opts.MinRefetch
+	s.mu.RUnlock()
+	if !recent {
+		s.fetch(ctx)
+		if key, ok := s.lookup(id); ok {
+			return key, nil
+		}
+	}
+	return Key{}, ErrUnknownKey
+}
+
+func (s *Set) lookup(id string) (Key, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[id]
+	return key, ok
+}
+
+func (s *Set) refresh(ctx context.Context) {
+	s.fetching.Lock()
+	defer s.fetching.Unlock()
+	s.fetch(ctx)
+}
+
+// fetch replaces the keys with the provider's current set. On failure the
+// keys stay as they were. The caller holds s.fetching.
+func (s *Set) fetch(ctx context.Context) {
+	s.mu.Lock()
+	s.attempted = time.Now()
+	s.mu.Unlock()
+
+	keys, err := s.download(ctx)
+	if err != nil {
+		s.mu.RLock()
+		cached := len(s.keys)
+		s.mu.RUnlock()
+		s.log.WarnContext(ctx, "failed to fetch key set, keeping the cached keys", slog.Int("cached", cached), slog.String("error", err.Error()))
+		return
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	s.log.DebugContext(ctx, "fetched key set", slog.Int("keys", len(keys)))
+}
+
+func (s *Set) download(ctx context.Context) (map[string]Key, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.opts.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSetBytes))
+	if err != nil {
+		return nil, err
+	}
+	return Parse(body)
+}
+
+// jsonKey is one JSON Web Key, with the members of the RSA and EC key
+// types used for signatures.
+type jsonKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// Parse decodes a key set document into its signing keys by ID. Keys for
+// encryption, of other types or without an ID are left out, as the set
+// may hold keys this service has no use for; a set with no usable key at
+// all is an error.
+func Parse(data []byte) (map[string]Key, error) {
+	var doc struct {
+		Keys []jsonKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("decode key set: %w", err)
+	}
+
+	keys := make(map[string]Key, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Kid == "" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		var public crypto.PublicKey
+		var err error
+		switch k.Kty {
+		case "RSA":
+			public, err = rsaKey(k)
+		case "EC":
+			public, err = ecKey(k)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", k.Kid, err)
+		}
+		keys[k.Kid] = Key{ID: k.Kid, Alg: k.Alg, Public: public}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("key set has no signing keys")
+	}
+	return keys, nil
+}
+
+func rsaKey(k jsonKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil || len(n) == 0 {
+		return nil, errors.New("invalid modulus")
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, errors.New("invalid exponent")
+	}
+	exponent := new(big.Int).SetBytes(e)
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+func ecKey(k jsonKey) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	var check ecdh.Curve
+	switch k.Crv {
+	case "P-256":
+		curve, check = elliptic.P256(), ecdh.P256()
+	case "P-384":
+		curve, check = elliptic.P384(), ecdh.P384()
+	case "P-521":
+		curve, check = elliptic.P521(), ecdh.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+	size := (curve.Params().BitSize + 7) / 8
+	x, errX := base64.RawURLEncoding.DecodeString(k.X)
+	y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+	if errX != nil || errY != nil || len(x) != size || len(y) != size {
+		return nil, errors.New("invalid coordinates")
+	}
+	// crypto/ecdh rejects points that are not on the curve.
+	point := append(append([]byte{4}, x...), y...)
+	if _, err := check.NewPublicKey(point); err != nil {
+		return nil, errors.New("point is not on the curve")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+}
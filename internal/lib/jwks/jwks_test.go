@@ -0,0 +1,203 @@
+package jwks_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"quotes-service/internal/lib/jwks"
+)
+
+var (
+	rsaKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _  = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+)
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func rsaJWK(kid string) map[string]string {
+	return map[string]string{
+		"kty": "RSA", "kid": kid, "alg": "RS256", "use": "sig",
+		"n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string) map[string]string {
+	return map[string]string{
+		"kty": "EC", "kid": kid, "crv": "P-256",
+		"x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func document(keys ...map[string]string) []byte {
+	doc, _ := json.Marshal(map[string]any{"keys": keys})
+	return doc
+}
+
+func TestParse(t *testing.T) {
+	offCurve := ecJWK("bad")
+	offCurve["y"] = b64(make([]byte, 32))
+	encryption := rsaJWK("enc")
+	encryption["use"] = "enc"
+
+	tests := []struct {
+		name     string
+		doc      []byte
+		expected []string
+		wantErr  bool
+	}{
+		{name: "rsa and ec", doc: document(rsaJWK("r1"), ecJWK("e1")), expected: []string{"r1", "e1"}},
+		{
+			name:     "skips what it cannot use",
+			doc:      document(rsaJWK("r1"), encryption, map[string]string{"kty": "oct", "kid": "s1", "k": "c2VjcmV0"}, ecJWK("")),
+			expected: []string{"r1"},
+		},
+		{name: "point off the curve", doc: document(rsaJWK("r1"), offCurve), wantErr: true},
+		{name: "no signing keys", doc: document(encryption), wantErr: true},
+		{name: "not json", doc: []byte("<html>"), wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			keys, err := jwks.Parse(tc.doc)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if len(keys) != len(tc.expected) {
+				t.Fatalf("expected keys %v, got %v", tc.expected, keys)
+			}
+			for _, kid := range tc.expected {
+				if _, ok := keys[kid]; !ok {
+					t.Fatalf("expected key %s, got %v", kid, keys)
+				}
+			}
+		})
+	}
+
+	keys, _ := jwks.Parse(document(rsaJWK("r1"), ecJWK("e1")))
+	if pub, ok := keys["r1"].Public.(*rsa.PublicKey); !ok || !pub.Equal(&rsaKey.PublicKey) || keys["r1"].Alg != "RS256" {
+		t.Errorf("expected the RSA key, got %+v", keys["r1"])
+	}
+	if pub, ok := keys["e1"].Public.(*ecdsa.PublicKey); !ok || !pub.Equal(&ecKey.PublicKey) {
+		t.Errorf("expected the EC key, got %+v", keys["e1"])
+	}
+}
+
+// provider serves a key set that tests change, counting the requests.
+type provider struct {
+	mu       sync.Mutex
+	doc      []byte
+	down     bool
+	requests int
+}
+
+func (p *provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests++
+	if p.down {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write(p.doc)
+}
+
+func (p *provider) set(doc []byte, down bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.doc, p.down = doc, down
+}
+
+func (p *provider) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.requests
+}
+
+func TestKey(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	p := &provider{doc: document(rsaJWK("r1"))}
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+	ctx := context.Background()
+
+	set := jwks.New(logger, jwks.Options{URL: srv.URL, Refresh: time.Hour, MinRefetch: time.Hour})
+
+	// The first lookup fetches the set.
+	if _, err := set.Key(ctx, "r1"); err != nil {
+		t.Fatalf("expected r1, got %v", err)
+	}
+	// An unknown key within MinRefetch of that fetch is not fetched again.
+	p.set(document(rsaJWK("r1"), ecJWK("e1")), false)
+	if _, err := set.Key(ctx, "e1"); !errors.Is(err, jwks.ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey, got %v", err)
+	}
+	if p.count() != 1 {
+		t.Fatalf("expected one fetch, got %d", p.count())
+	}
+
+	// Past MinRefetch a key the provider rotated in is found.
+	set = jwks.New(logger, jwks.Options{URL: srv.URL, Refresh: time.Hour})
+	if _, err := set.Key(ctx, "e1"); err != nil {
+		t.Fatalf("expected e1 after a fetch, got %v", err)
+	}
+
+	// While the provider is down the cached keys keep working.
+	p.set(nil, true)
+	if _, err := set.Key(ctx, "missing"); !errors.Is(err, jwks.ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey, got %v", err)
+	}
+	for _, kid := range []string{"r1", "e1"} {
+		if _, err := set.Key(ctx, kid); err != nil {
+			t.Fatalf("expected the cached key %s during the outage, got %v", kid, err)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	p := &provider{doc: document(rsaJWK("r1"))}
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+
+	set := jwks.New(logger, jwks.Options{URL: srv.URL, Refresh: 10 * time.Millisecond, MinRefetch: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		set.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	p.set(document(ecJWK("e1")), false)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		// Within MinRefetch only the background refresh can find e1.
+		if _, err := set.Key(context.Background(), "e1"); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the background refresh to pick up the new key")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, err := set.Key(context.Background(), "r1"); !errors.Is(err, jwks.ErrUnknownKey) {
+		t.Fatalf("expected keys dropped from the set to go, got %v", err)
+	}
+}
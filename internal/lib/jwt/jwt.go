@@ -0,0 +1,223 @@
+// Package jwt verifies JSON Web Tokens signed with public keys (RS*, PS*
+// and ES* algorithms), as issued by OpenID Connect identity providers.
+// Tokens signed with shared secrets or not signed at all are refused.
+package jwt
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformed            = errors.New("malformed token")
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrSignature            = errors.New("invalid token signature")
+	ErrExpired              = errors.New("token expired")
+	ErrNotYetValid          = errors.New("token not valid yet")
+	ErrIssuer               = errors.New("unexpected token issuer")
+	ErrAudience             = errors.New("token not meant for this audience")
+)
+
+// Header is the JOSE header of a token.
+type Header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+// Claims are the decoded claims of a token. Numbers are float64, as
+// encoding/json decodes them.
+type Claims map[string]any
+
+// Token is a parsed token whose signature is not checked yet.
+type Token struct {
+	Header Header
+	Claims Claims
+
+	signingInput string
+	signature    []byte
+}
+
+// Parse decodes a token in the compact serialization. It checks the form
+// only: the signature is for Verify and the claims for Claims.Validate.
+func Parse(token string) (*Token, error) {
+	header, rest, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrMalformed
+	}
+	payload, signature, ok := strings.Cut(rest, ".")
+	if !ok || strings.Contains(signature, ".") {
+		return nil, ErrMalformed
+	}
+
+	t := &Token{signingInput: token[:len(header)+1+len(payload)]}
+	if err := decodeJSON(header, &t.Header); err != nil || t.Header.Alg == "" {
+		return nil, ErrMalformed
+	}
+	if err := decodeJSON(payload, &t.Claims); err != nil || t.Claims == nil {
+		return nil, ErrMalformed
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	t.signature = sig
+	return t, nil
+}
+
+func decodeJSON(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// Verify checks the token's signature with key, an *rsa.PublicKey or an
+// *ecdsa.PublicKey, which must suit the algorithm in the header.
+func (t *Token) Verify(key crypto.PublicKey) error {
+	var hash crypto.Hash
+	switch t.Header.Alg[min(2, len(t.Header.Alg)):] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, t.Header.Alg)
+	}
+	h := hash.New()
+	h.Write([]byte(t.signingInput))
+	digest := h.Sum(nil)
+
+	switch t.Header.Alg[:2] {
+	case "RS", "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: %s needs an RSA key", ErrSignature, t.Header.Alg)
+		}
+		var err error
+		if t.Header.Alg[0] == 'R' {
+			err = rsa.VerifyPKCS1v15(pub, hash, digest, t.signature)
+		} else {
+			err = rsa.VerifyPSS(pub, hash, digest, t.signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		if err != nil {
+			return ErrSignature
+		}
+		return nil
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve.Params().BitSize != ecBits(hash) {
+			return fmt.Errorf("%w: %s needs an ECDSA key on its curve", ErrSignature, t.Header.Alg)
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(t.signature) != 2*size {
+			return ErrSignature
+		}
+		r := new(big.Int).SetBytes(t.signature[:size])
+		s := new(big.Int).SetBytes(t.signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return ErrSignature
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, t.Header.Alg)
+	}
+}
+
+// ecBits is the curve size that goes with hash in the ES* algorithms.
+func ecBits(hash crypto.Hash) int {
+	switch hash {
+	case crypto.SHA256:
+		return 256
+	case crypto.SHA384:
+		return 384
+	default:
+		return 521
+	}
+}
+
+// Validation is what Claims.Validate expects of a token.
+type Validation struct {
+	Issuer   string
+	Audience string
+	Now      time.Time
+	// Skew is how far exp and nbf may be off from Now.
+	Skew time.Duration
+}
+
+// Validate checks the registered claims: exp is required and must not be
+// past, nbf must not be ahead, iss must be the Issuer and aud must be or
+// contain the Audience.
+func (c Claims) Validate(v Validation) error {
+	exp, ok := c.time("exp")
+	if !ok {
+		return fmt.Errorf("%w: no exp claim", ErrMalformed)
+	}
+	if v.Now.After(exp.Add(v.Skew)) {
+		return ErrExpired
+	}
+	if nbf, ok := c.time("nbf"); ok && v.Now.Add(v.Skew).Before(nbf) {
+		return ErrNotYetValid
+	}
+	if iss, _ := c["iss"].(string); iss != v.Issuer {
+		return ErrIssuer
+	}
+	if !c.hasAudience(v.Audience) {
+		return ErrAudience
+	}
+	return nil
+}
+
+// Subject returns the sub claim, or "" if there is none.
+func (c Claims) Subject() string {
+	sub, _ := c["sub"].(string)
+	return sub
+}
+
+// Strings returns a claim that is a string or an array of strings.
+func (c Claims) Strings(name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func (c Claims) time(name string) (time.Time, bool) {
+	seconds, ok := c[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, 0).Add(time.Duration(seconds * float64(time.Second))), true
+}
+
+func (c Claims) hasAudience(audience string) bool {
+	for _, aud := range c.Strings("aud") {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
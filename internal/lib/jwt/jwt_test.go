@@ -0,0 +1,206 @@
+package jwt_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"quotes-service/internal/lib/jwt"
+)
+
+var (
+	rsaKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	p256, _   = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _   = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+)
+
+// sign makes a token with key ID k1 over claims.
+func sign(t *testing.T, alg string, key crypto.Signer, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": "k1"})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	hash := map[string]crypto.Hash{"256": crypto.SHA256, "384": crypto.SHA384, "512": crypto.SHA512}[alg[2:]]
+	h := hash.New()
+	h.Write([]byte(input))
+	digest := h.Sum(nil)
+
+	var sig []byte
+	var err error
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if alg[0] == 'P' {
+			sig, err = rsa.SignPSS(rand.Reader, k, hash, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		} else {
+			sig, err = rsa.SignPKCS1v15(rand.Reader, k, hash, digest)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, signErr := ecdsa.Sign(rand.Reader, k, digest)
+		size := (k.Curve.Params().BitSize + 7) / 8
+		sig = append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+		err = signErr
+	}
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerify(t *testing.T) {
+	claims := map[string]any{"sub": "alice"}
+	tests := []struct {
+		name    string
+		token   func(t *testing.T) string
+		key     crypto.PublicKey
+		wantErr error
+	}{
+		{name: "RS256", token: func(t *testing.T) string { return sign(t, "RS256", rsaKey, claims) }, key: &rsaKey.PublicKey},
+		{name: "RS512", token: func(t *testing.T) string { return sign(t, "RS512", rsaKey, claims) }, key: &rsaKey.PublicKey},
+		{name: "PS256", token: func(t *testing.T) string { return sign(t, "PS256", rsaKey, claims) }, key: &rsaKey.PublicKey},
+		{name: "ES256", token: func(t *testing.T) string { return sign(t, "ES256", p256, claims) }, key: &p256.PublicKey},
+		{name: "ES384", token: func(t *testing.T) string { return sign(t, "ES384", p384, claims) }, key: &p384.PublicKey},
+		{
+			name:    "other key",
+			token:   func(t *testing.T) string { return sign(t, "ES256", p256, claims) },
+			key:     &p384.PublicKey,
+			wantErr: jwt.ErrSignature,
+		},
+		{
+			name:    "RSA token, EC key",
+			token:   func(t *testing.T) string { return sign(t, "RS256", rsaKey, claims) },
+			key:     &p256.PublicKey,
+			wantErr: jwt.ErrSignature,
+		},
+		{
+			name: "tampered claims",
+			token: func(t *testing.T) string {
+				token := sign(t, "RS256", rsaKey, claims)
+				other := sign(t, "RS256", rsaKey, map[string]any{"sub": "mallory"})
+				// mallory's claims with alice's signature.
+				return other[:strings.LastIndex(other, ".")] + token[strings.LastIndex(token, "."):]
+			},
+			key:     &rsaKey.PublicKey,
+			wantErr: jwt.ErrSignature,
+		},
+		{
+			name: "shared secret",
+			token: func(t *testing.T) string {
+				return "eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiJhbGljZSJ9.c2ln"
+			},
+			key:     &rsaKey.PublicKey,
+			wantErr: jwt.ErrUnsupportedAlgorithm,
+		},
+		{
+			name: "unsigned",
+			token: func(t *testing.T) string {
+				return "eyJhbGciOiJub25lIn0.eyJzdWIiOiJhbGljZSJ9."
+			},
+			key:     &rsaKey.PublicKey,
+			wantErr: jwt.ErrUnsupportedAlgorithm,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := jwt.Parse(tc.token(t))
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			if err := token.Verify(tc.key); !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "api key", token: "ops-key"},
+		{name: "two segments", token: "eyJhbGciOiJSUzI1NiJ9.eyJzdWIiOiJhbGljZSJ9"},
+		{name: "four segments", token: "eyJhbGciOiJSUzI1NiJ9.eyJzdWIiOiJhbGljZSJ9.c2ln.c2ln"},
+		{name: "header not json", token: "bm9wZQ.eyJzdWIiOiJhbGljZSJ9.c2ln"},
+		{name: "no alg", token: "e30.eyJzdWIiOiJhbGljZSJ9.c2ln"},
+		{name: "claims not an object", token: "eyJhbGciOiJSUzI1NiJ9.WzFd.c2ln"},
+		{name: "signature not base64url", token: "eyJhbGciOiJSUzI1NiJ9.eyJzdWIiOiJhbGljZSJ9.c2ln+"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := jwt.Parse(tc.token); !errors.Is(err, jwt.ErrMalformed) {
+				t.Fatalf("expected ErrMalformed, got %v", err)
+			}
+		})
+	}
+
+	token, err := jwt.Parse(sign(t, "RS256", rsaKey, map[string]any{"sub": "alice", "roles": []string{"reader", "admin"}}))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if token.Header.Alg != "RS256" || token.Header.Kid != "k1" || token.Claims.Subject() != "alice" {
+		t.Fatalf("unexpected token %+v", token)
+	}
+	if roles := token.Claims.Strings("roles"); len(roles) != 2 || roles[1] != "admin" {
+		t.Fatalf("expected the roles array, got %v", roles)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	valid := func(changes map[string]any) jwt.Claims {
+		claims := jwt.Claims{
+			"iss": "https://id.example.com/",
+			"aud": "quotes",
+			"exp": float64(now.Add(time.Hour).Unix()),
+		}
+		for name, value := range changes {
+			if value == nil {
+				delete(claims, name)
+				continue
+			}
+			claims[name] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		claims  jwt.Claims
+		wantErr error
+	}{
+		{name: "valid", claims: valid(nil)},
+		{name: "audience array", claims: valid(map[string]any{"aud": []any{"other", "quotes"}})},
+		{name: "expired within skew", claims: valid(map[string]any{"exp": float64(now.Add(-30 * time.Second).Unix())})},
+		{name: "expired", claims: valid(map[string]any{"exp": float64(now.Add(-2 * time.Minute).Unix())}), wantErr: jwt.ErrExpired},
+		{name: "no exp", claims: valid(map[string]any{"exp": nil}), wantErr: jwt.ErrMalformed},
+		{name: "not yet valid within skew", claims: valid(map[string]any{"nbf": float64(now.Add(30 * time.Second).Unix())})},
+		{name: "not yet valid", claims: valid(map[string]any{"nbf": float64(now.Add(2 * time.Minute).Unix())}), wantErr: jwt.ErrNotYetValid},
+		{name: "other issuer", claims: valid(map[string]any{"iss": "https://evil.example.com/"}), wantErr: jwt.ErrIssuer},
+		{name: "other audience", claims: valid(map[string]any{"aud": "billing"}), wantErr: jwt.ErrAudience},
+		{name: "no audience", claims: valid(map[string]any{"aud": nil}), wantErr: jwt.ErrAudience},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.claims.Validate(jwt.Validation{
+				Issuer:   "https://id.example.com/",
+				Audience: "quotes",
+				Now:      now,
+				Skew:     time.Minute,
+			})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
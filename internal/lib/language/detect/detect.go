@@ -0,0 +1,150 @@
+// Package detect guesses the language of a short text by comparing its
+// character trigram ranking against small embedded profiles (Cavnar and
+// Trenkle's out-of-place measure).
+package detect
+
+import (
+	"embed"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"quotes-service/internal/lib/language"
+)
+
+//go:embed profiles/*.txt
+var profileFiles embed.FS
+
+const (
+	profileSize = 300
+	// minLetters is the shortest input worth guessing on; below it trigram
+	// statistics are mostly noise.
+	minLetters = 10
+	// minMargin is the relative distance the best profile must win by.
+	minMargin = 0.02
+)
+
+var profiles = loadProfiles()
+
+// Detect returns the BCP-47 code of the most likely language of text, or
+// language.Undetermined when the text is too short or no profile wins
+// clearly.
+func Detect(text string) string {
+	if countLetters(text) < minLetters {
+		return language.Undetermined
+	}
+
+	ranks := rankTrigrams(text, profileSize)
+
+	type score struct {
+		lang     string
+		distance int
+	}
+	scores := make([]score, 0, len(profiles))
+	for lang, profile := range profiles {
+		scores = append(scores, score{lang: lang, distance: distance(ranks, profile)})
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].distance != scores[j].distance {
+			return scores[i].distance < scores[j].distance
+		}
+		return scores[i].lang < scores[j].lang
+	})
+
+	best, second := scores[0], scores[1]
+	maxDistance := len(ranks) * profileSize
+	if float64(second.distance-best.distance)/float64(maxDistance) < minMargin {
+		return language.Undetermined
+	}
+	return best.lang
+}
+
+// Languages returns the codes of the embedded profiles.
+func Languages() []string {
+	langs := make([]string, 0, len(profiles))
+	for lang := range profiles {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+func loadProfiles() map[string]map[string]int {
+	entries, err := profileFiles.ReadDir("profiles")
+	if err != nil {
+		panic(err)
+	}
+
+	result := make(map[string]map[string]int, len(entries))
+	for _, entry := range entries {
+		data, err := profileFiles.ReadFile(path.Join("profiles", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		lang := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+		result[lang] = rankTrigrams(string(data), profileSize)
+	}
+	return result
+}
+
+// rankTrigrams maps the n most frequent trigrams of text to their rank.
+// Words are padded with spaces so word boundaries carry signal.
+func rankTrigrams(text string, n int) map[string]int {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		runes := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			counts[string(runes[i:i+3])]++
+		}
+	}
+
+	trigrams := make([]string, 0, len(counts))
+	for trigram := range counts {
+		trigrams = append(trigrams, trigram)
+	}
+	sort.Slice(trigrams, func(i, j int) bool {
+		if counts[trigrams[i]] != counts[trigrams[j]] {
+			return counts[trigrams[i]] > counts[trigrams[j]]
+		}
+		return trigrams[i] < trigrams[j]
+	})
+	if len(trigrams) > n {
+		trigrams = trigrams[:n]
+	}
+
+	ranks := make(map[string]int, len(trigrams))
+	for rank, trigram := range trigrams {
+		ranks[trigram] = rank
+	}
+	return ranks
+}
+
+func distance(ranks, profile map[string]int) int {
+	total := 0
+	for trigram, rank := range ranks {
+		profileRank, ok := profile[trigram]
+		if !ok {
+			total += profileSize
+			continue
+		}
+		if diff := rank - profileRank; diff < 0 {
+			total -= diff
+		} else {
+			total += diff
+		}
+	}
+	return total
+}
+
+func countLetters(text string) int {
+	n := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			n++
+		}
+	}
+	return n
+}
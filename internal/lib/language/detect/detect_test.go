@@ -0,0 +1,59 @@
+package detect_test
+
+import (
+	"testing"
+
+	"quotes-service/internal/lib/language"
+	"quotes-service/internal/lib/language/detect"
+)
+
+func TestDetectAccuracy(t *testing.T) {
+	samples := []struct {
+		text     string
+		expected string
+	}{
+		{text: "Imagination is more important than knowledge.", expected: "en"},
+		{text: "The unexamined life is not worth living.", expected: "en"},
+		{text: "Stay hungry, stay foolish, and never stop learning.", expected: "en"},
+		{text: "Красота спасёт мир.", expected: "ru"},
+		{text: "Все счастливые семьи похожи друг на друга.", expected: "ru"},
+		{text: "Ich denke, also bin ich, und das ist genug.", expected: "de"},
+		{text: "Was mich nicht umbringt, macht mich stärker.", expected: "de"},
+		{text: "L'enfer, c'est les autres.", expected: "fr"},
+		{text: "On ne voit bien qu'avec le cœur, l'essentiel est invisible pour les yeux.", expected: "fr"},
+		{text: "Caminante, no hay camino, se hace camino al andar.", expected: "es"},
+		{text: "El que lee mucho y anda mucho, ve mucho y sabe mucho.", expected: "es"},
+		{text: "Nel mezzo del cammin di nostra vita mi ritrovai per una selva oscura.", expected: "it"},
+		{text: "Chi va piano va sano e va lontano.", expected: "it"},
+	}
+
+	correct := 0
+	for _, sample := range samples {
+		got := detect.Detect(sample.text)
+		if got == sample.expected {
+			correct++
+		} else {
+			t.Logf("detected %q for %q, expected %q", got, sample.text, sample.expected)
+		}
+	}
+
+	if accuracy := float64(correct) / float64(len(samples)); accuracy < 0.85 {
+		t.Errorf("detection accuracy %.2f is below 0.85", accuracy)
+	}
+}
+
+func TestDetectUndetermined(t *testing.T) {
+	for _, text := range []string{"", "Hi", "12345 67890", "!!!"} {
+		if got := detect.Detect(text); got != language.Undetermined {
+			t.Errorf("expected %q for %q, got %q", language.Undetermined, text, got)
+		}
+	}
+}
+
+func TestLanguagesHaveValidTags(t *testing.T) {
+	for _, lang := range detect.Languages() {
+		if _, err := language.Normalize(lang); err != nil {
+			t.Errorf("profile %q is not a known language tag: %v", lang, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package language
+
+import (
+	"errors"
+	"strings"
+)
+
+// Undetermined is the BCP-47 code for an unknown language.
+const Undetermined = "und"
+
+var ErrInvalidTag = errors.New("invalid language tag")
+
+// knownPrefixes lists the primary language subtags accepted by Normalize.
+var knownPrefixes = map[string]struct{}{
+	"ar": {}, "be": {}, "bg": {}, "cs": {}, "da": {}, "de": {}, "el": {},
+	"en": {}, "es": {}, "et": {}, "fa": {}, "fi": {}, "fr": {}, "he": {},
+	"hi": {}, "hu": {}, "hy": {}, "it": {}, "ja": {}, "ka": {}, "kk": {},
+	"ko": {}, "la": {}, "lt": {}, "lv": {}, "nl": {}, "no": {}, "pl": {},
+	"pt": {}, "ro": {}, "ru": {}, "sk": {}, "sr": {}, "sv": {}, "tr": {},
+	"uk": {}, "zh": {}, Undetermined: {},
+}
+
+// Normalize validates a BCP-47 tag against the known primary subtags and
+// returns it in canonical case, e.g. "EN-us" becomes "en-US".
+func Normalize(tag string) (string, error) {
+	parts := strings.Split(strings.TrimSpace(tag), "-")
+	if _, ok := knownPrefixes[strings.ToLower(parts[0])]; !ok {
+		return "", ErrInvalidTag
+	}
+
+	for i, part := range parts {
+		if len(part) == 0 || len(part) > 8 || !isAlphanumeric(part) {
+			return "", ErrInvalidTag
+		}
+		switch {
+		case i == 0:
+			parts[i] = strings.ToLower(part)
+		case len(part) == 2:
+			parts[i] = strings.ToUpper(part)
+		case len(part) == 4:
+			parts[i] = strings.ToUpper(part[:1]) + strings.ToLower(part[1:])
+		default:
+			parts[i] = strings.ToLower(part)
+		}
+	}
+	return strings.Join(parts, "-"), nil
+}
+
+// Primary returns the primary subtag of a tag, treating an empty tag as
+// undetermined.
+func Primary(tag string) string {
+	if tag == "" {
+		return Undetermined
+	}
+	primary, _, _ := strings.Cut(tag, "-")
+	return strings.ToLower(primary)
+}
+
+// Matches reports whether a quote tagged with tag satisfies the filter. A
+// filter without subtags matches any region or script of that language.
+func Matches(tag, filter string) bool {
+	if tag == "" {
+		tag = Undetermined
+	}
+	if strings.EqualFold(tag, filter) {
+		return true
+	}
+	return len(tag) > len(filter) && strings.EqualFold(tag[:len(filter)], filter) && tag[len(filter)] == '-'
+}
+
+func isAlphanumeric(s string) bool {
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
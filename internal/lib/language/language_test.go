@@ -0,0 +1,59 @@
+package language_test
+
+import (
+	"errors"
+	"testing"
+
+	"quotes-service/internal/lib/language"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		tag      string
+		expected string
+		err      error
+	}{
+		{tag: "en", expected: "en"},
+		{tag: "EN-us", expected: "en-US"},
+		{tag: "sr-latn-RS", expected: "sr-Latn-RS"},
+		{tag: "und", expected: "und"},
+		{tag: "xx", err: language.ErrInvalidTag},
+		{tag: "en-", err: language.ErrInvalidTag},
+		{tag: "en_US", err: language.ErrInvalidTag},
+		{tag: "", err: language.ErrInvalidTag},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.tag, func(t *testing.T) {
+			got, err := language.Normalize(tc.tag)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		tag      string
+		filter   string
+		expected bool
+	}{
+		{tag: "en", filter: "en", expected: true},
+		{tag: "en-US", filter: "en", expected: true},
+		{tag: "en-US", filter: "en-GB", expected: false},
+		{tag: "eng", filter: "en", expected: false},
+		{tag: "", filter: "und", expected: true},
+		{tag: "und", filter: "und", expected: true},
+		{tag: "ru", filter: "und", expected: false},
+	}
+
+	for _, tc := range tests {
+		if got := language.Matches(tc.tag, tc.filter); got != tc.expected {
+			t.Errorf("Matches(%q, %q) = %v, expected %v", tc.tag, tc.filter, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Package lockout counts failed attempts per key and locks a key out once
+// it fails too often. Each lockout of a key lasts twice as long as the one
+// before, up to a maximum, until the key succeeds.
+package lockout
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Options configures a Tracker.
+type Options struct {
+	// MaxFailures failures within Window lock a key out.
+	MaxFailures int
+	Window      time.Duration
+	// Lockout is how long the first lockout lasts, and MaxLockout the
+	// longest any lasts.
+	Lockout    time.Duration
+	MaxLockout time.Duration
+	// MaxKeys caps the number of tracked keys; the least recently seen key
+	// is evicted first. Zero means no cap.
+	MaxKeys int
+}
+
+// Tracker keeps the failures of each key.
+type Tracker struct {
+	opts Options
+	now  func() time.Time
+
+	mu    sync.Mutex
+	keys  map[string]*list.Element
+	order *list.List
+}
+
+type entry struct {
+	key      string
+	failures int
+	// since is when the window of the current failures began.
+	since time.Time
+	// lockouts is how many times the key was locked out, which doubles
+	// the next lockout.
+	lockouts    int
+	lockedUntil time.Time
+}
+
+type Option func(*Tracker)
+
+// WithClock overrides the time source, mainly for tests.
+func WithClock(now func() time.Time) Option {
+	return func(t *Tracker) {
+		t.now = now
+	}
+}
+
+func New(opts Options, options ...Option) *Tracker {
+	t := &Tracker{
+		opts:  opts,
+		now:   time.Now,
+		keys:  make(map[string]*list.Element),
+		order: list.New(),
+	}
+	for _, opt := range options {
+		opt(t)
+	}
+	return t
+}
+
+// Locked returns how long key stays locked out, or zero.
+func (t *Tracker) Locked(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	el, ok := t.keys[key]
+	if !ok {
+		return 0
+	}
+	return max(el.Value.(*entry).lockedUntil.Sub(t.now()), 0)
+}
+
+// Fail records a failure of key. It returns how long key is locked out if
+// this failure locked it, or zero.
+func (t *Tracker) Fail(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	e := t.entry(key)
+	if e.failures == 0 || now.Sub(e.since) > t.opts.Window {
+		e.failures, e.since = 0, now
+	}
+	e.failures++
+	if e.failures < t.opts.MaxFailures {
+		return 0
+	}
+
+	lockout := t.opts.Lockout
+	for i := 0; i < e.lockouts && lockout < t.opts.MaxLockout; i++ {
+		lockout *= 2
+	}
+	lockout = min(lockout, t.opts.MaxLockout)
+	e.lockouts++
+	e.failures = 0
+	e.lockedUntil = now.Add(lockout)
+	return lockout
+}
+
+// Succeed forgets the failures and lockouts of key.
+func (t *Tracker) Succeed(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if el, ok := t.keys[key]; ok {
+		t.order.Remove(el)
+		delete(t.keys, key)
+	}
+}
+
+// Len reports how many keys are currently tracked.
+func (t *Tracker) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.order.Len()
+}
+
+func (t *Tracker) entry(key string) *entry {
+	if el, ok := t.keys[key]; ok {
+		t.order.MoveToFront(el)
+		return el.Value.(*entry)
+	}
+
+	if t.opts.MaxKeys > 0 && t.order.Len() >= t.opts.MaxKeys {
+		oldest := t.order.Back()
+		t.order.Remove(oldest)
+		delete(t.keys, oldest.Value.(*entry).key)
+	}
+
+	e := &entry{key: key}
+	t.keys[key] = t.order.PushFront(e)
+	return e
+}
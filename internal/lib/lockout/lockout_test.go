@@ -0,0 +1,63 @@
+package lockout_test
+
+import (
+	"testing"
+	"time"
+
+	"quotes-service/internal/lib/lockout"
+)
+
+func TestFail(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	tr := lockout.New(lockout.Options{
+		MaxFailures: 3,
+		Window:      time.Minute,
+		Lockout:     10 * time.Second,
+		MaxLockout:  30 * time.Second,
+	}, lockout.WithClock(func() time.Time { return now }))
+
+	// Failures spread wider than the window never lock.
+	for i := 0; i < 5; i++ {
+		if got := tr.Fail("ip:a"); got != 0 {
+			t.Fatalf("failure %d: expected no lockout, got %v", i, got)
+		}
+		now = now.Add(31 * time.Second)
+	}
+
+	// Every lockout doubles, up to MaxLockout.
+	for _, expected := range []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second, 30 * time.Second} {
+		tr.Fail("ip:b")
+		tr.Fail("ip:b")
+		if got := tr.Fail("ip:b"); got != expected {
+			t.Fatalf("expected a lockout of %v, got %v", expected, got)
+		}
+		if got := tr.Locked("ip:b"); got != expected {
+			t.Fatalf("expected to stay locked %v, got %v", expected, got)
+		}
+		now = now.Add(expected)
+		if got := tr.Locked("ip:b"); got != 0 {
+			t.Fatalf("expected the lockout over, got %v", got)
+		}
+	}
+
+	// Success starts over.
+	tr.Succeed("ip:b")
+	tr.Fail("ip:b")
+	tr.Fail("ip:b")
+	if got := tr.Fail("ip:b"); got != 10*time.Second {
+		t.Fatalf("expected the first lockout again after a success, got %v", got)
+	}
+	if got := tr.Locked("ip:c"); got != 0 {
+		t.Fatalf("expected an unknown key unlocked, got %v", got)
+	}
+}
+
+func TestEviction(t *testing.T) {
+	tr := lockout.New(lockout.Options{MaxFailures: 2, Window: time.Minute, Lockout: time.Minute, MaxLockout: time.Hour, MaxKeys: 100})
+	for i := 0; i < 10000; i++ {
+		tr.Fail(string(rune('a'+i%26)) + string(rune(i)))
+	}
+	if tr.Len() != 100 {
+		t.Fatalf("expected 100 tracked keys, got %d", tr.Len())
+	}
+}
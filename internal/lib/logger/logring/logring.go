@@ -0,0 +1,88 @@
+// Package logring keeps the last log lines in memory, so that a support
+// bundle can include them wherever the logs themselves go.
+package logring
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"quotes-service/internal/lib/redact"
+)
+
+// Ring holds the last size lines written to it. It is safe for concurrent
+// use.
+type Ring struct {
+	mu    sync.Mutex
+	lines [][]byte
+	next  int
+	full  bool
+}
+
+// New returns a Ring keeping the last size lines.
+func New(size int) *Ring {
+	return &Ring{lines: make([][]byte, size)}
+}
+
+// Write keeps p as one line, dropping the oldest once the ring is full.
+// The slog handlers write each record in one call.
+func (r *Ring) Write(p []byte) (int, error) {
+	if len(r.lines) == 0 {
+		return len(p), nil
+	}
+	line := append([]byte(nil), p...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % len(r.lines)
+	if r.next == 0 {
+		r.full = true
+	}
+	return len(p), nil
+}
+
+// Lines returns the lines kept, oldest first.
+func (r *Ring) Lines() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([][]byte(nil), r.lines[:r.next]...)
+	}
+	return append(append([][]byte(nil), r.lines[r.next:]...), r.lines[:r.next]...)
+}
+
+// Tee returns a handler that passes every record to next and writes those
+// at level or above to r as JSON lines, with their secrets masked. A ring
+// that keeps no lines leaves next as it is.
+func (r *Ring) Tee(next slog.Handler, level slog.Leveler) slog.Handler {
+	if len(r.lines) == 0 {
+		return next
+	}
+	return tee{next, slog.NewJSONHandler(r, &slog.HandlerOptions{Level: level, ReplaceAttr: redact.Attr})}
+}
+
+type tee [2]slog.Handler
+
+func (t tee) Enabled(ctx context.Context, level slog.Level) bool {
+	return t[0].Enabled(ctx, level) || t[1].Enabled(ctx, level)
+}
+
+func (t tee) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, h := range t {
+		if h.Enabled(ctx, record.Level) {
+			errs = append(errs, h.Handle(ctx, record.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t tee) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return tee{t[0].WithAttrs(attrs), t[1].WithAttrs(attrs)}
+}
+
+func (t tee) WithGroup(name string) slog.Handler {
+	return tee{t[0].WithGroup(name), t[1].WithGroup(name)}
+}
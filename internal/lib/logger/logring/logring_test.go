@@ -0,0 +1,72 @@
+package logring_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"quotes-service/internal/lib/logger/logring"
+)
+
+func TestTee(t *testing.T) {
+	var out bytes.Buffer
+	ring := logring.New(3)
+	logger := slog.New(ring.Tee(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}), slog.LevelInfo))
+	logger = logger.With(slog.String("op", "test"))
+
+	logger.Debug("only in the main log")
+	for _, msg := range []string{"first", "second", "third", "fourth"} {
+		logger.Info(msg, slog.String("password", "hunter2"))
+	}
+
+	if n := strings.Count(out.String(), "\n"); n != 5 {
+		t.Fatalf("expected every record in the main log, got %d lines:\n%s", n, out.String())
+	}
+
+	lines := ring.Lines()
+	var msgs []string
+	for _, line := range lines {
+		if bytes.Contains(line, []byte("hunter2")) {
+			t.Errorf("expected the password masked in %s", line)
+		}
+		if !bytes.Contains(line, []byte(`"op":"test"`)) {
+			t.Errorf("expected the logger's attributes in %s", line)
+		}
+		for _, msg := range []string{"second", "third", "fourth", "first", "only in the main log"} {
+			if bytes.Contains(line, []byte(`"msg":"`+msg+`"`)) {
+				msgs = append(msgs, msg)
+			}
+		}
+	}
+	if strings.Join(msgs, ",") != "second,third,fourth" {
+		t.Fatalf("expected the last three Info lines oldest first, got %v", msgs)
+	}
+}
+
+func TestRing(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		writes int
+		expect int
+	}{
+		{name: "disabled", size: 0, writes: 2, expect: 0},
+		{name: "not full", size: 3, writes: 2, expect: 2},
+		{name: "exactly full", size: 3, writes: 3, expect: 3},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ring := logring.New(tc.size)
+			for range tc.writes {
+				if _, err := ring.Write([]byte("line\n")); err != nil {
+					t.Fatalf("failed to write: %v", err)
+				}
+			}
+			if got := len(ring.Lines()); got != tc.expect {
+				t.Fatalf("expected %d lines, got %d", tc.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package sl
+
+import (
+	"log/slog"
+	"unicode/utf8"
+
+	"quotes-service/internal/lib/usertext"
+)
+
+// Preview describes a text that may be long or private, such as a quote,
+// by its length in characters and as much of its start as the usertext
+// policy lets the logs carry. Log the text itself at Debug only.
+func Preview(key string, text string) slog.Attr {
+	return slog.Group(key,
+		slog.Int("len", utf8.RuneCountInString(text)),
+		slog.String("preview", usertext.Cut(usertext.Log, text)),
+	)
+}
+
+// UserText logs a value a client sent, such as a query parameter or an
+// author name, cut to what the usertext policy lets the logs carry.
+func UserText(key string, value string) slog.Attr {
+	return slog.String(key, usertext.Cut(usertext.Log, value))
+}
+
+// Truncate cuts s to at most n characters and marks the cut with "…". It
+// never splits a UTF-8 sequence.
+func Truncate(s string, n int) string {
+	return usertext.Truncate(s, n)
+}
@@ -0,0 +1,96 @@
+package sl_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"unicode/utf8"
+
+	"quotes-service/internal/lib/logger/sl"
+	"quotes-service/internal/lib/usertext"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		n    int
+		want string
+	}{
+		{name: "short", s: "Well begun", n: 64, want: "Well begun"},
+		{name: "exact", s: "abc", n: 3, want: "abc"},
+		{name: "ascii", s: "abcdef", n: 3, want: "abc…"},
+		{name: "cyrillic", s: "Красота спасёт мир", n: 7, want: "Красота…"},
+		{name: "cjk", s: "千里之行始於足下", n: 4, want: "千里之行…"},
+		{name: "emoji", s: "🙂🙃🙂🙃", n: 1, want: "🙂…"},
+		{name: "zero", s: "abc", n: 0, want: ""},
+		{name: "empty", s: "", n: 5, want: ""},
+		{name: "invalid utf-8 is kept as is", s: "a\xffbc", n: 2, want: "a\xff…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sl.Truncate(tt.s, tt.n)
+			if got != tt.want {
+				t.Fatalf("Truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+			}
+			if utf8.ValidString(tt.s) && !utf8.ValidString(got) {
+				t.Fatalf("Truncate(%q, %d) split a UTF-8 sequence: %q", tt.s, tt.n, got)
+			}
+		})
+	}
+}
+
+func TestPreview(t *testing.T) {
+	defer usertext.Set(usertext.Default)
+
+	tests := []struct {
+		name    string
+		chars   int
+		text    string
+		wantLen int
+		want    string
+	}{
+		{name: "default", chars: usertext.Default.Log, text: "Жизнь коротка, искусство вечно.", wantLen: 31, want: "Жизнь коротка, искусство вечно."},
+		{name: "truncated", chars: 5, text: "Жизнь коротка, искусство вечно.", wantLen: 31, want: "Жизнь…"},
+		{name: "length only", chars: 0, text: "secret", wantLen: 6, want: ""},
+		{name: "unlimited", chars: usertext.Unlimited, text: "Жизнь коротка, искусство вечно.", wantLen: 31, want: "Жизнь коротка, искусство вечно."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usertext.Set(usertext.Policy{Log: tt.chars})
+			var buf bytes.Buffer
+			slog.New(slog.NewJSONHandler(&buf, nil)).Info("added", sl.Preview("text", tt.text))
+
+			var entry struct {
+				Text struct {
+					Len     int    `json:"len"`
+					Preview string `json:"preview"`
+				} `json:"text"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("invalid log entry %s: %v", buf.String(), err)
+			}
+			if entry.Text.Len != tt.wantLen || entry.Text.Preview != tt.want {
+				t.Fatalf("got len %d preview %q, want %d %q", entry.Text.Len, entry.Text.Preview, tt.wantLen, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserText(t *testing.T) {
+	defer usertext.Set(usertext.Default)
+	usertext.Set(usertext.Policy{Log: 3})
+
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("invalid parameter", sl.UserText("value", "Пушкин"))
+	var entry struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid log entry %s: %v", buf.String(), err)
+	}
+	if entry.Value != "Пуш…" {
+		t.Fatalf("expected the value cut to 3 characters, got %q", entry.Value)
+	}
+}
@@ -0,0 +1,206 @@
+// Package mailer assembles multipart emails and delivers them over SMTP.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is an email with a plain-text and an HTML version of the same
+// body. Mail clients show the HTML one when they can.
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Date    time.Time
+	Text    string
+	HTML    string
+	// Boundary separates the two parts. Empty picks a random one; tests
+	// set it to get stable output.
+	Boundary string
+}
+
+// Bytes renders the message in RFC 5322 format with CRLF line endings.
+func (m Message) Bytes() ([]byte, error) {
+	from, to, err := m.addresses()
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	if m.Boundary != "" {
+		if err := parts.SetBoundary(m.Boundary); err != nil {
+			return nil, fmt.Errorf("set boundary: %w", err)
+		}
+	}
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{contentType: "text/plain; charset=utf-8", content: m.Text},
+		{contentType: "text/html; charset=utf-8", content: m.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(toCRLF(part.content))); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	date := m.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	var msg bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&msg, "%s: %s\r\n", name, value)
+	}
+	header("From", from.String())
+	toList := make([]string, len(to))
+	for i, addr := range to {
+		toList[i] = addr.String()
+	}
+	header("To", strings.Join(toList, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", date.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": parts.Boundary()}))
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// addresses parses the sender and recipients, which may carry display
+// names ("Editors <editors@example.com>").
+func (m Message) addresses() (*mail.Address, []*mail.Address, error) {
+	if m.From == "" || len(m.To) == 0 {
+		return nil, nil, errors.New("message needs a sender and at least one recipient")
+	}
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse sender: %w", err)
+	}
+	to := make([]*mail.Address, 0, len(m.To))
+	for _, rcpt := range m.To {
+		addr, err := mail.ParseAddress(rcpt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse recipient %q: %w", rcpt, err)
+		}
+		to = append(to, addr)
+	}
+	return from, to, nil
+}
+
+func toCRLF(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\n", "\r\n")
+}
+
+// Options configures an SMTP sender. Username and Password are optional;
+// when set they are sent with PLAIN auth, which net/smtp only allows over
+// TLS or to localhost.
+type Options struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+// SMTP sends messages through a single relay, upgrading the connection
+// with STARTTLS whenever the server offers it.
+type SMTP struct {
+	opts Options
+}
+
+func New(opts Options) *SMTP {
+	return &SMTP{opts: opts}
+}
+
+// Send delivers msg to all of its recipients. ctx bounds the whole
+// exchange with the server.
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	from, to, err := msg.addresses()
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(s.opts.Host, strconv.Itoa(s.opts.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	// Closing the connection unblocks the client if ctx ends mid-exchange.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, s.opts.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.opts.Host}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if s.opts.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.opts.Username, s.opts.Password, s.opts.Host)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt.Address); err != nil {
+			return fmt.Errorf("recipient %s: %w", rcpt.Address, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,64 @@
+package mailer_test
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"quotes-service/internal/lib/mailer"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestMessageBytes(t *testing.T) {
+	msg := mailer.Message{
+		From:     "Quotes Service <quotes@example.com>",
+		To:       []string{"editors@example.com", "Ана Редактор <ana@example.com>"},
+		Subject:  "Weekly digest: 2 new quotes — «Цитаты»",
+		Date:     time.Date(2024, time.March, 11, 9, 0, 0, 0, time.UTC),
+		Text:     "Line one\nA long line that goes past the seventy six character limit of quoted-printable encoding.\n",
+		HTML:     "<p>Caf\u00e9 &amp; cr\u00e8me</p>\n",
+		Boundary: "digest-boundary",
+	}
+
+	got, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("failed to build message: %v", err)
+	}
+
+	golden := filepath.Join("testdata", "message.golden")
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("message does not match %s:\n%s", golden, got)
+	}
+}
+
+func TestMessageBytesInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  mailer.Message
+	}{
+		{name: "no sender", msg: mailer.Message{To: []string{"a@example.com"}}},
+		{name: "no recipients", msg: mailer.Message{From: "a@example.com"}},
+		{name: "bad recipient", msg: mailer.Message{From: "a@example.com", To: []string{"not an address"}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.msg.Bytes(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+// Package moderation keeps the quotes the content filter held until a
+// moderator approves or rejects them. The queue lives in memory, so held
+// quotes are lost on restart.
+package moderation
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+var (
+	ErrFull     = errors.New("moderation queue is full")
+	ErrNotFound = errors.New("held quote not found")
+)
+
+// Entry is a held quote and what approving it does: add Quote, or, when
+// Update is set, apply Update to the quote QuoteID.
+type Entry struct {
+	models.HeldQuote
+	Update *storage.QuoteUpdate
+}
+
+// Queue holds up to a fixed number of entries, oldest first.
+type Queue struct {
+	max int
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// New returns a queue of up to max entries.
+func New(max int) *Queue {
+	return &Queue{max: max, now: time.Now}
+}
+
+// Hold adds entry to the queue, assigning its ID and time.
+func (q *Queue) Hold(entry Entry) (models.HeldQuote, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.entries) >= q.max {
+		return models.HeldQuote{}, ErrFull
+	}
+	entry.ID = newID()
+	entry.HeldAt = q.now().UTC()
+	q.entries = append(q.entries, entry)
+	return entry.HeldQuote, nil
+}
+
+// List returns the held quotes, oldest first.
+func (q *Queue) List() []models.HeldQuote {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	held := make([]models.HeldQuote, len(q.entries))
+	for i, entry := range q.entries {
+		held[i] = entry.HeldQuote
+	}
+	return held
+}
+
+// Take removes the entry with the given ID from the queue and returns it,
+// so that only one moderator acts on it.
+func (q *Queue) Take(id string) (Entry, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i := slices.IndexFunc(q.entries, func(entry Entry) bool {
+		return entry.ID == id
+	})
+	if i < 0 {
+		return Entry{}, ErrNotFound
+	}
+	entry := q.entries[i]
+	q.entries = slices.Delete(q.entries, i, i+1)
+	return entry, nil
+}
+
+// Restore puts back an entry Take returned, in its place by age, when
+// acting on it failed. It may exceed the size of the queue.
+func (q *Queue) Restore(entry Entry) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i := slices.IndexFunc(q.entries, func(e Entry) bool {
+		return !e.HeldAt.Before(entry.HeldAt)
+	})
+	if i < 0 {
+		i = len(q.entries)
+	}
+	q.entries = slices.Insert(q.entries, i, entry)
+}
+
+func newID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
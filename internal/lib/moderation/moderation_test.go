@@ -0,0 +1,54 @@
+package moderation_test
+
+import (
+	"errors"
+	"testing"
+
+	"quotes-service/internal/lib/moderation"
+	"quotes-service/internal/models"
+)
+
+func hold(t *testing.T, q *moderation.Queue, text string) models.HeldQuote {
+	t.Helper()
+	held, err := q.Hold(moderation.Entry{HeldQuote: models.HeldQuote{Source: "create", Quote: models.Quote{Text: text}}})
+	if err != nil {
+		t.Fatalf("failed to hold %q: %v", text, err)
+	}
+	return held
+}
+
+func texts(q *moderation.Queue) []string {
+	var texts []string
+	for _, held := range q.List() {
+		texts = append(texts, held.Quote.Text)
+	}
+	return texts
+}
+
+func TestQueue(t *testing.T) {
+	q := moderation.New(2)
+	first := hold(t, q, "first")
+	hold(t, q, "second")
+	if first.ID == "" || first.HeldAt.IsZero() {
+		t.Fatalf("expected an ID and time, got %+v", first)
+	}
+	if _, err := q.Hold(moderation.Entry{}); !errors.Is(err, moderation.ErrFull) {
+		t.Fatalf("expected ErrFull, got %v", err)
+	}
+
+	entry, err := q.Take(first.ID)
+	if err != nil || entry.Quote.Text != "first" {
+		t.Fatalf("unexpected entry %+v, %v", entry, err)
+	}
+	if _, err := q.Take(first.ID); !errors.Is(err, moderation.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound taking twice, got %v", err)
+	}
+	if got := texts(q); len(got) != 1 || got[0] != "second" {
+		t.Fatalf("unexpected queue %v", got)
+	}
+
+	q.Restore(entry)
+	if got := texts(q); len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Fatalf("expected the restored entry back in its place, got %v", got)
+	}
+}
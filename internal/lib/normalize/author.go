@@ -0,0 +1,90 @@
+package normalize
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The problems CheckAuthor finds, worded to follow the name of the field
+// the author was given in.
+var (
+	ErrAuthorControl     = errors.New("must not contain control characters, line breaks or direction overrides")
+	ErrAuthorPunctuation = errors.New("must contain a letter or a digit")
+)
+
+// AuthorTooLongError reports an author name longer than Max characters.
+type AuthorTooLongError struct {
+	Max int
+}
+
+func (e *AuthorTooLongError) Error() string {
+	return fmt.Sprintf("must be at most %d characters", e.Max)
+}
+
+// CheckAuthor reports what is wrong with an author name, or nil if
+// nothing is: a control character or line break, which break exports and
+// lists, a bidirectional formatting character, which can make a name read
+// as another, invalid UTF-8, more than maxChars characters once
+// surrounding and repeated spaces are dropped, or no letter or digit at
+// all. Zero maxChars allows any length. Empty names are left to the caller.
+func CheckAuthor(name string, maxChars int) error {
+	hasWord := false
+	for _, r := range name {
+		if unsafeInName(r) {
+			return ErrAuthorControl
+		}
+		hasWord = hasWord || unicode.IsLetter(r) || unicode.IsNumber(r)
+	}
+	if maxChars > 0 && utf8.RuneCountInString(strings.Join(strings.Fields(name), " ")) > maxChars {
+		return &AuthorTooLongError{Max: maxChars}
+	}
+	if !hasWord && strings.TrimSpace(name) != "" {
+		return ErrAuthorPunctuation
+	}
+	return nil
+}
+
+// SanitizeAuthor is the lenient counterpart of CheckAuthor: it turns
+// control characters and line breaks into spaces, drops bidirectional
+// formatting characters, collapses spaces and cuts the name to maxChars
+// characters. A name without a letter or digit cannot be mended, so
+// CheckAuthor may still fail on the result.
+func SanitizeAuthor(name string, maxChars int) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case bidiControl(r):
+			return -1
+		case unsafeInName(r):
+			return ' '
+		}
+		return r
+	}, name)
+	name = strings.Join(strings.Fields(name), " ")
+	if maxChars > 0 && utf8.RuneCountInString(name) > maxChars {
+		name = strings.TrimSpace(string([]rune(name)[:maxChars]))
+	}
+	return name
+}
+
+// unsafeInName reports the characters no author name may contain. Zero
+// width joiners stay allowed: some scripts need them.
+func unsafeInName(r rune) bool {
+	return unicode.IsControl(r) || r == '\u2028' || r == '\u2029' || bidiControl(r) || r == utf8.RuneError
+}
+
+// bidiControl reports the bidirectional marks, embeddings, overrides and
+// isolates.
+func bidiControl(r rune) bool {
+	switch {
+	case r == '\u061c', r == '\u200e', r == '\u200f':
+		return true
+	case '\u202a' <= r && r <= '\u202e':
+		return true
+	case '\u2066' <= r && r <= '\u2069':
+		return true
+	}
+	return false
+}
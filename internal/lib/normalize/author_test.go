@@ -0,0 +1,88 @@
+package normalize_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"quotes-service/internal/lib/normalize"
+)
+
+func TestCheckAuthor(t *testing.T) {
+	tests := []struct {
+		name        string
+		author      string
+		maxChars    int
+		expectedErr error
+	}{
+		{name: "plain", author: "Марк Аврелий"},
+		{name: "arabic", author: "ابن خلدون"},
+		{name: "initials and digits", author: "J. R. R. Tolkien 3"},
+		{name: "zero width joiner", author: "क्\u200dष"},
+		{name: "newline", author: "Mark\nTwain", expectedErr: normalize.ErrAuthorControl},
+		{name: "carriage return", author: "Mark Twain\r", expectedErr: normalize.ErrAuthorControl},
+		{name: "tab", author: "Mark\tTwain", expectedErr: normalize.ErrAuthorControl},
+		{name: "line separator", author: "Mark\u2028Twain", expectedErr: normalize.ErrAuthorControl},
+		{name: "right-to-left override", author: "Mark \u202eniawT", expectedErr: normalize.ErrAuthorControl},
+		{name: "right-to-left isolate", author: "\u2067Mark Twain\u2069", expectedErr: normalize.ErrAuthorControl},
+		{name: "left-to-right mark", author: "Mark\u200e Twain", expectedErr: normalize.ErrAuthorControl},
+		{name: "invalid utf-8", author: "Mark \xff", expectedErr: normalize.ErrAuthorControl},
+		{name: "punctuation only", author: "?!", expectedErr: normalize.ErrAuthorPunctuation},
+		{name: "dashes and dots", author: " - . - ", expectedErr: normalize.ErrAuthorPunctuation},
+		{name: "at the limit", author: "Ab Cd", maxChars: 5},
+		{name: "spaces do not count", author: "  Ab   Cd  ", maxChars: 5},
+		{name: "over the limit", author: "Ab Cde", maxChars: 5, expectedErr: &normalize.AuthorTooLongError{Max: 5}},
+		{name: "characters not bytes", author: "Жжжжж", maxChars: 5},
+		{name: "no limit", author: strings.Repeat("a", 10000)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := normalize.CheckAuthor(tc.author, tc.maxChars)
+			var tooLong *normalize.AuthorTooLongError
+			switch {
+			case tc.expectedErr == nil && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case errors.As(tc.expectedErr, &tooLong):
+				var got *normalize.AuthorTooLongError
+				if !errors.As(err, &got) || got.Max != tooLong.Max {
+					t.Fatalf("expected %v, got %v", tc.expectedErr, err)
+				}
+			case tc.expectedErr != nil && !errors.Is(err, tc.expectedErr):
+				t.Fatalf("expected %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestSanitizeAuthor(t *testing.T) {
+	tests := []struct {
+		name     string
+		author   string
+		maxChars int
+		expected string
+		// unmendable names still fail CheckAuthor once sanitized.
+		unmendable bool
+	}{
+		{name: "unchanged", author: "Марк Аврелий", expected: "Марк Аврелий"},
+		{name: "newline", author: "Mark\nTwain", expected: "Mark Twain"},
+		{name: "crlf and tabs", author: "\tMark\r\n Twain\r\n", expected: "Mark Twain"},
+		{name: "right-to-left override", author: "Mark \u202eniawT", expected: "Mark niawT"},
+		{name: "isolates and marks", author: "\u2067Mark\u200e Twain\u2069", expected: "Mark Twain"},
+		{name: "zero width joiner kept", author: "क्\u200dष", expected: "क्\u200dष"},
+		{name: "truncated", author: "Mark Twain", maxChars: 5, expected: "Mark"},
+		{name: "punctuation left as it is", author: "?!", expected: "?!", unmendable: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := normalize.SanitizeAuthor(tc.author, tc.maxChars)
+			if got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+			if err := normalize.CheckAuthor(got, tc.maxChars); (err != nil) != tc.unmendable {
+				t.Fatalf("expected the sanitized name to pass the check: %t, got %v", !tc.unmendable, err)
+			}
+		})
+	}
+}
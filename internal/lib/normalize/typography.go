@@ -0,0 +1,125 @@
+// Package normalize rewrites quote text pasted from word processors so
+// that it compares and displays consistently.
+package normalize
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Typography selects how quote marks, dashes and non-breaking spaces in
+// quote text are stored.
+type Typography string
+
+const (
+	// TypographyOff stores text as it was sent.
+	TypographyOff Typography = "off"
+	// TypographyPlain stores straight quotes, hyphens and plain spaces.
+	TypographyPlain Typography = "plain"
+	// TypographyPrettify stores curly quotes and em dashes, keeping the
+	// typographic characters the text already had.
+	TypographyPrettify Typography = "prettify"
+)
+
+// ParseTypography validates a typography mode name from configuration.
+func ParseTypography(s string) (Typography, error) {
+	switch t := Typography(s); t {
+	case TypographyOff, TypographyPlain, TypographyPrettify:
+		return t, nil
+	default:
+		return "", fmt.Errorf("unknown typography mode %q", s)
+	}
+}
+
+// Apply rewrites text for storage according to t.
+func (t Typography) Apply(text string) string {
+	switch t {
+	case TypographyPlain:
+		return Plain(text)
+	case TypographyPrettify:
+		return Pretty(text)
+	default:
+		return text
+	}
+}
+
+// plainRunes maps typographic quote marks, dashes and fixed-width spaces
+// to their ASCII counterparts. Guillemets are left alone: they are the
+// ordinary quote marks of Russian text rather than something a word
+// processor substituted.
+var plainRunes = map[rune]rune{
+	'\u2018': '\'', // left single quotation mark
+	'\u2019': '\'', // right single quotation mark
+	'\u201a': '\'', // single low-9 quotation mark
+	'\u201b': '\'', // single high-reversed-9 quotation mark
+	'\u2032': '\'', // prime
+	'\u201c': '"',  // left double quotation mark
+	'\u201d': '"',  // right double quotation mark
+	'\u201e': '"',  // double low-9 quotation mark
+	'\u201f': '"',  // double high-reversed-9 quotation mark
+	'\u2033': '"',  // double prime
+	'\u2010': '-',  // hyphen
+	'\u2011': '-',  // non-breaking hyphen
+	'\u2012': '-',  // figure dash
+	'\u2013': '-',  // en dash
+	'\u2014': '-',  // em dash
+	'\u2015': '-',  // horizontal bar
+	'\u2212': '-',  // minus sign
+	'\u00a0': ' ',  // no-break space
+	'\u2007': ' ',  // figure space
+	'\u202f': ' ',  // narrow no-break space
+}
+
+// Plain replaces curly quotes with straight ones, dashes with hyphens and
+// non-breaking spaces with plain spaces.
+func Plain(text string) string {
+	return strings.Map(func(r rune) rune {
+		if plain, ok := plainRunes[r]; ok {
+			return plain
+		}
+		return r
+	}, text)
+}
+
+// Pretty replaces straight quotes with curly ones, opening or closing by
+// what precedes them, a hyphen standing alone between spaces or a double
+// hyphen with an em dash, and non-breaking spaces with plain spaces.
+// Hyphens inside words and characters that are already typographic are
+// kept.
+func Pretty(text string) string {
+	runes := []rune(text)
+	var b strings.Builder
+	b.Grow(len(text))
+	prev := ' '
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\u00a0' || r == '\u2007' || r == '\u202f':
+			r = ' '
+		case r == '"':
+			r = '”'
+			if opens(prev) {
+				r = '“'
+			}
+		case r == '\'':
+			r = '’'
+			if opens(prev) {
+				r = '‘'
+			}
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			r = '—'
+			i++
+		case r == '-' && unicode.IsSpace(prev) && i > 0 && (i+1 == len(runes) || unicode.IsSpace(runes[i+1])):
+			r = '—'
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+	return b.String()
+}
+
+// opens reports whether a quote mark after prev opens a quotation.
+func opens(prev rune) bool {
+	return unicode.IsSpace(prev) || strings.ContainsRune("([{—–-“‘", prev)
+}
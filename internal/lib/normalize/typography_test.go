@@ -0,0 +1,128 @@
+package normalize_test
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"quotes-service/internal/lib/normalize"
+)
+
+// plain lists every rune Plain rewrites. Every other rune must survive.
+var plain = map[rune]string{
+	'‘':      "'",
+	'’':      "'",
+	'‚':      "'",
+	'‛':      "'",
+	'′':      "'",
+	'“':      `"`,
+	'”':      `"`,
+	'„':      `"`,
+	'‟':      `"`,
+	'″':      `"`,
+	'‐':      "-",
+	'‑':      "-",
+	'‒':      "-",
+	'–':      "-",
+	'—':      "-",
+	'―':      "-",
+	'−':      "-",
+	'\u00a0': " ",
+	'\u2007': " ",
+	'\u202f': " ",
+}
+
+func TestPlainRunes(t *testing.T) {
+	for r := rune(0); r <= utf8.MaxRune; r++ {
+		if !utf8.ValidRune(r) {
+			continue
+		}
+		want, mapped := plain[r]
+		if !mapped {
+			want = string(r)
+		}
+		if got := normalize.Plain(string(r)); got != want {
+			t.Fatalf("Plain(%U) = %q, want %q", r, got, want)
+		}
+	}
+}
+
+func TestPlain(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{name: "curly quotes", text: "“Don’t panic,” he said.", expected: `"Don't panic," he said.`},
+		{name: "dashes", text: "1914–1918 — a war", expected: "1914-1918 - a war"},
+		{name: "no-break spaces", text: "Hello,\u00a0world\u202f!", expected: "Hello, world !"},
+		{name: "guillemets kept", text: "«Рукописи не горят»", expected: "«Рукописи не горят»"},
+		{name: "already plain", text: `"Plain" - text`, expected: `"Plain" - text`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := normalize.Plain(tc.text); got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestPretty(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{name: "double quotes", text: `"Hello," she said, "world."`, expected: "“Hello,” she said, “world.”"},
+		{name: "apostrophe", text: "Don't stop", expected: "Don’t stop"},
+		{name: "single quotes", text: "a 'word' here", expected: "a ‘word’ here"},
+		{name: "quote after bracket", text: `("yes")`, expected: "(“yes”)"},
+		{name: "spaced hyphen", text: "Wait - what?", expected: "Wait — what?"},
+		{name: "double hyphen", text: "Wait--what?", expected: "Wait—what?"},
+		{name: "hyphen in word", text: "well-known", expected: "well-known"},
+		{name: "leading hyphen", text: "-5 degrees", expected: "-5 degrees"},
+		{name: "trailing hyphen", text: "and so -", expected: "and so —"},
+		{name: "no-break space", text: "a\u00a0b", expected: "a b"},
+		{name: "typographic kept", text: "1914–1918 “war”", expected: "1914–1918 “war”"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := normalize.Pretty(tc.text); got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseTypography(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		text        string
+		expected    string
+		expectedErr bool
+	}{
+		{name: "off", mode: "off", text: "“a”\u00a0—", expected: "“a”\u00a0—"},
+		{name: "plain", mode: "plain", text: "“a”\u00a0—", expected: `"a" -`},
+		{name: "prettify", mode: "prettify", text: `"a" -`, expected: "“a” —"},
+		{name: "unknown", mode: "fancy", expectedErr: true},
+		{name: "empty", mode: "", expectedErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			typography, err := normalize.ParseTypography(tc.mode)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := typography.Apply(tc.text); got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+// Package panicreport forwards reports of recovered handler panics to an
+// external sink, a webhook endpoint or a Sentry-compatible server, so that
+// alerting can page on them. Reporting never blocks or fails the request
+// that panicked: reports wait in a small queue, and the ones that do not
+// fit are dropped.
+package panicreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"quotes-service/internal/lib/webhook"
+	"quotes-service/internal/models"
+)
+
+// Kinds of sink.
+const (
+	// SinkWebhook posts every report as a webhook event of EventType.
+	SinkWebhook = "webhook"
+	// SinkSentry stores every report as an event through the store
+	// endpoint of the project named by a Sentry DSN.
+	SinkSentry = "sentry"
+)
+
+// EventType is the webhook event a report is delivered as.
+const EventType = "panic.recovered"
+
+// SentryAuthHeader carries the DSN's public key to a Sentry server.
+const SentryAuthHeader = "X-Sentry-Auth"
+
+const (
+	defaultQueueSize = 100
+	// sendTimeout bounds one delivery, retries included.
+	sendTimeout = 30 * time.Second
+)
+
+// Options configures a Reporter.
+type Options struct {
+	// Sink is SinkWebhook or SinkSentry.
+	Sink string
+	// URL is the webhook endpoint, or the Sentry DSN:
+	// https://<public key>@<host>/<project id>.
+	URL string
+	// Secret signs webhook deliveries as webhook.Sign does. Unused for
+	// Sentry.
+	Secret string
+	// QueueSize bounds the reports waiting to be sent. Zero picks the
+	// default.
+	QueueSize int
+}
+
+type Reporter struct {
+	log     *slog.Logger
+	client  *http.Client
+	queue   chan models.PanicReport
+	send    func(ctx context.Context, report models.PanicReport) error
+	dropped atomic.Int64
+
+	webhookOpts []webhook.Option
+}
+
+type Option func(*Reporter)
+
+// WithHTTPClient replaces the client used for deliveries.
+func WithHTTPClient(client *http.Client) Option {
+	return func(r *Reporter) {
+		r.client = client
+		r.webhookOpts = append(r.webhookOpts, webhook.WithHTTPClient(client))
+	}
+}
+
+// New returns a Reporter for the sink in opts. Nothing is sent until Run
+// is called.
+func New(log *slog.Logger, opts Options, options ...Option) (*Reporter, error) {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultQueueSize
+	}
+	r := &Reporter{
+		log:    log.With(slog.String("op", "panicreport.Reporter")),
+		client: &http.Client{Timeout: 10 * time.Second},
+		queue:  make(chan models.PanicReport, opts.QueueSize),
+	}
+	for _, opt := range options {
+		opt(r)
+	}
+
+	switch opts.Sink {
+	case SinkWebhook:
+		dispatcher := webhook.New(log, []webhook.Endpoint{{URL: opts.URL, Secret: opts.Secret}}, r.webhookOpts...)
+		r.send = func(ctx context.Context, report models.PanicReport) error {
+			return dispatcher.Dispatch(ctx, webhook.NewEvent(EventType, report))
+		}
+	case SinkSentry:
+		dsn, err := ParseDSN(opts.URL)
+		if err != nil {
+			return nil, err
+		}
+		r.send = func(ctx context.Context, report models.PanicReport) error {
+			return r.sendSentry(ctx, dsn, report)
+		}
+	default:
+		return nil, fmt.Errorf("panicreport: unknown sink %q", opts.Sink)
+	}
+	return r, nil
+}
+
+// Report queues report to be sent without ever blocking. It is dropped if
+// the queue is full.
+func (r *Reporter) Report(report models.PanicReport) {
+	select {
+	case r.queue <- report:
+	default:
+		dropped := r.dropped.Add(1)
+		r.log.Warn("panic report dropped, queue is full", slog.String("request_id", report.RequestID), slog.Int64("dropped", dropped))
+	}
+}
+
+// Dropped returns how many reports did not fit in the queue.
+func (r *Reporter) Dropped() int64 {
+	return r.dropped.Load()
+}
+
+// Run sends queued reports until ctx is done. Reports still queued then
+// are not sent.
+func (r *Reporter) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case report := <-r.queue:
+			sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+			if err := r.send(sendCtx, report); err != nil {
+				r.log.WarnContext(ctx, "failed to send panic report", slog.String("request_id", report.RequestID), slog.String("error", err.Error()))
+			}
+			cancel()
+		}
+	}
+}
+
+// DSN is a parsed Sentry DSN.
+type DSN struct {
+	PublicKey string
+	// StoreURL is the project's event store endpoint.
+	StoreURL string
+}
+
+// ParseDSN parses a Sentry DSN of the form
+// https://<public key>@<host>[/<path>]/<project id>.
+func ParseDSN(raw string) (DSN, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return DSN{}, fmt.Errorf("panicreport: invalid DSN: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return DSN{}, errors.New("panicreport: DSN must be an absolute http(s) URL")
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return DSN{}, errors.New("panicreport: DSN has no public key")
+	}
+	path := strings.Trim(u.Path, "/")
+	prefix, project := "", path
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		prefix, project = "/"+path[:i], path[i+1:]
+	}
+	if project == "" {
+		return DSN{}, errors.New("panicreport: DSN has no project ID")
+	}
+	store := url.URL{Scheme: u.Scheme, Host: u.Host, Path: prefix + "/api/" + project + "/store/"}
+	return DSN{PublicKey: u.User.Username(), StoreURL: store.String()}, nil
+}
+
+// sentryEvent is the subset of the Sentry event payload a report fills.
+type sentryEvent struct {
+	EventID   string            `json:"event_id"`
+	Timestamp string            `json:"timestamp"`
+	Level     string            `json:"level"`
+	Platform  string            `json:"platform"`
+	Message   string            `json:"message"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Request   sentryRequest     `json:"request"`
+	Extra     map[string]string `json:"extra"`
+}
+
+type sentryRequest struct {
+	Method      string            `json:"method"`
+	URL         string            `json:"url"`
+	QueryString string            `json:"query_string,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+func (r *Reporter) sendSentry(ctx context.Context, dsn DSN, report models.PanicReport) error {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	event := sentryEvent{
+		EventID:   hex.EncodeToString(id[:]),
+		Timestamp: report.Time.UTC().Format(time.RFC3339),
+		Level:     "error",
+		Platform:  "go",
+		Message:   "panic: " + report.Panic,
+		Tags:      map[string]string{},
+		Request: sentryRequest{
+			Method:      report.Method,
+			URL:         report.Path,
+			QueryString: report.Query,
+		},
+		Extra: map[string]string{"stack": report.Stack},
+	}
+	if report.Route != "" {
+		event.Tags["route"] = report.Route
+	}
+	if report.RequestID != "" {
+		event.Tags["request_id"] = report.RequestID
+	}
+	if report.UserAgent != "" {
+		event.Request.Headers = map[string]string{"User-Agent": report.UserAgent}
+	}
+	if report.RemoteAddr != "" {
+		event.Extra["remote_addr"] = report.RemoteAddr
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode sentry event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dsn.StoreURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SentryAuthHeader, "Sentry sentry_version=7, sentry_client=quotes-service, sentry_key="+dsn.PublicKey)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("sentry returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package panicreport_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"quotes-service/internal/lib/panicreport"
+	"quotes-service/internal/lib/webhook"
+	"quotes-service/internal/models"
+)
+
+var testReport = models.PanicReport{
+	Time:      time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	RequestID: "0123456789abcdef0123456789abcdef",
+	Method:    http.MethodGet,
+	Route:     "/quotes/{id}",
+	Path:      "/quotes/7",
+	UserAgent: "curl/8.0",
+	Panic:     "runtime error: index out of range",
+	Stack:     "goroutine 1 [running]:\nmain.main()",
+}
+
+type request struct {
+	header http.Header
+	body   []byte
+}
+
+// capture serves requests with status and hands them to the test.
+func capture(t *testing.T, status int) (*httptest.Server, <-chan request) {
+	t.Helper()
+	requests := make(chan request, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{header: r.Header.Clone(), body: body}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, requests
+}
+
+// run runs reporter until the test ends.
+func run(t *testing.T, reporter *panicreport.Reporter) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		reporter.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func receive(t *testing.T, requests <-chan request) request {
+	t.Helper()
+	select {
+	case req := <-requests:
+		return req
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the report")
+		return request{}
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	srv, requests := capture(t, http.StatusNoContent)
+	reporter, err := panicreport.New(slog.New(slog.DiscardHandler), panicreport.Options{
+		Sink:   panicreport.SinkWebhook,
+		URL:    srv.URL,
+		Secret: "s3cret",
+	})
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+	run(t, reporter)
+
+	reporter.Report(testReport)
+	req := receive(t, requests)
+
+	if got := req.header.Get(webhook.EventHeader); got != panicreport.EventType {
+		t.Errorf("expected event %q, got %q", panicreport.EventType, got)
+	}
+	if got := req.header.Get(webhook.SignatureHeader); got != webhook.Sign("s3cret", req.body) {
+		t.Errorf("unexpected signature %q", got)
+	}
+	var event struct {
+		Type string             `json:"type"`
+		Data models.PanicReport `json:"data"`
+	}
+	if err := json.Unmarshal(req.body, &event); err != nil {
+		t.Fatalf("invalid payload %s: %v", req.body, err)
+	}
+	if event.Type != panicreport.EventType || event.Data != testReport {
+		t.Fatalf("unexpected event %+v", event)
+	}
+}
+
+func TestSentrySink(t *testing.T) {
+	srv, requests := capture(t, http.StatusOK)
+	dsn := strings.Replace(srv.URL, "://", "://public-key@", 1) + "/42"
+	reporter, err := panicreport.New(slog.New(slog.DiscardHandler), panicreport.Options{Sink: panicreport.SinkSentry, URL: dsn})
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+	run(t, reporter)
+
+	reporter.Report(testReport)
+	req := receive(t, requests)
+
+	if got := req.header.Get(panicreport.SentryAuthHeader); !strings.Contains(got, "sentry_key=public-key") {
+		t.Errorf("expected the DSN key in the auth header, got %q", got)
+	}
+	var event struct {
+		EventID   string            `json:"event_id"`
+		Timestamp string            `json:"timestamp"`
+		Level     string            `json:"level"`
+		Message   string            `json:"message"`
+		Tags      map[string]string `json:"tags"`
+		Request   struct {
+			Method string `json:"method"`
+			URL    string `json:"url"`
+		} `json:"request"`
+		Extra map[string]string `json:"extra"`
+	}
+	if err := json.Unmarshal(req.body, &event); err != nil {
+		t.Fatalf("invalid payload %s: %v", req.body, err)
+	}
+	if len(event.EventID) != 32 || event.Timestamp != "2026-03-01T12:00:00Z" || event.Level != "error" {
+		t.Errorf("unexpected event header fields %+v", event)
+	}
+	if event.Message != "panic: "+testReport.Panic || event.Extra["stack"] != testReport.Stack {
+		t.Errorf("unexpected message or stack %+v", event)
+	}
+	if event.Tags["route"] != testReport.Route || event.Tags["request_id"] != testReport.RequestID {
+		t.Errorf("unexpected tags %v", event.Tags)
+	}
+	if event.Request.Method != http.MethodGet || event.Request.URL != testReport.Path {
+		t.Errorf("unexpected request %+v", event.Request)
+	}
+}
+
+func TestParseDSN(t *testing.T) {
+	tests := []struct {
+		name    string
+		dsn     string
+		want    panicreport.DSN
+		wantErr bool
+	}{
+		{name: "hosted", dsn: "https://abc@o1.ingest.sentry.io/42", want: panicreport.DSN{PublicKey: "abc", StoreURL: "https://o1.ingest.sentry.io/api/42/store/"}},
+		{name: "path prefix", dsn: "http://abc@sentry.local/errors/7", want: panicreport.DSN{PublicKey: "abc", StoreURL: "http://sentry.local/errors/api/7/store/"}},
+		{name: "no key", dsn: "https://sentry.local/42", wantErr: true},
+		{name: "no project", dsn: "https://abc@sentry.local/", wantErr: true},
+		{name: "not http", dsn: "ftp://abc@sentry.local/42", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := panicreport.ParseDSN(tt.dsn)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFullQueueDropsReports(t *testing.T) {
+	reporter, err := panicreport.New(slog.New(slog.DiscardHandler), panicreport.Options{
+		Sink:      panicreport.SinkWebhook,
+		URL:       "http://127.0.0.1:1",
+		QueueSize: 2,
+	})
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+
+	// Without Run nothing drains the queue.
+	for range 5 {
+		reporter.Report(testReport)
+	}
+	if got := reporter.Dropped(); got != 3 {
+		t.Fatalf("expected 3 dropped reports, got %d", got)
+	}
+}
@@ -0,0 +1,89 @@
+// Package pathnorm puts request paths in one canonical form, so that
+// "/quotes/", "//quotes" and "/quotes" all reach the same route.
+package pathnorm
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Mode is how a request for a path that is not canonical is answered.
+type Mode string
+
+const (
+	// ModeRedirect redirects the client to the canonical path: 301 for GET
+	// and HEAD, 308 for the other methods so that their bodies are sent
+	// again.
+	ModeRedirect Mode = "redirect"
+	// ModeRewrite serves the canonical path as if it had been asked for.
+	ModeRewrite Mode = "rewrite"
+	// ModeOff leaves paths as they come, so that a trailing slash 404s.
+	ModeOff Mode = "off"
+)
+
+// ParseMode validates a mode name from configuration.
+func ParseMode(s string) (Mode, error) {
+	switch mode := Mode(s); mode {
+	case ModeRedirect, ModeRewrite, ModeOff:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown path mode %q", s)
+	}
+}
+
+// Clean returns the canonical form of the escaped path p: absolute, with
+// no empty, "." or ".." segments and no trailing slash, and with every
+// percent-encoded character that needs no encoding decoded and the others'
+// hex digits in upper case. An encoded slash stays encoded, as it is part
+// of a segment, such as an author name, rather than a separator.
+func Clean(p string) string {
+	return path.Clean("/" + unescapeUnreserved(p))
+}
+
+// unescapeUnreserved decodes the escapes in p of the characters RFC 3986
+// leaves unreserved and upper-cases the hex digits of the others. Invalid
+// escapes are left for the handler to reject.
+func unescapeUnreserved(p string) string {
+	if !strings.Contains(p, "%") {
+		return p
+	}
+	var b strings.Builder
+	b.Grow(len(p))
+	for i := 0; i < len(p); i++ {
+		if p[i] != '%' || i+2 >= len(p) || !isHex(p[i+1]) || !isHex(p[i+2]) {
+			b.WriteByte(p[i])
+			continue
+		}
+		c := unhex(p[i+1])<<4 | unhex(p[i+2])
+		if unreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteString(strings.ToUpper(p[i : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+func unreserved(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	}
+	return c - 'A' + 10
+}
@@ -0,0 +1,61 @@
+package pathnorm_test
+
+import (
+	"testing"
+
+	"quotes-service/internal/lib/pathnorm"
+)
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/quotes", expected: "/quotes"},
+		{path: "/quotes/", expected: "/quotes"},
+		{path: "//quotes", expected: "/quotes"},
+		{path: "/quotes//1/", expected: "/quotes/1"},
+		{path: "/quotes/./1/../2", expected: "/quotes/2"},
+		{path: "", expected: "/"},
+		{path: "/", expected: "/"},
+		{path: "quotes", expected: "/quotes"},
+		{path: "/quotes/%31", expected: "/quotes/1"},
+		{path: "/authors/%7eann%2d%5F", expected: "/authors/~ann-_"},
+		{path: "/authors/AC%2fDC/quotes", expected: "/authors/AC%2FDC/quotes"},
+		{path: "/authors/Ren%c3%a9", expected: "/authors/Ren%C3%A9"},
+		{path: "/quotes/%2E%2E/authors", expected: "/authors"},
+		{path: "/authors/100%", expected: "/authors/100%"},
+		{path: "/authors/%zz", expected: "/authors/%zz"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			if got := pathnorm.Clean(tc.path); got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    pathnorm.Mode
+		wantErr bool
+	}{
+		{name: "redirect", want: pathnorm.ModeRedirect},
+		{name: "rewrite", want: pathnorm.ModeRewrite},
+		{name: "off", want: pathnorm.ModeOff},
+		{name: "strict", wantErr: true},
+		{name: "", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pathnorm.ParseMode(tc.name)
+			if (err != nil) != tc.wantErr || got != tc.want {
+				t.Fatalf("expected %q (error %v), got %q (%v)", tc.want, tc.wantErr, got, err)
+			}
+		})
+	}
+}
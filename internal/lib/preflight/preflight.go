@@ -0,0 +1,158 @@
+// Package preflight checks the environment the service is about to run in,
+// such as addresses and directories, before it serves anything, so that a
+// failed start reports every problem at once with what to do about each.
+package preflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"quotes-service/internal/http-server/listener"
+)
+
+// Class groups failures by what an operator has to fix. Each class exits
+// the process with its own status.
+type Class string
+
+const (
+	ClassConfig  Class = "config"
+	ClassStorage Class = "storage"
+	ClassNetwork Class = "network"
+)
+
+// ExitCode returns the process exit status for a failure of class c:
+// 2 for config, 3 for storage and 4 for network.
+func (c Class) ExitCode() int {
+	switch c {
+	case ClassConfig:
+		return 2
+	case ClassStorage:
+		return 3
+	case ClassNetwork:
+		return 4
+	default:
+		return 1
+	}
+}
+
+// Check is one thing to verify before serving.
+type Check struct {
+	Class Class
+	// Name identifies the check in the report, e.g. "listen http_server".
+	Name string
+	// Hint tells the operator what to do when the check fails.
+	Hint string
+	Run  func(ctx context.Context) error
+}
+
+// Failure is a check that failed.
+type Failure struct {
+	Class Class
+	Check string
+	Hint  string
+	Err   error
+}
+
+// Report holds the failures of a run, in the order of the checks.
+type Report struct {
+	Checks   int
+	Failures []Failure
+}
+
+// Run runs every check, whether or not an earlier one failed.
+func Run(ctx context.Context, checks []Check) Report {
+	report := Report{Checks: len(checks)}
+	for _, check := range checks {
+		if err := check.Run(ctx); err != nil {
+			report.Failures = append(report.Failures, Failure{Class: check.Class, Check: check.Name, Hint: check.Hint, Err: err})
+		}
+	}
+	return report
+}
+
+// OK reports whether every check passed.
+func (r Report) OK() bool {
+	return len(r.Failures) == 0
+}
+
+// ExitCode returns the exit status for the most basic class that failed,
+// config before storage before network, or 0 if every check passed.
+func (r Report) ExitCode() int {
+	code := 0
+	for _, f := range r.Failures {
+		if c := f.Class.ExitCode(); code == 0 || c < code {
+			code = c
+		}
+	}
+	return code
+}
+
+// LogValue renders the report as one structured log attribute.
+func (r Report) LogValue() slog.Value {
+	failures := make([]map[string]string, 0, len(r.Failures))
+	for _, f := range r.Failures {
+		failures = append(failures, map[string]string{
+			"class": string(f.Class),
+			"check": f.Check,
+			"error": f.Err.Error(),
+			"hint":  f.Hint,
+		})
+	}
+	return slog.GroupValue(
+		slog.Int("checks", r.Checks),
+		slog.Int("failed", len(r.Failures)),
+		slog.Any("failures", failures),
+	)
+}
+
+// Bindable checks that address can be listened on, in the form
+// listener.New takes, by listening on it and closing it again.
+func Bindable(address string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		ln, err := listener.New(address, 0o600)
+		if err != nil {
+			return err
+		}
+		return ln.Close()
+	}
+}
+
+// Writable checks that files can be created in dir. A dir that does not
+// exist yet passes if its nearest existing parent is writable, as the
+// service creates the missing directories itself.
+func Writable(dir string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		path, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		for {
+			info, err := os.Stat(path)
+			if err == nil {
+				if !info.IsDir() {
+					return fmt.Errorf("%s is not a directory", path)
+				}
+				break
+			}
+			if !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			parent := filepath.Dir(path)
+			if parent == path {
+				return err
+			}
+			path = parent
+		}
+
+		f, err := os.CreateTemp(path, ".preflight-*")
+		if err != nil {
+			return err
+		}
+		f.Close()
+		return os.Remove(f.Name())
+	}
+}
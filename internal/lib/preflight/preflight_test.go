@@ -0,0 +1,138 @@
+package preflight_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"quotes-service/internal/lib/preflight"
+)
+
+func TestRun(t *testing.T) {
+	pass := func(context.Context) error { return nil }
+	fail := func(context.Context) error { return errors.New("broken") }
+
+	tests := []struct {
+		name             string
+		checks           []preflight.Check
+		expectedFailures int
+		expectedCode     int
+	}{
+		{name: "no checks", expectedCode: 0},
+		{
+			name: "all pass",
+			checks: []preflight.Check{
+				{Class: preflight.ClassNetwork, Name: "a", Run: pass},
+				{Class: preflight.ClassStorage, Name: "b", Run: pass},
+			},
+			expectedCode: 0,
+		},
+		{
+			name: "network",
+			checks: []preflight.Check{
+				{Class: preflight.ClassNetwork, Name: "a", Run: fail},
+				{Class: preflight.ClassStorage, Name: "b", Run: pass},
+			},
+			expectedFailures: 1,
+			expectedCode:     4,
+		},
+		{
+			name: "storage before network",
+			checks: []preflight.Check{
+				{Class: preflight.ClassNetwork, Name: "a", Run: fail},
+				{Class: preflight.ClassStorage, Name: "b", Run: fail},
+			},
+			expectedFailures: 2,
+			expectedCode:     3,
+		},
+		{
+			name: "config first",
+			checks: []preflight.Check{
+				{Class: preflight.ClassStorage, Name: "a", Run: fail},
+				{Class: preflight.ClassConfig, Name: "b", Run: fail},
+			},
+			expectedFailures: 2,
+			expectedCode:     2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			report := preflight.Run(context.Background(), tc.checks)
+			if report.Checks != len(tc.checks) || len(report.Failures) != tc.expectedFailures {
+				t.Fatalf("expected %d failures of %d checks, got %+v", tc.expectedFailures, len(tc.checks), report)
+			}
+			if report.OK() != (tc.expectedFailures == 0) {
+				t.Fatalf("expected OK to be %v", tc.expectedFailures == 0)
+			}
+			if code := report.ExitCode(); code != tc.expectedCode {
+				t.Fatalf("expected exit code %d, got %d", tc.expectedCode, code)
+			}
+		})
+	}
+}
+
+func TestBindable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	tests := []struct {
+		name        string
+		address     string
+		expectedErr bool
+	}{
+		{name: "free", address: "127.0.0.1:0"},
+		{name: "in use", address: ln.Addr().String(), expectedErr: true},
+		{name: "unix socket", address: "unix://" + filepath.Join(t.TempDir(), "quotes.sock")},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := preflight.Bindable(tc.address)(context.Background())
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestWritable(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		dir         string
+		expectedErr bool
+	}{
+		{name: "existing", dir: dir},
+		{name: "missing", dir: filepath.Join(dir, "a", "b")},
+		{name: "file", dir: file, expectedErr: true},
+		{name: "under a file", dir: filepath.Join(file, "sub"), expectedErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := preflight.Writable(tc.dir)(context.Background())
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("expected no files left behind, got %d entries", len(entries))
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// Package publicid generates and recognizes the public identifiers quotes
+// can carry next to their sequential IDs: random UUIDs (version 4) or
+// ULIDs, which also sort by creation time. Neither reveals how many quotes
+// exist, and IDs from two instances do not collide.
+package publicid
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	// FormatNone leaves quotes without public IDs.
+	FormatNone Format = ""
+	FormatUUID Format = "uuid"
+	FormatULID Format = "ulid"
+)
+
+// RoutePattern matches a UUID or a ULID in a gorilla/mux route variable.
+const RoutePattern = uuidPattern + "|" + ulidPattern
+
+const (
+	uuidPattern = `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`
+	// ulidPattern allows Crockford's base32 alphabet in either case. The
+	// first character is at most 7 since a ULID is 128 bits in 26
+	// characters of 5 bits.
+	ulidPattern = `[0-7][0-9A-HJKMNP-TV-Za-hjkmnp-tv-z]{25}`
+
+	crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+var (
+	uuidRe = regexp.MustCompile(`^(?:` + uuidPattern + `)$`)
+	ulidRe = regexp.MustCompile(`^(?:` + ulidPattern + `)$`)
+)
+
+// ParseFormat validates a format name from configuration.
+func ParseFormat(s string) (Format, error) {
+	switch format := Format(s); format {
+	case FormatNone, FormatUUID, FormatULID:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown public ID format %q", s)
+	}
+}
+
+// New returns a fresh ID in format f, or "" for FormatNone.
+func (f Format) New() string {
+	switch f {
+	case FormatUUID:
+		return NewUUID()
+	case FormatULID:
+		return NewULID(time.Now())
+	default:
+		return ""
+	}
+}
+
+// NewUUID returns a random version 4 UUID in lower case.
+func NewUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+	return string(buf[:])
+}
+
+// NewULID returns a ULID for t: 48 bits of Unix milliseconds followed by 80
+// random bits, in upper-case Crockford base32.
+func NewULID(t time.Time) string {
+	var b [16]byte
+	ms := uint64(t.UnixMilli())
+	b[0], b[1], b[2], b[3], b[4], b[5] = byte(ms>>40), byte(ms>>32), byte(ms>>24), byte(ms>>16), byte(ms>>8), byte(ms)
+	rand.Read(b[6:])
+
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	var out [26]byte
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// Valid reports whether s is a UUID or a ULID.
+func Valid(s string) bool {
+	return uuidRe.MatchString(s) || ulidRe.MatchString(s)
+}
+
+// Normalize returns the canonical spelling of a valid ID: UUIDs in lower
+// case and ULIDs in upper case, so lookups do not depend on how a client
+// wrote it.
+func Normalize(s string) string {
+	if uuidRe.MatchString(s) {
+		return strings.ToLower(s)
+	}
+	return strings.ToUpper(s)
+}
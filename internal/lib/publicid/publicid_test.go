@@ -0,0 +1,90 @@
+package publicid_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"quotes-service/internal/lib/publicid"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		format  publicid.Format
+		pattern string
+	}{
+		{format: publicid.FormatUUID, pattern: `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{format: publicid.FormatULID, pattern: `^[0-7][0-9A-HJKMNP-TV-Z]{25}$`},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			re := regexp.MustCompile(tt.pattern)
+			seen := make(map[string]bool)
+			for range 100 {
+				id := tt.format.New()
+				if !re.MatchString(id) || !publicid.Valid(id) {
+					t.Fatalf("unexpected id %q", id)
+				}
+				if seen[id] {
+					t.Fatalf("duplicate id %q", id)
+				}
+				seen[id] = true
+			}
+		})
+	}
+	if id := publicid.FormatNone.New(); id != "" {
+		t.Fatalf("expected no id for FormatNone, got %q", id)
+	}
+}
+
+func TestNewULIDSortsByTime(t *testing.T) {
+	t0 := time.Date(2024, time.March, 11, 9, 0, 0, 0, time.UTC)
+	earlier := publicid.NewULID(t0)
+	later := publicid.NewULID(t0.Add(time.Millisecond))
+	if earlier[:10] >= later[:10] {
+		t.Fatalf("expected %q to sort before %q", earlier, later)
+	}
+	// The timestamp part of the ULID spec example, 1469918176385 ms.
+	if got := publicid.NewULID(time.UnixMilli(1469918176385))[:10]; got != "01ARYZ6S41" {
+		t.Fatalf("unexpected timestamp encoding %q", got)
+	}
+}
+
+func TestValidAndNormalize(t *testing.T) {
+	tests := []struct {
+		id         string
+		valid      bool
+		normalized string
+	}{
+		{id: "3F2504E0-4F89-41D3-9A0C-0305E82C3301", valid: true, normalized: "3f2504e0-4f89-41d3-9a0c-0305e82c3301"},
+		{id: "01arz3ndektsv4rrffq69g5fav", valid: true, normalized: "01ARZ3NDEKTSV4RRFFQ69G5FAV"},
+		{id: "81ARZ3NDEKTSV4RRFFQ69G5FAV"},
+		{id: "01ARZ3NDEKTSV4RRFFQ69G5FAU"},
+		{id: "42"},
+		{id: strings.Repeat("0", 36)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			if got := publicid.Valid(tt.id); got != tt.valid {
+				t.Fatalf("Valid(%q) = %v, want %v", tt.id, got, tt.valid)
+			}
+			if tt.valid {
+				if got := publicid.Normalize(tt.id); got != tt.normalized {
+					t.Fatalf("Normalize(%q) = %q, want %q", tt.id, got, tt.normalized)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, s := range []string{"", "uuid", "ulid"} {
+		if _, err := publicid.ParseFormat(s); err != nil {
+			t.Errorf("ParseFormat(%q): %v", s, err)
+		}
+	}
+	if _, err := publicid.ParseFormat("snowflake"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
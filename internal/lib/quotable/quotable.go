@@ -0,0 +1,68 @@
+// Package quotable maps quotes to and from the record format of the
+// quotable API and the public datasets built on it:
+// {"_id": ..., "content": ..., "author": ..., "tags": [...], "length": ...}.
+package quotable
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"quotes-service/internal/models"
+)
+
+// Record is one quote in the quotable format. Length is the number of
+// characters in Content.
+type Record struct {
+	ID           string   `json:"_id,omitempty"`
+	Content      string   `json:"content"`
+	Author       string   `json:"author"`
+	AuthorSlug   string   `json:"authorSlug,omitempty"`
+	Tags         []string `json:"tags"`
+	Length       int      `json:"length"`
+	DateAdded    string   `json:"dateAdded,omitempty"`
+	DateModified string   `json:"dateModified,omitempty"`
+}
+
+// FromQuote converts a quote. The record's _id is the quote's public ID
+// when it has one, so it stays stable across instances, and its sequential
+// ID otherwise.
+func FromQuote(q models.Quote) Record {
+	r := Record{
+		ID:      strconv.FormatInt(q.ID, 10),
+		Content: q.Text,
+		Author:  q.Author,
+		Tags:    q.Tags,
+		Length:  utf8.RuneCountInString(q.Text),
+	}
+	if q.PublicID != "" {
+		r.ID = q.PublicID
+	}
+	if r.Tags == nil {
+		r.Tags = []string{}
+	}
+	if !q.CreatedAt.IsZero() {
+		r.DateAdded = q.CreatedAt.Format(time.DateOnly)
+	}
+	if !q.UpdatedAt.IsZero() {
+		r.DateModified = q.UpdatedAt.Format(time.DateOnly)
+	}
+	return r
+}
+
+// Quote converts a record to a quote to be added. Identifiers, dates and
+// the length are left out since the store assigns or derives them.
+func (r Record) Quote() models.Quote {
+	var tags []string
+	for _, tag := range r.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return models.Quote{
+		Text:   strings.TrimSpace(r.Content),
+		Author: strings.TrimSpace(r.Author),
+		Tags:   tags,
+	}
+}
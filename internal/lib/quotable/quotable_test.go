@@ -0,0 +1,45 @@
+package quotable_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"quotes-service/internal/lib/quotable"
+	"quotes-service/internal/models"
+)
+
+func TestFromQuote(t *testing.T) {
+	created := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		quote models.Quote
+		want  quotable.Record
+	}{
+		{
+			name:  "sequential id",
+			quote: models.Quote{ID: 7, Text: "Жизнь коротка", Author: "Гиппократ", CreatedAt: created, UpdatedAt: created.AddDate(0, 1, 0)},
+			want:  quotable.Record{ID: "7", Content: "Жизнь коротка", Author: "Гиппократ", Tags: []string{}, Length: 13, DateAdded: "2024-03-10", DateModified: "2024-04-10"},
+		},
+		{
+			name:  "public id and tags",
+			quote: models.Quote{ID: 7, PublicID: "01ARZ3NDEKTSV4RRFFQ69G5FAV", Text: "Done", Author: "Team", Tags: []string{"work"}},
+			want:  quotable.Record{ID: "01ARZ3NDEKTSV4RRFFQ69G5FAV", Content: "Done", Author: "Team", Tags: []string{"work"}, Length: 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quotable.FromQuote(tt.quote); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordQuote(t *testing.T) {
+	record := quotable.Record{ID: "x", Content: "  Done ", Author: " Team", Tags: []string{"work", " ", "life "}, Length: 99, DateAdded: "2024-03-10"}
+	want := models.Quote{Text: "Done", Author: "Team", Tags: []string{"work", "life"}}
+	if got := record.Quote(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
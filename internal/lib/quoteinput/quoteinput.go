@@ -0,0 +1,340 @@
+// Package quoteinput checks quotes sent by clients and reads bulk imports
+// of them, so the API and the background import jobs accept exactly the
+// same input.
+package quoteinput
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"quotes-service/internal/lib/contentfilter"
+	"quotes-service/internal/lib/fingerprint"
+	"quotes-service/internal/lib/language"
+	"quotes-service/internal/lib/language/detect"
+	"quotes-service/internal/lib/moderation"
+	"quotes-service/internal/lib/normalize"
+	"quotes-service/internal/lib/quotable"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// Formats of a bulk import, read as JSON Lines, one quote per line.
+const (
+	FormatNative   = "native"
+	FormatQuotable = "quotable"
+)
+
+const (
+	// MaxLineBytes bounds one line of an import.
+	MaxLineBytes = 1 << 20
+	// MaxErrors bounds the errors listed in a report; Skipped still counts
+	// every rejected line.
+	MaxErrors = 100
+)
+
+// DefaultMaxTagChars is the longest tag DecodeLine accepts until
+// SetMaxTagChars changes it.
+const DefaultMaxTagChars = 64
+
+var maxTagChars atomic.Int64
+
+func init() {
+	maxTagChars.Store(DefaultMaxTagChars)
+}
+
+// SetMaxTagChars sets the longest tag, in characters, that DecodeLine
+// accepts. Zero accepts tags of any length.
+func SetMaxTagChars(n int) {
+	maxTagChars.Store(int64(max(n, 0)))
+}
+
+// DefaultMaxAuthorChars is the longest author name Validate accepts until
+// SetMaxAuthorChars changes it.
+const DefaultMaxAuthorChars = 256
+
+var maxAuthorChars atomic.Int64
+
+func init() {
+	maxAuthorChars.Store(DefaultMaxAuthorChars)
+}
+
+// SetMaxAuthorChars sets the longest author name, in characters, that
+// AuthorProblem accepts. Zero accepts names of any length.
+func SetMaxAuthorChars(n int) {
+	maxAuthorChars.Store(int64(max(n, 0)))
+}
+
+var sanitizeAuthors atomic.Bool
+
+// SetSanitizeImportedAuthors makes imports mend the author names
+// AuthorProblem would reject, where they can be mended, rather than skip
+// their quotes.
+func SetSanitizeImportedAuthors(on bool) {
+	sanitizeAuthors.Store(on)
+}
+
+// AuthorProblem returns what is wrong with an author name given in field,
+// such as "author" or "into", as normalize.CheckAuthor finds it, or "" if
+// nothing is. Every path that stores a new author name checks it, so that
+// no name with a line break or a direction override gets in.
+func AuthorProblem(field, name string) string {
+	if err := normalize.CheckAuthor(name, int(maxAuthorChars.Load())); err != nil {
+		return field + " " + err.Error()
+	}
+	return ""
+}
+
+// ImportAuthor returns an imported author name in the form it is checked
+// in: mended by normalize.SanitizeAuthor when imports sanitize authors, as
+// it came otherwise.
+func ImportAuthor(name string) string {
+	if !sanitizeAuthors.Load() {
+		return name
+	}
+	return normalize.SanitizeAuthor(name, int(maxAuthorChars.Load()))
+}
+
+var typography atomic.Value // normalize.Typography
+
+// SetTypography sets how Text rewrites the quote marks, dashes and
+// non-breaking spaces of quotes created, updated or imported.
+func SetTypography(t normalize.Typography) {
+	typography.Store(t)
+}
+
+// Text returns quote text in the form it is stored and exported in.
+// Duplicates are found by fingerprint, which ignores typography, so a
+// quote is a duplicate whichever form it was stored in.
+func Text(text string) string {
+	t, _ := typography.Load().(normalize.Typography)
+	return t.Apply(text)
+}
+
+var (
+	nativeFields   = jsonFields(models.Quote{})
+	quotableFields = jsonFields(quotable.Record{})
+)
+
+// jsonFields returns the JSON names of the fields of the struct v.
+func jsonFields(v any) map[string]struct{} {
+	t := reflect.TypeOf(v)
+	fields := make(map[string]struct{}, t.NumField())
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		if name != "-" {
+			fields[name] = struct{}{}
+		}
+	}
+	return fields
+}
+
+// Validate checks the fields shared by create and update requests and
+// returns the normalized language. Nil fields are not checked; empty lang
+// and source_url are allowed.
+func Validate(text, author *string, weight *int, lang, sourceURL *string) (string, []string) {
+	var validationErrors []string
+	if text != nil && strings.TrimSpace(*text) == "" {
+		validationErrors = append(validationErrors, "text cannot be empty")
+	}
+	if author != nil && strings.TrimSpace(*author) == "" {
+		validationErrors = append(validationErrors, "author cannot be empty")
+	} else if author != nil {
+		if problem := AuthorProblem("author", *author); problem != "" {
+			validationErrors = append(validationErrors, problem)
+		}
+	}
+	if weight != nil && (*weight <= 0 || *weight > storage.MaxWeight) {
+		validationErrors = append(validationErrors, fmt.Sprintf("weight must be between 1 and %d", storage.MaxWeight))
+	}
+	if sourceURL != nil && *sourceURL != "" && !ValidSourceURL(*sourceURL) {
+		validationErrors = append(validationErrors, "source_url must be an absolute http(s) URL")
+	}
+	var normalized string
+	if lang != nil && *lang != "" {
+		var err error
+		normalized, err = language.Normalize(*lang)
+		if err != nil {
+			validationErrors = append(validationErrors, "lang must be a known BCP-47 language code")
+		}
+	}
+	return normalized, validationErrors
+}
+
+// ValidSourceURL accepts only absolute http(s) URLs with a host.
+func ValidSourceURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Line is a quote waiting to be stored and the input line it came from.
+type Line struct {
+	Number int
+	Quote  models.Quote
+}
+
+// Skip counts a rejected line in report, listing the reason among the
+// first MaxErrors.
+func Skip(report *models.ImportReport, line int, reason string) {
+	report.Skipped++
+	if len(report.Errors) < MaxErrors {
+		report.Errors = append(report.Errors, models.ImportError{Line: line, Error: reason})
+	}
+}
+
+// Read reads JSON Lines in format from r and returns the quotes to store:
+// those that decode, pass the content filter and are not already in index,
+// which they are added to. Every non-empty line is counted in report as a
+// duplicate, skipped, held, or left for the caller to count once it is
+// stored. Held quotes are queued for moderation unless report is a dry
+// run. Reading stops at the
+// first line longer than MaxLineBytes or when r fails, which is reported
+// as a skipped line.
+func Read(r io.Reader, format string, index fingerprint.Index, report *models.ImportReport) []Line {
+	var pending []Line
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), MaxLineBytes)
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		report.Lines++
+
+		quote, err := DecodeLine(raw, format, report)
+		if err != nil {
+			Skip(report, line, err.Error())
+			continue
+		}
+		if !index.Add(quote.Text, quote.Author) {
+			report.Duplicates++
+			continue
+		}
+		switch action, rule := Screen(quote.Text, SourceImport); action {
+		case contentfilter.ActionReject:
+			Skip(report, line, "content rejected")
+			continue
+		case contentfilter.ActionHold:
+			if !report.DryRun {
+				if _, err := Hold(moderation.Entry{HeldQuote: models.HeldQuote{Source: SourceImport, Quote: quote, Rule: rule}}); err != nil {
+					Skip(report, line, err.Error())
+					continue
+				}
+			}
+			report.Held++
+			continue
+		}
+		pending = append(pending, Line{Number: line, Quote: quote})
+	}
+	if err := scanner.Err(); err != nil {
+		reason := err.Error()
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			reason = fmt.Sprintf("body exceeds %d bytes, import stopped", tooLarge.Limit)
+		case errors.Is(err, bufio.ErrTooLong):
+			reason = fmt.Sprintf("line exceeds %d bytes, import stopped", MaxLineBytes)
+		}
+		Skip(report, line+1, reason)
+	}
+	return pending
+}
+
+// DecodeLine turns one input line into a quote to add, counting the
+// fields the format does not know in report.
+func DecodeLine(raw []byte, format string, report *models.ImportReport) (models.Quote, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return models.Quote{}, errors.New("not a JSON object")
+	}
+	known := nativeFields
+	if format == FormatQuotable {
+		known = quotableFields
+	}
+	for name := range fields {
+		if _, ok := known[name]; !ok {
+			if report.UnknownFields == nil {
+				report.UnknownFields = make(map[string]int)
+			}
+			report.UnknownFields[name]++
+		}
+	}
+
+	var quote models.Quote
+	if format == FormatQuotable {
+		var record quotable.Record
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return models.Quote{}, fmt.Errorf("invalid record: %w", err)
+		}
+		quote = record.Quote()
+		quote.Author = ImportAuthor(quote.Author)
+		var problems []string
+		if quote.Text == "" {
+			problems = append(problems, "content cannot be empty")
+		}
+		if quote.Author == "" {
+			problems = append(problems, "author cannot be empty")
+		} else if problem := AuthorProblem("author", quote.Author); problem != "" {
+			problems = append(problems, problem)
+		}
+		if len(problems) > 0 {
+			return models.Quote{}, errors.New(strings.Join(problems, "; "))
+		}
+	} else {
+		var stored models.Quote
+		if err := json.Unmarshal(raw, &stored); err != nil {
+			return models.Quote{}, fmt.Errorf("invalid record: %w", err)
+		}
+		stored.Author = ImportAuthor(stored.Author)
+		var weight *int
+		if stored.Weight != 0 {
+			weight = &stored.Weight
+		}
+		lang, problems := Validate(&stored.Text, &stored.Author, weight, &stored.Lang, &stored.SourceURL)
+		if len(problems) > 0 {
+			return models.Quote{}, errors.New(strings.Join(problems, "; "))
+		}
+		quote = models.Quote{
+			Text:         strings.TrimSpace(stored.Text),
+			Author:       strings.TrimSpace(stored.Author),
+			Weight:       stored.Weight,
+			Lang:         lang,
+			LangDetected: lang != "" && stored.LangDetected,
+			Source:       stored.Source,
+			SourceURL:    stored.SourceURL,
+			Tags:         stored.Tags,
+		}
+	}
+
+	quote.Text = Text(quote.Text)
+
+	if limit := int(maxTagChars.Load()); limit > 0 {
+		for _, tag := range quote.Tags {
+			if utf8.RuneCountInString(tag) > limit {
+				return models.Quote{}, fmt.Errorf("tag longer than %d characters", limit)
+			}
+		}
+	}
+
+	if quote.Lang == "" {
+		quote.Lang, quote.LangDetected = detect.Detect(quote.Text), true
+	}
+	return quote, nil
+}
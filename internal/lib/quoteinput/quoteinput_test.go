@@ -0,0 +1,156 @@
+package quoteinput_test
+
+import (
+	"strings"
+	"testing"
+
+	"quotes-service/internal/lib/fingerprint"
+	"quotes-service/internal/lib/normalize"
+	"quotes-service/internal/lib/quoteinput"
+	"quotes-service/internal/models"
+)
+
+func TestDecodeLineTagLimit(t *testing.T) {
+	quoteinput.SetMaxTagChars(4)
+	defer quoteinput.SetMaxTagChars(quoteinput.DefaultMaxTagChars)
+
+	tests := []struct {
+		name        string
+		format      string
+		tag         string
+		expectedErr string
+	}{
+		{name: "at the limit", format: quoteinput.FormatNative, tag: "мудр"},
+		{name: "one over the limit", format: quoteinput.FormatNative, tag: "мудро", expectedErr: "tag longer than 4 characters"},
+		{name: "quotable one over the limit", format: quoteinput.FormatQuotable, tag: "wisdom", expectedErr: "tag longer than 4 characters"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			line := `{"text":"A quote","author":"Someone","lang":"en","tags":["` + tc.tag + `"]}`
+			if tc.format == quoteinput.FormatQuotable {
+				line = `{"content":"A quote","author":"Someone","tags":["` + tc.tag + `"]}`
+			}
+			quote, err := quoteinput.DecodeLine([]byte(line), tc.format, &models.ImportReport{})
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("expected error %q, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(quote.Tags) != 1 || quote.Tags[0] != tc.tag {
+				t.Fatalf("unexpected tags %v", quote.Tags)
+			}
+		})
+	}
+}
+
+func TestReadTypography(t *testing.T) {
+	defer quoteinput.SetTypography(normalize.TypographyOff)
+
+	// The second line is the first as a word processor would paste it.
+	body := `{"text":"Don't panic - it's fine","author":"Someone","lang":"en"}` + "\n" +
+		`{"text":"Don’t panic — it’s\u00a0fine","author":"Someone","lang":"en"}` + "\n"
+
+	tests := []struct {
+		name       string
+		typography normalize.Typography
+		expected   string
+	}{
+		{name: "off", typography: normalize.TypographyOff, expected: "Don't panic - it's fine"},
+		{name: "plain", typography: normalize.TypographyPlain, expected: "Don't panic - it's fine"},
+		{name: "prettify", typography: normalize.TypographyPrettify, expected: "Don’t panic — it’s fine"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			quoteinput.SetTypography(tc.typography)
+			report := models.ImportReport{}
+			pending := quoteinput.Read(strings.NewReader(body), quoteinput.FormatNative, fingerprint.Index{}, &report)
+			if len(pending) != 1 || report.Duplicates != 1 {
+				t.Fatalf("expected 1 quote and 1 duplicate, got %d and %+v", len(pending), report)
+			}
+			if got := pending[0].Quote.Text; got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidateAuthor(t *testing.T) {
+	quoteinput.SetMaxAuthorChars(10)
+	defer quoteinput.SetMaxAuthorChars(quoteinput.DefaultMaxAuthorChars)
+
+	tests := []struct {
+		name        string
+		author      string
+		expectedErr string
+	}{
+		{name: "valid", author: "Mark Twain"},
+		{name: "newline", author: "Mark\nTwain", expectedErr: "author must not contain control characters"},
+		{name: "right-to-left override", author: "Mark \u202eniawT", expectedErr: "author must not contain control characters"},
+		{name: "punctuation only", author: "?!", expectedErr: "author must contain a letter or a digit"},
+		{name: "too long", author: "Samuel Clemens", expectedErr: "author must be at most 10 characters"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			text, lang := "A quote", "en"
+			_, problems := quoteinput.Validate(&text, &tc.author, nil, &lang, nil)
+			if tc.expectedErr == "" {
+				if len(problems) > 0 {
+					t.Fatalf("unexpected problems %v", problems)
+				}
+				return
+			}
+			if len(problems) != 1 || !strings.HasPrefix(problems[0], tc.expectedErr) {
+				t.Fatalf("expected problem %q, got %v", tc.expectedErr, problems)
+			}
+		})
+	}
+}
+
+func TestDecodeLineAuthor(t *testing.T) {
+	defer quoteinput.SetSanitizeImportedAuthors(false)
+
+	tests := []struct {
+		name        string
+		format      string
+		author      string
+		sanitize    bool
+		expected    string
+		expectedErr string
+	}{
+		{name: "newline rejected", format: quoteinput.FormatNative, author: `Mark\nTwain`, expectedErr: "author must not contain control characters"},
+		{name: "quotable override rejected", format: quoteinput.FormatQuotable, author: `Mark \u202eniawT`, expectedErr: "author must not contain control characters"},
+		{name: "newline sanitized", format: quoteinput.FormatNative, author: `Mark\nTwain`, sanitize: true, expected: "Mark Twain"},
+		{name: "quotable override sanitized", format: quoteinput.FormatQuotable, author: `Mark \u202eniawT`, sanitize: true, expected: "Mark niawT"},
+		{name: "punctuation rejected when sanitizing", format: quoteinput.FormatNative, author: "?!", sanitize: true, expectedErr: "author must contain a letter or a digit"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			quoteinput.SetSanitizeImportedAuthors(tc.sanitize)
+			line := `{"text":"A quote","author":"` + tc.author + `","lang":"en"}`
+			if tc.format == quoteinput.FormatQuotable {
+				line = `{"content":"A quote","author":"` + tc.author + `"}`
+			}
+			quote, err := quoteinput.DecodeLine([]byte(line), tc.format, &models.ImportReport{})
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("expected error %q, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if quote.Author != tc.expected {
+				t.Fatalf("expected author %q, got %q", tc.expected, quote.Author)
+			}
+		})
+	}
+}
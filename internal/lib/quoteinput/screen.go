@@ -0,0 +1,59 @@
+package quoteinput
+
+import (
+	"sync/atomic"
+
+	"quotes-service/internal/lib/contentfilter"
+	"quotes-service/internal/lib/moderation"
+	"quotes-service/internal/models"
+)
+
+// Sources of a quote, as counted by the content filter.
+const (
+	SourceCreate = "create"
+	SourceUpdate = "update"
+	SourceImport = "import"
+)
+
+type screen struct {
+	filter *contentfilter.Filter
+	held   *moderation.Queue
+}
+
+var contentScreen atomic.Pointer[screen]
+
+// SetContentFilter screens the text of every quote created, updated or
+// imported with filter. When its action is hold, matching quotes go to
+// held. A nil filter turns screening off.
+func SetContentFilter(filter *contentfilter.Filter, held *moderation.Queue) {
+	if filter == nil {
+		contentScreen.Store(nil)
+		return
+	}
+	contentScreen.Store(&screen{filter: filter, held: held})
+}
+
+// Screen checks text, from source, against the content filter. It returns
+// an empty action when the quote may be stored, and otherwise the action
+// to take and the denylist line that matched, which is safe to log unlike
+// the term itself.
+func Screen(text, source string) (action string, rule int) {
+	s := contentScreen.Load()
+	if s == nil {
+		return "", 0
+	}
+	rule, hit := s.filter.Check(text, source)
+	if !hit {
+		return "", 0
+	}
+	return s.filter.Action(), rule
+}
+
+// Hold queues entry for moderation.
+func Hold(entry moderation.Entry) (models.HeldQuote, error) {
+	s := contentScreen.Load()
+	if s == nil || s.held == nil {
+		return models.HeldQuote{}, moderation.ErrFull
+	}
+	return s.held.Hold(entry)
+}
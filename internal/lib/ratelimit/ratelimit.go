@@ -0,0 +1,174 @@
+package ratelimit
+
+import (
+	"container/list"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter keeps a token bucket per key. Each bucket holds up to burst tokens
+// and refills at rate tokens per second, unless AllowLimit gives it a limit
+// of its own. The number of tracked keys is
+// capped; the least recently seen key is evicted first, which at worst hands
+// an idle client a fresh, full bucket.
+type Limiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   int
+	maxKeys int
+	now     func() time.Time
+	keys    map[string]*list.Element
+	order   *list.List
+}
+
+// Limit is a bucket's refill rate in tokens per second and its capacity.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+type bucket struct {
+	key     string
+	tokens  float64
+	updated time.Time
+}
+
+// Result describes a bucket right after a request was counted against it.
+type Result struct {
+	Allowed bool
+	// Limit is the bucket capacity.
+	Limit int
+	// Remaining is the number of whole tokens left.
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request would be allowed. It
+	// is zero when Allowed is true.
+	RetryAfter time.Duration
+}
+
+type Option func(*Limiter)
+
+// WithClock overrides the time source, mainly for tests.
+func WithClock(now func() time.Time) Option {
+	return func(l *Limiter) {
+		l.now = now
+	}
+}
+
+func New(rate float64, burst int, maxKeys int, opts ...Option) *Limiter {
+	l := &Limiter{
+		rate:    rate,
+		burst:   burst,
+		maxKeys: maxKeys,
+		now:     time.Now,
+		keys:    make(map[string]*list.Element),
+		order:   list.New(),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Allow takes a token from the bucket of key if one is available. The
+// returned state is read under the same lock as the consumption, so
+// concurrent callers never observe each other's intermediate state.
+func (l *Limiter) Allow(key string) Result {
+	return l.AllowLimit(key, l.Limit())
+}
+
+// AllowLimit is Allow with the bucket of key held to limit instead of the
+// limiter's own. A bucket whose limit is lowered keeps no more than the new
+// burst.
+func (l *Limiter) AllowLimit(key string, limit Limit) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b := l.bucket(key, limit.Burst, now)
+
+	b.tokens = min(float64(limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*limit.Rate)
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int(math.Floor(b.tokens)),
+		Reset:     duration(float64(limit.Burst)-b.tokens, limit.Rate),
+	}
+	if !allowed {
+		res.RetryAfter = duration(1-b.tokens, limit.Rate)
+	}
+	return res
+}
+
+// Peek returns the state of the bucket of key held to limit, without
+// taking a token or refreshing the key, so looking never uses up a request.
+// Allowed reports whether the next request would be. A key not tracked has
+// a full bucket.
+func (l *Limiter) Peek(key string, limit Limit) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	tokens := float64(limit.Burst)
+	if el, ok := l.keys[key]; ok {
+		b := el.Value.(*bucket)
+		tokens = min(tokens, b.tokens+l.now().Sub(b.updated).Seconds()*limit.Rate)
+	}
+
+	res := Result{
+		Allowed:   tokens >= 1,
+		Limit:     limit.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     duration(float64(limit.Burst)-tokens, limit.Rate),
+	}
+	if !res.Allowed {
+		res.RetryAfter = duration(1-tokens, limit.Rate)
+	}
+	return res
+}
+
+// Limit returns the limiter's own limit.
+func (l *Limiter) Limit() Limit {
+	return Limit{Rate: l.rate, Burst: l.burst}
+}
+
+// Len reports how many keys are currently tracked.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
+
+func (l *Limiter) bucket(key string, burst int, now time.Time) *bucket {
+	if el, ok := l.keys[key]; ok {
+		l.order.MoveToFront(el)
+		return el.Value.(*bucket)
+	}
+
+	if l.maxKeys > 0 && l.order.Len() >= l.maxKeys {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.keys, oldest.Value.(*bucket).key)
+	}
+
+	b := &bucket{key: key, tokens: float64(burst), updated: now}
+	l.keys[key] = l.order.PushFront(b)
+	return b
+}
+
+// duration converts a token deficit into the time needed to refill it at
+// rate.
+func duration(tokens, rate float64) time.Duration {
+	if tokens <= 0 || rate <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / rate * float64(time.Second))
+}
@@ -0,0 +1,146 @@
+package ratelimit_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"quotes-service/internal/lib/ratelimit"
+)
+
+func TestAllowBurst(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	l := ratelimit.New(2, 5, 100, ratelimit.WithClock(func() time.Time { return now }))
+
+	previous := 5
+	for i := 0; i < 5; i++ {
+		res := l.Allow("client")
+		if !res.Allowed {
+			t.Fatalf("request %d: expected to be allowed", i)
+		}
+		if res.Limit != 5 {
+			t.Fatalf("expected limit 5, got %d", res.Limit)
+		}
+		if res.Remaining != previous-1 {
+			t.Fatalf("request %d: expected remaining %d, got %d", i, previous-1, res.Remaining)
+		}
+		previous = res.Remaining
+	}
+
+	res := l.Allow("client")
+	if res.Allowed || res.Remaining != 0 {
+		t.Fatalf("expected request over the burst to be rejected, got %+v", res)
+	}
+	if res.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("expected retry after 500ms, got %v", res.RetryAfter)
+	}
+	if res.Reset != 2500*time.Millisecond {
+		t.Fatalf("expected reset in 2.5s, got %v", res.Reset)
+	}
+
+	now = now.Add(time.Second)
+	res = l.Allow("client")
+	if !res.Allowed || res.Remaining != 1 {
+		t.Fatalf("expected refill of two tokens, got %+v", res)
+	}
+
+	if res := l.Allow("other"); !res.Allowed || res.Remaining != 4 {
+		t.Fatalf("expected a separate bucket per key, got %+v", res)
+	}
+}
+
+func TestAllowEviction(t *testing.T) {
+	l := ratelimit.New(1, 1, 2)
+
+	l.Allow("a")
+	l.Allow("b")
+	l.Allow("c")
+
+	if l.Len() != 2 {
+		t.Fatalf("expected 2 tracked keys, got %d", l.Len())
+	}
+	if res := l.Allow("a"); !res.Allowed {
+		t.Fatalf("expected evicted key to get a fresh bucket, got %+v", res)
+	}
+}
+
+func TestAllowConcurrent(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	l := ratelimit.New(1, 100, 10, ratelimit.WithClock(func() time.Time { return now }))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	seen := make(map[int]bool)
+	for i := 0; i < 150; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := l.Allow("client")
+			if res.Allowed {
+				mu.Lock()
+				seen[res.Remaining] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != 100 {
+		t.Fatalf("expected 100 distinct remaining values, got %d", len(seen))
+	}
+}
+
+func TestAllowLimit(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	l := ratelimit.New(1, 2, 100, ratelimit.WithClock(func() time.Time { return now }))
+	fast := ratelimit.Limit{Rate: 10, Burst: 20}
+
+	for i := 0; i < 20; i++ {
+		if res := l.AllowLimit("importer", fast); !res.Allowed || res.Limit != 20 {
+			t.Fatalf("request %d: expected the importer's own limit, got %+v", i, res)
+		}
+	}
+	res := l.AllowLimit("importer", fast)
+	if res.Allowed || res.RetryAfter != 100*time.Millisecond || res.Reset != 2*time.Second {
+		t.Fatalf("expected the importer to refill at its own rate, got %+v", res)
+	}
+	if res := l.Allow("other"); !res.Allowed || res.Limit != 2 || res.Remaining != 1 {
+		t.Fatalf("expected other keys to keep the limiter's limit, got %+v", res)
+	}
+
+	// Lowering the limit caps the tokens already in the bucket.
+	now = now.Add(5 * time.Second)
+	if res := l.AllowLimit("importer", ratelimit.Limit{Rate: 1, Burst: 3}); !res.Allowed || res.Remaining != 2 {
+		t.Fatalf("expected the bucket capped at the new burst, got %+v", res)
+	}
+}
+
+func TestPeek(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	l := ratelimit.New(1, 3, 100, ratelimit.WithClock(func() time.Time { return now }))
+
+	if res := l.Peek("alice", l.Limit()); !res.Allowed || res.Remaining != 3 || res.Reset != 0 {
+		t.Fatalf("expected a full bucket for an unseen key, got %+v", res)
+	}
+	if l.Len() != 0 {
+		t.Fatalf("expected peeking not to track the key, got %d keys", l.Len())
+	}
+
+	for i := 0; i < 3; i++ {
+		l.Allow("alice")
+	}
+	for i := 0; i < 3; i++ {
+		res := l.Peek("alice", l.Limit())
+		if res.Allowed || res.Remaining != 0 || res.RetryAfter != time.Second || res.Reset != 3*time.Second {
+			t.Fatalf("peek %d: expected an empty bucket left as it was, got %+v", i, res)
+		}
+	}
+
+	now = now.Add(1500 * time.Millisecond)
+	if res := l.Peek("alice", l.Limit()); !res.Allowed || res.Remaining != 1 || res.Reset != 1500*time.Millisecond {
+		t.Fatalf("expected the refill to show, got %+v", res)
+	}
+	if res := l.Allow("alice"); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("expected the peeks to have taken nothing, got %+v", res)
+	}
+}
@@ -0,0 +1,57 @@
+// Package recentfailures keeps the last requests that failed with a server
+// error, for a support bundle to show what went wrong lately.
+package recentfailures
+
+import (
+	"net/http"
+	"sync"
+
+	"quotes-service/internal/lib/slowest"
+	"quotes-service/internal/models"
+)
+
+// Ring holds the last size requests answered with a 5xx. It is safe for
+// concurrent use.
+type Ring struct {
+	mu       sync.Mutex
+	requests []slowest.Request
+	next     int
+	full     bool
+}
+
+// New returns a Ring keeping the last size failed requests.
+func New(size int) *Ring {
+	return &Ring{requests: make([]slowest.Request, size)}
+}
+
+// Record keeps req if it failed with a server error.
+func (r *Ring) Record(req slowest.Request) {
+	if req.Status < http.StatusInternalServerError || len(r.requests) == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests[r.next] = req
+	r.next = (r.next + 1) % len(r.requests)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Report returns the failed requests kept, newest first.
+func (r *Ring) Report() []models.SlowRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := r.next
+	if r.full {
+		n = len(r.requests)
+	}
+	report := make([]models.SlowRequest, 0, n)
+	for i := range n {
+		j := (r.next - 1 - i + len(r.requests)) % len(r.requests)
+		report = append(report, r.requests[j].Summary())
+	}
+	return report
+}
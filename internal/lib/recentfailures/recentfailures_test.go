@@ -0,0 +1,47 @@
+package recentfailures_test
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"quotes-service/internal/lib/recentfailures"
+	"quotes-service/internal/lib/slowest"
+)
+
+func TestRing(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int
+		statuses []int
+		expected []string
+	}{
+		{name: "only server errors", size: 3, statuses: []int{200, 500, 404, 503}, expected: []string{"r4", "r2"}},
+		{name: "newest first once full", size: 2, statuses: []int{500, 502, 503, 504}, expected: []string{"r4", "r3"}},
+		{name: "disabled", size: 0, statuses: []int{500}, expected: []string{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ring := recentfailures.New(tc.size)
+			for i, status := range tc.statuses {
+				ring.Record(slowest.Request{
+					Time:      time.Date(2026, 1, 1, 0, 0, i, 0, time.UTC),
+					RequestID: "r" + string(rune('1'+i)),
+					Method:    http.MethodGet,
+					Route:     "/quotes",
+					Status:    status,
+					Duration:  time.Millisecond,
+				})
+			}
+			got := []string{}
+			for _, req := range ring.Report() {
+				got = append(got, req.RequestID)
+			}
+			if !slices.Equal(got, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+// Package redact hides secrets, such as passwords, API keys and the
+// credentials in URLs, from whatever the service shows of itself: its
+// configuration, its logs and the URLs it reports.
+package redact
+
+import (
+	"encoding"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Mask replaces a secret.
+const Mask = "[redacted]"
+
+// secretNames are the names of the fields and log attributes that hold
+// secrets, lowercased. Clients is the signing secrets by client name and
+// APIKeys the principals by API key.
+var secretNames = []string{
+	"password",
+	"secret",
+	"secretkey",
+	"accesskey",
+	"apikey",
+	"apikeys",
+	"clients",
+	"token",
+	"dsn",
+}
+
+// IsSecret reports whether a field or log attribute called name holds a
+// secret.
+func IsSecret(name string) bool {
+	name = strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+	return slices.Contains(secretNames, name)
+}
+
+// URL returns raw with its user info, which may carry a password or, as in
+// a Sentry DSN, a key, replaced by Mask. A value that is not a URL with
+// user info is returned as it is.
+func URL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil || u.Host == "" {
+		return raw
+	}
+	u.User = url.User(Mask)
+	s := u.String()
+	// url.User escapes the brackets of Mask, which reads worse.
+	return strings.Replace(s, url.PathEscape(Mask), Mask, 1)
+}
+
+// Value returns v as a tree of maps, slices and plain values ready to be
+// encoded as JSON, with secrets masked: the fields named as IsSecret says,
+// when set, and the user info of URLs. Unexported fields, functions and
+// channels are left out; durations, times and values that marshal to text
+// are written as text.
+func Value(v any) any {
+	return value(reflect.ValueOf(v))
+}
+
+var (
+	textMarshaler = reflect.TypeFor[encoding.TextMarshaler]()
+	stringer      = reflect.TypeFor[fmt.Stringer]()
+)
+
+func value(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	switch x := v.Interface().(type) {
+	case time.Duration:
+		return x.String()
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	}
+	if nilable(v) && v.IsNil() {
+		return nil
+	}
+	if v.Type().Implements(textMarshaler) {
+		if text, err := v.Interface().(encoding.TextMarshaler).MarshalText(); err == nil {
+			return URL(string(text))
+		}
+	}
+	if v.Type().Implements(stringer) {
+		return URL(v.Interface().(fmt.Stringer).String())
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return value(v.Elem())
+	case reflect.Struct:
+		out := make(map[string]any, v.NumField())
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			if !field.IsExported() || skipped(field.Type) {
+				continue
+			}
+			if IsSecret(field.Name) {
+				out[field.Name] = mask(v.Field(i))
+				continue
+			}
+			out[field.Name] = value(v.Field(i))
+		}
+		return out
+	case reflect.Map:
+		out := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			if IsSecret(key) {
+				out[key] = mask(iter.Value())
+				continue
+			}
+			out[key] = value(iter.Value())
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		out := make([]any, v.Len())
+		for i := range v.Len() {
+			out[i] = value(v.Index(i))
+		}
+		return out
+	case reflect.String:
+		return URL(v.String())
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return nil
+	}
+	return v.Interface()
+}
+
+func nilable(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return true
+	}
+	return false
+}
+
+// mask returns Mask for a secret that is set, and an empty string for one
+// that is not, so that the difference still shows.
+func mask(v reflect.Value) string {
+	if v.IsZero() || ((v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && v.Len() == 0) {
+		return ""
+	}
+	return Mask
+}
+
+func skipped(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return true
+	}
+	return false
+}
+
+// Attr is a slog.HandlerOptions.ReplaceAttr that masks the attributes
+// named as IsSecret says and the user info of URLs in string attributes.
+func Attr(_ []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() == slog.KindGroup {
+		return a
+	}
+	if IsSecret(a.Key) {
+		return slog.String(a.Key, Mask)
+	}
+	if a.Value.Kind() == slog.KindString {
+		return slog.String(a.Key, URL(a.Value.String()))
+	}
+	return a
+}
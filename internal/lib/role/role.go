@@ -0,0 +1,55 @@
+// Package role orders what an authenticated principal may do, from
+// reading quotes up to running the /admin endpoints.
+package role
+
+import "fmt"
+
+// Role is what a principal may do. Each role may do everything the ones
+// below it may. The zero Role is Writer, what every principal could do
+// before roles existed.
+type Role string
+
+const (
+	// None may only use the routes open to everyone, such as /healthz.
+	None Role = "none"
+	// Reader may read quotes, authors and collections.
+	Reader Role = "reader"
+	// Writer may also add, change and delete them.
+	Writer Role = "writer"
+	// Admin may also use the /admin endpoints.
+	Admin Role = "admin"
+)
+
+var rank = map[Role]int{
+	None:   0,
+	Reader: 1,
+	Writer: 2,
+	Admin:  3,
+}
+
+// Parse validates a role name from configuration.
+func Parse(s string) (Role, error) {
+	switch r := Role(s); r {
+	case None, Reader, Writer, Admin:
+		return r, nil
+	default:
+		return "", fmt.Errorf("unknown role %q", s)
+	}
+}
+
+// Allows reports whether r may do what needs at least required.
+func (r Role) Allows(required Role) bool {
+	return rank[r.resolve()] >= rank[required.resolve()]
+}
+
+// String returns the role's name, "writer" for the zero Role.
+func (r Role) String() string {
+	return string(r.resolve())
+}
+
+func (r Role) resolve() Role {
+	if r == "" {
+		return Writer
+	}
+	return r
+}
@@ -0,0 +1,50 @@
+package role_test
+
+import (
+	"testing"
+
+	"quotes-service/internal/lib/role"
+)
+
+func TestAllows(t *testing.T) {
+	roles := []role.Role{role.None, role.Reader, role.Writer, role.Admin}
+	for i, r := range roles {
+		for j, required := range roles {
+			if got, want := r.Allows(required), i >= j; got != want {
+				t.Errorf("%s.Allows(%s) = %v, want %v", r, required, got, want)
+			}
+		}
+	}
+
+	var zero role.Role
+	if !zero.Allows(role.Writer) || zero.Allows(role.Admin) || zero.String() != "writer" {
+		t.Errorf("expected the zero role to be a writer, got %s", zero)
+	}
+	if role.Reader.Allows("") || !role.Writer.Allows("") {
+		t.Error("expected requiring the zero role to require a writer")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    role.Role
+		wantErr bool
+	}{
+		{in: "none", want: role.None},
+		{in: "reader", want: role.Reader},
+		{in: "writer", want: role.Writer},
+		{in: "admin", want: role.Admin},
+		{in: "", wantErr: true},
+		{in: "Admin", wantErr: true},
+		{in: "root", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := role.Parse(tc.in)
+			if (err != nil) != tc.wantErr || got != tc.want {
+				t.Fatalf("Parse(%q) = %q, %v; want %q, error %v", tc.in, got, err, tc.want, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Package rotation picks the quote of the day so that quotes take turns:
+// none comes back within a window of days, those featured least often go
+// first, so a quote just added is featured soon, and among them quote
+// weights decide. A pick depends on nothing but the quotes, the log of
+// past picks and the date, so replaying the log recomputes every pick.
+package rotation
+
+import (
+	"cmp"
+	"hash/fnv"
+	"maps"
+	"slices"
+	"time"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// Pick is the quote featured on a day.
+type Pick struct {
+	// Date is the day, as time.DateOnly.
+	Date    string `json:"date"`
+	QuoteID int64  `json:"quote_id"`
+}
+
+// Choose returns the quote for date, given as time.DateOnly, out of
+// quotes, given the picks of the days before it in history; picks of date
+// and later are ignored. featured counts, per quote, the picks older than
+// any in history, once they are trimmed from it; it may be nil. The quotes picked in the window days before date
+// are left out, unless that leaves none, as it does in a catalog smaller
+// than the window: then the quotes featured least recently are the ones
+// left. Of those, the quotes featured the fewest times are kept, and one
+// of them is picked at random by weight, the date seeding the draw. It
+// returns false when quotes is empty.
+func Choose(quotes []models.Quote, featured map[int64]int, history []Pick, date string, window int) (models.Quote, bool) {
+	if len(quotes) == 0 {
+		return models.Quote{}, false
+	}
+
+	// lastFeatured is the date each quote was last picked, counts how
+	// often.
+	lastFeatured := make(map[int64]string)
+	counts := maps.Clone(featured)
+	if counts == nil {
+		counts = make(map[int64]int)
+	}
+	for _, p := range history {
+		if p.Date >= date {
+			continue
+		}
+		counts[p.QuoteID]++
+		lastFeatured[p.QuoteID] = max(lastFeatured[p.QuoteID], p.Date)
+	}
+
+	cutoff := windowStart(date, window)
+	candidates := make([]models.Quote, 0, len(quotes))
+	for _, q := range quotes {
+		if last, ok := lastFeatured[q.ID]; !ok || last < cutoff {
+			candidates = append(candidates, q)
+		}
+	}
+	if len(candidates) == 0 {
+		oldest := date
+		for _, q := range quotes {
+			oldest = min(oldest, lastFeatured[q.ID])
+		}
+		for _, q := range quotes {
+			if lastFeatured[q.ID] == oldest {
+				candidates = append(candidates, q)
+			}
+		}
+	}
+
+	fewest := counts[candidates[0].ID]
+	for _, q := range candidates {
+		fewest = min(fewest, counts[q.ID])
+	}
+	candidates = slices.DeleteFunc(candidates, func(q models.Quote) bool {
+		return counts[q.ID] > fewest
+	})
+	slices.SortFunc(candidates, func(a, b models.Quote) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	var total uint64
+	for _, q := range candidates {
+		total += weight(q)
+	}
+	h := fnv.New64a()
+	h.Write([]byte(date))
+	target := h.Sum64() % total
+	for _, q := range candidates {
+		if target < weight(q) {
+			return q, true
+		}
+		target -= weight(q)
+	}
+	return candidates[len(candidates)-1], true
+}
+
+// windowStart is the first day of the window days before date. Quotes
+// last picked before it may be picked on date.
+func windowStart(date string, window int) string {
+	day, err := time.Parse(time.DateOnly, date)
+	if err != nil || window <= 0 {
+		return date
+	}
+	return day.AddDate(0, 0, -window).Format(time.DateOnly)
+}
+
+func weight(q models.Quote) uint64 {
+	if q.Weight <= 0 {
+		return storage.DefaultWeight
+	}
+	return uint64(q.Weight)
+}
@@ -0,0 +1,185 @@
+package rotation_test
+
+import (
+	"testing"
+	"time"
+
+	"quotes-service/internal/lib/rotation"
+	"quotes-service/internal/models"
+)
+
+func catalog(ids ...int64) []models.Quote {
+	quotes := make([]models.Quote, 0, len(ids))
+	for _, id := range ids {
+		quotes = append(quotes, models.Quote{ID: id, Text: "Quote", Author: "Author"})
+	}
+	return quotes
+}
+
+func TestChoose(t *testing.T) {
+	tests := []struct {
+		name     string
+		quotes   []models.Quote
+		featured map[int64]int
+		history  []rotation.Pick
+		date     string
+		window   int
+		expected []int64
+	}{
+		{
+			name:     "only quote",
+			quotes:   catalog(7),
+			history:  []rotation.Pick{{Date: "2024-03-10", QuoteID: 7}},
+			date:     "2024-03-11",
+			window:   3,
+			expected: []int64{7},
+		},
+		{
+			name:   "window left out",
+			quotes: catalog(1, 2, 3),
+			history: []rotation.Pick{
+				{Date: "2024-03-09", QuoteID: 1},
+				{Date: "2024-03-10", QuoteID: 2},
+			},
+			date:     "2024-03-11",
+			window:   2,
+			expected: []int64{3},
+		},
+		{
+			name:   "never featured first",
+			quotes: catalog(1, 2, 3),
+			history: []rotation.Pick{
+				{Date: "2024-01-01", QuoteID: 1},
+				{Date: "2024-01-02", QuoteID: 2},
+			},
+			date:     "2024-03-11",
+			window:   1,
+			expected: []int64{3},
+		},
+		{
+			name:   "fewest features first",
+			quotes: catalog(1, 2, 3),
+			history: []rotation.Pick{
+				{Date: "2024-01-01", QuoteID: 1},
+				{Date: "2024-01-02", QuoteID: 2},
+				{Date: "2024-01-03", QuoteID: 3},
+				{Date: "2024-01-04", QuoteID: 1},
+				{Date: "2024-01-05", QuoteID: 3},
+			},
+			date:     "2024-03-11",
+			window:   1,
+			expected: []int64{2},
+		},
+		{
+			name:     "trimmed picks count",
+			quotes:   catalog(1, 2, 3),
+			featured: map[int64]int{2: 1, 3: 2},
+			history: []rotation.Pick{
+				{Date: "2024-01-01", QuoteID: 1},
+			},
+			date:     "2024-03-11",
+			window:   1,
+			expected: []int64{1},
+		},
+		{
+			name:   "catalog smaller than the window",
+			quotes: catalog(1, 2, 3),
+			history: []rotation.Pick{
+				{Date: "2024-03-08", QuoteID: 2},
+				{Date: "2024-03-09", QuoteID: 3},
+				{Date: "2024-03-10", QuoteID: 1},
+			},
+			date:     "2024-03-11",
+			window:   7,
+			expected: []int64{2},
+		},
+		{
+			name:   "deleted quotes do not count",
+			quotes: catalog(1, 2),
+			history: []rotation.Pick{
+				{Date: "2024-03-09", QuoteID: 1},
+				{Date: "2024-03-10", QuoteID: 9},
+			},
+			date:     "2024-03-11",
+			window:   7,
+			expected: []int64{2},
+		},
+		{
+			name:   "picks of the day and later ignored",
+			quotes: catalog(1, 2),
+			history: []rotation.Pick{
+				{Date: "2024-03-10", QuoteID: 1},
+				{Date: "2024-03-11", QuoteID: 1},
+				{Date: "2024-03-12", QuoteID: 2},
+			},
+			date:     "2024-03-11",
+			window:   1,
+			expected: []int64{2},
+		},
+		{
+			name:     "no window",
+			quotes:   catalog(1, 2),
+			history:  []rotation.Pick{{Date: "2024-03-10", QuoteID: 1}, {Date: "2024-03-10", QuoteID: 2}},
+			date:     "2024-03-11",
+			expected: []int64{1, 2},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := rotation.Choose(tc.quotes, tc.featured, tc.history, tc.date, tc.window)
+			if !ok {
+				t.Fatal("expected a pick")
+			}
+			for _, id := range tc.expected {
+				if got.ID == id {
+					return
+				}
+			}
+			t.Fatalf("expected one of %v, got %d", tc.expected, got.ID)
+		})
+	}
+
+	if _, ok := rotation.Choose(nil, nil, nil, "2024-03-11", 7); ok {
+		t.Error("expected no pick from an empty catalog")
+	}
+}
+
+func TestChooseTakesTurns(t *testing.T) {
+	const (
+		days   = 365
+		window = 4
+	)
+	quotes := catalog(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	quotes[0].Weight = 100
+
+	var history []rotation.Pick
+	counts := make(map[int64]int)
+	day := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for range days {
+		date := day.Format(time.DateOnly)
+		q, ok := rotation.Choose(quotes, nil, history, date, window)
+		if !ok {
+			t.Fatal("expected a pick")
+		}
+		for _, p := range history[max(0, len(history)-window):] {
+			if p.QuoteID == q.ID {
+				t.Fatalf("quote %d picked on %s and again on %s", q.ID, p.Date, date)
+			}
+		}
+		if again, _ := rotation.Choose(quotes, nil, history, date, window); again.ID != q.ID {
+			t.Fatalf("expected the same pick on %s, got %d and %d", date, q.ID, again.ID)
+		}
+		history = append(history, rotation.Pick{Date: date, QuoteID: q.ID})
+		counts[q.ID]++
+		day = day.AddDate(0, 0, 1)
+	}
+
+	fewest, most := days, 0
+	for _, q := range quotes {
+		fewest, most = min(fewest, counts[q.ID]), max(most, counts[q.ID])
+	}
+	if most-fewest > 1 {
+		t.Fatalf("expected every quote featured as often as the others, give or take one: %v", counts)
+	}
+}
@@ -0,0 +1,55 @@
+// Package rss renders RSS 2.0 feeds.
+package rss
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+const ContentType = "application/rss+xml; charset=utf-8"
+
+type Feed struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	Channel Channel  `xml:"channel"`
+}
+
+type Channel struct {
+	Title         string `xml:"title"`
+	Link          string `xml:"link"`
+	Description   string `xml:"description"`
+	LastBuildDate string `xml:"lastBuildDate,omitempty"`
+	Items         []Item `xml:"item"`
+}
+
+type Item struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	GUID        GUID   `xml:"guid"`
+	PubDate     string `xml:"pubDate,omitempty"`
+}
+
+type GUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// Date formats t as an RFC 822 date as required by RSS.
+func Date(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC1123Z)
+}
+
+// Encode writes the feed as an XML document.
+func Encode(w io.Writer, channel Channel) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(Feed{Version: "2.0", Channel: channel})
+}
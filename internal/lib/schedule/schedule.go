@@ -0,0 +1,235 @@
+// Package schedule parses five-field cron expressions and computes their
+// fire times in a given time zone.
+//
+// The fields are minute, hour, day of month, month and day of week. Each
+// accepts *, a number, a range a-b, a step */n or a-b/n, and comma-separated
+// lists of those. Months and weekdays also accept three-letter English
+// names, and Sunday is both 0 and 7. As in classic cron, when both the day
+// of month and the day of week are restricted a day matching either fires.
+//
+// Around daylight saving changes the schedule follows the wall clock: a
+// time skipped by the spring-forward gap does not fire that day, and a time
+// repeated by the fall-back overlap fires once, at its first occurrence.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchDays bounds the search for the next fire time, so expressions that
+// can never fire, such as "0 0 30 2 *", end instead of looping forever.
+const searchDays = 5 * 366
+
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowField = field{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// Schedule is a parsed cron expression bound to a time zone.
+type Schedule struct {
+	expr    string
+	loc     *time.Location
+	minutes uint64
+	hours   uint64
+	doms    uint64
+	months  uint64
+	dows    uint64
+	domStar bool
+	dowStar bool
+}
+
+// Parse parses expr in the time zone loc.
+func Parse(expr string, loc *time.Location) (*Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule: expected 5 fields, got %d in %q", len(fields), expr)
+	}
+
+	s := &Schedule{expr: strings.Join(fields, " "), loc: loc}
+	var err error
+	if s.minutes, err = parseField(fields[0], minuteField); err != nil {
+		return nil, err
+	}
+	if s.hours, err = parseField(fields[1], hourField); err != nil {
+		return nil, err
+	}
+	if s.doms, err = parseField(fields[2], domField); err != nil {
+		return nil, err
+	}
+	if s.months, err = parseField(fields[3], monthField); err != nil {
+		return nil, err
+	}
+	if s.dows, err = parseField(fields[4], dowField); err != nil {
+		return nil, err
+	}
+	// Sunday may be written as 7.
+	if s.dows&(1<<7) != 0 {
+		s.dows |= 1
+	}
+	s.domStar = fields[2] == "*"
+	s.dowStar = fields[4] == "*"
+	return s, nil
+}
+
+// String returns the normalized expression.
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+// Location returns the time zone the schedule is evaluated in.
+func (s *Schedule) Location() *time.Location {
+	return s.loc
+}
+
+// Next returns the first fire time strictly after t, or the zero time if
+// the schedule never fires.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.In(s.loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.loc)
+
+	for i := 0; i < searchDays; i++ {
+		y, m, d := day.Date()
+		if s.matchDay(y, m, d) {
+			for hour := 0; hour < 24; hour++ {
+				if s.hours&(1<<hour) == 0 {
+					continue
+				}
+				for minute := 0; minute < 60; minute++ {
+					if s.minutes&(1<<minute) == 0 {
+						continue
+					}
+					candidate := time.Date(y, m, d, hour, minute, 0, 0, s.loc)
+					// time.Date moves a wall time inside a DST gap to
+					// another hour; such a time does not exist today.
+					if candidate.Hour() != hour || candidate.Minute() != minute {
+						continue
+					}
+					candidate = firstOccurrence(candidate)
+					if candidate.After(t) {
+						return candidate
+					}
+				}
+			}
+		}
+		// Rebuild midnight from the date; adding 24h would drift off
+		// midnight across DST changes.
+		day = time.Date(y, m, d+1, 0, 0, 0, 0, s.loc)
+	}
+	return time.Time{}
+}
+
+// firstOccurrence returns the earlier instant when t's wall time occurs
+// twice because clocks were set back shortly before. time.Date does not
+// promise which of the two it picks.
+func firstOccurrence(t time.Time) time.Time {
+	_, offset := t.Zone()
+	_, offsetBefore := t.Add(-3 * time.Hour).Zone()
+	if offsetBefore <= offset {
+		return t
+	}
+	earlier := t.Add(-time.Duration(offsetBefore-offset) * time.Second)
+	if earlier.Hour() == t.Hour() && earlier.Minute() == t.Minute() {
+		return earlier
+	}
+	return t
+}
+
+// NextN returns up to n consecutive fire times after t.
+func (s *Schedule) NextN(t time.Time, n int) []time.Time {
+	times := make([]time.Time, 0, n)
+	for len(times) < n {
+		t = s.Next(t)
+		if t.IsZero() {
+			break
+		}
+		times = append(times, t)
+	}
+	return times
+}
+
+func (s *Schedule) matchDay(y int, m time.Month, d int) bool {
+	if s.months&(1<<uint(m)) == 0 {
+		return false
+	}
+	domMatch := s.doms&(1<<uint(d)) != 0
+	dowMatch := s.dows&(1<<uint(time.Date(y, m, d, 12, 0, 0, 0, time.UTC).Weekday())) != 0
+	switch {
+	case s.domStar && s.dowStar:
+		return true
+	case s.domStar:
+		return dowMatch
+	case s.dowStar:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
+
+func parseField(expr string, f field) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		rangeExpr, stepExpr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepExpr)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("schedule: invalid step %q in %s field", stepExpr, f.name)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		if rangeExpr != "*" {
+			loExpr, hiExpr, isRange := strings.Cut(rangeExpr, "-")
+			var err error
+			if lo, err = f.value(loExpr); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = f.value(hiExpr); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				// "5/15" means from 5 to the end in steps of 15.
+				hi = f.max
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("schedule: range %q in %s field is backwards", rangeExpr, f.name)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (f field) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("schedule: %q is not a valid %s (%d-%d)", s, f.name, f.min, f.max)
+	}
+	return v, nil
+}
@@ -0,0 +1,187 @@
+package schedule_test
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"quotes-service/internal/lib/schedule"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("failed to load %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"* * * * funday",
+		"a * * * *",
+	} {
+		if _, err := schedule.Parse(expr, time.UTC); err == nil {
+			t.Errorf("expected %q to be rejected", expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		expr  string
+		after string
+		want  []string
+	}{
+		{
+			name:  "weekdays at nine",
+			expr:  "0 9 * * MON-FRI",
+			after: "2024-03-08T09:00:00Z", // a Friday, exactly at a fire time
+			want:  []string{"2024-03-11T09:00:00Z", "2024-03-12T09:00:00Z", "2024-03-13T09:00:00Z"},
+		},
+		{
+			name:  "steps and lists",
+			expr:  "*/20 8,17 * * *",
+			after: "2024-03-08T08:30:00Z",
+			want:  []string{"2024-03-08T08:40:00Z", "2024-03-08T17:00:00Z", "2024-03-08T17:20:00Z"},
+		},
+		{
+			name:  "day of month or day of week",
+			expr:  "0 0 1 * SUN",
+			after: "2024-03-28T00:00:00Z",
+			want:  []string{"2024-03-31T00:00:00Z", "2024-04-01T00:00:00Z", "2024-04-07T00:00:00Z"},
+		},
+		{
+			name:  "sunday as seven",
+			expr:  "0 12 * * 7",
+			after: "2024-03-08T00:00:00Z",
+			want:  []string{"2024-03-10T12:00:00Z"},
+		},
+		{
+			name:  "leap day",
+			expr:  "0 0 29 feb *",
+			after: "2024-03-01T00:00:00Z",
+			want:  []string{"2028-02-29T00:00:00Z"},
+		},
+		{
+			name:  "never",
+			expr:  "0 0 30 2 *",
+			after: "2024-01-01T00:00:00Z",
+			want:  []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := schedule.Parse(tc.expr, time.UTC)
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			after, _ := time.Parse(time.RFC3339, tc.after)
+			got := s.NextN(after, len(tc.want)+boolInt(len(tc.want) == 0))
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %d fire times, got %v", len(tc.want), got)
+			}
+			for i, want := range tc.want {
+				if got[i].UTC().Format(time.RFC3339) != want {
+					t.Errorf("fire time %d: expected %s, got %s", i, want, got[i].UTC().Format(time.RFC3339))
+				}
+			}
+		})
+	}
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestNextAcrossDST(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	berlin := mustLoad(t, "Europe/Berlin")
+
+	tests := []struct {
+		name  string
+		expr  string
+		loc   *time.Location
+		after time.Time
+		want  []string
+	}{
+		{
+			// Clocks jump from 2:00 to 3:00 on 2024-03-10, so 2:30 does
+			// not exist that day.
+			name:  "spring forward skips the gap",
+			expr:  "30 2 * * *",
+			loc:   newYork,
+			after: time.Date(2024, time.March, 9, 12, 0, 0, 0, newYork),
+			want:  []string{"2024-03-11T02:30:00-04:00"},
+		},
+		{
+			name:  "spring forward keeps the wall clock",
+			expr:  "0 9 * * *",
+			loc:   newYork,
+			after: time.Date(2024, time.March, 9, 12, 0, 0, 0, newYork),
+			want:  []string{"2024-03-10T09:00:00-04:00", "2024-03-11T09:00:00-04:00"},
+		},
+		{
+			// 1:30 happens twice on 2024-11-03; it fires only the first
+			// time.
+			name:  "fall back fires once",
+			expr:  "30 1 * * *",
+			loc:   newYork,
+			after: time.Date(2024, time.November, 2, 12, 0, 0, 0, newYork),
+			want:  []string{"2024-11-03T01:30:00-04:00", "2024-11-04T01:30:00-05:00"},
+		},
+		{
+			name:  "fall back from inside the repeated hour",
+			expr:  "30 1 * * *",
+			loc:   newYork,
+			after: time.Date(2024, time.November, 3, 5, 45, 0, 0, time.UTC), // 1:45 EDT
+			want:  []string{"2024-11-04T01:30:00-05:00"},
+		},
+		{
+			name:  "hourly across the fall back",
+			expr:  "0 * * * *",
+			loc:   berlin,
+			after: time.Date(2024, time.October, 27, 1, 30, 0, 0, berlin),
+			want:  []string{"2024-10-27T02:00:00+02:00", "2024-10-27T03:00:00+01:00"},
+		},
+		{
+			name:  "hourly across the spring forward",
+			expr:  "0 * * * *",
+			loc:   berlin,
+			after: time.Date(2024, time.March, 31, 1, 30, 0, 0, berlin),
+			want:  []string{"2024-03-31T03:00:00+02:00"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := schedule.Parse(tc.expr, tc.loc)
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			got := s.NextN(tc.after, len(tc.want))
+			for i, want := range tc.want {
+				if i >= len(got) || got[i].Format(time.RFC3339) != want {
+					t.Errorf("expected %v, got %v", tc.want, got)
+					break
+				}
+			}
+		})
+	}
+}
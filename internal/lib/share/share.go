@@ -0,0 +1,221 @@
+// Package share renders quotes as text ready to paste into a post or a
+// chat, one style per target, through text templates.
+package share
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	"quotes-service/internal/models"
+)
+
+// Built-in styles.
+const (
+	StyleTwitter  = "twitter"
+	StylePlain    = "plain"
+	StyleMarkdown = "markdown"
+)
+
+// DefaultTwitterLength is the length a twitter post is capped at.
+const DefaultTwitterLength = 280
+
+// Ellipsis ends a text cut to fit.
+const Ellipsis = "…"
+
+// zeroWidthJoiner glues emoji into one; a cut must not leave it dangling.
+const zeroWidthJoiner = '‍'
+
+var ErrUnknownStyle = errors.New("unknown share style")
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var funcs = template.FuncMap{
+	"md":         escapeMarkdown,
+	"blockquote": blockquote,
+}
+
+// Style is how one style renders.
+type Style struct {
+	// Template is the text/template source the quote is rendered through.
+	// Its fields are those of Data. A trailing newline is dropped.
+	Template string
+	// MaxLength caps the rendered text, in runes; zero leaves it uncapped.
+	// A text too long is cut and ends with Ellipsis, the rest of the
+	// template, attribution included, kept whole.
+	MaxLength int
+}
+
+// Data is what a template renders.
+type Data struct {
+	ID        int64
+	Text      string
+	Author    string
+	Source    string
+	SourceURL string
+}
+
+// Defaults returns the built-in styles, with the embedded templates.
+func Defaults() map[string]Style {
+	styles := make(map[string]Style, 3)
+	for name, maxLength := range map[string]int{StyleTwitter: DefaultTwitterLength, StylePlain: 0, StyleMarkdown: 0} {
+		src, err := templateFS.ReadFile("templates/" + name + ".tmpl")
+		if err != nil {
+			panic(err)
+		}
+		styles[name] = Style{Template: string(src), MaxLength: maxLength}
+	}
+	return styles
+}
+
+type style struct {
+	tmpl      *template.Template
+	maxLength int
+}
+
+// Formatter renders quotes in its styles.
+type Formatter struct {
+	styles map[string]style
+	names  []string
+}
+
+// New parses the templates of styles. It fails on a template that does not
+// parse, that fails on a quote, or that leaves no room within its
+// MaxLength for the shortest text and author.
+func New(styles map[string]Style) (*Formatter, error) {
+	f := &Formatter{
+		styles: make(map[string]style, len(styles)),
+		names:  slices.Sorted(maps.Keys(styles)),
+	}
+	for _, name := range f.names {
+		s := styles[name]
+		if s.MaxLength < 0 {
+			return nil, fmt.Errorf("style %s: max length must not be negative", name)
+		}
+		tmpl, err := template.New(name).Funcs(funcs).Parse(s.Template)
+		if err != nil {
+			return nil, fmt.Errorf("style %s: %w", name, err)
+		}
+		st := style{tmpl: tmpl, maxLength: s.MaxLength}
+		shortest, err := st.render(Data{Text: Ellipsis, Author: Ellipsis, Source: Ellipsis, SourceURL: Ellipsis})
+		if err != nil {
+			return nil, fmt.Errorf("style %s: %w", name, err)
+		}
+		if st.maxLength > 0 && utf8.RuneCountInString(shortest) > st.maxLength {
+			return nil, fmt.Errorf("style %s: the template alone is longer than %d", name, st.maxLength)
+		}
+		f.styles[name] = st
+	}
+	return f, nil
+}
+
+// Styles returns the names of the styles, sorted.
+func (f *Formatter) Styles() []string {
+	return slices.Clone(f.names)
+}
+
+// Format renders q in the named style. It fails with ErrUnknownStyle for a
+// style f does not have.
+func (f *Formatter) Format(name string, q models.Quote) (string, error) {
+	st, ok := f.styles[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownStyle, name)
+	}
+	d := Data{ID: q.ID, Text: q.Text, Author: q.Author, Source: q.Source, SourceURL: q.SourceURL}
+	out, err := st.render(d)
+	if err != nil || st.fits(out) {
+		return out, err
+	}
+
+	// The text gives way first; the author only when even an ellipsis
+	// alone does not leave it room.
+	text := []rune(d.Text)
+	if out, ok, err := st.fit(d, len(text), func(d *Data, n int) { d.Text = cut(text, n) }); err != nil || ok {
+		return out, err
+	}
+	d.Text = Ellipsis
+	author := []rune(d.Author)
+	if out, ok, err := st.fit(d, len(author), func(d *Data, n int) { d.Author = cut(author, n) }); err != nil || ok {
+		return out, err
+	}
+	d.Author = Ellipsis
+	return st.render(d)
+}
+
+func (s style) render(d Data) (string, error) {
+	var b strings.Builder
+	if err := s.tmpl.Execute(&b, d); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+func (s style) fits(out string) bool {
+	return s.maxLength == 0 || utf8.RuneCountInString(out) <= s.maxLength
+}
+
+// fit renders d with the most runes of a field, kept by set, for which the
+// result fits, if any does. n is the length of the field in full, which
+// does not fit.
+func (s style) fit(d Data, n int, set func(d *Data, n int)) (string, bool, error) {
+	best, found := "", false
+	lo, hi := 0, n-1
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		set(&d, mid)
+		out, err := s.render(d)
+		if err != nil {
+			return "", false, err
+		}
+		if s.fits(out) {
+			best, found = out, true
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+	return best, found, nil
+}
+
+// cut keeps at most the first n runes of text and ends them with Ellipsis.
+// It cuts at the last space when that keeps most of them, and never leaves
+// trailing spaces or a zero width joiner before the ellipsis.
+func cut(text []rune, n int) string {
+	kept := text[:n]
+	if n < len(text) && !unicode.IsSpace(text[n]) {
+		for i := len(kept) - 1; i > n/2; i-- {
+			if unicode.IsSpace(kept[i]) {
+				kept = kept[:i]
+				break
+			}
+		}
+	}
+	s := strings.TrimRightFunc(string(kept), func(r rune) bool {
+		return unicode.IsSpace(r) || r == zeroWidthJoiner
+	})
+	return s + Ellipsis
+}
+
+// escapeMarkdown escapes the characters Markdown would read as markup.
+func escapeMarkdown(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`\`+"`"+`*_[]<>#|~`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// blockquote quotes every line of s in Markdown.
+func blockquote(s string) string {
+	return "> " + strings.ReplaceAll(s, "\n", "\n> ")
+}
@@ -0,0 +1,141 @@
+package share_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"quotes-service/internal/lib/share"
+	"quotes-service/internal/models"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output does not match %s:\n%s", golden, got)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	formatter, err := share.New(share.Defaults())
+	if err != nil {
+		t.Fatalf("failed to parse the default styles: %v", err)
+	}
+
+	quotes := map[string]models.Quote{
+		"short": {ID: 1, Text: "Simplicity is prerequisite for reliability.", Author: "Edsger W. Dijkstra", Source: "EWD498", SourceURL: "https://www.cs.utexas.edu/~EWD/transcriptions/EWD04xx/EWD498.html"},
+		"long": {ID: 2, Author: "Marcus Aurelius", Source: "Meditations", Text: "Begin the morning by saying to thyself, I shall meet with the busy-body, the ungrateful, arrogant, deceitful, envious, unsocial. " +
+			"All these things happen to them by reason of their ignorance of what is good and evil. But I who have seen the nature of the good that it is beautiful, " +
+			"and of the bad that it is ugly, can neither be harmed by any of them, for no one can fix on me what is ugly."},
+		"emoji":       {ID: 3, Author: "Anonymous 🦊", Text: strings.TrimSpace(strings.Repeat("Keep going 👩‍👩‍👧‍👦 and smile 😀🎉. ", 12))},
+		"markup":      {ID: 4, Author: "Linus_Torvalds", Text: "Talk is *cheap*.\nShow me the `code` <now> & [here]."},
+		"long author": {ID: 5, Text: "Yes.", Author: strings.TrimSpace(strings.Repeat("Very Long Name ", 30))},
+	}
+
+	for _, style := range formatter.Styles() {
+		for name, q := range quotes {
+			t.Run(style+"/"+name, func(t *testing.T) {
+				out, err := formatter.Format(style, q)
+				if err != nil {
+					t.Fatalf("failed to format: %v", err)
+				}
+				if !utf8.ValidString(out) {
+					t.Fatalf("invalid UTF-8 in %q", out)
+				}
+				if style == share.StyleTwitter && utf8.RuneCountInString(out) > share.DefaultTwitterLength {
+					t.Fatalf("expected at most %d runes, got %d", share.DefaultTwitterLength, utf8.RuneCountInString(out))
+				}
+				checkGolden(t, style+"-"+strings.ReplaceAll(name, " ", "-")+".golden", []byte(out+"\n"))
+			})
+		}
+	}
+}
+
+func TestFormatTruncation(t *testing.T) {
+	formatter, err := share.New(map[string]share.Style{
+		"short": {Template: "{{.Text}} — {{.Author}}", MaxLength: 20},
+	})
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		author   string
+		expected string
+	}{
+		{name: "fits", text: "Be brief.", author: "Me", expected: "Be brief. — Me"},
+		{name: "exactly fits", text: "Brevity is wit.", author: "Me", expected: "Brevity is wit. — Me"},
+		{name: "cut at a space", text: "Brevity is the soul of wit.", author: "Me", expected: "Brevity is the… — Me"},
+		{name: "cut inside a long word", text: "Supercalifragilisticexpialidocious", author: "Me", expected: "Supercalifragi… — Me"},
+		{name: "runes, not bytes", text: "Краткость сестра таланта.", author: "Чехов", expected: "Краткость… — Чехов"},
+		{name: "no dangling joiner", text: "abcdefghijkl👩‍👩‍👧 wins", author: "Me", expected: "abcdefghijkl👩… — Me"},
+		{name: "author too long", text: "Yes.", author: "Bartholomew Fitzgerald", expected: "… — Bartholomew…"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := formatter.Format("short", models.Quote{Text: tc.text, Author: tc.author})
+			if err != nil {
+				t.Fatalf("failed to format: %v", err)
+			}
+			if out != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, out)
+			}
+			if n := utf8.RuneCountInString(out); n > 20 {
+				t.Fatalf("expected at most 20 runes, got %d", n)
+			}
+		})
+	}
+
+	if _, err := formatter.Format("twitter", models.Quote{Text: "x"}); !errors.Is(err, share.ErrUnknownStyle) {
+		t.Fatalf("expected ErrUnknownStyle, got %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		style   share.Style
+		wantErr string
+	}{
+		{name: "valid", style: share.Style{Template: "{{.Text}}"}},
+		{name: "does not parse", style: share.Style{Template: "{{.Text"}, wantErr: "style broken"},
+		{name: "unknown field", style: share.Style{Template: "{{.Quote}}"}, wantErr: "can't evaluate field Quote"},
+		{name: "no room", style: share.Style{Template: "Shared from quotes-service: {{.Text}}", MaxLength: 10}, wantErr: "longer than 10"},
+		{name: "negative length", style: share.Style{Template: "{{.Text}}", MaxLength: -1}, wantErr: "must not be negative"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := share.New(map[string]share.Style{"broken": tc.style})
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected an error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package signing_test
+
+import (
+	"testing"
+	"time"
+
+	"quotes-service/internal/lib/signing"
+)
+
+func TestVerify(t *testing.T) {
+	secret := []byte("secret")
+	signature := signing.Sign(secret, 1700000000, "POST", "/quotes?dry_run=true", []byte(`{"text":"a"}`))
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		timestamp int64
+		method    string
+		uri       string
+		body      string
+		expected  bool
+	}{
+		{name: "valid", secret: "secret", signature: signature, timestamp: 1700000000, method: "POST", uri: "/quotes?dry_run=true", body: `{"text":"a"}`, expected: true},
+		{name: "other secret", secret: "other", signature: signature, timestamp: 1700000000, method: "POST", uri: "/quotes?dry_run=true", body: `{"text":"a"}`},
+		{name: "other timestamp", secret: "secret", signature: signature, timestamp: 1700000001, method: "POST", uri: "/quotes?dry_run=true", body: `{"text":"a"}`},
+		{name: "other method", secret: "secret", signature: signature, timestamp: 1700000000, method: "PUT", uri: "/quotes?dry_run=true", body: `{"text":"a"}`},
+		{name: "other query", secret: "secret", signature: signature, timestamp: 1700000000, method: "POST", uri: "/quotes?dry_run=false", body: `{"text":"a"}`},
+		{name: "other body", secret: "secret", signature: signature, timestamp: 1700000000, method: "POST", uri: "/quotes?dry_run=true", body: `{"text":"b"}`},
+		{name: "not hex", secret: "secret", signature: "zz", timestamp: 1700000000, method: "POST", uri: "/quotes?dry_run=true", body: `{"text":"a"}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := signing.Verify([]byte(tc.secret), tc.signature, tc.timestamp, tc.method, tc.uri, []byte(tc.body)); got != tc.expected {
+				t.Fatalf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestFresh(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name      string
+		timestamp int64
+		expected  bool
+	}{
+		{name: "now", timestamp: 1700000000, expected: true},
+		{name: "at the edge", timestamp: 1700000000 - 300, expected: true},
+		{name: "too old", timestamp: 1700000000 - 301},
+		{name: "ahead within skew", timestamp: 1700000000 + 300, expected: true},
+		{name: "too far ahead", timestamp: 1700000000 + 301},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := signing.Fresh(tc.timestamp, now, 5*time.Minute); got != tc.expected {
+				t.Fatalf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+// Package slowest keeps the slowest requests served over a rolling time
+// window, for a quick look at what is slow without a metrics stack.
+package slowest
+
+import (
+	"cmp"
+	"container/heap"
+	"slices"
+	"sync"
+	"time"
+
+	"quotes-service/internal/models"
+)
+
+// buckets is how many slices the window is split into. A request leaves
+// the window up to window/buckets after it is older than the window.
+const buckets = 10
+
+// Request is a finished request offered to a Window.
+type Request struct {
+	Time      time.Time
+	RequestID string
+	Method    string
+	Route     string
+	Status    int
+	Duration  time.Duration
+}
+
+// Window remembers the size slowest requests of the last window. It splits
+// the window into a ring of buckets that each keep their own size slowest
+// requests, so memory stays bounded at buckets*size entries whatever the
+// traffic, and whole buckets expire as the window moves on. It is safe for
+// concurrent use.
+type Window struct {
+	mu      sync.Mutex
+	size    int
+	window  time.Duration
+	span    time.Duration
+	buckets [buckets]bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	start    time.Time
+	requests byDuration
+}
+
+type Option func(*Window)
+
+// WithClock overrides the time source, mainly for tests.
+func WithClock(now func() time.Time) Option {
+	return func(w *Window) {
+		w.now = now
+	}
+}
+
+// New returns a Window keeping the size slowest requests of the last
+// window.
+func New(size int, window time.Duration, opts ...Option) *Window {
+	w := &Window{
+		size:   size,
+		window: window,
+		span:   max(window/buckets, time.Nanosecond),
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Record offers req to the window. It is kept only while it is among the
+// slowest of its bucket.
+func (w *Window) Record(req Request) {
+	if w.size <= 0 {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	b := w.bucket(w.now())
+	switch {
+	case len(b.requests) < w.size:
+		heap.Push(&b.requests, req)
+	case req.Duration > b.requests[0].Duration:
+		b.requests[0] = req
+		heap.Fix(&b.requests, 0)
+	}
+}
+
+// bucket returns the bucket for now, emptying it first if it still holds
+// an older slice of time. The caller must hold w.mu.
+func (w *Window) bucket(now time.Time) *bucket {
+	start := now.Truncate(w.span)
+	b := &w.buckets[int(start.UnixNano()/int64(w.span)%buckets)]
+	if !b.start.Equal(start) {
+		b.start = start
+		b.requests = b.requests[:0]
+	}
+	return b
+}
+
+// Slowest returns up to n of the slowest requests still in the window,
+// slowest first.
+func (w *Window) Slowest(n int) []Request {
+	w.mu.Lock()
+	now := w.now()
+	oldest := now.Truncate(w.span).Add(-w.span * (buckets - 1))
+	var requests []Request
+	for i := range w.buckets {
+		b := &w.buckets[i]
+		if b.start.Before(oldest) || b.start.After(now) {
+			continue
+		}
+		requests = append(requests, b.requests...)
+	}
+	w.mu.Unlock()
+
+	slices.SortFunc(requests, func(a, b Request) int {
+		return cmp.Compare(b.Duration, a.Duration)
+	})
+	return requests[:min(n, w.size, len(requests))]
+}
+
+// Report returns the n slowest requests as the admin API shows them.
+func (w *Window) Report(n int) models.SlowRequests {
+	slowest := w.Slowest(n)
+	report := models.SlowRequests{
+		Window:   w.window.String(),
+		Requests: make([]models.SlowRequest, len(slowest)),
+	}
+	for i, req := range slowest {
+		report.Requests[i] = req.Summary()
+	}
+	return report
+}
+
+// Summary returns req as the admin API shows it.
+func (req Request) Summary() models.SlowRequest {
+	return models.SlowRequest{
+		Time:       req.Time,
+		RequestID:  req.RequestID,
+		Method:     req.Method,
+		Route:      req.Route,
+		Status:     req.Status,
+		Duration:   req.Duration.String(),
+		DurationMS: float64(req.Duration) / float64(time.Millisecond),
+	}
+}
+
+// byDuration is a min-heap, so the fastest kept request is the one to
+// replace.
+type byDuration []Request
+
+func (h byDuration) Len() int           { return len(h) }
+func (h byDuration) Less(i, j int) bool { return h[i].Duration < h[j].Duration }
+func (h byDuration) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *byDuration) Push(x any) { *h = append(*h, x.(Request)) }
+
+func (h *byDuration) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,113 @@
+package slowest_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"quotes-service/internal/lib/slowest"
+)
+
+func durations(requests []slowest.Request) []time.Duration {
+	got := make([]time.Duration, len(requests))
+	for i, req := range requests {
+		got[i] = req.Duration
+	}
+	return got
+}
+
+func record(w *slowest.Window, ds ...time.Duration) {
+	for _, d := range ds {
+		w.Record(slowest.Request{Route: "/quotes", Duration: d})
+	}
+}
+
+func TestWindowKeepsSlowest(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	w := slowest.New(3, 10*time.Minute, slowest.WithClock(func() time.Time { return now }))
+
+	record(w, 5*time.Millisecond, 50*time.Millisecond, time.Millisecond, 20*time.Millisecond, 30*time.Millisecond)
+
+	want := []time.Duration{50 * time.Millisecond, 30 * time.Millisecond, 20 * time.Millisecond}
+	if got := durations(w.Slowest(10)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if got := durations(w.Slowest(1)); !reflect.DeepEqual(got, want[:1]) {
+		t.Fatalf("expected the limit to apply, got %v", got)
+	}
+}
+
+func TestWindowMergesBuckets(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	w := slowest.New(2, 10*time.Minute, slowest.WithClock(func() time.Time { return now }))
+
+	record(w, 10*time.Millisecond, 40*time.Millisecond)
+	now = now.Add(3 * time.Minute)
+	record(w, 20*time.Millisecond, 30*time.Millisecond)
+
+	want := []time.Duration{40 * time.Millisecond, 30 * time.Millisecond}
+	if got := durations(w.Slowest(10)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected the overall slowest %v, got %v", want, got)
+	}
+}
+
+func TestWindowExpires(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	w := slowest.New(5, 10*time.Minute, slowest.WithClock(func() time.Time { return now }))
+
+	record(w, time.Second)
+	now = now.Add(5 * time.Minute)
+	record(w, 10*time.Millisecond)
+
+	now = now.Add(6 * time.Minute)
+	want := []time.Duration{10 * time.Millisecond}
+	if got := durations(w.Slowest(10)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected the old request to expire, got %v", got)
+	}
+
+	// A bucket reused after a full turn of the ring starts out empty.
+	now = now.Add(4 * time.Minute)
+	record(w, 20*time.Millisecond)
+	want = []time.Duration{20 * time.Millisecond}
+	if got := durations(w.Slowest(10)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected only the new request, got %v", got)
+	}
+}
+
+func TestWindowReport(t *testing.T) {
+	w := slowest.New(2, time.Minute)
+	w.Record(slowest.Request{RequestID: "abc", Method: "GET", Route: "/quotes", Status: 200, Duration: 1500 * time.Microsecond})
+
+	report := w.Report(10)
+	if report.Window != "1m0s" || len(report.Requests) != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if got := report.Requests[0]; got.RequestID != "abc" || got.Duration != "1.5ms" || got.DurationMS != 1.5 {
+		t.Fatalf("unexpected request %+v", got)
+	}
+}
+
+func TestWindowConcurrent(t *testing.T) {
+	w := slowest.New(10, time.Minute)
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 500 {
+				record(w, time.Duration(g*500+i))
+				if i%50 == 0 {
+					w.Slowest(5)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	got := durations(w.Slowest(100))
+	if len(got) != 10 || got[0] != 3999 || got[9] != 3990 {
+		t.Fatalf("expected the 10 slowest of all requests, got %v", got)
+	}
+}
@@ -0,0 +1,42 @@
+// Package storagecalls counts the storage calls made for a request, so
+// that a handler that looks items up one by one shows up in the logs and
+// metrics before it shows up in the latencies.
+package storagecalls
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type contextKey struct{}
+
+// Counter is the number of storage calls made for a request. It is safe
+// for concurrent use, since a handler may call the store from several
+// goroutines.
+type Counter struct {
+	calls atomic.Int64
+}
+
+// Track returns ctx carrying a Counter, and the Counter. When ctx already
+// carries one, it is returned as is, so every middleware of a request
+// shares the one the outermost started.
+func Track(ctx context.Context) (context.Context, *Counter) {
+	if c, ok := ctx.Value(contextKey{}).(*Counter); ok {
+		return ctx, c
+	}
+	c := &Counter{}
+	return context.WithValue(ctx, contextKey{}, c), c
+}
+
+// Add counts a storage call made with ctx. It does nothing when ctx
+// carries no Counter, as for background jobs.
+func Add(ctx context.Context) {
+	if c, ok := ctx.Value(contextKey{}).(*Counter); ok {
+		c.calls.Add(1)
+	}
+}
+
+// Calls returns the number of calls counted so far.
+func (c *Counter) Calls() int64 {
+	return c.calls.Load()
+}
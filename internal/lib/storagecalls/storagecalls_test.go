@@ -0,0 +1,33 @@
+package storagecalls_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"quotes-service/internal/lib/storagecalls"
+)
+
+func TestTrack(t *testing.T) {
+	// Without a counter there is nothing to count into.
+	storagecalls.Add(context.Background())
+
+	ctx, outer := storagecalls.Track(context.Background())
+	ctx, inner := storagecalls.Track(ctx)
+	if inner != outer {
+		t.Fatal("expected a second Track to reuse the counter")
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			storagecalls.Add(ctx)
+		}()
+	}
+	wg.Wait()
+	if got := outer.Calls(); got != 10 {
+		t.Fatalf("expected 10 calls, got %d", got)
+	}
+}
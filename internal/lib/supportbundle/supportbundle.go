@@ -0,0 +1,245 @@
+// Package supportbundle assembles the zip an operator attaches to a bug
+// report: the effective configuration with its secrets masked, the version
+// and build, the last log lines, runtime and storage statistics, and the
+// slowest and last failed requests.
+package supportbundle
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"quotes-service/internal/lib/logger/logring"
+	"quotes-service/internal/lib/recentfailures"
+	"quotes-service/internal/lib/redact"
+	"quotes-service/internal/lib/slowest"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// Entries of a bundle, in the order they are written.
+const (
+	EntryConfig   = "config.json"
+	EntryVersion  = "version.json"
+	EntryRuntime  = "runtime.json"
+	EntryStorage  = "storage.json"
+	EntryRequests = "requests.json"
+	EntryLogs     = "logs.jsonl"
+	EntryManifest = "manifest.json"
+)
+
+// Store is what a bundle reads of the storage backend. A store that is a
+// storage.Compactor also reports its memory.
+type Store interface {
+	Version(ctx context.Context) (uint64, error)
+}
+
+// Bundle is what goes into a support bundle. The logs and the requests
+// are left out while their source is nil.
+type Bundle struct {
+	// Config is the effective configuration. It is masked with
+	// redact.Value, so it never carries a secret.
+	Config any
+	// Version is the configured version of the service.
+	Version  string
+	Store    Store
+	Logs     *logring.Ring
+	Slow     *slowest.Window
+	Failures *recentfailures.Ring
+	// Requests is how many of the slowest requests to include.
+	Requests int
+	// Now is the time source, time.Now when nil.
+	Now func() time.Time
+}
+
+type version struct {
+	Version   string            `json:"version"`
+	GoVersion string            `json:"go_version"`
+	OS        string            `json:"os"`
+	Arch      string            `json:"arch"`
+	Module    string            `json:"module,omitempty"`
+	Build     map[string]string `json:"build,omitempty"`
+}
+
+type runtimeStats struct {
+	Goroutines int      `json:"goroutines"`
+	GOMAXPROCS int      `json:"gomaxprocs"`
+	NumCPU     int      `json:"num_cpu"`
+	Memory     memStats `json:"memory"`
+}
+
+type memStats struct {
+	Alloc        uint64    `json:"alloc_bytes"`
+	TotalAlloc   uint64    `json:"total_alloc_bytes"`
+	Sys          uint64    `json:"sys_bytes"`
+	HeapAlloc    uint64    `json:"heap_alloc_bytes"`
+	HeapInuse    uint64    `json:"heap_inuse_bytes"`
+	HeapIdle     uint64    `json:"heap_idle_bytes"`
+	HeapReleased uint64    `json:"heap_released_bytes"`
+	HeapObjects  uint64    `json:"heap_objects"`
+	StackInuse   uint64    `json:"stack_inuse_bytes"`
+	NumGC        uint32    `json:"num_gc"`
+	PauseTotal   string    `json:"pause_total"`
+	LastGC       time.Time `json:"last_gc,omitzero"`
+}
+
+type storageStats struct {
+	Backend string                `json:"backend"`
+	Version uint64                `json:"version"`
+	Memory  *models.StorageReport `json:"memory,omitempty"`
+}
+
+type requests struct {
+	Slowest *models.SlowRequests `json:"slowest,omitempty"`
+	Failed  []models.SlowRequest `json:"failed,omitempty"`
+}
+
+type manifest struct {
+	CreatedAt time.Time `json:"created_at"`
+	Entries   []string  `json:"entries"`
+	// Errors maps the entries that could not be assembled to why.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// Write streams the bundle to w as a zip. An entry that cannot be
+// assembled, such as the storage statistics of a failing store, is
+// replaced by its error, listed in the manifest, and the rest still goes
+// out; only a failure to write to w is returned.
+func (b *Bundle) Write(ctx context.Context, w io.Writer) error {
+	now := time.Now
+	if b.Now != nil {
+		now = b.Now
+	}
+	m := manifest{CreatedAt: now().UTC(), Errors: make(map[string]string)}
+	zw := zip.NewWriter(w)
+
+	entries := []struct {
+		name  string
+		build func() (any, error)
+	}{
+		{EntryConfig, func() (any, error) { return redact.Value(b.Config), nil }},
+		{EntryVersion, func() (any, error) { return b.version(), nil }},
+		{EntryRuntime, func() (any, error) { return readRuntime(), nil }},
+		{EntryStorage, func() (any, error) { return b.storage(ctx) }},
+		{EntryRequests, func() (any, error) { return b.requests(), nil }},
+	}
+	for _, e := range entries {
+		v, err := e.build()
+		if err != nil {
+			m.Errors[e.name] = err.Error()
+			v = map[string]string{"error": err.Error()}
+		}
+		if err := writeJSON(zw, e.name, v, m.CreatedAt); err != nil {
+			return err
+		}
+		m.Entries = append(m.Entries, e.name)
+	}
+	if b.Logs != nil {
+		f, err := create(zw, EntryLogs, m.CreatedAt)
+		if err != nil {
+			return err
+		}
+		for _, line := range b.Logs.Lines() {
+			if _, err := f.Write(line); err != nil {
+				return err
+			}
+		}
+		m.Entries = append(m.Entries, EntryLogs)
+	}
+	m.Entries = append(m.Entries, EntryManifest)
+	if err := writeJSON(zw, EntryManifest, m, m.CreatedAt); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func (b *Bundle) version() version {
+	v := version{
+		Version:   b.Version,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		v.Module = info.Main.Path + "@" + info.Main.Version
+		v.Build = make(map[string]string, len(info.Settings))
+		for _, s := range info.Settings {
+			v.Build[s.Key] = s.Value
+		}
+	}
+	return v
+}
+
+func readRuntime() runtimeStats {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	stats := runtimeStats{
+		Goroutines: runtime.NumGoroutine(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		NumCPU:     runtime.NumCPU(),
+		Memory: memStats{
+			Alloc:        ms.Alloc,
+			TotalAlloc:   ms.TotalAlloc,
+			Sys:          ms.Sys,
+			HeapAlloc:    ms.HeapAlloc,
+			HeapInuse:    ms.HeapInuse,
+			HeapIdle:     ms.HeapIdle,
+			HeapReleased: ms.HeapReleased,
+			HeapObjects:  ms.HeapObjects,
+			StackInuse:   ms.StackInuse,
+			NumGC:        ms.NumGC,
+			PauseTotal:   time.Duration(ms.PauseTotalNs).String(),
+		},
+	}
+	if ms.LastGC > 0 {
+		stats.Memory.LastGC = time.Unix(0, int64(ms.LastGC)).UTC()
+	}
+	return stats
+}
+
+func (b *Bundle) storage(ctx context.Context) (storageStats, error) {
+	stats := storageStats{Backend: storage.Backend(b.Store)}
+	v, err := b.Store.Version(ctx)
+	if err != nil {
+		return stats, err
+	}
+	stats.Version = v
+	if c, ok := b.Store.(storage.Compactor); ok {
+		report, err := c.MemoryReport(ctx)
+		if err != nil {
+			return stats, err
+		}
+		stats.Memory = &report
+	}
+	return stats, nil
+}
+
+func (b *Bundle) requests() requests {
+	var r requests
+	if b.Slow != nil {
+		slow := b.Slow.Report(b.Requests)
+		r.Slowest = &slow
+	}
+	if b.Failures != nil {
+		r.Failed = b.Failures.Report()
+	}
+	return r
+}
+
+func create(zw *zip.Writer, name string, modified time.Time) (io.Writer, error) {
+	return zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+}
+
+func writeJSON(zw *zip.Writer, name string, v any, modified time.Time) error {
+	f, err := create(zw, name, modified)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
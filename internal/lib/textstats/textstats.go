@@ -0,0 +1,137 @@
+package textstats
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"unicode/utf8"
+
+	"quotes-service/internal/lib/tokenizer"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// DefaultStopwords is a small list of common English words left out of the
+// top words ranking.
+var DefaultStopwords = []string{
+	"a", "about", "all", "an", "and", "are", "as", "at", "be", "but", "by",
+	"do", "for", "from", "have", "he", "her", "his", "i", "if", "in", "is",
+	"it", "its", "me", "my", "no", "not", "of", "on", "or", "our", "she",
+	"so", "that", "the", "their", "them", "there", "they", "this", "to",
+	"was", "we", "what", "when", "which", "who", "will", "with", "you", "your",
+}
+
+// Source provides the quotes to analyze and a counter that changes on
+// every mutation so results can be cached between mutations.
+type Source interface {
+	Version(ctx context.Context) (uint64, error)
+	GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error)
+}
+
+// Analyzer computes corpus statistics and caches the result until the
+// source version changes.
+type Analyzer struct {
+	stopwords map[string]struct{}
+	topN      int
+
+	mu      sync.Mutex
+	cached  bool
+	version uint64
+	stats   models.TextStats
+}
+
+func New(stopwords []string, topN int) *Analyzer {
+	set := make(map[string]struct{}, len(stopwords))
+	for _, word := range stopwords {
+		for _, token := range tokenizer.Tokens(word) {
+			set[token] = struct{}{}
+		}
+	}
+	return &Analyzer{
+		stopwords: set,
+		topN:      topN,
+	}
+}
+
+// Stats returns the statistics for the current contents of src, recomputing
+// them only when src reports a new version.
+func (a *Analyzer) Stats(ctx context.Context, src Source) (models.TextStats, error) {
+	// The version is read before the quotes so a mutation racing with the
+	// read at worst causes one extra recomputation, never a stale hit.
+	version, err := src.Version(ctx)
+	if err != nil {
+		return models.TextStats{}, err
+	}
+
+	a.mu.Lock()
+	if a.cached && a.version == version {
+		stats := a.stats
+		a.mu.Unlock()
+		return stats, nil
+	}
+	a.mu.Unlock()
+
+	quotes, err := src.GetAllQuotes(ctx, storage.QuoteFilter{})
+	if err != nil {
+		return models.TextStats{}, err
+	}
+	stats := a.Compute(quotes)
+
+	a.mu.Lock()
+	a.cached = true
+	a.version = version
+	a.stats = stats
+	a.mu.Unlock()
+
+	return stats, nil
+}
+
+// Compute calculates the statistics for quotes without caching. Lengths are
+// measured in characters and words are split with the shared tokenizer.
+func (a *Analyzer) Compute(quotes []models.Quote) models.TextStats {
+	stats := models.TextStats{
+		TotalQuotes: len(quotes),
+		TopWords:    []models.WordCount{},
+	}
+	if len(quotes) == 0 {
+		return stats
+	}
+
+	counts := make(map[string]int)
+	totalLength := 0
+	for _, q := range quotes {
+		length := utf8.RuneCountInString(q.Text)
+		totalLength += length
+
+		if stats.Longest == nil || length > stats.Longest.Length {
+			stats.Longest = &models.QuoteLength{ID: q.ID, PublicID: q.PublicID, Length: length}
+		}
+		if stats.Shortest == nil || length < stats.Shortest.Length {
+			stats.Shortest = &models.QuoteLength{ID: q.ID, PublicID: q.PublicID, Length: length}
+		}
+
+		tokens := tokenizer.Tokens(q.Text)
+		stats.TotalWords += len(tokens)
+		for _, token := range tokens {
+			if _, skip := a.stopwords[token]; !skip {
+				counts[token]++
+			}
+		}
+	}
+	stats.AverageLength = float64(totalLength) / float64(len(quotes))
+
+	for word, count := range counts {
+		stats.TopWords = append(stats.TopWords, models.WordCount{Word: word, Count: count})
+	}
+	sort.Slice(stats.TopWords, func(i, j int) bool {
+		if stats.TopWords[i].Count != stats.TopWords[j].Count {
+			return stats.TopWords[i].Count > stats.TopWords[j].Count
+		}
+		return stats.TopWords[i].Word < stats.TopWords[j].Word
+	})
+	if a.topN > 0 && len(stats.TopWords) > a.topN {
+		stats.TopWords = stats.TopWords[:a.topN]
+	}
+
+	return stats
+}
@@ -0,0 +1,92 @@
+package textstats_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"quotes-service/internal/lib/textstats"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+type fakeSource struct {
+	version uint64
+	quotes  []models.Quote
+	reads   int
+}
+
+func (f *fakeSource) Version(ctx context.Context) (uint64, error) {
+	return f.version, nil
+}
+
+func (f *fakeSource) GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error) {
+	f.reads++
+	return f.quotes, nil
+}
+
+func TestComputeUnicode(t *testing.T) {
+	analyzer := textstats.New(textstats.DefaultStopwords, 3)
+
+	stats := analyzer.Compute([]models.Quote{
+		{ID: 1, Text: "Рукописи не горят, рукописи живут."},
+		{ID: 2, Text: "Ça va, ça va bien."},
+		{ID: 3, Text: "The end"},
+	})
+
+	if stats.TotalQuotes != 3 {
+		t.Errorf("expected 3 quotes, got %d", stats.TotalQuotes)
+	}
+	if stats.TotalWords != 12 {
+		t.Errorf("expected 12 words, got %d", stats.TotalWords)
+	}
+	if stats.Longest == nil || stats.Longest.ID != 1 || stats.Longest.Length != 34 {
+		t.Errorf("unexpected longest quote %+v", stats.Longest)
+	}
+	if stats.Shortest == nil || stats.Shortest.ID != 3 || stats.Shortest.Length != 7 {
+		t.Errorf("unexpected shortest quote %+v", stats.Shortest)
+	}
+
+	expected := []models.WordCount{
+		{Word: "va", Count: 2},
+		{Word: "ça", Count: 2},
+		{Word: "рукописи", Count: 2},
+	}
+	if !reflect.DeepEqual(stats.TopWords, expected) {
+		t.Errorf("expected top words %v, got %v", expected, stats.TopWords)
+	}
+}
+
+func TestComputeCustomStopwords(t *testing.T) {
+	analyzer := textstats.New([]string{"ça", "va"}, 1)
+
+	stats := analyzer.Compute([]models.Quote{{ID: 1, Text: "Ça va, ça va bien."}})
+	if len(stats.TopWords) != 1 || stats.TopWords[0].Word != "bien" {
+		t.Errorf("expected custom stopwords to be skipped, got %v", stats.TopWords)
+	}
+}
+
+func TestStatsCachedUntilVersionChanges(t *testing.T) {
+	analyzer := textstats.New(nil, 5)
+	src := &fakeSource{version: 1, quotes: []models.Quote{{ID: 1, Text: "one"}}}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := analyzer.Stats(ctx, src); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if src.reads != 1 {
+		t.Errorf("expected a single read while version is unchanged, got %d", src.reads)
+	}
+
+	src.version = 2
+	src.quotes = append(src.quotes, models.Quote{ID: 2, Text: "two"})
+	stats, err := analyzer.Stats(ctx, src)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if src.reads != 2 || stats.TotalQuotes != 2 {
+		t.Errorf("expected recomputation after mutation, reads %d, stats %+v", src.reads, stats)
+	}
+}
@@ -0,0 +1,29 @@
+package tokenizer
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokens splits text into lowercased words. A word is a run of letters,
+// digits and combining marks, so non-ASCII scripts are kept intact.
+func Tokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r)
+	})
+}
+
+// Unique returns the distinct tokens of text in order of first appearance.
+func Unique(text string) []string {
+	tokens := Tokens(text)
+	seen := make(map[string]struct{}, len(tokens))
+	result := tokens[:0]
+	for _, token := range tokens {
+		if _, ok := seen[token]; ok {
+			continue
+		}
+		seen[token] = struct{}{}
+		result = append(result, token)
+	}
+	return result
+}
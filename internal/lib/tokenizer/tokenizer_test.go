@@ -0,0 +1,43 @@
+package tokenizer_test
+
+import (
+	"reflect"
+	"testing"
+
+	"quotes-service/internal/lib/tokenizer"
+)
+
+func TestTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{name: "ascii", text: "Be yourself; everyone else is already taken.", expected: []string{"be", "yourself", "everyone", "else", "is", "already", "taken"}},
+		{name: "cyrillic", text: "Рукописи не горят!", expected: []string{"рукописи", "не", "горят"}},
+		{name: "accents", text: "Je pense, donc je suis — Café crème", expected: []string{"je", "pense", "donc", "je", "suis", "café", "crème"}},
+		{name: "combining marks", text: "cafe\u0301 noir", expected: []string{"cafe\u0301", "noir"}},
+		{name: "digits", text: "Catch-22 in 1961", expected: []string{"catch", "22", "in", "1961"}},
+		{name: "empty", text: " ... ", expected: []string{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tokenizer.Tokens(tc.text)
+			if len(got) == 0 && len(tc.expected) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestUnique(t *testing.T) {
+	got := tokenizer.Unique("To be or not to be")
+	expected := []string{"to", "be", "or", "not"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
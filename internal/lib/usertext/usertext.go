@@ -0,0 +1,96 @@
+// Package usertext limits how much of the text clients send, such as a
+// quote, an author or a query parameter, each place the service writes to
+// may repeat. Every such place cuts the text through it, so no feature
+// decides on its own how much to leak.
+package usertext
+
+import "sync/atomic"
+
+// Sink is a place text flows out to.
+type Sink int
+
+const (
+	Log Sink = iota
+	Error
+	Audit
+	Webhook
+)
+
+// Unlimited is a limit that keeps the whole text.
+const Unlimited = -1
+
+// Policy is how many characters of a text each sink may carry. Zero keeps
+// none of it and Unlimited all of it.
+type Policy struct {
+	Log     int
+	Error   int
+	Audit   int
+	Webhook int
+}
+
+// Default is the policy until Set changes it: short previews in the logs,
+// enough of a value to recognize it in errors and the audit trail, and
+// whole quotes for webhook subscribers.
+var Default = Policy{Log: 64, Error: 256, Audit: 256, Webhook: Unlimited}
+
+var current atomic.Pointer[Policy]
+
+func init() {
+	Set(Default)
+}
+
+// Set makes p the policy of Cut.
+func Set(p Policy) {
+	current.Store(&p)
+}
+
+// Current returns the policy of Cut.
+func Current() Policy {
+	return *current.Load()
+}
+
+// Cut returns as much of s as the current policy lets sink carry.
+func Cut(sink Sink, s string) string {
+	return Current().Cut(sink, s)
+}
+
+// Limit returns how many characters sink may carry.
+func (p Policy) Limit(sink Sink) int {
+	switch sink {
+	case Log:
+		return p.Log
+	case Error:
+		return p.Error
+	case Audit:
+		return p.Audit
+	case Webhook:
+		return p.Webhook
+	default:
+		return 0
+	}
+}
+
+// Cut returns as much of s as p lets sink carry.
+func (p Policy) Cut(sink Sink, s string) string {
+	limit := p.Limit(sink)
+	if limit == Unlimited {
+		return s
+	}
+	return Truncate(s, limit)
+}
+
+// Truncate cuts s to at most n characters and marks the cut with "…". It
+// never splits a UTF-8 sequence.
+func Truncate(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	chars := 0
+	for i := range s {
+		if chars == n {
+			return s[:i] + "…"
+		}
+		chars++
+	}
+	return s
+}
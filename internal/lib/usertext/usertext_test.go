@@ -0,0 +1,47 @@
+package usertext_test
+
+import (
+	"strings"
+	"testing"
+
+	"quotes-service/internal/lib/usertext"
+)
+
+func TestCut(t *testing.T) {
+	defer usertext.Set(usertext.Default)
+	usertext.Set(usertext.Policy{Log: 4, Error: 8, Audit: 0, Webhook: usertext.Unlimited})
+
+	text := "Жизнь коротка, искусство вечно."
+	tests := []struct {
+		sink usertext.Sink
+		want string
+	}{
+		{usertext.Log, "Жизн…"},
+		{usertext.Error, "Жизнь ко…"},
+		{usertext.Audit, ""},
+		{usertext.Webhook, text},
+	}
+	for _, tc := range tests {
+		if got := usertext.Cut(tc.sink, text); got != tc.want {
+			t.Fatalf("sink %d: expected %q, got %q", tc.sink, tc.want, got)
+		}
+	}
+}
+
+func TestDefault(t *testing.T) {
+	long := strings.Repeat("a", 20000)
+	for _, tc := range []struct {
+		sink usertext.Sink
+		max  int
+	}{
+		{usertext.Log, 64},
+		{usertext.Error, 256},
+		{usertext.Audit, 256},
+		{usertext.Webhook, len(long)},
+	} {
+		got := usertext.Cut(tc.sink, long)
+		if n := len(strings.TrimSuffix(got, "…")); n != tc.max {
+			t.Fatalf("sink %d: expected %d characters, got %d", tc.sink, tc.max, n)
+		}
+	}
+}
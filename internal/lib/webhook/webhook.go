@@ -0,0 +1,173 @@
+// Package webhook delivers events as signed JSON POST requests to the
+// configured endpoints.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	// EventHeader carries the event type.
+	EventHeader = "X-Webhook-Event"
+	// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the body
+	// keyed with the endpoint secret. It is omitted without a secret.
+	SignatureHeader = "X-Webhook-Signature"
+
+	maxAttempts = 3
+)
+
+// Endpoint is a receiver of webhook deliveries.
+type Endpoint struct {
+	URL    string
+	Secret string
+}
+
+// Event is the envelope of every delivery.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// NewEvent returns an event of eventType with a fresh random ID.
+func NewEvent(eventType string, data any) Event {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return Event{
+		ID:        hex.EncodeToString(id[:]),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+}
+
+type Dispatcher struct {
+	log       *slog.Logger
+	client    *http.Client
+	endpoints []Endpoint
+	backoff   time.Duration
+}
+
+type Option func(*Dispatcher)
+
+// WithHTTPClient replaces the client used for deliveries.
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+// WithBackoff sets the pause before the first retry, mainly for tests.
+// Later retries wait twice as long as the one before.
+func WithBackoff(backoff time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.backoff = backoff
+	}
+}
+
+func New(log *slog.Logger, endpoints []Endpoint, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		log:       log.With(slog.String("op", "webhook.Dispatcher")),
+		client:    &http.Client{Timeout: 10 * time.Second},
+		endpoints: endpoints,
+		backoff:   time.Second,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Dispatch delivers event to every endpoint. A delivery that fails with a
+// network error, 429 or 5xx is retried; the returned error joins the
+// failures of all endpoints that never accepted the event.
+func (d *Dispatcher) Dispatch(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("webhook: encode event: %w", err)
+	}
+
+	var errs []error
+	for _, endpoint := range d.endpoints {
+		if err := d.deliver(ctx, endpoint, event.Type, body); err != nil {
+			d.log.WarnContext(ctx, "webhook delivery failed",
+				slog.String("url", endpoint.URL),
+				slog.String("event", event.Type),
+				slog.String("event_id", event.ID),
+				slog.String("error", err.Error()),
+			)
+			errs = append(errs, fmt.Errorf("%s: %w", endpoint.URL, err))
+			continue
+		}
+		d.log.InfoContext(ctx, "webhook delivered", slog.String("url", endpoint.URL), slog.String("event", event.Type), slog.String("event_id", event.ID))
+	}
+	return errors.Join(errs...)
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, endpoint Endpoint, eventType string, body []byte) error {
+	backoff := d.backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		var retry bool
+		retry, err = d.post(ctx, endpoint, eventType, body)
+		if err == nil || !retry || attempt == maxAttempts {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// post makes one delivery attempt and reports whether a failure is worth
+// retrying.
+func (d *Dispatcher) post(ctx context.Context, endpoint Endpoint, eventType string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, eventType)
+	if endpoint.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(endpoint.Secret, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("endpoint returned %s", resp.Status)
+}
+
+// Sign returns the SignatureHeader value for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
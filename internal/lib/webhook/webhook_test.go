@@ -0,0 +1,115 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"quotes-service/internal/lib/webhook"
+)
+
+func TestDispatch(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantAttempts int32
+		wantErr      bool
+	}{
+		{name: "accepted", statuses: []int{http.StatusNoContent}, wantAttempts: 1},
+		{name: "retried after server error", statuses: []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK}, wantAttempts: 3},
+		{name: "gives up after three attempts", statuses: []int{500, 500, 500, 500}, wantAttempts: 3, wantErr: true},
+		{name: "client error is not retried", statuses: []int{http.StatusBadRequest, http.StatusOK}, wantAttempts: 1, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			var gotBody []byte
+			var gotHeaders http.Header
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := attempts.Add(1)
+				gotBody, _ = io.ReadAll(r.Body)
+				gotHeaders = r.Header.Clone()
+				w.WriteHeader(tc.statuses[n-1])
+			}))
+			defer srv.Close()
+
+			d := webhook.New(logger, []webhook.Endpoint{{URL: srv.URL, Secret: "s3cret"}}, webhook.WithBackoff(time.Millisecond))
+			event := webhook.NewEvent("quote.scheduled", map[string]string{"text": "hi"})
+			err := d.Dispatch(context.Background(), event)
+
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if attempts.Load() != tc.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", tc.wantAttempts, attempts.Load())
+			}
+
+			if got := gotHeaders.Get(webhook.EventHeader); got != "quote.scheduled" {
+				t.Errorf("expected event header, got %q", got)
+			}
+			if got := gotHeaders.Get(webhook.SignatureHeader); got != webhook.Sign("s3cret", gotBody) {
+				t.Errorf("signature %q does not match the body", got)
+			}
+			var decoded webhook.Event
+			if err := json.Unmarshal(gotBody, &decoded); err != nil || decoded.ID != event.ID || decoded.Type != event.Type {
+				t.Errorf("unexpected body %s", gotBody)
+			}
+		})
+	}
+}
+
+func TestDispatchAllEndpoints(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	var delivered atomic.Int32
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(webhook.SignatureHeader) != "" {
+			t.Error("expected no signature without a secret")
+		}
+		delivered.Add(1)
+	}))
+	defer ok.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	}))
+	defer broken.Close()
+
+	d := webhook.New(logger, []webhook.Endpoint{{URL: broken.URL}, {URL: ok.URL}}, webhook.WithBackoff(time.Millisecond))
+	err := d.Dispatch(context.Background(), webhook.NewEvent("test", nil))
+
+	if err == nil || !strings.Contains(err.Error(), broken.URL) {
+		t.Errorf("expected an error naming the broken endpoint, got %v", err)
+	}
+	if delivered.Load() != 1 {
+		t.Errorf("expected the healthy endpoint to still get the event, got %d deliveries", delivered.Load())
+	}
+}
+
+func TestDispatchStopsOnCancel(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	d := webhook.New(logger, []webhook.Endpoint{{URL: srv.URL}}, webhook.WithBackoff(time.Hour))
+
+	start := time.Now()
+	if err := d.Dispatch(ctx, webhook.NewEvent("test", nil)); err == nil {
+		t.Fatal("expected an error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the retry wait to end with the context, took %s", elapsed)
+	}
+}
@@ -1,19 +1,32 @@
 package models
 
+import "time"
+
 type AddQuoteRequest struct {
-	Text   string `json:"text"`
-	Author string `json:"author"`
+	Text      string `json:"text"`
+	Author    string `json:"author"`
+	Weight    *int   `json:"weight,omitempty"`
+	Lang      string `json:"lang,omitempty"`
+	Source    string `json:"source,omitempty"`
+	SourceURL string `json:"source_url,omitempty"`
 }
 
 type AddQuoteResponse struct {
-	Status string `json:"status"`
-	ID     int64  `json:"id"`
-	Text   string `json:"text"`
-	Author string `json:"author"`
+	Status       string `json:"status"`
+	ID           int64  `json:"id"`
+	PublicID     string `json:"public_id,omitempty"`
+	Text         string `json:"text"`
+	Author       string `json:"author"`
+	Weight       int    `json:"weight,omitempty"`
+	Lang         string `json:"lang,omitempty"`
+	LangDetected bool   `json:"lang_detected,omitempty"`
+	Source       string `json:"source,omitempty"`
+	SourceURL    string `json:"source_url,omitempty"`
 }
 
 type ErrorResponse struct {
 	Status string   `json:"status"`
+	Code   string   `json:"code"`
 	Error  string   `json:"error"`
 	Fields []string `json:"fields,omitempty"`
 }
@@ -28,8 +41,817 @@ type GenericMessageResponse struct {
 	Message string `json:"message"`
 }
 
+// Problem is an RFC 7807 problem document, the error response of requests
+// that opt out of the envelope. Code and Fields carry what ErrorResponse
+// does.
+type Problem struct {
+	Type     string   `json:"type"`
+	Title    string   `json:"title"`
+	Status   int      `json:"status"`
+	Detail   string   `json:"detail"`
+	Instance string   `json:"instance,omitempty"`
+	Code     string   `json:"code"`
+	Fields   []string `json:"fields,omitempty"`
+}
+
 type Quote struct {
-	ID     int64  `json:"id"`
+	ID int64 `json:"id"`
+	// PublicID is a UUID or ULID assigned at creation when public IDs are
+	// enabled. It is accepted wherever ID is.
+	PublicID string `json:"public_id,omitempty"`
+	Text     string `json:"text"`
+	Author   string `json:"author"`
+	// AuthorKey is the form of Author that author lookups match on, as
+	// derived by authorname.Key. The store sets it from Author.
+	AuthorKey string `json:"author_key,omitempty"`
+	Weight    int    `json:"weight,omitempty"`
+	Lang      string `json:"lang,omitempty"`
+	// LangDetected is set when Lang was guessed rather than provided.
+	LangDetected bool   `json:"lang_detected,omitempty"`
+	Source       string `json:"source,omitempty"`
+	SourceURL    string `json:"source_url,omitempty"`
+	// Tags are free-form labels, as carried by imported datasets.
+	Tags      []string  `json:"tags,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+	// Version starts at 1 and is bumped on every update.
+	Version int64 `json:"version,omitempty"`
+}
+
+type PopularQuote struct {
+	Quote
+	Served int64 `json:"served"`
+}
+
+type SimilarQuote struct {
+	Quote
+	Score float64 `json:"score"`
+}
+
+type TextStats struct {
+	TotalQuotes   int          `json:"total_quotes"`
+	TotalWords    int          `json:"total_words"`
+	AverageLength float64      `json:"average_length"`
+	Longest       *QuoteLength `json:"longest,omitempty"`
+	Shortest      *QuoteLength `json:"shortest,omitempty"`
+	TopWords      []WordCount  `json:"top_words"`
+}
+
+type QuoteLength struct {
+	ID       int64  `json:"id"`
+	PublicID string `json:"public_id,omitempty"`
+	Length   int    `json:"length"`
+}
+
+type WordCount struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+type Collection struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	QuoteCount  int    `json:"quote_count"`
+}
+
+type CollectionWithQuotes struct {
+	Collection
+	Quotes []Quote `json:"quotes"`
+}
+
+type CreateCollectionRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// CollectionQuotesRequest names quotes by ID, public ID or both.
+type CollectionQuotesRequest struct {
+	QuoteIDs       []int64  `json:"quote_ids"`
+	PublicQuoteIDs []string `json:"public_quote_ids,omitempty"`
+}
+
+type QuotePage struct {
+	Quotes []Quote `json:"quotes"`
+	Total  int     `json:"total"`
+	Limit  int     `json:"limit"`
+	Offset int     `json:"offset"`
+}
+
+// UpdateQuoteRequest is the body of PUT and PATCH /quotes/{id}. PUT requires
+// text and author and resets omitted fields; PATCH changes only the fields
+// present.
+type UpdateQuoteRequest struct {
+	Text      *string `json:"text"`
+	Author    *string `json:"author"`
+	Weight    *int    `json:"weight"`
+	Lang      *string `json:"lang"`
+	Source    *string `json:"source"`
+	SourceURL *string `json:"source_url"`
+}
+
+// AuthorSummary describes the quotes by one author, that is by every
+// spelling with the same match key. Name is the display spelling; Variants
+// lists all of them when there is more than one.
+type AuthorSummary struct {
+	Name         string    `json:"name"`
+	Variants     []string  `json:"variants,omitempty"`
+	QuoteCount   int       `json:"quote_count"`
+	FirstAddedAt time.Time `json:"first_added_at,omitzero"`
+	LastAddedAt  time.Time `json:"last_added_at,omitzero"`
+	// Metadata is what the author enricher knows of the author, if
+	// anything.
+	Metadata *AuthorMetadata `json:"metadata,omitempty"`
+}
+
+// AuthorMetadata describes an author beyond their quotes. Every field is
+// optional; years before the common era are negative.
+type AuthorMetadata struct {
+	ImageURL  string `json:"image_url,omitempty"`
+	BirthYear int    `json:"birth_year,omitempty"`
+	DeathYear int    `json:"death_year,omitempty"`
+	WikiURL   string `json:"wiki_url,omitempty"`
+}
+
+type MergeAuthorsRequest struct {
+	Into string   `json:"into"`
+	From []string `json:"from"`
+}
+
+type MergeAuthorsResult struct {
+	Into string `json:"into"`
+	// Merged maps each source name to the number of quotes moved from it.
+	Merged map[string]int `json:"merged"`
+	Total  int            `json:"total"`
+	BulkProgress
+}
+
+// BulkProgress reports how far one call of a bulk operation got. Until the
+// operation is done, the call is answered 202 and the operation goes on
+// when the client calls again with ResumeToken.
+type BulkProgress struct {
+	Processed   int    `json:"processed"`
+	Done        bool   `json:"done"`
+	ResumeToken string `json:"resume_token,omitempty"`
+}
+
+// DeleteAuthorQuotesResult is the response to DELETE
+// /authors/{name}/quotes. Processed is the number of quotes deleted.
+type DeleteAuthorQuotesResult struct {
+	Author string `json:"author"`
+	BulkProgress
+}
+
+type SchemaRef struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// FaultSettings is the wire form of the injected storage faults. Latency
+// is a Go duration string such as "250ms".
+type FaultSettings struct {
+	ErrorRate float64  `json:"error_rate"`
+	Latency   string   `json:"latency"`
+	Methods   []string `json:"methods"`
+}
+
+// FeatureFlag is the state of an optional feature. A feature that is not
+// Dynamic changes only with the config, on restart.
+type FeatureFlag struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Dynamic bool   `json:"dynamic"`
+}
+
+// SetFeatureRequest is the body of PUT /admin/features/{name}.
+type SetFeatureRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// APIKey is an API key created through /admin/keys. Its secret is shown
+// only once, in CreatedAPIKey; afterwards only a hash of it is kept. An
+// empty Role leaves the principal the role the config gives it.
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Role       string     `json:"role,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// CreatedAPIKey is the response to POST /admin/keys.
+type CreatedAPIKey struct {
+	APIKey
+	Secret string `json:"secret"`
+}
+
+// CreateAPIKeyRequest is the body of POST /admin/keys. Name is the
+// principal the key authenticates as.
+type CreateAPIKeyRequest struct {
+	Name string `json:"name"`
+	Role string `json:"role,omitempty"`
+}
+
+// AuditEntry records a write made through the API: who made it, how it
+// was authenticated, the route it went to, the quote it was about and how
+// it ended. Principal is empty for anonymous writes.
+type AuditEntry struct {
+	Seq        uint64    `json:"seq"`
+	Time       time.Time `json:"time"`
+	Principal  string    `json:"principal,omitempty"`
+	AuthMethod string    `json:"auth_method,omitempty"`
+	Operation  string    `json:"operation"`
+	QuoteID    int64     `json:"quote_id,omitempty"`
+	Status     int       `json:"status"`
+	RequestID  string    `json:"request_id,omitempty"`
+}
+
+// AuditPage is a page of GET /admin/audit.
+type AuditPage struct {
+	Entries []AuditEntry `json:"entries"`
+	Total   int          `json:"total"`
+	Limit   int          `json:"limit"`
+	Offset  int          `json:"offset"`
+}
+
+// DuplicateGroup is quotes that differ only in case, punctuation, spacing
+// or typography, oldest first. Texts holds the start of the text of each
+// of QuoteIDs.
+type DuplicateGroup struct {
+	Key      string   `json:"key"`
+	QuoteIDs []int64  `json:"quote_ids"`
+	Texts    []string `json:"texts"`
+}
+
+// DuplicatePage is a page of GET /admin/duplicates.
+type DuplicatePage struct {
+	Groups []DuplicateGroup `json:"groups"`
+	Total  int              `json:"total"`
+	Limit  int              `json:"limit"`
+	Offset int              `json:"offset"`
+}
+
+// ResolveDuplicatesRequest is the body of POST /admin/duplicates/resolve.
+type ResolveDuplicatesRequest struct {
+	Strategy string `json:"strategy"`
+}
+
+// DuplicateResolution reports what POST /admin/duplicates/resolve did.
+// Skipped counts the groups left alone because they changed during the
+// resolution.
+type DuplicateResolution struct {
+	Strategy string `json:"strategy"`
+	Groups   int    `json:"groups"`
+	Resolved int    `json:"resolved"`
+	Deleted  int    `json:"deleted"`
+	Skipped  int    `json:"skipped"`
+	Atomic   bool   `json:"atomic"`
+}
+
+// Me is the response to GET /me: who the caller is and how much of its
+// rate limit is left. RateLimits is empty for a caller without a limit.
+type Me struct {
+	Principal  string       `json:"principal"`
+	Role       string       `json:"role"`
+	AuthMethod string       `json:"auth_method"`
+	RateLimits []QuotaUsage `json:"rate_limits"`
+}
+
+// QuotaUsage is the state of a rate-limit bucket the caller's requests
+// count against. Scope is "principal", or "ip" for the bucket of the
+// caller's address. Remaining requests may be made right away; the bucket
+// refills at RequestsPerSecond and is full again at ResetAt.
+type QuotaUsage struct {
+	Scope             string    `json:"scope"`
+	RequestsPerSecond float64   `json:"requests_per_second"`
+	Burst             int       `json:"burst"`
+	Remaining         int       `json:"remaining"`
+	ResetAt           time.Time `json:"reset_at"`
+}
+
+// SyncStatus is the state of the external quote sync. Interval is a Go
+// duration string.
+type SyncStatus struct {
+	SourceURL string      `json:"source_url"`
+	Interval  string      `json:"interval"`
+	Running   bool        `json:"running"`
+	LastRun   *SyncReport `json:"last_run,omitempty"`
+}
+
+// SyncReport summarizes one sync run. Trigger is "schedule" or "manual".
+// Skipped counts duplicates and entries without text or author.
+type SyncReport struct {
+	Trigger    string    `json:"trigger"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Fetched    int       `json:"fetched"`
+	Inserted   int       `json:"inserted"`
+	Skipped    int       `json:"skipped"`
+	Errors     int       `json:"errors"`
+	LastError  string    `json:"last_error,omitempty"`
+}
+
+// ImportReport summarizes a bulk import. Lines counts the non-empty input
+// lines; each is imported, a duplicate of a stored quote, or skipped. Errors
+// explains the first skipped lines, and UnknownFields counts the fields that
+// were ignored, by name. In a dry run nothing is stored and Imported counts
+// the quotes that would have been. Atomic reports whether the quotes were
+// stored in a single transaction; without one, quotes added before a failed
+// write stay stored.
+type ImportReport struct {
+	DryRun     bool   `json:"dry_run"`
+	Atomic     bool   `json:"atomic"`
+	Format     string `json:"format"`
+	Lines      int    `json:"lines"`
+	Imported   int    `json:"imported"`
+	Duplicates int    `json:"duplicates"`
+	Skipped    int    `json:"skipped"`
+	// Held counts the quotes the content filter held for moderation.
+	Held          int            `json:"held,omitempty"`
+	UnknownFields map[string]int `json:"unknown_fields,omitempty"`
+	Errors        []ImportError  `json:"errors,omitempty"`
+}
+
+type ImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// HeldQuote is a quote the content filter held for a moderator to approve
+// or reject. For an update, Quote has only the fields the update sets.
+type HeldQuote struct {
+	ID string `json:"id"`
+	// Source is how the quote arrived: create, update or import.
+	Source string `json:"source"`
+	// QuoteID is the quote an update would change.
+	QuoteID int64 `json:"quote_id,omitempty"`
+	Quote   Quote `json:"quote"`
+	// Rule is the line of the denylist that matched.
+	Rule   int       `json:"rule"`
+	HeldAt time.Time `json:"held_at"`
+}
+
+// ScheduleStatus describes the scheduled quote publisher. NextRuns are
+// given in the schedule's time zone.
+type ScheduleStatus struct {
+	Cron        string      `json:"cron"`
+	Timezone    string      `json:"timezone"`
+	Mode        string      `json:"mode"`
+	Collection  string      `json:"collection,omitempty"`
+	Backfill    bool        `json:"backfill"`
+	NextRuns    []time.Time `json:"next_runs"`
+	LastFiredAt *time.Time  `json:"last_fired_at,omitempty"`
+	LastError   string      `json:"last_error,omitempty"`
+}
+
+// ScheduledQuote is the payload of the quote.scheduled webhook event.
+type ScheduledQuote struct {
+	Quote        Quote     `json:"quote"`
+	Mode         string    `json:"mode"`
+	ScheduledFor time.Time `json:"scheduled_for"`
+}
+
+// DailyPick is a quote of the day of the daily scheduled publisher.
+// FeaturedCount is how many times the quote had been featured by Date,
+// that day included. Quote is left out once the quote is deleted.
+type DailyPick struct {
+	Date          string `json:"date"`
+	QuoteID       int64  `json:"quote_id"`
+	FeaturedCount int    `json:"featured_count"`
+	Quote         *Quote `json:"quote,omitempty"`
+}
+
+// DigestStatus is the state of the email digest job. Since is where the
+// next digest starts: the end of the last one that was sent.
+type DigestStatus struct {
+	Cron       string        `json:"cron"`
+	Timezone   string        `json:"timezone"`
+	Recipients int           `json:"recipients"`
+	Running    bool          `json:"running"`
+	Since      time.Time     `json:"since"`
+	NextRun    *time.Time    `json:"next_run,omitempty"`
+	LastRun    *DigestReport `json:"last_run,omitempty"`
+}
+
+// DigestReport summarizes one digest run. Trigger is "schedule" or
+// "manual". A run without new quotes sends nothing.
+type DigestReport struct {
+	Trigger    string    `json:"trigger"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Since      time.Time `json:"since"`
+	Until      time.Time `json:"until"`
+	Quotes     int       `json:"quotes"`
+	Sent       bool      `json:"sent"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// BackupStatus is the state of the backup job. Bucket is empty when
+// uploads are disabled.
+type BackupStatus struct {
+	Interval     string          `json:"interval"`
+	Dir          string          `json:"dir,omitempty"`
+	Bucket       string          `json:"bucket,omitempty"`
+	Prefix       string          `json:"prefix,omitempty"`
+	Uploading    bool            `json:"uploading"`
+	LastSnapshot *BackupSnapshot `json:"last_snapshot,omitempty"`
+	LastUpload   *BackupUpload   `json:"last_upload,omitempty"`
+}
+
+// BackupSnapshot describes the last snapshot. File is its path on local
+// disk, if snapshots are kept there.
+type BackupSnapshot struct {
+	Trigger   string    `json:"trigger"`
+	CreatedAt time.Time `json:"created_at"`
+	Quotes    int       `json:"quotes"`
+	Bytes     int       `json:"bytes"`
+	File      string    `json:"file,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// BackupUpload describes the last upload. Pruned counts the old backups
+// deleted after it.
+type BackupUpload struct {
+	Trigger    string    `json:"trigger"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Key        string    `json:"key"`
+	Attempts   int       `json:"attempts"`
+	Pruned     int       `json:"pruned"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// States of an ExportJob or ImportJob.
+const (
+	JobQueued   = "queued"
+	JobRunning  = "running"
+	JobDone     = "done"
+	JobFailed   = "failed"
+	JobCanceled = "canceled"
+)
+
+// ExportRequest starts a background export. Format is "native", the
+// default, or "quotable"; Lang and HasSource filter the quotes like the
+// query parameters of GET /quotes/export.
+type ExportRequest struct {
+	Format    string `json:"format"`
+	Lang      string `json:"lang"`
+	HasSource *bool  `json:"has_source"`
+}
+
+// ExportJob is an export running in the background. Total is known once
+// the job starts, and Written counts the quotes written so far. A job that
+// has finished, one way or another, is removed at ExpiresAt together with
+// its file.
+type ExportJob struct {
+	ID         string     `json:"id"`
+	Status     string     `json:"status"`
+	Format     string     `json:"format"`
+	Lang       string     `json:"lang,omitempty"`
+	HasSource  *bool      `json:"has_source,omitempty"`
+	Total      int        `json:"total"`
+	Written    int        `json:"written"`
+	Bytes      int64      `json:"bytes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// ImportRequest starts a background import of the JSON Lines at URL, in
+// Format, "native" by default, or "quotable". A Transactional import
+// stores every quote or none of them, even if it is canceled halfway.
+type ImportRequest struct {
+	URL           string `json:"url"`
+	Format        string `json:"format"`
+	Transactional bool   `json:"transactional"`
+}
+
+// ImportJob is an import running in the background. The report counts the
+// lines read so far. Total is the number of new quotes found once the
+// input has been read, and Processed how many of them have been stored or
+// skipped since. RolledBack is set when a transactional import failed or
+// was canceled and none of its quotes were kept.
+type ImportJob struct {
+	ID            string `json:"id"`
+	Status        string `json:"status"`
+	URL           string `json:"url,omitempty"`
+	Transactional bool   `json:"transactional"`
+	ImportReport
+	Total      int        `json:"total"`
+	Processed  int        `json:"processed"`
+	RolledBack bool       `json:"rolled_back"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// ReplicationStatus is the state of the mirror to the secondary store.
+// Divergences counts mutations the secondary may have missed: failed
+// mirrors, mirrors dropped because the queue was full, and replays that
+// came out different.
+type ReplicationStatus struct {
+	ReadsFrom     string               `json:"reads_from"`
+	QueueDepth    int                  `json:"queue_depth"`
+	QueueCapacity int                  `json:"queue_capacity"`
+	Mirrored      int64                `json:"mirrored"`
+	Divergences   int64                `json:"divergences"`
+	LastError     string               `json:"last_error,omitempty"`
+	LastErrorAt   *time.Time           `json:"last_error_at,omitempty"`
+	Backfilling   bool                 `json:"backfilling"`
+	LastBackfill  *ReplicationBackfill `json:"last_backfill,omitempty"`
+}
+
+// ReplicationBackfill describes a copy of every quote to the secondary.
+// Copied counts quotes queued for the secondary, not yet applied ones.
+type ReplicationBackfill struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Quotes     int       `json:"quotes"`
+	Copied     int       `json:"copied"`
+	Batches    int       `json:"batches"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type Readiness struct {
+	Ready        bool                `json:"ready"`
+	SelfCheck    *SelfCheckResult    `json:"self_check,omitempty"`
+	Certificates []CertificateStatus `json:"certificates,omitempty"`
+}
+
+// CertificateStatus reports the automatically managed TLS certificate of
+// one domain. A domain can be ready and carry an error when a renewal
+// failed while the current certificate is still valid.
+type CertificateStatus struct {
+	Domain   string     `json:"domain"`
+	Ready    bool       `json:"ready"`
+	NotAfter *time.Time `json:"not_after,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// SelfCheckResult reports the startup storage check. Duration is a Go
+// duration string.
+type SelfCheckResult struct {
+	Mode      string    `json:"mode"`
+	Passed    bool      `json:"passed"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checked_at"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// PanicReport describes a handler panic the server recovered from. Query
+// and UserAgent are truncated, and Stack is the panicking goroutine's.
+type PanicReport struct {
+	Time       time.Time `json:"time"`
+	RequestID  string    `json:"request_id,omitempty"`
+	Method     string    `json:"method"`
+	Route      string    `json:"route,omitempty"`
+	Path       string    `json:"path"`
+	Query      string    `json:"query,omitempty"`
+	RemoteAddr string    `json:"remote_addr,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	Panic      string    `json:"panic"`
+	Stack      string    `json:"stack"`
+}
+
+// SlowRequest is one of the slowest recent requests. Duration is a Go
+// duration string and DurationMS the same value in milliseconds.
+type SlowRequest struct {
+	Time       time.Time `json:"time"`
+	RequestID  string    `json:"request_id,omitempty"`
+	Method     string    `json:"method"`
+	Route      string    `json:"route"`
+	Status     int       `json:"status"`
+	Duration   string    `json:"duration"`
+	DurationMS float64   `json:"duration_ms"`
+}
+
+// SlowRequests lists the slowest requests of the last Window, slowest
+// first.
+type SlowRequests struct {
+	Window   string        `json:"window"`
+	Requests []SlowRequest `json:"requests"`
+}
+
+// HealthSummary is the traffic of the last few windows up to Time.
+type HealthSummary struct {
+	Time    time.Time      `json:"time"`
+	Windows []HealthWindow `json:"windows"`
+}
+
+// HealthWindow counts the requests of the last Window, a Go duration
+// string. Statuses counts them by status class, such as "5xx", and
+// ErrorRates gives the share of the "4xx" and "5xx" classes, from 0 to 1.
+type HealthWindow struct {
+	Window        string             `json:"window"`
+	Requests      int64              `json:"requests"`
+	Statuses      map[string]int64   `json:"statuses"`
+	ErrorRates    map[string]float64 `json:"error_rates"`
+	LatencyMS     LatencyPercentiles `json:"latency_ms"`
+	StorageErrors int64              `json:"storage_errors"`
+	Panics        int64              `json:"panics"`
+}
+
+// LatencyPercentiles are estimated request latencies in milliseconds.
+type LatencyPercentiles struct {
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+}
+
+// QuoteDigest summarizes the whole catalog for clients that sync it. Hash
+// is derived from the quotes themselves, so it survives restarts of a
+// persistent backend; Version is the storage's change counter and may not.
+type QuoteDigest struct {
+	Version uint64 `json:"version"`
+	Hash    string `json:"hash"`
+	Count   int    `json:"count"`
+}
+
+// LookupQuoteRequest is the body of POST /quotes/lookup, for texts too
+// long for the query of GET /quotes/lookup.
+type LookupQuoteRequest struct {
 	Text   string `json:"text"`
 	Author string `json:"author"`
-}
\ No newline at end of file
+}
+
+// QuoteLookup is the quotes with the fingerprint of a text and author,
+// oldest first. There are several only while duplicates are kept.
+type QuoteLookup struct {
+	Fingerprint string  `json:"fingerprint"`
+	Quotes      []Quote `json:"quotes"`
+}
+
+// SharedQuote is a quote rendered for sharing by GET /quotes/{id}/share.
+// Length is that of Text in runes.
+type SharedQuote struct {
+	ID     int64  `json:"id"`
+	Style  string `json:"style"`
+	Text   string `json:"text"`
+	Length int    `json:"length"`
+}
+
+// ClaimRequest is the body of POST /quotes/claim. Every field is
+// optional: Claimant defaults to the caller's principal, Lease to the
+// configured default lease and Strategy to ClaimRandom.
+type ClaimRequest struct {
+	Claimant  string `json:"claimant,omitempty"`
+	Lease     string `json:"lease,omitempty"`
+	Strategy  string `json:"strategy,omitempty"`
+	Lang      string `json:"lang,omitempty"`
+	HasSource *bool  `json:"has_source,omitempty"`
+}
+
+// Claim strategies of a ClaimRequest.
+const (
+	ClaimRandom = "random"
+	ClaimOldest = "oldest"
+)
+
+// ReleaseRequest is the body of POST /quotes/{id}/release. Claimant
+// defaults to the caller's principal.
+type ReleaseRequest struct {
+	Claimant string `json:"claimant,omitempty"`
+}
+
+// QuoteClaim is a quote leased to a claimant until ExpiresAt.
+type QuoteClaim struct {
+	Quote     Quote     `json:"quote"`
+	Claimant  string    `json:"claimant"`
+	ClaimedAt time.Time `json:"claimed_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// StorageReport is what GET /admin/storage shows of an in-memory store.
+// Sizes are estimates from element sizes and string lengths, not
+// measurements; a map is counted by its entries, since the room it keeps
+// after deletes is not visible.
+type StorageReport struct {
+	Backend     string             `json:"backend"`
+	Quotes      int                `json:"quotes"`
+	ApproxBytes int64              `json:"approx_bytes"`
+	Structures  []StorageStructure `json:"structures"`
+	// CompactedAt is when the store was last compacted, if ever.
+	CompactedAt *time.Time `json:"compacted_at,omitempty"`
+	// HeapInuseBytes is the process's heap in use, as the Go runtime
+	// reports it, for comparing with ApproxBytes.
+	HeapInuseBytes uint64 `json:"heap_inuse_bytes"`
+}
+
+// StorageStructure is one structure of a StorageReport. Capacity is set
+// for the structures backed by a slice, whose capacity can outgrow their
+// entries.
+type StorageStructure struct {
+	Name        string `json:"name"`
+	Entries     int    `json:"entries"`
+	Capacity    int    `json:"capacity,omitempty"`
+	ApproxBytes int64  `json:"approx_bytes"`
+}
+
+// CompactionResult is the answer of POST /admin/compact. Duration is a Go
+// duration string and DurationMS the same value in milliseconds.
+type CompactionResult struct {
+	ApproxBytesBefore    int64   `json:"approx_bytes_before"`
+	ApproxBytesAfter     int64   `json:"approx_bytes_after"`
+	HeapInuseBytesBefore uint64  `json:"heap_inuse_bytes_before"`
+	HeapInuseBytesAfter  uint64  `json:"heap_inuse_bytes_after"`
+	Duration             string  `json:"duration"`
+	DurationMS           float64 `json:"duration_ms"`
+}
+
+// FairPick is a random quote the client can check was not steered: see
+// FairPickProof.
+type FairPick struct {
+	Quote Quote         `json:"quote"`
+	Proof FairPickProof `json:"proof"`
+}
+
+// FairPickCommitment is the SHA-256 of a server nonce, hex encoded, given
+// out before the client chooses its nonce for a FairPick. It is good for
+// one pick until ExpiresAt.
+type FairPickCommitment struct {
+	Algorithm  string    `json:"algorithm"`
+	Commitment string    `json:"commitment"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// FairPickProof is what a FairPick was derived from. Commitment is the
+// SHA-256 of the hex-decoded ServerNonce, which the client held before it
+// chose ClientNonce. Digest is HMAC-SHA256 keyed with the hex-decoded
+// ServerNonce over ClientNonce;
+// Index is its first 8 bytes as a big-endian integer modulo PoolSize, into
+// the quotes matching the request's filter ordered by ID, as they were at
+// PoolVersion.
+type FairPickProof struct {
+	Algorithm   string `json:"algorithm"`
+	ServerNonce string `json:"server_nonce"`
+	Commitment  string `json:"commitment"`
+	ClientNonce string `json:"client_nonce"`
+	Digest      string `json:"digest"`
+	PoolSize    int    `json:"pool_size"`
+	PoolVersion uint64 `json:"pool_version"`
+	Index       int    `json:"index"`
+}
+
+// FairPickVerification is the server's recomputation of a FairPickProof.
+// Commitment, Digest and Index are the recomputed ones. QuoteID is the quote at Index
+// in the current pool, given when the caller asked about one.
+type FairPickVerification struct {
+	Valid      bool     `json:"valid"`
+	Commitment string   `json:"commitment"`
+	Digest     string   `json:"digest"`
+	Index      int      `json:"index"`
+	QuoteID    int64    `json:"quote_id,omitempty"`
+	Problems   []string `json:"problems,omitempty"`
+}
+
+// Operations recorded in a QuoteChange.
+const (
+	ChangeAdd    = "add"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+)
+
+// QuoteChange is one entry of the quote change log. Quote is the quote as
+// the change left it, and is absent for deletes.
+type QuoteChange struct {
+	Seq     uint64    `json:"seq"`
+	Op      string    `json:"op"`
+	QuoteID int64     `json:"quote_id"`
+	Quote   *Quote    `json:"quote,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// QuoteChanges is a page of the change log. Seq is the value to pass as
+// since for the next page, and More is set when there are changes after
+// it already.
+type QuoteChanges struct {
+	Changes []QuoteChange `json:"changes"`
+	Seq     uint64        `json:"seq"`
+	More    bool          `json:"more"`
+}
+
+// DeprecationUsage is who still uses a deprecated route or response field,
+// as reported by GET /admin/deprecations/usage.
+type DeprecationUsage struct {
+	Route   string              `json:"route"`
+	Field   string              `json:"field,omitempty"`
+	Since   time.Time           `json:"since"`
+	Sunset  time.Time           `json:"sunset"`
+	Link    string              `json:"link"`
+	Uses    int64               `json:"uses"`
+	Callers []DeprecationCaller `json:"callers"`
+}
+
+// DeprecationCaller is one caller of a deprecated route: "principal:" and
+// the principal for an authenticated one, "ip:" and the address for
+// others.
+type DeprecationCaller struct {
+	Caller    string    `json:"caller"`
+	Uses      int64     `json:"uses"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
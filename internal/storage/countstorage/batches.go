@@ -0,0 +1,39 @@
+package countstorage
+
+import (
+	"context"
+
+	"quotes-service/internal/lib/storagecalls"
+	"quotes-service/internal/storage"
+)
+
+// DeleteAuthorBatch forwards to the wrapped store when it is a
+// storage.Batcher and fails with storage.ErrBatchesUnsupported otherwise.
+func (s *Storage) DeleteAuthorBatch(ctx context.Context, author string, batch storage.Batch) (storage.BatchResult, error) {
+	batcher, ok := s.store.(storage.Batcher)
+	if !ok {
+		return storage.BatchResult{}, storage.ErrBatchesUnsupported
+	}
+	storagecalls.Add(ctx)
+	return batcher.DeleteAuthorBatch(ctx, author, batch)
+}
+
+// MergeAuthorsBatch forwards like DeleteAuthorBatch.
+func (s *Storage) MergeAuthorsBatch(ctx context.Context, into string, from []string, batch storage.Batch) (storage.BatchResult, error) {
+	batcher, ok := s.store.(storage.Batcher)
+	if !ok {
+		return storage.BatchResult{}, storage.ErrBatchesUnsupported
+	}
+	storagecalls.Add(ctx)
+	return batcher.MergeAuthorsBatch(ctx, into, from, batch)
+}
+
+// ReindexBatch forwards like DeleteAuthorBatch.
+func (s *Storage) ReindexBatch(ctx context.Context, batch storage.Batch) (storage.BatchResult, error) {
+	batcher, ok := s.store.(storage.Batcher)
+	if !ok {
+		return storage.BatchResult{}, storage.ErrBatchesUnsupported
+	}
+	storagecalls.Add(ctx)
+	return batcher.ReindexBatch(ctx, batch)
+}
@@ -0,0 +1,40 @@
+package countstorage
+
+import (
+	"context"
+
+	"quotes-service/internal/lib/storagecalls"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// ClaimQuote forwards to the wrapped store when it is a storage.Claimer
+// and fails with storage.ErrClaimsUnsupported otherwise.
+func (s *Storage) ClaimQuote(ctx context.Context, claimant string, opts storage.ClaimOptions) (models.QuoteClaim, error) {
+	claimer, ok := s.store.(storage.Claimer)
+	if !ok {
+		return models.QuoteClaim{}, storage.ErrClaimsUnsupported
+	}
+	storagecalls.Add(ctx)
+	return claimer.ClaimQuote(ctx, claimant, opts)
+}
+
+// ReleaseQuote forwards like ClaimQuote.
+func (s *Storage) ReleaseQuote(ctx context.Context, id int64, claimant string) error {
+	claimer, ok := s.store.(storage.Claimer)
+	if !ok {
+		return storage.ErrClaimsUnsupported
+	}
+	storagecalls.Add(ctx)
+	return claimer.ReleaseQuote(ctx, id, claimant)
+}
+
+// ExpireClaims forwards like ClaimQuote.
+func (s *Storage) ExpireClaims(ctx context.Context) (int, error) {
+	claimer, ok := s.store.(storage.Claimer)
+	if !ok {
+		return 0, storage.ErrClaimsUnsupported
+	}
+	storagecalls.Add(ctx)
+	return claimer.ExpireClaims(ctx)
+}
@@ -0,0 +1,30 @@
+package countstorage
+
+import (
+	"context"
+
+	"quotes-service/internal/lib/storagecalls"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// Compact forwards to the wrapped store when it is a storage.Compactor
+// and fails with storage.ErrCompactionUnsupported otherwise.
+func (s *Storage) Compact(ctx context.Context) error {
+	compactor, ok := s.store.(storage.Compactor)
+	if !ok {
+		return storage.ErrCompactionUnsupported
+	}
+	storagecalls.Add(ctx)
+	return compactor.Compact(ctx)
+}
+
+// MemoryReport forwards like Compact.
+func (s *Storage) MemoryReport(ctx context.Context) (models.StorageReport, error) {
+	compactor, ok := s.store.(storage.Compactor)
+	if !ok {
+		return models.StorageReport{}, storage.ErrCompactionUnsupported
+	}
+	storagecalls.Add(ctx)
+	return compactor.MemoryReport(ctx)
+}
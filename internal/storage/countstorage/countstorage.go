@@ -0,0 +1,177 @@
+// Package countstorage wraps a quote store and counts every call made
+// through it against the request it was made for, as storagecalls
+// tracks them.
+package countstorage
+
+import (
+	"context"
+
+	"quotes-service/internal/lib/storagecalls"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// Store is the set of methods the decorator forwards.
+type Store interface {
+	AddQuote(ctx context.Context, quote models.Quote) (int64, error)
+	GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error)
+	GetQuote(ctx context.Context, id int64) (models.Quote, error)
+	GetQuoteByPublicID(ctx context.Context, publicID string) (models.Quote, error)
+	GetRandomQuote(ctx context.Context, opts storage.RandomOptions) (models.Quote, error)
+	GetQuotesByAuthor(ctx context.Context, authorFilter string, filter storage.QuoteFilter) ([]models.Quote, error)
+	UpdateQuote(ctx context.Context, id int64, update storage.QuoteUpdate, ifVersion int64) (models.Quote, error)
+	DeleteQuote(ctx context.Context, id int64, ifVersion int64) error
+	MergeAuthors(ctx context.Context, into string, from []string) (map[string]int, error)
+	GetAuthors(ctx context.Context, query storage.AuthorQuery) ([]models.AuthorSummary, int, error)
+	IncrementServed(ctx context.Context, id int64) error
+	GetPopularQuotes(ctx context.Context, limit int) ([]models.PopularQuote, error)
+	GetSimilarQuotes(ctx context.Context, id int64, limit int) ([]models.SimilarQuote, error)
+	Version(ctx context.Context) (uint64, error)
+
+	CreateCollection(ctx context.Context, name string, description string) (models.Collection, error)
+	GetCollections(ctx context.Context) ([]models.Collection, error)
+	GetCollection(ctx context.Context, id int64) (models.CollectionWithQuotes, error)
+	AddQuotesToCollection(ctx context.Context, id int64, quoteIDs []int64) error
+	RemoveQuoteFromCollection(ctx context.Context, id int64, quoteID int64) error
+	DeleteCollection(ctx context.Context, id int64) error
+	GetRandomCollectionQuote(ctx context.Context, id int64) (models.Quote, error)
+
+	AddFavorite(ctx context.Context, principal string, quoteID int64) error
+	RemoveFavorite(ctx context.Context, principal string, quoteID int64) error
+	GetFavorites(ctx context.Context, principal string, limit, offset int) ([]models.Quote, int, error)
+}
+
+type Storage struct {
+	store Store
+}
+
+// New wraps store.
+func New(store Store) *Storage {
+	return &Storage{store: store}
+}
+
+func (s *Storage) AddQuote(ctx context.Context, quote models.Quote) (int64, error) {
+	storagecalls.Add(ctx)
+	return s.store.AddQuote(ctx, quote)
+}
+
+func (s *Storage) GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error) {
+	storagecalls.Add(ctx)
+	return s.store.GetAllQuotes(ctx, filter)
+}
+
+func (s *Storage) GetQuote(ctx context.Context, id int64) (models.Quote, error) {
+	storagecalls.Add(ctx)
+	return s.store.GetQuote(ctx, id)
+}
+
+func (s *Storage) GetQuoteByPublicID(ctx context.Context, publicID string) (models.Quote, error) {
+	storagecalls.Add(ctx)
+	return s.store.GetQuoteByPublicID(ctx, publicID)
+}
+
+func (s *Storage) GetRandomQuote(ctx context.Context, opts storage.RandomOptions) (models.Quote, error) {
+	storagecalls.Add(ctx)
+	return s.store.GetRandomQuote(ctx, opts)
+}
+
+func (s *Storage) GetQuotesByAuthor(ctx context.Context, authorFilter string, filter storage.QuoteFilter) ([]models.Quote, error) {
+	storagecalls.Add(ctx)
+	return s.store.GetQuotesByAuthor(ctx, authorFilter, filter)
+}
+
+func (s *Storage) UpdateQuote(ctx context.Context, id int64, update storage.QuoteUpdate, ifVersion int64) (models.Quote, error) {
+	storagecalls.Add(ctx)
+	return s.store.UpdateQuote(ctx, id, update, ifVersion)
+}
+
+func (s *Storage) DeleteQuote(ctx context.Context, id int64, ifVersion int64) error {
+	storagecalls.Add(ctx)
+	return s.store.DeleteQuote(ctx, id, ifVersion)
+}
+
+func (s *Storage) MergeAuthors(ctx context.Context, into string, from []string) (map[string]int, error) {
+	storagecalls.Add(ctx)
+	return s.store.MergeAuthors(ctx, into, from)
+}
+
+func (s *Storage) GetAuthors(ctx context.Context, query storage.AuthorQuery) ([]models.AuthorSummary, int, error) {
+	storagecalls.Add(ctx)
+	return s.store.GetAuthors(ctx, query)
+}
+
+func (s *Storage) IncrementServed(ctx context.Context, id int64) error {
+	storagecalls.Add(ctx)
+	return s.store.IncrementServed(ctx, id)
+}
+
+func (s *Storage) GetPopularQuotes(ctx context.Context, limit int) ([]models.PopularQuote, error) {
+	storagecalls.Add(ctx)
+	return s.store.GetPopularQuotes(ctx, limit)
+}
+
+func (s *Storage) GetSimilarQuotes(ctx context.Context, id int64, limit int) ([]models.SimilarQuote, error) {
+	storagecalls.Add(ctx)
+	return s.store.GetSimilarQuotes(ctx, id, limit)
+}
+
+func (s *Storage) Version(ctx context.Context) (uint64, error) {
+	storagecalls.Add(ctx)
+	return s.store.Version(ctx)
+}
+
+func (s *Storage) CreateCollection(ctx context.Context, name string, description string) (models.Collection, error) {
+	storagecalls.Add(ctx)
+	return s.store.CreateCollection(ctx, name, description)
+}
+
+func (s *Storage) GetCollections(ctx context.Context) ([]models.Collection, error) {
+	storagecalls.Add(ctx)
+	return s.store.GetCollections(ctx)
+}
+
+func (s *Storage) GetCollection(ctx context.Context, id int64) (models.CollectionWithQuotes, error) {
+	storagecalls.Add(ctx)
+	return s.store.GetCollection(ctx, id)
+}
+
+func (s *Storage) AddQuotesToCollection(ctx context.Context, id int64, quoteIDs []int64) error {
+	storagecalls.Add(ctx)
+	return s.store.AddQuotesToCollection(ctx, id, quoteIDs)
+}
+
+func (s *Storage) RemoveQuoteFromCollection(ctx context.Context, id int64, quoteID int64) error {
+	storagecalls.Add(ctx)
+	return s.store.RemoveQuoteFromCollection(ctx, id, quoteID)
+}
+
+func (s *Storage) DeleteCollection(ctx context.Context, id int64) error {
+	storagecalls.Add(ctx)
+	return s.store.DeleteCollection(ctx, id)
+}
+
+func (s *Storage) GetRandomCollectionQuote(ctx context.Context, id int64) (models.Quote, error) {
+	storagecalls.Add(ctx)
+	return s.store.GetRandomCollectionQuote(ctx, id)
+}
+
+func (s *Storage) AddFavorite(ctx context.Context, principal string, quoteID int64) error {
+	storagecalls.Add(ctx)
+	return s.store.AddFavorite(ctx, principal, quoteID)
+}
+
+func (s *Storage) RemoveFavorite(ctx context.Context, principal string, quoteID int64) error {
+	storagecalls.Add(ctx)
+	return s.store.RemoveFavorite(ctx, principal, quoteID)
+}
+
+func (s *Storage) GetFavorites(ctx context.Context, principal string, limit, offset int) ([]models.Quote, int, error) {
+	storagecalls.Add(ctx)
+	return s.store.GetFavorites(ctx, principal, limit, offset)
+}
+
+// Backend implements storage.Describer with the backend of the wrapped
+// store.
+func (s *Storage) Backend() string {
+	return storage.Backend(s.store)
+}
@@ -0,0 +1,78 @@
+package countstorage_test
+
+import (
+	"context"
+	"testing"
+
+	"quotes-service/internal/lib/storagecalls"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/countstorage"
+	"quotes-service/internal/storage/memorystorage"
+	"quotes-service/internal/storage/storagetest"
+)
+
+func TestCounts(t *testing.T) {
+	inner, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	store := countstorage.New(inner)
+
+	tests := []struct {
+		name     string
+		call     func(ctx context.Context) error
+		expected int64
+	}{
+		{
+			name: "single call",
+			call: func(ctx context.Context) error {
+				_, err := store.AddQuote(ctx, models.Quote{Text: "Less is more.", Author: "Mies"})
+				return err
+			},
+			expected: 1,
+		},
+		{
+			name: "transaction and its calls",
+			call: func(ctx context.Context) error {
+				return store.WithinTx(ctx, func(tx storage.QuoteStore) error {
+					if _, err := tx.GetQuote(ctx, 1); err != nil {
+						return err
+					}
+					_, err := tx.AddQuote(ctx, models.Quote{Text: "God is in the details.", Author: "Mies"})
+					return err
+				})
+			},
+			expected: 3,
+		},
+		{
+			name: "served in bulk",
+			call: func(ctx context.Context) error {
+				return store.AddServed(ctx, 1, 5)
+			},
+			expected: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, calls := storagecalls.Track(context.Background())
+			if err := tc.call(ctx); err != nil {
+				t.Fatalf("call failed: %v", err)
+			}
+			if got := calls.Calls(); got != tc.expected {
+				t.Fatalf("expected %d calls, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Conformance(t, func(t *testing.T) storage.QuoteStore {
+		inner, err := memorystorage.New()
+		if err != nil {
+			t.Fatalf("failed to init storage: %v", err)
+		}
+		return countstorage.New(inner)
+	})
+}
@@ -0,0 +1,91 @@
+package countstorage
+
+import (
+	"context"
+
+	"quotes-service/internal/lib/storagecalls"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// WithinTx forwards to the wrapped store when it is a storage.Transactor
+// and fails with storage.ErrTxUnsupported otherwise. The transaction
+// counts as a call, and so does every call made through tx.
+func (s *Storage) WithinTx(ctx context.Context, fn func(tx storage.QuoteStore) error) error {
+	transactor, ok := s.store.(storage.Transactor)
+	if !ok {
+		return storage.ErrTxUnsupported
+	}
+	storagecalls.Add(ctx)
+	return transactor.WithinTx(ctx, func(tx storage.QuoteStore) error {
+		return fn(&countedTx{tx: tx})
+	})
+}
+
+// ChangesSince forwards to the wrapped store when it is a
+// storage.ChangeLog and fails with storage.ErrChangeLogUnsupported
+// otherwise.
+func (s *Storage) ChangesSince(ctx context.Context, since uint64, limit int) ([]models.QuoteChange, uint64, error) {
+	log, ok := s.store.(storage.ChangeLog)
+	if !ok {
+		return nil, 0, storage.ErrChangeLogUnsupported
+	}
+	storagecalls.Add(ctx)
+	return log.ChangesSince(ctx, since, limit)
+}
+
+// AddServed forwards to the wrapped store when it is a storage.ServedAdder
+// and calls its IncrementServed n times otherwise, counting each call.
+func (s *Storage) AddServed(ctx context.Context, id int64, n int64) error {
+	if adder, ok := s.store.(storage.ServedAdder); ok {
+		storagecalls.Add(ctx)
+		return adder.AddServed(ctx, id, n)
+	}
+	for range n {
+		if err := s.IncrementServed(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type countedTx struct {
+	tx storage.QuoteStore
+}
+
+func (t *countedTx) AddQuote(ctx context.Context, quote models.Quote) (int64, error) {
+	storagecalls.Add(ctx)
+	return t.tx.AddQuote(ctx, quote)
+}
+
+func (t *countedTx) GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error) {
+	storagecalls.Add(ctx)
+	return t.tx.GetAllQuotes(ctx, filter)
+}
+
+func (t *countedTx) GetQuote(ctx context.Context, id int64) (models.Quote, error) {
+	storagecalls.Add(ctx)
+	return t.tx.GetQuote(ctx, id)
+}
+
+func (t *countedTx) UpdateQuote(ctx context.Context, id int64, update storage.QuoteUpdate, ifVersion int64) (models.Quote, error) {
+	storagecalls.Add(ctx)
+	return t.tx.UpdateQuote(ctx, id, update, ifVersion)
+}
+
+func (t *countedTx) DeleteQuote(ctx context.Context, id int64, ifVersion int64) error {
+	storagecalls.Add(ctx)
+	return t.tx.DeleteQuote(ctx, id, ifVersion)
+}
+
+// GetQuotesByFingerprint forwards to the wrapped store when it is a
+// storage.FingerprintIndex and fails with
+// storage.ErrFingerprintsUnsupported otherwise.
+func (s *Storage) GetQuotesByFingerprint(ctx context.Context, fp string) ([]models.Quote, error) {
+	index, ok := s.store.(storage.FingerprintIndex)
+	if !ok {
+		return nil, storage.ErrFingerprintsUnsupported
+	}
+	storagecalls.Add(ctx)
+	return index.GetQuotesByFingerprint(ctx, fp)
+}
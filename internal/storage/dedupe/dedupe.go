@@ -0,0 +1,218 @@
+// Package dedupe finds the quotes that fingerprint cannot tell apart, the
+// same words by the same author up to case, punctuation, spacing and
+// typography, and deletes all but one of each.
+package dedupe
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"quotes-service/internal/lib/fingerprint"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// Strategies tell Resolve which quote of a group to keep.
+const (
+	KeepOldest = "keep_oldest"
+	KeepNewest = "keep_newest"
+)
+
+// chunk is how many quotes Scan fingerprints between checks of its
+// context.
+const chunk = 1000
+
+// Store is what a scan and its resolution need from the storage backend.
+// If it is a storage.Transactor, each group is resolved in a transaction.
+type Store interface {
+	GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error)
+	GetQuote(ctx context.Context, id int64) (models.Quote, error)
+	DeleteQuote(ctx context.Context, id int64, ifVersion int64) error
+}
+
+// Group is the quotes sharing a fingerprint, oldest first.
+type Group struct {
+	Key    string
+	Quotes []models.Quote
+}
+
+// Result counts what Resolve did.
+type Result struct {
+	// Resolved is how many groups were left with a single quote.
+	Resolved int
+	Deleted  int
+	// Skipped is how many groups were left alone because one of their
+	// quotes changed or went away since the scan.
+	Skipped int
+	// Atomic is set when every group was resolved in a transaction.
+	Atomic bool
+}
+
+// ValidStrategy reports whether Resolve accepts strategy.
+func ValidStrategy(strategy string) bool {
+	return strategy == KeepOldest || strategy == KeepNewest
+}
+
+// Scan returns the groups of two or more duplicate quotes, ordered by the
+// ID of their first quote. The store is read once, so it is busy only for
+// as long as copying the quotes takes; they are fingerprinted afterwards,
+// in chunks between which ctx is checked.
+func Scan(ctx context.Context, store Store) ([]Group, error) {
+	quotes, err := store.GetAllQuotes(ctx, storage.QuoteFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("read quotes: %w", err)
+	}
+
+	byKey := make(map[string][]models.Quote)
+	var keys []string
+	for start := 0; start < len(quotes); start += chunk {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for _, q := range quotes[start:min(start+chunk, len(quotes))] {
+			key := fingerprint.Of(q.Text, q.Author)
+			if _, ok := byKey[key]; !ok {
+				keys = append(keys, key)
+			}
+			byKey[key] = append(byKey[key], q)
+		}
+	}
+
+	var groups []Group
+	for _, key := range keys {
+		if len(byKey[key]) < 2 {
+			continue
+		}
+		group := Group{Key: key, Quotes: byKey[key]}
+		sortGroup(group)
+		groups = append(groups, group)
+	}
+	slices.SortFunc(groups, func(a, b Group) int {
+		return cmp.Compare(minID(a), minID(b))
+	})
+	return groups, nil
+}
+
+// Finder is what Find needs from the storage backend.
+type Finder interface {
+	GetQuotesByAuthor(ctx context.Context, authorFilter string, filter storage.QuoteFilter) ([]models.Quote, error)
+}
+
+// Find returns the group of quotes with the fingerprint of text by author,
+// oldest first, without quotes when there are none. A store that is a
+// storage.FingerprintIndex is asked for the group; otherwise only the
+// quotes of the author are read: the fingerprint covers the author's key,
+// which is what the store looks authors up by.
+func Find(ctx context.Context, store Finder, text, author string) (Group, error) {
+	group := Group{Key: fingerprint.Of(text, author)}
+	if index, ok := store.(storage.FingerprintIndex); ok {
+		quotes, err := index.GetQuotesByFingerprint(ctx, group.Key)
+		if err == nil {
+			group.Quotes = quotes
+			sortGroup(group)
+			return group, nil
+		}
+		if !errors.Is(err, storage.ErrFingerprintsUnsupported) {
+			return group, fmt.Errorf("look up fingerprint %s: %w", group.Key, err)
+		}
+	}
+	quotes, err := store.GetQuotesByAuthor(ctx, author, storage.QuoteFilter{})
+	if err != nil {
+		return group, fmt.Errorf("read quotes of %s: %w", author, err)
+	}
+	for _, q := range quotes {
+		if fingerprint.Of(q.Text, q.Author) == group.Key {
+			group.Quotes = append(group.Quotes, q)
+		}
+	}
+	sortGroup(group)
+	return group, nil
+}
+
+func sortGroup(g Group) {
+	slices.SortFunc(g.Quotes, func(a, b models.Quote) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+}
+
+func minID(g Group) int64 {
+	return slices.MinFunc(g.Quotes, func(a, b models.Quote) int { return cmp.Compare(a.ID, b.ID) }).ID
+}
+
+// errChanged rolls back a group one of whose quotes changed since the
+// scan.
+var errChanged = errors.New("group changed since the scan")
+
+// Resolve keeps one quote of each group, the oldest or the newest as
+// strategy says, and deletes the others, each only if it is still the
+// version that was scanned. A group goes in one transaction when the
+// store supports them, so it is either resolved or left as it was; a
+// group that changed is skipped. Without transactions the deletes are
+// made one by one and a group that changed midway stays partly resolved.
+func Resolve(ctx context.Context, store Store, groups []Group, strategy string) (Result, error) {
+	if !ValidStrategy(strategy) {
+		return Result{}, fmt.Errorf("unknown strategy %q", strategy)
+	}
+	transactor, useTx := store.(storage.Transactor)
+	result := Result{Atomic: useTx}
+	for _, g := range groups {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		keep, drop := g.Quotes[0], g.Quotes[1:]
+		if strategy == KeepNewest {
+			keep, drop = g.Quotes[len(g.Quotes)-1], g.Quotes[:len(g.Quotes)-1]
+		}
+
+		var err error
+		if useTx {
+			err = transactor.WithinTx(ctx, func(tx storage.QuoteStore) error {
+				return deleteGroup(ctx, tx, keep, drop)
+			})
+			if errors.Is(err, storage.ErrTxUnsupported) {
+				useTx, result.Atomic = false, false
+			}
+		}
+		if !useTx {
+			err = deleteGroup(ctx, store, keep, drop)
+		}
+		switch {
+		case err == nil:
+			result.Resolved++
+			result.Deleted += len(drop)
+		case errors.Is(err, errChanged):
+			result.Skipped++
+		default:
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// deleteGroup deletes drop once it has made sure keep is still there as
+// scanned, so that a group is never left without a quote.
+func deleteGroup(ctx context.Context, store interface {
+	GetQuote(ctx context.Context, id int64) (models.Quote, error)
+	DeleteQuote(ctx context.Context, id int64, ifVersion int64) error
+}, keep models.Quote, drop []models.Quote) error {
+	current, err := store.GetQuote(ctx, keep.ID)
+	if errors.Is(err, storage.ErrQuoteNotFound) || (err == nil && current.Version != keep.Version) {
+		return errChanged
+	}
+	if err != nil {
+		return fmt.Errorf("get quote %d: %w", keep.ID, err)
+	}
+	for _, q := range drop {
+		err := store.DeleteQuote(ctx, q.ID, q.Version)
+		if errors.Is(err, storage.ErrQuoteNotFound) || errors.Is(err, storage.ErrVersionMismatch) {
+			return errChanged
+		}
+		if err != nil {
+			return fmt.Errorf("delete quote %d: %w", q.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package dedupe_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/dedupe"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+// plainStore hides WithinTx, so Resolve deletes quote by quote.
+type plainStore struct {
+	dedupe.Store
+}
+
+// authorStore hides GetQuotesByFingerprint, so Find reads the quotes of
+// the author.
+type authorStore struct {
+	dedupe.Finder
+}
+
+// newStore returns a store holding two duplicate groups, quotes 1, 3 and
+// 4 and quotes 2 and 5, each quote a minute younger than the one before.
+func newStore(t *testing.T) *memorystorage.Storage {
+	t.Helper()
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	store, err := memorystorage.New(memorystorage.WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for _, q := range []models.Quote{
+		{Text: "Stay hungry, stay foolish.", Author: "Steve Jobs"},
+		{Text: "Imagination is more important than knowledge.", Author: "Albert Einstein"},
+		{Text: "stay HUNGRY  stay foolish", Author: "Steve Jobs"},
+		{Text: "  Stay hungry,\tstay foolish!  ", Author: "steve jobs"},
+		{Text: "Imagination is more important than knowledge", Author: "Albert Einstein"},
+		{Text: "Stay hungry.", Author: "Steve Jobs"},
+	} {
+		if _, err := store.AddQuote(context.Background(), q); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+		now = now.Add(time.Minute)
+	}
+	return store
+}
+
+func groupIDs(groups []dedupe.Group) [][]int64 {
+	out := [][]int64{}
+	for _, g := range groups {
+		var ids []int64
+		for _, q := range g.Quotes {
+			ids = append(ids, q.ID)
+		}
+		out = append(out, ids)
+	}
+	return out
+}
+
+func TestScan(t *testing.T) {
+	store := newStore(t)
+
+	groups, err := dedupe.Scan(context.Background(), store)
+	if err != nil {
+		t.Fatalf("failed to scan: %v", err)
+	}
+	got := groupIDs(groups)
+	expected := [][]int64{{1, 3, 4}, {2, 5}}
+	if !slices.EqualFunc(got, expected, slices.Equal) {
+		t.Fatalf("expected groups %v, got %v", expected, got)
+	}
+	if groups[0].Key == groups[1].Key {
+		t.Fatalf("expected distinct keys, got %q twice", groups[0].Key)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := dedupe.Scan(ctx, store); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a canceled scan to fail, got %v", err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	store := newStore(t)
+	groups, err := dedupe.Scan(context.Background(), store)
+	if err != nil {
+		t.Fatalf("failed to scan: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		author   string
+		expected []int64
+		key      string
+	}{
+		{name: "as stored", text: "Stay hungry, stay foolish.", author: "Steve Jobs", expected: []int64{1, 3, 4}, key: groups[0].Key},
+		{name: "case and spacing", text: "  STAY hungry   stay\nfoolish ", author: "STEVE  JOBS", expected: []int64{1, 3, 4}, key: groups[0].Key},
+		{name: "typography", text: "Imagination is more important than knowledge…", author: "albert einstein", expected: []int64{2, 5}, key: groups[1].Key},
+		{name: "single quote", text: "stay hungry", author: "Steve Jobs", expected: []int64{6}},
+		{name: "other words", text: "Stay thirsty.", author: "Steve Jobs"},
+		{name: "other author", text: "Stay hungry, stay foolish.", author: "Albert Einstein"},
+	}
+
+	finders := map[string]dedupe.Finder{
+		"fingerprint index": store,
+		"author scan":       authorStore{store},
+	}
+	for finderName, finder := range finders {
+		for _, tc := range tests {
+			t.Run(finderName+"/"+tc.name, func(t *testing.T) {
+				group, err := dedupe.Find(context.Background(), finder, tc.text, tc.author)
+				if err != nil {
+					t.Fatalf("failed to find: %v", err)
+				}
+				var ids []int64
+				for _, q := range group.Quotes {
+					ids = append(ids, q.ID)
+				}
+				if !slices.Equal(ids, tc.expected) {
+					t.Fatalf("expected %v, got %v", tc.expected, ids)
+				}
+				if tc.key != "" && group.Key != tc.key {
+					t.Fatalf("expected the key of the scanned group %q, got %q", tc.key, group.Key)
+				}
+			})
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		plain    bool
+		// change updates quote 3 after the scan.
+		change            bool
+		expectedRemaining []int64
+		expectedResult    dedupe.Result
+	}{
+		{
+			name:              "keep oldest",
+			strategy:          dedupe.KeepOldest,
+			expectedRemaining: []int64{1, 2, 6},
+			expectedResult:    dedupe.Result{Resolved: 2, Deleted: 3, Atomic: true},
+		},
+		{
+			name:              "keep newest",
+			strategy:          dedupe.KeepNewest,
+			expectedRemaining: []int64{4, 5, 6},
+			expectedResult:    dedupe.Result{Resolved: 2, Deleted: 3, Atomic: true},
+		},
+		{
+			name:              "changed group is left alone",
+			strategy:          dedupe.KeepOldest,
+			change:            true,
+			expectedRemaining: []int64{1, 2, 3, 4, 6},
+			expectedResult:    dedupe.Result{Resolved: 1, Deleted: 1, Skipped: 1, Atomic: true},
+		},
+		{
+			name:              "without transactions",
+			strategy:          dedupe.KeepNewest,
+			plain:             true,
+			expectedRemaining: []int64{4, 5, 6},
+			expectedResult:    dedupe.Result{Resolved: 2, Deleted: 3},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			inner := newStore(t)
+			var store dedupe.Store = inner
+			if tc.plain {
+				store = plainStore{inner}
+			}
+
+			groups, err := dedupe.Scan(ctx, store)
+			if err != nil {
+				t.Fatalf("failed to scan: %v", err)
+			}
+			if tc.change {
+				text := "Stay hungry, stay foolish, stay curious."
+				if _, err := inner.UpdateQuote(ctx, 3, storage.QuoteUpdate{Text: &text}, storage.AnyVersion); err != nil {
+					t.Fatalf("failed to update quote: %v", err)
+				}
+			}
+
+			result, err := dedupe.Resolve(ctx, store, groups, tc.strategy)
+			if err != nil {
+				t.Fatalf("failed to resolve: %v", err)
+			}
+			if result != tc.expectedResult {
+				t.Fatalf("expected %+v, got %+v", tc.expectedResult, result)
+			}
+			quotes, err := inner.GetAllQuotes(ctx, storage.QuoteFilter{})
+			if err != nil {
+				t.Fatalf("failed to get quotes: %v", err)
+			}
+			var remaining []int64
+			for _, q := range quotes {
+				remaining = append(remaining, q.ID)
+			}
+			slices.Sort(remaining)
+			if !slices.Equal(remaining, tc.expectedRemaining) {
+				t.Fatalf("expected quotes %v to remain, got %v", tc.expectedRemaining, remaining)
+			}
+		})
+	}
+}
+
+func TestResolveRejectsUnknownStrategy(t *testing.T) {
+	if _, err := dedupe.Resolve(context.Background(), newStore(t), nil, "keep_all"); err == nil {
+		t.Fatal("expected an unknown strategy to fail")
+	}
+}
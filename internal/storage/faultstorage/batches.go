@@ -0,0 +1,44 @@
+package faultstorage
+
+import (
+	"context"
+
+	"quotes-service/internal/storage"
+)
+
+// DeleteAuthorBatch forwards to the wrapped store when it is a
+// storage.Batcher and fails with storage.ErrBatchesUnsupported otherwise.
+func (s *Storage) DeleteAuthorBatch(ctx context.Context, author string, batch storage.Batch) (storage.BatchResult, error) {
+	batcher, ok := s.store.(storage.Batcher)
+	if !ok {
+		return storage.BatchResult{}, storage.ErrBatchesUnsupported
+	}
+	if err := s.inject(ctx, "DeleteAuthorBatch"); err != nil {
+		return storage.BatchResult{}, err
+	}
+	return batcher.DeleteAuthorBatch(ctx, author, batch)
+}
+
+// MergeAuthorsBatch forwards like DeleteAuthorBatch.
+func (s *Storage) MergeAuthorsBatch(ctx context.Context, into string, from []string, batch storage.Batch) (storage.BatchResult, error) {
+	batcher, ok := s.store.(storage.Batcher)
+	if !ok {
+		return storage.BatchResult{}, storage.ErrBatchesUnsupported
+	}
+	if err := s.inject(ctx, "MergeAuthorsBatch"); err != nil {
+		return storage.BatchResult{}, err
+	}
+	return batcher.MergeAuthorsBatch(ctx, into, from, batch)
+}
+
+// ReindexBatch forwards like DeleteAuthorBatch.
+func (s *Storage) ReindexBatch(ctx context.Context, batch storage.Batch) (storage.BatchResult, error) {
+	batcher, ok := s.store.(storage.Batcher)
+	if !ok {
+		return storage.BatchResult{}, storage.ErrBatchesUnsupported
+	}
+	if err := s.inject(ctx, "ReindexBatch"); err != nil {
+		return storage.BatchResult{}, err
+	}
+	return batcher.ReindexBatch(ctx, batch)
+}
@@ -0,0 +1,22 @@
+package faultstorage
+
+import (
+	"context"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// ChangesSince forwards to the wrapped store when it is a
+// storage.ChangeLog and fails with storage.ErrChangeLogUnsupported
+// otherwise.
+func (s *Storage) ChangesSince(ctx context.Context, since uint64, limit int) ([]models.QuoteChange, uint64, error) {
+	log, ok := s.store.(storage.ChangeLog)
+	if !ok {
+		return nil, 0, storage.ErrChangeLogUnsupported
+	}
+	if err := s.inject(ctx, "ChangesSince"); err != nil {
+		return nil, 0, err
+	}
+	return log.ChangesSince(ctx, since, limit)
+}
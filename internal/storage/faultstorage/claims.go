@@ -0,0 +1,45 @@
+package faultstorage
+
+import (
+	"context"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// ClaimQuote forwards to the wrapped store when it is a storage.Claimer
+// and fails with storage.ErrClaimsUnsupported otherwise.
+func (s *Storage) ClaimQuote(ctx context.Context, claimant string, opts storage.ClaimOptions) (models.QuoteClaim, error) {
+	claimer, ok := s.store.(storage.Claimer)
+	if !ok {
+		return models.QuoteClaim{}, storage.ErrClaimsUnsupported
+	}
+	if err := s.inject(ctx, "ClaimQuote"); err != nil {
+		return models.QuoteClaim{}, err
+	}
+	return claimer.ClaimQuote(ctx, claimant, opts)
+}
+
+// ReleaseQuote forwards like ClaimQuote.
+func (s *Storage) ReleaseQuote(ctx context.Context, id int64, claimant string) error {
+	claimer, ok := s.store.(storage.Claimer)
+	if !ok {
+		return storage.ErrClaimsUnsupported
+	}
+	if err := s.inject(ctx, "ReleaseQuote"); err != nil {
+		return err
+	}
+	return claimer.ReleaseQuote(ctx, id, claimant)
+}
+
+// ExpireClaims forwards like ClaimQuote.
+func (s *Storage) ExpireClaims(ctx context.Context) (int, error) {
+	claimer, ok := s.store.(storage.Claimer)
+	if !ok {
+		return 0, storage.ErrClaimsUnsupported
+	}
+	if err := s.inject(ctx, "ExpireClaims"); err != nil {
+		return 0, err
+	}
+	return claimer.ExpireClaims(ctx)
+}
@@ -0,0 +1,33 @@
+package faultstorage
+
+import (
+	"context"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// Compact forwards to the wrapped store when it is a storage.Compactor
+// and fails with storage.ErrCompactionUnsupported otherwise.
+func (s *Storage) Compact(ctx context.Context) error {
+	compactor, ok := s.store.(storage.Compactor)
+	if !ok {
+		return storage.ErrCompactionUnsupported
+	}
+	if err := s.inject(ctx, "Compact"); err != nil {
+		return err
+	}
+	return compactor.Compact(ctx)
+}
+
+// MemoryReport forwards like Compact.
+func (s *Storage) MemoryReport(ctx context.Context) (models.StorageReport, error) {
+	compactor, ok := s.store.(storage.Compactor)
+	if !ok {
+		return models.StorageReport{}, storage.ErrCompactionUnsupported
+	}
+	if err := s.inject(ctx, "MemoryReport"); err != nil {
+		return models.StorageReport{}, err
+	}
+	return compactor.MemoryReport(ctx)
+}
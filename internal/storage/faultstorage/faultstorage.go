@@ -0,0 +1,392 @@
+// Package faultstorage wraps a quote store and injects latency and errors
+// into its calls. It exists so that clients and resilience code can be
+// exercised against a slow or flaky backend without touching a real one.
+package faultstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// ErrInjected is returned by calls selected for a fault.
+var ErrInjected = errors.New("injected storage fault")
+
+// MaxLatency caps the added latency so a typo cannot stall every request
+// for hours.
+const MaxLatency = time.Minute
+
+// Methods lists the store methods faults can be scoped to.
+var Methods = []string{
+	"AddQuote",
+	"GetAllQuotes",
+	"GetQuote",
+	"GetQuoteByPublicID",
+	"GetRandomQuote",
+	"GetQuotesByAuthor",
+	"UpdateQuote",
+	"DeleteQuote",
+	"MergeAuthors",
+	"GetAuthors",
+	"IncrementServed",
+	"GetPopularQuotes",
+	"GetSimilarQuotes",
+	"Version",
+	"CreateCollection",
+	"GetCollections",
+	"GetCollection",
+	"AddQuotesToCollection",
+	"RemoveQuoteFromCollection",
+	"DeleteCollection",
+	"GetRandomCollectionQuote",
+	"AddFavorite",
+	"RemoveFavorite",
+	"GetFavorites",
+	"WithinTx",
+	"ChangesSince",
+	"AddServed",
+}
+
+// Store is the set of methods the decorator forwards.
+type Store interface {
+	AddQuote(ctx context.Context, quote models.Quote) (int64, error)
+	GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error)
+	GetQuote(ctx context.Context, id int64) (models.Quote, error)
+	GetQuoteByPublicID(ctx context.Context, publicID string) (models.Quote, error)
+	GetRandomQuote(ctx context.Context, opts storage.RandomOptions) (models.Quote, error)
+	GetQuotesByAuthor(ctx context.Context, authorFilter string, filter storage.QuoteFilter) ([]models.Quote, error)
+	UpdateQuote(ctx context.Context, id int64, update storage.QuoteUpdate, ifVersion int64) (models.Quote, error)
+	DeleteQuote(ctx context.Context, id int64, ifVersion int64) error
+	MergeAuthors(ctx context.Context, into string, from []string) (map[string]int, error)
+	GetAuthors(ctx context.Context, query storage.AuthorQuery) ([]models.AuthorSummary, int, error)
+	IncrementServed(ctx context.Context, id int64) error
+	GetPopularQuotes(ctx context.Context, limit int) ([]models.PopularQuote, error)
+	GetSimilarQuotes(ctx context.Context, id int64, limit int) ([]models.SimilarQuote, error)
+	Version(ctx context.Context) (uint64, error)
+
+	CreateCollection(ctx context.Context, name string, description string) (models.Collection, error)
+	GetCollections(ctx context.Context) ([]models.Collection, error)
+	GetCollection(ctx context.Context, id int64) (models.CollectionWithQuotes, error)
+	AddQuotesToCollection(ctx context.Context, id int64, quoteIDs []int64) error
+	RemoveQuoteFromCollection(ctx context.Context, id int64, quoteID int64) error
+	DeleteCollection(ctx context.Context, id int64) error
+	GetRandomCollectionQuote(ctx context.Context, id int64) (models.Quote, error)
+
+	AddFavorite(ctx context.Context, principal string, quoteID int64) error
+	RemoveFavorite(ctx context.Context, principal string, quoteID int64) error
+	GetFavorites(ctx context.Context, principal string, limit, offset int) ([]models.Quote, int, error)
+}
+
+// Faults describes what to inject. The zero value injects nothing.
+type Faults struct {
+	// ErrorRate is the probability, from 0 to 1, that a call fails with
+	// ErrInjected instead of reaching the wrapped store.
+	ErrorRate float64
+	// Latency is added to every affected call before it runs.
+	Latency time.Duration
+	// Methods limits faults to the named methods. Empty means all methods.
+	Methods []string
+}
+
+// Validate reports every problem with f as a human-readable message.
+func (f Faults) Validate() []string {
+	var problems []string
+	if f.ErrorRate < 0 || f.ErrorRate > 1 {
+		problems = append(problems, "error_rate must be between 0 and 1")
+	}
+	if f.Latency < 0 {
+		problems = append(problems, "latency cannot be negative")
+	} else if f.Latency > MaxLatency {
+		problems = append(problems, fmt.Sprintf("latency cannot be longer than %s", MaxLatency))
+	}
+	for _, method := range f.Methods {
+		if !slices.Contains(Methods, method) {
+			problems = append(problems, fmt.Sprintf("unknown method %q", method))
+		}
+	}
+	return problems
+}
+
+type Storage struct {
+	store Store
+
+	mu     sync.RWMutex
+	faults Faults
+
+	randMu sync.Mutex
+	rand   func() float64
+}
+
+type Option func(*Storage)
+
+// WithRand overrides the random source deciding which calls fail, mainly
+// for tests. It must return values in [0, 1).
+func WithRand(rand func() float64) Option {
+	return func(s *Storage) {
+		s.rand = rand
+	}
+}
+
+// New wraps store. No faults are injected until SetFaults is called.
+func New(store Store, opts ...Option) *Storage {
+	s := &Storage{
+		store: store,
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())).Float64,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Faults returns the current fault settings.
+func (s *Storage) Faults() Faults {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f := s.faults
+	f.Methods = slices.Clone(f.Methods)
+	return f
+}
+
+// SetFaults replaces the fault settings. Invalid settings are rejected and
+// leave the current ones in place.
+func (s *Storage) SetFaults(f Faults) error {
+	if problems := f.Validate(); len(problems) > 0 {
+		return fmt.Errorf("invalid faults: %v", problems)
+	}
+
+	f.Methods = slices.Clone(f.Methods)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = f
+	return nil
+}
+
+// inject applies the configured faults to a call of method. The added
+// latency is cut short when ctx is done.
+func (s *Storage) inject(ctx context.Context, method string) error {
+	s.mu.RLock()
+	f := s.faults
+	s.mu.RUnlock()
+
+	if len(f.Methods) > 0 && !slices.Contains(f.Methods, method) {
+		return nil
+	}
+
+	if f.Latency > 0 {
+		timer := time.NewTimer(f.Latency)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if f.ErrorRate > 0 {
+		s.randMu.Lock()
+		roll := s.rand()
+		s.randMu.Unlock()
+		if roll < f.ErrorRate {
+			return fmt.Errorf("%s: %w", method, ErrInjected)
+		}
+	}
+	return nil
+}
+
+func (s *Storage) AddQuote(ctx context.Context, quote models.Quote) (int64, error) {
+	if err := s.inject(ctx, "AddQuote"); err != nil {
+		return 0, err
+	}
+	return s.store.AddQuote(ctx, quote)
+}
+
+func (s *Storage) GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error) {
+	if err := s.inject(ctx, "GetAllQuotes"); err != nil {
+		return nil, err
+	}
+	return s.store.GetAllQuotes(ctx, filter)
+}
+
+func (s *Storage) GetQuote(ctx context.Context, id int64) (models.Quote, error) {
+	if err := s.inject(ctx, "GetQuote"); err != nil {
+		return models.Quote{}, err
+	}
+	return s.store.GetQuote(ctx, id)
+}
+
+func (s *Storage) GetQuoteByPublicID(ctx context.Context, publicID string) (models.Quote, error) {
+	if err := s.inject(ctx, "GetQuoteByPublicID"); err != nil {
+		return models.Quote{}, err
+	}
+	return s.store.GetQuoteByPublicID(ctx, publicID)
+}
+
+func (s *Storage) GetRandomQuote(ctx context.Context, opts storage.RandomOptions) (models.Quote, error) {
+	if err := s.inject(ctx, "GetRandomQuote"); err != nil {
+		return models.Quote{}, err
+	}
+	return s.store.GetRandomQuote(ctx, opts)
+}
+
+func (s *Storage) GetQuotesByAuthor(ctx context.Context, authorFilter string, filter storage.QuoteFilter) ([]models.Quote, error) {
+	if err := s.inject(ctx, "GetQuotesByAuthor"); err != nil {
+		return nil, err
+	}
+	return s.store.GetQuotesByAuthor(ctx, authorFilter, filter)
+}
+
+// GetQuotesByFingerprint forwards to the wrapped store when it is a
+// storage.FingerprintIndex and fails with
+// storage.ErrFingerprintsUnsupported otherwise.
+func (s *Storage) GetQuotesByFingerprint(ctx context.Context, fp string) ([]models.Quote, error) {
+	index, ok := s.store.(storage.FingerprintIndex)
+	if !ok {
+		return nil, storage.ErrFingerprintsUnsupported
+	}
+	if err := s.inject(ctx, "GetQuotesByFingerprint"); err != nil {
+		return nil, err
+	}
+	return index.GetQuotesByFingerprint(ctx, fp)
+}
+
+func (s *Storage) UpdateQuote(ctx context.Context, id int64, update storage.QuoteUpdate, ifVersion int64) (models.Quote, error) {
+	if err := s.inject(ctx, "UpdateQuote"); err != nil {
+		return models.Quote{}, err
+	}
+	return s.store.UpdateQuote(ctx, id, update, ifVersion)
+}
+
+func (s *Storage) DeleteQuote(ctx context.Context, id int64, ifVersion int64) error {
+	if err := s.inject(ctx, "DeleteQuote"); err != nil {
+		return err
+	}
+	return s.store.DeleteQuote(ctx, id, ifVersion)
+}
+
+func (s *Storage) MergeAuthors(ctx context.Context, into string, from []string) (map[string]int, error) {
+	if err := s.inject(ctx, "MergeAuthors"); err != nil {
+		return nil, err
+	}
+	return s.store.MergeAuthors(ctx, into, from)
+}
+
+func (s *Storage) GetAuthors(ctx context.Context, query storage.AuthorQuery) ([]models.AuthorSummary, int, error) {
+	if err := s.inject(ctx, "GetAuthors"); err != nil {
+		return nil, 0, err
+	}
+	return s.store.GetAuthors(ctx, query)
+}
+
+func (s *Storage) IncrementServed(ctx context.Context, id int64) error {
+	if err := s.inject(ctx, "IncrementServed"); err != nil {
+		return err
+	}
+	return s.store.IncrementServed(ctx, id)
+}
+
+func (s *Storage) GetPopularQuotes(ctx context.Context, limit int) ([]models.PopularQuote, error) {
+	if err := s.inject(ctx, "GetPopularQuotes"); err != nil {
+		return nil, err
+	}
+	return s.store.GetPopularQuotes(ctx, limit)
+}
+
+func (s *Storage) GetSimilarQuotes(ctx context.Context, id int64, limit int) ([]models.SimilarQuote, error) {
+	if err := s.inject(ctx, "GetSimilarQuotes"); err != nil {
+		return nil, err
+	}
+	return s.store.GetSimilarQuotes(ctx, id, limit)
+}
+
+func (s *Storage) Version(ctx context.Context) (uint64, error) {
+	if err := s.inject(ctx, "Version"); err != nil {
+		return 0, err
+	}
+	return s.store.Version(ctx)
+}
+
+func (s *Storage) CreateCollection(ctx context.Context, name string, description string) (models.Collection, error) {
+	if err := s.inject(ctx, "CreateCollection"); err != nil {
+		return models.Collection{}, err
+	}
+	return s.store.CreateCollection(ctx, name, description)
+}
+
+func (s *Storage) GetCollections(ctx context.Context) ([]models.Collection, error) {
+	if err := s.inject(ctx, "GetCollections"); err != nil {
+		return nil, err
+	}
+	return s.store.GetCollections(ctx)
+}
+
+func (s *Storage) GetCollection(ctx context.Context, id int64) (models.CollectionWithQuotes, error) {
+	if err := s.inject(ctx, "GetCollection"); err != nil {
+		return models.CollectionWithQuotes{}, err
+	}
+	return s.store.GetCollection(ctx, id)
+}
+
+func (s *Storage) AddQuotesToCollection(ctx context.Context, id int64, quoteIDs []int64) error {
+	if err := s.inject(ctx, "AddQuotesToCollection"); err != nil {
+		return err
+	}
+	return s.store.AddQuotesToCollection(ctx, id, quoteIDs)
+}
+
+func (s *Storage) RemoveQuoteFromCollection(ctx context.Context, id int64, quoteID int64) error {
+	if err := s.inject(ctx, "RemoveQuoteFromCollection"); err != nil {
+		return err
+	}
+	return s.store.RemoveQuoteFromCollection(ctx, id, quoteID)
+}
+
+func (s *Storage) DeleteCollection(ctx context.Context, id int64) error {
+	if err := s.inject(ctx, "DeleteCollection"); err != nil {
+		return err
+	}
+	return s.store.DeleteCollection(ctx, id)
+}
+
+func (s *Storage) GetRandomCollectionQuote(ctx context.Context, id int64) (models.Quote, error) {
+	if err := s.inject(ctx, "GetRandomCollectionQuote"); err != nil {
+		return models.Quote{}, err
+	}
+	return s.store.GetRandomCollectionQuote(ctx, id)
+}
+
+func (s *Storage) AddFavorite(ctx context.Context, principal string, quoteID int64) error {
+	if err := s.inject(ctx, "AddFavorite"); err != nil {
+		return err
+	}
+	return s.store.AddFavorite(ctx, principal, quoteID)
+}
+
+func (s *Storage) RemoveFavorite(ctx context.Context, principal string, quoteID int64) error {
+	if err := s.inject(ctx, "RemoveFavorite"); err != nil {
+		return err
+	}
+	return s.store.RemoveFavorite(ctx, principal, quoteID)
+}
+
+func (s *Storage) GetFavorites(ctx context.Context, principal string, limit, offset int) ([]models.Quote, int, error) {
+	if err := s.inject(ctx, "GetFavorites"); err != nil {
+		return nil, 0, err
+	}
+	return s.store.GetFavorites(ctx, principal, limit, offset)
+}
+
+// Backend implements storage.Describer with the backend of the wrapped
+// store.
+func (s *Storage) Backend() string {
+	return storage.Backend(s.store)
+}
@@ -0,0 +1,191 @@
+package faultstorage_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/faultstorage"
+	"quotes-service/internal/storage/memorystorage"
+	"quotes-service/internal/storage/storagetest"
+)
+
+func newStore(t *testing.T, roll float64) *faultstorage.Storage {
+	t.Helper()
+	inner, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	if _, err := inner.AddQuote(context.Background(), models.Quote{Text: "Quote", Author: "Author"}); err != nil {
+		t.Fatalf("failed to add quote: %v", err)
+	}
+	return faultstorage.New(inner, faultstorage.WithRand(func() float64 { return roll }))
+}
+
+func TestInertByDefault(t *testing.T) {
+	store := newStore(t, 0)
+
+	quotes, err := store.GetAllQuotes(context.Background(), storage.QuoteFilter{})
+	if err != nil || len(quotes) != 1 {
+		t.Fatalf("expected the wrapped store to answer, got %d quotes, %v", len(quotes), err)
+	}
+}
+
+func TestErrorRate(t *testing.T) {
+	tests := []struct {
+		name      string
+		roll      float64
+		errorRate float64
+		wantErr   bool
+	}{
+		{name: "roll below rate fails", roll: 0.2, errorRate: 0.5, wantErr: true},
+		{name: "roll above rate passes", roll: 0.7, errorRate: 0.5, wantErr: false},
+		{name: "rate of one always fails", roll: 0.99, errorRate: 1, wantErr: true},
+		{name: "rate of zero never fails", roll: 0, errorRate: 0, wantErr: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newStore(t, tc.roll)
+			if err := store.SetFaults(faultstorage.Faults{ErrorRate: tc.errorRate}); err != nil {
+				t.Fatalf("failed to set faults: %v", err)
+			}
+
+			_, err := store.GetQuote(context.Background(), 1)
+			if gotErr := errors.Is(err, faultstorage.ErrInjected); gotErr != tc.wantErr {
+				t.Fatalf("expected injected error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestMethodScope(t *testing.T) {
+	store := newStore(t, 0)
+	if err := store.SetFaults(faultstorage.Faults{ErrorRate: 1, Methods: []string{"GetRandomQuote"}}); err != nil {
+		t.Fatalf("failed to set faults: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := store.GetRandomQuote(ctx, storage.RandomOptions{}); !errors.Is(err, faultstorage.ErrInjected) {
+		t.Fatalf("expected injected error for scoped method, got %v", err)
+	}
+	if _, err := store.GetQuote(ctx, 1); err != nil {
+		t.Fatalf("expected unscoped method to pass through, got %v", err)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	store := newStore(t, 0)
+	if err := store.SetFaults(faultstorage.Faults{Latency: 20 * time.Millisecond}); err != nil {
+		t.Fatalf("failed to set faults: %v", err)
+	}
+
+	start := time.Now()
+	if _, err := store.GetQuote(context.Background(), 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("expected at least 20ms of latency, got %s", elapsed)
+	}
+
+	if err := store.SetFaults(faultstorage.Faults{Latency: time.Minute}); err != nil {
+		t.Fatalf("failed to set faults: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := store.GetQuote(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected latency to stop at the deadline, got %v", err)
+	}
+}
+
+func TestSetFaultsValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		faults faultstorage.Faults
+	}{
+		{name: "error rate above one", faults: faultstorage.Faults{ErrorRate: 1.5}},
+		{name: "negative error rate", faults: faultstorage.Faults{ErrorRate: -0.1}},
+		{name: "negative latency", faults: faultstorage.Faults{Latency: -time.Second}},
+		{name: "latency above max", faults: faultstorage.Faults{Latency: faultstorage.MaxLatency + time.Second}},
+		{name: "unknown method", faults: faultstorage.Faults{Methods: []string{"DropTables"}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newStore(t, 0)
+			if err := store.SetFaults(tc.faults); err == nil {
+				t.Fatal("expected an error")
+			}
+			if got := store.Faults(); got.ErrorRate != 0 || got.Latency != 0 || got.Methods != nil {
+				t.Fatalf("expected settings to stay unchanged, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestWithinTx(t *testing.T) {
+	store := newStore(t, 0)
+	if err := store.SetFaults(faultstorage.Faults{ErrorRate: 1, Methods: []string{"DeleteQuote"}}); err != nil {
+		t.Fatalf("failed to set faults: %v", err)
+	}
+
+	ctx := context.Background()
+	err := store.WithinTx(ctx, func(tx storage.QuoteStore) error {
+		if _, err := tx.AddQuote(ctx, models.Quote{Text: "Another", Author: "Author"}); err != nil {
+			return err
+		}
+		return tx.DeleteQuote(ctx, 1, storage.AnyVersion)
+	})
+	if !errors.Is(err, faultstorage.ErrInjected) {
+		t.Fatalf("expected the injected error from inside the transaction, got %v", err)
+	}
+	if quotes, _ := store.GetAllQuotes(ctx, storage.QuoteFilter{}); len(quotes) != 1 {
+		t.Fatalf("expected the transaction to be rolled back, got %d quotes", len(quotes))
+	}
+
+	// Hiding the wrapped store's WithinTx leaves nothing to forward to.
+	plain := faultstorage.New(struct{ faultstorage.Store }{store})
+	called := false
+	err = plain.WithinTx(ctx, func(storage.QuoteStore) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, storage.ErrTxUnsupported) || called {
+		t.Fatalf("expected ErrTxUnsupported without calling fn, got %v, called %v", err, called)
+	}
+}
+
+func TestChangesSince(t *testing.T) {
+	store := newStore(t, 0)
+	ctx := context.Background()
+
+	changes, seq, err := store.ChangesSince(ctx, 0, 10)
+	if err != nil || len(changes) != 1 || seq != 1 {
+		t.Fatalf("expected the wrapped store's change, got %+v, %d, %v", changes, seq, err)
+	}
+
+	if err := store.SetFaults(faultstorage.Faults{ErrorRate: 1, Methods: []string{"ChangesSince"}}); err != nil {
+		t.Fatalf("failed to set faults: %v", err)
+	}
+	if _, _, err := store.ChangesSince(ctx, 0, 10); !errors.Is(err, faultstorage.ErrInjected) {
+		t.Fatalf("expected the injected error, got %v", err)
+	}
+
+	plain := faultstorage.New(struct{ faultstorage.Store }{store})
+	if _, _, err := plain.ChangesSince(ctx, 0, 10); !errors.Is(err, storage.ErrChangeLogUnsupported) {
+		t.Fatalf("expected ErrChangeLogUnsupported, got %v", err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Conformance(t, func(t *testing.T) storage.QuoteStore {
+		inner, err := memorystorage.New()
+		if err != nil {
+			t.Fatalf("failed to init storage: %v", err)
+		}
+		return faultstorage.New(inner)
+	})
+}
@@ -0,0 +1,24 @@
+package faultstorage
+
+import (
+	"context"
+
+	"quotes-service/internal/storage"
+)
+
+// AddServed forwards to the wrapped store when it is a storage.ServedAdder
+// and calls its IncrementServed n times otherwise.
+func (s *Storage) AddServed(ctx context.Context, id int64, n int64) error {
+	if err := s.inject(ctx, "AddServed"); err != nil {
+		return err
+	}
+	if adder, ok := s.store.(storage.ServedAdder); ok {
+		return adder.AddServed(ctx, id, n)
+	}
+	for range n {
+		if err := s.store.IncrementServed(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package faultstorage
+
+import (
+	"context"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// WithinTx forwards to the wrapped store when it is a storage.Transactor
+// and fails with storage.ErrTxUnsupported otherwise. Calls made through tx
+// get the same faults as the store's own methods, so an injected error
+// exercises the rollback.
+func (s *Storage) WithinTx(ctx context.Context, fn func(tx storage.QuoteStore) error) error {
+	transactor, ok := s.store.(storage.Transactor)
+	if !ok {
+		return storage.ErrTxUnsupported
+	}
+	if err := s.inject(ctx, "WithinTx"); err != nil {
+		return err
+	}
+	return transactor.WithinTx(ctx, func(tx storage.QuoteStore) error {
+		return fn(&faultyTx{s: s, tx: tx})
+	})
+}
+
+type faultyTx struct {
+	s  *Storage
+	tx storage.QuoteStore
+}
+
+func (t *faultyTx) AddQuote(ctx context.Context, quote models.Quote) (int64, error) {
+	if err := t.s.inject(ctx, "AddQuote"); err != nil {
+		return 0, err
+	}
+	return t.tx.AddQuote(ctx, quote)
+}
+
+func (t *faultyTx) GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error) {
+	if err := t.s.inject(ctx, "GetAllQuotes"); err != nil {
+		return nil, err
+	}
+	return t.tx.GetAllQuotes(ctx, filter)
+}
+
+func (t *faultyTx) GetQuote(ctx context.Context, id int64) (models.Quote, error) {
+	if err := t.s.inject(ctx, "GetQuote"); err != nil {
+		return models.Quote{}, err
+	}
+	return t.tx.GetQuote(ctx, id)
+}
+
+func (t *faultyTx) UpdateQuote(ctx context.Context, id int64, update storage.QuoteUpdate, ifVersion int64) (models.Quote, error) {
+	if err := t.s.inject(ctx, "UpdateQuote"); err != nil {
+		return models.Quote{}, err
+	}
+	return t.tx.UpdateQuote(ctx, id, update, ifVersion)
+}
+
+func (t *faultyTx) DeleteQuote(ctx context.Context, id int64, ifVersion int64) error {
+	if err := t.s.inject(ctx, "DeleteQuote"); err != nil {
+		return err
+	}
+	return t.tx.DeleteQuote(ctx, id, ifVersion)
+}
@@ -0,0 +1,174 @@
+package memorystorage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// DefaultAuditEntries bounds the audit trail of a store created without
+// WithAuditRetention.
+const DefaultAuditEntries = 100000
+
+// WithAuditRetention bounds the audit trail to maxEntries entries no older
+// than maxAge. A zero maxAge keeps entries until the entry limit pushes
+// them out.
+func WithAuditRetention(maxEntries int, maxAge time.Duration) Option {
+	return func(s *Storage) {
+		s.audit.maxEntries = maxEntries
+		s.audit.maxAge = maxAge
+	}
+}
+
+// auditLog holds the audit trail apart from the quotes: it has a lock of
+// its own, and transactions and restores leave it alone.
+type auditLog struct {
+	mu sync.RWMutex
+	// entries are ordered by Seq, which has no gaps, and so by Time too,
+	// which the time range of a query is searched by.
+	entries []models.AuditEntry
+	seq     uint64
+	// byPrincipal holds the Seq of each principal's entries, in order.
+	byPrincipal map[string][]uint64
+	maxEntries  int
+	maxAge      time.Duration
+}
+
+func newAuditLog() *auditLog {
+	return &auditLog{
+		byPrincipal: make(map[string][]uint64),
+		maxEntries:  DefaultAuditEntries,
+	}
+}
+
+// RecordAudit implements storage.AuditStore.
+func (s *Storage) RecordAudit(ctx context.Context, entry models.AuditEntry) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	l := s.audit
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := s.now().UTC()
+	// The time index needs the entries in time order, whatever the wall
+	// clock does.
+	if n := len(l.entries); n > 0 && now.Before(l.entries[n-1].Time) {
+		now = l.entries[n-1].Time
+	}
+	l.seq++
+	entry.Seq = l.seq
+	entry.Time = now
+	l.entries = append(l.entries, entry)
+	l.byPrincipal[entry.Principal] = append(l.byPrincipal[entry.Principal], entry.Seq)
+	l.trim(now)
+	return nil
+}
+
+// QueryAudit implements storage.AuditStore. Entries past maxAge are left
+// out even before a write trims them.
+func (s *Storage) QueryAudit(ctx context.Context, q storage.AuditQuery) ([]models.AuditEntry, int, error) {
+	select {
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	default:
+	}
+
+	l := s.audit
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	lo, hi := l.window(q, s.now().UTC())
+	matches := func(e models.AuditEntry) bool {
+		return (q.Operation == "" || e.Operation == q.Operation) && (q.QuoteID == 0 || e.QuoteID == q.QuoteID)
+	}
+
+	var page []models.AuditEntry
+	total := 0
+	collect := func(e models.AuditEntry) {
+		if !matches(e) {
+			return
+		}
+		if total >= q.Offset && (q.Limit <= 0 || len(page) < q.Limit) {
+			page = append(page, e)
+		}
+		total++
+	}
+
+	if q.Principal != "" {
+		seqs := l.byPrincipal[q.Principal]
+		if lo < hi {
+			first, last := l.entries[lo].Seq, l.entries[hi-1].Seq
+			start := sort.Search(len(seqs), func(i int) bool { return seqs[i] >= first })
+			for _, seq := range seqs[start:] {
+				if seq > last {
+					break
+				}
+				collect(l.entries[seq-l.entries[0].Seq])
+			}
+		}
+	} else {
+		for _, e := range l.entries[lo:hi] {
+			collect(e)
+		}
+	}
+	return page, total, nil
+}
+
+// window returns the range of entries within the time range and after the
+// sequence number of q, and younger than maxAge.
+func (l *auditLog) window(q storage.AuditQuery, now time.Time) (int, int) {
+	lo := sort.Search(len(l.entries), func(i int) bool {
+		return l.entries[i].Seq > q.AfterSeq
+	})
+	since := q.Since
+	if l.maxAge > 0 && since.Before(now.Add(-l.maxAge)) {
+		since = now.Add(-l.maxAge)
+	}
+	if !since.IsZero() {
+		lo = max(lo, sort.Search(len(l.entries), func(i int) bool {
+			return !l.entries[i].Time.Before(since)
+		}))
+	}
+	hi := len(l.entries)
+	if !q.Until.IsZero() {
+		hi = sort.Search(len(l.entries), func(i int) bool {
+			return !l.entries[i].Time.Before(q.Until)
+		})
+	}
+	return lo, max(lo, hi)
+}
+
+// trim drops the entries beyond the entry limit or older than maxAge, and
+// their places in the principal index. The caller holds l.mu.
+func (l *auditLog) trim(now time.Time) {
+	drop := max(len(l.entries)-l.maxEntries, 0)
+	if l.maxAge > 0 {
+		cutoff := now.Add(-l.maxAge)
+		drop = max(drop, sort.Search(len(l.entries), func(i int) bool {
+			return !l.entries[i].Time.Before(cutoff)
+		}))
+	}
+	if drop == 0 {
+		return
+	}
+	// Each dropped entry is the oldest left of its principal.
+	for _, e := range l.entries[:drop] {
+		seqs := l.byPrincipal[e.Principal][1:]
+		if len(seqs) == 0 {
+			delete(l.byPrincipal, e.Principal)
+			continue
+		}
+		l.byPrincipal[e.Principal] = seqs
+	}
+	// As with the change log, append moves the entries to a fresh array
+	// as the trail grows, leaving the dropped ones behind.
+	l.entries = l.entries[drop:]
+}
@@ -0,0 +1,143 @@
+package memorystorage_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+var _ storage.AuditStore = (*memorystorage.Storage)(nil)
+
+func TestQueryAudit(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	now := start
+	store, err := memorystorage.New(memorystorage.WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+
+	// A minute apart: seq 1 at 12:00, seq 8 at 12:07.
+	recorded := []models.AuditEntry{
+		{Principal: "importer", Operation: "POST /quotes", QuoteID: 1, Status: 201},
+		{Principal: "importer", Operation: "POST /quotes", QuoteID: 2, Status: 201},
+		{Principal: "editor", Operation: "PATCH /quotes/{id}", QuoteID: 1, Status: 200},
+		{Operation: "POST /quotes", Status: 401},
+		{Principal: "editor", Operation: "DELETE /quotes/{id}", QuoteID: 2, Status: 200},
+		{Principal: "ops", Operation: "POST /authors/merge", Status: 200},
+		{Principal: "importer", Operation: "POST /quotes", QuoteID: 3, Status: 201},
+		{Principal: "editor", Operation: "PATCH /quotes/{id}", QuoteID: 3, Status: 409},
+	}
+	for _, e := range recorded {
+		if err := store.RecordAudit(ctx, e); err != nil {
+			t.Fatalf("failed to record: %v", err)
+		}
+		now = now.Add(time.Minute)
+	}
+
+	tests := []struct {
+		name      string
+		query     storage.AuditQuery
+		wantSeqs  []uint64
+		wantTotal int
+	}{
+		{name: "everything", query: storage.AuditQuery{}, wantSeqs: []uint64{1, 2, 3, 4, 5, 6, 7, 8}, wantTotal: 8},
+		{name: "principal", query: storage.AuditQuery{Principal: "editor"}, wantSeqs: []uint64{3, 5, 8}, wantTotal: 3},
+		{name: "operation", query: storage.AuditQuery{Operation: "POST /quotes"}, wantSeqs: []uint64{1, 2, 4, 7}, wantTotal: 4},
+		{name: "quote", query: storage.AuditQuery{QuoteID: 3}, wantSeqs: []uint64{7, 8}, wantTotal: 2},
+		{
+			name:      "time range",
+			query:     storage.AuditQuery{Since: start.Add(2 * time.Minute), Until: start.Add(5 * time.Minute)},
+			wantSeqs:  []uint64{3, 4, 5},
+			wantTotal: 3,
+		},
+		{
+			name:      "principal in time range",
+			query:     storage.AuditQuery{Principal: "importer", Since: start.Add(time.Minute), Until: start.Add(7 * time.Minute)},
+			wantSeqs:  []uint64{2, 7},
+			wantTotal: 2,
+		},
+		{
+			name:      "principal and operation",
+			query:     storage.AuditQuery{Principal: "editor", Operation: "PATCH /quotes/{id}"},
+			wantSeqs:  []uint64{3, 8},
+			wantTotal: 2,
+		},
+		{name: "page", query: storage.AuditQuery{Principal: "importer", Limit: 1, Offset: 1}, wantSeqs: []uint64{2}, wantTotal: 3},
+		{name: "after seq", query: storage.AuditQuery{Operation: "POST /quotes", AfterSeq: 2, Limit: 1}, wantSeqs: []uint64{4}, wantTotal: 2},
+		{name: "unknown principal", query: storage.AuditQuery{Principal: "nobody"}, wantTotal: 0},
+		{name: "empty range", query: storage.AuditQuery{Since: start.Add(time.Hour)}, wantTotal: 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			entries, total, err := store.QueryAudit(ctx, tc.query)
+			if err != nil {
+				t.Fatalf("failed to query: %v", err)
+			}
+			var seqs []uint64
+			for _, e := range entries {
+				seqs = append(seqs, e.Seq)
+			}
+			if !slices.Equal(seqs, tc.wantSeqs) || total != tc.wantTotal {
+				t.Fatalf("expected %v of %d, got %v of %d", tc.wantSeqs, tc.wantTotal, seqs, total)
+			}
+		})
+	}
+}
+
+func TestAuditRetention(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	store, err := memorystorage.New(
+		memorystorage.WithClock(func() time.Time { return now }),
+		memorystorage.WithAuditRetention(3, time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	record := func(principal string) {
+		if err := store.RecordAudit(ctx, models.AuditEntry{Principal: principal, Operation: "POST /quotes"}); err != nil {
+			t.Fatalf("failed to record: %v", err)
+		}
+	}
+	query := func(q storage.AuditQuery) []uint64 {
+		entries, _, err := store.QueryAudit(ctx, q)
+		if err != nil {
+			t.Fatalf("failed to query: %v", err)
+		}
+		var seqs []uint64
+		for _, e := range entries {
+			seqs = append(seqs, e.Seq)
+		}
+		return seqs
+	}
+
+	for _, principal := range []string{"a", "b", "a", "b", "a"} {
+		record(principal)
+	}
+	if got := query(storage.AuditQuery{}); !slices.Equal(got, []uint64{3, 4, 5}) {
+		t.Fatalf("expected the newest three entries, got %v", got)
+	}
+	if got := query(storage.AuditQuery{Principal: "a"}); !slices.Equal(got, []uint64{3, 5}) {
+		t.Fatalf("expected the principal index trimmed too, got %v", got)
+	}
+
+	// Entries past the age limit are gone before the next write trims them.
+	now = now.Add(90 * time.Minute)
+	if got := query(storage.AuditQuery{Principal: "b"}); got != nil {
+		t.Fatalf("expected expired entries left out, got %v", got)
+	}
+	record("b")
+	if got := query(storage.AuditQuery{}); !slices.Equal(got, []uint64{6}) {
+		t.Fatalf("expected only the new entry, got %v", got)
+	}
+	if got := query(storage.AuditQuery{Principal: "a"}); got != nil {
+		t.Fatalf("expected no entries of a, got %v", got)
+	}
+}
@@ -0,0 +1,130 @@
+package memorystorage
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"quotes-service/internal/lib/authorname"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// authorList is every author summarized and sorted as of one version of
+// the store, so that paging through authors does not summarize and sort
+// them all on every call.
+type authorList struct {
+	version uint64
+	// keys are the sorted match keys, and authors their summaries.
+	keys    []string
+	authors []models.AuthorSummary
+	// byCount and byCountDesc index authors by quote count, up and down,
+	// then key.
+	byCount     []int
+	byCountDesc []int
+}
+
+// GetAuthors returns the page of author summaries, one per match key, that
+// query selects, and how many authors the query matches in all.
+func (s *Storage) GetAuthors(ctx context.Context, query storage.AuthorQuery) ([]models.AuthorSummary, int, error) {
+	select {
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	default:
+	}
+
+	list, err := s.authorList(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Keys with a prefix are a run of the sorted keys.
+	lo, hi := 0, len(list.keys)
+	if query.Prefix != "" {
+		prefix := authorname.Key(query.Prefix)
+		lo = sort.SearchStrings(list.keys, prefix)
+		hi = lo + sort.Search(hi-lo, func(i int) bool {
+			return !strings.HasPrefix(list.keys[lo+i], prefix)
+		})
+	}
+	total := hi - lo
+
+	var order []int
+	if query.Sort == storage.AuthorSortQuoteCount {
+		byCount := list.byCount
+		if query.Desc {
+			byCount = list.byCountDesc
+		}
+		order = make([]int, 0, total)
+		for _, i := range byCount {
+			if i >= lo && i < hi {
+				order = append(order, i)
+			}
+		}
+	}
+	at := func(n int) models.AuthorSummary {
+		if order != nil {
+			return list.authors[order[n]]
+		}
+		if query.Desc {
+			return list.authors[hi-1-n]
+		}
+		return list.authors[lo+n]
+	}
+
+	start := min(max(query.Offset, 0), total)
+	end := total
+	if query.Limit > 0 {
+		end = min(start+query.Limit, total)
+	}
+	authors := make([]models.AuthorSummary, 0, end-start)
+	for n := start; n < end; n++ {
+		authors = append(authors, at(n))
+	}
+	return authors, total, nil
+}
+
+// authorList returns the author list of the current version, building it
+// if the quotes changed since it was last built.
+func (s *Storage) authorList(ctx context.Context) (*authorList, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	s.authorsMu.Lock()
+	defer s.authorsMu.Unlock()
+	if s.authors != nil && s.authors.version == s.version {
+		return s.authors, nil
+	}
+
+	keys := slices.Sorted(maps.Keys(s.authorIndex))
+	authors := make([]models.AuthorSummary, 0, len(keys))
+	for i, key := range keys {
+		if err := checkCtx(ctx, i); err != nil {
+			return nil, err
+		}
+		ids := slices.Sorted(maps.Keys(s.authorIndex[key]))
+		quotes := make([]models.Quote, len(ids))
+		for j, id := range ids {
+			quotes[j] = s.quotes[id]
+		}
+		authors = append(authors, authorname.Summarize(quotes))
+	}
+	// Indexes follow the keys, so stable sorts leave ties by key.
+	byCount := make([]int, len(authors))
+	for i := range byCount {
+		byCount[i] = i
+	}
+	byCountDesc := slices.Clone(byCount)
+	slices.SortStableFunc(byCount, func(a, b int) int {
+		return cmp.Compare(authors[a].QuoteCount, authors[b].QuoteCount)
+	})
+	slices.SortStableFunc(byCountDesc, func(a, b int) int {
+		return cmp.Compare(authors[b].QuoteCount, authors[a].QuoteCount)
+	})
+
+	s.authors = &authorList{version: s.version, keys: keys, authors: authors, byCount: byCount, byCountDesc: byCountDesc}
+	return s.authors, nil
+}
@@ -0,0 +1,198 @@
+package memorystorage_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func TestAuthorKeys(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for _, author := range []string{"einstein", "Einstein", "  Einstein ", "EINSTEIN.", "Niels Bohr"} {
+		if _, err := store.AddQuote(ctx, models.Quote{Text: "Quote by " + author, Author: author}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+
+	quote, _ := store.GetQuote(ctx, 3)
+	if quote.Author != "Einstein" || quote.AuthorKey != "einstein" {
+		t.Fatalf("expected display %q and key %q, got %q and %q", "Einstein", "einstein", quote.Author, quote.AuthorKey)
+	}
+
+	byAuthor, err := store.GetQuotesByAuthor(ctx, "einstein!", storage.QuoteFilter{})
+	if err != nil || len(byAuthor) != 4 {
+		t.Fatalf("expected every spelling to match, got %d, %v", len(byAuthor), err)
+	}
+
+	authors, _, err := store.GetAuthors(ctx, storage.AuthorQuery{})
+	if err != nil {
+		t.Fatalf("failed to get authors: %v", err)
+	}
+	names := func(authors []models.AuthorSummary) map[string]int {
+		counts := make(map[string]int, len(authors))
+		for _, a := range authors {
+			counts[a.Name] = a.QuoteCount
+		}
+		return counts
+	}
+	if got, want := names(authors), map[string]int{"Einstein": 4, "Niels Bohr": 1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected authors %v, got %v", want, got)
+	}
+	if want := []string{"einstein", "Einstein", "EINSTEIN."}; !reflect.DeepEqual(authors[0].Variants, want) {
+		t.Fatalf("expected variants %v, got %v", want, authors[0].Variants)
+	}
+
+	// Respelling an author is a merge within one key.
+	if _, err := store.MergeAuthors(ctx, "Albert Einstein", []string{"einstein"}); err != nil {
+		t.Fatalf("failed to merge authors: %v", err)
+	}
+	renamed := "Niels  Bohr"
+	if _, err := store.UpdateQuote(ctx, 1, storage.QuoteUpdate{Author: &renamed}, storage.AnyVersion); err != nil {
+		t.Fatalf("failed to update quote: %v", err)
+	}
+	authors, _, _ = store.GetAuthors(ctx, storage.AuthorQuery{})
+	if got, want := names(authors), map[string]int{"Albert Einstein": 3, "Niels Bohr": 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected authors %v after renames, got %v", want, got)
+	}
+	if old, _ := store.GetQuotesByAuthor(ctx, "Einstein", storage.QuoteFilter{}); len(old) != 0 {
+		t.Fatalf("expected no quotes left under the old key, got %d", len(old))
+	}
+}
+
+func TestRestoreDerivesAuthorKeys(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	// Snapshots taken before quotes had keys carry none, or a stale one.
+	err = store.Restore(ctx, []models.Quote{
+		{ID: 1, Text: "One", Author: "Lao  Tzu"},
+		{ID: 2, Text: "Two", Author: "lao-tzu", AuthorKey: "stale"},
+	})
+	if err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+	quotes, err := store.GetQuotesByAuthor(ctx, "LAO TZU", storage.QuoteFilter{})
+	if err != nil || len(quotes) != 2 {
+		t.Fatalf("expected both quotes under one key, got %d, %v", len(quotes), err)
+	}
+	for _, q := range quotes {
+		if q.AuthorKey != "lao tzu" {
+			t.Errorf("quote %d: expected key %q, got %q", q.ID, "lao tzu", q.AuthorKey)
+		}
+	}
+	if quotes[0].Author != "Lao Tzu" {
+		t.Errorf("expected the display form to be normalized, got %q", quotes[0].Author)
+	}
+}
+
+func TestGetAuthorsQuery(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	counts := map[string]int{"Lao Tzu": 2, "Laozi": 1, "Albert Einstein": 3, "Marcus Aurelius": 2, "Seneca": 1}
+	for author, n := range counts {
+		for i := range n {
+			if _, err := store.AddQuote(ctx, models.Quote{Text: author + " " + string(rune('a'+i)), Author: author}); err != nil {
+				t.Fatalf("failed to add quote: %v", err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name          string
+		query         storage.AuthorQuery
+		expectedNames []string
+		expectedTotal int
+	}{
+		{
+			name:          "by name",
+			expectedNames: []string{"Albert Einstein", "Lao Tzu", "Laozi", "Marcus Aurelius", "Seneca"},
+			expectedTotal: 5,
+		},
+		{
+			name:          "by name descending",
+			query:         storage.AuthorQuery{Desc: true},
+			expectedNames: []string{"Seneca", "Marcus Aurelius", "Laozi", "Lao Tzu", "Albert Einstein"},
+			expectedTotal: 5,
+		},
+		{
+			name:          "by quote count",
+			query:         storage.AuthorQuery{Sort: storage.AuthorSortQuoteCount},
+			expectedNames: []string{"Laozi", "Seneca", "Lao Tzu", "Marcus Aurelius", "Albert Einstein"},
+			expectedTotal: 5,
+		},
+		{
+			name:          "by quote count descending keeps ties by name",
+			query:         storage.AuthorQuery{Sort: storage.AuthorSortQuoteCount, Desc: true},
+			expectedNames: []string{"Albert Einstein", "Lao Tzu", "Marcus Aurelius", "Laozi", "Seneca"},
+			expectedTotal: 5,
+		},
+		{
+			name:          "prefix",
+			query:         storage.AuthorQuery{Prefix: "LAO"},
+			expectedNames: []string{"Lao Tzu", "Laozi"},
+			expectedTotal: 2,
+		},
+		{
+			name:          "prefix by quote count descending",
+			query:         storage.AuthorQuery{Prefix: "lao", Sort: storage.AuthorSortQuoteCount, Desc: true},
+			expectedNames: []string{"Lao Tzu", "Laozi"},
+			expectedTotal: 2,
+		},
+		{
+			name:          "page",
+			query:         storage.AuthorQuery{Sort: storage.AuthorSortQuoteCount, Desc: true, Limit: 2, Offset: 1},
+			expectedNames: []string{"Lao Tzu", "Marcus Aurelius"},
+			expectedTotal: 5,
+		},
+		{
+			name:          "page past the end",
+			query:         storage.AuthorQuery{Limit: 2, Offset: 10},
+			expectedNames: []string{},
+			expectedTotal: 5,
+		},
+		{
+			name:          "no match",
+			query:         storage.AuthorQuery{Prefix: "zeno"},
+			expectedNames: []string{},
+			expectedTotal: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			authors, total, err := store.GetAuthors(ctx, tc.query)
+			if err != nil {
+				t.Fatalf("failed to get authors: %v", err)
+			}
+			names := make([]string, 0, len(authors))
+			for _, a := range authors {
+				names = append(names, a.Name)
+			}
+			if !reflect.DeepEqual(names, tc.expectedNames) || total != tc.expectedTotal {
+				t.Fatalf("expected %v of %d, got %v of %d", tc.expectedNames, tc.expectedTotal, names, total)
+			}
+		})
+	}
+
+	// A write must show up in the next listing.
+	if _, err := store.AddQuote(ctx, models.Quote{Text: "Seneca again", Author: "Seneca"}); err != nil {
+		t.Fatalf("failed to add quote: %v", err)
+	}
+	authors, _, err := store.GetAuthors(ctx, storage.AuthorQuery{Prefix: "seneca"})
+	if err != nil || len(authors) != 1 || authors[0].QuoteCount != 2 {
+		t.Fatalf("expected Seneca with 2 quotes after a write, got %+v, %v", authors, err)
+	}
+}
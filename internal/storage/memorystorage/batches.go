@@ -0,0 +1,159 @@
+package memorystorage
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"quotes-service/internal/lib/authorname"
+	"quotes-service/internal/lib/language"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// DeleteAuthorBatch implements storage.Batcher. The batch's quotes leave
+// the list in one pass rather than one at a time as DeleteQuote's do.
+func (s *Storage) DeleteAuthorBatch(ctx context.Context, author string, batch storage.Batch) (storage.BatchResult, error) {
+	select {
+	case <-ctx.Done():
+		return storage.BatchResult{}, ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids, result := nextBatch(s.authorIndex[authorname.Key(author)], batch)
+	if len(ids) == 0 {
+		return result, nil
+	}
+	deleted := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		quote := s.quotes[id]
+		delete(s.quotes, id)
+		delete(s.publicIDs, quote.PublicID)
+		removeFromIndex(s.langIndex, language.Primary(quote.Lang), id)
+		removeFromIndex(s.authorIndex, quote.AuthorKey, id)
+		s.unindexFingerprint(quote)
+		s.removeFromCollections(id)
+		s.removeFromFavorites(id)
+		delete(s.claims, id)
+		delete(s.served, id)
+		s.unindexTokens(id)
+		s.logChange(models.ChangeDelete, quote)
+		deleted[id] = struct{}{}
+	}
+	s.quotesList = slices.DeleteFunc(s.quotesList, func(q models.Quote) bool {
+		_, ok := deleted[q.ID]
+		return ok
+	})
+	s.rebuildWeights()
+	s.version++
+
+	result.IDs = ids
+	return result, nil
+}
+
+// MergeAuthorsBatch implements storage.Batcher. A quote whose key is that
+// of several from names is counted under the first.
+func (s *Storage) MergeAuthorsBatch(ctx context.Context, into string, from []string, batch storage.Batch) (storage.BatchResult, error) {
+	select {
+	case <-ctx.Done():
+		return storage.BatchResult{}, ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	into = authorname.Display(into)
+	intoKey := authorname.Key(into)
+	counts := make(map[string]int, len(from))
+	nameOf := make(map[int64]string)
+	for _, name := range from {
+		counts[name] = 0
+		for id := range s.authorIndex[authorname.Key(name)] {
+			if _, seen := nameOf[id]; !seen {
+				nameOf[id] = name
+			}
+		}
+	}
+
+	ids, result := nextBatch(nameOf, batch)
+	result.Counts = counts
+	now := s.now().UTC()
+	for _, id := range ids {
+		q := s.quotes[id]
+		if q.Author == into {
+			continue
+		}
+		counts[nameOf[id]]++
+		removeFromIndex(s.authorIndex, q.AuthorKey, id)
+		addToIndex(s.authorIndex, intoKey, id)
+		s.unindexFingerprint(q)
+		q.Author = into
+		q.AuthorKey = intoKey
+		s.indexFingerprint(q)
+		q.UpdatedAt = now
+		q.Version++
+		s.quotes[id] = q
+		s.quotesList[s.listIndex(id)] = q
+		s.logChange(models.ChangeUpdate, q)
+		result.IDs = append(result.IDs, id)
+	}
+	if len(result.IDs) > 0 {
+		s.version++
+	}
+
+	return result, nil
+}
+
+// ReindexBatch implements storage.Batcher. It walks quotesList, which is
+// in ID order, so a batch costs its own size rather than the store's. The
+// quotes themselves are left as they are, so the version does not change.
+func (s *Storage) ReindexBatch(ctx context.Context, batch storage.Batch) (storage.BatchResult, error) {
+	select {
+	case <-ctx.Done():
+		return storage.BatchResult{}, ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start := sort.Search(len(s.quotesList), func(i int) bool { return s.quotesList[i].ID > batch.After })
+	end := min(start+batch.Limit, len(s.quotesList))
+	result := storage.BatchResult{Last: batch.After, Done: end == len(s.quotesList)}
+	for _, q := range s.quotesList[start:end] {
+		s.unindexTokens(q.ID)
+		s.indexTokens(q)
+		addToIndex(s.langIndex, language.Primary(q.Lang), q.ID)
+		addToIndex(s.authorIndex, q.AuthorKey, q.ID)
+		s.indexFingerprint(q)
+		result.IDs = append(result.IDs, q.ID)
+		result.Last = q.ID
+	}
+	return result, nil
+}
+
+// nextBatch returns the IDs among the keys of set that fall in batch, and
+// a result for them with no changes yet.
+func nextBatch[V any](set map[int64]V, batch storage.Batch) ([]int64, storage.BatchResult) {
+	var ids []int64
+	for id := range set {
+		if id > batch.After {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	done := len(ids) <= batch.Limit
+	if !done {
+		ids = ids[:batch.Limit]
+	}
+
+	result := storage.BatchResult{Last: batch.After, Done: done}
+	if len(ids) > 0 {
+		result.Last = ids[len(ids)-1]
+	}
+	return ids, result
+}
@@ -0,0 +1,130 @@
+package memorystorage_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func newBatchStore(t *testing.T, authors ...string) *memorystorage.Storage {
+	t.Helper()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for i, author := range authors {
+		if _, err := store.AddQuote(context.Background(), models.Quote{Text: fmt.Sprintf("quote %d", i), Author: author}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+	return store
+}
+
+func TestDeleteAuthorBatch(t *testing.T) {
+	ctx := context.Background()
+	store := newBatchStore(t, "Alice", "Bob", "alice", "Alice", "Bob", "ALICE.", "Alice")
+
+	var batches []storage.BatchResult
+	batch := storage.Batch{Limit: 2}
+	for {
+		result, err := store.DeleteAuthorBatch(ctx, "alice", batch)
+		if err != nil {
+			t.Fatalf("failed to delete batch: %v", err)
+		}
+		batches = append(batches, result)
+		if result.Done {
+			break
+		}
+		batch.After = result.Last
+	}
+	if got := fmt.Sprint(batches); got != "[{[1 3] 3 false map[]} {[4 6] 6 false map[]} {[7] 7 true map[]}]" {
+		t.Fatalf("unexpected batches %s", got)
+	}
+
+	left, err := store.GetAllQuotes(ctx, storage.QuoteFilter{})
+	if err != nil || len(left) != 2 || left[0].Author != "Bob" || left[1].Author != "Bob" {
+		t.Fatalf("expected Bob's quotes left, got %+v (%v)", left, err)
+	}
+	for range 20 {
+		if q, err := store.GetRandomQuote(ctx, storage.RandomOptions{}); err != nil || q.Author != "Bob" {
+			t.Fatalf("expected a random quote by Bob, got %+v (%v)", q, err)
+		}
+	}
+
+	// Replaying a batch finds nothing left to delete.
+	replayed, err := store.DeleteAuthorBatch(ctx, "alice", storage.Batch{After: 3, Limit: 2})
+	if err != nil || len(replayed.IDs) != 0 || !replayed.Done || replayed.Last != 3 {
+		t.Fatalf("expected an empty replay, got %+v (%v)", replayed, err)
+	}
+}
+
+func TestMergeAuthorsBatch(t *testing.T) {
+	ctx := context.Background()
+	store := newBatchStore(t, "A. Einstein", "Albert Einstein", "Einstein, A.", "A. Einstein", "A. Einstein")
+	from := []string{"A. Einstein", "Einstein, A."}
+
+	counts := map[string]int{}
+	var changed []int64
+	batch := storage.Batch{Limit: 2}
+	for {
+		result, err := store.MergeAuthorsBatch(ctx, "Albert Einstein", from, batch)
+		if err != nil {
+			t.Fatalf("failed to merge batch: %v", err)
+		}
+		for name, n := range result.Counts {
+			counts[name] += n
+		}
+		changed = append(changed, result.IDs...)
+		if result.Done {
+			break
+		}
+		batch.After = result.Last
+	}
+	if fmt.Sprint(counts) != "map[A. Einstein:3 Einstein, A.:1]" || fmt.Sprint(changed) != "[1 3 4 5]" {
+		t.Fatalf("unexpected counts %v and changes %v", counts, changed)
+	}
+	merged, err := store.GetQuotesByAuthor(ctx, "Albert Einstein", storage.QuoteFilter{})
+	if err != nil || len(merged) != 5 {
+		t.Fatalf("expected 5 merged quotes, got %d (%v)", len(merged), err)
+	}
+
+	version, _ := store.Version(ctx)
+	replayed, err := store.MergeAuthorsBatch(ctx, "Albert Einstein", from, storage.Batch{Limit: 10})
+	if after, _ := store.Version(ctx); err != nil || len(replayed.IDs) != 0 || after != version {
+		t.Fatalf("expected a replay to change nothing, got %+v and version %d to %d (%v)", replayed, version, after, err)
+	}
+}
+
+func TestReindexBatch(t *testing.T) {
+	ctx := context.Background()
+	store := newBatchStore(t, "A", "B", "C", "D", "E")
+	version, _ := store.Version(ctx)
+
+	var reindexed []int64
+	batch := storage.Batch{Limit: 2}
+	for {
+		result, err := store.ReindexBatch(ctx, batch)
+		if err != nil {
+			t.Fatalf("failed to reindex batch: %v", err)
+		}
+		reindexed = append(reindexed, result.IDs...)
+		if result.Done {
+			break
+		}
+		batch.After = result.Last
+	}
+	if fmt.Sprint(reindexed) != "[1 2 3 4 5]" {
+		t.Fatalf("expected every quote reindexed once, got %v", reindexed)
+	}
+	if after, _ := store.Version(ctx); after != version {
+		t.Fatalf("expected the version to stay %d, got %d", version, after)
+	}
+	similar, err := store.GetSimilarQuotes(ctx, 1, 10)
+	if err != nil || len(similar) != 4 {
+		t.Fatalf("expected the search index intact, got %d similar quotes (%v)", len(similar), err)
+	}
+}
@@ -0,0 +1,307 @@
+package memorystorage_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+// benchSizes are the store sizes every benchmark runs at. The 1M size takes
+// a while to build and is skipped with -short.
+var benchSizes = []struct {
+	name  string
+	size  int
+	large bool
+}{
+	{name: "1k", size: 1_000},
+	{name: "100k", size: 100_000},
+	{name: "1M", size: 1_000_000, large: true},
+}
+
+const (
+	benchAuthors    = 500
+	benchVocabulary = 5000
+	benchWords      = 10
+)
+
+// benchStores caches read-only stores by size so that the slow setup of
+// the large ones is paid once per run.
+var benchStores = map[int]*memorystorage.Storage{}
+
+func benchAuthor(i int) string {
+	return fmt.Sprintf("Author %d", i%benchAuthors)
+}
+
+// benchQuote returns a deterministic quote with words drawn from a fixed
+// vocabulary, so the similarity index has realistic overlap.
+func benchQuote(rnd *rand.Rand, i int) models.Quote {
+	words := make([]string, benchWords)
+	for j := range words {
+		words[j] = fmt.Sprintf("word%d", rnd.Intn(benchVocabulary))
+	}
+	q := models.Quote{
+		Text:   strings.Join(words, " "),
+		Author: benchAuthor(i),
+		Weight: 1 + rnd.Intn(storage.MaxWeight),
+		Lang:   "en",
+	}
+	if i%3 == 0 {
+		q.Source = "Collected works"
+	}
+	return q
+}
+
+func newBenchStore(b *testing.B, size int) *memorystorage.Storage {
+	b.Helper()
+	store, err := memorystorage.New()
+	if err != nil {
+		b.Fatalf("failed to init storage: %v", err)
+	}
+	ctx := context.Background()
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < size; i++ {
+		if _, err := store.AddQuote(ctx, benchQuote(rnd, i)); err != nil {
+			b.Fatalf("failed to add quote: %v", err)
+		}
+	}
+	return store
+}
+
+func sharedBenchStore(b *testing.B, size int) *memorystorage.Storage {
+	b.Helper()
+	if store, ok := benchStores[size]; ok {
+		return store
+	}
+	store := newBenchStore(b, size)
+	benchStores[size] = store
+	return store
+}
+
+// runSizes runs fn once per size. Read-only benchmarks share a store per
+// size; mutating ones must pass fresh so they get a store of their own.
+func runSizes(b *testing.B, fresh bool, fn func(b *testing.B, store *memorystorage.Storage, size int)) {
+	for _, bs := range benchSizes {
+		b.Run(bs.name, func(b *testing.B) {
+			if bs.large && testing.Short() {
+				b.Skip("skipping large store in short mode")
+			}
+			var store *memorystorage.Storage
+			if fresh {
+				store = newBenchStore(b, bs.size)
+			} else {
+				store = sharedBenchStore(b, bs.size)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			fn(b, store, bs.size)
+		})
+	}
+}
+
+func BenchmarkAddQuote(b *testing.B) {
+	runSizes(b, true, func(b *testing.B, store *memorystorage.Storage, size int) {
+		ctx := context.Background()
+		rnd := rand.New(rand.NewSource(2))
+		for i := 0; i < b.N; i++ {
+			if _, err := store.AddQuote(ctx, benchQuote(rnd, i)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetAllQuotes(b *testing.B) {
+	runSizes(b, false, func(b *testing.B, store *memorystorage.Storage, size int) {
+		ctx := context.Background()
+		for i := 0; i < b.N; i++ {
+			if _, err := store.GetAllQuotes(ctx, storage.QuoteFilter{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetAllQuotesFiltered(b *testing.B) {
+	hasSource := true
+	runSizes(b, false, func(b *testing.B, store *memorystorage.Storage, size int) {
+		ctx := context.Background()
+		for i := 0; i < b.N; i++ {
+			if _, err := store.GetAllQuotes(ctx, storage.QuoteFilter{HasSource: &hasSource}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetQuote(b *testing.B) {
+	runSizes(b, false, func(b *testing.B, store *memorystorage.Storage, size int) {
+		ctx := context.Background()
+		for i := 0; i < b.N; i++ {
+			if _, err := store.GetQuote(ctx, int64(i%size)+1); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetRandomQuote(b *testing.B) {
+	runSizes(b, false, func(b *testing.B, store *memorystorage.Storage, size int) {
+		ctx := context.Background()
+		for i := 0; i < b.N; i++ {
+			if _, err := store.GetRandomQuote(ctx, storage.RandomOptions{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetRandomQuoteExcluding(b *testing.B) {
+	runSizes(b, false, func(b *testing.B, store *memorystorage.Storage, size int) {
+		ctx := context.Background()
+		opts := storage.RandomOptions{ExcludeIDs: make([]int64, 0, 20)}
+		for id := int64(1); id <= 20; id++ {
+			opts.ExcludeIDs = append(opts.ExcludeIDs, id)
+		}
+		for i := 0; i < b.N; i++ {
+			if _, err := store.GetRandomQuote(ctx, opts); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetQuotesByAuthor(b *testing.B) {
+	runSizes(b, false, func(b *testing.B, store *memorystorage.Storage, size int) {
+		ctx := context.Background()
+		for i := 0; i < b.N; i++ {
+			if _, err := store.GetQuotesByAuthor(ctx, benchAuthor(i), storage.QuoteFilter{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetPopularQuotes(b *testing.B) {
+	runSizes(b, false, func(b *testing.B, store *memorystorage.Storage, size int) {
+		ctx := context.Background()
+		for i := 0; i < b.N; i++ {
+			if _, err := store.GetPopularQuotes(ctx, 10); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetSimilarQuotes(b *testing.B) {
+	runSizes(b, false, func(b *testing.B, store *memorystorage.Storage, size int) {
+		ctx := context.Background()
+		for i := 0; i < b.N; i++ {
+			if _, err := store.GetSimilarQuotes(ctx, int64(i%size)+1, 5); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkIncrementServed(b *testing.B) {
+	runSizes(b, false, func(b *testing.B, store *memorystorage.Storage, size int) {
+		ctx := context.Background()
+		for i := 0; i < b.N; i++ {
+			if err := store.IncrementServed(ctx, int64(i%size)+1); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkUpdateQuote(b *testing.B) {
+	runSizes(b, true, func(b *testing.B, store *memorystorage.Storage, size int) {
+		ctx := context.Background()
+		for i := 0; i < b.N; i++ {
+			text := fmt.Sprintf("word%d word%d updated", i%benchVocabulary, (i+1)%benchVocabulary)
+			update := storage.QuoteUpdate{Text: &text}
+			if _, err := store.UpdateQuote(ctx, int64(i%size)+1, update, storage.AnyVersion); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkDeleteQuote deletes the oldest quote and adds a new one per
+// iteration, so the store keeps its size however large b.N gets.
+func BenchmarkDeleteQuote(b *testing.B) {
+	runSizes(b, true, func(b *testing.B, store *memorystorage.Storage, size int) {
+		ctx := context.Background()
+		rnd := rand.New(rand.NewSource(3))
+		for i := 0; i < b.N; i++ {
+			if err := store.DeleteQuote(ctx, int64(i)+1, storage.AnyVersion); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := store.AddQuote(ctx, benchQuote(rnd, i)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkMergeAuthors(b *testing.B) {
+	runSizes(b, true, func(b *testing.B, store *memorystorage.Storage, size int) {
+		ctx := context.Background()
+		for i := 0; i < b.N; i++ {
+			// Swap two authors back and forth so every run moves quotes.
+			from, into := benchAuthor(0), benchAuthor(1)
+			if i%2 == 1 {
+				from, into = into, from
+			}
+			if _, err := store.MergeAuthors(ctx, into, []string{from}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetFavorites(b *testing.B) {
+	runSizes(b, true, func(b *testing.B, store *memorystorage.Storage, size int) {
+		ctx := context.Background()
+		for id := int64(1); id <= int64(min(size, 200)); id++ {
+			if err := store.AddFavorite(ctx, "alice", id); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, _, err := store.GetFavorites(ctx, "alice", 20, 40); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetRandomCollectionQuote(b *testing.B) {
+	runSizes(b, true, func(b *testing.B, store *memorystorage.Storage, size int) {
+		ctx := context.Background()
+		collection, err := store.CreateCollection(ctx, "Bench", "")
+		if err != nil {
+			b.Fatal(err)
+		}
+		ids := make([]int64, 0, 200)
+		for id := int64(1); id <= int64(min(size, 200)); id++ {
+			ids = append(ids, id)
+		}
+		if err := store.AddQuotesToCollection(ctx, collection.ID, ids); err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := store.GetRandomCollectionQuote(ctx, collection.ID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package memorystorage
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// DefaultChangeLogEntries bounds the change log of a store created without
+// WithChangeLog.
+const DefaultChangeLogEntries = 10000
+
+// WithChangeLog bounds the change log to maxEntries changes no older than
+// maxAge. A zero maxAge keeps changes until the entry limit pushes them
+// out.
+func WithChangeLog(maxEntries int, maxAge time.Duration) Option {
+	return func(s *Storage) {
+		s.changes.maxEntries = maxEntries
+		s.changes.maxAge = maxAge
+	}
+}
+
+// changeLog records every quote written, one entry per quote even when a
+// single call writes many, so that replaying the entries in order rebuilds
+// the store's quotes. It is not safe for concurrent use; the store's lock
+// guards it.
+type changeLog struct {
+	// entries are ordered by Seq, oldest first.
+	entries []models.QuoteChange
+	// seq is the sequence number of the newest change.
+	seq uint64
+	// trimmed is the sequence number of the newest change dropped from the
+	// log. Callers that have seen less than that have to resync.
+	trimmed    uint64
+	maxEntries int
+	maxAge     time.Duration
+}
+
+func newChangeLog() *changeLog {
+	return &changeLog{maxEntries: DefaultChangeLogEntries}
+}
+
+// record logs a change to the quote with id; q is nil for deletes.
+func (l *changeLog) record(op string, id int64, q *models.Quote, now time.Time) {
+	l.seq++
+	l.entries = append(l.entries, models.QuoteChange{
+		Seq:     l.seq,
+		Op:      op,
+		QuoteID: id,
+		Quote:   q,
+		Time:    now,
+	})
+	l.trim(now)
+}
+
+// trim drops the changes beyond the entry limit or older than maxAge.
+func (l *changeLog) trim(now time.Time) {
+	drop := max(len(l.entries)-l.maxEntries, 0)
+	if l.maxAge > 0 {
+		drop = max(drop, l.expired(now))
+	}
+	if drop == 0 {
+		return
+	}
+	l.trimmed = l.entries[drop-1].Seq
+	// Reslicing rather than shifting leaves the array a clone shares
+	// untouched; append moves the entries to a fresh one as the log grows.
+	l.entries = l.entries[drop:]
+}
+
+// expired returns how many of the oldest entries are older than maxAge.
+func (l *changeLog) expired(now time.Time) int {
+	cutoff := now.Add(-l.maxAge)
+	return sort.Search(len(l.entries), func(i int) bool {
+		return !l.entries[i].Time.Before(cutoff)
+	})
+}
+
+// since returns up to limit changes after seq without modifying the log,
+// treating entries past maxAge as already trimmed.
+func (l *changeLog) since(seq uint64, limit int, now time.Time) ([]models.QuoteChange, error) {
+	horizon := l.trimmed
+	if l.maxAge > 0 {
+		if n := l.expired(now); n > 0 {
+			horizon = l.entries[n-1].Seq
+		}
+	}
+	if seq < horizon || seq > l.seq {
+		return nil, storage.ErrChangesExpired
+	}
+	start := sort.Search(len(l.entries), func(i int) bool {
+		return l.entries[i].Seq > seq
+	})
+	end := min(start+limit, len(l.entries))
+	return slices.Clone(l.entries[start:end]), nil
+}
+
+// clone returns a copy of the log for a transaction. Entries are never
+// modified, so they can be shared.
+func (l *changeLog) clone() *changeLog {
+	c := *l
+	c.entries = slices.Clip(l.entries)
+	return &c
+}
+
+// ChangesSince implements storage.ChangeLog. The log lives in memory with
+// the quotes, so it starts over whenever they do.
+func (s *Storage) ChangesSince(ctx context.Context, since uint64, limit int) ([]models.QuoteChange, uint64, error) {
+	select {
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	changes, err := s.changes.since(since, limit, s.now().UTC())
+	if err != nil {
+		return nil, 0, err
+	}
+	return changes, s.changes.seq, nil
+}
+
+// logChange records a change to a quote. The caller must hold s.mu.
+func (s *Storage) logChange(op string, q models.Quote) {
+	if op == models.ChangeDelete {
+		s.changes.record(op, q.ID, nil, s.now().UTC())
+		return
+	}
+	s.changes.record(op, q.ID, &q, s.now().UTC())
+}
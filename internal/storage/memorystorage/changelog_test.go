@@ -0,0 +1,170 @@
+package memorystorage_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+var _ storage.ChangeLog = (*memorystorage.Storage)(nil)
+
+type change struct {
+	seq uint64
+	op  string
+	id  int64
+}
+
+func changesOf(t *testing.T, store *memorystorage.Storage, since uint64) []change {
+	t.Helper()
+	entries, _, err := store.ChangesSince(context.Background(), since, 100)
+	if err != nil {
+		t.Fatalf("failed to get changes since %d: %v", since, err)
+	}
+	got := make([]change, len(entries))
+	for i, e := range entries {
+		got[i] = change{seq: e.Seq, op: e.Op, id: e.QuoteID}
+		if (e.Quote == nil) != (e.Op == models.ChangeDelete) {
+			t.Fatalf("change %d: expected a snapshot for everything but deletes, got %+v", e.Seq, e)
+		}
+	}
+	return got
+}
+
+func TestChangeLogRecordsEveryQuote(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for _, author := range []string{"A", "B", "A"} {
+		if _, err := store.AddQuote(ctx, models.Quote{Text: "text " + author, Author: author}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+	text := "changed"
+	if _, err := store.UpdateQuote(ctx, 2, storage.QuoteUpdate{Text: &text}, storage.AnyVersion); err != nil {
+		t.Fatalf("failed to update quote: %v", err)
+	}
+	// Merging renames two quotes in one call; each gets its own entry.
+	if _, err := store.MergeAuthors(ctx, "C", []string{"A"}); err != nil {
+		t.Fatalf("failed to merge authors: %v", err)
+	}
+	if err := store.DeleteQuote(ctx, 3, storage.AnyVersion); err != nil {
+		t.Fatalf("failed to delete quote: %v", err)
+	}
+	// Neither reads nor served counts are changes.
+	if err := store.IncrementServed(ctx, 1); err != nil {
+		t.Fatalf("failed to increment served: %v", err)
+	}
+
+	want := []change{
+		{1, models.ChangeAdd, 1},
+		{2, models.ChangeAdd, 2},
+		{3, models.ChangeAdd, 3},
+		{4, models.ChangeUpdate, 2},
+		{5, models.ChangeUpdate, 1},
+		{6, models.ChangeUpdate, 3},
+		{7, models.ChangeDelete, 3},
+	}
+	if got := changesOf(t, store, 0); !slices.Equal(got, want) {
+		t.Fatalf("expected changes %v, got %v", want, got)
+	}
+	if got := changesOf(t, store, 5); !slices.Equal(got, want[5:]) {
+		t.Fatalf("expected changes after 5 %v, got %v", want[5:], got)
+	}
+	if got := changesOf(t, store, 7); len(got) != 0 {
+		t.Fatalf("expected no changes after the newest, got %v", got)
+	}
+	if _, _, err := store.ChangesSince(ctx, 8, 100); !errors.Is(err, storage.ErrChangesExpired) {
+		t.Fatalf("expected a sequence number ahead of the log to be expired, got %v", err)
+	}
+
+	entries, seq, _ := store.ChangesSince(ctx, 0, 2)
+	if len(entries) != 2 || seq != 7 {
+		t.Fatalf("expected 2 changes and the newest seq 7, got %d and %d", len(entries), seq)
+	}
+}
+
+func TestChangeLogTrims(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store, err := memorystorage.New(
+		memorystorage.WithChangeLog(3, time.Hour),
+		memorystorage.WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	add := func() {
+		t.Helper()
+		if _, err := store.AddQuote(ctx, models.Quote{Text: "text", Author: "A"}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+
+	for range 5 {
+		add()
+	}
+	if _, _, err := store.ChangesSince(ctx, 1, 10); !errors.Is(err, storage.ErrChangesExpired) {
+		t.Fatalf("expected changes beyond the entry limit to expire, got %v", err)
+	}
+	want := []change{{3, models.ChangeAdd, 3}, {4, models.ChangeAdd, 4}, {5, models.ChangeAdd, 5}}
+	if got := changesOf(t, store, 2); !slices.Equal(got, want) {
+		t.Fatalf("expected the last 3 changes, got %v", got)
+	}
+
+	// Changes past maxAge expire even before the next write trims them.
+	now = now.Add(30 * time.Minute)
+	add()
+	now = now.Add(45 * time.Minute)
+	if _, _, err := store.ChangesSince(ctx, 2, 10); !errors.Is(err, storage.ErrChangesExpired) {
+		t.Fatalf("expected changes older than an hour to expire, got %v", err)
+	}
+	if got := changesOf(t, store, 5); !slices.Equal(got, []change{{6, models.ChangeAdd, 6}}) {
+		t.Fatalf("expected the recent change, got %v", got)
+	}
+}
+
+func TestChangeLogFollowsTransactions(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+
+	errAbort := errors.New("abort")
+	err = store.WithinTx(ctx, func(tx storage.QuoteStore) error {
+		if _, err := tx.AddQuote(ctx, models.Quote{Text: "rolled back", Author: "A"}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("expected the transaction to fail, got %v", err)
+	}
+	if got := changesOf(t, store, 0); len(got) != 0 {
+		t.Fatalf("expected a rolled back transaction to log nothing, got %v", got)
+	}
+
+	err = store.WithinTx(ctx, func(tx storage.QuoteStore) error {
+		for range 2 {
+			if _, err := tx.AddQuote(ctx, models.Quote{Text: "kept", Author: "A"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	want := []change{{1, models.ChangeAdd, 1}, {2, models.ChangeAdd, 2}}
+	if got := changesOf(t, store, 0); !slices.Equal(got, want) {
+		t.Fatalf("expected changes %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,116 @@
+package memorystorage
+
+import (
+	"cmp"
+	"context"
+	"math/rand"
+	"slices"
+	"time"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// claim is the lease of a quote to a claimant. Claims are not part of the
+// data: they do not bump the version, and transactions neither see nor
+// roll them back.
+type claim struct {
+	claimant  string
+	claimedAt time.Time
+	expiresAt time.Time
+}
+
+// ClaimQuote claims a random unclaimed quote matching opts.Filter, or the
+// oldest one with opts.Oldest, for claimant until opts.Lease from now.
+// The pick and the claim happen under one write lock.
+func (s *Storage) ClaimQuote(ctx context.Context, claimant string, opts storage.ClaimOptions) (models.QuoteClaim, error) {
+	select {
+	case <-ctx.Done():
+		return models.QuoteClaim{}, ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	candidates, err := s.filterQuotes(ctx, opts.Filter)
+	if err != nil {
+		return models.QuoteClaim{}, err
+	}
+	now := s.now()
+	unclaimed := candidates[:0]
+	for _, q := range candidates {
+		if c, claimed := s.claims[q.ID]; !claimed || !now.Before(c.expiresAt) {
+			unclaimed = append(unclaimed, q)
+		}
+	}
+	if len(unclaimed) == 0 {
+		return models.QuoteClaim{}, storage.ErrNothingToClaim
+	}
+
+	var quote models.Quote
+	if opts.Oldest {
+		quote = slices.MinFunc(unclaimed, func(a, b models.Quote) int {
+			return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+		})
+	} else {
+		quote = unclaimed[rand.Intn(len(unclaimed))]
+	}
+
+	c := claim{claimant: claimant, claimedAt: now, expiresAt: now.Add(opts.Lease)}
+	s.claims[quote.ID] = c
+	return models.QuoteClaim{
+		Quote:     quote,
+		Claimant:  c.claimant,
+		ClaimedAt: c.claimedAt,
+		ExpiresAt: c.expiresAt,
+	}, nil
+}
+
+// ReleaseQuote ends claimant's claim on quote id. A quote that does not
+// exist fails with a storage.QuoteNotFoundError.
+func (s *Storage) ReleaseQuote(ctx context.Context, id int64, claimant string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.quotes[id]; !exists {
+		return &storage.QuoteNotFoundError{ID: id}
+	}
+	c, claimed := s.claims[id]
+	if !claimed || c.claimant != claimant || !s.now().Before(c.expiresAt) {
+		return storage.ErrClaimNotHeld
+	}
+	delete(s.claims, id)
+	return nil
+}
+
+// ExpireClaims forgets the claims whose lease has run out by the store's
+// clock, and those of quotes deleted since. Claims are checked for expiry
+// whenever they are used, so this only bounds the memory they hold.
+func (s *Storage) ExpireClaims(ctx context.Context) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	expired := 0
+	for id, c := range s.claims {
+		_, exists := s.quotes[id]
+		if !exists || !now.Before(c.expiresAt) {
+			delete(s.claims, id)
+			expired++
+		}
+	}
+	return expired, nil
+}
@@ -0,0 +1,160 @@
+package memorystorage_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func newClaimStore(t *testing.T, quotes int) (*memorystorage.Storage, *time.Time) {
+	t.Helper()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store, err := memorystorage.New(memorystorage.WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for i := range quotes {
+		if _, err := store.AddQuote(context.Background(), models.Quote{Text: fmt.Sprintf("quote %d", i), Author: "A"}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+	return store, &now
+}
+
+func TestClaimQuote(t *testing.T) {
+	ctx := context.Background()
+	store, now := newClaimStore(t, 3)
+	lease := storage.ClaimOptions{Oldest: true, Lease: time.Minute}
+
+	var claimed []int64
+	for _, claimant := range []string{"w1", "w2", "w3"} {
+		c, err := store.ClaimQuote(ctx, claimant, lease)
+		if err != nil {
+			t.Fatalf("failed to claim: %v", err)
+		}
+		if c.Claimant != claimant || !c.ExpiresAt.Equal(now.Add(time.Minute)) {
+			t.Fatalf("unexpected claim %+v", c)
+		}
+		claimed = append(claimed, c.Quote.ID)
+	}
+	if fmt.Sprint(claimed) != "[1 2 3]" {
+		t.Fatalf("expected the oldest quotes in turn, got %v", claimed)
+	}
+	if _, err := store.ClaimQuote(ctx, "w4", lease); !errors.Is(err, storage.ErrNothingToClaim) {
+		t.Fatalf("expected ErrNothingToClaim, got %v", err)
+	}
+	if _, err := store.GetQuote(ctx, 2); err != nil {
+		t.Fatalf("expected a claimed quote to stay readable, got %v", err)
+	}
+
+	if err := store.ReleaseQuote(ctx, 2, "w1"); !errors.Is(err, storage.ErrClaimNotHeld) {
+		t.Fatalf("expected ErrClaimNotHeld for another claimant, got %v", err)
+	}
+	if err := store.ReleaseQuote(ctx, 42, "w1"); !errors.Is(err, storage.ErrQuoteNotFound) {
+		t.Fatalf("expected ErrQuoteNotFound, got %v", err)
+	}
+	if err := store.ReleaseQuote(ctx, 2, "w2"); err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+	if c, err := store.ClaimQuote(ctx, "w4", lease); err != nil || c.Quote.ID != 2 {
+		t.Fatalf("expected the released quote to be claimed again, got %+v (%v)", c, err)
+	}
+
+	*now = now.Add(time.Minute)
+	if err := store.ReleaseQuote(ctx, 1, "w1"); !errors.Is(err, storage.ErrClaimNotHeld) {
+		t.Fatalf("expected an expired claim not to be held, got %v", err)
+	}
+	if c, err := store.ClaimQuote(ctx, "w5", lease); err != nil || c.Quote.ID != 1 {
+		t.Fatalf("expected an expired claim to be claimable, got %+v (%v)", c, err)
+	}
+	// Quote 1 is claimed again; 2 and 3 have expired.
+	if expired, err := store.ExpireClaims(ctx); err != nil || expired != 2 {
+		t.Fatalf("expected 2 expired claims, got %d (%v)", expired, err)
+	}
+	if expired, _ := store.ExpireClaims(ctx); expired != 0 {
+		t.Fatalf("expected nothing left to expire, got %d", expired)
+	}
+}
+
+func TestClaimQuoteFilter(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newClaimStore(t, 2)
+	source := "Book"
+	id, err := store.AddQuote(ctx, models.Quote{Text: "sourced", Author: "B", Source: source})
+	if err != nil {
+		t.Fatalf("failed to add quote: %v", err)
+	}
+
+	hasSource := true
+	opts := storage.ClaimOptions{Filter: storage.QuoteFilter{HasSource: &hasSource}, Lease: time.Minute}
+	c, err := store.ClaimQuote(ctx, "w1", opts)
+	if err != nil || c.Quote.ID != id {
+		t.Fatalf("expected the only sourced quote, got %+v (%v)", c, err)
+	}
+	if _, err := store.ClaimQuote(ctx, "w2", opts); !errors.Is(err, storage.ErrNothingToClaim) {
+		t.Fatalf("expected ErrNothingToClaim, got %v", err)
+	}
+}
+
+func TestClaimQuoteConcurrent(t *testing.T) {
+	const (
+		quotes   = 200
+		claimers = 32
+	)
+	tests := []struct {
+		name   string
+		oldest bool
+	}{
+		{name: "random"},
+		{name: "oldest", oldest: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			store, _ := newClaimStore(t, quotes)
+			opts := storage.ClaimOptions{Oldest: tc.oldest, Lease: time.Hour}
+
+			var (
+				mu     sync.Mutex
+				owners = make(map[int64]string)
+				wg     sync.WaitGroup
+			)
+			for w := range claimers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					claimant := fmt.Sprintf("worker-%d", w)
+					for {
+						c, err := store.ClaimQuote(ctx, claimant, opts)
+						if errors.Is(err, storage.ErrNothingToClaim) {
+							return
+						}
+						if err != nil {
+							t.Errorf("failed to claim: %v", err)
+							return
+						}
+						mu.Lock()
+						if owner, taken := owners[c.Quote.ID]; taken {
+							t.Errorf("quote %d claimed by both %s and %s", c.Quote.ID, owner, claimant)
+						}
+						owners[c.Quote.ID] = claimant
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+
+			if len(owners) != quotes {
+				t.Fatalf("expected all %d quotes claimed once, got %d", quotes, len(owners))
+			}
+		})
+	}
+}
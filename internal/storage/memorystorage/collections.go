@@ -0,0 +1,191 @@
+package memorystorage
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+type collection struct {
+	id          int64
+	name        string
+	description string
+	quotes      *orderedSet
+}
+
+func (c *collection) model() models.Collection {
+	return models.Collection{
+		ID:          c.id,
+		Name:        c.name,
+		Description: c.description,
+		QuoteCount:  c.quotes.len(),
+	}
+}
+
+func (s *Storage) CreateCollection(ctx context.Context, name string, description string) (models.Collection, error) {
+	select {
+	case <-ctx.Done():
+		return models.Collection{}, ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := &collection{
+		id:          s.nextCollectionID,
+		name:        name,
+		description: description,
+		quotes:      newOrderedSet(),
+	}
+	s.nextCollectionID++
+	s.collections[c.id] = c
+
+	return c.model(), nil
+}
+
+func (s *Storage) GetCollections(ctx context.Context) ([]models.Collection, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]models.Collection, 0, len(s.collections))
+	for _, c := range s.collections {
+		result = append(result, c.model())
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+func (s *Storage) GetCollection(ctx context.Context, id int64) (models.CollectionWithQuotes, error) {
+	select {
+	case <-ctx.Done():
+		return models.CollectionWithQuotes{}, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, exists := s.collections[id]
+	if !exists {
+		return models.CollectionWithQuotes{}, storage.ErrCollectionNotFound
+	}
+
+	quotes := make([]models.Quote, 0, c.quotes.len())
+	for _, quoteID := range c.quotes.ids {
+		quotes = append(quotes, s.quotes[quoteID])
+	}
+	return models.CollectionWithQuotes{Collection: c.model(), Quotes: quotes}, nil
+}
+
+// AddQuotesToCollection appends quotes to a collection. Quotes that are
+// already members are skipped. Nothing is added if any ID does not exist.
+func (s *Storage) AddQuotesToCollection(ctx context.Context, id int64, quoteIDs []int64) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, exists := s.collections[id]
+	if !exists {
+		return storage.ErrCollectionNotFound
+	}
+	for _, quoteID := range quoteIDs {
+		if _, exists := s.quotes[quoteID]; !exists {
+			return &storage.QuoteNotFoundError{ID: quoteID}
+		}
+	}
+
+	for _, quoteID := range quoteIDs {
+		if c.quotes.add(quoteID) {
+			addToIndex(s.quoteCollections, quoteID, c.id)
+		}
+	}
+	return nil
+}
+
+func (s *Storage) RemoveQuoteFromCollection(ctx context.Context, id int64, quoteID int64) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, exists := s.collections[id]
+	if !exists {
+		return storage.ErrCollectionNotFound
+	}
+	if !c.quotes.remove(quoteID) {
+		return &storage.QuoteNotFoundError{ID: quoteID}
+	}
+	removeFromIndex(s.quoteCollections, quoteID, c.id)
+	return nil
+}
+
+func (s *Storage) DeleteCollection(ctx context.Context, id int64) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, exists := s.collections[id]
+	if !exists {
+		return storage.ErrCollectionNotFound
+	}
+	for _, quoteID := range c.quotes.ids {
+		removeFromIndex(s.quoteCollections, quoteID, c.id)
+	}
+	delete(s.collections, id)
+	return nil
+}
+
+func (s *Storage) GetRandomCollectionQuote(ctx context.Context, id int64) (models.Quote, error) {
+	select {
+	case <-ctx.Done():
+		return models.Quote{}, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, exists := s.collections[id]
+	if !exists {
+		return models.Quote{}, storage.ErrCollectionNotFound
+	}
+	if c.quotes.len() == 0 {
+		return models.Quote{}, storage.ErrQuoteNotFound
+	}
+	return s.quotes[c.quotes.ids[rand.Intn(c.quotes.len())]], nil
+}
+
+// removeFromCollections drops a deleted quote from every collection that
+// references it. The caller must hold the write lock.
+func (s *Storage) removeFromCollections(quoteID int64) {
+	for collectionID := range s.quoteCollections[quoteID] {
+		s.collections[collectionID].quotes.remove(quoteID)
+	}
+	delete(s.quoteCollections, quoteID)
+}
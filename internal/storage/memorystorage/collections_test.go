@@ -0,0 +1,89 @@
+package memorystorage_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func TestCollections(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+
+	for _, text := range []string{"one", "two", "three"} {
+		if _, err := store.AddQuote(ctx, models.Quote{Text: text, Author: "A"}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+
+	c, err := store.CreateCollection(ctx, "Retro closers", "")
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+
+	if err := store.AddQuotesToCollection(ctx, c.ID, []int64{3, 1}); err != nil {
+		t.Fatalf("failed to add quotes: %v", err)
+	}
+	if err := store.AddQuotesToCollection(ctx, c.ID, []int64{1, 2}); err != nil {
+		t.Fatalf("failed to add quotes again: %v", err)
+	}
+
+	var notFound *storage.QuoteNotFoundError
+	err = store.AddQuotesToCollection(ctx, c.ID, []int64{2, 99})
+	if !errors.As(err, &notFound) || notFound.ID != 99 || !errors.Is(err, storage.ErrQuoteNotFound) {
+		t.Fatalf("expected QuoteNotFoundError for 99, got %v", err)
+	}
+	if err := store.AddQuotesToCollection(ctx, 42, []int64{1}); !errors.Is(err, storage.ErrCollectionNotFound) {
+		t.Fatalf("expected ErrCollectionNotFound, got %v", err)
+	}
+
+	assertQuoteIDs := func(expected []int64) {
+		t.Helper()
+		got, err := store.GetCollection(ctx, c.ID)
+		if err != nil {
+			t.Fatalf("failed to get collection: %v", err)
+		}
+		ids := make([]int64, 0, len(got.Quotes))
+		for _, q := range got.Quotes {
+			ids = append(ids, q.ID)
+		}
+		if !reflect.DeepEqual(ids, expected) || got.QuoteCount != len(expected) {
+			t.Fatalf("expected quote ids %v, got %v (count %d)", expected, ids, got.QuoteCount)
+		}
+	}
+
+	assertQuoteIDs([]int64{3, 1, 2})
+
+	if err := store.DeleteQuote(ctx, 1, storage.AnyVersion); err != nil {
+		t.Fatalf("failed to delete quote: %v", err)
+	}
+	assertQuoteIDs([]int64{3, 2})
+
+	if err := store.RemoveQuoteFromCollection(ctx, c.ID, 3); err != nil {
+		t.Fatalf("failed to remove quote: %v", err)
+	}
+	assertQuoteIDs([]int64{2})
+
+	q, err := store.GetRandomCollectionQuote(ctx, c.ID)
+	if err != nil || q.ID != 2 {
+		t.Fatalf("expected random quote 2, got %+v, %v", q, err)
+	}
+
+	if err := store.DeleteCollection(ctx, c.ID); err != nil {
+		t.Fatalf("failed to delete collection: %v", err)
+	}
+	if err := store.DeleteQuote(ctx, 2, storage.AnyVersion); err != nil {
+		t.Fatalf("failed to delete quote after collection removal: %v", err)
+	}
+	if _, err := store.GetCollection(ctx, c.ID); !errors.Is(err, storage.ErrCollectionNotFound) {
+		t.Fatalf("expected ErrCollectionNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+package memorystorage
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"unsafe"
+
+	"quotes-service/internal/models"
+)
+
+// Compact rebuilds every map and slice of the store at the size of what it
+// holds. Go maps never shrink and a slice keeps its capacity when items are
+// cut from it, so after a bulk import and a mass delete the store holds on
+// to the memory of its peak until compacted. The data, and so the
+// version, is unchanged. It holds the write lock for as long as copying the
+// store takes.
+func (s *Storage) Compact(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.quotes = compactMap(s.quotes)
+	s.quotesList = compactSlice(s.quotesList)
+	s.cumWeights = compactSlice(s.cumWeights)
+	s.served = compactMap(s.served)
+	s.tokens = compactMap(s.tokens)
+	s.tokenIndex = compactIndex(s.tokenIndex)
+	s.langIndex = compactIndex(s.langIndex)
+	s.authorIndex = compactIndex(s.authorIndex)
+	s.fingerprintIndex = compactIndex(s.fingerprintIndex)
+	s.publicIDs = compactMap(s.publicIDs)
+
+	for _, col := range s.collections {
+		col.quotes = col.quotes.compact()
+	}
+	s.collections = compactMap(s.collections)
+	s.quoteCollections = compactIndex(s.quoteCollections)
+	for principal, set := range s.favorites {
+		s.favorites[principal] = set.compact()
+	}
+	s.favorites = compactMap(s.favorites)
+	s.quoteFavorites = compactIndex(s.quoteFavorites)
+	s.claims = compactMap(s.claims)
+
+	s.changes.entries = compactSlice(s.changes.entries)
+	s.audit.compact()
+
+	compactedAt := s.now().UTC()
+	s.compactedAt = &compactedAt
+	return nil
+}
+
+// MemoryReport estimates the memory of each structure of the store. The
+// runtime's heap figure is left to the caller.
+func (s *Storage) MemoryReport(ctx context.Context) (models.StorageReport, error) {
+	select {
+	case <-ctx.Done():
+		return models.StorageReport{}, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var textBytes int64
+	for _, q := range s.quotes {
+		textBytes += quoteTextBytes(q)
+	}
+	var tokenBytes int64
+	for _, tokens := range s.tokens {
+		tokenBytes += int64(cap(tokens)) * stringSize
+	}
+	quoteSize := int64(unsafe.Sizeof(models.Quote{}))
+	collectionSets := make([]*orderedSet, 0, len(s.collections))
+	for _, col := range s.collections {
+		collectionSets = append(collectionSets, col.quotes)
+	}
+
+	structures := []models.StorageStructure{
+		{Name: "quotes", Entries: len(s.quotes), ApproxBytes: mapBytes(len(s.quotes), 8, quoteSize) + textBytes},
+		{Name: "quote_list", Entries: len(s.quotesList), Capacity: cap(s.quotesList), ApproxBytes: int64(cap(s.quotesList)) * quoteSize},
+		{Name: "weights", Entries: len(s.cumWeights), Capacity: cap(s.cumWeights), ApproxBytes: int64(cap(s.cumWeights)) * 8},
+		{Name: "served", Entries: len(s.served), ApproxBytes: mapBytes(len(s.served), 8, 8) + int64(len(s.served))*8},
+		{Name: "tokens", Entries: len(s.tokens), ApproxBytes: mapBytes(len(s.tokens), 8, sliceSize) + tokenBytes},
+		indexUsage("token_index", s.tokenIndex),
+		indexUsage("lang_index", s.langIndex),
+		indexUsage("author_index", s.authorIndex),
+		indexUsage("fingerprint_index", s.fingerprintIndex),
+		{Name: "public_ids", Entries: len(s.publicIDs), ApproxBytes: mapBytes(len(s.publicIDs), stringSize, 8) + keyBytes(s.publicIDs)},
+		setsUsage("collections", collectionSets),
+		indexUsage("quote_collections", s.quoteCollections),
+		setsUsage("favorites", slices.Collect(maps.Values(s.favorites))),
+		indexUsage("quote_favorites", s.quoteFavorites),
+		{Name: "claims", Entries: len(s.claims), ApproxBytes: mapBytes(len(s.claims), 8, int64(unsafe.Sizeof(claim{})))},
+		{
+			Name:        "change_log",
+			Entries:     len(s.changes.entries),
+			Capacity:    cap(s.changes.entries),
+			ApproxBytes: int64(cap(s.changes.entries)) * int64(unsafe.Sizeof(models.QuoteChange{})),
+		},
+		s.audit.usage(),
+	}
+
+	report := models.StorageReport{
+		Backend:     s.Backend(),
+		Quotes:      len(s.quotes),
+		Structures:  structures,
+		CompactedAt: s.compactedAt,
+	}
+	for _, st := range structures {
+		report.ApproxBytes += st.ApproxBytes
+	}
+	return report, nil
+}
+
+// Sizes of the headers of a string and a slice.
+const (
+	stringSize = int64(unsafe.Sizeof(""))
+	sliceSize  = int64(unsafe.Sizeof([]int64(nil)))
+)
+
+// mapBytes estimates a map of n entries: a slot per entry for its key, its
+// value and a control byte, at the 7/8 load the runtime grows maps at.
+func mapBytes(n int, keySize, valueSize int64) int64 {
+	return int64(n) * (keySize + valueSize + 1) * 8 / 7
+}
+
+func quoteTextBytes(q models.Quote) int64 {
+	n := len(q.PublicID) + len(q.Text) + len(q.Author) + len(q.AuthorKey) + len(q.Lang) + len(q.Source) + len(q.SourceURL)
+	for _, tag := range q.Tags {
+		n += len(tag)
+	}
+	return int64(n) + int64(cap(q.Tags))*stringSize
+}
+
+func keyBytes[V any](m map[string]V) int64 {
+	var n int64
+	for key := range m {
+		n += int64(len(key))
+	}
+	return n
+}
+
+// indexUsage estimates an index of K to a set of V, counting the sets'
+// members as its entries.
+func indexUsage[K, V comparable](name string, index map[K]map[V]struct{}) models.StorageStructure {
+	var k K
+	var v V
+	keySize, memberSize := int64(unsafe.Sizeof(k)), int64(unsafe.Sizeof(v))
+	usage := models.StorageStructure{Name: name, ApproxBytes: mapBytes(len(index), keySize, 8)}
+	for key, members := range index {
+		usage.Entries += len(members)
+		usage.ApproxBytes += mapBytes(len(members), memberSize, 0)
+		if s, ok := any(key).(string); ok {
+			usage.ApproxBytes += int64(len(s))
+		}
+	}
+	return usage
+}
+
+// setsUsage estimates the ordered sets of a map, counting their members as
+// entries.
+func setsUsage(name string, sets []*orderedSet) models.StorageStructure {
+	usage := models.StorageStructure{Name: name, ApproxBytes: mapBytes(len(sets), 8, 8)}
+	for _, set := range sets {
+		usage.Entries += set.len()
+		usage.Capacity += cap(set.ids)
+		usage.ApproxBytes += int64(cap(set.ids))*8 + mapBytes(len(set.members), 8, 0)
+	}
+	return usage
+}
+
+// compactSlice copies s into a slice of its length. slices.Clone may round
+// the capacity up.
+func compactSlice[T any](s []T) []T {
+	c := make([]T, len(s))
+	copy(c, s)
+	return c
+}
+
+// compactMap copies m into a map sized for its entries. maps.Clone would
+// keep the size of m.
+func compactMap[K comparable, V any](m map[K]V) map[K]V {
+	c := make(map[K]V, len(m))
+	for key, value := range m {
+		c[key] = value
+	}
+	return c
+}
+
+// compactIndex compacts an index and each of its sets, dropping the empty
+// ones.
+func compactIndex[K, V comparable](index map[K]map[V]struct{}) map[K]map[V]struct{} {
+	c := make(map[K]map[V]struct{}, len(index))
+	for key, members := range index {
+		if len(members) > 0 {
+			c[key] = compactMap(members)
+		}
+	}
+	return c
+}
+
+func (s *orderedSet) compact() *orderedSet {
+	return &orderedSet{
+		ids:     compactSlice(s.ids),
+		members: compactMap(s.members),
+	}
+}
+
+// compact copies the trail into right-sized structures.
+func (l *auditLog) compact() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = compactSlice(l.entries)
+	byPrincipal := make(map[string][]uint64, len(l.byPrincipal))
+	for principal, seqs := range l.byPrincipal {
+		if len(seqs) > 0 {
+			byPrincipal[principal] = compactSlice(seqs)
+		}
+	}
+	l.byPrincipal = byPrincipal
+}
+
+func (l *auditLog) usage() models.StorageStructure {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	usage := models.StorageStructure{
+		Name:        "audit_log",
+		Entries:     len(l.entries),
+		Capacity:    cap(l.entries),
+		ApproxBytes: int64(cap(l.entries))*int64(unsafe.Sizeof(models.AuditEntry{})) + mapBytes(len(l.byPrincipal), stringSize, sliceSize),
+	}
+	for principal, seqs := range l.byPrincipal {
+		usage.ApproxBytes += int64(len(principal)) + int64(cap(seqs))*8
+	}
+	return usage
+}
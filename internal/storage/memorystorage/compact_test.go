@@ -0,0 +1,169 @@
+package memorystorage_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func heapAlloc() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+func TestCompact(t *testing.T) {
+	const (
+		imported = 4000
+		kept     = 40
+	)
+	ctx := context.Background()
+	store, err := memorystorage.New(
+		memorystorage.WithChangeLog(100, 0),
+		memorystorage.WithAuditRetention(100, 0),
+	)
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+
+	quotes := make([]models.Quote, 0, imported)
+	for i := range imported {
+		quotes = append(quotes, models.Quote{
+			ID:     int64(i + 1),
+			Text:   fmt.Sprintf("quote number %d about topic %d", i, i%50),
+			Author: fmt.Sprintf("Author %d", i%300),
+		})
+	}
+	if err := store.PutQuotes(ctx, quotes); err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	collection, err := store.CreateCollection(ctx, "kept", "")
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+	for id := int64(1); id <= imported; id++ {
+		if id%(imported/kept) == 0 {
+			if err := store.AddQuotesToCollection(ctx, collection.ID, []int64{id}); err != nil {
+				t.Fatalf("failed to add to collection: %v", err)
+			}
+			if err := store.AddFavorite(ctx, "alice", id); err != nil {
+				t.Fatalf("failed to favorite: %v", err)
+			}
+			continue
+		}
+		if err := store.DeleteQuote(ctx, id, storage.AnyVersion); err != nil {
+			t.Fatalf("failed to delete %d: %v", id, err)
+		}
+		if err := store.RecordAudit(ctx, models.AuditEntry{Principal: "importer", Operation: "DELETE /quotes/{id}", QuoteID: id}); err != nil {
+			t.Fatalf("failed to record audit: %v", err)
+		}
+	}
+
+	before, err := store.GetAllQuotes(ctx, storage.QuoteFilter{})
+	if err != nil || len(before) != kept {
+		t.Fatalf("expected %d quotes left, got %d (%v)", kept, len(before), err)
+	}
+	byAuthor, _ := store.GetQuotesByAuthor(ctx, before[0].Author, storage.QuoteFilter{})
+	similar, _ := store.GetSimilarQuotes(ctx, before[0].ID, 5)
+	favorites, _, _ := store.GetFavorites(ctx, "alice", 1000, 0)
+	version, _ := store.Version(ctx)
+	capsBefore := store.SliceCapacities()
+	reportBefore, err := store.MemoryReport(ctx)
+	if err != nil {
+		t.Fatalf("failed to report: %v", err)
+	}
+	heapBefore := heapAlloc()
+
+	// Readers keep going while the store is compacted.
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				q, err := store.GetRandomQuote(ctx, storage.RandomOptions{})
+				if err != nil || q.ID%(imported/kept) != 0 {
+					t.Errorf("unexpected random quote %d during compaction (%v)", q.ID, err)
+					return
+				}
+			}
+		}()
+	}
+	if err := store.Compact(ctx); err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+	close(stop)
+	wg.Wait()
+
+	heapAfter := heapAlloc()
+	capsAfter := store.SliceCapacities()
+	// Deletes already keep the quote list and weights tight; the logs are
+	// trimmed from the front and keep their peak capacity until compacted.
+	expectedCaps := map[string]int{"quote_list": kept, "weights": kept, "change_log": 100, "audit_log": 100}
+	for name, expected := range expectedCaps {
+		if capsAfter[name] != expected || capsBefore[name] < expected {
+			t.Errorf("expected %s capacity to go from %d to %d, got %d", name, capsBefore[name], expected, capsAfter[name])
+		}
+	}
+	if capsBefore["change_log"] <= 100 || capsBefore["audit_log"] <= 100 {
+		t.Errorf("expected the logs to have grown past their bounds before compaction, got %v", capsBefore)
+	}
+	if heapAfter >= heapBefore {
+		t.Errorf("expected the heap to shrink from %d bytes, got %d", heapBefore, heapAfter)
+	}
+	reportAfter, err := store.MemoryReport(ctx)
+	if err != nil {
+		t.Fatalf("failed to report: %v", err)
+	}
+	if reportAfter.ApproxBytes >= reportBefore.ApproxBytes || reportAfter.CompactedAt == nil || reportAfter.Quotes != kept {
+		t.Errorf("expected a smaller report after compaction, got %d bytes before and %+v after", reportBefore.ApproxBytes, reportAfter)
+	}
+
+	checks := []struct {
+		name string
+		got  func() (any, error)
+		want any
+	}{
+		{"quotes", func() (any, error) { return store.GetAllQuotes(ctx, storage.QuoteFilter{}) }, before},
+		{"by author", func() (any, error) { return store.GetQuotesByAuthor(ctx, before[0].Author, storage.QuoteFilter{}) }, byAuthor},
+		{"similar", func() (any, error) { return store.GetSimilarQuotes(ctx, before[0].ID, 5) }, similar},
+		{"favorites", func() (any, error) {
+			page, _, err := store.GetFavorites(ctx, "alice", 1000, 0)
+			return page, err
+		}, favorites},
+		{"version", func() (any, error) { return store.Version(ctx) }, version},
+	}
+	for _, check := range checks {
+		got, err := check.got()
+		if err != nil || !reflect.DeepEqual(got, check.want) {
+			t.Errorf("%s changed by compaction (%v)", check.name, err)
+		}
+	}
+	col, err := store.GetCollection(ctx, collection.ID)
+	if err != nil || col.QuoteCount != kept {
+		t.Errorf("expected the collection to keep %d quotes, got %+v (%v)", kept, col, err)
+	}
+
+	// The compacted store takes writes as before.
+	id, err := store.AddQuote(ctx, models.Quote{Text: "after compaction", Author: "B"})
+	if err != nil {
+		t.Fatalf("failed to add quote after compaction: %v", err)
+	}
+	if q, err := store.GetQuote(ctx, id); err != nil || q.Text != "after compaction" {
+		t.Fatalf("expected the new quote, got %+v (%v)", q, err)
+	}
+}
@@ -0,0 +1,17 @@
+package memorystorage
+
+// SliceCapacities returns the capacities of the store's slices by name,
+// for tests of Compact.
+func (s *Storage) SliceCapacities() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.audit.mu.RLock()
+	defer s.audit.mu.RUnlock()
+
+	return map[string]int{
+		"quote_list": cap(s.quotesList),
+		"weights":    cap(s.cumWeights),
+		"change_log": cap(s.changes.entries),
+		"audit_log":  cap(s.audit.entries),
+	}
+}
@@ -0,0 +1,117 @@
+package memorystorage
+
+import (
+	"context"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// AddFavorite bookmarks a quote for principal. Favoriting a quote twice is
+// a no-op.
+func (s *Storage) AddFavorite(ctx context.Context, principal string, quoteID int64) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.quotes[quoteID]; !exists {
+		return &storage.QuoteNotFoundError{ID: quoteID}
+	}
+
+	favorites, exists := s.favorites[principal]
+	if !exists {
+		favorites = newOrderedSet()
+		s.favorites[principal] = favorites
+	}
+	if favorites.add(quoteID) {
+		addToIndex(s.quoteFavorites, quoteID, principal)
+	}
+	return nil
+}
+
+// RemoveFavorite removes a bookmark. Removing a quote that is not a
+// favorite is a no-op.
+func (s *Storage) RemoveFavorite(ctx context.Context, principal string, quoteID int64) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	favorites, exists := s.favorites[principal]
+	if !exists || !favorites.remove(quoteID) {
+		return nil
+	}
+	removeFromIndex(s.quoteFavorites, quoteID, principal)
+	if favorites.len() == 0 {
+		delete(s.favorites, principal)
+	}
+	return nil
+}
+
+// GetFavorites returns a page of principal's favorites in the order they
+// were added, together with the total number of favorites.
+func (s *Storage) GetFavorites(ctx context.Context, principal string, limit, offset int) ([]models.Quote, int, error) {
+	select {
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	favorites, exists := s.favorites[principal]
+	if !exists || offset >= favorites.len() {
+		total := 0
+		if exists {
+			total = favorites.len()
+		}
+		return make([]models.Quote, 0), total, nil
+	}
+
+	ids := favorites.ids[offset:min(offset+limit, favorites.len())]
+	result := make([]models.Quote, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, s.quotes[id])
+	}
+	return result, favorites.len(), nil
+}
+
+// FavoriteCount reports how many principals have favorited a quote.
+func (s *Storage) FavoriteCount(ctx context.Context, quoteID int64) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.quotes[quoteID]; !exists {
+		return 0, storage.ErrQuoteNotFound
+	}
+	return len(s.quoteFavorites[quoteID]), nil
+}
+
+// removeFromFavorites drops a deleted quote from every principal's
+// favorites. The caller must hold the write lock.
+func (s *Storage) removeFromFavorites(quoteID int64) {
+	for principal := range s.quoteFavorites[quoteID] {
+		favorites := s.favorites[principal]
+		favorites.remove(quoteID)
+		if favorites.len() == 0 {
+			delete(s.favorites, principal)
+		}
+	}
+	delete(s.quoteFavorites, quoteID)
+}
@@ -0,0 +1,67 @@
+package memorystorage_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+func TestFavorites(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+
+	for _, text := range []string{"one", "two", "three"} {
+		if _, err := store.AddQuote(ctx, models.Quote{Text: text, Author: "A"}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+
+	for _, id := range []int64{2, 1, 2, 3} {
+		if err := store.AddFavorite(ctx, "alice", id); err != nil {
+			t.Fatalf("failed to favorite %d: %v", id, err)
+		}
+	}
+	if err := store.AddFavorite(ctx, "bob", 1); err != nil {
+		t.Fatalf("failed to favorite: %v", err)
+	}
+	if err := store.AddFavorite(ctx, "alice", 42); !errors.Is(err, storage.ErrQuoteNotFound) {
+		t.Fatalf("expected ErrQuoteNotFound, got %v", err)
+	}
+
+	page, total, err := store.GetFavorites(ctx, "alice", 2, 1)
+	if err != nil {
+		t.Fatalf("failed to get favorites: %v", err)
+	}
+	if total != 3 || len(page) != 2 || page[0].ID != 1 || page[1].ID != 3 {
+		t.Fatalf("unexpected page %+v (total %d)", page, total)
+	}
+
+	if count, _ := store.FavoriteCount(ctx, 1); count != 2 {
+		t.Fatalf("expected 2 favorites for quote 1, got %d", count)
+	}
+
+	if err := store.DeleteQuote(ctx, 1, storage.AnyVersion); err != nil {
+		t.Fatalf("failed to delete quote: %v", err)
+	}
+	if _, total, _ := store.GetFavorites(ctx, "bob", 10, 0); total != 0 {
+		t.Fatalf("expected bob to have no favorites, got %d", total)
+	}
+
+	if err := store.RemoveFavorite(ctx, "alice", 2); err != nil {
+		t.Fatalf("failed to remove favorite: %v", err)
+	}
+	if err := store.RemoveFavorite(ctx, "alice", 2); err != nil {
+		t.Fatalf("second remove should be a no-op, got %v", err)
+	}
+	page, total, _ = store.GetFavorites(ctx, "alice", 10, 0)
+	if total != 1 || page[0].ID != 3 {
+		t.Fatalf("unexpected favorites %+v (total %d)", page, total)
+	}
+}
@@ -1,10 +1,22 @@
 package memorystorage
 
 import (
+	"cmp"
 	"context"
+	"fmt"
+	"maps"
 	"math/rand"
+	"slices"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"quotes-service/internal/lib/authorname"
+	"quotes-service/internal/lib/fingerprint"
+	"quotes-service/internal/lib/language"
+	"quotes-service/internal/lib/publicid"
+	"quotes-service/internal/lib/tokenizer"
 	"quotes-service/internal/models"
 	"quotes-service/internal/storage"
 )
@@ -12,19 +24,107 @@ import (
 type Storage struct {
 	mu         sync.RWMutex
 	quotes     map[int64]models.Quote
+	// quotesList holds the quotes in ascending ID order, the order
+	// GetAllQuotes promises. AddQuote appends, as new IDs are above every
+	// stored one, and putQuotes and deletes keep the order in place.
 	quotesList []models.Quote
-	nextID     int64
+	served     map[int64]*atomic.Int64
+	// cumWeights[i] is the sum of weights of quotesList[0..i], kept in step
+	// with quotesList so weighted picks are a binary search.
+	cumWeights []int64
+	// tokens holds the distinct words of each quote and tokenIndex maps a
+	// word back to the quotes containing it, for similarity lookups.
+	tokens     map[int64][]string
+	tokenIndex map[string]map[int64]struct{}
+	// langIndex maps a primary language subtag to the quotes tagged with it.
+	langIndex map[string]map[int64]struct{}
+	// authorIndex maps an author match key to the quotes by that author.
+	authorIndex map[string]map[int64]struct{}
+	// fingerprintIndex maps a fingerprint.Of the text and author to the
+	// quotes that have it, for lookups by content.
+	fingerprintIndex map[string]map[int64]struct{}
+	// publicIDs maps a normalized public ID to the quote carrying it.
+	publicIDs map[string]int64
+	nextID    int64
+
+	collections      map[int64]*collection
+	quoteCollections map[int64]map[int64]struct{}
+	nextCollectionID int64
+
+	// favorites maps a principal to its bookmarked quotes; quoteFavorites is
+	// the reverse index used to clean up after deletes.
+	favorites      map[string]*orderedSet
+	quoteFavorites map[int64]map[string]struct{}
+
+	// claims holds the leases of claimed quotes, by quote.
+	claims map[int64]claim
+	// compactedAt is when Compact last ran, or nil.
+	compactedAt *time.Time
+
+	// version is bumped on every mutation so callers can cache derived data.
+	version uint64
+	// authors caches the sorted author list of one version. authorsMu
+	// guards it, and is taken inside a read lock of mu.
+	authorsMu sync.Mutex
+	authors   *authorList
+	changes *changeLog
+	audit   *auditLog
+
+	now         func() time.Time
+	newPublicID func() string
 }
 
-func New() (*Storage, error) {
-	return &Storage{
+type Option func(*Storage)
+
+// WithClock overrides the time source used for timestamps, mainly for tests.
+func WithClock(now func() time.Time) Option {
+	return func(s *Storage) {
+		s.now = now
+	}
+}
+
+// WithPublicIDs makes AddQuote give every new quote a public ID from
+// generate, and Restore fill in the ones a snapshot lacks.
+func WithPublicIDs(generate func() string) Option {
+	return func(s *Storage) {
+		s.newPublicID = generate
+	}
+}
+
+func New(opts ...Option) (*Storage, error) {
+	s := &Storage{
 		quotes:     make(map[int64]models.Quote),
 		quotesList: make([]models.Quote, 0),
+		served:     make(map[int64]*atomic.Int64),
+		tokens:     make(map[int64][]string),
+		tokenIndex: make(map[string]map[int64]struct{}),
+		langIndex:  make(map[string]map[int64]struct{}),
+		publicIDs:  make(map[string]int64),
 		nextID:     1,
-	}, nil
+
+		authorIndex:      make(map[string]map[int64]struct{}),
+		fingerprintIndex: make(map[string]map[int64]struct{}),
+
+		collections:      make(map[int64]*collection),
+		quoteCollections: make(map[int64]map[int64]struct{}),
+		nextCollectionID: 1,
+
+		favorites:      make(map[string]*orderedSet),
+		quoteFavorites: make(map[int64]map[string]struct{}),
+
+		claims: make(map[int64]claim),
+
+		changes: newChangeLog(),
+		audit:   newAuditLog(),
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
 }
 
-func (s *Storage) AddQuote(ctx context.Context, text string, author string) (int64, error) {
+func (s *Storage) AddQuote(ctx context.Context, quote models.Quote) (int64, error) {
 	select {
 	case <-ctx.Done():
 		return 0, ctx.Err() 
@@ -37,18 +137,37 @@ func (s *Storage) AddQuote(ctx context.Context, text string, author string) (int
 	id := s.nextID
 	s.nextID++
 
-	quote := models.Quote{
-		ID:     id,
-		Text:   text,
-		Author: author,
+	quote.ID = id
+	quote.PublicID = ""
+	if s.newPublicID != nil {
+		quote.PublicID = s.uniquePublicID(nil)
+		s.publicIDs[quote.PublicID] = id
 	}
+	quote.Author = authorname.Display(quote.Author)
+	quote.AuthorKey = authorname.Key(quote.Author)
+	quote.Weight = normalizeWeight(quote.Weight)
+	if quote.Lang == "" {
+		quote.Lang = language.Undetermined
+	}
+	quote.CreatedAt = s.now().UTC()
+	quote.UpdatedAt = quote.CreatedAt
+	quote.Version = 1
 	s.quotes[id] = quote
 	s.quotesList = append(s.quotesList, quote)
+	s.cumWeights = append(s.cumWeights, s.totalWeight()+int64(quote.Weight))
+	s.served[id] = new(atomic.Int64)
+	s.indexTokens(quote)
+	addToIndex(s.langIndex, language.Primary(quote.Lang), id)
+	addToIndex(s.authorIndex, quote.AuthorKey, id)
+	s.indexFingerprint(quote)
+	s.logChange(models.ChangeAdd, quote)
+	s.version++
 
 	return id, nil
 }
 
-func (s *Storage) GetAllQuotes(ctx context.Context) ([]models.Quote, error) {
+// GetAllQuotes implements storage.QuoteStore, in ascending ID order.
+func (s *Storage) GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error) {
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
@@ -58,12 +177,22 @@ func (s *Storage) GetAllQuotes(ctx context.Context) ([]models.Quote, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	if !filter.IsZero() {
+		return s.filterQuotes(ctx, filter)
+	}
+
+	// Copy in chunks so a canceled request stops paying for a huge list.
 	listCopy := make([]models.Quote, len(s.quotesList))
-	copy(listCopy, s.quotesList)
+	for start := 0; start < len(s.quotesList); start += cancelCheckInterval {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		copy(listCopy[start:], s.quotesList[start:min(start+cancelCheckInterval, len(s.quotesList))])
+	}
 	return listCopy, nil
 }
 
-func (s *Storage) GetRandomQuote(ctx context.Context) (models.Quote, error) {
+func (s *Storage) GetQuote(ctx context.Context, id int64) (models.Quote, error) {
 	select {
 	case <-ctx.Done():
 		return models.Quote{}, ctx.Err()
@@ -73,37 +202,223 @@ func (s *Storage) GetRandomQuote(ctx context.Context) (models.Quote, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	if len(s.quotesList) == 0 {
+	quote, exists := s.quotes[id]
+	if !exists {
 		return models.Quote{}, storage.ErrQuoteNotFound
 	}
-	randomIndex := rand.Intn(len(s.quotesList))
-	return s.quotesList[randomIndex], nil
+	return quote, nil
 }
 
-func (s *Storage) GetQuotesByAuthor(ctx context.Context, authorFilter string) ([]models.Quote, error) {
+// GetQuoteByPublicID looks a quote up by its UUID or ULID, in any case.
+func (s *Storage) GetQuoteByPublicID(ctx context.Context, publicID string) (models.Quote, error) {
 	select {
 	case <-ctx.Done():
-		return nil, ctx.Err()
+		return models.Quote{}, ctx.Err()
 	default:
 	}
 
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	var result []models.Quote
-	for _, q := range s.quotesList {
-		if q.Author == authorFilter {
+	id, exists := s.publicIDs[publicid.Normalize(publicID)]
+	if !exists {
+		return models.Quote{}, storage.ErrQuoteNotFound
+	}
+	return s.quotes[id], nil
+}
+
+// uniquePublicID generates a public ID that is neither indexed nor in
+// taken. The caller holds s.mu.
+func (s *Storage) uniquePublicID(taken map[string]struct{}) string {
+	for {
+		id := publicid.Normalize(s.newPublicID())
+		if _, exists := s.publicIDs[id]; exists {
+			continue
+		}
+		if _, exists := taken[id]; exists {
+			continue
+		}
+		return id
+	}
+}
+
+func (s *Storage) GetRandomQuote(ctx context.Context, opts storage.RandomOptions) (models.Quote, error) {
+	select {
+	case <-ctx.Done():
+		return models.Quote{}, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.quotesList) == 0 {
+		return models.Quote{}, storage.ErrQuoteNotFound
+	}
+
+	excluded := s.presentIDs(opts.ExcludeIDs)
+
+	if !opts.Filter.IsZero() {
+		candidates, err := s.filterQuotes(ctx, opts.Filter)
+		if err != nil {
+			return models.Quote{}, err
+		}
+		if len(candidates) == 0 {
+			return models.Quote{}, storage.ErrQuoteNotFound
+		}
+		return pickFrom(candidates, excluded, opts.Unweighted), nil
+	}
+
+	if len(excluded) == 0 || len(excluded) >= len(s.quotesList) {
+		return s.pick(opts.Unweighted), nil
+	}
+
+	// Rejection sampling is cheap while the excluded set is small, which is
+	// the common case; fall back to a scan if we keep hitting it.
+	for attempt := 0; attempt < maxRejections; attempt++ {
+		quote := s.pick(opts.Unweighted)
+		if _, skip := excluded[quote.ID]; !skip {
+			return quote, nil
+		}
+	}
+	return pickFrom(s.quotesList, excluded, opts.Unweighted), nil
+}
+
+const maxRejections = 32
+
+func (s *Storage) pick(unweighted bool) models.Quote {
+	if unweighted {
+		return s.quotesList[rand.Intn(len(s.quotesList))]
+	}
+
+	target := rand.Int63n(s.totalWeight())
+	randomIndex := sort.Search(len(s.cumWeights), func(i int) bool {
+		return s.cumWeights[i] > target
+	})
+	return s.quotesList[randomIndex]
+}
+
+// pickFrom makes a weighted pick among quotes with a linear scan, skipping
+// excluded ones unless that would leave nothing to pick from.
+func pickFrom(quotes []models.Quote, excluded map[int64]struct{}, unweighted bool) models.Quote {
+	var total int64
+	for _, q := range quotes {
+		if _, skip := excluded[q.ID]; !skip {
+			total += quoteWeight(q, unweighted)
+		}
+	}
+	if total == 0 {
+		return pickFrom(quotes, nil, unweighted)
+	}
+
+	target := rand.Int63n(total)
+	var chosen models.Quote
+	for _, q := range quotes {
+		if _, skip := excluded[q.ID]; skip {
+			continue
+		}
+		chosen = q
+		target -= quoteWeight(q, unweighted)
+		if target < 0 {
+			break
+		}
+	}
+	return chosen
+}
+
+// filterQuotes returns the quotes matching filter in insertion order. A
+// language filter narrows the candidates through the language index first.
+func (s *Storage) filterQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error) {
+	if filter.Lang == "" {
+		result := make([]models.Quote, 0)
+		for i, q := range s.quotesList {
+			if err := checkCtx(ctx, i); err != nil {
+				return nil, err
+			}
+			if filter.Matches(q) {
+				result = append(result, q)
+			}
+		}
+		return result, nil
+	}
+
+	ids := s.langIndex[language.Primary(filter.Lang)]
+	result := make([]models.Quote, 0, len(ids))
+	i := 0
+	for id := range ids {
+		if err := checkCtx(ctx, i); err != nil {
+			return nil, err
+		}
+		i++
+		if q := s.quotes[id]; filter.Matches(q) {
 			result = append(result, q)
 		}
 	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+// cancelCheckInterval is how many items a scan handles between checks of
+// the caller's context. Checking on every item would cost more than the
+// scan itself on large stores.
+const cancelCheckInterval = 1024
+
+// checkCtx returns ctx.Err() on every cancelCheckInterval-th iteration and
+// nil otherwise. Scans call it with their loop index.
+func checkCtx(ctx context.Context, i int) error {
+	if i%cancelCheckInterval != 0 {
+		return nil
+	}
+	return ctx.Err()
+}
 
-	if result == nil {
-		return []models.Quote{}, nil
+func (s *Storage) presentIDs(ids []int64) map[int64]struct{} {
+	present := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		if _, exists := s.quotes[id]; exists {
+			present[id] = struct{}{}
+		}
+	}
+	return present
+}
+
+func quoteWeight(q models.Quote, unweighted bool) int64 {
+	if unweighted {
+		return 1
+	}
+	return int64(q.Weight)
+}
+
+// GetQuotesByAuthor returns the quotes whose author has the same match key
+// as authorFilter, in the order they were added.
+func (s *Storage) GetQuotesByAuthor(ctx context.Context, authorFilter string, filter storage.QuoteFilter) ([]models.Quote, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := slices.Sorted(maps.Keys(s.authorIndex[authorname.Key(authorFilter)]))
+	result := make([]models.Quote, 0, len(ids))
+	for i, id := range ids {
+		if err := checkCtx(ctx, i); err != nil {
+			return nil, err
+		}
+		if q := s.quotes[id]; filter.Matches(q) {
+			result = append(result, q)
+		}
 	}
 	return result, nil
 }
 
-func (s *Storage) DeleteQuote(ctx context.Context, id int64) error {
+// DeleteQuote removes a quote. Unless ifVersion is storage.AnyVersion, the
+// quote is only removed while its version still equals ifVersion.
+func (s *Storage) DeleteQuote(ctx context.Context, id int64, ifVersion int64) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -113,12 +428,24 @@ func (s *Storage) DeleteQuote(ctx context.Context, id int64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, exists := s.quotes[id]
+	quote, exists := s.quotes[id]
 	if !exists {
 		return storage.ErrQuoteNotFound
 	}
+	if ifVersion != storage.AnyVersion && quote.Version != ifVersion {
+		return storage.ErrVersionMismatch
+	}
 
 	delete(s.quotes, id)
+	delete(s.publicIDs, quote.PublicID)
+	removeFromIndex(s.langIndex, language.Primary(quote.Lang), id)
+	removeFromIndex(s.authorIndex, quote.AuthorKey, id)
+	s.unindexFingerprint(quote)
+	s.removeFromCollections(id)
+	s.removeFromFavorites(id)
+	delete(s.claims, id)
+	delete(s.served, id)
+	s.unindexTokens(id)
 
 	var newList []models.Quote
 	if len(s.quotesList) > 0 {
@@ -128,21 +455,563 @@ func (s *Storage) DeleteQuote(ctx context.Context, id int64) error {
 	}
 
 
+	var total int64
+	newWeights := make([]int64, 0, cap(newList))
 	for _, q := range s.quotesList {
 		if q.ID != id {
 			newList = append(newList, q)
+			total += int64(q.Weight)
+			newWeights = append(newWeights, total)
 		}
 	}
 	s.quotesList = newList
+	s.cumWeights = newWeights
+	s.logChange(models.ChangeDelete, quote)
+	s.version++
+
+	return nil
+}
+
+// UpdateQuote applies update to a quote and returns the result. Unless
+// ifVersion is storage.AnyVersion, the update is only applied while the
+// quote's version still equals ifVersion.
+func (s *Storage) UpdateQuote(ctx context.Context, id int64, update storage.QuoteUpdate, ifVersion int64) (models.Quote, error) {
+	select {
+	case <-ctx.Done():
+		return models.Quote{}, ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quote, exists := s.quotes[id]
+	if !exists {
+		return models.Quote{}, storage.ErrQuoteNotFound
+	}
+	if ifVersion != storage.AnyVersion && quote.Version != ifVersion {
+		return models.Quote{}, storage.ErrVersionMismatch
+	}
+
+	old := quote
+	if update.Text != nil {
+		quote.Text = *update.Text
+	}
+	if update.Author != nil {
+		quote.Author = authorname.Display(*update.Author)
+		quote.AuthorKey = authorname.Key(quote.Author)
+	}
+	if update.Weight != nil {
+		quote.Weight = normalizeWeight(*update.Weight)
+	}
+	if update.Lang != nil {
+		quote.Lang = *update.Lang
+		if quote.Lang == "" {
+			quote.Lang = language.Undetermined
+		}
+		quote.LangDetected = update.LangDetected
+	}
+	if update.Source != nil {
+		quote.Source = *update.Source
+	}
+	if update.SourceURL != nil {
+		quote.SourceURL = *update.SourceURL
+	}
+	quote.UpdatedAt = s.now().UTC()
+	quote.Version++
+
+	s.quotes[id] = quote
+	for i := range s.quotesList {
+		if s.quotesList[i].ID == id {
+			s.quotesList[i] = quote
+			break
+		}
+	}
+	if quote.Weight != old.Weight {
+		s.rebuildWeights()
+	}
+	if quote.Text != old.Text {
+		s.unindexTokens(id)
+		s.indexTokens(quote)
+	}
+	if quote.Lang != old.Lang {
+		removeFromIndex(s.langIndex, language.Primary(old.Lang), id)
+		addToIndex(s.langIndex, language.Primary(quote.Lang), id)
+	}
+	if quote.AuthorKey != old.AuthorKey {
+		removeFromIndex(s.authorIndex, old.AuthorKey, id)
+		addToIndex(s.authorIndex, quote.AuthorKey, id)
+	}
+	if quote.Text != old.Text || quote.AuthorKey != old.AuthorKey {
+		s.unindexFingerprint(old)
+		s.indexFingerprint(quote)
+	}
+	s.logChange(models.ChangeUpdate, quote)
+	s.version++
+
+	return quote, nil
+}
+
+// MergeAuthors renames every quote by one of the from authors to into in a
+// single step and reports how many quotes each source name had. Authors
+// are matched by key, so each from name takes every spelling with its key;
+// quotes already spelled exactly as into are left alone.
+func (s *Storage) MergeAuthors(ctx context.Context, into string, from []string) (map[string]int, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int, len(from))
+	for _, name := range from {
+		counts[name] = 0
+	}
+	into = authorname.Display(into)
+	intoKey := authorname.Key(into)
+
+	now := s.now().UTC()
+	for _, name := range from {
+		key := authorname.Key(name)
+		for _, id := range slices.Sorted(maps.Keys(s.authorIndex[key])) {
+			q := s.quotes[id]
+			if q.Author == into {
+				continue
+			}
+			counts[name]++
+			s.unindexFingerprint(q)
+			q.Author = into
+			q.AuthorKey = intoKey
+			q.UpdatedAt = now
+			q.Version++
+			s.quotes[id] = q
+			s.quotesList[s.listIndex(id)] = q
+			removeFromIndex(s.authorIndex, key, id)
+			addToIndex(s.authorIndex, intoKey, id)
+			s.indexFingerprint(q)
+			s.logChange(models.ChangeUpdate, q)
+		}
+	}
+	s.version++
+
+	return counts, nil
+}
+
+// listIndex returns the position of the quote with id in quotesList, which
+// is kept in ID order. The caller holds s.mu.
+func (s *Storage) listIndex(id int64) int {
+	i, _ := slices.BinarySearchFunc(s.quotesList, id, func(q models.Quote, id int64) int {
+		return cmp.Compare(q.ID, id)
+	})
+	return i
+}
+
+func (s *Storage) IncrementServed(ctx context.Context, id int64) error {
+	return s.AddServed(ctx, id, 1)
+}
 
+// AddServed implements storage.ServedAdder.
+func (s *Storage) AddServed(ctx context.Context, id int64, n int64) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counter, exists := s.served[id]
+	if !exists {
+		return storage.ErrQuoteNotFound
+	}
+	counter.Add(n)
+
+	return nil
+}
+
+func (s *Storage) GetPopularQuotes(ctx context.Context, limit int) ([]models.PopularQuote, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]models.PopularQuote, 0, len(s.quotesList))
+	for i, q := range s.quotesList {
+		if err := checkCtx(ctx, i); err != nil {
+			return nil, err
+		}
+		result = append(result, models.PopularQuote{
+			Quote:  q,
+			Served: s.served[q.ID].Load(),
+		})
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Served > result[j].Served
+	})
+
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// GetSimilarQuotes ranks other quotes by Jaccard similarity of their word
+// sets to the quote with the given id. Quotes by other authors come first;
+// quotes by the same author only fill the remaining slots.
+func (s *Storage) GetSimilarQuotes(ctx context.Context, id int64, limit int) ([]models.SimilarQuote, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	source, exists := s.quotes[id]
+	if !exists {
+		return nil, storage.ErrQuoteNotFound
+	}
+
+	sourceTokens := s.tokens[id]
+	shared := make(map[int64]int)
+	for _, token := range sourceTokens {
+		for candidate := range s.tokenIndex[token] {
+			if candidate != id {
+				shared[candidate]++
+			}
+		}
+	}
+
+	result := make([]models.SimilarQuote, 0, len(shared))
+	i := 0
+	for candidate, intersection := range shared {
+		if err := checkCtx(ctx, i); err != nil {
+			return nil, err
+		}
+		i++
+		union := len(sourceTokens) + len(s.tokens[candidate]) - intersection
+		result = append(result, models.SimilarQuote{
+			Quote: s.quotes[candidate],
+			Score: float64(intersection) / float64(union),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		iOther := result[i].AuthorKey != source.AuthorKey
+		jOther := result[j].AuthorKey != source.AuthorKey
+		if iOther != jOther {
+			return iOther
+		}
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].ID < result[j].ID
+	})
+
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// Restore loads quotes from a snapshot into an empty store, keeping their
+// IDs, public IDs, timestamps and versions. New quotes get IDs after the
+// highest one restored, and quotes without a public ID get one when public
+// IDs are enabled. It fails with storage.ErrStoreNotEmpty if the store holds any
+// quote, and loads nothing if any quote is invalid.
+func (s *Storage) Restore(ctx context.Context, quotes []models.Quote) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	restored, err := normalizeStored(quotes)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.quotes) > 0 {
+		return storage.ErrStoreNotEmpty
+	}
+	if s.newPublicID != nil {
+		taken := make(map[string]struct{}, len(restored))
+		for _, q := range restored {
+			if q.PublicID != "" {
+				taken[q.PublicID] = struct{}{}
+			}
+		}
+		for i := range restored {
+			if restored[i].PublicID == "" {
+				restored[i].PublicID = s.uniquePublicID(taken)
+				taken[restored[i].PublicID] = struct{}{}
+			}
+		}
+	}
+	s.putQuotes(restored)
 	return nil
 }
 
+// PutQuotes stores quotes exactly as given, IDs, timestamps and versions
+// included, replacing any quote with the same ID. It lets a replica mirror
+// another store. Nothing is stored if any quote is invalid.
+func (s *Storage) PutQuotes(ctx context.Context, quotes []models.Quote) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	put, err := normalizeStored(quotes)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, q := range put {
+		if owner, exists := s.publicIDs[q.PublicID]; exists && owner != q.ID {
+			return fmt.Errorf("quote %d: public id %s belongs to quote %d", q.ID, q.PublicID, owner)
+		}
+	}
+	s.putQuotes(put)
+	return nil
+}
+
+// normalizeStored validates quotes that already carry an ID and applies
+// the defaults AddQuote would have.
+func normalizeStored(quotes []models.Quote) ([]models.Quote, error) {
+	seen := make(map[int64]struct{}, len(quotes))
+	seenPublic := make(map[string]struct{})
+	normalized := make([]models.Quote, 0, len(quotes))
+	for _, q := range quotes {
+		if q.ID <= 0 {
+			return nil, fmt.Errorf("quote has invalid id %d", q.ID)
+		}
+		if _, dup := seen[q.ID]; dup {
+			return nil, fmt.Errorf("duplicate quote id %d", q.ID)
+		}
+		seen[q.ID] = struct{}{}
+		// Keys are derived rather than trusted, which also fills them in
+		// for snapshots taken before quotes had them.
+		q.Author = authorname.Display(q.Author)
+		if q.Text == "" || q.Author == "" {
+			return nil, fmt.Errorf("quote %d has no text or author", q.ID)
+		}
+		q.AuthorKey = authorname.Key(q.Author)
+		if q.PublicID != "" {
+			if !publicid.Valid(q.PublicID) {
+				return nil, fmt.Errorf("quote %d has invalid public id %q", q.ID, q.PublicID)
+			}
+			q.PublicID = publicid.Normalize(q.PublicID)
+			if _, dup := seenPublic[q.PublicID]; dup {
+				return nil, fmt.Errorf("duplicate public id %s", q.PublicID)
+			}
+			seenPublic[q.PublicID] = struct{}{}
+		}
+		q.Weight = normalizeWeight(q.Weight)
+		if q.Lang == "" {
+			q.Lang = language.Undetermined
+		}
+		if q.Version <= 0 {
+			q.Version = 1
+		}
+		normalized = append(normalized, q)
+	}
+	return normalized, nil
+}
+
+// putQuotes inserts or replaces quotes, keeping quotesList in ID order.
+// The caller holds s.mu.
+func (s *Storage) putQuotes(quotes []models.Quote) {
+	if len(quotes) == 0 {
+		return
+	}
+	for _, q := range quotes {
+		if old, exists := s.quotes[q.ID]; exists {
+			s.unindexTokens(q.ID)
+			removeFromIndex(s.langIndex, language.Primary(old.Lang), q.ID)
+			removeFromIndex(s.authorIndex, old.AuthorKey, q.ID)
+			s.unindexFingerprint(old)
+			delete(s.publicIDs, old.PublicID)
+			s.logChange(models.ChangeUpdate, q)
+		} else {
+			s.served[q.ID] = new(atomic.Int64)
+			s.logChange(models.ChangeAdd, q)
+		}
+		s.quotes[q.ID] = q
+		if q.PublicID != "" {
+			s.publicIDs[q.PublicID] = q.ID
+		}
+		s.indexTokens(q)
+		addToIndex(s.langIndex, language.Primary(q.Lang), q.ID)
+		addToIndex(s.authorIndex, q.AuthorKey, q.ID)
+		s.indexFingerprint(q)
+
+		i := sort.Search(len(s.quotesList), func(i int) bool { return s.quotesList[i].ID >= q.ID })
+		if i < len(s.quotesList) && s.quotesList[i].ID == q.ID {
+			s.quotesList[i] = q
+		} else {
+			s.quotesList = slices.Insert(s.quotesList, i, q)
+		}
+		if q.ID >= s.nextID {
+			s.nextID = q.ID + 1
+		}
+	}
+	s.rebuildWeights()
+	s.version++
+}
+
+// Version returns a counter that changes whenever the stored quotes change.
+func (s *Storage) Version(ctx context.Context) (uint64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.version, nil
+}
+
+// Shutdown closes the store. Clearing memory cannot block, so ctx only
+// stops it from starting once done.
+func (s *Storage) Shutdown(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Close()
+}
+
 func (s *Storage) Close() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.quotes = make(map[int64]models.Quote)
 	s.quotesList = []models.Quote{}
+	s.served = make(map[int64]*atomic.Int64)
+	s.cumWeights = nil
+	s.tokens = make(map[int64][]string)
+	s.tokenIndex = make(map[string]map[int64]struct{})
+	s.langIndex = make(map[string]map[int64]struct{})
+	s.authorIndex = make(map[string]map[int64]struct{})
+	s.fingerprintIndex = make(map[string]map[int64]struct{})
+	s.publicIDs = make(map[string]int64)
 	s.nextID = 1
+	s.collections = make(map[int64]*collection)
+	s.quoteCollections = make(map[int64]map[int64]struct{})
+	s.nextCollectionID = 1
+	s.favorites = make(map[string]*orderedSet)
+	s.quoteFavorites = make(map[int64]map[string]struct{})
+	s.claims = make(map[int64]claim)
+	s.version++
 	return nil
-}
\ No newline at end of file
+}
+
+func (s *Storage) totalWeight() int64 {
+	if len(s.cumWeights) == 0 {
+		return 0
+	}
+	return s.cumWeights[len(s.cumWeights)-1]
+}
+
+func (s *Storage) rebuildWeights() {
+	var total int64
+	s.cumWeights = s.cumWeights[:0]
+	for _, q := range s.quotesList {
+		total += int64(q.Weight)
+		s.cumWeights = append(s.cumWeights, total)
+	}
+}
+
+func normalizeWeight(weight int) int {
+	switch {
+	case weight <= 0:
+		return storage.DefaultWeight
+	case weight > storage.MaxWeight:
+		return storage.MaxWeight
+	default:
+		return weight
+	}
+}
+
+func (s *Storage) indexTokens(quote models.Quote) {
+	tokens := tokenizer.Unique(quote.Text)
+	s.tokens[quote.ID] = tokens
+	for _, token := range tokens {
+		addToIndex(s.tokenIndex, token, quote.ID)
+	}
+}
+
+func (s *Storage) unindexTokens(id int64) {
+	for _, token := range s.tokens[id] {
+		removeFromIndex(s.tokenIndex, token, id)
+	}
+	delete(s.tokens, id)
+}
+
+// indexFingerprint and unindexFingerprint keep fingerprintIndex in step
+// with the text and author of a quote.
+func (s *Storage) indexFingerprint(quote models.Quote) {
+	addToIndex(s.fingerprintIndex, fingerprint.Of(quote.Text, quote.Author), quote.ID)
+}
+
+func (s *Storage) unindexFingerprint(quote models.Quote) {
+	removeFromIndex(s.fingerprintIndex, fingerprint.Of(quote.Text, quote.Author), quote.ID)
+}
+
+func addToIndex[K, V comparable](index map[K]map[V]struct{}, key K, id V) {
+	ids, ok := index[key]
+	if !ok {
+		ids = make(map[V]struct{})
+		index[key] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+func removeFromIndex[K, V comparable](index map[K]map[V]struct{}, key K, id V) {
+	ids := index[key]
+	delete(ids, id)
+	if len(ids) == 0 {
+		delete(index, key)
+	}
+}
+
+// Backend implements storage.Describer.
+func (s *Storage) Backend() string {
+	return "memory"
+}
+
+// GetQuotesByFingerprint implements storage.FingerprintIndex.
+func (s *Storage) GetQuotesByFingerprint(ctx context.Context, fp string) ([]models.Quote, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := slices.Sorted(maps.Keys(s.fingerprintIndex[fp]))
+	result := make([]models.Quote, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, s.quotes[id])
+	}
+	return result, nil
+}
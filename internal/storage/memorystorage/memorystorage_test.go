@@ -0,0 +1,778 @@
+package memorystorage_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"quotes-service/internal/lib/fingerprint"
+	"quotes-service/internal/lib/publicid"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+	"quotes-service/internal/storage/storagetest"
+)
+
+func TestGetRandomQuoteWeighted(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+
+	weights := map[int64]int{}
+	for _, w := range []int{1, 2, 3, 4, 10} {
+		id, err := store.AddQuote(ctx, models.Quote{Text: "text", Author: "author", Weight: w})
+		if err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+		weights[id] = w
+	}
+
+	// Deleting and re-adding exercises the prefix sum rebuild.
+	if err := store.DeleteQuote(ctx, 3, storage.AnyVersion); err != nil {
+		t.Fatalf("failed to delete quote: %v", err)
+	}
+	delete(weights, 3)
+	id, err := store.AddQuote(ctx, models.Quote{Text: "text", Author: "author", Weight: 5})
+	if err != nil {
+		t.Fatalf("failed to add quote: %v", err)
+	}
+	weights[id] = 5
+
+	const samples = 200000
+	counts := map[int64]int{}
+	for i := 0; i < samples; i++ {
+		q, err := store.GetRandomQuote(ctx, storage.RandomOptions{})
+		if err != nil {
+			t.Fatalf("failed to get random quote: %v", err)
+		}
+		counts[q.ID]++
+	}
+
+	totalWeight := 0
+	for _, w := range weights {
+		totalWeight += w
+	}
+
+	var chiSquare float64
+	for id, w := range weights {
+		expected := float64(samples) * float64(w) / float64(totalWeight)
+		diff := float64(counts[id]) - expected
+		chiSquare += diff * diff / expected
+	}
+
+	// Critical value for 4 degrees of freedom at p = 0.001.
+	const critical = 18.47
+	if chiSquare > critical {
+		t.Errorf("weighted distribution deviates from weights: chi-square %.2f > %.2f, counts %v, weights %v", chiSquare, critical, counts, weights)
+	}
+}
+
+func TestGetRandomQuoteUnweighted(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+
+	for _, w := range []int{1, 100} {
+		if _, err := store.AddQuote(ctx, models.Quote{Text: "text", Author: "author", Weight: w}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+
+	const samples = 100000
+	counts := map[int64]int{}
+	for i := 0; i < samples; i++ {
+		q, err := store.GetRandomQuote(ctx, storage.RandomOptions{Unweighted: true})
+		if err != nil {
+			t.Fatalf("failed to get random quote: %v", err)
+		}
+		counts[q.ID]++
+	}
+
+	expected := float64(samples) / 2
+	var chiSquare float64
+	for _, c := range counts {
+		diff := float64(c) - expected
+		chiSquare += diff * diff / expected
+	}
+
+	// Critical value for 1 degree of freedom at p = 0.001.
+	const critical = 10.83
+	if chiSquare > critical {
+		t.Errorf("unweighted distribution is not uniform: chi-square %.2f > %.2f, counts %v", chiSquare, critical, counts)
+	}
+}
+
+func TestAddQuoteNormalizesWeight(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		weight   int
+		expected int
+	}{
+		{name: "default", weight: 0, expected: storage.DefaultWeight},
+		{name: "in range", weight: 7, expected: 7},
+		{name: "capped", weight: storage.MaxWeight + 1, expected: storage.MaxWeight},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := store.AddQuote(ctx, models.Quote{Text: tc.name, Author: "author", Weight: tc.weight}); err != nil {
+				t.Fatalf("failed to add quote: %v", err)
+			}
+			quotes, err := store.GetAllQuotes(ctx, storage.QuoteFilter{})
+			if err != nil {
+				t.Fatalf("failed to get quotes: %v", err)
+			}
+			if got := quotes[len(quotes)-1].Weight; got != tc.expected {
+				t.Errorf("expected weight %d, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetRandomQuoteExcludeIDs(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+
+	for _, w := range []int{100, 100, 1} {
+		if _, err := store.AddQuote(ctx, models.Quote{Text: "text", Author: "author", Weight: w}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+
+	for i := 0; i < 1000; i++ {
+		q, err := store.GetRandomQuote(ctx, storage.RandomOptions{ExcludeIDs: []int64{1, 2}})
+		if err != nil {
+			t.Fatalf("failed to get random quote: %v", err)
+		}
+		if q.ID != 3 {
+			t.Fatalf("expected excluded quotes to be skipped, got %d", q.ID)
+		}
+	}
+
+	q, err := store.GetRandomQuote(ctx, storage.RandomOptions{ExcludeIDs: []int64{1, 2, 3, 4}})
+	if err != nil {
+		t.Fatalf("expected fallback when everything is excluded, got error: %v", err)
+	}
+	if q.ID < 1 || q.ID > 3 {
+		t.Errorf("unexpected quote id %d", q.ID)
+	}
+}
+
+func TestGetSimilarQuotes(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+
+	quotes := []models.Quote{
+		{Text: "The only way to do great work is to love what you do", Author: "Jobs"},
+		{Text: "Love what you do and do what you love", Author: "Jobs"},
+		{Text: "Great work is done by people who love what they do", Author: "Other"},
+		{Text: "Nothing in common here", Author: "Nobody"},
+	}
+	for _, q := range quotes {
+		if _, err := store.AddQuote(ctx, q); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+
+	similar, err := store.GetSimilarQuotes(ctx, 1, 10)
+	if err != nil {
+		t.Fatalf("failed to get similar quotes: %v", err)
+	}
+	if len(similar) != 2 {
+		t.Fatalf("expected 2 similar quotes, got %+v", similar)
+	}
+	if similar[0].ID != 3 || similar[1].ID != 2 {
+		t.Errorf("expected other authors first, got ids %d, %d", similar[0].ID, similar[1].ID)
+	}
+	for _, q := range similar {
+		if q.Score <= 0 || q.Score > 1 {
+			t.Errorf("unexpected score %f for quote %d", q.Score, q.ID)
+		}
+	}
+
+	if err := store.DeleteQuote(ctx, 3, storage.AnyVersion); err != nil {
+		t.Fatalf("failed to delete quote: %v", err)
+	}
+	similar, err = store.GetSimilarQuotes(ctx, 1, 10)
+	if err != nil {
+		t.Fatalf("failed to get similar quotes: %v", err)
+	}
+	if len(similar) != 1 || similar[0].ID != 2 {
+		t.Errorf("expected deleted quote to leave the index, got %+v", similar)
+	}
+
+	similar, err = store.GetSimilarQuotes(ctx, 4, 10)
+	if err != nil || len(similar) != 0 {
+		t.Errorf("expected no similar quotes, got %+v, %v", similar, err)
+	}
+
+	if _, err := store.GetSimilarQuotes(ctx, 42, 10); !errors.Is(err, storage.ErrQuoteNotFound) {
+		t.Errorf("expected ErrQuoteNotFound, got %v", err)
+	}
+}
+
+func TestLangFilter(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+
+	quotes := []models.Quote{
+		{Text: "one", Author: "A", Lang: "en"},
+		{Text: "два", Author: "B", Lang: "ru"},
+		{Text: "three", Author: "A", Lang: "en-US"},
+		{Text: "???", Author: "C"},
+	}
+	for _, q := range quotes {
+		if _, err := store.AddQuote(ctx, q); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+
+	tests := []struct {
+		lang     string
+		expected []int64
+	}{
+		{lang: "en", expected: []int64{1, 3}},
+		{lang: "en-US", expected: []int64{3}},
+		{lang: "ru", expected: []int64{2}},
+		{lang: "und", expected: []int64{4}},
+		{lang: "de", expected: []int64{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.lang, func(t *testing.T) {
+			got, err := store.GetAllQuotes(ctx, storage.QuoteFilter{Lang: tc.lang})
+			if err != nil {
+				t.Fatalf("failed to get quotes: %v", err)
+			}
+			ids := make([]int64, 0, len(got))
+			for _, q := range got {
+				ids = append(ids, q.ID)
+			}
+			if !reflect.DeepEqual(ids, tc.expected) {
+				t.Errorf("expected ids %v, got %v", tc.expected, ids)
+			}
+		})
+	}
+
+	byAuthor, err := store.GetQuotesByAuthor(ctx, "A", storage.QuoteFilter{Lang: "en-US"})
+	if err != nil || len(byAuthor) != 1 || byAuthor[0].ID != 3 {
+		t.Errorf("expected author search to honour lang filter, got %+v, %v", byAuthor, err)
+	}
+
+	for i := 0; i < 100; i++ {
+		q, err := store.GetRandomQuote(ctx, storage.RandomOptions{Filter: storage.QuoteFilter{Lang: "ru"}})
+		if err != nil || q.ID != 2 {
+			t.Fatalf("expected random quote in ru, got %+v, %v", q, err)
+		}
+	}
+	if _, err := store.GetRandomQuote(ctx, storage.RandomOptions{Filter: storage.QuoteFilter{Lang: "de"}}); !errors.Is(err, storage.ErrQuoteNotFound) {
+		t.Errorf("expected ErrQuoteNotFound for empty language, got %v", err)
+	}
+
+	if err := store.DeleteQuote(ctx, 2, storage.AnyVersion); err != nil {
+		t.Fatalf("failed to delete quote: %v", err)
+	}
+	if got, _ := store.GetAllQuotes(ctx, storage.QuoteFilter{Lang: "ru"}); len(got) != 0 {
+		t.Errorf("expected deleted quote to leave the language index, got %+v", got)
+	}
+}
+
+func TestHasSourceFilter(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+
+	quotes := []models.Quote{
+		{Text: "one", Author: "A", Source: "Book"},
+		{Text: "two", Author: "A"},
+		{Text: "three", Author: "B", SourceURL: "https://example.com"},
+	}
+	for _, q := range quotes {
+		if _, err := store.AddQuote(ctx, q); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+
+	yes, no := true, false
+	tests := []struct {
+		name     string
+		filter   storage.QuoteFilter
+		expected []int64
+	}{
+		{name: "with source", filter: storage.QuoteFilter{HasSource: &yes}, expected: []int64{1, 3}},
+		{name: "without source", filter: storage.QuoteFilter{HasSource: &no}, expected: []int64{2}},
+		{name: "combined with lang", filter: storage.QuoteFilter{HasSource: &yes, Lang: "und"}, expected: []int64{1, 3}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := store.GetAllQuotes(ctx, tc.filter)
+			if err != nil {
+				t.Fatalf("failed to get quotes: %v", err)
+			}
+			ids := make([]int64, 0, len(got))
+			for _, q := range got {
+				ids = append(ids, q.ID)
+			}
+			if !reflect.DeepEqual(ids, tc.expected) {
+				t.Errorf("expected ids %v, got %v", tc.expected, ids)
+			}
+		})
+	}
+}
+
+func TestUpdateQuote(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	store, err := memorystorage.New(memorystorage.WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+
+	id, err := store.AddQuote(ctx, models.Quote{Text: "Hello world", Author: "A", Lang: "en"})
+	if err != nil {
+		t.Fatalf("failed to add quote: %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	text, lang := "Привет мир", "ru"
+	updated, err := store.UpdateQuote(ctx, id, storage.QuoteUpdate{Text: &text, Lang: &lang}, 1)
+	if err != nil {
+		t.Fatalf("failed to update quote: %v", err)
+	}
+	if updated.Version != 2 || !updated.UpdatedAt.Equal(now) || updated.CreatedAt.Equal(now) || updated.Author != "A" {
+		t.Fatalf("unexpected updated quote %+v", updated)
+	}
+
+	if _, err := store.UpdateQuote(ctx, id, storage.QuoteUpdate{Text: &text}, 1); !errors.Is(err, storage.ErrVersionMismatch) {
+		t.Fatalf("expected ErrVersionMismatch, got %v", err)
+	}
+	if err := store.DeleteQuote(ctx, id, 1); !errors.Is(err, storage.ErrVersionMismatch) {
+		t.Fatalf("expected ErrVersionMismatch on delete, got %v", err)
+	}
+
+	ru, err := store.GetAllQuotes(ctx, storage.QuoteFilter{Lang: "ru"})
+	if err != nil || len(ru) != 1 || ru[0].Text != text {
+		t.Fatalf("expected the quote to be indexed under ru, got %+v, %v", ru, err)
+	}
+	en, _ := store.GetAllQuotes(ctx, storage.QuoteFilter{Lang: "en"})
+	if len(en) != 0 {
+		t.Fatalf("expected no en quotes after update, got %+v", en)
+	}
+
+	if err := store.DeleteQuote(ctx, id, 2); err != nil {
+		t.Fatalf("failed to delete with current version: %v", err)
+	}
+}
+
+func TestMergeAuthors(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+
+	for _, author := range []string{"A. Einstein", "Einstein, A.", "A. Einstein", "Albert Einstein", "Niels Bohr"} {
+		if _, err := store.AddQuote(ctx, models.Quote{Text: "Quote by " + author, Author: author}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+
+	counts, err := store.MergeAuthors(ctx, "Albert Einstein", []string{"A. Einstein", "Einstein, A.", "Unknown"})
+	if err != nil {
+		t.Fatalf("failed to merge authors: %v", err)
+	}
+	expected := map[string]int{"A. Einstein": 2, "Einstein, A.": 1, "Unknown": 0}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Fatalf("expected counts %v, got %v", expected, counts)
+	}
+
+	merged, err := store.GetQuotesByAuthor(ctx, "Albert Einstein", storage.QuoteFilter{})
+	if err != nil || len(merged) != 4 {
+		t.Fatalf("expected 4 quotes under the new name, got %d, %v", len(merged), err)
+	}
+	for _, q := range merged {
+		if q.ID != 4 && q.Version != 2 {
+			t.Errorf("expected merged quote %d to get a new version, got %d", q.ID, q.Version)
+		}
+	}
+	if old, _ := store.GetQuotesByAuthor(ctx, "A. Einstein", storage.QuoteFilter{}); len(old) != 0 {
+		t.Fatalf("expected no quotes under the old name, got %d", len(old))
+	}
+	all, _ := store.GetAllQuotes(ctx, storage.QuoteFilter{})
+	for _, q := range all {
+		if byID, _ := store.GetQuote(ctx, q.ID); byID.Author != q.Author {
+			t.Fatalf("list and by-ID lookups disagree for quote %d: %q vs %q", q.ID, q.Author, byID.Author)
+		}
+	}
+}
+
+func TestGetQuotesByFingerprint(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for _, q := range []models.Quote{
+		{Text: "Stay hungry, stay foolish.", Author: "Steve Jobs"},
+		{Text: "stay HUNGRY  stay foolish", Author: "steve jobs"},
+		{Text: "Stay hungry.", Author: "Steve Jobs"},
+		{Text: "Stay hungry, stay foolish.", Author: "S. Jobs"},
+	} {
+		if _, err := store.AddQuote(ctx, q); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+	foolish := fingerprint.Of("Stay hungry, stay foolish.", "Steve Jobs")
+	hungry := fingerprint.Of("Stay hungry.", "Steve Jobs")
+
+	steps := []struct {
+		name    string
+		change  func() error
+		foolish []int64
+		hungry  []int64
+	}{
+		{
+			name:    "added",
+			change:  func() error { return nil },
+			foolish: []int64{1, 2},
+			hungry:  []int64{3},
+		},
+		{
+			name: "text updated",
+			change: func() error {
+				text := "Stay hungry, stay foolish!"
+				_, err := store.UpdateQuote(ctx, 3, storage.QuoteUpdate{Text: &text}, storage.AnyVersion)
+				return err
+			},
+			foolish: []int64{1, 2, 3},
+		},
+		{
+			name: "authors merged",
+			change: func() error {
+				_, err := store.MergeAuthors(ctx, "Steve Jobs", []string{"S. Jobs"})
+				return err
+			},
+			foolish: []int64{1, 2, 3, 4},
+		},
+		{
+			name:    "deleted",
+			change:  func() error { return store.DeleteQuote(ctx, 2, storage.AnyVersion) },
+			foolish: []int64{1, 3, 4},
+		},
+	}
+
+	ids := func(fp string) []int64 {
+		quotes, err := store.GetQuotesByFingerprint(ctx, fp)
+		if err != nil {
+			t.Fatalf("failed to look up fingerprint: %v", err)
+		}
+		var out []int64
+		for _, q := range quotes {
+			out = append(out, q.ID)
+		}
+		return out
+	}
+	for _, step := range steps {
+		if err := step.change(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := ids(foolish); !slices.Equal(got, step.foolish) {
+			t.Fatalf("%s: expected %v, got %v", step.name, step.foolish, got)
+		}
+		if got := ids(hungry); !slices.Equal(got, step.hungry) {
+			t.Fatalf("%s: expected %v, got %v", step.name, step.hungry, got)
+		}
+	}
+}
+
+// cancelAfterCtx is never done at method entry but reports cancellation
+// once Err has been called more than checks times, and counts the calls.
+type cancelAfterCtx struct {
+	context.Context
+	checks int
+	calls  int
+}
+
+func (c *cancelAfterCtx) Err() error {
+	c.calls++
+	if c.calls > c.checks {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestScansStopWhenCanceled(t *testing.T) {
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	const total = 20000
+	for i := 0; i < total; i++ {
+		if _, err := store.AddQuote(context.Background(), models.Quote{Text: "Quote", Author: "Author", Lang: "en"}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+	hasSource := false
+
+	tests := []struct {
+		name string
+		call func(ctx context.Context) error
+	}{
+		{name: "GetAllQuotes", call: func(ctx context.Context) error {
+			_, err := store.GetAllQuotes(ctx, storage.QuoteFilter{})
+			return err
+		}},
+		{name: "GetAllQuotes filtered", call: func(ctx context.Context) error {
+			_, err := store.GetAllQuotes(ctx, storage.QuoteFilter{HasSource: &hasSource})
+			return err
+		}},
+		{name: "GetAllQuotes by language", call: func(ctx context.Context) error {
+			_, err := store.GetAllQuotes(ctx, storage.QuoteFilter{Lang: "en"})
+			return err
+		}},
+		{name: "GetQuotesByAuthor", call: func(ctx context.Context) error {
+			_, err := store.GetQuotesByAuthor(ctx, "Author", storage.QuoteFilter{})
+			return err
+		}},
+		{name: "GetPopularQuotes", call: func(ctx context.Context) error {
+			_, err := store.GetPopularQuotes(ctx, 10)
+			return err
+		}},
+		{name: "GetSimilarQuotes", call: func(ctx context.Context) error {
+			_, err := store.GetSimilarQuotes(ctx, 1, 10)
+			return err
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &cancelAfterCtx{Context: context.Background(), checks: 1}
+			if err := tc.call(ctx); !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected context.Canceled, got %v", err)
+			}
+			// The scan stops at the first check after cancellation instead
+			// of walking all quotes.
+			if ctx.calls != 2 {
+				t.Errorf("expected the scan to stop after 2 checks, got %d", ctx.calls)
+			}
+
+			canceled, cancel := context.WithCancel(context.Background())
+			cancel()
+			if err := tc.call(canceled); !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected context.Canceled for a pre-canceled context, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	ctx := context.Background()
+	created := time.Date(2023, time.May, 1, 8, 0, 0, 0, time.UTC)
+	quotes := []models.Quote{
+		{ID: 7, Text: "Hello world", Author: "B", Lang: "en", Weight: 3, CreatedAt: created, UpdatedAt: created, Version: 4},
+		{ID: 2, Text: "Привет мир", Author: "A", Lang: "ru", CreatedAt: created, UpdatedAt: created, Version: 1},
+	}
+
+	tests := []struct {
+		name    string
+		quotes  []models.Quote
+		wantErr bool
+	}{
+		{name: "valid", quotes: quotes},
+		{name: "duplicate id", quotes: append(quotes, models.Quote{ID: 2, Text: "t", Author: "a"}), wantErr: true},
+		{name: "zero id", quotes: []models.Quote{{Text: "t", Author: "a"}}, wantErr: true},
+		{name: "no author", quotes: []models.Quote{{ID: 1, Text: "t"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := memorystorage.New()
+			if err != nil {
+				t.Fatalf("failed to init storage: %v", err)
+			}
+			err = store.Restore(ctx, tt.quotes)
+			if tt.wantErr {
+				all, _ := store.GetAllQuotes(ctx, storage.QuoteFilter{})
+				if err == nil || len(all) != 0 {
+					t.Fatalf("expected an error and an empty store, got %v and %d quotes", err, len(all))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to restore: %v", err)
+			}
+
+			all, err := store.GetAllQuotes(ctx, storage.QuoteFilter{})
+			if err != nil {
+				t.Fatalf("failed to get quotes: %v", err)
+			}
+			if len(all) != 2 || all[0].ID != 2 || all[1].ID != 7 {
+				t.Fatalf("expected quotes 2 and 7 in id order, got %+v", all)
+			}
+			if all[1].Version != 4 || all[1].Weight != 3 || !all[1].CreatedAt.Equal(created) {
+				t.Fatalf("expected quote 7 to keep its fields, got %+v", all[1])
+			}
+			ru, _ := store.GetAllQuotes(ctx, storage.QuoteFilter{Lang: "ru"})
+			if len(ru) != 1 || ru[0].ID != 2 {
+				t.Fatalf("expected quote 2 to be indexed under ru, got %+v", ru)
+			}
+			similar, err := store.GetSimilarQuotes(ctx, 7, 5)
+			if err != nil {
+				t.Fatalf("failed to get similar quotes: %v", err)
+			}
+			if len(similar) != 0 {
+				t.Fatalf("expected no similar quotes, got %+v", similar)
+			}
+
+			id, err := store.AddQuote(ctx, models.Quote{Text: "new", Author: "C"})
+			if err != nil || id != 8 {
+				t.Fatalf("expected the next quote to get id 8, got %d, %v", id, err)
+			}
+			if err := store.Restore(ctx, quotes); !errors.Is(err, storage.ErrStoreNotEmpty) {
+				t.Fatalf("expected ErrStoreNotEmpty, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPutQuotes(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	for _, text := range []string{"one", "two"} {
+		if _, err := store.AddQuote(ctx, models.Quote{Text: text, Author: "A", Lang: "en"}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+
+	err = store.PutQuotes(ctx, []models.Quote{
+		{ID: 2, Text: "zwei", Author: "B", Lang: "de", Version: 3},
+		{ID: 5, Text: "five", Author: "C"},
+	})
+	if err != nil {
+		t.Fatalf("failed to put quotes: %v", err)
+	}
+
+	all, err := store.GetAllQuotes(ctx, storage.QuoteFilter{})
+	if err != nil {
+		t.Fatalf("failed to get quotes: %v", err)
+	}
+	var got []string
+	for _, q := range all {
+		got = append(got, q.Text)
+	}
+	if !reflect.DeepEqual(got, []string{"one", "zwei", "five"}) {
+		t.Fatalf("unexpected quotes %v", got)
+	}
+	if en, _ := store.GetAllQuotes(ctx, storage.QuoteFilter{Lang: "en"}); len(en) != 1 {
+		t.Fatalf("expected the replaced quote to leave the en index, got %+v", en)
+	}
+	if de, _ := store.GetAllQuotes(ctx, storage.QuoteFilter{Lang: "de"}); len(de) != 1 || de[0].Version != 3 {
+		t.Fatalf("expected the replaced quote under de with its version, got %+v", de)
+	}
+	if id, err := store.AddQuote(ctx, models.Quote{Text: "six", Author: "A"}); err != nil || id != 6 {
+		t.Fatalf("expected the next quote to get id 6, got %d, %v", id, err)
+	}
+	if err := store.PutQuotes(ctx, []models.Quote{{ID: 9, Text: "nine"}}); err == nil {
+		t.Fatal("expected an error for a quote without an author")
+	}
+}
+
+func TestPublicIDs(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New(memorystorage.WithPublicIDs(publicid.FormatUUID.New))
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	id, err := store.AddQuote(ctx, models.Quote{Text: "one", Author: "A", PublicID: "ignored"})
+	if err != nil {
+		t.Fatalf("failed to add quote: %v", err)
+	}
+	q, err := store.GetQuote(ctx, id)
+	if err != nil || !publicid.Valid(q.PublicID) {
+		t.Fatalf("expected a generated public id, got %+v, %v", q, err)
+	}
+
+	text := "uno"
+	if _, err := store.UpdateQuote(ctx, id, storage.QuoteUpdate{Text: &text}, storage.AnyVersion); err != nil {
+		t.Fatalf("failed to update quote: %v", err)
+	}
+	got, err := store.GetQuoteByPublicID(ctx, strings.ToUpper(q.PublicID))
+	if err != nil || got.ID != id || got.Text != "uno" || got.PublicID != q.PublicID {
+		t.Fatalf("expected the updated quote by public id, got %+v, %v", got, err)
+	}
+
+	if err := store.PutQuotes(ctx, []models.Quote{{ID: 7, PublicID: q.PublicID, Text: "seven", Author: "B"}}); err == nil {
+		t.Fatal("expected an error for a public id owned by another quote")
+	}
+	if err := store.PutQuotes(ctx, []models.Quote{{ID: 7, PublicID: "not-an-id", Text: "seven", Author: "B"}}); err == nil {
+		t.Fatal("expected an error for an invalid public id")
+	}
+
+	if err := store.DeleteQuote(ctx, id, storage.AnyVersion); err != nil {
+		t.Fatalf("failed to delete quote: %v", err)
+	}
+	if _, err := store.GetQuoteByPublicID(ctx, q.PublicID); !errors.Is(err, storage.ErrQuoteNotFound) {
+		t.Fatalf("expected ErrQuoteNotFound after delete, got %v", err)
+	}
+}
+
+func TestRestoreAssignsPublicIDs(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New(memorystorage.WithPublicIDs(publicid.FormatULID.New))
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	const kept = "01ARZ3NDEKTSV4RRFFQ69G5FAV"
+	err = store.Restore(ctx, []models.Quote{
+		{ID: 1, PublicID: strings.ToLower(kept), Text: "one", Author: "A"},
+		{ID: 2, Text: "two", Author: "A"},
+	})
+	if err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+	if q, err := store.GetQuoteByPublicID(ctx, kept); err != nil || q.ID != 1 || q.PublicID != kept {
+		t.Fatalf("expected the restored public id to be kept, got %+v, %v", q, err)
+	}
+	q, err := store.GetQuote(ctx, 2)
+	if err != nil || !publicid.Valid(q.PublicID) {
+		t.Fatalf("expected a public id for the quote without one, got %+v, %v", q, err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Conformance(t, func(t *testing.T) storage.QuoteStore {
+		store, err := memorystorage.New()
+		if err != nil {
+			t.Fatalf("failed to init storage: %v", err)
+		}
+		return store
+	})
+}
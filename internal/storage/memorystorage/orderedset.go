@@ -0,0 +1,61 @@
+package memorystorage
+
+import (
+	"maps"
+	"slices"
+)
+
+// orderedSet is a set of quote IDs that remembers insertion order. ids keeps
+// the order; members makes membership checks O(1).
+type orderedSet struct {
+	ids     []int64
+	members map[int64]struct{}
+}
+
+func newOrderedSet() *orderedSet {
+	return &orderedSet{
+		ids:     make([]int64, 0),
+		members: make(map[int64]struct{}),
+	}
+}
+
+func (s *orderedSet) has(id int64) bool {
+	_, ok := s.members[id]
+	return ok
+}
+
+// add appends id and reports whether it was not already present.
+func (s *orderedSet) add(id int64) bool {
+	if s.has(id) {
+		return false
+	}
+	s.members[id] = struct{}{}
+	s.ids = append(s.ids, id)
+	return true
+}
+
+// remove drops id and reports whether it was present.
+func (s *orderedSet) remove(id int64) bool {
+	if !s.has(id) {
+		return false
+	}
+	delete(s.members, id)
+	for i, member := range s.ids {
+		if member == id {
+			s.ids = append(s.ids[:i], s.ids[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (s *orderedSet) len() int {
+	return len(s.ids)
+}
+
+func (s *orderedSet) clone() *orderedSet {
+	return &orderedSet{
+		ids:     slices.Clone(s.ids),
+		members: maps.Clone(s.members),
+	}
+}
@@ -0,0 +1,108 @@
+package memorystorage
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync/atomic"
+
+	"quotes-service/internal/storage"
+)
+
+// WithinTx implements storage.Transactor. It holds the write lock for the
+// whole transaction and runs fn against a copy of the store, which replaces
+// the store's state only if fn succeeds. Other callers wait until the
+// transaction is over, so fn should not do slow work of its own.
+func (s *Storage) WithinTx(ctx context.Context, fn func(tx storage.QuoteStore) error) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := s.clone()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	s.commit(tx)
+	return nil
+}
+
+// clone copies the state of s deeply enough that no write to the copy is
+// visible in s. The caller must hold s.mu.
+func (s *Storage) clone() *Storage {
+	c := &Storage{
+		quotes:     maps.Clone(s.quotes),
+		quotesList: slices.Clone(s.quotesList),
+		served:     make(map[int64]*atomic.Int64, len(s.served)),
+		cumWeights: slices.Clone(s.cumWeights),
+		// Token slices are replaced, never modified, so they can be shared.
+		tokens:     maps.Clone(s.tokens),
+		tokenIndex: cloneIndex(s.tokenIndex),
+		langIndex:  cloneIndex(s.langIndex),
+		publicIDs:  maps.Clone(s.publicIDs),
+		nextID:     s.nextID,
+
+		authorIndex:      cloneIndex(s.authorIndex),
+		fingerprintIndex: cloneIndex(s.fingerprintIndex),
+
+		collections:      make(map[int64]*collection, len(s.collections)),
+		quoteCollections: cloneIndex(s.quoteCollections),
+		nextCollectionID: s.nextCollectionID,
+
+		favorites:      make(map[string]*orderedSet, len(s.favorites)),
+		quoteFavorites: cloneIndex(s.quoteFavorites),
+
+		version:     s.version,
+		changes:     s.changes.clone(),
+		now:         s.now,
+		newPublicID: s.newPublicID,
+	}
+	for id, served := range s.served {
+		c.served[id] = new(atomic.Int64)
+		c.served[id].Store(served.Load())
+	}
+	for id, col := range s.collections {
+		copied := *col
+		copied.quotes = col.quotes.clone()
+		c.collections[id] = &copied
+	}
+	for principal, set := range s.favorites {
+		c.favorites[principal] = set.clone()
+	}
+	return c
+}
+
+// commit takes over the state of tx, a clone of s. The caller must hold
+// s.mu.
+func (s *Storage) commit(tx *Storage) {
+	s.quotes = tx.quotes
+	s.quotesList = tx.quotesList
+	s.served = tx.served
+	s.cumWeights = tx.cumWeights
+	s.tokens = tx.tokens
+	s.tokenIndex = tx.tokenIndex
+	s.langIndex = tx.langIndex
+	s.authorIndex = tx.authorIndex
+	s.fingerprintIndex = tx.fingerprintIndex
+	s.publicIDs = tx.publicIDs
+	s.nextID = tx.nextID
+	s.collections = tx.collections
+	s.quoteCollections = tx.quoteCollections
+	s.nextCollectionID = tx.nextCollectionID
+	s.favorites = tx.favorites
+	s.quoteFavorites = tx.quoteFavorites
+	s.version = tx.version
+	s.changes = tx.changes
+}
+
+func cloneIndex[K, V comparable](index map[K]map[V]struct{}) map[K]map[V]struct{} {
+	c := make(map[K]map[V]struct{}, len(index))
+	for key, ids := range index {
+		c[key] = maps.Clone(ids)
+	}
+	return c
+}
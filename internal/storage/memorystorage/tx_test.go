@@ -0,0 +1,184 @@
+package memorystorage_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"quotes-service/internal/lib/publicid"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+)
+
+var _ storage.Transactor = (*memorystorage.Storage)(nil)
+
+// txState is everything a rolled back transaction must leave unchanged.
+type txState struct {
+	quotes      []models.Quote
+	version     uint64
+	collection  models.CollectionWithQuotes
+	favorites   []models.Quote
+	publicQuote models.Quote
+}
+
+func captureTxState(t *testing.T, store *memorystorage.Storage, publicID string) txState {
+	t.Helper()
+	ctx := context.Background()
+	var st txState
+	var err error
+	if st.quotes, err = store.GetAllQuotes(ctx, storage.QuoteFilter{}); err != nil {
+		t.Fatalf("failed to list quotes: %v", err)
+	}
+	if st.version, err = store.Version(ctx); err != nil {
+		t.Fatalf("failed to get version: %v", err)
+	}
+	if st.collection, err = store.GetCollection(ctx, 1); err != nil {
+		t.Fatalf("failed to get collection: %v", err)
+	}
+	if st.favorites, _, err = store.GetFavorites(ctx, "alice", 10, 0); err != nil {
+		t.Fatalf("failed to get favorites: %v", err)
+	}
+	if st.publicQuote, err = store.GetQuoteByPublicID(ctx, publicID); err != nil {
+		t.Fatalf("failed to get quote by public id: %v", err)
+	}
+	return st
+}
+
+func TestWithinTx(t *testing.T) {
+	errAbort := errors.New("abort")
+	tests := []struct {
+		name     string
+		fn       func(ctx context.Context, tx storage.QuoteStore) error
+		wantErr  error
+		panics   bool
+		rollback bool
+	}{
+		{
+			name: "commit",
+			fn: func(ctx context.Context, tx storage.QuoteStore) error {
+				if _, err := tx.AddQuote(ctx, models.Quote{Text: "third", Author: "C"}); err != nil {
+					return err
+				}
+				return tx.DeleteQuote(ctx, 1, storage.AnyVersion)
+			},
+		},
+		{
+			name: "error rolls back every write",
+			fn: func(ctx context.Context, tx storage.QuoteStore) error {
+				if _, err := tx.AddQuote(ctx, models.Quote{Text: "third", Author: "C"}); err != nil {
+					return err
+				}
+				text := "changed"
+				if _, err := tx.UpdateQuote(ctx, 2, storage.QuoteUpdate{Text: &text}, storage.AnyVersion); err != nil {
+					return err
+				}
+				if err := tx.DeleteQuote(ctx, 1, storage.AnyVersion); err != nil {
+					return err
+				}
+				return errAbort
+			},
+			wantErr:  errAbort,
+			rollback: true,
+		},
+		{
+			name: "failed write rolls back earlier ones",
+			fn: func(ctx context.Context, tx storage.QuoteStore) error {
+				if err := tx.DeleteQuote(ctx, 1, storage.AnyVersion); err != nil {
+					return err
+				}
+				return tx.DeleteQuote(ctx, 1, storage.AnyVersion)
+			},
+			wantErr:  storage.ErrQuoteNotFound,
+			rollback: true,
+		},
+		{
+			name: "panic rolls back",
+			fn: func(ctx context.Context, tx storage.QuoteStore) error {
+				if err := tx.DeleteQuote(ctx, 1, storage.AnyVersion); err != nil {
+					return err
+				}
+				panic("boom")
+			},
+			panics:   true,
+			rollback: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store, err := memorystorage.New(memorystorage.WithPublicIDs(publicid.NewUUID))
+			if err != nil {
+				t.Fatalf("failed to init storage: %v", err)
+			}
+			for _, text := range []string{"first", "second"} {
+				if _, err := store.AddQuote(ctx, models.Quote{Text: text, Author: "A"}); err != nil {
+					t.Fatalf("failed to add quote: %v", err)
+				}
+			}
+			if _, err := store.CreateCollection(ctx, "c", ""); err != nil {
+				t.Fatalf("failed to create collection: %v", err)
+			}
+			if err := store.AddQuotesToCollection(ctx, 1, []int64{1, 2}); err != nil {
+				t.Fatalf("failed to fill collection: %v", err)
+			}
+			if err := store.AddFavorite(ctx, "alice", 1); err != nil {
+				t.Fatalf("failed to add favorite: %v", err)
+			}
+			first, _ := store.GetQuote(ctx, 1)
+			before := captureTxState(t, store, first.PublicID)
+
+			err = func() (err error) {
+				defer func() {
+					if rvr := recover(); rvr != nil {
+						if !tt.panics {
+							panic(rvr)
+						}
+						err = errAbort
+					}
+				}()
+				return store.WithinTx(ctx, func(tx storage.QuoteStore) error {
+					return tt.fn(ctx, tx)
+				})
+			}()
+			switch {
+			case tt.panics:
+				if !errors.Is(err, errAbort) {
+					t.Fatalf("expected the panic to reach the caller, got %v", err)
+				}
+			case !errors.Is(err, tt.wantErr):
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if tt.rollback {
+				if after := captureTxState(t, store, first.PublicID); !reflect.DeepEqual(after, before) {
+					t.Fatalf("rollback left partial writes:\n got %+v\nwant %+v", after, before)
+				}
+				// Nothing the transaction did, IDs included, may leak.
+				id, err := store.AddQuote(ctx, models.Quote{Text: "next", Author: "A"})
+				if err != nil || id != 3 {
+					t.Fatalf("expected the next quote to get id 3, got %d, %v", id, err)
+				}
+				return
+			}
+
+			quotes, _ := store.GetAllQuotes(ctx, storage.QuoteFilter{})
+			if len(quotes) != 2 || quotes[0].ID != 2 || quotes[1].Text != "third" {
+				t.Fatalf("expected the transaction's writes, got %+v", quotes)
+			}
+			if version, _ := store.Version(ctx); version == before.version {
+				t.Fatal("expected the version to change on commit")
+			}
+			if col, _ := store.GetCollection(ctx, 1); len(col.Quotes) != 1 {
+				t.Fatalf("expected the deleted quote to leave the collection, got %+v", col.Quotes)
+			}
+			if _, err := store.GetQuoteByPublicID(ctx, first.PublicID); !errors.Is(err, storage.ErrQuoteNotFound) {
+				t.Fatalf("expected the deleted quote's public id to be gone, got %v", err)
+			}
+			if err := store.IncrementServed(ctx, quotes[1].ID); err != nil {
+				t.Fatalf("expected served counts for quotes added in the transaction: %v", err)
+			}
+		})
+	}
+}
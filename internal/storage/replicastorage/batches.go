@@ -0,0 +1,58 @@
+package replicastorage
+
+import (
+	"context"
+
+	"quotes-service/internal/storage"
+)
+
+// DeleteAuthorBatch deletes a batch on the primary and mirrors each of its
+// deletes, as DeleteQuote does. It fails with
+// storage.ErrBatchesUnsupported if the primary is not a storage.Batcher.
+func (s *Storage) DeleteAuthorBatch(ctx context.Context, author string, batch storage.Batch) (storage.BatchResult, error) {
+	batcher, ok := s.primary.(storage.Batcher)
+	if !ok {
+		return storage.BatchResult{}, storage.ErrBatchesUnsupported
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	result, err := batcher.DeleteAuthorBatch(ctx, author, batch)
+	if err != nil {
+		return storage.BatchResult{}, err
+	}
+	for _, id := range result.IDs {
+		s.enqueue(deleteQuote(id))
+	}
+	return result, nil
+}
+
+// MergeAuthorsBatch merges a batch on the primary and mirrors the quotes
+// it renamed.
+func (s *Storage) MergeAuthorsBatch(ctx context.Context, into string, from []string, batch storage.Batch) (storage.BatchResult, error) {
+	batcher, ok := s.primary.(storage.Batcher)
+	if !ok {
+		return storage.BatchResult{}, storage.ErrBatchesUnsupported
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	result, err := batcher.MergeAuthorsBatch(ctx, into, from, batch)
+	if err != nil {
+		return storage.BatchResult{}, err
+	}
+	if len(result.IDs) > 0 {
+		s.mirrorQuotes(ctx, "MergeAuthorsBatch", result.IDs...)
+	}
+	return result, nil
+}
+
+// ReindexBatch reindexes the primary only: the quotes do not change, and
+// the secondary keeps indexes of its own.
+func (s *Storage) ReindexBatch(ctx context.Context, batch storage.Batch) (storage.BatchResult, error) {
+	batcher, ok := s.primary.(storage.Batcher)
+	if !ok {
+		return storage.BatchResult{}, storage.ErrBatchesUnsupported
+	}
+	return batcher.ReindexBatch(ctx, batch)
+}
@@ -0,0 +1,21 @@
+package replicastorage
+
+import (
+	"context"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// ChangesSince is always served by the primary, even when reads come from
+// the secondary: the secondary numbers the changes it mirrors on its own,
+// so its sequence numbers mean nothing to clients synced with the primary.
+// It fails with storage.ErrChangeLogUnsupported if the primary is not a
+// storage.ChangeLog.
+func (s *Storage) ChangesSince(ctx context.Context, since uint64, limit int) ([]models.QuoteChange, uint64, error) {
+	log, ok := s.primary.(storage.ChangeLog)
+	if !ok {
+		return nil, 0, storage.ErrChangeLogUnsupported
+	}
+	return log.ChangesSince(ctx, since, limit)
+}
@@ -0,0 +1,38 @@
+package replicastorage
+
+import (
+	"context"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// ClaimQuote is always served by the primary and never mirrored: claims
+// are leases, not data, and only one store may hand them out for them to
+// be exclusive. It fails with storage.ErrClaimsUnsupported if the primary
+// is not a storage.Claimer.
+func (s *Storage) ClaimQuote(ctx context.Context, claimant string, opts storage.ClaimOptions) (models.QuoteClaim, error) {
+	claimer, ok := s.primary.(storage.Claimer)
+	if !ok {
+		return models.QuoteClaim{}, storage.ErrClaimsUnsupported
+	}
+	return claimer.ClaimQuote(ctx, claimant, opts)
+}
+
+// ReleaseQuote is served by the primary, like ClaimQuote.
+func (s *Storage) ReleaseQuote(ctx context.Context, id int64, claimant string) error {
+	claimer, ok := s.primary.(storage.Claimer)
+	if !ok {
+		return storage.ErrClaimsUnsupported
+	}
+	return claimer.ReleaseQuote(ctx, id, claimant)
+}
+
+// ExpireClaims is served by the primary, like ClaimQuote.
+func (s *Storage) ExpireClaims(ctx context.Context) (int, error) {
+	claimer, ok := s.primary.(storage.Claimer)
+	if !ok {
+		return 0, storage.ErrClaimsUnsupported
+	}
+	return claimer.ExpireClaims(ctx)
+}
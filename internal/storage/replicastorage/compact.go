@@ -0,0 +1,33 @@
+package replicastorage
+
+import (
+	"context"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// Compact compacts the primary only. The secondary is a store of its own,
+// compacted, if at all, by whoever runs it.
+func (s *Storage) Compact(ctx context.Context) error {
+	compactor, ok := s.primary.(storage.Compactor)
+	if !ok {
+		return storage.ErrCompactionUnsupported
+	}
+	return compactor.Compact(ctx)
+}
+
+// MemoryReport reports on the primary, like Compact. The backend named is
+// the wrapper's, so the report says the store is replicated.
+func (s *Storage) MemoryReport(ctx context.Context) (models.StorageReport, error) {
+	compactor, ok := s.primary.(storage.Compactor)
+	if !ok {
+		return models.StorageReport{}, storage.ErrCompactionUnsupported
+	}
+	report, err := compactor.MemoryReport(ctx)
+	if err != nil {
+		return models.StorageReport{}, err
+	}
+	report.Backend = s.Backend()
+	return report, nil
+}
@@ -0,0 +1,676 @@
+// Package replicastorage wraps two quote stores for a migration between
+// backends: every call is served by the primary, and mutations are then
+// mirrored to the secondary in the background. Mirroring never fails or
+// slows down the caller; mutations the secondary may have missed are
+// counted as divergences instead, and a backfill copies every quote over
+// again.
+package replicastorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// Where reads are served from.
+const (
+	ReadsPrimary   = "primary"
+	ReadsSecondary = "secondary"
+)
+
+const (
+	// mirrorTimeout bounds one mirrored call, so a stuck secondary cannot
+	// hold up the queue forever.
+	mirrorTimeout = 10 * time.Second
+	// drainTimeout bounds how long Run keeps mirroring queued mutations
+	// after it is asked to stop.
+	drainTimeout = 10 * time.Second
+	// backfillRetryDelay is how long a backfill waits for room in a full
+	// queue before trying again.
+	backfillRetryDelay = 100 * time.Millisecond
+
+	defaultQueueSize = 10000
+	defaultBatchSize = 500
+)
+
+// Divergence reasons, as labeled in the metrics.
+const (
+	reasonFailed   = "failed"
+	reasonDropped  = "dropped"
+	reasonMismatch = "mismatch"
+)
+
+// errMismatch marks a mirrored call that succeeded on the secondary with a
+// different result than on the primary.
+var errMismatch = errors.New("secondary result differs from primary")
+
+// Store is the set of methods the decorator forwards.
+type Store interface {
+	AddQuote(ctx context.Context, quote models.Quote) (int64, error)
+	GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error)
+	GetQuote(ctx context.Context, id int64) (models.Quote, error)
+	GetQuoteByPublicID(ctx context.Context, publicID string) (models.Quote, error)
+	GetRandomQuote(ctx context.Context, opts storage.RandomOptions) (models.Quote, error)
+	GetQuotesByAuthor(ctx context.Context, authorFilter string, filter storage.QuoteFilter) ([]models.Quote, error)
+	UpdateQuote(ctx context.Context, id int64, update storage.QuoteUpdate, ifVersion int64) (models.Quote, error)
+	DeleteQuote(ctx context.Context, id int64, ifVersion int64) error
+	MergeAuthors(ctx context.Context, into string, from []string) (map[string]int, error)
+	GetAuthors(ctx context.Context, query storage.AuthorQuery) ([]models.AuthorSummary, int, error)
+	IncrementServed(ctx context.Context, id int64) error
+	GetPopularQuotes(ctx context.Context, limit int) ([]models.PopularQuote, error)
+	GetSimilarQuotes(ctx context.Context, id int64, limit int) ([]models.SimilarQuote, error)
+	Version(ctx context.Context) (uint64, error)
+
+	CreateCollection(ctx context.Context, name string, description string) (models.Collection, error)
+	GetCollections(ctx context.Context) ([]models.Collection, error)
+	GetCollection(ctx context.Context, id int64) (models.CollectionWithQuotes, error)
+	AddQuotesToCollection(ctx context.Context, id int64, quoteIDs []int64) error
+	RemoveQuoteFromCollection(ctx context.Context, id int64, quoteID int64) error
+	DeleteCollection(ctx context.Context, id int64) error
+	GetRandomCollectionQuote(ctx context.Context, id int64) (models.Quote, error)
+
+	AddFavorite(ctx context.Context, principal string, quoteID int64) error
+	RemoveFavorite(ctx context.Context, principal string, quoteID int64) error
+	GetFavorites(ctx context.Context, principal string, limit, offset int) ([]models.Quote, int, error)
+}
+
+// Secondary is the store mutations are mirrored to. Quotes are mirrored
+// by their resulting state through PutQuotes, so they keep the IDs,
+// timestamps and versions the primary gave them; collections and
+// favorites are mirrored by repeating the call.
+type Secondary interface {
+	Store
+	PutQuotes(ctx context.Context, quotes []models.Quote) error
+}
+
+// Options configures a Storage. Zero sizes pick the defaults.
+type Options struct {
+	// QueueSize bounds the mutations waiting to be mirrored. Mutations
+	// made while it is full are not mirrored and count as divergences.
+	QueueSize int
+	// BatchSize is how many quotes a backfill copies per call.
+	BatchSize int
+	// ReadFromSecondary serves reads from the secondary, to verify it
+	// before switching over. Writes always go to the primary first.
+	ReadFromSecondary bool
+}
+
+// mirror is one mutation waiting to be applied to the secondary.
+type mirror struct {
+	method string
+	apply  func(ctx context.Context, secondary Secondary) error
+}
+
+type Storage struct {
+	log       *slog.Logger
+	primary   Store
+	secondary Secondary
+	reads     Store
+	opts      Options
+	now       func() time.Time
+
+	queue   chan mirror
+	trigger chan struct{}
+	// writeMu orders each mutation of the primary with the mirror it
+	// queues, so the secondary applies them in the same order and a
+	// backfill cannot overwrite a newer mirror with an older state.
+	writeMu sync.Mutex
+
+	mirrored    atomic.Int64
+	divergences atomic.Int64
+
+	mu           sync.Mutex
+	lastErr      string
+	lastErrAt    time.Time
+	backfilling  bool
+	lastBackfill *models.ReplicationBackfill
+
+	queueDepth       prometheus.GaugeFunc
+	mirroredTotal    prometheus.CounterFunc
+	divergencesTotal *prometheus.CounterVec
+}
+
+type Option func(*Storage)
+
+// WithClock overrides the time source, mainly for tests.
+func WithClock(now func() time.Time) Option {
+	return func(s *Storage) {
+		s.now = now
+	}
+}
+
+// New wraps primary and secondary. Nothing is mirrored until Run is
+// called.
+func New(log *slog.Logger, primary Store, secondary Secondary, opts Options, options ...Option) *Storage {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultQueueSize
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	s := &Storage{
+		log:       log.With(slog.String("op", "replicastorage.Storage")),
+		primary:   primary,
+		secondary: secondary,
+		reads:     primary,
+		opts:      opts,
+		now:       time.Now,
+		queue:     make(chan mirror, opts.QueueSize),
+		trigger:   make(chan struct{}, 1),
+	}
+	if opts.ReadFromSecondary {
+		s.reads = secondary
+	}
+	s.queueDepth = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "replication_queue_depth",
+		Help: "Mutations waiting to be mirrored to the secondary store.",
+	}, func() float64 { return float64(len(s.queue)) })
+	s.mirroredTotal = prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name: "replication_mirrored_total",
+		Help: "Mutations mirrored to the secondary store.",
+	}, func() float64 { return float64(s.mirrored.Load()) })
+	s.divergencesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "replication_divergences_total",
+		Help: "Mutations the secondary store may have missed, by reason.",
+	}, []string{"reason"})
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+// Describe implements prometheus.Collector.
+func (s *Storage) Describe(ch chan<- *prometheus.Desc) {
+	s.queueDepth.Describe(ch)
+	s.mirroredTotal.Describe(ch)
+	s.divergencesTotal.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (s *Storage) Collect(ch chan<- prometheus.Metric) {
+	s.queueDepth.Collect(ch)
+	s.mirroredTotal.Collect(ch)
+	s.divergencesTotal.Collect(ch)
+}
+
+// Run mirrors queued mutations and runs backfills until ctx is done. What
+// is still queued then is mirrored for up to drainTimeout.
+func (s *Storage) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.backfillLoop(ctx)
+	}()
+	defer wg.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.drain()
+			return
+		case m := <-s.queue:
+			s.apply(context.Background(), m)
+		}
+	}
+}
+
+func (s *Storage) drain() {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	for {
+		select {
+		case m := <-s.queue:
+			s.apply(ctx, m)
+		default:
+			return
+		}
+	}
+}
+
+// apply mirrors one mutation. A panic in the secondary is recorded like
+// any other failure.
+func (s *Storage) apply(ctx context.Context, m mirror) {
+	ctx, cancel := context.WithTimeout(ctx, mirrorTimeout)
+	defer cancel()
+
+	err := func() (err error) {
+		defer func() {
+			if rvr := recover(); rvr != nil {
+				err = fmt.Errorf("panic: %v", rvr)
+			}
+		}()
+		return m.apply(ctx, s.secondary)
+	}()
+	switch {
+	case err == nil:
+		s.mirrored.Add(1)
+	case errors.Is(err, errMismatch):
+		s.diverge(reasonMismatch, fmt.Errorf("%s: %w", m.method, err))
+	default:
+		s.diverge(reasonFailed, fmt.Errorf("%s: %w", m.method, err))
+	}
+}
+
+func (s *Storage) diverge(reason string, err error) {
+	s.divergences.Add(1)
+	s.divergencesTotal.WithLabelValues(reason).Inc()
+
+	s.mu.Lock()
+	s.lastErr = err.Error()
+	s.lastErrAt = s.now().UTC()
+	s.mu.Unlock()
+
+	s.log.Warn("secondary store diverged", slog.String("reason", reason), slog.String("error", err.Error()))
+}
+
+// enqueue queues m without ever blocking the caller.
+func (s *Storage) enqueue(m mirror) {
+	if !s.tryEnqueue(m) {
+		s.diverge(reasonDropped, fmt.Errorf("%s: queue is full", m.method))
+	}
+}
+
+func (s *Storage) tryEnqueue(m mirror) bool {
+	select {
+	case s.queue <- m:
+		return true
+	default:
+		return false
+	}
+}
+
+func putQuotes(method string, quotes []models.Quote) mirror {
+	return mirror{method: method, apply: func(ctx context.Context, secondary Secondary) error {
+		return secondary.PutQuotes(ctx, quotes)
+	}}
+}
+
+// mirrorQuotes queues the current state of the quotes with ids. It reads
+// them from the primary after the caller's mutation, under writeMu.
+func (s *Storage) mirrorQuotes(ctx context.Context, method string, ids ...int64) {
+	ctx = context.WithoutCancel(ctx)
+	quotes := make([]models.Quote, 0, len(ids))
+	for _, id := range ids {
+		q, err := s.primary.GetQuote(ctx, id)
+		if err != nil {
+			s.diverge(reasonFailed, fmt.Errorf("%s: read quote %d from primary: %w", method, id, err))
+			return
+		}
+		quotes = append(quotes, q)
+	}
+	s.enqueue(putQuotes(method, quotes))
+}
+
+// Status reports the queue, the divergence count and the last backfill.
+func (s *Storage) Status() models.ReplicationStatus {
+	status := models.ReplicationStatus{
+		ReadsFrom:     ReadsPrimary,
+		QueueDepth:    len(s.queue),
+		QueueCapacity: cap(s.queue),
+		Mirrored:      s.mirrored.Load(),
+		Divergences:   s.divergences.Load(),
+	}
+	if s.opts.ReadFromSecondary {
+		status.ReadsFrom = ReadsSecondary
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status.LastError = s.lastErr
+	if !s.lastErrAt.IsZero() {
+		at := s.lastErrAt
+		status.LastErrorAt = &at
+	}
+	status.Backfilling = s.backfilling
+	if s.lastBackfill != nil {
+		last := *s.lastBackfill
+		status.LastBackfill = &last
+	}
+	return status
+}
+
+// Backfill asks Run to copy every quote of the primary to the secondary as
+// soon as the current backfill, if any, is done. Requests made while one
+// is already pending are merged into it.
+func (s *Storage) Backfill() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Storage) backfillLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.trigger:
+			s.backfill(ctx)
+		}
+	}
+}
+
+// backfill queues every quote of the primary in batches, waiting for room
+// in the queue rather than dropping them.
+func (s *Storage) backfill(ctx context.Context) (report models.ReplicationBackfill) {
+	report.StartedAt = s.now().UTC()
+	s.mu.Lock()
+	s.backfilling = true
+	s.mu.Unlock()
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			report.Error = fmt.Sprintf("panic: %v", rvr)
+		}
+		report.FinishedAt = s.now().UTC()
+
+		s.mu.Lock()
+		s.backfilling = false
+		s.lastBackfill = &report
+		s.mu.Unlock()
+
+		attrs := []slog.Attr{
+			slog.Int("quotes", report.Quotes),
+			slog.Int("copied", report.Copied),
+			slog.Int("batches", report.Batches),
+		}
+		if report.Error != "" {
+			attrs = append(attrs, slog.String("error", report.Error))
+			s.log.LogAttrs(ctx, slog.LevelWarn, "backfill failed", attrs...)
+			return
+		}
+		s.log.LogAttrs(ctx, slog.LevelInfo, "backfill finished", attrs...)
+	}()
+
+	quotes, err := s.primary.GetAllQuotes(ctx, storage.QuoteFilter{})
+	if err != nil {
+		report.Error = fmt.Sprintf("list quotes: %v", err)
+		return report
+	}
+	report.Quotes = len(quotes)
+	for start := 0; start < len(quotes); start += s.opts.BatchSize {
+		batch := quotes[start:min(start+s.opts.BatchSize, len(quotes))]
+		ids := make([]int64, len(batch))
+		for i, q := range batch {
+			ids[i] = q.ID
+		}
+		copied, err := s.queueBatch(ctx, ids)
+		if err != nil {
+			report.Error = fmt.Sprintf("batch %d: %v", report.Batches+1, err)
+			return report
+		}
+		report.Copied += copied
+		report.Batches++
+	}
+	return report
+}
+
+// queueBatch reads the quotes with ids from the primary and queues them
+// together, under writeMu so no mutation slips in between. Quotes deleted
+// since the listing are skipped.
+func (s *Storage) queueBatch(ctx context.Context, ids []int64) (int, error) {
+	for {
+		s.writeMu.Lock()
+		quotes := make([]models.Quote, 0, len(ids))
+		for _, id := range ids {
+			q, err := s.primary.GetQuote(ctx, id)
+			if errors.Is(err, storage.ErrQuoteNotFound) {
+				continue
+			}
+			if err != nil {
+				s.writeMu.Unlock()
+				return 0, err
+			}
+			quotes = append(quotes, q)
+		}
+		queued := len(quotes) == 0 || s.tryEnqueue(putQuotes("Backfill", quotes))
+		s.writeMu.Unlock()
+		if queued {
+			return len(quotes), nil
+		}
+
+		timer := time.NewTimer(backfillRetryDelay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (s *Storage) AddQuote(ctx context.Context, quote models.Quote) (int64, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	id, err := s.primary.AddQuote(ctx, quote)
+	if err != nil {
+		return 0, err
+	}
+	s.mirrorQuotes(ctx, "AddQuote", id)
+	return id, nil
+}
+
+func (s *Storage) GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error) {
+	return s.reads.GetAllQuotes(ctx, filter)
+}
+
+func (s *Storage) GetQuote(ctx context.Context, id int64) (models.Quote, error) {
+	return s.reads.GetQuote(ctx, id)
+}
+
+func (s *Storage) GetQuoteByPublicID(ctx context.Context, publicID string) (models.Quote, error) {
+	return s.reads.GetQuoteByPublicID(ctx, publicID)
+}
+
+func (s *Storage) GetRandomQuote(ctx context.Context, opts storage.RandomOptions) (models.Quote, error) {
+	return s.reads.GetRandomQuote(ctx, opts)
+}
+
+func (s *Storage) GetQuotesByAuthor(ctx context.Context, authorFilter string, filter storage.QuoteFilter) ([]models.Quote, error) {
+	return s.reads.GetQuotesByAuthor(ctx, authorFilter, filter)
+}
+
+// GetQuotesByFingerprint is served from where reads are, when that store
+// is a storage.FingerprintIndex, and fails with
+// storage.ErrFingerprintsUnsupported otherwise.
+func (s *Storage) GetQuotesByFingerprint(ctx context.Context, fp string) ([]models.Quote, error) {
+	index, ok := s.reads.(storage.FingerprintIndex)
+	if !ok {
+		return nil, storage.ErrFingerprintsUnsupported
+	}
+	return index.GetQuotesByFingerprint(ctx, fp)
+}
+
+func (s *Storage) UpdateQuote(ctx context.Context, id int64, update storage.QuoteUpdate, ifVersion int64) (models.Quote, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	quote, err := s.primary.UpdateQuote(ctx, id, update, ifVersion)
+	if err != nil {
+		return models.Quote{}, err
+	}
+	s.enqueue(putQuotes("UpdateQuote", []models.Quote{quote}))
+	return quote, nil
+}
+
+func (s *Storage) DeleteQuote(ctx context.Context, id int64, ifVersion int64) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if err := s.primary.DeleteQuote(ctx, id, ifVersion); err != nil {
+		return err
+	}
+	s.enqueue(deleteQuote(id))
+	return nil
+}
+
+func (s *Storage) MergeAuthors(ctx context.Context, into string, from []string) (map[string]int, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	counts, err := s.primary.MergeAuthors(ctx, into, from)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := s.primary.GetQuotesByAuthor(context.WithoutCancel(ctx), into, storage.QuoteFilter{})
+	if err != nil {
+		s.diverge(reasonFailed, fmt.Errorf("MergeAuthors: read quotes by %q from primary: %w", into, err))
+		return counts, nil
+	}
+	if len(merged) > 0 {
+		s.enqueue(putQuotes("MergeAuthors", merged))
+	}
+	return counts, nil
+}
+
+func (s *Storage) GetAuthors(ctx context.Context, query storage.AuthorQuery) ([]models.AuthorSummary, int, error) {
+	return s.reads.GetAuthors(ctx, query)
+}
+
+// IncrementServed is mirrored without writeMu: served counts are not part
+// of the quotes a backfill copies, and random reads should not queue up
+// behind writes.
+func (s *Storage) IncrementServed(ctx context.Context, id int64) error {
+	if err := s.primary.IncrementServed(ctx, id); err != nil {
+		return err
+	}
+	s.enqueue(mirror{method: "IncrementServed", apply: func(ctx context.Context, secondary Secondary) error {
+		return secondary.IncrementServed(ctx, id)
+	}})
+	return nil
+}
+
+func (s *Storage) GetPopularQuotes(ctx context.Context, limit int) ([]models.PopularQuote, error) {
+	return s.reads.GetPopularQuotes(ctx, limit)
+}
+
+func (s *Storage) GetSimilarQuotes(ctx context.Context, id int64, limit int) ([]models.SimilarQuote, error) {
+	return s.reads.GetSimilarQuotes(ctx, id, limit)
+}
+
+func (s *Storage) Version(ctx context.Context) (uint64, error) {
+	return s.reads.Version(ctx)
+}
+
+// Backend implements storage.Describer with the primary's backend, marked
+// as replicated, and where reads are served from when that is the
+// secondary.
+func (s *Storage) Backend() string {
+	backend := storage.Backend(s.primary) + "+replica"
+	if s.opts.ReadFromSecondary {
+		backend += ";reads=secondary"
+	}
+	return backend
+}
+
+func (s *Storage) CreateCollection(ctx context.Context, name string, description string) (models.Collection, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	collection, err := s.primary.CreateCollection(ctx, name, description)
+	if err != nil {
+		return models.Collection{}, err
+	}
+	s.enqueue(mirror{method: "CreateCollection", apply: func(ctx context.Context, secondary Secondary) error {
+		created, err := secondary.CreateCollection(ctx, name, description)
+		if err != nil {
+			return err
+		}
+		if created.ID != collection.ID {
+			return fmt.Errorf("%w: collection got id %d, primary gave %d", errMismatch, created.ID, collection.ID)
+		}
+		return nil
+	}})
+	return collection, nil
+}
+
+func (s *Storage) GetCollections(ctx context.Context) ([]models.Collection, error) {
+	return s.reads.GetCollections(ctx)
+}
+
+func (s *Storage) GetCollection(ctx context.Context, id int64) (models.CollectionWithQuotes, error) {
+	return s.reads.GetCollection(ctx, id)
+}
+
+func (s *Storage) AddQuotesToCollection(ctx context.Context, id int64, quoteIDs []int64) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if err := s.primary.AddQuotesToCollection(ctx, id, quoteIDs); err != nil {
+		return err
+	}
+	s.enqueue(mirror{method: "AddQuotesToCollection", apply: func(ctx context.Context, secondary Secondary) error {
+		return secondary.AddQuotesToCollection(ctx, id, quoteIDs)
+	}})
+	return nil
+}
+
+func (s *Storage) RemoveQuoteFromCollection(ctx context.Context, id int64, quoteID int64) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if err := s.primary.RemoveQuoteFromCollection(ctx, id, quoteID); err != nil {
+		return err
+	}
+	s.enqueue(mirror{method: "RemoveQuoteFromCollection", apply: func(ctx context.Context, secondary Secondary) error {
+		return secondary.RemoveQuoteFromCollection(ctx, id, quoteID)
+	}})
+	return nil
+}
+
+func (s *Storage) DeleteCollection(ctx context.Context, id int64) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if err := s.primary.DeleteCollection(ctx, id); err != nil {
+		return err
+	}
+	s.enqueue(mirror{method: "DeleteCollection", apply: func(ctx context.Context, secondary Secondary) error {
+		return secondary.DeleteCollection(ctx, id)
+	}})
+	return nil
+}
+
+func (s *Storage) GetRandomCollectionQuote(ctx context.Context, id int64) (models.Quote, error) {
+	return s.reads.GetRandomCollectionQuote(ctx, id)
+}
+
+func (s *Storage) AddFavorite(ctx context.Context, principal string, quoteID int64) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if err := s.primary.AddFavorite(ctx, principal, quoteID); err != nil {
+		return err
+	}
+	s.enqueue(mirror{method: "AddFavorite", apply: func(ctx context.Context, secondary Secondary) error {
+		return secondary.AddFavorite(ctx, principal, quoteID)
+	}})
+	return nil
+}
+
+func (s *Storage) RemoveFavorite(ctx context.Context, principal string, quoteID int64) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if err := s.primary.RemoveFavorite(ctx, principal, quoteID); err != nil {
+		return err
+	}
+	s.enqueue(mirror{method: "RemoveFavorite", apply: func(ctx context.Context, secondary Secondary) error {
+		return secondary.RemoveFavorite(ctx, principal, quoteID)
+	}})
+	return nil
+}
+
+func (s *Storage) GetFavorites(ctx context.Context, principal string, limit, offset int) ([]models.Quote, int, error) {
+	return s.reads.GetFavorites(ctx, principal, limit, offset)
+}
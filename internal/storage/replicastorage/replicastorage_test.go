@@ -0,0 +1,294 @@
+package replicastorage_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+	"quotes-service/internal/storage/replicastorage"
+	"quotes-service/internal/storage/storagetest"
+)
+
+// failingSecondary rejects every mirrored quote.
+type failingSecondary struct {
+	*memorystorage.Storage
+}
+
+func (failingSecondary) PutQuotes(ctx context.Context, quotes []models.Quote) error {
+	return errors.New("connection refused")
+}
+
+func newStore(t *testing.T) *memorystorage.Storage {
+	t.Helper()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	return store
+}
+
+// start runs the replica until the test ends.
+func start(t *testing.T, replica *replicastorage.Storage) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		replica.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// waitFor polls the replica status until ok holds.
+func waitFor(t *testing.T, replica *replicastorage.Storage, ok func(models.ReplicationStatus) bool) models.ReplicationStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status := replica.Status()
+		if ok(status) {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the replica, status %+v", status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func allQuotes(t *testing.T, store interface {
+	GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error)
+}) []models.Quote {
+	t.Helper()
+	quotes, err := store.GetAllQuotes(context.Background(), storage.QuoteFilter{})
+	if err != nil {
+		t.Fatalf("failed to get quotes: %v", err)
+	}
+	return quotes
+}
+
+func TestMirror(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := newStore(t), newStore(t)
+	replica := replicastorage.New(slog.New(slog.DiscardHandler), primary, secondary, replicastorage.Options{})
+	start(t, replica)
+
+	var ids []int64
+	for _, q := range []models.Quote{
+		{Text: "one", Author: "A"},
+		{Text: "two", Author: "Alan"},
+		{Text: "three", Author: "B"},
+	} {
+		id, err := replica.AddQuote(ctx, q)
+		if err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	text := "uno"
+	if _, err := replica.UpdateQuote(ctx, ids[0], storage.QuoteUpdate{Text: &text}, storage.AnyVersion); err != nil {
+		t.Fatalf("failed to update quote: %v", err)
+	}
+	if err := replica.DeleteQuote(ctx, ids[2], storage.AnyVersion); err != nil {
+		t.Fatalf("failed to delete quote: %v", err)
+	}
+	if _, err := replica.MergeAuthors(ctx, "A", []string{"Alan"}); err != nil {
+		t.Fatalf("failed to merge authors: %v", err)
+	}
+	collection, err := replica.CreateCollection(ctx, "favorites", "")
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+	if err := replica.AddQuotesToCollection(ctx, collection.ID, ids[:2]); err != nil {
+		t.Fatalf("failed to add quotes to collection: %v", err)
+	}
+	if err := replica.IncrementServed(ctx, ids[1]); err != nil {
+		t.Fatalf("failed to count a view: %v", err)
+	}
+
+	status := waitFor(t, replica, func(s models.ReplicationStatus) bool { return s.Mirrored == 9 })
+	if status.Divergences != 0 || status.QueueDepth != 0 || status.ReadsFrom != replicastorage.ReadsPrimary {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if want, got := allQuotes(t, primary), allQuotes(t, secondary); !reflect.DeepEqual(want, got) {
+		t.Fatalf("secondary differs from primary:\nprimary   %+v\nsecondary %+v", want, got)
+	}
+	got, err := secondary.GetCollection(ctx, collection.ID)
+	if err != nil || len(got.Quotes) != 2 {
+		t.Fatalf("expected the collection with 2 quotes on the secondary, got %+v, %v", got, err)
+	}
+	popular, _ := secondary.GetPopularQuotes(ctx, 1)
+	if len(popular) != 1 || popular[0].ID != ids[1] || popular[0].Served != 1 {
+		t.Fatalf("expected the view to be mirrored, got %+v", popular)
+	}
+}
+
+func TestSecondaryFailuresDoNotFailWrites(t *testing.T) {
+	ctx := context.Background()
+	primary := newStore(t)
+	replica := replicastorage.New(slog.New(slog.DiscardHandler), primary, failingSecondary{newStore(t)}, replicastorage.Options{})
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(replica)
+	start(t, replica)
+
+	if _, err := replica.AddQuote(ctx, models.Quote{Text: "one", Author: "A"}); err != nil {
+		t.Fatalf("expected the write to succeed, got %v", err)
+	}
+	status := waitFor(t, replica, func(s models.ReplicationStatus) bool { return s.Divergences == 1 })
+	if status.LastError != "AddQuote: connection refused" || status.LastErrorAt == nil {
+		t.Fatalf("expected the failure in the status, got %+v", status)
+	}
+	if len(allQuotes(t, primary)) != 1 {
+		t.Fatal("expected the quote on the primary")
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	var failed float64
+	for _, family := range families {
+		if family.GetName() != "replication_divergences_total" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			if m.GetLabel()[0].GetValue() == "failed" {
+				failed = m.GetCounter().GetValue()
+			}
+		}
+	}
+	if failed != 1 {
+		t.Fatalf("expected 1 failed divergence in the metrics, got %v", failed)
+	}
+}
+
+func TestFullQueueDropsMirrors(t *testing.T) {
+	ctx := context.Background()
+	replica := replicastorage.New(slog.New(slog.DiscardHandler), newStore(t), newStore(t), replicastorage.Options{QueueSize: 1})
+
+	// Run is not started, so the queue stays full after the first write.
+	for range 3 {
+		if _, err := replica.AddQuote(ctx, models.Quote{Text: "text", Author: "A"}); err != nil {
+			t.Fatalf("expected the write to succeed, got %v", err)
+		}
+	}
+	status := replica.Status()
+	if status.QueueDepth != 1 || status.QueueCapacity != 1 || status.Divergences != 2 || status.LastError != "AddQuote: queue is full" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := newStore(t), newStore(t)
+	for range 5 {
+		if _, err := primary.AddQuote(ctx, models.Quote{Text: "text", Author: "A"}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+	// The queue holds a single batch, so the backfill has to wait for it.
+	replica := replicastorage.New(slog.New(slog.DiscardHandler), primary, secondary, replicastorage.Options{QueueSize: 1, BatchSize: 2})
+	start(t, replica)
+
+	replica.Backfill()
+	status := waitFor(t, replica, func(s models.ReplicationStatus) bool {
+		return s.LastBackfill != nil && s.QueueDepth == 0 && s.Mirrored == 3
+	})
+	if b := status.LastBackfill; b.Quotes != 5 || b.Copied != 5 || b.Batches != 3 || b.Error != "" {
+		t.Fatalf("unexpected backfill %+v", b)
+	}
+	if want, got := allQuotes(t, primary), allQuotes(t, secondary); !reflect.DeepEqual(want, got) {
+		t.Fatalf("secondary differs from primary:\nprimary   %+v\nsecondary %+v", want, got)
+	}
+}
+
+func TestReadFromSecondary(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := newStore(t), newStore(t)
+	if _, err := secondary.AddQuote(ctx, models.Quote{Text: "only on the secondary", Author: "A"}); err != nil {
+		t.Fatalf("failed to add quote: %v", err)
+	}
+	replica := replicastorage.New(slog.New(slog.DiscardHandler), primary, secondary, replicastorage.Options{ReadFromSecondary: true})
+
+	quotes := allQuotes(t, replica)
+	if len(quotes) != 1 || quotes[0].Text != "only on the secondary" {
+		t.Fatalf("expected reads from the secondary, got %+v", quotes)
+	}
+	if status := replica.Status(); status.ReadsFrom != replicastorage.ReadsSecondary {
+		t.Fatalf("expected reads_from secondary, got %q", status.ReadsFrom)
+	}
+}
+
+func TestWithinTx(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := newStore(t), newStore(t)
+	replica := replicastorage.New(slog.New(slog.DiscardHandler), primary, secondary, replicastorage.Options{})
+	start(t, replica)
+
+	for _, text := range []string{"one", "two"} {
+		if _, err := replica.AddQuote(ctx, models.Quote{Text: text, Author: "A"}); err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+	}
+	waitFor(t, replica, func(s models.ReplicationStatus) bool { return s.Mirrored == 2 })
+
+	errAbort := errors.New("abort")
+	err := replica.WithinTx(ctx, func(tx storage.QuoteStore) error {
+		if _, err := tx.AddQuote(ctx, models.Quote{Text: "lost", Author: "B"}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("expected the transaction's error, got %v", err)
+	}
+
+	err = replica.WithinTx(ctx, func(tx storage.QuoteStore) error {
+		text := "uno"
+		if _, err := tx.UpdateQuote(ctx, 1, storage.QuoteUpdate{Text: &text}, storage.AnyVersion); err != nil {
+			return err
+		}
+		if err := tx.DeleteQuote(ctx, 2, storage.AnyVersion); err != nil {
+			return err
+		}
+		if _, err := tx.AddQuote(ctx, models.Quote{Text: "three", Author: "B"}); err != nil {
+			return err
+		}
+		// Added and deleted within the transaction, so never mirrored.
+		id, err := tx.AddQuote(ctx, models.Quote{Text: "gone", Author: "B"})
+		if err != nil {
+			return err
+		}
+		return tx.DeleteQuote(ctx, id, storage.AnyVersion)
+	})
+	if err != nil {
+		t.Fatalf("failed to commit transaction: %v", err)
+	}
+
+	// One batch of written quotes and one delete.
+	status := waitFor(t, replica, func(s models.ReplicationStatus) bool { return s.Mirrored == 4 })
+	if status.Divergences != 0 || status.QueueDepth != 0 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if want, got := allQuotes(t, primary), allQuotes(t, secondary); !reflect.DeepEqual(want, got) {
+		t.Fatalf("secondary differs from primary:\nprimary   %+v\nsecondary %+v", want, got)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Conformance(t, func(t *testing.T) storage.QuoteStore {
+		replica := replicastorage.New(slog.New(slog.DiscardHandler), newStore(t), newStore(t), replicastorage.Options{})
+		start(t, replica)
+		return replica
+	})
+}
@@ -0,0 +1,31 @@
+package replicastorage
+
+import (
+	"context"
+
+	"quotes-service/internal/storage"
+)
+
+// AddServed is mirrored like IncrementServed. Either store that is not a
+// storage.ServedAdder gets IncrementServed n times instead.
+func (s *Storage) AddServed(ctx context.Context, id int64, n int64) error {
+	if err := addServed(ctx, s.primary, id, n); err != nil {
+		return err
+	}
+	s.enqueue(mirror{method: "AddServed", apply: func(ctx context.Context, secondary Secondary) error {
+		return addServed(ctx, secondary, id, n)
+	}})
+	return nil
+}
+
+func addServed(ctx context.Context, store Store, id int64, n int64) error {
+	if adder, ok := store.(storage.ServedAdder); ok {
+		return adder.AddServed(ctx, id, n)
+	}
+	for range n {
+		if err := store.IncrementServed(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package replicastorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// WithinTx runs the transaction on the primary, which must be a
+// storage.Transactor, and fails with storage.ErrTxUnsupported otherwise.
+// Once it commits, the quotes it wrote are mirrored like any other
+// mutation; a rolled back transaction mirrors nothing.
+func (s *Storage) WithinTx(ctx context.Context, fn func(tx storage.QuoteStore) error) error {
+	transactor, ok := s.primary.(storage.Transactor)
+	if !ok {
+		return storage.ErrTxUnsupported
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	var rec *recordingTx
+	err := transactor.WithinTx(ctx, func(tx storage.QuoteStore) error {
+		rec = &recordingTx{tx: tx, added: make(map[int64]struct{}), written: make(map[int64]bool)}
+		return fn(rec)
+	})
+	if err != nil {
+		return err
+	}
+
+	var put []int64
+	for id, deleted := range rec.written {
+		if _, added := rec.added[id]; deleted && !added {
+			s.enqueue(deleteQuote(id))
+		} else if !deleted {
+			put = append(put, id)
+		}
+	}
+	if len(put) > 0 {
+		slices.Sort(put)
+		s.mirrorQuotes(ctx, "WithinTx", put...)
+	}
+	return nil
+}
+
+// recordingTx notes which quotes a transaction wrote. written maps a quote
+// ID to whether the quote ends up deleted; added holds the quotes the
+// transaction created, which the secondary has never seen.
+type recordingTx struct {
+	tx      storage.QuoteStore
+	added   map[int64]struct{}
+	written map[int64]bool
+}
+
+func (t *recordingTx) AddQuote(ctx context.Context, quote models.Quote) (int64, error) {
+	id, err := t.tx.AddQuote(ctx, quote)
+	if err == nil {
+		t.added[id] = struct{}{}
+		t.written[id] = false
+	}
+	return id, err
+}
+
+func (t *recordingTx) GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error) {
+	return t.tx.GetAllQuotes(ctx, filter)
+}
+
+func (t *recordingTx) GetQuote(ctx context.Context, id int64) (models.Quote, error) {
+	return t.tx.GetQuote(ctx, id)
+}
+
+func (t *recordingTx) UpdateQuote(ctx context.Context, id int64, update storage.QuoteUpdate, ifVersion int64) (models.Quote, error) {
+	quote, err := t.tx.UpdateQuote(ctx, id, update, ifVersion)
+	if err == nil {
+		t.written[id] = false
+	}
+	return quote, err
+}
+
+func (t *recordingTx) DeleteQuote(ctx context.Context, id int64, ifVersion int64) error {
+	err := t.tx.DeleteQuote(ctx, id, ifVersion)
+	if err == nil {
+		t.written[id] = true
+	}
+	return err
+}
+
+func deleteQuote(id int64) mirror {
+	return mirror{method: "DeleteQuote", apply: func(ctx context.Context, secondary Secondary) error {
+		err := secondary.DeleteQuote(ctx, id, storage.AnyVersion)
+		if errors.Is(err, storage.ErrQuoteNotFound) {
+			return fmt.Errorf("%w: quote %d was missing", errMismatch, id)
+		}
+		return err
+	}}
+}
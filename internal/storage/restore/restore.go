@@ -0,0 +1,404 @@
+// Package restore loads a backup snapshot into an empty store at startup,
+// from a local file, an HTTP(S) URL or an S3-compatible bucket.
+package restore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"quotes-service/internal/lib/s3"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/snapshot"
+)
+
+const (
+	// maxSnapshotBytes caps the size of a downloaded snapshot.
+	maxSnapshotBytes = 1 << 30
+	// maxSidecarBytes caps the size of a .sha256 file.
+	maxSidecarBytes = 4 << 10
+	sidecarSuffix   = ".sha256"
+)
+
+// Store is what a restore needs from the storage backend.
+type Store interface {
+	GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error)
+	Restore(ctx context.Context, quotes []models.Quote) error
+}
+
+// Options configures a restore. URL is one of:
+//
+//   - file:///path/to/quotes-....json.gz, or a directory to take the newest
+//     snapshot in it;
+//   - http(s)://host/path/to/quotes-....json.gz;
+//   - s3://bucket/key, or s3://bucket/prefix/ to take the newest snapshot
+//     under the prefix.
+type Options struct {
+	URL string
+	// S3 reaches the service for s3:// URLs.
+	S3 s3.Options
+	// HTTPClient is used for http(s):// URLs. Nil uses a client with a
+	// five-minute timeout.
+	HTTPClient *http.Client
+}
+
+// Result describes a finished restore.
+type Result struct {
+	// Source is the file, URL or object the snapshot was read from.
+	Source string
+	// Skipped is set when the store already held quotes.
+	Skipped   bool
+	Quotes    int
+	CreatedAt time.Time
+	Duration  time.Duration
+}
+
+// ParseURL validates a restore URL from configuration.
+func ParseURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "file":
+		if u.Host != "" && u.Host != "localhost" {
+			return nil, fmt.Errorf("file URL must not name a host: %q", raw)
+		}
+		if u.Path == "" {
+			return nil, fmt.Errorf("file URL has no path: %q", raw)
+		}
+	case "http", "https":
+		if u.Host == "" {
+			return nil, fmt.Errorf("URL has no host: %q", raw)
+		}
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("s3 URL has no bucket: %q", raw)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q, want file, http, https or s3", u.Scheme)
+	}
+	return u, nil
+}
+
+// Check verifies that a snapshot can be found at opts.URL without reading
+// it: a file or a directory with snapshots in it exists, an HTTP(S) URL
+// answers a HEAD request with 200, or an S3 key or prefix lists objects.
+func Check(ctx context.Context, opts Options) error {
+	u, err := ParseURL(opts.URL)
+	if err != nil {
+		return fmt.Errorf("parse url: %w", err)
+	}
+	switch u.Scheme {
+	case "file":
+		path := filepath.FromSlash(u.Path)
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		if _, ok := snapshot.Latest(names); !ok {
+			return fmt.Errorf("no snapshots in directory %s", path)
+		}
+		return nil
+	case "http", "https":
+		client := opts.HTTPClient
+		if client == nil {
+			client = &http.Client{Timeout: 10 * time.Second}
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.String(), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s: unexpected status %d", u.Redacted(), resp.StatusCode)
+		}
+		return nil
+	default:
+		client, err := s3.New(opts.S3)
+		if err != nil {
+			return fmt.Errorf("s3 client: %w", err)
+		}
+		objects, err := client.ListObjects(ctx, u.Host, strings.TrimPrefix(u.Path, "/"))
+		if err != nil {
+			return err
+		}
+		if len(objects) == 0 {
+			return fmt.Errorf("no objects at %s", u.String())
+		}
+		return nil
+	}
+}
+
+// Run restores the snapshot at opts.URL into store if the store is empty.
+// The snapshot must carry a checksum, either embedded or in a .sha256 file
+// next to it, and every checksum present must match.
+func Run(ctx context.Context, log *slog.Logger, store Store, opts Options) (Result, error) {
+	log = log.With(slog.String("op", "restore.Run"))
+	start := time.Now()
+
+	existing, err := store.GetAllQuotes(ctx, storage.QuoteFilter{})
+	if err != nil {
+		return Result{}, fmt.Errorf("check store: %w", err)
+	}
+	if len(existing) > 0 {
+		log.InfoContext(ctx, "store is not empty, skipping restore", slog.Int("quotes", len(existing)))
+		return Result{Skipped: true, Duration: time.Since(start)}, nil
+	}
+
+	u, err := ParseURL(opts.URL)
+	if err != nil {
+		return Result{}, fmt.Errorf("parse url: %w", err)
+	}
+	var f fetcher
+	switch u.Scheme {
+	case "file":
+		f = fileFetcher{}
+	case "http", "https":
+		client := opts.HTTPClient
+		if client == nil {
+			client = &http.Client{Timeout: 5 * time.Minute}
+		}
+		f = httpFetcher{client: client}
+	case "s3":
+		client, err := s3.New(opts.S3)
+		if err != nil {
+			return Result{}, fmt.Errorf("s3 client: %w", err)
+		}
+		f = s3Fetcher{client: client}
+	}
+
+	source, data, sidecar, err := f.fetch(ctx, u)
+	if err != nil {
+		return Result{Source: source}, fmt.Errorf("fetch %s: %w", source, err)
+	}
+	result := Result{Source: source}
+
+	verified := false
+	if sidecar != nil {
+		want, err := parseSidecar(sidecar)
+		if err != nil {
+			return result, fmt.Errorf("read %s%s: %w", source, sidecarSuffix, err)
+		}
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); got != want {
+			return result, fmt.Errorf("%w: %s%s has %s, file is %s", snapshot.ErrChecksumMismatch, source, sidecarSuffix, want, got)
+		}
+		verified = true
+	}
+	snap, err := snapshot.Decode(data)
+	if err != nil {
+		return result, fmt.Errorf("read %s: %w", source, err)
+	}
+	if snap.Checksum != "" {
+		verified = true
+	}
+	if !verified {
+		return result, fmt.Errorf("%s has no checksum, embedded or in %s", source, sidecarSuffix)
+	}
+
+	if err := store.Restore(ctx, snap.Quotes); err != nil {
+		return result, fmt.Errorf("load %s: %w", source, err)
+	}
+	result.Quotes = len(snap.Quotes)
+	result.CreatedAt = snap.CreatedAt
+	result.Duration = time.Since(start)
+	log.InfoContext(ctx, "restored quotes from snapshot",
+		slog.String("source", source),
+		slog.Int("quotes", result.Quotes),
+		slog.Time("created_at", result.CreatedAt),
+		slog.Int("bytes", len(data)),
+		slog.Duration("duration", result.Duration),
+	)
+	return result, nil
+}
+
+// parseSidecar reads the digest from the output of sha256sum: the hex
+// digest, optionally followed by the file name.
+func parseSidecar(data []byte) (string, error) {
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", errors.New("file is empty")
+	}
+	digest := strings.ToLower(fields[0])
+	if b, err := hex.DecodeString(digest); err != nil || len(b) != sha256.Size {
+		return "", fmt.Errorf("not a SHA-256 digest: %q", fields[0])
+	}
+	return digest, nil
+}
+
+// fetcher downloads a snapshot and its .sha256 sidecar, which is nil when
+// there is none.
+type fetcher interface {
+	fetch(ctx context.Context, u *url.URL) (source string, data, sidecar []byte, err error)
+}
+
+type fileFetcher struct{}
+
+func (fileFetcher) fetch(ctx context.Context, u *url.URL) (string, []byte, []byte, error) {
+	path := filepath.FromSlash(u.Path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return path, nil, nil, err
+	}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return path, nil, nil, err
+		}
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			if !e.IsDir() {
+				names = append(names, e.Name())
+			}
+		}
+		latest, ok := snapshot.Latest(names)
+		if !ok {
+			return path, nil, nil, errors.New("no snapshots in directory")
+		}
+		path = filepath.Join(path, latest)
+	}
+
+	data, err := readFile(path, maxSnapshotBytes)
+	if err != nil {
+		return path, nil, nil, err
+	}
+	sidecar, err := readFile(path+sidecarSuffix, maxSidecarBytes)
+	if errors.Is(err, os.ErrNotExist) {
+		return path, data, nil, nil
+	}
+	if err != nil {
+		return path, nil, nil, err
+	}
+	return path, data, sidecar, nil
+}
+
+func readFile(path string, limit int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readAll(f, limit)
+}
+
+func readAll(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("larger than %d bytes", limit)
+	}
+	return data, nil
+}
+
+type httpFetcher struct {
+	client *http.Client
+}
+
+func (f httpFetcher) fetch(ctx context.Context, u *url.URL) (string, []byte, []byte, error) {
+	source := u.Redacted()
+	data, err := f.get(ctx, u.String(), maxSnapshotBytes)
+	if err != nil {
+		return source, nil, nil, err
+	}
+	sidecarURL := *u
+	sidecarURL.Path += sidecarSuffix
+	sidecarURL.RawPath = ""
+	sidecar, err := f.get(ctx, sidecarURL.String(), maxSidecarBytes)
+	if errors.Is(err, errNotFound) {
+		return source, data, nil, nil
+	}
+	if err != nil {
+		return source, nil, nil, fmt.Errorf("checksum: %w", err)
+	}
+	return source, data, sidecar, nil
+}
+
+var errNotFound = errors.New("not found")
+
+func (f httpFetcher) get(ctx context.Context, rawURL string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return readAll(resp.Body, limit)
+}
+
+type s3Fetcher struct {
+	client *s3.Client
+}
+
+func (f s3Fetcher) fetch(ctx context.Context, u *url.URL) (string, []byte, []byte, error) {
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+	if key == "" || strings.HasSuffix(key, "/") {
+		objects, err := f.client.ListObjects(ctx, bucket, key)
+		if err != nil {
+			return u.String(), nil, nil, err
+		}
+		names := make([]string, 0, len(objects))
+		for _, o := range objects {
+			// Only snapshots directly under the prefix.
+			if !strings.Contains(strings.TrimPrefix(o.Key, key), "/") {
+				names = append(names, o.Key)
+			}
+		}
+		latest, ok := snapshot.Latest(names)
+		if !ok {
+			return u.String(), nil, nil, errors.New("no snapshots under prefix")
+		}
+		key = latest
+	}
+	source := "s3://" + bucket + "/" + key
+
+	data, err := f.client.GetObject(ctx, bucket, key, maxSnapshotBytes)
+	if err != nil {
+		return source, nil, nil, err
+	}
+	sidecar, err := f.client.GetObject(ctx, bucket, key+sidecarSuffix, maxSidecarBytes)
+	if s3.IsNotFound(err) {
+		return source, data, nil, nil
+	}
+	if err != nil {
+		return source, nil, nil, fmt.Errorf("checksum: %w", err)
+	}
+	return source, data, sidecar, nil
+}
@@ -0,0 +1,259 @@
+package restore_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/memorystorage"
+	"quotes-service/internal/storage/restore"
+	"quotes-service/internal/storage/snapshot"
+)
+
+var createdAt = time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+var quotes = []models.Quote{
+	{ID: 1, Text: "First", Author: "A", CreatedAt: createdAt, UpdatedAt: createdAt, Version: 1},
+	{ID: 4, PublicID: "01ARZ3NDEKTSV4RRFFQ69G5FAV", Text: "Second", Author: "B", CreatedAt: createdAt, UpdatedAt: createdAt, Version: 2},
+}
+
+func encode(t *testing.T) []byte {
+	t.Helper()
+	data, err := snapshot.Encode(createdAt, quotes)
+	if err != nil {
+		t.Fatalf("failed to encode snapshot: %v", err)
+	}
+	return data
+}
+
+func sidecar(data []byte, name string) []byte {
+	sum := sha256.Sum256(data)
+	return []byte(hex.EncodeToString(sum[:]) + "  " + name + "\n")
+}
+
+// gzipJSON writes a snapshot by hand, for files Encode would never produce.
+func gzipJSON(t *testing.T, doc string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	io.WriteString(zw, doc)
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestRunFromFile(t *testing.T) {
+	valid := encode(t)
+	tampered := gzipJSON(t, `{"created_at":"2024-06-01T12:00:00Z","checksum":"sha256:`+strings.Repeat("0", 64)+`","quotes":[{"id":1,"text":"Forged","author":"X"}]}`)
+	unsigned := gzipJSON(t, `{"created_at":"2024-06-01T12:00:00Z","quotes":[{"id":1,"text":"First","author":"A"}]}`)
+	name := snapshot.Name(createdAt)
+
+	tests := []struct {
+		name      string
+		files     map[string][]byte
+		url       func(dir string) string
+		wantErr   error
+		wantErrIn string
+	}{
+		{
+			name:  "file with sidecar",
+			files: map[string][]byte{name: valid, name + ".sha256": sidecar(valid, name)},
+			url:   func(dir string) string { return "file://" + filepath.ToSlash(filepath.Join(dir, name)) },
+		},
+		{
+			name: "newest in directory",
+			files: map[string][]byte{
+				snapshot.Name(createdAt.Add(-time.Hour)): []byte("older and broken"),
+				name:                                     valid,
+				"notes.txt":                              []byte("ignored"),
+			},
+			url: func(dir string) string { return "file://" + filepath.ToSlash(dir) },
+		},
+		{
+			name:    "sidecar mismatch",
+			files:   map[string][]byte{name: valid, name + ".sha256": sidecar([]byte("something else"), name)},
+			url:     func(dir string) string { return "file://" + filepath.ToSlash(filepath.Join(dir, name)) },
+			wantErr: snapshot.ErrChecksumMismatch,
+		},
+		{
+			name:    "embedded checksum mismatch",
+			files:   map[string][]byte{name: tampered},
+			url:     func(dir string) string { return "file://" + filepath.ToSlash(filepath.Join(dir, name)) },
+			wantErr: snapshot.ErrChecksumMismatch,
+		},
+		{
+			name:      "no checksum",
+			files:     map[string][]byte{name: unsigned},
+			url:       func(dir string) string { return "file://" + filepath.ToSlash(filepath.Join(dir, name)) },
+			wantErrIn: "no checksum",
+		},
+		{
+			name:    "missing file",
+			url:     func(dir string) string { return "file://" + filepath.ToSlash(filepath.Join(dir, name)) },
+			wantErr: os.ErrNotExist,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			for file, data := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, file), data, 0o644); err != nil {
+					t.Fatalf("failed to write %s: %v", file, err)
+				}
+			}
+			store, err := memorystorage.New()
+			if err != nil {
+				t.Fatalf("failed to init storage: %v", err)
+			}
+
+			result, err := restore.Run(ctx, slog.New(slog.DiscardHandler), store, restore.Options{URL: tt.url(dir)})
+			all, _ := store.GetAllQuotes(ctx, storage.QuoteFilter{})
+			if tt.wantErr != nil || tt.wantErrIn != "" {
+				if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) || !strings.Contains(err.Error(), tt.wantErrIn) {
+					t.Fatalf("expected error %v %q, got %v", tt.wantErr, tt.wantErrIn, err)
+				}
+				if len(all) != 0 {
+					t.Fatalf("expected nothing to be restored, got %+v", all)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to restore: %v", err)
+			}
+			if result.Quotes != 2 || !result.CreatedAt.Equal(createdAt) || filepath.Base(result.Source) != name {
+				t.Fatalf("unexpected result %+v", result)
+			}
+			if len(all) != 2 || all[1].ID != 4 || all[1].Version != 2 {
+				t.Fatalf("unexpected quotes after restore: %+v", all)
+			}
+			if q, err := store.GetQuoteByPublicID(ctx, quotes[1].PublicID); err != nil || q.ID != 4 {
+				t.Fatalf("expected the public id to survive the snapshot, got %+v, %v", q, err)
+			}
+		})
+	}
+}
+
+func TestRunFromHTTP(t *testing.T) {
+	valid := encode(t)
+	name := snapshot.Name(createdAt)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/backups/" + name:
+			w.Write(valid)
+		case "/backups/" + name + ".sha256":
+			w.Write(sidecar([]byte("something else"), name))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	_, err = restore.Run(ctx, slog.New(slog.DiscardHandler), store, restore.Options{URL: srv.URL + "/backups/" + name})
+	if !errors.Is(err, snapshot.ErrChecksumMismatch) {
+		t.Fatalf("expected a checksum mismatch from the sidecar, got %v", err)
+	}
+	_, err = restore.Run(ctx, slog.New(slog.DiscardHandler), store, restore.Options{URL: srv.URL + "/other/" + name})
+	if err == nil {
+		t.Fatal("expected an error for a missing snapshot")
+	}
+}
+
+func TestRunSkipsNonEmptyStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := memorystorage.New()
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	if _, err := store.AddQuote(ctx, models.Quote{Text: "Existing", Author: "A"}); err != nil {
+		t.Fatalf("failed to add quote: %v", err)
+	}
+
+	// The URL is never fetched, so it does not need to exist.
+	result, err := restore.Run(ctx, slog.New(slog.DiscardHandler), store, restore.Options{URL: "file:///nonexistent/quotes.json.gz"})
+	if err != nil || !result.Skipped {
+		t.Fatalf("expected the restore to be skipped, got %+v, %v", result, err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	name := snapshot.Name(createdAt)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), encode(t), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	empty := t.TempDir()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/backups/"+name {
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{name: "file", url: "file://" + filepath.ToSlash(filepath.Join(dir, name))},
+		{name: "directory", url: "file://" + filepath.ToSlash(dir)},
+		{name: "missing file", url: "file:///nonexistent/" + name, wantErr: true},
+		{name: "empty directory", url: "file://" + filepath.ToSlash(empty), wantErr: true},
+		{name: "http", url: srv.URL + "/backups/" + name},
+		{name: "missing http", url: srv.URL + "/other/" + name, wantErr: true},
+		{name: "bad url", url: "ftp://host/" + name, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := restore.Check(context.Background(), restore.Options{URL: tt.url})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{url: "file:///var/backups/quotes"},
+		{url: "https://backups.example.com/quotes-20240601T120000Z.json.gz"},
+		{url: "s3://backups/quotes/"},
+		{url: "s3:///key", wantErr: true},
+		{url: "ftp://example.com/x", wantErr: true},
+		{url: "/var/backups", wantErr: true},
+		{url: "file://host/path", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			_, err := restore.ParseURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
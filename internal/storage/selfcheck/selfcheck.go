@@ -0,0 +1,115 @@
+// Package selfcheck verifies at startup that the storage backend can serve
+// requests, so a broken store fails the deploy instead of every request.
+package selfcheck
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+type Mode string
+
+const (
+	// ModeOff skips the check.
+	ModeOff Mode = "off"
+	// ModeRead pings the store if it supports it and runs a cheap query.
+	ModeRead Mode = "read"
+	// ModeWrite adds a sentinel quote, reads it back and deletes it.
+	ModeWrite Mode = "write"
+)
+
+// SentinelAuthor marks the quote written by the write check.
+const SentinelAuthor = "quotes-service self-check"
+
+// Store is what the checks need from the storage backend.
+type Store interface {
+	AddQuote(ctx context.Context, quote models.Quote) (int64, error)
+	GetQuote(ctx context.Context, id int64) (models.Quote, error)
+	DeleteQuote(ctx context.Context, id int64, ifVersion int64) error
+	Version(ctx context.Context) (uint64, error)
+}
+
+// Pinger is implemented by backends that can check their connection.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Result describes a finished check.
+type Result struct {
+	Mode      Mode
+	Duration  time.Duration
+	CheckedAt time.Time
+	// Err is nil when the check passed. It names the step that failed.
+	Err error
+}
+
+// ParseMode validates a mode name from configuration.
+func ParseMode(s string) (Mode, error) {
+	switch mode := Mode(s); mode {
+	case ModeOff, ModeRead, ModeWrite:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown self-check mode %q", s)
+	}
+}
+
+// Run performs the check for mode.
+func Run(ctx context.Context, store Store, mode Mode) Result {
+	start := time.Now()
+	var err error
+	switch mode {
+	case ModeRead:
+		err = checkRead(ctx, store)
+	case ModeWrite:
+		err = checkWrite(ctx, store)
+	}
+	return Result{
+		Mode:      mode,
+		Duration:  time.Since(start),
+		CheckedAt: start,
+		Err:       err,
+	}
+}
+
+func checkRead(ctx context.Context, store Store) error {
+	if pinger, ok := store.(Pinger); ok {
+		if err := pinger.Ping(ctx); err != nil {
+			return fmt.Errorf("ping storage: %w", err)
+		}
+	}
+	if _, err := store.Version(ctx); err != nil {
+		return fmt.Errorf("query storage version: %w", err)
+	}
+	return nil
+}
+
+func checkWrite(ctx context.Context, store Store) error {
+	sentinel := models.Quote{
+		Text:   fmt.Sprintf("Self-check sentinel written at %s.", time.Now().UTC().Format(time.RFC3339Nano)),
+		Author: SentinelAuthor,
+	}
+
+	id, err := store.AddQuote(ctx, sentinel)
+	if err != nil {
+		return fmt.Errorf("add sentinel quote: %w", err)
+	}
+
+	got, err := store.GetQuote(ctx, id)
+	if err == nil && (got.Text != sentinel.Text || got.Author != sentinel.Author) {
+		err = fmt.Errorf("got %q by %q", got.Text, got.Author)
+	}
+	if err != nil {
+		// Best effort, so a half-working store is not left with the sentinel.
+		_ = store.DeleteQuote(ctx, id, storage.AnyVersion)
+		return fmt.Errorf("read back sentinel quote %d: %w", id, err)
+	}
+
+	if err := store.DeleteQuote(ctx, id, storage.AnyVersion); err != nil {
+		return fmt.Errorf("delete sentinel quote %d: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package selfcheck_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/faultstorage"
+	"quotes-service/internal/storage/memorystorage"
+	"quotes-service/internal/storage/selfcheck"
+)
+
+type pingingStore struct {
+	*memorystorage.Storage
+	pingErr error
+}
+
+func (p pingingStore) Ping(ctx context.Context) error {
+	return p.pingErr
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       selfcheck.Mode
+		failMethod string
+		pingErr    error
+		wantErr    string
+	}{
+		{name: "write passes", mode: selfcheck.ModeWrite},
+		{name: "read passes", mode: selfcheck.ModeRead},
+		{name: "off does nothing", mode: selfcheck.ModeOff, failMethod: "AddQuote"},
+		{name: "add fails", mode: selfcheck.ModeWrite, failMethod: "AddQuote", wantErr: "add sentinel quote"},
+		{name: "read back fails", mode: selfcheck.ModeWrite, failMethod: "GetQuote", wantErr: "read back sentinel quote"},
+		{name: "delete fails", mode: selfcheck.ModeWrite, failMethod: "DeleteQuote", wantErr: "delete sentinel quote"},
+		{name: "read query fails", mode: selfcheck.ModeRead, failMethod: "Version", wantErr: "query storage version"},
+		{name: "ping fails", mode: selfcheck.ModeRead, pingErr: errors.New("database is locked"), wantErr: "ping storage"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			inner, err := memorystorage.New()
+			if err != nil {
+				t.Fatalf("failed to init storage: %v", err)
+			}
+
+			var store selfcheck.Store = inner
+			if tc.failMethod != "" {
+				faulty := faultstorage.New(inner)
+				if err := faulty.SetFaults(faultstorage.Faults{ErrorRate: 1, Methods: []string{tc.failMethod}}); err != nil {
+					t.Fatalf("failed to set faults: %v", err)
+				}
+				store = faulty
+			}
+			if tc.pingErr != nil {
+				store = pingingStore{Storage: inner, pingErr: tc.pingErr}
+			}
+
+			result := selfcheck.Run(ctx, store, tc.mode)
+			if result.Mode != tc.mode {
+				t.Errorf("expected mode %q, got %q", tc.mode, result.Mode)
+			}
+			if tc.wantErr == "" {
+				if result.Err != nil {
+					t.Fatalf("expected the check to pass, got %v", result.Err)
+				}
+			} else if result.Err == nil || !strings.Contains(result.Err.Error(), tc.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, result.Err)
+			}
+
+			if tc.failMethod != "DeleteQuote" {
+				quotes, _ := inner.GetAllQuotes(ctx, storage.QuoteFilter{})
+				if len(quotes) != 0 {
+					t.Fatalf("expected the sentinel to be cleaned up, found %d quotes", len(quotes))
+				}
+			}
+		})
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for _, s := range []string{"off", "read", "write"} {
+		if mode, err := selfcheck.ParseMode(s); err != nil || string(mode) != s {
+			t.Errorf("ParseMode(%q) = %q, %v", s, mode, err)
+		}
+	}
+	if _, err := selfcheck.ParseMode("full"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
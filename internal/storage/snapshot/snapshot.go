@@ -0,0 +1,159 @@
+// Package snapshot defines the backup file format: gzipped JSON holding
+// every quote and a SHA-256 checksum of them, named after the time it was
+// taken so that name order is time order.
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"quotes-service/internal/models"
+)
+
+const (
+	namePrefix = "quotes-"
+	nameSuffix = ".json.gz"
+	nameLayout = "20060102T150405Z"
+
+	checksumPrefix = "sha256:"
+	// maxDecodedBytes caps the uncompressed size of a snapshot, so a
+	// malicious file cannot exhaust memory.
+	maxDecodedBytes = 1 << 30
+)
+
+// ErrChecksumMismatch is returned when a snapshot does not match its
+// checksum.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// Snapshot is the content of a backup file. Checksum is "sha256:" and the
+// hex digest of the quotes array exactly as encoded in the file.
+type Snapshot struct {
+	CreatedAt time.Time      `json:"created_at"`
+	Checksum  string         `json:"checksum,omitempty"`
+	Quotes    []models.Quote `json:"quotes"`
+}
+
+type wireSnapshot struct {
+	CreatedAt time.Time       `json:"created_at"`
+	Checksum  string          `json:"checksum,omitempty"`
+	Quotes    json.RawMessage `json:"quotes"`
+}
+
+// Name returns the file name of a snapshot taken at t.
+func Name(t time.Time) string {
+	return namePrefix + t.UTC().Format(nameLayout) + nameSuffix
+}
+
+// ParseName returns the time a snapshot file name stands for, and false if
+// name is not a snapshot name.
+func ParseName(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, namePrefix) || !strings.HasSuffix(name, nameSuffix) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(nameLayout, strings.TrimSuffix(strings.TrimPrefix(name, namePrefix), nameSuffix))
+	return t, err == nil
+}
+
+// Latest returns the newest snapshot name among names, which may carry a
+// directory or key prefix.
+func Latest(names []string) (string, bool) {
+	sorted := Sort(names)
+	if len(sorted) == 0 {
+		return "", false
+	}
+	return sorted[0], true
+}
+
+// Sort orders snapshot names, which may carry a directory or key prefix,
+// newest first. Names that are not snapshots are dropped.
+func Sort(names []string) []string {
+	type named struct {
+		name string
+		at   time.Time
+	}
+	var snapshots []named
+	for _, name := range names {
+		if at, ok := ParseName(name[strings.LastIndex(name, "/")+1:]); ok {
+			snapshots = append(snapshots, named{name: name, at: at})
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].at.After(snapshots[j].at) })
+	sorted := make([]string, len(snapshots))
+	for i, s := range snapshots {
+		sorted[i] = s.name
+	}
+	return sorted
+}
+
+// Encode returns the gzipped JSON of a snapshot of quotes taken at
+// createdAt, checksum included.
+func Encode(createdAt time.Time, quotes []models.Quote) ([]byte, error) {
+	if quotes == nil {
+		quotes = []models.Quote{}
+	}
+	raw, err := json.Marshal(quotes)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	err = json.NewEncoder(zw).Encode(wireSnapshot{
+		CreatedAt: createdAt.UTC(),
+		Checksum:  Checksum(raw),
+		Quotes:    raw,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode reads a snapshot written by Encode and verifies its checksum, if
+// it has one.
+func Decode(data []byte) (Snapshot, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("decompress: %w", err)
+	}
+	defer zr.Close()
+	raw, err := io.ReadAll(io.LimitReader(zr, maxDecodedBytes+1))
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("decompress: %w", err)
+	}
+	if len(raw) > maxDecodedBytes {
+		return Snapshot{}, fmt.Errorf("snapshot is larger than %d bytes", maxDecodedBytes)
+	}
+
+	var wire wireSnapshot
+	if err := json.Unmarshal(raw, &wire); err != nil {
+		return Snapshot{}, fmt.Errorf("decode: %w", err)
+	}
+	if wire.Checksum != "" {
+		if got := Checksum(wire.Quotes); got != wire.Checksum {
+			return Snapshot{}, fmt.Errorf("%w: embedded %s, computed %s", ErrChecksumMismatch, wire.Checksum, got)
+		}
+	}
+	snap := Snapshot{CreatedAt: wire.CreatedAt, Checksum: wire.Checksum}
+	if err := json.Unmarshal(wire.Quotes, &snap.Quotes); err != nil {
+		return Snapshot{}, fmt.Errorf("decode quotes: %w", err)
+	}
+	return snap, nil
+}
+
+// Checksum returns "sha256:" and the hex digest of data.
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return checksumPrefix + hex.EncodeToString(sum[:])
+}
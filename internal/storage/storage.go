@@ -1,7 +1,351 @@
 package storage
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"quotes-service/internal/lib/language"
+	"quotes-service/internal/models"
+)
 
 var (
-	ErrQuoteNotFound = errors.New("url not found")
+	ErrQuoteNotFound      = errors.New("url not found")
+	ErrCollectionNotFound = errors.New("collection not found")
+	// ErrVersionMismatch is returned when a conditional write names a
+	// version other than the quote's current one.
+	ErrVersionMismatch = errors.New("version mismatch")
+	// ErrStoreNotEmpty is returned when restoring into a store that already
+	// holds quotes.
+	ErrStoreNotEmpty = errors.New("store is not empty")
+	// ErrTxUnsupported is returned by WithinTx of a wrapper whose
+	// underlying store cannot run transactions. fn is not called.
+	ErrTxUnsupported = errors.New("transactions are not supported")
+	// ErrChangesExpired is returned by ChangesSince when some of the
+	// changes asked for are no longer in the log, so the caller has to
+	// resync in full.
+	ErrChangesExpired = errors.New("changes expired")
+	// ErrChangeLogUnsupported is returned by ChangesSince of a wrapper whose
+	// underlying store keeps no change log.
+	ErrChangeLogUnsupported = errors.New("change log is not supported")
+	// ErrShutdownTimeout is returned by Shutdown when the store is still
+	// closing at the deadline.
+	ErrShutdownTimeout = errors.New("storage did not shut down in time")
+	// ErrNothingToClaim is returned by ClaimQuote when every quote it
+	// could claim is already claimed, or there is none.
+	ErrNothingToClaim = errors.New("no quote to claim")
+	// ErrClaimNotHeld is returned by ReleaseQuote when the quote is not
+	// claimed by the caller, or the claim has expired.
+	ErrClaimNotHeld = errors.New("claim not held")
+	// ErrClaimsUnsupported is returned by the Claimer methods of a wrapper
+	// whose underlying store cannot claim quotes.
+	ErrClaimsUnsupported = errors.New("claims are not supported")
+	// ErrCompactionUnsupported is returned by the Compactor methods of a
+	// wrapper whose underlying store is not a Compactor.
+	ErrCompactionUnsupported = errors.New("compaction is not supported")
+	// ErrBatchesUnsupported is returned by the Batcher methods of a wrapper
+	// whose underlying store is not a Batcher.
+	ErrBatchesUnsupported = errors.New("batched bulk operations are not supported")
+	// ErrFingerprintsUnsupported is returned by GetQuotesByFingerprint of a
+	// wrapper whose underlying store is not a FingerprintIndex.
+	ErrFingerprintsUnsupported = errors.New("fingerprint index is not supported")
 )
+
+// AnyVersion disables the version check of a conditional write.
+const AnyVersion int64 = 0
+
+// QuoteNotFoundError names the quote that was missing. It matches
+// ErrQuoteNotFound with errors.Is.
+type QuoteNotFoundError struct {
+	ID int64
+}
+
+func (e *QuoteNotFoundError) Error() string {
+	return fmt.Sprintf("quote %d not found", e.ID)
+}
+
+func (e *QuoteNotFoundError) Unwrap() error {
+	return ErrQuoteNotFound
+}
+
+const (
+	DefaultWeight = 1
+	MaxWeight     = 100
+)
+
+// RandomOptions tunes how a random quote is picked.
+type RandomOptions struct {
+	// Unweighted makes every quote equally likely regardless of its weight.
+	Unweighted bool
+	// ExcludeIDs are skipped when possible. If every quote is excluded the
+	// exclusion is ignored and a plain random quote is returned.
+	ExcludeIDs []int64
+	Filter     QuoteFilter
+}
+
+// QuoteFilter narrows down the quotes returned by list and random queries.
+// Zero values mean no filtering.
+type QuoteFilter struct {
+	// Lang matches quotes tagged with this language or one of its regional
+	// variants; "und" matches quotes with an unknown language.
+	Lang string
+	// HasSource, when set, keeps only quotes with (true) or without (false)
+	// a source or source URL.
+	HasSource *bool
+}
+
+// IsZero reports whether the filter matches every quote.
+func (f QuoteFilter) IsZero() bool {
+	return f.Lang == "" && f.HasSource == nil
+}
+
+// Matches reports whether q passes the filter.
+func (f QuoteFilter) Matches(q models.Quote) bool {
+	if f.Lang != "" && !language.Matches(q.Lang, f.Lang) {
+		return false
+	}
+	if f.HasSource != nil {
+		hasSource := q.Source != "" || q.SourceURL != ""
+		if hasSource != *f.HasSource {
+			return false
+		}
+	}
+	return true
+}
+
+// Author orders for AuthorQuery.Sort.
+const (
+	AuthorSortName       = "name"
+	AuthorSortQuoteCount = "quote_count"
+)
+
+// AuthorQuery selects a page of authors. The zero value selects every
+// author, by name.
+type AuthorQuery struct {
+	// Prefix keeps the authors whose match key starts with the match key
+	// of Prefix, as authorname.Key derives it.
+	Prefix string
+	// Sort is AuthorSortName, the default, or AuthorSortQuoteCount. Ties
+	// in quote count are ordered by name either way.
+	Sort string
+	// Desc sorts from Z to A, or from the most quotes down.
+	Desc bool
+	// Limit is the page size; zero or less means no limit.
+	Limit  int
+	Offset int
+}
+
+// QuoteUpdate lists the quote fields to change. Nil fields are kept.
+type QuoteUpdate struct {
+	Text      *string
+	Author    *string
+	Weight    *int
+	Lang      *string
+	Source    *string
+	SourceURL *string
+	// LangDetected is applied together with Lang.
+	LangDetected bool
+}
+
+// QuoteStore is the part of a store available inside a transaction. Every
+// backend must pass storagetest.Conformance.
+type QuoteStore interface {
+	AddQuote(ctx context.Context, quote models.Quote) (int64, error)
+	// GetAllQuotes returns the quotes matching filter in ascending ID
+	// order, whatever order they were added, updated or deleted in, so
+	// that listings and the pages cut from them are deterministic.
+	GetAllQuotes(ctx context.Context, filter QuoteFilter) ([]models.Quote, error)
+	GetQuote(ctx context.Context, id int64) (models.Quote, error)
+	UpdateQuote(ctx context.Context, id int64, update QuoteUpdate, ifVersion int64) (models.Quote, error)
+	DeleteQuote(ctx context.Context, id int64, ifVersion int64) error
+}
+
+// Transactor is implemented by stores that can apply several writes as
+// one: WithinTx runs fn against tx and keeps its writes only if fn returns
+// nil. On an error, or a panic, none of them are visible afterwards. tx
+// must not be used once fn has returned.
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(tx QuoteStore) error) error
+}
+
+// ChangeLog is implemented by stores that log every quote they add, update
+// or delete under an increasing sequence number, for clients that sync
+// incrementally. The log is bounded, so old changes eventually expire.
+type ChangeLog interface {
+	// ChangesSince returns up to limit changes with a sequence number
+	// above since, oldest first, along with the sequence number of the
+	// newest change logged. It fails with ErrChangesExpired if changes
+	// after since were trimmed, or if since is ahead of the log, as it is
+	// for a client that synced with a store since reset.
+	ChangesSince(ctx context.Context, since uint64, limit int) ([]models.QuoteChange, uint64, error)
+}
+
+// AuditQuery selects entries of the audit trail. Zero fields match every
+// entry. Since is inclusive and Until exclusive.
+type AuditQuery struct {
+	Principal string
+	Operation string
+	QuoteID   int64
+	Since     time.Time
+	Until     time.Time
+	// AfterSeq skips the entries up to and including it, so a reader can
+	// page through a trail that grows and is trimmed meanwhile.
+	AfterSeq uint64
+	// Limit is the page size; zero or less means no limit.
+	Limit  int
+	Offset int
+}
+
+// AuditStore keeps the audit trail of the writes made through the API.
+// Entries are only ever added: none is changed or deleted, except by the
+// store's retention policy dropping the oldest.
+type AuditStore interface {
+	// RecordAudit adds entry to the trail, numbered and stamped by the
+	// store.
+	RecordAudit(ctx context.Context, entry models.AuditEntry) error
+	// QueryAudit returns the page of entries matching q, oldest first, and
+	// how many match in all.
+	QueryAudit(ctx context.Context, q AuditQuery) ([]models.AuditEntry, int, error)
+}
+
+// ServedAdder is implemented by stores that can count several serves of a
+// quote in one call, as IncrementServed called n times would.
+type ServedAdder interface {
+	AddServed(ctx context.Context, id int64, n int64) error
+}
+
+// ClaimOptions selects the quote ClaimQuote claims and for how long.
+type ClaimOptions struct {
+	Filter QuoteFilter
+	// Oldest claims the unclaimed quote created first rather than a random
+	// one.
+	Oldest bool
+	// Lease is how long the claim lasts unless released. It must be
+	// positive.
+	Lease time.Duration
+}
+
+// Claimer is implemented by stores that can lease quotes to consumers, so
+// that several of them pulling the next quote to post never get the same
+// one while its lease lasts. A claim only keeps a quote from other claims:
+// reads see it as usual.
+type Claimer interface {
+	// ClaimQuote picks an unclaimed quote matching opts and claims it for
+	// claimant in one step, so concurrent claims never get the same quote.
+	// A quote whose lease has expired counts as unclaimed. It fails with
+	// ErrNothingToClaim when no quote matching opts is unclaimed.
+	ClaimQuote(ctx context.Context, claimant string, opts ClaimOptions) (models.QuoteClaim, error)
+	// ReleaseQuote ends claimant's claim on quote id before its lease
+	// runs out. It fails with ErrClaimNotHeld when the quote is not
+	// claimed by claimant.
+	ReleaseQuote(ctx context.Context, id int64, claimant string) error
+	// ExpireClaims forgets the claims whose lease has run out and returns
+	// how many there were.
+	ExpireClaims(ctx context.Context) (int, error)
+}
+
+// Compactor is implemented by stores that keep their data in memory, where
+// the room left behind by deletes is not given back on its own.
+type Compactor interface {
+	// Compact rebuilds the store's structures at the size of what they
+	// hold now. Reads and writes wait for it.
+	Compact(ctx context.Context) error
+	// MemoryReport says what the store holds and roughly how much memory
+	// each of its structures takes.
+	MemoryReport(ctx context.Context) (models.StorageReport, error)
+}
+
+// Batch selects one batch of a bulk operation: the quotes it applies to
+// with IDs above After, in ID order, at most Limit of them. Limit must be
+// positive.
+type Batch struct {
+	After int64
+	Limit int
+}
+
+// BatchResult is what one batch of a bulk operation did.
+type BatchResult struct {
+	// IDs are the quotes the batch changed, in order.
+	IDs []int64
+	// Last is the highest ID the batch looked at, where the next batch
+	// starts. It is the batch's After when it looked at none.
+	Last int64
+	// Done reports that no quote the operation applies to is left past
+	// Last.
+	Done bool
+	// Counts maps each source name of a merge to the quotes moved from it
+	// by the batch.
+	Counts map[string]int
+}
+
+// Batcher is implemented by stores that can run their long bulk operations
+// a batch at a time. Each batch is applied at once, as a single call would
+// be, and the caller decides between batches whether to go on, so an
+// operation over many quotes holds the store for no longer than a batch
+// and can be stopped and resumed after Last. Running a batch again is
+// harmless: what it deleted is gone and what it renamed is skipped.
+type Batcher interface {
+	// DeleteAuthorBatch deletes a batch of the quotes of author, matched
+	// by key.
+	DeleteAuthorBatch(ctx context.Context, author string, batch Batch) (BatchResult, error)
+	// MergeAuthorsBatch is MergeAuthors for a batch of the quotes of the
+	// from authors.
+	MergeAuthorsBatch(ctx context.Context, into string, from []string, batch Batch) (BatchResult, error)
+	// ReindexBatch rebuilds the search, language and author index entries
+	// of a batch of all quotes from the quotes themselves.
+	ReindexBatch(ctx context.Context, batch Batch) (BatchResult, error)
+}
+
+// FingerprintIndex is implemented by stores that index quotes by
+// fingerprint.Of their text and author, so that quotes can be found by
+// content without reading every quote of the author.
+type FingerprintIndex interface {
+	// GetQuotesByFingerprint returns the quotes with fingerprint fp in
+	// ascending ID order.
+	GetQuotesByFingerprint(ctx context.Context, fp string) ([]models.Quote, error)
+}
+
+// Shutdowner is implemented by stores that can give up closing when ctx is
+// done, such as a backend that would otherwise wait on a stuck connection.
+// Close stays for callers without a deadline.
+type Shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// Describer is implemented by stores that can name their backend, such as
+// "memory". Wrappers describe the store they wrap.
+type Describer interface {
+	Backend() string
+}
+
+// Backend returns the name of the backend behind store, or "unknown" when
+// it does not say.
+func Backend(store any) string {
+	if d, ok := store.(Describer); ok {
+		return d.Backend()
+	}
+	return "unknown"
+}
+
+// Shutdown closes store, through Shutdown if it implements Shutdowner and
+// Close otherwise, and returns ErrShutdownTimeout if it is not done when ctx
+// is. The store is then left closing in the background, so a backend that
+// ignores ctx cannot keep the caller waiting.
+func Shutdown(ctx context.Context, store interface{ Close() error }) error {
+	done := make(chan error, 1)
+	go func() {
+		if s, ok := store.(Shutdowner); ok {
+			done <- s.Shutdown(ctx)
+			return
+		}
+		done <- store.Close()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %w", ErrShutdownTimeout, ctx.Err())
+	}
+}
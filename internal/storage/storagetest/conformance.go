@@ -0,0 +1,152 @@
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// Putter is implemented by stores that take quotes with their IDs, such as
+// the memory backend a replica mirrors into.
+type Putter interface {
+	PutQuotes(ctx context.Context, quotes []models.Quote) error
+}
+
+// Conformance checks the contract of storage.QuoteStore that every backend
+// must keep, beyond what its own tests cover, against the empty stores
+// newStore returns. Stores that are also a storage.Transactor or a Putter
+// are checked through those too.
+func Conformance(t *testing.T, newStore func(t *testing.T) storage.QuoteStore) {
+	t.Run("GetAllQuotes in ID order", func(t *testing.T) {
+		store := newStore(t)
+		checkOrder(t, store)
+	})
+
+	t.Run("GetAllQuotes in ID order within a transaction", func(t *testing.T) {
+		store := newStore(t)
+		tr, ok := store.(storage.Transactor)
+		if !ok {
+			t.Skip("not a storage.Transactor")
+		}
+		err := tr.WithinTx(context.Background(), func(tx storage.QuoteStore) error {
+			checkOrder(t, tx)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("transaction failed: %v", err)
+		}
+		assertIDOrder(t, store, storage.QuoteFilter{}, nil)
+	})
+
+	t.Run("GetAllQuotes in ID order after PutQuotes", func(t *testing.T) {
+		store := newStore(t)
+		p, ok := store.(Putter)
+		if !ok {
+			t.Skip("not a Putter")
+		}
+		ctx := context.Background()
+		put := []models.Quote{
+			{ID: 7, Text: "seven", Author: "A"},
+			{ID: 3, Text: "three", Author: "B"},
+			{ID: 5, Text: "five", Author: "A"},
+		}
+		if err := p.PutQuotes(ctx, put); err != nil {
+			t.Fatalf("failed to put quotes: %v", err)
+		}
+		id, err := store.AddQuote(ctx, models.Quote{Text: "added", Author: "C"})
+		if err != nil {
+			t.Fatalf("failed to add quote: %v", err)
+		}
+		if err := p.PutQuotes(ctx, []models.Quote{{ID: 1, Text: "one", Author: "B"}}); err != nil {
+			t.Fatalf("failed to put quote: %v", err)
+		}
+		assertIDOrder(t, store, storage.QuoteFilter{}, []int64{1, 3, 5, 7, id})
+	})
+}
+
+// checkOrder interleaves adds, updates and deletes on store and checks
+// that every listing, filtered or not, comes back in ascending ID order.
+func checkOrder(t *testing.T, store storage.QuoteStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	var ids []int64
+	add := func(i int) {
+		t.Helper()
+		q := models.Quote{Text: fmt.Sprintf("quote %d", i), Author: fmt.Sprintf("Author %d", i%3), Lang: "en"}
+		if i%2 == 0 {
+			q.Lang = "fr"
+			q.Source = "a book"
+		}
+		id, err := store.AddQuote(ctx, q)
+		if err != nil {
+			t.Fatalf("failed to add quote %d: %v", i, err)
+		}
+		ids = append(ids, id)
+	}
+	remove := func(id int64) {
+		t.Helper()
+		if err := store.DeleteQuote(ctx, id, storage.AnyVersion); err != nil {
+			t.Fatalf("failed to delete quote %d: %v", id, err)
+		}
+		ids = slices.DeleteFunc(ids, func(other int64) bool { return other == id })
+	}
+
+	for i := range 6 {
+		add(i)
+	}
+	remove(ids[1])
+	remove(ids[3])
+	add(6)
+	// An update must not move a quote to the end, as re-inserting would.
+	text := "updated"
+	if _, err := store.UpdateQuote(ctx, ids[0], storage.QuoteUpdate{Text: &text}, storage.AnyVersion); err != nil {
+		t.Fatalf("failed to update quote %d: %v", ids[0], err)
+	}
+	remove(ids[len(ids)-1])
+	add(7)
+	add(8)
+	remove(ids[0])
+
+	slices.Sort(ids)
+	all := assertIDOrder(t, store, storage.QuoteFilter{}, ids)
+	hasSource := true
+	for _, filter := range []storage.QuoteFilter{{Lang: "en"}, {Lang: "fr"}, {HasSource: &hasSource}} {
+		expected := []int64{}
+		for _, q := range all {
+			if filter.Matches(q) {
+				expected = append(expected, q.ID)
+			}
+		}
+		assertIDOrder(t, store, filter, expected)
+	}
+}
+
+// assertIDOrder checks that store lists the quotes matching filter in
+// ascending ID order and, unless expected is nil, that their IDs are
+// expected. It returns the quotes listed.
+func assertIDOrder(t *testing.T, store storage.QuoteStore, filter storage.QuoteFilter, expected []int64) []models.Quote {
+	t.Helper()
+	quotes, err := store.GetAllQuotes(context.Background(), filter)
+	if err != nil {
+		t.Fatalf("failed to list quotes with %+v: %v", filter, err)
+	}
+	got := make([]int64, len(quotes))
+	for i, q := range quotes {
+		got[i] = q.ID
+		if !filter.Matches(q) {
+			t.Errorf("quote %d does not match %+v", q.ID, filter)
+		}
+	}
+	if !slices.IsSorted(got) || len(slices.Compact(slices.Clone(got))) != len(got) {
+		t.Errorf("expected quotes in ascending ID order with %+v, got %v", filter, got)
+	}
+	if expected != nil && !slices.Equal(got, expected) {
+		t.Errorf("expected IDs %v with %+v, got %v", expected, filter, got)
+	}
+	return quotes
+}
@@ -0,0 +1,418 @@
+// Package storagetest provides a quote store for tests of code built on the
+// storage interfaces, such as handlers and the router. FakeStore keeps
+// quotes in memory like a real backend, but deterministically, and can be
+// scripted to fail or slow down particular calls and to record every call
+// for assertions. Conformance checks a real backend against the contract
+// of storage.QuoteStore.
+package storagetest
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"quotes-service/internal/lib/authorname"
+	"quotes-service/internal/lib/tokenizer"
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+)
+
+// Methods lists the store methods calls can be scripted and recorded for.
+var Methods = []string{
+	"AddQuote",
+	"GetAllQuotes",
+	"GetQuote",
+	"GetRandomQuote",
+	"GetQuotesByAuthor",
+	"UpdateQuote",
+	"DeleteQuote",
+	"IncrementServed",
+	"GetPopularQuotes",
+	"GetSimilarQuotes",
+	"Version",
+}
+
+// Call is one recorded call: the method and its arguments without the
+// context, in the order the method takes them.
+type Call struct {
+	Method string
+	Args   []any
+}
+
+// FakeStore is an in-memory quote store. Unlike memorystorage it is fully
+// deterministic: quotes keep exactly the fields they were given, except
+// that AddQuote sets ID and Version and writes bump Version, timestamps
+// are never set, and GetRandomQuote picks the first quote in ID order that
+// the options allow.
+//
+// It is safe for concurrent use. The zero value is not usable; call New.
+type FakeStore struct {
+	mu      sync.Mutex
+	quotes  map[int64]models.Quote
+	served  map[int64]int64
+	nextID  int64
+	version uint64
+
+	calls   []Call
+	counts  map[string]int
+	fail    map[string]error
+	failAt  map[string]map[int]error
+	latency map[string]time.Duration
+}
+
+// New returns a store holding quotes as given. Quotes with an ID keep it,
+// the others are numbered after the highest ID so far. Each quote counts as a
+// write, so Version starts at len(quotes).
+func New(quotes ...models.Quote) *FakeStore {
+	s := &FakeStore{
+		quotes:  make(map[int64]models.Quote),
+		served:  make(map[int64]int64),
+		nextID:  1,
+		counts:  make(map[string]int),
+		fail:    make(map[string]error),
+		failAt:  make(map[string]map[int]error),
+		latency: make(map[string]time.Duration),
+	}
+	for _, q := range quotes {
+		if q.ID == 0 {
+			q.ID = s.nextID
+		}
+		if _, ok := s.quotes[q.ID]; ok {
+			panic(fmt.Sprintf("storagetest: duplicate quote id %d", q.ID))
+		}
+		s.put(q)
+	}
+	return s
+}
+
+// Fail makes every call of method return err, until Fail is called again
+// with a nil err.
+func (s *FakeStore) Fail(method string, err error) {
+	mustMethod(method)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		delete(s.fail, method)
+		return
+	}
+	s.fail[method] = err
+}
+
+// FailCall makes the nth call of method return err, counting from 1 over
+// every call since New.
+func (s *FakeStore) FailCall(method string, n int, err error) {
+	mustMethod(method)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failAt[method] == nil {
+		s.failAt[method] = make(map[int]error)
+	}
+	s.failAt[method][n] = err
+}
+
+// Delay makes every call of method wait d before it runs, or until its
+// context is done, in which case the call returns the context's error.
+func (s *FakeStore) Delay(method string, d time.Duration) {
+	mustMethod(method)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency[method] = d
+}
+
+// Calls returns the calls made so far, in order. With method names, only
+// the calls of those methods are returned.
+func (s *FakeStore) Calls(methods ...string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var calls []Call
+	for _, c := range s.calls {
+		if len(methods) == 0 || slices.Contains(methods, c.Method) {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Count returns how many times method was called.
+func (s *FakeStore) Count(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[method]
+}
+
+func mustMethod(method string) {
+	if !slices.Contains(Methods, method) {
+		panic(fmt.Sprintf("storagetest: unknown method %q", method))
+	}
+}
+
+// call records a call of method and applies the scripted latency and
+// errors.
+func (s *FakeStore) call(ctx context.Context, method string, args ...any) error {
+	s.mu.Lock()
+	s.calls = append(s.calls, Call{Method: method, Args: args})
+	s.counts[method]++
+	n := s.counts[method]
+	latency := s.latency[method]
+	err := s.failAt[method][n]
+	if err == nil {
+		err = s.fail[method]
+	}
+	s.mu.Unlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// put stores q as a write. s.mu must be held, except from New.
+func (s *FakeStore) put(q models.Quote) {
+	s.quotes[q.ID] = q
+	s.nextID = max(s.nextID, q.ID+1)
+	s.version++
+}
+
+// sorted returns the quotes that match in ID order. s.mu must be held.
+func (s *FakeStore) sorted(match func(models.Quote) bool) []models.Quote {
+	result := make([]models.Quote, 0, len(s.quotes))
+	for _, q := range s.quotes {
+		if match(q) {
+			result = append(result, q)
+		}
+	}
+	slices.SortFunc(result, func(a, b models.Quote) int { return cmp.Compare(a.ID, b.ID) })
+	return result
+}
+
+func (s *FakeStore) AddQuote(ctx context.Context, quote models.Quote) (int64, error) {
+	if err := s.call(ctx, "AddQuote", quote); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quote.ID = s.nextID
+	quote.Version = 1
+	s.put(quote)
+	return quote.ID, nil
+}
+
+func (s *FakeStore) GetAllQuotes(ctx context.Context, filter storage.QuoteFilter) ([]models.Quote, error) {
+	if err := s.call(ctx, "GetAllQuotes", filter); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sorted(filter.Matches), nil
+}
+
+func (s *FakeStore) GetQuote(ctx context.Context, id int64) (models.Quote, error) {
+	if err := s.call(ctx, "GetQuote", id); err != nil {
+		return models.Quote{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.quotes[id]
+	if !ok {
+		return models.Quote{}, storage.ErrQuoteNotFound
+	}
+	return q, nil
+}
+
+// GetRandomQuote returns the first quote in ID order that matches the
+// filter and is not excluded, or the first matching one if every match is
+// excluded. Weights are ignored.
+func (s *FakeStore) GetRandomQuote(ctx context.Context, opts storage.RandomOptions) (models.Quote, error) {
+	if err := s.call(ctx, "GetRandomQuote", opts); err != nil {
+		return models.Quote{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	candidates := s.sorted(opts.Filter.Matches)
+	if len(candidates) == 0 {
+		return models.Quote{}, storage.ErrQuoteNotFound
+	}
+	for _, q := range candidates {
+		if !slices.Contains(opts.ExcludeIDs, q.ID) {
+			return q, nil
+		}
+	}
+	return candidates[0], nil
+}
+
+func (s *FakeStore) GetQuotesByAuthor(ctx context.Context, authorFilter string, filter storage.QuoteFilter) ([]models.Quote, error) {
+	if err := s.call(ctx, "GetQuotesByAuthor", authorFilter, filter); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := authorname.Key(authorFilter)
+	return s.sorted(func(q models.Quote) bool {
+		return authorname.Key(q.Author) == key && filter.Matches(q)
+	}), nil
+}
+
+func (s *FakeStore) UpdateQuote(ctx context.Context, id int64, update storage.QuoteUpdate, ifVersion int64) (models.Quote, error) {
+	if err := s.call(ctx, "UpdateQuote", id, update, ifVersion); err != nil {
+		return models.Quote{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.quotes[id]
+	if !ok {
+		return models.Quote{}, storage.ErrQuoteNotFound
+	}
+	if ifVersion != storage.AnyVersion && q.Version != ifVersion {
+		return models.Quote{}, storage.ErrVersionMismatch
+	}
+	if update.Text != nil {
+		q.Text = *update.Text
+	}
+	if update.Author != nil {
+		q.Author = *update.Author
+	}
+	if update.Weight != nil {
+		q.Weight = *update.Weight
+	}
+	if update.Lang != nil {
+		q.Lang = *update.Lang
+		q.LangDetected = update.LangDetected
+	}
+	if update.Source != nil {
+		q.Source = *update.Source
+	}
+	if update.SourceURL != nil {
+		q.SourceURL = *update.SourceURL
+	}
+	q.Version++
+	s.put(q)
+	return q, nil
+}
+
+func (s *FakeStore) DeleteQuote(ctx context.Context, id int64, ifVersion int64) error {
+	if err := s.call(ctx, "DeleteQuote", id, ifVersion); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.quotes[id]
+	if !ok {
+		return storage.ErrQuoteNotFound
+	}
+	if ifVersion != storage.AnyVersion && q.Version != ifVersion {
+		return storage.ErrVersionMismatch
+	}
+	delete(s.quotes, id)
+	delete(s.served, id)
+	s.version++
+	return nil
+}
+
+func (s *FakeStore) IncrementServed(ctx context.Context, id int64) error {
+	if err := s.call(ctx, "IncrementServed", id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.quotes[id]; !ok {
+		return storage.ErrQuoteNotFound
+	}
+	s.served[id]++
+	return nil
+}
+
+// GetPopularQuotes returns the most served quotes first, ties in ID order.
+func (s *FakeStore) GetPopularQuotes(ctx context.Context, limit int) ([]models.PopularQuote, error) {
+	if err := s.call(ctx, "GetPopularQuotes", limit); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all := s.sorted(func(models.Quote) bool { return true })
+	result := make([]models.PopularQuote, 0, len(all))
+	for _, q := range all {
+		result = append(result, models.PopularQuote{Quote: q, Served: s.served[q.ID]})
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Served > result[j].Served })
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// GetSimilarQuotes ranks the other quotes that share a word with quote id
+// as memorystorage does: other authors first, then by Jaccard similarity
+// of their words, then by ID.
+func (s *FakeStore) GetSimilarQuotes(ctx context.Context, id int64, limit int) ([]models.SimilarQuote, error) {
+	if err := s.call(ctx, "GetSimilarQuotes", id, limit); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	source, ok := s.quotes[id]
+	if !ok {
+		return nil, storage.ErrQuoteNotFound
+	}
+	words := tokenizer.Unique(source.Text)
+	result := []models.SimilarQuote{}
+	for _, q := range s.sorted(func(q models.Quote) bool { return q.ID != id }) {
+		other := tokenizer.Unique(q.Text)
+		shared := 0
+		for _, w := range other {
+			if slices.Contains(words, w) {
+				shared++
+			}
+		}
+		if shared > 0 {
+			result = append(result, models.SimilarQuote{Quote: q, Score: float64(shared) / float64(len(words)+len(other)-shared)})
+		}
+	}
+	author := authorname.Key(source.Author)
+	sort.SliceStable(result, func(i, j int) bool {
+		iOther := authorname.Key(result[i].Author) != author
+		jOther := authorname.Key(result[j].Author) != author
+		if iOther != jOther {
+			return iOther
+		}
+		return result[i].Score > result[j].Score
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// Version counts the writes to the store, including the quotes given to
+// New.
+func (s *FakeStore) Version(ctx context.Context) (uint64, error) {
+	if err := s.call(ctx, "Version"); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.version, nil
+}
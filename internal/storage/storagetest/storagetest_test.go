@@ -0,0 +1,195 @@
+package storagetest_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"quotes-service/internal/models"
+	"quotes-service/internal/storage"
+	"quotes-service/internal/storage/storagetest"
+)
+
+var errBroken = errors.New("broken")
+
+func TestNew(t *testing.T) {
+	ctx := context.Background()
+	store := storagetest.New(
+		models.Quote{ID: 5, Text: "Five", Author: "A"},
+		models.Quote{Text: "Six", Author: "B"},
+	)
+
+	quotes, err := store.GetAllQuotes(ctx, storage.QuoteFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for _, q := range quotes {
+		ids = append(ids, q.ID)
+	}
+	if !reflect.DeepEqual(ids, []int64{5, 6}) {
+		t.Fatalf("expected ids [5 6], got %v", ids)
+	}
+	if version, _ := store.Version(ctx); version != 2 {
+		t.Fatalf("expected version 2 after two seeds, got %d", version)
+	}
+	if id, _ := store.AddQuote(ctx, models.Quote{Text: "Seven", Author: "C"}); id != 7 {
+		t.Fatalf("expected the next id to be 7, got %d", id)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a duplicate id to panic")
+		}
+	}()
+	storagetest.New(models.Quote{ID: 1}, models.Quote{ID: 1})
+}
+
+func TestScripting(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		script   func(*storagetest.FakeStore)
+		expected []error
+	}{
+		{
+			name:     "no script",
+			script:   func(*storagetest.FakeStore) {},
+			expected: []error{nil, nil, nil},
+		},
+		{
+			name:     "nth call",
+			script:   func(s *storagetest.FakeStore) { s.FailCall("GetQuote", 2, errBroken) },
+			expected: []error{nil, errBroken, nil},
+		},
+		{
+			name:     "every call",
+			script:   func(s *storagetest.FakeStore) { s.Fail("GetQuote", errBroken) },
+			expected: []error{errBroken, errBroken, errBroken},
+		},
+		{
+			name: "cleared",
+			script: func(s *storagetest.FakeStore) {
+				s.Fail("GetQuote", errBroken)
+				s.Fail("GetQuote", nil)
+			},
+			expected: []error{nil, nil, nil},
+		},
+		{
+			name:     "other method",
+			script:   func(s *storagetest.FakeStore) { s.Fail("AddQuote", errBroken) },
+			expected: []error{nil, nil, nil},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := storagetest.New(models.Quote{ID: 1, Text: "One", Author: "A"})
+			tc.script(store)
+			for i, want := range tc.expected {
+				if _, err := store.GetQuote(ctx, 1); !errors.Is(err, want) {
+					t.Fatalf("call %d: expected %v, got %v", i+1, want, err)
+				}
+			}
+			if got := store.Count("GetQuote"); got != len(tc.expected) {
+				t.Fatalf("expected %d calls, got %d", len(tc.expected), got)
+			}
+		})
+	}
+}
+
+func TestDelay(t *testing.T) {
+	store := storagetest.New()
+	store.Delay("Version", time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := store.Version(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the delay to end with the context, got %v", err)
+	}
+
+	store.Delay("Version", 0)
+	if _, err := store.Version(context.Background()); err != nil {
+		t.Fatalf("expected no delay after reset, got %v", err)
+	}
+}
+
+func TestCalls(t *testing.T) {
+	ctx := context.Background()
+	store := storagetest.New(models.Quote{ID: 1, Text: "One", Author: "A", Version: 1})
+
+	store.GetQuote(ctx, 1)
+	store.DeleteQuote(ctx, 1, 1)
+	store.GetQuote(ctx, 1)
+
+	expected := []storagetest.Call{
+		{Method: "GetQuote", Args: []any{int64(1)}},
+		{Method: "DeleteQuote", Args: []any{int64(1), int64(1)}},
+		{Method: "GetQuote", Args: []any{int64(1)}},
+	}
+	if got := store.Calls(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected calls %+v, got %+v", expected, got)
+	}
+	if got := store.Calls("DeleteQuote"); !reflect.DeepEqual(got, expected[1:2]) {
+		t.Fatalf("expected delete calls %+v, got %+v", expected[1:2], got)
+	}
+	if got := store.Count("GetQuote"); got != 2 {
+		t.Fatalf("expected 2 GetQuote calls, got %d", got)
+	}
+}
+
+func TestGetRandomQuote(t *testing.T) {
+	store := storagetest.New(
+		models.Quote{ID: 1, Text: "One", Author: "A"},
+		models.Quote{ID: 2, Text: "Two", Author: "B"},
+	)
+
+	tests := []struct {
+		name       string
+		exclude    []int64
+		expectedID int64
+	}{
+		{name: "first", expectedID: 1},
+		{name: "first excluded", exclude: []int64{1}, expectedID: 2},
+		{name: "all excluded", exclude: []int64{1, 2}, expectedID: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			quote, err := store.GetRandomQuote(context.Background(), storage.RandomOptions{ExcludeIDs: tc.exclude})
+			if err != nil || quote.ID != tc.expectedID {
+				t.Fatalf("expected quote %d, got %+v, %v", tc.expectedID, quote, err)
+			}
+		})
+	}
+
+	if _, err := storagetest.New().GetRandomQuote(context.Background(), storage.RandomOptions{}); !errors.Is(err, storage.ErrQuoteNotFound) {
+		t.Fatalf("expected ErrQuoteNotFound from an empty store, got %v", err)
+	}
+}
+
+func TestUpdateQuoteVersion(t *testing.T) {
+	ctx := context.Background()
+	store := storagetest.New(models.Quote{ID: 1, Text: "One", Author: "A", Version: 3})
+	text := "Uno"
+
+	if _, err := store.UpdateQuote(ctx, 1, storage.QuoteUpdate{Text: &text}, 2); !errors.Is(err, storage.ErrVersionMismatch) {
+		t.Fatalf("expected ErrVersionMismatch, got %v", err)
+	}
+	quote, err := store.UpdateQuote(ctx, 1, storage.QuoteUpdate{Text: &text}, 3)
+	if err != nil || quote.Text != "Uno" || quote.Version != 4 {
+		t.Fatalf("expected the update to apply at version 4, got %+v, %v", quote, err)
+	}
+	if version, _ := store.Version(ctx); version != 2 {
+		t.Fatalf("expected store version 2, got %d", version)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Conformance(t, func(t *testing.T) storage.QuoteStore {
+		return storagetest.New()
+	})
+}